@@ -101,6 +101,18 @@ func init() {
 			Usage:       "list database objects",
 			Subcommands: listSubcmd,
 		},
+		{
+			Name:      "validate",
+			Usage:     "validate authcrunch configuration file without starting it",
+			ArgsUsage: "PATH",
+			Action:    validateConfig,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "policy-tests",
+					Usage: "path to the file with the policy tests evaluated with the authorization policies",
+				},
+			},
+		},
 	}
 }
 
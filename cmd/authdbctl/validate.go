@@ -0,0 +1,70 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/greenpau/go-authcrunch"
+	"github.com/greenpau/go-authcrunch/pkg/authz/policytest"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+func validateConfig(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected the path to authcrunch configuration file")
+	}
+
+	logger := logutil.NewInfoLogger()
+	if c.Bool("debug") {
+		logger = logutil.NewLogger()
+	}
+
+	cfg, err := authcrunch.LoadConfig(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	for _, fp := range c.StringSlice("policy-tests") {
+		suite, err := policytest.LoadSuite(fp)
+		if err != nil {
+			return err
+		}
+		cfg.PolicyTests = append(cfg.PolicyTests, suite.Tests...)
+	}
+
+	report := authcrunch.ValidateConfig(cfg, logger)
+
+	var b []byte
+	switch c.String("format") {
+	case "yaml":
+		b, err = yaml.Marshal(report)
+	default:
+		b, err = json.MarshalIndent(report, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", b)
+
+	if !report.Valid {
+		return fmt.Errorf("configuration is invalid: %d issue(s) found", len(report.Issues))
+	}
+	return nil
+}
@@ -18,13 +18,25 @@ import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn"
 	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/authz/policytest"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/directory"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/extauthz"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
+	"github.com/greenpau/go-authcrunch/pkg/forwardauth"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
 	"github.com/greenpau/go-authcrunch/pkg/idp"
 	"github.com/greenpau/go-authcrunch/pkg/ids"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/registry"
+	"github.com/greenpau/go-authcrunch/pkg/rpc"
 	"github.com/greenpau/go-authcrunch/pkg/sso"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
 )
 
 // Config is a configuration of Server.
@@ -39,6 +51,20 @@ type Config struct {
 	disabledIdentityStores    map[string]interface{}
 	disabledIdentityProviders map[string]interface{}
 	UserRegistries            []*registry.UserRegistryConfig `json:"user_registries,omitempty" xml:"user_registries,omitempty" yaml:"user_registries,omitempty"`
+	Extensions                []*extension.Config            `json:"extensions,omitempty" xml:"extensions,omitempty" yaml:"extensions,omitempty"`
+	Vault                     *vault.Config                  `json:"vault,omitempty" xml:"vault,omitempty" yaml:"vault,omitempty"`
+	TokenStores               []*tokenstore.Config           `json:"token_stores,omitempty" xml:"token_stores,omitempty" yaml:"token_stores,omitempty"`
+	Directories               []*directory.Config            `json:"directories,omitempty" xml:"directories,omitempty" yaml:"directories,omitempty"`
+	Metrics                   *metrics.Config                `json:"metrics,omitempty" xml:"metrics,omitempty" yaml:"metrics,omitempty"`
+	AuditSinks                []*events.SinkConfig           `json:"audit_sinks,omitempty" xml:"audit_sinks,omitempty" yaml:"audit_sinks,omitempty"`
+	RPCServers                []*rpc.Config                  `json:"rpc_servers,omitempty" xml:"rpc_servers,omitempty" yaml:"rpc_servers,omitempty"`
+	ExtAuthzServers           []*extauthz.Config             `json:"ext_authz_servers,omitempty" xml:"ext_authz_servers,omitempty" yaml:"ext_authz_servers,omitempty"`
+	ForwardAuthServers        []*forwardauth.Config          `json:"forward_auth_servers,omitempty" xml:"forward_auth_servers,omitempty" yaml:"forward_auth_servers,omitempty"`
+	Geolocation               *geo.Config                    `json:"geolocation,omitempty" xml:"geolocation,omitempty" yaml:"geolocation,omitempty"`
+	Logging                   *logutil.Config                `json:"logging,omitempty" xml:"logging,omitempty" yaml:"logging,omitempty"`
+	// The sample requests with the expected decisions of the authorization
+	// policies, evaluated by ValidateConfig.
+	PolicyTests []*policytest.Case `json:"policy_tests,omitempty" xml:"policy_tests,omitempty" yaml:"policy_tests,omitempty"`
 }
 
 // NewConfig returns an instance of Config.
@@ -141,6 +167,56 @@ func (cfg *Config) Validate() error {
 
 	// Validate auth portal configurations.
 	for _, portalCfg := range cfg.AuthenticationPortals {
+		if portalCfg.PasswordRecoveryConfig != nil {
+			portalCfg.PasswordRecoveryConfig.SetCredentials(cfg.Credentials)
+			portalCfg.PasswordRecoveryConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.PasswordRecoveryConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.EmailChangeConfig != nil {
+			portalCfg.EmailChangeConfig.SetCredentials(cfg.Credentials)
+			portalCfg.EmailChangeConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.EmailChangeConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.AccountDeletionConfig != nil {
+			portalCfg.AccountDeletionConfig.SetCredentials(cfg.Credentials)
+			portalCfg.AccountDeletionConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.AccountDeletionConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.MagicLinkConfig != nil {
+			portalCfg.MagicLinkConfig.SetCredentials(cfg.Credentials)
+			portalCfg.MagicLinkConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.MagicLinkConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.SignInAlertConfig != nil {
+			portalCfg.SignInAlertConfig.SetCredentials(cfg.Credentials)
+			portalCfg.SignInAlertConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.SignInAlertConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.AccessRequestConfig != nil {
+			portalCfg.AccessRequestConfig.SetCredentials(cfg.Credentials)
+			portalCfg.AccessRequestConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.AccessRequestConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+		if portalCfg.BreakGlassConfig != nil {
+			portalCfg.BreakGlassConfig.SetCredentials(cfg.Credentials)
+			portalCfg.BreakGlassConfig.SetMessaging(cfg.Messaging)
+			if err := portalCfg.BreakGlassConfig.ValidateMessaging(); err != nil {
+				return err
+			}
+		}
+
 		// If there are no excplicitly specified identity stores and providers in a portal, add all of them.
 		if len(portalCfg.IdentityStores) == 0 && len(portalCfg.IdentityProviders) == 0 {
 			for _, entry := range cfg.IdentityStores {
@@ -258,6 +334,35 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.Metrics != nil {
+		if err := cfg.Metrics.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Geolocation != nil {
+		if err := cfg.Geolocation.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Logging != nil {
+		if err := cfg.Logging.Validate(); err != nil {
+			return err
+		}
+	}
+
+	auditSinkNames := make(map[string]bool)
+	for _, sink := range cfg.AuditSinks {
+		if err := sink.Validate(); err != nil {
+			return err
+		}
+		if auditSinkNames[sink.Name] {
+			return fmt.Errorf("audit sink %q already exists", sink.Name)
+		}
+		auditSinkNames[sink.Name] = true
+	}
+
 	return nil
 }
 
@@ -319,3 +424,138 @@ func (cfg *Config) AddUserRegistry(r *registry.UserRegistryConfig) error {
 	cfg.UserRegistries = append(cfg.UserRegistries, r)
 	return nil
 }
+
+// AddExtension adds a WebAssembly extension configuration.
+func (cfg *Config) AddExtension(e *extension.Config) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.Extensions {
+		if entry.Name == e.Name {
+			return fmt.Errorf("extension %q already exists", e.Name)
+		}
+	}
+	cfg.Extensions = append(cfg.Extensions, e)
+	return nil
+}
+
+// AddTokenStore adds a token store configuration.
+func (cfg *Config) AddTokenStore(s *tokenstore.Config) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.TokenStores {
+		if entry.Name == s.Name {
+			return fmt.Errorf("token store %q already exists", s.Name)
+		}
+	}
+	cfg.TokenStores = append(cfg.TokenStores, s)
+	return nil
+}
+
+// AddDirectory adds an LDAP directory configuration.
+func (cfg *Config) AddDirectory(d *directory.Config) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.Directories {
+		if entry.Name == d.Name {
+			return fmt.Errorf("directory %q already exists", d.Name)
+		}
+	}
+	cfg.Directories = append(cfg.Directories, d)
+	return nil
+}
+
+// AddRPCServer adds a gRPC server configuration.
+func (cfg *Config) AddRPCServer(s *rpc.Config) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.RPCServers {
+		if entry.Name == s.Name {
+			return fmt.Errorf("rpc server %q already exists", s.Name)
+		}
+	}
+	cfg.RPCServers = append(cfg.RPCServers, s)
+	return nil
+}
+
+// AddExtAuthzServer adds an Envoy external authorization server
+// configuration.
+func (cfg *Config) AddExtAuthzServer(s *extauthz.Config) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.ExtAuthzServers {
+		if entry.Name == s.Name {
+			return fmt.Errorf("ext_authz server %q already exists", s.Name)
+		}
+	}
+	cfg.ExtAuthzServers = append(cfg.ExtAuthzServers, s)
+	return nil
+}
+
+// AddForwardAuthServer adds a forward authentication endpoint configuration.
+func (cfg *Config) AddForwardAuthServer(s *forwardauth.Config) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.ForwardAuthServers {
+		if entry.Name == s.Name {
+			return fmt.Errorf("forward auth server %q already exists", s.Name)
+		}
+	}
+	cfg.ForwardAuthServers = append(cfg.ForwardAuthServers, s)
+	return nil
+}
+
+// AddAuditSink adds an audit sink configuration.
+func (cfg *Config) AddAuditSink(s *events.SinkConfig) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for _, entry := range cfg.AuditSinks {
+		if entry.Name == s.Name {
+			return fmt.Errorf("audit sink %q already exists", s.Name)
+		}
+	}
+	cfg.AuditSinks = append(cfg.AuditSinks, s)
+	return nil
+}
+
+// SetMetrics sets metrics endpoint configuration.
+func (cfg *Config) SetMetrics(m *metrics.Config) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	cfg.Metrics = m
+	return nil
+}
+
+// SetGeolocation sets the geolocation database configuration.
+func (cfg *Config) SetGeolocation(g *geo.Config) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	cfg.Geolocation = g
+	return nil
+}
+
+// SetLogging sets the configuration of the log levels and the redaction.
+func (cfg *Config) SetLogging(l *logutil.Config) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	cfg.Logging = l
+	return nil
+}
+
+// SetVault sets HashiCorp Vault configuration.
+func (cfg *Config) SetVault(v *vault.Config) error {
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	cfg.Vault = v
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestSign(t *testing.T) {
+	// The get-vanilla example of AWS Signature Version 4 test suite.
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	creds := &Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	Sign(req, nil, creds, "us-east-1", "service", ts)
+	tests.EvalObjects(t, "authorization",
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"),
+	)
+
+	// The session token of the temporary credentials is signed.
+	req, _ = http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds.SessionToken = "AQoDYXdzEPT//////////wEXAMPLE"
+	Sign(req, nil, creds, "us-east-1", "service", ts)
+	tests.EvalObjects(t, "session token", creds.SessionToken, req.Header.Get("X-Amz-Security-Token"))
+	tests.EvalObjects(t, "signed headers", true,
+		strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,"),
+	)
+}
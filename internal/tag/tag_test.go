@@ -18,39 +18,98 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/greenpau/go-authcrunch"
+	"github.com/greenpau/go-authcrunch/internal/sigv4"
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/internal/testutils"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authn"
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/authn/admission"
+	"github.com/greenpau/go-authcrunch/pkg/authn/bodylimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/breakglass"
 	authncache "github.com/greenpau/go-authcrunch/pkg/authn/cache"
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/authn/continuation"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authn/crossdomain"
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/deletion"
+	"github.com/greenpau/go-authcrunch/pkg/authn/emailchange"
+	"github.com/greenpau/go-authcrunch/pkg/authn/groupmap"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/authn/idphint"
+	"github.com/greenpau/go-authcrunch/pkg/authn/impersonation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/lifecycle"
+	"github.com/greenpau/go-authcrunch/pkg/authn/linking"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginchain"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginguard"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginhistory"
+	"github.com/greenpau/go-authcrunch/pkg/authn/magiclink"
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/authn/netfilter"
+	"github.com/greenpau/go-authcrunch/pkg/authn/normalizer"
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/oidc"
+	"github.com/greenpau/go-authcrunch/pkg/authn/onetime"
+	"github.com/greenpau/go-authcrunch/pkg/authn/profile"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/realmdiscovery"
+	"github.com/greenpau/go-authcrunch/pkg/authn/recovery"
+	"github.com/greenpau/go-authcrunch/pkg/authn/redirect"
+	"github.com/greenpau/go-authcrunch/pkg/authn/sessionpolicy"
+	"github.com/greenpau/go-authcrunch/pkg/authn/shaper"
+	"github.com/greenpau/go-authcrunch/pkg/authn/signin"
+	"github.com/greenpau/go-authcrunch/pkg/authn/terms"
+	"github.com/greenpau/go-authcrunch/pkg/authn/tokenlifetime"
 	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
 	"github.com/greenpau/go-authcrunch/pkg/authn/ui"
+	"github.com/greenpau/go-authcrunch/pkg/authn/usage"
 	"github.com/greenpau/go-authcrunch/pkg/authproxy"
 	"github.com/greenpau/go-authcrunch/pkg/authz"
 	"github.com/greenpau/go-authcrunch/pkg/authz/bypass"
 	"github.com/greenpau/go-authcrunch/pkg/authz/cache"
+	"github.com/greenpau/go-authcrunch/pkg/authz/forbidden"
 	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
+	"github.com/greenpau/go-authcrunch/pkg/authz/issuer"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
+	"github.com/greenpau/go-authcrunch/pkg/authz/policytest"
+	"github.com/greenpau/go-authcrunch/pkg/authz/stream"
 	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/directory"
+	"github.com/greenpau/go-authcrunch/pkg/enrichment"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/extauthz"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
+	"github.com/greenpau/go-authcrunch/pkg/forwardauth"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/identity/qr"
 	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/idp/kerberos"
+	"github.com/greenpau/go-authcrunch/pkg/idp/mtls"
 	"github.com/greenpau/go-authcrunch/pkg/idp/oauth"
 	"github.com/greenpau/go-authcrunch/pkg/idp/saml"
 	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/ids/external"
 	"github.com/greenpau/go-authcrunch/pkg/ids/ldap"
 	"github.com/greenpau/go-authcrunch/pkg/ids/local"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/pwned"
 	"github.com/greenpau/go-authcrunch/pkg/registry"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/rpc"
 	"github.com/greenpau/go-authcrunch/pkg/sso"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	"github.com/greenpau/go-authcrunch/pkg/util/cfg"
+	"github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
 	"strings"
 	"unicode"
 
@@ -276,7 +335,12 @@ func TestTagCompliance(t *testing.T) {
 		{
 			name:  "test requests.WebAuthn struct",
 			entry: &requests.WebAuthn{},
-			opts:  &Options{},
+			opts: &Options{
+				AllowFieldMismatch: true,
+				AllowedFields: map[string]interface{}{
+					"hardware_key_aaguids": true,
+				},
+			},
 		},
 		{
 			name:  "test public key",
@@ -872,34 +936,1093 @@ func TestTagCompliance(t *testing.T) {
 			entry: &requests.AuthorizationToken{},
 			opts:  &Options{},
 		},
-	}
-
-	for _, tc := range testcases {
-		t.Run(tc.name, func(t *testing.T) {
-			msgs, err := GetTagCompliance(tc.entry, tc.opts)
-			if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, tc.err, msgs) {
-				return
-			}
-		})
-	}
-}
-
-func TestStructTagCompliance(t *testing.T) {
-	var files []string
-	structMap := make(map[string]bool)
-	walkFn := func(path string, fileInfo os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if fileInfo.IsDir() {
-			return nil
-		}
-		fileName := filepath.Base(path)
-		fileExt := filepath.Ext(fileName)
-		if fileExt != ".go" {
-			return nil
-		}
-		if strings.Contains(fileName, "_test.go") {
+		{
+			name:  "test admission.Config struct",
+			entry: &admission.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test admission.Controller struct",
+			entry: &admission.Controller{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test admission.Stats struct",
+			entry: &admission.Stats{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test issuer.ClaimMapping struct",
+			entry: &issuer.ClaimMapping{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test issuer.Config struct",
+			entry: &issuer.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test issuer.Issuer struct",
+			entry: &issuer.Issuer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test usage.Collector struct",
+			entry: &usage.Collector{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test usage.Config struct",
+			entry: &usage.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test usage.Report struct",
+			entry: &usage.Report{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test extension.Config struct",
+			entry: &extension.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test extension.Extension struct",
+			entry: &extension.Extension{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test vault.Config struct",
+			entry: &vault.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test vault.Client struct",
+			entry: &vault.Client{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tests.VaultServer struct",
+			entry: &tests.VaultServer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tokenstore.Config struct",
+			entry: &tokenstore.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test shaper.Config struct",
+			entry: &shaper.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test shaper.Shaper struct",
+			entry: &shaper.Shaper{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test captcha.Config struct",
+			entry: &captcha.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test captcha.Verifier struct",
+			entry: &captcha.Verifier{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test registry.Invite struct",
+			entry: &registry.Invite{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test registry.InviteStore struct",
+			entry: &registry.InviteStore{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test registry.RegistrationRecord struct",
+			entry: &registry.RegistrationRecord{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test notify.Notifier struct",
+			entry: &notify.Notifier{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test onetime.Config struct",
+			entry: &onetime.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test onetime.Issuer struct",
+			entry: &onetime.Issuer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test recovery.Config struct",
+			entry: &recovery.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test recovery.Token struct",
+			entry: &recovery.Token{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test recovery.Manager struct",
+			entry: &recovery.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test emailchange.Config struct",
+			entry: &emailchange.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test emailchange.PendingChange struct",
+			entry: &emailchange.PendingChange{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test emailchange.Manager struct",
+			entry: &emailchange.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test profile.Config struct",
+			entry: &profile.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test profile.FieldRule struct",
+			entry: &profile.FieldRule{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test profile.Validator struct",
+			entry: &profile.Validator{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.Consent struct",
+			entry: &identity.Consent{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Profile struct",
+			entry: &requests.Profile{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Consent struct",
+			entry: &requests.Consent{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test clients.Config struct",
+			entry: &clients.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test clients.ClientConfig struct",
+			entry: &clients.ClientConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test clients.Client struct",
+			entry: &clients.Client{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test clients.Registry struct",
+			entry: &clients.Registry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.Config struct",
+			entry: &oidc.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.ClientConfig struct",
+			entry: &oidc.ClientConfig{},
+			opts: &Options{
+				AllowFieldMismatch: true,
+				AllowedFields: map[string]interface{}{
+					"redirect_uris": true,
+				},
+			},
+		},
+		{
+			name:  "test oidc.AuthorizationRequest struct",
+			entry: &oidc.AuthorizationRequest{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.Grant struct",
+			entry: &oidc.Grant{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.Provider struct",
+			entry: &oidc.Provider{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sso.ServiceProviderConfig struct",
+			entry: &sso.ServiceProviderConfig{},
+			opts: &Options{
+				AllowFieldMismatch: true,
+				AllowedFields: map[string]interface{}{
+					"acs_url": true,
+				},
+			},
+		},
+		{
+			name:  "test sso.AuthnRequest struct",
+			entry: &sso.AuthnRequest{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sso.RoleCatalogConfig struct",
+			entry: &sso.RoleCatalogConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sso.RoleCatalog struct",
+			entry: &sso.RoleCatalog{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sso.AccountEntry struct",
+			entry: &sso.AccountEntry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sso.AssumeRole struct",
+			entry: &sso.AssumeRole{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test directory.Config struct",
+			entry: &directory.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test directory.ServiceAccountConfig struct",
+			entry: &directory.ServiceAccountConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test directory.Server struct",
+			entry: &directory.Server{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test kerberos.Config struct",
+			entry: &kerberos.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test kerberos.IdentityProvider struct",
+			entry: &kerberos.IdentityProvider{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test mtls.Config struct",
+			entry: &mtls.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test mtls.ClaimMapping struct",
+			entry: &mtls.ClaimMapping{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test mtls.IdentityProvider struct",
+			entry: &mtls.IdentityProvider{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test magiclink.Config struct",
+			entry: &magiclink.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test magiclink.Token struct",
+			entry: &magiclink.Token{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test magiclink.Manager struct",
+			entry: &magiclink.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Config struct",
+			entry: &metrics.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Registry struct",
+			entry: &metrics.Registry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Counter struct",
+			entry: &metrics.Counter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Histogram struct",
+			entry: &metrics.Histogram{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Server struct",
+			entry: &metrics.Server{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test events.Event struct",
+			entry: &events.Event{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test events.Bus struct",
+			entry: &events.Bus{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test events.SinkConfig struct",
+			entry: &events.SinkConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test events.Forwarder struct",
+			entry: &events.Forwarder{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test signin.Config struct",
+			entry: &signin.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test signin.Fingerprint struct",
+			entry: &signin.Fingerprint{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test signin.Observation struct",
+			entry: &signin.Observation{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test signin.Token struct",
+			entry: &signin.Token{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test signin.Tracker struct",
+			entry: &signin.Tracker{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test pwned.Config struct",
+			entry: &pwned.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test pwned.Checker struct",
+			entry: &pwned.Checker{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test pwned.Filter struct",
+			entry: &pwned.Filter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test rpc.Config struct",
+			entry: &rpc.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test rpc.Server struct",
+			entry: &rpc.Server{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test extauthz.Config struct",
+			entry: &extauthz.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test extauthz.Server struct",
+			entry: &extauthz.Server{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authz.Decision struct",
+			entry: &authz.Decision{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test forwardauth.Config struct",
+			entry: &forwardauth.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test forwardauth.Server struct",
+			entry: &forwardauth.Server{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authcrunch.ConfigDiff struct",
+			entry: &authcrunch.ConfigDiff{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authcrunch.SectionDiff struct",
+			entry: &authcrunch.SectionDiff{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authcrunch.ValidationIssue struct",
+			entry: &authcrunch.ValidationIssue{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authcrunch.EffectiveACL struct",
+			entry: &authcrunch.EffectiveACL{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authcrunch.ValidationReport struct",
+			entry: &authcrunch.ValidationReport{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test ui.Branding struct",
+			entry: &ui.Branding{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test terms.Config struct",
+			entry: &terms.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test terms.Document struct",
+			entry: &terms.Document{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.Acceptance struct",
+			entry: &identity.Acceptance{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Acceptance struct",
+			entry: &requests.Acceptance{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test deletion.Config struct",
+			entry: &deletion.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test deletion.PendingRequest struct",
+			entry: &deletion.PendingRequest{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test deletion.Manager struct",
+			entry: &deletion.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.DeletionState struct",
+			entry: &identity.DeletionState{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Deletion struct",
+			entry: &requests.Deletion{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.UserExport struct",
+			entry: &identity.UserExport{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.LoginRecord struct",
+			entry: &identity.LoginRecord{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.LoginRecord struct",
+			entry: &requests.LoginRecord{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginhistory.Config struct",
+			entry: &loginhistory.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test geo.Location struct",
+			entry: &geo.Location{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test geo.Config struct",
+			entry: &geo.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test geo.Resolver struct",
+			entry: &geo.Resolver{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test netfilter.Config struct",
+			entry: &netfilter.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test netfilter.Group struct",
+			entry: &netfilter.Group{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test netfilter.Filter struct",
+			entry: &netfilter.Filter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test netfilter.RealmConfig struct",
+			entry: &netfilter.RealmConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tests.OAuthServerQuirks struct",
+			entry: &tests.OAuthServerQuirks{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tests.OAuthServer struct",
+			entry: &tests.OAuthServer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test bodylimit.Config struct",
+			entry: &bodylimit.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test bodylimit.Limiter struct",
+			entry: &bodylimit.Limiter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test log.SubsystemConfig struct",
+			entry: &log.SubsystemConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test log.Config struct",
+			entry: &log.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test log.Entry struct",
+			entry: &log.Entry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cookie.RealmConfig struct",
+			entry: &cookie.RealmConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test validator.TokenSourceConfig struct",
+			entry: &validator.TokenSourceConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test stream.Config struct",
+			entry: &stream.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test redirect.Config struct",
+			entry: &redirect.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test redirect.Policy struct",
+			entry: &redirect.Policy{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test continuation.Config struct",
+			entry: &continuation.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test continuation.State struct",
+			entry: &continuation.State{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test continuation.Codec struct",
+			entry: &continuation.Codec{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.RegistrationConfig struct",
+			entry: &oidc.RegistrationConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test oidc.ClientMetadata struct",
+			entry: &oidc.ClientMetadata{},
+			opts: &Options{
+				AllowFieldMismatch: true,
+				AllowedFields: map[string]interface{}{
+					"redirect_uris": true,
+				},
+			},
+		},
+		{
+			name:  "test oidc.ClientInformation struct",
+			entry: &oidc.ClientInformation{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test transformer.AppConfig struct",
+			entry: &transformer.AppConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test impersonation.Config struct",
+			entry: &impersonation.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test impersonation.Actor struct",
+			entry: &impersonation.Actor{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test delegation.Config struct",
+			entry: &delegation.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test delegation.AdminConfig struct",
+			entry: &delegation.AdminConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test delegation.Scope struct",
+			entry: &delegation.Scope{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.Group struct",
+			entry: &identity.Group{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Group struct",
+			entry: &requests.Group{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test groupmap.Config struct",
+			entry: &groupmap.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test groupmap.File struct",
+			entry: &groupmap.File{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test groupmap.Mapping struct",
+			entry: &groupmap.Mapping{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test groupmap.Mapper struct",
+			entry: &groupmap.Mapper{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test enrichment.Config struct",
+			entry: &enrichment.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test enrichment.Cache struct",
+			entry: &enrichment.Cache{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.QuarantinedUser struct",
+			entry: &identity.QuarantinedUser{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.IntegrityReport struct",
+			entry: &identity.IntegrityReport{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.IntegrityIssue struct",
+			entry: &identity.IntegrityIssue{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginchain.Config struct",
+			entry: &loginchain.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginchain.Chain struct",
+			entry: &loginchain.Chain{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginchain.Method struct",
+			entry: &loginchain.Method{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test realmdiscovery.Config struct",
+			entry: &realmdiscovery.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test realmdiscovery.Rule struct",
+			entry: &realmdiscovery.Rule{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test idphint.Config struct",
+			entry: &idphint.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test idphint.Signer struct",
+			entry: &idphint.Signer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test ratelimit.Config struct",
+			entry: &ratelimit.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test ratelimit.Limiter struct",
+			entry: &ratelimit.Limiter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sessionpolicy.Config struct",
+			entry: &sessionpolicy.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sessionpolicy.Rule struct",
+			entry: &sessionpolicy.Rule{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sessionpolicy.Session struct",
+			entry: &sessionpolicy.Session{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sessionpolicy.Policy struct",
+			entry: &sessionpolicy.Policy{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tokenlifetime.Config struct",
+			entry: &tokenlifetime.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test tokenlifetime.Rule struct",
+			entry: &tokenlifetime.Rule{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test crossdomain.Config struct",
+			entry: &crossdomain.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test crossdomain.Audience struct",
+			entry: &crossdomain.Audience{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test httpclient.Config struct",
+			entry: &httpclient.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test external.Config struct",
+			entry: &external.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test external.WebhookRequest struct",
+			entry: &external.WebhookRequest{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test external.WebhookResponse struct",
+			entry: &external.WebhookResponse{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test external.WebhookUser struct",
+			entry: &external.WebhookUser{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test external.IdentityStore struct",
+			entry: &external.IdentityStore{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginguard.Config struct",
+			entry: &loginguard.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test loginguard.Guard struct",
+			entry: &loginguard.Guard{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authn.ChallengeRequiredResponse struct",
+			entry: &authn.ChallengeRequiredResponse{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test normalizer.Config struct",
+			entry: &normalizer.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test normalizer.Normalizer struct",
+			entry: &normalizer.Normalizer{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test linking.Config struct",
+			entry: &linking.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test linking.Manager struct",
+			entry: &linking.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test linking.PendingLink struct",
+			entry: &linking.PendingLink{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test identity.LinkedIdentity struct",
+			entry: &identity.LinkedIdentity{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.LinkedIdentity struct",
+			entry: &requests.LinkedIdentity{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test policytest.Case struct",
+			entry: &policytest.Case{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test policytest.Suite struct",
+			entry: &policytest.Suite{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test policytest.Result struct",
+			entry: &policytest.Result{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.AuthorizationDenial struct",
+			entry: &requests.AuthorizationDenial{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test acl.Decision struct",
+			entry: &acl.Decision{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test forbidden.Config struct",
+			entry: &forbidden.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test forbidden.Page struct",
+			entry: &forbidden.Page{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test accessrequest.Config struct",
+			entry: &accessrequest.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test accessrequest.App struct",
+			entry: &accessrequest.App{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test accessrequest.Request struct",
+			entry: &accessrequest.Request{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test accessrequest.Manager struct",
+			entry: &accessrequest.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.RoleGrant struct",
+			entry: &requests.RoleGrant{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test sigv4.Credentials struct",
+			entry: &sigv4.Credentials{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test breakglass.Config struct",
+			entry: &breakglass.Config{},
+			opts: &Options{
+				AllowFieldMismatch: true,
+				AllowedFields: map[string]interface{}{
+					"hardware_key_aaguids": true,
+				},
+			},
+		},
+		{
+			name:  "test breakglass.Manager struct",
+			entry: &breakglass.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test maintenance.Config struct",
+			entry: &maintenance.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test maintenance.Switch struct",
+			entry: &maintenance.Switch{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test maintenance.Manager struct",
+			entry: &maintenance.Manager{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.Lifecycle struct",
+			entry: &requests.Lifecycle{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test requests.LifecycleItem struct",
+			entry: &requests.LifecycleItem{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.Config struct",
+			entry: &lifecycle.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.JobConfig struct",
+			entry: &lifecycle.JobConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.Job struct",
+			entry: &lifecycle.Job{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.Item struct",
+			entry: &lifecycle.Item{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.Report struct",
+			entry: &lifecycle.Report{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test lifecycle.Manager struct",
+			entry: &lifecycle.Manager{},
+			opts:  &Options{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs, err := GetTagCompliance(tc.entry, tc.opts)
+			if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, tc.err, msgs) {
+				return
+			}
+		})
+	}
+}
+
+func TestStructTagCompliance(t *testing.T) {
+	var files []string
+	structMap := make(map[string]bool)
+	walkFn := func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fileInfo.IsDir() {
+			return nil
+		}
+		fileName := filepath.Base(path)
+		fileExt := filepath.Ext(fileName)
+		if fileExt != ".go" {
+			return nil
+		}
+		if strings.Contains(fileName, "_test.go") {
+			return nil
+		}
+		if strings.HasSuffix(fileName, ".pb.go") {
+			// Skip the code generated by protoc.
 			return nil
 		}
 		if strings.Contains(path, "/tag/") || strings.Contains(path, "/errors/") {
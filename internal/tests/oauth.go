@@ -0,0 +1,311 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+)
+
+// OAuthServerQuirks holds the deviations from the standard OAuth 2.0 and
+// OpenID Connect behavior exhibited by some authorization servers.
+type OAuthServerQuirks struct {
+	// AcceptHeaderRequired makes the token endpoint respond with
+	// form-encoded body unless the request accepts JSON, e.g. GitHub.
+	AcceptHeaderRequired bool `json:"accept_header_required,omitempty" xml:"accept_header_required,omitempty" yaml:"accept_header_required,omitempty"`
+	// FormEncodedTokenResponse makes the token endpoint always respond
+	// with form-encoded body.
+	FormEncodedTokenResponse bool `json:"form_encoded_token_response,omitempty" xml:"form_encoded_token_response,omitempty" yaml:"form_encoded_token_response,omitempty"`
+	// TokenTypeOmitted removes token_type field from token response.
+	TokenTypeOmitted bool `json:"token_type_omitted,omitempty" xml:"token_type_omitted,omitempty" yaml:"token_type_omitted,omitempty"`
+	// KeyRotationEnabled replaces the signing key prior to issuing
+	// each identity token.
+	KeyRotationEnabled bool `json:"key_rotation_enabled,omitempty" xml:"key_rotation_enabled,omitempty" yaml:"key_rotation_enabled,omitempty"`
+}
+
+// OAuthServer is a minimal in-memory implementation of OAuth 2.0
+// authorization server with OpenID Connect discovery, JWKS, token and
+// user info endpoints.
+type OAuthServer struct {
+	mu           sync.Mutex
+	server       *httptest.Server
+	clientID     string
+	clientSecret string
+	quirks       *OAuthServerQuirks
+	keyID        string
+	key          *rsa.PrivateKey
+	keyCount     int
+	claims       map[string]interface{}
+	profile      map[string]interface{}
+	codes        map[string]string
+	tokens       map[string]bool
+	requests     map[string]int
+}
+
+// NewOAuthServer returns an instance of OAuthServer accepting the client
+// credentials.
+func NewOAuthServer(clientID, clientSecret string) (*OAuthServer, error) {
+	s := &OAuthServer{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		quirks:       &OAuthServerQuirks{},
+		claims:       make(map[string]interface{}),
+		profile:      make(map[string]interface{}),
+		codes:        make(map[string]string),
+		tokens:       make(map[string]bool),
+		requests:     make(map[string]int),
+	}
+	if err := s.RotateKey(); err != nil {
+		return nil, err
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// URL returns the address of OAuthServer.
+func (s *OAuthServer) URL() string {
+	return s.server.URL
+}
+
+// Close shuts down OAuthServer.
+func (s *OAuthServer) Close() {
+	s.server.Close()
+}
+
+// Transport returns http.RoundTripper directing requests to OAuthServer
+// regardless of the requested host. It allows exercising the drivers
+// with hard-coded endpoints.
+func (s *OAuthServer) Transport() http.RoundTripper {
+	u, _ := url.Parse(s.server.URL)
+	return &oauthServerTransport{url: u}
+}
+
+// SetQuirks sets the deviations from the standard behavior.
+func (s *OAuthServer) SetQuirks(quirks *OAuthServerQuirks) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quirks = quirks
+}
+
+// SetClaims sets the claims of the issued identity tokens.
+func (s *OAuthServer) SetClaims(claims map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims = claims
+}
+
+// SetProfile sets the response of user info and user profile endpoints.
+func (s *OAuthServer) SetProfile(profile map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profile = profile
+}
+
+// RotateKey replaces the signing key. The previous key is no longer
+// published at JWKS endpoint.
+func (s *OAuthServer) RotateKey() error {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyCount++
+	s.key = pk
+	s.keyID = fmt.Sprintf("key%d", s.keyCount)
+	return nil
+}
+
+// GetRequestCount returns the number of requests received for the path.
+func (s *OAuthServer) GetRequestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *OAuthServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	rotateKey := s.quirks.KeyRotationEnabled
+	s.mu.Unlock()
+	if rotateKey && isOAuthTokenPath(r.URL.Path) {
+		if err := s.RotateKey(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.URL.Path]++
+
+	var resp interface{}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+		resp = map[string]interface{}{
+			"issuer":                 s.server.URL,
+			"authorization_endpoint": s.server.URL + "/authorize",
+			"token_endpoint":         s.server.URL + "/token",
+			"jwks_uri":               s.server.URL + "/jwks",
+			"userinfo_endpoint":      s.server.URL + "/userinfo",
+		}
+	case strings.HasSuffix(r.URL.Path, "/authorize"), strings.HasSuffix(r.URL.Path, "/dialog/oauth"):
+		params := r.URL.Query()
+		redirectURL, err := url.Parse(params.Get("redirect_uri"))
+		if err != nil || params.Get("client_id") != s.clientID || params.Get("state") == "" {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "malformed authorization request")
+			return
+		}
+		code := NewRandomString(32)
+		s.codes[code] = params.Get("nonce")
+		query := url.Values{}
+		query.Set("code", code)
+		query.Set("state", params.Get("state"))
+		redirectURL.RawQuery = query.Encode()
+		http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+		return
+	case isOAuthTokenPath(r.URL.Path):
+		if err := r.ParseForm(); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if r.Form.Get("client_id") != s.clientID || r.Form.Get("client_secret") != s.clientSecret {
+			s.writeError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+			return
+		}
+		nonce, exists := s.codes[r.Form.Get("code")]
+		if !exists {
+			s.writeError(w, http.StatusBadRequest, "invalid_grant", "authorization code not found")
+			return
+		}
+		delete(s.codes, r.Form.Get("code"))
+		idToken, err := s.signToken(nonce)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		accessToken := NewRandomString(32)
+		s.tokens[accessToken] = true
+		data := map[string]string{
+			"access_token": accessToken,
+			"id_token":     idToken,
+			"token_type":   "Bearer",
+			"expires_in":   "3600",
+		}
+		if s.quirks.TokenTypeOmitted {
+			delete(data, "token_type")
+		}
+		if s.quirks.FormEncodedTokenResponse || (s.quirks.AcceptHeaderRequired && r.Header.Get("Accept") != "application/json") {
+			values := url.Values{}
+			for k, v := range data {
+				values.Set(k, v)
+			}
+			w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			w.Write([]byte(values.Encode()))
+			return
+		}
+		resp = data
+	case r.URL.Path == "/jwks":
+		resp = map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": s.keyID,
+					"alg": "RS256",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+				},
+			},
+		}
+	default:
+		// Any other path is user info or user profile endpoint.
+		if !s.tokens[getOAuthBearerToken(r)] {
+			s.writeError(w, http.StatusUnauthorized, "invalid_token", "access token not found")
+			return
+		}
+		resp = s.profile
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *OAuthServer) signToken(nonce string) (string, error) {
+	claims := jwtlib.MapClaims{}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	claims["aud"] = s.clientID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodRS256, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.key)
+}
+
+func (s *OAuthServer) writeError(w http.ResponseWriter, code int, errCode, errDescription string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             errCode,
+		"error_description": errDescription,
+	})
+}
+
+func isOAuthTokenPath(s string) bool {
+	return strings.HasSuffix(s, "/token") || strings.HasSuffix(s, "/access_token")
+}
+
+func getOAuthBearerToken(r *http.Request) string {
+	if v := r.URL.Query().Get("access_token"); v != "" {
+		return v
+	}
+	arr := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(arr) != 2 {
+		return ""
+	}
+	switch strings.ToLower(arr[0]) {
+	case "bearer", "token":
+		return arr[1]
+	}
+	return ""
+}
+
+type oauthServerTransport struct {
+	url *url.URL
+}
+
+// RoundTrip sends the request to OAuthServer.
+func (t *oauthServerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := r.Clone(r.Context())
+	req.URL.Scheme = t.url.Scheme
+	req.URL.Host = t.url.Host
+	req.Host = ""
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// VaultServer is a minimal in-memory implementation of Vault API serving
+// KV version 2 secrets, Transit ECDSA P-256 signing keys, and token
+// renewal.
+type VaultServer struct {
+	mu          sync.Mutex
+	server      *httptest.Server
+	token       string
+	secrets     map[string]map[string]interface{}
+	transitKeys map[string][]*ecdsa.PrivateKey
+	requests    map[string]int
+}
+
+// NewVaultServer returns an instance of VaultServer accepting the token.
+func NewVaultServer(token string) *VaultServer {
+	vs := &VaultServer{
+		token:       token,
+		secrets:     make(map[string]map[string]interface{}),
+		transitKeys: make(map[string][]*ecdsa.PrivateKey),
+		requests:    make(map[string]int),
+	}
+	vs.server = httptest.NewServer(http.HandlerFunc(vs.serveHTTP))
+	return vs
+}
+
+// URL returns the address of VaultServer.
+func (vs *VaultServer) URL() string {
+	return vs.server.URL
+}
+
+// Close shuts down VaultServer.
+func (vs *VaultServer) Close() {
+	vs.server.Close()
+}
+
+// SetSecret stores the secret data at the path of KV secrets engine.
+func (vs *VaultServer) SetSecret(path string, data map[string]interface{}) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.secrets[path] = data
+}
+
+// RotateTransitKey creates the Transit key or adds a new version to it.
+func (vs *VaultServer) RotateTransitKey(name string) error {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.transitKeys[name] = append(vs.transitKeys[name], pk)
+	return nil
+}
+
+// GetRequestCount returns the number of requests received for the path.
+func (vs *VaultServer) GetRequestCount(path string) int {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return vs.requests[path]
+}
+
+func (vs *VaultServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	vs.requests[path]++
+
+	if r.Header.Get("X-Vault-Token") != vs.token {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	var resp interface{}
+	switch {
+	case path == "auth/token/lookup-self":
+		resp = map[string]interface{}{
+			"data": map[string]interface{}{"ttl": 3600, "renewable": true},
+		}
+	case path == "auth/token/renew-self":
+		resp = map[string]interface{}{
+			"auth": map[string]interface{}{"lease_duration": 3600},
+		}
+	case strings.HasPrefix(path, "secret/data/"):
+		data, exists := vs.secrets[strings.TrimPrefix(path, "secret/data/")]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		resp = map[string]interface{}{
+			"data": map[string]interface{}{"data": data},
+		}
+	case strings.HasPrefix(path, "transit/keys/"):
+		versions, exists := vs.transitKeys[strings.TrimPrefix(path, "transit/keys/")]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		keys := make(map[string]interface{})
+		for i, pk := range versions {
+			b, _ := x509.MarshalPKIXPublicKey(pk.Public())
+			keys[fmt.Sprintf("%d", i+1)] = map[string]interface{}{
+				"public_key": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})),
+			}
+		}
+		resp = map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":           "ecdsa-p256",
+				"latest_version": len(versions),
+				"keys":           keys,
+			},
+		}
+	case strings.HasPrefix(path, "transit/sign/"):
+		arr := strings.Split(strings.TrimPrefix(path, "transit/sign/"), "/")
+		versions, exists := vs.transitKeys[arr[0]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		var req struct {
+			Input      string `json:"input"`
+			KeyVersion int    `json:"key_version"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.KeyVersion == 0 {
+			req.KeyVersion = len(versions)
+		}
+		input, _ := base64.StdEncoding.DecodeString(req.Input)
+		digest := sha256.Sum256(input)
+		sigR, sigS, err := ecdsa.Sign(rand.Reader, versions[req.KeyVersion-1], digest[:])
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		size := (elliptic.P256().Params().BitSize + 7) / 8
+		sig := append(sigR.FillBytes(make([]byte, size)), sigS.FillBytes(make([]byte, size))...)
+		resp = map[string]interface{}{
+			"data": map[string]interface{}{
+				"signature":   fmt.Sprintf("vault:v%d:%s", req.KeyVersion, base64.RawURLEncoding.EncodeToString(sig)),
+				"key_version": req.KeyVersion,
+			},
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
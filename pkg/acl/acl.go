@@ -16,6 +16,10 @@ package acl
 
 import (
 	"context"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
+	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 	"go.uber.org/zap"
 )
 
@@ -71,6 +75,27 @@ func (acl *AccessList) AddRule(ctx context.Context, cfg *RuleConfiguration) erro
 	return nil
 }
 
+// CheckExtensions returns an error when the extension referenced in a rule
+// condition is not loaded or does not implement ACL conditions. The check
+// runs after the extensions are loaded, i.e. not when the rules are added.
+func (acl *AccessList) CheckExtensions(ctx context.Context) error {
+	for i, rule := range acl.rules {
+		for _, cond := range rule.getConfig(ctx).conditions {
+			if cond.matchStrategy != fieldMatchExtension {
+				continue
+			}
+			ext, err := extension.Get(cond.values[0])
+			if err != nil {
+				return errors.ErrAccessListExtension.WithArgs(i, err)
+			}
+			if !ext.CanEvaluate() {
+				return errors.ErrAccessListExtension.WithArgs(i, errors.ErrExtensionEvaluateNotSupported.WithArgs(ext.GetName()))
+			}
+		}
+	}
+	return nil
+}
+
 // AsMap returns acl configuration as map.
 func (acl *AccessList) AsMap() map[string]interface{} {
 	m := make(map[string]interface{})
@@ -109,6 +134,22 @@ func (acl *AccessList) Allow(ctx context.Context, data map[string]interface{}) b
 	return false
 }
 
+// IsAnonymous returns true when the action of a rule has anonymous keyword,
+// e.g. "allow anonymous", i.e. the rule also applies to unauthenticated
+// requests.
+func (cfg *RuleConfiguration) IsAnonymous() bool {
+	tokens, err := cfgutil.DecodeArgs(cfg.Action)
+	if err != nil {
+		return false
+	}
+	for _, token := range tokens {
+		if token == "anonymous" {
+			return true
+		}
+	}
+	return false
+}
+
 // GetFieldDataType return data type for a particular data field.
 func GetFieldDataType(s string) (string, string) {
 	k := s
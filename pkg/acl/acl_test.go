@@ -21,6 +21,7 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"strings"
 	"testing"
 	"time"
 )
@@ -888,3 +889,92 @@ func TestCustomAccessList(t *testing.T) {
 		})
 	}
 }
+
+func newBenchmarkAccessList(b *testing.B, n int) *AccessList {
+	var cfgs []*RuleConfiguration
+	for i := 0; i < n; i++ {
+		var roles []string
+		for j := 0; j < 10; j++ {
+			roles = append(roles, fmt.Sprintf("role%d_%d", i, j))
+		}
+		cfgs = append(cfgs, &RuleConfiguration{
+			Conditions: []string{
+				"match roles " + strings.Join(roles, " "),
+				fmt.Sprintf("match org org%d", i),
+			},
+			Action: `allow`,
+		})
+	}
+	accessList := NewAccessList()
+	if err := accessList.AddRules(context.Background(), cfgs); err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	return accessList
+}
+
+func BenchmarkAccessListAllow(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("rules_%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			accessList := newBenchmarkAccessList(b, n)
+			input := map[string]interface{}{
+				"roles": []string{"guest", "viewer", fmt.Sprintf("role%d_9", n-1)},
+				"org":   []string{fmt.Sprintf("org%d", n-1)},
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !accessList.Allow(ctx, input) {
+					b.Fatal("expected allow")
+				}
+			}
+		})
+	}
+}
+
+func TestAccessListCheckExtensions(t *testing.T) {
+	loadTestExtension(t, "claims")
+
+	var testcases = []struct {
+		name      string
+		config    []*RuleConfiguration
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "extension is loaded",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"extension claims match roles"},
+					Action:     `deny`,
+				},
+			},
+		},
+		{
+			name: "extension is not loaded",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles foobar"},
+					Action:     `allow`,
+				},
+				{
+					Conditions: []string{"extension foobar match roles"},
+					Action:     `deny`,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrAccessListExtension.WithArgs(1, errors.ErrExtensionNotFound.WithArgs("foobar")),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			accessList := NewAccessList()
+			if err := accessList.AddRules(ctx, tc.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := accessList.CheckExtensions(ctx)
+			tests.EvalErr(t, err, tc.config, tc.shouldErr, tc.err)
+		})
+	}
+}
@@ -17,6 +17,7 @@ package acl
 import (
 	"context"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
 	"regexp"
 	"strings"
 )
@@ -27,22 +28,25 @@ type fieldMatchStrategy int
 var (
 	matchWithStrategyRgx *regexp.Regexp
 	matchFieldRgx        *regexp.Regexp
+	matchExtensionRgx    *regexp.Regexp
 
 	inputDataTypes = map[string]dataType{
-		"roles":  dataTypeListStr,
-		"email":  dataTypeStr,
-		"origin": dataTypeStr,
-		"name":   dataTypeStr,
-		"realm":  dataTypeStr,
-		"aud":    dataTypeListStr,
-		"scopes": dataTypeListStr,
-		"org":    dataTypeListStr,
-		"jti":    dataTypeStr,
-		"iss":    dataTypeStr,
-		"sub":    dataTypeStr,
-		"addr":   dataTypeStr,
-		"method": dataTypeStr,
-		"path":   dataTypeStr,
+		"roles":   dataTypeListStr,
+		"email":   dataTypeStr,
+		"origin":  dataTypeStr,
+		"name":    dataTypeStr,
+		"realm":   dataTypeStr,
+		"aud":     dataTypeListStr,
+		"scopes":  dataTypeListStr,
+		"org":     dataTypeListStr,
+		"jti":     dataTypeStr,
+		"iss":     dataTypeStr,
+		"sub":     dataTypeStr,
+		"addr":    dataTypeStr,
+		"method":  dataTypeStr,
+		"path":    dataTypeStr,
+		"country": dataTypeStr,
+		"city":    dataTypeStr,
 	}
 
 	inputDataAliases = map[string]string{
@@ -72,16 +76,17 @@ const (
 	dataTypeStr     dataType = 2
 	dataTypeAny     dataType = 3
 
-	fieldMatchUnknown  fieldMatchStrategy = 0
-	fieldMatchReserved fieldMatchStrategy = 1
-	fieldMatchExact    fieldMatchStrategy = 2
-	fieldMatchPartial  fieldMatchStrategy = 3
-	fieldMatchPrefix   fieldMatchStrategy = 4
-	fieldMatchSuffix   fieldMatchStrategy = 5
-	fieldMatchRegex    fieldMatchStrategy = 6
-	fieldFound         fieldMatchStrategy = 7
-	fieldNotFound      fieldMatchStrategy = 8
-	fieldMatchAlways   fieldMatchStrategy = 9
+	fieldMatchUnknown   fieldMatchStrategy = 0
+	fieldMatchReserved  fieldMatchStrategy = 1
+	fieldMatchExact     fieldMatchStrategy = 2
+	fieldMatchPartial   fieldMatchStrategy = 3
+	fieldMatchPrefix    fieldMatchStrategy = 4
+	fieldMatchSuffix    fieldMatchStrategy = 5
+	fieldMatchRegex     fieldMatchStrategy = 6
+	fieldFound          fieldMatchStrategy = 7
+	fieldNotFound       fieldMatchStrategy = 8
+	fieldMatchAlways    fieldMatchStrategy = 9
+	fieldMatchExtension fieldMatchStrategy = 10
 )
 
 type field struct {
@@ -134,6 +139,17 @@ type ruleCondFieldNotFound struct {
 	config *config
 }
 
+// ruleCondExtensionMatchAnyInput delegates the evaluation of the input
+// value to a WebAssembly extension. When the evaluation fails, the condition
+// matches if failClosed is set, i.e. the condition belongs to a deny rule.
+type ruleCondExtensionMatchAnyInput struct {
+	field      *field
+	exprs      []*expr
+	config     *config
+	extension  string
+	failClosed bool
+}
+
 func (c *ruleAnyCondAlwaysMatchAnyInput) match(ctx context.Context, v interface{}) bool {
 	return true
 }
@@ -158,12 +174,29 @@ func (c *ruleCondFieldNotFound) getConfig(ctx context.Context) *config {
 	return c.config
 }
 
+func (c *ruleCondExtensionMatchAnyInput) match(ctx context.Context, v interface{}) bool {
+	ext, err := extension.Get(c.extension)
+	if err != nil {
+		return c.failClosed
+	}
+	matched, err := ext.Evaluate(c.field.name, v)
+	if err != nil {
+		return c.failClosed
+	}
+	return matched
+}
+
+func (c *ruleCondExtensionMatchAnyInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
 // ruleListStrCondExactNegativeMatchListStrInput not matches a list of strings
 // input against a list of strings where any of the input values not match at least
 // one value of the condition using exact not match.
 type ruleListStrCondExactNegativeMatchListStrInput struct {
 	field  *field
 	exprs  []*expr
+	set    map[string]struct{}
 	config *config
 }
 
@@ -249,6 +282,7 @@ type ruleStrCondRegexNegativeMatchListStrInput struct {
 type ruleListStrCondExactNegativeMatchStrInput struct {
 	field  *field
 	exprs  []*expr
+	set    map[string]struct{}
 	config *config
 }
 
@@ -334,6 +368,7 @@ type ruleStrCondRegexNegativeMatchStrInput struct {
 type ruleListStrCondExactMatchListStrInput struct {
 	field  *field
 	exprs  []*expr
+	set    map[string]struct{}
 	config *config
 }
 
@@ -419,6 +454,7 @@ type ruleStrCondRegexMatchListStrInput struct {
 type ruleListStrCondExactMatchStrInput struct {
 	field  *field
 	exprs  []*expr
+	set    map[string]struct{}
 	config *config
 }
 
@@ -499,11 +535,9 @@ type ruleStrCondRegexMatchStrInput struct {
 }
 
 func (c *ruleListStrCondExactNegativeMatchListStrInput) match(ctx context.Context, values interface{}) bool {
-	for _, exp := range c.exprs {
-		for _, v := range values.([]string) {
-			if v == exp.value {
-				return false
-			}
+	for _, v := range values.([]string) {
+		if _, exists := c.set[v]; exists {
+			return false
 		}
 	}
 	return true
@@ -599,10 +633,8 @@ func (c *ruleStrCondRegexNegativeMatchListStrInput) match(ctx context.Context, v
 }
 
 func (c *ruleListStrCondExactNegativeMatchStrInput) match(ctx context.Context, v interface{}) bool {
-	for _, exp := range c.exprs {
-		if v.(string) == exp.value {
-			return false
-		}
+	if _, exists := c.set[v.(string)]; exists {
+		return false
 	}
 	return true
 }
@@ -679,11 +711,9 @@ func (c *ruleStrCondRegexNegativeMatchStrInput) match(ctx context.Context, v int
 }
 
 func (c *ruleListStrCondExactMatchListStrInput) match(ctx context.Context, values interface{}) bool {
-	for _, exp := range c.exprs {
-		for _, v := range values.([]string) {
-			if v == exp.value {
-				return true
-			}
+	for _, v := range values.([]string) {
+		if _, exists := c.set[v]; exists {
+			return true
 		}
 	}
 	return false
@@ -779,10 +809,8 @@ func (c *ruleStrCondRegexMatchListStrInput) match(ctx context.Context, values in
 }
 
 func (c *ruleListStrCondExactMatchStrInput) match(ctx context.Context, v interface{}) bool {
-	for _, exp := range c.exprs {
-		if v.(string) == exp.value {
-			return true
-		}
+	if _, exists := c.set[v.(string)]; exists {
+		return true
 	}
 	return false
 }
@@ -1021,6 +1049,7 @@ func (c *ruleStrCondRegexMatchStrInput) getConfig(ctx context.Context) *config {
 func init() {
 	matchWithStrategyRgx = regexp.MustCompile(`^\s*((?P<negative_match>no)\s)?((?P<match_strategy>exact|partial|prefix|suffix|regex)\s)?match`)
 	matchFieldRgx = regexp.MustCompile(`^\s*field\s+(?P<field_name>\S+)\s+(?P<field_exists>exists|not\s+exists)\s*$`)
+	matchExtensionRgx = regexp.MustCompile(`^\s*extension\s+(?P<extension_name>\S+)\s+match\s+(?P<field_name>\S+)\s*$`)
 }
 
 func (cfg *config) AsMap() map[string]interface{} {
@@ -1125,7 +1154,7 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 	var inputDataType, condDataType dataType
 	var matchStrategy fieldMatchStrategy
 	var negativeMatch bool
-	var fieldName string
+	var fieldName, extensionName string
 	var values []string
 
 	line := strings.Join(tokens, " ")
@@ -1153,6 +1182,24 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 		}
 		inputDataType = dataTypeAny
 		condDataType = dataTypeAny
+	case matchExtensionRgx.Match([]byte(line)):
+		matched := matchExtensionRgx.FindStringSubmatch(line)
+		for i, k := range matchExtensionRgx.SubexpNames() {
+			if i > 0 && i <= len(matched) {
+				switch k {
+				case "extension_name":
+					extensionName = matched[i]
+				case "field_name":
+					fieldName = matched[i]
+					if alias, exists := inputDataAliases[fieldName]; exists {
+						fieldName = alias
+					}
+				}
+			}
+		}
+		matchStrategy = fieldMatchExtension
+		inputDataType = dataTypeAny
+		condDataType = dataTypeAny
 	case matchWithStrategyRgx.Match([]byte(line)):
 		matched := matchWithStrategyRgx.FindStringSubmatch(line)
 		for i, k := range matchWithStrategyRgx.SubexpNames() {
@@ -1180,7 +1227,7 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 	}
 
 	switch matchStrategy {
-	case fieldMatchAlways, fieldFound, fieldNotFound:
+	case fieldMatchAlways, fieldFound, fieldNotFound, fieldMatchExtension:
 	default:
 		if err := validateFieldNameValues(line, fieldName, values); err != nil {
 			return nil, err
@@ -1234,6 +1281,27 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			exprs: []*expr{},
 		}
 		return c, nil
+	case matchStrategy == fieldMatchExtension:
+		// Match: Extension, Condition Type: Any, Input Type: Any
+		c := &ruleCondExtensionMatchAnyInput{
+			config: &config{
+				field:         fieldName,
+				matchStrategy: fieldMatchExtension,
+				values:        []string{extensionName},
+				regexEnabled:  false,
+				alwaysTrue:    false,
+				exprDataType:  inputDataType,
+				inputDataType: condDataType,
+				conditionType: `ruleCondExtensionMatchAnyInput`,
+			},
+			field: &field{
+				name:   fieldName,
+				length: len(fieldName),
+			},
+			exprs:     []*expr{},
+			extension: extensionName,
+		}
+		return c, nil
 	case matchStrategy == fieldMatchAlways:
 		// Match: Always, Condition Type: Any, Input Type: Any
 		c := &ruleAnyCondAlwaysMatchAnyInput{
@@ -1273,10 +1341,12 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			},
 		}
 		c.exprs = []*expr{}
+		c.set = make(map[string]struct{})
 		for _, val := range values {
 			c.exprs = append(c.exprs, &expr{
 				value: val,
 			})
+			c.set[val] = struct{}{}
 		}
 		return c, nil
 	case negativeMatch && matchStrategy == fieldMatchPartial && condDataType == dataTypeListStr && inputDataType == dataTypeListStr:
@@ -1512,10 +1582,12 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			},
 		}
 		c.exprs = []*expr{}
+		c.set = make(map[string]struct{})
 		for _, val := range values {
 			c.exprs = append(c.exprs, &expr{
 				value: val,
 			})
+			c.set[val] = struct{}{}
 		}
 		return c, nil
 	case negativeMatch && matchStrategy == fieldMatchPartial && condDataType == dataTypeListStr && inputDataType == dataTypeStr:
@@ -1751,10 +1823,12 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			},
 		}
 		c.exprs = []*expr{}
+		c.set = make(map[string]struct{})
 		for _, val := range values {
 			c.exprs = append(c.exprs, &expr{
 				value: val,
 			})
+			c.set[val] = struct{}{}
 		}
 		return c, nil
 	case matchStrategy == fieldMatchPartial && condDataType == dataTypeListStr && inputDataType == dataTypeListStr:
@@ -1990,10 +2064,12 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			},
 		}
 		c.exprs = []*expr{}
+		c.set = make(map[string]struct{})
 		for _, val := range values {
 			c.exprs = append(c.exprs, &expr{
 				value: val,
 			})
+			c.set[val] = struct{}{}
 		}
 		return c, nil
 	case matchStrategy == fieldMatchPartial && condDataType == dataTypeListStr && inputDataType == dataTypeStr:
@@ -2233,6 +2309,8 @@ func getMatchStrategyName(s fieldMatchStrategy) string {
 		return "fieldNotFound"
 	case fieldMatchAlways:
 		return "fieldMatchAlways"
+	case fieldMatchExtension:
+		return "fieldMatchExtension"
 	case fieldMatchReserved:
 		return "fieldMatchReserved"
 	}
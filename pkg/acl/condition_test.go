@@ -19,11 +19,24 @@ import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
 	"reflect"
 	"strings"
 	"testing"
 )
 
+func loadTestExtension(t *testing.T, name string) *extension.Extension {
+	ext, err := extension.NewExtension(&extension.Config{
+		Name: name,
+		Path: "../../testdata/extension/claims.wasm",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	extension.Register(ext)
+	return ext
+}
+
 func TestNewAclRuleCondition(t *testing.T) {
 	var testcases = []struct {
 		name      string
@@ -2183,6 +2196,21 @@ func TestNewAclRuleCondition(t *testing.T) {
 				"input_data_type":         "dataTypeStr",
 				"values":                  []string{`foobar`},
 			},
+		}, {name: "match an input string against a list of strings condition in country field",
+			condition: `match country US CA`,
+			want: map[string]interface{}{
+				"condition_type":          "*acl.ruleListStrCondExactMatchStrInput",
+				"field_name":              "country",
+				"regex_enabled":           false,
+				"match_strategy":          "fieldMatchExact",
+				"always_true":             false,
+				"default_match_strategy":  "fieldMatchUnknown",
+				"reserved_match_strategy": "fieldMatchReserved",
+				"default_data_type":       "dataTypeUnknown",
+				"expr_data_type":          "dataTypeListStr",
+				"input_data_type":         "dataTypeStr",
+				"values":                  []string{`US`, `CA`},
+			},
 		}, {name: "default match an input string against a string condition in addr field",
 			condition: ` match addr foobar`,
 			want: map[string]interface{}{
@@ -2738,6 +2766,21 @@ func TestNewAclRuleCondition(t *testing.T) {
 			condition: `reserved match roles anonymous`,
 			shouldErr: true,
 			err:       errors.ErrACLRuleConditionSyntaxMatchNotFound.WithArgs("reserved match roles anonymous"),
+		}, {name: "extension match an input against roles field",
+			condition: `extension claims match groups`,
+			want: map[string]interface{}{
+				"condition_type":          "*acl.ruleCondExtensionMatchAnyInput",
+				"field_name":              "roles",
+				"regex_enabled":           false,
+				"match_strategy":          "fieldMatchExtension",
+				"always_true":             false,
+				"default_match_strategy":  "fieldMatchUnknown",
+				"reserved_match_strategy": "fieldMatchReserved",
+				"default_data_type":       "dataTypeUnknown",
+				"expr_data_type":          "dataTypeAny",
+				"input_data_type":         "dataTypeAny",
+				"values":                  []string{`claims`},
+			},
 		},
 	}
 	for _, tc := range testcases {
@@ -4955,6 +4998,22 @@ func TestMatchAclRuleCondition(t *testing.T) {
 			want: map[string]interface{}{
 				"match": false,
 			},
+		}, {name: "match an input string against a list of strings condition in country field",
+			condition: `match country US CA`,
+			values: map[string]interface{}{
+				"data": "CA",
+			},
+			want: map[string]interface{}{
+				"match": true,
+			},
+		}, {name: "failed match an input string against a list of strings condition in country field",
+			condition: `match country US CA`,
+			values: map[string]interface{}{
+				"data": "RU",
+			},
+			want: map[string]interface{}{
+				"match": false,
+			},
 		}, {name: "failed partial match an input string against a list of strings in addr field",
 			condition: `partial match addr barfoo foobar`,
 			values: map[string]interface{}{
@@ -5488,3 +5547,73 @@ func TestMatchAclRuleCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchAclRuleExtensionCondition(t *testing.T) {
+	loadTestExtension(t, "claims")
+	// The calls to the closed extension fail.
+	loadTestExtension(t, "closed").Close()
+
+	var testcases = []struct {
+		name       string
+		condition  string
+		failClosed bool
+		values     map[string]interface{}
+		want       map[string]interface{}
+	}{
+		{name: "extension match an input string in roles field",
+			condition: `extension claims match roles`,
+			values: map[string]interface{}{
+				"data": "admin",
+			},
+			want: map[string]interface{}{
+				"match": true,
+			},
+		}, {name: "failed extension match an input string in roles field",
+			condition: `extension claims match roles`,
+			values: map[string]interface{}{
+				"data": "user",
+			},
+			want: map[string]interface{}{
+				"match": false,
+			},
+		}, {name: "failed extension match with unknown extension",
+			condition: `extension foobar match roles`,
+			values: map[string]interface{}{
+				"data": "admin",
+			},
+			want: map[string]interface{}{
+				"match": false,
+			},
+		}, {name: "failed extension match with failed evaluation",
+			condition: `extension closed match roles`,
+			values: map[string]interface{}{
+				"data": "admin",
+			},
+			want: map[string]interface{}{
+				"match": false,
+			},
+		}, {name: "extension match with failed evaluation in deny rule",
+			condition:  `extension closed match roles`,
+			failClosed: true,
+			values: map[string]interface{}{
+				"data": "user",
+			},
+			want: map[string]interface{}{
+				"match": true,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cond, err := newACLRuleCondition(ctx, strings.Split(tc.condition, " "))
+			if tests.EvalErr(t, err, tc.condition, false, nil) {
+				return
+			}
+			cond.(*ruleCondExtensionMatchAnyInput).failClosed = tc.failClosed
+			got := make(map[string]interface{})
+			got["match"] = cond.match(ctx, tc.values["data"])
+			tests.EvalObjects(t, "match result", tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+)
+
+// The reasons for access list decisions.
+const (
+	ReasonRuleAllowed    = "rule_allowed"
+	ReasonRuleDenied     = "rule_denied"
+	ReasonNoMatchingRule = "no_matching_rule"
+	ReasonDefaultAllow   = "default_allow"
+)
+
+// Decision is the explanation of an access list decision.
+type Decision struct {
+	Allowed bool `json:"allowed,omitempty" xml:"allowed,omitempty" yaml:"allowed,omitempty"`
+	// Rule is the position, starting from 1, of the rule deciding the
+	// request. It is zero when no rule matched the request.
+	Rule    int    `json:"rule,omitempty" xml:"rule,omitempty" yaml:"rule,omitempty"`
+	Comment string `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	Reason  string `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	// MissingRoles are the roles required by the allow rules of the access
+	// list and absent in the client identity.
+	MissingRoles []string `json:"missing_roles,omitempty" xml:"missing_roles,omitempty" yaml:"missing_roles,omitempty"`
+}
+
+// Evaluate takes in client identity and metadata and returns the
+// explanation of the decision made by Allow.
+func (acl *AccessList) Evaluate(ctx context.Context, data map[string]interface{}) *Decision {
+	d := &Decision{}
+	for i, rule := range acl.rules {
+		v := rule.eval(ctx, data)
+		switch v {
+		case ruleVerdictAllowStop:
+			d.setRule(ctx, i, rule, true, ReasonRuleAllowed)
+			return d
+		case ruleVerdictAllow:
+			if !d.Allowed {
+				d.setRule(ctx, i, rule, true, ReasonRuleAllowed)
+			}
+		case ruleVerdictDenyStop, ruleVerdictDeny:
+			d.setRule(ctx, i, rule, false, ReasonRuleDenied)
+			return d
+		}
+	}
+	if d.Allowed {
+		return d
+	}
+	if acl.defaultAllow {
+		d.Allowed = true
+		d.Reason = ReasonDefaultAllow
+		return d
+	}
+	d.Reason = ReasonNoMatchingRule
+	d.MissingRoles = acl.getMissingRoles(ctx, data)
+	return d
+}
+
+func (d *Decision) setRule(ctx context.Context, i int, rule aclRule, allowed bool, reason string) {
+	d.Allowed = allowed
+	d.Rule = i + 1
+	d.Comment = rule.getConfig(ctx).comment
+	d.Reason = reason
+}
+
+func (acl *AccessList) getMissingRoles(ctx context.Context, data map[string]interface{}) []string {
+	roles := make(map[string]bool)
+	switch v := data["roles"].(type) {
+	case []string:
+		for _, role := range v {
+			roles[role] = true
+		}
+	case []interface{}:
+		for _, role := range v {
+			if s, ok := role.(string); ok {
+				roles[s] = true
+			}
+		}
+	case string:
+		roles[v] = true
+	}
+
+	var missingRoles []string
+	seen := make(map[string]bool)
+	for _, rule := range acl.rules {
+		cfg := rule.getConfig(ctx)
+		if cfg.action != ruleActionAllow {
+			continue
+		}
+		for _, c := range cfg.conditions {
+			if c.field != "roles" || c.matchStrategy != fieldMatchExact {
+				continue
+			}
+			for _, role := range c.values {
+				if roles[role] || seen[role] {
+					continue
+				}
+				seen[role] = true
+				missingRoles = append(missingRoles, role)
+			}
+		}
+	}
+	return missingRoles
+}
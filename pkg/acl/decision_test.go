@@ -0,0 +1,138 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"testing"
+)
+
+func TestEvaluateAccessList(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		config       []*RuleConfiguration
+		defaultAllow bool
+		input        map[string]interface{}
+		want         *Decision
+	}{
+		{
+			name: "allowed by second rule",
+			config: []*RuleConfiguration{
+				{
+					Comment:    "admins",
+					Conditions: []string{"exact match roles admin"},
+					Action:     `allow stop`,
+				},
+				{
+					Comment:    "editors",
+					Conditions: []string{"exact match roles editor"},
+					Action:     `allow stop`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"editor"},
+			},
+			want: &Decision{
+				Allowed: true,
+				Rule:    2,
+				Comment: "editors",
+				Reason:  ReasonRuleAllowed,
+			},
+		},
+		{
+			name: "denied by rule",
+			config: []*RuleConfiguration{
+				{
+					Comment:    "block contractors",
+					Conditions: []string{"exact match roles contractor"},
+					Action:     `deny stop`,
+				},
+				{
+					Conditions: []string{"exact match roles editor"},
+					Action:     `allow stop`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"contractor", "editor"},
+			},
+			want: &Decision{
+				Rule:    1,
+				Comment: "block contractors",
+				Reason:  ReasonRuleDenied,
+			},
+		},
+		{
+			name: "denied without matching rule with missing roles",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"exact match roles admin editor"},
+					Action:     `allow stop`,
+				},
+				{
+					Conditions: []string{"exact match roles editor viewer"},
+					Action:     `allow`,
+				},
+				{
+					Conditions: []string{"exact match roles guest"},
+					Action:     `deny`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"viewer-ro"},
+			},
+			want: &Decision{
+				Reason:       ReasonNoMatchingRule,
+				MissingRoles: []string{"admin", "editor", "viewer"},
+			},
+		},
+		{
+			name: "allowed by default",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"exact match roles admin"},
+					Action:     `allow stop`,
+				},
+			},
+			defaultAllow: true,
+			input: map[string]interface{}{
+				"roles": []string{"viewer"},
+			},
+			want: &Decision{
+				Allowed: true,
+				Reason:  ReasonDefaultAllow,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			accessList := NewAccessList()
+			accessList.SetLogger(logutil.NewLogger())
+			if tc.defaultAllow {
+				accessList.SetDefaultAllowAction()
+			}
+			if err := accessList.AddRules(ctx, tc.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := accessList.Evaluate(ctx, tc.input)
+			if got.Allowed != accessList.Allow(ctx, tc.input) {
+				t.Fatalf("decision mismatch: Evaluate %t, Allow %t", got.Allowed, !got.Allowed)
+			}
+			tests.EvalObjects(t, "decision", tc.want, got)
+		})
+	}
+}
@@ -17,9 +17,11 @@ package acl
 import (
 	"regexp"
 	"strings"
+	"sync"
 )
 
 var pathACLPatterns map[string]*regexp.Regexp
+var pathACLPatternsMu sync.RWMutex
 
 func init() {
 	pathACLPatterns = make(map[string]*regexp.Regexp)
@@ -43,18 +45,23 @@ func MatchPathBasedACL(pattern, uri string) bool {
 	var found bool
 
 	// Check cached entries
+	pathACLPatternsMu.RLock()
 	regex, found = pathACLPatterns[pattern]
+	pathACLPatternsMu.RUnlock()
 	if !found {
 		// advPattern = strings.ReplaceAll(pattern, "/", "\\/")
 		advPattern := strings.ReplaceAll(pattern, "**", "[a-zA-Z0-9_/.~-]+")
 		advPattern = strings.ReplaceAll(advPattern, "*", "[a-zA-Z0-9_.~-]+")
 		advPattern = "^" + advPattern + "$"
 		r, err := regexp.Compile(advPattern)
+		pathACLPatternsMu.Lock()
 		if err != nil {
 			pathACLPatterns[pattern] = nil
+			pathACLPatternsMu.Unlock()
 			return false
 		}
 		pathACLPatterns[pattern] = r
+		pathACLPatternsMu.Unlock()
 		regex = r
 	}
 	if regex == nil {
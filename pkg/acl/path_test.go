@@ -128,3 +128,12 @@ func TestMatchPathBasedACL(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkMatchPathBasedACL(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			MatchPathBasedACL("/api/**/users/*", "/api/v1/tenants/users/jsmith")
+		}
+	})
+}
@@ -4301,12 +4301,21 @@ func newACLRule(ctx context.Context, ruleID int, cfg *RuleConfiguration, logger
 			}
 			tag = tokens[i+1]
 			skipNext = true
-		case "and", "with":
+		case "and", "with", "anonymous":
 		default:
 			return nil, errors.ErrACLRuleSyntaxInvalidToken.WithArgs(token)
 		}
 	}
 
+	if action == "deny" {
+		// The deny rules with extension conditions fail closed.
+		for _, cond := range conditions {
+			if c, ok := cond.(*ruleCondExtensionMatchAnyInput); ok {
+				c.failClosed = true
+			}
+		}
+	}
+
 	ruleTypeName := "aclRule"
 
 	if fieldCondFound {
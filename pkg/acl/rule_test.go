@@ -12856,3 +12856,46 @@ func TestEvalAclRule(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalAclRuleExtensionCondition(t *testing.T) {
+	// The calls to the closed extension fail.
+	loadTestExtension(t, "closed").Close()
+
+	var testcases = []struct {
+		name   string
+		config *RuleConfiguration
+		want   map[string]interface{}
+	}{
+		{name: "deny rule with failed extension evaluation",
+			config: &RuleConfiguration{
+				Conditions: []string{"extension closed match roles"},
+				Action:     `deny`,
+			},
+			want: map[string]interface{}{
+				"verdict": getRuleVerdictName(ruleVerdictDeny),
+			},
+		}, {name: "allow rule with failed extension evaluation",
+			config: &RuleConfiguration{
+				Conditions: []string{"extension closed match roles"},
+				Action:     `allow`,
+			},
+			want: map[string]interface{}{
+				"verdict": getRuleVerdictName(ruleVerdictContinue),
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			rule, err := newACLRule(ctx, 0, tc.config, logutil.NewLogger())
+			if tests.EvalErr(t, err, tc.config, false, nil) {
+				return
+			}
+			got := make(map[string]interface{})
+			got["verdict"] = getRuleVerdictName(rule.eval(ctx, map[string]interface{}{
+				"roles": []string{"admin"},
+			}))
+			tests.EvalObjects(t, "match result", tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+	"time"
+)
+
+// grantAccessRole adds the role of an approved access request to the user
+// in the local identity store of the realm of the request. The sessions of
+// the user are ended, so that the tokens issued after the next sign-in carry
+// the role.
+func (p *Portal) grantAccessRole(req *accessrequest.Request) error {
+	return p.updateAccessRole(req, true)
+}
+
+// revokeAccessRole removes the role of an expired or revoked access request
+// from the user, and ends the sessions of the user carrying the role.
+func (p *Portal) revokeAccessRole(req *accessrequest.Request) error {
+	return p.updateAccessRole(req, false)
+}
+
+func (p *Portal) updateAccessRole(req *accessrequest.Request, grant bool) error {
+	store := p.getIdentityStoreByRealm(req.Realm)
+	if store == nil || store.GetKind() != "local" {
+		return fmt.Errorf("local identity store for realm %q not found", req.Realm)
+	}
+	rr := &requests.Request{Context: context.Background()}
+	rr.User.Username = req.Username
+	rr.User.Email = req.Email
+	if err := store.Request(operator.GetUser, rr); err != nil {
+		return err
+	}
+	u, ok := rr.Response.Payload.(*identity.User)
+	if !ok {
+		return fmt.Errorf("user %q not found", req.Username)
+	}
+
+	var found bool
+	var roles []string
+	for _, role := range u.GetRolesClaim() {
+		if role == req.Role {
+			found = true
+			continue
+		}
+		roles = append(roles, role)
+	}
+	switch {
+	case grant && found:
+		// The expiry of the grant would withdraw the role held before.
+		return fmt.Errorf("user %q already has role %q", req.Username, req.Role)
+	case grant && req.Lifetime > 0:
+		// The identity store withdraws the time-boxed role on its own,
+		// should the expiry of the access request be delayed.
+		rr.RoleGrant.Role = req.Role
+		rr.RoleGrant.GrantedBy = req.ReviewedBy
+		rr.RoleGrant.ExpiresAt = time.Now().Add(time.Duration(req.Lifetime) * time.Second)
+		if err := store.Request(operator.GrantRole, rr); err != nil {
+			return err
+		}
+		p.endUserSessions(req.Email)
+		return nil
+	case grant:
+		roles = append(roles, req.Role)
+	case !found:
+		return nil
+	}
+
+	rr.User.Roles = roles
+	if err := store.Request(operator.UpdateRoles, rr); err != nil {
+		return err
+	}
+	p.endUserSessions(req.Email)
+	return nil
+}
+
+// expireAccessGrants withdraws the roles granted by the access requests
+// whose grants expired.
+func (p *Portal) expireAccessGrants() {
+	expired, err := p.accessRequests.Expire(p.revokeAccessRole)
+	if err != nil {
+		p.logger.Warn(
+			"Failed expiring access grants",
+			zap.String("portal_name", p.config.Name),
+			zap.Error(err),
+		)
+	}
+	for _, req := range expired {
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "access_grant_expired"),
+			zap.String("access_request_id", req.ID),
+			zap.String("app", req.App),
+			zap.String("role", req.Role),
+			zap.String("realm", req.Realm),
+			zap.String("username", req.Username),
+		)
+		p.notifyAccessRequestVerdict(req)
+	}
+}
+
+// notifyAccessRequest informs the administrators of the app about a new
+// access request.
+func (p *Portal) notifyAccessRequest(rr *requests.Request, req *accessrequest.Request, srcAddr string) {
+	data := map[string]string{
+		"session_id":        rr.Upstream.SessionID,
+		"request_id":        rr.ID,
+		"access_request_id": req.ID,
+		"app":               req.App,
+		"role":              req.Role,
+		"username":          req.Username,
+		"email":             req.Email,
+		"justification":     req.Justification,
+		"src_ip":            srcAddr,
+		"timestamp":         time.Now().UTC().Format(time.UnixDate),
+	}
+	if req.Lifetime > 0 {
+		data["lifetime"] = (time.Duration(req.Lifetime) * time.Second).String()
+	}
+	p.addBrandingData(req.Realm, data)
+	p.addLocationData(srcAddr, data)
+	if err := p.accessRequests.NotifyAdmins(data); err != nil {
+		p.logger.Warn(
+			"Failed sending access request notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("access_request_id", req.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// notifyAccessRequestVerdict informs the user about the status of the
+// access request, i.e. approved, declined, revoked, or expired.
+func (p *Portal) notifyAccessRequestVerdict(req *accessrequest.Request) {
+	if req.Email == "" {
+		return
+	}
+	data := map[string]string{
+		"access_request_id": req.ID,
+		"app":               req.App,
+		"role":              req.Role,
+		"username":          req.Username,
+		"email":             req.Email,
+		"verdict":           req.Status,
+		"comment":           req.Comment,
+		"timestamp":         time.Now().UTC().Format(time.UnixDate),
+	}
+	if !req.ExpiresAt.IsZero() {
+		data["expires_at"] = req.ExpiresAt.Format(time.UnixDate)
+	}
+	p.addBrandingData(req.Realm, data)
+	if err := p.accessRequests.NotifyVerdict(data); err != nil {
+		p.logger.Warn(
+			"Failed sending access request verdict notification",
+			zap.String("portal_name", p.config.Name),
+			zap.String("access_request_id", req.ID),
+			zap.Error(err),
+		)
+	}
+}
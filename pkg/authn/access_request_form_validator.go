@@ -0,0 +1,65 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type accessRequestForm struct {
+	app           string
+	role          string
+	justification string
+	lifetime      int
+}
+
+func validateAccessRequestForm(r *http.Request) (*accessRequestForm, error) {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return nil, fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("Failed parsing submitted form")
+	}
+	form := &accessRequestForm{
+		app:           strings.TrimSpace(r.PostFormValue("app")),
+		role:          strings.TrimSpace(r.PostFormValue("role")),
+		justification: strings.TrimSpace(r.PostFormValue("justification")),
+	}
+	if form.app == "" || form.role == "" || form.justification == "" {
+		return nil, fmt.Errorf("Required form field not found")
+	}
+	// The lifetime is submitted in hours.
+	if s := strings.TrimSpace(r.PostFormValue("lifetime")); s != "" {
+		hours, err := strconv.Atoi(s)
+		if err != nil || hours < 0 {
+			return nil, fmt.Errorf("Invalid access lifetime")
+		}
+		form.lifetime = hours * 3600
+	}
+	return form, nil
+}
+
+func validateAccessReviewForm(r *http.Request) (string, error) {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return "", fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", fmt.Errorf("Failed parsing submitted form")
+	}
+	return strings.TrimSpace(r.PostFormValue("comment")), nil
+}
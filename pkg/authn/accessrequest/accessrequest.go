@@ -0,0 +1,485 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accessrequest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const defaultExpiryInterval = 60
+
+// The statuses of access requests.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDeclined = "declined"
+	StatusExpired  = "expired"
+	StatusRevoked  = "revoked"
+)
+
+// Config holds the configuration of the access request workflow, i.e. the
+// users denied access to an app request a role, and the administrators of
+// the app approve or decline the request.
+type Config struct {
+	// The email provider used to deliver the notifications.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The path to the file where the access requests are persisted. When
+	// empty, the requests are kept in memory only.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// The apps the users request access to.
+	Apps []*App `json:"apps,omitempty" xml:"apps,omitempty" yaml:"apps,omitempty"`
+	// The number of seconds between the checks for the expired grants.
+	ExpiryInterval int `json:"expiry_interval,omitempty" xml:"expiry_interval,omitempty" yaml:"expiry_interval,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// App is a protected app the users request access to.
+type App struct {
+	Name        string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Description string `json:"description,omitempty" xml:"description,omitempty" yaml:"description,omitempty"`
+	// The roles granting access to the app.
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	// The email addresses of the administrators owning the app. They are
+	// notified about the new requests.
+	AdminEmails []string `json:"admin_emails,omitempty" xml:"admin_emails,omitempty" yaml:"admin_emails,omitempty"`
+	// The maximum number of seconds a role is granted for. When zero, the
+	// roles are granted until revoked.
+	MaxLifetime int `json:"max_lifetime,omitempty" xml:"max_lifetime,omitempty" yaml:"max_lifetime,omitempty"`
+}
+
+// Request is a request for a role granting access to an app.
+type Request struct {
+	ID            string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	App           string `json:"app,omitempty" xml:"app,omitempty" yaml:"app,omitempty"`
+	Role          string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	Realm         string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username      string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email         string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Justification string `json:"justification,omitempty" xml:"justification,omitempty" yaml:"justification,omitempty"`
+	// The number of seconds the role is granted for. When zero, the role is
+	// granted until revoked.
+	Lifetime   int       `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+	Status     string    `json:"status,omitempty" xml:"status,omitempty" yaml:"status,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	ReviewedBy string    `json:"reviewed_by,omitempty" xml:"reviewed_by,omitempty" yaml:"reviewed_by,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty" xml:"reviewed_at,omitempty" yaml:"reviewed_at,omitempty"`
+	Comment    string    `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	// The time the granted role expires at. Zero for the permanent grants.
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// Manager tracks access requests and the roles granted by them, and
+// periodically triggers the expiry of the time-boxed grants.
+type Manager struct {
+	mu       sync.Mutex
+	config   *Config
+	apps     map[string]*App
+	interval time.Duration
+	requests map[string]*Request
+	exit     chan bool
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrAccessRequestConfigEmailProvider
+	}
+	if len(cfg.Apps) == 0 {
+		return errors.ErrAccessRequestConfigAppsNotFound
+	}
+	names := make(map[string]bool)
+	for _, app := range cfg.Apps {
+		if app.Name == "" {
+			return errors.ErrAccessRequestConfigAppName
+		}
+		if names[app.Name] {
+			return errors.ErrAccessRequestConfigAppDuplicate.WithArgs(app.Name)
+		}
+		names[app.Name] = true
+		if len(app.Roles) == 0 {
+			return errors.ErrAccessRequestConfigAppRoles.WithArgs(app.Name)
+		}
+		if len(app.AdminEmails) == 0 {
+			return errors.ErrAccessRequestConfigAppAdmins.WithArgs(app.Name)
+		}
+		if app.MaxLifetime < 0 {
+			return errors.ErrAccessRequestConfigMaxLifetime.WithArgs(app.Name, app.MaxLifetime)
+		}
+	}
+	if cfg.ExpiryInterval < 0 {
+		return errors.ErrAccessRequestConfigExpiryInterval.WithArgs(cfg.ExpiryInterval)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of access request notifications.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager. The requests are loaded from
+// the configured file, if any.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config:   cfg,
+		apps:     make(map[string]*App),
+		interval: time.Duration(defaultExpiryInterval) * time.Second,
+		requests: make(map[string]*Request),
+	}
+	for _, app := range cfg.Apps {
+		m.apps[app.Name] = app
+	}
+	if cfg.ExpiryInterval > 0 {
+		m.interval = time.Duration(cfg.ExpiryInterval) * time.Second
+	}
+	if cfg.Path == "" {
+		return m, nil
+	}
+	b, err := ioutil.ReadFile(cfg.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, errors.ErrAccessRequestStoreLoad.WithArgs(cfg.Path, err)
+	}
+	var entries []*Request
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, errors.ErrAccessRequestStoreLoad.WithArgs(cfg.Path, err)
+	}
+	for _, entry := range entries {
+		m.requests[entry.ID] = entry
+	}
+	return m, nil
+}
+
+// GetApps returns the apps the users request access to.
+func (m *Manager) GetApps() []*App {
+	return m.config.Apps
+}
+
+// GetApp returns the app with the name.
+func (m *Manager) GetApp(name string) (*App, error) {
+	app, exists := m.apps[name]
+	if !exists {
+		return nil, errors.ErrAccessRequestAppNotFound.WithArgs(name)
+	}
+	return app, nil
+}
+
+// Submit records a request of a user for a role of an app. The lifetime
+// is the number of seconds the role is requested for. When the app limits
+// the lifetime of the grants, zero lifetime is the maximum one.
+func (m *Manager) Submit(realm, username, email, appName, role, justification string, lifetime int) (*Request, error) {
+	app, err := m.GetApp(appName)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(app.Roles, role) {
+		return nil, errors.ErrAccessRequestRoleNotPermitted.WithArgs(role, app.Name)
+	}
+	lifetime, err = getLifetime(app, lifetime)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.ErrAccessRequestIDGenerate.WithArgs(err)
+	}
+	req := &Request{
+		ID:            hex.EncodeToString(b),
+		App:           app.Name,
+		Role:          role,
+		Realm:         realm,
+		Username:      username,
+		Email:         email,
+		Justification: strings.TrimSpace(justification),
+		Lifetime:      lifetime,
+		Status:        StatusPending,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entry := range m.requests {
+		if entry.Realm != realm || !strings.EqualFold(entry.Username, username) || entry.Role != role {
+			continue
+		}
+		if entry.Status == StatusPending || entry.Status == StatusApproved {
+			return nil, errors.ErrAccessRequestDuplicate.WithArgs(role)
+		}
+	}
+	m.requests[req.ID] = req
+	if err := m.save(); err != nil {
+		delete(m.requests, req.ID)
+		return nil, err
+	}
+	entry := *req
+	return &entry, nil
+}
+
+// List returns the access requests sorted by creation time.
+func (m *Manager) List() []*Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []*Request
+	for _, req := range m.requests {
+		entry := *req
+		entries = append(entries, &entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
+}
+
+// ListUser returns the access requests of a user sorted by creation time.
+func (m *Manager) ListUser(realm, username string) []*Request {
+	var entries []*Request
+	for _, entry := range m.List() {
+		if entry.Realm == realm && strings.EqualFold(entry.Username, username) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Get returns the access request with the id.
+func (m *Manager) Get(id string) (*Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, exists := m.requests[id]
+	if !exists {
+		return nil, errors.ErrAccessRequestNotFound
+	}
+	entry := *req
+	return &entry, nil
+}
+
+// Approve grants the role of a pending access request with the grant
+// function. The non-zero lifetime overrides the requested one.
+func (m *Manager) Approve(id, reviewer, comment string, lifetime int, grant func(*Request) error) (*Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, err := m.getPending(id)
+	if err != nil {
+		return nil, err
+	}
+	if lifetime == 0 {
+		lifetime = req.Lifetime
+	}
+	lifetime, err = getLifetime(m.apps[req.App], lifetime)
+	if err != nil {
+		return nil, err
+	}
+	entry := *req
+	entry.Lifetime = lifetime
+	entry.ReviewedBy = reviewer
+	if err := grant(&entry); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	entry.Status = StatusApproved
+	entry.ReviewedAt = now
+	entry.Comment = strings.TrimSpace(comment)
+	if lifetime > 0 {
+		entry.ExpiresAt = now.Add(time.Duration(lifetime) * time.Second)
+	}
+	return m.update(req, &entry)
+}
+
+// Decline declines a pending access request.
+func (m *Manager) Decline(id, reviewer, comment string) (*Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, err := m.getPending(id)
+	if err != nil {
+		return nil, err
+	}
+	entry := *req
+	entry.Status = StatusDeclined
+	entry.ReviewedBy = reviewer
+	entry.ReviewedAt = time.Now().UTC()
+	entry.Comment = strings.TrimSpace(comment)
+	return m.update(req, &entry)
+}
+
+// Revoke withdraws the role granted by an approved access request with the
+// revoke function.
+func (m *Manager) Revoke(id, reviewer string, revoke func(*Request) error) (*Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, exists := m.requests[id]
+	if !exists {
+		return nil, errors.ErrAccessRequestNotFound
+	}
+	if req.Status != StatusApproved {
+		return nil, errors.ErrAccessRequestStatus.WithArgs(req.Status)
+	}
+	entry := *req
+	if err := revoke(&entry); err != nil {
+		return nil, err
+	}
+	entry.Status = StatusRevoked
+	entry.ReviewedBy = reviewer
+	entry.ReviewedAt = time.Now().UTC()
+	return m.update(req, &entry)
+}
+
+// Expire withdraws the roles of the approved access requests whose grants
+// expired with the revoke function, and returns the expired requests. The
+// grants failing revocation are retried on the next call.
+func (m *Manager) Expire(revoke func(*Request) error) ([]*Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var expired []*Request
+	for _, req := range m.requests {
+		if req.Status != StatusApproved || req.ExpiresAt.IsZero() || now.Before(req.ExpiresAt) {
+			continue
+		}
+		if err := revoke(req); err != nil {
+			continue
+		}
+		req.Status = StatusExpired
+		entry := *req
+		expired = append(expired, &entry)
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	return expired, m.save()
+}
+
+// Run calls the expiry function at the configured expiry interval until
+// Stop is called.
+func (m *Manager) Run(expire func()) {
+	m.mu.Lock()
+	if m.exit != nil {
+		m.mu.Unlock()
+		return
+	}
+	exit := make(chan bool)
+	m.exit = exit
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				expire()
+			}
+		}
+	}()
+}
+
+// Stop stops the expiry started with Run.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.exit == nil {
+		return
+	}
+	close(m.exit)
+	m.exit = nil
+}
+
+func (m *Manager) getPending(id string) (*Request, error) {
+	req, exists := m.requests[id]
+	if !exists {
+		return nil, errors.ErrAccessRequestNotFound
+	}
+	if req.Status != StatusPending {
+		return nil, errors.ErrAccessRequestStatus.WithArgs(req.Status)
+	}
+	return req, nil
+}
+
+// update replaces an access request with the entry. The request is
+// restored when the entry fails to persist.
+func (m *Manager) update(req, entry *Request) (*Request, error) {
+	m.requests[req.ID] = entry
+	if err := m.save(); err != nil {
+		m.requests[req.ID] = req
+		return nil, err
+	}
+	resp := *entry
+	return &resp, nil
+}
+
+func (m *Manager) save() error {
+	if m.config.Path == "" {
+		return nil
+	}
+	var entries []*Request
+	for _, req := range m.requests {
+		entries = append(entries, req)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.ErrAccessRequestStoreSave.WithArgs(m.config.Path, err)
+	}
+	if err := ioutil.WriteFile(m.config.Path, b, 0600); err != nil {
+		return errors.ErrAccessRequestStoreSave.WithArgs(m.config.Path, err)
+	}
+	return nil
+}
+
+func getLifetime(app *App, lifetime int) (int, error) {
+	if lifetime < 0 {
+		return 0, errors.ErrAccessRequestLifetime.WithArgs(lifetime)
+	}
+	if app.MaxLifetime == 0 {
+		return lifetime, nil
+	}
+	if lifetime == 0 {
+		return app.MaxLifetime, nil
+	}
+	if lifetime > app.MaxLifetime {
+		return 0, errors.ErrAccessRequestLifetimeExceeded.WithArgs(lifetime, app.MaxLifetime, app.Name)
+	}
+	return lifetime, nil
+}
+
+func contains(entries []string, s string) bool {
+	for _, entry := range entries {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
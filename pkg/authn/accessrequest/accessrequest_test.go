@@ -0,0 +1,338 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accessrequest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func newTestConfig() *Config {
+	return &Config{
+		EmailProvider: "default",
+		Apps: []*App{
+			{
+				Name:        "grafana",
+				Roles:       []string{"grafana/viewer", "grafana/editor"},
+				AdminEmails: []string{"admin@localhost"},
+				MaxLifetime: 3600,
+			},
+			{
+				Name:        "wiki",
+				Roles:       []string{"wiki/reader"},
+				AdminEmails: []string{"admin@localhost"},
+			},
+		},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: newTestConfig(),
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigEmailProvider,
+		},
+		{
+			name:      "config without apps",
+			config:    &Config{EmailProvider: "default"},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigAppsNotFound,
+		},
+		{
+			name: "config with duplicate app",
+			config: &Config{EmailProvider: "default", Apps: []*App{
+				{Name: "wiki", Roles: []string{"wiki/reader"}, AdminEmails: []string{"admin@localhost"}},
+				{Name: "wiki", Roles: []string{"wiki/reader"}, AdminEmails: []string{"admin@localhost"}},
+			}},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigAppDuplicate.WithArgs("wiki"),
+		},
+		{
+			name: "config with app without roles",
+			config: &Config{EmailProvider: "default", Apps: []*App{
+				{Name: "wiki", AdminEmails: []string{"admin@localhost"}},
+			}},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigAppRoles.WithArgs("wiki"),
+		},
+		{
+			name: "config with app without admins",
+			config: &Config{EmailProvider: "default", Apps: []*App{
+				{Name: "wiki", Roles: []string{"wiki/reader"}},
+			}},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigAppAdmins.WithArgs("wiki"),
+		},
+		{
+			name: "config with negative max lifetime",
+			config: &Config{EmailProvider: "default", Apps: []*App{
+				{Name: "wiki", Roles: []string{"wiki/reader"}, AdminEmails: []string{"admin@localhost"}, MaxLifetime: -1},
+			}},
+			shouldErr: true,
+			err:       errors.ErrAccessRequestConfigMaxLifetime.WithArgs("wiki", -1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	testcases := []struct {
+		name      string
+		app       string
+		role      string
+		lifetime  int
+		want      int
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "request time-boxed role with maximum lifetime",
+			app:  "grafana",
+			role: "grafana/viewer",
+			want: 3600,
+		},
+		{
+			name:     "request time-boxed role with shorter lifetime",
+			app:      "grafana",
+			role:     "grafana/editor",
+			lifetime: 600,
+			want:     600,
+		},
+		{
+			name: "request permanent role",
+			app:  "wiki",
+			role: "wiki/reader",
+		},
+		{
+			name:      "request role exceeding maximum lifetime",
+			app:       "grafana",
+			role:      "grafana/editor",
+			lifetime:  7200,
+			shouldErr: true,
+			err:       errors.ErrAccessRequestLifetimeExceeded.WithArgs(7200, 3600, "grafana"),
+		},
+		{
+			name:      "request role of another app",
+			app:       "wiki",
+			role:      "grafana/editor",
+			shouldErr: true,
+			err:       errors.ErrAccessRequestRoleNotPermitted.WithArgs("grafana/editor", "wiki"),
+		},
+		{
+			name:      "request role of unknown app",
+			app:       "jira",
+			role:      "jira/user",
+			shouldErr: true,
+			err:       errors.ErrAccessRequestAppNotFound.WithArgs("jira"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewManager(newTestConfig())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			req, err := m.Submit("local", "jsmith", "jsmith@localhost", tc.app, tc.role, "on call", tc.lifetime)
+			if tests.EvalErrWithLog(t, err, "submit", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjects(t, "lifetime", tc.want, req.Lifetime)
+			tests.EvalObjects(t, "status", StatusPending, req.Status)
+
+			// The role cannot be requested while the prior request is pending.
+			_, err = m.Submit("local", "JSmith", "jsmith@localhost", tc.app, tc.role, "", 0)
+			tests.EvalErrWithLog(t, err, "duplicate", true, errors.ErrAccessRequestDuplicate.WithArgs(tc.role), nil)
+		})
+	}
+}
+
+func TestReview(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "access_requests.json")
+	cfg := newTestConfig()
+	cfg.Path = fp
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	granted := make(map[string]bool)
+	grant := func(req *Request) error {
+		granted[req.Username+"/"+req.Role] = true
+		return nil
+	}
+	revoke := func(req *Request) error {
+		delete(granted, req.Username+"/"+req.Role)
+		return nil
+	}
+
+	viewer, err := m.Submit("local", "jsmith", "jsmith@localhost", "grafana", "grafana/viewer", "", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reader, err := m.Submit("local", "jsmith", "jsmith@localhost", "wiki", "wiki/reader", "", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	editor, err := m.Submit("local", "jsmith", "jsmith@localhost", "grafana", "grafana/editor", "", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	approved, err := m.Approve(viewer.ID, "admin@localhost", "ok", 60, grant)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "approved status", StatusApproved, approved.Status)
+	tests.EvalObjects(t, "approved lifetime", 60, approved.Lifetime)
+	if approved.ExpiresAt.IsZero() {
+		t.Fatalf("expected expiry time of time-boxed grant")
+	}
+
+	_, err = m.Approve(viewer.ID, "admin@localhost", "", 0, grant)
+	tests.EvalErrWithLog(t, err, "approve twice", true, errors.ErrAccessRequestStatus.WithArgs(StatusApproved), nil)
+
+	_, err = m.Approve(editor.ID, "admin@localhost", "", 7200, grant)
+	tests.EvalErrWithLog(t, err, "approve exceeding lifetime", true, errors.ErrAccessRequestLifetimeExceeded.WithArgs(7200, 3600, "grafana"), nil)
+
+	_, err = m.Approve(editor.ID, "admin@localhost", "", 0, func(*Request) error {
+		return fmt.Errorf("store unavailable")
+	})
+	tests.EvalErrWithLog(t, err, "approve with failed grant", true, fmt.Errorf("store unavailable"), nil)
+
+	declined, err := m.Decline(editor.ID, "admin@localhost", "not needed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "declined status", StatusDeclined, declined.Status)
+
+	if _, err := m.Approve(reader.ID, "admin@localhost", "", 0, grant); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revoked, err := m.Revoke(reader.ID, "admin@localhost", revoke)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "revoked status", StatusRevoked, revoked.Status)
+
+	// Nothing expires before the expiry time.
+	expired, err := m.Expire(revoke)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "expired before expiry", 0, len(expired))
+
+	// The requests survive the restart.
+	m, err = NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.requests[viewer.ID].ExpiresAt = time.Now().Add(-time.Second)
+	expired, err = m.Expire(revoke)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "expired count", 1, len(expired))
+	tests.EvalObjects(t, "granted roles", map[string]bool{}, granted)
+
+	got := make(map[string]string)
+	for _, req := range m.ListUser("local", "jsmith") {
+		got[req.Role] = req.Status
+	}
+	tests.EvalObjects(t, "statuses", map[string]string{
+		"grafana/viewer": StatusExpired,
+		"grafana/editor": StatusDeclined,
+		"wiki/reader":    StatusRevoked,
+	}, got)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := newTestConfig()
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := map[string]string{
+		"access_request_id": "abc",
+		"app":               "grafana",
+		"role":              "grafana/viewer",
+		"username":          "jsmith",
+		"email":             "jsmith@localhost",
+		"verdict":           StatusApproved,
+	}
+	if err := m.NotifyAdmins(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.NotifyVerdict(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 2, len(files))
+	var subjects []string
+	for _, fp := range files {
+		b, _ := ioutil.ReadFile(fp)
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "Subject: ") {
+				subjects = append(subjects, strings.TrimSpace(line))
+			}
+		}
+	}
+	for _, want := range []string{
+		"Subject: Access Request for grafana",
+		"Subject: Access Request Approved",
+	} {
+		var found bool
+		for _, s := range subjects {
+			if s == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("message with %q not found in %v", want, subjects)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accessrequest
+
+const (
+	requestTemplateName = "en/access_request"
+	verdictTemplateName = "en/access_request_verdict"
+)
+
+// NotifyAdmins informs the administrators of the app in data about a new
+// access request.
+func (m *Manager) NotifyAdmins(data map[string]string) error {
+	app, err := m.GetApp(data["app"])
+	if err != nil {
+		return err
+	}
+	return m.notify(requestTemplateName, app.AdminEmails, data)
+}
+
+// NotifyVerdict informs the email address in data about the status of
+// the access request, e.g. approved or expired.
+func (m *Manager) NotifyVerdict(data map[string]string) error {
+	return m.notify(verdictTemplateName, []string{data["email"]}, data)
+}
+
+func (m *Manager) notify(templateName string, rcpts []string, data map[string]string) error {
+	return m.config.Send(m.config.EmailProvider, templateName, rcpts, data)
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// admit reserves an execution slot for an expensive operation, e.g. password
+// verification, LDAP bind, or token exchange. When the admission control is
+// not configured, the request is admitted right away.
+func (p *Portal) admit(ctx context.Context) (func(), error) {
+	if p.admission == nil {
+		return func() {}, nil
+	}
+	return p.admission.Acquire(ctx)
+}
+
+// handleHTTPOverload responds to the requests shed by the admission control
+// with 503 Service Unavailable page and Retry-After header.
+func (p *Portal) handleHTTPOverload(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, err error) error {
+	p.logOverload(r, rr, err)
+	w.Header().Set("Retry-After", strconv.Itoa(p.admission.GetRetryAfter()))
+	return p.handleHTTPError(ctx, w, r, rr, http.StatusServiceUnavailable)
+}
+
+// handleJSONOverload responds to the API requests shed by the admission
+// control with 503 Service Unavailable and Retry-After header.
+func (p *Portal) handleJSONOverload(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, err error) error {
+	p.logOverload(r, rr, err)
+	w.Header().Set("Retry-After", strconv.Itoa(p.admission.GetRetryAfter()))
+	return p.handleJSONError(ctx, w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+}
+
+func (p *Portal) logOverload(r *http.Request, rr *requests.Request, err error) {
+	rr.Response.Code = http.StatusServiceUnavailable
+	p.logger.Warn(
+		"request shed by admission control",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", addrutil.GetSourceAddress(r)),
+		zap.Any("admission_stats", p.admission.GetStats()),
+		zap.Error(err),
+	)
+}
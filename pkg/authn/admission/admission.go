@@ -0,0 +1,167 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultQueueTimeout = 5
+	defaultRetryAfter   = 5
+)
+
+// Config holds the configuration of admission Controller.
+type Config struct {
+	// The maximum number of expensive operations, e.g. password hash
+	// verification, LDAP binds, or token exchanges, running concurrently.
+	MaxConcurrent int `json:"max_concurrent,omitempty" xml:"max_concurrent,omitempty" yaml:"max_concurrent,omitempty"`
+	// The maximum number of operations waiting for an available slot. When
+	// the queue is full, the requests are shed immediately.
+	MaxQueued int `json:"max_queued,omitempty" xml:"max_queued,omitempty" yaml:"max_queued,omitempty"`
+	// The maximum number of seconds an operation waits in the queue.
+	QueueTimeout int `json:"queue_timeout,omitempty" xml:"queue_timeout,omitempty" yaml:"queue_timeout,omitempty"`
+	// The number of seconds returned in Retry-After header of shed requests.
+	RetryAfter int `json:"retry_after,omitempty" xml:"retry_after,omitempty" yaml:"retry_after,omitempty"`
+}
+
+// Stats holds the counters of admission Controller.
+type Stats struct {
+	Active   int64 `json:"active,omitempty" xml:"active,omitempty" yaml:"active,omitempty"`
+	Queued   int64 `json:"queued,omitempty" xml:"queued,omitempty" yaml:"queued,omitempty"`
+	Admitted int64 `json:"admitted,omitempty" xml:"admitted,omitempty" yaml:"admitted,omitempty"`
+	Shed     int64 `json:"shed,omitempty" xml:"shed,omitempty" yaml:"shed,omitempty"`
+}
+
+// Controller bounds the concurrency of expensive operations and sheds the
+// load exceeding the configured queue capacity.
+type Controller struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+	retryAfter   int
+	active       int64
+	queued       int64
+	admitted     int64
+	shed         int64
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MaxConcurrent < 1 {
+		return errors.ErrAdmissionConfigMaxConcurrent.WithArgs(cfg.MaxConcurrent)
+	}
+	if cfg.MaxQueued < 0 {
+		return errors.ErrAdmissionConfigMaxQueued.WithArgs(cfg.MaxQueued)
+	}
+	if cfg.QueueTimeout < 0 {
+		return errors.ErrAdmissionConfigQueueTimeout.WithArgs(cfg.QueueTimeout)
+	}
+	if cfg.RetryAfter < 0 {
+		return errors.ErrAdmissionConfigRetryAfter.WithArgs(cfg.RetryAfter)
+	}
+	return nil
+}
+
+// NewController returns an instance of Controller.
+func NewController(cfg *Config) (*Controller, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Controller{
+		slots:        make(chan struct{}, cfg.MaxConcurrent),
+		queue:        make(chan struct{}, cfg.MaxQueued),
+		queueTimeout: time.Duration(defaultQueueTimeout) * time.Second,
+		retryAfter:   defaultRetryAfter,
+	}
+	if cfg.QueueTimeout > 0 {
+		c.queueTimeout = time.Duration(cfg.QueueTimeout) * time.Second
+	}
+	if cfg.RetryAfter > 0 {
+		c.retryAfter = cfg.RetryAfter
+	}
+	return c, nil
+}
+
+// Acquire reserves a slot for an expensive operation. If no slot is
+// available, the caller waits in the queue until a slot frees up, the queue
+// timeout elapses, or the context is canceled. The returned function must be
+// called to release the slot.
+func (c *Controller) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case c.slots <- struct{}{}:
+		return c.admit(), nil
+	default:
+	}
+
+	select {
+	case c.queue <- struct{}{}:
+	default:
+		atomic.AddInt64(&c.shed, 1)
+		return nil, errors.ErrAdmissionQueueFull
+	}
+	atomic.AddInt64(&c.queued, 1)
+	defer func() {
+		<-c.queue
+		atomic.AddInt64(&c.queued, -1)
+	}()
+
+	timer := time.NewTimer(c.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.admit(), nil
+	case <-timer.C:
+		atomic.AddInt64(&c.shed, 1)
+		return nil, errors.ErrAdmissionQueueTimeout
+	case <-ctx.Done():
+		atomic.AddInt64(&c.shed, 1)
+		return nil, errors.ErrAdmissionCanceled
+	}
+}
+
+func (c *Controller) admit() func() {
+	atomic.AddInt64(&c.active, 1)
+	atomic.AddInt64(&c.admitted, 1)
+	var released int32
+	return func() {
+		if !atomic.CompareAndSwapInt32(&released, 0, 1) {
+			return
+		}
+		atomic.AddInt64(&c.active, -1)
+		<-c.slots
+	}
+}
+
+// GetRetryAfter returns the number of seconds a shed client should wait
+// before retrying.
+func (c *Controller) GetRetryAfter() int {
+	return c.retryAfter
+}
+
+// GetStats returns a snapshot of Controller counters.
+func (c *Controller) GetStats() *Stats {
+	return &Stats{
+		Active:   atomic.LoadInt64(&c.active),
+		Queued:   atomic.LoadInt64(&c.queued),
+		Admitted: atomic.LoadInt64(&c.admitted),
+		Shed:     atomic.LoadInt64(&c.shed),
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewController(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config with defaults",
+			config: &Config{MaxConcurrent: 2},
+			want: map[string]interface{}{
+				"retry_after": 5,
+			},
+		},
+		{
+			name:   "valid config with custom retry after",
+			config: &Config{MaxConcurrent: 2, MaxQueued: 10, QueueTimeout: 1, RetryAfter: 30},
+			want: map[string]interface{}{
+				"retry_after": 30,
+			},
+		},
+		{
+			name:      "invalid max concurrent",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrAdmissionConfigMaxConcurrent.WithArgs(0),
+		},
+		{
+			name:      "invalid max queued",
+			config:    &Config{MaxConcurrent: 1, MaxQueued: -1},
+			shouldErr: true,
+			err:       errors.ErrAdmissionConfigMaxQueued.WithArgs(-1),
+		},
+		{
+			name:      "invalid queue timeout",
+			config:    &Config{MaxConcurrent: 1, QueueTimeout: -1},
+			shouldErr: true,
+			err:       errors.ErrAdmissionConfigQueueTimeout.WithArgs(-1),
+		},
+		{
+			name:      "invalid retry after",
+			config:    &Config{MaxConcurrent: 1, RetryAfter: -1},
+			shouldErr: true,
+			err:       errors.ErrAdmissionConfigRetryAfter.WithArgs(-1),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			c, err := NewController(tc.config)
+			if tests.EvalErrWithLog(t, err, "admission controller", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := make(map[string]interface{})
+			got["retry_after"] = c.GetRetryAfter()
+			tests.EvalObjectsWithLog(t, "admission controller", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestControllerAcquire(t *testing.T) {
+	c, err := NewController(&Config{MaxConcurrent: 1, MaxQueued: 1, QueueTimeout: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	release, err := c.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected first request to be admitted, got error: %v", err)
+	}
+
+	// The second request waits in the queue, the third one gets shed.
+	queued := make(chan error)
+	go func() {
+		r, err := c.Acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		queued <- err
+	}()
+	for c.GetStats().Queued < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := c.Acquire(context.Background()); err != errors.ErrAdmissionQueueFull {
+		t.Fatalf("expected %v, got: %v", errors.ErrAdmissionQueueFull, err)
+	}
+
+	release()
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("expected queued request to be admitted, got error: %v", err)
+	}
+
+	// The request times out in the queue.
+	release, _ = c.Acquire(context.Background())
+	if _, err := c.Acquire(context.Background()); err != errors.ErrAdmissionQueueTimeout {
+		t.Fatalf("expected %v, got: %v", errors.ErrAdmissionQueueTimeout, err)
+	}
+
+	// The request is canceled while waiting in the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Acquire(ctx); err != errors.ErrAdmissionCanceled {
+		t.Fatalf("expected %v, got: %v", errors.ErrAdmissionCanceled, err)
+	}
+	release()
+
+	tests.EvalObjects(t, "stats", &Stats{Admitted: 3, Shed: 3}, c.GetStats())
+}
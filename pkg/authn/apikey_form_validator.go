@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func validateAPIKeyInputForm(r *http.Request, rr *requests.Request) error {
@@ -33,5 +35,14 @@ func validateAPIKeyInputForm(r *http.Request, rr *requests.Request) error {
 	if comment != "" {
 		rr.Key.Comment = comment
 	}
+	rr.Key.Scopes = strings.Fields(r.PostFormValue("scopes"))
+	rr.Key.Roles = strings.Fields(r.PostFormValue("roles"))
+	if v := strings.TrimSpace(r.PostFormValue("expires_in_days")); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			return fmt.Errorf("Invalid expiration")
+		}
+		rr.Key.ExpiresAt = time.Now().Add(time.Duration(days) * 24 * time.Hour).UTC()
+	}
 	return nil
 }
@@ -65,6 +65,17 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 		return errors.ErrBasicAuthFailed
 	}
 
+	if entry := p.getLoginMaintenance(r.Realm); entry != nil {
+		p.logger.Warn(
+			"realm login disabled",
+			zap.String("source_address", r.Address),
+			zap.String("custom_auth", "basicauth"),
+			zap.String("realm", r.Realm),
+			zap.String("maintenance_scope", entry.Scope),
+		)
+		return errors.ErrLoginDisabled.WithArgs(r.Realm)
+	}
+
 	/*
 		if err := backend.Request(operator.LookupBasic, rr); err != nil {
 			p.logger.Warn(
@@ -89,6 +100,17 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 		return errors.ErrBasicAuthFailed
 	}
 
+	if p.isBreakGlassAccount(backend.GetRealm(), rr.User.Username) {
+		p.logger.Warn(
+			"user lookup failed",
+			zap.String("source_address", r.Address),
+			zap.String("custom_auth", "basicauth"),
+			zap.String("realm", r.Realm),
+			zap.Error(errors.ErrBreakGlassLoginMethod.WithArgs(rr.User.Username)),
+		)
+		return errors.ErrBasicAuthFailed
+	}
+
 	if len(rr.User.Challenges) != 1 {
 		p.logger.Warn(
 			"user lookup failed",
@@ -111,6 +133,23 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 		return errors.ErrBasicAuthFailed
 	}
 
+	if p.admission != nil {
+		release, err := p.admission.Acquire(context.Background())
+		if err != nil {
+			p.logger.Warn(
+				"request shed by admission control",
+				zap.String("source_address", r.Address),
+				zap.String("custom_auth", "basicauth"),
+				zap.String("realm", r.Realm),
+				zap.Any("admission_stats", p.admission.GetStats()),
+				zap.Error(err),
+			)
+			r.Response.RetryAfter = p.admission.GetRetryAfter()
+			return err
+		}
+		defer release()
+	}
+
 	if err := backend.Request(operator.Authenticate, rr); err != nil {
 		p.logger.Warn(
 			"user authentication failed",
@@ -131,6 +170,9 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 	if len(rr.User.Roles) > 0 {
 		m["roles"] = rr.User.Roles
 	}
+	if len(rr.User.Organizations) > 0 {
+		m["org"] = rr.User.Organizations
+	}
 
 	// m["jti"] = rr.Upstream.SessionID
 	m["exp"] = time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix()
@@ -141,6 +183,7 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 	}
 	m["iss"] = "authp"
 	m["addr"] = r.Address
+	p.mapGroupRoles(r.Realm, rr.User.Groups, m)
 
 	// Perform user claim transformation if necessary.
 	if err := p.transformUser(context.Background(), rr, m); err != nil {
@@ -149,6 +192,7 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 
 	// Inject portal specific roles
 	injectPortalRoles(m)
+	p.shapeClaims(m)
 
 	// Create a new user and sign the token.
 	usr, err := user.NewUser(m)
@@ -162,6 +206,8 @@ func (p *Portal) BasicAuth(r *authproxy.Request) error {
 		)
 		return errors.ErrBasicAuthFailed
 	}
+	usr.SetExpiresAtClaim(time.Now().Add(time.Duration(p.getTokenLifetime(usr, r.Realm)) * time.Second).UTC().Unix())
+	p.setTokenAudience(usr)
 	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
 		p.logger.Warn(
 			"user token signing failed",
@@ -0,0 +1,60 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// limitRequestBody caps the body of the request at the limit of the
+// endpoint. It returns an error and the status code of the response when
+// the body is oversized or the content type is unexpected by the endpoint.
+func (p *Portal) limitRequestBody(w http.ResponseWriter, r *http.Request) (int, error) {
+	if p.bodyLimiter == nil {
+		return 0, nil
+	}
+	if err := p.bodyLimiter.LimitBody(w, r); err != nil {
+		return http.StatusRequestEntityTooLarge, err
+	}
+	if err := p.bodyLimiter.CheckContentType(r); err != nil {
+		return http.StatusUnsupportedMediaType, err
+	}
+	return 0, nil
+}
+
+// handleRequestBodyRejected responds to the requests rejected by the
+// request body limits.
+func (p *Portal) handleRequestBodyRejected(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, code int, err error) error {
+	rr.Response.Code = code
+	p.logger.Warn(
+		"request body rejected",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", addrutil.GetSourceAddress(r)),
+		zap.String("content_type", r.Header.Get("Content-Type")),
+		zap.Int64("content_length", r.ContentLength),
+		zap.Error(err),
+	)
+	p.recordUsage("body_limit/reject")
+	if isJSONRequest(r, rr) {
+		return p.handleJSONError(ctx, w, code, http.StatusText(code))
+	}
+	return p.handleHTTPError(ctx, w, r, rr, code)
+}
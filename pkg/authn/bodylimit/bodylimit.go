@@ -0,0 +1,213 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bodylimit
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// EndpointRegistration is the class of the user registration
+	// endpoints.
+	EndpointRegistration = "registration"
+	// EndpointAPI is the class of the API endpoints.
+	EndpointAPI = "api"
+	// EndpointSAML is the class of the SAML assertion consumer service
+	// endpoints.
+	EndpointSAML = "saml"
+	// EndpointCallback is the class of the callback endpoints of the
+	// external identity providers.
+	EndpointCallback = "callback"
+	// EndpointDefault is the class of the remaining endpoints, e.g. the
+	// login and the settings forms.
+	EndpointDefault = "default"
+
+	defaultRegistrationMaxBodySize int64 = 16 << 10
+	defaultAPIMaxBodySize          int64 = 64 << 10
+	defaultSAMLMaxBodySize         int64 = 256 << 10
+	defaultCallbackMaxBodySize     int64 = 16 << 10
+	defaultMaxBodySize             int64 = 64 << 10
+
+	contentTypeForm = "application/x-www-form-urlencoded"
+	contentTypeJSON = "application/json"
+)
+
+var endpoints = []string{
+	EndpointRegistration,
+	EndpointAPI,
+	EndpointSAML,
+	EndpointCallback,
+	EndpointDefault,
+}
+
+// The content types accepted by the endpoint classes.
+var contentTypes = map[string][]string{
+	EndpointRegistration: {contentTypeForm},
+	EndpointAPI:          {contentTypeJSON},
+	EndpointSAML:         {contentTypeForm},
+	EndpointCallback:     {contentTypeForm},
+	EndpointDefault:      {contentTypeForm, contentTypeJSON},
+}
+
+// Config holds the configuration of the limits applied to the bodies of
+// the inbound requests. The sizes are in bytes. When a size is zero, the
+// default applies.
+type Config struct {
+	// The maximum body size of the registration requests. Defaults to 16KB.
+	RegistrationMaxBodySize int64 `json:"registration_max_body_size,omitempty" xml:"registration_max_body_size,omitempty" yaml:"registration_max_body_size,omitempty"`
+	// The maximum body size of the API requests. Defaults to 64KB.
+	APIMaxBodySize int64 `json:"api_max_body_size,omitempty" xml:"api_max_body_size,omitempty" yaml:"api_max_body_size,omitempty"`
+	// The maximum body size of the SAML responses. Defaults to 256KB.
+	SAMLMaxBodySize int64 `json:"saml_max_body_size,omitempty" xml:"saml_max_body_size,omitempty" yaml:"saml_max_body_size,omitempty"`
+	// The maximum body size of the identity provider callbacks. Defaults
+	// to 16KB.
+	CallbackMaxBodySize int64 `json:"callback_max_body_size,omitempty" xml:"callback_max_body_size,omitempty" yaml:"callback_max_body_size,omitempty"`
+	// The maximum body size of the remaining requests. Defaults to 64KB.
+	DefaultMaxBodySize int64 `json:"default_max_body_size,omitempty" xml:"default_max_body_size,omitempty" yaml:"default_max_body_size,omitempty"`
+	// Disables the validation of the content type of the requests.
+	ContentTypeCheckDisabled bool `json:"content_type_check_disabled,omitempty" xml:"content_type_check_disabled,omitempty" yaml:"content_type_check_disabled,omitempty"`
+}
+
+// Limiter rejects oversized requests and the requests with the content
+// type unexpected by the endpoint.
+type Limiter struct {
+	sizes            map[string]int64
+	checkContentType bool
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	sizes := cfg.getSizes()
+	for _, endpoint := range endpoints {
+		if size := sizes[endpoint]; size < 0 {
+			return errors.ErrBodyLimitConfigMaxBodySize.WithArgs(endpoint, size)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) getSizes() map[string]int64 {
+	return map[string]int64{
+		EndpointRegistration: cfg.RegistrationMaxBodySize,
+		EndpointAPI:          cfg.APIMaxBodySize,
+		EndpointSAML:         cfg.SAMLMaxBodySize,
+		EndpointCallback:     cfg.CallbackMaxBodySize,
+		EndpointDefault:      cfg.DefaultMaxBodySize,
+	}
+}
+
+// NewLimiter returns an instance of Limiter. When the config is nil, the
+// defaults apply.
+func NewLimiter(cfg *Config) (*Limiter, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Limiter{
+		sizes: map[string]int64{
+			EndpointRegistration: defaultRegistrationMaxBodySize,
+			EndpointAPI:          defaultAPIMaxBodySize,
+			EndpointSAML:         defaultSAMLMaxBodySize,
+			EndpointCallback:     defaultCallbackMaxBodySize,
+			EndpointDefault:      defaultMaxBodySize,
+		},
+		checkContentType: !cfg.ContentTypeCheckDisabled,
+	}
+	for endpoint, size := range cfg.getSizes() {
+		if size > 0 {
+			l.sizes[endpoint] = size
+		}
+	}
+	return l, nil
+}
+
+// GetEndpoint returns the class of the endpoint serving the path.
+func GetEndpoint(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/oauth2/register"):
+		// The client registration endpoint of the OpenID Connect provider.
+		return EndpointAPI
+	case strings.Contains(path, "/api/"):
+		return EndpointAPI
+	case strings.HasSuffix(path, "/register"), strings.Contains(path, "/register/"):
+		return EndpointRegistration
+	case strings.Contains(path, "/saml/"):
+		return EndpointSAML
+	case strings.HasSuffix(path, "/oauth2/authorize"), strings.HasSuffix(path, "/oauth2/token"), strings.HasSuffix(path, "/oauth2/userinfo"),
+		strings.HasSuffix(path, "/oauth2/introspect"), strings.HasSuffix(path, "/oauth2/revoke"):
+		// The endpoints of the portal acting as OAuth 2.0 provider.
+		return EndpointDefault
+	case strings.Contains(path, "/oauth2/"), strings.Contains(path, "/kerberos/"), strings.Contains(path, "/mtls/"):
+		return EndpointCallback
+	}
+	return EndpointDefault
+}
+
+// GetMaxBodySize returns the maximum body size for the endpoint class.
+func (l *Limiter) GetMaxBodySize(endpoint string) int64 {
+	return l.sizes[endpoint]
+}
+
+// LimitBody returns an error when the declared length of the request body
+// exceeds the limit. Otherwise, it caps the reading of the body at the
+// limit.
+func (l *Limiter) LimitBody(w http.ResponseWriter, r *http.Request) error {
+	endpoint := GetEndpoint(r.URL.Path)
+	size := l.sizes[endpoint]
+	if r.ContentLength > size {
+		return errors.ErrBodyLimitTooLarge.WithArgs(endpoint, size)
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, size)
+	}
+	return nil
+}
+
+// CheckContentType returns an error when the request carrying a body has
+// the content type unexpected by the endpoint. The requests without the
+// content type are not checked.
+func (l *Limiter) CheckContentType(r *http.Request) error {
+	if !l.checkContentType || r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		// The clients omitting the content type, e.g. the JSON clients
+		// setting the Accept header only, are left to the handlers.
+		return nil
+	}
+	endpoint := GetEndpoint(r.URL.Path)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errors.ErrBodyLimitContentTypeUnsupported.WithArgs(contentType, endpoint)
+	}
+	for _, s := range contentTypes[endpoint] {
+		if mediaType == s {
+			return nil
+		}
+	}
+	return errors.ErrBodyLimitContentTypeUnsupported.WithArgs(contentType, endpoint)
+}
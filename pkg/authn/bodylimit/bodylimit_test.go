@@ -0,0 +1,219 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bodylimit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewLimiter(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      map[string]int64
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "default config",
+			want: map[string]int64{
+				EndpointRegistration: 16384,
+				EndpointAPI:          65536,
+				EndpointSAML:         262144,
+				EndpointCallback:     16384,
+				EndpointDefault:      65536,
+			},
+		},
+		{
+			name: "custom api and saml sizes",
+			config: &Config{
+				APIMaxBodySize:  1024,
+				SAMLMaxBodySize: 1048576,
+			},
+			want: map[string]int64{
+				EndpointRegistration: 16384,
+				EndpointAPI:          1024,
+				EndpointSAML:         1048576,
+				EndpointCallback:     16384,
+				EndpointDefault:      65536,
+			},
+		},
+		{
+			name:      "negative registration size",
+			config:    &Config{RegistrationMaxBodySize: -1},
+			shouldErr: true,
+			err:       errors.ErrBodyLimitConfigMaxBodySize.WithArgs("registration", -1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			l, err := NewLimiter(tc.config)
+			if tests.EvalErrWithLog(t, err, "NewLimiter", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := make(map[string]int64)
+			for _, endpoint := range endpoints {
+				got[endpoint] = l.GetMaxBodySize(endpoint)
+			}
+			tests.EvalObjectsWithLog(t, "sizes", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	testcases := []struct {
+		name        string
+		config      *Config
+		method      string
+		path        string
+		contentType string
+		body        string
+		// The body size unknown in advance, i.e. chunked request.
+		chunked   bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:        "login form",
+			method:      http.MethodPost,
+			path:        "/auth/login",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=jsmith&password=secret",
+		},
+		{
+			name:        "json login",
+			method:      http.MethodPost,
+			path:        "/auth/login",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"username":"jsmith"}`,
+		},
+		{
+			name:   "json login without content type",
+			method: http.MethodPost,
+			path:   "/auth/login",
+			body:   `{"username":"jsmith"}`,
+		},
+		{
+			name:        "api request with form content type",
+			method:      http.MethodPost,
+			path:        "/auth/api/profile",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=jsmith",
+			shouldErr:   true,
+			err:         errors.ErrBodyLimitContentTypeUnsupported.WithArgs("application/x-www-form-urlencoded", "api"),
+		},
+		{
+			name:        "registration with json content type",
+			method:      http.MethodPost,
+			path:        "/auth/register/local",
+			contentType: "application/json",
+			body:        `{"username":"jsmith"}`,
+			shouldErr:   true,
+			err:         errors.ErrBodyLimitContentTypeUnsupported.WithArgs("application/json", "registration"),
+		},
+		{
+			name:        "oidc client registration with json content type",
+			method:      http.MethodPost,
+			path:        "/auth/oauth2/register",
+			contentType: "application/json",
+			body:        `{"redirect_uris":["https://app.contoso.com/callback"]}`,
+		},
+		{
+			name:        "saml response with multipart content type",
+			method:      http.MethodPost,
+			path:        "/auth/saml/azure",
+			contentType: "multipart/form-data; boundary=foo",
+			body:        "--foo--",
+			shouldErr:   true,
+			err:         errors.ErrBodyLimitContentTypeUnsupported.WithArgs("multipart/form-data; boundary=foo", "saml"),
+		},
+		{
+			name:        "malformed content type",
+			method:      http.MethodPost,
+			path:        "/auth/login",
+			contentType: "application/json; =",
+			body:        `{}`,
+			shouldErr:   true,
+			err:         errors.ErrBodyLimitContentTypeUnsupported.WithArgs("application/json; =", "default"),
+		},
+		{
+			name:        "content type check disabled",
+			config:      &Config{ContentTypeCheckDisabled: true},
+			method:      http.MethodPost,
+			path:        "/auth/api/profile",
+			contentType: "text/plain",
+			body:        "foo",
+		},
+		{
+			name:        "oversized callback",
+			config:      &Config{CallbackMaxBodySize: 8},
+			method:      http.MethodPost,
+			path:        "/auth/oauth2/google/authorization-code-callback",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "code=foobarbaz",
+			shouldErr:   true,
+			err:         errors.ErrBodyLimitTooLarge.WithArgs("callback", 8),
+		},
+		{
+			name:        "oversized chunked api request",
+			config:      &Config{APIMaxBodySize: 8},
+			method:      http.MethodPost,
+			path:        "/auth/api/profile",
+			contentType: "application/json",
+			body:        `{"name":"jsmith"}`,
+			chunked:     true,
+			shouldErr:   true,
+			err:         fmt.Errorf("http: request body too large"),
+		},
+		{
+			name:   "get request",
+			method: http.MethodGet,
+			path:   "/auth/api/profile",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			l, err := NewLimiter(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			err = l.LimitBody(httptest.NewRecorder(), r)
+			if err == nil {
+				err = l.CheckContentType(r)
+			}
+			if err == nil {
+				_, err = io.ReadAll(r.Body)
+			}
+			tests.EvalErrWithLog(t, err, "Limiter", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/ui"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// getUIArgs returns page arguments with the branding of the realm
+// associated with the request applied.
+func (p *Portal) getUIArgs(r *http.Request, rr *requests.Request, usr *user.User) *ui.Args {
+	return p.ui.GetRealmArgs(p.getRequestRealm(r, rr, usr))
+}
+
+// getRequestRealm returns the realm of the user, if any. Otherwise, it
+// returns the realm requested by the client or the default realm.
+func (p *Portal) getRequestRealm(r *http.Request, rr *requests.Request, usr *user.User) string {
+	if usr != nil && usr.Authenticator.Realm != "" {
+		return usr.Authenticator.Realm
+	}
+	if r != nil {
+		if realm := r.URL.Query().Get("realm"); realm != "" {
+			return realm
+		}
+	}
+	if rr != nil && rr.Upstream.Realm != "" {
+		return rr.Upstream.Realm
+	}
+	if v, exists := p.loginOptions["default_realm"]; exists {
+		if realm, ok := v.(string); ok {
+			return realm
+		}
+	}
+	return ""
+}
+
+// addBrandingData adds the branding of a realm to the data passed
+// to email templates.
+func (p *Portal) addBrandingData(realm string, data map[string]string) {
+	b := p.ui.GetBranding(realm)
+	if b == nil {
+		return
+	}
+	for k, v := range b.GetEmailData() {
+		data[k] = v
+	}
+}
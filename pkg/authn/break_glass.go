@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// isBreakGlassAccount returns true when the user of the realm is a
+// break-glass account.
+func (p *Portal) isBreakGlassAccount(realm, username string) bool {
+	if p.breakGlass == nil {
+		return false
+	}
+	return p.breakGlass.IsAccount(realm, username)
+}
+
+// startBreakGlassLogin starts the login of the break-glass account in the
+// local identity store of the break-glass accounts, bypassing the realm
+// discovery and the login chains. The account signs in with a hardware
+// security key only.
+func (p *Portal) startBreakGlassLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, username string) error {
+	realm := p.breakGlass.GetRealm()
+	rr.Upstream.Realm = realm
+	rr.User.Username = username
+	if retryAfter, err := p.breakGlass.Allow(addrutil.GetSourceAddress(r), username); err != nil {
+		p.logger.Warn(
+			"break glass login rate limit exceeded",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", realm),
+			zap.String("username", username),
+			zap.String("source_address", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		p.publishEvent(events.BreakGlassAttempt, r, rr, nil, map[string]interface{}{
+			"outcome": "rate_limited",
+		})
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusTooManyRequests, err.Error())
+	}
+
+	rr.User = requests.User{}
+	if err := p.identifyUserRequest(rr, map[string]string{"realm": realm, "user": username}); err != nil {
+		rr.Response.Code = http.StatusBadRequest
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
+	}
+	if !p.hasHardwareKey(realm, rr) {
+		p.publishEvent(events.BreakGlassAttempt, r, rr, nil, map[string]interface{}{
+			"outcome": "no_hardware_key",
+		})
+		rr.Response.Code = http.StatusUnauthorized
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, errors.ErrBreakGlassHardwareKey.WithArgs(username).Error())
+	}
+	rr.User.Challenges = []string{"passkey"}
+	p.publishEvent(events.BreakGlassAttempt, r, rr, nil, map[string]interface{}{
+		"outcome": "started",
+	})
+	return p.startSandboxSession(ctx, w, r, rr)
+}
+
+// hasHardwareKey returns true when the identified user has a hardware
+// security key.
+func (p *Portal) hasHardwareKey(realm string, rr *requests.Request) bool {
+	if rr.User.Username == "nobody" {
+		return false
+	}
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil {
+		return false
+	}
+	if err := store.Request(operator.GetMfaTokens, rr); err != nil {
+		return false
+	}
+	bundle, ok := rr.Response.Payload.(*identity.MfaTokenBundle)
+	if !ok {
+		return false
+	}
+	for _, token := range bundle.Get() {
+		if !token.Disabled && token.IsHardwareKey(p.breakGlass.GetAAGUIDs()) {
+			return true
+		}
+	}
+	return false
+}
+
+// observeBreakGlassLogin records the login with the break-glass account and
+// notifies the recipients about it.
+func (p *Portal) observeBreakGlassLogin(r *http.Request, rr *requests.Request, usr *user.User) {
+	if usr.Claims == nil || !p.isBreakGlassAccount(usr.Authenticator.Realm, usr.Claims.Subject) {
+		return
+	}
+	srcAddr := addrutil.GetSourceAddress(r)
+	p.logger.Warn(
+		"Audit",
+		zap.String("event", "break_glass_login"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("realm", usr.Authenticator.Realm),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("src_ip", srcAddr),
+	)
+	p.publishEvent(events.BreakGlassLogin, r, rr, usr, nil)
+	data := map[string]string{
+		"session_id": rr.Upstream.SessionID,
+		"request_id": rr.ID,
+		"realm":      usr.Authenticator.Realm,
+		"username":   usr.Claims.Subject,
+		"email":      usr.Claims.Email,
+		"src_ip":     srcAddr,
+		"timestamp":  time.Now().UTC().Format(time.UnixDate),
+	}
+	p.addBrandingData(usr.Authenticator.Realm, data)
+	p.addLocationData(srcAddr, data)
+	if err := p.breakGlass.NotifyLogin(data); err != nil {
+		p.logger.Warn(
+			"Failed sending break glass login notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+	}
+	p.recordUsage("break_glass/login")
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breakglass
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+)
+
+const (
+	defaultRealm       = "local"
+	defaultMaxAttempts = 3
+	defaultInterval    = 3600
+)
+
+// Config holds the configuration of the break-glass accounts, i.e. the
+// emergency administrator accounts of a local identity store for the time
+// the upstream identity providers are down. The accounts sign in with a
+// hardware security key only, bypassing the realm discovery and the login
+// chains. When a value is zero, the default applies.
+type Config struct {
+	// Realm is the realm of the local identity store holding the accounts.
+	// Defaults to local.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// Usernames are the usernames of the break-glass accounts.
+	Usernames []string `json:"usernames,omitempty" xml:"usernames,omitempty" yaml:"usernames,omitempty"`
+	// HardwareKeyAAGUIDs are the AAGUIDs of the hardware security key models
+	// the accounts sign in with, e.g. cb69481e-8ff7-4039-93ec-0a2729a154a8.
+	HardwareKeyAAGUIDs []string `json:"hardware_key_aaguids,omitempty" xml:"hardware_key_aaguids,omitempty" yaml:"hardware_key_aaguids,omitempty"`
+	// The maximum number of login attempts per account and source address
+	// within the interval. Defaults to 3.
+	MaxAttempts int `json:"max_attempts,omitempty" xml:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// The interval in seconds. Defaults to 1 hour.
+	Interval int `json:"interval,omitempty" xml:"interval,omitempty" yaml:"interval,omitempty"`
+	// EmailProvider is the messaging provider notifying the recipients
+	// about the logins with the accounts. The security events are published
+	// regardless.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// NotifyEmails are the email addresses of the notification recipients.
+	NotifyEmails []string `json:"notify_emails,omitempty" xml:"notify_emails,omitempty" yaml:"notify_emails,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// Manager identifies the break-glass accounts, limits their login attempts,
+// and notifies about their use.
+type Manager struct {
+	config    *Config
+	realm     string
+	usernames map[string]bool
+	aaguids   []string
+	limiter   *ratelimit.Limiter
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if len(cfg.Usernames) == 0 {
+		return errors.ErrBreakGlassConfigUsernamesNotFound
+	}
+	for _, username := range cfg.Usernames {
+		if strings.TrimSpace(username) == "" {
+			return errors.ErrBreakGlassConfigUsername
+		}
+	}
+	if len(cfg.HardwareKeyAAGUIDs) == 0 {
+		return errors.ErrBreakGlassConfigAAGUIDsNotFound
+	}
+	for _, aaguid := range cfg.HardwareKeyAAGUIDs {
+		if _, err := identity.NormalizeAAGUID(aaguid); err != nil {
+			return errors.ErrBreakGlassConfigAAGUID.WithArgs(err)
+		}
+	}
+	if cfg.MaxAttempts < 0 {
+		return errors.ErrBreakGlassConfigMaxAttempts.WithArgs(cfg.MaxAttempts)
+	}
+	if cfg.Interval < 0 {
+		return errors.ErrBreakGlassConfigInterval.WithArgs(cfg.Interval)
+	}
+	if cfg.EmailProvider != "" && len(cfg.NotifyEmails) == 0 {
+		return errors.ErrBreakGlassConfigNotifyEmails.WithArgs(cfg.EmailProvider)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of break-glass login notifications.
+func (cfg *Config) ValidateMessaging() error {
+	if cfg.EmailProvider == "" {
+		return nil
+	}
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config:    cfg,
+		realm:     defaultRealm,
+		usernames: make(map[string]bool),
+	}
+	if cfg.Realm != "" {
+		m.realm = cfg.Realm
+	}
+	for _, username := range cfg.Usernames {
+		m.usernames[strings.ToLower(strings.TrimSpace(username))] = true
+	}
+	for _, aaguid := range cfg.HardwareKeyAAGUIDs {
+		v, _ := identity.NormalizeAAGUID(aaguid)
+		m.aaguids = append(m.aaguids, v)
+	}
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{
+		MaxAttempts: getValue(cfg.MaxAttempts, defaultMaxAttempts),
+		Interval:    getValue(cfg.Interval, defaultInterval),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.limiter = limiter
+	return m, nil
+}
+
+// GetRealm returns the realm of the local identity store holding the
+// break-glass accounts.
+func (m *Manager) GetRealm() string {
+	return m.realm
+}
+
+// GetAAGUIDs returns the normalized AAGUIDs of the hardware security key
+// models the break-glass accounts sign in with.
+func (m *Manager) GetAAGUIDs() []string {
+	return m.aaguids
+}
+
+// IsUsername returns true when the username is the one of a break-glass
+// account.
+func (m *Manager) IsUsername(username string) bool {
+	return m.usernames[strings.ToLower(strings.TrimSpace(username))]
+}
+
+// IsAccount returns true when the user of the realm is a break-glass
+// account.
+func (m *Manager) IsAccount(realm, username string) bool {
+	return realm == m.realm && m.IsUsername(username)
+}
+
+// Allow records a login attempt of the break-glass account from the source
+// address. The attempts are limited per account and source address, so the
+// attempts from one source do not lock the account out for the others. When
+// the attempts are exhausted, the number of seconds until the account
+// accepts the attempts from the source again is returned with an error.
+func (m *Manager) Allow(addr, username string) (int, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if retryAfter, err := m.limiter.Allow(addr + "/" + username); err != nil {
+		return retryAfter, errors.ErrBreakGlassRateLimited.WithArgs(username, retryAfter)
+	}
+	return 0, nil
+}
+
+func getValue(v, defaultValue int) int {
+	if v > 0 {
+		return v
+	}
+	return defaultValue
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breakglass
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+var testAAGUIDs = []string{"CB69481E-8FF7-4039-93EC-0A2729A154A8"}
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Usernames: []string{"breakglass"}, HardwareKeyAAGUIDs: testAAGUIDs},
+		},
+		{
+			name:      "config without accounts",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigUsernamesNotFound,
+		},
+		{
+			name:      "config with empty username",
+			config:    &Config{Usernames: []string{" "}},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigUsername,
+		},
+		{
+			name:      "config without hardware key aaguids",
+			config:    &Config{Usernames: []string{"breakglass"}},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigAAGUIDsNotFound,
+		},
+		{
+			name:      "config with anonymous hardware key aaguid",
+			config:    &Config{Usernames: []string{"breakglass"}, HardwareKeyAAGUIDs: []string{"00000000-0000-0000-0000-000000000000"}},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigAAGUID.WithArgs(errors.ErrMfaTokenAAGUID.WithArgs("00000000-0000-0000-0000-000000000000")),
+		},
+		{
+			name:      "config with negative max attempts",
+			config:    &Config{Usernames: []string{"breakglass"}, HardwareKeyAAGUIDs: testAAGUIDs, MaxAttempts: -1},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigMaxAttempts.WithArgs(-1),
+		},
+		{
+			name:      "config with negative interval",
+			config:    &Config{Usernames: []string{"breakglass"}, HardwareKeyAAGUIDs: testAAGUIDs, Interval: -1},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigInterval.WithArgs(-1),
+		},
+		{
+			name:      "config with email provider without recipients",
+			config:    &Config{Usernames: []string{"breakglass"}, HardwareKeyAAGUIDs: testAAGUIDs, EmailProvider: "default"},
+			shouldErr: true,
+			err:       errors.ErrBreakGlassConfigNotifyEmails.WithArgs("default"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	m, err := NewManager(&Config{Usernames: []string{"BreakGlass"}, HardwareKeyAAGUIDs: testAAGUIDs, MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "realm", "local", m.GetRealm())
+	tests.EvalObjects(t, "account", true, m.IsAccount("local", "breakglass"))
+	tests.EvalObjects(t, "account of other realm", false, m.IsAccount("contoso", "breakglass"))
+	tests.EvalObjects(t, "other account", false, m.IsAccount("local", "jsmith"))
+	tests.EvalObjects(t, "aaguids", []string{"cb69481e8ff7403993ec0a2729a154a8"}, m.GetAAGUIDs())
+
+	// The attempts are limited per account and source address.
+	for i := 0; i < 2; i++ {
+		if _, err := m.Allow("10.0.0.1", "breakglass"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	retryAfter, err := m.Allow("10.0.0.1", "BreakGlass")
+	tests.EvalErrWithLog(t, err, "rate limited", true, errors.ErrBreakGlassRateLimited.WithArgs("breakglass", retryAfter), nil)
+	if retryAfter < 1 {
+		t.Fatalf("expected retry after, got %d", retryAfter)
+	}
+	_, err = m.Allow("10.0.0.2", "breakglass")
+	tests.EvalErrWithLog(t, err, "other source", false, nil, nil)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{
+		Usernames:          []string{"breakglass"},
+		HardwareKeyAAGUIDs: testAAGUIDs,
+		EmailProvider:      "default",
+		NotifyEmails:       []string{"security@localhost"},
+	}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := map[string]string{
+		"realm":    "local",
+		"username": "breakglass",
+		"email":    "breakglass@localhost",
+		"src_ip":   "10.0.0.1",
+	}
+	if err := m.NotifyLogin(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 1, len(files))
+	b, _ := ioutil.ReadFile(files[0])
+	if !strings.Contains(string(b), "Subject: URGENT: Break-Glass Account breakglass Signed In") {
+		t.Fatalf("unexpected message: %s", b)
+	}
+}
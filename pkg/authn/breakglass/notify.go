@@ -0,0 +1,30 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breakglass
+
+const loginTemplateName = "en/break_glass_login"
+
+// NotifyLogin informs the notification recipients about the login with the
+// break-glass account in data.
+func (m *Manager) NotifyLogin(data map[string]string) error {
+	if m.config.EmailProvider == "" {
+		return nil
+	}
+	return m.notify(loginTemplateName, m.config.NotifyEmails, data)
+}
+
+func (m *Manager) notify(templateName string, rcpts []string, data map[string]string) error {
+	return m.config.Send(m.config.EmailProvider, templateName, rcpts, data)
+}
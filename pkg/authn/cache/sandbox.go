@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 )
 
@@ -184,10 +185,13 @@ func (c *SandboxCache) Get(sandboxID string) (*user.User, error) {
 	defer c.mu.RUnlock()
 	if entry, exists := c.Entries[sandboxID]; exists {
 		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			metrics.RecordCacheLookup("sandbox", false)
 			return nil, err
 		}
+		metrics.RecordCacheLookup("sandbox", true)
 		return entry.user, nil
 	}
+	metrics.RecordCacheLookup("sandbox", false)
 	return nil, errors.New("cached sandbox id not found")
 }
 
@@ -17,9 +17,11 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 )
 
@@ -157,6 +159,41 @@ func (c *SessionCache) Delete(sessionID string) error {
 	return nil
 }
 
+// DeleteUserSessions removes the cached entries of the user with the provided
+// email address and returns the removed users.
+func (c *SessionCache) DeleteUserSessions(email string) []*user.User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var users []*user.User
+	for sessionID, entry := range c.Entries {
+		if entry.user == nil || !strings.EqualFold(entry.user.Claims.Email, email) {
+			continue
+		}
+		users = append(users, entry.user)
+		delete(c.Entries, sessionID)
+	}
+	return users
+}
+
+// PruneSessions removes the cached entries created before the cutoff and
+// returns the removed users. In the dry run, the entries are returned, but
+// not removed.
+func (c *SessionCache) PruneSessions(cutoff time.Time, dryRun bool) []*user.User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var users []*user.User
+	for sessionID, entry := range c.Entries {
+		if !entry.createdAt.Before(cutoff) {
+			continue
+		}
+		users = append(users, entry.user)
+		if !dryRun {
+			delete(c.Entries, sessionID)
+		}
+	}
+	return users
+}
+
 // Get returns cached user entry.
 func (c *SessionCache) Get(sessionID string) (*user.User, error) {
 	if err := parseCacheID(sessionID); err != nil {
@@ -166,10 +203,13 @@ func (c *SessionCache) Get(sessionID string) (*user.User, error) {
 	defer c.mu.RUnlock()
 	if entry, exists := c.Entries[sessionID]; exists {
 		if err := entry.Valid(); err != nil {
+			metrics.RecordCacheLookup("session", false)
 			return nil, fmt.Errorf("cached session id error: %s", err)
 		}
+		metrics.RecordCacheLookup("session", true)
 		return entry.user, nil
 	}
+	metrics.RecordCacheLookup("session", false)
 	return nil, errors.New("cached session id not found")
 }
 
@@ -0,0 +1,212 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clients
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds the configuration of the service clients allowed to obtain
+// tokens with the client credentials grant.
+type Config struct {
+	Clients []*ClientConfig `json:"clients,omitempty" xml:"clients,omitempty" yaml:"clients,omitempty"`
+}
+
+// ClientConfig holds the configuration of a service client.
+type ClientConfig struct {
+	ID string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	// Secret is the client secret. It is either a bcrypt hash, or a
+	// plain-text string.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	// CertificateSubject is the common name or the DNS name of the
+	// verified TLS client certificate authenticating the client.
+	CertificateSubject string `json:"certificate_subject,omitempty" xml:"certificate_subject,omitempty" yaml:"certificate_subject,omitempty"`
+	// Roles are the roles in the issued tokens.
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	// Scopes are the scopes the client may request. When the client does
+	// not request specific scopes, the tokens carry all of them.
+	Scopes []string `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// TokenLifetime is the lifetime of the issued tokens in seconds. When it
+	// is zero, the lifetime of the signing key applies.
+	TokenLifetime int  `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+	Disabled      bool `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// Client is an authenticated service client.
+type Client struct {
+	ID            string   `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Roles         []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Scopes        []string `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	TokenLifetime int      `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+}
+
+type client struct {
+	config       *ClientConfig
+	secretHash   []byte
+	secretDigest [sha256.Size]byte
+}
+
+// Registry authenticates service clients.
+type Registry struct {
+	clients map[string]*client
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	ids := make(map[string]bool)
+	for _, c := range cfg.Clients {
+		if c.ID == "" {
+			return errors.ErrClientConfigIDEmpty
+		}
+		if ids[c.ID] {
+			return errors.ErrClientConfigIDDuplicate.WithArgs(c.ID)
+		}
+		ids[c.ID] = true
+		if c.Secret == "" && c.CertificateSubject == "" {
+			return errors.ErrClientConfigCredentials.WithArgs(c.ID)
+		}
+		if c.TokenLifetime < 0 {
+			return errors.ErrClientConfigTokenLifetime.WithArgs(c.ID, c.TokenLifetime)
+		}
+	}
+	return nil
+}
+
+// NewRegistry returns an instance of Registry.
+func NewRegistry(cfg *Config) (*Registry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	reg := &Registry{
+		clients: make(map[string]*client),
+	}
+	for _, c := range cfg.Clients {
+		entry := &client{config: c}
+		switch {
+		case c.Secret == "":
+		case isBcryptHash(c.Secret):
+			entry.secretHash = []byte(c.Secret)
+		default:
+			entry.secretDigest = sha256.Sum256([]byte(c.Secret))
+		}
+		reg.clients[c.ID] = entry
+	}
+	return reg, nil
+}
+
+// Authenticate authenticates a client with its secret.
+func (reg *Registry) Authenticate(id, secret string) (*Client, error) {
+	entry, err := reg.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.config.Secret == "" || secret == "" {
+		return nil, errors.ErrClientAuthFailed
+	}
+	if entry.secretHash != nil {
+		if err := bcrypt.CompareHashAndPassword(entry.secretHash, []byte(secret)); err != nil {
+			return nil, errors.ErrClientAuthFailed
+		}
+		return entry.asClient(), nil
+	}
+	digest := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(entry.secretDigest[:], digest[:]) != 1 {
+		return nil, errors.ErrClientAuthFailed
+	}
+	return entry.asClient(), nil
+}
+
+// AuthenticateCertificate authenticates a client with the verified TLS
+// client certificate.
+func (reg *Registry) AuthenticateCertificate(id string, cert *x509.Certificate) (*Client, error) {
+	entry, err := reg.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.config.CertificateSubject == "" || cert == nil {
+		return nil, errors.ErrClientAuthFailed
+	}
+	subject := entry.config.CertificateSubject
+	if cert.Subject.CommonName == subject {
+		return entry.asClient(), nil
+	}
+	for _, name := range cert.DNSNames {
+		if name == subject {
+			return entry.asClient(), nil
+		}
+	}
+	return nil, errors.ErrClientAuthFailed
+}
+
+func (reg *Registry) get(id string) (*client, error) {
+	entry, exists := reg.clients[id]
+	if !exists {
+		return nil, errors.ErrClientAuthFailed
+	}
+	if entry.config.Disabled {
+		return nil, errors.ErrClientDisabled.WithArgs(id)
+	}
+	return entry, nil
+}
+
+func (entry *client) asClient() *Client {
+	return &Client{
+		ID:            entry.config.ID,
+		Roles:         entry.config.Roles,
+		Scopes:        entry.config.Scopes,
+		TokenLifetime: entry.config.TokenLifetime,
+	}
+}
+
+// GrantScopes returns the scopes granted for the requested scopes. Every
+// requested scope must be allowed for the client. When no scopes are
+// requested, all allowed scopes are granted.
+func (c *Client) GrantScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return c.Scopes, nil
+	}
+	allowed := make(map[string]bool)
+	for _, scope := range c.Scopes {
+		allowed[scope] = true
+	}
+	var scopes []string
+	granted := make(map[string]bool)
+	for _, scope := range requested {
+		if !allowed[scope] {
+			return nil, errors.ErrClientScopeNotAllowed.WithArgs(scope, c.ID)
+		}
+		if granted[scope] {
+			continue
+		}
+		granted[scope] = true
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+func isBcryptHash(s string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clients
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewRegistry(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{ID: "billing", Secret: "foobar"},
+					{ID: "reports", CertificateSubject: "reports.contoso.com"},
+				},
+			},
+		},
+		{
+			name: "client without id",
+			config: &Config{
+				Clients: []*ClientConfig{{Secret: "foobar"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrClientConfigIDEmpty,
+		},
+		{
+			name: "duplicate client id",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{ID: "billing", Secret: "foobar"},
+					{ID: "billing", Secret: "barfoo"},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrClientConfigIDDuplicate.WithArgs("billing"),
+		},
+		{
+			name: "client without credentials",
+			config: &Config{
+				Clients: []*ClientConfig{{ID: "billing"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrClientConfigCredentials.WithArgs("billing"),
+		},
+		{
+			name: "negative token lifetime",
+			config: &Config{
+				Clients: []*ClientConfig{{ID: "billing", Secret: "foobar", TokenLifetime: -1}},
+			},
+			shouldErr: true,
+			err:       errors.ErrClientConfigTokenLifetime.WithArgs("billing", -1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewRegistry(tc.config)
+			tests.EvalErrWithLog(t, err, "NewRegistry", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reg, err := NewRegistry(&Config{
+		Clients: []*ClientConfig{
+			{ID: "billing", Secret: "foobar", Roles: []string{"svc/billing"}, Scopes: []string{"invoices:read", "invoices:write"}},
+			{ID: "hashed", Secret: string(hash)},
+			{ID: "reports", CertificateSubject: "reports.contoso.com"},
+			{ID: "legacy", Secret: "foobar", Disabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		id        string
+		secret    string
+		cert      *x509.Certificate
+		scopes    []string
+		want      []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "plain-text secret with all scopes",
+			id:     "billing",
+			secret: "foobar",
+			want:   []string{"invoices:read", "invoices:write"},
+		},
+		{
+			name:   "plain-text secret with requested scope",
+			id:     "billing",
+			secret: "foobar",
+			scopes: []string{"invoices:read", "invoices:read"},
+			want:   []string{"invoices:read"},
+		},
+		{
+			name:      "plain-text secret with disallowed scope",
+			id:        "billing",
+			secret:    "foobar",
+			scopes:    []string{"payroll:read"},
+			shouldErr: true,
+			err:       errors.ErrClientScopeNotAllowed.WithArgs("payroll:read", "billing"),
+		},
+		{
+			name:      "wrong secret",
+			id:        "billing",
+			secret:    "barfoo",
+			shouldErr: true,
+			err:       errors.ErrClientAuthFailed,
+		},
+		{
+			name:   "bcrypt secret",
+			id:     "hashed",
+			secret: "s3cret",
+		},
+		{
+			name:      "wrong bcrypt secret",
+			id:        "hashed",
+			secret:    "foobar",
+			shouldErr: true,
+			err:       errors.ErrClientAuthFailed,
+		},
+		{
+			name: "certificate dns name",
+			id:   "reports",
+			cert: &x509.Certificate{DNSNames: []string{"reports.contoso.com"}},
+		},
+		{
+			name:      "certificate subject mismatch",
+			id:        "reports",
+			cert:      &x509.Certificate{Subject: pkix.Name{CommonName: "billing.contoso.com"}},
+			shouldErr: true,
+			err:       errors.ErrClientAuthFailed,
+		},
+		{
+			name:      "secret for certificate client",
+			id:        "reports",
+			secret:    "foobar",
+			shouldErr: true,
+			err:       errors.ErrClientAuthFailed,
+		},
+		{
+			name:      "disabled client",
+			id:        "legacy",
+			secret:    "foobar",
+			shouldErr: true,
+			err:       errors.ErrClientDisabled.WithArgs("legacy"),
+		},
+		{
+			name:      "unknown client",
+			id:        "unknown",
+			secret:    "foobar",
+			shouldErr: true,
+			err:       errors.ErrClientAuthFailed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			var c *Client
+			var err error
+			if tc.cert != nil {
+				c, err = reg.AuthenticateCertificate(tc.id, tc.cert)
+			} else {
+				c, err = reg.Authenticate(tc.id, tc.secret)
+			}
+			if err == nil {
+				var scopes []string
+				scopes, err = c.GrantScopes(tc.scopes)
+				if err == nil {
+					tests.EvalObjectsWithLog(t, "scopes", tc.want, scopes, msgs)
+				}
+			}
+			tests.EvalErrWithLog(t, err, "Authenticate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
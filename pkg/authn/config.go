@@ -16,9 +16,43 @@ package authn
 
 import (
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/authn/admission"
+	"github.com/greenpau/go-authcrunch/pkg/authn/bodylimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/breakglass"
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/authn/continuation"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authn/crossdomain"
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/deletion"
+	"github.com/greenpau/go-authcrunch/pkg/authn/emailchange"
+	"github.com/greenpau/go-authcrunch/pkg/authn/groupmap"
+	"github.com/greenpau/go-authcrunch/pkg/authn/idphint"
+	"github.com/greenpau/go-authcrunch/pkg/authn/impersonation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/lifecycle"
+	"github.com/greenpau/go-authcrunch/pkg/authn/linking"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginchain"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginguard"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginhistory"
+	"github.com/greenpau/go-authcrunch/pkg/authn/magiclink"
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/authn/netfilter"
+	"github.com/greenpau/go-authcrunch/pkg/authn/normalizer"
+	"github.com/greenpau/go-authcrunch/pkg/authn/oidc"
+	"github.com/greenpau/go-authcrunch/pkg/authn/profile"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/realmdiscovery"
+	"github.com/greenpau/go-authcrunch/pkg/authn/recovery"
+	"github.com/greenpau/go-authcrunch/pkg/authn/redirect"
+	"github.com/greenpau/go-authcrunch/pkg/authn/sessionpolicy"
+	"github.com/greenpau/go-authcrunch/pkg/authn/shaper"
+	"github.com/greenpau/go-authcrunch/pkg/authn/signin"
+	"github.com/greenpau/go-authcrunch/pkg/authn/terms"
+	"github.com/greenpau/go-authcrunch/pkg/authn/tokenlifetime"
 	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
 	"github.com/greenpau/go-authcrunch/pkg/authn/ui"
+	"github.com/greenpau/go-authcrunch/pkg/authn/usage"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
@@ -57,6 +91,151 @@ type PortalConfig struct {
 	// API holds the configuration for API endpoints.
 	API *APIConfig `json:"api,omitempty" xml:"api,omitempty" yaml:"api,omitempty"`
 
+	// AdmissionConfig holds the configuration for the admission control of
+	// expensive authentication operations.
+	AdmissionConfig *admission.Config `json:"admission_config,omitempty" xml:"admission_config,omitempty" yaml:"admission_config,omitempty"`
+
+	// NetworkFilterConfig holds the configuration of the network filter
+	// applied to the requests prior to authentication.
+	NetworkFilterConfig *netfilter.Config `json:"network_filter_config,omitempty" xml:"network_filter_config,omitempty" yaml:"network_filter_config,omitempty"`
+
+	// BodyLimitConfig holds the configuration of the size and the content
+	// type limits applied to the bodies of the inbound requests.
+	BodyLimitConfig *bodylimit.Config `json:"body_limit_config,omitempty" xml:"body_limit_config,omitempty" yaml:"body_limit_config,omitempty"`
+
+	// RedirectConfig holds the configuration of the policy applied to the
+	// URLs the users are redirected to after the authentication.
+	RedirectConfig *redirect.Config `json:"redirect_config,omitempty" xml:"redirect_config,omitempty" yaml:"redirect_config,omitempty"`
+
+	// ContinuationConfig holds the configuration of the continuation tokens
+	// carrying the state of multi-step login flows across the replicas.
+	ContinuationConfig *continuation.Config `json:"continuation_config,omitempty" xml:"continuation_config,omitempty" yaml:"continuation_config,omitempty"`
+
+	// UsageConfig holds the configuration for the opt-in collection of
+	// usage statistics.
+	UsageConfig *usage.Config `json:"usage_config,omitempty" xml:"usage_config,omitempty" yaml:"usage_config,omitempty"`
+
+	// TokenShaperConfig holds the configuration for the shaping of the
+	// claims in the issued tokens.
+	TokenShaperConfig *shaper.Config `json:"token_shaper_config,omitempty" xml:"token_shaper_config,omitempty" yaml:"token_shaper_config,omitempty"`
+
+	// IdentityNormalizationConfig holds the rules normalizing the usernames
+	// and the email addresses of the users across the realms.
+	IdentityNormalizationConfig *normalizer.Config `json:"identity_normalization_config,omitempty" xml:"identity_normalization_config,omitempty" yaml:"identity_normalization_config,omitempty"`
+
+	// IdentityLinkingConfig holds the configuration for the linking of the
+	// identities of external providers to the users in local identity stores.
+	IdentityLinkingConfig *linking.Config `json:"identity_linking_config,omitempty" xml:"identity_linking_config,omitempty" yaml:"identity_linking_config,omitempty"`
+
+	// PasswordRecoveryConfig holds the configuration for the self-service
+	// password reset of the users in local identity stores.
+	PasswordRecoveryConfig *recovery.Config `json:"password_recovery_config,omitempty" xml:"password_recovery_config,omitempty" yaml:"password_recovery_config,omitempty"`
+
+	// EmailChangeConfig holds the configuration for the verified change of
+	// the email addresses of the users in local identity stores.
+	EmailChangeConfig *emailchange.Config `json:"email_change_config,omitempty" xml:"email_change_config,omitempty" yaml:"email_change_config,omitempty"`
+
+	// AccountDeletionConfig holds the configuration for the self-service
+	// deletion of the accounts of the users in local identity stores.
+	AccountDeletionConfig *deletion.Config `json:"account_deletion_config,omitempty" xml:"account_deletion_config,omitempty" yaml:"account_deletion_config,omitempty"`
+
+	// MagicLinkConfig holds the configuration for the passwordless login
+	// of the users in local identity stores via emailed login links.
+	MagicLinkConfig *magiclink.Config `json:"magic_link_config,omitempty" xml:"magic_link_config,omitempty" yaml:"magic_link_config,omitempty"`
+
+	// SignInAlertConfig holds the configuration for the notifications about
+	// the sign-ins from unrecognized devices and locations.
+	SignInAlertConfig *signin.Config `json:"sign_in_alert_config,omitempty" xml:"sign_in_alert_config,omitempty" yaml:"sign_in_alert_config,omitempty"`
+
+	// AccessRequestConfig holds the configuration for the requests of the
+	// users for the roles granting access to the protected apps.
+	AccessRequestConfig *accessrequest.Config `json:"access_request_config,omitempty" xml:"access_request_config,omitempty" yaml:"access_request_config,omitempty"`
+
+	// BreakGlassConfig holds the configuration of the emergency accounts
+	// signing in with hardware security keys when the upstream identity
+	// providers are down.
+	BreakGlassConfig *breakglass.Config `json:"break_glass_config,omitempty" xml:"break_glass_config,omitempty" yaml:"break_glass_config,omitempty"`
+
+	// MaintenanceConfig holds the maintenance switches disabling the logins
+	// to the realms or with the identity providers. The switches are also
+	// changed at runtime via the administrative API.
+	MaintenanceConfig *maintenance.Config `json:"maintenance_config,omitempty" xml:"maintenance_config,omitempty" yaml:"maintenance_config,omitempty"`
+
+	// LoginHistoryConfig holds the retention limits of the login history
+	// recorded for the users in local identity stores.
+	LoginHistoryConfig *loginhistory.Config `json:"login_history_config,omitempty" xml:"login_history_config,omitempty" yaml:"login_history_config,omitempty"`
+
+	// LifecycleConfig holds the scheduled user lifecycle jobs, e.g. the
+	// disabling of dormant accounts and the pruning of old records.
+	LifecycleConfig *lifecycle.Config `json:"lifecycle_config,omitempty" xml:"lifecycle_config,omitempty" yaml:"lifecycle_config,omitempty"`
+
+	// ProfileConfig holds the field validation rules for the self-service
+	// profile management.
+	ProfileConfig *profile.Config `json:"profile_config,omitempty" xml:"profile_config,omitempty" yaml:"profile_config,omitempty"`
+
+	// ClientCredentialsConfig holds the service clients allowed to obtain
+	// tokens with the client credentials grant.
+	ClientCredentialsConfig *clients.Config `json:"client_credentials_config,omitempty" xml:"client_credentials_config,omitempty" yaml:"client_credentials_config,omitempty"`
+
+	// OIDCProviderConfig holds the configuration of the OpenID Connect
+	// provider serving the registered relying parties.
+	OIDCProviderConfig *oidc.Config `json:"oidc_provider_config,omitempty" xml:"oidc_provider_config,omitempty" yaml:"oidc_provider_config,omitempty"`
+
+	// TermsConfig holds the versioned documents, e.g. terms of service, the
+	// users of local identity stores must accept after signing in.
+	TermsConfig *terms.Config `json:"terms_config,omitempty" xml:"terms_config,omitempty" yaml:"terms_config,omitempty"`
+
+	// ImpersonationConfig holds the configuration of the time-boxed
+	// sessions the administrators obtain as other users.
+	ImpersonationConfig *impersonation.Config `json:"impersonation_config,omitempty" xml:"impersonation_config,omitempty" yaml:"impersonation_config,omitempty"`
+
+	// DelegationConfig holds the roles of the administrators managing the
+	// users and the registrations within their own realm or organization.
+	DelegationConfig *delegation.Config `json:"delegation_config,omitempty" xml:"delegation_config,omitempty" yaml:"delegation_config,omitempty"`
+
+	// GroupMappingConfig holds the configuration of the file mapping the
+	// upstream groups of LDAP, SAML, and OAuth users to roles.
+	GroupMappingConfig *groupmap.Config `json:"group_mapping_config,omitempty" xml:"group_mapping_config,omitempty" yaml:"group_mapping_config,omitempty"`
+
+	// LoginChainConfig holds the chains of the login methods, e.g. passkey
+	// with the fallback to password, of the authentication realms.
+	LoginChainConfig *loginchain.Config `json:"login_chain_config,omitempty" xml:"login_chain_config,omitempty" yaml:"login_chain_config,omitempty"`
+
+	// RealmDiscoveryConfig holds the rules routing the users to the identity
+	// stores and providers by the domains of their email addresses.
+	RealmDiscoveryConfig *realmdiscovery.Config `json:"realm_discovery_config,omitempty" xml:"realm_discovery_config,omitempty" yaml:"realm_discovery_config,omitempty"`
+
+	// IdentityProviderHintConfig holds the configuration of the signed
+	// cookie sending the returning users to the identity provider they last
+	// authenticated with.
+	IdentityProviderHintConfig *idphint.Config `json:"identity_provider_hint_config,omitempty" xml:"identity_provider_hint_config,omitempty" yaml:"identity_provider_hint_config,omitempty"`
+
+	// LoginRateLimitConfig holds the limits of the attempts of the JSON
+	// login API per source address and per account.
+	LoginRateLimitConfig *ratelimit.Config `json:"login_rate_limit_config,omitempty" xml:"login_rate_limit_config,omitempty" yaml:"login_rate_limit_config,omitempty"`
+
+	// LoginChallengeConfig holds the CAPTCHA challenge of the password
+	// logins from the source addresses and to the accounts with recent
+	// failed attempts.
+	LoginChallengeConfig *loginguard.Config `json:"login_challenge_config,omitempty" xml:"login_challenge_config,omitempty" yaml:"login_challenge_config,omitempty"`
+
+	// SessionPolicyConfig holds the idle timeouts and the absolute lifetimes
+	// of the portal sessions, whose access tokens are renewed silently.
+	SessionPolicyConfig *sessionpolicy.Config `json:"session_policy_config,omitempty" xml:"session_policy_config,omitempty" yaml:"session_policy_config,omitempty"`
+
+	// TokenLifetimeConfig holds the overrides of the lifetime of the access
+	// tokens by the realm and the roles of the users.
+	TokenLifetimeConfig *tokenlifetime.Config `json:"token_lifetime_config,omitempty" xml:"token_lifetime_config,omitempty" yaml:"token_lifetime_config,omitempty"`
+
+	// CrossDomainConfig enables the single sign-on across the subdomains of
+	// a parent domain with the encrypted tokens restricted to the subdomains
+	// allowed for the users.
+	CrossDomainConfig *crossdomain.Config `json:"cross_domain_config,omitempty" xml:"cross_domain_config,omitempty" yaml:"cross_domain_config,omitempty"`
+
+	// DiscoveryEnabled enables the publishing of OpenID Connect
+	// discovery metadata alongside the JWKS endpoint.
+	DiscoveryEnabled bool `json:"discovery_enabled,omitempty" xml:"discovery_enabled,omitempty" yaml:"discovery_enabled,omitempty"`
+
 	// Holds raw crypto configuration.
 	cryptoRawConfigs []string
 
@@ -129,6 +308,169 @@ func (cfg *PortalConfig) Validate() error {
 		return err
 	}
 
+	if cfg.AdmissionConfig != nil {
+		if err := cfg.AdmissionConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.NetworkFilterConfig != nil {
+		if err := cfg.NetworkFilterConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.BodyLimitConfig != nil {
+		if err := cfg.BodyLimitConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.RedirectConfig != nil {
+		if err := cfg.RedirectConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ContinuationConfig != nil {
+		if err := cfg.ContinuationConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.UsageConfig != nil {
+		if err := cfg.UsageConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ClientCredentialsConfig != nil {
+		if err := cfg.ClientCredentialsConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.OIDCProviderConfig != nil {
+		if err := cfg.OIDCProviderConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LoginHistoryConfig != nil {
+		if err := cfg.LoginHistoryConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TermsConfig != nil {
+		if err := cfg.TermsConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ImpersonationConfig != nil {
+		if err := cfg.ImpersonationConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DelegationConfig != nil {
+		if err := cfg.DelegationConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.GroupMappingConfig != nil {
+		if err := cfg.GroupMappingConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.IdentityNormalizationConfig != nil {
+		if err := cfg.IdentityNormalizationConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.IdentityLinkingConfig != nil {
+		if err := cfg.IdentityLinkingConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.AccessRequestConfig != nil {
+		if err := cfg.AccessRequestConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.BreakGlassConfig != nil {
+		if err := cfg.BreakGlassConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MaintenanceConfig != nil {
+		if err := cfg.MaintenanceConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LifecycleConfig != nil {
+		cfg.LifecycleConfig.SetLoginHistory(cfg.LoginHistoryConfig)
+		if err := cfg.LifecycleConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LoginChainConfig != nil {
+		if err := cfg.LoginChainConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.RealmDiscoveryConfig != nil {
+		if err := cfg.RealmDiscoveryConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.IdentityProviderHintConfig != nil {
+		if err := cfg.IdentityProviderHintConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LoginRateLimitConfig != nil {
+		if err := cfg.LoginRateLimitConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.LoginChallengeConfig != nil {
+		if err := cfg.LoginChallengeConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.SessionPolicyConfig != nil {
+		if err := cfg.SessionPolicyConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TokenLifetimeConfig != nil {
+		if err := cfg.TokenLifetimeConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CrossDomainConfig != nil {
+		if err := cfg.CrossDomainConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Inialize user interface settings
 	if cfg.UI == nil {
 		cfg.UI = &ui.Parameters{}
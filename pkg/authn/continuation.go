@@ -0,0 +1,121 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/continuation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// setContinuationCookies sets the cookies carrying the continuation token
+// of the login flow of the user. The token exceeding the chunk size is
+// split across multiple cookies.
+func (p *Portal) setContinuationCookies(w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) {
+	if p.continuation == nil {
+		return
+	}
+	state := continuation.NewState(usr)
+	if c, err := r.Cookie(p.cookie.Referer); err == nil {
+		state.RedirectURL = c.Value
+	}
+	token, err := p.continuation.Issue(state)
+	if err != nil {
+		p.logger.Warn(
+			"failed to encode continuation token",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	h := addrutil.GetSourceHost(r)
+	name := p.cookie.Continuation
+	cookies := p.cookie.GetRealmCookies(h, usr.Authenticator.Realm, name, token)
+	for i, c := range cookies {
+		p.checkCookieSize(rr, cookie.GetChunkName(name, i), c)
+		w.Header().Add("Set-Cookie", c)
+	}
+	for _, chunkName := range cookie.GetChunkNames(r.Cookies(), name, len(cookies)) {
+		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, chunkName))
+	}
+}
+
+// deleteContinuationCookies deletes the cookies carrying the continuation
+// token once the login flow completes, and makes the tokens of the flow no
+// longer redeemable.
+func (p *Portal) deleteContinuationCookies(w http.ResponseWriter, r *http.Request, rr *requests.Request, h, flowID string) {
+	if p.continuation == nil {
+		return
+	}
+	p.deleteTokenCookies(w, r, h, p.cookie.Continuation)
+	p.terminateContinuation(rr, flowID)
+}
+
+// terminateContinuation makes the continuation tokens of the login flow no
+// longer redeemable.
+func (p *Portal) terminateContinuation(rr *requests.Request, flowID string) {
+	if p.continuation == nil || flowID == "" {
+		return
+	}
+	if err := p.continuation.Terminate(flowID); err != nil {
+		p.logger.Warn(
+			"failed to terminate continuation tokens",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("sandbox_id", flowID),
+			zap.Error(err),
+		)
+	}
+}
+
+// resumeSandbox restores the sandbox of the login flow started by another
+// instance of the portal, e.g. prior to a restart or on another replica,
+// from the continuation token. The token is redeemed once, and the sandbox
+// of the terminated flow is not restored.
+func (p *Portal) resumeSandbox(r *http.Request, rr *requests.Request, sandboxID string) (*user.User, error) {
+	token, found := cookie.JoinChunks(r.Cookies(), p.cookie.Continuation)
+	if !found {
+		return nil, errors.ErrContinuationNotFound
+	}
+	state, err := p.continuation.Redeem(token, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	usr, err := state.GetUser()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.sandboxes.Add(sandboxID, usr); err != nil {
+		return nil, err
+	}
+	if _, err := r.Cookie(p.cookie.Referer); err != nil && state.RedirectURL != "" {
+		r.AddCookie(&http.Cookie{Name: p.cookie.Referer, Value: state.RedirectURL})
+	}
+	p.logger.Info(
+		"sandbox resumed from continuation token",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("sandbox_id", sandboxID),
+	)
+	p.recordUsage("continuation/resume")
+	return usr, nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package continuation
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+const (
+	// MinKeyLength is the minimum length of the key encrypting the
+	// continuation tokens.
+	MinKeyLength = 32
+
+	// The default lifetime matches the lifetime of the sandbox cache
+	// entries.
+	defaultLifetime = 300
+	flowKeyPrefix   = "continuation_flow:"
+)
+
+// Config holds the configuration of the continuation tokens. The tokens
+// carry the intermediate state of multi-step login flows, so that the
+// flows survive restarts and span load-balanced replicas. The replicas
+// must share the encryption key and the token store.
+type Config struct {
+	// The key encrypting and authenticating the tokens.
+	EncryptionKey string `json:"encryption_key,omitempty" xml:"encryption_key,omitempty" yaml:"encryption_key,omitempty"`
+	// The lifetime of the tokens in seconds. Defaults to 5 minutes.
+	Lifetime int `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+	// TokenStore is the name of the token store tracking the latest token
+	// of each flow, so that a token is redeemed once, and the tokens of the
+	// terminated flows are not redeemed.
+	TokenStore string `json:"token_store,omitempty" xml:"token_store,omitempty" yaml:"token_store,omitempty"`
+}
+
+// State is the intermediate state of a login flow.
+type State struct {
+	// The identifier of the sandbox the flow runs in.
+	FlowID string `json:"flow_id,omitempty" xml:"flow_id,omitempty" yaml:"flow_id,omitempty"`
+	// The identifier of the token, unique across the tokens of the flow.
+	TokenID string `json:"token_id,omitempty" xml:"token_id,omitempty" yaml:"token_id,omitempty"`
+	// The claims of the user being authenticated.
+	Claims map[string]interface{} `json:"claims,omitempty" xml:"claims,omitempty" yaml:"claims,omitempty"`
+	// The identity store or provider chosen by the user.
+	Authenticator user.Authenticator `json:"authenticator,omitempty" xml:"authenticator,omitempty" yaml:"authenticator,omitempty"`
+	// The progress of the user through the checkpoints, e.g. MFA.
+	Checkpoints   []*user.Checkpoint `json:"checkpoints,omitempty" xml:"checkpoints,omitempty" yaml:"checkpoints,omitempty"`
+	FrontendLinks []string           `json:"frontend_links,omitempty" xml:"frontend_links,omitempty" yaml:"frontend_links,omitempty"`
+	// The original destination of the user.
+	RedirectURL string `json:"redirect_url,omitempty" xml:"redirect_url,omitempty" yaml:"redirect_url,omitempty"`
+	ExpiresAt   int64  `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// Codec encodes the state of login flows into continuation tokens and
+// decodes it back.
+type Codec struct {
+	aead       cipher.AEAD
+	lifetime   time.Duration
+	tokenStore string
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if len(cfg.EncryptionKey) < MinKeyLength {
+		return errors.ErrContinuationConfigKey.WithArgs(MinKeyLength)
+	}
+	if cfg.Lifetime < 0 {
+		return errors.ErrContinuationConfigLifetime.WithArgs(cfg.Lifetime)
+	}
+	if cfg.TokenStore == "" {
+		return errors.ErrContinuationConfigStore
+	}
+	return nil
+}
+
+// NewCodec returns an instance of Codec.
+func NewCodec(cfg *Config) (*Codec, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(cfg.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c := &Codec{
+		aead:       aead,
+		lifetime:   time.Duration(defaultLifetime) * time.Second,
+		tokenStore: cfg.TokenStore,
+	}
+	if cfg.Lifetime > 0 {
+		c.lifetime = time.Duration(cfg.Lifetime) * time.Second
+	}
+	return c, nil
+}
+
+// NewState returns the state of the login flow of the user.
+func NewState(usr *user.User) *State {
+	return &State{
+		FlowID:        usr.Authenticator.TempSessionID,
+		Claims:        usr.AsMap(),
+		Authenticator: usr.Authenticator,
+		Checkpoints:   usr.Checkpoints,
+		FrontendLinks: usr.FrontendLinks,
+	}
+}
+
+// GetUser returns the user of the login flow.
+func (s *State) GetUser() (*user.User, error) {
+	usr, err := user.NewUser(s.Claims)
+	if err != nil {
+		return nil, err
+	}
+	usr.Authenticator = s.Authenticator
+	usr.Checkpoints = s.Checkpoints
+	usr.FrontendLinks = s.FrontendLinks
+	return usr, nil
+}
+
+// Encode returns the continuation token for the state. The token is
+// encrypted and authenticated with AES-GCM.
+func (c *Codec) Encode(s *State) (string, error) {
+	s.ExpiresAt = time.Now().Add(c.lifetime).Unix()
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", errors.ErrContinuationEncode.WithArgs(err)
+	}
+	s.TokenID = base64.RawURLEncoding.EncodeToString(id)
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.ErrContinuationEncode.WithArgs(err)
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.ErrContinuationEncode.WithArgs(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, b, nil)), nil
+}
+
+// Decode returns the state of the continuation token of the flow.
+func (c *Codec) Decode(token, flowID string) (*State, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) < c.aead.NonceSize() {
+		return nil, errors.ErrContinuationMalformed
+	}
+	nonce, ciphertext := b[:c.aead.NonceSize()], b[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.ErrContinuationDecrypt
+	}
+	s := &State{}
+	if err := json.Unmarshal(plaintext, s); err != nil {
+		return nil, errors.ErrContinuationMalformed
+	}
+	if time.Now().Unix() > s.ExpiresAt {
+		return nil, errors.ErrContinuationExpired
+	}
+	if s.FlowID != flowID {
+		return nil, errors.ErrContinuationFlowMismatch.WithArgs(s.FlowID)
+	}
+	return s, nil
+}
+
+// Issue returns the continuation token for the state and records the token
+// as the latest token of the flow. The previous tokens of the flow are no
+// longer redeemable.
+func (c *Codec) Issue(s *State) (string, error) {
+	token, err := c.Encode(s)
+	if err != nil {
+		return "", err
+	}
+	if err := c.record(s.FlowID, map[string]interface{}{"token_id": s.TokenID}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Redeem returns the state of the continuation token of the flow. Only the
+// latest token of the flow is redeemable, and only once.
+func (c *Codec) Redeem(token, flowID string) (*State, error) {
+	s, err := c.Decode(token, flowID)
+	if err != nil {
+		return nil, err
+	}
+	store, err := tokenstore.Get(c.tokenStore)
+	if err != nil {
+		return nil, errors.ErrContinuationStore.WithArgs(c.tokenStore, err)
+	}
+	m, err := store.Get(flowKeyPrefix + flowID)
+	if err != nil {
+		return nil, errors.ErrContinuationRedeemed
+	}
+	if tokenID, _ := m["token_id"].(string); tokenID != s.TokenID {
+		return nil, errors.ErrContinuationRedeemed
+	}
+	if err := c.record(flowID, map[string]interface{}{"redeemed": true}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Terminate makes the tokens of the flow no longer redeemable, e.g. when
+// the user completes or terminates the flow.
+func (c *Codec) Terminate(flowID string) error {
+	return c.record(flowID, map[string]interface{}{"terminated": true})
+}
+
+// record replaces the state of the flow in the token store. The state
+// outlives the tokens issued so far.
+func (c *Codec) record(flowID string, m map[string]interface{}) error {
+	if flowID == "" {
+		return errors.ErrContinuationFlowMismatch.WithArgs(flowID)
+	}
+	store, err := tokenstore.Get(c.tokenStore)
+	if err != nil {
+		return errors.ErrContinuationStore.WithArgs(c.tokenStore, err)
+	}
+	key := flowKeyPrefix + flowID
+	if err := store.Delete(key); err != nil {
+		return errors.ErrContinuationStore.WithArgs(c.tokenStore, err)
+	}
+	if err := store.Add(key, m, time.Now().Add(c.lifetime)); err != nil {
+		return errors.ErrContinuationStore.WithArgs(c.tokenStore, err)
+	}
+	return nil
+}
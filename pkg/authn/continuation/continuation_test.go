@@ -0,0 +1,243 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package continuation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+const (
+	testKey   = "0123456789abcdef0123456789abcdef"
+	testStore = "continuation_test"
+)
+
+func init() {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: testStore, Kind: "memory"})
+	if err != nil {
+		panic(err)
+	}
+	tokenstore.Register(testStore, store)
+}
+
+func TestNewCodec(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EncryptionKey: testKey, Lifetime: 600, TokenStore: testStore},
+		},
+		{
+			name:      "no token store",
+			config:    &Config{EncryptionKey: testKey},
+			shouldErr: true,
+			err:       errors.ErrContinuationConfigStore,
+		},
+		{
+			name:      "short encryption key",
+			config:    &Config{EncryptionKey: "foobar"},
+			shouldErr: true,
+			err:       errors.ErrContinuationConfigKey.WithArgs(MinKeyLength),
+		},
+		{
+			name:      "negative lifetime",
+			config:    &Config{EncryptionKey: testKey, Lifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrContinuationConfigLifetime.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewCodec(tc.config)
+			tests.EvalErrWithLog(t, err, "NewCodec", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	usr, err := user.NewUser(map[string]interface{}{
+		"sub":   "jsmith",
+		"email": "jsmith@contoso.com",
+		"roles": []string{"authp/user"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr.Authenticator = user.Authenticator{
+		Name:          "local",
+		Realm:         "local",
+		Method:        "local",
+		TempSecret:    "secret",
+		TempSessionID: "flow-1",
+	}
+	usr.Checkpoints = []*user.Checkpoint{
+		{ID: 0, Name: "Password", Type: "password", Passed: true},
+		{ID: 1, Name: "Multi-factor authentication", Type: "mfa", FailedAttempts: 2},
+	}
+
+	c, err := NewCodec(&Config{EncryptionKey: testKey, TokenStore: testStore})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherCodec, err := NewCodec(&Config{EncryptionKey: strings.Repeat("x", MinKeyLength), TokenStore: testStore})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expiredCodec, err := NewCodec(&Config{EncryptionKey: testKey, TokenStore: testStore})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expiredCodec.lifetime = -1 * time.Minute
+
+	state := NewState(usr)
+	state.RedirectURL = "https://app.contoso.com/"
+	validToken, _ := c.Encode(state)
+	expiredToken, _ := expiredCodec.Encode(NewState(usr))
+	foreignToken, _ := otherCodec.Encode(NewState(usr))
+
+	testcases := []struct {
+		name      string
+		token     string
+		flowID    string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid token",
+			token:  validToken,
+			flowID: "flow-1",
+			want: map[string]interface{}{
+				"sub":             "jsmith",
+				"email":           "jsmith@contoso.com",
+				"realm":           "local",
+				"temp_secret":     "secret",
+				"redirect_url":    "https://app.contoso.com/",
+				"checkpoints":     2,
+				"passed":          true,
+				"failed_attempts": 2,
+			},
+		},
+		{
+			name:      "token of other flow",
+			token:     validToken,
+			flowID:    "flow-2",
+			shouldErr: true,
+			err:       errors.ErrContinuationFlowMismatch.WithArgs("flow-1"),
+		},
+		{
+			name:      "expired token",
+			token:     expiredToken,
+			flowID:    "flow-1",
+			shouldErr: true,
+			err:       errors.ErrContinuationExpired,
+		},
+		{
+			name:      "token encrypted with other key",
+			token:     foreignToken,
+			flowID:    "flow-1",
+			shouldErr: true,
+			err:       errors.ErrContinuationDecrypt,
+		},
+		{
+			name:      "tampered token",
+			token:     validToken[:len(validToken)-4] + "AAAA",
+			flowID:    "flow-1",
+			shouldErr: true,
+			err:       errors.ErrContinuationDecrypt,
+		},
+		{
+			name:      "malformed token",
+			token:     "foo",
+			flowID:    "flow-1",
+			shouldErr: true,
+			err:       errors.ErrContinuationMalformed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			s, err := c.Decode(tc.token, tc.flowID)
+			if tests.EvalErrWithLog(t, err, "Decode", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			u, err := s.GetUser()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"sub":             u.Claims.Subject,
+				"email":           u.Claims.Email,
+				"realm":           u.Authenticator.Realm,
+				"temp_secret":     u.Authenticator.TempSecret,
+				"redirect_url":    s.RedirectURL,
+				"checkpoints":     len(u.Checkpoints),
+				"passed":          u.Checkpoints[0].Passed,
+				"failed_attempts": u.Checkpoints[1].FailedAttempts,
+			}
+			tests.EvalObjectsWithLog(t, "state", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestRedeem(t *testing.T) {
+	usr, err := user.NewUser(map[string]interface{}{"sub": "jsmith"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr.Authenticator = user.Authenticator{
+		Realm:         "local",
+		TempSessionID: "flow-2",
+	}
+	c, err := NewCodec(&Config{EncryptionKey: testKey, TokenStore: testStore})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The token not issued by the codec is not redeemable.
+	token, _ := c.Encode(NewState(usr))
+	_, err = c.Redeem(token, "flow-2")
+	tests.EvalErrWithLog(t, err, "unrecorded token", true, errors.ErrContinuationRedeemed, nil)
+
+	// Only the latest token of the flow is redeemable, and only once.
+	prevToken, _ := c.Issue(NewState(usr))
+	token, _ = c.Issue(NewState(usr))
+	_, err = c.Redeem(prevToken, "flow-2")
+	tests.EvalErrWithLog(t, err, "superseded token", true, errors.ErrContinuationRedeemed, nil)
+	_, err = c.Redeem(token, "flow-2")
+	tests.EvalErrWithLog(t, err, "latest token", false, nil, nil)
+	_, err = c.Redeem(token, "flow-2")
+	tests.EvalErrWithLog(t, err, "redeemed token", true, errors.ErrContinuationRedeemed, nil)
+
+	// The tokens of the terminated flow are not redeemable.
+	token, _ = c.Issue(NewState(usr))
+	if err := c.Terminate("flow-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = c.Redeem(token, "flow-2")
+	tests.EvalErrWithLog(t, err, "terminated flow", true, errors.ErrContinuationRedeemed, nil)
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+// setTokenCookies sets the cookies carrying the token of a user. The token
+// exceeding the chunk size is split across multiple cookies, and the chunks
+// left over from a previously issued larger token are deleted.
+func (p *Portal) setTokenCookies(w http.ResponseWriter, r *http.Request, rr *requests.Request, h, name, token string) {
+	var cookies []string
+	if p.config.CrossDomainConfig != nil {
+		cookies = p.cookie.GetDomainCookies(h, p.config.CrossDomainConfig.Domain, name, token)
+	} else {
+		cookies = p.cookie.GetRealmCookies(h, rr.Upstream.Realm, name, token)
+	}
+	for i, c := range cookies {
+		p.checkCookieSize(rr, cookie.GetChunkName(name, i), c)
+		if i == 0 {
+			w.Header().Set("Set-Cookie", c)
+			continue
+		}
+		w.Header().Add("Set-Cookie", c)
+	}
+	if len(cookies) > 1 {
+		p.recordUsage("cookie/chunked")
+	}
+	for _, chunkName := range cookie.GetChunkNames(r.Cookies(), name, len(cookies)) {
+		if p.config.CrossDomainConfig != nil {
+			w.Header().Add("Set-Cookie", p.cookie.GetDomainDeleteCookie(h, p.config.CrossDomainConfig.Domain, chunkName))
+			continue
+		}
+		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, chunkName))
+	}
+}
+
+// deleteTokenCookies deletes the cookie carrying a token, including its
+// chunks, the copies issued with the attributes of the realms, and the copy
+// issued for the parent domain of the cross-domain single sign-on.
+func (p *Portal) deleteTokenCookies(w http.ResponseWriter, r *http.Request, h, name string) {
+	names := append([]string{name}, cookie.GetChunkNames(r.Cookies(), name, 1)...)
+	for _, k := range names {
+		for _, c := range p.cookie.GetDeleteCookies(h, k) {
+			w.Header().Add("Set-Cookie", c)
+		}
+		if p.config.CrossDomainConfig != nil {
+			w.Header().Add("Set-Cookie", p.cookie.GetDomainDeleteCookie(h, p.config.CrossDomainConfig.Domain, k))
+		}
+	}
+}
@@ -17,10 +17,21 @@ package cookie
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+const (
+	// DefaultChunkSize is the maximum size of the value of a cookie before
+	// it is split into chunks. It leaves room for the cookie attributes
+	// within the 4KB limit of most browsers.
+	DefaultChunkSize = 3800
+	// MaxChunks is the maximum number of chunks of a cookie.
+	MaxChunks = 10
+)
+
 // Config represents a common set of configuration settings
 // applicable to the cookies issued by authn.Authenticator.
 type Config struct {
@@ -30,6 +41,28 @@ type Config struct {
 	Insecure           bool                     `json:"insecure,omitempty" xml:"insecure,omitempty" yaml:"insecure,omitempty"`
 	SameSite           string                   `json:"same_site,omitempty" xml:"same_site,omitempty" yaml:"same_site,omitempty"`
 	StripDomainEnabled bool                     `json:"strip_domain_enabled,omitempty" xml:"strip_domain_enabled,omitempty" yaml:"strip_domain_enabled,omitempty"`
+	// Partitioned adds the Partitioned attribute (CHIPS) to secure cookies.
+	Partitioned bool `json:"partitioned,omitempty" xml:"partitioned,omitempty" yaml:"partitioned,omitempty"`
+	// Realms holds the cookie attributes overriding the above for the
+	// cookies issued to the users of a realm.
+	Realms map[string]*RealmConfig `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+	// ChunkSize is the maximum size of the value of a cookie above which
+	// the value is split across multiple cookies, i.e. name, name_1, etc.
+	ChunkSize int `json:"chunk_size,omitempty" xml:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`
+	// ChunkingDisabled disables the splitting of large cookies.
+	ChunkingDisabled bool `json:"chunking_disabled,omitempty" xml:"chunking_disabled,omitempty" yaml:"chunking_disabled,omitempty"`
+}
+
+// RealmConfig represents the cookie attributes applicable to the cookies
+// issued to the users of a realm.
+type RealmConfig struct {
+	Realm       string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Domain      string `json:"domain,omitempty" xml:"domain,omitempty" yaml:"domain,omitempty"`
+	Path        string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	Lifetime    int    `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+	Insecure    bool   `json:"insecure,omitempty" xml:"insecure,omitempty" yaml:"insecure,omitempty"`
+	SameSite    string `json:"same_site,omitempty" xml:"same_site,omitempty" yaml:"same_site,omitempty"`
+	Partitioned bool   `json:"partitioned,omitempty" xml:"partitioned,omitempty" yaml:"partitioned,omitempty"`
 }
 
 // DomainConfig represents a common set of configuration settings
@@ -42,6 +75,7 @@ type DomainConfig struct {
 	Insecure           bool   `json:"insecure,omitempty" xml:"insecure,omitempty" yaml:"insecure,omitempty"`
 	SameSite           string `json:"same_site,omitempty" xml:"same_site,omitempty" yaml:"same_site,omitempty"`
 	StripDomainEnabled bool   `json:"strip_domain_enabled,omitempty" xml:"strip_domain_enabled,omitempty" yaml:"strip_domain_enabled,omitempty"`
+	Partitioned        bool   `json:"partitioned,omitempty" xml:"partitioned,omitempty" yaml:"partitioned,omitempty"`
 }
 
 // Factory holds configuration and associated finctions
@@ -52,6 +86,18 @@ type Factory struct {
 	Referer   string `json:"referer,omitempty" xml:"referer,omitempty" yaml:"referer,omitempty"`
 	SessionID string `json:"session_id,omitempty" xml:"session_id,omitempty" yaml:"session_id,omitempty"`
 	SandboxID string `json:"sandbox_id,omitempty" xml:"sandbox_id,omitempty" yaml:"sandbox_id,omitempty"`
+	// Continuation is the name of the cookie carrying the state of
+	// multi-step login flows.
+	Continuation string `json:"continuation,omitempty" xml:"continuation,omitempty" yaml:"continuation,omitempty"`
+	// IdentityProviderHint is the name of the cookie remembering the
+	// identity provider a user last authenticated with.
+	IdentityProviderHint string `json:"identity_provider_hint,omitempty" xml:"identity_provider_hint,omitempty" yaml:"identity_provider_hint,omitempty"`
+	// SessionRenewal is the name of the cookie carrying the token renewing
+	// the access tokens of a sliding session.
+	SessionRenewal string `json:"session_renewal,omitempty" xml:"session_renewal,omitempty" yaml:"session_renewal,omitempty"`
+	// IdentityLink is the name of the cookie carrying the identifier of
+	// the pending link of an identity provider to a local user account.
+	IdentityLink string `json:"identity_link,omitempty" xml:"identity_link,omitempty" yaml:"identity_link,omitempty"`
 }
 
 // NewFactory returns an instance of cookie factory.
@@ -79,28 +125,82 @@ func NewFactory(c *Config) (*Factory, error) {
 	f.Referer = "AUTHP_REDIRECT_URL"
 	f.SessionID = "AUTHP_SESSION_ID"
 	f.SandboxID = "AUTHP_SANDBOX_ID"
-	switch strings.ToLower(f.config.SameSite) {
+	f.Continuation = "AUTHP_CONTINUATION"
+	f.IdentityProviderHint = "AUTHP_IDP_HINT"
+	f.SessionRenewal = "AUTHP_SESSION_RENEWAL"
+	f.IdentityLink = "AUTHP_IDENTITY_LINK"
+	sameSite, err := parseSameSite(f.config.SameSite)
+	if err != nil {
+		return nil, err
+	}
+	f.config.SameSite = sameSite
+
+	for k, rc := range f.config.Realms {
+		if rc == nil {
+			return nil, fmt.Errorf("the cookie config for realm %q is empty", k)
+		}
+		sameSite, err := parseSameSite(rc.SameSite)
+		if err != nil {
+			return nil, err
+		}
+		rc.SameSite = sameSite
+	}
+
+	if f.config.ChunkSize < 0 {
+		return nil, fmt.Errorf("the cookie chunk size %d is invalid", f.config.ChunkSize)
+	}
+
+	return f, nil
+}
+
+func parseSameSite(s string) (string, error) {
+	switch strings.ToLower(s) {
 	case "":
 	case "lax", "strict", "none":
-		f.config.SameSite = strings.Title(f.config.SameSite)
+		return strings.Title(strings.ToLower(s)), nil
 	default:
-		return nil, fmt.Errorf("the SameSite cookie attribute %q is invalid", f.config.SameSite)
+		return "", fmt.Errorf("the SameSite cookie attribute %q is invalid", s)
 	}
-
-	return f, nil
+	return s, nil
 }
 
 // GetCookie returns raw cookie string from key-value input.
 func (f *Factory) GetCookie(h, k, v string) string {
+	return f.GetRealmCookie(h, "", k, v)
+}
+
+// GetRealmCookie returns raw cookie string from key-value input. The
+// attributes configured for the realm take precedence.
+func (f *Factory) GetRealmCookie(h, realm, k, v string) string {
+	return f.getCookie(h, realm, "", k, v, 0)
+}
+
+// GetPersistentCookie returns raw cookie string from key-value input. The
+// cookie expires after the lifetime in seconds regardless of the configured
+// lifetime.
+func (f *Factory) GetPersistentCookie(h, k, v string, lifetime int) string {
+	return f.getCookie(h, "", "", k, v, lifetime)
+}
+
+func (f *Factory) getCookie(h, realm, domain, k, v string, lifetime int) string {
 	var sb strings.Builder
 	sb.WriteString(k + "=" + v + ";")
 
 	entry := f.evalHost(h)
-	if entry != nil && entry.Domain != "" {
+	rc := f.config.Realms[realm]
+
+	switch {
+	case domain != "":
+		sb.WriteString(fmt.Sprintf(" Domain=%s;", domain))
+	case rc != nil && rc.Domain != "":
+		sb.WriteString(fmt.Sprintf(" Domain=%s;", rc.Domain))
+	case entry != nil && entry.Domain != "":
 		sb.WriteString(fmt.Sprintf(" Domain=%s;", entry.Domain))
 	}
 
 	switch {
+	case rc != nil && rc.Path != "":
+		sb.WriteString(fmt.Sprintf(" Path=%s;", rc.Path))
 	case entry != nil && entry.Path != "":
 		sb.WriteString(fmt.Sprintf(" Path=%s;", entry.Path))
 	case f.config.Path != "":
@@ -110,6 +210,10 @@ func (f *Factory) GetCookie(h, k, v string) string {
 	}
 
 	switch {
+	case lifetime != 0:
+		sb.WriteString(fmt.Sprintf(" Max-Age=%d;", lifetime))
+	case rc != nil && rc.Lifetime != 0:
+		sb.WriteString(fmt.Sprintf(" Max-Age=%d;", rc.Lifetime))
 	case entry != nil && entry.Lifetime != 0:
 		sb.WriteString(fmt.Sprintf(" Max-Age=%d;", entry.Lifetime))
 	case f.config.Lifetime != 0:
@@ -117,22 +221,139 @@ func (f *Factory) GetCookie(h, k, v string) string {
 	}
 
 	switch {
+	case rc != nil && rc.SameSite != "":
+		sb.WriteString(fmt.Sprintf(" SameSite=%s;", rc.SameSite))
 	case entry != nil && entry.SameSite != "":
 		sb.WriteString(fmt.Sprintf(" SameSite=%s;", entry.SameSite))
 	case f.config.SameSite != "":
 		sb.WriteString(fmt.Sprintf(" SameSite=%s;", f.config.SameSite))
 	}
 
+	var secure bool
 	switch {
+	case rc != nil && rc.Insecure:
 	case entry != nil && !entry.Insecure:
-		sb.WriteString(" Secure; HttpOnly;")
+		secure = true
 	case !f.config.Insecure:
+		secure = true
+	}
+
+	if secure {
 		sb.WriteString(" Secure; HttpOnly;")
+		// The Partitioned attribute is only valid for secure cookies.
+		switch {
+		case rc != nil && rc.Partitioned:
+			sb.WriteString(" Partitioned;")
+		case entry != nil && entry.Partitioned:
+			sb.WriteString(" Partitioned;")
+		case f.config.Partitioned:
+			sb.WriteString(" Partitioned;")
+		}
 	}
 
 	return sb.String()
 }
 
+// GetRealmCookies returns raw cookie strings from key-value input. When
+// the value exceeds the chunk size, it is split across the cookies named
+// k, k_1, k_2, etc. See JoinChunks.
+func (f *Factory) GetRealmCookies(h, realm, k, v string) []string {
+	chunks := f.splitValue(v)
+	cookies := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		cookies[i] = f.GetRealmCookie(h, realm, GetChunkName(k, i), chunk)
+	}
+	return cookies
+}
+
+// GetDomainCookies returns raw cookie strings from key-value input, like
+// GetRealmCookies. The cookies are issued for the domain regardless of the
+// host, e.g. for the parent domain shared by the subdomains.
+func (f *Factory) GetDomainCookies(h, domain, k, v string) []string {
+	chunks := f.splitValue(v)
+	cookies := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		cookies[i] = f.getCookie(h, "", domain, GetChunkName(k, i), chunk, 0)
+	}
+	return cookies
+}
+
+func (f *Factory) splitValue(v string) []string {
+	size := f.config.ChunkSize
+	if size == 0 {
+		size = DefaultChunkSize
+	}
+	if f.config.ChunkingDisabled || len(v) <= size {
+		return []string{v}
+	}
+	var chunks []string
+	for len(v) > size && len(chunks) < MaxChunks-1 {
+		chunks = append(chunks, v[:size])
+		v = v[size:]
+	}
+	return append(chunks, v)
+}
+
+// GetChunkName returns the name of the i-th chunk of the cookie k. The first
+// chunk keeps the name of the cookie.
+func GetChunkName(k string, i int) string {
+	if i == 0 {
+		return k
+	}
+	return k + "_" + strconv.Itoa(i)
+}
+
+// ParseChunkName returns the name of the cookie the chunk belongs to and the
+// index of the chunk.
+func ParseChunkName(s string) (string, int) {
+	i := strings.LastIndexByte(s, '_')
+	if i < 1 {
+		return s, 0
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil || n < 1 || n >= MaxChunks {
+		return s, 0
+	}
+	return s[:i], n
+}
+
+// JoinChunks returns the value of the cookie k reassembled from its chunks.
+func JoinChunks(cookies []*http.Cookie, k string) (string, bool) {
+	chunks := make(map[string]string)
+	for _, c := range cookies {
+		if _, exists := chunks[c.Name]; !exists {
+			chunks[c.Name] = c.Value
+		}
+	}
+	v, exists := chunks[k]
+	if !exists {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString(v)
+	for i := 1; i < MaxChunks; i++ {
+		chunk, exists := chunks[GetChunkName(k, i)]
+		if !exists {
+			break
+		}
+		sb.WriteString(chunk)
+	}
+	return sb.String(), true
+}
+
+// GetChunkNames returns the names of the chunks of the cookie k, other than
+// the first one, found in the cookies starting with the chunk n.
+func GetChunkNames(cookies []*http.Cookie, k string, n int) []string {
+	var names []string
+	for _, c := range cookies {
+		name, i := ParseChunkName(c.Name)
+		if name == k && i >= n && i > 0 {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
 // GetIdentityTokenCookie returns raw identity token cookie string from key-value input.
 func (f *Factory) GetIdentityTokenCookie(k, v string) string {
 	var sb strings.Builder
@@ -173,11 +394,24 @@ func (f *Factory) GetSessionCookie(h, s string) string {
 
 // GetDeleteCookie returns raw cookie with attributes for delete action.
 func (f *Factory) GetDeleteCookie(h, s string) string {
+	return f.getDeleteCookie(h, "", s)
+}
+
+// GetDomainDeleteCookie returns raw cookie with attributes for delete action
+// for the cookie issued for the domain. See GetDomainCookies.
+func (f *Factory) GetDomainDeleteCookie(h, domain, s string) string {
+	return f.getDeleteCookie(h, domain, s)
+}
+
+func (f *Factory) getDeleteCookie(h, domain, s string) string {
 	var sb strings.Builder
 	sb.WriteString(s)
 	sb.WriteString("=delete;")
 	entry := f.evalHost(h)
-	if entry != nil && entry.Domain != "" {
+	switch {
+	case domain != "":
+		sb.WriteString(fmt.Sprintf(" Domain=%s;", domain))
+	case entry != nil && entry.Domain != "":
 		sb.WriteString(fmt.Sprintf(" Domain=%s;", entry.Domain))
 	}
 
@@ -194,6 +428,52 @@ func (f *Factory) GetDeleteCookie(h, s string) string {
 	return sb.String()
 }
 
+// GetDeleteCookies returns raw cookies with attributes for delete action.
+// In addition to the default domain and path, the cookie is deleted for
+// the domains and paths overridden by the realms.
+func (f *Factory) GetDeleteCookies(h, s string) []string {
+	cookies := []string{f.GetDeleteCookie(h, s)}
+	if len(f.config.Realms) == 0 {
+		return cookies
+	}
+	entry := f.evalHost(h)
+	seen := map[string]bool{cookies[0]: true}
+	realms := make([]string, 0, len(f.config.Realms))
+	for k := range f.config.Realms {
+		realms = append(realms, k)
+	}
+	sort.Strings(realms)
+	for _, k := range realms {
+		rc := f.config.Realms[k]
+		var sb strings.Builder
+		sb.WriteString(s)
+		sb.WriteString("=delete;")
+		switch {
+		case rc.Domain != "":
+			sb.WriteString(fmt.Sprintf(" Domain=%s;", rc.Domain))
+		case entry != nil && entry.Domain != "":
+			sb.WriteString(fmt.Sprintf(" Domain=%s;", entry.Domain))
+		}
+		switch {
+		case rc.Path != "":
+			sb.WriteString(fmt.Sprintf(" Path=%s;", rc.Path))
+		case entry != nil && entry.Path != "":
+			sb.WriteString(fmt.Sprintf(" Path=%s;", entry.Path))
+		case f.config.Path != "":
+			sb.WriteString(fmt.Sprintf(" Path=%s;", f.config.Path))
+		default:
+			sb.WriteString(" Path=/;")
+		}
+		sb.WriteString(" Expires=Thu, 01 Jan 1970 00:00:00 GMT;")
+		if seen[sb.String()] {
+			continue
+		}
+		seen[sb.String()] = true
+		cookies = append(cookies, sb.String())
+	}
+	return cookies
+}
+
 // GetDeleteSessionCookie returns raw cookie with attributes for delete action
 // for session id cookie.
 func (f *Factory) GetDeleteSessionCookie(h string) string {
@@ -272,5 +552,6 @@ func (f *Factory) evalHost(h string) *DomainConfig {
 	c.Lifetime = f.config.Lifetime
 	c.Insecure = f.config.Insecure
 	c.SameSite = f.config.SameSite
+	c.Partitioned = f.config.Partitioned
 	return c
 }
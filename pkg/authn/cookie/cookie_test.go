@@ -17,6 +17,7 @@ package cookie
 import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/internal/tests"
+	"net/http"
 	"testing"
 )
 
@@ -239,3 +240,313 @@ func TestFactory(t *testing.T) {
 		})
 	}
 }
+
+func TestFactoryRealmCookies(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		host   string
+		realm  string
+		value  string
+		config *Config
+		// Expected results.
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "partitioned cookie",
+			host:  "localhost",
+			value: "foobar",
+			config: &Config{
+				SameSite:    "none",
+				Partitioned: true,
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=foobar; Path=/; SameSite=None; Secure; HttpOnly; Partitioned;",
+				},
+				"delete": []string{
+					"access_token=delete; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name:  "insecure cookie is not partitioned",
+			host:  "localhost",
+			value: "foobar",
+			config: &Config{
+				Insecure:    true,
+				Partitioned: true,
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=foobar; Path=/;",
+				},
+				"delete": []string{
+					"access_token=delete; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name:  "realm overrides cookie attributes",
+			host:  "auth.contoso.com",
+			realm: "github",
+			value: "foobar",
+			config: &Config{
+				Lifetime: 900,
+				SameSite: "strict",
+				Realms: map[string]*RealmConfig{
+					"github": {
+						Realm:    "github",
+						Domain:   "apps.contoso.com",
+						Path:     "/apps",
+						Lifetime: 3600,
+						SameSite: "LAX",
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=foobar; Domain=apps.contoso.com; Path=/apps; Max-Age=3600; SameSite=Lax; Secure; HttpOnly;",
+				},
+				"delete": []string{
+					"access_token=delete; Domain=contoso.com; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+					"access_token=delete; Domain=apps.contoso.com; Path=/apps; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name:  "cookie of other realm uses default attributes",
+			host:  "auth.contoso.com",
+			realm: "local",
+			value: "foobar",
+			config: &Config{
+				Realms: map[string]*RealmConfig{
+					"github": {
+						Realm:    "github",
+						Insecure: true,
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=foobar; Domain=contoso.com; Path=/; Secure; HttpOnly;",
+				},
+				"delete": []string{
+					"access_token=delete; Domain=contoso.com; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name:  "large cookie split into chunks",
+			host:  "localhost",
+			value: "foobarbazqux",
+			config: &Config{
+				ChunkSize: 5,
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=fooba; Path=/; Secure; HttpOnly;",
+					"access_token_1=rbazq; Path=/; Secure; HttpOnly;",
+					"access_token_2=ux; Path=/; Secure; HttpOnly;",
+				},
+				"delete": []string{
+					"access_token=delete; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name:  "large cookie with chunking disabled",
+			host:  "localhost",
+			value: "foobarbazqux",
+			config: &Config{
+				ChunkSize:        5,
+				ChunkingDisabled: true,
+			},
+			want: map[string]interface{}{
+				"grant": []string{
+					"access_token=foobarbazqux; Path=/; Secure; HttpOnly;",
+				},
+				"delete": []string{
+					"access_token=delete; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+				},
+			},
+		},
+		{
+			name: "invalid realm same site attribute",
+			config: &Config{
+				Realms: map[string]*RealmConfig{
+					"github": {
+						SameSite: "foo",
+					},
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("the SameSite cookie attribute %q is invalid", "foo"),
+		},
+		{
+			name: "invalid chunk size",
+			config: &Config{
+				ChunkSize: -1,
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("the cookie chunk size %d is invalid", -1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cf, err := NewFactory(tc.config)
+			if tests.EvalErrWithLog(t, err, "cookie", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := make(map[string]interface{})
+			got["grant"] = cf.GetRealmCookies(tc.host, tc.realm, "access_token", tc.value)
+			got["delete"] = cf.GetDeleteCookies(tc.host, "access_token")
+			tests.EvalObjectsWithLog(t, "cookie", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestCookieChunks(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		cookies []*http.Cookie
+		// Expected results.
+		want map[string]interface{}
+	}{
+		{
+			name: "single cookie",
+			cookies: []*http.Cookie{
+				{Name: "access_token", Value: "foobar"},
+			},
+			want: map[string]interface{}{
+				"value":  "foobar",
+				"found":  true,
+				"chunks": []string(nil),
+			},
+		},
+		{
+			name: "chunked cookie",
+			cookies: []*http.Cookie{
+				{Name: "access_token_2", Value: "ux"},
+				{Name: "access_token", Value: "fooba"},
+				{Name: "access_token_1", Value: "rbazq"},
+				{Name: "refresh_token_1", Value: "foo"},
+			},
+			want: map[string]interface{}{
+				"value":  "foobarbazqux",
+				"found":  true,
+				"chunks": []string{"access_token_2", "access_token_1"},
+			},
+		},
+		{
+			name: "chunk sequence gap",
+			cookies: []*http.Cookie{
+				{Name: "access_token", Value: "fooba"},
+				{Name: "access_token_2", Value: "ux"},
+			},
+			want: map[string]interface{}{
+				"value":  "fooba",
+				"found":  true,
+				"chunks": []string{"access_token_2"},
+			},
+		},
+		{
+			name: "cookie not found",
+			cookies: []*http.Cookie{
+				{Name: "access_token_1", Value: "rbazq"},
+			},
+			want: map[string]interface{}{
+				"value":  "",
+				"found":  false,
+				"chunks": []string{"access_token_1"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got := make(map[string]interface{})
+			got["value"], got["found"] = JoinChunks(tc.cookies, "access_token")
+			got["chunks"] = GetChunkNames(tc.cookies, "access_token", 1)
+			tests.EvalObjectsWithLog(t, "cookie", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestFactoryPersistentCookie(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		config *Config
+		want   string
+	}{
+		{
+			name: "default config",
+			want: "AUTHP_IDP_HINT=foobar; Path=/; Max-Age=3600; Secure; HttpOnly;",
+		},
+		{
+			name: "lifetime overrides configured lifetime",
+			config: &Config{
+				Lifetime: 900,
+				SameSite: "lax",
+			},
+			want: "AUTHP_IDP_HINT=foobar; Path=/; Max-Age=3600; SameSite=Lax; Secure; HttpOnly;",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cf, err := NewFactory(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := cf.GetPersistentCookie("", cf.IdentityProviderHint, "foobar", 3600)
+			tests.EvalObjectsWithLog(t, "cookie", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestFactoryDomainCookies(t *testing.T) {
+	var testcases = []struct {
+		name       string
+		config     *Config
+		host       string
+		want       []string
+		wantDelete string
+	}{
+		{
+			name: "parent domain overrides host domain",
+			host: "auth.eu.example.com",
+			want: []string{
+				"access_token=foobar; Domain=example.com; Path=/; Secure; HttpOnly;",
+			},
+			wantDelete: "access_token=delete; Domain=example.com; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+		},
+		{
+			name: "parent domain overrides configured domain",
+			config: &Config{
+				Domains: map[string]*DomainConfig{
+					"auth.example.com": {Domain: "auth.example.com", SameSite: "Lax"},
+				},
+			},
+			host: "auth.example.com",
+			want: []string{
+				"access_token=foobar; Domain=example.com; Path=/; SameSite=Lax; Secure; HttpOnly;",
+			},
+			wantDelete: "access_token=delete; Domain=example.com; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT;",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cf, err := NewFactory(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := cf.GetDomainCookies(tc.host, "example.com", "access_token", "foobar")
+			tests.EvalObjectsWithLog(t, "cookies", tc.want, got, msgs)
+			tests.EvalObjectsWithLog(t, "delete cookie", tc.wantDelete, cf.GetDomainDeleteCookie(tc.host, "example.com", "access_token"), msgs)
+		})
+	}
+}
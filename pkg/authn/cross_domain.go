@@ -0,0 +1,29 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// setTokenAudience restricts the token of the user to the subdomains the
+// user is allowed to access when the single sign-on across the subdomains
+// of a parent domain is enabled.
+func (p *Portal) setTokenAudience(usr *user.User) {
+	if p.config.CrossDomainConfig == nil {
+		return
+	}
+	usr.SetAudienceClaim(p.config.CrossDomainConfig.GetAudiences(usr.Claims.Roles))
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/crossdomain"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestCrossDomainSingleSignOn(t *testing.T) {
+	testcases := []struct {
+		name      string
+		keys      string
+		roles     []string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "user allowed to access one subdomain",
+			keys: "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+			roles: []string{"authp/user"},
+			want: map[string]interface{}{
+				"domain":    "example.com",
+				"audiences": []string{"app1.example.com"},
+			},
+		},
+		{
+			name: "admin allowed to access all subdomains",
+			keys: "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+			roles: []string{"authp/admin"},
+			want: map[string]interface{}{
+				"domain":    "example.com",
+				"audiences": []string{"app1.example.com", "app2.example.com"},
+			},
+		},
+		{
+			name:      "tokens without encryption",
+			keys:      "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286",
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyStoreConfig.WithArgs("myportal", errors.ErrCrossDomainTokenEncryption),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			keys, err := kms.ParseCryptoKeyConfigs(tc.keys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p, err := newTestMultiRealmPortal(t, &PortalConfig{
+				CryptoKeyConfigs: keys,
+				CrossDomainConfig: &crossdomain.Config{
+					Domain: "example.com",
+					Audiences: []*crossdomain.Audience{
+						{Host: "app1.example.com"},
+						{Host: "app2.example.com", Roles: []string{"authp/admin"}},
+					},
+				},
+			})
+			if tests.EvalErrWithLog(t, err, "portal", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			sessionID := util.GetRandomStringFromRange(36, 46)
+			usr, err := user.NewUser(map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": tc.roles,
+				"jti":   sessionID,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "https://auth.eu.example.com/login", nil)
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = sessionID
+			rr.Upstream.Realm = "local"
+			p.grantAccess(context.Background(), w, r, rr, usr)
+
+			c, found := getTestResponseCookies(w)[usr.TokenName]
+			if !found {
+				t.Fatalf("token cookie not found")
+			}
+
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = usr.TokenName
+			ar.Token.Payload = c.Value
+			parsedUser, err := p.keystore.ParseToken(ar)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]interface{}{
+				"domain":    c.Domain,
+				"audiences": parsedUser.Claims.Audience,
+			}
+			tests.EvalObjectsWithLog(t, "cross-domain token", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crossdomain
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config holds the settings of the single sign-on across the sibling
+// subdomains of a parent domain, e.g. app1.example.com and app2.example.com.
+// The token cookies are issued for the parent domain, the tokens are
+// encrypted, and the aud claim of the tokens lists the subdomains the users
+// are allowed to access. The authorizers with the audience validation
+// enabled reject the tokens whose audiences do not include the requested
+// host.
+type Config struct {
+	// Domain is the parent domain, e.g. example.com.
+	Domain    string      `json:"domain,omitempty" xml:"domain,omitempty" yaml:"domain,omitempty"`
+	Audiences []*Audience `json:"audiences,omitempty" xml:"audiences,omitempty" yaml:"audiences,omitempty"`
+}
+
+// Audience restricts the access to a subdomain to the users with any of the
+// roles.
+type Audience struct {
+	// Host is the subdomain, e.g. app1.example.com.
+	Host string `json:"host,omitempty" xml:"host,omitempty" yaml:"host,omitempty"`
+	// Roles are the roles allowed to access the host. When empty, the host
+	// is accessible to all users.
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	cfg.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(cfg.Domain)), ".")
+	if cfg.Domain == "" {
+		return errors.ErrCrossDomainConfigDomainEmpty
+	}
+	if !strings.Contains(cfg.Domain, ".") || strings.ContainsAny(cfg.Domain, ":/* ") {
+		return errors.ErrCrossDomainConfigDomain.WithArgs(cfg.Domain)
+	}
+	if len(cfg.Audiences) < 1 {
+		return errors.ErrCrossDomainConfigAudiencesEmpty
+	}
+	for i, audience := range cfg.Audiences {
+		host := strings.ToLower(strings.TrimSpace(audience.Host))
+		if host != cfg.Domain && !strings.HasSuffix(host, "."+cfg.Domain) {
+			return errors.ErrCrossDomainConfigAudienceHost.WithArgs(i, audience.Host, cfg.Domain)
+		}
+		audience.Host = host
+	}
+	return nil
+}
+
+// GetAudiences returns the hosts the users with the roles are allowed to
+// access.
+func (cfg *Config) GetAudiences(roles []string) []string {
+	var audiences []string
+	for _, audience := range cfg.Audiences {
+		if len(audience.Roles) > 0 && !hasAnyRole(roles, audience.Roles) {
+			continue
+		}
+		audiences = append(audiences, audience.Host)
+	}
+	return audiences
+}
+
+func hasAnyRole(roles, allowed []string) bool {
+	for _, role := range roles {
+		for _, r := range allowed {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crossdomain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Domain: ".Example.com",
+				Audiences: []*Audience{
+					{Host: "App1.example.com"},
+					{Host: "app2.example.com", Roles: []string{"authp/admin"}},
+				},
+			},
+			want: &Config{
+				Domain: "example.com",
+				Audiences: []*Audience{
+					{Host: "app1.example.com"},
+					{Host: "app2.example.com", Roles: []string{"authp/admin"}},
+				},
+			},
+		},
+		{
+			name:      "empty domain",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrCrossDomainConfigDomainEmpty,
+		},
+		{
+			name:      "top level domain",
+			config:    &Config{Domain: "com"},
+			shouldErr: true,
+			err:       errors.ErrCrossDomainConfigDomain.WithArgs("com"),
+		},
+		{
+			name:      "no audiences",
+			config:    &Config{Domain: "example.com"},
+			shouldErr: true,
+			err:       errors.ErrCrossDomainConfigAudiencesEmpty,
+		},
+		{
+			name: "audience outside of parent domain",
+			config: &Config{
+				Domain:    "example.com",
+				Audiences: []*Audience{{Host: "app1.example.com"}, {Host: "app1.badexample.com"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrCrossDomainConfigAudienceHost.WithArgs(1, "app1.badexample.com", "example.com"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "config", tc.want, tc.config, msgs)
+		})
+	}
+}
+
+func TestGetAudiences(t *testing.T) {
+	cfg := &Config{
+		Domain: "example.com",
+		Audiences: []*Audience{
+			{Host: "app1.example.com"},
+			{Host: "app2.example.com", Roles: []string{"authp/admin", "authp/editor"}},
+		},
+	}
+	testcases := []struct {
+		name  string
+		roles []string
+		want  []string
+	}{
+		{name: "user", roles: []string{"authp/user"}, want: []string{"app1.example.com"}},
+		{name: "editor", roles: []string{"authp/user", "authp/editor"}, want: []string{"app1.example.com", "app2.example.com"}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			tests.EvalObjectsWithLog(t, "audiences", tc.want, cfg.GetAudiences(tc.roles), msgs)
+		})
+	}
+}
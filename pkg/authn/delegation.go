@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/registry"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// getAdminScope returns the scope of a delegated administrator. It returns
+// nil for the full administrators and for the users who are not delegated
+// administrators.
+func (p *Portal) getAdminScope(usr *user.User) *delegation.Scope {
+	if p.config.DelegationConfig == nil || usr.HasRole("authp/admin") {
+		return nil
+	}
+	return p.config.DelegationConfig.GetScope(usr.Claims.Roles, usr.Claims.Origin, usr.Claims.Organizations)
+}
+
+// filterScopedUsers returns the users within the scope of a delegated
+// administrator.
+func (p *Portal) filterScopedUsers(rr *requests.Request, store ids.IdentityStore, scope *delegation.Scope, users []*identity.UserMetadata) []*identity.UserMetadata {
+	if scope.IsRealmWide() {
+		return users
+	}
+	filtered := []*identity.UserMetadata{}
+	for _, u := range users {
+		req := &requests.Request{Context: rr.GetContext()}
+		req.User.Username = u.Username
+		if err := store.Request(operator.IdentifyUser, req); err != nil {
+			continue
+		}
+		if err := scope.PermitsUser(u.Username, req.User.Roles, req.User.Organizations); err != nil {
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
+
+// permitsScopedRegistration checks whether the registration is within the
+// scope of a delegated administrator. The realm of the registrations is
+// the one of the identity store the user registry imports the users to.
+func (p *Portal) permitsScopedRegistration(scope *delegation.Scope, entry *registry.RegistrationRecord) error {
+	realm := p.userRegistry.GetIdentityStoreName()
+	for _, store := range p.identityStores {
+		if store.GetName() == realm {
+			realm = store.GetRealm()
+			break
+		}
+	}
+	if err := scope.PermitsRealm(realm); err != nil {
+		return err
+	}
+	return scope.PermitsUser(entry.Username, entry.Roles, nil)
+}
+
+// permitsScopedRegistrationID checks whether the registration with the
+// provided id is within the scope of a delegated administrator.
+func (p *Portal) permitsScopedRegistrationID(scope *delegation.Scope, registrationID string) error {
+	entries, err := p.userRegistry.GetRegistrations()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.ID == registrationID {
+			return p.permitsScopedRegistration(scope, entry)
+		}
+	}
+	// The verdict on the unknown registration fails on its own.
+	return nil
+}
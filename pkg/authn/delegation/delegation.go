@@ -0,0 +1,260 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delegation
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// RealmScope permits the management of the users of the realm of the
+	// administrator.
+	RealmScope = "realm"
+	// OrgScope permits the management of the users holding the roles of the
+	// organizations of the administrator, e.g. acme/editor for acme.
+	OrgScope = "org"
+
+	fullAdminRole = "authp/admin"
+)
+
+// Config holds the configuration of the delegated administrators, i.e. the
+// users managing the users and the registrations within their own realm or
+// organization only.
+type Config struct {
+	Admins []*AdminConfig `json:"admins,omitempty" xml:"admins,omitempty" yaml:"admins,omitempty"`
+}
+
+// AdminConfig is the role of the delegated administrators.
+type AdminConfig struct {
+	// Role is the role of the delegated administrators, e.g. acme/admin.
+	Role string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	// Scope is either realm or org.
+	Scope string `json:"scope,omitempty" xml:"scope,omitempty" yaml:"scope,omitempty"`
+	// AssignableRoles are the roles outside of the organizations of the
+	// administrator the administrator may assign, e.g. authp/user.
+	AssignableRoles []string `json:"assignable_roles,omitempty" xml:"assignable_roles,omitempty" yaml:"assignable_roles,omitempty"`
+}
+
+// Scope is the scope of a delegated administrator.
+type Scope struct {
+	// Realm is the realm of the administrator.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// Organizations are the organizations of the administrator. When
+	// empty, the scope covers the entire realm.
+	Organizations []string `json:"organizations,omitempty" xml:"organizations,omitempty" yaml:"organizations,omitempty"`
+	// AssignableRoles are the roles outside of the organizations the
+	// administrator may assign.
+	AssignableRoles []string `json:"assignable_roles,omitempty" xml:"assignable_roles,omitempty" yaml:"assignable_roles,omitempty"`
+	reserved        map[string]bool
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	roles := make(map[string]bool)
+	for _, entry := range cfg.Admins {
+		if entry.Role == "" {
+			return errors.ErrDelegationConfigRole
+		}
+		if entry.Role == fullAdminRole {
+			return errors.ErrDelegationConfigRoleFull.WithArgs(entry.Role)
+		}
+		if roles[entry.Role] {
+			return errors.ErrDelegationConfigDuplicate.WithArgs(entry.Role)
+		}
+		roles[entry.Role] = true
+		switch entry.Scope {
+		case RealmScope, OrgScope:
+		default:
+			return errors.ErrDelegationConfigScope.WithArgs(entry.Role, entry.Scope)
+		}
+	}
+	return nil
+}
+
+// GetScope returns the scope of the administrator with the provided roles,
+// realm and organizations. It returns nil when the administrator holds none
+// of the delegated administrator roles. The realm scope takes precedence
+// over the organization scope. Without the organizations, the organizations
+// are the ones of the administrator roles, e.g. acme for acme/admin.
+func (cfg *Config) GetScope(roles []string, realm string, orgs []string) *Scope {
+	var scope *Scope
+	var realmWide bool
+	var roleOrgs []string
+	for _, entry := range cfg.Admins {
+		if !contains(roles, entry.Role) {
+			continue
+		}
+		if scope == nil {
+			scope = &Scope{
+				Realm:    realm,
+				reserved: map[string]bool{fullAdminRole: true},
+			}
+			for _, e := range cfg.Admins {
+				scope.reserved[e.Role] = true
+			}
+		}
+		if entry.Scope == RealmScope {
+			realmWide = true
+		}
+		if i := strings.Index(entry.Role, "/"); i > 0 {
+			roleOrgs = append(roleOrgs, entry.Role[:i])
+		}
+		scope.AssignableRoles = append(scope.AssignableRoles, entry.AssignableRoles...)
+	}
+	if scope == nil {
+		return nil
+	}
+	if !realmWide {
+		scope.Organizations = append([]string{}, orgs...)
+		if len(scope.Organizations) == 0 {
+			scope.Organizations = roleOrgs
+		}
+		if len(scope.Organizations) == 0 {
+			// The administrator without organizations manages nobody.
+			scope.Organizations = []string{""}
+		}
+	}
+	return scope
+}
+
+// IsRealmWide returns true when the scope covers the entire realm.
+func (s *Scope) IsRealmWide() bool {
+	return len(s.Organizations) == 0
+}
+
+// PermitsRealm returns an error when the realm is outside of the scope.
+func (s *Scope) PermitsRealm(realm string) error {
+	if realm != s.Realm {
+		return errors.ErrDelegationRealmNotPermitted.WithArgs(realm)
+	}
+	return nil
+}
+
+// PermitsUser returns an error when the user with the provided roles and
+// organizations is outside of the scope, i.e. the user neither belongs to
+// the organizations of the administrator nor holds their roles. The full
+// and the delegated administrators are always outside of the scope.
+func (s *Scope) PermitsUser(username string, roles, orgs []string) error {
+	for _, roleName := range roles {
+		if s.reserved[roleName] {
+			return errors.ErrDelegationUserNotPermitted.WithArgs(username)
+		}
+	}
+	if s.IsRealmWide() {
+		return nil
+	}
+	for _, org := range orgs {
+		if org != "" && contains(s.Organizations, org) {
+			return nil
+		}
+	}
+	for _, roleName := range roles {
+		if s.inOrganizations(roleName) {
+			return nil
+		}
+	}
+	return errors.ErrDelegationUserNotPermitted.WithArgs(username)
+}
+
+// PermitsOrganizations returns an error when any of the organizations is
+// outside of the scope.
+func (s *Scope) PermitsOrganizations(orgs []string) error {
+	if s.IsRealmWide() {
+		return nil
+	}
+	for _, org := range orgs {
+		if !contains(s.Organizations, org) {
+			return errors.ErrDelegationOrgNotPermitted.WithArgs(org)
+		}
+	}
+	return nil
+}
+
+// MergeOrganizations returns the requested organizations along with the
+// current organizations of a user outside of the scope.
+func (s *Scope) MergeOrganizations(current, requested []string) ([]string, error) {
+	if err := s.PermitsOrganizations(requested); err != nil {
+		return nil, err
+	}
+	orgs := append([]string{}, requested...)
+	if s.IsRealmWide() {
+		return orgs, nil
+	}
+	for _, org := range current {
+		if contains(s.Organizations, org) || contains(orgs, org) {
+			continue
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+// PermitsRoles returns an error when any of the roles is outside of the
+// scope. The full and the delegated administrator roles are never
+// assignable.
+func (s *Scope) PermitsRoles(roles []string) error {
+	for _, roleName := range roles {
+		if !s.permitsRole(roleName) {
+			return errors.ErrDelegationRoleNotPermitted.WithArgs(roleName)
+		}
+	}
+	return nil
+}
+
+// MergeRoles returns the requested roles along with the current roles of a
+// user outside of the scope, i.e. the roles the administrator may neither
+// assign nor remove.
+func (s *Scope) MergeRoles(current, requested []string) ([]string, error) {
+	if err := s.PermitsRoles(requested); err != nil {
+		return nil, err
+	}
+	roles := append([]string{}, requested...)
+	for _, roleName := range current {
+		if s.permitsRole(roleName) || contains(roles, roleName) {
+			continue
+		}
+		roles = append(roles, roleName)
+	}
+	return roles, nil
+}
+
+func (s *Scope) permitsRole(roleName string) bool {
+	if s.reserved[roleName] {
+		return false
+	}
+	if s.IsRealmWide() || contains(s.AssignableRoles, roleName) {
+		return true
+	}
+	return s.inOrganizations(roleName)
+}
+
+func (s *Scope) inOrganizations(roleName string) bool {
+	i := strings.Index(roleName, "/")
+	if i < 1 {
+		return false
+	}
+	return contains(s.Organizations, roleName[:i])
+}
+
+func contains(entries []string, s string) bool {
+	for _, entry := range entries {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
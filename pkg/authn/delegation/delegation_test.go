@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delegation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Admins: []*AdminConfig{
+					{Role: "authp/realm_admin", Scope: "realm"},
+					{Role: "acme/admin", Scope: "org", AssignableRoles: []string{"authp/user"}},
+				},
+			},
+		},
+		{
+			name:      "empty role",
+			config:    &Config{Admins: []*AdminConfig{{Scope: "realm"}}},
+			shouldErr: true,
+			err:       errors.ErrDelegationConfigRole,
+		},
+		{
+			name:      "full admin role",
+			config:    &Config{Admins: []*AdminConfig{{Role: "authp/admin", Scope: "realm"}}},
+			shouldErr: true,
+			err:       errors.ErrDelegationConfigRoleFull.WithArgs("authp/admin"),
+		},
+		{
+			name:      "unsupported scope",
+			config:    &Config{Admins: []*AdminConfig{{Role: "acme/admin", Scope: "team"}}},
+			shouldErr: true,
+			err:       errors.ErrDelegationConfigScope.WithArgs("acme/admin", "team"),
+		},
+		{
+			name: "duplicate role",
+			config: &Config{
+				Admins: []*AdminConfig{
+					{Role: "acme/admin", Scope: "org"},
+					{Role: "acme/admin", Scope: "realm"},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrDelegationConfigDuplicate.WithArgs("acme/admin"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	cfg := &Config{
+		Admins: []*AdminConfig{
+			{Role: "authp/realm_admin", Scope: "realm"},
+			{Role: "acme/admin", Scope: "org", AssignableRoles: []string{"authp/user"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if scope := cfg.GetScope([]string{"authp/user"}, "local", nil); scope != nil {
+		t.Fatalf("unexpected scope for non-admin: %v", scope)
+	}
+
+	// The realm administrator.
+	scope := cfg.GetScope([]string{"authp/realm_admin"}, "local", nil)
+	tests.EvalObjects(t, "realm wide", true, scope.IsRealmWide())
+	tests.EvalErrWithLog(t, scope.PermitsRealm("local"), "own realm", false, nil, nil)
+	tests.EvalErrWithLog(t, scope.PermitsRealm("contoso"), "other realm", true, errors.ErrDelegationRealmNotPermitted.WithArgs("contoso"), nil)
+	tests.EvalErrWithLog(t, scope.PermitsUser("jsmith", []string{"globex/user"}, nil), "any user", false, nil, nil)
+	tests.EvalErrWithLog(t, scope.PermitsUser("root", []string{"authp/admin"}, nil), "full admin", true, errors.ErrDelegationUserNotPermitted.WithArgs("root"), nil)
+	tests.EvalErrWithLog(t, scope.PermitsUser("bjones", []string{"acme/admin"}, nil), "delegated admin", true, errors.ErrDelegationUserNotPermitted.WithArgs("bjones"), nil)
+	tests.EvalErrWithLog(t, scope.PermitsRoles([]string{"authp/admin"}), "full admin role", true, errors.ErrDelegationRoleNotPermitted.WithArgs("authp/admin"), nil)
+	tests.EvalErrWithLog(t, scope.PermitsRoles([]string{"acme/admin"}), "delegated admin role", true, errors.ErrDelegationRoleNotPermitted.WithArgs("acme/admin"), nil)
+
+	// The organization administrator without the org claim.
+	scope = cfg.GetScope([]string{"acme/admin"}, "local", nil)
+	tests.EvalObjects(t, "organizations", []string{"acme"}, scope.Organizations)
+	tests.EvalErrWithLog(t, scope.PermitsUser("jsmith", []string{"authp/user", "acme/editor"}, nil), "org user", false, nil, nil)
+	tests.EvalErrWithLog(t, scope.PermitsUser("bsmith", []string{"authp/user", "globex/editor"}, nil), "other org user", true, errors.ErrDelegationUserNotPermitted.WithArgs("bsmith"), nil)
+	tests.EvalErrWithLog(t, scope.PermitsUser("asmith", []string{"authp/user"}, []string{"acme"}), "org member", false, nil, nil)
+	tests.EvalErrWithLog(t, scope.PermitsRoles([]string{"acme/viewer", "authp/user"}), "org and assignable roles", false, nil, nil)
+	tests.EvalErrWithLog(t, scope.PermitsRoles([]string{"globex/viewer"}), "other org role", true, errors.ErrDelegationRoleNotPermitted.WithArgs("globex/viewer"), nil)
+
+	roles, err := scope.MergeRoles([]string{"authp/user", "acme/editor", "globex/editor"}, []string{"acme/viewer"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "merged roles", []string{"acme/viewer", "globex/editor"}, roles)
+
+	tests.EvalErrWithLog(t, scope.PermitsOrganizations([]string{"globex"}), "other org", true, errors.ErrDelegationOrgNotPermitted.WithArgs("globex"), nil)
+	orgs, err := scope.MergeOrganizations([]string{"acme", "globex"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "merged organizations", []string{"globex"}, orgs)
+
+	// The organization administrator with the org claim.
+	scope = cfg.GetScope([]string{"acme/admin"}, "local", []string{"initech"})
+	tests.EvalObjects(t, "claimed organizations", []string{"initech"}, scope.Organizations)
+}
@@ -0,0 +1,287 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deletion
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultGracePeriod   = 2592000
+	defaultCodeLifetime  = 900
+	defaultMaxAttempts   = 5
+	defaultPurgeInterval = 3600
+	codeLength           = 6
+)
+
+// Config holds the configuration of the self-service account deletion.
+type Config struct {
+	// The email provider used to deliver confirmation codes and notices.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The number of seconds between the confirmation of the deletion and
+	// the deletion of the account. Signing in during the grace period
+	// reactivates the account. The default is 30 days.
+	GracePeriod int `json:"grace_period,omitempty" xml:"grace_period,omitempty" yaml:"grace_period,omitempty"`
+	// The number of seconds a confirmation code is valid for.
+	CodeLifetime int `json:"code_lifetime,omitempty" xml:"code_lifetime,omitempty" yaml:"code_lifetime,omitempty"`
+	// The maximum number of failed confirmation attempts before the pending
+	// request is discarded.
+	MaxAttempts int `json:"max_attempts,omitempty" xml:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// The number of seconds between the checks for the accounts whose
+	// deletion is due.
+	PurgeInterval int `json:"purge_interval,omitempty" xml:"purge_interval,omitempty" yaml:"purge_interval,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// PendingRequest is an account deletion request awaiting confirmation.
+type PendingRequest struct {
+	Realm     string    `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username  string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email     string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	code      string
+	attempts  int
+}
+
+// Manager tracks pending account deletion requests and their confirmation
+// codes, and periodically triggers the purge of the accounts whose deletion
+// is due.
+type Manager struct {
+	mu          sync.Mutex
+	config      *Config
+	gracePeriod time.Duration
+	lifetime    time.Duration
+	max         int
+	interval    time.Duration
+	pending     map[string]*PendingRequest
+	exit        chan bool
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrAccountDeletionConfigEmailProvider
+	}
+	if cfg.GracePeriod < 0 {
+		return errors.ErrAccountDeletionConfigGracePeriod.WithArgs(cfg.GracePeriod)
+	}
+	if cfg.CodeLifetime < 0 {
+		return errors.ErrAccountDeletionConfigCodeLifetime.WithArgs(cfg.CodeLifetime)
+	}
+	if cfg.MaxAttempts < 0 {
+		return errors.ErrAccountDeletionConfigMaxAttempts.WithArgs(cfg.MaxAttempts)
+	}
+	if cfg.PurgeInterval < 0 {
+		return errors.ErrAccountDeletionConfigPurgeInterval.WithArgs(cfg.PurgeInterval)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of confirmation codes.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager. The pending requests are held
+// in memory, i.e. they are discarded after a restart. The confirmed
+// deletions are recorded in the identity stores.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config:      cfg,
+		gracePeriod: time.Duration(defaultGracePeriod) * time.Second,
+		lifetime:    time.Duration(defaultCodeLifetime) * time.Second,
+		max:         defaultMaxAttempts,
+		interval:    time.Duration(defaultPurgeInterval) * time.Second,
+		pending:     make(map[string]*PendingRequest),
+	}
+	if cfg.GracePeriod > 0 {
+		m.gracePeriod = time.Duration(cfg.GracePeriod) * time.Second
+	}
+	if cfg.CodeLifetime > 0 {
+		m.lifetime = time.Duration(cfg.CodeLifetime) * time.Second
+	}
+	if cfg.MaxAttempts > 0 {
+		m.max = cfg.MaxAttempts
+	}
+	if cfg.PurgeInterval > 0 {
+		m.interval = time.Duration(cfg.PurgeInterval) * time.Second
+	}
+	return m, nil
+}
+
+// GetGracePeriod returns the period between the confirmation of the
+// deletion and the deletion of the account.
+func (m *Manager) GetGracePeriod() time.Duration {
+	return m.gracePeriod
+}
+
+// GetCodeLifetime returns the lifetime of confirmation codes.
+func (m *Manager) GetCodeLifetime() time.Duration {
+	return m.lifetime
+}
+
+// Request starts an account deletion for a user and returns the
+// confirmation code to be sent to the email address of the user. A
+// subsequent request for the same user replaces the prior one.
+func (m *Manager) Request(realm, username, email string) (*PendingRequest, string, error) {
+	code, err := generateCode()
+	if err != nil {
+		return nil, "", err
+	}
+	req := &PendingRequest{
+		Realm:     realm,
+		Username:  username,
+		Email:     email,
+		ExpiresAt: time.Now().Add(m.lifetime).UTC(),
+		code:      code,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	m.pending[getKey(realm, username)] = req
+	return req, code, nil
+}
+
+// Get returns the pending account deletion request of a user.
+func (m *Manager) Get(realm, username string) (*PendingRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	req, exists := m.pending[getKey(realm, username)]
+	if !exists {
+		return nil, errors.ErrAccountDeletionNotFound
+	}
+	return req, nil
+}
+
+// Confirm checks the confirmation code of the pending account deletion
+// request of a user. On success, the pending request is removed and
+// returned.
+func (m *Manager) Confirm(realm, username, code string) (*PendingRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := getKey(realm, username)
+	req, exists := m.pending[k]
+	if !exists {
+		return nil, errors.ErrAccountDeletionNotFound
+	}
+	if time.Now().After(req.ExpiresAt) {
+		delete(m.pending, k)
+		return nil, errors.ErrAccountDeletionCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(req.code), []byte(strings.TrimSpace(code))) != 1 {
+		req.attempts++
+		if req.attempts >= m.max {
+			delete(m.pending, k)
+			return nil, errors.ErrAccountDeletionCodeAttempts
+		}
+		return nil, errors.ErrAccountDeletionCodeMismatch
+	}
+	delete(m.pending, k)
+	return req, nil
+}
+
+// Cancel discards the pending account deletion request of a user.
+func (m *Manager) Cancel(realm, username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, getKey(realm, username))
+}
+
+// Run calls the purge function at the configured purge interval until
+// Stop is called.
+func (m *Manager) Run(purge func()) {
+	m.mu.Lock()
+	if m.exit != nil {
+		m.mu.Unlock()
+		return
+	}
+	exit := make(chan bool)
+	m.exit = exit
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				purge()
+			}
+		}
+	}()
+}
+
+// Stop stops the purge started with Run.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.exit == nil {
+		return
+	}
+	close(m.exit)
+	m.exit = nil
+}
+
+// GetPseudonym returns the identifier of a deleted user in the records
+// retained after the deletion, e.g. security events, in lieu of the
+// username and email address.
+func GetPseudonym(realm, userID string) string {
+	h := sha256.Sum256([]byte(realm + "/" + userID))
+	return "deleted-" + hex.EncodeToString(h[:8])
+}
+
+func (m *Manager) prune() {
+	now := time.Now()
+	for k, req := range m.pending {
+		if now.After(req.ExpiresAt) {
+			delete(m.pending, k)
+		}
+	}
+}
+
+func getKey(realm, username string) string {
+	return realm + "/" + strings.ToLower(username)
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deletion
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EmailProvider: "default", GracePeriod: 604800},
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrAccountDeletionConfigEmailProvider,
+		},
+		{
+			name:      "config with negative grace period",
+			config:    &Config{EmailProvider: "default", GracePeriod: -1},
+			shouldErr: true,
+			err:       errors.ErrAccountDeletionConfigGracePeriod.WithArgs(-1),
+		},
+		{
+			name:      "config with negative code lifetime",
+			config:    &Config{EmailProvider: "default", CodeLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrAccountDeletionConfigCodeLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max attempts",
+			config:    &Config{EmailProvider: "default", MaxAttempts: -1},
+			shouldErr: true,
+			err:       errors.ErrAccountDeletionConfigMaxAttempts.WithArgs(-1),
+		},
+		{
+			name:      "config with negative purge interval",
+			config:    &Config{EmailProvider: "default", PurgeInterval: -1},
+			shouldErr: true,
+			err:       errors.ErrAccountDeletionConfigPurgeInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default", MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "grace period", 30*24*time.Hour, m.GetGracePeriod())
+
+	_, err = m.Confirm("local", "jsmith", "123456")
+	tests.EvalErrWithLog(t, err, "no pending request", true, errors.ErrAccountDeletionNotFound, nil)
+
+	_, code, err := m.Request("local", "jsmith", "jsmith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "code length", codeLength, len(code))
+	if _, err := m.Get("local", "JSMITH"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = m.Confirm("local", "jsmith", "x"+code)
+	tests.EvalErrWithLog(t, err, "wrong code", true, errors.ErrAccountDeletionCodeMismatch, nil)
+	req, err := m.Confirm("local", "jsmith", code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "email", "jsmith@localhost", req.Email)
+	_, err = m.Confirm("local", "jsmith", code)
+	tests.EvalErrWithLog(t, err, "confirmed request", true, errors.ErrAccountDeletionNotFound, nil)
+
+	// The pending request is discarded after too many failed attempts.
+	_, code, err = m.Request("local", "jsmith", "jsmith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.Confirm("local", "jsmith", "x"+code)
+	_, err = m.Confirm("local", "jsmith", "x"+code)
+	tests.EvalErrWithLog(t, err, "too many attempts", true, errors.ErrAccountDeletionCodeAttempts, nil)
+
+	m.lifetime = -time.Second
+	_, code, err = m.Request("local", "jsmith", "jsmith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.Confirm("local", "jsmith", code)
+	tests.EvalErrWithLog(t, err, "expired code", true, errors.ErrAccountDeletionCodeExpired, nil)
+}
+
+func TestRun(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.interval = 10 * time.Millisecond
+	var counter int32
+	m.Run(func() {
+		atomic.AddInt32(&counter, 1)
+	})
+	time.Sleep(100 * time.Millisecond)
+	m.Stop()
+	if atomic.LoadInt32(&counter) == 0 {
+		t.Fatalf("expected purge to run")
+	}
+	m.Stop()
+
+	tests.EvalObjects(t, "pseudonym", GetPseudonym("local", "foo"), GetPseudonym("local", "foo"))
+	if GetPseudonym("local", "foo") == GetPseudonym("local", "bar") {
+		t.Fatalf("expected distinct pseudonyms")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{EmailProvider: "default"}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := map[string]string{
+		"email":        "jsmith@localhost",
+		"username":     "jsmith",
+		"code":         "123456",
+		"lifetime":     "15m0s",
+		"scheduled_at": "Mon Jan  2 15:04:05 UTC 2006",
+	}
+	if err := m.NotifyCode(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.NotifyNotice(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 2, len(files))
+	var subjects []string
+	for _, fp := range files {
+		b, _ := ioutil.ReadFile(fp)
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "Subject: ") {
+				subjects = append(subjects, strings.TrimSpace(line))
+			}
+		}
+	}
+	for _, want := range []string{
+		"Subject: Account Deletion Confirmation",
+		"Subject: Account Scheduled for Deletion",
+	} {
+		var found bool
+		for _, s := range subjects {
+			if s == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("message with %q not found in %v", want, subjects)
+		}
+	}
+
+	cfg = &Config{EmailProvider: "foo"}
+	cfg.SetMessaging(msgCfg)
+	err = cfg.ValidateMessaging()
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+}
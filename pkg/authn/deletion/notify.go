@@ -0,0 +1,35 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deletion
+
+const (
+	codeTemplateName   = "en/account_deletion_code"
+	noticeTemplateName = "en/account_deletion_notice"
+)
+
+// NotifyCode sends the confirmation code to the email address in data.
+func (m *Manager) NotifyCode(data map[string]string) error {
+	return m.notify(codeTemplateName, data)
+}
+
+// NotifyNotice informs the email address in data about the scheduled
+// deletion of the account.
+func (m *Manager) NotifyNotice(data map[string]string) error {
+	return m.notify(noticeTemplateName, data)
+}
+
+func (m *Manager) notify(templateName string, data map[string]string) error {
+	return m.config.Send(m.config.EmailProvider, templateName, []string{data["email"]}, data)
+}
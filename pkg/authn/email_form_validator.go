@@ -0,0 +1,58 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"strings"
+)
+
+func validateEmailChangeForm(r *http.Request, rr *requests.Request) error {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Failed parsing submitted form")
+	}
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	if email == "" {
+		return fmt.Errorf("Required form field not found")
+	}
+	if _, err := identity.NewEmailAddress(email); err != nil {
+		return fmt.Errorf("Invalid email address")
+	}
+	if strings.EqualFold(email, rr.User.Email) {
+		return fmt.Errorf("New email address matches current email address")
+	}
+	rr.User.NewEmail = email
+	return nil
+}
+
+func validateEmailConfirmForm(r *http.Request) (string, error) {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return "", fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", fmt.Errorf("Failed parsing submitted form")
+	}
+	code := strings.TrimSpace(r.PostFormValue("code"))
+	if code == "" {
+		return "", fmt.Errorf("Required form field not found")
+	}
+	return code, nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emailchange
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultCodeLifetime = 900
+	defaultMaxAttempts  = 5
+	defaultMaxRequests  = 3
+	defaultWindow       = 3600
+	codeLength          = 6
+)
+
+// Config holds the configuration of the email address change verification.
+type Config struct {
+	// The email provider used to deliver confirmation codes and notices.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The number of seconds a confirmation code is valid for.
+	CodeLifetime int `json:"code_lifetime,omitempty" xml:"code_lifetime,omitempty" yaml:"code_lifetime,omitempty"`
+	// The maximum number of failed confirmation attempts before the pending
+	// change is discarded.
+	MaxAttempts int `json:"max_attempts,omitempty" xml:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// The maximum number of email change requests per user and per source
+	// IP address within the throttling window.
+	MaxRequests int `json:"max_requests,omitempty" xml:"max_requests,omitempty" yaml:"max_requests,omitempty"`
+	// The throttling window in seconds.
+	Window int `json:"window,omitempty" xml:"window,omitempty" yaml:"window,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// PendingChange is an email address change awaiting confirmation. The
+// current email address remains active until the change is confirmed.
+type PendingChange struct {
+	Realm     string    `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username  string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	OldEmail  string    `json:"old_email,omitempty" xml:"old_email,omitempty" yaml:"old_email,omitempty"`
+	NewEmail  string    `json:"new_email,omitempty" xml:"new_email,omitempty" yaml:"new_email,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	code      string
+	attempts  int
+}
+
+// Manager tracks pending email address changes and their confirmation codes.
+type Manager struct {
+	mu       sync.Mutex
+	config   *Config
+	lifetime time.Duration
+	max      int
+	pending  map[string]*PendingChange
+	limiter  *ratelimit.Limiter
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrEmailChangeConfigEmailProvider
+	}
+	if cfg.CodeLifetime < 0 {
+		return errors.ErrEmailChangeConfigCodeLifetime.WithArgs(cfg.CodeLifetime)
+	}
+	if cfg.MaxAttempts < 0 {
+		return errors.ErrEmailChangeConfigMaxAttempts.WithArgs(cfg.MaxAttempts)
+	}
+	if cfg.MaxRequests < 0 {
+		return errors.ErrEmailChangeConfigMaxRequests.WithArgs(cfg.MaxRequests)
+	}
+	if cfg.Window < 0 {
+		return errors.ErrEmailChangeConfigWindow.WithArgs(cfg.Window)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of confirmation codes.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager. The pending changes are held in
+// memory, i.e. they are discarded after a restart.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config:   cfg,
+		lifetime: time.Duration(defaultCodeLifetime) * time.Second,
+		max:      defaultMaxAttempts,
+		pending:  make(map[string]*PendingChange),
+	}
+	if cfg.CodeLifetime > 0 {
+		m.lifetime = time.Duration(cfg.CodeLifetime) * time.Second
+	}
+	if cfg.MaxAttempts > 0 {
+		m.max = cfg.MaxAttempts
+	}
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{
+		MaxAttempts: getValue(cfg.MaxRequests, defaultMaxRequests),
+		Interval:    getValue(cfg.Window, defaultWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.limiter = limiter
+	return m, nil
+}
+
+// GetCodeLifetime returns the lifetime of confirmation codes.
+func (m *Manager) GetCodeLifetime() time.Duration {
+	return m.lifetime
+}
+
+// Allow records an email change request for each of the provided keys,
+// e.g. user and source IP address. It returns an error when any of the
+// keys exceeded the number of requests allowed within the window.
+func (m *Manager) Allow(keys ...string) error {
+	if _, err := m.limiter.Allow(keys...); err != nil {
+		return errors.ErrEmailChangeThrottled
+	}
+	return nil
+}
+
+// Request starts an email address change for a user and returns the
+// confirmation code to be sent to the new email address. A subsequent
+// request for the same user replaces the prior one.
+func (m *Manager) Request(realm, username, oldEmail, newEmail string) (*PendingChange, string, error) {
+	if strings.EqualFold(oldEmail, newEmail) {
+		return nil, "", errors.ErrEmailChangeSameAddress
+	}
+	code, err := generateCode()
+	if err != nil {
+		return nil, "", err
+	}
+	change := &PendingChange{
+		Realm:     realm,
+		Username:  username,
+		OldEmail:  oldEmail,
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(m.lifetime).UTC(),
+		code:      code,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	m.pending[getKey(realm, username)] = change
+	return change, code, nil
+}
+
+// Get returns the pending email address change of a user.
+func (m *Manager) Get(realm, username string) (*PendingChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	change, exists := m.pending[getKey(realm, username)]
+	if !exists {
+		return nil, errors.ErrEmailChangeNotFound
+	}
+	return change, nil
+}
+
+// Confirm checks the confirmation code of the pending email address change
+// of a user. On success, the pending change is removed and returned.
+func (m *Manager) Confirm(realm, username, code string) (*PendingChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := getKey(realm, username)
+	change, exists := m.pending[k]
+	if !exists {
+		return nil, errors.ErrEmailChangeNotFound
+	}
+	if time.Now().After(change.ExpiresAt) {
+		delete(m.pending, k)
+		return nil, errors.ErrEmailChangeCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(change.code), []byte(strings.TrimSpace(code))) != 1 {
+		change.attempts++
+		if change.attempts >= m.max {
+			delete(m.pending, k)
+			return nil, errors.ErrEmailChangeCodeAttempts
+		}
+		return nil, errors.ErrEmailChangeCodeMismatch
+	}
+	delete(m.pending, k)
+	return change, nil
+}
+
+// Cancel discards the pending email address change of a user.
+func (m *Manager) Cancel(realm, username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, getKey(realm, username))
+}
+
+func (m *Manager) prune() {
+	now := time.Now()
+	for k, change := range m.pending {
+		if now.After(change.ExpiresAt) {
+			delete(m.pending, k)
+		}
+	}
+}
+
+func getValue(v, defaultValue int) int {
+	if v > 0 {
+		return v
+	}
+	return defaultValue
+}
+
+func getKey(realm, username string) string {
+	return realm + "/" + strings.ToLower(username)
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
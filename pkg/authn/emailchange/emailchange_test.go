@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emailchange
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EmailProvider: "default", CodeLifetime: 600},
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrEmailChangeConfigEmailProvider,
+		},
+		{
+			name:      "config with negative code lifetime",
+			config:    &Config{EmailProvider: "default", CodeLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrEmailChangeConfigCodeLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max attempts",
+			config:    &Config{EmailProvider: "default", MaxAttempts: -1},
+			shouldErr: true,
+			err:       errors.ErrEmailChangeConfigMaxAttempts.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max requests",
+			config:    &Config{EmailProvider: "default", MaxRequests: -1},
+			shouldErr: true,
+			err:       errors.ErrEmailChangeConfigMaxRequests.WithArgs(-1),
+		},
+		{
+			name:      "config with negative window",
+			config:    &Config{EmailProvider: "default", Window: -1},
+			shouldErr: true,
+			err:       errors.ErrEmailChangeConfigWindow.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default", MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, err = m.Request("local", "jsmith", "jsmith@localhost", "JSmith@localhost")
+	tests.EvalErrWithLog(t, err, "same address", true, errors.ErrEmailChangeSameAddress, nil)
+
+	_, err = m.Confirm("local", "jsmith", "123456")
+	tests.EvalErrWithLog(t, err, "no pending change", true, errors.ErrEmailChangeNotFound, nil)
+
+	_, code, err := m.Request("local", "jsmith", "jsmith@localhost", "john.smith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "code length", codeLength, len(code))
+	change, err := m.Get("local", "JSMITH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "pending email", "john.smith@localhost", change.NewEmail)
+
+	_, err = m.Confirm("local", "jsmith", "x"+code)
+	tests.EvalErrWithLog(t, err, "wrong code", true, errors.ErrEmailChangeCodeMismatch, nil)
+	change, err = m.Confirm("local", "jsmith", code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "old email", "jsmith@localhost", change.OldEmail)
+	tests.EvalObjects(t, "new email", "john.smith@localhost", change.NewEmail)
+	_, err = m.Confirm("local", "jsmith", code)
+	tests.EvalErrWithLog(t, err, "confirmed change", true, errors.ErrEmailChangeNotFound, nil)
+
+	// The pending change is discarded after too many failed attempts.
+	_, code, err = m.Request("local", "jsmith", "jsmith@localhost", "john.smith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.Confirm("local", "jsmith", "x"+code)
+	_, err = m.Confirm("local", "jsmith", "x"+code)
+	tests.EvalErrWithLog(t, err, "too many attempts", true, errors.ErrEmailChangeCodeAttempts, nil)
+	_, err = m.Confirm("local", "jsmith", code)
+	tests.EvalErrWithLog(t, err, "discarded change", true, errors.ErrEmailChangeNotFound, nil)
+
+	m.lifetime = -time.Second
+	_, code, err = m.Request("local", "jsmith", "jsmith@localhost", "john.smith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.Confirm("local", "jsmith", code)
+	tests.EvalErrWithLog(t, err, "expired code", true, errors.ErrEmailChangeCodeExpired, nil)
+}
+
+func TestAllow(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default", MaxRequests: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.Allow("user:local/jsmith", "ip:10.0.0.1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err = m.Allow("user:local/jsmith", "ip:10.0.0.2")
+	tests.EvalErrWithLog(t, err, "throttled user", true, errors.ErrEmailChangeThrottled, nil)
+	err = m.Allow("user:local/bjones", "ip:10.0.0.1")
+	tests.EvalErrWithLog(t, err, "throttled ip", true, errors.ErrEmailChangeThrottled, nil)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{EmailProvider: "default"}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := map[string]string{
+		"email":     "john.smith@localhost",
+		"username":  "jsmith",
+		"new_email": "john.smith@localhost",
+		"code":      "123456",
+		"lifetime":  "15m0s",
+	}
+	if err := m.NotifyCode(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data["email"] = "jsmith@localhost"
+	if err := m.NotifyNotice(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 2, len(files))
+	var subjects []string
+	for _, fp := range files {
+		b, _ := ioutil.ReadFile(fp)
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "Subject: ") {
+				subjects = append(subjects, strings.TrimSpace(line))
+			}
+		}
+	}
+	for _, want := range []string{
+		"Subject: Email Address Change Confirmation",
+		"Subject: Email Address Change Request",
+	} {
+		var found bool
+		for _, s := range subjects {
+			if s == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("message with %q not found in %v", want, subjects)
+		}
+	}
+
+	cfg = &Config{EmailProvider: "foo"}
+	cfg.SetMessaging(msgCfg)
+	err = cfg.ValidateMessaging()
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+}
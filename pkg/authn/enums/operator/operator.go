@@ -62,6 +62,92 @@ const (
 	// LookupAPIKey operator signals the retrieval of user identity associated
 	// with an API key
 	LookupAPIKey
+	// ResetPassword operator signals the setting of a new password without
+	// the knowledge of the current one, e.g. via password recovery.
+	ResetPassword
+	// ChangeEmail operator signals the replacement of the primary email
+	// address.
+	ChangeEmail
+	// UpdateProfile operator signals the update of self-managed profile
+	// attributes.
+	UpdateProfile
+	// GetConsents operator signals the retrieval of application consents.
+	GetConsents
+	// AddConsent operator signals the addition of an application consent.
+	AddConsent
+	// DeleteConsent operator signals the withdrawal of an application
+	// consent.
+	DeleteConsent
+	// RotateAPIKey operator signals the replacement of an API key.
+	RotateAPIKey
+	// UpdateRoles operator signals the replacement of the roles of a user.
+	UpdateRoles
+	// ResetMfaTokens operator signals the deletion of all MFA tokens of a
+	// user.
+	ResetMfaTokens
+	// GetAcceptances operator signals the retrieval of the accepted document
+	// versions, e.g. terms of service.
+	GetAcceptances
+	// AddAcceptance operator signals the acceptance of a document version.
+	AddAcceptance
+	// ScheduleDeletion operator signals the scheduling of the deletion of a
+	// user account.
+	ScheduleDeletion
+	// CancelDeletion operator signals the cancellation of the scheduled
+	// deletion of a user account.
+	CancelDeletion
+	// PurgeDeletions operator signals the deletion of the user accounts
+	// whose scheduled deletion is due.
+	PurgeDeletions
+	// ExportUser operator signals the retrieval of the data held about a
+	// user.
+	ExportUser
+	// AddLoginRecord operator signals the recording of an authentication
+	// attempt in the login history of a user.
+	AddLoginRecord
+	// UpdateOrganizations operator signals the replacement of the
+	// organizations of a user.
+	UpdateOrganizations
+	// GetGroups operator signals the retrieval of the groups of users.
+	GetGroups
+	// GetGroup operator signals the retrieval of a group of users.
+	GetGroup
+	// AddGroup operator signals the addition of a group of users.
+	AddGroup
+	// UpdateGroup operator signals the replacement of the roles of a group of users.
+	UpdateGroup
+	// DeleteGroup operator signals the deletion of a group of users.
+	DeleteGroup
+	// AddGroupMember operator signals the addition of a user to a group.
+	AddGroupMember
+	// DeleteGroupMember operator signals the removal of a user from a group.
+	DeleteGroupMember
+	// GetLinkedIdentities operator signals the retrieval of the identities
+	// at identity providers linked to a user account.
+	GetLinkedIdentities
+	// AddLinkedIdentity operator signals the linking of an identity at an
+	// identity provider to a user account.
+	AddLinkedIdentity
+	// DeleteLinkedIdentity operator signals the unlinking of an identity at
+	// an identity provider from a user account.
+	DeleteLinkedIdentity
+	// LookupLinkedIdentity operator signals the retrieval of the user
+	// account an identity at an identity provider is linked to.
+	LookupLinkedIdentity
+	// GrantRole operator signals the time-boxed assignment of a role to a
+	// user.
+	GrantRole
+	// ExpireRoles operator signals the removal of the expired time-boxed
+	// roles from the users.
+	ExpireRoles
+	// DisableDormantUsers operator signals the disabling of the users
+	// without recent logins.
+	DisableDormantUsers
+	// EnableUser operator signals the removal of the lockout of a user.
+	EnableUser
+	// PruneLoginRecords operator signals the removal of the old records
+	// from the login history of the users.
+	PruneLoginRecords
 )
 
 // String returns string representation of an operator.
@@ -105,6 +191,72 @@ func (e Type) String() string {
 		return "IdentifyUser"
 	case LookupAPIKey:
 		return "LookupAPIKey"
+	case ResetPassword:
+		return "ResetPassword"
+	case ChangeEmail:
+		return "ChangeEmail"
+	case UpdateProfile:
+		return "UpdateProfile"
+	case GetConsents:
+		return "GetConsents"
+	case AddConsent:
+		return "AddConsent"
+	case DeleteConsent:
+		return "DeleteConsent"
+	case RotateAPIKey:
+		return "RotateAPIKey"
+	case UpdateRoles:
+		return "UpdateRoles"
+	case ResetMfaTokens:
+		return "ResetMfaTokens"
+	case GetAcceptances:
+		return "GetAcceptances"
+	case AddAcceptance:
+		return "AddAcceptance"
+	case ScheduleDeletion:
+		return "ScheduleDeletion"
+	case CancelDeletion:
+		return "CancelDeletion"
+	case PurgeDeletions:
+		return "PurgeDeletions"
+	case ExportUser:
+		return "ExportUser"
+	case AddLoginRecord:
+		return "AddLoginRecord"
+	case UpdateOrganizations:
+		return "UpdateOrganizations"
+	case GetGroups:
+		return "GetGroups"
+	case GetGroup:
+		return "GetGroup"
+	case AddGroup:
+		return "AddGroup"
+	case UpdateGroup:
+		return "UpdateGroup"
+	case DeleteGroup:
+		return "DeleteGroup"
+	case AddGroupMember:
+		return "AddGroupMember"
+	case DeleteGroupMember:
+		return "DeleteGroupMember"
+	case GetLinkedIdentities:
+		return "GetLinkedIdentities"
+	case AddLinkedIdentity:
+		return "AddLinkedIdentity"
+	case DeleteLinkedIdentity:
+		return "DeleteLinkedIdentity"
+	case LookupLinkedIdentity:
+		return "LookupLinkedIdentity"
+	case GrantRole:
+		return "GrantRole"
+	case ExpireRoles:
+		return "ExpireRoles"
+	case DisableDormantUsers:
+		return "DisableDormantUsers"
+	case EnableUser:
+		return "EnableUser"
+	case PruneLoginRecords:
+		return "PruneLoginRecords"
 	}
 	return fmt.Sprintf("Type(%d)", int(e))
 }
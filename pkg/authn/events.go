@@ -0,0 +1,90 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// newEvent returns a security event associated with the request. The user
+// attributes are from the provided user, if any, or from the request.
+func (p *Portal) newEvent(eventType string, r *http.Request, rr *requests.Request, usr *user.User) *events.Event {
+	e := &events.Event{
+		Type:          eventType,
+		Realm:         rr.Upstream.Realm,
+		Method:        rr.Upstream.Method,
+		Username:      rr.User.Username,
+		Email:         rr.User.Email,
+		SourceAddress: addrutil.GetSourceAddress(r),
+		SessionID:     rr.Upstream.SessionID,
+		RequestID:     rr.ID,
+	}
+	if loc := p.locate(e.SourceAddress); loc != nil {
+		e.Country = loc.Country
+		e.City = loc.City
+	}
+	if usr == nil || usr.Claims == nil {
+		return e
+	}
+	e.Username = usr.Claims.Subject
+	e.Email = usr.Claims.Email
+	switch {
+	case usr.Authenticator.Realm != "":
+		e.Realm = usr.Authenticator.Realm
+	case e.Realm == "":
+		e.Realm = usr.Claims.Origin
+	}
+	if usr.Authenticator.Method != "" {
+		e.Method = usr.Authenticator.Method
+	}
+	return e
+}
+
+// publishEvent publishes a security event associated with the request.
+func (p *Portal) publishEvent(eventType string, r *http.Request, rr *requests.Request, usr *user.User, data map[string]interface{}) {
+	e := p.newEvent(eventType, r, rr, usr)
+	e.Data = data
+	events.Publish(e)
+	if eventType == events.LoginFailure {
+		p.recordNetworkFailure(r, rr, e.SourceAddress)
+	}
+}
+
+// revokeToken revokes the token of the user.
+func (p *Portal) revokeToken(r *http.Request, rr *requests.Request, usr *user.User, reason string) error {
+	if err := p.keystore.RevokeToken(usr.Token); err != nil {
+		p.logger.Warn(
+			"Failed revoking token",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return err
+	}
+	data := map[string]interface{}{
+		"reason": reason,
+	}
+	if usr.Claims != nil && usr.Claims.ID != "" {
+		data["token_id"] = usr.Claims.ID
+	}
+	p.publishEvent(events.TokenRevoked, r, rr, usr, data)
+	return nil
+}
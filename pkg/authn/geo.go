@@ -0,0 +1,40 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+)
+
+// locate returns the location of the source address. The sign-in tracker
+// may have its own geolocation database.
+func (p *Portal) locate(srcAddr string) *geo.Location {
+	if p.signIns != nil {
+		return p.signIns.Locate(srcAddr)
+	}
+	return geo.LocateAddress(srcAddr)
+}
+
+// addLocationData adds the country and the city of the source address to
+// the data of a notification.
+func (p *Portal) addLocationData(srcAddr string, data map[string]string) {
+	loc := p.locate(srcAddr)
+	if loc == nil {
+		return
+	}
+	data["country"] = loc.Country
+	data["city"] = loc.City
+	data["location"] = loc.String()
+}
@@ -0,0 +1,28 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+// mapGroupRoles translates the upstream groups of a user into the roles of
+// the portal with the group mapping file. The groups arrive either as the
+// roles, e.g. from SAML and OAuth identity providers, or separately, e.g.
+// the LDAP group DNs.
+func (p *Portal) mapGroupRoles(realm string, groups []string, m map[string]interface{}) {
+	if p.groupMapper == nil {
+		return
+	}
+	if roles := p.groupMapper.Apply(realm, getRoles(m), groups); len(roles) > 0 {
+		m["roles"] = roles
+	}
+}
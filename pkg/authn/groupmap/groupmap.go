@@ -0,0 +1,238 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupmap
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultRefreshInterval = 60
+
+// Config holds the configuration of the mapping of upstream groups, e.g.
+// Active Directory group DNs, Azure AD group object IDs, or GitHub teams,
+// to the roles of the portal. The LDAP users must still match a group
+// binding or the fallback roles of their identity store.
+type Config struct {
+	// The path to the mapping file in YAML or JSON format.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// The number of seconds between the checks whether the mapping file
+	// changed. The changed file is reloaded. Defaults to 60.
+	RefreshInterval int `json:"refresh_interval,omitempty" xml:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+}
+
+// File is the content of the mapping file.
+type File struct {
+	Mappings []*Mapping `json:"mappings,omitempty" xml:"mappings,omitempty" yaml:"mappings,omitempty"`
+}
+
+// Mapping translates an upstream group into roles. When the realm is set,
+// the mapping applies to the users of the realm only.
+type Mapping struct {
+	Group string   `json:"group,omitempty" xml:"group,omitempty" yaml:"group,omitempty"`
+	Realm string   `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// Mapper translates upstream groups into roles with the mappings in the
+// configured file. The file is reloaded when it changes. When the reload
+// fails, the previously loaded mappings remain in use.
+type Mapper struct {
+	config   *Config
+	interval time.Duration
+	logger   *zap.Logger
+	mu       sync.RWMutex
+	mappings map[string][]*Mapping
+	modTime  time.Time
+	checked  time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Path == "" {
+		return errors.ErrGroupMapConfigPathEmpty
+	}
+	if cfg.RefreshInterval < 0 {
+		return errors.ErrGroupMapConfigRefreshInterval.WithArgs(cfg.RefreshInterval)
+	}
+	return nil
+}
+
+// NewMapper returns an instance of Mapper. The mapping file is loaded
+// immediately, i.e. a missing or malformed file is a configuration error.
+func NewMapper(cfg *Config) (*Mapper, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Mapper{
+		config:   cfg,
+		interval: time.Duration(defaultRefreshInterval) * time.Second,
+	}
+	if cfg.RefreshInterval > 0 {
+		m.interval = time.Duration(cfg.RefreshInterval) * time.Second
+	}
+	fi, err := os.Stat(cfg.Path)
+	if err != nil {
+		return nil, errors.ErrGroupMapLoad.WithArgs(cfg.Path, err)
+	}
+	mappings, err := load(cfg.Path)
+	if err != nil {
+		return nil, errors.ErrGroupMapLoad.WithArgs(cfg.Path, err)
+	}
+	m.mappings = mappings
+	m.modTime = fi.ModTime()
+	m.checked = time.Now()
+	return m, nil
+}
+
+// SetLogger adds a logger to Mapper.
+func (m *Mapper) SetLogger(logger *zap.Logger) {
+	m.logger = logger
+}
+
+// Apply translates the upstream groups of a user of the realm into roles.
+// The roles matching a mapping, e.g. the SAML role attribute values and
+// the OAuth group claims, are replaced with the mapped roles. The groups,
+// e.g. LDAP group DNs, contribute the mapped roles only. The groups are
+// matched case-insensitively.
+func (m *Mapper) Apply(realm string, roles, groups []string) []string {
+	m.refresh(time.Now())
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var output []string
+	seen := make(map[string]bool)
+	add := func(role string) {
+		if seen[role] {
+			return
+		}
+		seen[role] = true
+		output = append(output, role)
+	}
+	for _, role := range roles {
+		mapped := m.getRoles(realm, role)
+		if mapped == nil {
+			add(role)
+			continue
+		}
+		for _, s := range mapped {
+			add(s)
+		}
+	}
+	for _, group := range groups {
+		for _, s := range m.getRoles(realm, group) {
+			add(s)
+		}
+	}
+	return output
+}
+
+// getRoles returns the roles mapped to the group, or nil when the group
+// is not mapped.
+func (m *Mapper) getRoles(realm, group string) []string {
+	var roles []string
+	for _, mapping := range m.mappings[strings.ToLower(group)] {
+		if mapping.Realm != "" && mapping.Realm != realm {
+			continue
+		}
+		roles = append(roles, mapping.Roles...)
+	}
+	return roles
+}
+
+// refresh reloads the mapping file when it changed, unless it was checked
+// within the refresh interval.
+func (m *Mapper) refresh(now time.Time) {
+	m.mu.RLock()
+	fresh := now.Sub(m.checked) < m.interval
+	m.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.checked) < m.interval {
+		return
+	}
+	m.checked = now
+
+	fi, err := os.Stat(m.config.Path)
+	if err == nil && fi.ModTime().Equal(m.modTime) {
+		return
+	}
+	var mappings map[string][]*Mapping
+	if err == nil {
+		mappings, err = load(m.config.Path)
+	}
+	if err != nil {
+		if m.logger != nil {
+			m.logger.Warn(
+				"Failed reloading group mapping file",
+				zap.String("path", m.config.Path),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+	m.mappings = mappings
+	m.modTime = fi.ModTime()
+	if m.logger != nil {
+		m.logger.Info(
+			"Reloaded group mapping file",
+			zap.String("path", m.config.Path),
+			zap.Int("group_count", len(mappings)),
+		)
+	}
+}
+
+// load reads the mapping file and indexes the mappings by the lowercase
+// group.
+func load(fp string) (map[string][]*Mapping, error) {
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{}
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".json":
+		err = json.Unmarshal(b, f)
+	default:
+		err = yaml.Unmarshal(b, f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	mappings := make(map[string][]*Mapping)
+	for i, mapping := range f.Mappings {
+		if mapping.Group == "" {
+			return nil, errors.ErrGroupMapEntryGroupEmpty.WithArgs(i)
+		}
+		if len(mapping.Roles) == 0 {
+			return nil, errors.ErrGroupMapEntryRolesEmpty.WithArgs(i, mapping.Group)
+		}
+		group := strings.ToLower(mapping.Group)
+		mappings[group] = append(mappings[group], mapping)
+	}
+	return mappings, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const testMappingFile = `mappings:
+- group: CN=Admins,OU=Groups,DC=contoso,DC=com
+  roles:
+  - authp/admin
+- group: 3f9c2b1e-7d4a-4c8e-9b2f-1a5d6e7f8a9b
+  realm: azure
+  roles:
+  - authp/user
+  - reader
+- group: acme/platform
+  realm: github
+  roles:
+  - authp/user
+`
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Path: "/etc/authp/group_mappings.yaml"},
+		},
+		{
+			name:      "empty path",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrGroupMapConfigPathEmpty,
+		},
+		{
+			name: "negative refresh interval",
+			config: &Config{
+				Path:            "/etc/authp/group_mappings.yaml",
+				RefreshInterval: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrGroupMapConfigRefreshInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "group mapping config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestMapperApply(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "group_mappings.yaml")
+	if err := ioutil.WriteFile(fp, []byte(testMappingFile), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewMapper(&Config{Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name   string
+		realm  string
+		roles  []string
+		groups []string
+		want   []string
+	}{
+		{
+			name:   "ldap group dn",
+			realm:  "contoso",
+			roles:  []string{"authp/user"},
+			groups: []string{"cn=admins,ou=groups,dc=contoso,dc=com", "CN=Printers,OU=Groups,DC=contoso,DC=com"},
+			want:   []string{"authp/user", "authp/admin"},
+		},
+		{
+			name:  "azure group object id",
+			realm: "azure",
+			roles: []string{"3f9c2b1e-7d4a-4c8e-9b2f-1a5d6e7f8a9b", "viewer"},
+			want:  []string{"authp/user", "reader", "viewer"},
+		},
+		{
+			name:  "github team of other realm",
+			realm: "gitlab",
+			roles: []string{"acme/platform"},
+			want:  []string{"acme/platform"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got := m.Apply(tc.realm, tc.roles, tc.groups)
+			tests.EvalObjectsWithLog(t, "roles", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestMapperReload(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "group_mappings.json")
+	_, err := NewMapper(&Config{Path: fp})
+	if err == nil {
+		t.Fatalf("expected error for missing mapping file")
+	}
+
+	if err := ioutil.WriteFile(fp, []byte(`{"mappings":[{"group":"acme/platform","roles":["authp/user"]}]}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewMapper(&Config{Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "roles", []string{"authp/user"}, m.Apply("github", []string{"acme/platform"}, nil))
+
+	// The changed mapping file is reloaded.
+	if err := ioutil.WriteFile(fp, []byte(`{"mappings":[{"group":"acme/platform","roles":["authp/admin"]}]}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(fp, now, now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(m.interval)
+	m.refresh(now)
+	tests.EvalObjects(t, "reloaded roles", []string{"authp/admin"}, m.Apply("github", []string{"acme/platform"}, nil))
+
+	// The previously loaded mappings remain in use when reload fails.
+	if err := ioutil.WriteFile(fp, []byte(`{"mappings":[{"group":"acme/platform"}]}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chtimes(fp, now, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(m.interval)
+	m.refresh(now)
+	tests.EvalObjects(t, "retained roles", []string{"authp/admin"}, m.Apply("github", []string{"acme/platform"}, nil))
+}
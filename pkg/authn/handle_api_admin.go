@@ -0,0 +1,511 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+)
+
+const adminAPIPrefix = "/api/v1/admin/"
+
+type adminUserRequest struct {
+	Username      string   `json:"username"`
+	Password      string   `json:"password"`
+	Email         string   `json:"email"`
+	Name          string   `json:"name"`
+	Roles         []string `json:"roles"`
+	Organizations []string `json:"organizations"`
+}
+
+type adminDeletionRequest struct {
+	Immediate   bool `json:"immediate"`
+	GracePeriod int  `json:"grace_period"`
+}
+
+type adminRoleGrantRequest struct {
+	Role      string `json:"role"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type adminAPIKeyRequest struct {
+	Usage     string   `json:"usage"`
+	Comment   string   `json:"comment"`
+	Roles     []string `json:"roles"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in"`
+}
+
+// handleAPIAdmin handles the versioned administrative API. The users are
+// managed in the identity store of the realm in the "realm" query parameter,
+// "local" by default. The delegated administrators, i.e. the ones with the
+// scope, manage the users and the registrations within the scope only.
+//
+//	GET    /api/v1/admin/users
+//	POST   /api/v1/admin/users
+//	GET    /api/v1/admin/users/{user}
+//	DELETE /api/v1/admin/users/{user}
+//	PUT    /api/v1/admin/users/{user}/roles
+//	POST   /api/v1/admin/users/{user}/grants
+//	PUT    /api/v1/admin/users/{user}/orgs
+//	POST   /api/v1/admin/users/{user}/password
+//	DELETE /api/v1/admin/users/{user}/mfa
+//	POST   /api/v1/admin/users/{user}/deletion
+//	DELETE /api/v1/admin/users/{user}/deletion
+//	DELETE /api/v1/admin/users/{user}/lockout
+//	GET    /api/v1/admin/users/{user}/history
+//	GET    /api/v1/admin/users/{user}/apikeys
+//	POST   /api/v1/admin/users/{user}/apikeys
+//	POST   /api/v1/admin/users/{user}/impersonate
+//	GET    /api/v1/admin/groups
+//	POST   /api/v1/admin/groups
+//	GET    /api/v1/admin/groups/{group}
+//	PUT    /api/v1/admin/groups/{group}
+//	DELETE /api/v1/admin/groups/{group}
+//	PUT    /api/v1/admin/groups/{group}/members/{user}
+//	DELETE /api/v1/admin/groups/{group}/members/{user}
+//	GET    /api/v1/admin/registrations
+//	POST   /api/v1/admin/registrations/{id}/{approve|decline}
+//	DELETE /api/v1/admin/enrichment
+//	DELETE /api/v1/admin/enrichment/{user}
+//	GET    /api/v1/admin/access-requests
+//	POST   /api/v1/admin/access-requests/{id}/{approve|decline|revoke}
+//	GET    /api/v1/admin/maintenance
+//	PUT    /api/v1/admin/maintenance/{all|realm/{realm}|provider/{name}}
+//	DELETE /api/v1/admin/maintenance/{all|realm/{realm}|provider/{name}}
+//	GET    /api/v1/admin/lifecycle
+//	POST   /api/v1/admin/lifecycle/{job}
+func (p *Portal) handleAPIAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope) error {
+	endpoint, err := getEndpoint(r.URL.Path, adminAPIPrefix)
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	}
+	arr := strings.Split(strings.Trim(endpoint, "/"), "/")
+	switch arr[0] {
+	case "registrations":
+		return p.handleAPIRegistrations(ctx, w, r, rr, usr, scope)
+	case "enrichment":
+		return p.handleAPIAdminEnrichment(ctx, w, r, rr, usr, scope, arr[1:])
+	case "access-requests":
+		return p.handleAPIAdminAccessRequests(ctx, w, r, rr, usr, scope, arr[1:])
+	case "maintenance":
+		return p.handleAPIAdminMaintenance(ctx, w, r, rr, usr, scope, arr[1:])
+	case "lifecycle":
+		return p.handleAPIAdminLifecycle(ctx, w, r, rr, usr, scope, arr[1:])
+	case "users", "groups":
+	default:
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+
+	realm := r.URL.Query().Get("realm")
+	if realm == "" {
+		realm = "local"
+	}
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, "realm not found")
+	}
+	if scope != nil {
+		if err := scope.PermitsRealm(realm); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+		}
+	}
+	if arr[0] == "groups" {
+		return p.handleAPIAdminGroups(ctx, w, r, rr, usr, store, scope, arr[1:])
+	}
+
+	if len(arr) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			return p.handleAPIAdminListUsers(ctx, w, r, rr, usr, store, scope)
+		case http.MethodPost:
+			return p.handleAPIAdminAddUser(ctx, w, r, rr, usr, store, scope)
+		}
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	target := &requests.Request{Context: rr.GetContext()}
+	target.User.Username = arr[1]
+	if err := store.Request(operator.IdentifyUser, target); err != nil || target.User.Username == "nobody" {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, "user not found")
+	}
+	if scope != nil {
+		// The users outside of the scope are not disclosed.
+		if err := scope.PermitsUser(target.User.Username, target.User.Roles, target.User.Organizations); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, "user not found")
+		}
+	}
+
+	var action string
+	if len(arr) > 2 {
+		action = arr[2]
+	}
+	resp := make(map[string]interface{})
+	req := &requests.Request{Context: rr.GetContext()}
+	req.User.Username = target.User.Username
+	req.User.Email = target.User.Email
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		if err := store.Request(operator.GetUser, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["user"] = newAdminUserView(req.Response.Payload.(*identity.User))
+	case action == "" && r.Method == http.MethodDelete:
+		if err := store.Request(operator.DeleteUser, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "user_deleted")
+	case action == "roles" && r.Method == http.MethodPut:
+		body := &adminUserRequest{}
+		if err := decodeAdminRequest(r, body); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		req.User.Roles = body.Roles
+		if scope != nil {
+			// The roles outside of the scope are retained.
+			roles, err := scope.MergeRoles(target.User.Roles, body.Roles)
+			if err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+			req.User.Roles = roles
+		}
+		if err := store.Request(operator.UpdateRoles, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "roles_updated")
+	case action == "grants" && r.Method == http.MethodPost:
+		// The role is granted until the expiry, e.g. for the just-in-time
+		// privileged access, and is removed when it expires.
+		body := &adminRoleGrantRequest{}
+		if err := decodeAdminRequest(r, body); err != nil || body.Role == "" || body.ExpiresIn < 1 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if scope != nil {
+			if err := scope.PermitsRoles([]string{body.Role}); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+		}
+		req.RoleGrant.Role = body.Role
+		req.RoleGrant.GrantedBy = usr.Claims.Email
+		req.RoleGrant.ExpiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second).UTC()
+		if err := store.Request(operator.GrantRole, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "role_granted")
+		e := p.newEvent(events.RoleGranted, r, rr, nil)
+		e.Realm = realm
+		e.Username = req.User.Username
+		e.Email = req.User.Email
+		e.Data = map[string]interface{}{
+			"role":       req.RoleGrant.Role,
+			"granted_by": req.RoleGrant.GrantedBy,
+			"expires_at": req.RoleGrant.ExpiresAt,
+		}
+		events.Publish(e)
+		resp["role_grant"] = e.Data
+	case action == "orgs" && r.Method == http.MethodPut:
+		body := &adminUserRequest{}
+		if err := decodeAdminRequest(r, body); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		req.User.Organizations = body.Organizations
+		if scope != nil {
+			// The organizations outside of the scope are retained.
+			orgs, err := scope.MergeOrganizations(target.User.Organizations, body.Organizations)
+			if err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+			req.User.Organizations = orgs
+		}
+		if err := store.Request(operator.UpdateOrganizations, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "organizations_updated")
+	case action == "password" && r.Method == http.MethodPost:
+		body := &adminUserRequest{}
+		if err := decodeAdminRequest(r, body); err != nil || body.Password == "" {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		req.User.Password = body.Password
+		if err := store.Request(operator.ResetPassword, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		if req.Response.Message != "" {
+			resp["message"] = req.Response.Message
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "password_reset")
+	case action == "mfa" && r.Method == http.MethodDelete:
+		if err := store.Request(operator.ResetMfaTokens, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+	case action == "deletion" && r.Method == http.MethodPost:
+		// The deletion is either immediate or scheduled after the grace
+		// period, the configured one by default.
+		body := &adminDeletionRequest{}
+		if err := decodeAdminRequest(r, body); err != nil || body.GracePeriod < 0 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if body.Immediate {
+			if err := store.Request(operator.GetUser, req); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+			}
+			deletedUser := req.Response.Payload.(*identity.User)
+			if err := store.Request(operator.DeleteUser, req); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+			}
+			p.eraseDeletedAccount(realm, deletedUser, "admin")
+			resp["deleted"] = true
+			break
+		}
+		gracePeriod := time.Duration(body.GracePeriod) * time.Second
+		if gracePeriod == 0 {
+			if p.deletion == nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, "grace period is not configured")
+			}
+			gracePeriod = p.deletion.GetGracePeriod()
+		}
+		req.Deletion.ScheduledAt = time.Now().Add(gracePeriod).UTC()
+		if err := store.Request(operator.ScheduleDeletion, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, "account_deletion")
+		resp["deletion"] = req.Response.Payload
+	case action == "deletion" && r.Method == http.MethodDelete:
+		if err := store.Request(operator.CancelDeletion, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["cancelled"] = req.Response.Payload
+	case action == "lockout" && r.Method == http.MethodDelete:
+		// The lockout, e.g. the one of a dormant account, is removed.
+		if err := store.Request(operator.EnableUser, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["enabled"] = req.Response.Payload
+	case action == "history" && r.Method == http.MethodGet:
+		if err := store.Request(operator.GetUser, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		history := req.Response.Payload.(*identity.User).LoginHistory
+		if history == nil {
+			history = []*identity.LoginRecord{}
+		}
+		resp["login_history"] = history
+	case action == "apikeys" && r.Method == http.MethodGet:
+		if err := store.Request(operator.GetAPIKeys, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		keys := []*identity.APIKey{}
+		for _, k := range req.Response.Payload.(*identity.APIKeyBundle).Get() {
+			// The digest of the key is not disclosed.
+			entry := *k
+			entry.Payload = ""
+			keys = append(keys, &entry)
+		}
+		resp["api_keys"] = keys
+	case action == "apikeys" && r.Method == http.MethodPost:
+		body := &adminAPIKeyRequest{}
+		if err := decodeAdminRequest(r, body); err != nil || body.ExpiresIn < 0 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		req.Key.Usage = body.Usage
+		if req.Key.Usage == "" {
+			req.Key.Usage = "api"
+		}
+		if scope != nil {
+			if err := scope.PermitsRoles(body.Roles); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+		}
+		req.Key.Comment = body.Comment
+		req.Key.Roles = body.Roles
+		req.Key.Scopes = body.Scopes
+		if body.ExpiresIn > 0 {
+			req.Key.ExpiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second).UTC()
+		}
+		if err := store.Request(operator.AddAPIKey, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["api_key"] = req.Response.Payload
+	case action == "impersonate" && r.Method == http.MethodPost:
+		if p.config.ImpersonationConfig == nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		}
+		if scope != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		}
+		body := &adminImpersonationRequest{}
+		if err := decodeAdminRequest(r, body); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		impersonation, code, err := p.impersonateUser(w, r, rr, usr, realm, target, body.Duration)
+		if err != nil {
+			return p.handleJSONError(ctx, w, code, err.Error())
+		}
+		resp["impersonation"] = impersonation
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	p.logAdminOperation(rr, usr, realm, r.Method+" "+action, req.User.Username)
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
+
+func (p *Portal) handleAPIAdminListUsers(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, store ids.IdentityStore, scope *delegation.Scope) error {
+	// The retrieval of users is performed on behalf of the administrator.
+	req := &requests.Request{Context: rr.GetContext()}
+	req.User.Username = usr.Claims.Subject
+	req.User.Email = usr.Claims.Email
+	if err := store.Request(operator.GetUsers, req); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+	}
+	bundle := req.Response.Payload.(*identity.UserMetadataBundle)
+	resp := map[string]interface{}{
+		"users": bundle.Get(),
+		"count": bundle.Size(),
+	}
+	if scope != nil {
+		users := p.filterScopedUsers(rr, store, scope, bundle.Get())
+		resp["users"] = users
+		resp["count"] = len(users)
+	}
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
+
+func (p *Portal) handleAPIAdminAddUser(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, store ids.IdentityStore, scope *delegation.Scope) error {
+	body := &adminUserRequest{}
+	if err := decodeAdminRequest(r, body); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	}
+	if scope != nil {
+		// The delegated administrators add the users within their scope.
+		if err := scope.PermitsRoles(body.Roles); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+		}
+		if err := scope.PermitsOrganizations(body.Organizations); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+		}
+		if err := scope.PermitsUser(body.Username, body.Roles, body.Organizations); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+		}
+	}
+	req := &requests.Request{
+		Context: rr.GetContext(),
+		User: requests.User{
+			Username:      body.Username,
+			Password:      body.Password,
+			Email:         body.Email,
+			FullName:      body.Name,
+			Roles:         body.Roles,
+			Organizations: body.Organizations,
+		},
+	}
+	if err := store.Request(operator.AddUser, req); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+	}
+	req.User.Password = ""
+	if err := store.Request(operator.GetUser, req); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusInternalServerError, err.Error())
+	}
+	p.logAdminOperation(rr, usr, store.GetRealm(), "POST", body.Username)
+	resp := map[string]interface{}{
+		"user": newAdminUserView(req.Response.Payload.(*identity.User)),
+	}
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
+
+func (p *Portal) handleAPIAdminResponse(w http.ResponseWriter, rr *requests.Request, resp map[string]interface{}) error {
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// terminateUserSessions deletes the cached sessions of the user, ends the
+// sliding sessions, and revokes their tokens.
+func (p *Portal) terminateUserSessions(r *http.Request, rr *requests.Request, email, reason string) {
+	if p.sessionPolicy != nil {
+		p.sessionPolicy.EndUserSessions(email)
+	}
+	for _, u := range p.sessions.DeleteUserSessions(email) {
+		p.revokeToken(r, rr, u, reason)
+	}
+}
+
+func (p *Portal) logAdminOperation(rr *requests.Request, usr *user.User, realm, op, username string) {
+	p.logger.Info(
+		"Performed administrative operation",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("admin", usr.Claims.Email),
+		zap.String("realm", realm),
+		zap.String("operation", strings.TrimSpace(op)),
+		zap.String("username", username),
+	)
+}
+
+func decodeAdminRequest(r *http.Request, v interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 65536)).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// newAdminUserView returns the user attributes disclosed to administrators.
+func newAdminUserView(u *identity.User) map[string]interface{} {
+	m := map[string]interface{}{
+		"metadata":        u.GetMetadata(),
+		"roles":           u.GetRolesClaim(),
+		"organizations":   u.GetOrganizationsClaim(),
+		"challenges":      u.GetChallenges(),
+		"mfa_token_count": len(u.MfaTokens),
+		"api_key_count":   len(u.APIKeys),
+	}
+	if u.Lockout != nil {
+		m["lockout"] = u.Lockout
+	}
+	if u.Deletion != nil {
+		m["deletion"] = u.Deletion
+	}
+	var grants []*identity.Role
+	now := time.Now()
+	for _, role := range u.Roles {
+		if role.IsTemporary() && !role.Expired(now) {
+			grants = append(grants, role)
+		}
+	}
+	if len(grants) > 0 {
+		m["role_grants"] = grants
+	}
+	return m
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+)
+
+type accessRequestVerdictRequest struct {
+	Comment string `json:"comment"`
+	// Lifetime is the number of seconds the role is granted for. It
+	// overrides the requested lifetime.
+	Lifetime int `json:"lifetime"`
+}
+
+// handleAPIAdminAccessRequests lists the access requests and records the
+// administrator verdicts for them. The delegated administrators access the
+// requests for the roles within their scope only.
+//
+//	GET  /api/v1/admin/access-requests
+//	POST /api/v1/admin/access-requests/{id}/{approve|decline|revoke}
+func (p *Portal) handleAPIAdminAccessRequests(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope, arr []string) error {
+	if p.accessRequests == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch {
+	case len(arr) == 0 && r.Method == http.MethodGet:
+		status := r.URL.Query().Get("status")
+		entries := []*accessrequest.Request{}
+		for _, entry := range p.accessRequests.List() {
+			if status != "" && entry.Status != status {
+				continue
+			}
+			if !permitsScopedAccessRequest(scope, entry) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		resp["access_requests"] = entries
+	case len(arr) == 2 && r.Method == http.MethodPost:
+		id, verdict := arr[0], arr[1]
+		req := &accessRequestVerdictRequest{}
+		if err := decodeAdminRequest(r, req); err != nil || req.Lifetime < 0 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		entry, err := p.accessRequests.Get(id)
+		if err != nil || !permitsScopedAccessRequest(scope, entry) {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, "access request not found")
+		}
+		entry, err = p.reviewAccessRequest(id, verdict, usr.Claims.Email, req)
+		if err != nil {
+			p.logger.Warn(
+				"failed recording access request verdict",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("access_request_id", id),
+				zap.String("verdict", verdict),
+				zap.Error(err),
+			)
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.logAccessRequestVerdict(rr, entry)
+		resp["access_request"] = entry
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
+
+// reviewAccessRequest approves, declines, or revokes an access request,
+// and informs the user about the verdict.
+func (p *Portal) reviewAccessRequest(id, verdict, reviewer string, req *accessRequestVerdictRequest) (*accessrequest.Request, error) {
+	var entry *accessrequest.Request
+	var err error
+	switch verdict {
+	case "approve":
+		entry, err = p.accessRequests.Approve(id, reviewer, req.Comment, req.Lifetime, p.grantAccessRole)
+	case "decline":
+		entry, err = p.accessRequests.Decline(id, reviewer, req.Comment)
+	case "revoke":
+		entry, err = p.accessRequests.Revoke(id, reviewer, p.revokeAccessRole)
+	default:
+		return nil, errors.ErrAccessRequestVerdict.WithArgs(verdict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.notifyAccessRequestVerdict(entry)
+	return entry, nil
+}
+
+func (p *Portal) logAccessRequestVerdict(rr *requests.Request, entry *accessrequest.Request) {
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "access_request_"+entry.Status),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("access_request_id", entry.ID),
+		zap.String("app", entry.App),
+		zap.String("role", entry.Role),
+		zap.String("realm", entry.Realm),
+		zap.String("username", entry.Username),
+		zap.String("reviewed_by", entry.ReviewedBy),
+		zap.Time("expires_at", entry.ExpiresAt),
+	)
+}
+
+// permitsScopedAccessRequest returns true when the role of the access
+// request is within the scope of a delegated administrator.
+func permitsScopedAccessRequest(scope *delegation.Scope, entry *accessrequest.Request) bool {
+	if scope == nil {
+		return true
+	}
+	if err := scope.PermitsRealm(entry.Realm); err != nil {
+		return false
+	}
+	return scope.PermitsRoles([]string{entry.Role}) == nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// groupCacher is implemented by the identity stores and the identity
+// providers caching the group lookups of their users.
+type groupCacher interface {
+	InvalidateGroupCache(string) int
+}
+
+// handleAPIAdminEnrichment invalidates the cached group lookups of a user,
+// identified by the username, the email address, or the subject, in all
+// identity stores and providers. Without the user, it invalidates the
+// cached group lookups of all users. The delegated administrators are not
+// permitted, because the caches span the realms.
+func (p *Portal) handleAPIAdminEnrichment(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope, arr []string) error {
+	if scope != nil {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+	if r.Method != http.MethodDelete || len(arr) > 1 {
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	var username string
+	if len(arr) == 1 {
+		username = arr[0]
+	}
+
+	var count int
+	for _, store := range p.identityStores {
+		if c, ok := store.(groupCacher); ok {
+			count += c.InvalidateGroupCache(username)
+		}
+	}
+	for _, provider := range p.identityProviders {
+		if c, ok := provider.(groupCacher); ok {
+			count += c.InvalidateGroupCache(username)
+		}
+	}
+
+	p.logAdminOperation(rr, usr, "", "DELETE enrichment", username)
+	resp := map[string]interface{}{
+		"invalidated": count,
+	}
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+type adminGroupRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Roles       []string `json:"roles"`
+}
+
+// handleAPIAdminGroups manages the groups of users of an identity store.
+// The members of a group inherit its roles. The delegated administrators
+// manage the groups when their scope covers the entire realm, and only
+// with the roles within their scope.
+func (p *Portal) handleAPIAdminGroups(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, store ids.IdentityStore, scope *delegation.Scope, arr []string) error {
+	if scope != nil && !scope.IsRealmWide() {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+	realm := store.GetRealm()
+	resp := make(map[string]interface{})
+	req := &requests.Request{Context: rr.GetContext()}
+
+	if len(arr) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			if err := store.Request(operator.GetGroups, req); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+			}
+			resp["groups"] = req.Response.Payload
+			return p.handleAPIAdminResponse(w, rr, resp)
+		case http.MethodPost:
+			body := &adminGroupRequest{}
+			if err := decodeAdminRequest(r, body); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+			}
+			if scope != nil {
+				if err := scope.PermitsRoles(body.Roles); err != nil {
+					return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+				}
+			}
+			req.Group.Name = body.Name
+			req.Group.Description = body.Description
+			req.Group.Roles = body.Roles
+			if err := store.Request(operator.AddGroup, req); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+			}
+			p.logAdminOperation(rr, usr, realm, "POST group", body.Name)
+			resp["group"] = req.Response.Payload
+			return p.handleAPIAdminResponse(w, rr, resp)
+		}
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	req.Group.Name = arr[0]
+	if err := store.Request(operator.GetGroup, req); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, "group not found")
+	}
+	group := req.Response.Payload.(*identity.Group)
+	if scope != nil {
+		// The groups granting the roles outside of the scope are managed
+		// by the full administrators only.
+		if err := scope.PermitsRoles(group.GetRolesClaim()); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+		}
+	}
+
+	var action, username string
+	if len(arr) > 1 {
+		action = arr[1]
+	}
+	if len(arr) > 2 {
+		username = arr[2]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		resp["group"] = group
+	case action == "" && r.Method == http.MethodPut:
+		body := &adminGroupRequest{}
+		if err := decodeAdminRequest(r, body); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if scope != nil {
+			if err := scope.PermitsRoles(body.Roles); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+		}
+		req.Group.Description = body.Description
+		req.Group.Roles = body.Roles
+		if err := store.Request(operator.UpdateGroup, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateGroupSessions(r, rr, store, group.Members, "group_updated")
+		resp["group"] = req.Response.Payload
+	case action == "" && r.Method == http.MethodDelete:
+		if err := store.Request(operator.DeleteGroup, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateGroupSessions(r, rr, store, group.Members, "group_deleted")
+	case action == "members" && username != "" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		target := &requests.Request{Context: rr.GetContext()}
+		target.User.Username = username
+		if err := store.Request(operator.IdentifyUser, target); err != nil || target.User.Username == "nobody" {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, "user not found")
+		}
+		req.User.Username = target.User.Username
+		req.User.Email = target.User.Email
+		op := operator.AddGroupMember
+		if r.Method == http.MethodDelete {
+			op = operator.DeleteGroupMember
+		}
+		if err := store.Request(op, req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.terminateUserSessions(r, rr, target.User.Email, "group_membership_updated")
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	p.logAdminOperation(rr, usr, realm, r.Method+" group "+action, group.Name+" "+username)
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
+
+// terminateGroupSessions deletes the cached sessions of the members of a
+// group and revokes their tokens, because their inherited roles changed.
+func (p *Portal) terminateGroupSessions(r *http.Request, rr *requests.Request, store ids.IdentityStore, members []string, reason string) {
+	for _, member := range members {
+		req := &requests.Request{Context: rr.GetContext()}
+		req.User.Username = member
+		if err := store.Request(operator.IdentifyUser, req); err != nil || req.User.Username == "nobody" {
+			continue
+		}
+		p.terminateUserSessions(r, rr, req.User.Email, reason)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+type adminLifecycleRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// handleAPIAdminLifecycle returns the reports of the last runs of the user
+// lifecycle jobs and runs a job on demand, either for real or as a dry run.
+// The delegated administrators are not permitted, because the jobs span the
+// realms.
+//
+//	GET    /api/v1/admin/lifecycle
+//	POST   /api/v1/admin/lifecycle/{job}
+func (p *Portal) handleAPIAdminLifecycle(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope, arr []string) error {
+	if scope != nil {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+	if p.lifecycle == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, "user lifecycle jobs are not configured")
+	}
+
+	resp := make(map[string]interface{})
+	switch {
+	case len(arr) == 0 && r.Method == http.MethodGet:
+		resp["jobs"] = p.lifecycle.GetJobs()
+		resp["reports"] = p.lifecycle.GetReports()
+		return p.handleAPIAdminResponse(w, rr, resp)
+	case len(arr) == 1 && r.Method == http.MethodPost:
+	case len(arr) > 1:
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	req := &adminLifecycleRequest{}
+	if err := decodeAdminRequest(r, req); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	}
+	job, err := p.lifecycle.NewJob(arr[0], req.DryRun)
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, err.Error())
+	}
+	report := p.runLifecycleJob(job)
+	p.lifecycle.Record(report)
+	p.logAdminOperation(rr, usr, "", r.Method+" lifecycle/"+job.Name, "")
+	resp["report"] = report
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+type adminMaintenanceRequest struct {
+	Message string `json:"message"`
+	// ExpiresIn is the number of seconds the logins stay disabled. When
+	// zero, the logins stay disabled until the switch is removed.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// handleAPIAdminMaintenance lists and changes the maintenance switches
+// disabling the logins to all realms, to a realm, or with an identity store
+// or provider of the name. The sessions issued before are not affected. The
+// delegated administrators are not permitted, because the switches span the
+// realms.
+//
+//	GET    /api/v1/admin/maintenance
+//	PUT    /api/v1/admin/maintenance/{all|realm/{realm}|provider/{name}}
+//	DELETE /api/v1/admin/maintenance/{all|realm/{realm}|provider/{name}}
+func (p *Portal) handleAPIAdminMaintenance(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope, arr []string) error {
+	if scope != nil {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+
+	resp := make(map[string]interface{})
+	if len(arr) == 0 {
+		if r.Method != http.MethodGet {
+			return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		}
+		resp["maintenance"] = p.maintenance.List()
+		return p.handleAPIAdminResponse(w, rr, resp)
+	}
+
+	entry := &maintenance.Switch{Scope: arr[0]}
+	switch {
+	case len(arr) == 1 && arr[0] == maintenance.ScopeAll:
+	case len(arr) == 2 && (arr[0] == maintenance.ScopeRealm || arr[0] == maintenance.ScopeProvider):
+		entry.Name = arr[1]
+	default:
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		req := &adminMaintenanceRequest{}
+		if err := decodeAdminRequest(r, req); err != nil || req.ExpiresIn < 0 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		entry.Message = req.Message
+		entry.DisabledBy = usr.Claims.Email
+		if req.ExpiresIn > 0 {
+			entry.Until = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		}
+		saved, err := p.maintenance.Disable(entry)
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["maintenance"] = saved
+	case http.MethodDelete:
+		if err := p.maintenance.Enable(entry.Scope, entry.Name); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, err.Error())
+		}
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	p.logAdminOperation(rr, usr, entry.Name, r.Method+" maintenance/"+strings.Join(arr, "/"), "")
+	return p.handleAPIAdminResponse(w, rr, resp)
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type inviteRequest struct {
+	MaxUses int      `json:"max_uses"`
+	Roles   []string `json:"roles"`
+	// Lifetime is the number of seconds the invitation code is valid for.
+	Lifetime int `json:"lifetime"`
+}
+
+// handleAPIInvites lists, creates, and revokes the invitation codes of the
+// user registry.
+func (p *Portal) handleAPIInvites(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if p.userRegistry == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch r.Method {
+	case http.MethodGet:
+		resp["invites"] = p.userRegistry.GetInvites()
+	case http.MethodPost:
+		req := &inviteRequest{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		invite, err := p.userRegistry.CreateInvite(req.MaxUses, req.Roles, time.Duration(req.Lifetime)*time.Second, usr.Claims.Email)
+		if err != nil {
+			p.logger.Warn(
+				"failed creating invitation code",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.logger.Info(
+			"Created invitation code",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("created_by", usr.Claims.Email),
+			zap.Int("max_uses", invite.MaxUses),
+			zap.Strings("roles", invite.Roles),
+		)
+		resp["invite"] = invite
+	case http.MethodDelete:
+		code, err := getEndpointKeyID(r.URL.Path, "/api/invites/")
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if err := p.userRegistry.DeleteInvite(strings.TrimSpace(code)); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, err.Error())
+		}
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
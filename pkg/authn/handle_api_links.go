@@ -0,0 +1,96 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+type identityLinkRequest struct {
+	Realm string `json:"realm"`
+}
+
+// handleAPILinks lists, starts and removes the links of the identities at
+// identity providers to the local account of an authenticated user.
+func (p *Portal) handleAPILinks(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	store := p.getSelfServiceStore(rr, usr)
+	if store == nil || p.linker == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch r.Method {
+	case http.MethodGet:
+		if err := store.Request(operator.GetLinkedIdentities, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["links"] = rr.Response.Payload
+		resp["providers"] = p.getLinkableProviders()
+	case http.MethodPost:
+		var req identityLinkRequest
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1000))
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if err := json.Unmarshal(body, &req); err != nil || req.Realm == "" {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		location, err := p.startIdentityLink(w, r, rr, usr, req.Realm)
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["redirect_url"] = rr.Upstream.BaseURL + path.Join(rr.Upstream.BasePath, location)
+	case http.MethodDelete:
+		linkID, err := getEndpoint(r.URL.Path, "/api/links/")
+		if err != nil || linkID == "" || strings.Contains(linkID, "/") {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		rr.LinkedIdentity.ID = linkID
+		if err := store.Request(operator.DeleteLinkedIdentity, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, err.Error())
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "identity_unlinked"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", usr.Authenticator.Realm),
+			zap.String("username", rr.User.Username),
+			zap.String("linked_identity_id", linkID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		)
+		resp["unlinked"] = linkID
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type profileUpdateRequest struct {
+	Name     *string `json:"name"`
+	Phone    *string `json:"phone"`
+	Language *string `json:"language"`
+}
+
+// getSelfServiceStore returns the local identity store holding the identity
+// of an authenticated user.
+func (p *Portal) getSelfServiceStore(rr *requests.Request, usr *user.User) ids.IdentityStore {
+	if usr.Authenticator.Method != "local" {
+		return nil
+	}
+	store := p.getIdentityStoreByRealm(usr.Authenticator.Realm)
+	if store == nil {
+		return nil
+	}
+	rr.User.Username = usr.Claims.Subject
+	rr.User.Email = usr.Claims.Email
+	return store
+}
+
+// handleAPIProfile returns and updates the self-managed profile attributes
+// of an authenticated user.
+func (p *Portal) handleAPIProfile(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	store := p.getSelfServiceStore(rr, usr)
+	if store == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch r.Method {
+	case http.MethodGet:
+		if err := store.Request(operator.GetUser, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+	case http.MethodPatch, http.MethodPost:
+		req := &profileUpdateRequest{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(req); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		var changed []string
+		for _, f := range []struct {
+			name  string
+			value *string
+			dst   **string
+		}{
+			{"name", req.Name, &rr.Profile.Name},
+			{"phone", req.Phone, &rr.Profile.Phone},
+			{"language", req.Language, &rr.Profile.Language},
+		} {
+			if f.value == nil {
+				continue
+			}
+			v, err := p.profile.Validate(f.name, *f.value)
+			if err != nil {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+			}
+			*f.dst = &v
+			changed = append(changed, f.name)
+		}
+		if len(changed) == 0 {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if err := store.Request(operator.UpdateProfile, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "profile_updated"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", usr.Authenticator.Realm),
+			zap.String("username", rr.User.Username),
+			zap.Strings("fields", changed),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		)
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	resp["profile"] = rr.Response.Payload.(*identity.User).GetMetadata()
+	resp["editable_fields"] = p.profile.GetFields()
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// handleDataExport responds with the archive of the data held about an
+// authenticated user, i.e. the identity and the sign-in history.
+func (p *Portal) handleDataExport(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, store ids.IdentityStore) error {
+	if r.Method != http.MethodGet {
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	realm := usr.Authenticator.Realm
+	if err := store.Request(operator.ExportUser, rr); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+	}
+	resp := map[string]interface{}{
+		"realm":       realm,
+		"user":        rr.Response.Payload,
+		"exported_at": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if p.signIns != nil {
+		resp["sign_in_history"] = p.signIns.GetFingerprints(realm, rr.User.Username)
+	}
+	respBytes, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "data_exported"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("realm", realm),
+		zap.String("username", rr.User.Username),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("export")
+	rr.Response.Code = http.StatusOK
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+realm+`-`+rr.User.Username+`.json"`)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// handleAPIConsents lists the applications an authenticated user consented
+// to and withdraws the consents.
+func (p *Portal) handleAPIConsents(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	store := p.getSelfServiceStore(rr, usr)
+	if store == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch r.Method {
+	case http.MethodGet:
+		if err := store.Request(operator.GetConsents, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		resp["consents"] = rr.Response.Payload
+	case http.MethodDelete:
+		consentID, err := getEndpoint(r.URL.Path, "/api/consents/")
+		if err != nil || consentID == "" || strings.Contains(consentID, "/") {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		rr.Consent.ID = consentID
+		if err := store.Request(operator.DeleteConsent, rr); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, err.Error())
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "consent_withdrawn"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", usr.Authenticator.Realm),
+			zap.String("username", rr.User.Username),
+			zap.String("consent_id", consentID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		)
+		resp["withdrawn"] = consentID
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/delegation"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/registry"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type registrationVerdictRequest struct {
+	Comment string `json:"comment"`
+}
+
+// handleAPIRegistrations lists the registrations held by the user registry
+// and records administrator verdicts for them. The delegated administrators
+// access the registrations within their scope only.
+func (p *Portal) handleAPIRegistrations(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, scope *delegation.Scope) error {
+	if p.userRegistry == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	resp := make(map[string]interface{})
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := p.userRegistry.GetRegistrations()
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			var filtered []*registry.RegistrationRecord
+			for _, entry := range entries {
+				if entry.Status == status {
+					filtered = append(filtered, entry)
+				}
+			}
+			entries = filtered
+		}
+		if scope != nil {
+			var filtered []*registry.RegistrationRecord
+			for _, entry := range entries {
+				if err := p.permitsScopedRegistration(scope, entry); err == nil {
+					filtered = append(filtered, entry)
+				}
+			}
+			entries = filtered
+		}
+		resp["registrations"] = entries
+	case http.MethodPost:
+		endpoint, err := getEndpoint(r.URL.Path, "/registrations/")
+		if err != nil {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		arr := strings.Split(endpoint, "/")
+		if len(arr) != 2 || arr[0] == "" {
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		registrationID, verdict := arr[0], arr[1]
+		req := &registrationVerdictRequest{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(req); err != nil && err != io.EOF {
+				return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+			}
+		}
+		if scope != nil {
+			if err := p.permitsScopedRegistrationID(scope, registrationID); err != nil {
+				return p.handleJSONError(ctx, w, http.StatusForbidden, err.Error())
+			}
+		}
+		var entry *registry.RegistrationRecord
+		switch verdict {
+		case "approve":
+			entry, err = p.userRegistry.ApproveRegistration(registrationID, req.Comment, usr.Claims.Email, p.importRegisteredUser)
+		case "decline":
+			entry, err = p.userRegistry.DeclineRegistration(registrationID, req.Comment, usr.Claims.Email)
+		default:
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		if err != nil {
+			p.logger.Warn(
+				"failed recording registration verdict",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("registration_id", registrationID),
+				zap.String("verdict", verdict),
+				zap.Error(err),
+			)
+			return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+		}
+		p.logger.Info(
+			"Recorded registration verdict",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("registration_id", registrationID),
+			zap.String("status", entry.Status),
+			zap.String("reviewed_by", entry.ReviewedBy),
+		)
+		p.notifyRegistrationVerdict(rr, entry)
+		resp["registration"] = entry
+	default:
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	rr.Response.Code = http.StatusOK
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// importRegisteredUser adds an approved user to the identity store associated
+// with the user registry.
+func (p *Portal) importRegisteredUser(u *identity.User) error {
+	storeName := p.userRegistry.GetIdentityStoreName()
+	for _, store := range p.identityStores {
+		if store.GetName() != storeName {
+			continue
+		}
+		importer, ok := store.(interface {
+			ImportUser(*identity.User) error
+		})
+		if !ok {
+			return fmt.Errorf("identity store %q does not support user import", storeName)
+		}
+		return importer.ImportUser(u)
+	}
+	return fmt.Errorf("identity store %q not found", storeName)
+}
+
+func (p *Portal) notifyRegistrationVerdict(rr *requests.Request, entry *registry.RegistrationRecord) {
+	if entry.Email == "" {
+		return
+	}
+	regData := map[string]string{
+		"template":   "registration_verdict",
+		"session_id": rr.Upstream.SessionID,
+		"request_id": rr.ID,
+		"username":   entry.Username,
+		"email":      entry.Email,
+		"verdict":    entry.Status,
+		"timestamp":  time.Now().UTC().Format(time.UnixDate),
+	}
+	p.addBrandingData(p.getRequestRealm(nil, rr, nil), regData)
+	if err := p.userRegistry.Notify(regData); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("registration_id", entry.ID),
+			zap.String("registration_type", "registration_verdict"),
+			zap.Error(err),
+		)
+	}
+}
@@ -57,6 +57,15 @@ func (p *Portal) handleHTTPBasicLogin(ctx context.Context, w http.ResponseWriter
 		w.Write([]byte(http.StatusText(http.StatusBadRequest)))
 		return nil
 	}
+	if entry := p.getLoginMaintenance(realm); entry != nil {
+		return p.handleJSONMaintenance(ctx, w, r, rr, realm, entry)
+	}
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleJSONOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
 	if err := p.authenticateLoginRequest(ctx, w, r, rr, credentials); err != nil {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
 	}
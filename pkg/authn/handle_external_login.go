@@ -16,11 +16,18 @@ package authn
 
 import (
 	"context"
+	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"go.uber.org/zap"
+	"html"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func (p *Portal) handleHTTPExternalLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, authMethod string) error {
@@ -63,14 +70,33 @@ func (p *Portal) handleHTTPExternalLogin(ctx context.Context, w http.ResponseWri
 		)
 		return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
 	}
+	if entry := p.getLoginMaintenance(authRealm); entry != nil {
+		return p.handleHTTPMaintenance(ctx, w, r, rr, authRealm, entry)
+	}
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleHTTPOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	spanCtx, span := tracing.Start(ctx, "idp.Authenticate",
+		tracing.RealmKey.String(authRealm),
+		tracing.MethodKey.String(authMethod),
+	)
+	rr.Context = spanCtx
+	startedAt := time.Now()
 	err = provider.Request(operator.Authenticate, rr)
+	metrics.IdentityProviderLatency.Observe(time.Since(startedAt).Seconds(), authRealm, authMethod)
+	tracing.End(span, err)
+	rr.Context = ctx
 	if err != nil {
-		p.logger.Warn(
-			"Authentication failed",
-			zap.String("session_id", rr.Upstream.SessionID),
-			zap.String("request_id", rr.ID),
-			zap.Error(err),
-		)
+		metrics.Logins.Inc(authRealm, authMethod, "failure")
+		// Forget the failing provider, so that the user is not sent back to
+		// it from the login screen.
+		p.deleteIdentityProviderHint(w, r)
+		p.publishEvent(events.LoginFailure, r, rr, nil, map[string]interface{}{
+			"error": err.Error(),
+		})
 		return p.handleHTTPError(ctx, w, r, rr, http.StatusUnauthorized)
 	}
 	switch rr.Response.Code {
@@ -94,9 +120,15 @@ func (p *Portal) handleHTTPExternalLogin(ctx context.Context, w http.ResponseWri
 		)
 		http.Redirect(w, r, rr.Response.RedirectURL, http.StatusFound)
 		return nil
+	case http.StatusUnauthorized:
+		return p.handleNegotiateChallenge(ctx, w, r, rr)
 	default:
 		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
 	}
+	// The user signed in to link the identity to the local account.
+	if linked, err := p.completeIdentityLink(ctx, w, r, rr); linked {
+		return err
+	}
 	// User authenticated successfully.
 	if err := p.authorizeLoginRequest(ctx, w, r, rr); err != nil {
 		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
@@ -105,6 +137,29 @@ func (p *Portal) handleHTTPExternalLogin(ctx context.Context, w http.ResponseWri
 	return nil
 }
 
+// handleNegotiateChallenge asks the browser for SPNEGO token. The browsers
+// unable to negotiate render the page redirecting to the login screen.
+func (p *Portal) handleNegotiateChallenge(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.logger.Debug(
+		"Negotiate authentication challenge",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+	)
+	w.Header().Set("WWW-Authenticate", "Negotiate")
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusUnauthorized)
+	redirectURL := html.EscapeString(rr.Response.RedirectURL)
+	w.Write([]byte(fmt.Sprintf(`<html>
+  <head>
+    <meta http-equiv="refresh" content="0; url=%s" />
+  </head>
+  <body>
+    <p>Redirecting to <a href="%s">login page</a>.</p>
+  </body>
+</html>`, redirectURL, redirectURL)))
+	return nil
+}
+
 func (p *Portal) handleJavascriptCallbackIntercept(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	p.disableClientCache(w)
 	w.WriteHeader(200)
@@ -127,3 +182,30 @@ func (p *Portal) handleJavascriptCallbackIntercept(ctx context.Context, w http.R
 </html>`))
 	return nil
 }
+
+// getDriverAuthMethod returns the kind of the identity provider driver
+// registered via idp.RegisterIdentityProviderDriver and referenced in the
+// path, or an empty string when the path references none.
+func getDriverAuthMethod(s string) string {
+	for _, kind := range idp.GetIdentityProviderDriverKinds() {
+		if strings.Contains(s, "/"+kind+"/") {
+			return kind
+		}
+	}
+	return ""
+}
+
+// isExternalAuthMethod returns true when the users of the auth method
+// are authenticated by identity providers.
+func isExternalAuthMethod(s string) bool {
+	switch s {
+	case "oauth2", "saml", "kerberos", "mtls":
+		return true
+	}
+	for _, kind := range idp.GetIdentityProviderDriverKinds() {
+		if s == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -49,7 +49,7 @@ func (p *Portal) handleHTTPAppsMobileAccess(ctx context.Context, w http.Response
 		return p.handleHTTPRedirect(ctx, w, r, rr, "/login")
 	}
 
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, parsedUser)
 	resp.PageTitle = "Mobile Access"
 	resp.BaseURL(rr.Upstream.BasePath)
 
@@ -18,17 +18,26 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strings"
+	"net/url"
 
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/sso"
 	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 	"go.uber.org/zap"
 )
 
 type assumeRoleEntry struct {
 	Name         string
 	AccountID    string
+	AccountName  string
+	ProviderName string
+}
+
+type serviceProviderEntry struct {
+	ID           string
+	Name         string
+	EntityID     string
 	ProviderName string
 }
 
@@ -36,9 +45,20 @@ func (p *Portal) handleHTTPAppsSingleSignOn(ctx context.Context, w http.Response
 	p.disableClientCache(w)
 	p.injectRedirectURL(ctx, w, r, rr)
 
+	loginURL := "/login?redirect_url=" + r.RequestURI
+	if req, err := sso.ParseRequestURL(r); err == nil && req.Kind == sso.SingleSignOnRequest {
+		// The authentication request of a service provider is passed to the
+		// login page with HTTP-Redirect binding.
+		uri, err := sso.GetRedirectBindingURI(r)
+		if err != nil {
+			return p.handleHTTPRenderError(ctx, w, r, rr, err)
+		}
+		loginURL = "/login?redirect_url=" + url.QueryEscape(uri)
+	}
+
 	if parsedUser == nil {
 		if rr.Response.RedirectURL == "" {
-			return p.handleHTTPRedirect(ctx, w, r, rr, "/login?redirect_url="+r.RequestURI)
+			return p.handleHTTPRedirect(ctx, w, r, rr, loginURL)
 		}
 		return p.handleHTTPRedirect(ctx, w, r, rr, "/login")
 	}
@@ -54,7 +74,7 @@ func (p *Portal) handleHTTPAppsSingleSignOn(ctx context.Context, w http.Response
 			zap.Error(err),
 		)
 		if rr.Response.RedirectURL == "" {
-			return p.handleHTTPRedirect(ctx, w, r, rr, "/login?redirect_url="+r.RequestURI)
+			return p.handleHTTPRedirect(ctx, w, r, rr, loginURL)
 		}
 		return p.handleHTTPRedirect(ctx, w, r, rr, "/login")
 	}
@@ -71,13 +91,25 @@ func (p *Portal) handleHTTPAppsSingleSignOn(ctx context.Context, w http.Response
 		return p.handleHTTPRenderError(ctx, w, r, rr, err)
 	}
 
-	roles := fetchSingleSignOnRoles(provider.GetName(), usr)
+	roles, err := fetchSingleSignOnRoles(provider, usr)
+	if err != nil {
+		p.logger.Warn(
+			"SSO request failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("provider_name", provider.GetName()),
+			zap.Error(err),
+		)
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusInternalServerError)
+	}
 
 	switch req.Kind {
 	case sso.MetadataRequest:
 		return p.handleHTTPAppsSingleSignOnMetadata(ctx, w, r, rr, provider, roles)
 	case sso.AssumeRoleRequest:
 		return p.handleHTTPAppsSingleSignOnAssumeRole(ctx, w, r, rr, provider, roles, usr)
+	case sso.SingleSignOnRequest, sso.LoginRequest:
+		return p.handleHTTPAppsSingleSignOnAuthnRequest(ctx, w, r, rr, provider, req, usr)
 	case sso.MenuRequest:
 		return p.handleHTTPAppsSingleSignOnMenu(ctx, w, r, rr, provider, roles, usr)
 	}
@@ -165,12 +197,36 @@ func (p *Portal) handleHTTPAppsSingleSignOnAssumeRole(ctx context.Context, w htt
 func (p *Portal) handleHTTPAppsSingleSignOnMenu(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request,
 	provider sso.SingleSignOnProvider, roles []*assumeRoleEntry, usr *user.User) error {
 
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, usr)
 	resp.PageTitle = "AWS SSO"
 	resp.BaseURL(rr.Upstream.BasePath)
+	resp.Data["driver"] = provider.GetDriver()
 	resp.Data["role_count"] = len(roles)
 	resp.Data["roles"] = roles
 
+	if provider.GetDriver() == "saml" {
+		// The catalog of the service providers available to the user.
+		entries := []*serviceProviderEntry{}
+		for _, sp := range provider.GetServiceProviders() {
+			if !sp.Permits(usr.Claims.Roles) {
+				continue
+			}
+			entry := &serviceProviderEntry{
+				ID:           sp.ID,
+				Name:         sp.Name,
+				EntityID:     sp.EntityID,
+				ProviderName: provider.GetName(),
+			}
+			if entry.Name == "" {
+				entry.Name = sp.ID
+			}
+			entries = append(entries, entry)
+		}
+		resp.PageTitle = "Applications"
+		resp.Data["service_provider_count"] = len(entries)
+		resp.Data["service_providers"] = entries
+	}
+
 	content, err := p.ui.Render("apps_sso", resp)
 	if err != nil {
 		return p.handleHTTPRenderError(ctx, w, r, rr, err)
@@ -178,6 +234,58 @@ func (p *Portal) handleHTTPAppsSingleSignOnMenu(ctx context.Context, w http.Resp
 	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
 }
 
+// handleHTTPAppsSingleSignOnAuthnRequest responds to the authentication
+// requests of the service providers, and to the IdP-initiated logins, with a
+// signed assertion.
+func (p *Portal) handleHTTPAppsSingleSignOnAuthnRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request,
+	provider sso.SingleSignOnProvider, req *sso.Request, usr *user.User) error {
+	var authnRequest *sso.AuthnRequest
+	var err error
+	if req.Kind == sso.LoginRequest {
+		authnRequest, err = provider.NewAuthnRequest(r, req.Params)
+	} else {
+		authnRequest, err = provider.ParseAuthnRequest(r)
+	}
+	if err != nil {
+		p.logger.Warn(
+			"SSO request failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("provider_name", provider.GetName()),
+			zap.Error(err),
+		)
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+	}
+
+	if err := provider.RespondAuthnRequest(w, authnRequest, usr); err != nil {
+		p.logger.Warn(
+			"SSO request failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("provider_name", provider.GetName()),
+			zap.String("service_provider_id", authnRequest.ServiceProviderID),
+			zap.String("username", usr.Claims.Subject),
+			zap.Error(err),
+		)
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusForbidden)
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "saml_assertion_issued"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("provider_name", provider.GetName()),
+		zap.String("service_provider_id", authnRequest.ServiceProviderID),
+		zap.String("entity_id", authnRequest.EntityID),
+		zap.Bool("idp_initiated", authnRequest.IdpInitiated),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("sso/saml")
+	return nil
+}
+
 func (p *Portal) fetchSingleSignOnProvider(providerName string) (sso.SingleSignOnProvider, error) {
 	for _, provider := range p.ssoProviders {
 		if provider.GetName() == providerName {
@@ -191,22 +299,23 @@ func (p *Portal) parseSingleSignOnProviderName() (string, string, error) {
 	return "aws", "metadata", nil
 }
 
-func fetchSingleSignOnRoles(providerName string, usr *user.User) []*assumeRoleEntry {
+func fetchSingleSignOnRoles(provider sso.SingleSignOnProvider, usr *user.User) ([]*assumeRoleEntry, error) {
 	roles := []*assumeRoleEntry{}
-	for _, entry := range usr.Claims.Roles {
-		arr := strings.Split(entry, "/")
-		if len(arr) != 3 {
-			continue
-		}
-		if arr[0] != "aws" {
-			continue
-		}
+	if provider.GetDriver() != "aws" {
+		return roles, nil
+	}
+	entries, err := provider.GetAssumeRoles(usr.Claims.Roles)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
 		role := &assumeRoleEntry{
-			Name:         arr[2],
-			AccountID:    arr[1],
-			ProviderName: providerName,
+			Name:         entry.Name,
+			AccountID:    entry.AccountID,
+			AccountName:  entry.AccountName,
+			ProviderName: provider.GetName(),
 		}
 		roles = append(roles, role)
 	}
-	return roles
+	return roles, nil
 }
@@ -18,12 +18,18 @@ import (
 	"context"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/idp/kerberos"
 	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"github.com/greenpau/go-authcrunch/pkg/util/validate"
 	"go.uber.org/zap"
 	"net/http"
 	"net/url"
@@ -45,7 +51,39 @@ func (p *Portal) handleHTTPLogin(ctx context.Context, w http.ResponseWriter, r *
 }
 
 func (p *Portal) handleHTTPLoginScreen(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
-	resp := p.ui.GetArgs()
+	// Route the user with the login hint matching a realm discovery rule,
+	// e.g. the one sent by an application, past the login screen.
+	if hint := r.URL.Query().Get("login_hint"); hint != "" && p.config.RealmDiscoveryConfig != nil {
+		if err := validate.LoginHint(hint, []string{"email"}); err == nil {
+			if realm, found := p.config.RealmDiscoveryConfig.Match(hint); found {
+				return p.handleHTTPLoginIdentity(ctx, w, r, rr, map[string]string{"user": hint, "realm": realm})
+			}
+		}
+	}
+
+	// Offer the returning user to continue with the identity provider the
+	// user last authenticated with, unless the user chose another account.
+	var hintedProvider idp.IdentityProvider
+	if r.URL.Query().Get("use_another_account") == "true" {
+		p.deleteIdentityProviderHint(w, r)
+	} else {
+		hintedProvider = p.getIdentityProviderHint(r, rr)
+	}
+	if hintedProvider != nil && p.getLoginMaintenance(hintedProvider.GetRealm()) != nil {
+		hintedProvider = nil
+	}
+
+	// Start Kerberos negotiation for the browsers of domain-joined
+	// workstations. The failed negotiation returns to the login screen.
+	if hintedProvider == nil && r.URL.Query().Get("negotiate") != "false" {
+		for _, provider := range p.identityProviders {
+			if kp, ok := provider.(*kerberos.IdentityProvider); ok && kp.Negotiable(r) && p.getLoginMaintenance(kp.GetRealm()) == nil {
+				return p.handleHTTPRedirect(ctx, w, r, rr, path.Join(kp.GetKind(), kp.GetRealm()))
+			}
+		}
+	}
+
+	resp := p.getUIArgs(r, rr, nil)
 	resp.BaseURL(rr.Upstream.BasePath)
 	if p.config.UI.Title == "" {
 		resp.PageTitle = "Sign In"
@@ -54,6 +92,17 @@ func (p *Portal) handleHTTPLoginScreen(ctx context.Context, w http.ResponseWrite
 	}
 	resp.Data["authenticated"] = rr.Response.Authenticated
 	resp.Data["login_options"] = p.loginOptions
+	if entry := p.getLoginMaintenance(""); entry != nil {
+		resp.Data["maintenance_message"] = p.getMaintenanceMessage(entry, "")
+	}
+	if hintedProvider != nil {
+		resp.Data["idp_hint"] = map[string]interface{}{
+			"realm":    hintedProvider.GetRealm(),
+			"text":     hintedProvider.GetLoginIcon().GetConfig()["text"],
+			"endpoint": getIdentityProviderEndpoint(hintedProvider),
+			"delay":    p.idpHints.GetRedirectDelay(),
+		}
+	}
 
 	content, err := p.ui.Render("login", resp)
 	if err != nil {
@@ -71,6 +120,16 @@ func (p *Portal) getIdentityProviderByRealm(realm string) idp.IdentityProvider {
 	return nil
 }
 
+// getIdentityProviderEndpoint returns the path of the login endpoint of the
+// identity provider.
+func getIdentityProviderEndpoint(provider idp.IdentityProvider) string {
+	switch provider.GetKind() {
+	case "oauth":
+		return path.Join(provider.GetKind()+"2", provider.GetRealm())
+	}
+	return path.Join(provider.GetKind(), provider.GetRealm())
+}
+
 func (p *Portal) getIdentityStoreByRealm(realm string) ids.IdentityStore {
 	for _, store := range p.identityStores {
 		if store.GetRealm() == realm {
@@ -113,6 +172,42 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 	if err != nil {
 		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, err.Error())
 	}
+	return p.handleHTTPLoginIdentity(ctx, w, r, rr, identity)
+}
+
+// handleHTTPLoginIdentity starts the login of the identified user. The user
+// with the email address matching a realm discovery rule is routed to the
+// realm of the rule. When the realm belongs to an identity provider, the
+// user is redirected to the provider with the email address as the login
+// hint. The break-glass accounts bypass the realm discovery and the
+// maintenance switches.
+func (p *Portal) handleHTTPLoginIdentity(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, identity map[string]string) error {
+	if p.breakGlass != nil && p.breakGlass.IsUsername(identity["user"]) {
+		return p.startBreakGlassLogin(ctx, w, r, rr, identity["user"])
+	}
+
+	if realm, found := p.config.RealmDiscoveryConfig.Match(identity["user"]); found {
+		p.logger.Debug(
+			"discovered user realm",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("user", identity["user"]),
+			zap.String("realm", realm),
+		)
+		if provider := p.getIdentityProviderByRealm(realm); provider != nil {
+			location := getIdentityProviderEndpoint(provider) + "?login_hint=" + url.QueryEscape(identity["user"])
+			return p.handleHTTPRedirect(ctx, w, r, rr, location)
+		}
+		identity["realm"] = realm
+	}
+
+	if entry := p.getLoginMaintenance(identity["realm"]); entry != nil {
+		return p.handleHTTPMaintenance(ctx, w, r, rr, identity["realm"], entry)
+	}
+
+	if chain := p.config.LoginChainConfig.GetChain(identity["realm"]); chain != nil {
+		return p.startLoginChain(ctx, w, r, rr, chain, identity["user"], 0)
+	}
 
 	// Identify the backend associated with the user and determine challenges.
 	if err := p.identifyUserRequest(rr, identity); err != nil {
@@ -120,6 +215,13 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
 	}
 
+	return p.startSandboxSession(ctx, w, r, rr)
+}
+
+// startSandboxSession creates a temporary user for the identified user and
+// redirects the requester to sandbox for authentication. The checkpoints
+// of the types in passed, e.g. password, are considered satisfied.
+func (p *Portal) startSandboxSession(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, passed ...string) error {
 	// Create a temporary user.
 	m := make(map[string]interface{})
 	m["sub"] = rr.User.Username
@@ -130,6 +232,9 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 	if len(rr.User.Roles) > 0 {
 		m["roles"] = rr.User.Roles
 	}
+	if len(rr.User.Organizations) > 0 {
+		m["org"] = rr.User.Organizations
+	}
 	m["jti"] = rr.Upstream.SessionID
 	m["exp"] = time.Now().Add(time.Duration(5) * time.Second).UTC().Unix()
 	m["iat"] = time.Now().UTC().Unix()
@@ -141,6 +246,7 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 	m["addr"] = addrutil.GetSourceAddress(r)
 
 	combineGroupRoles(m)
+	p.mapGroupRoles(rr.Upstream.Realm, rr.User.Groups, m)
 
 	// Perform user claim transformation if necessary.
 	if err := p.transformUser(ctx, rr, m); err != nil {
@@ -149,6 +255,7 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 
 	// Inject portal-specific roles.
 	injectPortalRoles(m)
+	p.shapeClaims(m)
 	usr, err := user.NewUser(m)
 	if err != nil {
 		rr.Response.Code = http.StatusBadRequest
@@ -168,6 +275,23 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 		rr.Response.Code = http.StatusInternalServerError
 		return err
 	}
+	if err := p.injectTermsCheckpoint(rr, usr); err != nil {
+		p.logger.Warn(
+			"user terms checkpoint injection failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		rr.Response.Code = http.StatusInternalServerError
+		return err
+	}
+	for _, checkpoint := range usr.Checkpoints {
+		for _, s := range passed {
+			if checkpoint.Type == s {
+				checkpoint.Passed = true
+			}
+		}
+	}
 
 	// Build a list of additional user-specific UI links.
 	if v, exists := m["frontend_links"]; exists {
@@ -202,6 +326,7 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 	)
 
 	w.Header().Set("Set-Cookie", p.cookie.GetCookie(addrutil.GetSourceHost(r), p.cookie.SandboxID, usr.Authenticator.TempSecret))
+	p.setContinuationCookies(w, r, rr, usr)
 	w.Header().Set("Location", redirectLocation)
 	w.WriteHeader(http.StatusSeeOther)
 	return nil
@@ -270,32 +395,77 @@ func (p *Portal) authenticateLoginRequest(ctx context.Context, w http.ResponseWr
 		rr.Response.Code = http.StatusUnauthorized
 		return err
 	}
+	if p.isBreakGlassAccount(backend.GetRealm(), rr.User.Username) {
+		rr.Response.Code = http.StatusUnauthorized
+		return errors.ErrBreakGlassLoginMethod.WithArgs(rr.User.Username)
+	}
 
-	if len(rr.User.Challenges) != 1 {
+	if len(rr.User.Challenges) > 2 {
 		return fmt.Errorf("detected too many auth challenges")
 	}
-	if rr.User.Challenges[0] != "password" {
+	if rr.User.Challenges[0] != "password" || (len(rr.User.Challenges) == 2 && rr.User.Challenges[1] != "mfa") {
 		return fmt.Errorf("detected unsupported auth challenges")
 	}
 	if err := backend.Request(operator.Authenticate, rr); err != nil {
 		rr.Response.Code = http.StatusUnauthorized
 		return err
 	}
+	// The users with MFA tokens provide the passcode of the authenticator
+	// app in the same request.
+	if len(rr.User.Challenges) == 2 {
+		if err := p.verifyLoginPasscode(backend, rr, credentials["passcode"]); err != nil {
+			rr.Response.Code = http.StatusUnauthorized
+			return err
+		}
+	}
 	rr.Response.Code = http.StatusOK
 	return nil
 }
 
+// verifyLoginPasscode verifies the passcode against the authenticator app
+// tokens of the authenticated user.
+func (p *Portal) verifyLoginPasscode(backend ids.IdentityStore, rr *requests.Request, passcode string) error {
+	if passcode == "" {
+		return errors.ErrLoginPasscodeRequired
+	}
+	if err := backend.Request(operator.GetMfaTokens, rr); err != nil {
+		return err
+	}
+	bundle := rr.Response.Payload.(*identity.MfaTokenBundle)
+	for _, token := range bundle.Get() {
+		if token.Type != "totp" {
+			continue
+		}
+		if err := token.ValidateCode(passcode); err == nil {
+			metrics.MfaChallenges.Inc(rr.Upstream.Realm, "totp", "success")
+			return nil
+		}
+	}
+	metrics.MfaChallenges.Inc(rr.Upstream.Realm, "totp", "failure")
+	return errors.ErrLoginPasscodeInvalid
+}
+
 func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	if isExternalAuthMethod(rr.Upstream.Method) {
+		// The users signing in with linked identities get their local accounts.
+		p.resolveLinkedIdentity(rr)
+	}
 	backend := p.getAuthenticatorByRealm(rr.Upstream.Realm)
 	if backend == nil {
 		rr.Response.Code = http.StatusBadRequest
 		return fmt.Errorf("no matching realm found")
 	}
+	// The logins started before the realm was switched to maintenance do
+	// not complete.
+	if p.getLoginMaintenance(rr.Upstream.Realm) != nil && !p.isBreakGlassAccount(rr.Upstream.Realm, rr.User.Username) {
+		rr.Response.Code = http.StatusServiceUnavailable
+		return errors.ErrLoginDisabled.WithArgs(rr.Upstream.Realm)
+	}
 
 	m := make(map[string]interface{})
 
-	switch rr.Upstream.Method {
-	case "oauth2", "saml":
+	switch {
+	case isExternalAuthMethod(rr.Upstream.Method):
 		switch pm := rr.Response.Payload.(type) {
 		case map[string]interface{}:
 			m = pm
@@ -313,7 +483,11 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 		if len(rr.User.Roles) > 0 {
 			m["roles"] = rr.User.Roles
 		}
+		if len(rr.User.Organizations) > 0 {
+			m["org"] = rr.User.Organizations
+		}
 	}
+	p.normalizeIdentity(m)
 
 	m["jti"] = rr.Upstream.SessionID
 	m["exp"] = time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix()
@@ -324,12 +498,14 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 	}
 	m["iss"] = util.GetIssuerURL(r)
 	m["addr"] = addrutil.GetSourceAddress(r)
+	p.mapGroupRoles(rr.Upstream.Realm, rr.User.Groups, m)
 
 	// Perform user claim transformation if necessary.
 	if err := p.transformUser(ctx, rr, m); err != nil {
 		return err
 	}
 	injectPortalRoles(m)
+	p.shapeClaims(m)
 	usr, err := user.NewUser(m)
 	if err != nil {
 		rr.Response.Code = http.StatusUnauthorized
@@ -374,6 +550,7 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 		zap.Any("backend", usr.Authenticator),
 		zap.Any("user", m),
 	)
+	p.recordUsage("login/" + backend["kind"])
 	p.grantAccess(ctx, w, r, rr, usr)
 	return nil
 }
@@ -381,9 +558,16 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) {
 	var redirectLocation string
 
-	usr.SetExpiresAtClaim(time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix())
+	lifetime := p.getTokenLifetime(usr, rr.Upstream.Realm)
+	expiresAt := time.Now().Add(time.Duration(lifetime) * time.Second).UTC().Unix()
+	session, renewalToken := p.startSession(rr, usr)
+	if session != nil {
+		expiresAt = session.GetExpiresAt(lifetime)
+	}
+	usr.SetExpiresAtClaim(expiresAt)
 	usr.SetIssuedAtClaim(time.Now().UTC().Unix())
 	usr.SetNotBeforeClaim(time.Now().Add(time.Duration(60) * time.Second * -1).UTC().Unix())
+	p.setTokenAudience(usr)
 
 	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
 		p.logger.Warn(
@@ -392,10 +576,21 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 			zap.String("request_id", rr.ID),
 			zap.Error(err),
 		)
+		if session != nil {
+			p.sessionPolicy.End(renewalToken)
+		}
 		rr.Response.Code = http.StatusInternalServerError
 		return
 	}
 
+	metrics.Logins.Inc(rr.Upstream.Realm, rr.Upstream.Method, "success")
+	metrics.TokensIssued.Inc(rr.Upstream.Realm, "login")
+	p.publishEvent(events.LoginSuccess, r, rr, usr, nil)
+	p.observeBreakGlassLogin(r, rr, usr)
+	p.observeSignIn(r, rr, usr)
+	p.recordLogin(r, rr, usr, "success")
+	p.reactivateAccount(r, rr, usr)
+
 	h := addrutil.GetSourceHost(r)
 
 	rr.Response.Authenticated = true
@@ -403,7 +598,10 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 	p.sessions.Add(rr.Upstream.SessionID, usr)
 
 	w.Header().Set("Authorization", "Bearer "+usr.Token)
-	w.Header().Set("Set-Cookie", p.cookie.GetCookie(h, usr.TokenName, usr.Token))
+	p.setTokenCookies(w, r, rr, h, usr.TokenName, usr.Token)
+	if session != nil {
+		p.setSessionRenewalCookie(w, r, session, renewalToken)
+	}
 
 	// Add a cookie with identity token, if id_token is available.
 	if rr.Response.IdentityTokenCookie.Enabled {
@@ -418,11 +616,13 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 
 	// Delete sandbox cookie, if present.
 	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.SandboxID))
+	p.deleteContinuationCookies(w, r, rr, h, usr.Authenticator.TempSessionID)
+	p.setIdentityProviderHint(w, r, rr, usr)
 
 	// Determine whether redirect cookie is present and reditect to the page that
 	// forwarded a user to the authentication portal.
 	if cookie, err := r.Cookie(p.cookie.Referer); err == nil {
-		if redirectURL, err := url.Parse(cookie.Value); err == nil {
+		if redirectURL, err := p.getCookieRedirectURL(r, rr, cookie.Value); err == nil {
 			redirectLocation = redirectURL.String()
 			p.logger.Debug(
 				"Detected cookie-based redirect",
@@ -430,8 +630,8 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 				zap.String("request_id", rr.ID),
 				zap.String("redirect_url", redirectLocation),
 			)
-			w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.Referer))
 		}
+		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.Referer))
 	}
 	if redirectLocation == "" {
 		// Redirect authenticated user to portal page when no redirect cookie found.
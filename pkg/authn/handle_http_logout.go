@@ -27,7 +27,7 @@ import (
 
 func (p *Portal) deleteAuthCookies(w http.ResponseWriter, r *http.Request) {
 	for tokenName := range p.validator.GetAuthCookies() {
-		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), tokenName))
+		p.deleteTokenCookies(w, r, addrutil.GetSourceHost(r), tokenName)
 	}
 }
 
@@ -36,10 +36,16 @@ func (p *Portal) handleHTTPLogout(ctx context.Context, w http.ResponseWriter, r
 	p.injectRedirectURL(ctx, w, r, rr)
 	h := addrutil.GetSourceHost(r)
 	for tokenName := range p.validator.GetAuthCookies() {
-		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, tokenName))
+		p.deleteTokenCookies(w, r, h, tokenName)
 	}
 	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.Referer))
 	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.SessionID))
+	p.endSession(w, r)
+	p.recordUsage("logout")
+
+	if parsedUser != nil && parsedUser.Token != "" {
+		p.revokeToken(r, rr, parsedUser, "logout")
+	}
 
 	if parsedUser != nil && parsedUser.Claims != nil {
 		p.logger.Debug(
@@ -68,7 +74,7 @@ func (p *Portal) handleHTTPLogoutWithLocalRedirect(ctx context.Context, w http.R
 	p.injectRedirectURL(ctx, w, r, rr)
 	h := addrutil.GetSourceHost(r)
 	for tokenName := range p.validator.GetAuthCookies() {
-		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, tokenName))
+		p.deleteTokenCookies(w, r, h, tokenName)
 	}
 	if rr.Response.RedirectURL == "" {
 		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.Referer))
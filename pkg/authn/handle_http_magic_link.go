@@ -0,0 +1,273 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type magicLinkRequest struct {
+	view    string
+	message string
+	realm   string
+	token   string
+}
+
+func (p *Portal) handleHTTPMagicLink(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	p.disableClientCache(w)
+	if p.magicLinks == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
+	}
+	if usr != nil {
+		return p.handleHTTPRedirect(ctx, w, r, rr, "/portal")
+	}
+
+	if r.Method == http.MethodPost {
+		if !p.parseRecoverForm(r) {
+			req := &magicLinkRequest{view: "request", message: "Sign in link request is non compliant"}
+			return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+		}
+		if token := r.Form.Get("token"); token != "" {
+			// The user confirmed the sign in on the device other than the
+			// one the link was requested from.
+			return p.handleHTTPMagicLinkLogin(ctx, w, r, rr, token, true)
+		}
+		return p.handleHTTPMagicLinkRequest(ctx, w, r, rr)
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		req := &magicLinkRequest{view: "request", realm: r.URL.Query().Get("realm")}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+	return p.handleHTTPMagicLinkLogin(ctx, w, r, rr, token, false)
+}
+
+func (p *Portal) handleHTTPMagicLinkScreen(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, req *magicLinkRequest) error {
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.PageTitle = "Sign In"
+	resp.Data["view"] = req.view
+	switch req.view {
+	case "request":
+		if req.realm == "" {
+			if v, exists := p.loginOptions["default_realm"]; exists {
+				req.realm, _ = v.(string)
+			}
+		}
+		resp.Data["realm"] = req.realm
+	case "confirm":
+		resp.Data["token"] = req.token
+	case "failed":
+		resp.Data["message"] = req.message
+	}
+	if req.message != "" && req.view != "failed" {
+		resp.Message = req.message
+	}
+	content, err := p.ui.Render("magic_link", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
+}
+
+// getMagicLinkStore returns the local identity store with the enabled
+// magic link login in the provided realm.
+func (p *Portal) getMagicLinkStore(realm string) ids.IdentityStore {
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil || store.GetKind() != "local" {
+		return nil
+	}
+	if icon := store.GetLoginIcon(); icon == nil || !icon.MagicLinkEnabled {
+		return nil
+	}
+	return store
+}
+
+func (p *Portal) handleHTTPMagicLinkRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	email := strings.ToLower(strings.TrimSpace(r.Form.Get("email")))
+	realm := r.Form.Get("realm")
+	srcAddr := addrutil.GetSourceAddress(r)
+
+	store := p.getMagicLinkStore(realm)
+	if store == nil || !strings.Contains(email, "@") {
+		req := &magicLinkRequest{view: "request", realm: realm, message: "Sign in with email link is not available"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	if err := p.magicLinks.Allow("email:"+email, "ip:"+srcAddr); err != nil {
+		p.logger.Warn(
+			"Throttled magic link request",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", srcAddr),
+			zap.String("email", email),
+		)
+		req := &magicLinkRequest{view: "request", realm: realm, message: "Too many sign in link requests, please try again later"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	// The response does not reveal whether the email address exists.
+	resp := &magicLinkRequest{view: "sent"}
+
+	identity := &requests.Request{User: requests.User{Username: email}}
+	if err := store.Request(operator.IdentifyUser, identity); err != nil || identity.User.Username == "nobody" {
+		p.logger.Debug(
+			"Magic link requested for unknown email address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("email", email),
+		)
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, resp)
+	}
+	if p.isBreakGlassAccount(realm, identity.User.Username) {
+		p.logger.Warn(
+			"Magic link requested for break glass account",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", srcAddr),
+			zap.String("username", identity.User.Username),
+		)
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, resp)
+	}
+
+	token, err := p.magicLinks.IssueToken(realm, identity.User.Username, identity.User.Email, srcAddr, r.UserAgent())
+	if err != nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusInternalServerError)
+	}
+
+	loginURL := rr.Upstream.BaseURL + path.Join(rr.Upstream.BasePath, "magic-link") + "?token=" + url.QueryEscape(token)
+	data := map[string]string{
+		"session_id": rr.Upstream.SessionID,
+		"request_id": rr.ID,
+		"username":   identity.User.Username,
+		"email":      identity.User.Email,
+		"login_url":  loginURL,
+		"lifetime":   p.magicLinks.GetTokenLifetime().String(),
+		"src_ip":     srcAddr,
+		"timestamp":  time.Now().UTC().Format(time.UnixDate),
+	}
+	p.addBrandingData(realm, data)
+	p.addLocationData(srcAddr, data)
+	if err := p.magicLinks.Notify(data); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("notification_type", "magic_link"),
+			zap.Error(err),
+		)
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, resp)
+	}
+
+	p.logger.Info(
+		"Sent magic link",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", identity.User.Username),
+		zap.String("realm", realm),
+		zap.String("src_ip", srcAddr),
+	)
+	p.recordUsage("magic_link/request")
+	return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, resp)
+}
+
+// handleHTTPMagicLinkLogin redeems the login link and redirects the user to
+// sandbox with the password checkpoint satisfied. The remaining checkpoints,
+// e.g. mfa, still apply. When the link is opened on the device other than
+// the one it was requested from, the user must confirm the sign in first.
+func (p *Portal) handleHTTPMagicLinkLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, token string, confirmed bool) error {
+	srcAddr := addrutil.GetSourceAddress(r)
+	tkn, err := p.magicLinks.VerifyToken(token)
+	if err != nil {
+		p.logger.Debug(
+			"Invalid magic link",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		req := &magicLinkRequest{view: "failed", message: "The sign in link is invalid or has expired"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	if tkn.DeviceMismatch(srcAddr, r.UserAgent()) && !confirmed {
+		p.logger.Warn(
+			"Magic link opened on different device",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", tkn.Username),
+			zap.String("requested_src_ip", tkn.SourceAddress),
+			zap.String("src_ip", srcAddr),
+		)
+		req := &magicLinkRequest{
+			view:    "confirm",
+			token:   token,
+			message: "The sign in link was requested from a different device or network",
+		}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	if store := p.getMagicLinkStore(tkn.Realm); store == nil {
+		req := &magicLinkRequest{view: "failed", message: "Sign in with email link is not available"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleHTTPOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	if _, err := p.magicLinks.RedeemToken(token); err != nil {
+		req := &magicLinkRequest{view: "failed", message: "The sign in link is invalid or has expired"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	identity := map[string]string{"realm": tkn.Realm, "user": tkn.Username}
+	if err := p.identifyUserRequest(rr, identity); err != nil || !strings.EqualFold(rr.User.Email, tkn.Email) {
+		p.logger.Warn(
+			"Failed magic link login",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", tkn.Username),
+			zap.Any("error", err),
+		)
+		req := &magicLinkRequest{view: "failed", message: "The sign in link is invalid or has expired"}
+		return p.handleHTTPMagicLinkScreen(ctx, w, r, rr, req)
+	}
+
+	p.logger.Info(
+		"Redeemed magic link",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", tkn.Username),
+		zap.String("realm", tkn.Realm),
+		zap.String("src_ip", srcAddr),
+		zap.Bool("device_confirmed", confirmed),
+	)
+	p.recordUsage("magic_link/login")
+	return p.startSandboxSession(ctx, w, r, rr, "password")
+}
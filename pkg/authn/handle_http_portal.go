@@ -21,7 +21,6 @@ import (
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 	"go.uber.org/zap"
 	"net/http"
-	"net/url"
 )
 
 func (p *Portal) handleHTTPPortal(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, parsedUser *user.User) error {
@@ -47,7 +46,7 @@ func (p *Portal) handleHTTPPortal(ctx context.Context, w http.ResponseWriter, r
 
 func (p *Portal) handleHTTPPortalScreen(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
 	if cookie, err := r.Cookie(p.cookie.Referer); err == nil {
-		redirectURL, err := url.Parse(cookie.Value)
+		redirectURL, err := p.getCookieRedirectURL(r, rr, cookie.Value)
 		if err == nil {
 			p.logger.Debug(
 				"Cookie-based redirect",
@@ -60,8 +59,9 @@ func (p *Portal) handleHTTPPortalScreen(ctx context.Context, w http.ResponseWrit
 			w.WriteHeader(http.StatusSeeOther)
 			return nil
 		}
+		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), p.cookie.Referer))
 	}
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, usr)
 	resp.BaseURL(rr.Upstream.BasePath)
 	resp.PageTitle = "Applications"
 	if len(usr.FrontendLinks) > 0 {
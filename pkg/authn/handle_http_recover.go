@@ -16,12 +16,293 @@ package authn
 
 import (
 	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 )
 
+type recoverRequest struct {
+	view    string
+	message string
+	realm   string
+	token   string
+}
+
 func (p *Portal) handleHTTPRecover(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
 	p.disableClientCache(w)
-	// TODO(greenpau): implement
-	return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
+	if p.recovery == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/forgot") {
+		if r.Method == http.MethodPost {
+			return p.handleHTTPRecoverRequest(ctx, w, r, rr)
+		}
+		req := &recoverRequest{view: "forgot", realm: r.URL.Query().Get("realm")}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	if r.Method == http.MethodPost {
+		return p.handleHTTPRecoverReset(ctx, w, r, rr)
+	}
+	token := r.URL.Query().Get("token")
+	if _, err := p.recovery.VerifyToken(token); err != nil {
+		p.logger.Debug(
+			"Invalid password reset link",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		req := &recoverRequest{view: "failed", message: "The password reset link is invalid or has expired"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+	return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "reset", token: token})
+}
+
+func (p *Portal) handleHTTPRecoverScreen(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, req *recoverRequest) error {
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.PageTitle = "Password Recovery"
+	resp.Data["view"] = req.view
+	switch req.view {
+	case "forgot":
+		if req.realm == "" {
+			if v, exists := p.loginOptions["default_realm"]; exists {
+				req.realm, _ = v.(string)
+			}
+		}
+		resp.Data["realm"] = req.realm
+	case "reset":
+		resp.Data["token"] = req.token
+	case "failed":
+		resp.Data["message"] = req.message
+	}
+	if req.message != "" && req.view != "failed" {
+		resp.Message = req.message
+	}
+	content, err := p.ui.Render("recover", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
+}
+
+// getRecoveryStore returns the local identity store with the enabled
+// password recovery in the provided realm.
+func (p *Portal) getRecoveryStore(realm string) ids.IdentityStore {
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil || store.GetKind() != "local" {
+		return nil
+	}
+	if icon := store.GetLoginIcon(); icon == nil || !icon.PasswordRecoveryEnabled {
+		return nil
+	}
+	return store
+}
+
+func (p *Portal) parseRecoverForm(r *http.Request) bool {
+	if r.ContentLength > 1000 || r.ContentLength < 10 {
+		return false
+	}
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		return false
+	}
+	return true
+}
+
+func (p *Portal) handleHTTPRecoverRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	if !p.parseRecoverForm(r) {
+		req := &recoverRequest{view: "forgot", message: "Password recovery request is non compliant"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+	email := strings.ToLower(strings.TrimSpace(r.Form.Get("email")))
+	realm := r.Form.Get("realm")
+	srcAddr := addrutil.GetSourceAddress(r)
+
+	store := p.getRecoveryStore(realm)
+	if store == nil || !strings.Contains(email, "@") {
+		req := &recoverRequest{view: "forgot", realm: realm, message: "Password recovery is not available"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+	if isReadOnlyStore(store) {
+		req := &recoverRequest{view: "forgot", realm: realm, message: readOnlyMessage}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	if err := p.recovery.Allow("email:"+email, "ip:"+srcAddr); err != nil {
+		p.logger.Warn(
+			"Throttled password recovery request",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", srcAddr),
+			zap.String("email", email),
+		)
+		req := &recoverRequest{view: "forgot", realm: realm, message: "Too many password recovery requests, please try again later"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	// The response does not reveal whether the email address exists.
+	resp := &recoverRequest{view: "sent"}
+
+	identity := &requests.Request{User: requests.User{Username: email}}
+	if err := store.Request(operator.IdentifyUser, identity); err != nil || identity.User.Username == "nobody" {
+		p.logger.Debug(
+			"Password recovery requested for unknown email address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("email", email),
+		)
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, resp)
+	}
+	if p.isBreakGlassAccount(realm, identity.User.Username) {
+		p.logger.Warn(
+			"Password recovery requested for break glass account",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", identity.User.Username),
+		)
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, resp)
+	}
+
+	token, err := p.recovery.IssueToken(realm, identity.User.Username, identity.User.Email)
+	if err != nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusInternalServerError)
+	}
+
+	recoveryURL := rr.Upstream.BaseURL + path.Join(rr.Upstream.BasePath, "recover") + "?token=" + url.QueryEscape(token)
+	data := map[string]string{
+		"session_id":   rr.Upstream.SessionID,
+		"request_id":   rr.ID,
+		"username":     identity.User.Username,
+		"email":        identity.User.Email,
+		"recovery_url": recoveryURL,
+		"lifetime":     p.recovery.GetTokenLifetime().String(),
+		"src_ip":       srcAddr,
+		"timestamp":    time.Now().UTC().Format(time.UnixDate),
+	}
+	p.addBrandingData(realm, data)
+	p.addLocationData(srcAddr, data)
+	if err := p.recovery.Notify(data); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("notification_type", "password_recovery"),
+			zap.Error(err),
+		)
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, resp)
+	}
+
+	p.logger.Info(
+		"Sent password reset link",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", identity.User.Username),
+		zap.String("realm", realm),
+		zap.String("src_ip", srcAddr),
+	)
+	p.recordUsage("password_recovery/request")
+	return p.handleHTTPRecoverScreen(ctx, w, r, rr, resp)
+}
+
+// handleHTTPRecoverReset sets the new password of the user holding a valid
+// password reset link. The link is redeemed before the password changes, so
+// it resets the password once, even when the requests with the link race.
+// The sessions cached by the portal end, and the tokens of the sessions are
+// revoked. The gatekeepers reject the revoked JWT tokens only when the
+// verification keys have revocation stores, otherwise the tokens issued
+// before the reset remain valid until they expire.
+func (p *Portal) handleHTTPRecoverReset(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	if !p.parseRecoverForm(r) {
+		req := &recoverRequest{view: "failed", message: "Password reset request is non compliant"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+	token := r.Form.Get("token")
+	secret := r.Form.Get("secret")
+
+	tkn, err := p.recovery.VerifyToken(token)
+	if err != nil {
+		req := &recoverRequest{view: "failed", message: "The password reset link is invalid or has expired"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	switch {
+	case secret == "":
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "reset", token: token, message: "Password is empty"})
+	case secret != r.Form.Get("secret_confirm"):
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "reset", token: token, message: "Passwords do not match"})
+	case strings.HasPrefix(secret, "bcrypt:"):
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "reset", token: token, message: "Password is invalid"})
+	}
+
+	store := p.getRecoveryStore(tkn.Realm)
+	if store == nil {
+		req := &recoverRequest{view: "failed", message: "Password recovery is not available"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+	if isReadOnlyStore(store) {
+		req := &recoverRequest{view: "reset", token: token, message: readOnlyMessage}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleHTTPOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	if _, err := p.recovery.RedeemToken(token); err != nil {
+		req := &recoverRequest{view: "failed", message: "The password reset link is invalid or has expired"}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, req)
+	}
+
+	req := &requests.Request{
+		User: requests.User{
+			Username: tkn.Username,
+			Email:    tkn.Email,
+			Password: secret,
+		},
+	}
+	if err := store.Request(operator.ResetPassword, req); err != nil {
+		p.logger.Warn(
+			"Failed password reset",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", tkn.Username),
+			zap.Error(err),
+		)
+		// The link is redeemed, the user requests another one.
+		message := "Password does not meet the policy requirements, please request a new password reset link"
+		if err == errors.ErrPasswordCompromised {
+			message = "Password appeared in a data breach, please request a new password reset link and choose a different password"
+		}
+		return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "failed", message: message})
+	}
+
+	// Terminate the sessions established with the previous password.
+	for _, usr := range p.sessions.DeleteUserSessions(tkn.Email) {
+		p.revokeToken(r, rr, usr, "password_reset")
+	}
+
+	p.logger.Info(
+		"Successful password reset",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", tkn.Username),
+		zap.String("realm", tkn.Realm),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("password_recovery/reset")
+	return p.handleHTTPRecoverScreen(ctx, w, r, rr, &recoverRequest{view: "done"})
 }
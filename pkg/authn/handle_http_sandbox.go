@@ -18,12 +18,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/identity/qr"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
 	"go.uber.org/zap"
 	"net/http"
 	"strings"
@@ -75,6 +79,9 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 	}
 
 	usr, err := p.sandboxes.Get(sandboxID)
+	if err != nil && p.continuation != nil {
+		usr, err = p.resumeSandbox(r, rr, sandboxID)
+	}
 	if err != nil {
 		p.logger.Debug(
 			"failed to extract cached entry from sandbox",
@@ -116,13 +123,16 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 		return p.handleHTTPMfaBarcode(ctx, w, r, sandboxPartition)
 	case sandboxPartition == "terminate":
 		p.sandboxes.Delete(sandboxID)
+		p.deleteContinuationCookies(w, r, rr, addrutil.GetSourceHost(r), sandboxID)
 		return p.handleHTTPRedirectSeeOther(ctx, w, r, rr, "login")
+	case sandboxPartition == "fallback":
+		return p.handleHTTPLoginFallback(ctx, w, r, rr, sandboxID, usr)
 	}
 
 	p.logger.Debug(
 		"user authorization sandbox",
 		zap.String("sandbox_id", sandboxID),
-		zap.String("sandbox_secret", sandboxSecret),
+		logutil.MaskedString("sandbox_secret", sandboxSecret),
 		zap.String("sandbox_partition", sandboxPartition),
 		zap.Any("checkpoints", usr.Checkpoints),
 	)
@@ -131,6 +141,17 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 	rr.User.Username = usr.Claims.Subject
 	rr.User.Email = usr.Claims.Email
 
+	// The submission of checkpoint forms, e.g. password, triggers expensive
+	// backend operations, subject to admission control.
+	if r.Method == "POST" {
+		release, err := p.admit(ctx)
+		if err != nil {
+			rr.Response.RedirectURL = rr.Upstream.BasePath
+			return p.handleHTTPOverload(ctx, w, r, rr, err)
+		}
+		defer release()
+	}
+
 	data, err := p.nextSandboxCheckpoint(r, rr, usr, sandboxPartition)
 	if err != nil {
 		p.logger.Warn(
@@ -149,10 +170,16 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 		)
 	}
 
+	if _, exists := data["authorized"]; !exists && data["view"] != "terminate" {
+		// Persist the progress of the user through the checkpoints.
+		p.setContinuationCookies(w, r, rr, usr)
+	}
+
 	if _, exists := data["view"]; exists {
 		switch data["view"] {
 		case "terminate":
 			p.sandboxes.Delete(sandboxID)
+			p.deleteContinuationCookies(w, r, rr, addrutil.GetSourceHost(r), sandboxID)
 		case "redirect":
 			return p.handleHTTPRedirectSeeOther(ctx, w, r, rr, "sandbox/"+sandboxID)
 		}
@@ -176,7 +203,7 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 	}
 
 	// Handle the processing of user views, e.g. app or U2F tokens, etc.
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, usr)
 	resp.PageTitle = "User Authorization"
 	if _, exists := data["title"]; exists {
 		resp.PageTitle = data["title"].(string)
@@ -186,6 +213,9 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 	for k, v := range data {
 		resp.Data[k] = v
 	}
+	if chain, _ := p.getLoginFallback(usr); chain != nil {
+		resp.Data["fallback"] = true
+	}
 
 	content, err := p.ui.Render("sandbox", resp)
 	if err != nil {
@@ -211,7 +241,7 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 			continue
 		}
 		switch checkpoint.Type {
-		case "password", "mfa":
+		case "password", "passkey", "mfa", "consent":
 			verifiedCount++
 		}
 	}
@@ -221,6 +251,12 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 			continue
 		}
 		if checkpoint.FailedAttempts > 5 {
+			p.publishEvent(events.UserLocked, r, rr, usr, map[string]interface{}{
+				"checkpoint_id":   checkpoint.ID,
+				"checkpoint_name": checkpoint.Name,
+				"checkpoint_type": checkpoint.Type,
+				"failed_attempts": checkpoint.FailedAttempts,
+			})
 			rr.Response.Code = http.StatusForbidden
 			m["title"] = "Authorization Failed"
 			m["view"] = "terminate"
@@ -228,6 +264,8 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 		}
 		switch checkpoint.Type {
 		case "password":
+			guardKeys := getLoginGuardKeys(r, usr.Authenticator.Realm, rr.User.Username)
+			challenge := p.getLoginChallenge(usr.Authenticator.Realm, guardKeys)
 			if r.Method != "POST" {
 				switch action {
 				case "password-recovery":
@@ -238,6 +276,11 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					m["title"] = "Authentication"
 					m["view"] = "password_auth"
 					m["action"] = "auth"
+					if challenge != nil {
+						for k, v := range getCaptchaData(challenge) {
+							m[k] = v
+						}
+					}
 				}
 				return m, nil
 			}
@@ -267,24 +310,32 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					)
 					return m, err
 				}
+				if challenge != nil {
+					if err := p.verifyLoginChallenge(r, rr, challenge, r.PostFormValue(challenge.GetResponseField())); err != nil {
+						rr.Response.Code = http.StatusUnauthorized
+						m["title"] = "Authentication Failed"
+						m["view"] = "error"
+						return m, fmt.Errorf("Challenge verification failed. Please retry")
+					}
+				}
 				rr.Flags.Enabled = true
 				if err := backend.Request(operator.Authenticate, rr); err != nil {
+					p.recordLoginOutcome(guardKeys, true)
+					metrics.Logins.Inc(rr.Upstream.Realm, rr.Upstream.Method, "failure")
 					rr.Response.Code = http.StatusUnauthorized
 					checkpoint.FailedAttempts++
 					m["title"] = "Authentication Failed"
 					m["view"] = "error"
-					p.logger.Warn(
-						"password authentication failed",
-						zap.String("session_id", rr.Upstream.SessionID),
-						zap.String("request_id", rr.ID),
-						zap.Int("checkpoint_id", checkpoint.ID),
-						zap.String("src_ip", addrutil.GetSourceAddress(r)),
-						zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
-						zap.String("checkpoint_name", checkpoint.Name),
-						zap.String("checkpoint_type", checkpoint.Type),
-					)
+					p.recordLogin(r, rr, usr, "failure")
+					p.publishEvent(events.LoginFailure, r, rr, usr, map[string]interface{}{
+						"checkpoint_id":   checkpoint.ID,
+						"checkpoint_name": checkpoint.Name,
+						"checkpoint_type": checkpoint.Type,
+						"src_conn_ip":     addrutil.GetSourceConnAddress(r),
+					})
 					return m, fmt.Errorf("Password authentication failed. Please retry")
 				}
+				p.recordLoginOutcome(guardKeys, false)
 				p.logger.Info(
 					"user authorization checkpoint passed",
 					zap.String("session_id", rr.Upstream.SessionID),
@@ -355,6 +406,7 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					break
 				}
 				if tokenValidated {
+					metrics.MfaChallenges.Inc(rr.Upstream.Realm, "totp", "success")
 					// If validated successfully, continue.
 					p.logger.Info(
 						"user authorization checkpoint passed",
@@ -373,6 +425,7 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 				if len(tokenErrors) == 0 {
 					tokenErrors = append(tokenErrors, "No available application tokens found")
 				}
+				metrics.MfaChallenges.Inc(rr.Upstream.Realm, "totp", "failure")
 				m["view"] = "error"
 				checkpoint.FailedAttempts++
 				return m, fmt.Errorf(strings.Join(tokenErrors, "\n"))
@@ -388,42 +441,23 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					}
 					rr.WebAuthn.Challenge = usr.Authenticator.TempChallenge
 					if err := backend.Request(operator.Authenticate, rr); err != nil {
+						metrics.MfaChallenges.Inc(rr.Upstream.Realm, "u2f", "failure")
 						m["view"] = "error"
 						checkpoint.FailedAttempts++
 						return m, fmt.Errorf("Token verification failed. Please retry")
 					}
+					metrics.MfaChallenges.Inc(rr.Upstream.Realm, "u2f", "success")
 					checkpoint.Passed = true
 					checkpoint.FailedAttempts = 0
 					verifiedCount++
 					m["view"] = "redirect"
 					return m, nil
 				}
-				if err := backend.Request(operator.GetMfaTokens, rr); err != nil {
+				if err := addWebAuthnRequest(backend, rr, usr, m, "discouraged"); err != nil {
 					m["view"] = "error"
 					checkpoint.FailedAttempts++
 					return m, err
 				}
-				bundle := rr.Response.Payload.(*identity.MfaTokenBundle)
-				creds := []map[string]interface{}{}
-				for _, t := range bundle.Get() {
-					if t.Type != "u2f" {
-						continue
-					}
-					cred := make(map[string]interface{})
-					cred["id"] = t.Parameters["u2f_id"]
-					cred["type"] = t.Parameters["u2f_type"]
-					cred["transports"] = strings.Split(t.Parameters["u2f_transports"], ",")
-					creds = append(creds, cred)
-				}
-				usr.Authenticator.TempChallenge = util.GetRandomString(64)
-				m["webauthn_challenge"] = usr.Authenticator.TempChallenge
-				m["webauthn_rp_name"] = "AUTHP"
-				m["webauthn_timeout"] = "60000"
-				m["webauthn_user_verification"] = "discouraged"
-				m["webauthn_ext_uvm"] = "false"
-				m["webauthn_ext_loc"] = "false"
-				m["webauthn_tx_auth_simple"] = "Could you please verify yourself?"
-				m["webauthn_credentials"] = creds
 			case !appConfigured && (action == "mfa-app-register"):
 				m["title"] = "Authenticator App Registration"
 				m["view"] = "mfa_app_register"
@@ -440,6 +474,9 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 						checkpoint.FailedAttempts++
 						return m, err
 					}
+					p.publishEvent(events.MfaEnrolled, r, rr, usr, map[string]interface{}{
+						"type": "totp",
+					})
 					checkpoint.Passed = true
 					checkpoint.FailedAttempts = 0
 					verifiedCount++
@@ -481,6 +518,9 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 						checkpoint.FailedAttempts++
 						return m, err
 					}
+					p.publishEvent(events.MfaEnrolled, r, rr, usr, map[string]interface{}{
+						"type": "u2f",
+					})
 					checkpoint.Passed = true
 					checkpoint.FailedAttempts = 0
 					verifiedCount++
@@ -509,6 +549,108 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 			if !checkpoint.Passed {
 				return m, nil
 			}
+		case "passkey":
+			m["title"] = "Passkey"
+			m["view"] = "mfa_u2f_auth"
+			m["action"] = "auth"
+			if p.isBreakGlassAccount(usr.Authenticator.Realm, usr.Claims.Subject) {
+				rr.WebAuthn.HardwareKey = true
+				rr.WebAuthn.HardwareKeyAAGUIDs = p.breakGlass.GetAAGUIDs()
+			}
+			if r.Method != "POST" {
+				if err := addWebAuthnRequest(backend, rr, usr, m, "preferred"); err != nil {
+					checkpoint.FailedAttempts++
+					m["view"] = "error"
+					return m, err
+				}
+				return m, nil
+			}
+			if err := validateAuthU2FTokenForm(r, rr); err != nil {
+				checkpoint.FailedAttempts++
+				rr.Response.Code = http.StatusBadRequest
+				m["view"] = "error"
+				return m, err
+			}
+			if usr.Authenticator.TempChallenge == "" {
+				checkpoint.FailedAttempts++
+				rr.Response.Code = http.StatusBadRequest
+				m["view"] = "error"
+				return m, fmt.Errorf("Passkey challenge not found. Please retry")
+			}
+			rr.WebAuthn.Challenge = usr.Authenticator.TempChallenge
+			rr.Flags.Enabled = true
+			if err := backend.Request(operator.Authenticate, rr); err != nil {
+				metrics.Logins.Inc(rr.Upstream.Realm, rr.Upstream.Method, "failure")
+				rr.Response.Code = http.StatusUnauthorized
+				checkpoint.FailedAttempts++
+				m["title"] = "Authentication Failed"
+				m["view"] = "error"
+				p.recordLogin(r, rr, usr, "failure")
+				p.publishEvent(events.LoginFailure, r, rr, usr, map[string]interface{}{
+					"checkpoint_id":   checkpoint.ID,
+					"checkpoint_name": checkpoint.Name,
+					"checkpoint_type": checkpoint.Type,
+					"src_conn_ip":     addrutil.GetSourceConnAddress(r),
+				})
+				return m, fmt.Errorf("Passkey verification failed. Please retry")
+			}
+			p.logger.Info(
+				"user authorization checkpoint passed",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Int("checkpoint_id", checkpoint.ID),
+				zap.String("checkpoint_name", checkpoint.Name),
+				zap.String("checkpoint_type", checkpoint.Type),
+			)
+			checkpoint.Passed = true
+			checkpoint.FailedAttempts = 0
+			verifiedCount++
+			m["view"] = "redirect"
+			return m, nil
+		case "consent":
+			store := p.getTermsStore(usr.Authenticator.Realm)
+			if store == nil {
+				m["title"] = "Internal Server Error"
+				m["view"] = "terminate"
+				return m, fmt.Errorf("Terms acceptance is not available")
+			}
+			docs, err := p.getPendingTerms(store, rr)
+			if err != nil {
+				checkpoint.FailedAttempts++
+				m["title"] = "Authorization Failed"
+				m["view"] = "error"
+				return m, err
+			}
+			if len(docs) > 0 {
+				if r.Method != "POST" {
+					m["title"] = "Terms and Conditions"
+					m["view"] = "terms"
+					m["documents"] = docs
+					return m, nil
+				}
+				if err := p.acceptTerms(r, rr, usr, store, docs); err != nil {
+					checkpoint.FailedAttempts++
+					rr.Response.Code = http.StatusBadRequest
+					m["title"] = "Terms Not Accepted"
+					m["view"] = "error"
+					return m, err
+				}
+			}
+			p.logger.Info(
+				"user authorization checkpoint passed",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Int("checkpoint_id", checkpoint.ID),
+				zap.String("checkpoint_name", checkpoint.Name),
+				zap.String("checkpoint_type", checkpoint.Type),
+			)
+			checkpoint.Passed = true
+			checkpoint.FailedAttempts = 0
+			verifiedCount++
+			if r.Method == "POST" {
+				m["view"] = "redirect"
+				return m, nil
+			}
 		default:
 			checkpoint.FailedAttempts++
 			m["title"] = "Bad Request"
@@ -522,3 +664,36 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 	}
 	return m, nil
 }
+
+// addWebAuthnRequest adds the WebAuthn authentication request for the
+// hardware tokens of the user to the view data.
+func addWebAuthnRequest(backend ids.IdentityStore, rr *requests.Request, usr *user.User, m map[string]interface{}, verification string) error {
+	if err := backend.Request(operator.GetMfaTokens, rr); err != nil {
+		return err
+	}
+	bundle := rr.Response.Payload.(*identity.MfaTokenBundle)
+	creds := []map[string]interface{}{}
+	for _, t := range bundle.Get() {
+		if t.Type != "u2f" {
+			continue
+		}
+		if rr.WebAuthn.HardwareKey && !t.IsHardwareKey(rr.WebAuthn.HardwareKeyAAGUIDs) {
+			continue
+		}
+		cred := make(map[string]interface{})
+		cred["id"] = t.Parameters["u2f_id"]
+		cred["type"] = t.Parameters["u2f_type"]
+		cred["transports"] = strings.Split(t.Parameters["u2f_transports"], ",")
+		creds = append(creds, cred)
+	}
+	usr.Authenticator.TempChallenge = util.GetRandomString(64)
+	m["webauthn_challenge"] = usr.Authenticator.TempChallenge
+	m["webauthn_rp_name"] = "AUTHP"
+	m["webauthn_timeout"] = "60000"
+	m["webauthn_user_verification"] = verification
+	m["webauthn_ext_uvm"] = "false"
+	m["webauthn_ext_loc"] = "false"
+	m["webauthn_tx_auth_simple"] = "Could you please verify yourself?"
+	m["webauthn_credentials"] = creds
+	return nil
+}
@@ -86,7 +86,7 @@ func (p *Portal) handleHTTPSettings(ctx context.Context, w http.ResponseWriter,
 		zap.String("endpoint", endpoint),
 	)
 
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, usr)
 	resp.PageTitle = "Profile"
 	resp.BaseURL(rr.Upstream.BasePath)
 
@@ -94,7 +94,52 @@ func (p *Portal) handleHTTPSettings(ctx context.Context, w http.ResponseWriter,
 	rr.User.Username = usr.Claims.Subject
 	rr.User.Email = usr.Claims.Email
 
+	if p.emailChange != nil {
+		resp.Data["email_change_enabled"] = "yes"
+	}
+	if p.deletion != nil {
+		resp.Data["account_deletion_enabled"] = "yes"
+	}
+	if p.accessRequests != nil {
+		resp.Data["access_requests_enabled"] = "yes"
+	}
+	if isReadOnlyStore(backend) {
+		resp.Data["read_only_message"] = readOnlyMessage
+	}
+
 	switch {
+	case strings.HasPrefix(endpoint, "/email"):
+		resp.PageTitle = "Email Address"
+		resp.NavItems = p.config.UI.GetNavigationItems("settings/")
+		if p.emailChange == nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusNotFound)
+		}
+		if err := p.handleHTTPEmailSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
+	case strings.HasPrefix(endpoint, "/export"):
+		if p.config.UI.IsDisabledPage("settings/export") {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusForbidden)
+		}
+		return p.handleDataExport(ctx, w, r, rr, usr, backend)
+	case strings.HasPrefix(endpoint, "/delete"):
+		resp.PageTitle = "Delete Account"
+		resp.NavItems = p.config.UI.GetNavigationItems("settings/")
+		if p.deletion == nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusNotFound)
+		}
+		if err := p.handleHTTPDeleteAccountSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
+	case strings.HasPrefix(endpoint, "/access"):
+		resp.PageTitle = "Access Requests"
+		resp.NavItems = p.config.UI.GetNavigationItems("settings/")
+		if p.accessRequests == nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusNotFound)
+		}
+		if err := p.handleHTTPAccessSettings(ctx, r, rr, usr, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
 	case strings.HasPrefix(endpoint, "/password"):
 		resp.PageTitle = "Password Management"
 		resp.NavItems = p.config.UI.GetNavigationItems("settings/password")
@@ -148,7 +193,12 @@ func (p *Portal) handleHTTPSettings(ctx context.Context, w http.ResponseWriter,
 		if p.config.UI.IsDisabledPage("settings/connected") {
 			return p.handleHTTPError(ctx, w, r, rr, http.StatusForbidden)
 		}
-		resp.Data["view"] = "connected"
+		if strings.HasPrefix(endpoint, "/connected/add/") {
+			return p.handleHTTPConnectedAccountLink(ctx, w, r, rr, usr)
+		}
+		if err := p.handleHTTPConnectedSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
 	default:
 		resp.NavItems = p.config.UI.GetNavigationItems("settings/")
 		if err := p.handleHTTPGeneralSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
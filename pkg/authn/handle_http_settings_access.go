@@ -0,0 +1,121 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+func (p *Portal) handleHTTPAccessSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "access"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	realm := usr.Authenticator.Realm
+	srcAddr := addrutil.GetSourceAddress(r)
+	isAdmin := usr.HasRole("authp/admin")
+	switch {
+	case strings.HasPrefix(endpoint, "/request") && r.Method == "POST":
+		action = "request"
+		status = true
+		form, err := validateAccessRequestForm(r)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		req, err := p.accessRequests.Submit(realm, rr.User.Username, rr.User.Email, form.app, form.role, form.justification, form.lifetime)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "access_requested"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("access_request_id", req.ID),
+			zap.String("app", req.App),
+			zap.String("role", req.Role),
+			zap.String("realm", realm),
+			zap.String("username", rr.User.Username),
+			zap.String("src_ip", srcAddr),
+		)
+		p.notifyAccessRequest(rr, req, srcAddr)
+		p.recordUsage("access_request/submit")
+		attachSuccessStatus(data, fmt.Sprintf("Access to %s has been requested", req.App))
+	case (strings.HasPrefix(endpoint, "/approve/") || strings.HasPrefix(endpoint, "/decline/")) && r.Method == "POST":
+		action = "review"
+		status = true
+		if !isAdmin {
+			attachFailStatus(data, "Access request review is not permitted")
+			break
+		}
+		arr := strings.SplitN(strings.TrimPrefix(endpoint, "/"), "/", 2)
+		verdict, id := arr[0], arr[1]
+		comment, err := validateAccessReviewForm(r)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		entry, err := p.reviewAccessRequest(id, verdict, rr.User.Email, &accessRequestVerdictRequest{Comment: comment})
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		p.logAccessRequestVerdict(rr, entry)
+		attachSuccessStatus(data, fmt.Sprintf("Access request %s has been %s", entry.ID, entry.Status))
+	default:
+		// List the apps and the access requests of the user.
+		apps := p.accessRequests.GetApps()
+		if appName := r.URL.Query().Get("app"); appName != "" {
+			if app, err := p.accessRequests.GetApp(appName); err == nil {
+				apps = []*accessrequest.App{app}
+			}
+		}
+		data["access_apps"] = apps
+		if entries := p.accessRequests.ListUser(realm, rr.User.Username); len(entries) > 0 {
+			data["access_requests"] = entries
+		}
+		if !isAdmin {
+			break
+		}
+		var pending []*accessrequest.Request
+		for _, entry := range p.accessRequests.List() {
+			if entry.Status == accessrequest.StatusPending {
+				pending = append(pending, entry)
+			}
+		}
+		if len(pending) > 0 {
+			data["access_pending_requests"] = pending
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
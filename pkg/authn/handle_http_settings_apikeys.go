@@ -26,6 +26,10 @@ import (
 	"strings"
 )
 
+// apiKeyRotationGracePeriod is the number of seconds a rotated API key
+// remains valid alongside its replacement.
+const apiKeyRotationGracePeriod = 86400
+
 func (p *Portal) handleHTTPAPIKeysSettings(
 	ctx context.Context, r *http.Request, rr *requests.Request,
 	usr *user.User, store ids.IdentityStore, data map[string]interface{},
@@ -55,6 +59,22 @@ func (p *Portal) handleHTTPAPIKeysSettings(
 		attachSuccessStatus(data, "New API key has been added")
 	case strings.HasPrefix(endpoint, "/add"):
 		action = "add"
+	case strings.HasPrefix(endpoint, "/rotate"):
+		action = "rotate"
+		status = true
+		keyID, err := getEndpointKeyID(endpoint, "/rotate/")
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		rr.Key.ID = keyID
+		rr.Key.GracePeriod = apiKeyRotationGracePeriod
+		if err = store.Request(operator.RotateAPIKey, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("failed rotating key id %s: %v", keyID, err))
+			break
+		}
+		data["api_key"] = rr.Response.Payload.(string)
+		attachSuccessStatus(data, fmt.Sprintf("key id %s rotated successfully", keyID))
 	case strings.HasPrefix(endpoint, "/delete"):
 		action = "delete"
 		status = true
@@ -0,0 +1,102 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+func (p *Portal) handleHTTPConnectedSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, store ids.IdentityStore, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "connected"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	if p.linker != nil {
+		data["identity_linking_enabled"] = "yes"
+		if providers := p.getLinkableProviders(); len(providers) > 0 {
+			data["linkable_providers"] = providers
+		}
+	}
+	switch {
+	case strings.HasPrefix(endpoint, "/delete"):
+		action = "delete"
+		status = true
+		linkID, err := getEndpointKeyID(endpoint, "/delete/")
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		rr.LinkedIdentity.ID = linkID
+		if err = store.Request(operator.DeleteLinkedIdentity, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("failed unlinking identity id %s: %v", linkID, err))
+			break
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "identity_unlinked"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", usr.Authenticator.Realm),
+			zap.String("username", rr.User.Username),
+			zap.String("linked_identity_id", linkID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		)
+		attachSuccessStatus(data, fmt.Sprintf("identity id %s unlinked successfully", linkID))
+	default:
+		// List linked identities.
+		if err = store.Request(operator.GetLinkedIdentities, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		if rr.Response.Payload != nil {
+			data["linked_identities"] = rr.Response.Payload
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
+
+// handleHTTPConnectedAccountLink starts the linking of an identity provider
+// to the local account of a user and redirects the user to the provider.
+func (p *Portal) handleHTTPConnectedAccountLink(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if p.linker == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotFound)
+	}
+	realm, err := getEndpointKeyID(r.URL.Path, "/settings/connected/add/")
+	if err != nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+	}
+	location, err := p.startIdentityLink(w, r, rr, usr, realm)
+	if err != nil {
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	return p.handleHTTPRedirect(ctx, w, r, rr, location)
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/deletion"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (p *Portal) handleHTTPDeleteAccountSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, store ids.IdentityStore, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "delete"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	realm := usr.Authenticator.Realm
+	srcAddr := addrutil.GetSourceAddress(r)
+	data["grace_period"] = p.deletion.GetGracePeriod().String()
+	switch {
+	case strings.HasPrefix(endpoint, "/request") && r.Method == "POST":
+		action = "request"
+		status = true
+		req, code, err := p.deletion.Request(realm, rr.User.Username, rr.User.Email)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		msg := map[string]string{
+			"session_id": rr.Upstream.SessionID,
+			"request_id": rr.ID,
+			"username":   rr.User.Username,
+			"email":      req.Email,
+			"code":       code,
+			"lifetime":   p.deletion.GetCodeLifetime().String(),
+			"src_ip":     srcAddr,
+			"timestamp":  time.Now().UTC().Format(time.UnixDate),
+		}
+		p.addBrandingData(realm, msg)
+		p.addLocationData(srcAddr, msg)
+		if err := p.deletion.NotifyCode(msg); err != nil {
+			p.deletion.Cancel(realm, rr.User.Username)
+			p.logger.Warn(
+				"Failed sending account deletion confirmation code",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+			attachFailStatus(data, "Failed sending confirmation code")
+			break
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "account_deletion_requested"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", realm),
+			zap.String("username", rr.User.Username),
+			zap.String("src_ip", srcAddr),
+		)
+		p.recordUsage("account_deletion/request")
+		data["pending_deletion"] = "yes"
+		attachSuccessStatus(data, "Confirmation code has been sent to "+req.Email)
+	case strings.HasPrefix(endpoint, "/confirm") && r.Method == "POST":
+		action = "confirm"
+		status = true
+		code, err := validateEmailConfirmForm(r)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		req, err := p.deletion.Confirm(realm, rr.User.Username, code)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		rr.Deletion.ScheduledAt = time.Now().Add(p.deletion.GetGracePeriod()).UTC()
+		if err := store.Request(operator.ScheduleDeletion, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		scheduledAt := rr.Deletion.ScheduledAt.Format(time.UnixDate)
+		msg := map[string]string{
+			"session_id":   rr.Upstream.SessionID,
+			"request_id":   rr.ID,
+			"username":     rr.User.Username,
+			"email":        req.Email,
+			"scheduled_at": scheduledAt,
+			"src_ip":       srcAddr,
+			"timestamp":    time.Now().UTC().Format(time.UnixDate),
+		}
+		p.addBrandingData(realm, msg)
+		p.addLocationData(srcAddr, msg)
+		if err := p.deletion.NotifyNotice(msg); err != nil {
+			p.logger.Warn(
+				"Failed sending account deletion notice",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+		}
+		// The account remains inactive until it is deleted or the user
+		// signs in again.
+		p.terminateUserSessions(r, rr, rr.User.Email, "account_deletion")
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "account_deletion_scheduled"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", realm),
+			zap.String("username", rr.User.Username),
+			zap.String("scheduled_at", scheduledAt),
+			zap.String("src_ip", srcAddr),
+		)
+		p.publishEvent(events.UserDeletionScheduled, r, rr, usr, map[string]interface{}{
+			"scheduled_at": rr.Deletion.ScheduledAt,
+		})
+		p.recordUsage("account_deletion/confirm")
+		data["scheduled_at"] = scheduledAt
+		attachSuccessStatus(data, "Your account is scheduled for deletion on "+scheduledAt+". Sign in before then to reactivate it.")
+	default:
+		if _, err := p.deletion.Get(realm, rr.User.Username); err == nil {
+			data["pending_deletion"] = "yes"
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
+
+// reactivateAccount cancels the scheduled deletion of the account of the
+// user signing in.
+func (p *Portal) reactivateAccount(r *http.Request, rr *requests.Request, usr *user.User) {
+	if p.deletion == nil || usr.Claims == nil || usr.Authenticator.Method != "local" {
+		return
+	}
+	store := p.getIdentityStoreByRealm(usr.Authenticator.Realm)
+	if store == nil {
+		return
+	}
+	req := &requests.Request{Context: rr.GetContext()}
+	req.User.Username = usr.Claims.Subject
+	req.User.Email = usr.Claims.Email
+	if err := store.Request(operator.CancelDeletion, req); err != nil {
+		p.logger.Warn(
+			"Failed cancelling account deletion",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	if cancelled, ok := req.Response.Payload.(bool); !ok || !cancelled {
+		return
+	}
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "account_reactivated"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("realm", usr.Authenticator.Realm),
+		zap.String("username", req.User.Username),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.publishEvent(events.UserReactivated, r, rr, usr, nil)
+	p.recordUsage("account_deletion/cancel")
+}
+
+// purgeDeletedAccounts deletes the accounts whose scheduled deletion is due
+// from the local identity stores, along with their sessions and sign-in
+// history. The security event about the deletion carries a pseudonym in
+// lieu of the username and email address.
+func (p *Portal) purgeDeletedAccounts() {
+	for _, store := range p.identityStores {
+		if store.GetKind() != "local" {
+			continue
+		}
+		req := &requests.Request{Context: context.Background()}
+		if err := store.Request(operator.PurgeDeletions, req); err != nil {
+			p.logger.Warn(
+				"Failed purging deleted accounts",
+				zap.String("portal_name", p.config.Name),
+				zap.String("realm", store.GetRealm()),
+				zap.Error(err),
+			)
+			continue
+		}
+		users, ok := req.Response.Payload.([]*identity.User)
+		if !ok {
+			continue
+		}
+		for _, u := range users {
+			p.eraseDeletedAccount(store.GetRealm(), u, "scheduled")
+		}
+	}
+}
+
+// eraseDeletedAccount removes the traces of a deleted account held by the
+// portal.
+func (p *Portal) eraseDeletedAccount(realm string, u *identity.User, reason string) {
+	for _, email := range u.EmailAddresses {
+		for _, entry := range p.sessions.DeleteUserSessions(email.Address) {
+			p.keystore.RevokeToken(entry.Token)
+		}
+	}
+	if p.signIns != nil {
+		if err := p.signIns.Forget(realm, u.Username); err != nil {
+			p.logger.Warn(
+				"Failed erasing sign-in history",
+				zap.String("portal_name", p.config.Name),
+				zap.String("realm", realm),
+				zap.Error(err),
+			)
+		}
+	}
+	pseudonym := deletion.GetPseudonym(realm, u.ID)
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "account_deleted"),
+		zap.String("realm", realm),
+		zap.String("user", pseudonym),
+		zap.String("reason", reason),
+	)
+	events.Publish(&events.Event{
+		Type:   events.UserDeleted,
+		Realm:  realm,
+		Method: "local",
+		Data: map[string]interface{}{
+			"user":   pseudonym,
+			"reason": reason,
+		},
+	})
+	p.recordUsage("account_deletion/purge")
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (p *Portal) handleHTTPEmailSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, store ids.IdentityStore, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "email"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	realm := usr.Authenticator.Realm
+	srcAddr := addrutil.GetSourceAddress(r)
+	data["current_email"] = rr.User.Email
+	switch {
+	case strings.HasPrefix(endpoint, "/edit") && r.Method == "POST":
+		action = "edit"
+		status = true
+		if err := validateEmailChangeForm(r, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		if err := p.emailChange.Allow("user:"+realm+"/"+rr.User.Username, "ip:"+srcAddr); err != nil {
+			p.logger.Warn(
+				"Throttled email change request",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("username", rr.User.Username),
+				zap.String("src_ip", srcAddr),
+			)
+			attachFailStatus(data, "Too many email change requests, please try again later")
+			break
+		}
+		// The address belonging to another user is rejected before the
+		// confirmation code is sent to it. The lookup happens after the
+		// throttle, i.e. it cannot be used to enumerate the addresses.
+		identity := &requests.Request{User: requests.User{Username: rr.User.NewEmail}}
+		if err := store.Request(operator.IdentifyUser, identity); err == nil &&
+			identity.User.Username != "nobody" && !strings.EqualFold(identity.User.Username, rr.User.Username) {
+			attachFailStatus(data, fmt.Sprintf("%v", errors.ErrEmailChangeAddressTaken))
+			break
+		}
+		change, code, err := p.emailChange.Request(realm, rr.User.Username, rr.User.Email, rr.User.NewEmail)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		msg := map[string]string{
+			"session_id": rr.Upstream.SessionID,
+			"request_id": rr.ID,
+			"username":   rr.User.Username,
+			"email":      change.NewEmail,
+			"new_email":  change.NewEmail,
+			"code":       code,
+			"lifetime":   p.emailChange.GetCodeLifetime().String(),
+			"src_ip":     srcAddr,
+			"timestamp":  time.Now().UTC().Format(time.UnixDate),
+		}
+		p.addBrandingData(realm, msg)
+		p.addLocationData(srcAddr, msg)
+		if err := p.emailChange.NotifyCode(msg); err != nil {
+			p.emailChange.Cancel(realm, rr.User.Username)
+			p.logger.Warn(
+				"Failed sending email change confirmation code",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+			attachFailStatus(data, "Failed sending confirmation code")
+			break
+		}
+		msg["email"] = change.OldEmail
+		if err := p.emailChange.NotifyNotice(msg); err != nil {
+			p.logger.Warn(
+				"Failed sending email change notice",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "email_change_requested"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", realm),
+			zap.String("username", rr.User.Username),
+			zap.String("old_email", change.OldEmail),
+			zap.String("new_email", change.NewEmail),
+			zap.String("src_ip", srcAddr),
+		)
+		p.recordUsage("email_change/request")
+		data["pending_email"] = change.NewEmail
+		attachSuccessStatus(data, "Confirmation code has been sent to "+change.NewEmail)
+	case strings.HasPrefix(endpoint, "/confirm") && r.Method == "POST":
+		action = "confirm"
+		status = true
+		code, err := validateEmailConfirmForm(r)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		change, err := p.emailChange.Confirm(realm, rr.User.Username, code)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		rr.User.NewEmail = change.NewEmail
+		if err := store.Request(operator.ChangeEmail, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		// Terminate the sessions carrying the previous email address.
+		for _, u := range p.sessions.DeleteUserSessions(change.OldEmail) {
+			p.revokeToken(r, rr, u, "email_change")
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "email_change_confirmed"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", realm),
+			zap.String("username", rr.User.Username),
+			zap.String("old_email", change.OldEmail),
+			zap.String("new_email", change.NewEmail),
+			zap.String("src_ip", srcAddr),
+		)
+		p.recordUsage("email_change/confirm")
+		attachSuccessStatus(data, "Email address has been changed")
+	default:
+		if change, err := p.emailChange.Get(realm, rr.User.Username); err == nil {
+			data["pending_email"] = change.NewEmail
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
@@ -37,6 +37,9 @@ func (p *Portal) handleHTTPGeneralSettings(
 	}
 	user := rr.Response.Payload.(*identity.User)
 	data["metadata"] = user.GetMetadata()
+	if p.config.LoginHistoryConfig != nil {
+		data["login_history"] = user.LoginHistory
+	}
 
 	attachSuccessStatus(data, "User identity has been discovered")
 	return nil
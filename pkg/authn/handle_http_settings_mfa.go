@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/identity/qr"
 	"github.com/greenpau/go-authcrunch/pkg/ids"
@@ -73,6 +74,9 @@ func (p *Portal) handleHTTPMfaSettings(
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
+		p.publishEvent(events.MfaEnrolled, r, rr, usr, map[string]interface{}{
+			"type": "u2f",
+		})
 		attachSuccessStatus(data, "U2F token has been added")
 	case strings.HasPrefix(endpoint, "/add/u2f"):
 		// Add U2F token.
@@ -100,6 +104,9 @@ func (p *Portal) handleHTTPMfaSettings(
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
+		p.publishEvent(events.MfaEnrolled, r, rr, usr, map[string]interface{}{
+			"type": "totp",
+		})
 		attachSuccessStatus(data, "MFA token has been added")
 	case strings.HasPrefix(endpoint, "/add/app"):
 		action = "add-app"
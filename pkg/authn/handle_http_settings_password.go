@@ -48,6 +48,10 @@ func (p *Portal) handleHTTPPasswordSettings(
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
+		if rr.Response.Message != "" {
+			attachSuccessStatus(data, "Password has been changed. "+rr.Response.Message)
+			break
+		}
 		attachSuccessStatus(data, "Password has been changed")
 	}
 	attachView(data, entrypoint, action, status)
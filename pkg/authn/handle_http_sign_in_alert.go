@@ -0,0 +1,170 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// observeSignIn records the fingerprint of the sign-in and notifies the user
+// when the sign-in is from an unrecognized device or location.
+func (p *Portal) observeSignIn(r *http.Request, rr *requests.Request, usr *user.User) {
+	if p.signIns == nil || usr.Claims == nil || usr.Claims.Email == "" {
+		return
+	}
+	realm := usr.Authenticator.Realm
+	if realm == "" {
+		realm = rr.Upstream.Realm
+	}
+	username := usr.Claims.Subject
+	email := usr.Claims.Email
+	srcAddr := addrutil.GetSourceAddress(r)
+	userAgent := r.UserAgent()
+
+	if !p.signIns.Known(realm, username) {
+		p.seedSignIns(realm, username, email)
+	}
+	obs, err := p.signIns.Observe(realm, username, srcAddr, userAgent, time.Now().UTC())
+	if err != nil {
+		p.logger.Warn(
+			"Failed recording sign-in",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+	}
+	if obs == nil || !obs.Notable() {
+		return
+	}
+
+	reason := "new_device"
+	switch {
+	case obs.ImpossibleTravel:
+		reason = "impossible_travel"
+	case obs.NewLocation:
+		reason = "new_location"
+	}
+
+	p.logger.Warn(
+		"Detected sign-in from unrecognized device or location",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", username),
+		zap.String("realm", realm),
+		zap.String("src_ip", srcAddr),
+		zap.String("reason", reason),
+		zap.Float64("distance", obs.Distance),
+	)
+
+	token, err := p.signIns.IssueToken(realm, username, email)
+	if err != nil {
+		return
+	}
+	location := &geo.Location{
+		Country: obs.Fingerprint.Country,
+		City:    obs.Fingerprint.City,
+	}
+	data := map[string]string{
+		"session_id": rr.Upstream.SessionID,
+		"request_id": rr.ID,
+		"username":   username,
+		"email":      email,
+		"reason":     reason,
+		"revoke_url": rr.Upstream.BaseURL + path.Join(rr.Upstream.BasePath, "sign-in-alert") + "?token=" + url.QueryEscape(token),
+		"lifetime":   p.signIns.GetTokenLifetime().String(),
+		"src_ip":     srcAddr,
+		"country":    location.Country,
+		"city":       location.City,
+		"location":   location.String(),
+		"user_agent": userAgent,
+		"timestamp":  time.Now().UTC().Format(time.UnixDate),
+	}
+	p.addBrandingData(realm, data)
+
+	// The delivery of the notification does not delay the sign-in.
+	go func() {
+		if err := p.signIns.Notify(data); err != nil {
+			p.logger.Warn(
+				"Failed to send notification",
+				zap.String("session_id", data["session_id"]),
+				zap.String("request_id", data["request_id"]),
+				zap.String("notification_type", "new_sign_in"),
+				zap.Error(err),
+			)
+		}
+	}()
+	p.recordUsage("sign_in_alert/notify")
+}
+
+// handleHTTPSignInAlert redeems the link from a new sign-in notification,
+// terminates the sessions of the user and revokes their tokens.
+func (p *Portal) handleHTTPSignInAlert(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	if p.signIns == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
+	}
+
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.Data["go_back_url"] = rr.Upstream.BasePath
+	code := http.StatusOK
+
+	tkn, err := p.signIns.RedeemToken(r.URL.Query().Get("token"))
+	if err != nil {
+		p.logger.Debug(
+			"Invalid sign-in alert link",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		code = http.StatusBadRequest
+		resp.PageTitle = "Invalid Link"
+		resp.Data["message"] = "The link is invalid or has expired."
+	} else {
+		var count int
+		for _, usr := range p.sessions.DeleteUserSessions(tkn.Email) {
+			p.revokeToken(r, rr, usr, "sign_in_alert")
+			count++
+		}
+		p.logger.Info(
+			"Revoked sessions via sign-in alert",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", tkn.Username),
+			zap.String("realm", tkn.Realm),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.Int("session_count", count),
+		)
+		p.recordUsage("sign_in_alert/revoke")
+		resp.PageTitle = "Sessions Revoked"
+		resp.Data["message"] = "All sessions of your account were signed out. Please sign in and change your password."
+	}
+
+	content, err := p.ui.Render("generic", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, code, content.Bytes())
+}
@@ -32,7 +32,7 @@ func (p *Portal) handleHTTPWhoami(ctx context.Context, w http.ResponseWriter, r
 		}
 		return p.handleHTTPRedirect(ctx, w, r, rr, "/login")
 	}
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, usr)
 	resp.PageTitle = "User Identity"
 	resp.BaseURL(rr.Upstream.BasePath)
 	tokenMap := make(map[string]interface{})
@@ -17,21 +17,36 @@ package authn
 import (
 	"context"
 	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/authproxy"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
 	"net/http"
+	"strconv"
 )
 
-// AuthRequest is authentication request.
+// AuthRequest is authentication request. The request carries either the
+// username and password, or the API key.
 type AuthRequest struct {
 	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
 	Password string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
 	Realm    string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// The passcode of the authenticator app required from the users with
+	// MFA tokens.
+	Passcode string `json:"passcode,omitempty" xml:"passcode,omitempty" yaml:"passcode,omitempty"`
+	// The API key of the user. The API key bypasses the MFA.
+	APIKey string `json:"api_key,omitempty" xml:"api_key,omitempty" yaml:"api_key,omitempty"`
+	// The response to the CAPTCHA challenge required from the logins
+	// following the failed attempts.
+	Captcha string `json:"captcha,omitempty" xml:"captcha,omitempty" yaml:"captcha,omitempty"`
 }
 
 // AuthResponse is the response to authentication request.
 type AuthResponse struct {
 	Token     string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
 	TokenName string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
 }
 
 func (p *Portal) handleJSONLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
@@ -39,21 +54,73 @@ func (p *Portal) handleJSONLogin(ctx context.Context, w http.ResponseWriter, r *
 	if r.Method != "POST" {
 		return p.handleJSONError(ctx, w, http.StatusUnauthorized, "Authentication Required")
 	}
-	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	var maxBytesLimit int64 = 1024
+	if p.loginGuard != nil {
+		// The CAPTCHA responses are up to a few kilobytes.
+		maxBytesLimit = 10000
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytesLimit)
 	respDecoder := json.NewDecoder(r.Body)
 	respDecoder.DisallowUnknownFields()
 	if err := respDecoder.Decode(authRequest); err != nil {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
 	}
+	if authRequest.Password == "" && authRequest.APIKey == "" {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, errors.ErrLoginCredentialsEmpty.Error())
+	}
 
 	rr.Response.Workflow = "json-api"
+
+	if entry := p.getLoginMaintenance(authRequest.Realm); entry != nil {
+		return p.handleJSONMaintenance(ctx, w, r, rr, authRequest.Realm, entry)
+	}
+
+	// Limit the attempts per source address and per account, so that the
+	// API could not be used for guessing the credentials.
+	limitKeys := getLoginGuardKeys(r, authRequest.Realm, authRequest.Username)
+	if retryAfter, err := p.loginLimiter.Allow(limitKeys...); err != nil {
+		p.logger.Warn(
+			"login rate limit exceeded",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("source_address", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return p.handleJSONError(ctx, w, http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+	}
+
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleJSONOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	if authRequest.APIKey != "" {
+		return p.handleJSONAPIKeyLogin(ctx, w, r, rr, authRequest)
+	}
+
+	// Challenge the password logins from the source addresses and to the
+	// accounts with recent failed attempts.
+	if challenge := p.getLoginChallenge(authRequest.Realm, limitKeys); challenge != nil {
+		if err := p.verifyLoginChallenge(r, rr, challenge, authRequest.Captcha); err != nil {
+			return p.handleJSONChallengeRequired(ctx, w, challenge)
+		}
+	}
+
 	credentials := map[string]string{
 		"username": authRequest.Username,
 		"password": authRequest.Password,
 		"realm":    authRequest.Realm,
+		"passcode": authRequest.Passcode,
 	}
 
-	if err := p.authenticateLoginRequest(ctx, w, r, rr, credentials); err != nil {
+	err = p.authenticateLoginRequest(ctx, w, r, rr, credentials)
+	p.recordLoginOutcome(limitKeys, err != nil && err != errors.ErrLoginPasscodeRequired)
+	if err != nil {
+		if err == errors.ErrLoginPasscodeRequired {
+			return p.handleJSONError(ctx, w, http.StatusUnauthorized, "Passcode Required")
+		}
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
 	}
 	if err := p.authorizeLoginRequest(ctx, w, r, rr); err != nil {
@@ -66,9 +133,33 @@ func (p *Portal) handleJSONLogin(ctx context.Context, w http.ResponseWriter, r *
 	resp := &AuthResponse{
 		TokenName: usr.TokenName,
 		Token:     usr.Token,
+		ExpiresAt: usr.Claims.ExpiresAt,
 	}
 	respBytes, _ := json.Marshal(resp)
 	w.WriteHeader(rr.Response.Code)
 	w.Write(respBytes)
 	return nil
 }
+
+// handleJSONAPIKeyLogin exchanges the API key for the token of the user
+// the key belongs to.
+func (p *Portal) handleJSONAPIKeyLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, authRequest *AuthRequest) error {
+	usr, err := p.newAPIKeyUser(&authproxy.Request{
+		Address: addrutil.GetSourceAddress(r),
+		Realm:   authRequest.Realm,
+		Secret:  authRequest.APIKey,
+	})
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, err.Error())
+	}
+	p.recordUsage("login/apikey")
+	resp := &AuthResponse{
+		TokenName: usr.TokenName,
+		Token:     usr.Token,
+		ExpiresAt: usr.Claims.ExpiresAt,
+	}
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/breakglass"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+// getTestPasscode returns the current passcode of the authenticator app
+// token with the secret, SHA1 algorithm, 30 second period, and 6 digits.
+func getTestPasscode(secret string) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(buf)
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0xf
+	val := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", val%1000000)
+}
+
+func TestJSONLogin(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LoginRateLimitConfig: &ratelimit.Config{MaxAttempts: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := p.getIdentityStoreByRealm("local")
+
+	// The second user enables the authenticator app.
+	secret := "c71ca4c68bc14ec5b4ab8d3c3b63802c"
+	mfaReq := requests.NewRequest()
+	mfaReq.User.Username = tests.TestUser2
+	mfaReq.User.Email = tests.TestEmail2
+	mfaReq.MfaToken.Type = "totp"
+	mfaReq.MfaToken.Comment = "ms auth app"
+	mfaReq.MfaToken.Secret = secret
+	mfaReq.MfaToken.Period = 30
+	mfaReq.MfaToken.Passcode = getTestPasscode(secret)
+	if err := store.Request(operator.AddMfaToken, mfaReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first user issues an API key.
+	keyReq := requests.NewRequest()
+	keyReq.User.Username = tests.TestUser1
+	keyReq.User.Email = tests.TestEmail1
+	keyReq.Key.Usage = "api"
+	keyReq.Key.Comment = "ci"
+	if err := store.Request(operator.AddAPIKey, keyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	apiKey := keyReq.Response.Payload.(string)
+	invalidAPIKey := apiKey[:30] + "x" + apiKey[31:]
+	if invalidAPIKey == apiKey {
+		invalidAPIKey = apiKey[:30] + "y" + apiKey[31:]
+	}
+
+	testcases := []struct {
+		name       string
+		addr       string
+		req        *AuthRequest
+		code       int
+		message    string
+		retryAfter bool
+	}{
+		{
+			name: "password login",
+			addr: "10.0.0.1",
+			req:  &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local"},
+			code: http.StatusOK,
+		},
+		{
+			name:    "wrong password",
+			addr:    "10.0.0.2",
+			req:     &AuthRequest{Username: tests.TestUser1, Password: "foobar", Realm: "local"},
+			code:    http.StatusUnauthorized,
+			message: "Access denied",
+		},
+		{
+			name:    "empty credentials",
+			addr:    "10.0.0.3",
+			req:     &AuthRequest{Username: tests.TestUser1, Realm: "local"},
+			code:    http.StatusBadRequest,
+			message: "Bad Request",
+		},
+		{
+			name:    "mfa user without passcode",
+			addr:    "10.0.0.4",
+			req:     &AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local"},
+			code:    http.StatusUnauthorized,
+			message: "Passcode Required",
+		},
+		{
+			name:    "mfa user with invalid passcode",
+			addr:    "10.0.0.5",
+			req:     &AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local", Passcode: "000"},
+			code:    http.StatusUnauthorized,
+			message: "Access denied",
+		},
+		{
+			name: "mfa user with passcode",
+			addr: "10.0.0.6",
+			req:  &AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local", Passcode: getTestPasscode(secret)},
+			code: http.StatusOK,
+		},
+		{
+			name:       "account rate limit exceeded",
+			addr:       "10.0.0.7",
+			req:        &AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local"},
+			code:       http.StatusTooManyRequests,
+			message:    "Too Many Requests",
+			retryAfter: true,
+		},
+		{
+			name: "api key login",
+			addr: "10.0.0.8",
+			req:  &AuthRequest{APIKey: apiKey, Realm: "local"},
+			code: http.StatusOK,
+		},
+		{
+			name:    "invalid api key",
+			addr:    "10.0.0.8",
+			req:     &AuthRequest{APIKey: invalidAPIKey, Realm: "local"},
+			code:    http.StatusUnauthorized,
+			message: "Access denied",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			b, _ := json.Marshal(tc.req)
+			r := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+			r.RemoteAddr = tc.addr + ":12345"
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 48)
+			if err := p.handleJSONLogin(context.Background(), w, r, rr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+			tests.EvalObjectsWithLog(t, "retry after", tc.retryAfter, w.Header().Get("Retry-After") != "", msgs)
+			if tc.code != http.StatusOK {
+				resp := &AccessDeniedResponse{}
+				json.Unmarshal(w.Body.Bytes(), resp)
+				tests.EvalObjectsWithLog(t, "message", tc.message, resp.Message, msgs)
+				return
+			}
+			resp := &AuthResponse{}
+			json.Unmarshal(w.Body.Bytes(), resp)
+			tests.EvalObjectsWithLog(t, "token issued", true, resp.Token != "" && resp.ExpiresAt > 0, msgs)
+		})
+	}
+}
+
+func TestBreakGlassJSONLogin(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		BreakGlassConfig: &breakglass.Config{
+			Usernames:          []string{tests.TestUser1},
+			HardwareKeyAAGUIDs: []string{"cb69481e-8ff7-4039-93ec-0a2729a154a8"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name string
+		req  *AuthRequest
+		code int
+	}{
+		{
+			name: "break-glass account password login",
+			req:  &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local"},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "regular account password login",
+			req:  &AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local"},
+			code: http.StatusOK,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			b, _ := json.Marshal(tc.req)
+			r := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 48)
+			if err := p.handleJSONLogin(context.Background(), w, r, rr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+		})
+	}
+}
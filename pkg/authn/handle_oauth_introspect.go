@@ -0,0 +1,116 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// handleOAuthIntrospect serves the token introspection endpoint of the
+// portal, see RFC 7662. The service clients authenticate the same way as
+// with the client credentials grant. The tokens are verified with the
+// keystore, which consults the token stores, so that the expired and the
+// revoked tokens are reported inactive.
+func (p *Portal) handleOAuthIntrospect(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	w.Header().Set("Content-Type", "application/json")
+	if p.clients == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	if err := r.ParseForm(); err != nil {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_request")
+	}
+
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleJSONOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	srcAddr := addrutil.GetSourceAddress(r)
+	client, err := p.authenticateClient(r)
+	if err != nil {
+		p.logger.Warn(
+			"token introspection failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", getClientID(r)),
+			zap.String("src_ip", srcAddr),
+			zap.Error(err),
+		)
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+p.config.Name+`"`)
+		return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_client")
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_request")
+	}
+
+	resp := map[string]interface{}{"active": false}
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = token
+	claims, err := p.keystore.ParseTokenClaims(ar)
+	if err == nil {
+		resp = getOAuthIntrospectionClaims(claims)
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "token_introspection"),
+		zap.String("request_id", rr.ID),
+		zap.String("client_id", client.ID),
+		zap.Any("active", resp["active"]),
+		zap.Any("subject", resp["sub"]),
+		zap.String("src_ip", srcAddr),
+	)
+	p.recordUsage("oauth/introspect")
+
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// getOAuthIntrospectionClaims returns the introspection response for the
+// claims of an active token, see RFC 7662, Section 2.2.
+func getOAuthIntrospectionClaims(claims map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	for k, v := range claims {
+		m[k] = v
+	}
+	delete(m, "scopes")
+	if scopes := getOAuthTokenScopes(claims); len(scopes) > 0 {
+		m["scope"] = strings.Join(scopes, " ")
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		m["username"] = sub
+	}
+	m["active"] = true
+	m["token_type"] = "Bearer"
+	return m
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+func newTestOAuthFormRequest(path string, form url.Values, id, secret string) *http.Request {
+	r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != "" {
+		r.SetBasicAuth(id, secret)
+	}
+	return r
+}
+
+func TestOAuthIntrospect(t *testing.T) {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: "authn_introspect_test", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("failed creating token store: %v", err)
+	}
+	tokenstore.Register("authn_introspect_test", store)
+	keyConfigs, err := kms.ParseCryptoKeyConfigs("crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+		"crypto key token store authn_introspect_test")
+	if err != nil {
+		t.Fatalf("failed parsing crypto key configs: %v", err)
+	}
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		CryptoKeyConfigs: keyConfigs,
+		ClientCredentialsConfig: &clients.Config{
+			Clients: []*clients.ClientConfig{
+				{ID: "billing", Secret: "billing-secret", Scopes: []string{"invoices:read"}},
+				{ID: "api-gateway", Secret: "gateway-secret"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func() string {
+		r := newTestOAuthFormRequest("/oauth2/token", url.Values{"grant_type": {"client_credentials"}}, "billing", "billing-secret")
+		w := httptest.NewRecorder()
+		if err := p.handleOAuthToken(context.Background(), w, r, requests.NewRequest()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp := make(map[string]interface{})
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		token, _ := resp["access_token"].(string)
+		if token == "" {
+			t.Fatalf("failed issuing token: %s", w.Body.String())
+		}
+		return token
+	}
+	activeToken := issue()
+	revokedToken := issue()
+	if err := p.keystore.RevokeToken(revokedToken); err != nil {
+		t.Fatalf("failed revoking token: %v", err)
+	}
+
+	testcases := []struct {
+		name   string
+		method string
+		id     string
+		secret string
+		token  string
+		code   int
+		want   map[string]interface{}
+	}{
+		{
+			name:   "active token",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			token:  activeToken,
+			code:   http.StatusOK,
+			want: map[string]interface{}{
+				"active":     true,
+				"sub":        "billing",
+				"client_id":  "billing",
+				"username":   "billing",
+				"scope":      "invoices:read",
+				"token_type": "Bearer",
+			},
+		},
+		{
+			name:   "revoked token",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			token:  revokedToken,
+			code:   http.StatusOK,
+			want:   map[string]interface{}{"active": false},
+		},
+		{
+			name:   "malformed token",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			token:  "foobar",
+			code:   http.StatusOK,
+			want:   map[string]interface{}{"active": false},
+		},
+		{
+			name:   "missing token",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			code:   http.StatusBadRequest,
+			want:   map[string]interface{}{"error": "invalid_request"},
+		},
+		{
+			name:   "invalid client secret",
+			id:     "api-gateway",
+			secret: "foobar",
+			token:  activeToken,
+			code:   http.StatusUnauthorized,
+			want:   map[string]interface{}{"error": "invalid_client"},
+		},
+		{
+			name:  "unauthenticated client",
+			token: activeToken,
+			code:  http.StatusUnauthorized,
+			want:  map[string]interface{}{"error": "invalid_client"},
+		},
+		{
+			name:   "get method",
+			method: "GET",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			code:   http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			r := newTestOAuthFormRequest("/oauth2/introspect", url.Values{"token": {tc.token}}, tc.id, tc.secret)
+			if tc.method != "" {
+				r.Method = tc.method
+			}
+			w := httptest.NewRecorder()
+			if err := p.handleOAuthIntrospect(context.Background(), w, r, requests.NewRequest()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+			if tc.want == nil {
+				return
+			}
+			resp := make(map[string]interface{})
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			got := make(map[string]interface{})
+			for k := range tc.want {
+				got[k] = resp[k]
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+			if resp["active"] == false {
+				tests.EvalObjectsWithLog(t, "inactive response", 1, len(resp), msgs)
+			}
+		})
+	}
+}
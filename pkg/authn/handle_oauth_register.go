@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/oidc"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// handleOAuthRegister serves the client registration endpoint of the OpenID
+// Connect provider, see RFC 7591. The requests must carry an initial access
+// token.
+func (p *Portal) handleOAuthRegister(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	w.Header().Set("Content-Type", "application/json")
+	if p.oidc == nil || !p.oidc.RegistrationEnabled() {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer"))
+	if err := p.oidc.AuthorizeRegistration(token); err != nil {
+		p.logger.Warn(
+			"client registration unauthorized",
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_token")
+	}
+
+	md := &oidc.ClientMetadata{}
+	if err := json.NewDecoder(r.Body).Decode(md); err != nil {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_client_metadata")
+	}
+	info, err := p.oidc.Register(md)
+	if err != nil {
+		p.logger.Warn(
+			"client registration failed",
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		switch {
+		case err == errors.ErrOIDCClientMetadataRedirectURIs, strings.HasPrefix(err.Error(), "oidc: client metadata redirect uri"):
+			return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_redirect_uri")
+		case strings.HasPrefix(err.Error(), "oidc: client metadata"):
+			return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_client_metadata")
+		}
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "oidc_client_registered"),
+		zap.String("request_id", rr.ID),
+		zap.String("client_id", info.ClientID),
+		zap.String("client_name", md.ClientName),
+		zap.Strings("redirect_uris", md.RedirectURIs),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("oidc/register")
+
+	resp := map[string]interface{}{
+		"client_id":                  info.ClientID,
+		"client_id_issued_at":        info.ClientIDIssuedAt,
+		"client_name":                md.ClientName,
+		"redirect_uris":              md.RedirectURIs,
+		"grant_types":                md.GrantTypes,
+		"response_types":             md.ResponseTypes,
+		"token_endpoint_auth_method": md.TokenEndpointAuthMethod,
+		"scope":                      md.Scope,
+	}
+	if info.ClientSecret != "" {
+		resp["client_secret"] = info.ClientSecret
+		resp["client_secret_expires_at"] = info.ClientSecretExpiresAt
+	}
+	rr.Response.Code = http.StatusCreated
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// handleOAuthRevoke serves the token revocation endpoint of the portal, see
+// RFC 7009. The service clients and the relying parties of the OpenID
+// Connect provider revoke the tokens issued to them. The opaque tokens are
+// removed from the token stores, and the other tokens are added to the
+// revocation lists shared with the gatekeepers.
+func (p *Portal) handleOAuthRevoke(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	w.Header().Set("Content-Type", "application/json")
+	if p.clients == nil && p.oidc == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	if err := r.ParseForm(); err != nil {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_request")
+	}
+
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleJSONOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	srcAddr := addrutil.GetSourceAddress(r)
+	clientID, err := p.authenticateOAuthClient(r)
+	if err != nil {
+		p.logger.Warn(
+			"token revocation failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", getClientID(r)),
+			zap.String("src_ip", srcAddr),
+			zap.Error(err),
+		)
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+p.config.Name+`"`)
+		return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_client")
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_request")
+	}
+	if !kms.IsOpaqueToken(token) && !p.keystore.HasRevocationList() {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "unsupported_token_type")
+	}
+
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = token
+	claims, err := p.keystore.ParseTokenClaims(ar)
+	if err != nil {
+		// The invalid, expired, and already revoked tokens do not
+		// cause an error response, see RFC 7009, Section 2.2.
+		rr.Response.Code = http.StatusOK
+		w.WriteHeader(rr.Response.Code)
+		return nil
+	}
+	if owner, _ := claims["client_id"].(string); owner != clientID {
+		p.logger.Warn(
+			"token revocation failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", clientID),
+			zap.String("src_ip", srcAddr),
+			zap.String("error", "token was not issued to the client"),
+		)
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "unauthorized_client")
+	}
+
+	usr, err := user.NewUser(claims)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	usr.Token = token
+	if err := p.revokeToken(r, rr, usr, "client_revocation"); err != nil {
+		return p.respondOAuthError(w, rr, http.StatusServiceUnavailable, "temporarily_unavailable")
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "token_revocation"),
+		zap.String("request_id", rr.ID),
+		zap.String("client_id", clientID),
+		zap.String("subject", usr.Claims.Subject),
+		zap.String("src_ip", srcAddr),
+	)
+	p.recordUsage("oauth/revoke")
+
+	rr.Response.Code = http.StatusOK
+	w.WriteHeader(rr.Response.Code)
+	return nil
+}
+
+// authenticateOAuthClient authenticates either a service client or a
+// relying party of the OpenID Connect provider and returns its id.
+func (p *Portal) authenticateOAuthClient(r *http.Request) (string, error) {
+	if p.clients != nil {
+		client, err := p.authenticateClient(r)
+		if err == nil {
+			return client.ID, nil
+		}
+		if p.oidc == nil {
+			return "", err
+		}
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	client, err := p.oidc.AuthenticateClient(id, secret)
+	if err != nil {
+		return "", err
+	}
+	return client.ID, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+func TestOAuthRevoke(t *testing.T) {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: "authn_revoke_test", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("failed creating token store: %v", err)
+	}
+	tokenstore.Register("authn_revoke_test", store)
+	keyConfigs, err := kms.ParseCryptoKeyConfigs("crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+		"crypto key token revocation authn_revoke_test")
+	if err != nil {
+		t.Fatalf("failed parsing crypto key configs: %v", err)
+	}
+	clientsConfig := &clients.Config{
+		Clients: []*clients.ClientConfig{
+			{ID: "billing", Secret: "billing-secret"},
+			{ID: "api-gateway", Secret: "gateway-secret"},
+		},
+	}
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		CryptoKeyConfigs:        keyConfigs,
+		ClientCredentialsConfig: clientsConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func() string {
+		r := newTestOAuthFormRequest("/oauth2/token", url.Values{"grant_type": {"client_credentials"}}, "billing", "billing-secret")
+		w := httptest.NewRecorder()
+		if err := p.handleOAuthToken(context.Background(), w, r, requests.NewRequest()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp := make(map[string]interface{})
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		token, _ := resp["access_token"].(string)
+		if token == "" {
+			t.Fatalf("failed issuing token: %s", w.Body.String())
+		}
+		return token
+	}
+	token := issue()
+
+	testcases := []struct {
+		name   string
+		id     string
+		secret string
+		token  string
+		code   int
+		want   string
+		active bool
+	}{
+		{
+			name:   "revoke token of another client",
+			id:     "api-gateway",
+			secret: "gateway-secret",
+			token:  token,
+			code:   http.StatusBadRequest,
+			want:   "unauthorized_client",
+			active: true,
+		},
+		{
+			name:   "invalid client secret",
+			id:     "billing",
+			secret: "foobar",
+			token:  token,
+			code:   http.StatusUnauthorized,
+			want:   "invalid_client",
+			active: true,
+		},
+		{
+			name:   "missing token",
+			id:     "billing",
+			secret: "billing-secret",
+			code:   http.StatusBadRequest,
+			want:   "invalid_request",
+			active: true,
+		},
+		{
+			name:   "revoke token",
+			id:     "billing",
+			secret: "billing-secret",
+			token:  token,
+			code:   http.StatusOK,
+		},
+		{
+			name:   "revoke revoked token",
+			id:     "billing",
+			secret: "billing-secret",
+			token:  token,
+			code:   http.StatusOK,
+		},
+		{
+			name:   "revoke malformed token",
+			id:     "billing",
+			secret: "billing-secret",
+			token:  "foo.bar.baz",
+			code:   http.StatusOK,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			r := newTestOAuthFormRequest("/oauth2/revoke", url.Values{"token": {tc.token}}, tc.id, tc.secret)
+			w := httptest.NewRecorder()
+			if err := p.handleOAuthRevoke(context.Background(), w, r, requests.NewRequest()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+			if tc.want != "" {
+				resp := make(map[string]interface{})
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				tests.EvalObjectsWithLog(t, "error", tc.want, resp["error"], msgs)
+			}
+
+			// The revoked tokens are reported inactive by the
+			// introspection endpoint.
+			r = newTestOAuthFormRequest("/oauth2/introspect", url.Values{"token": {token}}, "api-gateway", "gateway-secret")
+			w = httptest.NewRecorder()
+			if err := p.handleOAuthIntrospect(context.Background(), w, r, requests.NewRequest()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp := make(map[string]interface{})
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			tests.EvalObjectsWithLog(t, "active", tc.active, resp["active"], msgs)
+		})
+	}
+
+	// The JWT tokens cannot be revoked without the revocation list.
+	p, err = newTestMultiRealmPortal(t, &PortalConfig{ClientCredentialsConfig: clientsConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestOAuthFormRequest("/oauth2/revoke", url.Values{"token": {issue()}}, "billing", "billing-secret")
+	w := httptest.NewRecorder()
+	if err := p.handleOAuthRevoke(context.Background(), w, r, requests.NewRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "code", http.StatusBadRequest, w.Code)
+	tests.EvalObjects(t, "body", `{"error":"unsupported_token_type"}`, w.Body.String())
+}
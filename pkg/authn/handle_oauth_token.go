@@ -0,0 +1,186 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// handleOAuthToken serves the token endpoint of the portal. It supports the
+// client credentials grant for the service clients and the authorization
+// code grant for the relying parties of the OpenID Connect provider.
+func (p *Portal) handleOAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	w.Header().Set("Content-Type", "application/json")
+	if p.clients == nil && p.oidc == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	if err := r.ParseForm(); err != nil {
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_request")
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if p.clients != nil {
+			return p.handleClientCredentialsToken(ctx, w, r, rr)
+		}
+	case "authorization_code":
+		if p.oidc != nil {
+			return p.handleAuthorizationCodeToken(ctx, w, r, rr)
+		}
+	}
+	return p.respondOAuthError(w, rr, http.StatusBadRequest, "unsupported_grant_type")
+}
+
+// handleClientCredentialsToken issues tokens to the service clients
+// authenticating with the client credentials grant, see RFC 6749, Section
+// 4.4. The clients authenticate with the client secret, either in the
+// Authorization header or in the request body, or with a verified TLS client
+// certificate.
+func (p *Portal) handleClientCredentialsToken(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	release, err := p.admit(ctx)
+	if err != nil {
+		return p.handleJSONOverload(ctx, w, r, rr, err)
+	}
+	defer release()
+
+	srcAddr := addrutil.GetSourceAddress(r)
+	client, err := p.authenticateClient(r)
+	if err != nil {
+		p.logger.Warn(
+			"client credentials grant failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", getClientID(r)),
+			zap.String("src_ip", srcAddr),
+			zap.Error(err),
+		)
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+p.config.Name+`"`)
+		return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_client")
+	}
+
+	scopes, err := client.GrantScopes(strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		p.logger.Warn(
+			"client credentials grant failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", client.ID),
+			zap.String("src_ip", srcAddr),
+			zap.Error(err),
+		)
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_scope")
+	}
+
+	lifetime := p.keystore.GetTokenLifetime(nil, nil)
+	if client.TokenLifetime > 0 && client.TokenLifetime < lifetime {
+		lifetime = client.TokenLifetime
+	}
+
+	m := make(map[string]interface{})
+	m["sub"] = client.ID
+	m["client_id"] = client.ID
+	if len(client.Roles) > 0 {
+		m["roles"] = client.Roles
+	}
+	if len(scopes) > 0 {
+		m["scopes"] = scopes
+	}
+	m["exp"] = time.Now().Add(time.Duration(lifetime) * time.Second).UTC().Unix()
+	m["iat"] = time.Now().UTC().Unix()
+	m["nbf"] = time.Now().Add(time.Duration(60) * time.Second * -1).UTC().Unix()
+	m["origin"] = "client_credentials"
+	m["iss"] = "authp"
+	m["addr"] = srcAddr
+	p.shapeClaims(m)
+
+	usr, err := user.NewUser(m)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "client_credentials_grant"),
+		zap.String("request_id", rr.ID),
+		zap.String("client_id", client.ID),
+		zap.Strings("scopes", scopes),
+		zap.String("src_ip", srcAddr),
+	)
+	p.recordUsage("client_credentials")
+	metrics.TokensIssued.Inc(usr.Claims.Origin, "client_credentials")
+
+	resp := map[string]interface{}{
+		"access_token": usr.Token,
+		"token_type":   "Bearer",
+		"expires_in":   lifetime,
+	}
+	if len(scopes) > 0 {
+		resp["scope"] = strings.Join(scopes, " ")
+	}
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// authenticateClient authenticates a service client. The client secret
+// takes precedence over the TLS client certificate.
+func (p *Portal) authenticateClient(r *http.Request) (*clients.Client, error) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		return p.clients.Authenticate(id, secret)
+	}
+	id := r.PostForm.Get("client_id")
+	if secret := r.PostForm.Get("client_secret"); secret != "" {
+		return p.clients.Authenticate(id, secret)
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return p.clients.AuthenticateCertificate(id, r.TLS.VerifiedChains[0][0])
+	}
+	return p.clients.Authenticate(id, "")
+}
+
+func getClientID(r *http.Request) string {
+	if id, _, ok := r.BasicAuth(); ok {
+		return id
+	}
+	return r.PostForm.Get("client_id")
+}
+
+// respondOAuthError responds with the error response defined in RFC 6749,
+// Section 5.2.
+func (p *Portal) respondOAuthError(w http.ResponseWriter, rr *requests.Request, code int, reason string) error {
+	rr.Response.Code = code
+	respBytes, _ := json.Marshal(map[string]string{"error": reason})
+	w.WriteHeader(code)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,479 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/oidc"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+var oidcScopeDescriptions = map[string]string{
+	"openid":  "Sign you in with your identity",
+	"profile": "View your name",
+	"email":   "View your email address",
+	"groups":  "View your roles",
+}
+
+// handleHTTPOAuthAuthorize serves the authorization endpoint of the OpenID
+// Connect provider. Authenticated users either consent to the request of a
+// relying party or are redirected back to it with an authorization code.
+func (p *Portal) handleHTTPOAuthAuthorize(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, parsedUser *user.User) error {
+	p.disableClientCache(w)
+	if p.oidc == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusNotFound)
+	}
+
+	var usr *user.User
+	if parsedUser != nil {
+		sessionUser, err := p.sessions.Get(parsedUser.Claims.ID)
+		if err != nil {
+			p.deleteAuthCookies(w, r)
+		} else {
+			usr = sessionUser
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		if usr == nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusUnauthorized)
+		}
+		return p.handleHTTPOAuthConsent(ctx, w, r, rr, usr)
+	}
+
+	req, err := p.oidc.ParseAuthorizationRequest(r.URL.Query())
+	if req == nil {
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return p.redirectOAuthError(w, r, rr, req, getOAuthErrorCode(err))
+	}
+
+	if usr == nil {
+		if req.Prompt == "none" {
+			return p.redirectOAuthError(w, r, rr, req, "login_required")
+		}
+		return p.handleHTTPRedirect(ctx, w, r, rr, "/login?redirect_url="+url.QueryEscape(r.RequestURI))
+	}
+
+	client, _ := p.oidc.GetClient(req.ClientID)
+	if !p.oidc.IsConsentRequired(req.ClientID) || p.hasOAuthConsent(rr, usr, req) {
+		return p.grantOAuthAuthorization(ctx, w, r, rr, usr, req)
+	}
+	if req.Prompt == "none" {
+		return p.redirectOAuthError(w, r, rr, req, "consent_required")
+	}
+
+	consentID, err := p.oidc.Hold(req, usr.Claims.Subject)
+	if err != nil {
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if s, exists := oidcScopeDescriptions[scope]; exists {
+			scopes = append(scopes, s)
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+
+	resp := p.getUIArgs(r, rr, usr)
+	resp.PageTitle = "Authorize Application"
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.Data["consent_id"] = consentID
+	resp.Data["client_name"] = client.Name
+	if client.Name == "" {
+		resp.Data["client_name"] = client.ID
+	}
+	resp.Data["username"] = usr.Claims.Email
+	resp.Data["scopes"] = scopes
+	resp.Data["claims"] = getOIDCConsentClaims(usr, req.Scopes)
+	content, err := p.ui.Render("consent", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
+}
+
+// handleHTTPOAuthConsent processes the decision of a user on the consent
+// screen.
+func (p *Portal) handleHTTPOAuthConsent(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if err := r.ParseForm(); err != nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+	}
+	req, err := p.oidc.Release(r.PostForm.Get("consent_id"), usr.Claims.Subject)
+	if err != nil {
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	if r.PostForm.Get("decision") != "allow" {
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "oidc_consent_denied"),
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", usr.Claims.Subject),
+			zap.String("client_id", req.ClientID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		)
+		return p.redirectOAuthError(w, r, rr, req, "access_denied")
+	}
+
+	// The decision is remembered, unless the user opted out, and the user
+	// is not asked again for the same scopes.
+	if store := p.getSelfServiceStore(rr, usr); store != nil && r.PostForm.Get("remember") == "yes" {
+		rr.Consent.App = req.ClientID
+		rr.Consent.Scopes = req.Scopes
+		if err := store.Request(operator.AddConsent, rr); err != nil {
+			p.logger.Warn(
+				"failed recording consent",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("client_id", req.ClientID),
+				zap.Error(err),
+			)
+		}
+	}
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "oidc_consent_granted"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("client_id", req.ClientID),
+		zap.Strings("scopes", req.Scopes),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	return p.grantOAuthAuthorization(ctx, w, r, rr, usr, req)
+}
+
+// hasOAuthConsent returns true when a user of a local identity store
+// previously consented to all the requested scopes of a relying party.
+func (p *Portal) hasOAuthConsent(rr *requests.Request, usr *user.User, req *oidc.AuthorizationRequest) bool {
+	store := p.getSelfServiceStore(rr, usr)
+	if store == nil {
+		return false
+	}
+	if err := store.Request(operator.GetConsents, rr); err != nil {
+		return false
+	}
+	consents, ok := rr.Response.Payload.([]*identity.Consent)
+	if !ok {
+		return false
+	}
+	for _, consent := range consents {
+		if consent.App != req.ClientID {
+			continue
+		}
+		granted := make(map[string]bool)
+		for _, scope := range consent.Scopes {
+			granted[scope] = true
+		}
+		for _, scope := range req.Scopes {
+			if !granted[scope] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// grantOAuthAuthorization redirects a user back to the relying party with an
+// authorization code.
+func (p *Portal) grantOAuthAuthorization(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, req *oidc.AuthorizationRequest) error {
+	claims := map[string]interface{}{
+		"sub": usr.Claims.Subject,
+	}
+	if usr.Claims.Email != "" {
+		claims["email"] = usr.Claims.Email
+	}
+	if usr.Claims.Name != "" {
+		claims["name"] = usr.Claims.Name
+	}
+	if len(usr.Claims.Roles) > 0 {
+		claims["roles"] = usr.Claims.Roles
+	}
+	code, err := p.oidc.IssueCode(req, claims, usr.Claims.IssuedAt)
+	if err != nil {
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "oidc_authorization_granted"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("client_id", req.ClientID),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("oidc/authorize")
+	return p.redirectOAuth(w, r, rr, req, url.Values{"code": {code}})
+}
+
+// handleAuthorizationCodeToken exchanges an authorization code for an access
+// token and an ID token, see RFC 6749, Section 4.1.3.
+func (p *Portal) handleAuthorizationCodeToken(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	grant, err := p.oidc.Exchange(
+		r.PostForm.Get("code"), clientID, secret,
+		r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"),
+	)
+	if err != nil {
+		p.logger.Warn(
+			"authorization code grant failed",
+			zap.String("request_id", rr.ID),
+			zap.String("client_id", clientID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		if err == errors.ErrOIDCClientAuthFailed {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+p.config.Name+`"`)
+			return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_client")
+		}
+		return p.respondOAuthError(w, rr, http.StatusBadRequest, "invalid_grant")
+	}
+
+	issuer := p.getOAuthIssuer(r, "/oauth2/token")
+	lifetime := p.keystore.GetTokenLifetime(nil, nil)
+	now := time.Now().UTC()
+
+	m := make(map[string]interface{})
+	for k, v := range grant.Claims {
+		m[k] = v
+	}
+	if !oidcContains(grant.Scopes, "groups") {
+		delete(m, "roles")
+	}
+	m["aud"] = grant.ClientID
+	m["client_id"] = grant.ClientID
+	m["scopes"] = grant.Scopes
+	m["exp"] = now.Add(time.Duration(lifetime) * time.Second).Unix()
+	m["iat"] = now.Unix()
+	m["nbf"] = now.Add(time.Duration(60) * time.Second * -1).Unix()
+	m["iss"] = issuer
+	p.shapeClaims(m)
+	// The validators of the portal and the gatekeepers reject the token.
+	m["token_use"] = user.TokenUseRelyingParty
+	usr, err := user.NewUser(m)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+
+	idClaims := getOIDCClaims(grant.Claims, grant.Scopes)
+	idClaims["iss"] = issuer
+	idClaims["aud"] = grant.ClientID
+	idClaims["exp"] = now.Add(time.Duration(lifetime) * time.Second).Unix()
+	idClaims["iat"] = now.Unix()
+	if grant.AuthTime > 0 {
+		idClaims["auth_time"] = grant.AuthTime
+	}
+	if grant.Nonce != "" {
+		idClaims["nonce"] = grant.Nonce
+	}
+	idToken, err := p.keystore.SignClaims(nil, nil, idClaims)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "oidc_token_issued"),
+		zap.String("request_id", rr.ID),
+		zap.String("client_id", grant.ClientID),
+		zap.Any("subject", grant.Claims["sub"]),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.recordUsage("oidc/token")
+	metrics.TokensIssued.Inc(usr.Claims.Origin, "oidc")
+
+	resp := map[string]interface{}{
+		"access_token": usr.Token,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   lifetime,
+		"scope":        strings.Join(grant.Scopes, " "),
+	}
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// handleOAuthUserInfo serves the userinfo endpoint of the OpenID Connect
+// provider. It returns the claims of the user permitted by the scopes of
+// the access token.
+func (p *Portal) handleOAuthUserInfo(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	w.Header().Set("Content-Type", "application/json")
+	if p.oidc == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer"))
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = token
+	claims, err := p.keystore.ParseTokenClaims(ar)
+	var scopes []string
+	if err == nil {
+		scopes = getOAuthTokenScopes(claims)
+		if !oidcContains(scopes, "openid") {
+			err = errors.ErrOIDCTokenScopeInvalid
+		}
+	}
+	if token == "" || err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return p.respondOAuthError(w, rr, http.StatusUnauthorized, "invalid_token")
+	}
+
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(getOIDCClaims(claims, scopes))
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// redirectOAuth redirects a user back to the relying party with the
+// provided parameters and the state of the authorization request.
+func (p *Portal) redirectOAuth(w http.ResponseWriter, r *http.Request, rr *requests.Request, req *oidc.AuthorizationRequest, params url.Values) error {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	u.RawQuery = q.Encode()
+	rr.Response.Code = http.StatusFound
+	http.Redirect(w, r, u.String(), http.StatusFound)
+	return nil
+}
+
+// redirectOAuthError redirects a user back to the relying party with the
+// error response defined in RFC 6749, Section 4.1.2.1.
+func (p *Portal) redirectOAuthError(w http.ResponseWriter, r *http.Request, rr *requests.Request, req *oidc.AuthorizationRequest, reason string) error {
+	return p.redirectOAuth(w, r, rr, req, url.Values{"error": {reason}})
+}
+
+// getOAuthIssuer returns the issuer identifier of the OpenID Connect
+// provider. Unless configured, it is the URL of the portal.
+func (p *Portal) getOAuthIssuer(r *http.Request, suffix string) string {
+	if p.oidc != nil && p.oidc.GetIssuer() != "" {
+		return p.oidc.GetIssuer()
+	}
+	return strings.TrimSuffix(util.GetCurrentURL(r), suffix)
+}
+
+func getOAuthErrorCode(err error) string {
+	switch err {
+	case errors.ErrOIDCResponseTypeUnsupported:
+		return "unsupported_response_type"
+	case errors.ErrOIDCScopeInvalid:
+		return "invalid_scope"
+	}
+	return "invalid_request"
+}
+
+func getOAuthTokenScopes(claims map[string]interface{}) []string {
+	var scopes []string
+	for _, k := range []string{"scopes", "scope"} {
+		switch v := claims[k].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []interface{}:
+			for _, entry := range v {
+				if s, ok := entry.(string); ok {
+					scopes = append(scopes, s)
+				}
+			}
+		case []string:
+			scopes = append(scopes, v...)
+		}
+	}
+	return scopes
+}
+
+// getOIDCClaims returns the standard claims of a user permitted by the
+// granted scopes.
+func getOIDCClaims(claims map[string]interface{}, scopes []string) map[string]interface{} {
+	m := map[string]interface{}{
+		"sub": claims["sub"],
+	}
+	if oidcContains(scopes, "profile") && claims["name"] != nil {
+		m["name"] = claims["name"]
+	}
+	if oidcContains(scopes, "email") && claims["email"] != nil {
+		m["email"] = claims["email"]
+	}
+	if oidcContains(scopes, "groups") && claims["roles"] != nil {
+		m["groups"] = claims["roles"]
+	}
+	return m
+}
+
+// getOIDCConsentClaims returns the claims of a user released to a relying
+// party with the requested scopes, as displayed on the consent screen.
+func getOIDCConsentClaims(usr *user.User, scopes []string) []map[string]string {
+	var entries []map[string]string
+	if oidcContains(scopes, "profile") && usr.Claims.Name != "" {
+		entries = append(entries, map[string]string{"name": "Name", "value": usr.Claims.Name})
+	}
+	if oidcContains(scopes, "email") && usr.Claims.Email != "" {
+		entries = append(entries, map[string]string{"name": "Email", "value": usr.Claims.Email})
+	}
+	if oidcContains(scopes, "groups") && len(usr.Claims.Roles) > 0 {
+		entries = append(entries, map[string]string{"name": "Roles", "value": strings.Join(usr.Claims.Roles, ", ")})
+	}
+	return entries
+}
+
+func oidcContains(arr []string, s string) bool {
+	for _, entry := range arr {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
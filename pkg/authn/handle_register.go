@@ -49,6 +49,10 @@ func (p *Portal) handleHTTPRegister(ctx context.Context, w http.ResponseWriter,
 		return p.handleHTTPRegisterAckRequest(ctx, w, r, rr)
 	}
 
+	if p.isRegistrationReadOnly() {
+		return p.handleHTTPReadOnly(ctx, w, r, rr, "Registration")
+	}
+
 	if r.Method != "POST" {
 		// Handle registration landing page.
 		return p.handleHTTPRegisterScreen(ctx, w, r, rr)
@@ -69,7 +73,7 @@ func (p *Portal) handleHTTPRegisterScreenWithMessage(ctx context.Context, w http
 		return p.handleHTTPError(ctx, w, r, rr, http.StatusServiceUnavailable)
 	}
 
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, nil)
 	resp.BaseURL(rr.Upstream.BasePath)
 	resp.Data["view"] = reg.view
 
@@ -84,6 +88,13 @@ func (p *Portal) handleHTTPRegisterScreenWithMessage(ctx context.Context, w http
 			resp.Data["require_registration_code"] = true
 		}
 
+		if p.userRegistry.GetRequireInvite() {
+			resp.Data["require_invite_code"] = true
+		}
+		if code := r.URL.Query().Get("invite"); code != "" {
+			resp.Data["invite_code"] = code
+		}
+
 		if p.userRegistry.GetTermsConditionsLink() != "" {
 			resp.Data["terms_conditions_link"] = p.userRegistry.GetTermsConditionsLink()
 		} else {
@@ -96,6 +107,12 @@ func (p *Portal) handleHTTPRegisterScreenWithMessage(ctx context.Context, w http
 			resp.Data["privacy_policy_link"] = path.Join(rr.Upstream.BasePath, "/privacy-policy")
 		}
 
+		if captcha := p.userRegistry.GetCaptcha(); captcha != nil {
+			for k, v := range getCaptchaData(captcha) {
+				resp.Data[k] = v
+			}
+		}
+
 		resp.Data["username_validate_pattern"] = p.userRegistry.GetUsernamePolicyRegex()
 		resp.Data["username_validate_title"] = p.userRegistry.GetUsernamePolicySummary()
 		resp.Data["password_validate_pattern"] = p.userRegistry.GetPasswordPolicyRegex()
@@ -126,11 +143,17 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 	var message string
 	var maxBytesLimit int64 = 1000
 	var minBytesLimit int64 = 15
-	var userHandle, userMail, userSecret, userCode string
+	var userHandle, userMail, userSecret, userCode, userInvite string
 	var violations []string
 	var userAccept, validUserRegistration bool
 	validUserRegistration = true
 
+	captcha := p.userRegistry.GetCaptcha()
+	if captcha != nil {
+		// The CAPTCHA responses are up to a few kilobytes.
+		maxBytesLimit = 10000
+	}
+
 	if r.ContentLength > maxBytesLimit || r.ContentLength < minBytesLimit {
 		violations = append(violations, "payload size")
 	}
@@ -176,6 +199,8 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 				userMail = v[0]
 			case "registrant_code":
 				userCode = v[0]
+			case "registrant_invite":
+				userInvite = strings.TrimSpace(v[0])
 			case "accept_terms":
 				if v[0] == "on" {
 					userAccept = true
@@ -184,6 +209,21 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 		}
 	}
 
+	if validUserRegistration && captcha != nil {
+		if err := captcha.Verify(r.Form.Get(captcha.GetResponseField()), addrutil.GetSourceAddress(r)); err != nil {
+			p.logger.Warn(
+				"failed registration challenge",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("src_ip", addrutil.GetSourceAddress(r)),
+				zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+				zap.Error(err),
+			)
+			validUserRegistration = false
+			message = "Failed processing the registration form due to failed challenge verification"
+		}
+	}
+
 	if validUserRegistration {
 		// Inspect registration values.
 		if p.userRegistry.GetCode() != "" {
@@ -193,6 +233,13 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 			}
 		}
 
+		if p.userRegistry.GetRequireInvite() || userInvite != "" {
+			if _, err := p.userRegistry.CheckInvite(userInvite); err != nil {
+				validUserRegistration = false
+				message = "Failed processing the registration form due to invalid invitation code"
+			}
+		}
+
 		if p.userRegistry.GetRequireAcceptTerms() {
 			if !userAccept {
 				validUserRegistration = false
@@ -216,6 +263,11 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 				if err := validators.ValidateUserInput("secret", userSecret, secretOpts); err != nil {
 					validUserRegistration = false
 					message = "Failed processing the registration form due " + err.Error()
+					break
+				}
+				if err := p.checkRegistrationPassword(rr, userHandle, userSecret); err != nil {
+					validUserRegistration = false
+					message = "Failed processing the registration form: " + err.Error()
 				}
 			case "email":
 				emailOpts := make(map[string]interface{})
@@ -225,6 +277,11 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 				if err := validators.ValidateUserInput(k, userMail, emailOpts); err != nil {
 					validUserRegistration = false
 					message = "Failed processing the registration form due " + err.Error()
+					break
+				}
+				if err := p.userRegistry.CheckEmailDomain(userMail); err != nil {
+					validUserRegistration = false
+					message = "Failed processing the registration form due to " + err.Error()
 				}
 			}
 		}
@@ -239,6 +296,9 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 			"email":             userMail,
 			"registration_code": registrationCode,
 		}
+		if userInvite != "" {
+			cachedEntry["invite_code"] = userInvite
+		}
 		if err := p.userRegistry.AddRegistrationEntry(registrationID, cachedEntry); err != nil {
 			p.logger.Warn(
 				"failed adding a record to registration cache",
@@ -281,6 +341,8 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 			regData["src_ip"] = addrutil.GetSourceAddress(r)
 			regData["src_conn_ip"] = addrutil.GetSourceConnAddress(r)
 			regData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
+			p.addBrandingData(p.getRequestRealm(r, rr, nil), regData)
+			p.addLocationData(regData["src_ip"], regData)
 			if err := p.userRegistry.Notify(regData); err != nil {
 				p.logger.Warn(
 					"Failed to send notification",
@@ -318,6 +380,7 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 		zap.String("src_ip", addrutil.GetSourceAddress(r)),
 		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
 	)
+	p.recordUsage("register")
 	reg := &registerRequest{view: "registered"}
 	return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 }
@@ -405,6 +468,21 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 		return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 	}
 
+	if usr["invite_code"] != "" {
+		invite, err := p.userRegistry.RedeemInvite(usr["invite_code"])
+		if err != nil {
+			p.logger.Warn(
+				"failed registration acknowledgement due to invitation code",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Error(err),
+			)
+			reg.message = "Invitation code is no longer valid"
+			return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
+		}
+		req.User.Roles = append(req.User.Roles, invite.Roles...)
+	}
+
 	if err := p.userRegistry.AddUser(req); err != nil {
 		p.logger.Warn(
 			"registration request backend erred",
@@ -441,6 +519,8 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 	regData["src_conn_ip"] = addrutil.GetSourceConnAddress(r)
 	regData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
 
+	p.addBrandingData(p.getRequestRealm(r, rr, nil), regData)
+	p.addLocationData(regData["src_ip"], regData)
 	if err := p.userRegistry.Notify(regData); err != nil {
 		p.logger.Warn(
 			"Failed to send notification",
@@ -455,3 +535,30 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 	reg.view = "acked"
 	return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 }
+
+// checkRegistrationPassword checks the password of a registrant against the
+// known data breaches, if the identity store associated with the user
+// registry supports it.
+func (p *Portal) checkRegistrationPassword(rr *requests.Request, username, password string) error {
+	storeName := p.userRegistry.GetIdentityStoreName()
+	for _, store := range p.identityStores {
+		if store.GetName() != storeName {
+			continue
+		}
+		checker, ok := store.(interface {
+			CheckPassword(*requests.Request) error
+		})
+		if !ok {
+			return nil
+		}
+		req := &requests.Request{
+			Context: rr.GetContext(),
+			User: requests.User{
+				Username: username,
+				Password: password,
+			},
+		}
+		return checker.CheckPassword(req)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+// handleJWKS publishes the public keys verifying the tokens issued by the
+// portal.
+func (p *Portal) handleJWKS(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(p.keystore.GetJWKS())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
+
+// handleOpenIDConfiguration publishes OpenID Connect discovery metadata
+// referencing the JWKS endpoint of the portal.
+func (p *Portal) handleOpenIDConfiguration(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	if !p.config.DiscoveryEnabled && p.oidc == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	issuer := p.getOAuthIssuer(r, "/.well-known/openid-configuration")
+	resp := map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/login",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": p.keystore.GetSigningMethods(),
+	}
+	var grantTypes, authMethods []string
+	if p.oidc != nil {
+		resp["authorization_endpoint"] = issuer + "/oauth2/authorize"
+		resp["userinfo_endpoint"] = issuer + "/oauth2/userinfo"
+		resp["response_types_supported"] = []string{"code"}
+		resp["scopes_supported"] = p.oidc.GetScopes()
+		resp["claims_supported"] = []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email", "groups"}
+		resp["code_challenge_methods_supported"] = []string{"S256", "plain"}
+		if p.oidc.RegistrationEnabled() {
+			resp["registration_endpoint"] = issuer + "/oauth2/register"
+		}
+		grantTypes = append(grantTypes, "authorization_code")
+		authMethods = append(authMethods, "client_secret_basic", "client_secret_post", "none")
+	}
+	if p.clients != nil {
+		grantTypes = append(grantTypes, "client_credentials")
+		authMethods = append(authMethods, "tls_client_auth")
+		if p.oidc == nil {
+			authMethods = append(authMethods, "client_secret_basic", "client_secret_post")
+		}
+		resp["introspection_endpoint"] = issuer + "/oauth2/introspect"
+		resp["introspection_endpoint_auth_methods_supported"] = []string{"client_secret_basic", "client_secret_post", "tls_client_auth"}
+	}
+	if len(grantTypes) > 0 {
+		resp["token_endpoint"] = issuer + "/oauth2/token"
+		resp["grant_types_supported"] = grantTypes
+		resp["token_endpoint_auth_methods_supported"] = authMethods
+		resp["revocation_endpoint"] = issuer + "/oauth2/revoke"
+		resp["revocation_endpoint_auth_methods_supported"] = authMethods
+	}
+	rr.Response.Code = http.StatusOK
+	respBytes, _ := json.Marshal(resp)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
@@ -39,6 +39,8 @@ type LoginIcon struct {
 	UsernameRecoveryEnabled bool `json:"username_recovery_enabled,omitempty" xml:"username_recovery_enabled,omitempty" yaml:"username_recovery_enabled,omitempty"`
 	// PasswordRecoveryEnabled controls whether a user could recover password by providing an email address.
 	PasswordRecoveryEnabled bool `json:"password_recovery_enabled,omitempty" xml:"password_recovery_enabled,omitempty" yaml:"password_recovery_enabled,omitempty"`
+	// MagicLinkEnabled controls whether a user could sign in with a link sent to an email address.
+	MagicLinkEnabled bool `json:"magic_link_enabled,omitempty" xml:"magic_link_enabled,omitempty" yaml:"magic_link_enabled,omitempty"`
 	// ContactSupportEnabled controls whether contact support link is available.
 	ContactSupportEnabled bool `json:"contact_support_enabled,omitempty" xml:"contact_support_enabled,omitempty" yaml:"contact_support_enabled,omitempty"`
 
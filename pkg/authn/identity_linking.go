@@ -0,0 +1,178 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/linking"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// getLinkableProviders returns the identity providers the users could link
+// to their local accounts.
+func (p *Portal) getLinkableProviders() []map[string]string {
+	var entries []map[string]string
+	if p.linker == nil {
+		return entries
+	}
+	for _, provider := range p.identityProviders {
+		if !p.linker.Linkable(provider.GetRealm()) {
+			continue
+		}
+		entries = append(entries, map[string]string{
+			"realm": provider.GetRealm(),
+			"name":  provider.GetName(),
+			"kind":  provider.GetKind(),
+		})
+	}
+	return entries
+}
+
+// startIdentityLink registers the pending link of an identity provider to
+// the local account of an authenticated user and sets the cookie carrying
+// the link. It returns the path of the login endpoint of the provider.
+func (p *Portal) startIdentityLink(w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, realm string) (string, error) {
+	provider := p.getIdentityProviderByRealm(realm)
+	if provider == nil {
+		return "", errors.ErrIdentityLinkingRealmNotAllowed.WithArgs(realm)
+	}
+	id, err := p.linker.Start(&linking.PendingLink{
+		Realm:    usr.Authenticator.Realm,
+		Username: usr.Claims.Subject,
+		Email:    usr.Claims.Email,
+		Provider: realm,
+	})
+	if err != nil {
+		return "", err
+	}
+	lifetime := int(p.linker.GetLifetime().Seconds())
+	w.Header().Add("Set-Cookie", p.cookie.GetPersistentCookie(addrutil.GetSourceHost(r), p.cookie.IdentityLink, id, lifetime))
+	p.logger.Debug(
+		"Identity link started",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("realm", usr.Authenticator.Realm),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("provider", realm),
+	)
+	return "/" + getIdentityProviderEndpoint(provider), nil
+}
+
+// completeIdentityLink links the identity a user signed in with at an
+// identity provider to the local account of the user when the request
+// carries the cookie of a pending link. It returns false when there is no
+// pending link, i.e. the sign in is a regular one.
+func (p *Portal) completeIdentityLink(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) (bool, error) {
+	if p.linker == nil {
+		return false, nil
+	}
+	c, err := r.Cookie(p.cookie.IdentityLink)
+	if err != nil || c.Value == "" {
+		return false, nil
+	}
+	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), p.cookie.IdentityLink))
+
+	link, err := p.linker.Claim(c.Value, rr.Upstream.Realm)
+	if err != nil {
+		return true, p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	store := p.getIdentityStoreByRealm(link.Realm)
+	if store == nil || store.GetKind() != "local" {
+		err := errors.ErrIdentityLinkingStoreNotFound.WithArgs(link.Realm)
+		return true, p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	m, ok := rr.Response.Payload.(map[string]interface{})
+	if !ok {
+		return true, p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, "response payload not a map")
+	}
+	lr := requests.NewRequest()
+	lr.User.Username = link.Username
+	lr.User.Email = link.Email
+	lr.LinkedIdentity.Realm = rr.Upstream.Realm
+	lr.LinkedIdentity.Kind = rr.Upstream.Method
+	lr.LinkedIdentity.Subject = fmt.Sprint(m["sub"])
+	if v, exists := m["email"]; exists {
+		lr.LinkedIdentity.Email = fmt.Sprint(v)
+	}
+	if err := store.Request(operator.AddLinkedIdentity, lr); err != nil {
+		return true, p.handleHTTPErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "identity_linked"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("realm", link.Realm),
+		zap.String("username", link.Username),
+		zap.String("provider", rr.Upstream.Realm),
+		zap.String("subject", lr.LinkedIdentity.Subject),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	return true, p.handleHTTPRedirect(ctx, w, r, rr, "/settings/connected")
+}
+
+// resolveLinkedIdentity looks up the local account the identity a user
+// signed in with at an identity provider is linked to. When found, the
+// request is switched to the local account, i.e. the user gets the
+// identity of the local account.
+func (p *Portal) resolveLinkedIdentity(rr *requests.Request) bool {
+	if p.linker == nil {
+		return false
+	}
+	m, ok := rr.Response.Payload.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	sub, exists := m["sub"]
+	if !exists {
+		return false
+	}
+	for _, store := range p.identityStores {
+		if store.GetKind() != "local" {
+			continue
+		}
+		lr := requests.NewRequest()
+		lr.LinkedIdentity.Realm = rr.Upstream.Realm
+		lr.LinkedIdentity.Subject = fmt.Sprint(sub)
+		if err := store.Request(operator.LookupLinkedIdentity, lr); err != nil {
+			continue
+		}
+		p.logger.Debug(
+			"Linked identity resolved",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("provider", rr.Upstream.Realm),
+			zap.String("subject", lr.LinkedIdentity.Subject),
+			zap.String("realm", store.GetRealm()),
+			zap.String("username", lr.User.Username),
+		)
+		rr.User.Username = lr.User.Username
+		rr.User.Email = lr.User.Email
+		rr.User.FullName = lr.User.FullName
+		rr.User.Roles = lr.User.Roles
+		rr.User.Organizations = lr.User.Organizations
+		rr.Upstream.Realm = store.GetRealm()
+		rr.Upstream.Method = store.GetKind()
+		return true
+	}
+	return false
+}
@@ -27,6 +27,18 @@ import (
 
 // APIKeyAuth performs API key authentication.
 func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
+	usr, err := p.newAPIKeyUser(r)
+	if err != nil {
+		return err
+	}
+	r.Response.Payload = usr.Token
+	r.Response.Name = usr.TokenName
+	return nil
+}
+
+// newAPIKeyUser returns the user with the signed token associated with the
+// API key in the request.
+func (p *Portal) newAPIKeyUser(r *authproxy.Request) (*user.User, error) {
 	if r.Realm == "" {
 		r.Realm = "local"
 	}
@@ -35,6 +47,7 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 	rr.Logger = p.logger
 	rr.Response.Authenticated = false
 	rr.Key.Payload = r.Secret
+	rr.Key.Address = r.Address
 	rr.Upstream.Realm = r.Realm
 
 	backend := p.getIdentityStoreByRealm(r.Realm)
@@ -45,7 +58,7 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 			zap.String("custom_auth", "apikey"),
 			zap.String("realm", r.Realm),
 		)
-		return errors.ErrAPIKeyAuthFailed
+		return nil, errors.ErrAPIKeyAuthFailed
 	}
 
 	if err := backend.Request(operator.LookupAPIKey, rr); err != nil {
@@ -56,7 +69,7 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 			zap.String("realm", r.Realm),
 			zap.Error(err),
 		)
-		return errors.ErrAPIKeyAuthFailed
+		return nil, errors.ErrAPIKeyAuthFailed
 	}
 
 	if err := backend.Request(operator.IdentifyUser, rr); err != nil {
@@ -67,7 +80,18 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 			zap.String("realm", r.Realm),
 			zap.Error(err),
 		)
-		return errors.ErrAPIKeyAuthFailed
+		return nil, errors.ErrAPIKeyAuthFailed
+	}
+
+	if p.isBreakGlassAccount(backend.GetRealm(), rr.User.Username) {
+		p.logger.Warn(
+			"user lookup following api key lookup failed",
+			zap.String("source_address", r.Address),
+			zap.String("custom_auth", "apikey"),
+			zap.String("realm", r.Realm),
+			zap.Error(errors.ErrBreakGlassLoginMethod.WithArgs(rr.User.Username)),
+		)
+		return nil, errors.ErrAPIKeyAuthFailed
 	}
 
 	m := make(map[string]interface{})
@@ -76,12 +100,26 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 	if rr.User.FullName != "" {
 		m["name"] = rr.User.FullName
 	}
-	if len(rr.User.Roles) > 0 {
+	if len(rr.Key.Roles) > 0 {
+		// The roles of a scoped key are limited to the roles the user
+		// currently holds.
+		m["roles"] = intersectRoles(rr.Key.Roles, rr.User.Roles)
+	} else if len(rr.User.Roles) > 0 {
 		m["roles"] = rr.User.Roles
 	}
+	if len(rr.User.Organizations) > 0 {
+		m["org"] = rr.User.Organizations
+	}
+	if len(rr.Key.Scopes) > 0 {
+		m["scopes"] = rr.Key.Scopes
+	}
 
 	// m["jti"] = rr.Upstream.SessionID
-	m["exp"] = time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix()
+	expiresAt := time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC()
+	if !rr.Key.ExpiresAt.IsZero() && rr.Key.ExpiresAt.Before(expiresAt) {
+		expiresAt = rr.Key.ExpiresAt
+	}
+	m["exp"] = expiresAt.Unix()
 	m["iat"] = time.Now().UTC().Unix()
 	m["nbf"] = time.Now().Add(time.Duration(60) * time.Second * -1).UTC().Unix()
 	if _, exists := m["origin"]; !exists {
@@ -92,11 +130,12 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 
 	// Perform user claim transformation if necessary.
 	if err := p.transformUser(context.Background(), rr, m); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Inject portal specific roles
 	injectPortalRoles(m)
+	p.shapeClaims(m)
 
 	// Create a new user and sign the token.
 	usr, err := user.NewUser(m)
@@ -108,8 +147,14 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 			zap.String("realm", r.Realm),
 			zap.Error(err),
 		)
-		return errors.ErrAPIKeyAuthFailed
+		return nil, errors.ErrAPIKeyAuthFailed
+	}
+	expiresAt = time.Now().Add(time.Duration(p.getTokenLifetime(usr, r.Realm)) * time.Second).UTC()
+	if !rr.Key.ExpiresAt.IsZero() && rr.Key.ExpiresAt.Before(expiresAt) {
+		expiresAt = rr.Key.ExpiresAt
 	}
+	usr.SetExpiresAtClaim(expiresAt.Unix())
+	p.setTokenAudience(usr)
 	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
 		p.logger.Warn(
 			"user token signing failed",
@@ -118,10 +163,22 @@ func (p *Portal) APIKeyAuth(r *authproxy.Request) error {
 			zap.String("realm", r.Realm),
 			zap.Error(err),
 		)
-		return errors.ErrAPIKeyAuthFailed
+		return nil, errors.ErrAPIKeyAuthFailed
 	}
 
-	r.Response.Payload = usr.Token
-	r.Response.Name = usr.TokenName
-	return nil
+	return usr, nil
+}
+
+func intersectRoles(keyRoles, userRoles []string) []string {
+	m := make(map[string]bool)
+	for _, role := range userRoles {
+		m[role] = true
+	}
+	roles := []string{}
+	for _, role := range keyRoles {
+		if m[role] {
+			roles = append(roles, role)
+		}
+	}
+	return roles
 }
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// setIdentityProviderHint remembers the identity provider the user
+// authenticated with. The login with an identity store forgets the
+// previously remembered provider.
+func (p *Portal) setIdentityProviderHint(w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) {
+	if p.idpHints == nil {
+		return
+	}
+	if p.getIdentityProviderByRealm(usr.Authenticator.Realm) == nil {
+		p.deleteIdentityProviderHint(w, r)
+		return
+	}
+	hint, err := p.idpHints.Sign(usr.Authenticator.Realm)
+	if err != nil {
+		p.logger.Warn(
+			"failed to sign identity provider hint",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	h := addrutil.GetSourceHost(r)
+	w.Header().Add("Set-Cookie", p.cookie.GetPersistentCookie(h, p.cookie.IdentityProviderHint, hint, p.idpHints.GetLifetime()))
+}
+
+// deleteIdentityProviderHint forgets the identity provider remembered for
+// the user, if any.
+func (p *Portal) deleteIdentityProviderHint(w http.ResponseWriter, r *http.Request) {
+	if p.idpHints == nil {
+		return
+	}
+	if _, err := r.Cookie(p.cookie.IdentityProviderHint); err != nil {
+		return
+	}
+	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), p.cookie.IdentityProviderHint))
+}
+
+// getIdentityProviderHint returns the identity provider remembered for the
+// user. The hint with an invalid signature, or naming a provider no longer
+// configured, is ignored.
+func (p *Portal) getIdentityProviderHint(r *http.Request, rr *requests.Request) idp.IdentityProvider {
+	if p.idpHints == nil {
+		return nil
+	}
+	c, err := r.Cookie(p.cookie.IdentityProviderHint)
+	if err != nil {
+		return nil
+	}
+	realm, err := p.idpHints.Verify(c.Value)
+	if err != nil {
+		p.logger.Debug(
+			"invalid identity provider hint",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return p.getIdentityProviderByRealm(realm)
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/idphint"
+	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func getTestResponseCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	m := make(map[string]*http.Cookie)
+	for _, c := range w.Result().Cookies() {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func TestIdentityProviderHint(t *testing.T) {
+	provider, err := idp.NewIdentityProvider(&idp.IdentityProviderConfig{
+		Name: "contoso",
+		Kind: "oauth",
+		Params: map[string]interface{}{
+			"driver":        "github",
+			"realm":         "contoso",
+			"client_id":     "foo",
+			"client_secret": "bar",
+		},
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		IdentityProviderHintConfig: &idphint.Config{
+			SigningKey: "0123456789abcdef0123456789abcdef",
+		},
+	}, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderLoginScreen := func(target string, hint *http.Cookie) (*httptest.ResponseRecorder, string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", target, nil)
+		if hint != nil {
+			r.AddCookie(hint)
+		}
+		rr := requests.NewRequest()
+		rr.Upstream.BasePath = "/"
+		if err := p.handleHTTPLoginScreen(context.Background(), w, r, rr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return w, w.Body.String()
+	}
+
+	// The login with the identity provider remembers the provider.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/oauth2/contoso", nil)
+	usr := &user.User{}
+	usr.Authenticator.Realm = "contoso"
+	p.setIdentityProviderHint(w, r, requests.NewRequest(), usr)
+	hint, found := getTestResponseCookies(w)[p.cookie.IdentityProviderHint]
+	if !found {
+		t.Fatalf("identity provider hint cookie not found")
+	}
+	tests.EvalObjects(t, "hint lifetime", p.idpHints.GetLifetime(), hint.MaxAge)
+
+	// The returning user is offered to continue with the provider.
+	_, body := renderLoginScreen("/login", hint)
+	tests.EvalObjects(t, "hint redirect", true, strings.Contains(body, `content="2; url=/oauth2/contoso"`))
+	tests.EvalObjects(t, "use another account link", true, strings.Contains(body, "/login?use_another_account=true"))
+
+	// The user choosing another account gets the regular login screen and
+	// the hint is forgotten.
+	w, body = renderLoginScreen("/login?use_another_account=true", hint)
+	tests.EvalObjects(t, "skipped hint redirect", false, strings.Contains(body, "url=/oauth2/contoso"))
+	tests.EvalObjects(t, "hint deleted", "delete", getTestResponseCookies(w)[p.cookie.IdentityProviderHint].Value)
+
+	// The tampered hint is ignored.
+	_, body = renderLoginScreen("/login", &http.Cookie{Name: p.cookie.IdentityProviderHint, Value: hint.Value + "x"})
+	tests.EvalObjects(t, "tampered hint redirect", false, strings.Contains(body, "url=/oauth2/contoso"))
+
+	// The login with an identity store forgets the provider.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/login", nil)
+	r.AddCookie(hint)
+	usr.Authenticator.Realm = "local"
+	p.setIdentityProviderHint(w, r, requests.NewRequest(), usr)
+	tests.EvalObjects(t, "hint deleted after store login", "delete", getTestResponseCookies(w)[p.cookie.IdentityProviderHint].Value)
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idphint
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// MinSigningKeyLength is the minimum length of the key signing the
+	// identity provider hints.
+	MinSigningKeyLength = 32
+
+	defaultLifetime      = 2592000
+	defaultRedirectDelay = 2
+)
+
+// Config holds the configuration of the identity provider hints. The hint
+// remembers the identity provider a user last authenticated with, so that
+// the returning user is sent straight to that provider instead of being
+// asked to pick one. The replicas must share the signing key.
+type Config struct {
+	// The key signing the hints.
+	SigningKey string `json:"signing_key,omitempty" xml:"signing_key,omitempty" yaml:"signing_key,omitempty"`
+	// The lifetime of the hints in seconds. Defaults to 30 days.
+	Lifetime int `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+	// The number of seconds the login screen offers to use another account
+	// before redirecting the user to the provider. Defaults to 2 seconds.
+	RedirectDelay int `json:"redirect_delay,omitempty" xml:"redirect_delay,omitempty" yaml:"redirect_delay,omitempty"`
+}
+
+// Signer signs and verifies the identity provider hints.
+type Signer struct {
+	key           []byte
+	lifetime      time.Duration
+	redirectDelay int
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if len(cfg.SigningKey) < MinSigningKeyLength {
+		return errors.ErrIdentityProviderHintConfigSigningKey.WithArgs(MinSigningKeyLength)
+	}
+	if cfg.Lifetime < 0 {
+		return errors.ErrIdentityProviderHintConfigLifetime.WithArgs(cfg.Lifetime)
+	}
+	if cfg.RedirectDelay < 0 {
+		return errors.ErrIdentityProviderHintConfigDelay.WithArgs(cfg.RedirectDelay)
+	}
+	return nil
+}
+
+// NewSigner returns an instance of Signer.
+func NewSigner(cfg *Config) (*Signer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Signer{
+		key:           []byte(cfg.SigningKey),
+		lifetime:      time.Duration(defaultLifetime) * time.Second,
+		redirectDelay: defaultRedirectDelay,
+	}
+	if cfg.Lifetime > 0 {
+		s.lifetime = time.Duration(cfg.Lifetime) * time.Second
+	}
+	if cfg.RedirectDelay > 0 {
+		s.redirectDelay = cfg.RedirectDelay
+	}
+	return s, nil
+}
+
+// GetLifetime returns the lifetime of the hints in seconds.
+func (s *Signer) GetLifetime() int {
+	return int(s.lifetime / time.Second)
+}
+
+// GetRedirectDelay returns the number of seconds the login screen waits
+// before redirecting the user to the provider.
+func (s *Signer) GetRedirectDelay() int {
+	return s.redirectDelay
+}
+
+// Sign returns the hint for the realm of the identity provider.
+func (s *Signer) Sign(realm string) (string, error) {
+	return s.sign(realm, time.Now().Add(s.lifetime))
+}
+
+func (s *Signer) sign(realm string, expiresAt time.Time) (string, error) {
+	if realm == "" {
+		return "", errors.ErrIdentityProviderHintRealm.WithArgs(realm)
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(realm)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + s.getSignature(payload), nil
+}
+
+// Verify returns the realm of the identity provider of the hint.
+func (s *Signer) Verify(hint string) (string, error) {
+	i := strings.LastIndex(hint, ".")
+	if i < 0 {
+		return "", errors.ErrIdentityProviderHintMalformed
+	}
+	payload, signature := hint[:i], hint[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.getSignature(payload))) {
+		return "", errors.ErrIdentityProviderHintSignature
+	}
+	arr := strings.Split(payload, ".")
+	if len(arr) != 2 {
+		return "", errors.ErrIdentityProviderHintMalformed
+	}
+	expiresAt, err := strconv.ParseInt(arr[1], 10, 64)
+	if err != nil {
+		return "", errors.ErrIdentityProviderHintMalformed
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", errors.ErrIdentityProviderHintExpired
+	}
+	b, err := base64.RawURLEncoding.DecodeString(arr[0])
+	if err != nil || len(b) == 0 {
+		return "", errors.ErrIdentityProviderHintMalformed
+	}
+	return string(b), nil
+}
+
+func (s *Signer) getSignature(payload string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
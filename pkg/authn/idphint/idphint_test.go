@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idphint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewSigner(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		lifetime  int
+		delay     int
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "default config",
+			config:   &Config{SigningKey: testSigningKey},
+			lifetime: defaultLifetime,
+			delay:    defaultRedirectDelay,
+		},
+		{
+			name:     "custom lifetime and delay",
+			config:   &Config{SigningKey: testSigningKey, Lifetime: 3600, RedirectDelay: 5},
+			lifetime: 3600,
+			delay:    5,
+		},
+		{
+			name:      "short signing key",
+			config:    &Config{SigningKey: "foobar"},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintConfigSigningKey.WithArgs(MinSigningKeyLength),
+		},
+		{
+			name:      "negative lifetime",
+			config:    &Config{SigningKey: testSigningKey, Lifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintConfigLifetime.WithArgs(-1),
+		},
+		{
+			name:      "negative redirect delay",
+			config:    &Config{SigningKey: testSigningKey, RedirectDelay: -1},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintConfigDelay.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			s, err := NewSigner(tc.config)
+			if tests.EvalErrWithLog(t, err, "NewSigner", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "lifetime", tc.lifetime, s.GetLifetime(), msgs)
+			tests.EvalObjectsWithLog(t, "redirect delay", tc.delay, s.GetRedirectDelay(), msgs)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s, err := NewSigner(&Config{SigningKey: testSigningKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherSigner, err := NewSigner(&Config{SigningKey: strings.Repeat("x", MinSigningKeyLength)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	validHint, _ := s.Sign("contoso")
+	expiredHint, _ := s.sign("contoso", time.Now().Add(-1*time.Minute))
+	foreignHint, _ := otherSigner.Sign("contoso")
+
+	if _, err := s.Sign(""); err == nil {
+		t.Fatalf("expected error for empty realm")
+	}
+
+	testcases := []struct {
+		name      string
+		hint      string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid hint",
+			hint: validHint,
+			want: "contoso",
+		},
+		{
+			name:      "expired hint",
+			hint:      expiredHint,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintExpired,
+		},
+		{
+			name:      "hint signed with other key",
+			hint:      foreignHint,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintSignature,
+		},
+		{
+			name:      "raw realm",
+			hint:      "contoso",
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintMalformed,
+		},
+		{
+			name:      "tampered realm",
+			hint:      "Z2l0aHVi" + validHint[strings.Index(validHint, "."):],
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderHintSignature,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got, err := s.Verify(tc.hint)
+			if tests.EvalErrWithLog(t, err, "Verify", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "realm", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/impersonation"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+type adminImpersonationRequest struct {
+	Duration int `json:"duration"`
+}
+
+// impersonateUser issues a time-boxed token of the target user to the
+// administrator. The token carries the actor claim identifying the
+// administrator and replaces the token cookies of the administrator.
+func (p *Portal) impersonateUser(w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User, realm string, target *requests.Request, duration int) (map[string]interface{}, int, error) {
+	cfg := p.config.ImpersonationConfig
+	lifetime, err := cfg.GetLifetime(duration)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	m := make(map[string]interface{})
+	m["sub"] = target.User.Username
+	m["email"] = target.User.Email
+	if target.User.FullName != "" {
+		m["name"] = target.User.FullName
+	}
+	if len(target.User.Roles) > 0 {
+		m["roles"] = target.User.Roles
+	}
+	if len(target.User.Organizations) > 0 {
+		m["org"] = target.User.Organizations
+	}
+	now := time.Now().UTC()
+	m["exp"] = now.Add(lifetime).Unix()
+	m["iat"] = now.Unix()
+	m["nbf"] = now.Add(time.Duration(60) * time.Second * -1).Unix()
+	m["origin"] = realm
+	m["iss"] = "authp"
+	m["addr"] = addrutil.GetSourceAddress(r)
+
+	if err := p.transformUser(context.Background(), rr, m); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	injectPortalRoles(m)
+	p.shapeClaims(m)
+
+	// The roles are checked after the transformation, because it may
+	// grant the protected roles.
+	if err := cfg.Authorize(usr.AsMap(), usr.Claims.Roles, target.User.Username, getRoles(m)); err != nil {
+		p.logger.Warn(
+			"impersonation denied",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("admin", usr.Claims.Email),
+			zap.String("username", target.User.Username),
+			zap.Error(err),
+		)
+		return nil, http.StatusForbidden, err
+	}
+
+	actor := &impersonation.Actor{
+		Subject: usr.Claims.Subject,
+		Email:   usr.Claims.Email,
+	}
+	m[impersonation.ActorClaim] = impersonation.NewActorClaim(actor)
+
+	imp, err := user.NewUser(m)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	p.setTokenAudience(imp)
+	if err := p.keystore.SignToken(nil, nil, imp); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "user_impersonated"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("admin", usr.Claims.Email),
+		zap.String("realm", realm),
+		zap.String("username", target.User.Username),
+		zap.String("token_id", imp.Claims.ID),
+		zap.Int64("expires_at", imp.Claims.ExpiresAt),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.publishEvent(events.UserImpersonated, r, rr, imp, map[string]interface{}{
+		"actor":       usr.Claims.Subject,
+		"actor_email": usr.Claims.Email,
+		"expires_at":  imp.Claims.ExpiresAt,
+	})
+	p.recordUsage("impersonation/start")
+
+	imp.Authorized = true
+	p.sessions.Add(rr.Upstream.SessionID, imp)
+	w.Header().Set("Authorization", "Bearer "+imp.Token)
+	p.setTokenCookies(w, r, rr, addrutil.GetSourceHost(r), imp.TokenName, imp.Token)
+
+	resp := map[string]interface{}{
+		"username":   target.User.Username,
+		"token_name": imp.TokenName,
+		"token":      imp.Token,
+		"expires_at": time.Unix(imp.Claims.ExpiresAt, 0).UTC().Format(time.RFC3339),
+	}
+	return resp, http.StatusOK, nil
+}
+
+// handleAPIImpersonation ends the impersonation session of an
+// administrator. The token of the impersonated user is revoked and its
+// cookies are removed, so the administrator signs in again as themselves.
+//
+//	DELETE /api/impersonation
+func (p *Portal) handleAPIImpersonation(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if r.Method != http.MethodDelete {
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	actor := impersonation.GetActor(usr.AsMap())
+	if actor == nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, errors.ErrImpersonationNotActive.WithArgs(usr.Claims.Subject).Error())
+	}
+	if usr.Token != "" {
+		if err := p.revokeToken(r, rr, usr, "impersonation_ended"); err != nil {
+			return p.handleJSONError(ctx, w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+	}
+	p.sessions.Delete(rr.Upstream.SessionID)
+	p.deleteAuthCookies(w, r)
+
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "user_impersonation_ended"),
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("admin", actor.Email),
+		zap.String("admin_subject", actor.Subject),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("token_id", usr.Claims.ID),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+	p.publishEvent(events.ImpersonationEnded, r, rr, usr, map[string]interface{}{
+		"actor":       actor.Subject,
+		"actor_email": actor.Email,
+	})
+	p.recordUsage("impersonation/end")
+
+	return p.handleAPIAdminResponse(w, rr, map[string]interface{}{
+		"username": usr.Claims.Subject,
+		"ended":    true,
+	})
+}
+
+// logImpersonatedRequest logs the requests made by an administrator on
+// behalf of an impersonated user.
+func (p *Portal) logImpersonatedRequest(r *http.Request, rr *requests.Request, usr *user.User) {
+	actor := impersonation.GetActor(usr.AsMap())
+	if actor == nil {
+		return
+	}
+	p.logger.Info(
+		"Impersonated request",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("admin", actor.Email),
+		zap.String("admin_subject", actor.Subject),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("method", r.Method),
+		zap.String("url_path", r.URL.Path),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+}
+
+func getRoles(m map[string]interface{}) []string {
+	roles, _ := m["roles"].([]string)
+	return roles
+}
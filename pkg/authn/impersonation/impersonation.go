@@ -0,0 +1,146 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impersonation
+
+import (
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultRole     = "authp/impersonator"
+	defaultLifetime = 900
+	// ActorClaim is the claim identifying the administrator acting as the
+	// impersonated user, see RFC 8693.
+	ActorClaim = "act"
+)
+
+var defaultProtectedRoles = []string{"authp/admin"}
+
+// Config holds the configuration of the impersonation of the users by the
+// administrators.
+type Config struct {
+	// Role is the role an administrator must hold to impersonate the users.
+	// The default is authp/impersonator.
+	Role string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	// Lifetime is the maximum number of seconds an impersonation session
+	// lasts. The default is 15 minutes.
+	Lifetime int `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+	// ProtectedRoles are the roles of the users who cannot be impersonated.
+	// The default is authp/admin.
+	ProtectedRoles []string `json:"protected_roles,omitempty" xml:"protected_roles,omitempty" yaml:"protected_roles,omitempty"`
+}
+
+// Actor is the administrator acting as the impersonated user.
+type Actor struct {
+	Subject string `json:"subject,omitempty" xml:"subject,omitempty" yaml:"subject,omitempty"`
+	Email   string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Role == "" {
+		cfg.Role = defaultRole
+	}
+	if cfg.Lifetime < 0 {
+		return errors.ErrImpersonationConfigLifetime.WithArgs(cfg.Lifetime)
+	}
+	if cfg.Lifetime == 0 {
+		cfg.Lifetime = defaultLifetime
+	}
+	if len(cfg.ProtectedRoles) == 0 {
+		cfg.ProtectedRoles = defaultProtectedRoles
+	}
+	for _, roleName := range cfg.ProtectedRoles {
+		if roleName == "" {
+			return errors.ErrImpersonationConfigRole
+		}
+	}
+	return nil
+}
+
+// Authorize checks whether the administrator with the provided claims may
+// impersonate the user with the provided subject and roles.
+func (cfg *Config) Authorize(admin map[string]interface{}, adminRoles []string, subject string, roles []string) error {
+	adminSubject, _ := admin["sub"].(string)
+	if actor := GetActor(admin); actor != nil {
+		return errors.ErrImpersonationNested.WithArgs(adminSubject, actor.Subject)
+	}
+	if !contains(adminRoles, cfg.Role) {
+		return errors.ErrImpersonationNotPermitted.WithArgs(adminSubject)
+	}
+	if adminSubject == subject {
+		return errors.ErrImpersonationSelf.WithArgs(adminSubject)
+	}
+	for _, roleName := range cfg.ProtectedRoles {
+		if contains(roles, roleName) {
+			return errors.ErrImpersonationProtectedUser.WithArgs(subject, roleName)
+		}
+	}
+	return nil
+}
+
+// GetLifetime returns the lifetime of an impersonation session. The
+// requested duration of zero seconds is the maximum lifetime.
+func (cfg *Config) GetLifetime(duration int) (time.Duration, error) {
+	if duration < 0 || duration > cfg.Lifetime {
+		return 0, errors.ErrImpersonationLifetimeExceeded.WithArgs(duration, cfg.Lifetime)
+	}
+	if duration == 0 {
+		duration = cfg.Lifetime
+	}
+	return time.Duration(duration) * time.Second, nil
+}
+
+// NewActorClaim returns the value of the actor claim.
+func NewActorClaim(actor *Actor) map[string]interface{} {
+	m := map[string]interface{}{
+		"sub": actor.Subject,
+	}
+	if actor.Email != "" {
+		m["email"] = actor.Email
+	}
+	return m
+}
+
+// GetActor returns the administrator acting as the user with the provided
+// claims, if any.
+func GetActor(m map[string]interface{}) *Actor {
+	v, exists := m[ActorClaim]
+	if !exists {
+		return nil
+	}
+	claim, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	actor := &Actor{}
+	actor.Subject, _ = claim["sub"].(string)
+	actor.Email, _ = claim["email"].(string)
+	if actor.Subject == "" {
+		return nil
+	}
+	return actor
+}
+
+func contains(entries []string, s string) bool {
+	for _, entry := range entries {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impersonation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "default config",
+			config: &Config{},
+			want: &Config{
+				Role:           "authp/impersonator",
+				Lifetime:       900,
+				ProtectedRoles: []string{"authp/admin"},
+			},
+		},
+		{
+			name:      "negative lifetime",
+			config:    &Config{Lifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrImpersonationConfigLifetime.WithArgs(-1),
+		},
+		{
+			name:      "empty protected role",
+			config:    &Config{ProtectedRoles: []string{""}},
+			shouldErr: true,
+			err:       errors.ErrImpersonationConfigRole,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "Config", tc.want, tc.config, msgs)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := &Config{}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		name       string
+		admin      map[string]interface{}
+		adminRoles []string
+		subject    string
+		roles      []string
+		shouldErr  bool
+		err        error
+	}{
+		{
+			name:       "administrator with impersonator role",
+			admin:      map[string]interface{}{"sub": "webadmin"},
+			adminRoles: []string{"authp/admin", "authp/impersonator"},
+			subject:    "jsmith",
+			roles:      []string{"authp/user"},
+		},
+		{
+			name:       "administrator without impersonator role",
+			admin:      map[string]interface{}{"sub": "webadmin"},
+			adminRoles: []string{"authp/admin"},
+			subject:    "jsmith",
+			roles:      []string{"authp/user"},
+			shouldErr:  true,
+			err:        errors.ErrImpersonationNotPermitted.WithArgs("webadmin"),
+		},
+		{
+			name:       "administrator impersonating themselves",
+			admin:      map[string]interface{}{"sub": "webadmin"},
+			adminRoles: []string{"authp/admin", "authp/impersonator"},
+			subject:    "webadmin",
+			roles:      []string{"authp/user"},
+			shouldErr:  true,
+			err:        errors.ErrImpersonationSelf.WithArgs("webadmin"),
+		},
+		{
+			name:       "user with protected role",
+			admin:      map[string]interface{}{"sub": "webadmin"},
+			adminRoles: []string{"authp/admin", "authp/impersonator"},
+			subject:    "root",
+			roles:      []string{"authp/admin"},
+			shouldErr:  true,
+			err:        errors.ErrImpersonationProtectedUser.WithArgs("root", "authp/admin"),
+		},
+		{
+			name: "nested impersonation",
+			admin: map[string]interface{}{
+				"sub": "webadmin",
+				"act": map[string]interface{}{"sub": "root"},
+			},
+			adminRoles: []string{"authp/admin", "authp/impersonator"},
+			subject:    "jsmith",
+			roles:      []string{"authp/user"},
+			shouldErr:  true,
+			err:        errors.ErrImpersonationNested.WithArgs("webadmin", "root"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := cfg.Authorize(tc.admin, tc.adminRoles, tc.subject, tc.roles)
+			tests.EvalErrWithLog(t, err, "Authorize", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestGetLifetime(t *testing.T) {
+	cfg := &Config{Lifetime: 600}
+	testcases := []struct {
+		name      string
+		duration  int
+		want      time.Duration
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "default duration",
+			want: 600 * time.Second,
+		},
+		{
+			name:     "requested duration",
+			duration: 60,
+			want:     60 * time.Second,
+		},
+		{
+			name:      "requested duration exceeds lifetime",
+			duration:  601,
+			shouldErr: true,
+			err:       errors.ErrImpersonationLifetimeExceeded.WithArgs(601, 600),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got, err := cfg.GetLifetime(tc.duration)
+			if tests.EvalErrWithLog(t, err, "GetLifetime", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "lifetime", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestGetActor(t *testing.T) {
+	actor := &Actor{Subject: "webadmin", Email: "webadmin@localdomain.local"}
+	m := map[string]interface{}{
+		"sub":      "jsmith",
+		ActorClaim: NewActorClaim(actor),
+	}
+	tests.EvalObjects(t, "actor", actor, GetActor(m))
+	tests.EvalObjects(t, "no actor", (*Actor)(nil), GetActor(map[string]interface{}{"sub": "jsmith"}))
+}
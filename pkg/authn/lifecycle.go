@@ -0,0 +1,225 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/lifecycle"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+)
+
+// runLifecycleJob runs the user lifecycle job and returns its report. In the
+// dry run, the report holds the accounts and the records the job would have
+// affected.
+func (p *Portal) runLifecycleJob(job *lifecycle.Job) *lifecycle.Report {
+	report := lifecycle.NewReport(job)
+	switch job.Name {
+	case lifecycle.JobDormantAccounts:
+		p.disableDormantAccounts(job, report)
+	case lifecycle.JobPendingRegistrations:
+		p.purgePendingRegistrations(job, report)
+	case lifecycle.JobRoleGrants:
+		p.expireLifecycleRoleGrants(job, report)
+	case lifecycle.JobSessions:
+		p.pruneSessions(job, report)
+	case lifecycle.JobAuditRecords:
+		p.pruneLoginRecords(job, report)
+	}
+	report.Finish()
+	for _, s := range report.Errors {
+		p.logger.Warn(
+			"Failed running user lifecycle job",
+			zap.String("portal_name", p.config.Name),
+			zap.String("job", job.Name),
+			zap.String("error", s),
+		)
+	}
+	p.logger.Info(
+		"Completed user lifecycle job",
+		zap.String("portal_name", p.config.Name),
+		zap.String("job", job.Name),
+		zap.Bool("dry_run", job.DryRun),
+		zap.Int("count", len(report.Items)),
+	)
+	return report
+}
+
+// getLifecycleStores returns the local identity stores subject to the user
+// lifecycle jobs. The read-only stores are skipped by the jobs changing the
+// accounts.
+func (p *Portal) getLifecycleStores(writable bool) []ids.IdentityStore {
+	var stores []ids.IdentityStore
+	for _, store := range p.identityStores {
+		if store.GetKind() != "local" {
+			continue
+		}
+		if writable && isReadOnlyStore(store) {
+			continue
+		}
+		stores = append(stores, store)
+	}
+	return stores
+}
+
+// requestLifecycleItems runs the lifecycle operator against the identity
+// store and returns the affected accounts.
+func (p *Portal) requestLifecycleItems(op operator.Type, store ids.IdentityStore, job *lifecycle.Job, report *lifecycle.Report) []*requests.LifecycleItem {
+	req := &requests.Request{Context: context.Background()}
+	req.Lifecycle.Cutoff = job.Cutoff
+	req.Lifecycle.DryRun = job.DryRun
+	if err := store.Request(op, req); err != nil {
+		report.AddError(err)
+		return nil
+	}
+	items, _ := req.Response.Payload.([]*requests.LifecycleItem)
+	return items
+}
+
+// disableDormantAccounts disables the accounts without logins since the
+// cutoff and ends their sessions.
+func (p *Portal) disableDormantAccounts(job *lifecycle.Job, report *lifecycle.Report) {
+	for _, store := range p.getLifecycleStores(true) {
+		for _, entry := range p.requestLifecycleItems(operator.DisableDormantUsers, store, job, report) {
+			report.Add(&lifecycle.Item{
+				Realm:    store.GetRealm(),
+				Username: entry.Username,
+				Email:    entry.Email,
+				Detail:   "last seen",
+				Time:     entry.Time,
+			})
+			if job.DryRun {
+				continue
+			}
+			p.logger.Info(
+				"Audit",
+				zap.String("event", "user_disabled"),
+				zap.String("realm", store.GetRealm()),
+				zap.String("username", entry.Username),
+				zap.Time("last_seen", entry.Time),
+			)
+			events.Publish(&events.Event{
+				Type:     events.UserDisabled,
+				Realm:    store.GetRealm(),
+				Method:   "local",
+				Username: entry.Username,
+				Email:    entry.Email,
+				Data: map[string]interface{}{
+					"last_seen": entry.Time,
+				},
+			})
+			p.endUserSessions(entry.Email)
+		}
+	}
+}
+
+// purgePendingRegistrations deletes the registrations not approved since
+// the cutoff. The registrations of the read-only identity store are kept.
+func (p *Portal) purgePendingRegistrations(job *lifecycle.Job, report *lifecycle.Report) {
+	if p.userRegistry == nil || p.isRegistrationReadOnly() {
+		return
+	}
+	records, err := p.userRegistry.PurgeRegistrations(job.Cutoff, job.DryRun)
+	if err != nil {
+		report.AddError(err)
+		return
+	}
+	for _, rec := range records {
+		report.Add(&lifecycle.Item{
+			Username: rec.Username,
+			Email:    rec.Email,
+			Detail:   rec.Status,
+			Time:     rec.CreatedAt,
+		})
+		if job.DryRun {
+			continue
+		}
+		p.logger.Info(
+			"Audit",
+			zap.String("event", "registration_purged"),
+			zap.String("registration_id", rec.ID),
+			zap.String("username", rec.Username),
+			zap.String("status", rec.Status),
+		)
+	}
+}
+
+// expireLifecycleRoleGrants removes the expired time-boxed roles. Unlike
+// the removal at the role expiry interval, it supports the dry run and
+// reports the removed roles.
+func (p *Portal) expireLifecycleRoleGrants(job *lifecycle.Job, report *lifecycle.Report) {
+	for _, store := range p.getLifecycleStores(false) {
+		req := &requests.Request{Context: context.Background()}
+		req.Lifecycle.DryRun = job.DryRun
+		if err := store.Request(operator.ExpireRoles, req); err != nil {
+			report.AddError(err)
+			continue
+		}
+		grants, _ := req.Response.Payload.([]*requests.RoleGrant)
+		for _, grant := range grants {
+			report.Add(&lifecycle.Item{
+				Realm:    store.GetRealm(),
+				Username: grant.Username,
+				Email:    grant.Email,
+				Detail:   grant.Role,
+				Time:     grant.ExpiresAt,
+			})
+			if !job.DryRun {
+				p.handleExpiredRoleGrant(store.GetRealm(), grant)
+			}
+		}
+	}
+}
+
+// pruneSessions ends the sessions started before the cutoff and revokes
+// the tokens issued in the sessions.
+func (p *Portal) pruneSessions(job *lifecycle.Job, report *lifecycle.Report) {
+	for _, entry := range p.sessions.PruneSessions(job.Cutoff, job.DryRun) {
+		if entry == nil || entry.Claims == nil {
+			continue
+		}
+		report.Add(&lifecycle.Item{
+			Realm:    entry.Authenticator.Realm,
+			Username: entry.Claims.Subject,
+			Email:    entry.Claims.Email,
+			Time:     time.Unix(entry.Claims.IssuedAt, 0).UTC(),
+		})
+		if job.DryRun {
+			continue
+		}
+		p.keystore.RevokeToken(entry.Token)
+	}
+}
+
+// pruneLoginRecords removes the login history records older than the
+// cutoff.
+func (p *Portal) pruneLoginRecords(job *lifecycle.Job, report *lifecycle.Report) {
+	for _, store := range p.getLifecycleStores(false) {
+		for _, entry := range p.requestLifecycleItems(operator.PruneLoginRecords, store, job, report) {
+			report.Add(&lifecycle.Item{
+				Realm:    store.GetRealm(),
+				Username: entry.Username,
+				Email:    entry.Email,
+				Detail:   "login_history",
+				Count:    entry.Count,
+			})
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginhistory"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// JobDormantAccounts disables the accounts without logins for the
+	// configured number of days.
+	JobDormantAccounts = "dormant_accounts"
+	// JobPendingRegistrations deletes the registrations not approved within
+	// the configured number of days.
+	JobPendingRegistrations = "pending_registrations"
+	// JobRoleGrants removes the expired time-boxed roles.
+	JobRoleGrants = "role_grants"
+	// JobSessions ends the sessions older than the configured number of
+	// days.
+	JobSessions = "sessions"
+	// JobAuditRecords removes the login history records older than the
+	// configured number of days.
+	JobAuditRecords = "audit_records"
+
+	defaultInterval = 86400
+	day             = 24 * time.Hour
+)
+
+// jobNames are the names of the jobs in the order of their execution.
+var jobNames = []string{
+	JobDormantAccounts,
+	JobPendingRegistrations,
+	JobRoleGrants,
+	JobSessions,
+	JobAuditRecords,
+}
+
+// JobConfig holds the configuration of a user lifecycle job.
+type JobConfig struct {
+	Enabled bool `json:"enabled,omitempty" xml:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// DryRun reports the accounts and the records subject to the job
+	// without changing them.
+	DryRun bool `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	// Days is the age, in days, of the accounts and the records subject to
+	// the job. The role grants job disregards it.
+	Days int `json:"days,omitempty" xml:"days,omitempty" yaml:"days,omitempty"`
+}
+
+// Config holds the configuration of the scheduled user lifecycle jobs.
+type Config struct {
+	// The number of seconds between the runs of the jobs. The default is
+	// one day.
+	Interval             int        `json:"interval,omitempty" xml:"interval,omitempty" yaml:"interval,omitempty"`
+	DormantAccounts      *JobConfig `json:"dormant_accounts,omitempty" xml:"dormant_accounts,omitempty" yaml:"dormant_accounts,omitempty"`
+	PendingRegistrations *JobConfig `json:"pending_registrations,omitempty" xml:"pending_registrations,omitempty" yaml:"pending_registrations,omitempty"`
+	RoleGrants           *JobConfig `json:"role_grants,omitempty" xml:"role_grants,omitempty" yaml:"role_grants,omitempty"`
+	Sessions             *JobConfig `json:"sessions,omitempty" xml:"sessions,omitempty" yaml:"sessions,omitempty"`
+	AuditRecords         *JobConfig `json:"audit_records,omitempty" xml:"audit_records,omitempty" yaml:"audit_records,omitempty"`
+
+	loginHistory *loginhistory.Config
+}
+
+// Job is a run of a user lifecycle job. The accounts and the records older
+// than the cutoff are subject to the job.
+type Job struct {
+	Name   string    `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	DryRun bool      `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	Cutoff time.Time `json:"cutoff,omitempty" xml:"cutoff,omitempty" yaml:"cutoff,omitempty"`
+}
+
+// Item is an account, a registration, or a session affected by a job.
+type Item struct {
+	Realm    string    `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Detail   string    `json:"detail,omitempty" xml:"detail,omitempty" yaml:"detail,omitempty"`
+	Time     time.Time `json:"time,omitempty" xml:"time,omitempty" yaml:"time,omitempty"`
+	Count    int       `json:"count,omitempty" xml:"count,omitempty" yaml:"count,omitempty"`
+}
+
+// Report is the outcome of a run of a job. In the dry run, the items are
+// the ones the job would have affected.
+type Report struct {
+	Job        string    `json:"job,omitempty" xml:"job,omitempty" yaml:"job,omitempty"`
+	DryRun     bool      `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	Cutoff     time.Time `json:"cutoff,omitempty" xml:"cutoff,omitempty" yaml:"cutoff,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty" xml:"started_at,omitempty" yaml:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty" xml:"finished_at,omitempty" yaml:"finished_at,omitempty"`
+	Items      []*Item   `json:"items,omitempty" xml:"items,omitempty" yaml:"items,omitempty"`
+	Errors     []string  `json:"errors,omitempty" xml:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Manager runs the enabled user lifecycle jobs at the configured interval
+// and holds the report of the last run of each job.
+type Manager struct {
+	mu       sync.Mutex
+	interval time.Duration
+	jobs     map[string]*JobConfig
+	reports  map[string]*Report
+	exit     chan bool
+}
+
+// getJobs returns the configured jobs by name.
+func (cfg *Config) getJobs() map[string]*JobConfig {
+	jobs := make(map[string]*JobConfig)
+	for name, job := range map[string]*JobConfig{
+		JobDormantAccounts:      cfg.DormantAccounts,
+		JobPendingRegistrations: cfg.PendingRegistrations,
+		JobRoleGrants:           cfg.RoleGrants,
+		JobSessions:             cfg.Sessions,
+		JobAuditRecords:         cfg.AuditRecords,
+	} {
+		if job != nil {
+			jobs[name] = job
+		}
+	}
+	return jobs
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Interval < 0 {
+		return errors.ErrLifecycleConfigInterval.WithArgs(cfg.Interval)
+	}
+	for name, job := range cfg.getJobs() {
+		if job.Days < 0 {
+			return errors.ErrLifecycleConfigDays.WithArgs(name, job.Days)
+		}
+		if job.Enabled && job.Days == 0 && name != JobRoleGrants {
+			return errors.ErrLifecycleConfigDaysEmpty.WithArgs(name)
+		}
+	}
+	// The logins are recorded only with the login history enabled. Without
+	// it, the accounts appear to have never been used.
+	if cfg.IsEnabled(JobDormantAccounts) && cfg.loginHistory == nil {
+		return errors.ErrLifecycleConfigLoginHistory
+	}
+	return nil
+}
+
+// SetLoginHistory binds to the login history config.
+func (cfg *Config) SetLoginHistory(c *loginhistory.Config) {
+	cfg.loginHistory = c
+}
+
+// IsEnabled returns true when the job with the provided name is enabled.
+func (cfg *Config) IsEnabled(name string) bool {
+	job, exists := cfg.getJobs()[name]
+	return exists && job.Enabled
+}
+
+// NewManager returns an instance of Manager.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		interval: time.Duration(defaultInterval) * time.Second,
+		jobs:     cfg.getJobs(),
+		reports:  make(map[string]*Report),
+	}
+	if cfg.Interval > 0 {
+		m.interval = time.Duration(cfg.Interval) * time.Second
+	}
+	return m, nil
+}
+
+// NewJob returns a run of the enabled job with the provided name. The run
+// is a dry run when either requested or configured.
+func (m *Manager) NewJob(name string, dryRun bool) (*Job, error) {
+	cfg, exists := m.jobs[name]
+	if !exists {
+		return nil, errors.ErrLifecycleJobNotFound.WithArgs(name)
+	}
+	if !cfg.Enabled {
+		return nil, errors.ErrLifecycleJobDisabled.WithArgs(name)
+	}
+	job := &Job{
+		Name:   name,
+		DryRun: dryRun || cfg.DryRun,
+	}
+	if cfg.Days > 0 {
+		job.Cutoff = time.Now().Add(-time.Duration(cfg.Days) * day).UTC()
+	}
+	return job, nil
+}
+
+// GetJobs returns the runs of the enabled jobs in the order of their
+// execution.
+func (m *Manager) GetJobs() []*Job {
+	jobs := []*Job{}
+	for _, name := range jobNames {
+		if job, err := m.NewJob(name, false); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// Run calls the run function for each of the enabled jobs at the configured
+// interval, and records the returned reports, until Stop is called.
+func (m *Manager) Run(run func(*Job) *Report) {
+	m.mu.Lock()
+	if m.exit != nil {
+		m.mu.Unlock()
+		return
+	}
+	exit := make(chan bool)
+	m.exit = exit
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				for _, job := range m.GetJobs() {
+					m.Record(run(job))
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the runs started with Run.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.exit == nil {
+		return
+	}
+	close(m.exit)
+	m.exit = nil
+}
+
+// Record records the report of the last run of a job.
+func (m *Manager) Record(report *Report) {
+	if report == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports[report.Job] = report
+}
+
+// GetReports returns the reports of the last runs of the jobs in the order
+// of their execution.
+func (m *Manager) GetReports() []*Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reports := []*Report{}
+	for _, name := range jobNames {
+		if report, exists := m.reports[name]; exists {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// Inherit takes over the reports of the previous instance of the manager,
+// e.g. after reconfiguration. The reports recorded by the manager prevail.
+func (m *Manager) Inherit(prev *Manager) {
+	if prev == nil || prev == m {
+		return
+	}
+	for _, report := range prev.GetReports() {
+		m.mu.Lock()
+		if _, exists := m.reports[report.Job]; !exists {
+			m.reports[report.Job] = report
+		}
+		m.mu.Unlock()
+	}
+}
+
+// NewReport returns the report of the run of the job.
+func NewReport(job *Job) *Report {
+	return &Report{
+		Job:       job.Name,
+		DryRun:    job.DryRun,
+		Cutoff:    job.Cutoff,
+		StartedAt: time.Now().UTC(),
+		Items:     []*Item{},
+	}
+}
+
+// Add adds the item to the report.
+func (r *Report) Add(item *Item) {
+	r.Items = append(r.Items, item)
+}
+
+// AddError adds the error to the report.
+func (r *Report) AddError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// Finish records the completion of the run.
+func (r *Report) Finish() *Report {
+	r.FinishedAt = time.Now().UTC()
+	return r
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginhistory"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				DormantAccounts: &JobConfig{Enabled: true, Days: 90},
+				RoleGrants:      &JobConfig{Enabled: true},
+				AuditRecords:    &JobConfig{Enabled: true, Days: 30},
+				loginHistory:    &loginhistory.Config{},
+			},
+		},
+		{
+			name:      "config with negative interval",
+			config:    &Config{Interval: -1},
+			shouldErr: true,
+			err:       errors.ErrLifecycleConfigInterval.WithArgs(-1),
+		},
+		{
+			name:      "config with negative days",
+			config:    &Config{Sessions: &JobConfig{Days: -1}},
+			shouldErr: true,
+			err:       errors.ErrLifecycleConfigDays.WithArgs(JobSessions, -1),
+		},
+		{
+			name:      "config with enabled job without days",
+			config:    &Config{PendingRegistrations: &JobConfig{Enabled: true}},
+			shouldErr: true,
+			err:       errors.ErrLifecycleConfigDaysEmpty.WithArgs(JobPendingRegistrations),
+		},
+		{
+			name:      "config with dormant accounts job without login history",
+			config:    &Config{DormantAccounts: &JobConfig{Enabled: true, Days: 90}},
+			shouldErr: true,
+			err:       errors.ErrLifecycleConfigLoginHistory,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	cfg := &Config{
+		DormantAccounts: &JobConfig{Enabled: true, Days: 30},
+		RoleGrants:      &JobConfig{Enabled: true, DryRun: true},
+		Sessions:        &JobConfig{Days: 7},
+	}
+	cfg.SetLoginHistory(&loginhistory.Config{})
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The disabled and the unconfigured jobs do not run.
+	var names []string
+	for _, job := range m.GetJobs() {
+		names = append(names, job.Name)
+	}
+	tests.EvalObjects(t, "jobs", []string{JobDormantAccounts, JobRoleGrants}, names)
+
+	job, err := m.NewJob(JobDormantAccounts, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "requested dry run", true, job.DryRun)
+	cutoff := time.Now().Add(-30 * day)
+	if job.Cutoff.Sub(cutoff) > time.Minute || cutoff.Sub(job.Cutoff) > time.Minute {
+		t.Fatalf("unexpected cutoff: %v", job.Cutoff)
+	}
+
+	job, err = m.NewJob(JobRoleGrants, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "configured dry run", true, job.DryRun)
+	tests.EvalObjects(t, "role grants cutoff", true, job.Cutoff.IsZero())
+
+	_, err = m.NewJob(JobSessions, false)
+	tests.EvalErrWithLog(t, err, "disabled job", true, errors.ErrLifecycleJobDisabled.WithArgs(JobSessions), nil)
+	_, err = m.NewJob("foo", false)
+	tests.EvalErrWithLog(t, err, "unknown job", true, errors.ErrLifecycleJobNotFound.WithArgs("foo"), nil)
+
+	// The reports are kept per job and survive the reconfiguration.
+	report := NewReport(job)
+	report.Add(&Item{Realm: "local", Username: "jsmith", Detail: "authp/admin"})
+	m.Record(report.Finish())
+	next, err := NewManager(&Config{RoleGrants: &JobConfig{Enabled: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next.Inherit(m)
+	reports := next.GetReports()
+	tests.EvalObjects(t, "inherited reports", 1, len(reports))
+	tests.EvalObjects(t, "inherited report", report, reports[0])
+}
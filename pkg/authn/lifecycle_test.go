@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/lifecycle"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginhistory"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestLifecycleJobs(t *testing.T) {
+	_, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LifecycleConfig: &lifecycle.Config{
+			DormantAccounts: &lifecycle.JobConfig{Enabled: true, Days: 30},
+		},
+	})
+	tests.EvalErrWithLog(t, err, "dormant accounts without login history", true, errors.ErrNewPortal.WithArgs(errors.ErrLifecycleConfigLoginHistory), nil)
+
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LoginHistoryConfig: &loginhistory.Config{},
+		LifecycleConfig: &lifecycle.Config{
+			DormantAccounts: &lifecycle.JobConfig{Enabled: true, Days: 30},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.lifecycle.Stop()
+
+	// The accounts created just now are not dormant.
+	job, err := p.lifecycle.NewJob(lifecycle.JobDormantAccounts, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "recent accounts", 0, len(p.runLifecycleJob(job).Items))
+
+	testcases := []struct {
+		name   string
+		dryRun bool
+		items  []string
+		code   int
+	}{
+		{
+			name:   "dry run reports dormant accounts",
+			dryRun: true,
+			// The last administrator of a realm remains enabled.
+			items: []string{"local/" + tests.TestUser2, "backup/" + tests.TestUser2},
+			code:  http.StatusOK,
+		},
+		{
+			name:  "run disables dormant accounts",
+			items: []string{"local/" + tests.TestUser2, "backup/" + tests.TestUser2},
+			code:  http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			job := &lifecycle.Job{
+				Name:   lifecycle.JobDormantAccounts,
+				DryRun: tc.dryRun,
+				Cutoff: time.Now().Add(time.Minute),
+			}
+			report := p.runLifecycleJob(job)
+			var items []string
+			for _, item := range report.Items {
+				items = append(items, item.Realm+"/"+item.Username)
+			}
+			tests.EvalObjectsWithLog(t, "items", tc.items, items, msgs)
+			tests.EvalObjectsWithLog(t, "errors", 0, len(report.Errors), msgs)
+
+			b, _ := json.Marshal(&AuthRequest{Username: tests.TestUser2, Password: tests.TestPwd2, Realm: "local"})
+			r := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 48)
+			if err := p.handleJSONLogin(context.Background(), w, r, rr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+		})
+	}
+}
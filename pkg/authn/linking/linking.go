@@ -0,0 +1,152 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultLifetime = 600
+	linkIDLength    = 24
+)
+
+// Config holds the configuration of identity linking, i.e. the merging of
+// the identities of external providers into local user accounts.
+type Config struct {
+	// The realms of the identity providers users are allowed to link. When
+	// empty, all identity providers are linkable.
+	Realms []string `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+	// The number of seconds a user has to complete the sign in with the
+	// provider being linked.
+	Lifetime int `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+}
+
+// PendingLink is an identity link awaiting the sign in with the provider
+// being linked.
+type PendingLink struct {
+	// The realm and username of the local user account.
+	Realm    string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	// The realm of the identity provider being linked.
+	Provider  string    `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// Manager tracks pending identity links.
+type Manager struct {
+	mu       sync.Mutex
+	realms   map[string]bool
+	lifetime time.Duration
+	pending  map[string]*PendingLink
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for _, realm := range cfg.Realms {
+		if realm == "" {
+			return errors.ErrIdentityLinkingConfigRealmEmpty
+		}
+	}
+	if cfg.Lifetime < 0 {
+		return errors.ErrIdentityLinkingConfigLifetime.WithArgs(cfg.Lifetime)
+	}
+	return nil
+}
+
+// NewManager returns an instance of Manager. The pending links are held in
+// memory, i.e. they are discarded after a restart.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		realms:   make(map[string]bool),
+		lifetime: time.Duration(defaultLifetime) * time.Second,
+		pending:  make(map[string]*PendingLink),
+	}
+	for _, realm := range cfg.Realms {
+		m.realms[realm] = true
+	}
+	if cfg.Lifetime > 0 {
+		m.lifetime = time.Duration(cfg.Lifetime) * time.Second
+	}
+	return m, nil
+}
+
+// GetLifetime returns the lifetime of pending links.
+func (m *Manager) GetLifetime() time.Duration {
+	return m.lifetime
+}
+
+// Linkable returns true when the identity provider realm is linkable.
+func (m *Manager) Linkable(realm string) bool {
+	if len(m.realms) == 0 {
+		return true
+	}
+	return m.realms[realm]
+}
+
+// Start registers a pending link and returns its identifier.
+func (m *Manager) Start(link *PendingLink) (string, error) {
+	if !m.Linkable(link.Provider) {
+		return "", errors.ErrIdentityLinkingRealmNotAllowed.WithArgs(link.Provider)
+	}
+	b := make([]byte, linkIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	link.ExpiresAt = time.Now().Add(m.lifetime).UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	m.pending[id] = link
+	return id, nil
+}
+
+// Claim removes and returns the pending link with the provided identifier.
+// The link must have been started for the identity provider realm.
+func (m *Manager) Claim(id, provider string) (*PendingLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, exists := m.pending[id]
+	if !exists {
+		return nil, errors.ErrIdentityLinkingNotFound
+	}
+	delete(m.pending, id)
+	if time.Now().After(link.ExpiresAt) {
+		return nil, errors.ErrIdentityLinkingExpired
+	}
+	if link.Provider != provider {
+		return nil, errors.ErrIdentityLinkingRealmMismatch.WithArgs(link.Provider, provider)
+	}
+	return link, nil
+}
+
+func (m *Manager) prune() {
+	now := time.Now()
+	for id, link := range m.pending {
+		if now.After(link.ExpiresAt) {
+			delete(m.pending, id)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Realms: []string{"google"}, Lifetime: 300},
+		},
+		{
+			name:   "valid config without realms",
+			config: &Config{},
+		},
+		{
+			name:      "config with empty realm",
+			config:    &Config{Realms: []string{""}},
+			shouldErr: true,
+			err:       errors.ErrIdentityLinkingConfigRealmEmpty,
+		},
+		{
+			name:      "config with negative lifetime",
+			config:    &Config{Lifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrIdentityLinkingConfigLifetime.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestClaim(t *testing.T) {
+	m, err := NewManager(&Config{Realms: []string{"google", "github"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "linkable", true, m.Linkable("github"))
+	tests.EvalObjects(t, "not linkable", false, m.Linkable("facebook"))
+
+	_, err = m.Start(&PendingLink{Realm: "local", Username: "jsmith", Provider: "facebook"})
+	tests.EvalErrWithLog(t, err, "realm not allowed", true, errors.ErrIdentityLinkingRealmNotAllowed.WithArgs("facebook"), nil)
+
+	_, err = m.Claim("foo", "google")
+	tests.EvalErrWithLog(t, err, "no pending link", true, errors.ErrIdentityLinkingNotFound, nil)
+
+	id, err := m.Start(&PendingLink{Realm: "local", Username: "jsmith", Provider: "google"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "id length", linkIDLength*2, len(id))
+	link, err := m.Claim(id, "google")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "username", "jsmith", link.Username)
+	_, err = m.Claim(id, "google")
+	tests.EvalErrWithLog(t, err, "claimed link", true, errors.ErrIdentityLinkingNotFound, nil)
+
+	// The pending link is discarded when claimed by another provider.
+	id, err = m.Start(&PendingLink{Realm: "local", Username: "jsmith", Provider: "google"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.Claim(id, "github")
+	tests.EvalErrWithLog(t, err, "realm mismatch", true, errors.ErrIdentityLinkingRealmMismatch.WithArgs("google", "github"), nil)
+	_, err = m.Claim(id, "google")
+	tests.EvalErrWithLog(t, err, "discarded link", true, errors.ErrIdentityLinkingNotFound, nil)
+
+	m.lifetime = -time.Second
+	id, err = m.Start(&PendingLink{Realm: "local", Username: "jsmith", Provider: "google"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.Claim(id, "google")
+	tests.EvalErrWithLog(t, err, "expired link", true, errors.ErrIdentityLinkingExpired, nil)
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginchain"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
+	"go.uber.org/zap"
+)
+
+func (p *Portal) configureLoginChains() error {
+	if p.config.LoginChainConfig == nil {
+		return nil
+	}
+	for _, chain := range p.config.LoginChainConfig.Chains {
+		if p.getIdentityStoreByRealm(chain.Realm) == nil {
+			return errors.ErrLoginChainRealmNotFound.WithArgs(chain.Realm, chain.Realm)
+		}
+		for _, method := range chain.Methods {
+			if p.getIdentityStoreByRealm(method.Realm) == nil {
+				return errors.ErrLoginChainRealmNotFound.WithArgs(chain.Realm, method.Realm)
+			}
+		}
+	}
+	p.logger.Debug(
+		"Configured login chains",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.Any("login_chain_config", p.config.LoginChainConfig),
+	)
+	return nil
+}
+
+// startLoginChain starts the login of the user with the first method of the
+// chain, beginning at the provided position, available to the user. The
+// next available method becomes the fallback offered in the sandbox. When
+// none of the methods is available, the last one is used, i.e. the login
+// fails the way the login without the chain does.
+func (p *Portal) startLoginChain(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, chain *loginchain.Chain, username string, from int) error {
+	selected, next := -1, -1
+	for i := from; i < len(chain.Methods); i++ {
+		if !p.isLoginMethodAvailable(rr, chain.Methods[i], username) {
+			continue
+		}
+		if selected < 0 {
+			selected = i
+			continue
+		}
+		next = i
+		break
+	}
+	if selected < 0 {
+		selected = len(chain.Methods) - 1
+	}
+
+	method := chain.Methods[selected]
+	rr.User = requests.User{}
+	if err := p.identifyUserRequest(rr, map[string]string{"realm": method.Realm, "user": username}); err != nil {
+		rr.Response.Code = http.StatusBadRequest
+		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
+	}
+	if method.Kind == loginchain.MethodPasskey {
+		rr.User.Challenges = []string{"passkey"}
+	}
+	if next >= 0 && len(rr.User.Challenges) > 0 {
+		rr.User.Challenges[0] = cfgutil.EncodeArgs([]string{rr.User.Challenges[0], "fallback", chain.Realm, strconv.Itoa(next)})
+	}
+
+	p.logger.Debug(
+		"selected login method",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("chain_realm", chain.Realm),
+		zap.String("method", method.Kind),
+		zap.String("method_realm", method.Realm),
+		zap.Int("fallback", next),
+	)
+	return p.startSandboxSession(ctx, w, r, rr)
+}
+
+// isLoginMethodAvailable returns true when the identity store of the method
+// knows the user and, for the passkey method, the user has a WebAuthn token.
+func (p *Portal) isLoginMethodAvailable(rr *requests.Request, method *loginchain.Method, username string) bool {
+	rr.User = requests.User{}
+	if err := p.identifyUserRequest(rr, map[string]string{"realm": method.Realm, "user": username}); err != nil {
+		return false
+	}
+	if rr.User.Username == "nobody" {
+		return false
+	}
+	if method.Kind != loginchain.MethodPasskey {
+		return true
+	}
+	store := p.getIdentityStoreByRealm(method.Realm)
+	if err := store.Request(operator.GetMfaTokens, rr); err != nil {
+		return false
+	}
+	bundle, ok := rr.Response.Payload.(*identity.MfaTokenBundle)
+	if !ok {
+		return false
+	}
+	for _, token := range bundle.Get() {
+		if token.Type == "u2f" {
+			return true
+		}
+	}
+	return false
+}
+
+// getLoginFallback returns the chain and the position of the fallback
+// login method of the user in the sandbox, if any.
+func (p *Portal) getLoginFallback(usr *user.User) (*loginchain.Chain, int) {
+	for _, checkpoint := range usr.Checkpoints {
+		if checkpoint.Parameters == "" {
+			continue
+		}
+		args, err := cfgutil.DecodeArgs(checkpoint.Parameters)
+		if err != nil || len(args) != 3 || args[0] != "fallback" {
+			continue
+		}
+		chain := p.config.LoginChainConfig.GetChain(args[1])
+		if chain == nil {
+			continue
+		}
+		i, err := strconv.Atoi(args[2])
+		if err != nil || i < 1 || i >= len(chain.Methods) {
+			continue
+		}
+		return chain, i
+	}
+	return nil, 0
+}
+
+// handleHTTPLoginFallback replaces the sandbox of the user with the one of
+// the fallback login method.
+func (p *Portal) handleHTTPLoginFallback(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, sandboxID string, usr *user.User) error {
+	chain, next := p.getLoginFallback(usr)
+	if chain == nil {
+		return p.handleHTTPRedirectSeeOther(ctx, w, r, rr, "sandbox/"+sandboxID)
+	}
+	p.sandboxes.Delete(sandboxID)
+	p.terminateContinuation(rr, sandboxID)
+	p.logger.Debug(
+		"falling back to next login method",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("sandbox_id", sandboxID),
+		zap.String("chain_realm", chain.Realm),
+		zap.Int("method", next),
+	)
+	return p.startLoginChain(ctx, w, r, rr, chain, usr.Claims.Subject, next)
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginchain"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/idp"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+// newTestMultiRealmPortal returns the portal with the local identity stores
+// of the local and backup realms, and the identity providers, if any.
+func newTestMultiRealmPortal(t *testing.T, cfg *PortalConfig, providers ...idp.IdentityProvider) (*Portal, error) {
+	logger := logutil.NewLogger()
+	var stores []ids.IdentityStore
+	for _, realm := range []string{"local", "backup"} {
+		db, err := testutils.CreateTestDatabase(t.Name() + realm)
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		store, err := ids.NewIdentityStore(&ids.IdentityStoreConfig{
+			Name: realm + "_backend",
+			Kind: "local",
+			Params: map[string]interface{}{
+				"path":  db.GetPath(),
+				"realm": realm,
+			},
+		}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Configure(); err != nil {
+			t.Fatal(err)
+		}
+		stores = append(stores, store)
+	}
+	cfg.Name = "myportal"
+	cfg.IdentityStores = []string{"local_backend", "backup_backend"}
+	for _, provider := range providers {
+		cfg.IdentityProviders = append(cfg.IdentityProviders, provider.GetName())
+	}
+	return NewPortal(PortalParameters{
+		Config:            cfg,
+		Logger:            logger,
+		IdentityStores:    stores,
+		IdentityProviders: providers,
+	})
+}
+
+func getTestSandboxUser(t *testing.T, p *Portal, w *httptest.ResponseRecorder) *user.User {
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("unexpected response code: %d", w.Code)
+	}
+	usr, err := p.sandboxes.Get(path.Base(w.Header().Get("Location")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return usr
+}
+
+func TestLoginChain(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LoginChainConfig: &loginchain.Config{
+			Chains: []*loginchain.Chain{
+				{
+					Realm: "local",
+					Methods: []*loginchain.Method{
+						{Kind: "passkey"},
+						{Kind: "password"},
+						{Kind: "password", Realm: "backup"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := p.config.LoginChainConfig.GetChain("local")
+
+	// The passkey is not available to the user without WebAuthn tokens.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/login", nil)
+	rr := requests.NewRequest()
+	rr.Upstream.BasePath = "/"
+	if err := p.startLoginChain(context.Background(), w, r, rr, chain, tests.TestUser1, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr := getTestSandboxUser(t, p, w)
+	tests.EvalObjects(t, "realm", "local", usr.Authenticator.Realm)
+	tests.EvalObjects(t, "checkpoint", "password", usr.Checkpoints[0].Type)
+	tests.EvalObjects(t, "parameters", "fallback local 2", usr.Checkpoints[0].Parameters)
+
+	// The fallback replaces the sandbox with the one of the next method.
+	fallback, next := p.getLoginFallback(usr)
+	tests.EvalObjects(t, "fallback", 2, next)
+	w = httptest.NewRecorder()
+	rr = requests.NewRequest()
+	rr.Upstream.BasePath = "/"
+	if err := p.startLoginChain(context.Background(), w, r, rr, fallback, usr.Claims.Subject, next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr = getTestSandboxUser(t, p, w)
+	tests.EvalObjects(t, "fallback realm", "backup", usr.Authenticator.Realm)
+	tests.EvalObjects(t, "fallback parameters", "", usr.Checkpoints[0].Parameters)
+	fallback, _ = p.getLoginFallback(usr)
+	tests.EvalObjects(t, "last method fallback", true, fallback == nil)
+
+	// The unknown user gets the last method without the fallback.
+	w = httptest.NewRecorder()
+	rr = requests.NewRequest()
+	rr.Upstream.BasePath = "/"
+	if err := p.startLoginChain(context.Background(), w, r, rr, chain, "foobar", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr = getTestSandboxUser(t, p, w)
+	tests.EvalObjects(t, "unknown user realm", "backup", usr.Authenticator.Realm)
+	tests.EvalObjects(t, "unknown user checkpoint", "password", usr.Checkpoints[0].Type)
+}
+
+func TestLoginChainRealmNotFound(t *testing.T) {
+	_, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LoginChainConfig: &loginchain.Config{
+			Chains: []*loginchain.Chain{
+				{
+					Realm:   "local",
+					Methods: []*loginchain.Method{{Kind: "password", Realm: "contoso.com"}},
+				},
+			},
+		},
+	})
+	tests.EvalErrWithLog(t, err, "NewPortal", true, errors.ErrLoginChainRealmNotFound.WithArgs("local", "contoso.com"), nil)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// ChallengeRequiredResponse is the response to the authentication request
+// which must pass the CAPTCHA challenge. The request is retried with the
+// response to the challenge.
+type ChallengeRequiredResponse struct {
+	Error     bool                   `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+	Message   string                 `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	Timestamp string                 `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Captcha   map[string]interface{} `json:"captcha,omitempty" xml:"captcha,omitempty" yaml:"captcha,omitempty"`
+}
+
+// getLoginGuardKeys returns the keys the failed login attempts of the
+// source address and of the account are counted by.
+func getLoginGuardKeys(r *http.Request, realm, username string) []string {
+	keys := []string{"addr:" + addrutil.GetSourceAddress(r)}
+	if username != "" {
+		keys = append(keys, "user:"+realm+"/"+strings.ToLower(username))
+	}
+	return keys
+}
+
+// getLoginChallenge returns the CAPTCHA verifier when the login to the
+// realm must pass the challenge, or nil otherwise.
+func (p *Portal) getLoginChallenge(realm string, keys []string) *captcha.Verifier {
+	if p.loginGuard == nil || !p.loginGuard.Required(realm, keys...) {
+		return nil
+	}
+	return p.loginGuard.GetVerifier()
+}
+
+// recordLoginOutcome counts the failed login attempts towards the
+// challenge. The successful login forgets the failures of the account, but
+// not of the source address.
+func (p *Portal) recordLoginOutcome(keys []string, failed bool) {
+	if p.loginGuard == nil {
+		return
+	}
+	if failed {
+		p.loginGuard.Fail(keys...)
+		return
+	}
+	for _, k := range keys {
+		if strings.HasPrefix(k, "user:") {
+			p.loginGuard.Reset(k)
+		}
+	}
+}
+
+// verifyLoginChallenge verifies the response to the CAPTCHA challenge of
+// the login.
+func (p *Portal) verifyLoginChallenge(r *http.Request, rr *requests.Request, verifier *captcha.Verifier, response string) error {
+	if err := verifier.Verify(response, addrutil.GetSourceAddress(r)); err != nil {
+		p.logger.Warn(
+			"failed login challenge",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("source_address", addrutil.GetSourceAddress(r)),
+			zap.Error(err),
+		)
+		return errors.ErrLoginChallengeRequired
+	}
+	return nil
+}
+
+// getCaptchaData returns the data rendering the CAPTCHA widget.
+func getCaptchaData(verifier *captcha.Verifier) map[string]interface{} {
+	m := map[string]interface{}{
+		"captcha_provider":       verifier.GetProvider(),
+		"captcha_response_field": verifier.GetResponseField(),
+	}
+	if verifier.GetProvider() == "pow" {
+		m["captcha_puzzle"] = verifier.NewPuzzle()
+		m["captcha_difficulty"] = verifier.GetDifficulty()
+	} else {
+		m["captcha_site_key"] = verifier.GetSiteKey()
+	}
+	return m
+}
+
+func (p *Portal) handleJSONChallengeRequired(ctx context.Context, w http.ResponseWriter, verifier *captcha.Verifier) error {
+	resp := &ChallengeRequiredResponse{
+		Error:     true,
+		Message:   "Challenge Required",
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Captcha:   make(map[string]interface{}),
+	}
+	for k, v := range getCaptchaData(verifier) {
+		resp.Captcha[strings.TrimPrefix(k, "captcha_")] = v
+	}
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginguard"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+// solveTestPuzzle returns the solution of the proof-of-work puzzle.
+func solveTestPuzzle(puzzle string, difficulty int) string {
+	for nonce := 0; ; nonce++ {
+		solution := fmt.Sprintf("%s:%d", puzzle, nonce)
+		digest := sha256.Sum256([]byte(solution))
+		var zeros int
+		for _, b := range digest {
+			if b != 0 {
+				zeros += bits.LeadingZeros8(b)
+				break
+			}
+			zeros += 8
+		}
+		if zeros >= difficulty {
+			return solution
+		}
+	}
+}
+
+func TestJSONLoginChallenge(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		LoginRateLimitConfig: &ratelimit.Config{MaxAttempts: 10},
+		LoginChallengeConfig: &loginguard.Config{
+			Captcha:        &captcha.Config{Provider: "pow", Difficulty: 4},
+			Realms:         []string{"local"},
+			FailedAttempts: 2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var puzzle string
+	testcases := []struct {
+		name      string
+		addr      string
+		req       *AuthRequest
+		solve     bool
+		code      int
+		message   string
+		challenge bool
+	}{
+		{
+			name:    "first wrong password",
+			addr:    "10.0.0.1",
+			req:     &AuthRequest{Username: tests.TestUser1, Password: "foobar", Realm: "local"},
+			code:    http.StatusUnauthorized,
+			message: "Access denied",
+		},
+		{
+			name:    "second wrong password",
+			addr:    "10.0.0.1",
+			req:     &AuthRequest{Username: tests.TestUser1, Password: "foobar", Realm: "local"},
+			code:    http.StatusUnauthorized,
+			message: "Access denied",
+		},
+		{
+			name:      "password login without challenge response",
+			addr:      "10.0.0.1",
+			req:       &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local"},
+			code:      http.StatusUnauthorized,
+			message:   "Challenge Required",
+			challenge: true,
+		},
+		{
+			name:      "password login with invalid challenge response",
+			addr:      "10.0.0.1",
+			req:       &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local", Captcha: "foo:1"},
+			code:      http.StatusUnauthorized,
+			message:   "Challenge Required",
+			challenge: true,
+		},
+		{
+			name:  "password login with challenge response",
+			addr:  "10.0.0.1",
+			req:   &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local"},
+			solve: true,
+			code:  http.StatusOK,
+		},
+		{
+			name: "password login to reset account from another address",
+			addr: "10.0.0.2",
+			req:  &AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: "local"},
+			code: http.StatusOK,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			if tc.solve {
+				tc.req.Captcha = solveTestPuzzle(puzzle, 4)
+			}
+			b, _ := json.Marshal(tc.req)
+			r := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+			r.RemoteAddr = tc.addr + ":12345"
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 48)
+			if err := p.handleJSONLogin(context.Background(), w, r, rr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+			if tc.code == http.StatusOK {
+				return
+			}
+			resp := &ChallengeRequiredResponse{}
+			json.Unmarshal(w.Body.Bytes(), resp)
+			tests.EvalObjectsWithLog(t, "message", tc.message, resp.Message, msgs)
+			tests.EvalObjectsWithLog(t, "challenge", tc.challenge, resp.Captcha["provider"] == "pow", msgs)
+			if tc.challenge {
+				puzzle = resp.Captcha["puzzle"].(string)
+			}
+		})
+	}
+}
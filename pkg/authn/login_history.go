@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+const maxLoginRecordDeviceLength = 256
+
+// recordLogin adds the authentication attempt to the login history of the
+// user. Only local identity stores keep the login history.
+func (p *Portal) recordLogin(r *http.Request, rr *requests.Request, usr *user.User, outcome string) {
+	if p.config.LoginHistoryConfig == nil || usr == nil || usr.Claims == nil {
+		return
+	}
+	// The authentication attempts of unknown users are not recorded.
+	if usr.Claims.Subject == "nobody" {
+		return
+	}
+	realm := usr.Authenticator.Realm
+	if realm == "" {
+		realm = rr.Upstream.Realm
+	}
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil || store.GetKind() != "local" {
+		return
+	}
+	req := &requests.Request{Context: rr.GetContext()}
+	req.User.Username = usr.Claims.Subject
+	req.User.Email = usr.Claims.Email
+	req.LoginRecord = requests.LoginRecord{
+		Timestamp:  time.Now().UTC(),
+		Address:    addrutil.GetSourceAddress(r),
+		Device:     r.UserAgent(),
+		Provider:   store.GetName(),
+		Outcome:    outcome,
+		MaxEntries: p.config.LoginHistoryConfig.GetMaxEntries(),
+		MaxAge:     p.config.LoginHistoryConfig.GetMaxAge(),
+	}
+	if len(req.LoginRecord.Device) > maxLoginRecordDeviceLength {
+		req.LoginRecord.Device = req.LoginRecord.Device[:maxLoginRecordDeviceLength]
+	}
+	if loc := p.locate(req.LoginRecord.Address); loc != nil {
+		req.LoginRecord.Country = loc.Country
+		req.LoginRecord.City = loc.City
+	}
+	if err := store.Request(operator.AddLoginRecord, req); err != nil {
+		p.logger.Warn(
+			"Failed recording login history",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// seedSignIns replays the successful logins in the login history of the
+// user to the sign-in tracker without the fingerprints of the user, e.g.
+// after the loss of the tracker state, so that the devices and locations
+// known from the history are not reported as new.
+func (p *Portal) seedSignIns(realm, username, email string) {
+	if p.config.LoginHistoryConfig == nil {
+		return
+	}
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil || store.GetKind() != "local" {
+		return
+	}
+	req := &requests.Request{}
+	req.User.Username = username
+	req.User.Email = email
+	if err := store.Request(operator.GetUser, req); err != nil {
+		return
+	}
+	usr, ok := req.Response.Payload.(*identity.User)
+	if !ok {
+		return
+	}
+	for i := len(usr.LoginHistory) - 1; i >= 0; i-- {
+		rec := usr.LoginHistory[i]
+		if !rec.Succeeded() {
+			continue
+		}
+		p.signIns.Observe(realm, username, rec.Address, rec.Device, rec.Timestamp)
+	}
+}
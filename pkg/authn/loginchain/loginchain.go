@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginchain
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// MethodPasskey is the password-less authentication with a WebAuthn
+	// token registered by the user.
+	MethodPasskey = "passkey"
+	// MethodPassword is the authentication with a password, followed by
+	// the multi-factor authentication when the user has MFA tokens.
+	MethodPassword = "password"
+)
+
+// Config holds the chains of the login methods of the authentication
+// realms. The realms without a chain use the password-based login.
+type Config struct {
+	Chains []*Chain `json:"chains,omitempty" xml:"chains,omitempty" yaml:"chains,omitempty"`
+}
+
+// Chain is the ordered list of the login methods offered to the users of a
+// realm. The user identified by username is offered the first method
+// available to the user, and may fall back to the subsequent available
+// methods, e.g. passkey, then password and TOTP, then LDAP password.
+type Chain struct {
+	Realm   string    `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Methods []*Method `json:"methods,omitempty" xml:"methods,omitempty" yaml:"methods,omitempty"`
+}
+
+// Method is a login method of a chain.
+type Method struct {
+	// Kind is either passkey or password.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// Realm is the realm of the identity store performing the method. When
+	// empty, the realm of the chain is used.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if len(cfg.Chains) < 1 {
+		return errors.ErrLoginChainConfigChainsEmpty
+	}
+	realms := make(map[string]bool)
+	for i, chain := range cfg.Chains {
+		if chain.Realm == "" {
+			return errors.ErrLoginChainConfigRealmEmpty.WithArgs(i)
+		}
+		if realms[chain.Realm] {
+			return errors.ErrLoginChainConfigRealmExists.WithArgs(chain.Realm)
+		}
+		realms[chain.Realm] = true
+		if len(chain.Methods) < 1 {
+			return errors.ErrLoginChainConfigMethodsEmpty.WithArgs(chain.Realm)
+		}
+		methods := make(map[string]bool)
+		for _, method := range chain.Methods {
+			switch method.Kind {
+			case MethodPasskey, MethodPassword:
+			default:
+				return errors.ErrLoginChainConfigMethod.WithArgs(chain.Realm, method.Kind)
+			}
+			if method.Realm == "" {
+				method.Realm = chain.Realm
+			}
+			k := method.Kind + "/" + method.Realm
+			if methods[k] {
+				return errors.ErrLoginChainConfigMethodExists.WithArgs(chain.Realm, method.Kind, method.Realm)
+			}
+			methods[k] = true
+		}
+	}
+	return nil
+}
+
+// GetChain returns the chain of the realm, if any.
+func (cfg *Config) GetChain(realm string) *Chain {
+	if cfg == nil {
+		return nil
+	}
+	for _, chain := range cfg.Chains {
+		if chain.Realm == realm {
+			return chain
+		}
+	}
+	return nil
+}
+
+// GetRealms returns the realms of the identity stores performing the
+// methods of the chains.
+func (cfg *Config) GetRealms() []string {
+	var realms []string
+	seen := make(map[string]bool)
+	for _, chain := range cfg.Chains {
+		for _, method := range chain.Methods {
+			if seen[method.Realm] {
+				continue
+			}
+			seen[method.Realm] = true
+			realms = append(realms, method.Realm)
+		}
+	}
+	return realms
+}
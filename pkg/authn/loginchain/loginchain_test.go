@@ -0,0 +1,135 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginchain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Chains: []*Chain{
+					{
+						Realm: "local",
+						Methods: []*Method{
+							{Kind: "passkey"},
+							{Kind: "password"},
+							{Kind: "password", Realm: "contoso.com"},
+						},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"realms": []string{"local", "contoso.com"},
+			},
+		},
+		{
+			name:      "no chains",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigChainsEmpty,
+		},
+		{
+			name: "empty realm",
+			config: &Config{
+				Chains: []*Chain{{Methods: []*Method{{Kind: "password"}}}},
+			},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigRealmEmpty.WithArgs(0),
+		},
+		{
+			name: "duplicate realm",
+			config: &Config{
+				Chains: []*Chain{
+					{Realm: "local", Methods: []*Method{{Kind: "password"}}},
+					{Realm: "local", Methods: []*Method{{Kind: "passkey"}}},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigRealmExists.WithArgs("local"),
+		},
+		{
+			name: "no methods",
+			config: &Config{
+				Chains: []*Chain{{Realm: "local"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigMethodsEmpty.WithArgs("local"),
+		},
+		{
+			name: "unsupported method",
+			config: &Config{
+				Chains: []*Chain{{Realm: "local", Methods: []*Method{{Kind: "sms"}}}},
+			},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigMethod.WithArgs("local", "sms"),
+		},
+		{
+			name: "duplicate method",
+			config: &Config{
+				Chains: []*Chain{
+					{
+						Realm: "local",
+						Methods: []*Method{
+							{Kind: "password"},
+							{Kind: "password", Realm: "local"},
+						},
+					},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrLoginChainConfigMethodExists.WithArgs("local", "password", "local"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"realms": tc.config.GetRealms(),
+			}
+			tests.EvalObjectsWithLog(t, "Validate", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestGetChain(t *testing.T) {
+	cfg := &Config{
+		Chains: []*Chain{{Realm: "local", Methods: []*Method{{Kind: "passkey"}}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "local chain", "local", cfg.GetChain("local").Methods[0].Realm)
+	tests.EvalObjects(t, "unknown chain", true, cfg.GetChain("ldap") == nil)
+	var empty *Config
+	tests.EvalObjects(t, "nil config", true, empty.GetChain("local") == nil)
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginguard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultFailedAttempts = 3
+	defaultInterval       = 900
+)
+
+// Config holds the configuration of the CAPTCHA challenge presented to the
+// logins from the source addresses and to the accounts with recent failed
+// attempts. When a value is zero, the default applies.
+type Config struct {
+	// The CAPTCHA or proof-of-work challenge.
+	Captcha *captcha.Config `json:"captcha,omitempty" xml:"captcha,omitempty" yaml:"captcha,omitempty"`
+	// The realms the challenge applies to. When empty, the challenge applies
+	// to all realms.
+	Realms []string `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+	// The number of failed attempts per source address or per account
+	// within the interval after which the logins are challenged. Defaults
+	// to 3. When negative, every login is challenged.
+	FailedAttempts int `json:"failed_attempts,omitempty" xml:"failed_attempts,omitempty" yaml:"failed_attempts,omitempty"`
+	// The interval in seconds the failed attempts are remembered for.
+	// Defaults to 15 minutes.
+	Interval int `json:"interval,omitempty" xml:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+type window struct {
+	failures  int
+	expiresAt time.Time
+}
+
+// Guard counts the failed login attempts per key and decides whether the
+// next login is challenged.
+type Guard struct {
+	mu        sync.Mutex
+	verifier  *captcha.Verifier
+	realms    map[string]bool
+	threshold int
+	interval  time.Duration
+	windows   map[string]*window
+	prunedAt  time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Captcha == nil {
+		return errors.ErrLoginChallengeConfigCaptchaNotFound
+	}
+	if err := cfg.Captcha.Validate(); err != nil {
+		return err
+	}
+	if cfg.Interval < 0 {
+		return errors.ErrLoginChallengeConfigInterval.WithArgs(cfg.Interval)
+	}
+	return nil
+}
+
+// NewGuard returns an instance of Guard.
+func NewGuard(cfg *Config) (*Guard, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	verifier, err := captcha.NewVerifier(cfg.Captcha)
+	if err != nil {
+		return nil, err
+	}
+	g := &Guard{
+		verifier:  verifier,
+		realms:    make(map[string]bool),
+		threshold: defaultFailedAttempts,
+		interval:  time.Duration(defaultInterval) * time.Second,
+		windows:   make(map[string]*window),
+	}
+	for _, realm := range cfg.Realms {
+		g.realms[realm] = true
+	}
+	switch {
+	case cfg.FailedAttempts < 0:
+		g.threshold = 0
+	case cfg.FailedAttempts > 0:
+		g.threshold = cfg.FailedAttempts
+	}
+	if cfg.Interval > 0 {
+		g.interval = time.Duration(cfg.Interval) * time.Second
+	}
+	return g, nil
+}
+
+// GetVerifier returns the verifier of the CAPTCHA responses.
+func (g *Guard) GetVerifier() *captcha.Verifier {
+	return g.verifier
+}
+
+// Required returns true when the login to the realm must pass the
+// challenge, because any of the keys reached the failed attempts
+// threshold.
+func (g *Guard) Required(realm string, keys ...string) bool {
+	return g.required(time.Now(), realm, keys...)
+}
+
+func (g *Guard) required(now time.Time, realm string, keys ...string) bool {
+	if len(g.realms) > 0 && !g.realms[realm] {
+		return false
+	}
+	if g.threshold == 0 {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range keys {
+		w, exists := g.windows[k]
+		if !exists || now.After(w.expiresAt) {
+			continue
+		}
+		if w.failures >= g.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Fail records a failed attempt for each of the keys.
+func (g *Guard) Fail(keys ...string) {
+	g.fail(time.Now(), keys...)
+}
+
+func (g *Guard) fail(now time.Time, keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prune(now)
+	for _, k := range keys {
+		w, exists := g.windows[k]
+		if !exists || now.After(w.expiresAt) {
+			w = &window{}
+			g.windows[k] = w
+		}
+		w.failures++
+		w.expiresAt = now.Add(g.interval)
+	}
+}
+
+// Reset forgets the failed attempts of the keys, e.g. of the account
+// after a successful login.
+func (g *Guard) Reset(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range keys {
+		delete(g.windows, k)
+	}
+}
+
+// prune removes the expired windows at most once per interval.
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.prunedAt) < g.interval {
+		return
+	}
+	for k, w := range g.windows {
+		if now.After(w.expiresAt) {
+			delete(g.windows, k)
+		}
+	}
+	g.prunedAt = now
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginguard
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewGuard(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "custom config",
+			config: &Config{Captcha: &captcha.Config{Provider: "pow"}, FailedAttempts: 5, Interval: 60},
+		},
+		{
+			name:      "captcha config not found",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrLoginChallengeConfigCaptchaNotFound,
+		},
+		{
+			name:      "invalid captcha config",
+			config:    &Config{Captcha: &captcha.Config{Provider: "foo"}},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigProviderInvalid.WithArgs("foo"),
+		},
+		{
+			name:      "negative interval",
+			config:    &Config{Captcha: &captcha.Config{Provider: "pow"}, Interval: -1},
+			shouldErr: true,
+			err:       errors.ErrLoginChallengeConfigInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewGuard(tc.config)
+			tests.EvalErrWithLog(t, err, "NewGuard", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	g, err := NewGuard(&Config{
+		Captcha:        &captcha.Config{Provider: "pow"},
+		Realms:         []string{"local"},
+		FailedAttempts: 2,
+		Interval:       60,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	always, err := NewGuard(&Config{Captcha: &captcha.Config{Provider: "pow"}, FailedAttempts: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+
+	testcases := []struct {
+		name   string
+		guard  *Guard
+		offset time.Duration
+		fail   []string
+		reset  []string
+		realm  string
+		keys   []string
+		want   bool
+	}{
+		{
+			name:  "no failed attempts",
+			guard: g,
+			realm: "local",
+			keys:  []string{"addr:10.0.0.1", "user:local/jsmith"},
+		},
+		{
+			name:  "one failed attempt",
+			guard: g,
+			fail:  []string{"addr:10.0.0.1", "user:local/jsmith"},
+			realm: "local",
+			keys:  []string{"addr:10.0.0.1", "user:local/jsmith"},
+		},
+		{
+			name:   "two failed attempts",
+			guard:  g,
+			offset: time.Second,
+			fail:   []string{"addr:10.0.0.1", "user:local/jsmith"},
+			realm:  "local",
+			keys:   []string{"addr:10.0.0.1", "user:local/jsmith"},
+			want:   true,
+		},
+		{
+			name:   "same account from another address",
+			guard:  g,
+			offset: 2 * time.Second,
+			realm:  "local",
+			keys:   []string{"addr:10.0.0.2", "user:local/jsmith"},
+			want:   true,
+		},
+		{
+			name:   "another realm",
+			guard:  g,
+			offset: 2 * time.Second,
+			realm:  "contoso.com",
+			keys:   []string{"addr:10.0.0.1", "user:contoso.com/jsmith"},
+		},
+		{
+			name:   "account reset after successful login",
+			guard:  g,
+			offset: 3 * time.Second,
+			reset:  []string{"user:local/jsmith"},
+			realm:  "local",
+			keys:   []string{"addr:10.0.0.2", "user:local/jsmith"},
+		},
+		{
+			name:   "address remains challenged",
+			guard:  g,
+			offset: 3 * time.Second,
+			realm:  "local",
+			keys:   []string{"addr:10.0.0.1", "user:local/jsmith"},
+			want:   true,
+		},
+		{
+			name:   "failed attempts expired",
+			guard:  g,
+			offset: 62 * time.Second,
+			realm:  "local",
+			keys:   []string{"addr:10.0.0.1", "user:local/jsmith"},
+		},
+		{
+			name:  "every login challenged",
+			guard: always,
+			realm: "local",
+			keys:  []string{"addr:10.0.0.1", "user:local/jsmith"},
+			want:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			if len(tc.fail) > 0 {
+				tc.guard.fail(now.Add(tc.offset), tc.fail...)
+			}
+			if len(tc.reset) > 0 {
+				tc.guard.Reset(tc.reset...)
+			}
+			got := tc.guard.required(now.Add(tc.offset), tc.realm, tc.keys...)
+			tests.EvalObjectsWithLog(t, "required", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginhistory
+
+import (
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultMaxEntries = 50
+	defaultMaxAge     = 7776000
+)
+
+// Config holds the retention limits of the login history of the users in
+// local identity stores.
+type Config struct {
+	// The maximum number of authentication attempts retained per user. The
+	// default is 50.
+	MaxEntries int `json:"max_entries,omitempty" xml:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	// The maximum number of seconds an authentication attempt is retained
+	// for. The default is 90 days.
+	MaxAge int `json:"max_age,omitempty" xml:"max_age,omitempty" yaml:"max_age,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MaxEntries < 0 {
+		return errors.ErrLoginHistoryConfigMaxEntries.WithArgs(cfg.MaxEntries)
+	}
+	if cfg.MaxAge < 0 {
+		return errors.ErrLoginHistoryConfigMaxAge.WithArgs(cfg.MaxAge)
+	}
+	return nil
+}
+
+// GetMaxEntries returns the maximum number of retained authentication
+// attempts.
+func (cfg *Config) GetMaxEntries() int {
+	if cfg.MaxEntries > 0 {
+		return cfg.MaxEntries
+	}
+	return defaultMaxEntries
+}
+
+// GetMaxAge returns the retention period of authentication attempts.
+func (cfg *Config) GetMaxAge() time.Duration {
+	if cfg.MaxAge > 0 {
+		return time.Duration(cfg.MaxAge) * time.Second
+	}
+	return time.Duration(defaultMaxAge) * time.Second
+}
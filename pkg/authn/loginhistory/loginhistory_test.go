@@ -0,0 +1,69 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loginhistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name       string
+		config     *Config
+		maxEntries int
+		maxAge     time.Duration
+		shouldErr  bool
+		err        error
+	}{
+		{
+			name:       "default config",
+			config:     &Config{},
+			maxEntries: 50,
+			maxAge:     90 * 24 * time.Hour,
+		},
+		{
+			name:       "config with custom limits",
+			config:     &Config{MaxEntries: 10, MaxAge: 3600},
+			maxEntries: 10,
+			maxAge:     time.Hour,
+		},
+		{
+			name:      "config with negative max entries",
+			config:    &Config{MaxEntries: -1},
+			shouldErr: true,
+			err:       errors.ErrLoginHistoryConfigMaxEntries.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max age",
+			config:    &Config{MaxAge: -1},
+			shouldErr: true,
+			err:       errors.ErrLoginHistoryConfigMaxAge.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjects(t, "max entries", tc.maxEntries, tc.config.GetMaxEntries())
+			tests.EvalObjects(t, "max age", tc.maxAge, tc.config.GetMaxAge())
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package magiclink
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/onetime"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultTokenLifetime = 600
+	defaultMaxRequests   = 5
+	defaultWindow        = 3600
+)
+
+// Config holds the configuration of the passwordless magic link login.
+type Config struct {
+	// The email provider used to deliver login links.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The number of seconds a login link is valid for.
+	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+	// The maximum number of login link requests per email address and
+	// per source IP address within the throttling window.
+	MaxRequests int `json:"max_requests,omitempty" xml:"max_requests,omitempty" yaml:"max_requests,omitempty"`
+	// The throttling window in seconds.
+	Window int `json:"window,omitempty" xml:"window,omitempty" yaml:"window,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// Token is the payload of a login link.
+type Token struct {
+	Realm    string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	// The source IP address and the digest of the user agent of the
+	// device the link was requested from.
+	SourceAddress string `json:"source_address,omitempty" xml:"source_address,omitempty" yaml:"source_address,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty" xml:"user_agent,omitempty" yaml:"user_agent,omitempty"`
+}
+
+// Manager issues and redeems login link tokens and throttles login link
+// requests.
+type Manager struct {
+	config *Config
+	issuer *onetime.Issuer
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrMagicLinkConfigEmailProvider
+	}
+	if cfg.TokenLifetime < 0 {
+		return errors.ErrMagicLinkConfigTokenLifetime.WithArgs(cfg.TokenLifetime)
+	}
+	if cfg.MaxRequests < 0 {
+		return errors.ErrMagicLinkConfigMaxRequests.WithArgs(cfg.MaxRequests)
+	}
+	if cfg.Window < 0 {
+		return errors.ErrMagicLinkConfigWindow.WithArgs(cfg.Window)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of login links.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager. The tokens are signed with
+// a random key generated at startup, i.e. the outstanding links become
+// invalid after a restart.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	issuer, err := onetime.NewIssuer(&onetime.Config{
+		TokenLifetime: getValue(cfg.TokenLifetime, defaultTokenLifetime),
+		MaxRequests:   getValue(cfg.MaxRequests, defaultMaxRequests),
+		Window:        getValue(cfg.Window, defaultWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config: cfg,
+		issuer: issuer,
+	}
+	return m, nil
+}
+
+// GetTokenLifetime returns the lifetime of login link tokens.
+func (m *Manager) GetTokenLifetime() time.Duration {
+	return m.issuer.GetTokenLifetime()
+}
+
+// Allow records a login link request for each of the provided keys,
+// e.g. email and source IP addresses. It returns an error when any of the
+// keys exceeded the number of requests allowed within the window.
+func (m *Manager) Allow(keys ...string) error {
+	return m.issuer.Allow(keys...)
+}
+
+// IssueToken returns a signed login link token bound to the source
+// address and the user agent of the requesting device.
+func (m *Manager) IssueToken(realm, username, email, srcAddr, userAgent string) (string, error) {
+	return m.issuer.Issue(&Token{
+		Realm:         realm,
+		Username:      username,
+		Email:         email,
+		SourceAddress: srcAddr,
+		UserAgent:     digest(userAgent),
+	})
+}
+
+// VerifyToken checks the signature, expiry, and prior use of a login link
+// token and returns its payload.
+func (m *Manager) VerifyToken(s string) (*Token, error) {
+	tkn := &Token{}
+	if err := m.issuer.Verify(s, tkn); err != nil {
+		return nil, err
+	}
+	if tkn.Email == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return tkn, nil
+}
+
+// RedeemToken verifies a login link token and marks it used.
+func (m *Manager) RedeemToken(s string) (*Token, error) {
+	tkn := &Token{}
+	if err := m.issuer.Redeem(s, tkn); err != nil {
+		return nil, err
+	}
+	if tkn.Email == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return tkn, nil
+}
+
+// DeviceMismatch returns true when the login link is opened on a device
+// other than the one it was requested from.
+func (t *Token) DeviceMismatch(srcAddr, userAgent string) bool {
+	return t.SourceAddress != srcAddr || t.UserAgent != digest(userAgent)
+}
+
+func digest(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(h[:12])
+}
+
+func getValue(v, defaultValue int) int {
+	if v > 0 {
+		return v
+	}
+	return defaultValue
+}
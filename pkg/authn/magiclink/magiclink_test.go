@@ -0,0 +1,135 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package magiclink
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EmailProvider: "default", TokenLifetime: 300},
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrMagicLinkConfigEmailProvider,
+		},
+		{
+			name:      "config with negative token lifetime",
+			config:    &Config{EmailProvider: "default", TokenLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrMagicLinkConfigTokenLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max requests",
+			config:    &Config{EmailProvider: "default", MaxRequests: -1},
+			shouldErr: true,
+			err:       errors.ErrMagicLinkConfigMaxRequests.WithArgs(-1),
+		},
+		{
+			name:      "config with negative window",
+			config:    &Config{EmailProvider: "default", Window: -1},
+			shouldErr: true,
+			err:       errors.ErrMagicLinkConfigWindow.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "default lifetime", 10*time.Minute, m.GetTokenLifetime())
+	token, err := m.IssueToken("local", "jsmith", "jsmith@localhost", "10.0.0.1", "Mozilla/5.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tkn, err := m.VerifyToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "token username", "jsmith", tkn.Username)
+	tests.EvalObjects(t, "token realm", "local", tkn.Realm)
+	tests.EvalObjects(t, "same device", false, tkn.DeviceMismatch("10.0.0.1", "Mozilla/5.0"))
+	tests.EvalObjects(t, "other address", true, tkn.DeviceMismatch("10.0.0.2", "Mozilla/5.0"))
+	tests.EvalObjects(t, "other user agent", true, tkn.DeviceMismatch("10.0.0.1", "curl/7.79.1"))
+
+	if _, err := m.RedeemToken(token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.RedeemToken(token)
+	tests.EvalErrWithLog(t, err, "used token", true, errors.ErrOneTimeTokenUsed, nil)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{EmailProvider: "default"}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Notify(map[string]string{
+		"email":     "jsmith@localhost",
+		"username":  "jsmith",
+		"login_url": "https://localhost/magic-link?token=foo",
+		"lifetime":  "10m0s",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 1, len(files))
+	b, _ := ioutil.ReadFile(files[0])
+	if !strings.Contains(string(b), "Subject: Sign In Link") {
+		t.Fatalf("unexpected message: %s", b)
+	}
+
+	cfg = &Config{EmailProvider: "foo"}
+	cfg.SetMessaging(msgCfg)
+	err = cfg.ValidateMessaging()
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+}
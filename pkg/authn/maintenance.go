@@ -0,0 +1,96 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// getLoginMaintenance returns the maintenance switch disabling the logins
+// to the realm, or nil when the logins are enabled.
+func (p *Portal) getLoginMaintenance(realm string) *maintenance.Switch {
+	if p.maintenance == nil {
+		return nil
+	}
+	var provider string
+	if backend := p.getAuthenticatorByRealm(realm); backend != nil {
+		provider = backend["name"]
+	}
+	return p.maintenance.Check(realm, provider)
+}
+
+// getMaintenanceMessage returns the maintenance message of the switch for
+// the login to the realm.
+func (p *Portal) getMaintenanceMessage(entry *maintenance.Switch, realm string) string {
+	var provider string
+	if backend := p.getAuthenticatorByRealm(realm); backend != nil {
+		provider = backend["name"]
+	}
+	return p.maintenance.GetMessage(entry, realm, provider)
+}
+
+// handleHTTPMaintenance responds to the logins disabled by the maintenance
+// switch with 503 Service Unavailable page holding the maintenance message.
+func (p *Portal) handleHTTPMaintenance(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, realm string, entry *maintenance.Switch) error {
+	p.logMaintenance(w, r, rr, realm, entry)
+	p.disableClientCache(w)
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.PageTitle = p.maintenance.GetTitle()
+	resp.Data["message"] = p.getMaintenanceMessage(entry, realm)
+	resp.Data["authenticated"] = rr.Response.Authenticated
+	resp.Data["go_back_url"] = rr.Upstream.BasePath
+	content, err := p.ui.Render("generic", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusServiceUnavailable, content.Bytes())
+}
+
+// handleJSONMaintenance responds to the API logins disabled by the
+// maintenance switch with 503 Service Unavailable and the maintenance
+// message.
+func (p *Portal) handleJSONMaintenance(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, realm string, entry *maintenance.Switch) error {
+	p.logMaintenance(w, r, rr, realm, entry)
+	return p.handleJSONError(ctx, w, http.StatusServiceUnavailable, p.getMaintenanceMessage(entry, realm))
+}
+
+// logMaintenance logs the login disabled by the maintenance switch and sets
+// Retry-After header when the switch turns off by itself.
+func (p *Portal) logMaintenance(w http.ResponseWriter, r *http.Request, rr *requests.Request, realm string, entry *maintenance.Switch) {
+	rr.Response.Code = http.StatusServiceUnavailable
+	if !entry.Until.IsZero() {
+		if secs := int(time.Until(entry.Until).Seconds()) + 1; secs > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+		}
+	}
+	p.logger.Info(
+		"login disabled for maintenance",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", addrutil.GetSourceAddress(r)),
+		zap.String("realm", realm),
+		zap.String("maintenance_scope", entry.Scope),
+		zap.String("maintenance_name", entry.Name),
+	)
+}
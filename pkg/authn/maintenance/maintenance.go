@@ -0,0 +1,292 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// ScopeAll disables all logins.
+	ScopeAll = "all"
+	// ScopeRealm disables the logins to a realm.
+	ScopeRealm = "realm"
+	// ScopeProvider disables the logins with an identity store or provider
+	// of the name.
+	ScopeProvider = "provider"
+
+	defaultTitle   = "Maintenance"
+	defaultMessage = `Sign in{{ if .realm }} to {{ .realm }}{{ end }} is temporarily unavailable due to maintenance.` +
+		`{{ if .until }} Please try again after {{ .until }}.{{ else }} Please try again later.{{ end }}`
+	configAuthor = "config"
+)
+
+// Config holds the configuration of the login maintenance switches. The
+// switches disable the logins to all realms, to specific realms, or with
+// specific identity stores and providers, e.g. during an identity provider
+// migration. The sessions issued before are not affected.
+type Config struct {
+	// Enabled disables all logins.
+	Enabled bool `json:"enabled,omitempty" xml:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Realms are the realms with disabled logins.
+	Realms []string `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+	// Providers are the names of the identity stores and providers with
+	// disabled logins.
+	Providers []string `json:"providers,omitempty" xml:"providers,omitempty" yaml:"providers,omitempty"`
+	// Title is the title of the maintenance page.
+	Title string `json:"title,omitempty" xml:"title,omitempty" yaml:"title,omitempty"`
+	// Message is the text/template of the maintenance message. The template
+	// receives the realm, the provider, and the until values.
+	Message string `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Switch disables the logins within its scope.
+type Switch struct {
+	Scope string `json:"scope,omitempty" xml:"scope,omitempty" yaml:"scope,omitempty"`
+	// Name is the name of the realm or the provider. It is empty for the
+	// all scope.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Message overrides the message template of the config.
+	Message    string    `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	DisabledBy string    `json:"disabled_by,omitempty" xml:"disabled_by,omitempty" yaml:"disabled_by,omitempty"`
+	DisabledAt time.Time `json:"disabled_at,omitempty" xml:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
+	// Until is the time the switch turns off by itself. When zero, the
+	// switch stays on until removed.
+	Until time.Time `json:"until,omitempty" xml:"until,omitempty" yaml:"until,omitempty"`
+}
+
+// Manager holds the maintenance switches, changed at runtime via the
+// administrative API.
+type Manager struct {
+	mu       sync.RWMutex
+	title    string
+	tmpl     *template.Template
+	switches map[string]*Switch
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for _, realm := range cfg.Realms {
+		if strings.TrimSpace(realm) == "" {
+			return errors.ErrMaintenanceConfigNameEmpty.WithArgs(ScopeRealm)
+		}
+	}
+	for _, provider := range cfg.Providers {
+		if strings.TrimSpace(provider) == "" {
+			return errors.ErrMaintenanceConfigNameEmpty.WithArgs(ScopeProvider)
+		}
+	}
+	if cfg.Message != "" {
+		if _, err := parseMessage(cfg.Message); err != nil {
+			return errors.ErrMaintenanceConfigMessage.WithArgs(err)
+		}
+	}
+	return nil
+}
+
+// NewManager returns an instance of Manager with the switches of the
+// config turned on.
+func NewManager(cfg *Config) (*Manager, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		title:    cfg.Title,
+		switches: make(map[string]*Switch),
+	}
+	if m.title == "" {
+		m.title = defaultTitle
+	}
+	msg := cfg.Message
+	if msg == "" {
+		msg = defaultMessage
+	}
+	m.tmpl, _ = parseMessage(msg)
+
+	now := time.Now().UTC()
+	entries := []*Switch{}
+	if cfg.Enabled {
+		entries = append(entries, &Switch{Scope: ScopeAll})
+	}
+	for _, realm := range cfg.Realms {
+		entries = append(entries, &Switch{Scope: ScopeRealm, Name: realm})
+	}
+	for _, provider := range cfg.Providers {
+		entries = append(entries, &Switch{Scope: ScopeProvider, Name: provider})
+	}
+	for _, entry := range entries {
+		entry.DisabledBy = configAuthor
+		entry.DisabledAt = now
+		m.switches[entry.key()] = entry
+	}
+	return m, nil
+}
+
+// Disable turns the switch on, replacing the switch of the same scope and
+// name.
+func (m *Manager) Disable(entry *Switch) (*Switch, error) {
+	entry = &Switch{
+		Scope:      entry.Scope,
+		Name:       strings.TrimSpace(entry.Name),
+		Message:    entry.Message,
+		DisabledBy: entry.DisabledBy,
+		DisabledAt: time.Now().UTC(),
+		Until:      entry.Until.UTC(),
+	}
+	switch entry.Scope {
+	case ScopeAll:
+		entry.Name = ""
+	case ScopeRealm, ScopeProvider:
+		if entry.Name == "" {
+			return nil, errors.ErrMaintenanceSwitchName.WithArgs(entry.Scope)
+		}
+	default:
+		return nil, errors.ErrMaintenanceSwitchScope.WithArgs(entry.Scope)
+	}
+	if !entry.Until.IsZero() && !entry.Until.After(entry.DisabledAt) {
+		return nil, errors.ErrMaintenanceSwitchUntil.WithArgs(entry.Until.Format(time.RFC3339))
+	}
+	if entry.Message != "" {
+		if _, err := parseMessage(entry.Message); err != nil {
+			return nil, errors.ErrMaintenanceSwitchMessage.WithArgs(err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.switches[entry.key()] = entry
+	return entry, nil
+}
+
+// Enable turns the switch of the scope and name off.
+func (m *Manager) Enable(scope, name string) error {
+	key := (&Switch{Scope: scope, Name: name}).key()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.switches[key]; !exists {
+		return errors.ErrMaintenanceSwitchNotFound.WithArgs(key)
+	}
+	delete(m.switches, key)
+	return nil
+}
+
+// List returns the switches that are on, sorted by scope and name.
+func (m *Manager) List() []*Switch {
+	now := time.Now().UTC()
+	entries := []*Switch{}
+	m.mu.RLock()
+	for _, entry := range m.switches {
+		if entry.expired(now) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	m.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key() < entries[j].key()
+	})
+	return entries
+}
+
+// Check returns the switch disabling the logins to the realm with the
+// provider of the name, or nil when the logins are enabled. The all scope
+// takes precedence over the realm scope, and the realm scope over the
+// provider scope.
+func (m *Manager) Check(realm, provider string) *Switch {
+	now := time.Now().UTC()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range []string{ScopeAll, ScopeRealm + "/" + realm, ScopeProvider + "/" + provider} {
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		if entry, exists := m.switches[key]; exists && !entry.expired(now) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// GetTitle returns the title of the maintenance page.
+func (m *Manager) GetTitle() string {
+	return m.title
+}
+
+// GetMessage returns the maintenance message of the switch for the login
+// to the realm with the provider of the name.
+func (m *Manager) GetMessage(entry *Switch, realm, provider string) string {
+	tmpl := m.tmpl
+	if entry.Message != "" {
+		if t, err := parseMessage(entry.Message); err == nil {
+			tmpl = t
+		}
+	}
+	data := map[string]string{
+		"realm":    realm,
+		"provider": provider,
+		"until":    "",
+	}
+	if !entry.Until.IsZero() {
+		data["until"] = entry.Until.Format(time.RFC1123)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return entry.Message
+	}
+	return buf.String()
+}
+
+// Inherit carries over the switches turned on at runtime in the previous
+// instance of Manager, e.g. after the portal reconfiguration. The switches
+// of the previous config are dropped.
+func (m *Manager) Inherit(prev *Manager) {
+	if prev == nil || prev == m {
+		return
+	}
+	prev.mu.RLock()
+	defer prev.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, entry := range prev.switches {
+		if entry.DisabledBy == configAuthor {
+			continue
+		}
+		m.switches[key] = entry
+	}
+}
+
+func (s *Switch) key() string {
+	if s.Scope == ScopeAll {
+		return ScopeAll
+	}
+	return s.Scope + "/" + s.Name
+}
+
+func (s *Switch) expired(t time.Time) bool {
+	return !s.Until.IsZero() && !s.Until.After(t)
+}
+
+func parseMessage(s string) (*template.Template, error) {
+	return template.New("maintenance").Option("missingkey=zero").Parse(s)
+}
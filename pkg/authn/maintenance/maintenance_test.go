@@ -0,0 +1,117 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Realms: []string{"contoso"}, Message: "Sign in to {{ .realm }} is paused."},
+		},
+		{
+			name:      "config with empty realm",
+			config:    &Config{Realms: []string{" "}},
+			shouldErr: true,
+			err:       errors.ErrMaintenanceConfigNameEmpty.WithArgs("realm"),
+		},
+		{
+			name:      "config with empty provider",
+			config:    &Config{Providers: []string{""}},
+			shouldErr: true,
+			err:       errors.ErrMaintenanceConfigNameEmpty.WithArgs("provider"),
+		},
+		{
+			name:      "config with invalid message template",
+			config:    &Config{Message: "{{ .realm "},
+			shouldErr: true,
+			err:       errors.ErrMaintenanceConfigMessage.WithArgs("template: maintenance:1: unclosed action"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	m, err := NewManager(&Config{Realms: []string{"contoso"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry := m.Check("contoso", "azure")
+	if entry == nil {
+		t.Fatal("expected contoso realm switch")
+	}
+	tests.EvalObjects(t, "config switch", "config", entry.DisabledBy)
+	tests.EvalObjects(t, "message", "Sign in to contoso is temporarily unavailable due to maintenance. Please try again later.", m.GetMessage(entry, "contoso", "azure"))
+	tests.EvalObjects(t, "other realm", true, m.Check("local", "local_backend") == nil)
+
+	// The switch of the provider disables the logins with the provider only.
+	until := time.Now().Add(time.Hour)
+	if _, err := m.Disable(&Switch{Scope: ScopeProvider, Name: "local_backend", Message: "{{ .provider }} is migrating until {{ .until }}.", Until: until, DisabledBy: "admin@localhost"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry = m.Check("local", "local_backend")
+	if entry == nil {
+		t.Fatal("expected local_backend provider switch")
+	}
+	tests.EvalObjects(t, "provider message", "local_backend is migrating until "+until.UTC().Format(time.RFC1123)+".", m.GetMessage(entry, "local", "local_backend"))
+	tests.EvalObjects(t, "switch count", 2, len(m.List()))
+
+	// The all scope takes precedence over the other scopes.
+	if _, err := m.Disable(&Switch{Scope: ScopeAll, Name: "ignored"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "all scope", ScopeAll, m.Check("contoso", "azure").Scope)
+	if err := m.Enable(ScopeAll, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = m.Enable(ScopeAll, "")
+	tests.EvalErrWithLog(t, err, "enable", true, errors.ErrMaintenanceSwitchNotFound.WithArgs("all"), nil)
+
+	// The invalid switches are rejected.
+	_, err = m.Disable(&Switch{Scope: "group", Name: "admins"})
+	tests.EvalErrWithLog(t, err, "scope", true, errors.ErrMaintenanceSwitchScope.WithArgs("group"), nil)
+	_, err = m.Disable(&Switch{Scope: ScopeRealm})
+	tests.EvalErrWithLog(t, err, "name", true, errors.ErrMaintenanceSwitchName.WithArgs("realm"), nil)
+	past := time.Now().Add(-time.Minute).UTC()
+	_, err = m.Disable(&Switch{Scope: ScopeRealm, Name: "local", Until: past})
+	tests.EvalErrWithLog(t, err, "until", true, errors.ErrMaintenanceSwitchUntil.WithArgs(past.Format(time.RFC3339)), nil)
+
+	// The runtime switches survive the reconfiguration, unlike the
+	// switches of the previous config.
+	next, err := NewManager(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next.Inherit(m)
+	tests.EvalObjects(t, "inherited realm switch", true, next.Check("contoso", "azure") == nil)
+	tests.EvalObjects(t, "inherited provider switch", false, next.Check("local", "local_backend") == nil)
+}
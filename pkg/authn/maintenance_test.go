@@ -0,0 +1,92 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestLoginMaintenance(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		MaintenanceConfig: &maintenance.Config{
+			Realms:  []string{"backup"},
+			Message: "Sign in to {{ .realm }} is paused.",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name    string
+		realm   string
+		enable  bool
+		code    int
+		message string
+	}{
+		{
+			name:  "login to realm without maintenance",
+			realm: "local",
+			code:  http.StatusOK,
+		},
+		{
+			name:    "login to realm under maintenance",
+			realm:   "backup",
+			code:    http.StatusServiceUnavailable,
+			message: "Sign in to backup is paused.",
+		},
+		{
+			name:   "login to realm after maintenance",
+			realm:  "backup",
+			enable: true,
+			code:   http.StatusOK,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			if tc.enable {
+				if err := p.maintenance.Enable(maintenance.ScopeRealm, tc.realm); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			b, _ := json.Marshal(&AuthRequest{Username: tests.TestUser1, Password: tests.TestPwd1, Realm: tc.realm})
+			r := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 48)
+			if err := p.handleJSONLogin(context.Background(), w, r, rr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "code", tc.code, w.Code, msgs)
+			if tc.code != http.StatusOK {
+				resp := &AccessDeniedResponse{}
+				json.Unmarshal(w.Body.Bytes(), resp)
+				tests.EvalObjectsWithLog(t, "message", tc.message, resp.Message, msgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/netfilter"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// filterNetwork returns an error when the network filter denies the source
+// address of the request access to the portal. The discovery endpoints,
+// e.g. the public keys, remain available to the relying parties.
+func (p *Portal) filterNetwork(r *http.Request) error {
+	if p.netFilter == nil || strings.Contains(r.URL.Path, "/.well-known/") {
+		return nil
+	}
+	return p.netFilter.Check(addrutil.GetSourceAddress(r), time.Now())
+}
+
+// handleNetworkDenied responds to the requests denied by the network filter
+// with 403 Forbidden. When the filter is configured to tarpit, the response
+// is delayed. The requests from the banned source addresses may be served
+// by the honeypot instead.
+func (p *Portal) handleNetworkDenied(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, err error) error {
+	srcAddr := addrutil.GetSourceAddress(r)
+	action := p.netFilter.GetAction()
+	var username, realm string
+	if p.netFilter.IsBanned(srcAddr, time.Now()) {
+		username, realm = p.getSubmittedCredentials(w, r, rr)
+		action = p.netFilter.GetBanAction(realm)
+	}
+
+	p.netFilter.Tarpit(action, r.Context().Done())
+	if action == netfilter.ActionHoneypot {
+		return p.handleHoneypot(ctx, w, r, rr, username, realm)
+	}
+
+	rr.Response.Code = http.StatusForbidden
+	p.logger.Warn(
+		"request denied by network filter",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", srcAddr),
+		zap.Error(err),
+	)
+	p.recordUsage("network_filter/deny")
+	if isJSONRequest(r, rr) {
+		return p.handleJSONError(ctx, w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+	return p.handleHTTPError(ctx, w, r, rr, http.StatusForbidden)
+}
+
+// handleHoneypot responds to the request from a banned source address as if
+// the login succeeded. No session is created and no token is issued. The
+// submission of credentials is published as a security event.
+func (p *Portal) handleHoneypot(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, username, realm string) error {
+	p.disableClientCache(w)
+	rr.Response.Code = http.StatusOK
+	if r.Method == http.MethodPost {
+		rr.User.Username = username
+		rr.Upstream.Realm = realm
+		p.logger.Warn(
+			"Served honeypot response to banned source address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("source_address", addrutil.GetSourceAddress(r)),
+			zap.String("username", username),
+			zap.String("realm", realm),
+		)
+		p.publishEvent(events.HoneypotLogin, r, rr, nil, map[string]interface{}{
+			"path": r.URL.Path,
+		})
+		p.recordUsage("network_filter/honeypot")
+	}
+
+	switch {
+	case isJSONRequest(r, rr):
+		resp := &AuthResponse{
+			TokenName: "access_token",
+			Token:     util.GetRandomEncodedStringFromRange(96, 128),
+		}
+		respBytes, _ := json.Marshal(resp)
+		w.WriteHeader(rr.Response.Code)
+		w.Write(respBytes)
+		return nil
+	case r.Method == http.MethodPost:
+		return p.handleHTTPRedirect(ctx, w, r, rr, "/portal")
+	}
+
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.PageTitle = "Applications"
+	content, err := p.ui.Render("portal", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
+}
+
+// getSubmittedCredentials returns the username and the realm submitted with
+// the request, if any. When the realm was not submitted, it is the realm
+// requested by the client or the default realm.
+func (p *Portal) getSubmittedCredentials(w http.ResponseWriter, r *http.Request, rr *requests.Request) (string, string) {
+	var username, realm string
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, 1024)
+		if rr.Upstream.ContentType == "application/json" {
+			m := make(map[string]interface{})
+			if err := json.NewDecoder(r.Body).Decode(&m); err == nil {
+				username, _ = m["username"].(string)
+				realm, _ = m["realm"].(string)
+			}
+		} else {
+			username = r.PostFormValue("username")
+			realm = r.PostFormValue("realm")
+		}
+	}
+	if realm == "" {
+		realm = p.getRequestRealm(r, rr, nil)
+	}
+	return username, realm
+}
+
+// isJSONRequest returns true when the request is served with JSON responses.
+func isJSONRequest(r *http.Request, rr *requests.Request) bool {
+	switch {
+	case strings.Contains(r.URL.Path, "/api/"),
+		strings.Contains(r.URL.Path, "/oauth2/"),
+		strings.Contains(r.URL.RawQuery, "format=json"),
+		rr.Upstream.ContentType == "application/json":
+		return true
+	}
+	return false
+}
+
+// recordNetworkFailure records the failed login from the source address
+// and publishes the ban of the address, if any.
+func (p *Portal) recordNetworkFailure(r *http.Request, rr *requests.Request, srcAddr string) {
+	if p.netFilter == nil {
+		return
+	}
+	until, banned := p.netFilter.RecordFailure(srcAddr, time.Now())
+	if !banned {
+		return
+	}
+	p.logger.Warn(
+		"Banned source address after failed logins",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", srcAddr),
+		zap.Time("banned_until", until),
+	)
+	p.publishEvent(events.AddressBanned, r, rr, nil, map[string]interface{}{
+		"banned_until": until.UTC().Format(time.RFC3339),
+	})
+	p.recordUsage("network_filter/ban")
+}
@@ -0,0 +1,422 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// ActionDeny responds to the denied requests with 403 Forbidden.
+	ActionDeny = "deny"
+	// ActionTarpit delays the 403 Forbidden response to the denied
+	// requests, slowing down the automated clients.
+	ActionTarpit = "tarpit"
+	// ActionHoneypot responds to the requests from the banned source
+	// addresses with delayed responses resembling successful logins.
+	ActionHoneypot = "honeypot"
+
+	defaultTarpitDelay = 10
+	defaultBanWindow   = 300
+	defaultBanDuration = 900
+
+	// The maximum number of the responses delayed at once. The requests
+	// above the limit are denied right away.
+	maxTarpitted = 256
+	// The number of tracked source addresses above which the stale
+	// entries are pruned.
+	maxTrackedAddresses = 10000
+)
+
+// Group is a named list of networks.
+type Group struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// The networks in CIDR notation or the IP addresses.
+	Networks []string `json:"networks,omitempty" xml:"networks,omitempty" yaml:"networks,omitempty"`
+}
+
+// RealmConfig overrides the response to the banned source addresses for
+// an authentication realm.
+type RealmConfig struct {
+	Realm     string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	BanAction string `json:"ban_action,omitempty" xml:"ban_action,omitempty" yaml:"ban_action,omitempty"`
+}
+
+// Config holds the configuration of the network filter applied to the
+// requests to the portal prior to authentication.
+type Config struct {
+	// The networks in CIDR notation, the IP addresses, or the names of the
+	// groups allowed to access the portal. When empty, all source addresses
+	// are allowed, except the denied ones.
+	Allow []string `json:"allow,omitempty" xml:"allow,omitempty" yaml:"allow,omitempty"`
+	// The networks in CIDR notation, the IP addresses, or the names of the
+	// groups denied access to the portal. The deny list takes precedence
+	// over the allow list.
+	Deny []string `json:"deny,omitempty" xml:"deny,omitempty" yaml:"deny,omitempty"`
+	// The named groups of networks referenced in the allow and deny lists.
+	Groups []*Group `json:"groups,omitempty" xml:"groups,omitempty" yaml:"groups,omitempty"`
+	// The response to the denied requests, i.e. deny or tarpit. Defaults
+	// to deny.
+	Action string `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+	// The number of seconds the tarpit delays the response. Defaults to 10.
+	TarpitDelay int `json:"tarpit_delay,omitempty" xml:"tarpit_delay,omitempty" yaml:"tarpit_delay,omitempty"`
+	// The number of failed logins from a source address within the ban
+	// window triggering the temporary ban of the address. When zero, the
+	// addresses are not banned.
+	BanThreshold int `json:"ban_threshold,omitempty" xml:"ban_threshold,omitempty" yaml:"ban_threshold,omitempty"`
+	// The ban window in seconds. Defaults to 300.
+	BanWindow int `json:"ban_window,omitempty" xml:"ban_window,omitempty" yaml:"ban_window,omitempty"`
+	// The ban duration in seconds. Defaults to 900.
+	BanDuration int `json:"ban_duration,omitempty" xml:"ban_duration,omitempty" yaml:"ban_duration,omitempty"`
+	// The response to the requests from the banned source addresses, i.e.
+	// deny, tarpit or honeypot. Defaults to the action.
+	BanAction string `json:"ban_action,omitempty" xml:"ban_action,omitempty" yaml:"ban_action,omitempty"`
+	// The overrides of the ban action for the authentication realms.
+	Realms []*RealmConfig `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+}
+
+// Filter allows or denies the requests by their source address. It bans
+// the source addresses with too many failed logins.
+type Filter struct {
+	allow       []*net.IPNet
+	deny        []*net.IPNet
+	action      string
+	banActions  map[string]string
+	tarpitDelay time.Duration
+	tarpitted   int64
+	threshold   int
+	window      time.Duration
+	duration    time.Duration
+	mu          sync.Mutex
+	failures    map[string][]time.Time
+	bans        map[string]time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if _, _, err := cfg.parseNetworks(); err != nil {
+		return err
+	}
+	switch cfg.Action {
+	case "", ActionDeny, ActionTarpit:
+	default:
+		return errors.ErrNetworkFilterConfigAction.WithArgs(cfg.Action)
+	}
+	if cfg.TarpitDelay < 0 {
+		return errors.ErrNetworkFilterConfigTarpitDelay.WithArgs(cfg.TarpitDelay)
+	}
+	if cfg.BanThreshold < 0 {
+		return errors.ErrNetworkFilterConfigBanThreshold.WithArgs(cfg.BanThreshold)
+	}
+	if cfg.BanWindow < 0 {
+		return errors.ErrNetworkFilterConfigBanWindow.WithArgs(cfg.BanWindow)
+	}
+	if cfg.BanDuration < 0 {
+		return errors.ErrNetworkFilterConfigBanDuration.WithArgs(cfg.BanDuration)
+	}
+	if err := validateBanAction(cfg.BanAction); err != nil {
+		return err
+	}
+	realms := make(map[string]bool)
+	for _, rc := range cfg.Realms {
+		if rc.Realm == "" {
+			return errors.ErrNetworkFilterConfigRealmName
+		}
+		if realms[rc.Realm] {
+			return errors.ErrNetworkFilterConfigRealmDuplicate.WithArgs(rc.Realm)
+		}
+		realms[rc.Realm] = true
+		if err := validateBanAction(rc.BanAction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBanAction(s string) error {
+	switch s {
+	case "", ActionDeny, ActionTarpit, ActionHoneypot:
+	default:
+		return errors.ErrNetworkFilterConfigBanAction.WithArgs(s)
+	}
+	return nil
+}
+
+// parseNetworks returns the allowed and denied networks. The group names
+// are expanded to the networks of the groups.
+func (cfg *Config) parseNetworks() ([]*net.IPNet, []*net.IPNet, error) {
+	groups := make(map[string][]*net.IPNet)
+	for _, g := range cfg.Groups {
+		if g.Name == "" {
+			return nil, nil, errors.ErrNetworkFilterConfigGroupName
+		}
+		if _, exists := groups[g.Name]; exists {
+			return nil, nil, errors.ErrNetworkFilterConfigGroupDuplicate.WithArgs(g.Name)
+		}
+		if len(g.Networks) == 0 {
+			return nil, nil, errors.ErrNetworkFilterConfigGroupEmpty.WithArgs(g.Name)
+		}
+		var networks []*net.IPNet
+		for _, s := range g.Networks {
+			n, err := parseNetwork(s)
+			if err != nil {
+				return nil, nil, err
+			}
+			networks = append(networks, n)
+		}
+		groups[g.Name] = networks
+	}
+
+	expand := func(entries []string) ([]*net.IPNet, error) {
+		var networks []*net.IPNet
+		for _, s := range entries {
+			if g, exists := groups[s]; exists {
+				networks = append(networks, g...)
+				continue
+			}
+			n, err := parseNetwork(s)
+			if err != nil {
+				return nil, err
+			}
+			networks = append(networks, n)
+		}
+		return networks, nil
+	}
+	allow, err := expand(cfg.Allow)
+	if err != nil {
+		return nil, nil, err
+	}
+	deny, err := expand(cfg.Deny)
+	if err != nil {
+		return nil, nil, err
+	}
+	return allow, deny, nil
+}
+
+// parseNetwork returns the network in CIDR notation or the single address
+// network of the IP address.
+func parseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, errors.ErrNetworkFilterConfigNetwork.WithArgs(s)
+		}
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.ErrNetworkFilterConfigNetwork.WithArgs(s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// NewFilter returns an instance of Filter.
+func NewFilter(cfg *Config) (*Filter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	allow, deny, _ := cfg.parseNetworks()
+	f := &Filter{
+		allow:       allow,
+		deny:        deny,
+		action:      ActionDeny,
+		banActions:  make(map[string]string),
+		tarpitDelay: time.Duration(defaultTarpitDelay) * time.Second,
+		threshold:   cfg.BanThreshold,
+		window:      time.Duration(defaultBanWindow) * time.Second,
+		duration:    time.Duration(defaultBanDuration) * time.Second,
+		failures:    make(map[string][]time.Time),
+		bans:        make(map[string]time.Time),
+	}
+	if cfg.Action != "" {
+		f.action = cfg.Action
+	}
+	f.banActions[""] = f.action
+	if cfg.BanAction != "" {
+		f.banActions[""] = cfg.BanAction
+	}
+	for _, rc := range cfg.Realms {
+		if rc.BanAction != "" {
+			f.banActions[rc.Realm] = rc.BanAction
+		}
+	}
+	if cfg.TarpitDelay > 0 {
+		f.tarpitDelay = time.Duration(cfg.TarpitDelay) * time.Second
+	}
+	if cfg.BanWindow > 0 {
+		f.window = time.Duration(cfg.BanWindow) * time.Second
+	}
+	if cfg.BanDuration > 0 {
+		f.duration = time.Duration(cfg.BanDuration) * time.Second
+	}
+	return f, nil
+}
+
+// GetAction returns the response to the requests denied by the allow and
+// deny lists.
+func (f *Filter) GetAction() string {
+	return f.action
+}
+
+// GetBanAction returns the response to the requests from the banned source
+// addresses to the realm.
+func (f *Filter) GetBanAction(realm string) string {
+	if action, exists := f.banActions[realm]; exists {
+		return action
+	}
+	return f.banActions[""]
+}
+
+// IsBanned returns true when the source address is banned.
+func (f *Filter) IsBanned(addr string, now time.Time) bool {
+	ip := net.ParseIP(addr)
+	if f.threshold == 0 || ip == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	until, exists := f.bans[ip.String()]
+	return exists && now.Before(until)
+}
+
+// Check returns an error when the requests from the source address are
+// not allowed, i.e. the address is denied, not allowed, or banned.
+func (f *Filter) Check(addr string, now time.Time) error {
+	ip := net.ParseIP(addr)
+	if ip != nil && contains(f.deny, ip) {
+		return errors.ErrNetworkFilterDenied.WithArgs(addr)
+	}
+	if len(f.allow) > 0 && (ip == nil || !contains(f.allow, ip)) {
+		return errors.ErrNetworkFilterNotAllowed.WithArgs(addr)
+	}
+	if f.threshold == 0 || ip == nil {
+		return nil
+	}
+	key := ip.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	until, exists := f.bans[key]
+	if !exists {
+		return nil
+	}
+	if !now.Before(until) {
+		delete(f.bans, key)
+		return nil
+	}
+	return errors.ErrNetworkFilterBanned.WithArgs(addr, until.UTC().Format(time.RFC3339))
+}
+
+// RecordFailure records the failed login from the source address. It
+// returns the time the ban ends when the failure triggers the ban of the
+// address.
+func (f *Filter) RecordFailure(addr string, now time.Time) (time.Time, bool) {
+	ip := net.ParseIP(addr)
+	if f.threshold == 0 || ip == nil {
+		return time.Time{}, false
+	}
+	key := ip.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failures) > maxTrackedAddresses {
+		f.prune(now)
+	}
+	var entries []time.Time
+	for _, ts := range f.failures[key] {
+		if now.Sub(ts) < f.window {
+			entries = append(entries, ts)
+		}
+	}
+	entries = append(entries, now)
+	if len(entries) < f.threshold {
+		f.failures[key] = entries
+		return time.Time{}, false
+	}
+	delete(f.failures, key)
+	until := now.Add(f.duration)
+	f.bans[key] = until
+	return until, true
+}
+
+// prune removes the expired failures and bans.
+func (f *Filter) prune(now time.Time) {
+	for key, entries := range f.failures {
+		if now.Sub(entries[len(entries)-1]) >= f.window {
+			delete(f.failures, key)
+		}
+	}
+	for key, until := range f.bans {
+		if !now.Before(until) {
+			delete(f.bans, key)
+		}
+	}
+}
+
+// Inherit carries over the failed logins and the bans tracked by the
+// previous instance of Filter, e.g. after the portal reconfiguration.
+func (f *Filter) Inherit(prev *Filter) {
+	if prev == nil || prev == f {
+		return
+	}
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, entries := range prev.failures {
+		f.failures[key] = append([]time.Time(nil), entries...)
+	}
+	for key, until := range prev.bans {
+		f.bans[key] = until
+	}
+}
+
+// Tarpit delays the response to the denied request when the action is
+// tarpit or honeypot, unless the number of the delayed responses reached
+// the limit or the context is done. It returns false when the response was
+// not delayed.
+func (f *Filter) Tarpit(action string, done <-chan struct{}) bool {
+	if action != ActionTarpit && action != ActionHoneypot {
+		return false
+	}
+	if atomic.AddInt64(&f.tarpitted, 1) > maxTarpitted {
+		atomic.AddInt64(&f.tarpitted, -1)
+		return false
+	}
+	defer atomic.AddInt64(&f.tarpitted, -1)
+	timer := time.NewTimer(f.tarpitDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-done:
+	}
+	return true
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
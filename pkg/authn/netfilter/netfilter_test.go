@@ -0,0 +1,247 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Allow: []string{"office", "192.168.1.1"},
+				Deny:  []string{"10.1.0.0/16", "2001:db8::/32"},
+				Groups: []*Group{
+					{Name: "office", Networks: []string{"10.0.0.0/8"}},
+				},
+				Action:       "tarpit",
+				BanThreshold: 10,
+			},
+		},
+		{
+			name:      "invalid network",
+			config:    &Config{Deny: []string{"10.0.0.0/33"}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigNetwork.WithArgs("10.0.0.0/33"),
+		},
+		{
+			name:      "undefined group",
+			config:    &Config{Allow: []string{"office"}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigNetwork.WithArgs("office"),
+		},
+		{
+			name:      "group without name",
+			config:    &Config{Groups: []*Group{{Networks: []string{"10.0.0.0/8"}}}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigGroupName,
+		},
+		{
+			name: "duplicate group",
+			config: &Config{Groups: []*Group{
+				{Name: "office", Networks: []string{"10.0.0.0/8"}},
+				{Name: "office", Networks: []string{"10.0.0.0/8"}},
+			}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigGroupDuplicate.WithArgs("office"),
+		},
+		{
+			name:      "group without networks",
+			config:    &Config{Groups: []*Group{{Name: "office"}}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigGroupEmpty.WithArgs("office"),
+		},
+		{
+			name:      "unsupported action",
+			config:    &Config{Action: "drop"},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigAction.WithArgs("drop"),
+		},
+		{
+			name:      "unsupported ban action",
+			config:    &Config{BanAction: "drop"},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigBanAction.WithArgs("drop"),
+		},
+		{
+			name:      "honeypot action for allow and deny lists",
+			config:    &Config{Action: "honeypot"},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigAction.WithArgs("honeypot"),
+		},
+		{
+			name: "duplicate realm",
+			config: &Config{Realms: []*RealmConfig{
+				{Realm: "local", BanAction: "honeypot"},
+				{Realm: "local", BanAction: "deny"},
+			}},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigRealmDuplicate.WithArgs("local"),
+		},
+		{
+			name:      "negative ban threshold",
+			config:    &Config{BanThreshold: -1},
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterConfigBanThreshold.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "network filter config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f, err := NewFilter(&Config{
+		Allow: []string{"office", "192.168.1.1", "2001:db8::/32"},
+		Deny:  []string{"10.1.0.0/16"},
+		Groups: []*Group{
+			{Name: "office", Networks: []string{"10.0.0.0/8"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		addr      string
+		shouldErr bool
+		err       error
+	}{
+		{name: "address in allowed group", addr: "10.2.0.1"},
+		{name: "allowed address", addr: "192.168.1.1"},
+		{name: "allowed ipv6 address", addr: "2001:db8::1"},
+		{
+			name:      "denied address in allowed group",
+			addr:      "10.1.0.1",
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterDenied.WithArgs("10.1.0.1"),
+		},
+		{
+			name:      "address not allowed",
+			addr:      "192.168.1.2",
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterNotAllowed.WithArgs("192.168.1.2"),
+		},
+		{
+			name:      "malformed address",
+			addr:      "foobar",
+			shouldErr: true,
+			err:       errors.ErrNetworkFilterNotAllowed.WithArgs("foobar"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := f.Check(tc.addr, time.Now())
+			tests.EvalErrWithLog(t, err, "check", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestBan(t *testing.T) {
+	f, err := NewFilter(&Config{BanThreshold: 3, BanWindow: 60, BanDuration: 600})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := "192.168.1.1"
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The failures outside of the window are not counted.
+	f.RecordFailure(addr, now)
+	f.RecordFailure(addr, now.Add(61*time.Second))
+	if _, banned := f.RecordFailure(addr, now.Add(62*time.Second)); banned {
+		t.Fatalf("unexpected ban with failures outside of ban window")
+	}
+
+	until, banned := f.RecordFailure(addr, now.Add(63*time.Second))
+	tests.EvalObjects(t, "banned", true, banned)
+	tests.EvalObjects(t, "banned until", now.Add(663*time.Second), until)
+
+	err = f.Check(addr, now.Add(64*time.Second))
+	tests.EvalErr(t, err, "banned address", true, errors.ErrNetworkFilterBanned.WithArgs(addr, "2022-01-01T00:11:03Z"))
+	if err := f.Check("192.168.1.2", now.Add(64*time.Second)); err != nil {
+		t.Fatalf("unexpected error for other address: %v", err)
+	}
+
+	// The bans carry over to the new instance of the filter.
+	next, err := NewFilter(&Config{BanThreshold: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next.Inherit(f)
+	err = next.Check(addr, now.Add(65*time.Second))
+	tests.EvalErr(t, err, "inherited ban", true, errors.ErrNetworkFilterBanned.WithArgs(addr, "2022-01-01T00:11:03Z"))
+
+	// The ban expires.
+	if err := next.Check(addr, until); err != nil {
+		t.Fatalf("unexpected error after ban expired: %v", err)
+	}
+}
+
+func TestTarpit(t *testing.T) {
+	f, err := NewFilter(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "deny action delays", false, f.Tarpit(f.GetAction(), nil))
+
+	f, err = NewFilter(&Config{Action: "tarpit", TarpitDelay: 60})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := make(chan struct{})
+	close(done)
+	tests.EvalObjects(t, "tarpit action delays", true, f.Tarpit(f.GetAction(), done))
+	tests.EvalObjects(t, "honeypot action delays", true, f.Tarpit(ActionHoneypot, done))
+}
+
+func TestGetBanAction(t *testing.T) {
+	f, err := NewFilter(&Config{
+		Action:       "tarpit",
+		BanThreshold: 1,
+		Realms: []*RealmConfig{
+			{Realm: "local", BanAction: "honeypot"},
+			{Realm: "contoso"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "realm ban action", "honeypot", f.GetBanAction("local"))
+	tests.EvalObjects(t, "realm without ban action", "tarpit", f.GetBanAction("contoso"))
+	tests.EvalObjects(t, "default ban action", "tarpit", f.GetBanAction("example"))
+
+	now := time.Now()
+	tests.EvalObjects(t, "banned before failure", false, f.IsBanned("10.0.0.1", now))
+	f.RecordFailure("10.0.0.1", now)
+	tests.EvalObjects(t, "banned after failure", true, f.IsBanned("10.0.0.1", now))
+}
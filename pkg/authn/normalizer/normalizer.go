@@ -0,0 +1,162 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalizer
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config holds the configuration of the normalization of the user
+// identities arriving from the identity stores and providers, so that the
+// same user resolves to the same identity and roles regardless of the
+// realm.
+type Config struct {
+	// Trim removes the leading and trailing whitespace from the usernames
+	// and the email addresses.
+	Trim bool `json:"trim,omitempty" xml:"trim,omitempty" yaml:"trim,omitempty"`
+	// CaseFold converts the usernames and the email addresses to lower
+	// case, e.g. user@CORP.COM to user@corp.com.
+	CaseFold bool `json:"case_fold,omitempty" xml:"case_fold,omitempty" yaml:"case_fold,omitempty"`
+	// CoerceUPN sets the email address of the user without one to the user
+	// principal name, i.e. the upn claim or the username in the form of
+	// user@domain.
+	CoerceUPN bool `json:"coerce_upn,omitempty" xml:"coerce_upn,omitempty" yaml:"coerce_upn,omitempty"`
+	// StripDomains is the list of the domains removed from the usernames,
+	// e.g. CORP\user and user@corp.com become user. The "*" entry strips
+	// any domain. The email addresses are unaffected.
+	StripDomains []string `json:"strip_domains,omitempty" xml:"strip_domains,omitempty" yaml:"strip_domains,omitempty"`
+	// Aliases maps the usernames and the email addresses to the canonical
+	// ones. The aliases are matched after the other rules apply.
+	Aliases map[string]string `json:"aliases,omitempty" xml:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// Normalizer normalizes the usernames and the email addresses in the
+// claims of the users.
+type Normalizer struct {
+	trim      bool
+	caseFold  bool
+	coerceUPN bool
+	anyDomain bool
+	domains   map[string]bool
+	aliases   map[string]string
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for _, domain := range cfg.StripDomains {
+		if strings.TrimSpace(domain) == "" {
+			return errors.ErrNormalizerConfigDomainEmpty
+		}
+	}
+	for k, v := range cfg.Aliases {
+		if v == "" {
+			return errors.ErrNormalizerConfigAliasEmpty.WithArgs(k)
+		}
+		if _, exists := cfg.Aliases[v]; exists && v != k {
+			return errors.ErrNormalizerConfigAliasChain.WithArgs(k, v)
+		}
+	}
+	return nil
+}
+
+// NewNormalizer returns an instance of Normalizer.
+func NewNormalizer(cfg *Config) (*Normalizer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	n := &Normalizer{
+		trim:      cfg.Trim,
+		caseFold:  cfg.CaseFold,
+		coerceUPN: cfg.CoerceUPN,
+		domains:   make(map[string]bool),
+		aliases:   make(map[string]string),
+	}
+	for _, domain := range cfg.StripDomains {
+		if domain == "*" {
+			n.anyDomain = true
+			continue
+		}
+		n.domains[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	for k, v := range cfg.Aliases {
+		n.aliases[n.normalize(k)] = n.normalize(v)
+	}
+	return n, nil
+}
+
+// Normalize normalizes the username, i.e. the sub claim, and the email
+// address, i.e. the email claim, of a user.
+func (n *Normalizer) Normalize(m map[string]interface{}) {
+	sub, _ := m["sub"].(string)
+	email, _ := m["email"].(string)
+	sub = n.normalize(sub)
+	email = n.normalize(email)
+
+	if n.coerceUPN && email == "" {
+		if upn, ok := m["upn"].(string); ok && strings.Contains(upn, "@") {
+			email = n.normalize(upn)
+		} else if strings.Contains(sub, "@") {
+			email = sub
+		}
+	}
+
+	sub = n.stripDomain(sub)
+
+	if v, exists := n.aliases[sub]; exists {
+		sub = v
+	}
+	if v, exists := n.aliases[email]; exists {
+		email = v
+	}
+
+	if sub != "" {
+		m["sub"] = sub
+	}
+	if email != "" {
+		m["email"] = email
+	}
+}
+
+func (n *Normalizer) normalize(s string) string {
+	if n.trim {
+		s = strings.TrimSpace(s)
+	}
+	if n.caseFold {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+// stripDomain removes the domain from the username in the form of
+// DOMAIN\user or user@domain.
+func (n *Normalizer) stripDomain(s string) string {
+	if !n.anyDomain && len(n.domains) == 0 {
+		return s
+	}
+	if i := strings.Index(s, `\`); i > 0 {
+		if n.anyDomain || n.domains[strings.ToLower(s[:i])] {
+			return s[i+1:]
+		}
+		return s
+	}
+	if i := strings.LastIndex(s, "@"); i > 0 {
+		if n.anyDomain || n.domains[strings.ToLower(s[i+1:])] {
+			return s[:i]
+		}
+	}
+	return s
+}
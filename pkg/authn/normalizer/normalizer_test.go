@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalizer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewNormalizer(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				CaseFold:     true,
+				StripDomains: []string{"corp.com"},
+				Aliases:      map[string]string{"john.smith": "jsmith"},
+			},
+		},
+		{
+			name:      "empty strip domain",
+			config:    &Config{StripDomains: []string{" "}},
+			shouldErr: true,
+			err:       errors.ErrNormalizerConfigDomainEmpty,
+		},
+		{
+			name:      "empty alias",
+			config:    &Config{Aliases: map[string]string{"john.smith": ""}},
+			shouldErr: true,
+			err:       errors.ErrNormalizerConfigAliasEmpty.WithArgs("john.smith"),
+		},
+		{
+			name:      "alias resolving to another alias",
+			config:    &Config{Aliases: map[string]string{"smithj": "john.smith", "john.smith": "jsmith"}},
+			shouldErr: true,
+			err:       errors.ErrNormalizerConfigAliasChain.WithArgs("smithj", "john.smith"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewNormalizer(tc.config)
+			tests.EvalErrWithLog(t, err, "NewNormalizer", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testcases := []struct {
+		name   string
+		config *Config
+		input  map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "no rules",
+			config: &Config{},
+			input:  map[string]interface{}{"sub": " User@CORP.COM ", "email": "User@CORP.COM"},
+			want:   map[string]interface{}{"sub": " User@CORP.COM ", "email": "User@CORP.COM"},
+		},
+		{
+			name:   "trim and case fold",
+			config: &Config{Trim: true, CaseFold: true},
+			input:  map[string]interface{}{"sub": " User@CORP.COM ", "email": "User@CORP.COM"},
+			want:   map[string]interface{}{"sub": "user@corp.com", "email": "user@corp.com"},
+		},
+		{
+			name:   "coerce upn claim to email",
+			config: &Config{CaseFold: true, CoerceUPN: true},
+			input:  map[string]interface{}{"sub": "S-1-5-21-1004", "upn": "User@CORP.COM"},
+			want:   map[string]interface{}{"sub": "s-1-5-21-1004", "upn": "User@CORP.COM", "email": "user@corp.com"},
+		},
+		{
+			name:   "coerce username to email",
+			config: &Config{CaseFold: true, CoerceUPN: true},
+			input:  map[string]interface{}{"sub": "User@CORP.COM"},
+			want:   map[string]interface{}{"sub": "user@corp.com", "email": "user@corp.com"},
+		},
+		{
+			name:   "keep existing email",
+			config: &Config{CoerceUPN: true},
+			input:  map[string]interface{}{"sub": "user@corp.com", "email": "user@contoso.com"},
+			want:   map[string]interface{}{"sub": "user@corp.com", "email": "user@contoso.com"},
+		},
+		{
+			name:   "strip domain from upn",
+			config: &Config{CaseFold: true, CoerceUPN: true, StripDomains: []string{"CORP.COM"}},
+			input:  map[string]interface{}{"sub": "User@CORP.COM"},
+			want:   map[string]interface{}{"sub": "user", "email": "user@corp.com"},
+		},
+		{
+			name:   "strip domain from down-level logon name",
+			config: &Config{StripDomains: []string{"corp"}},
+			input:  map[string]interface{}{"sub": `CORP\user`, "email": "user@corp.com"},
+			want:   map[string]interface{}{"sub": "user", "email": "user@corp.com"},
+		},
+		{
+			name:   "keep unlisted domain",
+			config: &Config{StripDomains: []string{"corp.com"}},
+			input:  map[string]interface{}{"sub": "user@contoso.com"},
+			want:   map[string]interface{}{"sub": "user@contoso.com"},
+		},
+		{
+			name:   "strip any domain",
+			config: &Config{StripDomains: []string{"*"}},
+			input:  map[string]interface{}{"sub": "user@contoso.com"},
+			want:   map[string]interface{}{"sub": "user"},
+		},
+		{
+			name: "resolve aliases",
+			config: &Config{
+				CaseFold: true,
+				Aliases: map[string]string{
+					"John.Smith":           "jsmith",
+					"john.smith@corp.com":  "jsmith@corp.com",
+					"smithj@contoso.com":   "jsmith@corp.com",
+					"unrelated@corp.com":   "foo@corp.com",
+					"jsmith@contoso.com":   "jsmith@corp.com",
+					"john.smith@gmail.com": "jsmith@corp.com",
+				},
+			},
+			input: map[string]interface{}{"sub": "John.Smith", "email": "John.Smith@CORP.COM"},
+			want:  map[string]interface{}{"sub": "jsmith", "email": "jsmith@corp.com"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			n, err := NewNormalizer(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			n.Normalize(tc.input)
+			tests.EvalObjectsWithLog(t, "Normalize", tc.want, tc.input, msgs)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"mime/quotedprintable"
+	"strings"
+	"text/template"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+// Notifier renders the messaging email templates and delivers them via
+// the email providers. The configurations sending email notifications
+// embed it, and the messaging and credentials are bound to it when the
+// configuration is validated.
+type Notifier struct {
+	credentials *credentials.Config
+	messaging   *messaging.Config
+}
+
+// SetCredentials binds to shared credentials.
+func (n *Notifier) SetCredentials(c *credentials.Config) {
+	n.credentials = c
+}
+
+// SetMessaging binds to messaging config.
+func (n *Notifier) SetMessaging(c *messaging.Config) {
+	n.messaging = c
+}
+
+// ValidateProvider validates the messaging provider and the credentials
+// used for the delivery of email notifications.
+func (n *Notifier) ValidateProvider(name string) error {
+	if n.messaging == nil {
+		return errors.ErrNotifyMessagingNil
+	}
+	if found := n.messaging.FindProvider(name); !found {
+		return errors.ErrNotifyProvider.WithArgs(name)
+	}
+	if n.messaging.GetProviderType(name) != "email" {
+		return nil
+	}
+	providerCreds := n.messaging.FindProviderCredentials(name)
+	if providerCreds == "" {
+		return errors.ErrNotifyProviderCreds.WithArgs(name)
+	}
+	if providerCreds == "passwordless" {
+		return nil
+	}
+	if n.credentials == nil {
+		return errors.ErrNotifyCredentialsNil
+	}
+	if found := n.credentials.FindCredential(providerCreds); !found {
+		return errors.ErrNotifyCredNotFound.WithArgs(providerCreds)
+	}
+	return nil
+}
+
+// Send renders the email template with data and delivers it to the
+// recipients via the messaging provider.
+func (n *Notifier) Send(name, templateName string, rcpts []string, data map[string]string) error {
+	if n.messaging == nil {
+		return errors.ErrNotifyMessagingNil
+	}
+
+	subj, err := render(templateName, messaging.EmailTemplateSubject[templateName], data)
+	if err != nil {
+		return errors.ErrNotifySend.WithArgs(templateName, name, err)
+	}
+	subj = strings.TrimSpace(strings.NewReplacer("\r", "", "\n", " ").Replace(subj))
+
+	body, err := render(templateName, messaging.EmailTemplateBody[templateName], data)
+	if err != nil {
+		return errors.ErrNotifySend.WithArgs(templateName, name, err)
+	}
+	var b bytes.Buffer
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return errors.ErrNotifySend.WithArgs(templateName, name, err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.ErrNotifySend.WithArgs(templateName, name, err)
+	}
+
+	switch n.messaging.GetProviderType(name) {
+	case "email":
+		provider := n.messaging.ExtractEmailProvider(name)
+		if provider == nil {
+			return errors.ErrNotifyProvider.WithArgs(name)
+		}
+		var providerCred *credentials.Generic
+		providerCredName := n.messaging.FindProviderCredentials(name)
+		if providerCredName != "passwordless" {
+			if n.credentials == nil {
+				return errors.ErrNotifyCredentialsNil
+			}
+			providerCred = n.credentials.ExtractGeneric(providerCredName)
+			if providerCred == nil {
+				return errors.ErrNotifyCredNotFound.WithArgs(providerCredName)
+			}
+		}
+		err = provider.Send(&messaging.EmailProviderSendInput{
+			Subject:     subj,
+			Body:        b.String(),
+			Recipients:  rcpts,
+			Credentials: providerCred,
+		})
+	case "file":
+		provider := n.messaging.ExtractFileProvider(name)
+		if provider == nil {
+			return errors.ErrNotifyProvider.WithArgs(name)
+		}
+		err = provider.Send(&messaging.FileProviderSendInput{
+			Subject:    subj,
+			Body:       b.String(),
+			Recipients: rcpts,
+		})
+	default:
+		return errors.ErrNotifyProvider.WithArgs(name)
+	}
+	if err != nil {
+		return errors.ErrNotifySend.WithArgs(templateName, name, err)
+	}
+	return nil
+}
+
+func render(name, s string, data map[string]string) (string, error) {
+	tmpl, err := template.New(name).Parse(s)
+	if err != nil {
+		return "", err
+	}
+	b := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
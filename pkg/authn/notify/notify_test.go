@@ -0,0 +1,150 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func TestValidateProvider(t *testing.T) {
+	msgCfg := &messaging.Config{}
+	for _, p := range []messaging.Provider{
+		&messaging.FileProvider{Name: "file", RootDir: t.TempDir()},
+		&messaging.EmailProvider{
+			Name:        "email",
+			Address:     "localhost",
+			Protocol:    "smtp",
+			Credentials: "email_creds",
+			SenderEmail: "root@localhost",
+		},
+		&messaging.EmailProvider{
+			Name:         "passwordless",
+			Address:      "localhost",
+			Protocol:     "smtp",
+			Passwordless: true,
+			SenderEmail:  "root@localhost",
+		},
+	} {
+		if err := msgCfg.Add(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	credCfg := &credentials.Config{}
+	if err := credCfg.Add(&credentials.Generic{Name: "email_creds", Username: "jsmith", Password: "foobar"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name        string
+		provider    string
+		messaging   *messaging.Config
+		credentials *credentials.Config
+		shouldErr   bool
+		err         error
+	}{
+		{
+			name:      "file provider",
+			provider:  "file",
+			messaging: msgCfg,
+		},
+		{
+			name:      "passwordless email provider",
+			provider:  "passwordless",
+			messaging: msgCfg,
+		},
+		{
+			name:        "email provider with credentials",
+			provider:    "email",
+			messaging:   msgCfg,
+			credentials: credCfg,
+		},
+		{
+			name:      "messaging is not configured",
+			provider:  "file",
+			shouldErr: true,
+			err:       errors.ErrNotifyMessagingNil,
+		},
+		{
+			name:      "unknown provider",
+			provider:  "foo",
+			messaging: msgCfg,
+			shouldErr: true,
+			err:       errors.ErrNotifyProvider.WithArgs("foo"),
+		},
+		{
+			name:      "credentials are not configured",
+			provider:  "email",
+			messaging: msgCfg,
+			shouldErr: true,
+			err:       errors.ErrNotifyCredentialsNil,
+		},
+		{
+			name:        "credential not found",
+			provider:    "email",
+			messaging:   msgCfg,
+			credentials: &credentials.Config{},
+			shouldErr:   true,
+			err:         errors.ErrNotifyCredNotFound.WithArgs("email_creds"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &Notifier{}
+			n.SetMessaging(tc.messaging)
+			n.SetCredentials(tc.credentials)
+			err := n.ValidateProvider(tc.provider)
+			tests.EvalErrWithLog(t, err, "validate provider", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := &Notifier{}
+	err := n.Send("default", "en/password_recovery", []string{"jsmith@localhost"}, nil)
+	tests.EvalErrWithLog(t, err, "messaging is not configured", true, errors.ErrNotifyMessagingNil, nil)
+
+	n.SetMessaging(msgCfg)
+	err = n.Send("foo", "en/password_recovery", []string{"jsmith@localhost"}, nil)
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+
+	if err := n.Send("default", "en/password_recovery", []string{"jsmith@localhost", "bjones@localhost"}, map[string]string{
+		"username":     "jsmith",
+		"recovery_url": "https://localhost/recover?token=foo",
+		"lifetime":     "15m0s",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 1, len(files))
+	b, _ := ioutil.ReadFile(files[0])
+	for _, s := range []string{"Subject: Password Reset Request", "jsmith@localhost", "bjones@localhost"} {
+		if !strings.Contains(string(b), s) {
+			t.Fatalf("message has no %q: %s", s, b)
+		}
+	}
+}
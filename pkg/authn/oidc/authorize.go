@@ -0,0 +1,244 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// AuthorizationRequest is a validated authorization request of a relying
+// party.
+type AuthorizationRequest struct {
+	ID                  string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	ClientID            string    `json:"client_id,omitempty" xml:"client_id,omitempty" yaml:"client_id,omitempty"`
+	RedirectURI         string    `json:"redirect_uri,omitempty" xml:"redirect_uri,omitempty" yaml:"redirect_uri,omitempty"`
+	RedirectURIProvided bool      `json:"redirect_uri_provided,omitempty" xml:"redirect_uri_provided,omitempty" yaml:"redirect_uri_provided,omitempty"`
+	Scopes              []string  `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	State               string    `json:"state,omitempty" xml:"state,omitempty" yaml:"state,omitempty"`
+	Nonce               string    `json:"nonce,omitempty" xml:"nonce,omitempty" yaml:"nonce,omitempty"`
+	Prompt              string    `json:"prompt,omitempty" xml:"prompt,omitempty" yaml:"prompt,omitempty"`
+	CodeChallenge       string    `json:"code_challenge,omitempty" xml:"code_challenge,omitempty" yaml:"code_challenge,omitempty"`
+	CodeChallengeMethod string    `json:"code_challenge_method,omitempty" xml:"code_challenge_method,omitempty" yaml:"code_challenge_method,omitempty"`
+	Subject             string    `json:"subject,omitempty" xml:"subject,omitempty" yaml:"subject,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// Grant is the result of the exchange of an authorization code.
+type Grant struct {
+	ClientID string                 `json:"client_id,omitempty" xml:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Scopes   []string               `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Nonce    string                 `json:"nonce,omitempty" xml:"nonce,omitempty" yaml:"nonce,omitempty"`
+	AuthTime int64                  `json:"auth_time,omitempty" xml:"auth_time,omitempty" yaml:"auth_time,omitempty"`
+	Claims   map[string]interface{} `json:"claims,omitempty" xml:"claims,omitempty" yaml:"claims,omitempty"`
+}
+
+type authorizationCode struct {
+	request   *AuthorizationRequest
+	grant     *Grant
+	expiresAt time.Time
+}
+
+// ParseAuthorizationRequest validates the parameters of an authorization
+// request. When the client or the redirect URI is invalid, the request is
+// nil and the user must not be redirected. Otherwise, the returned error is
+// reported to the client via the redirect URI.
+func (p *Provider) ParseAuthorizationRequest(q url.Values) (*AuthorizationRequest, error) {
+	clientID := q.Get("client_id")
+	entry, exists := p.getClient(clientID)
+	if !exists {
+		return nil, errors.ErrOIDCClientNotFound.WithArgs(clientID)
+	}
+	redirectURI := q.Get("redirect_uri")
+	switch {
+	case redirectURI == "" && len(entry.config.RedirectURIs) == 1:
+		redirectURI = entry.config.RedirectURIs[0]
+	case !contains(entry.config.RedirectURIs, redirectURI):
+		return nil, errors.ErrOIDCRedirectURIMismatch.WithArgs(redirectURI, clientID)
+	}
+
+	req := &AuthorizationRequest{
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		RedirectURIProvided: q.Get("redirect_uri") != "",
+		State:               q.Get("state"),
+		Nonce:               q.Get("nonce"),
+		Prompt:              q.Get("prompt"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+	if q.Get("response_type") != "code" {
+		return req, errors.ErrOIDCResponseTypeUnsupported
+	}
+
+	scopes := strings.Fields(q.Get("scope"))
+	if !contains(scopes, "openid") {
+		return req, errors.ErrOIDCScopeInvalid
+	}
+	for _, scope := range scopes {
+		if !entry.scopes[scope] {
+			return req, errors.ErrOIDCScopeInvalid
+		}
+		if !contains(req.Scopes, scope) {
+			req.Scopes = append(req.Scopes, scope)
+		}
+	}
+
+	switch {
+	case req.CodeChallenge == "" && entry.config.Public:
+		return req, errors.ErrOIDCCodeChallengeRequired
+	case req.CodeChallenge == "":
+	case req.CodeChallengeMethod == "":
+		req.CodeChallengeMethod = "plain"
+	case req.CodeChallengeMethod != "S256" && req.CodeChallengeMethod != "plain":
+		return req, errors.ErrOIDCCodeChallengeMethod
+	}
+	return req, nil
+}
+
+// Hold keeps an authorization request of a user pending consent and returns
+// the identifier of the request.
+func (p *Provider) Hold(req *AuthorizationRequest, subject string) (string, error) {
+	id, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	req.ID = id
+	req.Subject = subject
+	req.ExpiresAt = time.Now().Add(p.consentLifetime).UTC()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune()
+	p.pending[id] = req
+	return id, nil
+}
+
+// Release removes the authorization request of a user pending consent and
+// returns it.
+func (p *Provider) Release(id, subject string) (*AuthorizationRequest, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req, exists := p.pending[id]
+	if !exists || req.Subject != subject {
+		return nil, errors.ErrOIDCAuthorizationNotFound
+	}
+	delete(p.pending, id)
+	if time.Now().After(req.ExpiresAt) {
+		return nil, errors.ErrOIDCAuthorizationNotFound
+	}
+	return req, nil
+}
+
+// IssueCode issues a single-use authorization code for the authorization
+// request of a user with the provided claims. The claims are transformed
+// for the client, when the client has the claim transform.
+func (p *Provider) IssueCode(req *AuthorizationRequest, claims map[string]interface{}, authTime int64) (string, error) {
+	code, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	if entry, exists := p.getClient(req.ClientID); exists && entry.config.ClaimTransform != nil {
+		claims = entry.config.ClaimTransform.Apply(claims)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune()
+	p.codes[code] = &authorizationCode{
+		request: req,
+		grant: &Grant{
+			ClientID: req.ClientID,
+			Scopes:   req.Scopes,
+			Nonce:    req.Nonce,
+			AuthTime: authTime,
+			Claims:   claims,
+		},
+		expiresAt: time.Now().Add(p.codeLifetime).UTC(),
+	}
+	return code, nil
+}
+
+// Exchange redeems an authorization code. The client must authenticate
+// with its secret, and present the PKCE code verifier of the authorization
+// request. The redirect URI must be present when the authorization request
+// included it, see RFC 6749, Section 4.1.3.
+func (p *Provider) Exchange(code, clientID, secret, redirectURI, verifier string) (*Grant, error) {
+	if _, err := p.authenticateClient(clientID, secret); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	ac, exists := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !exists || time.Now().After(ac.expiresAt) {
+		return nil, errors.ErrOIDCCodeInvalid
+	}
+	req := ac.request
+	if req.ClientID != clientID {
+		return nil, errors.ErrOIDCCodeInvalid
+	}
+	if redirectURI != req.RedirectURI && (req.RedirectURIProvided || redirectURI != "") {
+		return nil, errors.ErrOIDCCodeInvalid
+	}
+	if req.CodeChallenge != "" || verifier != "" {
+		if !verifyCodeChallenge(req.CodeChallenge, req.CodeChallengeMethod, verifier) {
+			return nil, errors.ErrOIDCCodeVerifierMismatch
+		}
+	}
+	return ac.grant, nil
+}
+
+func (p *Provider) prune() {
+	now := time.Now()
+	for k, req := range p.pending {
+		if now.After(req.ExpiresAt) {
+			delete(p.pending, k)
+		}
+	}
+	for k, ac := range p.codes {
+		if now.After(ac.expiresAt) {
+			delete(p.codes, k)
+		}
+	}
+}
+
+func verifyCodeChallenge(challenge, method, verifier string) bool {
+	if challenge == "" || verifier == "" {
+		return false
+	}
+	var computed string
+	switch method {
+	case "S256":
+		h := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(h[:])
+	default:
+		computed = verifier
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
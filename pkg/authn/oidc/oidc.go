@@ -0,0 +1,300 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultCodeLifetime    = 60
+	defaultConsentLifetime = 600
+)
+
+var defaultScopes = []string{"openid", "profile", "email", "groups"}
+
+// Config holds the configuration of the OpenID Connect provider.
+type Config struct {
+	// Issuer is the issuer identifier of the provider. When it is empty,
+	// the issuer is derived from the URL of the portal.
+	Issuer string `json:"issuer,omitempty" xml:"issuer,omitempty" yaml:"issuer,omitempty"`
+	// CodeLifetime is the lifetime of authorization codes in seconds.
+	// Defaults to 60 seconds.
+	CodeLifetime int `json:"code_lifetime,omitempty" xml:"code_lifetime,omitempty" yaml:"code_lifetime,omitempty"`
+	// AutoApproveFirstParty disables the consent screen for the clients
+	// marked as first-party applications.
+	AutoApproveFirstParty bool            `json:"auto_approve_first_party,omitempty" xml:"auto_approve_first_party,omitempty" yaml:"auto_approve_first_party,omitempty"`
+	Clients               []*ClientConfig `json:"clients,omitempty" xml:"clients,omitempty" yaml:"clients,omitempty"`
+	// Registration enables the dynamic registration of clients.
+	Registration *RegistrationConfig `json:"registration,omitempty" xml:"registration,omitempty" yaml:"registration,omitempty"`
+}
+
+// ClientConfig holds the registration of a relying party.
+type ClientConfig struct {
+	ID string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	// Name is the name of the application displayed on the consent screen.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Secret is the client secret of a confidential client. It is either a
+	// bcrypt hash, or a plain-text string.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	// Public indicates that the client cannot keep a secret, e.g. a
+	// single-page or a native application. Public clients must use PKCE.
+	Public bool `json:"public,omitempty" xml:"public,omitempty" yaml:"public,omitempty"`
+	// RedirectURIs are the exact redirect URIs the client may use.
+	RedirectURIs []string `json:"redirect_uris,omitempty" xml:"redirect_uris,omitempty" yaml:"redirect_uris,omitempty"`
+	// Scopes are the scopes the client may request. Defaults to openid,
+	// profile, email, and groups.
+	Scopes []string `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// SkipConsent disables the consent screen, e.g. for first-party
+	// applications.
+	SkipConsent bool `json:"skip_consent,omitempty" xml:"skip_consent,omitempty" yaml:"skip_consent,omitempty"`
+	// FirstParty indicates that the application is operated by the
+	// organization operating the provider.
+	FirstParty bool `json:"first_party,omitempty" xml:"first_party,omitempty" yaml:"first_party,omitempty"`
+	// ClaimTransform holds the transformation of the claims in the tokens
+	// issued for the application, e.g. the mapping of the roles.
+	ClaimTransform *transformer.AppConfig `json:"claim_transform,omitempty" xml:"claim_transform,omitempty" yaml:"claim_transform,omitempty"`
+}
+
+type client struct {
+	config       *ClientConfig
+	scopes       map[string]bool
+	secretHash   []byte
+	secretDigest [sha256.Size]byte
+}
+
+// Provider is an OpenID Connect provider issuing authorization codes to the
+// registered relying parties.
+type Provider struct {
+	config          *Config
+	clients         map[string]*client
+	codeLifetime    time.Duration
+	consentLifetime time.Duration
+	mu              sync.Mutex
+	pending         map[string]*AuthorizationRequest
+	codes           map[string]*authorizationCode
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.CodeLifetime < 0 {
+		return errors.ErrOIDCConfigCodeLifetime.WithArgs(cfg.CodeLifetime)
+	}
+	if cfg.Issuer != "" {
+		u, err := url.Parse(cfg.Issuer)
+		if err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return errors.ErrOIDCConfigIssuer.WithArgs(cfg.Issuer)
+		}
+	}
+	if cfg.Registration != nil {
+		if err := cfg.Registration.Validate(); err != nil {
+			return err
+		}
+	}
+	ids := make(map[string]bool)
+	for _, c := range cfg.Clients {
+		if c.ID == "" {
+			return errors.ErrOIDCConfigClientIDEmpty
+		}
+		if ids[c.ID] {
+			return errors.ErrOIDCConfigClientIDDuplicate.WithArgs(c.ID)
+		}
+		ids[c.ID] = true
+		if !c.Public && c.Secret == "" {
+			return errors.ErrOIDCConfigClientSecret.WithArgs(c.ID)
+		}
+		if len(c.RedirectURIs) == 0 {
+			return errors.ErrOIDCConfigClientRedirectURIs.WithArgs(c.ID)
+		}
+		for _, s := range c.RedirectURIs {
+			u, err := url.Parse(s)
+			if err != nil || !u.IsAbs() || u.Fragment != "" {
+				return errors.ErrOIDCConfigClientRedirectURI.WithArgs(c.ID, s)
+			}
+		}
+		if len(c.Scopes) > 0 && !contains(c.Scopes, "openid") {
+			return errors.ErrOIDCConfigClientScopeOpenID.WithArgs(c.ID)
+		}
+		if c.ClaimTransform != nil {
+			if err := c.ClaimTransform.Validate(); err != nil {
+				return errors.ErrOIDCConfigClientClaimTransform.WithArgs(c.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewProvider returns an instance of Provider.
+func NewProvider(cfg *Config) (*Provider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		config:          cfg,
+		clients:         make(map[string]*client),
+		codeLifetime:    time.Duration(defaultCodeLifetime) * time.Second,
+		consentLifetime: time.Duration(defaultConsentLifetime) * time.Second,
+		pending:         make(map[string]*AuthorizationRequest),
+		codes:           make(map[string]*authorizationCode),
+	}
+	if cfg.CodeLifetime > 0 {
+		p.codeLifetime = time.Duration(cfg.CodeLifetime) * time.Second
+	}
+	for _, c := range cfg.Clients {
+		entry := &client{
+			config: c,
+			scopes: make(map[string]bool),
+		}
+		scopes := c.Scopes
+		if len(scopes) == 0 {
+			scopes = defaultScopes
+		}
+		for _, scope := range scopes {
+			entry.scopes[scope] = true
+		}
+		switch {
+		case c.Secret == "":
+		case isBcryptHash(c.Secret):
+			entry.secretHash = []byte(c.Secret)
+		default:
+			entry.secretDigest = sha256.Sum256([]byte(c.Secret))
+		}
+		p.clients[c.ID] = entry
+	}
+	return p, nil
+}
+
+// GetIssuer returns the configured issuer identifier, if any.
+func (p *Provider) GetIssuer() string {
+	return strings.TrimSuffix(p.config.Issuer, "/")
+}
+
+// GetClient returns the registration of a client.
+func (p *Provider) GetClient(id string) (*ClientConfig, error) {
+	entry, exists := p.getClient(id)
+	if !exists {
+		return nil, errors.ErrOIDCClientNotFound.WithArgs(id)
+	}
+	return entry.config, nil
+}
+
+// IsConsentRequired returns true when the users must consent to the
+// requests of a client, i.e. the consent screen is neither disabled for
+// the client nor auto-approved for the first-party clients.
+func (p *Provider) IsConsentRequired(id string) bool {
+	entry, exists := p.getClient(id)
+	if !exists {
+		return true
+	}
+	if entry.config.SkipConsent {
+		return false
+	}
+	if entry.config.FirstParty && p.config.AutoApproveFirstParty {
+		return false
+	}
+	return true
+}
+
+// GetScopes returns the scopes supported by the provider.
+func (p *Provider) GetScopes() []string {
+	var scopes []string
+	m := make(map[string]bool)
+	for _, scope := range defaultScopes {
+		m[scope] = true
+		scopes = append(scopes, scope)
+	}
+	for _, c := range p.config.Clients {
+		for _, scope := range c.Scopes {
+			if m[scope] {
+				continue
+			}
+			m[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// AuthenticateClient authenticates a client outside of the authorization
+// code exchange, e.g. at the token revocation endpoint.
+func (p *Provider) AuthenticateClient(id, secret string) (*ClientConfig, error) {
+	entry, err := p.authenticateClient(id, secret)
+	if err != nil {
+		return nil, err
+	}
+	return entry.config, nil
+}
+
+// getClient returns either the configured or the dynamically registered
+// client.
+func (p *Provider) getClient(id string) (*client, bool) {
+	if entry, exists := p.clients[id]; exists {
+		return entry, true
+	}
+	return p.getRegisteredClient(id)
+}
+
+// authenticateClient authenticates a client at the token endpoint. Public
+// clients authenticate with the code verifier instead of a secret.
+func (p *Provider) authenticateClient(id, secret string) (*client, error) {
+	entry, exists := p.getClient(id)
+	if !exists {
+		return nil, errors.ErrOIDCClientAuthFailed
+	}
+	if entry.config.Public {
+		return entry, nil
+	}
+	if secret == "" {
+		return nil, errors.ErrOIDCClientAuthFailed
+	}
+	if entry.secretHash != nil {
+		if err := bcrypt.CompareHashAndPassword(entry.secretHash, []byte(secret)); err != nil {
+			return nil, errors.ErrOIDCClientAuthFailed
+		}
+		return entry, nil
+	}
+	digest := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(entry.secretDigest[:], digest[:]) != 1 {
+		return nil, errors.ErrOIDCClientAuthFailed
+	}
+	return entry, nil
+}
+
+func contains(arr []string, s string) bool {
+	for _, entry := range arr {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isBcryptHash(s string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	p, err := NewProvider(&Config{
+		Clients: []*ClientConfig{
+			{
+				ID:           "grafana",
+				Name:         "Grafana",
+				Secret:       "foobar",
+				RedirectURIs: []string{"https://grafana.contoso.com/login/generic_oauth"},
+			},
+			{
+				ID:           "cli",
+				Public:       true,
+				RedirectURIs: []string{"http://127.0.0.1:8085/callback", "http://127.0.0.1:8086/callback"},
+				Scopes:       []string{"openid", "email"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestNewProvider(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Issuer: "https://auth.contoso.com",
+				Clients: []*ClientConfig{
+					{ID: "grafana", Secret: "foobar", RedirectURIs: []string{"https://grafana.contoso.com/callback"}},
+				},
+			},
+		},
+		{
+			name:      "invalid issuer",
+			config:    &Config{Issuer: "auth.contoso.com"},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigIssuer.WithArgs("auth.contoso.com"),
+		},
+		{
+			name: "confidential client without secret",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{ID: "grafana", RedirectURIs: []string{"https://grafana.contoso.com/callback"}},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigClientSecret.WithArgs("grafana"),
+		},
+		{
+			name: "client without redirect uris",
+			config: &Config{
+				Clients: []*ClientConfig{{ID: "grafana", Secret: "foobar"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigClientRedirectURIs.WithArgs("grafana"),
+		},
+		{
+			name: "client with relative redirect uri",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{ID: "grafana", Secret: "foobar", RedirectURIs: []string{"/callback"}},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigClientRedirectURI.WithArgs("grafana", "/callback"),
+		},
+		{
+			name: "client scopes without openid",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{ID: "grafana", Secret: "foobar", RedirectURIs: []string{"https://grafana.contoso.com/callback"}, Scopes: []string{"email"}},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigClientScopeOpenID.WithArgs("grafana"),
+		},
+		{
+			name: "client with invalid claim transform",
+			config: &Config{
+				Clients: []*ClientConfig{
+					{
+						ID:             "grafana",
+						Secret:         "foobar",
+						RedirectURIs:   []string{"https://grafana.contoso.com/callback"},
+						ClaimTransform: &transformer.AppConfig{RoleMappings: map[string]string{"authp/admin": ""}},
+					},
+				},
+			},
+			shouldErr: true,
+			err: errors.ErrOIDCConfigClientClaimTransform.WithArgs(
+				"grafana", fmt.Errorf("app transformer has empty role mapping: %q to %q", "authp/admin", ""),
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewProvider(tc.config)
+			tests.EvalErrWithLog(t, err, "NewProvider", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestIsConsentRequired(t *testing.T) {
+	clients := []*ClientConfig{
+		{ID: "grafana", Secret: "foobar", RedirectURIs: []string{"https://grafana.contoso.com/callback"}, FirstParty: true},
+		{ID: "wiki", Secret: "foobar", RedirectURIs: []string{"https://wiki.contoso.com/callback"}, SkipConsent: true},
+		{ID: "partner", Secret: "foobar", RedirectURIs: []string{"https://app.partner.com/callback"}},
+	}
+	testcases := []struct {
+		name   string
+		config *Config
+		want   map[string]bool
+	}{
+		{
+			name:   "first-party clients require consent by default",
+			config: &Config{Clients: clients},
+			want: map[string]bool{
+				"grafana": true,
+				"wiki":    false,
+				"partner": true,
+				"unknown": true,
+			},
+		},
+		{
+			name:   "first-party clients auto-approved",
+			config: &Config{Clients: clients, AutoApproveFirstParty: true},
+			want: map[string]bool{
+				"grafana": false,
+				"wiki":    false,
+				"partner": true,
+				"unknown": true,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewProvider(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string]bool)
+			for id := range tc.want {
+				got[id] = p.IsConsentRequired(id)
+			}
+			tests.EvalObjectsWithLog(t, "consent required", tc.want, got, []string{fmt.Sprintf("test name: %s", tc.name)})
+		})
+	}
+}
+
+func TestParseAuthorizationRequest(t *testing.T) {
+	p := newTestProvider(t)
+	testcases := []struct {
+		name      string
+		query     string
+		want      *AuthorizationRequest
+		wantNil   bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "valid request with default redirect uri",
+			query: "response_type=code&client_id=grafana&scope=openid+email+openid&state=abc&nonce=xyz",
+			want: &AuthorizationRequest{
+				ClientID:    "grafana",
+				RedirectURI: "https://grafana.contoso.com/login/generic_oauth",
+				Scopes:      []string{"openid", "email"},
+				State:       "abc",
+				Nonce:       "xyz",
+			},
+		},
+		{
+			name:      "unknown client",
+			query:     "response_type=code&client_id=unknown&scope=openid",
+			wantNil:   true,
+			shouldErr: true,
+			err:       errors.ErrOIDCClientNotFound.WithArgs("unknown"),
+		},
+		{
+			name:      "unregistered redirect uri",
+			query:     "response_type=code&client_id=grafana&scope=openid&redirect_uri=https%3A%2F%2Fevil.com",
+			wantNil:   true,
+			shouldErr: true,
+			err:       errors.ErrOIDCRedirectURIMismatch.WithArgs("https://evil.com", "grafana"),
+		},
+		{
+			name:      "ambiguous redirect uri",
+			query:     "response_type=code&client_id=cli&scope=openid",
+			wantNil:   true,
+			shouldErr: true,
+			err:       errors.ErrOIDCRedirectURIMismatch.WithArgs("", "cli"),
+		},
+		{
+			name:      "implicit flow",
+			query:     "response_type=token&client_id=grafana&scope=openid",
+			shouldErr: true,
+			err:       errors.ErrOIDCResponseTypeUnsupported,
+		},
+		{
+			name:      "scope without openid",
+			query:     "response_type=code&client_id=grafana&scope=email",
+			shouldErr: true,
+			err:       errors.ErrOIDCScopeInvalid,
+		},
+		{
+			name:      "scope not allowed for client",
+			query:     "response_type=code&client_id=cli&scope=openid+groups&redirect_uri=http%3A%2F%2F127.0.0.1%3A8085%2Fcallback&code_challenge=foo",
+			shouldErr: true,
+			err:       errors.ErrOIDCScopeInvalid,
+		},
+		{
+			name:      "public client without code challenge",
+			query:     "response_type=code&client_id=cli&scope=openid&redirect_uri=http%3A%2F%2F127.0.0.1%3A8085%2Fcallback",
+			shouldErr: true,
+			err:       errors.ErrOIDCCodeChallengeRequired,
+		},
+		{
+			name:      "unsupported code challenge method",
+			query:     "response_type=code&client_id=cli&scope=openid&redirect_uri=http%3A%2F%2F127.0.0.1%3A8085%2Fcallback&code_challenge=foo&code_challenge_method=S512",
+			shouldErr: true,
+			err:       errors.ErrOIDCCodeChallengeMethod,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			q, _ := url.ParseQuery(tc.query)
+			req, err := p.ParseAuthorizationRequest(q)
+			tests.EvalObjectsWithLog(t, "nil request", tc.wantNil, req == nil, msgs)
+			if tests.EvalErrWithLog(t, err, "ParseAuthorizationRequest", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "request", tc.want, req, msgs)
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	p := newTestProvider(t)
+	verifier := "dBjftJeZ4CVP-mJ92K9qmZqPxZhIwDVOLgGMlk0E2k"
+	h := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(h[:])
+	redirectURI := "http://127.0.0.1:8085/callback"
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"cli"},
+		"scope":                 {"openid email"},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+		"nonce":                 {"xyz"},
+	}
+	req, err := p.ParseAuthorizationRequest(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The pending request is bound to the user.
+	id, err := p.Hold(req, "jsmith")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = p.Release(id, "mallory")
+	tests.EvalErrWithLog(t, err, "release for another user", true, errors.ErrOIDCAuthorizationNotFound, nil)
+	req, err = p.Release(id, "jsmith")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = p.Release(id, "jsmith")
+	tests.EvalErrWithLog(t, err, "release twice", true, errors.ErrOIDCAuthorizationNotFound, nil)
+
+	claims := map[string]interface{}{"sub": "jsmith", "email": "jsmith@contoso.com"}
+	code, err := p.IssueCode(req, claims, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = p.Exchange(code, "cli", "", redirectURI, "wrong")
+	tests.EvalErrWithLog(t, err, "wrong verifier", true, errors.ErrOIDCCodeVerifierMismatch, nil)
+	_, err = p.Exchange(code, "cli", "", redirectURI, verifier)
+	tests.EvalErrWithLog(t, err, "code reuse", true, errors.ErrOIDCCodeInvalid, nil)
+
+	code, _ = p.IssueCode(req, claims, 1000)
+	_, err = p.Exchange(code, "cli", "", "http://127.0.0.1:8086/callback", verifier)
+	tests.EvalErrWithLog(t, err, "redirect uri mismatch", true, errors.ErrOIDCCodeInvalid, nil)
+
+	code, _ = p.IssueCode(req, claims, 1000)
+	_, err = p.Exchange(code, "cli", "", "", verifier)
+	tests.EvalErrWithLog(t, err, "redirect uri missing", true, errors.ErrOIDCCodeInvalid, nil)
+
+	code, _ = p.IssueCode(req, claims, 1000)
+	grant, err := p.Exchange(code, "cli", "", redirectURI, verifier)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "grant", &Grant{
+		ClientID: "cli",
+		Scopes:   []string{"openid", "email"},
+		Nonce:    "xyz",
+		AuthTime: 1000,
+		Claims:   claims,
+	}, grant)
+
+	// Confidential clients must authenticate.
+	q, _ = url.ParseQuery("response_type=code&client_id=grafana&scope=openid")
+	req, _ = p.ParseAuthorizationRequest(q)
+	code, _ = p.IssueCode(req, claims, 1000)
+	_, err = p.Exchange(code, "grafana", "barfoo", req.RedirectURI, "")
+	tests.EvalErrWithLog(t, err, "wrong secret", true, errors.ErrOIDCClientAuthFailed, nil)
+	_, err = p.Exchange(code, "grafana", "foobar", req.RedirectURI, "")
+	tests.EvalErrWithLog(t, err, "client secret", false, nil, nil)
+
+	// The redirect uri is optional when the authorization request omitted it.
+	code, _ = p.IssueCode(req, claims, 1000)
+	_, err = p.Exchange(code, "grafana", "foobar", "", "")
+	tests.EvalErrWithLog(t, err, "redirect uri omitted", false, nil, nil)
+	code, _ = p.IssueCode(req, claims, 1000)
+	_, err = p.Exchange(code, "grafana", "foobar", "https://grafana.contoso.com/callback", "")
+	tests.EvalErrWithLog(t, err, "redirect uri omitted and mismatched", true, errors.ErrOIDCCodeInvalid, nil)
+}
+
+func TestIssueCodeClaimTransform(t *testing.T) {
+	p, err := NewProvider(&Config{
+		Clients: []*ClientConfig{
+			{
+				ID:           "grafana",
+				Secret:       "foobar",
+				RedirectURIs: []string{"https://grafana.contoso.com/login/generic_oauth"},
+				ClaimTransform: &transformer.AppConfig{
+					RoleMappings: map[string]string{
+						"authp/admin": "administrator",
+					},
+					DropUnmappedRoles: true,
+					Claims:            []string{"roles"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q, _ := url.ParseQuery("response_type=code&client_id=grafana&scope=openid")
+	req, err := p.ParseAuthorizationRequest(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims := map[string]interface{}{
+		"sub":   "jsmith",
+		"email": "jsmith@contoso.com",
+		"roles": []string{"authp/admin", "authp/user"},
+	}
+	code, err := p.IssueCode(req, claims, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	grant, err := p.Exchange(code, "grafana", "foobar", req.RedirectURI, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "claims", map[string]interface{}{
+		"sub":   "jsmith",
+		"roles": []string{"administrator"},
+	}, grant.Claims)
+}
@@ -0,0 +1,307 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	registeredClientKeyPrefix = "oidc_client:"
+	// The registrations without lifetime are kept for 100 years.
+	maxRegisteredClientLifetime = 100 * 365 * 24 * time.Hour
+	// The roles of the users are released to the configured clients only,
+	// i.e. the registered clients cannot request the groups scope.
+	registeredClientExcludedScope = "groups"
+)
+
+// RegistrationConfig holds the configuration of the dynamic client
+// registration, see RFC 7591.
+type RegistrationConfig struct {
+	// InitialAccessTokens are the bearer tokens authorizing the
+	// registration requests. A token is either a bcrypt hash, or a
+	// plain-text string.
+	InitialAccessTokens []string `json:"initial_access_tokens,omitempty" xml:"initial_access_tokens,omitempty" yaml:"initial_access_tokens,omitempty"`
+	// TokenStore is the name of the token store holding the registered
+	// clients.
+	TokenStore string `json:"token_store,omitempty" xml:"token_store,omitempty" yaml:"token_store,omitempty"`
+	// ClientLifetime is the lifetime of the registered clients in seconds.
+	// When it is zero, the clients do not expire.
+	ClientLifetime int `json:"client_lifetime,omitempty" xml:"client_lifetime,omitempty" yaml:"client_lifetime,omitempty"`
+}
+
+// ClientMetadata is the metadata of the client submitted in a registration
+// request.
+type ClientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris,omitempty" xml:"redirect_uris,omitempty" yaml:"redirect_uris,omitempty"`
+	ClientName              string   `json:"client_name,omitempty" xml:"client_name,omitempty" yaml:"client_name,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty" xml:"grant_types,omitempty" yaml:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty" xml:"response_types,omitempty" yaml:"response_types,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty" xml:"token_endpoint_auth_method,omitempty" yaml:"token_endpoint_auth_method,omitempty"`
+	Scope                   string   `json:"scope,omitempty" xml:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+// ClientInformation is the registered client returned in a registration
+// response.
+type ClientInformation struct {
+	ClientID              string `json:"client_id,omitempty" xml:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret          string `json:"client_secret,omitempty" xml:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	ClientIDIssuedAt      int64  `json:"client_id_issued_at,omitempty" xml:"client_id_issued_at,omitempty" yaml:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt int64  `json:"client_secret_expires_at,omitempty" xml:"client_secret_expires_at,omitempty" yaml:"client_secret_expires_at,omitempty"`
+	// The metadata of the client, with the defaults applied.
+	Metadata *ClientMetadata `json:"metadata,omitempty" xml:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// Validate validates RegistrationConfig.
+func (cfg *RegistrationConfig) Validate() error {
+	if len(cfg.InitialAccessTokens) == 0 {
+		return errors.ErrOIDCConfigRegistrationTokens
+	}
+	if cfg.TokenStore == "" {
+		return errors.ErrOIDCConfigRegistrationStore
+	}
+	if cfg.ClientLifetime < 0 {
+		return errors.ErrOIDCConfigRegistrationLifetime.WithArgs(cfg.ClientLifetime)
+	}
+	return nil
+}
+
+// RegistrationEnabled returns true when the provider accepts client
+// registration requests.
+func (p *Provider) RegistrationEnabled() bool {
+	return p.config.Registration != nil
+}
+
+// AuthorizeRegistration validates the initial access token of a
+// registration request.
+func (p *Provider) AuthorizeRegistration(token string) error {
+	if !p.RegistrationEnabled() {
+		return errors.ErrOIDCRegistrationDisabled
+	}
+	if token == "" {
+		return errors.ErrOIDCRegistrationUnauthorized
+	}
+	digest := sha256.Sum256([]byte(token))
+	for _, s := range p.config.Registration.InitialAccessTokens {
+		if isBcryptHash(s) {
+			if bcrypt.CompareHashAndPassword([]byte(s), []byte(token)) == nil {
+				return nil
+			}
+			continue
+		}
+		expected := sha256.Sum256([]byte(s))
+		if subtle.ConstantTimeCompare(expected[:], digest[:]) == 1 {
+			return nil
+		}
+	}
+	return errors.ErrOIDCRegistrationUnauthorized
+}
+
+// Register validates the metadata of a client, and registers the client
+// in the token store. The secret of a confidential client is returned
+// once and stored as a digest. The access tokens of the registered clients
+// carry no roles, because the clients cannot request the groups scope.
+func (p *Provider) Register(md *ClientMetadata) (*ClientInformation, error) {
+	if !p.RegistrationEnabled() {
+		return nil, errors.ErrOIDCRegistrationDisabled
+	}
+	if err := p.validateClientMetadata(md); err != nil {
+		return nil, err
+	}
+	store, err := tokenstore.Get(p.config.Registration.TokenStore)
+	if err != nil {
+		return nil, errors.ErrOIDCRegistrationStore.WithArgs(p.config.Registration.TokenStore, err)
+	}
+
+	id, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	info := &ClientInformation{
+		ClientID:         id,
+		ClientIDIssuedAt: now.Unix(),
+		Metadata:         md,
+	}
+	expiresAt := now.Add(maxRegisteredClientLifetime)
+	if p.config.Registration.ClientLifetime > 0 {
+		expiresAt = now.Add(time.Duration(p.config.Registration.ClientLifetime) * time.Second)
+		info.ClientSecretExpiresAt = expiresAt.Unix()
+	}
+
+	m := map[string]interface{}{
+		"client_id":     id,
+		"client_name":   md.ClientName,
+		"redirect_uris": md.RedirectURIs,
+		"scopes":        strings.Fields(md.Scope),
+		"public":        md.TokenEndpointAuthMethod == "none",
+	}
+	if md.TokenEndpointAuthMethod != "none" {
+		secret, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256([]byte(secret))
+		m["secret_digest"] = hex.EncodeToString(digest[:])
+		info.ClientSecret = secret
+	}
+	if err := store.Add(registeredClientKeyPrefix+id, m, expiresAt); err != nil {
+		return nil, errors.ErrOIDCRegistrationStore.WithArgs(p.config.Registration.TokenStore, err)
+	}
+	return info, nil
+}
+
+// validateClientMetadata validates the metadata of a client and applies
+// the defaults, see RFC 7591, Section 2.
+func (p *Provider) validateClientMetadata(md *ClientMetadata) error {
+	if len(md.RedirectURIs) == 0 {
+		return errors.ErrOIDCClientMetadataRedirectURIs
+	}
+	for _, s := range md.RedirectURIs {
+		if !isRegistrableRedirectURI(s) {
+			return errors.ErrOIDCClientMetadataRedirectURI.WithArgs(s)
+		}
+	}
+	for _, s := range md.ResponseTypes {
+		if s != "code" {
+			return errors.ErrOIDCClientMetadataInvalid.WithArgs("response type " + s + " is unsupported")
+		}
+	}
+	md.ResponseTypes = []string{"code"}
+	for _, s := range md.GrantTypes {
+		if s != "authorization_code" {
+			return errors.ErrOIDCClientMetadataInvalid.WithArgs("grant type " + s + " is unsupported")
+		}
+	}
+	md.GrantTypes = []string{"authorization_code"}
+	switch md.TokenEndpointAuthMethod {
+	case "":
+		md.TokenEndpointAuthMethod = "client_secret_basic"
+	case "client_secret_basic", "client_secret_post", "none":
+	default:
+		return errors.ErrOIDCClientMetadataInvalid.WithArgs("token endpoint auth method " + md.TokenEndpointAuthMethod + " is unsupported")
+	}
+	scopes := strings.Fields(md.Scope)
+	if len(scopes) == 0 {
+		for _, scope := range defaultScopes {
+			if scope != registeredClientExcludedScope {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	if contains(scopes, registeredClientExcludedScope) {
+		return errors.ErrOIDCClientMetadataInvalid.WithArgs("scope " + registeredClientExcludedScope + " is not available to registered clients")
+	}
+	if !contains(scopes, "openid") {
+		return errors.ErrOIDCClientMetadataInvalid.WithArgs("scope must include openid")
+	}
+	supported := p.GetScopes()
+	for _, scope := range scopes {
+		if !contains(supported, scope) {
+			return errors.ErrOIDCClientMetadataInvalid.WithArgs("scope " + scope + " is unsupported")
+		}
+	}
+	md.Scope = strings.Join(scopes, " ")
+	return nil
+}
+
+// getRegisteredClient returns the client registered in the token store.
+func (p *Provider) getRegisteredClient(id string) (*client, bool) {
+	if !p.RegistrationEnabled() || id == "" {
+		return nil, false
+	}
+	store, err := tokenstore.Get(p.config.Registration.TokenStore)
+	if err != nil {
+		return nil, false
+	}
+	m, err := store.Get(registeredClientKeyPrefix + id)
+	if err != nil {
+		return nil, false
+	}
+	cfg := &ClientConfig{
+		ID:           id,
+		RedirectURIs: getStrings(m["redirect_uris"]),
+		Scopes:       getStrings(m["scopes"]),
+	}
+	cfg.Name, _ = m["client_name"].(string)
+	cfg.Public, _ = m["public"].(bool)
+	entry := &client{
+		config: cfg,
+		scopes: make(map[string]bool),
+	}
+	for _, scope := range cfg.Scopes {
+		if scope == registeredClientExcludedScope {
+			continue
+		}
+		entry.scopes[scope] = true
+	}
+	if s, ok := m["secret_digest"].(string); ok {
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != sha256.Size {
+			return nil, false
+		}
+		copy(entry.secretDigest[:], b)
+		// Mark the client as confidential.
+		cfg.Secret = s
+	}
+	return entry, true
+}
+
+// isRegistrableRedirectURI returns true when the redirect URI uses https,
+// or http on the loopback interface for native applications.
+func isRegistrableRedirectURI(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Fragment != "" || u.Host == "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+func getStrings(v interface{}) []string {
+	switch data := v.(type) {
+	case []string:
+		return data
+	case []interface{}:
+		var arr []string
+		for _, entry := range data {
+			if s, ok := entry.(string); ok {
+				arr = append(arr, s)
+			}
+		}
+		return arr
+	}
+	return nil
+}
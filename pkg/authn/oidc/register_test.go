@@ -0,0 +1,209 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+func newTestRegistrationProvider(t *testing.T) *Provider {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: "oidc_registration_test", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokenstore.Register("oidc_registration_test", store)
+	p, err := NewProvider(&Config{
+		Registration: &RegistrationConfig{
+			InitialAccessTokens: []string{"foobar"},
+			TokenStore:          "oidc_registration_test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestRegistrationConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *RegistrationConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &RegistrationConfig{InitialAccessTokens: []string{"foobar"}, TokenStore: "default", ClientLifetime: 3600},
+		},
+		{
+			name:      "no initial access tokens",
+			config:    &RegistrationConfig{TokenStore: "default"},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigRegistrationTokens,
+		},
+		{
+			name:      "no token store",
+			config:    &RegistrationConfig{InitialAccessTokens: []string{"foobar"}},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigRegistrationStore,
+		},
+		{
+			name:      "negative client lifetime",
+			config:    &RegistrationConfig{InitialAccessTokens: []string{"foobar"}, TokenStore: "default", ClientLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrOIDCConfigRegistrationLifetime.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewProvider(&Config{Registration: tc.config})
+			tests.EvalErrWithLog(t, err, "NewProvider", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestAuthorizeRegistration(t *testing.T) {
+	p := newTestRegistrationProvider(t)
+	tests.EvalErrWithLog(t, p.AuthorizeRegistration("foobar"), "valid token", false, nil, nil)
+	tests.EvalErrWithLog(t, p.AuthorizeRegistration("barfoo"), "invalid token", true, errors.ErrOIDCRegistrationUnauthorized, nil)
+	tests.EvalErrWithLog(t, p.AuthorizeRegistration(""), "empty token", true, errors.ErrOIDCRegistrationUnauthorized, nil)
+	tests.EvalErrWithLog(t, newTestProvider(t).AuthorizeRegistration("foobar"), "registration disabled", true, errors.ErrOIDCRegistrationDisabled, nil)
+}
+
+func TestRegister(t *testing.T) {
+	testcases := []struct {
+		name       string
+		metadata   *ClientMetadata
+		want       *ClientMetadata
+		wantSecret bool
+		shouldErr  bool
+		err        error
+	}{
+		{
+			name: "confidential client with defaults",
+			metadata: &ClientMetadata{
+				ClientName:   "Wiki",
+				RedirectURIs: []string{"https://wiki.contoso.com/callback"},
+			},
+			want: &ClientMetadata{
+				ClientName:              "Wiki",
+				RedirectURIs:            []string{"https://wiki.contoso.com/callback"},
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+				Scope:                   "openid profile email",
+			},
+			wantSecret: true,
+		},
+		{
+			name: "client with groups scope",
+			metadata: &ClientMetadata{
+				RedirectURIs: []string{"https://wiki.contoso.com/callback"},
+				Scope:        "openid groups",
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataInvalid.WithArgs("scope groups is not available to registered clients"),
+		},
+		{
+			name: "public native client",
+			metadata: &ClientMetadata{
+				RedirectURIs:            []string{"http://127.0.0.1:8085/callback"},
+				TokenEndpointAuthMethod: "none",
+				Scope:                   "openid email",
+			},
+			want: &ClientMetadata{
+				RedirectURIs:            []string{"http://127.0.0.1:8085/callback"},
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: "none",
+				Scope:                   "openid email",
+			},
+		},
+		{
+			name:      "no redirect uris",
+			metadata:  &ClientMetadata{},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataRedirectURIs,
+		},
+		{
+			name:      "plain http redirect uri",
+			metadata:  &ClientMetadata{RedirectURIs: []string{"http://wiki.contoso.com/callback"}},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataRedirectURI.WithArgs("http://wiki.contoso.com/callback"),
+		},
+		{
+			name: "unsupported grant type",
+			metadata: &ClientMetadata{
+				RedirectURIs: []string{"https://wiki.contoso.com/callback"},
+				GrantTypes:   []string{"implicit"},
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataInvalid.WithArgs("grant type implicit is unsupported"),
+		},
+		{
+			name: "scope without openid",
+			metadata: &ClientMetadata{
+				RedirectURIs: []string{"https://wiki.contoso.com/callback"},
+				Scope:        "email",
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataInvalid.WithArgs("scope must include openid"),
+		},
+		{
+			name: "unsupported scope",
+			metadata: &ClientMetadata{
+				RedirectURIs: []string{"https://wiki.contoso.com/callback"},
+				Scope:        "openid admin",
+			},
+			shouldErr: true,
+			err:       errors.ErrOIDCClientMetadataInvalid.WithArgs("scope admin is unsupported"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			p := newTestRegistrationProvider(t)
+			info, err := p.Register(tc.metadata)
+			if tests.EvalErrWithLog(t, err, "Register", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "metadata", tc.want, info.Metadata, msgs)
+			tests.EvalObjectsWithLog(t, "secret issued", tc.wantSecret, info.ClientSecret != "", msgs)
+
+			// The registered client is able to start authorization requests
+			// and to redeem authorization codes.
+			q := url.Values{
+				"response_type":  {"code"},
+				"client_id":      {info.ClientID},
+				"scope":          {"openid"},
+				"code_challenge": {"foo"},
+			}
+			req, err := p.ParseAuthorizationRequest(q)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			code, _ := p.IssueCode(req, map[string]interface{}{"sub": "jsmith"}, 1000)
+			_, err = p.Exchange(code, info.ClientID, info.ClientSecret, req.RedirectURI, "foo")
+			tests.EvalErrWithLog(t, err, "Exchange", false, nil, msgs)
+		})
+	}
+}
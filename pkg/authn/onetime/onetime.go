@@ -0,0 +1,215 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onetime
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultTokenLifetime = 900
+	defaultMaxRequests   = 3
+	defaultWindow        = 3600
+)
+
+// Config holds the lifetime of one-time tokens and the limits of the
+// requests for them. When a value is zero, the default applies.
+type Config struct {
+	// The number of seconds a token is valid for. Defaults to 15 minutes.
+	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+	// The maximum number of requests per key within the throttling window.
+	// Defaults to 3.
+	MaxRequests int `json:"max_requests,omitempty" xml:"max_requests,omitempty" yaml:"max_requests,omitempty"`
+	// The throttling window in seconds. Defaults to 1 hour.
+	Window int `json:"window,omitempty" xml:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// envelope is the signed part of a one-time token.
+type envelope struct {
+	Nonce     string          `json:"nonce"`
+	ExpiresAt int64           `json:"expires_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Issuer issues and redeems signed one-time tokens, e.g. the tokens of
+// the links sent by email, and throttles the requests for them. The tokens
+// are signed with a random key generated at startup, i.e. the outstanding
+// tokens become invalid after a restart.
+type Issuer struct {
+	mu       sync.Mutex
+	secret   []byte
+	lifetime time.Duration
+	used     map[string]time.Time
+	limiter  *ratelimit.Limiter
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.TokenLifetime < 0 {
+		return errors.ErrOneTimeConfigTokenLifetime.WithArgs(cfg.TokenLifetime)
+	}
+	if cfg.MaxRequests < 0 {
+		return errors.ErrOneTimeConfigMaxRequests.WithArgs(cfg.MaxRequests)
+	}
+	if cfg.Window < 0 {
+		return errors.ErrOneTimeConfigWindow.WithArgs(cfg.Window)
+	}
+	return nil
+}
+
+// NewIssuer returns an instance of Issuer.
+func NewIssuer(cfg *Config) (*Issuer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	i := &Issuer{
+		secret:   make([]byte, 32),
+		lifetime: time.Duration(getValue(cfg.TokenLifetime, defaultTokenLifetime)) * time.Second,
+		used:     make(map[string]time.Time),
+	}
+	if _, err := rand.Read(i.secret); err != nil {
+		return nil, err
+	}
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{
+		MaxAttempts: getValue(cfg.MaxRequests, defaultMaxRequests),
+		Interval:    getValue(cfg.Window, defaultWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	i.limiter = limiter
+	return i, nil
+}
+
+// GetTokenLifetime returns the lifetime of the tokens.
+func (i *Issuer) GetTokenLifetime() time.Duration {
+	return i.lifetime
+}
+
+// Allow records a request for each of the provided keys, e.g. email and
+// source IP addresses. It returns an error when any of the keys exceeded
+// the number of requests allowed within the window. The expired windows
+// are discarded, i.e. the memory held by the throttle is bounded by the
+// keys seen within a window.
+func (i *Issuer) Allow(keys ...string) error {
+	if _, err := i.limiter.Allow(keys...); err != nil {
+		return errors.ErrOneTimeThrottled
+	}
+	return nil
+}
+
+// Issue returns a signed token carrying the JSON encoding of the payload.
+func (i *Issuer) Issue(payload interface{}) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(&envelope{
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: time.Now().Add(i.lifetime).Unix(),
+		Payload:   b,
+	})
+	if err != nil {
+		return "", err
+	}
+	s := base64.RawURLEncoding.EncodeToString(data)
+	return s + "." + i.sign(s), nil
+}
+
+// Verify checks the signature, expiry, and prior use of a token and
+// decodes its payload into v.
+func (i *Issuer) Verify(s string, v interface{}) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, err := i.verify(s, v)
+	return err
+}
+
+// Redeem verifies a token, decodes its payload into v, and marks the
+// token used.
+func (i *Issuer) Redeem(s string, v interface{}) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	env, err := i.verify(s, v)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for nonce, expiresAt := range i.used {
+		if now.After(expiresAt) {
+			delete(i.used, nonce)
+		}
+	}
+	i.used[env.Nonce] = time.Unix(env.ExpiresAt, 0)
+	return nil
+}
+
+func (i *Issuer) verify(s string, v interface{}) (*envelope, error) {
+	arr := strings.Split(s, ".")
+	if len(arr) != 2 {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	if !hmac.Equal([]byte(i.sign(arr[0])), []byte(arr[1])) {
+		return nil, errors.ErrOneTimeTokenSignature
+	}
+	data, err := base64.RawURLEncoding.DecodeString(arr[0])
+	if err != nil {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	env := &envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	if env.Nonce == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	if time.Now().Unix() > env.ExpiresAt {
+		return nil, errors.ErrOneTimeTokenExpired
+	}
+	if _, exists := i.used[env.Nonce]; exists {
+		return nil, errors.ErrOneTimeTokenUsed
+	}
+	if err := json.Unmarshal(env.Payload, v); err != nil {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return env, nil
+}
+
+func (i *Issuer) sign(s string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func getValue(v, defaultValue int) int {
+	if v > 0 {
+		return v
+	}
+	return defaultValue
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+type testPayload struct {
+	Email string `json:"email,omitempty"`
+}
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{TokenLifetime: 600, MaxRequests: 5, Window: 60},
+		},
+		{
+			name:   "default config",
+			config: &Config{},
+		},
+		{
+			name:      "config with negative token lifetime",
+			config:    &Config{TokenLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrOneTimeConfigTokenLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max requests",
+			config:    &Config{MaxRequests: -1},
+			shouldErr: true,
+			err:       errors.ErrOneTimeConfigMaxRequests.WithArgs(-1),
+		},
+		{
+			name:      "config with negative window",
+			config:    &Config{Window: -1},
+			shouldErr: true,
+			err:       errors.ErrOneTimeConfigWindow.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	i, err := NewIssuer(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "default lifetime", 15*time.Minute, i.GetTokenLifetime())
+	token, err := i.Issue(&testPayload{Email: "jsmith@localhost"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	payload := &testPayload{}
+	if err := i.Verify(token, payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "payload", "jsmith@localhost", payload.Email)
+
+	err = i.Verify(token+"x", &testPayload{})
+	tests.EvalErrWithLog(t, err, "tampered token", true, errors.ErrOneTimeTokenSignature, nil)
+	err = i.Verify("foobar", &testPayload{})
+	tests.EvalErrWithLog(t, err, "malformed token", true, errors.ErrOneTimeTokenMalformed, nil)
+
+	// The token of another issuer is not accepted.
+	other, err := NewIssuer(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = other.Verify(token, &testPayload{})
+	tests.EvalErrWithLog(t, err, "foreign token", true, errors.ErrOneTimeTokenSignature, nil)
+
+	if err := i.Redeem(token, &testPayload{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = i.Redeem(token, &testPayload{})
+	tests.EvalErrWithLog(t, err, "used token", true, errors.ErrOneTimeTokenUsed, nil)
+	err = i.Verify(token, &testPayload{})
+	tests.EvalErrWithLog(t, err, "verify used token", true, errors.ErrOneTimeTokenUsed, nil)
+
+	i.lifetime = -time.Second
+	token, err = i.Issue(&testPayload{Email: "jsmith@localhost"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = i.Redeem(token, &testPayload{})
+	tests.EvalErrWithLog(t, err, "expired token", true, errors.ErrOneTimeTokenExpired, nil)
+}
+
+func TestAllow(t *testing.T) {
+	i, err := NewIssuer(&Config{MaxRequests: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for n := 0; n < 2; n++ {
+		if err := i.Allow("email:jsmith@localhost", "ip:10.0.0.1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err = i.Allow("email:jsmith@localhost", "ip:10.0.0.2")
+	tests.EvalErrWithLog(t, err, "throttled email", true, errors.ErrOneTimeThrottled, nil)
+	err = i.Allow("email:bjones@localhost", "ip:10.0.0.1")
+	tests.EvalErrWithLog(t, err, "throttled ip", true, errors.ErrOneTimeThrottled, nil)
+	if err := i.Allow("email:bjones@localhost", "ip:10.0.0.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
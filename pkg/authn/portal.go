@@ -19,11 +19,37 @@ import (
 	"sort"
 
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authn/accessrequest"
+	"github.com/greenpau/go-authcrunch/pkg/authn/admission"
+	"github.com/greenpau/go-authcrunch/pkg/authn/bodylimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/breakglass"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cache"
+	"github.com/greenpau/go-authcrunch/pkg/authn/clients"
+	"github.com/greenpau/go-authcrunch/pkg/authn/continuation"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authn/deletion"
+	"github.com/greenpau/go-authcrunch/pkg/authn/emailchange"
+	"github.com/greenpau/go-authcrunch/pkg/authn/groupmap"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/authn/idphint"
+	"github.com/greenpau/go-authcrunch/pkg/authn/lifecycle"
+	"github.com/greenpau/go-authcrunch/pkg/authn/linking"
+	"github.com/greenpau/go-authcrunch/pkg/authn/loginguard"
+	"github.com/greenpau/go-authcrunch/pkg/authn/magiclink"
+	"github.com/greenpau/go-authcrunch/pkg/authn/maintenance"
+	"github.com/greenpau/go-authcrunch/pkg/authn/netfilter"
+	"github.com/greenpau/go-authcrunch/pkg/authn/normalizer"
+	"github.com/greenpau/go-authcrunch/pkg/authn/oidc"
+	"github.com/greenpau/go-authcrunch/pkg/authn/profile"
+	"github.com/greenpau/go-authcrunch/pkg/authn/ratelimit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/recovery"
+	"github.com/greenpau/go-authcrunch/pkg/authn/redirect"
+	"github.com/greenpau/go-authcrunch/pkg/authn/sessionpolicy"
+	"github.com/greenpau/go-authcrunch/pkg/authn/shaper"
+	"github.com/greenpau/go-authcrunch/pkg/authn/signin"
 	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
 	"github.com/greenpau/go-authcrunch/pkg/authn/ui"
+	"github.com/greenpau/go-authcrunch/pkg/authn/usage"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
 	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
@@ -46,6 +72,8 @@ import (
 const (
 	defaultPortalACLCondition = "match roles authp/admin authp/user authp/guest superuser superadmin"
 	defaultPortalACLAction    = "allow stop"
+
+	defaultTemplatesReloadInterval = 10 * time.Second
 )
 
 // Portal is an authentication portal.
@@ -60,11 +88,38 @@ type Portal struct {
 	ssoProviders      []sso.SingleSignOnProvider
 	cookie            *cookie.Factory
 	transformer       *transformer.Factory
+	groupMapper       *groupmap.Mapper
 	ui                *ui.Factory
 	startedAt         time.Time
 	sessions          *cache.SessionCache
 	sandboxes         *cache.SandboxCache
 	loginOptions      map[string]interface{}
+	admission         *admission.Controller
+	netFilter         *netfilter.Filter
+	bodyLimiter       *bodylimit.Limiter
+	loginLimiter      *ratelimit.Limiter
+	loginGuard        *loginguard.Guard
+	redirectPolicy    *redirect.Policy
+	continuation      *continuation.Codec
+	idpHints          *idphint.Signer
+	sessionPolicy     *sessionpolicy.Policy
+	usage             *usage.Collector
+	shaper            *shaper.Shaper
+	normalizer        *normalizer.Normalizer
+	linker            *linking.Manager
+	recovery          *recovery.Manager
+	emailChange       *emailchange.Manager
+	deletion          *deletion.Manager
+	magicLinks        *magiclink.Manager
+	signIns           *signin.Tracker
+	accessRequests    *accessrequest.Manager
+	roleExpiryExit    chan bool
+	breakGlass        *breakglass.Manager
+	maintenance       *maintenance.Manager
+	lifecycle         *lifecycle.Manager
+	profile           *profile.Validator
+	clients           *clients.Registry
+	oidc              *oidc.Provider
 	logger            *zap.Logger
 }
 
@@ -183,6 +238,12 @@ func (p *Portal) configure() error {
 	if err := p.configureLoginOptions(); err != nil {
 		return err
 	}
+	if err := p.configureLoginChains(); err != nil {
+		return err
+	}
+	if err := p.configureRealmDiscovery(); err != nil {
+		return err
+	}
 	if err := p.configureUserInterface(); err != nil {
 		return err
 	}
@@ -214,6 +275,361 @@ func (p *Portal) configureEssentials() error {
 		return err
 	}
 	p.cookie = c
+
+	if p.config.AdmissionConfig != nil {
+		p.logger.Debug(
+			"Configuring admission control",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("admission_config", p.config.AdmissionConfig),
+		)
+		ac, err := admission.NewController(p.config.AdmissionConfig)
+		if err != nil {
+			return err
+		}
+		p.admission = ac
+	}
+
+	if p.config.NetworkFilterConfig != nil {
+		p.logger.Debug(
+			"Configuring network filter",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("network_filter_config", p.config.NetworkFilterConfig),
+		)
+		nf, err := netfilter.NewFilter(p.config.NetworkFilterConfig)
+		if err != nil {
+			return err
+		}
+		p.netFilter = nf
+	}
+
+	p.logger.Debug(
+		"Configuring request body limits",
+		zap.String("portal_name", p.config.Name),
+		zap.Any("body_limit_config", p.config.BodyLimitConfig),
+	)
+	bl, err := bodylimit.NewLimiter(p.config.BodyLimitConfig)
+	if err != nil {
+		return err
+	}
+	p.bodyLimiter = bl
+
+	p.logger.Debug(
+		"Configuring login rate limits",
+		zap.String("portal_name", p.config.Name),
+		zap.Any("login_rate_limit_config", p.config.LoginRateLimitConfig),
+	)
+	ll, err := ratelimit.NewLimiter(p.config.LoginRateLimitConfig)
+	if err != nil {
+		return err
+	}
+	p.loginLimiter = ll
+
+	if p.config.LoginChallengeConfig != nil {
+		p.logger.Debug(
+			"Configuring login challenge",
+			zap.String("portal_name", p.config.Name),
+			zap.Strings("realms", p.config.LoginChallengeConfig.Realms),
+			zap.Int("failed_attempts", p.config.LoginChallengeConfig.FailedAttempts),
+		)
+		lg, err := loginguard.NewGuard(p.config.LoginChallengeConfig)
+		if err != nil {
+			return err
+		}
+		p.loginGuard = lg
+	}
+
+	if p.config.RedirectConfig != nil {
+		p.logger.Debug(
+			"Configuring redirect policy",
+			zap.String("portal_name", p.config.Name),
+			zap.Strings("allowed_hosts", p.config.RedirectConfig.AllowedHosts),
+			zap.Strings("allowed_patterns", p.config.RedirectConfig.AllowedPatterns),
+		)
+		rp, err := redirect.NewPolicy(p.config.RedirectConfig)
+		if err != nil {
+			return err
+		}
+		p.redirectPolicy = rp
+	}
+
+	if p.config.ContinuationConfig != nil {
+		p.logger.Debug(
+			"Configuring continuation tokens",
+			zap.String("portal_name", p.config.Name),
+			zap.Int("lifetime", p.config.ContinuationConfig.Lifetime),
+		)
+		cc, err := continuation.NewCodec(p.config.ContinuationConfig)
+		if err != nil {
+			return err
+		}
+		p.continuation = cc
+	}
+
+	if p.config.IdentityProviderHintConfig != nil {
+		p.logger.Debug(
+			"Configuring identity provider hints",
+			zap.String("portal_name", p.config.Name),
+			zap.Int("lifetime", p.config.IdentityProviderHintConfig.Lifetime),
+			zap.Int("redirect_delay", p.config.IdentityProviderHintConfig.RedirectDelay),
+		)
+		hs, err := idphint.NewSigner(p.config.IdentityProviderHintConfig)
+		if err != nil {
+			return err
+		}
+		p.idpHints = hs
+	}
+
+	if p.config.SessionPolicyConfig != nil {
+		p.logger.Debug(
+			"Configuring session policy",
+			zap.String("portal_name", p.config.Name),
+			zap.Int("idle_timeout", p.config.SessionPolicyConfig.IdleTimeout),
+			zap.Int("absolute_lifetime", p.config.SessionPolicyConfig.AbsoluteLifetime),
+		)
+		sp, err := sessionpolicy.NewPolicy(p.config.SessionPolicyConfig)
+		if err != nil {
+			return err
+		}
+		p.sessionPolicy = sp
+	}
+
+	if p.config.UsageConfig != nil {
+		p.logger.Debug(
+			"Configuring usage statistics",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("usage_config", p.config.UsageConfig),
+		)
+		uc, err := usage.NewCollector(p.config.UsageConfig)
+		if err != nil {
+			return err
+		}
+		for _, store := range p.identityStores {
+			uc.AddProvider(store.GetKind())
+		}
+		for _, provider := range p.identityProviders {
+			uc.AddProvider(provider.GetKind())
+		}
+		p.usage = uc
+	}
+
+	if p.config.TokenShaperConfig != nil {
+		p.logger.Debug(
+			"Configuring token claims shaping",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("token_shaper_config", p.config.TokenShaperConfig),
+		)
+		ts, err := shaper.NewShaper(p.config.TokenShaperConfig)
+		if err != nil {
+			return err
+		}
+		p.shaper = ts
+	}
+
+	if p.config.IdentityNormalizationConfig != nil {
+		p.logger.Debug(
+			"Configuring identity normalization",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("identity_normalization_config", p.config.IdentityNormalizationConfig),
+		)
+		nr, err := normalizer.NewNormalizer(p.config.IdentityNormalizationConfig)
+		if err != nil {
+			return err
+		}
+		p.normalizer = nr
+	}
+
+	if p.config.IdentityLinkingConfig != nil {
+		p.logger.Debug(
+			"Configuring identity linking",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("identity_linking_config", p.config.IdentityLinkingConfig),
+		)
+		lm, err := linking.NewManager(p.config.IdentityLinkingConfig)
+		if err != nil {
+			return err
+		}
+		p.linker = lm
+	}
+
+	if p.config.GroupMappingConfig != nil {
+		p.logger.Debug(
+			"Configuring group mapping",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("group_mapping_config", p.config.GroupMappingConfig),
+		)
+		gm, err := groupmap.NewMapper(p.config.GroupMappingConfig)
+		if err != nil {
+			return err
+		}
+		gm.SetLogger(p.logger)
+		p.groupMapper = gm
+	}
+
+	if p.config.PasswordRecoveryConfig != nil {
+		p.logger.Debug(
+			"Configuring password recovery",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("password_recovery_config", p.config.PasswordRecoveryConfig),
+		)
+		rm, err := recovery.NewManager(p.config.PasswordRecoveryConfig)
+		if err != nil {
+			return err
+		}
+		p.recovery = rm
+	}
+
+	if p.config.EmailChangeConfig != nil {
+		p.logger.Debug(
+			"Configuring email change",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("email_change_config", p.config.EmailChangeConfig),
+		)
+		em, err := emailchange.NewManager(p.config.EmailChangeConfig)
+		if err != nil {
+			return err
+		}
+		p.emailChange = em
+	}
+
+	if p.config.AccountDeletionConfig != nil {
+		p.logger.Debug(
+			"Configuring account deletion",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("account_deletion_config", p.config.AccountDeletionConfig),
+		)
+		dm, err := deletion.NewManager(p.config.AccountDeletionConfig)
+		if err != nil {
+			return err
+		}
+		p.deletion = dm
+		p.deletion.Run(p.purgeDeletedAccounts)
+	}
+
+	if p.config.MagicLinkConfig != nil {
+		p.logger.Debug(
+			"Configuring magic link login",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("magic_link_config", p.config.MagicLinkConfig),
+		)
+		mm, err := magiclink.NewManager(p.config.MagicLinkConfig)
+		if err != nil {
+			return err
+		}
+		p.magicLinks = mm
+	}
+
+	if p.config.SignInAlertConfig != nil {
+		p.logger.Debug(
+			"Configuring sign-in alerts",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("sign_in_alert_config", p.config.SignInAlertConfig),
+		)
+		tracker, err := signin.NewTracker(p.config.SignInAlertConfig)
+		if err != nil {
+			return err
+		}
+		p.signIns = tracker
+	}
+
+	if p.config.AccessRequestConfig != nil {
+		p.logger.Debug(
+			"Configuring access requests",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("access_request_config", p.config.AccessRequestConfig),
+		)
+		am, err := accessrequest.NewManager(p.config.AccessRequestConfig)
+		if err != nil {
+			return err
+		}
+		p.accessRequests = am
+		p.accessRequests.Run(p.expireAccessGrants)
+	}
+
+	if p.config.BreakGlassConfig != nil {
+		p.logger.Debug(
+			"Configuring break glass accounts",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("break_glass_config", p.config.BreakGlassConfig),
+		)
+		bm, err := breakglass.NewManager(p.config.BreakGlassConfig)
+		if err != nil {
+			return err
+		}
+		if store := p.getIdentityStoreByRealm(bm.GetRealm()); store == nil || store.GetKind() != "local" {
+			return errors.ErrBreakGlassConfigRealmNotFound.WithArgs(bm.GetRealm())
+		}
+		p.breakGlass = bm
+	}
+
+	p.logger.Debug(
+		"Configuring maintenance switches",
+		zap.String("portal_name", p.config.Name),
+		zap.Any("maintenance_config", p.config.MaintenanceConfig),
+	)
+	mm, err := maintenance.NewManager(p.config.MaintenanceConfig)
+	if err != nil {
+		return err
+	}
+	p.maintenance = mm
+
+	if p.config.LifecycleConfig != nil {
+		p.logger.Debug(
+			"Configuring user lifecycle jobs",
+			zap.String("portal_name", p.config.Name),
+			zap.Any("lifecycle_config", p.config.LifecycleConfig),
+		)
+		lm, err := lifecycle.NewManager(p.config.LifecycleConfig)
+		if err != nil {
+			return err
+		}
+		p.lifecycle = lm
+		p.lifecycle.Run(p.runLifecycleJob)
+	}
+
+	for _, store := range p.identityStores {
+		if store.GetKind() == "local" {
+			p.runRoleExpiry()
+			break
+		}
+	}
+
+	p.logger.Debug(
+		"Configuring profile validation",
+		zap.String("portal_name", p.config.Name),
+		zap.Any("profile_config", p.config.ProfileConfig),
+	)
+	pv, err := profile.NewValidator(p.config.ProfileConfig)
+	if err != nil {
+		return err
+	}
+	p.profile = pv
+
+	if p.config.ClientCredentialsConfig != nil {
+		p.logger.Debug(
+			"Configuring service clients",
+			zap.String("portal_name", p.config.Name),
+			zap.Int("client_count", len(p.config.ClientCredentialsConfig.Clients)),
+		)
+		reg, err := clients.NewRegistry(p.config.ClientCredentialsConfig)
+		if err != nil {
+			return err
+		}
+		p.clients = reg
+	}
+
+	if p.config.OIDCProviderConfig != nil {
+		p.logger.Debug(
+			"Configuring OpenID Connect provider",
+			zap.String("portal_name", p.config.Name),
+			zap.String("issuer", p.config.OIDCProviderConfig.Issuer),
+			zap.Int("client_count", len(p.config.OIDCProviderConfig.Clients)),
+		)
+		op, err := oidc.NewProvider(p.config.OIDCProviderConfig)
+		if err != nil {
+			return err
+		}
+		p.oidc = op
+	}
 	return nil
 }
 
@@ -249,6 +665,9 @@ func (p *Portal) configureCryptoKeyStore() error {
 	if err := accessList.AddRules(ctx, p.config.AccessListConfigs); err != nil {
 		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 	}
+	if err := accessList.CheckExtensions(ctx); err != nil {
+		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
+	}
 
 	p.keystore = kms.NewCryptoKeyStore()
 	p.keystore.SetLogger(p.logger)
@@ -276,6 +695,14 @@ func (p *Portal) configureCryptoKeyStore() error {
 		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 	}
 
+	if err := p.validateKeyRealms(); err != nil {
+		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
+	}
+
+	if p.config.CrossDomainConfig != nil && !p.keystore.HasTokenEncryption() {
+		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, errors.ErrCrossDomainTokenEncryption)
+	}
+
 	p.validator = validator.NewTokenValidator()
 	if err := p.validator.Configure(ctx, p.keystore.GetVerifyKeys(), accessList, p.config.TokenValidatorOptions); err != nil {
 		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
@@ -291,6 +718,24 @@ func (p *Portal) configureCryptoKeyStore() error {
 	return nil
 }
 
+// validateKeyRealms checks that the keys bound to realms have the identity
+// stores or the identity providers of the realms.
+func (p *Portal) validateKeyRealms() error {
+	realms := make(map[string]bool)
+	for _, store := range p.identityStores {
+		realms[store.GetRealm()] = true
+	}
+	for _, provider := range p.identityProviders {
+		realms[provider.GetRealm()] = true
+	}
+	for _, k := range p.keystore.GetKeys() {
+		if k.Config.Realm != "" && !realms[k.Config.Realm] {
+			return errors.ErrCryptoKeyConfigRealmNotFound.WithArgs(k.Config.ID, k.Config.Realm)
+		}
+	}
+	return nil
+}
+
 func (p *Portal) configureLoginOptions() error {
 	p.loginOptions = make(map[string]interface{})
 	p.loginOptions["form_required"] = "no"
@@ -326,11 +771,17 @@ func (p *Portal) configureLoginIcons() error {
 	var entries []*icons.LoginIcon
 
 	for _, store := range p.identityStores {
+		if p.config.RealmDiscoveryConfig.IsHidden(store.GetRealm()) {
+			continue
+		}
 		icon := store.GetLoginIcon()
 		entries = append(entries, icon)
 	}
 
 	for _, provider := range p.identityProviders {
+		if p.config.RealmDiscoveryConfig.IsHidden(provider.GetRealm()) {
+			continue
+		}
 		icon := provider.GetLoginIcon()
 		entries = append(entries, icon)
 	}
@@ -354,6 +805,8 @@ func (p *Portal) configureLoginIcons() error {
 	if len(iconConfigs) == 1 {
 		p.loginOptions["hide_contact_support_link"] = "yes"
 		p.loginOptions["hide_forgot_username_link"] = "yes"
+		p.loginOptions["hide_forgot_password_link"] = "yes"
+		p.loginOptions["hide_magic_link"] = "yes"
 		p.loginOptions["hide_register_link"] = "yes"
 		p.loginOptions["hide_links"] = "yes"
 		for _, iconConfig := range iconConfigs {
@@ -369,6 +822,14 @@ func (p *Portal) configureLoginIcons() error {
 				p.loginOptions["hide_forgot_username_link"] = "no"
 				p.loginOptions["hide_links"] = "no"
 			}
+			if v, exists := iconConfig["password_recovery_enabled"]; exists && v == "yes" && p.config.PasswordRecoveryConfig != nil {
+				p.loginOptions["hide_forgot_password_link"] = "no"
+				p.loginOptions["hide_links"] = "no"
+			}
+			if v, exists := iconConfig["magic_link_enabled"]; exists && v == "yes" && p.config.MagicLinkConfig != nil {
+				p.loginOptions["hide_magic_link"] = "no"
+				p.loginOptions["hide_links"] = "no"
+			}
 		}
 	}
 
@@ -390,6 +851,9 @@ func (p *Portal) configureIdentityStoreLogin() error {
 	var stores []map[string]string
 
 	for _, store := range p.identityStores {
+		if p.config.RealmDiscoveryConfig.IsHidden(store.GetRealm()) {
+			continue
+		}
 		cfg := make(map[string]string)
 		cfg["realm"] = store.GetRealm()
 		cfg["default"] = "no"
@@ -447,12 +911,7 @@ func (p *Portal) configureIdentityProviderLogin() error {
 	for _, provider := range p.identityProviders {
 		icon := provider.GetLoginIcon()
 		icon.SetRealm(provider.GetRealm())
-		switch provider.GetKind() {
-		case "oauth":
-			icon.SetEndpoint(path.Join(provider.GetKind()+"2", provider.GetRealm()))
-		default:
-			icon.SetEndpoint(path.Join(provider.GetKind(), provider.GetRealm()))
-		}
+		icon.SetEndpoint(getIdentityProviderEndpoint(provider))
 	}
 
 	p.loginOptions["authenticators_required"] = "yes"
@@ -521,6 +980,12 @@ func (p *Portal) configureUserInterface() error {
 		p.ui.Realms = p.config.UI.Realms
 	}
 
+	for _, b := range p.config.UI.Branding {
+		if err := p.ui.AddBranding(b); err != nil {
+			return errors.ErrUserInterfaceBrandingAddFailed.WithArgs(p.config.Name, b.Realm, err)
+		}
+	}
+
 	if p.config.UI.Theme == "" {
 		p.config.UI.Theme = "basic"
 	}
@@ -550,6 +1015,44 @@ func (p *Portal) configureUserInterface() error {
 		}
 	}
 
+	if p.config.UI.TemplatesDir != "" {
+		tmplNames, err := p.ui.AddTemplatesDir(p.config.UI.TemplatesDir, p.config.UI.Theme)
+		if err != nil {
+			return errors.ErrUserInterfaceTemplatesDirAddFailed.WithArgs(p.config.Name, p.config.UI.TemplatesDir, err)
+		}
+		p.logger.Debug(
+			"Configuring authentication user interface template overrides",
+			zap.String("portal_name", p.config.Name),
+			zap.String("portal_id", p.id),
+			zap.String("templates_dir", p.config.UI.TemplatesDir),
+			zap.Strings("template_names", tmplNames),
+		)
+		if p.config.UI.TemplatesReloadInterval >= 0 {
+			interval := defaultTemplatesReloadInterval
+			if p.config.UI.TemplatesReloadInterval > 0 {
+				interval = time.Duration(p.config.UI.TemplatesReloadInterval) * time.Second
+			}
+			p.ui.WatchTemplatesDir(interval, func(tmplNames []string, err error) {
+				if err != nil {
+					p.logger.Warn(
+						"Failed reloading authentication user interface templates",
+						zap.String("portal_name", p.config.Name),
+						zap.String("portal_id", p.id),
+						zap.Strings("template_names", tmplNames),
+						zap.Error(err),
+					)
+					return
+				}
+				p.logger.Info(
+					"Reloaded authentication user interface templates",
+					zap.String("portal_name", p.config.Name),
+					zap.String("portal_id", p.id),
+					zap.Strings("template_names", tmplNames),
+				)
+			})
+		}
+	}
+
 	for tmplName, tmplPath := range p.config.UI.Templates {
 		p.logger.Debug(
 			"Configuring non-default authentication user interface templates",
@@ -628,6 +1131,51 @@ func (p *Portal) AddUserRegistry(userRegistry registry.UserRegistry) error {
 	return nil
 }
 
+// TransferSessions hands the active sessions and sandboxes of a previous
+// instance of the portal over to the portal. It allows replacing a portal
+// during reconfiguration without signing its users out. The previous
+// instance stops reloading its templates. The source addresses banned by
+// the network filter remain banned, and the sessions renewable.
+func (p *Portal) TransferSessions(prev *Portal) {
+	if prev == nil || prev == p {
+		return
+	}
+	p.sessions.Stop()
+	p.sandboxes.Stop()
+	prev.ui.StopWatchingTemplatesDir()
+	if prev.deletion != nil {
+		prev.deletion.Stop()
+	}
+	if prev.accessRequests != nil {
+		prev.accessRequests.Stop()
+	}
+	prev.stopRoleExpiry()
+	if prev.lifecycle != nil {
+		prev.lifecycle.Stop()
+	}
+	p.sessions = prev.sessions
+	p.sandboxes = prev.sandboxes
+	if p.netFilter != nil {
+		p.netFilter.Inherit(prev.netFilter)
+	}
+	if p.sessionPolicy != nil {
+		p.sessionPolicy.Inherit(prev.sessionPolicy)
+	}
+	if p.maintenance != nil {
+		p.maintenance.Inherit(prev.maintenance)
+	}
+	if p.lifecycle != nil {
+		p.lifecycle.Inherit(prev.lifecycle)
+	}
+
+	p.logger.Debug(
+		"Transferred sessions",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.String("prev_portal_id", prev.id),
+	)
+}
+
 // GetIdentityStoreNames returns a list of existing identity stores.
 func (p *Portal) GetIdentityStoreNames() map[string]string {
 	var m map[string]string
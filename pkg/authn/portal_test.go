@@ -146,6 +146,36 @@ func TestNewPortal(t *testing.T) {
               }
             }`,
 		},
+		{
+			name: "test new portal with key bound to unknown realm",
+			loggerFunc: func() *zap.Logger {
+				return logutil.NewLogger()
+			},
+			configFunc: func() *PortalConfig {
+				return &PortalConfig{
+					Name: "myportal",
+					IdentityStores: []string{
+						"local_backend",
+					},
+				}
+			},
+			identityStoreConfigs: []*ids.IdentityStoreConfig{
+				{
+					Name: "local_backend",
+					Kind: "local",
+					Params: map[string]interface{}{
+						"path":  dbPath,
+						"realm": "local",
+					},
+				},
+			},
+			cryptoRawConfigs: []string{
+				"key k1 sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286",
+				"key k1 realm contoso",
+			},
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyStoreConfig.WithArgs("myportal", errors.ErrCryptoKeyConfigRealmNotFound.WithArgs("k1", "contoso")),
+		},
 	}
 
 	for _, tc := range testcases {
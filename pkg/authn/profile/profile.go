@@ -0,0 +1,177 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var supportedFields = []string{"name", "phone", "language"}
+
+// defaultRules are the validation rules applied to the fields without
+// explicit configuration.
+var defaultRules = map[string]*FieldRule{
+	"name": {
+		Field:     "name",
+		MaxLength: 128,
+	},
+	"phone": {
+		Field:     "phone",
+		MaxLength: 16,
+		Pattern:   `^\+[1-9][0-9]{6,14}$`,
+	},
+	"language": {
+		Field:     "language",
+		MaxLength: 35,
+		Pattern:   `^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`,
+	},
+}
+
+// Config holds the configuration of the self-service profile management.
+type Config struct {
+	// The validation rules of the profile fields. The fields without a rule
+	// are validated with the built-in defaults.
+	Rules []*FieldRule `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// FieldRule is the validation rule of a profile field.
+type FieldRule struct {
+	// The name of the field, i.e. name, phone, or language.
+	Field string `json:"field,omitempty" xml:"field,omitempty" yaml:"field,omitempty"`
+	// Disallows the changes of the field by the users.
+	ReadOnly bool `json:"read_only,omitempty" xml:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// Disallows the clearing of the field.
+	Required bool `json:"required,omitempty" xml:"required,omitempty" yaml:"required,omitempty"`
+	// The maximum number of characters in the field value.
+	MaxLength int `json:"max_length,omitempty" xml:"max_length,omitempty" yaml:"max_length,omitempty"`
+	// The regular expression the field value must match.
+	Pattern string `json:"pattern,omitempty" xml:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// The list of allowed field values.
+	Values []string `json:"values,omitempty" xml:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// Validator validates the changes of profile fields.
+type Validator struct {
+	rules    map[string]*FieldRule
+	patterns map[string]*regexp.Regexp
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	seen := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		if rule.Field == "" {
+			return errors.ErrProfileConfigFieldEmpty
+		}
+		if _, exists := defaultRules[rule.Field]; !exists {
+			return errors.ErrProfileConfigFieldUnsupported.WithArgs(rule.Field)
+		}
+		if seen[rule.Field] {
+			return errors.ErrProfileConfigFieldDuplicate.WithArgs(rule.Field)
+		}
+		seen[rule.Field] = true
+		if rule.MaxLength < 0 {
+			return errors.ErrProfileConfigMaxLength.WithArgs(rule.Field, rule.MaxLength)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return errors.ErrProfileConfigPattern.WithArgs(rule.Field, rule.Pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewValidator returns an instance of Validator. The nil config results in
+// the built-in validation rules.
+func NewValidator(cfg *Config) (*Validator, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	v := &Validator{
+		rules:    make(map[string]*FieldRule),
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	for k, rule := range defaultRules {
+		v.rules[k] = rule
+	}
+	for _, rule := range cfg.Rules {
+		v.rules[rule.Field] = rule
+	}
+	for k, rule := range v.rules {
+		if rule.Pattern == "" {
+			continue
+		}
+		v.patterns[k] = regexp.MustCompile(rule.Pattern)
+	}
+	return v, nil
+}
+
+// GetFields returns the names of the fields the users may change.
+func (v *Validator) GetFields() []string {
+	var fields []string
+	for _, k := range supportedFields {
+		if v.rules[k].ReadOnly {
+			continue
+		}
+		fields = append(fields, k)
+	}
+	return fields
+}
+
+// Validate checks the new value of a profile field. The value is trimmed
+// prior to the validation. The empty value clears the field.
+func (v *Validator) Validate(field, value string) (string, error) {
+	rule, exists := v.rules[field]
+	if !exists {
+		return "", errors.ErrProfileConfigFieldUnsupported.WithArgs(field)
+	}
+	if rule.ReadOnly {
+		return "", errors.ErrProfileFieldReadOnly.WithArgs(field)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if rule.Required {
+			return "", errors.ErrProfileFieldRequired.WithArgs(field)
+		}
+		return value, nil
+	}
+	if rule.MaxLength > 0 && utf8.RuneCountInString(value) > rule.MaxLength {
+		return "", errors.ErrProfileFieldTooLong.WithArgs(field, rule.MaxLength)
+	}
+	if p, exists := v.patterns[field]; exists && !p.MatchString(value) {
+		return "", errors.ErrProfileFieldPattern.WithArgs(field)
+	}
+	if len(rule.Values) > 0 {
+		var found bool
+		for _, s := range rule.Values {
+			if s == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", errors.ErrProfileFieldNotListed.WithArgs(field, value)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "empty config",
+			config: &Config{},
+		},
+		{
+			name: "config with language allow list",
+			config: &Config{Rules: []*FieldRule{
+				{Field: "language", Values: []string{"en", "fr"}},
+			}},
+		},
+		{
+			name:      "config with empty field name",
+			config:    &Config{Rules: []*FieldRule{{}}},
+			shouldErr: true,
+			err:       errors.ErrProfileConfigFieldEmpty,
+		},
+		{
+			name:      "config with unsupported field",
+			config:    &Config{Rules: []*FieldRule{{Field: "title"}}},
+			shouldErr: true,
+			err:       errors.ErrProfileConfigFieldUnsupported.WithArgs("title"),
+		},
+		{
+			name:      "config with duplicate field",
+			config:    &Config{Rules: []*FieldRule{{Field: "name"}, {Field: "name"}}},
+			shouldErr: true,
+			err:       errors.ErrProfileConfigFieldDuplicate.WithArgs("name"),
+		},
+		{
+			name:      "config with negative max length",
+			config:    &Config{Rules: []*FieldRule{{Field: "name", MaxLength: -1}}},
+			shouldErr: true,
+			err:       errors.ErrProfileConfigMaxLength.WithArgs("name", -1),
+		},
+		{
+			name:      "config with invalid pattern",
+			config:    &Config{Rules: []*FieldRule{{Field: "phone", Pattern: "["}}},
+			shouldErr: true,
+			err:       errors.ErrProfileConfigPattern.WithArgs("phone", "[", "error parsing regexp: missing closing ]: `[`"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	v, err := NewValidator(&Config{Rules: []*FieldRule{
+		{Field: "name", Required: true, MaxLength: 10},
+		{Field: "language", Values: []string{"en", "fr"}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		name      string
+		field     string
+		value     string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{name: "valid name", field: "name", value: " John Smith ", want: "John Smith"},
+		{name: "long name", field: "name", value: "John Jacob Smith", shouldErr: true, err: errors.ErrProfileFieldTooLong.WithArgs("name", 10)},
+		{name: "required name", field: "name", value: " ", shouldErr: true, err: errors.ErrProfileFieldRequired.WithArgs("name")},
+		{name: "valid phone", field: "phone", value: "+14155550100", want: "+14155550100"},
+		{name: "cleared phone", field: "phone", value: ""},
+		{name: "invalid phone", field: "phone", value: "555-0100", shouldErr: true, err: errors.ErrProfileFieldPattern.WithArgs("phone")},
+		{name: "allowed language", field: "language", value: "fr", want: "fr"},
+		{name: "disallowed language", field: "language", value: "de", shouldErr: true, err: errors.ErrProfileFieldNotListed.WithArgs("language", "de")},
+		{name: "unsupported field", field: "title", value: "CEO", shouldErr: true, err: errors.ErrProfileConfigFieldUnsupported.WithArgs("title")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := v.Validate(tc.field, tc.value)
+			if tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjects(t, "value", tc.want, got)
+		})
+	}
+
+	v, err = NewValidator(&Config{Rules: []*FieldRule{{Field: "name", ReadOnly: true}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "editable fields", []string{"phone", "language"}, v.GetFields())
+	_, err = v.Validate("name", "John Smith")
+	tests.EvalErrWithLog(t, err, "read-only name", true, errors.ErrProfileFieldReadOnly.WithArgs("name"), nil)
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultInterval    = 60
+)
+
+// Config holds the configuration of the limits applied to the login
+// attempts. When a value is zero, the default applies.
+type Config struct {
+	// The maximum number of attempts per source address and per account
+	// within the interval. Defaults to 5.
+	MaxAttempts int `json:"max_attempts,omitempty" xml:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// The interval in seconds. Defaults to 1 minute.
+	Interval int `json:"interval,omitempty" xml:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+type window struct {
+	attempts  int
+	expiresAt time.Time
+}
+
+// Limiter counts the attempts per key within fixed windows and rejects the
+// attempts exceeding the limit.
+type Limiter struct {
+	mu          sync.Mutex
+	maxAttempts int
+	interval    time.Duration
+	windows     map[string]*window
+	prunedAt    time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MaxAttempts < 0 {
+		return errors.ErrRateLimitConfigMaxAttempts.WithArgs(cfg.MaxAttempts)
+	}
+	if cfg.Interval < 0 {
+		return errors.ErrRateLimitConfigInterval.WithArgs(cfg.Interval)
+	}
+	return nil
+}
+
+// NewLimiter returns an instance of Limiter. When the config is nil, the
+// defaults apply.
+func NewLimiter(cfg *Config) (*Limiter, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Limiter{
+		maxAttempts: defaultMaxAttempts,
+		interval:    time.Duration(defaultInterval) * time.Second,
+		windows:     make(map[string]*window),
+	}
+	if cfg.MaxAttempts > 0 {
+		l.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.Interval > 0 {
+		l.interval = time.Duration(cfg.Interval) * time.Second
+	}
+	return l, nil
+}
+
+// Allow records an attempt for each of the keys. When any of the keys
+// exhausted its attempts, the attempt is not recorded and the number of
+// seconds until the key accepts the attempts again is returned with an
+// error.
+func (l *Limiter) Allow(keys ...string) (int, error) {
+	return l.allow(time.Now(), keys...)
+}
+
+func (l *Limiter) allow(now time.Time, keys ...string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	for _, k := range keys {
+		w, exists := l.windows[k]
+		if !exists || now.After(w.expiresAt) {
+			continue
+		}
+		if w.attempts >= l.maxAttempts {
+			retryAfter := int(math.Ceil(w.expiresAt.Sub(now).Seconds()))
+			return retryAfter, errors.ErrRateLimitExceeded.WithArgs(k)
+		}
+	}
+	for _, k := range keys {
+		w, exists := l.windows[k]
+		if !exists || now.After(w.expiresAt) {
+			w = &window{expiresAt: now.Add(l.interval)}
+			l.windows[k] = w
+		}
+		w.attempts++
+	}
+	return 0, nil
+}
+
+// prune removes the expired windows at most once per interval.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.prunedAt) < l.interval {
+		return
+	}
+	for k, w := range l.windows {
+		if now.After(w.expiresAt) {
+			delete(l.windows, k)
+		}
+	}
+	l.prunedAt = now
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewLimiter(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{name: "nil config"},
+		{name: "custom config", config: &Config{MaxAttempts: 3, Interval: 30}},
+		{
+			name:      "negative max attempts",
+			config:    &Config{MaxAttempts: -1},
+			shouldErr: true,
+			err:       errors.ErrRateLimitConfigMaxAttempts.WithArgs(-1),
+		},
+		{
+			name:      "negative interval",
+			config:    &Config{Interval: -1},
+			shouldErr: true,
+			err:       errors.ErrRateLimitConfigInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewLimiter(tc.config)
+			tests.EvalErrWithLog(t, err, "NewLimiter", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l, err := NewLimiter(&Config{MaxAttempts: 2, Interval: 60})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+
+	testcases := []struct {
+		name       string
+		offset     time.Duration
+		keys       []string
+		retryAfter int
+		shouldErr  bool
+		err        error
+	}{
+		{name: "first attempt", keys: []string{"addr:10.0.0.1", "user:local/jsmith"}},
+		{name: "second attempt", offset: time.Second, keys: []string{"addr:10.0.0.1", "user:local/jsmith"}},
+		{
+			name:       "third attempt from same address",
+			offset:     10 * time.Second,
+			keys:       []string{"addr:10.0.0.1", "user:local/foo"},
+			retryAfter: 50,
+			shouldErr:  true,
+			err:        errors.ErrRateLimitExceeded.WithArgs("addr:10.0.0.1"),
+		},
+		{
+			name:       "third attempt for same account",
+			offset:     10 * time.Second,
+			keys:       []string{"addr:10.0.0.2", "user:local/jsmith"},
+			retryAfter: 50,
+			shouldErr:  true,
+			err:        errors.ErrRateLimitExceeded.WithArgs("user:local/jsmith"),
+		},
+		{name: "rejected attempts are not counted", offset: 10 * time.Second, keys: []string{"user:local/foo"}},
+		{name: "attempt after interval", offset: 61 * time.Second, keys: []string{"addr:10.0.0.1", "user:local/jsmith"}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			retryAfter, err := l.allow(now.Add(tc.offset), tc.keys...)
+			tests.EvalObjectsWithLog(t, "retry after", tc.retryAfter, retryAfter, msgs)
+			tests.EvalErrWithLog(t, err, "Allow", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
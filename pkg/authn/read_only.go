@@ -0,0 +1,72 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+const readOnlyMessage = "Account changes are temporarily unavailable due to maintenance. Please try again later."
+
+// readOnlyStore is implemented by the identity stores blocking the account
+// changes, e.g. during a backup or a migration of the database.
+type readOnlyStore interface {
+	IsReadOnly() bool
+}
+
+// isReadOnlyStore returns true when the identity store blocks the account
+// changes.
+func isReadOnlyStore(store ids.IdentityStore) bool {
+	if s, ok := store.(readOnlyStore); ok {
+		return s.IsReadOnly()
+	}
+	return false
+}
+
+// isRegistrationReadOnly returns true when the identity store associated
+// with the user registry blocks the account changes.
+func (p *Portal) isRegistrationReadOnly() bool {
+	if p.userRegistry == nil {
+		return false
+	}
+	storeName := p.userRegistry.GetIdentityStoreName()
+	for _, store := range p.identityStores {
+		if store.GetName() == storeName {
+			return isReadOnlyStore(store)
+		}
+	}
+	return false
+}
+
+// handleHTTPReadOnly responds to the requests for the account changes in the
+// read-only identity store with 503 Service Unavailable page.
+func (p *Portal) handleHTTPReadOnly(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, title string) error {
+	p.disableClientCache(w)
+	resp := p.getUIArgs(r, rr, nil)
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.PageTitle = title
+	resp.Data["message"] = readOnlyMessage
+	resp.Data["authenticated"] = rr.Response.Authenticated
+	resp.Data["go_back_url"] = rr.Upstream.BasePath
+	content, err := p.ui.Render("generic", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusServiceUnavailable, content.Bytes())
+}
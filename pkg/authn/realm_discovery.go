@@ -0,0 +1,38 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func (p *Portal) configureRealmDiscovery() error {
+	if p.config.RealmDiscoveryConfig == nil {
+		return nil
+	}
+	for _, realm := range p.config.RealmDiscoveryConfig.GetRealms() {
+		if p.getIdentityStoreByRealm(realm) == nil && p.getIdentityProviderByRealm(realm) == nil {
+			return errors.ErrRealmDiscoveryRealmNotFound.WithArgs(realm)
+		}
+	}
+	p.logger.Debug(
+		"Configured realm discovery",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.Any("realm_discovery_config", p.config.RealmDiscoveryConfig),
+	)
+	return nil
+}
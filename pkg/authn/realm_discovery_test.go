@@ -0,0 +1,83 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/realmdiscovery"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestRealmDiscovery(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		RealmDiscoveryConfig: &realmdiscovery.Config{
+			Rules: []*realmdiscovery.Rule{
+				{Domains: []string{"gmail.com"}, Realm: "backup"},
+			},
+			HiddenRealms: []string{"backup"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hidden realm is not offered on the login page.
+	var realms []string
+	for _, entry := range p.loginOptions["realms"].([]map[string]string) {
+		realms = append(realms, entry["realm"])
+	}
+	tests.EvalObjects(t, "login realms", []string{"local"}, realms)
+	tests.EvalObjects(t, "login authenticators", 1, len(p.loginOptions["authenticators"].([]map[string]string)))
+
+	// The user with the matching email address is routed to the hidden realm.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/login", nil)
+	rr := requests.NewRequest()
+	rr.Upstream.BasePath = "/"
+	identity := map[string]string{"user": tests.TestEmail1, "realm": "local"}
+	if err := p.handleHTTPLoginIdentity(context.Background(), w, r, rr, identity); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr := getTestSandboxUser(t, p, w)
+	tests.EvalObjects(t, "discovered realm", "backup", usr.Authenticator.Realm)
+	tests.EvalObjects(t, "discovered user", tests.TestUser1, usr.Claims.Subject)
+
+	// The login hint routes the user past the login screen.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/login?login_hint="+tests.TestEmail1, nil)
+	rr = requests.NewRequest()
+	rr.Upstream.BasePath = "/"
+	if err := p.handleHTTPLoginScreen(context.Background(), w, r, rr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr = getTestSandboxUser(t, p, w)
+	tests.EvalObjects(t, "login hint realm", "backup", usr.Authenticator.Realm)
+}
+
+func TestRealmDiscoveryRealmNotFound(t *testing.T) {
+	_, err := newTestMultiRealmPortal(t, &PortalConfig{
+		RealmDiscoveryConfig: &realmdiscovery.Config{
+			Rules: []*realmdiscovery.Rule{
+				{Domains: []string{"corp.com"}, Realm: "azure"},
+			},
+		},
+	})
+	tests.EvalErrWithLog(t, err, "NewPortal", true, errors.ErrRealmDiscoveryRealmNotFound.WithArgs("azure"), nil)
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realmdiscovery
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config holds the rules routing the users to the identity stores and the
+// identity providers by the domains of their email addresses, i.e. the home
+// realm discovery. For example, the users of corp.com are routed to the
+// Azure AD provider of the corporation, while the users of gmail.com use
+// the local login.
+type Config struct {
+	Rules []*Rule `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+	// HiddenRealms are the realms of the identity stores and providers not
+	// shown on the login page. The users reach them through the rules,
+	// including the rules matching the login_hint query parameter.
+	HiddenRealms []string `json:"hidden_realms,omitempty" xml:"hidden_realms,omitempty" yaml:"hidden_realms,omitempty"`
+}
+
+// Rule routes the users with the email addresses in the domains to the
+// realm.
+type Rule struct {
+	// Domains are the email domains, e.g. corp.com. The domain prefixed
+	// with the wildcard, e.g. *.corp.com, matches the subdomains.
+	Domains []string `json:"domains,omitempty" xml:"domains,omitempty" yaml:"domains,omitempty"`
+	// Realm is the realm of the identity store or the identity provider.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for i, rule := range cfg.Rules {
+		if rule.Realm == "" {
+			return errors.ErrRealmDiscoveryConfigRealmEmpty.WithArgs(i)
+		}
+		if len(rule.Domains) < 1 {
+			return errors.ErrRealmDiscoveryConfigDomainsEmpty.WithArgs(i)
+		}
+		for j, domain := range rule.Domains {
+			domain = strings.ToLower(strings.TrimSpace(domain))
+			name := strings.TrimPrefix(domain, "*.")
+			if name == "" || strings.ContainsAny(name, "@*/ ") {
+				return errors.ErrRealmDiscoveryConfigDomain.WithArgs(i, rule.Domains[j])
+			}
+			rule.Domains[j] = domain
+		}
+	}
+	for _, realm := range cfg.HiddenRealms {
+		if realm == "" {
+			return errors.ErrRealmDiscoveryConfigHiddenRealm
+		}
+	}
+	return nil
+}
+
+// Match returns the realm of the first rule matching the domain of the
+// email address.
+func (cfg *Config) Match(email string) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return "", false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[i+1:]))
+	if domain == "" {
+		return "", false
+	}
+	for _, rule := range cfg.Rules {
+		for _, pattern := range rule.Domains {
+			if strings.HasPrefix(pattern, "*.") {
+				if strings.HasSuffix(domain, pattern[1:]) {
+					return rule.Realm, true
+				}
+				continue
+			}
+			if domain == pattern {
+				return rule.Realm, true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsHidden returns true when the realm is not shown on the login page.
+func (cfg *Config) IsHidden(realm string) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, s := range cfg.HiddenRealms {
+		if s == realm {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRealms returns the realms referenced by the configuration.
+func (cfg *Config) GetRealms() []string {
+	var realms []string
+	seen := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		if !seen[rule.Realm] {
+			seen[rule.Realm] = true
+			realms = append(realms, rule.Realm)
+		}
+	}
+	for _, realm := range cfg.HiddenRealms {
+		if !seen[realm] {
+			seen[realm] = true
+			realms = append(realms, realm)
+		}
+	}
+	return realms
+}
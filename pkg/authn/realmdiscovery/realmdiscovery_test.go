@@ -0,0 +1,122 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realmdiscovery
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Rules: []*Rule{
+					{Domains: []string{"corp.com", "*.corp.com"}, Realm: "azure"},
+				},
+				HiddenRealms: []string{"azure"},
+			},
+		},
+		{
+			name: "empty realm",
+			config: &Config{
+				Rules: []*Rule{{Domains: []string{"corp.com"}}},
+			},
+			shouldErr: true,
+			err:       errors.ErrRealmDiscoveryConfigRealmEmpty.WithArgs(0),
+		},
+		{
+			name: "no domains",
+			config: &Config{
+				Rules: []*Rule{{Realm: "azure"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrRealmDiscoveryConfigDomainsEmpty.WithArgs(0),
+		},
+		{
+			name: "invalid domain",
+			config: &Config{
+				Rules: []*Rule{{Domains: []string{"jsmith@corp.com"}, Realm: "azure"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrRealmDiscoveryConfigDomain.WithArgs(0, "jsmith@corp.com"),
+		},
+		{
+			name: "empty wildcard domain",
+			config: &Config{
+				Rules: []*Rule{{Domains: []string{"*."}, Realm: "azure"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrRealmDiscoveryConfigDomain.WithArgs(0, "*."),
+		},
+		{
+			name: "empty hidden realm",
+			config: &Config{
+				HiddenRealms: []string{""},
+			},
+			shouldErr: true,
+			err:       errors.ErrRealmDiscoveryConfigHiddenRealm,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cfg := &Config{
+		Rules: []*Rule{
+			{Domains: []string{"Corp.com", "*.corp.com"}, Realm: "azure"},
+			{Domains: []string{"gmail.com"}, Realm: "local"},
+		},
+		HiddenRealms: []string{"azure"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		input string
+		realm string
+		found bool
+	}{
+		{input: "jsmith@corp.com", realm: "azure", found: true},
+		{input: "jsmith@CORP.COM", realm: "azure", found: true},
+		{input: "jsmith@eu.corp.com", realm: "azure", found: true},
+		{input: "jsmith@notcorp.com", found: false},
+		{input: "jsmith@gmail.com", realm: "local", found: true},
+		{input: "jsmith", found: false},
+		{input: "jsmith@", found: false},
+	}
+	for _, tc := range testcases {
+		realm, found := cfg.Match(tc.input)
+		tests.EvalObjects(t, tc.input, map[string]interface{}{"realm": tc.realm, "found": tc.found}, map[string]interface{}{"realm": realm, "found": found})
+	}
+	tests.EvalObjects(t, "hidden", true, cfg.IsHidden("azure"))
+	tests.EvalObjects(t, "visible", false, cfg.IsHidden("local"))
+	tests.EvalObjects(t, "realms", []string{"azure", "local"}, cfg.GetRealms())
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recovery
+
+import (
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/onetime"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultTokenLifetime = 900
+	defaultMaxRequests   = 3
+	defaultWindow        = 3600
+)
+
+// Config holds the configuration of the self-service password recovery.
+type Config struct {
+	// The email provider used to deliver password reset links.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The number of seconds a password reset link is valid for.
+	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+	// The maximum number of password reset requests per email address and
+	// per source IP address within the throttling window.
+	MaxRequests int `json:"max_requests,omitempty" xml:"max_requests,omitempty" yaml:"max_requests,omitempty"`
+	// The throttling window in seconds.
+	Window int `json:"window,omitempty" xml:"window,omitempty" yaml:"window,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// Token is the payload of a password reset link.
+type Token struct {
+	Realm    string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Manager issues and redeems password reset tokens and throttles password
+// reset requests.
+type Manager struct {
+	config *Config
+	issuer *onetime.Issuer
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrRecoveryConfigEmailProvider
+	}
+	if cfg.TokenLifetime < 0 {
+		return errors.ErrRecoveryConfigTokenLifetime.WithArgs(cfg.TokenLifetime)
+	}
+	if cfg.MaxRequests < 0 {
+		return errors.ErrRecoveryConfigMaxRequests.WithArgs(cfg.MaxRequests)
+	}
+	if cfg.Window < 0 {
+		return errors.ErrRecoveryConfigWindow.WithArgs(cfg.Window)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of password reset links.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewManager returns an instance of Manager. The tokens are signed with
+// a random key generated at startup, i.e. the outstanding links become
+// invalid after a restart.
+func NewManager(cfg *Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	issuer, err := onetime.NewIssuer(&onetime.Config{
+		TokenLifetime: getValue(cfg.TokenLifetime, defaultTokenLifetime),
+		MaxRequests:   getValue(cfg.MaxRequests, defaultMaxRequests),
+		Window:        getValue(cfg.Window, defaultWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		config: cfg,
+		issuer: issuer,
+	}
+	return m, nil
+}
+
+// GetTokenLifetime returns the lifetime of password reset tokens.
+func (m *Manager) GetTokenLifetime() time.Duration {
+	return m.issuer.GetTokenLifetime()
+}
+
+// Allow records a password reset request for each of the provided keys,
+// e.g. email and source IP addresses. It returns an error when any of the
+// keys exceeded the number of requests allowed within the window.
+func (m *Manager) Allow(keys ...string) error {
+	return m.issuer.Allow(keys...)
+}
+
+// IssueToken returns a signed password reset token.
+func (m *Manager) IssueToken(realm, username, email string) (string, error) {
+	return m.issuer.Issue(&Token{
+		Realm:    realm,
+		Username: username,
+		Email:    email,
+	})
+}
+
+// VerifyToken checks the signature, expiry, and prior use of a password
+// reset token and returns its payload.
+func (m *Manager) VerifyToken(s string) (*Token, error) {
+	tkn := &Token{}
+	if err := m.issuer.Verify(s, tkn); err != nil {
+		return nil, err
+	}
+	if tkn.Email == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return tkn, nil
+}
+
+// RedeemToken verifies a password reset token and marks it used.
+func (m *Manager) RedeemToken(s string) (*Token, error) {
+	tkn := &Token{}
+	if err := m.issuer.Redeem(s, tkn); err != nil {
+		return nil, err
+	}
+	if tkn.Email == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return tkn, nil
+}
+
+func getValue(v, defaultValue int) int {
+	if v > 0 {
+		return v
+	}
+	return defaultValue
+}
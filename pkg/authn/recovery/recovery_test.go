@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recovery
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EmailProvider: "default", TokenLifetime: 600},
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrRecoveryConfigEmailProvider,
+		},
+		{
+			name:      "config with negative token lifetime",
+			config:    &Config{EmailProvider: "default", TokenLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrRecoveryConfigTokenLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max requests",
+			config:    &Config{EmailProvider: "default", MaxRequests: -1},
+			shouldErr: true,
+			err:       errors.ErrRecoveryConfigMaxRequests.WithArgs(-1),
+		},
+		{
+			name:      "config with negative window",
+			config:    &Config{EmailProvider: "default", Window: -1},
+			shouldErr: true,
+			err:       errors.ErrRecoveryConfigWindow.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	m, err := NewManager(&Config{EmailProvider: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "default lifetime", 15*time.Minute, m.GetTokenLifetime())
+	token, err := m.IssueToken("local", "jsmith", "jsmith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tkn, err := m.VerifyToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "token", &Token{Realm: "local", Username: "jsmith", Email: "jsmith@localhost"}, tkn)
+	if _, err := m.RedeemToken(token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.RedeemToken(token)
+	tests.EvalErrWithLog(t, err, "used token", true, errors.ErrOneTimeTokenUsed, nil)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{EmailProvider: "default"}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Notify(map[string]string{
+		"email":        "jsmith@localhost",
+		"username":     "jsmith",
+		"recovery_url": "https://localhost/recover?token=foo",
+		"lifetime":     "15m0s",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 1, len(files))
+	b, _ := ioutil.ReadFile(files[0])
+	if !strings.Contains(string(b), "Subject: Password Reset Request") {
+		t.Fatalf("unexpected message: %s", b)
+	}
+
+	cfg = &Config{EmailProvider: "foo"}
+	cfg.SetMessaging(msgCfg)
+	err = cfg.ValidateMessaging()
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// getQueryRedirectURL returns the value of the redirect cookie. The value
+// is either the raw URL in the "redirect_url" query parameter or the
+// signed token in the "redirect_token" one.
+func (p *Portal) getQueryRedirectURL(r *http.Request, rr *requests.Request) (string, bool) {
+	q := r.URL.Query()
+	if token := q.Get("redirect_token"); token != "" && p.redirectPolicy != nil && p.redirectPolicy.SigningEnabled() {
+		if _, err := p.redirectPolicy.Verify(token); err != nil {
+			p.rejectRedirect(r, rr, err)
+			return "", false
+		}
+		// The token is kept as is. It is verified again when the redirect
+		// cookie is consumed.
+		return token, true
+	}
+	redirectURL, exists := q["redirect_url"]
+	if !exists {
+		return "", false
+	}
+	if _, err := p.resolveRedirectURL(r, rr, redirectURL[0]); err != nil {
+		return "", false
+	}
+	return util.StripQueryParam(redirectURL[0], "login_hint"), true
+}
+
+// getCookieRedirectURL returns the URL the redirect cookie points to,
+// provided the redirect policy allows it.
+func (p *Portal) getCookieRedirectURL(r *http.Request, rr *requests.Request, s string) (*url.URL, error) {
+	s, err := p.resolveRedirectURL(r, rr, s)
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(s)
+}
+
+// resolveRedirectURL returns the URL the redirect cookie or query parameter
+// points to. The value is either the URL allowed by the redirect policy or
+// the signed token.
+func (p *Portal) resolveRedirectURL(r *http.Request, rr *requests.Request, s string) (string, error) {
+	if p.redirectPolicy == nil {
+		return s, nil
+	}
+	if p.redirectPolicy.SigningEnabled() {
+		if u, err := p.redirectPolicy.Verify(s); err == nil {
+			return u, nil
+		}
+	}
+	if err := p.redirectPolicy.Check(s, r.Host); err != nil {
+		p.rejectRedirect(r, rr, err)
+		return "", err
+	}
+	return s, nil
+}
+
+func (p *Portal) rejectRedirect(r *http.Request, rr *requests.Request, err error) {
+	p.logger.Warn(
+		"redirect rejected",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("source_address", addrutil.GetSourceAddress(r)),
+		zap.Error(err),
+	)
+	p.recordUsage("redirect/reject")
+}
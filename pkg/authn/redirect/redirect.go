@@ -0,0 +1,242 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// MinSigningKeyLength is the minimum length of the key signing the
+	// redirect tokens.
+	MinSigningKeyLength = 32
+
+	defaultTokenLifetime = 3600
+)
+
+// Config holds the configuration of the policy applied to the URLs the
+// users are redirected to after the authentication. The relative URLs and
+// the URLs pointing to the host of the portal are always allowed.
+type Config struct {
+	// The hosts the users may be redirected to. The host prefixed with
+	// "*." matches its subdomains, e.g. "*.contoso.com".
+	AllowedHosts []string `json:"allowed_hosts,omitempty" xml:"allowed_hosts,omitempty" yaml:"allowed_hosts,omitempty"`
+	// The regular expressions matching the URLs the users may be
+	// redirected to. A pattern must match the entire URL without the query
+	// and the fragment, e.g. "https://app\.contoso\.com/.*".
+	AllowedPatterns []string `json:"allowed_patterns,omitempty" xml:"allowed_patterns,omitempty" yaml:"allowed_patterns,omitempty"`
+	// The key signing the redirect tokens. When set, the redirect tokens
+	// are accepted as an alternative to the raw URLs.
+	SigningKey string `json:"signing_key,omitempty" xml:"signing_key,omitempty" yaml:"signing_key,omitempty"`
+	// The lifetime of the redirect tokens in seconds. Defaults to 1 hour.
+	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+}
+
+// Policy validates the URLs the users are redirected to, and signs and
+// verifies the redirect tokens.
+type Policy struct {
+	hosts    map[string]bool
+	domains  []string
+	patterns []*regexp.Regexp
+	key      []byte
+	lifetime time.Duration
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for _, host := range cfg.AllowedHosts {
+		s := strings.TrimPrefix(host, "*.")
+		if s == "" || strings.ContainsAny(s, "*/:@\\ ") {
+			return errors.ErrRedirectConfigAllowedHost.WithArgs(host)
+		}
+	}
+	for _, pattern := range cfg.AllowedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.ErrRedirectConfigPattern.WithArgs(pattern, err)
+		}
+	}
+	if cfg.SigningKey != "" && len(cfg.SigningKey) < MinSigningKeyLength {
+		return errors.ErrRedirectConfigSigningKey.WithArgs(MinSigningKeyLength)
+	}
+	if cfg.TokenLifetime < 0 {
+		return errors.ErrRedirectConfigTokenLifetime.WithArgs(cfg.TokenLifetime)
+	}
+	return nil
+}
+
+// NewPolicy returns an instance of Policy.
+func NewPolicy(cfg *Config) (*Policy, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		hosts:    make(map[string]bool),
+		lifetime: time.Duration(defaultTokenLifetime) * time.Second,
+	}
+	for _, host := range cfg.AllowedHosts {
+		host = strings.ToLower(host)
+		if strings.HasPrefix(host, "*.") {
+			p.domains = append(p.domains, strings.TrimPrefix(host, "*"))
+			continue
+		}
+		p.hosts[host] = true
+	}
+	for _, pattern := range cfg.AllowedPatterns {
+		// The patterns are anchored, so that the allowed URL embedded in
+		// the query or the path of another URL does not match.
+		p.patterns = append(p.patterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+	if cfg.SigningKey != "" {
+		p.key = []byte(cfg.SigningKey)
+	}
+	if cfg.TokenLifetime > 0 {
+		p.lifetime = time.Duration(cfg.TokenLifetime) * time.Second
+	}
+	return p, nil
+}
+
+// SigningEnabled returns true when the policy accepts the redirect tokens.
+func (p *Policy) SigningEnabled() bool {
+	return len(p.key) > 0
+}
+
+// Check returns an error when the URL is not allowed. The host is the host
+// of the portal the request arrived at.
+func (p *Policy) Check(s, host string) error {
+	u, err := parseURL(s)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return nil
+	}
+	hostname := strings.ToLower(u.Hostname())
+	if hostname == getHostname(host) || p.hosts[hostname] {
+		return nil
+	}
+	for _, domain := range p.domains {
+		if strings.HasSuffix(hostname, domain) {
+			return nil
+		}
+	}
+	target := u.Scheme + "://" + u.Host + u.EscapedPath()
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(target) {
+			return nil
+		}
+	}
+	return errors.ErrRedirectURLNotAllowed.WithArgs(s)
+}
+
+// Sign returns the redirect token for the URL.
+func (p *Policy) Sign(s string) (string, error) {
+	return p.sign(s, time.Now().Add(p.lifetime))
+}
+
+func (p *Policy) sign(s string, expiresAt time.Time) (string, error) {
+	if !p.SigningEnabled() {
+		return "", errors.ErrRedirectTokenDisabled
+	}
+	if _, err := parseURL(s); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(s)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + p.getSignature(payload), nil
+}
+
+// Verify returns the URL of the redirect token. The URLs of the valid
+// tokens bypass the allowed hosts and patterns.
+func (p *Policy) Verify(token string) (string, error) {
+	if !p.SigningEnabled() {
+		return "", errors.ErrRedirectTokenDisabled
+	}
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", errors.ErrRedirectTokenMalformed
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(p.getSignature(payload))) {
+		return "", errors.ErrRedirectTokenSignature
+	}
+	arr := strings.Split(payload, ".")
+	if len(arr) != 2 {
+		return "", errors.ErrRedirectTokenMalformed
+	}
+	expiresAt, err := strconv.ParseInt(arr[1], 10, 64)
+	if err != nil {
+		return "", errors.ErrRedirectTokenMalformed
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", errors.ErrRedirectTokenExpired
+	}
+	b, err := base64.RawURLEncoding.DecodeString(arr[0])
+	if err != nil {
+		return "", errors.ErrRedirectTokenMalformed
+	}
+	s := string(b)
+	if _, err := parseURL(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func (p *Policy) getSignature(payload string) string {
+	h := hmac.New(sha256.New, p.key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// parseURL parses the URL and rejects the URLs the browsers could resolve
+// to other hosts or to the scripts, e.g. "//host", "/\host", or
+// "javascript:".
+func parseURL(s string) (*url.URL, error) {
+	if s == "" || strings.ContainsAny(s, "\\\t\r\n") {
+		return nil, errors.ErrRedirectURLMalformed.WithArgs(s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.ErrRedirectURLMalformed.WithArgs(s)
+	}
+	switch u.Scheme {
+	case "":
+		if u.Host != "" || !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") {
+			return nil, errors.ErrRedirectURLMalformed.WithArgs(s)
+		}
+	case "http", "https":
+		if u.Host == "" || u.User != nil {
+			return nil, errors.ErrRedirectURLMalformed.WithArgs(s)
+		}
+	default:
+		return nil, errors.ErrRedirectURLScheme.WithArgs(u.Scheme)
+	}
+	return u, nil
+}
+
+func getHostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redirect
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewPolicy(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				AllowedHosts:    []string{"app.contoso.com", "*.contoso.org"},
+				AllowedPatterns: []string{`https://[a-z]+\.contoso\.net/.*`},
+				SigningKey:      testSigningKey,
+				TokenLifetime:   60,
+			},
+		},
+		{
+			name:      "invalid allowed host",
+			config:    &Config{AllowedHosts: []string{"*.*.contoso.com"}},
+			shouldErr: true,
+			err:       errors.ErrRedirectConfigAllowedHost.WithArgs("*.*.contoso.com"),
+		},
+		{
+			name:      "invalid allowed pattern",
+			config:    &Config{AllowedPatterns: []string{"^(foo"}},
+			shouldErr: true,
+			err:       errors.ErrRedirectConfigPattern.WithArgs("^(foo", "error parsing regexp: missing closing ): `^(foo`"),
+		},
+		{
+			name:      "short signing key",
+			config:    &Config{SigningKey: "foobar"},
+			shouldErr: true,
+			err:       errors.ErrRedirectConfigSigningKey.WithArgs(MinSigningKeyLength),
+		},
+		{
+			name:      "negative token lifetime",
+			config:    &Config{TokenLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrRedirectConfigTokenLifetime.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewPolicy(tc.config)
+			tests.EvalErrWithLog(t, err, "NewPolicy", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := NewPolicy(&Config{
+		AllowedHosts:    []string{"app.contoso.com", "*.contoso.org"},
+		AllowedPatterns: []string{`https://[a-z]+\.contoso\.net/.*`},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		name      string
+		url       string
+		shouldErr bool
+		err       error
+	}{
+		{name: "relative url", url: "/portal"},
+		{name: "portal host", url: "https://auth.contoso.com/settings"},
+		{name: "allowed host", url: "https://app.contoso.com/"},
+		{name: "allowed subdomain", url: "https://foo.contoso.org:8443/bar"},
+		{name: "allowed pattern", url: "https://foo.contoso.net/bar"},
+		{name: "allowed pattern with query", url: "https://foo.contoso.net/bar?baz=1"},
+		{
+			name:      "off-host url",
+			url:       "https://evil.com/contoso.com",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLNotAllowed.WithArgs("https://evil.com/contoso.com"),
+		},
+		{
+			name:      "allowed pattern in query",
+			url:       "https://evil.com/?u=https://foo.contoso.net/bar",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLNotAllowed.WithArgs("https://evil.com/?u=https://foo.contoso.net/bar"),
+		},
+		{
+			name:      "domain suffix without subdomain",
+			url:       "https://evilcontoso.org/",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLNotAllowed.WithArgs("https://evilcontoso.org/"),
+		},
+		{
+			name:      "protocol-relative url",
+			url:       "//evil.com/",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLMalformed.WithArgs("//evil.com/"),
+		},
+		{
+			name:      "backslash url",
+			url:       "/\\evil.com",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLMalformed.WithArgs("/\\evil.com"),
+		},
+		{
+			name:      "url with user info",
+			url:       "https://app.contoso.com@evil.com/",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLMalformed.WithArgs("https://app.contoso.com@evil.com/"),
+		},
+		{
+			name:      "javascript url",
+			url:       "javascript:alert(1)",
+			shouldErr: true,
+			err:       errors.ErrRedirectURLScheme.WithArgs("javascript"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := p.Check(tc.url, "auth.contoso.com:443")
+			tests.EvalErrWithLog(t, err, "Check", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p, err := NewPolicy(&Config{SigningKey: testSigningKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherPolicy, err := NewPolicy(&Config{SigningKey: strings.Repeat("x", MinSigningKeyLength)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unsignedPolicy, err := NewPolicy(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	validToken, _ := p.Sign("https://app.contoso.com/foo?bar=baz")
+	expiredToken, _ := p.sign("https://app.contoso.com/", time.Now().Add(-1*time.Minute))
+	foreignToken, _ := otherPolicy.Sign("https://app.contoso.com/")
+
+	testcases := []struct {
+		name      string
+		policy    *Policy
+		token     string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid token",
+			policy: p,
+			token:  validToken,
+			want:   "https://app.contoso.com/foo?bar=baz",
+		},
+		{
+			name:      "expired token",
+			policy:    p,
+			token:     expiredToken,
+			shouldErr: true,
+			err:       errors.ErrRedirectTokenExpired,
+		},
+		{
+			name:      "token signed with other key",
+			policy:    p,
+			token:     foreignToken,
+			shouldErr: true,
+			err:       errors.ErrRedirectTokenSignature,
+		},
+		{
+			name:      "raw url",
+			policy:    p,
+			token:     "https://evil.com/",
+			shouldErr: true,
+			err:       errors.ErrRedirectTokenSignature,
+		},
+		{
+			name:      "malformed token",
+			policy:    p,
+			token:     "foobar",
+			shouldErr: true,
+			err:       errors.ErrRedirectTokenMalformed,
+		},
+		{
+			name:      "signing disabled",
+			policy:    unsignedPolicy,
+			token:     validToken,
+			shouldErr: true,
+			err:       errors.ErrRedirectTokenDisabled,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got, err := tc.policy.Verify(tc.token)
+			if tests.EvalErrWithLog(t, err, "Verify", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "url", tc.want, got, msgs)
+		})
+	}
+}
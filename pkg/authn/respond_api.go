@@ -16,6 +16,7 @@ package authn
 
 import (
 	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authproxy"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 	"go.uber.org/zap"
@@ -41,11 +42,61 @@ func (p *Portal) handleAPI(ctx context.Context, w http.ResponseWriter, r *http.R
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 	}
 
+	// The API requests without a token could be authenticated with an API key.
+	if usr == nil && r.Header.Get("X-Api-Key") != "" {
+		usr, err = p.newAPIKeyUser(&authproxy.Request{
+			Address: addrutil.GetSourceAddress(r),
+			Realm:   r.Header.Get("X-Api-Realm"),
+			Secret:  r.Header.Get("X-Api-Key"),
+		})
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		}
+		rr.Response.Authenticated = true
+	}
+
 	if !rr.Response.Authenticated {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 	}
 
-	if !usr.HasRole("authp/admin") {
+	// The administrator ends the impersonation with the token of the
+	// impersonated user, whatever the roles of the user are.
+	if strings.HasSuffix(r.URL.Path, "/api/impersonation") {
+		if p.config.API == nil || !p.config.API.Enabled || p.config.ImpersonationConfig == nil {
+			return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+		}
+		return p.handleAPIImpersonation(ctx, w, r, rr, usr)
+	}
+
+	// The self-service APIs are available to all users.
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/api/profile"), strings.HasSuffix(r.URL.Path, "/api/profile/export"), strings.Contains(r.URL.Path, "/api/consents"), strings.Contains(r.URL.Path, "/api/links"):
+		if !usr.HasRole("authp/admin", "authp/user") {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		}
+		if p.config.API == nil || !p.config.API.Enabled {
+			return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+		}
+		if strings.HasSuffix(r.URL.Path, "/api/profile") {
+			return p.handleAPIProfile(ctx, w, r, rr, usr)
+		}
+		if strings.HasSuffix(r.URL.Path, "/api/profile/export") {
+			store := p.getSelfServiceStore(rr, usr)
+			if store == nil {
+				return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+			}
+			return p.handleDataExport(ctx, w, r, rr, usr, store)
+		}
+		if strings.Contains(r.URL.Path, "/api/links") {
+			return p.handleAPILinks(ctx, w, r, rr, usr)
+		}
+		return p.handleAPIConsents(ctx, w, r, rr, usr)
+	}
+
+	// The delegated administrators manage the users and the registrations
+	// within their scope via the administrative API only.
+	scope := p.getAdminScope(usr)
+	if !usr.HasRole("authp/admin") && (scope == nil || !strings.Contains(r.URL.Path, adminAPIPrefix)) {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusForbidden, http.StatusText(http.StatusForbidden))
 	}
 
@@ -54,12 +105,20 @@ func (p *Portal) handleAPI(ctx context.Context, w http.ResponseWriter, r *http.R
 	}
 
 	switch {
+	case strings.Contains(r.URL.Path, adminAPIPrefix):
+		return p.handleAPIAdmin(ctx, w, r, rr, usr, scope)
 	case strings.HasSuffix(r.URL.Path, "/api/metadata"):
 		return p.handleAPIMetadata(ctx, w, r, rr, usr)
+	case strings.HasSuffix(r.URL.Path, "/api/usage"):
+		return p.handleAPIUsage(ctx, w, r, rr, usr)
 	case strings.Contains(r.URL.Path, "/api/orgs"):
 		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
 	case strings.Contains(r.URL.Path, "/api/teams"):
 		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	case strings.Contains(r.URL.Path, "/api/registrations"):
+		return p.handleAPIRegistrations(ctx, w, r, rr, usr, nil)
+	case strings.Contains(r.URL.Path, "/api/invites"):
+		return p.handleAPIInvites(ctx, w, r, rr, usr)
 	case strings.Contains(r.URL.Path, "/api/users"):
 		return p.handleAPIListUsers(ctx, w, r, rr, usr)
 	}
@@ -39,8 +39,11 @@ func (p *Portal) handleHTTP(ctx context.Context, w http.ResponseWriter, r *http.
 	case strings.Contains(r.URL.Path, "/portal"):
 		return p.handleHTTPPortal(ctx, w, r, rr, usr)
 	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"):
-		// TODO(greenpau): implement password recovery.
 		return p.handleHTTPRecover(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/magic-link"):
+		return p.handleHTTPMagicLink(ctx, w, r, rr, usr)
+	case strings.HasSuffix(r.URL.Path, "/sign-in-alert"):
+		return p.handleHTTPSignInAlert(ctx, w, r, rr)
 	case strings.Contains(r.URL.Path, "/settings"):
 		return p.handleHTTPSettings(ctx, w, r, rr, usr)
 	case strings.HasSuffix(r.URL.Path, "/register"), strings.Contains(r.URL.Path, "/register/"):
@@ -51,12 +54,20 @@ func (p *Portal) handleHTTP(ctx context.Context, w http.ResponseWriter, r *http.
 		return p.handleHTTPAppsSingleSignOn(ctx, w, r, rr, usr)
 	case strings.Contains(r.URL.Path, "/apps/mobile-access"):
 		return p.handleHTTPAppsMobileAccess(ctx, w, r, rr, usr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/authorize"):
+		return p.handleHTTPOAuthAuthorize(ctx, w, r, rr, usr)
 	case strings.Contains(r.URL.Path, "/oauth2/") && strings.HasSuffix(r.URL.Path, "/logout"):
 		return p.handleHTTPExternalLogout(ctx, w, r, rr, "oauth2")
 	case strings.Contains(r.URL.Path, "/saml/"):
 		return p.handleHTTPExternalLogin(ctx, w, r, rr, "saml")
 	case strings.Contains(r.URL.Path, "/oauth2/"):
 		return p.handleHTTPExternalLogin(ctx, w, r, rr, "oauth2")
+	case strings.Contains(r.URL.Path, "/kerberos/"):
+		return p.handleHTTPExternalLogin(ctx, w, r, rr, "kerberos")
+	case strings.Contains(r.URL.Path, "/mtls/"):
+		return p.handleHTTPExternalLogin(ctx, w, r, rr, "mtls")
+	case getDriverAuthMethod(r.URL.Path) != "":
+		return p.handleHTTPExternalLogin(ctx, w, r, rr, getDriverAuthMethod(r.URL.Path))
 	case strings.Contains(r.URL.Path, "/basic/login/"):
 		return p.handleHTTPBasicLogin(ctx, w, r, rr)
 	case strings.HasSuffix(r.URL.Path, "/logout"):
@@ -98,7 +109,7 @@ func (p *Portal) handleHTTPErrorWithLog(ctx context.Context, w http.ResponseWrit
 
 func (p *Portal) handleHTTPError(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, code int) error {
 	p.disableClientCache(w)
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, nil)
 	resp.BaseURL(rr.Upstream.BasePath)
 	resp.PageTitle = http.StatusText(code)
 
@@ -133,7 +144,7 @@ func (p *Portal) handleHTTPError(ctx context.Context, w http.ResponseWriter, r *
 
 func (p *Portal) handleHTTPGeneric(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, code int, msg string) error {
 	p.disableClientCache(w)
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, nil)
 	resp.BaseURL(rr.Upstream.BasePath)
 	resp.PageTitle = msg
 	switch code {
@@ -212,7 +223,7 @@ func (p *Portal) handleHTTPRenderError(ctx context.Context, w http.ResponseWrite
 		zap.Error(err),
 	)
 
-	resp := p.ui.GetArgs()
+	resp := p.getUIArgs(r, rr, nil)
 	resp.BaseURL(rr.Upstream.BasePath)
 	resp.PageTitle = http.StatusText(http.StatusInternalServerError)
 	resp.Data["go_back_url"] = "/"
@@ -257,9 +268,8 @@ func (p *Portal) injectSessionID(ctx context.Context, w http.ResponseWriter, r *
 
 func (p *Portal) injectRedirectURL(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) {
 	if r.Method == "GET" {
-		q := r.URL.Query()
-		if redirectURL, exists := q["redirect_url"]; exists {
-			c := p.cookie.GetCookie(addrutil.GetSourceHost(r), p.cookie.Referer, util.StripQueryParam(redirectURL[0], "login_hint"))
+		if redirectURL, exists := p.getQueryRedirectURL(r, rr); exists {
+			c := p.cookie.GetCookie(addrutil.GetSourceHost(r), p.cookie.Referer, redirectURL)
 			p.logger.Debug(
 				"redirect recorded",
 				zap.String("session_id", rr.Upstream.SessionID),
@@ -279,12 +289,16 @@ func (p *Portal) authorizeRequest(ctx context.Context, w http.ResponseWriter, r
 	ar.SessionID = rr.Upstream.SessionID
 	usr, err := p.validator.Authorize(ctx, r, ar)
 	if err != nil {
+		if renewedUser := p.renewSession(w, r, rr); renewedUser != nil {
+			rr.Response.Authenticated = true
+			return renewedUser, nil
+		}
 		switch err.Error() {
 		case "no token found":
 			return nil, nil
 		default:
 			for tokenName := range p.validator.GetAuthCookies() {
-				w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), tokenName))
+				p.deleteTokenCookies(w, r, addrutil.GetSourceHost(r), tokenName)
 			}
 			if strings.Contains(r.URL.Path, "/assets/") || strings.Contains(r.URL.Path, "/favicon") {
 				return nil, nil
@@ -300,6 +314,9 @@ func (p *Portal) authorizeRequest(ctx context.Context, w http.ResponseWriter, r
 	}
 	if usr != nil {
 		rr.Response.Authenticated = true
+		if p.config.ImpersonationConfig != nil {
+			p.logImpersonatedRequest(r, rr, usr)
+		}
 	}
 	return usr, nil
 }
@@ -334,6 +351,10 @@ func extractBasePath(ctx context.Context, r *http.Request, rr *requests.Request)
 		extractBaseURLPath(ctx, r, rr, "/settings")
 	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"):
 		extractBaseURLPath(ctx, r, rr, "/recover,/forgot")
+	case strings.HasSuffix(r.URL.Path, "/magic-link"):
+		extractBaseURLPath(ctx, r, rr, "/magic-link")
+	case strings.HasSuffix(r.URL.Path, "/sign-in-alert"):
+		extractBaseURLPath(ctx, r, rr, "/sign-in-alert")
 	case strings.HasSuffix(r.URL.Path, "/register"):
 		extractBaseURLPath(ctx, r, rr, "/register")
 	case strings.HasSuffix(r.URL.Path, "/whoami"):
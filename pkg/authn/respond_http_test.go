@@ -18,6 +18,7 @@ import (
 	"context"
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authn/redirect"
 	"github.com/greenpau/go-authcrunch/pkg/authn/ui"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
@@ -77,6 +78,33 @@ func TestInjectRedirectURL(t *testing.T) {
 		cookieParts := strings.Split(request.Response.RedirectURL, ";")
 		tests.EvalObjectsWithLog(t, "redirect url", "AUTHP_REDIRECT_URL=https://foo.bar/redir", cookieParts[0], []string{})
 	})
+
+	t.Run("Rejects redirect URL not allowed by redirect policy", func(t *testing.T) {
+		reqURL := url.URL{
+			Scheme:   "https",
+			Host:     "foo.bar",
+			Path:     "/myPage",
+			RawQuery: "redirect_url=https%3A%2F%2Fevil.com%2Fredir",
+		}
+		r := http.Request{URL: &reqURL, Method: "GET", Host: "foo.bar"}
+		f, _ := cookie.NewFactory(nil)
+		rp, _ := redirect.NewPolicy(&redirect.Config{AllowedHosts: []string{"*.foo.bar"}})
+		p := Portal{
+			config: &PortalConfig{
+				Name: "somePortal",
+			},
+			logger:         zap.L(),
+			cookie:         f,
+			redirectPolicy: rp,
+		}
+		request := requests.NewRequest()
+		rw := buildCustomResponseWriter()
+
+		p.injectRedirectURL(context.Background(), rw, &r, request)
+
+		tests.EvalObjectsWithLog(t, "redirect url", "", request.Response.RedirectURL, []string{})
+		tests.EvalObjectsWithLog(t, "set cookie", "", rw.Header().Get("Set-Cookie"), []string{})
+	})
 }
 
 func TestRefererSanitization(t *testing.T) {
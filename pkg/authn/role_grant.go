@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+	"time"
+)
+
+// roleExpiryInterval is the interval between the removals of the expired
+// time-boxed roles from the local identity stores.
+const roleExpiryInterval = time.Minute
+
+// runRoleExpiry removes the expired time-boxed roles at the role expiry
+// interval until stopRoleExpiry is called.
+func (p *Portal) runRoleExpiry() {
+	if p.roleExpiryExit != nil {
+		return
+	}
+	exit := make(chan bool)
+	p.roleExpiryExit = exit
+	go func() {
+		ticker := time.NewTicker(roleExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				p.expireRoleGrants()
+			}
+		}
+	}()
+}
+
+// stopRoleExpiry stops the removal started with runRoleExpiry.
+func (p *Portal) stopRoleExpiry() {
+	if p.roleExpiryExit == nil {
+		return
+	}
+	close(p.roleExpiryExit)
+	p.roleExpiryExit = nil
+}
+
+// expireRoleGrants removes the expired time-boxed roles from the users of
+// the local identity stores and ends the sessions of the users, so that the
+// tokens carrying the roles are no longer accepted.
+func (p *Portal) expireRoleGrants() {
+	for _, store := range p.identityStores {
+		if store.GetKind() != "local" {
+			continue
+		}
+		req := &requests.Request{Context: context.Background()}
+		if err := store.Request(operator.ExpireRoles, req); err != nil {
+			p.logger.Warn(
+				"Failed expiring time-boxed roles",
+				zap.String("portal_name", p.config.Name),
+				zap.String("realm", store.GetRealm()),
+				zap.Error(err),
+			)
+			continue
+		}
+		grants, ok := req.Response.Payload.([]*requests.RoleGrant)
+		if !ok {
+			continue
+		}
+		for _, grant := range grants {
+			p.handleExpiredRoleGrant(store.GetRealm(), grant)
+		}
+	}
+}
+
+// handleExpiredRoleGrant records the removal of the expired time-boxed role
+// and ends the sessions of the user.
+func (p *Portal) handleExpiredRoleGrant(realm string, grant *requests.RoleGrant) {
+	p.logger.Info(
+		"Audit",
+		zap.String("event", "role_expired"),
+		zap.String("realm", realm),
+		zap.String("username", grant.Username),
+		zap.String("role", grant.Role),
+		zap.String("granted_by", grant.GrantedBy),
+		zap.Time("expires_at", grant.ExpiresAt),
+	)
+	events.Publish(&events.Event{
+		Type:     events.RoleExpired,
+		Realm:    realm,
+		Method:   "local",
+		Username: grant.Username,
+		Email:    grant.Email,
+		Data: map[string]interface{}{
+			"role":       grant.Role,
+			"granted_by": grant.GrantedBy,
+			"expires_at": grant.ExpiresAt,
+		},
+	})
+	p.endUserSessions(grant.Email)
+}
+
+// endUserSessions ends the sessions of a user and revokes the tokens issued
+// in the sessions.
+func (p *Portal) endUserSessions(email string) {
+	if email == "" {
+		return
+	}
+	if p.sessionPolicy != nil {
+		p.sessionPolicy.EndUserSessions(email)
+	}
+	for _, entry := range p.sessions.DeleteUserSessions(email) {
+		p.keystore.RevokeToken(entry.Token)
+	}
+}
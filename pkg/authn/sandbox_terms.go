@@ -0,0 +1,108 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/terms"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// getTermsStore returns the identity store recording the acceptance of the
+// terms by the users of the realm, if any. Only local identity stores
+// record the acceptance.
+func (p *Portal) getTermsStore(realm string) ids.IdentityStore {
+	if p.config.TermsConfig == nil {
+		return nil
+	}
+	store := p.getIdentityStoreByRealm(realm)
+	if store == nil || store.GetKind() != "local" {
+		return nil
+	}
+	return store
+}
+
+// getPendingTerms returns the documents whose current version the user
+// has not accepted.
+func (p *Portal) getPendingTerms(store ids.IdentityStore, rr *requests.Request) ([]*terms.Document, error) {
+	if err := store.Request(operator.GetAcceptances, rr); err != nil {
+		return nil, err
+	}
+	acceptances, ok := rr.Response.Payload.([]*identity.Acceptance)
+	if !ok {
+		return nil, fmt.Errorf("malformed acceptances")
+	}
+	return p.config.TermsConfig.GetPending(acceptances), nil
+}
+
+// injectTermsCheckpoint adds the acceptance checkpoint when the user has
+// not accepted the current version of any of the documents.
+func (p *Portal) injectTermsCheckpoint(rr *requests.Request, usr *user.User) error {
+	store := p.getTermsStore(rr.Upstream.Realm)
+	if store == nil {
+		return nil
+	}
+	docs, err := p.getPendingTerms(store, rr)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	checkpoint, err := user.NewCheckpoint("consent")
+	if err != nil {
+		return err
+	}
+	checkpoint.ID = len(usr.Checkpoints)
+	usr.Checkpoints = append(usr.Checkpoints, checkpoint)
+	return nil
+}
+
+// acceptTerms records the acceptance of the documents. The form must carry
+// the accepted version of every document, so that the acceptance of a
+// version replaced in the meantime is rejected.
+func (p *Portal) acceptTerms(r *http.Request, rr *requests.Request, usr *user.User, store ids.IdentityStore, docs []*terms.Document) error {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Failed parsing submitted form")
+	}
+	for _, doc := range docs {
+		if r.PostFormValue("accept_"+doc.Name) != doc.Version {
+			return errors.ErrTermsNotAccepted
+		}
+	}
+	for _, doc := range docs {
+		rr.Acceptance.Document = doc.Name
+		rr.Acceptance.Version = doc.Version
+		if err := store.Request(operator.AddAcceptance, rr); err != nil {
+			return err
+		}
+		p.publishEvent(events.TermsAccepted, r, rr, usr, map[string]interface{}{
+			"document": doc.Name,
+			"version":  doc.Version,
+		})
+	}
+	return nil
+}
@@ -17,7 +17,9 @@ package authn
 import (
 	"context"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"github.com/greenpau/go-authcrunch/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"strings"
 )
@@ -27,6 +29,24 @@ func (p *Portal) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.R
 	if rr.ID == "" {
 		rr.ID = util.GetRequestID(r)
 	}
+	ctx, span := tracing.StartServer(ctx, r, "authn.ServeHTTP",
+		tracing.PortalKey.String(p.config.Name),
+		attribute.String("request_id", rr.ID),
+	)
+	rr.Context = ctx
+	err := p.serveHTTP(ctx, w, r, rr)
+	if rr.Upstream.Realm != "" {
+		span.SetAttributes(
+			tracing.RealmKey.String(rr.Upstream.Realm),
+			tracing.MethodKey.String(rr.Upstream.Method),
+		)
+	}
+	span.SetAttributes(attribute.Bool("authenticated", rr.Response.Authenticated))
+	tracing.End(span, err)
+	return err
+}
+
+func (p *Portal) serveHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
 	rr.Logger = p.logger
 	rr.Upstream.Request = r
 	rr.Upstream.ContentType = util.GetContentType(r)
@@ -35,7 +55,27 @@ func (p *Portal) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.R
 		rr.Response.Title = p.config.UI.Title
 	}
 	rr.Response.RedirectTokenName = p.cookie.Referer
+	if err := p.filterNetwork(r); err != nil {
+		return p.handleNetworkDenied(ctx, w, r, rr, err)
+	}
+	if code, err := p.limitRequestBody(w, r); err != nil {
+		return p.handleRequestBodyRejected(ctx, w, r, rr, code, err)
+	}
 	switch {
+	case strings.HasSuffix(r.URL.Path, "/.well-known/jwks.json"):
+		return p.handleJWKS(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+		return p.handleOpenIDConfiguration(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/token"):
+		return p.handleOAuthToken(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/revoke"):
+		return p.handleOAuthRevoke(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/introspect"):
+		return p.handleOAuthIntrospect(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/userinfo"):
+		return p.handleOAuthUserInfo(ctx, w, r, rr)
+	case strings.HasSuffix(r.URL.Path, "/oauth2/register"):
+		return p.handleOAuthRegister(ctx, w, r, rr)
 	case strings.Contains(r.URL.Path, "/api/"):
 		return p.handleAPI(ctx, w, r, rr)
 	case strings.Contains(r.URL.Path, "/qrcode/"):
@@ -238,6 +238,40 @@ func TestServeHTTP(t *testing.T) {
 				"content_type": "",
 			},
 		},
+		{
+			name: "test unauthenticated user accessing jwks endpoint",
+			requests: []*testAppRequest{
+				{
+					method: "GET",
+					path:   "/auth/.well-known/jwks.json",
+				},
+			},
+			want: map[string]interface{}{
+				"response": requests.Response{
+					RedirectTokenName: "AUTHP_REDIRECT_URL",
+					Code:              http.StatusOK,
+				},
+				"status_code":  http.StatusOK,
+				"content_type": "application/json",
+			},
+		},
+		{
+			name: "test unauthenticated user accessing disabled openid discovery endpoint",
+			requests: []*testAppRequest{
+				{
+					method: "GET",
+					path:   "/auth/.well-known/openid-configuration",
+				},
+			},
+			want: map[string]interface{}{
+				"response": requests.Response{
+					RedirectTokenName: "AUTHP_REDIRECT_URL",
+				},
+				"status_code":  http.StatusNotFound,
+				"content_type": "application/json",
+				"message":      "Not Found",
+			},
+		},
 	}
 
 	for _, tc := range testcases {
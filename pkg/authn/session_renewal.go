@@ -0,0 +1,123 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/sessionpolicy"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+// startSession starts a sliding session for the user logging in with a
+// browser. It returns nil when the sessions are not renewable.
+func (p *Portal) startSession(rr *requests.Request, usr *user.User) (*sessionpolicy.Session, string) {
+	if p.sessionPolicy == nil || rr.Response.Workflow == "json-api" {
+		return nil, ""
+	}
+	return p.sessionPolicy.Start(rr.Upstream.SessionID, usr)
+}
+
+// setSessionRenewalCookie sets the cookie renewing the access tokens of the
+// session until the session reaches its absolute lifetime.
+func (p *Portal) setSessionRenewalCookie(w http.ResponseWriter, r *http.Request, session *sessionpolicy.Session, token string) {
+	h := addrutil.GetSourceHost(r)
+	w.Header().Add("Set-Cookie", p.cookie.GetPersistentCookie(h, p.cookie.SessionRenewal, token, session.GetRemainingLifetime()))
+}
+
+// endSession ends the sliding session of the user, if any, e.g. at logout.
+func (p *Portal) endSession(w http.ResponseWriter, r *http.Request) {
+	if p.sessionPolicy == nil {
+		return
+	}
+	c, err := r.Cookie(p.cookie.SessionRenewal)
+	if err != nil {
+		return
+	}
+	p.sessionPolicy.End(c.Value)
+	w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(addrutil.GetSourceHost(r), p.cookie.SessionRenewal))
+}
+
+// renewSession silently issues a new access token to the user whose token
+// is either missing or no longer valid, provided the sliding session of the
+// user has neither been idle for too long nor reached its absolute
+// lifetime.
+func (p *Portal) renewSession(w http.ResponseWriter, r *http.Request, rr *requests.Request) *user.User {
+	if p.sessionPolicy == nil {
+		return nil
+	}
+	c, err := r.Cookie(p.cookie.SessionRenewal)
+	if err != nil {
+		return nil
+	}
+	h := addrutil.GetSourceHost(r)
+	session, err := p.sessionPolicy.Renew(c.Value)
+	if err != nil {
+		p.logger.Debug(
+			"session renewal failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		w.Header().Add("Set-Cookie", p.cookie.GetDeleteCookie(h, p.cookie.SessionRenewal))
+		return nil
+	}
+
+	// The renewed token carries the claims of the token issued at login.
+	m := make(map[string]interface{})
+	for k, v := range session.User.AsMap() {
+		m[k] = v
+	}
+	usr, err := user.NewUser(m)
+	if err != nil {
+		return nil
+	}
+	usr.Authenticator = session.User.Authenticator
+	usr.FrontendLinks = session.User.FrontendLinks
+	now := time.Now().UTC()
+	usr.SetExpiresAtClaim(session.GetExpiresAt(p.getTokenLifetime(usr, rr.Upstream.Realm)))
+	usr.SetIssuedAtClaim(now.Unix())
+	usr.SetNotBeforeClaim(now.Add(time.Duration(60) * time.Second * -1).Unix())
+	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
+		p.logger.Warn(
+			"user token signing failed",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	usr.Authorized = true
+	p.sessions.Add(session.ID, usr)
+
+	rr.Upstream.Realm = usr.Authenticator.Realm
+	w.Header().Set("Authorization", "Bearer "+usr.Token)
+	p.setTokenCookies(w, r, rr, h, usr.TokenName, usr.Token)
+	p.logger.Debug(
+		"Renewed session",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("renewed_session_id", session.ID),
+		zap.String("subject", usr.Claims.Subject),
+	)
+	p.recordUsage("session/renew")
+	metrics.TokensIssued.Inc(usr.Authenticator.Realm, "renewal")
+	return usr
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/sessionpolicy"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestSessionRenewal(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		SessionPolicyConfig: &sessionpolicy.Config{
+			IdleTimeout:      600,
+			AbsoluteLifetime: 86400,
+			Rules: []*sessionpolicy.Rule{
+				{Role: "authp/admin", AbsoluteLifetime: 28800},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(roles []string) (*user.User, *http.Cookie) {
+		sessionID := util.GetRandomStringFromRange(36, 46)
+		usr, err := user.NewUser(map[string]interface{}{
+			"sub":    "jsmith",
+			"email":  "jsmith@contoso.com",
+			"roles":  roles,
+			"jti":    sessionID,
+			"origin": "local",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		usr.Authenticator.Realm = "local"
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/login", nil)
+		rr := requests.NewRequest()
+		rr.Upstream.SessionID = sessionID
+		rr.Upstream.Realm = "local"
+		p.grantAccess(context.Background(), w, r, rr, usr)
+		c, found := getTestResponseCookies(w)[p.cookie.SessionRenewal]
+		if !found {
+			t.Fatalf("session renewal cookie not found")
+		}
+		return usr, c
+	}
+
+	authorize := func(renewal *http.Cookie) (*user.User, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/portal", nil)
+		if renewal != nil {
+			r.AddCookie(renewal)
+		}
+		rr := requests.NewRequest()
+		rr.Upstream.SessionID = util.GetRandomStringFromRange(36, 46)
+		usr, _ := p.authorizeRequest(context.Background(), w, r, rr)
+		return usr, w
+	}
+
+	testcases := []struct {
+		name     string
+		roles    []string
+		lifetime int
+	}{
+		{
+			name:     "user session",
+			roles:    []string{"authp/user"},
+			lifetime: 86400,
+		},
+		{
+			name:     "admin session",
+			roles:    []string{"authp/admin"},
+			lifetime: 28800,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			usr, renewal := login(tc.roles)
+			tests.EvalObjectsWithLog(t, "renewal cookie lifetime", true, renewal.MaxAge > tc.lifetime-5 && renewal.MaxAge <= tc.lifetime, msgs)
+			tests.EvalObjectsWithLog(t, "token capped by idle timeout", true, usr.Claims.ExpiresAt <= time.Now().Add(600*time.Second).Unix(), msgs)
+
+			// The user without a token is renewed silently.
+			renewedUser, w := authorize(renewal)
+			if renewedUser == nil {
+				t.Fatalf("session not renewed")
+			}
+			tests.EvalObjectsWithLog(t, "renewed subject", usr.Claims.Subject, renewedUser.Claims.Subject, msgs)
+			tests.EvalObjectsWithLog(t, "renewed session id", usr.Claims.ID, renewedUser.Claims.ID, msgs)
+			tests.EvalObjectsWithLog(t, "renewed roles", tc.roles, renewedUser.Claims.Roles, msgs)
+			tests.EvalObjectsWithLog(t, "authorization header", "Bearer "+renewedUser.Token, w.Header().Get("Authorization"), msgs)
+			_, err := p.sessions.Get(usr.Claims.ID)
+			tests.EvalObjectsWithLog(t, "portal session", nil, err, msgs)
+
+			// The ended session is no longer renewed.
+			w = httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/logout", nil)
+			r.AddCookie(renewal)
+			p.endSession(w, r)
+			tests.EvalObjectsWithLog(t, "renewal cookie deleted", "delete", getTestResponseCookies(w)[p.cookie.SessionRenewal].Value, msgs)
+			renewedUser, w = authorize(renewal)
+			tests.EvalObjectsWithLog(t, "ended session", true, renewedUser == nil, msgs)
+			tests.EvalObjectsWithLog(t, "stale renewal cookie deleted", "delete", getTestResponseCookies(w)[p.cookie.SessionRenewal].Value, msgs)
+		})
+	}
+
+	// The sessions of a user terminated by an administrator are no longer
+	// renewed.
+	_, renewal := login([]string{"authp/user"})
+	p.terminateUserSessions(httptest.NewRequest("POST", "/api/admin", nil), requests.NewRequest(), "jsmith@contoso.com", "test")
+	renewedUser, _ := authorize(renewal)
+	tests.EvalObjects(t, "terminated session", true, renewedUser == nil)
+}
@@ -0,0 +1,302 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionpolicy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+const (
+	defaultIdleTimeout      = 3600
+	defaultAbsoluteLifetime = 86400
+	pruneInterval           = time.Minute
+)
+
+// Config holds the configuration of the sliding portal sessions. The access
+// tokens of a session are renewed silently until the session is either
+// idle for longer than the idle timeout or reaches its absolute lifetime.
+// When a value is zero, the default applies.
+type Config struct {
+	// The number of seconds a session remains renewable after the last
+	// renewal. Defaults to 1 hour.
+	IdleTimeout int `json:"idle_timeout,omitempty" xml:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	// The number of seconds a session lasts since the login. Defaults to
+	// 24 hours.
+	AbsoluteLifetime int `json:"absolute_lifetime,omitempty" xml:"absolute_lifetime,omitempty" yaml:"absolute_lifetime,omitempty"`
+	// Rules override the timeouts for the sessions in a realm or with a
+	// role. The first matching rule applies.
+	Rules []*Rule `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Rule holds the timeouts of the sessions in a realm, with a role, or both.
+// When a value is zero, the value of the Config applies.
+type Rule struct {
+	Realm            string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Role             string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	IdleTimeout      int    `json:"idle_timeout,omitempty" xml:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	AbsoluteLifetime int    `json:"absolute_lifetime,omitempty" xml:"absolute_lifetime,omitempty" yaml:"absolute_lifetime,omitempty"`
+}
+
+// Session is a renewable portal session.
+type Session struct {
+	ID               string     `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	User             *user.User `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	StartedAt        time.Time  `json:"started_at,omitempty" xml:"started_at,omitempty" yaml:"started_at,omitempty"`
+	RenewedAt        time.Time  `json:"renewed_at,omitempty" xml:"renewed_at,omitempty" yaml:"renewed_at,omitempty"`
+	IdleTimeout      int        `json:"idle_timeout,omitempty" xml:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	AbsoluteLifetime int        `json:"absolute_lifetime,omitempty" xml:"absolute_lifetime,omitempty" yaml:"absolute_lifetime,omitempty"`
+	key              string
+}
+
+// Policy tracks the renewable sessions. The sessions are referenced by
+// random renewal tokens held by the browsers.
+type Policy struct {
+	mu       sync.Mutex
+	config   *Config
+	sessions map[string]*Session
+	prunedAt time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.IdleTimeout < 0 {
+		return errors.ErrSessionPolicyConfigIdleTimeout.WithArgs(cfg.IdleTimeout)
+	}
+	if cfg.AbsoluteLifetime < 0 {
+		return errors.ErrSessionPolicyConfigAbsoluteLifetime.WithArgs(cfg.AbsoluteLifetime)
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+	if cfg.AbsoluteLifetime == 0 {
+		cfg.AbsoluteLifetime = defaultAbsoluteLifetime
+	}
+	if cfg.IdleTimeout > cfg.AbsoluteLifetime {
+		return errors.ErrSessionPolicyConfigIdleExceeded.WithArgs(cfg.IdleTimeout, cfg.AbsoluteLifetime)
+	}
+	for i, rule := range cfg.Rules {
+		if rule.Realm == "" && rule.Role == "" {
+			return errors.ErrSessionPolicyConfigRuleEmpty.WithArgs(i)
+		}
+		if rule.IdleTimeout < 0 {
+			return errors.ErrSessionPolicyConfigIdleTimeout.WithArgs(rule.IdleTimeout)
+		}
+		if rule.AbsoluteLifetime < 0 {
+			return errors.ErrSessionPolicyConfigAbsoluteLifetime.WithArgs(rule.AbsoluteLifetime)
+		}
+		idleTimeout, absoluteLifetime := cfg.getTimeouts(rule)
+		if idleTimeout > absoluteLifetime {
+			return errors.ErrSessionPolicyConfigIdleExceeded.WithArgs(idleTimeout, absoluteLifetime)
+		}
+	}
+	return nil
+}
+
+// getTimeouts returns the timeouts of the rule, falling back to the ones of
+// the config.
+func (cfg *Config) getTimeouts(rule *Rule) (int, int) {
+	idleTimeout, absoluteLifetime := cfg.IdleTimeout, cfg.AbsoluteLifetime
+	if rule == nil {
+		return idleTimeout, absoluteLifetime
+	}
+	if rule.IdleTimeout > 0 {
+		idleTimeout = rule.IdleTimeout
+	}
+	if rule.AbsoluteLifetime > 0 {
+		absoluteLifetime = rule.AbsoluteLifetime
+	}
+	return idleTimeout, absoluteLifetime
+}
+
+// GetTimeouts returns the idle timeout and the absolute lifetime of the
+// sessions in the realm with the roles.
+func (cfg *Config) GetTimeouts(realm string, roles []string) (int, int) {
+	for _, rule := range cfg.Rules {
+		if rule.Realm != "" && rule.Realm != realm {
+			continue
+		}
+		if rule.Role != "" && !hasRole(roles, rule.Role) {
+			continue
+		}
+		return cfg.getTimeouts(rule)
+	}
+	return cfg.getTimeouts(nil)
+}
+
+// NewPolicy returns an instance of Policy.
+func NewPolicy(cfg *Config) (*Policy, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		config:   cfg,
+		sessions: make(map[string]*Session),
+	}
+	return p, nil
+}
+
+// Start starts a renewable session for the user and returns the session
+// with the renewal token.
+func (p *Policy) Start(id string, usr *user.User) (*Session, string) {
+	return p.start(time.Now().UTC(), id, usr)
+}
+
+func (p *Policy) start(now time.Time, id string, usr *user.User) (*Session, string) {
+	realm := usr.Authenticator.Realm
+	if realm == "" {
+		realm = usr.Claims.Origin
+	}
+	idleTimeout, absoluteLifetime := p.config.GetTimeouts(realm, usr.Claims.Roles)
+	token := util.GetRandomStringFromRange(48, 64)
+	s := &Session{
+		ID:               id,
+		User:             usr,
+		StartedAt:        now,
+		RenewedAt:        now,
+		IdleTimeout:      idleTimeout,
+		AbsoluteLifetime: absoluteLifetime,
+		key:              getSessionKey(token),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune(now)
+	p.sessions[s.key] = s
+	return s, token
+}
+
+// Renew renews the session referenced by the renewal token, unless the
+// session has been idle for too long or exceeded its absolute lifetime.
+func (p *Policy) Renew(token string) (*Session, error) {
+	return p.renew(time.Now().UTC(), token)
+}
+
+func (p *Policy) renew(now time.Time, token string) (*Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.prune(now)
+	s, exists := p.sessions[getSessionKey(token)]
+	if !exists {
+		return nil, errors.ErrSessionPolicyNotFound
+	}
+	if err := s.valid(now); err != nil {
+		delete(p.sessions, s.key)
+		return nil, err
+	}
+	s.RenewedAt = now
+	return s, nil
+}
+
+// End ends the session referenced by the renewal token.
+func (p *Policy) End(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, getSessionKey(token))
+}
+
+// EndUserSessions ends the sessions of the user with the provided email
+// address.
+func (p *Policy) EndUserSessions(email string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, s := range p.sessions {
+		if strings.EqualFold(s.User.Claims.Email, email) {
+			delete(p.sessions, k)
+		}
+	}
+}
+
+// Inherit takes over the sessions of a previous instance of the policy. The
+// sessions keep their timeouts.
+func (p *Policy) Inherit(prev *Policy) {
+	if prev == nil || prev == p {
+		return
+	}
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, s := range prev.sessions {
+		p.sessions[k] = s
+	}
+}
+
+// GetExpiresAt returns the expiry time of the access token issued with the
+// lifetime in seconds. The token expires no later than the session becomes
+// idle or reaches its absolute lifetime.
+func (s *Session) GetExpiresAt(lifetime int) int64 {
+	return s.getExpiresAt(time.Now().UTC(), lifetime)
+}
+
+func (s *Session) getExpiresAt(now time.Time, lifetime int) int64 {
+	expiresAt := now.Add(time.Duration(lifetime) * time.Second)
+	if t := now.Add(time.Duration(s.IdleTimeout) * time.Second); t.Before(expiresAt) {
+		expiresAt = t
+	}
+	if t := s.StartedAt.Add(time.Duration(s.AbsoluteLifetime) * time.Second); t.Before(expiresAt) {
+		expiresAt = t
+	}
+	return expiresAt.Unix()
+}
+
+// GetRemainingLifetime returns the number of seconds until the session
+// reaches its absolute lifetime.
+func (s *Session) GetRemainingLifetime() int {
+	return int(time.Until(s.StartedAt.Add(time.Duration(s.AbsoluteLifetime) * time.Second)).Seconds())
+}
+
+func (s *Session) valid(now time.Time) error {
+	if now.After(s.StartedAt.Add(time.Duration(s.AbsoluteLifetime) * time.Second)) {
+		return errors.ErrSessionPolicyAbsoluteLifetime.WithArgs(s.ID)
+	}
+	if now.After(s.RenewedAt.Add(time.Duration(s.IdleTimeout) * time.Second)) {
+		return errors.ErrSessionPolicyIdleTimeout.WithArgs(s.ID)
+	}
+	return nil
+}
+
+// prune removes the expired sessions at most once per minute.
+func (p *Policy) prune(now time.Time) {
+	if now.Sub(p.prunedAt) < pruneInterval {
+		return
+	}
+	for k, s := range p.sessions {
+		if s.valid(now) != nil {
+			delete(p.sessions, k)
+		}
+	}
+	p.prunedAt = now
+}
+
+func getSessionKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionpolicy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+func TestNewPolicy(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{name: "default config", config: &Config{}},
+		{
+			name: "config with rules",
+			config: &Config{
+				IdleTimeout: 900,
+				Rules: []*Rule{
+					{Realm: "contoso", Role: "authp/admin", AbsoluteLifetime: 28800},
+					{Role: "authp/admin", IdleTimeout: 300},
+				},
+			},
+		},
+		{
+			name:      "negative idle timeout",
+			config:    &Config{IdleTimeout: -1},
+			shouldErr: true,
+			err:       errors.ErrSessionPolicyConfigIdleTimeout.WithArgs(-1),
+		},
+		{
+			name:      "negative absolute lifetime",
+			config:    &Config{AbsoluteLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrSessionPolicyConfigAbsoluteLifetime.WithArgs(-1),
+		},
+		{
+			name:      "idle timeout exceeds absolute lifetime",
+			config:    &Config{IdleTimeout: 7200, AbsoluteLifetime: 3600},
+			shouldErr: true,
+			err:       errors.ErrSessionPolicyConfigIdleExceeded.WithArgs(7200, 3600),
+		},
+		{
+			name:      "rule without realm and role",
+			config:    &Config{Rules: []*Rule{{IdleTimeout: 300}}},
+			shouldErr: true,
+			err:       errors.ErrSessionPolicyConfigRuleEmpty.WithArgs(0),
+		},
+		{
+			name:      "rule idle timeout exceeds absolute lifetime",
+			config:    &Config{Rules: []*Rule{{Role: "authp/admin", AbsoluteLifetime: 1800}}},
+			shouldErr: true,
+			err:       errors.ErrSessionPolicyConfigIdleExceeded.WithArgs(3600, 1800),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := NewPolicy(tc.config)
+			tests.EvalErrWithLog(t, err, "NewPolicy", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestGetTimeouts(t *testing.T) {
+	cfg := &Config{
+		IdleTimeout: 900,
+		Rules: []*Rule{
+			{Realm: "contoso", Role: "authp/admin", AbsoluteLifetime: 28800},
+			{Role: "authp/admin", IdleTimeout: 300},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		name  string
+		realm string
+		roles []string
+		want  []int
+	}{
+		{name: "user", realm: "contoso", roles: []string{"authp/user"}, want: []int{900, 86400}},
+		{name: "admin in realm", realm: "contoso", roles: []string{"authp/user", "authp/admin"}, want: []int{900, 28800}},
+		{name: "admin in other realm", realm: "local", roles: []string{"authp/admin"}, want: []int{300, 86400}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			idleTimeout, absoluteLifetime := cfg.GetTimeouts(tc.realm, tc.roles)
+			tests.EvalObjectsWithLog(t, "timeouts", tc.want, []int{idleTimeout, absoluteLifetime}, msgs)
+		})
+	}
+}
+
+func TestRenew(t *testing.T) {
+	p, err := NewPolicy(&Config{IdleTimeout: 600, AbsoluteLifetime: 3600})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usr, err := user.NewUser(map[string]interface{}{
+		"sub":    "jsmith",
+		"email":  "jsmith@contoso.com",
+		"origin": "local",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now().UTC()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	s, token := p.start(start, "foo", usr)
+	tests.EvalObjects(t, "token expiry capped by idle timeout", at(600).Unix(), s.getExpiresAt(start, 900))
+	tests.EvalObjects(t, "token expiry", at(300).Unix(), s.getExpiresAt(start, 300))
+
+	testcases := []struct {
+		name      string
+		token     string
+		at        int
+		shouldErr bool
+		err       error
+	}{
+		{name: "renew within idle timeout", token: token, at: 500},
+		{name: "renew within idle timeout after renewal", token: token, at: 1000},
+		{name: "renew with unknown token", token: "bar", at: 1100, shouldErr: true, err: errors.ErrSessionPolicyNotFound},
+		{name: "renew after idle timeout", token: token, at: 1700, shouldErr: true, err: errors.ErrSessionPolicyIdleTimeout.WithArgs("foo")},
+		{name: "renew ended session", token: token, at: 1800, shouldErr: true, err: errors.ErrSessionPolicyNotFound},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := p.renew(at(tc.at), tc.token)
+			tests.EvalErrWithLog(t, err, "renew", tc.shouldErr, tc.err, msgs)
+		})
+	}
+
+	// The active session ends at its absolute lifetime.
+	s, token = p.start(start, "bar", usr)
+	for i := 500; i < 3600; i += 500 {
+		if _, err := p.renew(at(i), token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	tests.EvalObjects(t, "token expiry capped by absolute lifetime", at(3600).Unix(), s.getExpiresAt(at(3500), 900))
+	_, err = p.renew(at(3700), token)
+	tests.EvalErrWithLog(t, err, "renew after absolute lifetime", true, errors.ErrSessionPolicyAbsoluteLifetime.WithArgs("bar"), []string{})
+}
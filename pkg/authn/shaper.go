@@ -0,0 +1,64 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/authn/shaper"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+)
+
+// shapeClaims applies the token claims shaping to the claims of a user
+// prior to issuing a token. When the shaping is not configured, it does
+// nothing.
+func (p *Portal) shapeClaims(m map[string]interface{}) {
+	if p.shaper == nil {
+		return
+	}
+	p.shaper.Shape(m)
+}
+
+// normalizeIdentity applies the identity normalization rules to the claims
+// of a user, so that the rules matching the username or the email address,
+// e.g. the role transforms, apply regardless of the realm. When the
+// normalization is not configured, it does nothing.
+func (p *Portal) normalizeIdentity(m map[string]interface{}) {
+	if p.normalizer == nil {
+		return
+	}
+	p.normalizer.Normalize(m)
+}
+
+// checkCookieSize warns when the size of the cookie carrying a token
+// exceeds the limit of the browsers. The browsers silently drop such
+// cookies, and the user ends up in a login loop.
+func (p *Portal) checkCookieSize(rr *requests.Request, name, cookie string) {
+	maxSize := shaper.DefaultMaxCookieSize
+	if p.shaper != nil {
+		maxSize = p.shaper.GetMaxCookieSize()
+	}
+	if len(cookie) <= maxSize {
+		return
+	}
+	p.logger.Warn(
+		"cookie exceeds browser size limit",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("cookie_name", name),
+		zap.Int("cookie_size", len(cookie)),
+		zap.Int("max_cookie_size", maxSize),
+	)
+	p.recordUsage("cookie/oversized")
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// DefaultMaxCookieSize is the cookie size limit of most browsers.
+const DefaultMaxCookieSize = 4096
+
+var (
+	// reservedClaims are never removed or renamed, because the tokens are
+	// not valid without them.
+	reservedClaims = map[string]bool{
+		"exp": true,
+		"iat": true,
+		"nbf": true,
+		"iss": true,
+		"jti": true,
+		"sub": true,
+	}
+	// transientClaims are consumed by the portal and never end up in the
+	// issued tokens.
+	transientClaims = map[string]bool{
+		"challenges":     true,
+		"frontend_links": true,
+	}
+	groupClaims = []string{"roles", "role", "groups", "group"}
+)
+
+// Config holds the configuration of token claims Shaper.
+type Config struct {
+	// IncludeClaims is the list of the claims allowed in the issued tokens.
+	// When it is empty, all claims are allowed. The reserved claims, i.e.
+	// exp, iat, nbf, iss, jti, and sub, are always included.
+	IncludeClaims []string `json:"include_claims,omitempty" xml:"include_claims,omitempty" yaml:"include_claims,omitempty"`
+	// ExcludeClaims is the list of the claims removed from the issued tokens.
+	ExcludeClaims []string `json:"exclude_claims,omitempty" xml:"exclude_claims,omitempty" yaml:"exclude_claims,omitempty"`
+	// RenameClaims maps the names of the claims to the names used in the
+	// issued tokens.
+	RenameClaims map[string]string `json:"rename_claims,omitempty" xml:"rename_claims,omitempty" yaml:"rename_claims,omitempty"`
+	// MaxGroups is the maximum number of roles or groups in the issued
+	// tokens. When a list is truncated, the <claim>_overflow claim is set
+	// to true.
+	MaxGroups int `json:"max_groups,omitempty" xml:"max_groups,omitempty" yaml:"max_groups,omitempty"`
+	// MaxCookieSize is the size of a cookie in bytes above which a warning
+	// is logged. Defaults to 4096, the limit of most browsers.
+	MaxCookieSize int `json:"max_cookie_size,omitempty" xml:"max_cookie_size,omitempty" yaml:"max_cookie_size,omitempty"`
+}
+
+// Shaper controls which claims end up in the issued tokens.
+type Shaper struct {
+	include       map[string]bool
+	exclude       map[string]bool
+	rename        map[string]string
+	maxGroups     int
+	maxCookieSize int
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MaxGroups < 0 {
+		return errors.ErrShaperConfigMaxGroups.WithArgs(cfg.MaxGroups)
+	}
+	if cfg.MaxCookieSize < 0 {
+		return errors.ErrShaperConfigMaxCookieSize.WithArgs(cfg.MaxCookieSize)
+	}
+	for _, k := range cfg.ExcludeClaims {
+		if reservedClaims[k] {
+			return errors.ErrShaperConfigReservedClaim.WithArgs(k, "excluded")
+		}
+	}
+	for k, v := range cfg.RenameClaims {
+		if reservedClaims[k] || reservedClaims[v] {
+			return errors.ErrShaperConfigReservedClaim.WithArgs(k, "renamed")
+		}
+		if v == "" {
+			return errors.ErrShaperConfigRenameEmpty.WithArgs(k)
+		}
+	}
+	return nil
+}
+
+// NewShaper returns an instance of Shaper.
+func NewShaper(cfg *Config) (*Shaper, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Shaper{
+		exclude:       make(map[string]bool),
+		rename:        make(map[string]string),
+		maxGroups:     cfg.MaxGroups,
+		maxCookieSize: DefaultMaxCookieSize,
+	}
+	if len(cfg.IncludeClaims) > 0 {
+		s.include = make(map[string]bool)
+		for _, k := range cfg.IncludeClaims {
+			s.include[k] = true
+		}
+	}
+	for _, k := range cfg.ExcludeClaims {
+		s.exclude[k] = true
+	}
+	for k, v := range cfg.RenameClaims {
+		s.rename[k] = v
+	}
+	if cfg.MaxCookieSize > 0 {
+		s.maxCookieSize = cfg.MaxCookieSize
+	}
+	return s, nil
+}
+
+// Shape caps the roles and groups, removes the claims not allowed in the
+// issued tokens, and renames the claims.
+func (s *Shaper) Shape(m map[string]interface{}) {
+	if s.maxGroups > 0 {
+		for _, k := range groupClaims {
+			if capped := capList(m[k], s.maxGroups); capped != nil {
+				m[k] = capped
+				m[k+"_overflow"] = true
+			}
+		}
+	}
+
+	for k := range m {
+		if reservedClaims[k] || transientClaims[k] {
+			continue
+		}
+		if s.exclude[k] {
+			delete(m, k)
+			continue
+		}
+		if s.include != nil && !s.include[k] && !isOverflowClaim(k, s.include) {
+			delete(m, k)
+		}
+	}
+
+	for from, to := range s.rename {
+		v, exists := m[from]
+		if !exists {
+			continue
+		}
+		delete(m, from)
+		m[to] = v
+	}
+}
+
+// GetMaxCookieSize returns the cookie size limit.
+func (s *Shaper) GetMaxCookieSize() int {
+	return s.maxCookieSize
+}
+
+// capList returns the truncated list, or nil when the list is within the
+// limit.
+func capList(v interface{}, max int) interface{} {
+	switch entries := v.(type) {
+	case []string:
+		if len(entries) > max {
+			return append([]string{}, entries[:max]...)
+		}
+	case []interface{}:
+		if len(entries) > max {
+			return append([]interface{}{}, entries[:max]...)
+		}
+	}
+	return nil
+}
+
+// isOverflowClaim returns true when the claim is the overflow indicator of
+// an included group claim.
+func isOverflowClaim(k string, include map[string]bool) bool {
+	for _, g := range groupClaims {
+		if k == g+"_overflow" && include[g] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestShape(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		input     map[string]interface{}
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "cap groups with overflow indicator",
+			config: &Config{MaxGroups: 2},
+			input: map[string]interface{}{
+				"sub":    "jsmith",
+				"roles":  []string{"admin", "editor", "viewer"},
+				"groups": []interface{}{"a", "b"},
+			},
+			want: map[string]interface{}{
+				"sub":            "jsmith",
+				"roles":          []string{"admin", "editor"},
+				"roles_overflow": true,
+				"groups":         []interface{}{"a", "b"},
+			},
+		},
+		{
+			name: "include and exclude claims",
+			config: &Config{
+				IncludeClaims: []string{"email", "roles", "picture"},
+				ExcludeClaims: []string{"picture"},
+				MaxGroups:     1,
+			},
+			input: map[string]interface{}{
+				"sub":            "jsmith",
+				"exp":            1000,
+				"email":          "jsmith@contoso.com",
+				"name":           "John Smith",
+				"picture":        "https://contoso.com/jsmith.png",
+				"roles":          []string{"admin", "editor"},
+				"frontend_links": []string{"foo"},
+			},
+			want: map[string]interface{}{
+				"sub":            "jsmith",
+				"exp":            1000,
+				"email":          "jsmith@contoso.com",
+				"roles":          []string{"admin"},
+				"roles_overflow": true,
+				"frontend_links": []string{"foo"},
+			},
+		},
+		{
+			name: "rename claims",
+			config: &Config{
+				RenameClaims: map[string]string{"org": "tenant"},
+			},
+			input: map[string]interface{}{
+				"sub": "jsmith",
+				"org": "contoso",
+			},
+			want: map[string]interface{}{
+				"sub":    "jsmith",
+				"tenant": "contoso",
+			},
+		},
+		{
+			name:      "negative max groups",
+			config:    &Config{MaxGroups: -1},
+			shouldErr: true,
+			err:       errors.ErrShaperConfigMaxGroups.WithArgs(-1),
+		},
+		{
+			name:      "negative max cookie size",
+			config:    &Config{MaxCookieSize: -1},
+			shouldErr: true,
+			err:       errors.ErrShaperConfigMaxCookieSize.WithArgs(-1),
+		},
+		{
+			name:      "exclude reserved claim",
+			config:    &Config{ExcludeClaims: []string{"exp"}},
+			shouldErr: true,
+			err:       errors.ErrShaperConfigReservedClaim.WithArgs("exp", "excluded"),
+		},
+		{
+			name:      "rename reserved claim",
+			config:    &Config{RenameClaims: map[string]string{"sub": "user"}},
+			shouldErr: true,
+			err:       errors.ErrShaperConfigReservedClaim.WithArgs("sub", "renamed"),
+		},
+		{
+			name:      "rename claim to empty name",
+			config:    &Config{RenameClaims: map[string]string{"org": ""}},
+			shouldErr: true,
+			err:       errors.ErrShaperConfigRenameEmpty.WithArgs("org"),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			s, err := NewShaper(tc.config)
+			if tests.EvalErrWithLog(t, err, "shaper", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			s.Shape(tc.input)
+			tests.EvalObjectsWithLog(t, "claims", tc.want, tc.input, msgs)
+		})
+	}
+}
+
+func TestGetMaxCookieSize(t *testing.T) {
+	s, err := NewShaper(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "default max cookie size", DefaultMaxCookieSize, s.GetMaxCookieSize())
+
+	s, err = NewShaper(&Config{MaxCookieSize: 8192})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "max cookie size", 8192, s.GetMaxCookieSize())
+}
@@ -0,0 +1,22 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signin
+
+const templateName = "en/new_sign_in"
+
+// Notify sends the new sign-in notification to the email address in data.
+func (t *Tracker) Notify(data map[string]string) error {
+	return t.config.Send(t.config.EmailProvider, templateName, []string{data["email"]}, data)
+}
@@ -0,0 +1,419 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signin
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/notify"
+	"github.com/greenpau/go-authcrunch/pkg/authn/onetime"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+)
+
+const (
+	defaultTokenLifetime = 86400
+	defaultMaxSpeed      = 1000
+	defaultMaxDevices    = 20
+	// The distance in kilometers below which the travel between two
+	// locations is not evaluated. It absorbs the imprecision of geolocation.
+	minTravelDistance = 200
+	earthRadius       = 6371
+)
+
+// Config holds the configuration of the new sign-in notifications.
+type Config struct {
+	// The email provider used to deliver the notifications.
+	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
+	// The path to the file where the sign-in fingerprints are persisted.
+	// When empty, the fingerprints are kept in memory only.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// The path to MaxMind GeoIP2 or GeoLite2 City database. When empty,
+	// the geolocation database of the server is used, if any. Otherwise,
+	// the location is the network of the source IP address.
+	GeoDatabasePath string `json:"geo_database_path,omitempty" xml:"geo_database_path,omitempty" yaml:"geo_database_path,omitempty"`
+	// The speed, in kilometers per hour, above which the travel between
+	// two consecutive sign-ins is considered impossible.
+	MaxSpeed int `json:"max_speed,omitempty" xml:"max_speed,omitempty" yaml:"max_speed,omitempty"`
+	// The maximum number of fingerprints retained per user.
+	MaxDevices int `json:"max_devices,omitempty" xml:"max_devices,omitempty" yaml:"max_devices,omitempty"`
+	// The number of seconds the session revocation link is valid for.
+	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
+
+	notify.Notifier `json:"-" xml:"-" yaml:"-"`
+}
+
+// Fingerprint is a device and location a user signed in from.
+type Fingerprint struct {
+	// The digest of the user agent.
+	Device string `json:"device,omitempty" xml:"device,omitempty" yaml:"device,omitempty"`
+	// The network of the source IP address, i.e. /24 for IPv4 and /48 for
+	// IPv6 addresses.
+	Network   string    `json:"network,omitempty" xml:"network,omitempty" yaml:"network,omitempty"`
+	Country   string    `json:"country,omitempty" xml:"country,omitempty" yaml:"country,omitempty"`
+	City      string    `json:"city,omitempty" xml:"city,omitempty" yaml:"city,omitempty"`
+	Latitude  float64   `json:"latitude,omitempty" xml:"latitude,omitempty" yaml:"latitude,omitempty"`
+	Longitude float64   `json:"longitude,omitempty" xml:"longitude,omitempty" yaml:"longitude,omitempty"`
+	FirstSeen time.Time `json:"first_seen,omitempty" xml:"first_seen,omitempty" yaml:"first_seen,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty" xml:"last_seen,omitempty" yaml:"last_seen,omitempty"`
+}
+
+// Observation is the outcome of the evaluation of a sign-in against the
+// previously seen fingerprints of a user.
+type Observation struct {
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty" xml:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	// The fingerprint of the most recent previous sign-in, if any.
+	Previous    *Fingerprint `json:"previous,omitempty" xml:"previous,omitempty" yaml:"previous,omitempty"`
+	NewDevice   bool         `json:"new_device,omitempty" xml:"new_device,omitempty" yaml:"new_device,omitempty"`
+	NewLocation bool         `json:"new_location,omitempty" xml:"new_location,omitempty" yaml:"new_location,omitempty"`
+	// ImpossibleTravel is set when the user could not have travelled from
+	// the location of the previous sign-in at the speed below the limit.
+	ImpossibleTravel bool `json:"impossible_travel,omitempty" xml:"impossible_travel,omitempty" yaml:"impossible_travel,omitempty"`
+	// The distance in kilometers and the speed in kilometers per hour
+	// between the previous and this sign-in.
+	Distance float64 `json:"distance,omitempty" xml:"distance,omitempty" yaml:"distance,omitempty"`
+	Speed    float64 `json:"speed,omitempty" xml:"speed,omitempty" yaml:"speed,omitempty"`
+}
+
+// Token is the payload of a session revocation link.
+type Token struct {
+	Realm    string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Tracker records the fingerprints of user sign-ins and detects the sign-ins
+// from unrecognized devices and locations.
+type Tracker struct {
+	mu         sync.Mutex
+	config     *Config
+	locator    geo.Locator
+	issuer     *onetime.Issuer
+	maxSpeed   float64
+	maxDevices int
+	users      map[string][]*Fingerprint
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.EmailProvider == "" {
+		return errors.ErrSignInAlertConfigEmailProvider
+	}
+	if cfg.TokenLifetime < 0 {
+		return errors.ErrSignInAlertConfigTokenLifetime.WithArgs(cfg.TokenLifetime)
+	}
+	if cfg.MaxSpeed < 0 {
+		return errors.ErrSignInAlertConfigMaxSpeed.WithArgs(cfg.MaxSpeed)
+	}
+	if cfg.MaxDevices < 0 {
+		return errors.ErrSignInAlertConfigMaxDevices.WithArgs(cfg.MaxDevices)
+	}
+	return nil
+}
+
+// ValidateMessaging validates messaging provider and credentials used for
+// the delivery of sign-in notifications.
+func (cfg *Config) ValidateMessaging() error {
+	return cfg.ValidateProvider(cfg.EmailProvider)
+}
+
+// NewTracker returns an instance of Tracker. The revocation links are
+// signed with a random key generated at startup, i.e. the outstanding
+// links become invalid after a restart.
+func NewTracker(cfg *Config) (*Tracker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	t := &Tracker{
+		config:     cfg,
+		locator:    geo.Default,
+		maxSpeed:   defaultMaxSpeed,
+		maxDevices: defaultMaxDevices,
+		users:      make(map[string][]*Fingerprint),
+	}
+	lifetime := defaultTokenLifetime
+	if cfg.TokenLifetime > 0 {
+		lifetime = cfg.TokenLifetime
+	}
+	issuer, err := onetime.NewIssuer(&onetime.Config{TokenLifetime: lifetime})
+	if err != nil {
+		return nil, err
+	}
+	t.issuer = issuer
+	if cfg.MaxSpeed > 0 {
+		t.maxSpeed = float64(cfg.MaxSpeed)
+	}
+	if cfg.MaxDevices > 0 {
+		t.maxDevices = cfg.MaxDevices
+	}
+	if cfg.GeoDatabasePath != "" {
+		locator, err := geo.NewResolver(&geo.Config{
+			Kind: geo.KindMaxMind,
+			Path: cfg.GeoDatabasePath,
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.locator = locator
+	}
+	if cfg.Path != "" {
+		b, err := ioutil.ReadFile(cfg.Path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, &t.users); err != nil {
+				return nil, errors.ErrSignInAlertLoad.WithArgs(cfg.Path, err)
+			}
+		case !os.IsNotExist(err):
+			return nil, errors.ErrSignInAlertLoad.WithArgs(cfg.Path, err)
+		}
+	}
+	return t, nil
+}
+
+// SetLocator sets the locator used to resolve the location of source IP
+// addresses.
+func (t *Tracker) SetLocator(locator geo.Locator) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.locator = locator
+}
+
+// GetTokenLifetime returns the lifetime of revocation link tokens.
+func (t *Tracker) GetTokenLifetime() time.Duration {
+	return t.issuer.GetTokenLifetime()
+}
+
+// Observe records the sign-in of the user from the provided source address
+// and user agent and returns its evaluation. The first sign-in of a user
+// establishes the baseline and is never flagged.
+func (t *Tracker) Observe(realm, username, srcAddr, userAgent string, ts time.Time) (*Observation, error) {
+	fp := &Fingerprint{
+		Device:    digest(userAgent),
+		FirstSeen: ts,
+		LastSeen:  ts,
+	}
+	ip := net.ParseIP(srcAddr)
+	if ip != nil {
+		fp.Network = getNetwork(ip)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ip != nil {
+		if loc, err := t.locator.Locate(ip); err == nil && loc != nil {
+			fp.Country = loc.Country
+			fp.City = loc.City
+			fp.Latitude = loc.Latitude
+			fp.Longitude = loc.Longitude
+		}
+	}
+
+	key := strings.ToLower(realm + "/" + username)
+	entries := t.users[key]
+	obs := &Observation{Fingerprint: fp}
+
+	if len(entries) > 0 {
+		obs.NewDevice = true
+		obs.NewLocation = true
+		for _, entry := range entries {
+			if entry.Device == fp.Device {
+				obs.NewDevice = false
+			}
+			if entry.location() == fp.location() {
+				obs.NewLocation = false
+			}
+			if obs.Previous == nil || entry.LastSeen.After(obs.Previous.LastSeen) {
+				obs.Previous = entry
+			}
+		}
+		if obs.Previous.hasCoordinates() && fp.hasCoordinates() {
+			obs.Distance = getDistance(obs.Previous, fp)
+			if obs.Distance > minTravelDistance {
+				hours := ts.Sub(obs.Previous.LastSeen).Hours()
+				if hours > 0 {
+					obs.Speed = obs.Distance / hours
+				} else {
+					obs.Speed = math.Inf(1)
+				}
+				obs.ImpossibleTravel = obs.Speed > t.maxSpeed
+			}
+		}
+		// Copy the previous fingerprint, because it is updated below.
+		prev := *obs.Previous
+		obs.Previous = &prev
+	}
+
+	var found bool
+	for _, entry := range entries {
+		if entry.Device == fp.Device && entry.location() == fp.location() {
+			entry.LastSeen = ts
+			entry.Network = fp.Network
+			entry.Latitude = fp.Latitude
+			entry.Longitude = fp.Longitude
+			found = true
+			break
+		}
+	}
+	if !found {
+		entries = append(entries, fp)
+	}
+	if len(entries) > t.maxDevices {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].LastSeen.After(entries[j].LastSeen)
+		})
+		entries = entries[:t.maxDevices]
+	}
+	t.users[key] = entries
+
+	if err := t.save(); err != nil {
+		return obs, err
+	}
+	return obs, nil
+}
+
+// Known returns true when the tracker holds fingerprints of the user.
+func (t *Tracker) Known(realm, username string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.users[strings.ToLower(realm+"/"+username)]) > 0
+}
+
+// Locate returns the location of the source address, if known.
+func (t *Tracker) Locate(srcAddr string) *geo.Location {
+	ip := net.ParseIP(srcAddr)
+	if ip == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	loc, err := t.locator.Locate(ip)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// GetFingerprints returns the copies of the fingerprints of the user,
+// the most recent sign-in first.
+func (t *Tracker) GetFingerprints(realm, username string) []*Fingerprint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := []*Fingerprint{}
+	for _, entry := range t.users[strings.ToLower(realm+"/"+username)] {
+		fp := *entry
+		entries = append(entries, &fp)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastSeen.After(entries[j].LastSeen)
+	})
+	return entries
+}
+
+// Forget removes the fingerprints of the user.
+func (t *Tracker) Forget(realm, username string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.users, strings.ToLower(realm+"/"+username))
+	return t.save()
+}
+
+// Notable returns true when the sign-in is from an unrecognized device or
+// location, or the travel from the previous sign-in is impossible.
+func (o *Observation) Notable() bool {
+	return o.NewDevice || o.NewLocation || o.ImpossibleTravel
+}
+
+// IssueToken returns a signed token of the link revoking the sessions of
+// the user.
+func (t *Tracker) IssueToken(realm, username, email string) (string, error) {
+	return t.issuer.Issue(&Token{
+		Realm:    realm,
+		Username: username,
+		Email:    email,
+	})
+}
+
+// RedeemToken verifies a revocation link token and marks it used.
+func (t *Tracker) RedeemToken(s string) (*Token, error) {
+	tkn := &Token{}
+	if err := t.issuer.Redeem(s, tkn); err != nil {
+		return nil, err
+	}
+	if tkn.Email == "" {
+		return nil, errors.ErrOneTimeTokenMalformed
+	}
+	return tkn, nil
+}
+
+func (t *Tracker) save() error {
+	if t.config.Path == "" {
+		return nil
+	}
+	b, err := json.Marshal(t.users)
+	if err != nil {
+		return errors.ErrSignInAlertSave.WithArgs(t.config.Path, err)
+	}
+	if err := ioutil.WriteFile(t.config.Path, b, 0600); err != nil {
+		return errors.ErrSignInAlertSave.WithArgs(t.config.Path, err)
+	}
+	return nil
+}
+
+// location returns the city and country of the fingerprint, if known, or
+// the network of its source IP address.
+func (fp *Fingerprint) location() string {
+	if fp.Country != "" {
+		return fp.City + "/" + fp.Country
+	}
+	return fp.Network
+}
+
+func (fp *Fingerprint) hasCoordinates() bool {
+	return fp.Latitude != 0 || fp.Longitude != 0
+}
+
+// getDistance returns the great-circle distance in kilometers between the
+// locations of the fingerprints.
+func getDistance(a, b *Fingerprint) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+func getNetwork(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+func digest(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(h[:12])
+}
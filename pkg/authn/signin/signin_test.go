@@ -0,0 +1,259 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signin
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+	"github.com/greenpau/go-authcrunch/pkg/messaging"
+)
+
+type testLocator map[string]*geo.Location
+
+func (l testLocator) Locate(ip net.IP) (*geo.Location, error) {
+	return l[ip.String()], nil
+}
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid config",
+			config: &Config{EmailProvider: "default", MaxSpeed: 800},
+		},
+		{
+			name:      "config without email provider",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrSignInAlertConfigEmailProvider,
+		},
+		{
+			name:      "config with negative token lifetime",
+			config:    &Config{EmailProvider: "default", TokenLifetime: -1},
+			shouldErr: true,
+			err:       errors.ErrSignInAlertConfigTokenLifetime.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max speed",
+			config:    &Config{EmailProvider: "default", MaxSpeed: -1},
+			shouldErr: true,
+			err:       errors.ErrSignInAlertConfigMaxSpeed.WithArgs(-1),
+		},
+		{
+			name:      "config with negative max devices",
+			config:    &Config{EmailProvider: "default", MaxDevices: -1},
+			shouldErr: true,
+			err:       errors.ErrSignInAlertConfigMaxDevices.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	locator := testLocator{
+		"198.51.100.10": {Country: "US", City: "New York", Latitude: 40.71, Longitude: -74.01},
+		"198.51.100.20": {Country: "US", City: "New York", Latitude: 40.71, Longitude: -74.01},
+		"203.0.113.10":  {Country: "JP", City: "Tokyo", Latitude: 35.68, Longitude: 139.69},
+		"192.0.2.10":    {Country: "US", City: "Boston", Latitude: 42.36, Longitude: -71.06},
+	}
+	start := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name      string
+		srcAddr   string
+		userAgent string
+		offset    time.Duration
+		want      map[string]interface{}
+	}{
+		{
+			name:      "first sign-in establishes baseline",
+			srcAddr:   "198.51.100.10",
+			userAgent: "Mozilla/5.0",
+			want:      map[string]interface{}{"new_device": false, "new_location": false, "impossible_travel": false},
+		},
+		{
+			name:      "known device from same city",
+			srcAddr:   "198.51.100.20",
+			userAgent: "Mozilla/5.0",
+			offset:    time.Hour,
+			want:      map[string]interface{}{"new_device": false, "new_location": false, "impossible_travel": false},
+		},
+		{
+			name:      "new device from same city",
+			srcAddr:   "198.51.100.20",
+			userAgent: "curl/7.79.1",
+			offset:    2 * time.Hour,
+			want:      map[string]interface{}{"new_device": true, "new_location": false, "impossible_travel": false},
+		},
+		{
+			name:      "known device from nearby city",
+			srcAddr:   "192.0.2.10",
+			userAgent: "Mozilla/5.0",
+			offset:    6 * time.Hour,
+			want:      map[string]interface{}{"new_device": false, "new_location": true, "impossible_travel": false},
+		},
+		{
+			name:      "known device from other continent an hour later",
+			srcAddr:   "203.0.113.10",
+			userAgent: "Mozilla/5.0",
+			offset:    7 * time.Hour,
+			want:      map[string]interface{}{"new_device": false, "new_location": true, "impossible_travel": true},
+		},
+	}
+
+	tracker, err := NewTracker(&Config{EmailProvider: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracker.SetLocator(locator)
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			obs, err := tracker.Observe("local", "jsmith", tc.srcAddr, tc.userAgent, start.Add(tc.offset))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"new_device":        obs.NewDevice,
+				"new_location":      obs.NewLocation,
+				"impossible_travel": obs.ImpossibleTravel,
+			}
+			tests.EvalObjectsWithLog(t, "observation", tc.want, got, nil)
+		})
+	}
+
+	tests.EvalObjects(t, "known user", true, tracker.Known("local", "jsmith"))
+	tests.EvalObjects(t, "location", "Tokyo", tracker.Locate("203.0.113.10").City)
+	entries := tracker.GetFingerprints("local", "JSMITH")
+	tests.EvalObjects(t, "fingerprint count", 4, len(entries))
+	tests.EvalObjects(t, "most recent city", "Tokyo", entries[0].City)
+	if err := tracker.Forget("local", "jsmith"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "forgotten fingerprints", 0, len(tracker.GetFingerprints("local", "jsmith")))
+	tests.EvalObjects(t, "forgotten user", false, tracker.Known("local", "jsmith"))
+}
+
+func TestObserveNetwork(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "fingerprints.json")
+	tracker, err := NewTracker(&Config{EmailProvider: "default", Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	if _, err := tracker.Observe("local", "jsmith", "10.0.0.1", "Mozilla/5.0", now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obs, err := tracker.Observe("local", "jsmith", "10.0.0.2", "Mozilla/5.0", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "same network", false, obs.Notable())
+
+	// The fingerprints survive a restart.
+	tracker, err = NewTracker(&Config{EmailProvider: "default", Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obs, err = tracker.Observe("local", "jsmith", "10.0.1.1", "Mozilla/5.0", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "other network", true, obs.NewLocation)
+	tests.EvalObjects(t, "previous network", "10.0.0.0/24", obs.Previous.Network)
+
+	if err := tracker.Forget("local", "jsmith"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obs, err = tracker.Observe("local", "jsmith", "10.0.2.1", "curl/7.79.1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "forgotten user", false, obs.Notable())
+}
+
+func TestToken(t *testing.T) {
+	tracker, err := NewTracker(&Config{EmailProvider: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "default lifetime", 24*time.Hour, tracker.GetTokenLifetime())
+	token, err := tracker.IssueToken("local", "jsmith", "jsmith@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tkn, err := tracker.RedeemToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "token email", "jsmith@localhost", tkn.Email)
+	_, err = tracker.RedeemToken(token)
+	tests.EvalErrWithLog(t, err, "used token", true, errors.ErrOneTimeTokenUsed, nil)
+}
+
+func TestNotify(t *testing.T) {
+	rootDir := t.TempDir()
+	msgCfg := &messaging.Config{}
+	if err := msgCfg.Add(&messaging.FileProvider{Name: "default", RootDir: rootDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{EmailProvider: "default"}
+	cfg.SetMessaging(msgCfg)
+	if err := cfg.ValidateMessaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracker, err := NewTracker(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tracker.Notify(map[string]string{
+		"email":      "jsmith@localhost",
+		"username":   "jsmith",
+		"reason":     "new_location",
+		"location":   "Tokyo, JP",
+		"revoke_url": "https://localhost/sign-in-alert?token=foo",
+		"lifetime":   "24h0m0s",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(rootDir, "*.eml"))
+	tests.EvalObjects(t, "message count", 1, len(files))
+	b, _ := ioutil.ReadFile(files[0])
+	if !strings.Contains(string(b), "Subject: New Sign-In to Your Account") {
+		t.Fatalf("unexpected message: %s", b)
+	}
+
+	cfg = &Config{EmailProvider: "foo"}
+	cfg.SetMessaging(msgCfg)
+	err = cfg.ValidateMessaging()
+	tests.EvalErrWithLog(t, err, "unknown provider", true, errors.ErrNotifyProvider.WithArgs("foo"), nil)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+)
+
+// Config holds the configuration of the acknowledgment of the documents,
+// e.g. terms of service and privacy policy, the users must accept after
+// signing in.
+type Config struct {
+	Documents []*Document `json:"documents,omitempty" xml:"documents,omitempty" yaml:"documents,omitempty"`
+}
+
+// Document is a versioned document the users must accept. The users are
+// prompted again when the version changes.
+type Document struct {
+	// The name of the document, e.g. terms or privacy.
+	Name    string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Title   string `json:"title,omitempty" xml:"title,omitempty" yaml:"title,omitempty"`
+	URL     string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	Version string `json:"version,omitempty" xml:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if len(cfg.Documents) == 0 {
+		return errors.ErrTermsConfigDocumentsEmpty
+	}
+	seen := make(map[string]bool)
+	for _, doc := range cfg.Documents {
+		if doc.Name == "" {
+			return errors.ErrTermsConfigDocumentName
+		}
+		if seen[doc.Name] {
+			return errors.ErrTermsConfigDocumentDuplicate.WithArgs(doc.Name)
+		}
+		seen[doc.Name] = true
+		if doc.Title == "" {
+			return errors.ErrTermsConfigDocumentTitle.WithArgs(doc.Name)
+		}
+		if !strings.HasPrefix(doc.URL, "https://") && !strings.HasPrefix(doc.URL, "http://") && !strings.HasPrefix(doc.URL, "/") {
+			return errors.ErrTermsConfigDocumentURL.WithArgs(doc.Name, doc.URL)
+		}
+		if doc.Version == "" {
+			return errors.ErrTermsConfigDocumentVersion.WithArgs(doc.Name)
+		}
+	}
+	return nil
+}
+
+// GetPending returns the documents whose current version the user has
+// not accepted.
+func (cfg *Config) GetPending(acceptances []*identity.Acceptance) []*Document {
+	accepted := make(map[string]string)
+	for _, a := range acceptances {
+		accepted[a.Document] = a.Version
+	}
+	var docs []*Document
+	for _, doc := range cfg.Documents {
+		if v, exists := accepted[doc.Name]; exists && v == doc.Version {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "config with terms and privacy policy",
+			config: &Config{Documents: []*Document{
+				{Name: "terms", Title: "Terms of Service", URL: "https://example.com/terms", Version: "2023-01"},
+				{Name: "privacy", Title: "Privacy Policy", URL: "/privacy", Version: "1"},
+			}},
+		},
+		{
+			name:      "config without documents",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentsEmpty,
+		},
+		{
+			name:      "config with unnamed document",
+			config:    &Config{Documents: []*Document{{}}},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentName,
+		},
+		{
+			name: "config with duplicate document",
+			config: &Config{Documents: []*Document{
+				{Name: "terms", Title: "Terms of Service", URL: "/terms", Version: "1"},
+				{Name: "terms", Title: "Terms of Service", URL: "/terms", Version: "2"},
+			}},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentDuplicate.WithArgs("terms"),
+		},
+		{
+			name:      "config with document without title",
+			config:    &Config{Documents: []*Document{{Name: "terms"}}},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentTitle.WithArgs("terms"),
+		},
+		{
+			name:      "config with script url",
+			config:    &Config{Documents: []*Document{{Name: "terms", Title: "Terms", URL: "javascript:alert(1)"}}},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentURL.WithArgs("terms", "javascript:alert(1)"),
+		},
+		{
+			name:      "config with document without version",
+			config:    &Config{Documents: []*Document{{Name: "terms", Title: "Terms", URL: "/terms"}}},
+			shouldErr: true,
+			err:       errors.ErrTermsConfigDocumentVersion.WithArgs("terms"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestGetPending(t *testing.T) {
+	cfg := &Config{Documents: []*Document{
+		{Name: "terms", Title: "Terms of Service", URL: "/terms", Version: "2"},
+		{Name: "privacy", Title: "Privacy Policy", URL: "/privacy", Version: "1"},
+	}}
+	testcases := []struct {
+		name        string
+		acceptances []*identity.Acceptance
+		want        []string
+	}{
+		{
+			name: "new user",
+			want: []string{"terms", "privacy"},
+		},
+		{
+			name: "user accepted previous terms version",
+			acceptances: []*identity.Acceptance{
+				identity.NewAcceptance("terms", "1"),
+				identity.NewAcceptance("privacy", "1"),
+			},
+			want: []string{"terms"},
+		},
+		{
+			name: "user accepted current versions",
+			acceptances: []*identity.Acceptance{
+				identity.NewAcceptance("terms", "2"),
+				identity.NewAcceptance("privacy", "1"),
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, doc := range cfg.GetPending(tc.acceptances) {
+				got = append(got, doc.Name)
+			}
+			tests.EvalObjectsWithLog(t, "pending", tc.want, got, []string{fmt.Sprintf("test name: %s", tc.name)})
+		})
+	}
+}
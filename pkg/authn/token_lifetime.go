@@ -0,0 +1,37 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// getTokenLifetime returns the lifetime, in seconds, of the access token
+// issued to the user. The lifetime of the signing key applies unless the
+// token lifetime policy overrides it for the realm or the roles of the user.
+func (p *Portal) getTokenLifetime(usr *user.User, realm string) int {
+	lifetime := p.keystore.GetTokenLifetime(nil, nil)
+	if p.config.TokenLifetimeConfig == nil || usr == nil {
+		return lifetime
+	}
+	if usr.Authenticator.Realm != "" {
+		realm = usr.Authenticator.Realm
+	}
+	var roles []string
+	if usr.Claims != nil {
+		roles = usr.Claims.Roles
+	}
+	return p.config.TokenLifetimeConfig.GetLifetime(realm, roles, lifetime)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/tokenlifetime"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestTokenLifetime(t *testing.T) {
+	p, err := newTestMultiRealmPortal(t, &PortalConfig{
+		TokenLifetimeConfig: &tokenlifetime.Config{
+			Rules: []*tokenlifetime.Rule{
+				{Realm: "backup", Lifetime: 3600},
+				{Realm: "local", Role: "employee", Lifetime: 43200},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name  string
+		realm string
+		roles []string
+		want  int
+	}{
+		{name: "user in backup realm", realm: "backup", roles: []string{"employee"}, want: 3600},
+		{name: "employee in local realm", realm: "local", roles: []string{"authp/user", "employee"}, want: 43200},
+		{name: "user without matching rule", realm: "local", roles: []string{"authp/user"}, want: p.keystore.GetTokenLifetime(nil, nil)},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			sessionID := util.GetRandomStringFromRange(36, 46)
+			usr, err := user.NewUser(map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": tc.roles,
+				"jti":   sessionID,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			usr.Authenticator.Realm = tc.realm
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/login", nil)
+			rr := requests.NewRequest()
+			rr.Upstream.SessionID = sessionID
+			rr.Upstream.Realm = tc.realm
+			p.grantAccess(context.Background(), w, r, rr, usr)
+			got := usr.Claims.ExpiresAt - time.Now().Unix()
+			tests.EvalObjectsWithLog(t, "lifetime", true, got > int64(tc.want-60) && got <= int64(tc.want), msgs)
+		})
+	}
+}
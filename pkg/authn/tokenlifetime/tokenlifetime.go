@@ -0,0 +1,81 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenlifetime
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config holds the policy table overriding the lifetime of the tokens issued
+// to the users by the realm they authenticated in, i.e. the identity store
+// or the identity provider, and by their roles. For example, the tokens of
+// the guests authenticated by a social login provider live 1 hour, while
+// the ones of the employees authenticated by LDAP live 12 hours. The rules
+// are evaluated when the tokens are issued, and the first matching rule
+// applies. Without a matching rule, the lifetime of the signing key
+// applies.
+type Config struct {
+	Rules []*Rule `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Rule holds the lifetime of the tokens issued in a realm, to the users
+// with a role, or both.
+type Rule struct {
+	// Realm is the realm of the identity store or the identity provider.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Role  string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	// Lifetime is the token lifetime in seconds.
+	Lifetime int `json:"lifetime,omitempty" xml:"lifetime,omitempty" yaml:"lifetime,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for i, rule := range cfg.Rules {
+		if rule.Realm == "" && rule.Role == "" {
+			return errors.ErrTokenLifetimeConfigRuleEmpty.WithArgs(i)
+		}
+		if rule.Lifetime < 1 {
+			return errors.ErrTokenLifetimeConfigLifetime.WithArgs(i, rule.Lifetime)
+		}
+	}
+	return nil
+}
+
+// GetLifetime returns the lifetime of the first rule matching the realm and
+// the roles, or the provided default lifetime.
+func (cfg *Config) GetLifetime(realm string, roles []string, lifetime int) int {
+	if cfg == nil {
+		return lifetime
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Realm != "" && rule.Realm != realm {
+			continue
+		}
+		if rule.Role != "" && !hasRole(roles, rule.Role) {
+			continue
+		}
+		return rule.Lifetime
+	}
+	return lifetime
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenlifetime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{Rules: []*Rule{
+				{Realm: "google", Lifetime: 3600},
+				{Realm: "ldap", Role: "employee", Lifetime: 43200},
+			}},
+		},
+		{
+			name:      "rule without realm and role",
+			config:    &Config{Rules: []*Rule{{Lifetime: 3600}}},
+			shouldErr: true,
+			err:       errors.ErrTokenLifetimeConfigRuleEmpty.WithArgs(0),
+		},
+		{
+			name:      "rule without lifetime",
+			config:    &Config{Rules: []*Rule{{Realm: "google", Lifetime: 3600}, {Role: "guest"}}},
+			shouldErr: true,
+			err:       errors.ErrTokenLifetimeConfigLifetime.WithArgs(1, 0),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestGetLifetime(t *testing.T) {
+	cfg := &Config{Rules: []*Rule{
+		{Realm: "google", Lifetime: 3600},
+		{Realm: "ldap", Role: "employee", Lifetime: 43200},
+		{Role: "authp/admin", Lifetime: 1800},
+	}}
+	testcases := []struct {
+		name   string
+		config *Config
+		realm  string
+		roles  []string
+		want   int
+	}{
+		{name: "guest of provider", config: cfg, realm: "google", roles: []string{"authp/admin"}, want: 3600},
+		{name: "employee in ldap", config: cfg, realm: "ldap", roles: []string{"contractor", "employee"}, want: 43200},
+		{name: "admin in ldap", config: cfg, realm: "ldap", roles: []string{"authp/admin"}, want: 1800},
+		{name: "user without matching rule", config: cfg, realm: "local", roles: []string{"authp/user"}, want: 900},
+		{name: "nil config", realm: "google", want: 900},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			tests.EvalObjectsWithLog(t, "lifetime", tc.want, tc.config.GetLifetime(tc.realm, tc.roles, 900), msgs)
+		})
+	}
+}
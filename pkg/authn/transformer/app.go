@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AppConfig holds the transformation of the claims in the tokens and the
+// assertions issued for an application, e.g. a relying party of the OpenID
+// Connect provider or a SAML service provider.
+type AppConfig struct {
+	// RoleMappings maps the roles of the portal to the roles of the
+	// application, e.g. "authp/admin" to "administrator".
+	RoleMappings map[string]string `json:"role_mappings,omitempty" xml:"role_mappings,omitempty" yaml:"role_mappings,omitempty"`
+	// DropUnmappedRoles removes the roles without a mapping.
+	DropUnmappedRoles bool `json:"drop_unmapped_roles,omitempty" xml:"drop_unmapped_roles,omitempty" yaml:"drop_unmapped_roles,omitempty"`
+	// Claims are the claims passed to the application. When empty, all the
+	// claims are passed. The subject is always passed.
+	Claims []string `json:"claims,omitempty" xml:"claims,omitempty" yaml:"claims,omitempty"`
+}
+
+// Validate validates AppConfig.
+func (cfg *AppConfig) Validate() error {
+	for k, v := range cfg.RoleMappings {
+		if k == "" || v == "" {
+			return fmt.Errorf("app transformer has empty role mapping: %q to %q", k, v)
+		}
+	}
+	for _, k := range cfg.Claims {
+		if k == "" {
+			return fmt.Errorf("app transformer has empty claim name")
+		}
+	}
+	return nil
+}
+
+// Apply returns the claims transformed for the application. The provided
+// claims are not modified.
+func (cfg *AppConfig) Apply(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	if len(cfg.Claims) == 0 {
+		for k, v := range m {
+			out[k] = v
+		}
+	} else {
+		for _, k := range append([]string{"sub"}, cfg.Claims...) {
+			if v, exists := m[k]; exists {
+				out[k] = v
+			}
+		}
+	}
+	if v, exists := out["roles"]; exists {
+		roles := cfg.mapRoles(getRoles(v))
+		if len(roles) > 0 {
+			out["roles"] = roles
+		} else {
+			delete(out, "roles")
+		}
+	}
+	return out
+}
+
+func (cfg *AppConfig) mapRoles(roles []string) []string {
+	var entries []string
+	entryMap := make(map[string]bool)
+	for _, role := range roles {
+		if v, exists := cfg.RoleMappings[role]; exists {
+			role = v
+		} else if cfg.DropUnmappedRoles {
+			continue
+		}
+		if entryMap[role] {
+			continue
+		}
+		entryMap[role] = true
+		entries = append(entries, role)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+func getRoles(v interface{}) []string {
+	var roles []string
+	switch val := v.(type) {
+	case string:
+		roles = append(roles, strings.Fields(val)...)
+	case []string:
+		roles = append(roles, val...)
+	case []interface{}:
+		for _, entry := range val {
+			if s, ok := entry.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return roles
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestAppConfig(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		config *AppConfig
+		claims map[string]interface{}
+		// Expected results.
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "map admin role and drop other claims",
+			config: &AppConfig{
+				RoleMappings: map[string]string{
+					"authp/admin": "administrator",
+				},
+				DropUnmappedRoles: true,
+				Claims:            []string{"roles"},
+			},
+			claims: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": []interface{}{"authp/admin", "authp/user"},
+			},
+			want: map[string]interface{}{
+				"sub":   "jsmith",
+				"roles": []string{"administrator"},
+			},
+		},
+		{
+			name: "map roles and keep unmapped roles",
+			config: &AppConfig{
+				RoleMappings: map[string]string{
+					"authp/admin":  "administrator",
+					"authp/editor": "administrator",
+				},
+			},
+			claims: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": "authp/admin authp/editor authp/user",
+			},
+			want: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": []string{"administrator", "authp/user"},
+			},
+		},
+		{
+			name: "drop roles without mapping",
+			config: &AppConfig{
+				RoleMappings: map[string]string{
+					"authp/admin": "administrator",
+				},
+				DropUnmappedRoles: true,
+			},
+			claims: map[string]interface{}{
+				"sub":   "jsmith",
+				"roles": []string{"authp/user"},
+			},
+			want: map[string]interface{}{
+				"sub": "jsmith",
+			},
+		},
+		{
+			name: "empty role mapping",
+			config: &AppConfig{
+				RoleMappings: map[string]string{
+					"authp/admin": "",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("app transformer has empty role mapping: %q to %q", "authp/admin", ""),
+		},
+		{
+			name: "empty claim name",
+			config: &AppConfig{
+				Claims: []string{""},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("app transformer has empty claim name"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "AppConfig", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := tc.config.Apply(tc.claims)
+			tests.EvalObjectsWithLog(t, "AppConfig", tc.want, got, msgs)
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 	"strings"
 )
@@ -98,6 +99,11 @@ func NewFactory(cfgs []*Config) (*Factory, error) {
 					return nil, fmt.Errorf("transformer for %q erred: invalid action config", encodedArgs)
 				}
 				actions = append(actions, args[1:])
+			case "extension":
+				if len(args) != 2 {
+					return nil, fmt.Errorf("transformer for %q erred: invalid extension config", encodedArgs)
+				}
+				actions = append(actions, args)
 			default:
 				return nil, fmt.Errorf("transformer has unsupported action: %v", args)
 			}
@@ -112,6 +118,9 @@ func NewFactory(cfgs []*Config) (*Factory, error) {
 		if err := matcher.AddRules(context.Background(), matchRuleConfigs); err != nil {
 			return nil, err
 		}
+		if err := matcher.CheckExtensions(context.Background()); err != nil {
+			return nil, err
+		}
 		tr := &transform{
 			matcher: matcher,
 			actions: actions,
@@ -140,6 +149,14 @@ func (f *Factory) Transform(m map[string]interface{}) error {
 				challenges = append(challenges, cfgutil.EncodeArgs(args[1:]))
 			case "link":
 				frontendLinks = append(frontendLinks, cfgutil.EncodeArgs(args[1:]))
+			case "extension":
+				ext, err := extension.Get(args[1])
+				if err != nil {
+					return fmt.Errorf("transformer for %v erred: %v", args, err)
+				}
+				if err := ext.Transform(m); err != nil {
+					return fmt.Errorf("transformer for %v erred: %v", args, err)
+				}
 			default:
 				if err := transformData(args, m); err != nil {
 					return fmt.Errorf("transformer for %v erred: %v", args, err)
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
 	"testing"
 )
 
@@ -92,6 +93,40 @@ func TestFactory(t *testing.T) {
 	}
 }
 
+func TestFactoryExtension(t *testing.T) {
+	ext, err := extension.NewExtension(&extension.Config{
+		Name: "claims",
+		Path: "../../../testdata/extension/claims.wasm",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	extension.Register(ext)
+
+	tr, err := NewFactory([]*Config{
+		{
+			Matchers: []string{"exact match sub jsmith"},
+			Actions:  []string{"extension claims", "require mfa"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := map[string]interface{}{
+		"sub":   "jsmith",
+		"roles": "editor",
+	}
+	if err := tr.Transform(m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"sub":        "wasm",
+		"roles":      []interface{}{"authp/user"},
+		"challenges": []string{"mfa"},
+	}
+	tests.EvalObjects(t, "transformer", want, m)
+}
+
 func TestTransformData(t *testing.T) {
 	var testcases = []struct {
 		name      string
@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+var (
+	brandingColorRegex     = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	brandingForbiddenCSS   = []string{"<", "expression(", "javascript:", "vbscript:", "@import", "behavior:", "-moz-binding"}
+	brandingAllowedSchemes = []string{"/", "https://", "http://", "mailto:"}
+)
+
+// Branding represents the look and feel of portal pages and emails
+// for an authentication realm. The branding with an empty realm applies
+// to the realms without a branding of their own.
+type Branding struct {
+	Realm           string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	ProductName     string `json:"product_name,omitempty" xml:"product_name,omitempty" yaml:"product_name,omitempty"`
+	LogoURL         string `json:"logo_url,omitempty" xml:"logo_url,omitempty" yaml:"logo_url,omitempty"`
+	LogoDescription string `json:"logo_description,omitempty" xml:"logo_description,omitempty" yaml:"logo_description,omitempty"`
+	PrimaryColor    string `json:"primary_color,omitempty" xml:"primary_color,omitempty" yaml:"primary_color,omitempty"`
+	AccentColor     string `json:"accent_color,omitempty" xml:"accent_color,omitempty" yaml:"accent_color,omitempty"`
+	FooterLinks     []Link `json:"footer_links,omitempty" xml:"footer_links,omitempty" yaml:"footer_links,omitempty"`
+	// The custom CSS is either inline or loaded from a file.
+	CustomCSS     string `json:"custom_css,omitempty" xml:"custom_css,omitempty" yaml:"custom_css,omitempty"`
+	CustomCSSPath string `json:"custom_css_path,omitempty" xml:"custom_css_path,omitempty" yaml:"custom_css_path,omitempty"`
+	style         string
+}
+
+// Validate validates branding configuration and prepares the style
+// injected into portal pages.
+func (b *Branding) Validate() error {
+	for k, v := range map[string]string{"primary": b.PrimaryColor, "accent": b.AccentColor} {
+		if v != "" && !brandingColorRegex.MatchString(v) {
+			return fmt.Errorf("invalid %s color %q", k, v)
+		}
+	}
+	if strings.ContainsAny(b.LogoURL, "\"'<> ") {
+		return fmt.Errorf("invalid logo url %q", b.LogoURL)
+	}
+	if b.LogoURL != "" && !hasAllowedScheme(b.LogoURL) {
+		return fmt.Errorf("invalid logo url %q", b.LogoURL)
+	}
+	for _, lnk := range b.FooterLinks {
+		if lnk.Title == "" {
+			return fmt.Errorf("footer link %q has no title", lnk.Link)
+		}
+		if strings.ContainsAny(lnk.Link, "\"'<> ") || !hasAllowedScheme(lnk.Link) {
+			return fmt.Errorf("invalid footer link %q", lnk.Link)
+		}
+	}
+
+	css := b.CustomCSS
+	if b.CustomCSSPath != "" {
+		if css != "" {
+			return fmt.Errorf("custom css and custom css path are mutually exclusive")
+		}
+		content, err := ioutil.ReadFile(b.CustomCSSPath)
+		if err != nil {
+			return fmt.Errorf("failed to load custom css from %s: %v", b.CustomCSSPath, err)
+		}
+		css = string(content)
+	}
+	lowerCSS := strings.ToLower(css)
+	for _, s := range brandingForbiddenCSS {
+		if strings.Contains(lowerCSS, s) {
+			return fmt.Errorf("custom css contains forbidden %q", s)
+		}
+	}
+
+	var sb strings.Builder
+	if b.PrimaryColor != "" || b.AccentColor != "" {
+		sb.WriteString(":root {")
+		if b.PrimaryColor != "" {
+			sb.WriteString(" --app-primary-color: " + b.PrimaryColor + ";")
+		}
+		if b.AccentColor != "" {
+			sb.WriteString(" --app-accent-color: " + b.AccentColor + ";")
+		}
+		sb.WriteString(" }\n")
+		if b.PrimaryColor != "" {
+			sb.WriteString(".app-btn-pri { background-color: var(--app-primary-color); }\n")
+		}
+		if b.AccentColor != "" {
+			sb.WriteString(".app-footer-lnk { color: var(--app-accent-color); }\n")
+		}
+	}
+	sb.WriteString(strings.TrimSpace(css))
+	b.style = strings.TrimSpace(sb.String())
+	return nil
+}
+
+// Apply overrides page attributes with the branding. The values are
+// escaped, because the page templates do not escape them.
+func (b *Branding) Apply(args *Args) {
+	if b.ProductName != "" {
+		args.ProductName = html.EscapeString(b.ProductName)
+		args.MetaTitle = args.ProductName
+		args.LogoDescription = args.ProductName
+	}
+	if b.LogoURL != "" {
+		args.LogoURL = b.LogoURL
+	}
+	if b.LogoDescription != "" {
+		args.LogoDescription = html.EscapeString(b.LogoDescription)
+	}
+	if len(b.FooterLinks) > 0 {
+		args.FooterLinks = []Link{}
+		for _, lnk := range b.FooterLinks {
+			lnk.Title = html.EscapeString(lnk.Title)
+			if lnk.OpenNewWindow {
+				lnk.Target = "_blank"
+				lnk.TargetEnabled = true
+			}
+			args.FooterLinks = append(args.FooterLinks, lnk)
+		}
+	}
+	args.BrandingStyle = b.style
+}
+
+// GetEmailData returns the branding attributes used by email templates.
+func (b *Branding) GetEmailData() map[string]string {
+	m := make(map[string]string)
+	if b.ProductName != "" {
+		m["brand_name"] = html.EscapeString(b.ProductName)
+	}
+	if strings.HasPrefix(b.LogoURL, "https://") || strings.HasPrefix(b.LogoURL, "http://") {
+		m["brand_logo_url"] = b.LogoURL
+	}
+	if b.PrimaryColor != "" {
+		m["brand_primary_color"] = b.PrimaryColor
+	}
+	if b.AccentColor != "" {
+		m["brand_accent_color"] = b.AccentColor
+	}
+	var links []string
+	for _, lnk := range b.FooterLinks {
+		links = append(links, fmt.Sprintf("<a href=\"%s\">%s</a>", lnk.Link, html.EscapeString(lnk.Title)))
+	}
+	if len(links) > 0 {
+		m["brand_footer"] = strings.Join(links, " | ")
+	}
+	return m
+}
+
+// AddBranding adds realm branding to Factory.
+func (f *Factory) AddBranding(b *Branding) error {
+	if f.Branding == nil {
+		f.Branding = make(map[string]*Branding)
+	}
+	if _, exists := f.Branding[b.Realm]; exists {
+		return fmt.Errorf("branding for %q realm already defined", b.Realm)
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	f.Branding[b.Realm] = b
+	return nil
+}
+
+// GetBranding returns the branding of a realm. If the realm has no
+// branding, the default branding is returned, if any.
+func (f *Factory) GetBranding(realm string) *Branding {
+	if b, exists := f.Branding[realm]; exists {
+		return b
+	}
+	if b, exists := f.Branding[""]; exists {
+		return b
+	}
+	return nil
+}
+
+// GetRealmArgs returns an instance of Args with the branding of
+// a realm applied.
+func (f *Factory) GetRealmArgs(realm string) *Args {
+	args := f.GetArgs()
+	if b := f.GetBranding(realm); b != nil {
+		b.Apply(args)
+	}
+	return args
+}
+
+func hasAllowedScheme(s string) bool {
+	for _, prefix := range brandingAllowedSchemes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
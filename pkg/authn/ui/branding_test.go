@@ -0,0 +1,154 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestBranding(t *testing.T) {
+	testcases := []struct {
+		name      string
+		branding  *Branding
+		realm     string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test realm branding",
+			branding: &Branding{
+				Realm:        "contoso",
+				ProductName:  "Contoso <SSO>",
+				LogoURL:      "https://contoso.com/logo.png",
+				PrimaryColor: "#004080",
+				AccentColor:  "#fc0",
+				FooterLinks: []Link{
+					{Title: "Privacy", Link: "https://contoso.com/privacy", OpenNewWindow: true},
+				},
+				CustomCSS: ".logo-img { width: 64px; }",
+			},
+			realm: "contoso",
+			want: map[string]interface{}{
+				"product_name": "Contoso &lt;SSO&gt;",
+				"meta_title":   "Contoso &lt;SSO&gt;",
+				"logo_url":     "https://contoso.com/logo.png",
+				"footer":       `<a class="app-footer-lnk" href="https://contoso.com/privacy" target="_blank">Privacy</a>`,
+				"style": strings.Join([]string{
+					":root { --app-primary-color: #004080; --app-accent-color: #fc0; }",
+					".app-btn-pri { background-color: var(--app-primary-color); }",
+					".app-footer-lnk { color: var(--app-accent-color); }",
+					".logo-img { width: 64px; }",
+				}, "\n"),
+				"email": map[string]string{
+					"brand_name":          "Contoso &lt;SSO&gt;",
+					"brand_logo_url":      "https://contoso.com/logo.png",
+					"brand_primary_color": "#004080",
+					"brand_accent_color":  "#fc0",
+					"brand_footer":        `<a href="https://contoso.com/privacy">Privacy</a>`,
+				},
+			},
+		},
+		{
+			name: "test default branding for unknown realm",
+			branding: &Branding{
+				ProductName: "Acme",
+			},
+			realm: "local",
+			want: map[string]interface{}{
+				"product_name": "Acme",
+				"meta_title":   "Acme",
+				"logo_url":     "/assets/images/logo.svg",
+				"footer":       "",
+				"style":        "",
+				"email": map[string]string{
+					"brand_name": "Acme",
+				},
+			},
+		},
+		{
+			name: "test invalid color",
+			branding: &Branding{
+				PrimaryColor: "red;}",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("invalid primary color %q", "red;}"),
+		},
+		{
+			name: "test script in custom css",
+			branding: &Branding{
+				CustomCSS: "</style><script>alert(1)</script>",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("custom css contains forbidden %q", "<"),
+		},
+		{
+			name: "test javascript footer link",
+			branding: &Branding{
+				FooterLinks: []Link{
+					{Title: "Help", Link: "javascript:alert(1)"},
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("invalid footer link %q", "javascript:alert(1)"),
+		},
+		{
+			name: "test custom css and path",
+			branding: &Branding{
+				CustomCSS:     "body {}",
+				CustomCSSPath: "/tmp/custom.css",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("custom css and custom css path are mutually exclusive"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			f := NewFactory()
+			err := f.AddBranding(tc.branding)
+			if tests.EvalErrWithLog(t, err, "branding", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			if err := f.AddBuiltinTemplate("basic/generic"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			args := f.GetRealmArgs(tc.realm)
+			b, err := f.Render("basic/generic", args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"product_name": args.ProductName,
+				"meta_title":   args.MetaTitle,
+				"logo_url":     args.LogoURL,
+				"footer":       "",
+				"style":        args.BrandingStyle,
+				"email":        f.GetBranding(tc.realm).GetEmailData(),
+			}
+			if i := strings.Index(b.String(), `<a class="app-footer-lnk"`); i >= 0 {
+				got["footer"] = b.String()[i : i+strings.Index(b.String()[i:], "</a>")+4]
+			}
+			if args.BrandingStyle != "" && !strings.Contains(b.String(), "<style>"+args.BrandingStyle+"</style>") {
+				t.Fatalf("rendered page has no branding style")
+			}
+			tests.EvalObjectsWithLog(t, "branding", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// TemplateFuncs is the set of functions available to page templates in
+// addition to the comparison, logical, length, index, slice, print and
+// escaping functions built into text/template. The "call" built-in is
+// not available, because it invokes arbitrary functions.
+//
+//   - pathjoin: joins path elements, e.g. {{ pathjoin .ActionEndpoint "/login" }}.
+//   - brsplitline: inserts a line break after every 25 characters.
+//
+// The data passed to page templates is Args. The templates may only
+// reference the fields of Args, and not its methods.
+var TemplateFuncs = template.FuncMap{
+	"pathjoin": path.Join,
+	"brsplitline": func(s string) string {
+		var output []rune
+		var count = 0
+		for _, c := range s {
+			count++
+			if count > 25 {
+				count = 0
+				output = append(output, []rune{'<', 'b', 'r', '>'}...)
+			}
+			output = append(output, c)
+		}
+		return string(output)
+	},
+}
+
+var templateBuiltinFuncs = map[string]bool{
+	"and": true, "or": true, "not": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"len": true, "index": true, "slice": true,
+	"print": true, "printf": true, "println": true,
+	"html": true, "js": true, "urlquery": true,
+}
+
+var templateArgsFields = getTemplateArgsFields()
+
+func getTemplateArgsFields() map[string]bool {
+	m := make(map[string]bool)
+	t := reflect.TypeOf(Args{})
+	for i := 0; i < t.NumField(); i++ {
+		m[t.Field(i).Name] = true
+	}
+	return m
+}
+
+// validateTemplate checks that a page template uses only the permitted
+// functions and that it references the existing fields of Args.
+func validateTemplate(t *template.Template) error {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		// The dot of the associated templates is not known.
+		root := tmpl.Name() == t.Name()
+		if err := validateTemplateNode(tmpl.Tree.Root, root); err != nil {
+			return fmt.Errorf("%s: %v", tmpl.Name(), err)
+		}
+	}
+	return nil
+}
+
+func validateTemplateNode(node parse.Node, root bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, entry := range n.Nodes {
+			if err := validateTemplateNode(entry, root); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return validateTemplateNode(n.Pipe, root)
+	case *parse.IfNode:
+		return validateTemplateBranch(&n.BranchNode, root, root)
+	case *parse.RangeNode:
+		return validateTemplateBranch(&n.BranchNode, root, false)
+	case *parse.WithNode:
+		return validateTemplateBranch(&n.BranchNode, root, false)
+	case *parse.TemplateNode:
+		return validateTemplateNode(n.Pipe, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := validateTemplateNode(cmd, root); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := validateTemplateNode(arg, root); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return validateTemplateNode(n.Node, root)
+	case *parse.IdentifierNode:
+		if _, exists := TemplateFuncs[n.Ident]; exists {
+			return nil
+		}
+		if !templateBuiltinFuncs[n.Ident] {
+			return fmt.Errorf("function %q is not permitted", n.Ident)
+		}
+	case *parse.FieldNode:
+		if root && !templateArgsFields[n.Ident[0]] {
+			return fmt.Errorf("field %q does not exist", n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 && !templateArgsFields[n.Ident[1]] {
+			return fmt.Errorf("field %q does not exist", n.Ident[1])
+		}
+	}
+	return nil
+}
+
+func validateTemplateBranch(n *parse.BranchNode, root, bodyRoot bool) error {
+	if err := validateTemplateNode(n.Pipe, root); err != nil {
+		return err
+	}
+	if err := validateTemplateNode(n.List, bodyRoot); err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return validateTemplateNode(n.ElseList, root)
+	}
+	return nil
+}
+
+// AddTemplatesDir adds the page templates found in a directory to Factory.
+// The name of a template file is the name of the page it overrides with
+// the ".template" extension, e.g. login.template. The templates override
+// the built-in templates of a theme.
+func (f *Factory) AddTemplatesDir(dir, theme string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory %s: %v", dir, err)
+	}
+	var names []string
+	modTimes := make(map[string]time.Time)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".template" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".template")
+		if _, exists := PageTemplates[theme+"/"+name]; !exists {
+			return nil, fmt.Errorf("template %s in %s does not override any of the %s theme pages", entry.Name(), dir, theme)
+		}
+		fp := filepath.Join(dir, entry.Name())
+		tmpl, err := NewTemplate(name, fp)
+		if err != nil {
+			return nil, err
+		}
+		f.mu.Lock()
+		f.Templates[name] = tmpl
+		f.mu.Unlock()
+		modTimes[fp] = entry.ModTime()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f.mu.Lock()
+	f.templatesDir = dir
+	f.templatesModTimes = modTimes
+	f.mu.Unlock()
+	return names, nil
+}
+
+// ReloadTemplatesDir reloads the templates added with AddTemplatesDir
+// whose files changed. When a changed template fails to load, the
+// previous version of the template remains in use.
+func (f *Factory) ReloadTemplatesDir() ([]string, error) {
+	f.mu.RLock()
+	modTimes := make(map[string]time.Time)
+	for fp, modTime := range f.templatesModTimes {
+		modTimes[fp] = modTime
+	}
+	f.mu.RUnlock()
+
+	var names []string
+	var errs []string
+	for fp, modTime := range modTimes {
+		fileInfo, err := os.Stat(fp)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if fileInfo.ModTime().Equal(modTime) {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(fp), ".template")
+		tmpl, err := NewTemplate(name, fp)
+		f.mu.Lock()
+		f.templatesModTimes[fp] = fileInfo.ModTime()
+		if err == nil {
+			f.Templates[name] = tmpl
+		}
+		f.mu.Unlock()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return names, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return names, nil
+}
+
+// WatchTemplatesDir periodically reloads the changed templates added with
+// AddTemplatesDir. The callback receives the outcome of every reload that
+// changed anything.
+func (f *Factory) WatchTemplatesDir(interval time.Duration, callback func([]string, error)) {
+	f.mu.Lock()
+	if f.templatesWatchExit != nil {
+		f.mu.Unlock()
+		return
+	}
+	exit := make(chan bool)
+	f.templatesWatchExit = exit
+	f.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				names, err := f.ReloadTemplatesDir()
+				if (len(names) > 0 || err != nil) && callback != nil {
+					callback(names, err)
+				}
+			}
+		}
+	}()
+}
+
+// StopWatchingTemplatesDir stops the reloading of templates started
+// with WatchTemplatesDir.
+func (f *Factory) StopWatchingTemplatesDir() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.templatesWatchExit == nil {
+		return
+	}
+	close(f.templatesWatchExit)
+	f.templatesWatchExit = nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		body      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid template",
+			body: `{{ $n := len .Realms }}<a href="{{ pathjoin .ActionEndpoint "/login" }}">{{ .PageTitle | html }}</a>` +
+				`{{ range .FooterLinks }}{{ .Title }}{{ $.MetaTitle }}{{ end }}{{ with .Data.user }}{{ .email }}{{ end }}`,
+		},
+		{
+			name:      "test call function",
+			body:      `{{ call .Data.fn }}`,
+			shouldErr: true,
+			err:       fmt.Errorf("login: function %q is not permitted", "call"),
+		},
+		{
+			name:      "test unknown field",
+			body:      `{{ if .Foo }}{{ end }}`,
+			shouldErr: true,
+			err:       fmt.Errorf("login: field %q does not exist", "Foo"),
+		},
+		{
+			name:      "test method",
+			body:      `{{ .BaseURL "/" }}`,
+			shouldErr: true,
+			err:       fmt.Errorf("login: field %q does not exist", "BaseURL"),
+		},
+		{
+			name:      "test unknown root field in range",
+			body:      `{{ range .Realms }}{{ $.Bar }}{{ end }}`,
+			shouldErr: true,
+			err:       fmt.Errorf("login: field %q does not exist", "Bar"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			_, err := loadTemplateFromString("login", tc.body)
+			tests.EvalErrWithLog(t, err, "template", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestTemplatesDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, "login.template")
+	if err := ioutil.WriteFile(fp, []byte(`v1 {{ .PageTitle }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFactory()
+	names, err := f.AddTemplatesDir(dir, "basic")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "names", []string{"login"}, names)
+	render := func() string {
+		b, err := f.Render("login", &Args{PageTitle: "Sign In"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return b.String()
+	}
+	tests.EvalObjects(t, "initial", "v1 Sign In", render())
+
+	names, err = f.ReloadTemplatesDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "unchanged", 0, len(names))
+
+	updateTemplate := func(s string, ts time.Time) {
+		if err := ioutil.WriteFile(fp, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(fp, ts, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updateTemplate(`v2 {{ .PageTitle }}`, time.Now().Add(time.Minute))
+	names, err = f.ReloadTemplatesDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "reloaded", []string{"login"}, names)
+	tests.EvalObjects(t, "updated", "v2 Sign In", render())
+
+	// The invalid template does not replace the last valid one.
+	updateTemplate(`v3 {{ .Secret }}`, time.Now().Add(2*time.Minute))
+	if _, err := f.ReloadTemplatesDir(); err == nil {
+		t.Fatalf("expected error, but got success")
+	}
+	tests.EvalObjects(t, "kept", "v2 Sign In", render())
+
+	updateTemplate(`v4`, time.Now().Add(3*time.Minute))
+	if err := ioutil.WriteFile(filepath.Join(dir, "logon.template"), []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewFactory().AddTemplatesDir(dir, "basic")
+	tests.EvalErr(t, err, "dir", true, fmt.Errorf("template logon.template in %s does not override any of the basic theme pages", dir))
+}
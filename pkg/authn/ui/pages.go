@@ -19,6 +19,12 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
+    {{ if .Data.idp_hint }}
+      <meta http-equiv="refresh" content="{{ .Data.idp_hint.delay }}; url={{ pathjoin .ActionEndpoint .Data.idp_hint.endpoint }}" />
+    {{ end }}
   </head>
 
   {{ $authenticatorCount := len .Data.login_options.authenticators }}
@@ -36,7 +42,28 @@ var PageTemplates = map[string]string{
             <h2 class="logo-txt">{{ .PageTitle }}</h2>
           </div>
 
-          {{ if eq .Data.login_options.form_required "yes" }}
+          {{ if .Data.maintenance_message }}
+            <div id="maintenance_message" class="pb-4">
+              <p class="block text-center text-lg font-sans font-medium text-primary-700">{{ .Data.maintenance_message }}</p>
+            </div>
+          {{ end }}
+
+          {{ if .Data.idp_hint }}
+            <div id="idp_hint" class="flex flex-col gap-4">
+              <p class="block text-center pb-2 text-lg font-sans font-medium text-primary-700">Continuing with {{ .Data.idp_hint.text }}</p>
+              <a href="{{ pathjoin .ActionEndpoint .Data.idp_hint.endpoint }}">
+                <button type="button" class="app-btn-pri">
+                  <div><i class="las la-check-circle"></i></div>
+                  <div class="pl-2"><span>Continue</span></div>
+                </button>
+              </a>
+              <div id="use_another_account_link" class="text-center">
+                <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/login" }}?use_another_account=true">Use another account</a>
+              </div>
+            </div>
+          {{ end }}
+
+          {{ if and (not .Data.idp_hint) (eq .Data.login_options.form_required "yes") }}
             <div id="loginform" {{ if ne $authenticatorCount 1 }}class="hidden"{{ end }}>
               <div>
                 <form class="space-y-6" action="{{ pathjoin .ActionEndpoint "/login" }}" method="POST">
@@ -103,6 +130,20 @@ var PageTemplates = map[string]string{
                   </a>
                 </div>
 
+                <div id="forgot_password_link" {{ if ne .Data.login_options.hide_forgot_password_link "no" }}class="hidden"{{ end -}}>
+                  <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/forgot" }}?realm={{ .Data.login_options.default_realm }}">
+                    <i class="las la-key"></i>
+                    <span class="text-lg">Forgot Password?</span>
+                  </a>
+                </div>
+
+                <div id="magic_link" {{ if ne .Data.login_options.hide_magic_link "no" }}class="hidden"{{ end -}}>
+                  <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/magic-link" }}?realm={{ .Data.login_options.default_realm }}">
+                    <i class="las la-envelope"></i>
+                    <span class="text-lg">Email Sign In Link</span>
+                  </a>
+                </div>
+
                 <div id="contact_support_link" {{ if eq .Data.login_options.hide_contact_support_link "yes" }}class="hidden"{{ end -}}>
                   <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/help" .Data.login_options.default_realm }}">
                     <i class="las la-info-circle"></i>
@@ -113,7 +154,7 @@ var PageTemplates = map[string]string{
             </div>
           {{ end }}
 
-          {{ if eq .Data.login_options.authenticators_required "yes" }}
+          {{ if and (not .Data.idp_hint) (eq .Data.login_options.authenticators_required "yes") }}
             <div id="authenticators" class="flex flex-col gap-2">
               {{ range .Data.login_options.authenticators }}
                 <div>
@@ -160,6 +201,13 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_js_required "yes" }}
       <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/portal": `<!DOCTYPE html>
@@ -179,6 +227,9 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -226,6 +277,13 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_js_required "yes" }}
       <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/whoami": `<!DOCTYPE html>
@@ -246,6 +304,9 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -294,6 +355,13 @@ var PageTemplates = map[string]string{
     <script>
       hljs.initHighlightingOnLoad();
     </script>
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/register": `<!DOCTYPE html>
@@ -314,6 +382,9 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -423,6 +494,19 @@ var PageTemplates = map[string]string{
                 </div>
                 {{ end }}
 
+                {{ if or .Data.require_invite_code .Data.invite_code }}
+                <div>
+                  <label for="registrant_invite" class="app-gen-inp-lbl">Invitation Code</label>
+                  <div class="mt-1">
+                    <input type="text" id="registrant_invite" name="registrant_invite"
+                      class="app-gen-inp-txt validate" value="{{ .Data.invite_code }}"
+                      autocorrect="off" autocapitalize="off" autocomplete="off" spellcheck="false"
+                      {{ if .Data.require_invite_code }}required{{ end }}
+                    />
+                  </div>
+                </div>
+                {{ end }}
+
                 {{ if .Data.require_accept_terms }}
                 <div class="sm:col-span-2">
                   <div class="flex items-start">
@@ -444,6 +528,58 @@ var PageTemplates = map[string]string{
                   </div>
                 </div>
                 {{ end }}
+
+                {{ if .Data.captcha_provider }}
+                <div class="sm:col-span-2">
+                  {{ if eq .Data.captcha_provider "hcaptcha" }}
+                  <div class="h-captcha" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                  <script src="https://js.hcaptcha.com/1/api.js" async defer></script>
+                  {{ end }}
+                  {{ if eq .Data.captcha_provider "recaptcha" }}
+                  <div class="g-recaptcha" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                  <script src="https://www.google.com/recaptcha/api.js" async defer></script>
+                  {{ end }}
+                  {{ if eq .Data.captcha_provider "turnstile" }}
+                  <div class="cf-turnstile" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                  <script src="https://challenges.cloudflare.com/turnstile/v0/api.js" async defer></script>
+                  {{ end }}
+                  {{ if eq .Data.captcha_provider "pow" }}
+                  <input type="hidden" id="pow-response" name="pow-response"
+                    data-puzzle="{{ .Data.captcha_puzzle }}" data-difficulty="{{ .Data.captcha_difficulty }}" />
+                  <p id="pow-status" class="text-base text-gray-500">Verifying your browser, please wait.</p>
+                  <script>
+                  (async function() {
+                    const field = document.getElementById("pow-response");
+                    const status = document.getElementById("pow-status");
+                    const submit = field.form.querySelector('button[type="submit"]');
+                    const puzzle = field.dataset.puzzle;
+                    const difficulty = parseInt(field.dataset.difficulty, 10);
+                    const encoder = new TextEncoder();
+                    submit.disabled = true;
+                    for (let nonce = 0; ; nonce++) {
+                      const solution = puzzle + ":" + nonce;
+                      const digest = new Uint8Array(await crypto.subtle.digest("SHA-256", encoder.encode(solution)));
+                      let zeros = 0;
+                      for (const b of digest) {
+                        if (b === 0) {
+                          zeros += 8;
+                          continue;
+                        }
+                        zeros += Math.clz32(b) - 24;
+                        break;
+                      }
+                      if (zeros >= difficulty) {
+                        field.value = solution;
+                        break;
+                      }
+                    }
+                    status.textContent = "Your browser has been verified.";
+                    submit.disabled = false;
+                  })();
+                  </script>
+                  {{ end }}
+                </div>
+                {{ end }}
               {{ end }}
 
               {{ if eq .Data.view "registered" }}
@@ -554,6 +690,318 @@ var PageTemplates = map[string]string{
     }
     </script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
+  </body>
+</html>`,
+	"basic/recover": `<!DOCTYPE html>
+<html lang="en" class="h-full bg-blue-100">
+  <head>
+    <title>{{ .MetaTitle }} - {{ .PageTitle }}</title>
+    <!-- Required meta tags -->
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no" />
+    <meta name="description" content="{{ .MetaDescription }}" />
+    <meta name="author" content="{{ .MetaAuthor }}" />
+    <link rel="shortcut icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/line-awesome/line-awesome.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/roboto.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/montserrat.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/register.css" }}" />
+    {{ if eq .Data.ui_options.custom_css_required "yes" }}
+      <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
+    {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
+  </head>
+
+  <body class="h-full">
+    <div class="app-page">
+      <div class="app-content">
+        <div class="app-container">
+          <div class="logo-col-box justify-center">
+            {{ if .LogoURL }}
+              <div>
+                <img class="logo-img" src="{{ .LogoURL }}" alt="{{ .LogoDescription }}" />
+              </div>
+            {{ end }}
+            <div>
+              <h2 class="logo-col-txt">{{ .PageTitle }}</h2>
+            </div>
+          </div>
+
+          {{ if .Message }}
+          <div id="alerts" class="rounded-md bg-red-50 p-4">
+            <div class="flex items-center">
+              <div class="flex-shrink-0"><i class="las la-exclamation-triangle text-2xl text-red-600"></i></div>
+              <div class="ml-3"><p class="text-sm font-medium text-red-800">{{ .Message }}</p></div>
+              <div class="ml-auto pl-3">
+                <div class="-mx-1.5 -my-1.5">
+                  <button type="button" onclick="hideAlert(); return false;" class="app-alert-banner">
+                    <span class="sr-only">Dismiss</span>
+                    <i class="las la-times text-2xl text-red-600"></i>
+                  </button>
+                </div>
+              </div>
+            </div>
+          </div>
+          {{ end }}
+
+          <div class="mt-3">
+              {{ if eq .Data.view "forgot" }}
+              <form method="POST" action="{{ pathjoin .ActionEndpoint "/forgot" }}">
+                <input type="hidden" name="realm" value="{{ .Data.realm }}" />
+                <div class="pb-4">
+                  <label for="email" class="app-gen-inp-lbl">Email</label>
+                  <div class="mt-1">
+                    <input id="email" name="email" type="email"
+                      class="app-gen-inp-txt validate"
+                      autocorrect="off" autocapitalize="off" autocomplete="email" spellcheck="false"
+                      required
+                    />
+                  </div>
+                </div>
+              {{ end }}
+
+              {{ if eq .Data.view "reset" }}
+              <form method="POST" action="{{ pathjoin .ActionEndpoint "/recover" }}">
+                <input type="hidden" name="token" value="{{ .Data.token }}" />
+                <div class="pb-4">
+                  <label for="secret" class="app-gen-inp-lbl">New Password</label>
+                  <div class="mt-1">
+                    <input type="password" name="secret" id="secret"
+                      class="app-gen-inp-txt validate"
+                      autocorrect="off" autocapitalize="off" autocomplete="new-password" spellcheck="false"
+                      required
+                    />
+                  </div>
+                </div>
+                <div class="pb-4">
+                  <label for="secret_confirm" class="app-gen-inp-lbl">Confirm New Password</label>
+                  <div class="mt-1">
+                    <input type="password" name="secret_confirm" id="secret_confirm"
+                      class="app-gen-inp-txt validate"
+                      autocorrect="off" autocapitalize="off" autocomplete="new-password" spellcheck="false"
+                      required
+                    />
+                  </div>
+                </div>
+              {{ end }}
+
+              {{ if eq .Data.view "sent" }}
+              <div class="app-txt-section">
+                <p>If the email address is associated with an account, you will receive
+                  a password reset link shortly. The link may be used once.</p>
+              </div>
+              {{ end }}
+
+              {{ if eq .Data.view "failed" }}
+              <div class="app-txt-section">
+                <p>Unfortunately, things did not go as expected. {{ .Data.message }}.</p>
+              </div>
+              {{ end }}
+
+              {{ if eq .Data.view "done" }}
+              <div class="app-txt-section">
+                <p>Your password has been changed. The sessions established with
+                  the previous password have been terminated.</p>
+              </div>
+              {{ end }}
+
+              <div class="pt-2">
+                <div class="flex gap-4 justify-end">
+                  <a href="{{ .ActionEndpoint }}">
+                    <button type="button" name="portal" class="app-btn-sec">
+                      <div><i class="las la-home"></i></div>
+                      <div class="pl-1 pr-2"><span>Home</span></div>
+                    </button>
+                  </a>
+                  {{ if or (eq .Data.view "forgot") (eq .Data.view "reset") }}
+                  <button type="submit" name="submit" class="app-btn-pri">
+                    <div><i class="las la-check"></i></div>
+                    <div class="pl-1 pr-2"><span>Submit</span></div>
+                  </button>
+                  {{ end }}
+                </div>
+              </div>
+
+            {{ if or (eq .Data.view "forgot") (eq .Data.view "reset") }}
+            </form>
+            {{ end }}
+          </div>
+        </div>
+      </div>
+    </div>
+    <!-- JavaScript -->
+    <script src="{{ pathjoin .ActionEndpoint "/assets/js/register.js" }}"></script>
+    {{ if eq .Data.ui_options.custom_js_required "yes" }}
+      <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
+    {{ end }}
+    {{ if .Message }}
+    <script>
+    function hideAlert() {
+      document.getElementById("alerts").remove();
+    }
+    </script>
+    {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
+  </body>
+</html>`,
+	"basic/magic_link": `<!DOCTYPE html>
+<html lang="en" class="h-full bg-blue-100">
+  <head>
+    <title>{{ .MetaTitle }} - {{ .PageTitle }}</title>
+    <!-- Required meta tags -->
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no" />
+    <meta name="description" content="{{ .MetaDescription }}" />
+    <meta name="author" content="{{ .MetaAuthor }}" />
+    <link rel="shortcut icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/line-awesome/line-awesome.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/roboto.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/montserrat.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/register.css" }}" />
+    {{ if eq .Data.ui_options.custom_css_required "yes" }}
+      <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
+    {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
+  </head>
+
+  <body class="h-full">
+    <div class="app-page">
+      <div class="app-content">
+        <div class="app-container">
+          <div class="logo-col-box justify-center">
+            {{ if .LogoURL }}
+              <div>
+                <img class="logo-img" src="{{ .LogoURL }}" alt="{{ .LogoDescription }}" />
+              </div>
+            {{ end }}
+            <div>
+              <h2 class="logo-col-txt">{{ .PageTitle }}</h2>
+            </div>
+          </div>
+
+          {{ if .Message }}
+          <div id="alerts" class="rounded-md bg-red-50 p-4">
+            <div class="flex items-center">
+              <div class="flex-shrink-0"><i class="las la-exclamation-triangle text-2xl text-red-600"></i></div>
+              <div class="ml-3"><p class="text-sm font-medium text-red-800">{{ .Message }}</p></div>
+              <div class="ml-auto pl-3">
+                <div class="-mx-1.5 -my-1.5">
+                  <button type="button" onclick="hideAlert(); return false;" class="app-alert-banner">
+                    <span class="sr-only">Dismiss</span>
+                    <i class="las la-times text-2xl text-red-600"></i>
+                  </button>
+                </div>
+              </div>
+            </div>
+          </div>
+          {{ end }}
+
+          <div class="mt-3">
+              {{ if eq .Data.view "request" }}
+              <form method="POST" action="{{ pathjoin .ActionEndpoint "/magic-link" }}">
+                <input type="hidden" name="realm" value="{{ .Data.realm }}" />
+                <div class="pb-4">
+                  <label for="email" class="app-gen-inp-lbl">Email</label>
+                  <div class="mt-1">
+                    <input id="email" name="email" type="email"
+                      class="app-gen-inp-txt validate"
+                      autocorrect="off" autocapitalize="off" autocomplete="email" spellcheck="false"
+                      required
+                    />
+                  </div>
+                </div>
+              {{ end }}
+
+              {{ if eq .Data.view "confirm" }}
+              <form method="POST" action="{{ pathjoin .ActionEndpoint "/magic-link" }}">
+                <input type="hidden" name="token" value="{{ .Data.token }}" />
+                <div class="app-txt-section">
+                  <p>The sign in link was requested from a different browser or
+                    network. If you did not request the link, do not continue.</p>
+                </div>
+              {{ end }}
+
+              {{ if eq .Data.view "sent" }}
+              <div class="app-txt-section">
+                <p>If the email address is associated with an account, you will receive
+                  a sign in link shortly. The link may be used once.</p>
+              </div>
+              {{ end }}
+
+              {{ if eq .Data.view "failed" }}
+              <div class="app-txt-section">
+                <p>Unfortunately, things did not go as expected. {{ .Data.message }}.</p>
+              </div>
+              {{ end }}
+
+              <div class="pt-2">
+                <div class="flex gap-4 justify-end">
+                  <a href="{{ .ActionEndpoint }}">
+                    <button type="button" name="portal" class="app-btn-sec">
+                      <div><i class="las la-home"></i></div>
+                      <div class="pl-1 pr-2"><span>Home</span></div>
+                    </button>
+                  </a>
+                  {{ if eq .Data.view "request" }}
+                  <button type="submit" name="submit" class="app-btn-pri">
+                    <div><i class="las la-envelope"></i></div>
+                    <div class="pl-1 pr-2"><span>Send Link</span></div>
+                  </button>
+                  {{ end }}
+                  {{ if eq .Data.view "confirm" }}
+                  <button type="submit" name="submit" class="app-btn-pri">
+                    <div><i class="las la-check"></i></div>
+                    <div class="pl-1 pr-2"><span>Continue</span></div>
+                  </button>
+                  {{ end }}
+                </div>
+              </div>
+
+            {{ if or (eq .Data.view "request") (eq .Data.view "confirm") }}
+            </form>
+            {{ end }}
+          </div>
+        </div>
+      </div>
+    </div>
+    <!-- JavaScript -->
+    <script src="{{ pathjoin .ActionEndpoint "/assets/js/register.js" }}"></script>
+    {{ if eq .Data.ui_options.custom_js_required "yes" }}
+      <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
+    {{ end }}
+    {{ if .Message }}
+    <script>
+    function hideAlert() {
+      document.getElementById("alerts").remove();
+    }
+    </script>
+    {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/generic": `<!DOCTYPE html>
@@ -573,6 +1021,9 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -607,6 +1058,13 @@ var PageTemplates = map[string]string{
     {{ if eq .Data.ui_options.custom_js_required "yes" }}
       <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/settings": `<!doctype html>
@@ -631,13 +1089,16 @@ var PageTemplates = map[string]string{
     {{ if or (eq .Data.view "sshkeys-add") (eq .Data.view "gpgkeys-add") (eq .Data.view "sshkeys-view") (eq .Data.view "gpgkeys-view") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/highlight.js/css/atom-one-dark.min.css" }}" />
     {{ end }}
-    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") }}
+    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") (eq .Data.view "apikeys-rotate-status") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/highlight.js/css/atom-one-dark.min.css" }}" />
     {{ end }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/styles.css" }}" />
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
     {{ if or (eq .Data.view "mfa-add-app") (eq .Data.view "mfa-test-app") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/mfa_app.css" }}" />
     {{ end }}
@@ -682,11 +1143,21 @@ var PageTemplates = map[string]string{
             <a href="{{ pathjoin .ActionEndpoint "/settings/mfa" }}" class="collection-item{{ if eq .Data.view "mfa" }} active{{ end }}">MFA</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/password" }}" class="collection-item{{ if eq .Data.view "password" }} active{{ end }}">Password</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/connected" }}" class="collection-item{{ if eq .Data.view "connected" }} active{{ end }}">Connected Accounts</a>
+            {{ if eq .Data.access_requests_enabled "yes" }}
+            <a href="{{ pathjoin .ActionEndpoint "/settings/access" }}" class="collection-item{{ if eq .Data.view "access" }} active{{ end }}">Access</a>
+            {{ end }}
             <a href="{{ pathjoin .ActionEndpoint "/portal" }}" class="hide-on-med-and-up collection-item">Portal</a>
             <a href="{{ pathjoin .ActionEndpoint "/logout" }}" class="hide-on-med-and-up collection-item">Logout</a>
           </div>
         </div>
         <div class="col s12 l9 app-content">
+          {{ if .Data.read_only_message }}
+          <div class="row">
+            <div class="col s12">
+              <p id="read_only_message"><b>{{ .Data.read_only_message }}</b></p>
+            </div>
+          </div>
+          {{ end }}
           {{ if eq .Data.view "general" }}
           <div class="row">
             <div class="col s12">
@@ -696,11 +1167,42 @@ var PageTemplates = map[string]string{
             {{ if .Data.metadata.Name }}<b>Name</b>: {{ .Data.metadata.Name }}<br/>{{ end }}
             {{ if .Data.metadata.Title }}<b>Title</b>: {{ .Data.metadata.Title }}<br/>{{ end }}
             <b>Username</b>: {{ .Data.metadata.Username }}<br/>
-            <b>Email</b>: {{ .Data.metadata.Email }}<br/>
+            <b>Email</b>: {{ .Data.metadata.Email }}{{ if eq .Data.email_change_enabled "yes" }} (<a href="{{ pathjoin .ActionEndpoint "/settings/email" }}">Change</a>){{ end }}<br/>
             <b>Created</b>: {{ .Data.metadata.Created }}<br/>
             <b>LastModified</b>: {{ .Data.metadata.LastModified }}<br/>
             <b>Revision</b>: {{ .Data.metadata.Revision }}
             </p>
+            {{ if .Data.login_history }}
+            <h2>Recent Sign-Ins</h2>
+            <table class="striped">
+              <thead>
+                <tr>
+                  <th>Time</th>
+                  <th>IP Address</th>
+                  <th>Location</th>
+                  <th>Device</th>
+                  <th>Provider</th>
+                  <th>Outcome</th>
+                </tr>
+              </thead>
+              <tbody>
+              {{ range .Data.login_history }}
+                <tr>
+                  <td>{{ .Timestamp.Format "2006-01-02 15:04:05 MST" }}</td>
+                  <td><code>{{ .Address }}</code></td>
+                  <td>{{ if .City }}{{ .City }}, {{ end }}{{ .Country }}</td>
+                  <td>{{ .Device }}</td>
+                  <td>{{ .Provider }}</td>
+                  <td>{{ .Outcome }}</td>
+                </tr>
+              {{ end }}
+              </tbody>
+            </table>
+            {{ end }}
+            <p><a href="{{ pathjoin .ActionEndpoint "/settings/export" }}" download>Download My Data</a></p>
+            {{ if eq .Data.account_deletion_enabled "yes" }}
+            <p><a href="{{ pathjoin .ActionEndpoint "/settings/delete" }}">Delete Account</a></p>
+            {{ end }}
             {{ else }}
             <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
             {{ end }}
@@ -757,6 +1259,16 @@ var PageTemplates = map[string]string{
                   <div class="input-field">
                     <input placeholder="Comment" name="comment1" id="comment1" type="text" autocorrect="off" autocapitalize="off" autocomplete="off" class="validate">
                   </div>
+                  <p>Optionally, limit the key to space-separated scopes and roles, and set the number of days the key is valid for.</p>
+                  <div class="input-field">
+                    <input placeholder="Scopes" name="scopes" id="scopes" type="text" autocorrect="off" autocapitalize="off" autocomplete="off">
+                  </div>
+                  <div class="input-field">
+                    <input placeholder="Roles" name="roles" id="roles" type="text" autocorrect="off" autocapitalize="off" autocomplete="off">
+                  </div>
+                  <div class="input-field">
+                    <input placeholder="Expires In (Days)" name="expires_in_days" id="expires_in_days" type="number" min="1">
+                  </div>
                   <div class="right">
                     <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
                       <i class="las la-plus-circle left app-btn-icon"></i>
@@ -956,9 +1468,15 @@ var PageTemplates = map[string]string{
                   <p>
                     <b>ID</b>: {{ .ID }}<br/>
                     <b>Created At</b>: {{ .CreatedAt }}
+                    {{ if .Scopes }}<br/><b>Scopes</b>: {{ range .Scopes }}<code>{{ . }}</code> {{ end }}{{ end }}
+                    {{ if .Roles }}<br/><b>Roles</b>: {{ range .Roles }}<code>{{ . }}</code> {{ end }}{{ end }}
+                    {{ if not .ExpiresAt.IsZero }}<br/><b>Expires At</b>: {{ .ExpiresAt }}{{ end }}
+                    {{ if .RotatedTo }}<br/><b>Rotated To</b>: {{ .RotatedTo }}{{ end }}
+                    {{ if not .LastUsedAt.IsZero }}<br/><b>Last Used</b>: {{ .LastUsedAt }} from <code>{{ .LastUsedAddress }}</code>{{ end }}
                   </p>
                 </div>
                 <div class="card-action">
+                  {{ if not .RotatedTo }}<a href="{{ pathjoin $.ActionEndpoint "/settings/apikeys/rotate" .ID }}">Rotate</a>{{ end }}
                   <a href="{{ pathjoin $.ActionEndpoint "/settings/apikeys/delete" .ID }}">Delete</a>
                 </div>
               </div>
@@ -988,7 +1506,7 @@ var PageTemplates = map[string]string{
               </div>
             </form>
           {{ end }}
-          {{ if eq .Data.view "apikeys-add-status" }}
+          {{ if or (eq .Data.view "apikeys-add-status") (eq .Data.view "apikeys-rotate-status") }}
           <div class="row">
             <div class="col s12">
               <h1>API Key</h1>
@@ -1338,72 +1856,201 @@ var PageTemplates = map[string]string{
               </div>
             </form>
           {{ end }}
-          {{ if eq .Data.view "mfa-test-u2f-status" }}
+          {{ if eq .Data.view "mfa-test-u2f-status" }}
+          <div class="row">
+            <div class="col s12">
+            <h1>Test Token</h1>
+            <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
+            {{ if eq .Data.status "SUCCESS" }}
+              <a href="{{ pathjoin .ActionEndpoint "/settings/mfa" }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active">
+                  <i class="las la-undo-alt left app-btn-icon"></i>
+                  <span class="app-btn-text">Go Back</span>
+                </button>
+              </a>
+            {{ else }}
+              {{ if ne .Data.mfa_token_id "" }}
+              <a href="{{ pathjoin .ActionEndpoint "/settings/mfa/test/u2f/generic" .Data.mfa_token_id }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active">
+                  <i class="las la-undo-alt left app-btn-icon"></i>
+                  <span class="app-btn-text">Try Again</span>
+                </button>
+              </a>
+              {{ end }}
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "password" }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/password/edit" }}" method="POST">
+              <div class="row">
+                <h1>Password Management</h1>
+                <div class="row">
+                  <div class="col s12 m6 l6">
+                    <p>If you want to change your password, please provide your current password and 
+                    </p>
+                    <div class="input-field">
+                      <input id="secret1" name="secret1" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
+                      <label for="secret1">Current Password</label>
+                    </div>
+                    <div class="input-field">
+                      <input id="secret2" name="secret2" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
+                      <label for="secret2">New Password</label>
+                    </div>
+                    <div class="input-field">
+                      <input id="secret3" name="secret3" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
+                      <label for="secret3">Confirm New Password</label>
+                    </div>
+                  </div>
+                </div>
+              </div>
+              <div class="row right">
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-paper-plane left app-btn-icon"></i>
+                  <span class="app-btn-text">Change Password</span>
+                </button>
+              </div>
+            </form>
+          {{ end }}
+          {{ if eq .Data.view "password-edit" }}
+          <div class="row">
+            <div class="col s12">
+            {{ if eq .Data.status "SUCCESS" }}
+              <h1>Password Has Been Changed</h1>
+              <p>Please log out and log back in.</p>
+            {{ else }}
+              <h1>Password Change Failed</h1>
+              <p>Reason: {{ .Data.status_reason }} </p>
+              <a href="{{ pathjoin .ActionEndpoint "/settings/password" }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active">
+                  <i class="las la-undo-alt left app-btn-icon"></i>
+                  <span class="app-btn-text">Try Again</span>
+                </button>
+              </a>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "email" }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/email/edit" }}" method="POST">
+              <div class="row">
+                <h1>Email Address</h1>
+                <div class="row">
+                  <div class="col s12 m6 l6">
+                    <p>Your current email address is <code>{{ .Data.current_email }}</code>.
+                    It remains active until the new email address is confirmed.
+                    </p>
+                    <div class="input-field">
+                      <input id="email" name="email" type="email" autocorrect="off" autocapitalize="off" autocomplete="off" required />
+                      <label for="email">New Email Address</label>
+                    </div>
+                  </div>
+                </div>
+              </div>
+              <div class="row right">
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-paper-plane left app-btn-icon"></i>
+                  <span class="app-btn-text">Send Confirmation Code</span>
+                </button>
+              </div>
+            </form>
+          {{ end }}
+          {{ if or (and (eq .Data.view "email") .Data.pending_email) (and (eq .Data.view "email-edit-status") (eq .Data.status "SUCCESS")) }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/email/confirm" }}" method="POST">
+              <div class="row">
+                <h1>Confirm Email Address</h1>
+                <div class="row">
+                  <div class="col s12 m6 l6">
+                    <p>Please enter the confirmation code sent to <code>{{ .Data.pending_email }}</code>.
+                    </p>
+                    <div class="input-field">
+                      <input id="code" name="code" type="text" inputmode="numeric" autocorrect="off" autocapitalize="off" autocomplete="one-time-code" required />
+                      <label for="code">Confirmation Code</label>
+                    </div>
+                  </div>
+                </div>
+              </div>
+              <div class="row right">
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-check left app-btn-icon"></i>
+                  <span class="app-btn-text">Confirm</span>
+                </button>
+              </div>
+            </form>
+          {{ end }}
+          {{ if or (eq .Data.view "email-confirm-status") (and (eq .Data.view "email-edit-status") (ne .Data.status "SUCCESS")) }}
           <div class="row">
             <div class="col s12">
-            <h1>Test Token</h1>
-            <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
             {{ if eq .Data.status "SUCCESS" }}
-              <a href="{{ pathjoin .ActionEndpoint "/settings/mfa" }}">
-                <button type="button" class="btn waves-effect waves-light navbtn active">
-                  <i class="las la-undo-alt left app-btn-icon"></i>
-                  <span class="app-btn-text">Go Back</span>
-                </button>
-              </a>
+              <h1>Email Address Has Been Changed</h1>
+              <p>Please log out and log back in.</p>
             {{ else }}
-              {{ if ne .Data.mfa_token_id "" }}
-              <a href="{{ pathjoin .ActionEndpoint "/settings/mfa/test/u2f/generic" .Data.mfa_token_id }}">
+              <h1>Email Address Change Failed</h1>
+              <p>Reason: {{ .Data.status_reason }} </p>
+              <a href="{{ pathjoin .ActionEndpoint "/settings/email" }}">
                 <button type="button" class="btn waves-effect waves-light navbtn active">
                   <i class="las la-undo-alt left app-btn-icon"></i>
                   <span class="app-btn-text">Try Again</span>
                 </button>
               </a>
-              {{ end }}
             {{ end }}
             </div>
           </div>
           {{ end }}
-          {{ if eq .Data.view "password" }}
-            <form action="{{ pathjoin .ActionEndpoint "/settings/password/edit" }}" method="POST">
+          {{ if eq .Data.view "delete" }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/delete/request" }}" method="POST">
               <div class="row">
-                <h1>Password Management</h1>
+                <h1>Delete Account</h1>
                 <div class="row">
                   <div class="col s12 m6 l6">
-                    <p>If you want to change your password, please provide your current password and 
+                    <p>The account is deleted {{ .Data.grace_period }} after you confirm the deletion.
+                    Signing in before then reactivates the account. After the deletion, the account
+                    and the associated data cannot be recovered.
+                    </p>
+                  </div>
+                </div>
+              </div>
+              <div class="row right">
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-paper-plane left app-btn-icon"></i>
+                  <span class="app-btn-text">Send Confirmation Code</span>
+                </button>
+              </div>
+            </form>
+          {{ end }}
+          {{ if or (and (eq .Data.view "delete") .Data.pending_deletion) (and (eq .Data.view "delete-request-status") (eq .Data.status "SUCCESS")) }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/delete/confirm" }}" method="POST">
+              <div class="row">
+                <h1>Confirm Account Deletion</h1>
+                <div class="row">
+                  <div class="col s12 m6 l6">
+                    <p>Please enter the confirmation code sent to your email address.
                     </p>
                     <div class="input-field">
-                      <input id="secret1" name="secret1" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
-                      <label for="secret1">Current Password</label>
-                    </div>
-                    <div class="input-field">
-                      <input id="secret2" name="secret2" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
-                      <label for="secret2">New Password</label>
-                    </div>
-                    <div class="input-field">
-                      <input id="secret3" name="secret3" type="password" autocorrect="off" autocapitalize="off" autocomplete="off" required />
-                      <label for="secret3">Confirm New Password</label>
+                      <input id="code" name="code" type="text" inputmode="numeric" autocorrect="off" autocapitalize="off" autocomplete="one-time-code" required />
+                      <label for="code">Confirmation Code</label>
                     </div>
                   </div>
                 </div>
               </div>
               <div class="row right">
                 <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
-                  <i class="las la-paper-plane left app-btn-icon"></i>
-                  <span class="app-btn-text">Change Password</span>
+                  <i class="las la-trash left app-btn-icon"></i>
+                  <span class="app-btn-text">Delete Account</span>
                 </button>
               </div>
             </form>
           {{ end }}
-          {{ if eq .Data.view "password-edit" }}
+          {{ if or (eq .Data.view "delete-confirm-status") (and (eq .Data.view "delete-request-status") (ne .Data.status "SUCCESS")) }}
           <div class="row">
             <div class="col s12">
             {{ if eq .Data.status "SUCCESS" }}
-              <h1>Password Has Been Changed</h1>
-              <p>Please log out and log back in.</p>
+              <h1>Account Scheduled for Deletion</h1>
+              <p>The account is deleted on {{ .Data.scheduled_at }}. Sign in before then to reactivate it.</p>
             {{ else }}
-              <h1>Password Change Failed</h1>
+              <h1>Account Deletion Failed</h1>
               <p>Reason: {{ .Data.status_reason }} </p>
-              <a href="{{ pathjoin .ActionEndpoint "/settings/password" }}">
+              <a href="{{ pathjoin .ActionEndpoint "/settings/delete" }}">
                 <button type="button" class="btn waves-effect waves-light navbtn active">
                   <i class="las la-undo-alt left app-btn-icon"></i>
                   <span class="app-btn-text">Try Again</span>
@@ -1414,9 +2061,175 @@ var PageTemplates = map[string]string{
           </div>
           {{ end }}
           {{ if eq .Data.view "connected" }}
+          {{ if eq .Data.identity_linking_enabled "yes" }}
+          <div class="row right">
+            <div class="col s12 right">
+            {{ range .Data.linkable_providers }}
+              <a href="{{ pathjoin $.ActionEndpoint "/settings/connected/add" .realm }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active app-btn">
+                  <i class="las la-link left app-btn-icon"></i>
+                  <span class="app-btn-text">Link {{ .name }}</span>
+                </button>
+              </a>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
           <div class="row">
             <div class="col s12">
+            {{ if .Data.linked_identities }}
+            <table class="striped">
+              <thead>
+                <tr>
+                  <th>Provider</th>
+                  <th>Identity</th>
+                  <th>Linked</th>
+                  <th></th>
+                </tr>
+              </thead>
+              <tbody>
+              {{ range .Data.linked_identities }}
+                <tr>
+                  <td>{{ .Realm }}</td>
+                  <td>{{ if .Email }}{{ .Email }}{{ else }}{{ .Subject }}{{ end }}</td>
+                  <td>{{ .LinkedAt.Format "2006-01-02 15:04:05 MST" }}</td>
+                  <td>
+                    <a href="{{ pathjoin $.ActionEndpoint "/settings/connected/delete" .ID }}">
+                      <i class="las la-unlink"></i>
+                    </a>
+                  </td>
+                </tr>
+              {{ end }}
+              </tbody>
+            </table>
+            {{ else }}
             <p>No connected accounts found.</p>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "access" }}
+          <div class="row">
+            <div class="col s12">
+            <h1>Request Access</h1>
+            {{ range .Data.access_apps }}
+            <form action="{{ pathjoin $.ActionEndpoint "/settings/access/request" }}" method="POST">
+              <input type="hidden" name="app" value="{{ .Name }}" />
+              <div class="row">
+                <h2>{{ .Name }}</h2>
+                {{ if .Description }}<p>{{ .Description }}</p>{{ end }}
+                <div class="col s12 m6 l6">
+                  <div class="input-field">
+                    <select id="role-{{ .Name }}" name="role" class="browser-default">
+                    {{ range .Roles }}
+                      <option value="{{ . }}">{{ . }}</option>
+                    {{ end }}
+                    </select>
+                  </div>
+                  <div class="input-field">
+                    <textarea id="justification-{{ .Name }}" name="justification" class="materialize-textarea" required></textarea>
+                    <label for="justification-{{ .Name }}">Justification</label>
+                  </div>
+                  <div class="input-field">
+                    <input id="lifetime-{{ .Name }}" name="lifetime" type="number" min="0" inputmode="numeric" />
+                    <label for="lifetime-{{ .Name }}">Duration (hours)</label>
+                  </div>
+                </div>
+              </div>
+              <div class="row right">
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-paper-plane left app-btn-icon"></i>
+                  <span class="app-btn-text">Request Access</span>
+                </button>
+              </div>
+            </form>
+            {{ end }}
+            </div>
+          </div>
+          <div class="row">
+            <div class="col s12">
+            <h2>My Access Requests</h2>
+            {{ if .Data.access_requests }}
+            <table class="striped">
+              <thead>
+                <tr>
+                  <th>App</th>
+                  <th>Role</th>
+                  <th>Status</th>
+                  <th>Requested</th>
+                  <th>Expires</th>
+                </tr>
+              </thead>
+              <tbody>
+              {{ range .Data.access_requests }}
+                <tr>
+                  <td>{{ .App }}</td>
+                  <td><code>{{ .Role }}</code></td>
+                  <td>{{ .Status }}</td>
+                  <td>{{ .CreatedAt.Format "2006-01-02 15:04:05 MST" }}</td>
+                  <td>{{ if not .ExpiresAt.IsZero }}{{ .ExpiresAt.Format "2006-01-02 15:04:05 MST" }}{{ end }}</td>
+                </tr>
+              {{ end }}
+              </tbody>
+            </table>
+            {{ else }}
+            <p>No access requests found.</p>
+            {{ end }}
+            </div>
+          </div>
+          {{ if .Data.access_pending_requests }}
+          <div class="row">
+            <div class="col s12">
+            <h2>Pending Review</h2>
+            {{ range .Data.access_pending_requests }}
+            <div class="row">
+              <p><b>{{ .Username }}</b> ({{ .Realm }}) requests <code>{{ .Role }}</code> for {{ .App }}{{ if .Lifetime }}, {{ .Lifetime }} seconds{{ end }}.</p>
+              <p>{{ .Justification }}</p>
+              <form action="{{ pathjoin $.ActionEndpoint "/settings/access/approve" .ID }}" method="POST">
+                <div class="input-field">
+                  <input id="comment-approve-{{ .ID }}" name="comment" type="text" />
+                  <label for="comment-approve-{{ .ID }}">Comment</label>
+                </div>
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active app-btn">
+                  <i class="las la-check left app-btn-icon"></i>
+                  <span class="app-btn-text">Approve</span>
+                </button>
+                <button type="submit" name="submit" formaction="{{ pathjoin $.ActionEndpoint "/settings/access/decline" .ID }}" class="btn waves-effect waves-light navbtn active app-btn">
+                  <i class="las la-times left app-btn-icon"></i>
+                  <span class="app-btn-text">Decline</span>
+                </button>
+              </form>
+            </div>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ end }}
+          {{ if or (eq .Data.view "access-request-status") (eq .Data.view "access-review-status") }}
+          <div class="row">
+            <div class="col s12">
+            <h1>Access Requests</h1>
+            <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
+            <a href="{{ pathjoin .ActionEndpoint "/settings/access" }}">
+              <button type="button" class="btn waves-effect waves-light navbtn active">
+                <i class="las la-undo-alt left app-btn-icon"></i>
+                <span class="app-btn-text">Go Back</span>
+              </button>
+            </a>
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "connected-delete-status" }}
+          <div class="row">
+            <div class="col s12">
+            <h1>Connected Account</h1>
+            <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
+            <a href="{{ pathjoin .ActionEndpoint "/settings/connected" }}">
+              <button type="button" class="btn waves-effect waves-light navbtn active">
+                <i class="las la-undo-alt left app-btn-icon"></i>
+                <span class="app-btn-text">Go Back</span>
+              </button>
+            </a>
             </div>
           </div>
           {{ end }}
@@ -1431,7 +2244,7 @@ var PageTemplates = map[string]string{
     <script src="{{ pathjoin .ActionEndpoint "/assets/highlight.js/js/languages/json.min.js" }}"></script>
     <script src="{{ pathjoin .ActionEndpoint "/assets/highlight.js/js/languages/plaintext.min.js" }}"></script>
     {{ end }}
-    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") }}
+    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") (eq .Data.view "apikeys-rotate-status") }}
     <script src="{{ pathjoin .ActionEndpoint "/assets/highlight.js/js/highlight.js" }}"></script>
     <script src="{{ pathjoin .ActionEndpoint "/assets/highlight.js/js/languages/json.min.js" }}"></script>
     <script src="{{ pathjoin .ActionEndpoint "/assets/highlight.js/js/languages/plaintext.min.js" }}"></script>
@@ -1457,7 +2270,7 @@ var PageTemplates = map[string]string{
     hljs.initHighlightingOnLoad();
     </script>
     {{ end }}
-    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") }}
+    {{ if or (eq .Data.view "apikeys-add") (eq .Data.view "apikeys-add-status") (eq .Data.view "apikeys-rotate-status") }}
     <script>
     hljs.initHighlightingOnLoad();
     </script>
@@ -1519,6 +2332,13 @@ function u2f_token_authenticate(formID, btnID) {
 }
     </script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/sandbox": `<!doctype html>
@@ -1540,6 +2360,9 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
     {{ if or (eq .Data.view "mfa_app_auth") (eq .Data.view "mfa_app_register") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/mfa_app.css" }}" />
     {{ end }}
@@ -1610,6 +2433,58 @@ function u2f_token_authenticate(formID, btnID) {
                 </div>
               </div>
 
+              {{ if .Data.captcha_provider }}
+              <div class="flex justify-center">
+                {{ if eq .Data.captcha_provider "hcaptcha" }}
+                <div class="h-captcha" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                <script src="https://js.hcaptcha.com/1/api.js" async defer></script>
+                {{ end }}
+                {{ if eq .Data.captcha_provider "recaptcha" }}
+                <div class="g-recaptcha" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                <script src="https://www.google.com/recaptcha/api.js" async defer></script>
+                {{ end }}
+                {{ if eq .Data.captcha_provider "turnstile" }}
+                <div class="cf-turnstile" data-sitekey="{{ .Data.captcha_site_key }}"></div>
+                <script src="https://challenges.cloudflare.com/turnstile/v0/api.js" async defer></script>
+                {{ end }}
+                {{ if eq .Data.captcha_provider "pow" }}
+                <input type="hidden" id="pow-response" name="pow-response"
+                  data-puzzle="{{ .Data.captcha_puzzle }}" data-difficulty="{{ .Data.captcha_difficulty }}" />
+                <p id="pow-status" class="text-base text-gray-500">Verifying your browser, please wait.</p>
+                <script>
+                (async function() {
+                  const field = document.getElementById("pow-response");
+                  const status = document.getElementById("pow-status");
+                  const submit = field.form.querySelector('button[type="submit"]');
+                  const puzzle = field.dataset.puzzle;
+                  const difficulty = parseInt(field.dataset.difficulty, 10);
+                  const encoder = new TextEncoder();
+                  submit.disabled = true;
+                  for (let nonce = 0; ; nonce++) {
+                    const solution = puzzle + ":" + nonce;
+                    const digest = new Uint8Array(await crypto.subtle.digest("SHA-256", encoder.encode(solution)));
+                    let zeros = 0;
+                    for (const b of digest) {
+                      if (b === 0) {
+                        zeros += 8;
+                        continue;
+                      }
+                      zeros += Math.clz32(b) - 24;
+                      break;
+                    }
+                    if (zeros >= difficulty) {
+                      field.value = solution;
+                      break;
+                    }
+                  }
+                  status.textContent = "Your browser has been verified.";
+                  submit.disabled = false;
+                })();
+                </script>
+                {{ end }}
+              </div>
+              {{ end }}
+
               <div class="hidden">
                 <input id="sandbox_id" name="sandbox_id" type="hidden" value="{{ .Data.id }}" />
               </div>
@@ -1650,6 +2525,11 @@ function u2f_token_authenticate(formID, btnID) {
                 </div>
               </div>
             </form>
+            {{ if .Data.fallback }}
+            <div id="login_fallback_link" class="pt-4 text-center">
+              <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "fallback" }}">Use another sign-in method</a>
+            </div>
+            {{ end }}
           </div>
           {{ else if eq .Data.view "password_recovery" }}
 
@@ -1781,6 +2661,11 @@ function u2f_token_authenticate(formID, btnID) {
                 </button>
               </a>
             </div>
+            {{ if .Data.fallback }}
+            <div id="login_fallback_link" class="pt-4 text-center">
+              <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "fallback" }}">Use another sign-in method</a>
+            </div>
+            {{ end }}
           </div>
           {{ else if eq .Data.view "mfa_app_register" }}
           <div>
@@ -2011,6 +2896,58 @@ function u2f_token_authenticate(formID, btnID) {
               </a>
             </div>
           </div>
+          {{ else if eq .Data.view "terms" }}
+          <div>
+            <form class="space-y-6"
+                  action="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "terms-accept" }}"
+                  method="POST"
+                  autocomplete="off"
+                  >
+              <div class="app-txt-section">
+                <p>Please review and accept the following documents to continue.</p>
+              </div>
+              <ul class="divide-y divide-gray-200">
+                {{ range .Data.documents }}
+                <li class="py-4 flex">
+                  <input id="accept_{{ .Name }}" name="accept_{{ .Name }}" type="checkbox" value="{{ .Version }}" required />
+                  <label for="accept_{{ .Name }}" class="ml-3">
+                    I accept the <a class="app-lst-lnk" href="{{ .URL }}" target="_blank">{{ .Title }}</a> (version {{ .Version }})
+                  </label>
+                </li>
+                {{ end }}
+              </ul>
+
+              <div class="hidden">
+                <input id="sandbox_id" name="sandbox_id" type="hidden" value="{{ .Data.id }}" />
+              </div>
+
+              <div class="flex gap-4">
+                <div class="flex-none">
+                  <a href="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "terminate" }}">
+                    <button type="button" class="app-btn-sec">
+                      <div>
+                        <svg xmlns="http://www.w3.org/2000/svg" class="h-6 w-6" fill="none" viewBox="0 0 24 24" stroke="currentColor" stroke-width="2">
+                          <path stroke-linecap="round" stroke-linejoin="round" d="M6 18L18 6M6 6l12 12" />
+                        </svg>
+                      </div>
+                    </button>
+                  </a>
+                </div>
+                <div class="grow">
+                  <button type="submit" name="submit" class="app-btn-pri">
+                    <div>
+                      <svg xmlns="http://www.w3.org/2000/svg" class="h-6 w-6" fill="none" viewBox="0 0 24 24" stroke="currentColor" stroke-width="2">
+                        <path stroke-linecap="round" stroke-linejoin="round" d="M5 13l4 4L19 7" />
+                      </svg>
+                    </div>
+                    <div class="pl-2">
+                      <span>Accept</span>
+                    </div>
+                  </button>
+                </div>
+              </div>
+            </form>
+          </div>
           {{ else if eq .Data.view "terminate" }}
           <div class="app-txt-section">
             <p>{{ .Data.error }}.</p>
@@ -2132,6 +3069,13 @@ function u2f_token_authenticate(formID, btnID) {
     appContainer.prepend(toastElement.el)
     </script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/apps_sso": `<!DOCTYPE html>
@@ -2151,6 +3095,9 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -2168,7 +3115,42 @@ function u2f_token_authenticate(formID, btnID) {
             </div>
           </div>
 
-          {{ if gt .Data.role_count 0 }}
+          {{ if eq .Data.driver "saml" }}
+            {{ if gt .Data.service_provider_count 0 }}
+              <div class="pb-4 pt-4">
+                <p class="app-inp-lbl">Sign in to any of the following applications by clicking the name of the application.</p>
+              </div>
+
+              <div class="flex flex-col">
+                <div class="-my-2 -mx-4 overflow-x-auto sm:-mx-6 lg:-mx-8">
+                  <div class="inline-block min-w-full py-2 align-middle md:px-6 lg:px-8">
+                    <table class="min-w-full divide-y divide-gray-300">
+                      <thead>
+                        <tr>
+                          <th scope="col" class="py-3.5 pl-4 pr-3 text-left text-sm font-semibold text-primary-700 sm:pl-6 md:pl-0">Application</th>
+                          <th scope="col" class="py-3.5 px-3 text-left text-sm font-semibold text-primary-700">Entity ID</th>
+                        </tr>
+                      </thead>
+                      <tbody class="divide-y divide-gray-200">
+                        {{ range .Data.service_providers }}
+                          <tr>
+                            <td class="whitespace-nowrap py-4 pl-4 pr-3 text-sm font-medium text-primary-700 sm:pl-6 md:pl-0 leading-none">
+                              <a href="{{ pathjoin $.ActionEndpoint "/apps/sso" .ProviderName "login" .ID }}">{{ .Name }}</a>
+                            </td>
+                            <td class="whitespace-nowrap py-4 px-3 text-sm text-primary-500">{{ .EntityID }}</td>
+                          </tr>
+                        {{ end }}
+                      </tbody>
+                    </table>
+                  </div>
+                </div>
+              </div>
+            {{ else }}
+              <div class="pb-4 pt-4">
+                <p class="app-inp-lbl">Your user identity has no access to applications.</p>
+              </div>
+            {{ end }}
+          {{ else if gt .Data.role_count 0 }}
             <div class="pb-4 pt-4">
               <p class="app-inp-lbl">Assume any of the following roles on the associated AWS accounts by clicking the name of the role.</p>
             </div>
@@ -2180,7 +3162,7 @@ function u2f_token_authenticate(formID, btnID) {
                     <thead>
                       <tr>
                         <th scope="col" class="py-3.5 pl-4 pr-3 text-left text-sm font-semibold text-primary-700 sm:pl-6 md:pl-0">Role Name</th>
-                        <th scope="col" class="py-3.5 px-3 text-left text-sm font-semibold text-primary-700">Account ID</th>
+                        <th scope="col" class="py-3.5 px-3 text-left text-sm font-semibold text-primary-700">Account</th>
                       </tr>
                     </thead>
                     <tbody class="divide-y divide-gray-200">
@@ -2189,7 +3171,7 @@ function u2f_token_authenticate(formID, btnID) {
                           <td class="whitespace-nowrap py-4 pl-4 pr-3 text-sm font-medium text-primary-700 sm:pl-6 md:pl-0 leading-none">
                             <a href="{{ pathjoin $.ActionEndpoint "/apps/sso" .ProviderName "assume" .AccountID .Name }}">{{ brsplitline .Name }}</a>
                           </td>
-                          <td class="whitespace-nowrap py-4 px-3 text-sm text-primary-500">{{ .AccountID }}</td>
+                          <td class="whitespace-nowrap py-4 px-3 text-sm text-primary-500">{{ if .AccountName }}{{ .AccountName }} ({{ .AccountID }}){{ else }}{{ .AccountID }}{{ end }}</td>
                         </tr>
                       {{ end }}
                     </tbody>
@@ -2204,7 +3186,7 @@ function u2f_token_authenticate(formID, btnID) {
           {{ end }}
 
 
-          <div class="flex flex-wrap {{ if gt .Data.role_count 0 }}pt-6{{ end }} justify-center gap-4">
+          <div class="flex flex-wrap {{ if or (gt .Data.role_count 0) (eq .Data.driver "saml") }}pt-6{{ end }} justify-center gap-4">
             <div id="forgot_username_link">
               <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/portal" }}">
                 <i class="las la-layer-group"></i>
@@ -2226,6 +3208,13 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if eq .Data.ui_options.custom_js_required "yes" }}
       <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 	"basic/apps_mobile_access": `<!DOCTYPE html>
@@ -2245,6 +3234,9 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if eq .Data.ui_options.custom_css_required "yes" }}
       <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
     {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
   </head>
 
   <body class="h-full">
@@ -2287,6 +3279,111 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if eq .Data.ui_options.custom_js_required "yes" }}
       <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
     {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
+  </body>
+</html>`,
+	"basic/consent": `<!DOCTYPE html>
+<html lang="en" class="h-full bg-blue-100">
+  <head>
+    <title>{{ .MetaTitle }} - {{ .PageTitle }}</title>
+    <!-- Required meta tags -->
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no" />
+    <meta name="description" content="{{ .MetaDescription }}" />
+    <meta name="author" content="{{ .MetaAuthor }}" />
+    <link rel="shortcut icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/roboto.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/line-awesome/line-awesome.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/login.css" }}" />
+    {{ if eq .Data.ui_options.custom_css_required "yes" }}
+      <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
+    {{ end }}
+    {{ if .BrandingStyle }}
+      <style>{{ .BrandingStyle }}</style>
+    {{ end }}
+  </head>
+
+  <body class="h-full">
+    <div class="app-page">
+      <div class="app-content">
+        <div class="app-container">
+          <div class="logo-box">
+            {{ if .LogoURL }}
+              <img class="logo-img" src="{{ .LogoURL }}" alt="{{ .LogoDescription }}" />
+            {{ end }}
+            <h2 class="logo-txt">{{ .PageTitle }}</h2>
+          </div>
+
+          <div>
+            <form class="space-y-6" action="{{ pathjoin .ActionEndpoint "/oauth2/authorize" }}" method="POST">
+              <div>
+                <p class="text-center pb-2 text-lg font-sans font-medium text-primary-700">
+                  <b>{{ .Data.client_name }}</b> requests access to your account <b>{{ .Data.username }}</b>.
+                </p>
+                <ul class="pt-2 text-primary-700">
+                  {{ range .Data.scopes }}
+                    <li><i class="las la-check"></i> <span>{{ . }}</span></li>
+                  {{ end }}
+                </ul>
+                {{ if .Data.claims }}
+                  <p class="pt-4 text-primary-700">The application receives the following information:</p>
+                  <ul class="pt-2 text-primary-700">
+                    {{ range .Data.claims }}
+                      <li><i class="las la-info-circle"></i> <span>{{ .name }}: <b>{{ .value }}</b></span></li>
+                    {{ end }}
+                  </ul>
+                {{ end }}
+              </div>
+              <div class="flex items-center">
+                <input id="remember" type="checkbox" name="remember" value="yes" checked />
+                <label for="remember" class="pl-2 text-primary-700">Remember my decision</label>
+              </div>
+              <input type="hidden" name="consent_id" value="{{ .Data.consent_id }}" />
+              <div class="flex gap-4">
+                <div class="flex-none">
+                  <button type="submit" name="decision" value="deny" class="app-btn-sec">
+                    <div><i class="las la-times-circle"></i></div>
+                    <div class="pl-1 pr-2"><span>Deny</span></div>
+                  </button>
+                </div>
+                <div class="grow">
+                  <button type="submit" name="decision" value="allow" class="app-btn-pri">
+                    <div><i class="las la-check-circle"></i></div>
+                    <div class="pl-2"><span>Allow</span></div>
+                  </button>
+                </div>
+              </div>
+            </form>
+          </div>
+
+          <div class="flex flex-wrap pt-6 justify-center gap-4">
+            <div id="portal_link">
+              <a class="text-primary-600" href="{{ pathjoin .ActionEndpoint "/portal" }}">
+                <i class="las la-layer-group"></i>
+                <span class="text-lg">Portal</span>
+              </a>
+            </div>
+          </div>
+        </div>
+      </div>
+    </div>
+    {{ if eq .Data.ui_options.custom_js_required "yes" }}
+      <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
+    {{ end }}
+    {{ if .FooterLinks }}
+      <footer class="app-footer">
+        {{ range .FooterLinks }}
+          <a class="app-footer-lnk" href="{{ .Link }}"{{ if .TargetEnabled }} target="{{ .Target }}"{{ end }}>{{ .Title }}</a>
+        {{ end }}
+      </footer>
+    {{ end }}
   </body>
 </html>`,
 }
@@ -31,6 +31,8 @@ type NavigationItem struct {
 type Parameters struct {
 	Theme                   string            `json:"theme,omitempty" xml:"theme,omitempty" yaml:"theme,omitempty"`
 	Templates               map[string]string `json:"templates,omitempty" xml:"templates,omitempty" yaml:"templates,omitempty"`
+	TemplatesDir            string            `json:"templates_dir,omitempty" xml:"templates_dir,omitempty" yaml:"templates_dir,omitempty"`
+	TemplatesReloadInterval int               `json:"templates_reload_interval,omitempty" xml:"templates_reload_interval,omitempty" yaml:"templates_reload_interval,omitempty"`
 	AllowRoleSelection      bool              `json:"allow_role_selection,omitempty" xml:"allow_role_selection,omitempty" yaml:"allow_role_selection,omitempty"`
 	Title                   string            `json:"title,omitempty" xml:"title,omitempty" yaml:"title,omitempty"`
 	LogoURL                 string            `json:"logo_url,omitempty" xml:"logo_url,omitempty" yaml:"logo_url,omitempty"`
@@ -46,6 +48,7 @@ type Parameters struct {
 	CustomJsPath            string            `json:"custom_js_path,omitempty" xml:"custom_js_path,omitempty" yaml:"custom_js_path,omitempty"`
 	Language                string            `json:"language,omitempty" xml:"language,omitempty" yaml:"language,omitempty"`
 	DisabledPages           map[string]bool   `json:"disabled_pages,omitempty" xml:"disabled_pages,omitempty" yaml:"disabled_pages,omitempty"`
+	Branding                []*Branding       `json:"branding,omitempty" xml:"branding,omitempty" yaml:"branding,omitempty"`
 }
 
 // DisablePage disables a specific page.
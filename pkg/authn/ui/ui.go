@@ -21,7 +21,9 @@ import (
 	"io/ioutil"
 	"path"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 )
 
 // Themes stores UI themes.
@@ -53,6 +55,13 @@ type Factory struct {
 	ActionEndpoint string `json:"-"`
 	CustomCSSPath  string `json:"custom_css_path,omitempty" xml:"custom_css_path,omitempty" yaml:"custom_css_path,omitempty"`
 	CustomJsPath   string `json:"custom_js_path,omitempty" xml:"custom_js_path,omitempty" yaml:"custom_js_path,omitempty"`
+	// The per-realm branding, keyed by realm name.
+	Branding map[string]*Branding `json:"branding,omitempty" xml:"branding,omitempty" yaml:"branding,omitempty"`
+
+	mu                 sync.RWMutex
+	templatesDir       string
+	templatesModTimes  map[string]time.Time
+	templatesWatchExit chan bool
 }
 
 // Template represents a user interface instance, e.g. a single
@@ -93,6 +102,9 @@ type Args struct {
 	MfaEnabled              bool                   `json:"mfa_enabled,omitempty" xml:"mfa_enabled,omitempty" yaml:"mfa_enabled,omitempty"`
 	CustomCSSEnabled        bool                   `json:"custom_css_enabled,omitempty" xml:"custom_css_enabled,omitempty" yaml:"custom_css_enabled,omitempty"`
 	CustomJsEnabled         bool                   `json:"custom_js_enabled,omitempty" xml:"custom_js_enabled,omitempty" yaml:"custom_js_enabled,omitempty"`
+	ProductName             string                 `json:"product_name,omitempty" xml:"product_name,omitempty" yaml:"product_name,omitempty"`
+	FooterLinks             []Link                 `json:"footer_links,omitempty" xml:"footer_links,omitempty" yaml:"footer_links,omitempty"`
+	BrandingStyle           string                 `json:"branding_style,omitempty" xml:"branding_style,omitempty" yaml:"branding_style,omitempty"`
 }
 
 // NewFactory return an instance of a user interface factory.
@@ -277,37 +289,27 @@ func (f *Factory) DeleteTemplates() {
 }
 
 func loadTemplateFromString(s, p string) (*template.Template, error) {
-	funcMap := template.FuncMap{
-		"pathjoin": path.Join,
-		"brsplitline": func(s string) string {
-			var output []rune
-			var count = 0
-			for _, c := range s {
-				count++
-				if count > 25 {
-					count = 0
-					output = append(output, []rune{'<', 'b', 'r', '>'}...)
-				}
-				output = append(output, c)
-			}
-			return string(output)
-		},
-	}
-	t := template.New(s).Funcs(funcMap)
+	t := template.New(s).Funcs(TemplateFuncs)
 	t, err := t.Parse(p)
 	if err != nil {
 		return nil, err
 	}
+	if err := validateTemplate(t); err != nil {
+		return nil, err
+	}
 	return t, nil
 }
 
 // Render returns a pointer to a data buffer.
 func (f *Factory) Render(name string, args *Args) (*bytes.Buffer, error) {
-	if _, exists := f.Templates[name]; !exists {
+	f.mu.RLock()
+	tmpl, exists := f.Templates[name]
+	f.mu.RUnlock()
+	if !exists {
 		return nil, fmt.Errorf("template %s does not exist", name)
 	}
 	b := bytes.NewBuffer(nil)
-	err := f.Templates[name].Template.Execute(b, args)
+	err := tmpl.Template.Execute(b, args)
 	if err != nil {
 		return nil, err
 	}
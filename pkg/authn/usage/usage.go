@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultMinCount    = 10
+	defaultGranularity = 10
+)
+
+// Config holds the configuration for the collection of usage statistics.
+// The statistics are aggregated locally and never include identities, i.e.
+// usernames, email addresses, or source addresses.
+type Config struct {
+	// MinCount is the smallest count reported for a flow. The flows with
+	// lower counts are reported as suppressed. Defaults to 10.
+	MinCount int `json:"min_count,omitempty" xml:"min_count,omitempty" yaml:"min_count,omitempty"`
+	// Granularity is the step the reported counts are rounded down to.
+	// Defaults to 10.
+	Granularity int `json:"granularity,omitempty" xml:"granularity,omitempty" yaml:"granularity,omitempty"`
+}
+
+// Report is the usage statistics report.
+type Report struct {
+	Since      time.Time        `json:"since,omitempty" xml:"since,omitempty" yaml:"since,omitempty"`
+	Flows      map[string]int64 `json:"flows,omitempty" xml:"flows,omitempty" yaml:"flows,omitempty"`
+	Providers  map[string]int64 `json:"providers,omitempty" xml:"providers,omitempty" yaml:"providers,omitempty"`
+	Suppressed int              `json:"suppressed,omitempty" xml:"suppressed,omitempty" yaml:"suppressed,omitempty"`
+}
+
+// Collector aggregates usage statistics.
+type Collector struct {
+	mu          sync.Mutex
+	minCount    int64
+	granularity int64
+	since       time.Time
+	flows       map[string]int64
+	providers   map[string]int64
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MinCount < 0 {
+		return errors.ErrUsageConfigMinCount.WithArgs(cfg.MinCount)
+	}
+	if cfg.Granularity < 0 {
+		return errors.ErrUsageConfigGranularity.WithArgs(cfg.Granularity)
+	}
+	return nil
+}
+
+// NewCollector returns an instance of Collector.
+func NewCollector(cfg *Config) (*Collector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Collector{
+		minCount:    defaultMinCount,
+		granularity: defaultGranularity,
+		since:       time.Now().UTC(),
+		flows:       make(map[string]int64),
+		providers:   make(map[string]int64),
+	}
+	if cfg.MinCount > 0 {
+		c.minCount = int64(cfg.MinCount)
+	}
+	if cfg.Granularity > 0 {
+		c.granularity = int64(cfg.Granularity)
+	}
+	return c, nil
+}
+
+// AddProvider registers a configured identity store or provider of a
+// particular kind, e.g. local, ldap, oauth2, or saml.
+func (c *Collector) AddProvider(kind string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[kind]++
+}
+
+// Record increments the counter of a flow, e.g. login/local or logout.
+func (c *Collector) Record(flow string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flows[flow]++
+}
+
+// GetReport returns the usage statistics report. The flow counts are rounded
+// down to the configured granularity and the counts below the configured
+// minimum are suppressed.
+func (c *Collector) GetReport() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := &Report{
+		Since:     c.since,
+		Flows:     make(map[string]int64),
+		Providers: make(map[string]int64),
+	}
+	for k, v := range c.flows {
+		if v < c.minCount {
+			r.Suppressed++
+			continue
+		}
+		r.Flows[k] = v - v%c.granularity
+	}
+	for k, v := range c.providers {
+		r.Providers[k] = v
+	}
+	return r
+}
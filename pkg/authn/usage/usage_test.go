@@ -0,0 +1,117 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestCollector(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		flows     map[string]int
+		providers []string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "collector with default settings",
+			config: &Config{},
+			flows: map[string]int{
+				"login/local":  27,
+				"login/oauth2": 3,
+				"logout":       10,
+			},
+			providers: []string{"local", "oauth2", "oauth2"},
+			want: map[string]interface{}{
+				"flows": map[string]int64{
+					"login/local": 20,
+					"logout":      10,
+				},
+				"providers": map[string]int64{
+					"local":  1,
+					"oauth2": 2,
+				},
+				"suppressed": 1,
+			},
+		},
+		{
+			name: "collector with custom min count and granularity",
+			config: &Config{
+				MinCount:    2,
+				Granularity: 1,
+			},
+			flows: map[string]int{
+				"login/local":  27,
+				"login/oauth2": 3,
+				"register":     1,
+			},
+			want: map[string]interface{}{
+				"flows": map[string]int64{
+					"login/local":  27,
+					"login/oauth2": 3,
+				},
+				"providers":  map[string]int64{},
+				"suppressed": 1,
+			},
+		},
+		{
+			name: "collector with negative min count",
+			config: &Config{
+				MinCount: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrUsageConfigMinCount.WithArgs(-1),
+		},
+		{
+			name: "collector with negative granularity",
+			config: &Config{
+				Granularity: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrUsageConfigGranularity.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			c, err := NewCollector(tc.config)
+			if tests.EvalErrWithLog(t, err, "collector", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			for _, kind := range tc.providers {
+				c.AddProvider(kind)
+			}
+			for flow, n := range tc.flows {
+				for i := 0; i < n; i++ {
+					c.Record(flow)
+				}
+			}
+			report := c.GetReport()
+			got := map[string]interface{}{
+				"flows":      report.Flows,
+				"providers":  report.Providers,
+				"suppressed": report.Suppressed,
+			}
+			tests.EvalObjectsWithLog(t, "report", tc.want, got, msgs)
+		})
+	}
+}
@@ -18,6 +18,9 @@ package authproxy
 type Response struct {
 	Name    string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
 	Payload string `json:"payload,omitempty" xml:"payload,omitempty" yaml:"payload,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying the
+	// request shed by the admission control of an identity store.
+	RetryAfter int `json:"retry_after,omitempty" xml:"retry_after,omitempty" yaml:"retry_after,omitempty"`
 }
 
 // Request is a request to an identity store via Authenticator.
@@ -0,0 +1,123 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+)
+
+const (
+	anonymousSubject = "anonymous"
+	anonymousRole    = "anonymous"
+)
+
+// authorizeAnonymousUser evaluates the access list rules with anonymous
+// keyword against a synthetic anonymous identity. It returns the identity
+// when the unauthenticated request is allowed.
+func (g *Gatekeeper) authorizeAnonymousUser(r *http.Request, ar *requests.AuthorizationRequest, err error) *user.User {
+	if g.anonymousAccessList == nil {
+		return nil
+	}
+
+	switch err {
+	case errors.ErrAccessNotAllowed, errors.ErrAccessNotAllowedByPathACL:
+		// The request has a valid token, but the user has no access.
+		return nil
+	case errors.ErrBasicAuthFailed, errors.ErrAPIKeyAuthFailed:
+		// The request has invalid credentials.
+		return nil
+	case errors.ErrAdmissionQueueFull, errors.ErrAdmissionQueueTimeout:
+		return nil
+	}
+
+	addr := addrutil.GetSourceAddress(r)
+	usr, err := user.NewUser(map[string]interface{}{
+		"sub":   anonymousSubject,
+		"name":  anonymousSubject,
+		"roles": []string{anonymousRole},
+		"addr":  addr,
+	})
+	if err != nil {
+		return nil
+	}
+
+	headers := map[string]string{
+		"X-Token-Subject":      anonymousSubject,
+		"X-Token-User-Name":    anonymousSubject,
+		"X-Token-User-Roles":   anonymousRole,
+		"X-Token-User-Address": addr,
+	}
+
+	kv := make(map[string]interface{})
+	for k, v := range usr.GetData() {
+		kv[k] = v
+	}
+	kv["method"] = r.Method
+	kv["path"] = r.URL.Path
+	if loc := geo.LocateAddress(addr); loc != nil {
+		kv["country"] = loc.Country
+		headers["X-Token-User-Country"] = loc.Country
+		if loc.City != "" {
+			kv["city"] = loc.City
+			headers["X-Token-User-City"] = loc.City
+		}
+	}
+	if !g.anonymousAccessList.Allow(context.Background(), kv) {
+		return nil
+	}
+
+	usr.SetRequestHeaders(headers)
+	return usr
+}
+
+// handleAnonymousUser handles unauthenticated requests allowed by the access
+// list rules with anonymous keyword.
+func (g *Gatekeeper) handleAnonymousUser(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest, usr *user.User) error {
+	g.logger.Debug(
+		"anonymous access granted",
+		zap.String("session_id", ar.SessionID),
+		zap.String("request_id", ar.ID),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("url", addrutil.GetTargetURL(r)),
+	)
+	g.stripInjectedHeaders(r)
+	g.injectHeaders(r, usr)
+	ar.Response.Authorized = true
+	ar.Response.User = usr.BuildRequestIdentity(g.config.UserIdentityField)
+	return nil
+}
+
+// stripInjectedHeaders removes the headers the gatekeeper injects from the
+// request, so that the client cannot supply identity headers, e.g. the email
+// address, absent from the anonymous identity.
+func (g *Gatekeeper) stripInjectedHeaders(r *http.Request) {
+	for k := range r.Header {
+		if strings.HasPrefix(k, "X-Token-") {
+			r.Header.Del(k)
+		}
+	}
+	for k := range g.injectedHeaders {
+		r.Header.Del(k)
+	}
+}
@@ -16,17 +16,23 @@ package authz
 
 import (
 	"context"
+	cookieutil "github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authn/impersonation"
 	"github.com/greenpau/go-authcrunch/pkg/authz/bypass"
 	"github.com/greenpau/go-authcrunch/pkg/authz/handlers"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 	"github.com/greenpau/go-authcrunch/pkg/util/validate"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -39,6 +45,17 @@ var (
 
 // Authenticate authorizes HTTP requests.
 func (g *Gatekeeper) Authenticate(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	ctx, span := tracing.StartServer(r.Context(), r, "authz.Authenticate", tracing.GatekeeperKey.String(g.config.Name))
+	err := g.authenticate(ctx, w, r, ar)
+	span.SetAttributes(
+		attribute.Bool("authorized", ar.Response.Authorized),
+		attribute.Bool("bypassed", ar.Response.Bypassed),
+	)
+	tracing.End(span, err)
+	return err
+}
+
+func (g *Gatekeeper) authenticate(ctx context.Context, w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
 	// Perform authorization bypass checks
 	if g.bypassEnabled && bypass.Match(r, g.config.BypassConfigs) {
 		ar.Response.Authorized = false
@@ -51,25 +68,40 @@ func (g *Gatekeeper) Authenticate(w http.ResponseWriter, r *http.Request, ar *re
 			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
 			zap.String("url", addrutil.GetTargetURL(r)),
 		)
+		metrics.AuthorizationDecisions.Inc(g.config.Name, "", "bypass")
 		return nil
 	}
 
 	g.parseSessionID(r, ar)
 
-	usr, err := g.tokenValidator.Authorize(context.Background(), r, ar)
+	spanCtx, span := tracing.Start(ctx, "authz.Authorize", tracing.GatekeeperKey.String(g.config.Name))
+	usr, err := g.tokenValidator.Authorize(spanCtx, r, ar)
+	tracing.End(span, err)
+	if ar.Token.Found {
+		metrics.TokenSources.Inc(g.config.Name, ar.Token.Source)
+	}
 	if err != nil {
+		if anonymousUser := g.authorizeAnonymousUser(r, ar, err); anonymousUser != nil {
+			metrics.AuthorizationDecisions.Inc(g.config.Name, "", "anonymous")
+			return g.handleAnonymousUser(w, r, ar, anonymousUser)
+		}
+		metrics.AuthorizationDecisions.Inc(g.config.Name, "", "deny")
 		ar.Response.Error = err
+		g.explainDenial(ctx, r, ar, usr)
 		return g.handleUnauthorizedUser(w, r, ar)
 	}
+	metrics.AuthorizationDecisions.Inc(g.config.Name, usr.Claims.Origin, "allow")
 	return g.handleAuthorizedUser(w, r, ar, usr)
 }
 
 // handleAuthorizedUser handles authorized requests.
 func (g *Gatekeeper) handleAuthorizedUser(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest, usr *user.User) error {
+	g.logImpersonatedRequest(r, ar, usr)
 	g.injectHeaders(r, usr)
 	g.stripAuthToken(r, usr)
 
 	ar.Response.Authorized = true
+	g.watchStream(r, ar, usr)
 
 	if usr.Cached {
 		ar.Response.User = usr.GetRequestIdentity()
@@ -89,6 +121,27 @@ func (g *Gatekeeper) handleAuthorizedUser(w http.ResponseWriter, r *http.Request
 	return nil
 }
 
+// logImpersonatedRequest logs the requests to the protected resources made
+// by an administrator on behalf of an impersonated user.
+func (g *Gatekeeper) logImpersonatedRequest(r *http.Request, ar *requests.AuthorizationRequest, usr *user.User) {
+	actor := impersonation.GetActor(usr.AsMap())
+	if actor == nil {
+		return
+	}
+	g.logger.Info(
+		"Impersonated request",
+		zap.String("session_id", ar.SessionID),
+		zap.String("request_id", ar.ID),
+		zap.String("gatekeeper", g.config.Name),
+		zap.String("admin", actor.Email),
+		zap.String("admin_subject", actor.Subject),
+		zap.String("username", usr.Claims.Subject),
+		zap.String("method", r.Method),
+		zap.String("url_path", r.URL.Path),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+	)
+}
+
 // parseSessionID extracts Session ID from HTTP request.
 func (g *Gatekeeper) parseSessionID(r *http.Request, ar *requests.AuthorizationRequest) {
 	if cookie, err := r.Cookie("AUTHP_SESSION_ID"); err == nil {
@@ -116,10 +169,17 @@ func (g *Gatekeeper) handleUnauthorizedUser(w http.ResponseWriter, r *http.Reque
 		return g.handleAuthorizeWithAuthFailed(w, r, ar)
 	case err == errors.ErrCryptoKeyStoreTokenData:
 		return g.handleAuthorizeWithBadRequest(w, r, ar)
+	case (err == errors.ErrAdmissionQueueFull) || (err == errors.ErrAdmissionQueueTimeout):
+		return g.handleAuthorizeWithServiceUnavailable(w, r, ar)
 	}
 
 	g.expireAuthCookies(w, r)
 
+	if g.isStream(r) {
+		// The clients of long-lived requests do not follow redirects.
+		return g.handleAuthorizeWithAuthFailed(w, r, ar)
+	}
+
 	if !g.config.AuthRedirectDisabled {
 		return g.handleAuthorizeWithRedirect(w, r, ar)
 	}
@@ -135,7 +195,8 @@ func (g *Gatekeeper) expireAuthCookies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, cookie := range r.Cookies() {
-		if _, exists := cookies[cookie.Name]; !exists {
+		name, _ := cookieutil.ParseChunkName(cookie.Name)
+		if _, exists := cookies[name]; !exists {
 			continue
 		}
 		w.Header().Add("Set-Cookie", cookie.Name+"=delete; path=/; expires=Thu, 01 Jan 1970 00:00:00 GMT")
@@ -161,8 +222,31 @@ func (g *Gatekeeper) handleAuthorizeWithBadRequest(w http.ResponseWriter, r *htt
 	return ar.Response.Error
 }
 
+// handleAuthorizeWithServiceUnavailable handles authorization requests shed
+// by the admission control of the authenticator.
+func (g *Gatekeeper) handleAuthorizeWithServiceUnavailable(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	if ar.Response.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(ar.Response.RetryAfter))
+	}
+	w.WriteHeader(503)
+	w.Write([]byte(`503 Service Unavailable`))
+	return ar.Response.Error
+}
+
 // handleAuthorizeWithForbidden handles forbidden responses.
 func (g *Gatekeeper) handleAuthorizeWithForbidden(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	if g.forbiddenPage != nil {
+		if err := g.forbiddenPage.Render(w, r, ar.Response.Denial); err != nil {
+			g.logger.Error(
+				"failed rendering forbidden page",
+				zap.String("session_id", ar.SessionID),
+				zap.String("request_id", ar.ID),
+				zap.Error(err),
+			)
+		}
+		return ar.Response.Error
+	}
+
 	if g.config.ForbiddenURL == "" {
 		w.WriteHeader(403)
 		w.Write([]byte(`Forbidden`))
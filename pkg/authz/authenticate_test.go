@@ -16,6 +16,7 @@ package authz
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -23,6 +24,11 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/internal/testutils"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authz/forbidden"
+	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
+	"github.com/greenpau/go-authcrunch/pkg/authz/stream"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
 	"io/ioutil"
@@ -61,6 +67,12 @@ func TestAuthenticate(t *testing.T) {
 				},
 				Action: "allow stop",
 			},
+			{
+				Conditions: []string{
+					"match path /public",
+				},
+				Action: "allow anonymous stop",
+			},
 		},
 		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
 	}
@@ -93,6 +105,20 @@ func TestAuthenticate(t *testing.T) {
 				"content_type": "text/plain; charset=utf-8",
 			},
 		},
+		{
+			name: "anonymous user accesses public path",
+			req: &testRequest{
+				method: "GET",
+				path:   "/public",
+			},
+			want: map[string]interface{}{
+				"response": map[string]interface{}{
+					"authorized": true,
+				},
+				"status_code":  200,
+				"content_type": "text/plain; charset=utf-8",
+			},
+		},
 	}
 
 	// Initialize HTTP server.
@@ -168,6 +194,166 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
+type testLocator map[string]*geo.Location
+
+func (l testLocator) Locate(ip net.IP) (*geo.Location, error) {
+	return l[ip.String()], nil
+}
+
+func TestAuthorizeAnonymousUser(t *testing.T) {
+	defaultLocator := geo.Default
+	geo.Default = testLocator{
+		"198.51.100.1": {Country: "US", City: "Los Angeles"},
+	}
+	defer func() {
+		geo.Default = defaultLocator
+	}()
+
+	cfg := &PolicyConfig{
+		Name:        "mygatekeeper",
+		AuthURLPath: "/auth",
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{"match roles authp/admin authp/user"},
+				Action:     "allow stop",
+			},
+			{
+				Conditions: []string{"match path /public"},
+				Action:     "allow anonymous stop",
+			},
+		},
+		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
+	}
+
+	gatekeeper, err := NewGatekeeper(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testcases = []struct {
+		name string
+		path string
+		addr string
+		err  error
+		want map[string]interface{}
+	}{
+		{
+			name: "request without token to public path",
+			path: "/public",
+			err:  errors.ErrNoTokenFound,
+			want: map[string]interface{}{
+				"allowed": true,
+				"headers": map[string]string{
+					"X-Token-Subject":      "anonymous",
+					"X-Token-User-Name":    "anonymous",
+					"X-Token-User-Roles":   "anonymous",
+					"X-Token-User-Address": "192.0.2.1",
+				},
+			},
+		},
+		{
+			name: "request without token from known location to public path",
+			path: "/public",
+			addr: "198.51.100.1",
+			err:  errors.ErrNoTokenFound,
+			want: map[string]interface{}{
+				"allowed": true,
+				"headers": map[string]string{
+					"X-Token-Subject":      "anonymous",
+					"X-Token-User-Name":    "anonymous",
+					"X-Token-User-Roles":   "anonymous",
+					"X-Token-User-Address": "198.51.100.1",
+					"X-Token-User-Country": "US",
+					"X-Token-User-City":    "Los Angeles",
+				},
+			},
+		},
+		{
+			name: "request without token to private path",
+			path: "/private",
+			err:  errors.ErrNoTokenFound,
+			want: map[string]interface{}{
+				"allowed": false,
+			},
+		},
+		{
+			name: "request with forbidden token to public path",
+			path: "/public",
+			err:  errors.ErrAccessNotAllowed,
+			want: map[string]interface{}{
+				"allowed": false,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			r := httptest.NewRequest("GET", tc.path, nil)
+			if tc.addr != "" {
+				r.RemoteAddr = tc.addr + ":1234"
+			}
+			ar := requests.NewAuthorizationRequest()
+			usr := gatekeeper.authorizeAnonymousUser(r, ar, tc.err)
+			got := make(map[string]interface{})
+			got["allowed"] = usr != nil
+			if usr != nil {
+				got["headers"] = usr.GetRequestHeaders()
+			}
+			tests.EvalObjectsWithLog(t, "anonymous user", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestHandleAnonymousUser(t *testing.T) {
+	cfg := &PolicyConfig{
+		Name:                  "mygatekeeper",
+		AuthURLPath:           "/auth",
+		PassClaimsWithHeaders: true,
+		HeaderInjectionConfigs: []*injector.Config{
+			{Header: "X-Email", Field: "email"},
+		},
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{"match path /public"},
+				Action:     "allow anonymous stop",
+			},
+		},
+		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
+	}
+
+	gatekeeper, err := NewGatekeeper(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/public", nil)
+	r.Header.Set("X-Token-User-Email", "jsmith@localhost.localdomain")
+	r.Header.Set("X-Token-User-Roles", "authp/admin")
+	r.Header.Set("X-Email", "jsmith@localhost.localdomain")
+	r.Header.Set("X-Request-Id", "foobar")
+	ar := requests.NewAuthorizationRequest()
+	usr := gatekeeper.authorizeAnonymousUser(r, ar, errors.ErrNoTokenFound)
+	if usr == nil {
+		t.Fatalf("expected anonymous user")
+	}
+	if err := gatekeeper.handleAnonymousUser(httptest.NewRecorder(), r, ar, usr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"X-Request-Id":         "foobar",
+		"X-Token-Subject":      "anonymous",
+		"X-Token-User-Name":    "anonymous",
+		"X-Token-User-Roles":   "anonymous",
+		"X-Token-User-Address": "192.0.2.1",
+	}
+	got := make(map[string]string)
+	for k := range r.Header {
+		got[k] = r.Header.Get(k)
+	}
+	tests.EvalObjects(t, "request headers", want, got)
+}
+
 func buildClient(t *testing.T, ts *httptest.Server, req *testRequest) http.Client {
 	cert, err := x509.ParseCertificate(ts.TLS.Certificates[0].Certificate[0])
 	if err != nil {
@@ -238,3 +424,200 @@ func buildRequest(t *testing.T, ts *httptest.Server, req *testRequest) *http.Req
 	}
 	return r
 }
+
+func TestAuthenticateStream(t *testing.T) {
+	cfg := &PolicyConfig{
+		Name:        "mygatekeeper",
+		AuthURLPath: "/auth",
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{
+					"match roles authp/admin authp/user",
+				},
+				Action: "allow stop",
+			},
+		},
+		StreamConfig:     &stream.Config{},
+		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
+	}
+
+	gatekeeper, err := NewGatekeeper(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testcases = []struct {
+		name      string
+		headers   map[string]string
+		expiresIn time.Duration
+		// Expected results.
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "server-sent events closed on token expiry",
+			headers: map[string]string{
+				"Accept": "text/event-stream",
+			},
+			expiresIn: time.Second,
+			want: map[string]interface{}{
+				"authorized": true,
+				"closed":     true,
+			},
+		},
+		{
+			name: "websocket upgrade open before token expiry",
+			headers: map[string]string{
+				"Upgrade":    "websocket",
+				"Connection": "Upgrade",
+			},
+			expiresIn: time.Hour,
+			want: map[string]interface{}{
+				"authorized": true,
+				"closed":     false,
+			},
+		},
+		{
+			name: "websocket upgrade without token",
+			headers: map[string]string{
+				"Upgrade":    "websocket",
+				"Connection": "Upgrade",
+			},
+			want: map[string]interface{}{
+				"status_code": 401,
+			},
+			shouldErr: true,
+			err:       errors.ErrNoTokenFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			if tc.expiresIn > 0 {
+				usr := testutils.NewTestUser()
+				usr.SetRolesClaim([]string{"authp/user"})
+				usr.SetExpiresAtClaim(time.Now().Add(tc.expiresIn).Unix())
+				ks := testutils.NewTestCryptoKeyStore()
+				if err := ks.SignToken("access_token", "HS512", usr); err != nil {
+					t.Fatal(err)
+				}
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: usr.Token})
+			}
+
+			w := httptest.NewRecorder()
+			ar := requests.NewAuthorizationRequest()
+			err := gatekeeper.Authenticate(w, r, ar)
+			got := make(map[string]interface{})
+			if tests.EvalErrWithLog(t, err, "authenticate", tc.shouldErr, tc.err, msgs) {
+				got["status_code"] = w.Code
+				tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+				return
+			}
+			got["authorized"] = ar.Response.Authorized
+			select {
+			case <-r.Context().Done():
+				got["closed"] = true
+			case <-time.After(2500 * time.Millisecond):
+				got["closed"] = false
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestAuthenticateForbidden(t *testing.T) {
+	cfg := &PolicyConfig{
+		Name:        "mygatekeeper",
+		AuthURLPath: "/auth",
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{
+					"match roles authp/admin",
+				},
+				Action: "allow stop",
+			},
+		},
+		ForbiddenPageConfig: &forbidden.Config{
+			RequestAccessURL: "https://tickets.example.com/new?reason={reason}",
+			APIPaths:         []string{"/api/"},
+		},
+		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
+	}
+
+	gatekeeper, err := NewGatekeeper(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testcases = []struct {
+		name  string
+		roles []string
+		path  string
+		// Expected results.
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "user denied access to api route",
+			roles: []string{"authp/user"},
+			path:  "/api/users",
+			want: map[string]interface{}{
+				"status_code":  403,
+				"content_type": "application/json",
+				"denial": &requests.AuthorizationDenial{
+					Reason:       acl.ReasonNoMatchingRule,
+					MissingRoles: []string{"authp/admin"},
+				},
+				"body": map[string]interface{}{
+					"error":              "forbidden",
+					"message":            "You do not have permission to access this resource.",
+					"reason":             "no_matching_rule",
+					"missing_roles":      []interface{}{"authp/admin"},
+					"request_access_url": "https://tickets.example.com/new?reason=no_matching_rule",
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrAccessNotAllowed,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			usr := testutils.NewTestUser()
+			usr.SetRolesClaim(tc.roles)
+			ks := testutils.NewTestCryptoKeyStore()
+			if err := ks.SignToken("access_token", "HS512", usr); err != nil {
+				t.Fatal(err)
+			}
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: usr.Token})
+
+			w := httptest.NewRecorder()
+			ar := requests.NewAuthorizationRequest()
+			err := gatekeeper.Authenticate(w, r, ar)
+			tests.EvalErrWithLog(t, err, "authenticate", tc.shouldErr, tc.err, msgs)
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"status_code":  w.Code,
+				"content_type": w.Header().Get("Content-Type"),
+				"denial":       ar.Response.Denial,
+				"body":         body,
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
@@ -16,6 +16,7 @@ package cache
 
 import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"sync"
 	"time"
@@ -93,11 +94,14 @@ func (c *TokenCache) Get(token string) *user.User {
 	usr, exists := c.Entries[token]
 	c.mu.RUnlock()
 	if !exists {
+		metrics.RecordCacheLookup("token", false)
 		return nil
 	}
 	if usr.Claims.ExpiresAt < time.Now().Unix() {
 		c.Delete(token)
+		metrics.RecordCacheLookup("token", false)
 		return nil
 	}
+	metrics.RecordCacheLookup("token", true)
 	return usr
 }
@@ -19,7 +19,11 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authproxy"
 	"github.com/greenpau/go-authcrunch/pkg/authz/bypass"
+	"github.com/greenpau/go-authcrunch/pkg/authz/forbidden"
 	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
+	"github.com/greenpau/go-authcrunch/pkg/authz/issuer"
+	"github.com/greenpau/go-authcrunch/pkg/authz/stream"
+	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
@@ -59,12 +63,31 @@ type PolicyConfig struct {
 	ValidateAccessListPathClaim bool `json:"validate_access_list_path_claim,omitempty" xml:"validate_access_list_path_claim,omitempty" yaml:"validate_access_list_path_claim,omitempty"`
 	// Validate source address matches between HTTP request and JWT token.
 	ValidateSourceAddress bool `json:"validate_source_address,omitempty" xml:"validate_source_address,omitempty" yaml:"validate_source_address,omitempty"`
+	// Validate the host of HTTP request is in the audience of JWT token,
+	// e.g. the tokens shared by the subdomains of a parent domain.
+	ValidateAudience bool `json:"validate_audience,omitempty" xml:"validate_audience,omitempty" yaml:"validate_audience,omitempty"`
 	// Pass claims from JWT token via HTTP X- headers.
 	PassClaimsWithHeaders bool `json:"pass_claims_with_headers,omitempty" xml:"pass_claims_with_headers,omitempty" yaml:"pass_claims_with_headers,omitempty"`
 	// Validate the login hint which can be passed to the auth provider
 	LoginHintValidators []string `json:"login_hint_validators,omitempty" xml:"login_hint_validators,omitempty" yaml:"login_hint_validators,omitempty"`
 	// Allow to append scopes that come from the query parameter 'additionalScopes'
 	AdditionalScopes bool `json:"additional_scopes,omitempty" xml:"additional_scopes,omitempty" yaml:"additional_scopes,omitempty"`
+	// The ordered list of the sources of the tokens, e.g. cookie, bearer,
+	// header, query. Overrides allowed_token_sources.
+	TokenSourceConfigs []*validator.TokenSourceConfig `json:"token_source_configs,omitempty" xml:"token_source_configs,omitempty" yaml:"token_source_configs,omitempty"`
+	// StreamConfig holds the configuration for the authorization of the
+	// WebSocket and Server-Sent Events requests.
+	StreamConfig *stream.Config `json:"stream_config,omitempty" xml:"stream_config,omitempty" yaml:"stream_config,omitempty"`
+	// The list of trusted token issuers, e.g. external OpenID Connect providers,
+	// with their own keys, audiences, and claim mappings.
+	TrustedIssuerConfigs []*issuer.Config `json:"trusted_issuer_configs,omitempty" xml:"trusted_issuer_configs,omitempty" yaml:"trusted_issuer_configs,omitempty"`
+	// The candidate access list rules evaluated alongside the active ones
+	// without affecting the decisions, i.e. dry run. The divergent decisions
+	// are logged.
+	ShadowAccessListRules []*acl.RuleConfiguration `json:"shadow_access_list_rules,omitempty" xml:"shadow_access_list_rules,omitempty" yaml:"shadow_access_list_rules,omitempty"`
+	// ForbiddenPageConfig holds the configuration for the page rendered to
+	// the users denied access. Mutually exclusive with forbidden_url.
+	ForbiddenPageConfig *forbidden.Config `json:"forbidden_page_config,omitempty" xml:"forbidden_page_config,omitempty" yaml:"forbidden_page_config,omitempty"`
 	// Holds raw crypto configuration.
 	cryptoRawConfigs []string
 	// Holds raw identity provider configuration.
@@ -177,6 +200,33 @@ func (cfg *PolicyConfig) Validate() error {
 		}
 	}
 
+	// Validate token source configs.
+	if len(cfg.TokenSourceConfigs) > 0 && len(cfg.AllowedTokenSources) > 0 {
+		return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, "allowed token sources and token source configs are mutually exclusive")
+	}
+	for _, entry := range cfg.TokenSourceConfigs {
+		if err := entry.Validate(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
+	// Validate long-lived request config.
+	if cfg.StreamConfig != nil {
+		if err := cfg.StreamConfig.Validate(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
+	// Validate access denied page config.
+	if cfg.ForbiddenPageConfig != nil {
+		if cfg.ForbiddenURL != "" {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, "forbidden url and forbidden page config are mutually exclusive")
+		}
+		if err := cfg.ForbiddenPageConfig.Validate(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
 	// Validate header injection configs.
 	for _, entry := range cfg.HeaderInjectionConfigs {
 		if err := entry.Validate(); err != nil {
@@ -185,6 +235,13 @@ func (cfg *PolicyConfig) Validate() error {
 		// cfg.PassClaimsWithHeaders = true
 	}
 
+	// Validate trusted issuer configs.
+	for _, entry := range cfg.TrustedIssuerConfigs {
+		if err := entry.Validate(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
 	if len(cfg.AccessListRules) == 0 {
 		return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, "access list rule config not found")
 	}
@@ -195,6 +252,14 @@ func (cfg *PolicyConfig) Validate() error {
 		return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
 	}
 
+	if len(cfg.ShadowAccessListRules) > 0 {
+		shadowAccessList := acl.NewAccessList()
+		shadowAccessList.SetLogger(logutil.NewLogger())
+		if err := shadowAccessList.AddRules(context.Background(), cfg.ShadowAccessListRules); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
 	cfg.validated = true
 	return nil
 }
@@ -0,0 +1,113 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+// Authorizer authorizes HTTP requests, e.g. Gatekeeper.
+type Authorizer interface {
+	Authenticate(http.ResponseWriter, *http.Request, *requests.AuthorizationRequest) error
+}
+
+// Decision is the outcome of the authorization of an HTTP request on behalf
+// of an external proxy, e.g. Envoy.
+type Decision struct {
+	Allowed  bool `json:"allowed,omitempty" xml:"allowed,omitempty" yaml:"allowed,omitempty"`
+	Bypassed bool `json:"bypassed,omitempty" xml:"bypassed,omitempty" yaml:"bypassed,omitempty"`
+	// StatusCode, ResponseHeaders, and Body are the response to the client
+	// when the request is denied, e.g. a redirect to the login page.
+	StatusCode      int         `json:"status_code,omitempty" xml:"status_code,omitempty" yaml:"status_code,omitempty"`
+	ResponseHeaders http.Header `json:"response_headers,omitempty" xml:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Body            []byte      `json:"body,omitempty" xml:"body,omitempty" yaml:"body,omitempty"`
+	Reason          string      `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	// UpstreamHeaders are the headers added or changed by the authorizer,
+	// e.g. the injected identity headers, to forward upstream.
+	UpstreamHeaders http.Header `json:"upstream_headers,omitempty" xml:"upstream_headers,omitempty" yaml:"upstream_headers,omitempty"`
+	// RemovedHeaders are the headers removed by the authorizer, e.g. the
+	// stripped authorization token.
+	RemovedHeaders []string               `json:"removed_headers,omitempty" xml:"removed_headers,omitempty" yaml:"removed_headers,omitempty"`
+	Identity       map[string]interface{} `json:"identity,omitempty" xml:"identity,omitempty" yaml:"identity,omitempty"`
+}
+
+// Decide authorizes the HTTP request and records the response of the
+// authorizer instead of writing it to the client.
+func Decide(a Authorizer, r *http.Request) *Decision {
+	original := r.Header.Clone()
+	w := &decisionRecorder{header: make(http.Header)}
+	ar := requests.NewAuthorizationRequest()
+	err := a.Authenticate(w, r, ar)
+
+	d := &Decision{}
+	switch {
+	case err == nil && ar.Response.Bypassed:
+		d.Allowed = true
+		d.Bypassed = true
+		d.Reason = "bypassed"
+	case err == nil && ar.Response.Authorized:
+		d.Allowed = true
+		d.Identity = ar.Response.User
+		d.UpstreamHeaders = make(http.Header)
+		for k, values := range r.Header {
+			if strings.Join(original.Values(k), ",") != strings.Join(values, ",") {
+				d.UpstreamHeaders[k] = values
+			}
+		}
+		for k := range original {
+			if _, exists := r.Header[k]; !exists {
+				d.RemovedHeaders = append(d.RemovedHeaders, k)
+			}
+		}
+		sort.Strings(d.RemovedHeaders)
+	default:
+		d.StatusCode = w.code
+		if d.StatusCode == 0 {
+			d.StatusCode = http.StatusUnauthorized
+		}
+		if err != nil {
+			d.Reason = err.Error()
+		}
+		d.ResponseHeaders = w.header
+		d.Body = w.body.Bytes()
+	}
+	return d
+}
+
+// decisionRecorder captures the response of an authorizer.
+type decisionRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *decisionRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *decisionRecorder) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *decisionRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// The reason of the denials by the path claim of a token.
+const denialReasonPathACL = "path_acl_claim"
+
+// explainDenial records and logs the reason an authenticated user was denied
+// access, e.g. the access list rule denying the request or the roles the
+// user is missing.
+func (g *Gatekeeper) explainDenial(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest, usr *user.User) {
+	if usr == nil {
+		return
+	}
+	switch ar.Response.Error {
+	case errors.ErrAccessNotAllowed:
+		d := g.tokenValidator.Explain(ctx, r, usr)
+		if d == nil {
+			return
+		}
+		ar.Response.Denial = &requests.AuthorizationDenial{
+			Reason:       d.Reason,
+			Rule:         d.Rule,
+			Comment:      d.Comment,
+			MissingRoles: d.MissingRoles,
+		}
+	case errors.ErrAccessNotAllowedByPathACL:
+		ar.Response.Denial = &requests.AuthorizationDenial{
+			Reason: denialReasonPathACL,
+		}
+	default:
+		return
+	}
+
+	g.logger.Info(
+		"access denied",
+		zap.String("gatekeeper_name", g.config.Name),
+		zap.String("session_id", ar.SessionID),
+		zap.String("request_id", ar.ID),
+		zap.String("sub", usr.Claims.Subject),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("reason", ar.Response.Denial.Reason),
+		zap.Int("rule", ar.Response.Denial.Rule),
+		zap.String("comment", ar.Response.Denial.Comment),
+		zap.Strings("missing_roles", ar.Response.Denial.MissingRoles),
+	)
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// Evaluate returns true when the access list rules of the gatekeeper grant
+// the identity access to the HTTP request. The identity is nil for the
+// unauthenticated requests. It implements policytest.Evaluator.
+func (g *Gatekeeper) Evaluate(r *http.Request, usr *user.User) bool {
+	if usr == nil {
+		ar := requests.NewAuthorizationRequest()
+		return g.authorizeAnonymousUser(r, ar, errors.ErrNoTokenFound) != nil
+	}
+	return g.tokenValidator.AuthorizeUser(r.Context(), r, usr) == nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forbidden
+
+import (
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	defaultTitle   = "Access Denied"
+	defaultMessage = "You do not have permission to access this resource."
+)
+
+var defaultTemplate = template.Must(template.New("forbidden").Parse(`<!doctype html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>{{ .Title }}</title>
+  </head>
+  <body>
+    <h1>{{ .Title }}</h1>
+    <p>{{ .Message }}</p>
+    {{- if .MissingRoles }}
+    <p>Required roles: {{ range $i, $role := .MissingRoles }}{{ if $i }}, {{ end }}{{ $role }}{{ end }}</p>
+    {{- end }}
+    {{- if .RequestAccessURL }}
+    <p><a href="{{ .RequestAccessURL }}">Request access</a></p>
+    {{- end }}
+    {{- if .Reason }}
+    <p><small>Reason: {{ .Reason }}</small></p>
+    {{- end }}
+  </body>
+</html>
+`))
+
+// Config holds the configuration for the page rendered to the users denied
+// access to a resource.
+type Config struct {
+	// TemplatePath is the path to the html/template file of the page.
+	// When empty, the built-in template is used.
+	TemplatePath string `json:"template_path,omitempty" xml:"template_path,omitempty" yaml:"template_path,omitempty"`
+	Title        string `json:"title,omitempty" xml:"title,omitempty" yaml:"title,omitempty"`
+	Message      string `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	// RequestAccessURL is the link to the page where users request access,
+	// e.g. a ticketing system. The {uri}, {url}, and {reason} placeholders
+	// are replaced with the query-escaped values of the denied request.
+	RequestAccessURL string `json:"request_access_url,omitempty" xml:"request_access_url,omitempty" yaml:"request_access_url,omitempty"`
+	// APIPaths are the path prefixes of the API routes. The requests to the
+	// routes, and the requests accepting JSON, receive a JSON body.
+	APIPaths []string `json:"api_paths,omitempty" xml:"api_paths,omitempty" yaml:"api_paths,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.TemplatePath != "" {
+		if _, err := loadTemplate(cfg.TemplatePath); err != nil {
+			return err
+		}
+	}
+	if cfg.RequestAccessURL != "" {
+		if _, err := url.Parse(cfg.RequestAccessURL); err != nil {
+			return errors.ErrForbiddenPageConfigRequestAccess.WithArgs(cfg.RequestAccessURL, err)
+		}
+	}
+	for _, p := range cfg.APIPaths {
+		if !strings.HasPrefix(p, "/") {
+			return errors.ErrForbiddenPageConfigAPIPathInvalid.WithArgs(p)
+		}
+	}
+	return nil
+}
+
+// Page renders the responses to the requests denied access.
+type Page struct {
+	config *Config
+	tmpl   *template.Template
+}
+
+// NewPage returns an instance of Page.
+func NewPage(cfg *Config) (*Page, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p := &Page{
+		config: cfg,
+		tmpl:   defaultTemplate,
+	}
+	if cfg.TemplatePath != "" {
+		tmpl, err := loadTemplate(cfg.TemplatePath)
+		if err != nil {
+			return nil, err
+		}
+		p.tmpl = tmpl
+	}
+	return p, nil
+}
+
+// Render writes the 403 response for the denied request. The denial may be
+// nil when the reason is unknown.
+func (p *Page) Render(w http.ResponseWriter, r *http.Request, denial *requests.AuthorizationDenial) error {
+	if denial == nil {
+		denial = &requests.AuthorizationDenial{}
+	}
+	data := map[string]interface{}{
+		"Title":            p.config.Title,
+		"Message":          p.config.Message,
+		"RequestAccessURL": p.getRequestAccessURL(r, denial),
+		"Reason":           denial.Reason,
+		"Rule":             denial.Rule,
+		"MissingRoles":     denial.MissingRoles,
+	}
+	if data["Title"] == "" {
+		data["Title"] = defaultTitle
+	}
+	if data["Message"] == "" {
+		data["Message"] = defaultMessage
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if p.isAPIRequest(r) {
+		resp := map[string]interface{}{
+			"error":   "forbidden",
+			"message": data["Message"],
+		}
+		if denial.Reason != "" {
+			resp["reason"] = denial.Reason
+		}
+		if denial.Rule > 0 {
+			resp["rule"] = denial.Rule
+		}
+		if len(denial.MissingRoles) > 0 {
+			resp["missing_roles"] = denial.MissingRoles
+		}
+		if data["RequestAccessURL"] != "" {
+			resp["request_access_url"] = data["RequestAccessURL"]
+		}
+		b, _ := json.Marshal(resp)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, err := w.Write(b)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	return p.tmpl.Execute(w, data)
+}
+
+func (p *Page) isAPIRequest(r *http.Request) bool {
+	for _, prefix := range p.config.APIPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+func (p *Page) getRequestAccessURL(r *http.Request, denial *requests.AuthorizationDenial) string {
+	s := p.config.RequestAccessURL
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	return strings.NewReplacer(
+		"{uri}", url.QueryEscape(r.URL.String()),
+		"{url}", url.QueryEscape(util.GetCurrentURL(r)),
+		"{reason}", url.QueryEscape(denial.Reason),
+	).Replace(s)
+}
+
+func loadTemplate(fp string) (*template.Template, error) {
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, errors.ErrForbiddenPageConfigTemplate.WithArgs(fp, err)
+	}
+	tmpl, err := template.New("forbidden").Parse(string(b))
+	if err != nil {
+		return nil, errors.ErrForbiddenPageConfigTemplate.WithArgs(fp, err)
+	}
+	return tmpl, nil
+}
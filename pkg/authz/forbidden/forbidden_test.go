@@ -0,0 +1,175 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forbidden
+
+import (
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmplPath := filepath.Join(tmpDir, "forbidden.html")
+	if err := os.WriteFile(tmplPath, []byte(`{{ .Title }}|{{ .Reason }}|{{ .RequestAccessURL }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	denial := &requests.AuthorizationDenial{
+		Reason:       "no_matching_rule",
+		MissingRoles: []string{"admin"},
+	}
+
+	var testcases = []struct {
+		name      string
+		config    *Config
+		path      string
+		accept    string
+		denial    *requests.AuthorizationDenial
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "render built-in page",
+			config: &Config{
+				RequestAccessURL: "https://tickets.example.com/new?resource={uri}&reason={reason}",
+			},
+			path:   "/admin?id=1",
+			denial: denial,
+			want: map[string]interface{}{
+				"content_type": "text/html; charset=utf-8",
+				"contains": []string{
+					"Access Denied",
+					"Required roles: admin",
+					`href="https://tickets.example.com/new?resource=%2Fadmin%3Fid%3D1&amp;reason=no_matching_rule"`,
+				},
+			},
+		},
+		{
+			name: "render custom template",
+			config: &Config{
+				TemplatePath:     tmplPath,
+				Title:            "Nope",
+				RequestAccessURL: "https://tickets.example.com/new",
+			},
+			path:   "/admin",
+			denial: denial,
+			want: map[string]interface{}{
+				"content_type": "text/html; charset=utf-8",
+				"contains": []string{
+					"Nope|no_matching_rule|https://tickets.example.com/new",
+				},
+			},
+		},
+		{
+			name: "render json for api path",
+			config: &Config{
+				APIPaths:         []string{"/api/"},
+				RequestAccessURL: "https://tickets.example.com/new",
+			},
+			path:   "/api/users",
+			denial: denial,
+			want: map[string]interface{}{
+				"content_type": "application/json",
+				"body": map[string]interface{}{
+					"error":              "forbidden",
+					"message":            defaultMessage,
+					"reason":             "no_matching_rule",
+					"missing_roles":      []interface{}{"admin"},
+					"request_access_url": "https://tickets.example.com/new",
+				},
+			},
+		},
+		{
+			name:   "render json for client accepting json without denial",
+			config: &Config{},
+			path:   "/admin",
+			accept: "application/json",
+			want: map[string]interface{}{
+				"content_type": "application/json",
+				"body": map[string]interface{}{
+					"error":   "forbidden",
+					"message": defaultMessage,
+				},
+			},
+		},
+		{
+			name: "test config with missing template",
+			config: &Config{
+				TemplatePath: filepath.Join(tmpDir, "missing.html"),
+			},
+			shouldErr: true,
+			err: errors.ErrForbiddenPageConfigTemplate.WithArgs(
+				filepath.Join(tmpDir, "missing.html"),
+				"open "+filepath.Join(tmpDir, "missing.html")+": no such file or directory",
+			),
+		},
+		{
+			name: "test config with invalid api path",
+			config: &Config{
+				APIPaths: []string{"api"},
+			},
+			shouldErr: true,
+			err:       errors.ErrForbiddenPageConfigAPIPathInvalid.WithArgs("api"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{tc.name}
+			p, err := NewPage(tc.config)
+			if tests.EvalErrWithLog(t, err, "page", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			r := httptest.NewRequest("GET", tc.path, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+			if err := p.Render(w, r, tc.denial); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != 403 {
+				t.Fatalf("unexpected status code: %d", w.Code)
+			}
+			got := map[string]interface{}{
+				"content_type": w.Header().Get("Content-Type"),
+			}
+			if _, exists := tc.want["body"]; exists {
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got["body"] = body
+			}
+			if entries, exists := tc.want["contains"]; exists {
+				var found []string
+				for _, entry := range entries.([]string) {
+					if strings.Contains(w.Body.String(), entry) {
+						found = append(found, entry)
+					}
+				}
+				got["contains"] = found
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
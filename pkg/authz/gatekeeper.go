@@ -18,6 +18,7 @@ import (
 	"context"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authproxy"
+	"github.com/greenpau/go-authcrunch/pkg/authz/forbidden"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
 	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
@@ -36,11 +37,15 @@ type Gatekeeper struct {
 	opts           *options.TokenValidatorOptions
 	accessList     *acl.AccessList
 	authenticators []authproxy.Authenticator
+	// The access list with the rules applying to unauthenticated requests.
+	anonymousAccessList *acl.AccessList
 	// Enable authorization bypass for specific URIs.
 	bypassEnabled bool
 	// The names of the headers injected by an instance.
 	injectedHeaders map[string]bool
-	logger          *zap.Logger
+	// The page rendered to the users denied access.
+	forbiddenPage *forbidden.Page
+	logger        *zap.Logger
 }
 
 // NewGatekeeper returns an instance of Gatekeeper.
@@ -96,6 +101,9 @@ func (g *Gatekeeper) configure() error {
 	if g.config.ValidateSourceAddress {
 		g.opts.ValidateSourceAddress = true
 	}
+	if g.config.ValidateAudience {
+		g.opts.ValidateAudience = true
+	}
 
 	// Load token configuration into key managers, extract token verification
 	// keys and add them to token validator.
@@ -128,18 +136,72 @@ func (g *Gatekeeper) configure() error {
 	if err := accessList.AddRules(ctx, g.config.AccessListRules); err != nil {
 		return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
 	}
+	if err := accessList.CheckExtensions(ctx); err != nil {
+		return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+	}
+
+	// Load the rules applying to unauthenticated requests.
+	var anonymousRules []*acl.RuleConfiguration
+	for _, rule := range g.config.AccessListRules {
+		if rule.IsAnonymous() {
+			anonymousRules = append(anonymousRules, rule)
+		}
+	}
+	if len(anonymousRules) > 0 {
+		g.anonymousAccessList = acl.NewAccessList()
+		g.anonymousAccessList.SetLogger(g.logger)
+		if err := g.anonymousAccessList.AddRules(ctx, anonymousRules); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+	}
 
 	// Configure token validator with keys and access list.
 	if err := g.tokenValidator.Configure(ctx, ks.GetVerifyKeys(), accessList, g.opts); err != nil {
 		return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
 	}
 
+	// Evaluate the candidate access list alongside the active one.
+	if len(g.config.ShadowAccessListRules) > 0 {
+		shadowAccessList := acl.NewAccessList()
+		shadowAccessList.SetLogger(g.logger)
+		if err := shadowAccessList.AddRules(ctx, g.config.ShadowAccessListRules); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+		if err := shadowAccessList.CheckExtensions(ctx); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+		if err := g.tokenValidator.SetShadowAccessList(ctx, shadowAccessList, g.reportShadowDecision); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+	}
+
+	// Configure access denied page.
+	if g.config.ForbiddenPageConfig != nil {
+		page, err := forbidden.NewPage(g.config.ForbiddenPageConfig)
+		if err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+		g.forbiddenPage = page
+	}
+
+	// Add trusted token issuers.
+	if len(g.config.TrustedIssuerConfigs) > 0 {
+		if err := g.tokenValidator.AddIssuers(ctx, g.config.TrustedIssuerConfigs); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+	}
+
 	// Set allow token sources and their priority.
 	if len(g.config.AllowedTokenSources) > 0 {
 		if err := g.tokenValidator.SetSourcePriority(g.config.AllowedTokenSources); err != nil {
 			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
 		}
 	}
+	if len(g.config.TokenSourceConfigs) > 0 {
+		if err := g.tokenValidator.SetSources(g.config.TokenSourceConfigs); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+	}
 
 	g.logger.Debug(
 		"Configured gatekeeper",
@@ -149,7 +211,9 @@ func (g *Gatekeeper) configure() error {
 		zap.String("token_sources", strings.Join(g.tokenValidator.GetSourcePriority(), " ")),
 		zap.Any("token_validator_options", g.opts),
 		zap.Any("access_list_rules", g.config.AccessListRules),
+		zap.Any("shadow_access_list_rules", g.config.ShadowAccessListRules),
 		zap.String("forbidden_path", g.config.ForbiddenURL),
+		zap.Any("forbidden_page_config", g.config.ForbiddenPageConfig),
 	)
 	return nil
 }
@@ -19,7 +19,9 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authz/bypass"
+	"github.com/greenpau/go-authcrunch/pkg/authz/forbidden"
 	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
+	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
 	"go.uber.org/zap"
@@ -77,6 +79,60 @@ func TestNewGatekeeper(t *testing.T) {
 			shouldErr: true,
 			err:       errors.ErrNewGatekeeper.WithArgs(errors.ErrPolicyConfigNameNotFound),
 		},
+		{
+			name: "test new gatekeeper with conflicting token sources",
+			loggerFunc: func() *zap.Logger {
+				return logutil.NewLogger()
+			},
+			configFunc: func() *PolicyConfig {
+				return &PolicyConfig{
+					Name:                "mygatekeeper",
+					AllowedTokenSources: []string{"cookie"},
+					TokenSourceConfigs: []*validator.TokenSourceConfig{
+						{Type: "bearer"},
+					},
+				}
+			},
+			shouldErr: true,
+			err: errors.ErrNewGatekeeper.WithArgs(
+				errors.ErrInvalidConfiguration.WithArgs("mygatekeeper", "allowed token sources and token source configs are mutually exclusive"),
+			),
+		},
+		{
+			name: "test new gatekeeper with conflicting forbidden page configs",
+			loggerFunc: func() *zap.Logger {
+				return logutil.NewLogger()
+			},
+			configFunc: func() *PolicyConfig {
+				return &PolicyConfig{
+					Name:                "mygatekeeper",
+					ForbiddenURL:        "/forbidden",
+					ForbiddenPageConfig: &forbidden.Config{},
+				}
+			},
+			shouldErr: true,
+			err: errors.ErrNewGatekeeper.WithArgs(
+				errors.ErrInvalidConfiguration.WithArgs("mygatekeeper", "forbidden url and forbidden page config are mutually exclusive"),
+			),
+		},
+		{
+			name: "test new gatekeeper with invalid token source",
+			loggerFunc: func() *zap.Logger {
+				return logutil.NewLogger()
+			},
+			configFunc: func() *PolicyConfig {
+				return &PolicyConfig{
+					Name: "mygatekeeper",
+					TokenSourceConfigs: []*validator.TokenSourceConfig{
+						{Type: "body"},
+					},
+				}
+			},
+			shouldErr: true,
+			err: errors.ErrNewGatekeeper.WithArgs(
+				errors.ErrInvalidConfiguration.WithArgs("mygatekeeper", errors.ErrTokenSourceConfigType.WithArgs("body")),
+			),
+		},
 		{
 			name: "test new gatekeeper",
 			loggerFunc: func() *zap.Logger {
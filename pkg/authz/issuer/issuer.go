@@ -0,0 +1,182 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuer
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	datautil "github.com/greenpau/go-authcrunch/pkg/util/data"
+)
+
+// ClaimMapping maps the claim of a token, e.g. "resource_access|portal|roles", to a
+// user claim, e.g. "roles". The nested claims are separated with pipe.
+type ClaimMapping struct {
+	Source string `json:"source,omitempty" xml:"source,omitempty" yaml:"source,omitempty"`
+	Target string `json:"target,omitempty" xml:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// Config is the configuration of a trusted token issuer, e.g. an external
+// OpenID Connect provider.
+type Config struct {
+	// Name is the name of the trusted issuer.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Issuer is the value of the iss claim in the tokens of the issuer.
+	Issuer string `json:"issuer,omitempty" xml:"issuer,omitempty" yaml:"issuer,omitempty"`
+	// Audiences is the list of accepted aud claim values. If empty, the
+	// audience is not checked.
+	Audiences []string `json:"audiences,omitempty" xml:"audiences,omitempty" yaml:"audiences,omitempty"`
+	// CryptoKeyConfigs hold the configurations for the keys used to verify
+	// the tokens of the issuer.
+	CryptoKeyConfigs []*kms.CryptoKeyConfig `json:"crypto_key_configs,omitempty" xml:"crypto_key_configs,omitempty" yaml:"crypto_key_configs,omitempty"`
+	// ClaimMappings hold the rules for mapping token claims to user claims.
+	ClaimMappings []*ClaimMapping `json:"claim_mappings,omitempty" xml:"claim_mappings,omitempty" yaml:"claim_mappings,omitempty"`
+}
+
+// Issuer verifies the tokens of a trusted issuer.
+type Issuer struct {
+	config    *Config
+	keystore  *kms.CryptoKeyStore
+	audiences map[string]bool
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	cfg.Name = strings.TrimSpace(cfg.Name)
+	cfg.Issuer = strings.TrimSpace(cfg.Issuer)
+	if cfg.Name == "" {
+		return errors.ErrIssuerConfigNameNotFound
+	}
+	if cfg.Issuer == "" {
+		return errors.ErrIssuerConfigIssuerNotFound.WithArgs(cfg.Name)
+	}
+	if len(cfg.CryptoKeyConfigs) == 0 {
+		return errors.ErrIssuerConfigKeysNotFound.WithArgs(cfg.Name)
+	}
+	for i, m := range cfg.ClaimMappings {
+		m.Source = strings.TrimSpace(m.Source)
+		m.Target = strings.TrimSpace(m.Target)
+		if m.Source == "" {
+			return errors.ErrIssuerConfigClaimMapping.WithArgs(cfg.Name, i, "empty source")
+		}
+		if m.Target == "" {
+			return errors.ErrIssuerConfigClaimMapping.WithArgs(cfg.Name, i, "empty target")
+		}
+	}
+	return nil
+}
+
+// NewIssuer returns an instance of Issuer.
+func NewIssuer(cfg *Config) (*Issuer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	i := &Issuer{
+		config:   cfg,
+		keystore: kms.NewCryptoKeyStore(),
+	}
+	if err := i.keystore.AddKeysWithConfigs(cfg.CryptoKeyConfigs); err != nil {
+		return nil, errors.ErrIssuerConfigKeys.WithArgs(cfg.Name, err)
+	}
+	if err := i.keystore.HasVerifyKeys(); err != nil {
+		return nil, errors.ErrIssuerConfigKeys.WithArgs(cfg.Name, err)
+	}
+	if len(cfg.Audiences) > 0 {
+		i.audiences = make(map[string]bool)
+		for _, aud := range cfg.Audiences {
+			i.audiences[aud] = true
+		}
+	}
+	return i, nil
+}
+
+// GetName returns the name of Issuer.
+func (i *Issuer) GetName() string {
+	return i.config.Name
+}
+
+// GetIssuer returns the iss claim value of the tokens of Issuer.
+func (i *Issuer) GetIssuer() string {
+	return i.config.Issuer
+}
+
+// GetTokenNames returns the names of the tokens verified by Issuer.
+func (i *Issuer) GetTokenNames() []string {
+	var names []string
+	m := make(map[string]bool)
+	for _, k := range i.keystore.GetVerifyKeys() {
+		if k.Verify.Token.Name == "" {
+			continue
+		}
+		if _, exists := m[k.Verify.Token.Name]; exists {
+			continue
+		}
+		m[k.Verify.Token.Name] = true
+		names = append(names, k.Verify.Token.Name)
+	}
+	return names
+}
+
+// ParseToken verifies the token issued by Issuer, checks its audience, maps
+// its claims, and returns User instance.
+func (i *Issuer) ParseToken(ar *requests.AuthorizationRequest) (*user.User, error) {
+	m, err := i.keystore.ParseTokenClaims(ar)
+	// The authentication URL of the external issuer is not a portal URL.
+	ar.Redirect.AuthURL = ""
+	if err != nil {
+		return nil, err
+	}
+
+	if i.audiences != nil && !i.matchAudience(m["aud"]) {
+		return nil, errors.ErrIssuerTokenAudienceMismatch.WithArgs(i.config.Name)
+	}
+
+	for _, mapping := range i.config.ClaimMappings {
+		v := datautil.GetValueFromMapByPath(mapping.Source, m)
+		if s, ok := v.(string); ok && s == "" {
+			continue
+		}
+		m[mapping.Target] = v
+	}
+
+	usr, err := user.NewUser(m)
+	if err != nil {
+		return usr, errors.ErrCryptoKeyStoreTokenData
+	}
+	return usr, nil
+}
+
+func (i *Issuer) matchAudience(v interface{}) bool {
+	switch aud := v.(type) {
+	case string:
+		return i.audiences[aud]
+	case []interface{}:
+		for _, entry := range aud {
+			if s, ok := entry.(string); ok && i.audiences[s] {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range aud {
+			if i.audiences[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
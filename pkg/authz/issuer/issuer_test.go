@@ -0,0 +1,170 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestIssuerParseToken(t *testing.T) {
+	secret := "b006d65b-c923-46a1-8da1-7d52558508fe"
+	keyConfigs, err := kms.ParseCryptoKeyConfigs(fmt.Sprintf("crypto key verify %s", secret))
+	if err != nil {
+		t.Fatalf("failed parsing crypto key configs: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		config    *Config
+		claims    jwtlib.MapClaims
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "token with matching audience and mapped roles",
+			config: &Config{
+				Name:             "keycloak",
+				Issuer:           "https://sso.contoso.com/realms/contoso",
+				Audiences:        []string{"portal", "api"},
+				CryptoKeyConfigs: keyConfigs,
+				ClaimMappings: []*ClaimMapping{
+					{Source: "resource_access|portal|roles", Target: "roles"},
+				},
+			},
+			claims: jwtlib.MapClaims{
+				"iss":   "https://sso.contoso.com/realms/contoso",
+				"aud":   []string{"account", "api"},
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"resource_access": map[string]interface{}{
+					"portal": map[string]interface{}{
+						"roles": []string{"authp/user"},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"roles": []string{"authp/user"},
+			},
+		},
+		{
+			name: "token without audience check",
+			config: &Config{
+				Name:             "keycloak",
+				Issuer:           "https://sso.contoso.com/realms/contoso",
+				CryptoKeyConfigs: keyConfigs,
+			},
+			claims: jwtlib.MapClaims{
+				"iss":   "https://sso.contoso.com/realms/contoso",
+				"aud":   "account",
+				"sub":   "jsmith",
+				"roles": []string{"authp/admin"},
+			},
+			want: map[string]interface{}{
+				"sub":   "jsmith",
+				"roles": []string{"authp/admin"},
+			},
+		},
+		{
+			name: "token with mismatched audience",
+			config: &Config{
+				Name:             "keycloak",
+				Issuer:           "https://sso.contoso.com/realms/contoso",
+				Audiences:        []string{"portal"},
+				CryptoKeyConfigs: keyConfigs,
+			},
+			claims: jwtlib.MapClaims{
+				"iss":   "https://sso.contoso.com/realms/contoso",
+				"aud":   "account",
+				"sub":   "jsmith",
+				"roles": []string{"authp/admin"},
+			},
+			shouldErr: true,
+			err:       errors.ErrIssuerTokenAudienceMismatch.WithArgs("keycloak"),
+		},
+		{
+			name: "config without keys",
+			config: &Config{
+				Name:   "keycloak",
+				Issuer: "https://sso.contoso.com/realms/contoso",
+			},
+			shouldErr: true,
+			err:       errors.ErrIssuerConfigKeysNotFound.WithArgs("keycloak"),
+		},
+		{
+			name: "config with invalid claim mapping",
+			config: &Config{
+				Name:             "keycloak",
+				Issuer:           "https://sso.contoso.com/realms/contoso",
+				CryptoKeyConfigs: keyConfigs,
+				ClaimMappings: []*ClaimMapping{
+					{Source: "groups"},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrIssuerConfigClaimMapping.WithArgs("keycloak", 0, "empty target"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			i, err := NewIssuer(tc.config)
+			if err != nil {
+				if tests.EvalErrWithLog(t, err, "issuer", tc.shouldErr, tc.err, msgs) {
+					return
+				}
+			}
+
+			tc.claims["exp"] = time.Now().Add(10 * time.Minute).Unix()
+			token, err := jwtlib.NewWithClaims(jwtlib.SigningMethodHS512, tc.claims).SignedString([]byte(secret))
+			if err != nil {
+				t.Fatalf("failed signing token: %v", err)
+			}
+
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = token
+			usr, err := i.ParseToken(ar)
+			if tests.EvalErrWithLog(t, err, "parse token", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			got := make(map[string]interface{})
+			got["sub"] = usr.Claims.Subject
+			if usr.Claims.Email != "" {
+				got["email"] = usr.Claims.Email
+			}
+			got["roles"] = usr.Claims.Roles
+			if ar.Redirect.AuthURL != "" {
+				t.Fatalf("unexpected auth url: %s", ar.Redirect.AuthURL)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Logf("unexpected user claims (-want +got):\n%s", diff)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ type TokenValidatorOptions struct {
 	ValidateBearerHeader        bool `json:"validate_bearer_header,omitempty" xml:"validate_bearer_header,omitempty" yaml:"validate_bearer_header,omitempty"`
 	ValidateMethodPath          bool `json:"validate_method_path,omitempty" xml:"validate_method_path,omitempty" yaml:"validate_method_path,omitempty"`
 	ValidateAccessListPathClaim bool `json:"validate_access_list_path_claim,omitempty" xml:"validate_access_list_path_claim,omitempty" yaml:"validate_access_list_path_claim,omitempty"`
+	ValidateAudience            bool `json:"validate_audience,omitempty" xml:"validate_audience,omitempty" yaml:"validate_audience,omitempty"`
 }
 
 // TokenGrantorOptions provides options for TokenGrantor.
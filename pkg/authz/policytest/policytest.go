@@ -0,0 +1,185 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policytest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ExpectAllow is the expected result of the requests granted access.
+	ExpectAllow = "allow"
+	// ExpectDeny is the expected result of the requests denied access.
+	ExpectDeny = "deny"
+)
+
+// Evaluator evaluates the access of an identity to an HTTP request, e.g.
+// authz.Gatekeeper. The identity is nil for unauthenticated requests.
+type Evaluator interface {
+	Evaluate(*http.Request, *user.User) bool
+}
+
+// Case is a sample request with the expected decision of an authorization
+// policy.
+type Case struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// The name of the authorization policy evaluating the request.
+	Policy string `json:"policy,omitempty" xml:"policy,omitempty" yaml:"policy,omitempty"`
+	// The claims of the identity making the request. When empty, the request
+	// is unauthenticated.
+	Identity map[string]interface{} `json:"identity,omitempty" xml:"identity,omitempty" yaml:"identity,omitempty"`
+	Method   string                 `json:"method,omitempty" xml:"method,omitempty" yaml:"method,omitempty"`
+	Path     string                 `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	Host     string                 `json:"host,omitempty" xml:"host,omitempty" yaml:"host,omitempty"`
+	// The source address of the request.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// The expected decision, i.e. allow or deny.
+	Expect string `json:"expect,omitempty" xml:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// Suite is a collection of policy test cases.
+type Suite struct {
+	Tests []*Case `json:"tests,omitempty" xml:"tests,omitempty" yaml:"tests,omitempty"`
+}
+
+// Result is the outcome of a policy test case.
+type Result struct {
+	Name     string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Policy   string `json:"policy,omitempty" xml:"policy,omitempty" yaml:"policy,omitempty"`
+	Expected string `json:"expected,omitempty" xml:"expected,omitempty" yaml:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty" xml:"actual,omitempty" yaml:"actual,omitempty"`
+	Passed   bool   `json:"passed,omitempty" xml:"passed,omitempty" yaml:"passed,omitempty"`
+	Error    string `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Validate validates Case.
+func (c *Case) Validate() error {
+	if c.Name == "" {
+		return errors.ErrPolicyTestNameEmpty
+	}
+	if c.Policy == "" {
+		return errors.ErrPolicyTestPolicyEmpty.WithArgs(c.Name)
+	}
+	if c.Path == "" {
+		return errors.ErrPolicyTestPathEmpty.WithArgs(c.Name)
+	}
+	c.Expect = strings.ToLower(c.Expect)
+	switch c.Expect {
+	case ExpectAllow, ExpectDeny:
+	default:
+		return errors.ErrPolicyTestExpectInvalid.WithArgs(c.Name, c.Expect)
+	}
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	return nil
+}
+
+// Evaluate evaluates the sample request with the evaluator.
+func (c *Case) Evaluate(e Evaluator) *Result {
+	err := c.Validate()
+	res := &Result{
+		Name:     c.Name,
+		Policy:   c.Policy,
+		Expected: c.Expect,
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	var usr *user.User
+	if len(c.Identity) > 0 {
+		m := make(map[string]interface{})
+		for k, v := range c.Identity {
+			m[k] = v
+		}
+		if _, exists := m["addr"]; !exists && c.Address != "" {
+			m["addr"] = c.Address
+		}
+		u, err := user.NewUser(m)
+		if err != nil {
+			res.Error = errors.ErrPolicyTestIdentity.WithArgs(c.Name, err).Error()
+			return res
+		}
+		usr = u
+	}
+	r := httptest.NewRequest(c.Method, c.Path, nil)
+	if c.Host != "" {
+		r.Host = c.Host
+	}
+	if c.Address != "" {
+		r.RemoteAddr = net.JoinHostPort(c.Address, "0")
+	}
+	res.Actual = ExpectDeny
+	if e.Evaluate(r, usr) {
+		res.Actual = ExpectAllow
+	}
+	res.Passed = res.Actual == res.Expected
+	return res
+}
+
+// Run evaluates the test cases of the suite. The lookup function returns
+// the evaluator of an authorization policy by its name.
+func (s *Suite) Run(lookup func(string) (Evaluator, error)) []*Result {
+	var results []*Result
+	for _, c := range s.Tests {
+		e, err := lookup(c.Policy)
+		if err != nil {
+			results = append(results, &Result{
+				Name:     c.Name,
+				Policy:   c.Policy,
+				Expected: c.Expect,
+				Error:    errors.ErrPolicyTestPolicyNotFound.WithArgs(c.Name, c.Policy).Error(),
+			})
+			continue
+		}
+		results = append(results, c.Evaluate(e))
+	}
+	return results
+}
+
+// LoadSuite reads Suite from a JSON or YAML file.
+func LoadSuite(fp string) (*Suite, error) {
+	b, err := fileutil.ReadFileBytes(fp)
+	if err != nil {
+		return nil, errors.ErrPolicyTestLoad.WithArgs(fp, err)
+	}
+	suite := &Suite{}
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, suite)
+	default:
+		err = json.Unmarshal(b, suite)
+	}
+	if err != nil {
+		return nil, errors.ErrPolicyTestLoad.WithArgs(fp, err)
+	}
+	for _, c := range suite.Tests {
+		if err := c.Validate(); err != nil {
+			return nil, errors.ErrPolicyTestLoad.WithArgs(fp, err)
+		}
+	}
+	return suite, nil
+}
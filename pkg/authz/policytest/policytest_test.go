@@ -0,0 +1,164 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policytest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+// testEvaluator allows the admins and the unauthenticated GET requests to
+// the public path.
+type testEvaluator struct{}
+
+func (e *testEvaluator) Evaluate(r *http.Request, usr *user.User) bool {
+	if usr == nil {
+		return r.Method == http.MethodGet && r.URL.Path == "/public"
+	}
+	return usr.HasRole("admin")
+}
+
+func TestRun(t *testing.T) {
+	suite := &Suite{
+		Tests: []*Case{
+			{
+				Name:     "admin accesses app",
+				Policy:   "mypolicy",
+				Identity: map[string]interface{}{"sub": "jsmith", "roles": []interface{}{"admin"}},
+				Path:     "/app",
+				Expect:   "ALLOW",
+			},
+			{
+				Name:     "user accesses app",
+				Policy:   "mypolicy",
+				Identity: map[string]interface{}{"sub": "jsmith", "roles": []interface{}{"user"}},
+				Path:     "/app",
+				Expect:   "allow",
+			},
+			{
+				Name:   "anonymous user accesses public path",
+				Policy: "mypolicy",
+				Path:   "/public",
+				Expect: "allow",
+			},
+			{
+				Name:   "anonymous user posts to public path",
+				Policy: "mypolicy",
+				Method: "POST",
+				Path:   "/public",
+				Expect: "deny",
+			},
+			{
+				Name:   "test with unknown policy",
+				Policy: "otherpolicy",
+				Path:   "/app",
+				Expect: "deny",
+			},
+			{
+				Name:   "test with invalid expected result",
+				Policy: "mypolicy",
+				Path:   "/app",
+				Expect: "maybe",
+			},
+		},
+	}
+	got := suite.Run(func(name string) (Evaluator, error) {
+		if name != "mypolicy" {
+			return nil, errors.ErrGatekeeperRegistryEntryNotFound.WithArgs(name)
+		}
+		return &testEvaluator{}, nil
+	})
+	want := []*Result{
+		{Name: "admin accesses app", Policy: "mypolicy", Expected: "allow", Actual: "allow", Passed: true},
+		{Name: "user accesses app", Policy: "mypolicy", Expected: "allow", Actual: "deny"},
+		{Name: "anonymous user accesses public path", Policy: "mypolicy", Expected: "allow", Actual: "allow", Passed: true},
+		{Name: "anonymous user posts to public path", Policy: "mypolicy", Expected: "deny", Actual: "deny", Passed: true},
+		{
+			Name: "test with unknown policy", Policy: "otherpolicy", Expected: "deny",
+			Error: errors.ErrPolicyTestPolicyNotFound.WithArgs("test with unknown policy", "otherpolicy").Error(),
+		},
+		{
+			Name: "test with invalid expected result", Policy: "mypolicy", Expected: "maybe",
+			Error: errors.ErrPolicyTestExpectInvalid.WithArgs("test with invalid expected result", "maybe").Error(),
+		},
+	}
+	tests.EvalObjectsWithLog(t, "results", want, got, nil)
+}
+
+func TestLoadSuite(t *testing.T) {
+	dir := t.TempDir()
+
+	var testcases = []struct {
+		name      string
+		fileName  string
+		data      string
+		want      []*Case
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test loading yaml suite",
+			fileName: "tests.yaml",
+			data: "tests:\n" +
+				"  - name: admin accesses app\n" +
+				"    policy: mypolicy\n" +
+				"    identity:\n" +
+				"      sub: jsmith\n" +
+				"      roles: [admin]\n" +
+				"    path: /app\n" +
+				"    expect: allow\n",
+			want: []*Case{
+				{
+					Name:     "admin accesses app",
+					Policy:   "mypolicy",
+					Identity: map[string]interface{}{"sub": "jsmith", "roles": []interface{}{"admin"}},
+					Method:   "GET",
+					Path:     "/app",
+					Expect:   "allow",
+				},
+			},
+		},
+		{
+			name:      "test loading json suite without path",
+			fileName:  "tests.json",
+			data:      `{"tests": [{"name": "foo", "policy": "mypolicy", "expect": "deny"}]}`,
+			shouldErr: true,
+			err: errors.ErrPolicyTestLoad.WithArgs(
+				filepath.Join(dir, "tests.json"),
+				errors.ErrPolicyTestPathEmpty.WithArgs("foo"),
+			),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fp := filepath.Join(dir, tc.fileName)
+			if err := os.WriteFile(fp, []byte(tc.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			suite, err := LoadSuite(fp)
+			if tests.EvalErrWithLog(t, err, "LoadSuite", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "cases", tc.want, suite.Tests, nil)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// reportShadowDecision logs the decision of the candidate access list
+// diverging from the decision of the active one. The decision of the
+// active access list is enforced.
+func (g *Gatekeeper) reportShadowDecision(r *http.Request, usr *user.User, allowed, candidateAllowed bool) {
+	decision := "deny"
+	if candidateAllowed {
+		decision = "allow"
+	}
+	metrics.ShadowDecisions.Inc(g.config.Name, decision)
+	g.logger.Warn(
+		"shadow access list decision diverged",
+		zap.String("gatekeeper_name", g.config.Name),
+		zap.String("user", usr.Claims.Subject),
+		zap.String("origin", usr.Claims.Origin),
+		zap.Strings("roles", usr.Claims.Roles),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.Bool("allowed", allowed),
+		zap.Bool("shadow_allowed", candidateAllowed),
+	)
+}
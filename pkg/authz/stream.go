@@ -0,0 +1,70 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz/stream"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+)
+
+// isStream returns true when the long-lived request authorization is
+// configured and the request is a WebSocket upgrade or Server-Sent Events.
+func (g *Gatekeeper) isStream(r *http.Request) bool {
+	return g.config.StreamConfig != nil && stream.GetKind(r) != ""
+}
+
+// watchStream replaces the context of the authorized long-lived request
+// with the context canceled when the token of the user expires or fails
+// re-validation. The reverse proxies close the upgraded connections, and
+// the handlers of Server-Sent Events stop, when the context is canceled.
+func (g *Gatekeeper) watchStream(r *http.Request, ar *requests.AuthorizationRequest, usr *user.User) {
+	if !g.isStream(r) {
+		return
+	}
+	kind := stream.GetKind(r)
+	var expiresAt time.Time
+	if usr.Claims != nil && usr.Claims.ExpiresAt > 0 {
+		expiresAt = time.Unix(usr.Claims.ExpiresAt, 0)
+	}
+	validate := func() error {
+		return g.tokenValidator.Revalidate(usr)
+	}
+	sessionID, requestID := ar.SessionID, ar.ID
+	onClose := func(reason string) {
+		g.logger.Info(
+			"closing long-lived request",
+			zap.String("session_id", sessionID),
+			zap.String("request_id", requestID),
+			zap.String("kind", kind),
+			zap.String("reason", reason),
+		)
+		metrics.StreamClosures.Inc(g.config.Name, kind, reason)
+	}
+	ctx := stream.Watch(r.Context(), g.config.StreamConfig, expiresAt, validate, onClose)
+	*r = *r.WithContext(ctx)
+	g.logger.Debug(
+		"watching long-lived request",
+		zap.String("session_id", ar.SessionID),
+		zap.String("request_id", ar.ID),
+		zap.String("kind", kind),
+		zap.Time("expires_at", expiresAt),
+	)
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// The kinds of long-lived requests.
+const (
+	KindWebSocket = "websocket"
+	KindSSE       = "sse"
+)
+
+// The reasons for closing long-lived requests.
+const (
+	ReasonExpired = "expired"
+	ReasonInvalid = "invalid"
+)
+
+// Config holds the configuration for the authorization of long-lived
+// requests, i.e. WebSocket upgrades and Server-Sent Events. The request is
+// authorized at upgrade time, and its context is canceled when the token
+// expires or fails re-validation.
+type Config struct {
+	// RevalidationInterval is the interval in seconds at which the token of
+	// a long-lived request is validated again, e.g. to detect revoked opaque
+	// tokens. When zero, the token is validated only at upgrade time.
+	RevalidationInterval int `json:"revalidation_interval,omitempty" xml:"revalidation_interval,omitempty" yaml:"revalidation_interval,omitempty"`
+	// GracePeriod is the time in seconds a long-lived request stays open
+	// after its token expired or failed re-validation.
+	GracePeriod int `json:"grace_period,omitempty" xml:"grace_period,omitempty" yaml:"grace_period,omitempty"`
+	// ExpiryEnforcementDisabled keeps long-lived requests open past the
+	// expiry of their tokens.
+	ExpiryEnforcementDisabled bool `json:"expiry_enforcement_disabled,omitempty" xml:"expiry_enforcement_disabled,omitempty" yaml:"expiry_enforcement_disabled,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.RevalidationInterval < 0 {
+		return errors.ErrStreamConfigRevalidationInterval.WithArgs(cfg.RevalidationInterval)
+	}
+	if cfg.GracePeriod < 0 {
+		return errors.ErrStreamConfigGracePeriod.WithArgs(cfg.GracePeriod)
+	}
+	return nil
+}
+
+// GetKind returns the kind of the long-lived request, i.e. websocket or
+// sse, or an empty string for the other requests.
+func GetKind(r *http.Request) string {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") && headerContains(r, "Connection", "upgrade") {
+		return KindWebSocket
+	}
+	if headerContains(r, "Accept", "text/event-stream") {
+		return KindSSE
+	}
+	return ""
+}
+
+func headerContains(r *http.Request, k, v string) bool {
+	for _, values := range r.Header.Values(k) {
+		for _, s := range strings.Split(values, ",") {
+			s = strings.TrimSpace(s)
+			if i := strings.IndexByte(s, ';'); i >= 0 {
+				s = strings.TrimSpace(s[:i])
+			}
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Watch returns the context of a long-lived request. The context is
+// canceled after the grace period once the token expires or the validate
+// function returns an error. The onClose function is called with the reason
+// prior to the cancellation.
+func Watch(parent context.Context, cfg *Config, expiresAt time.Time, validate func() error, onClose func(reason string)) context.Context {
+	w := &watcher{
+		interval: time.Duration(cfg.RevalidationInterval) * time.Second,
+		grace:    time.Duration(cfg.GracePeriod) * time.Second,
+		validate: validate,
+		onClose:  onClose,
+	}
+	if !cfg.ExpiryEnforcementDisabled {
+		w.expiresAt = expiresAt
+	}
+	return w.watch(parent)
+}
+
+type watcher struct {
+	interval  time.Duration
+	grace     time.Duration
+	expiresAt time.Time
+	validate  func() error
+	onClose   func(reason string)
+}
+
+func (w *watcher) watch(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		defer cancel()
+
+		var expired <-chan time.Time
+		if !w.expiresAt.IsZero() {
+			timer := time.NewTimer(time.Until(w.expiresAt) + w.grace)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		var revalidate <-chan time.Time
+		if w.interval > 0 && w.validate != nil {
+			ticker := time.NewTicker(w.interval)
+			defer ticker.Stop()
+			revalidate = ticker.C
+		}
+
+		var invalidated <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-expired:
+				w.onClose(ReasonExpired)
+				return
+			case <-revalidate:
+				if invalidated != nil || w.validate() == nil {
+					continue
+				}
+				timer := time.NewTimer(w.grace)
+				defer timer.Stop()
+				invalidated = timer.C
+			case <-invalidated:
+				w.onClose(ReasonInvalid)
+				return
+			}
+		}
+	}()
+	return ctx
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				RevalidationInterval: 60,
+				GracePeriod:          5,
+			},
+		},
+		{
+			name: "negative revalidation interval",
+			config: &Config{
+				RevalidationInterval: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrStreamConfigRevalidationInterval.WithArgs(-1),
+		},
+		{
+			name: "negative grace period",
+			config: &Config{
+				GracePeriod: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrStreamConfigGracePeriod.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestGetKind(t *testing.T) {
+	testcases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "websocket upgrade",
+			headers: map[string]string{
+				"Upgrade":    "websocket",
+				"Connection": "keep-alive, Upgrade",
+			},
+			want: KindWebSocket,
+		},
+		{
+			name: "upgrade without connection header",
+			headers: map[string]string{
+				"Upgrade": "websocket",
+			},
+			want: "",
+		},
+		{
+			name: "server-sent events",
+			headers: map[string]string{
+				"Accept": "text/event-stream",
+			},
+			want: KindSSE,
+		},
+		{
+			name: "html page",
+			headers: map[string]string{
+				"Accept": "text/html,application/xhtml+xml;q=0.9",
+			},
+			want: "",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			tests.EvalObjectsWithLog(t, "kind", tc.want, GetKind(r), []string{fmt.Sprintf("test name: %s", tc.name)})
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	testcases := []struct {
+		name      string
+		interval  time.Duration
+		grace     time.Duration
+		expiresIn time.Duration
+		validate  func() error
+		// Expected results.
+		want   string
+		closed bool
+	}{
+		{
+			name:      "token expired",
+			expiresIn: 20 * time.Millisecond,
+			want:      ReasonExpired,
+			closed:    true,
+		},
+		{
+			name:      "token expired with grace period",
+			expiresIn: 10 * time.Millisecond,
+			grace:     20 * time.Millisecond,
+			want:      ReasonExpired,
+			closed:    true,
+		},
+		{
+			name:     "token revoked",
+			interval: 10 * time.Millisecond,
+			validate: func() error {
+				return errors.ErrCryptoKeyStoreParseTokenFailed
+			},
+			want:   ReasonInvalid,
+			closed: true,
+		},
+		{
+			name:     "token valid",
+			interval: 10 * time.Millisecond,
+			validate: func() error {
+				return nil
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			parent, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			reasons := make(chan string, 1)
+			w := &watcher{
+				interval: tc.interval,
+				grace:    tc.grace,
+				validate: tc.validate,
+				onClose: func(reason string) {
+					reasons <- reason
+				},
+			}
+			if tc.expiresIn > 0 {
+				w.expiresAt = time.Now().Add(tc.expiresIn)
+			}
+			start := time.Now()
+			ctx := w.watch(parent)
+
+			select {
+			case <-ctx.Done():
+				if !tc.closed {
+					t.Fatalf("unexpected close of the context")
+				}
+				if elapsed := time.Since(start); elapsed < tc.expiresIn+tc.grace {
+					t.Fatalf("context closed after %s, prior to grace period", elapsed)
+				}
+				tests.EvalObjectsWithLog(t, "reason", tc.want, <-reasons, msgs)
+			case <-time.After(100 * time.Millisecond):
+				if tc.closed {
+					t.Fatalf("expected close of the context")
+				}
+				cancel()
+				<-ctx.Done()
+			}
+		})
+	}
+}
@@ -87,6 +87,7 @@ func (v *TokenValidator) parseCustomBasicAuthHeader(ctx context.Context, r *http
 		}
 
 		if err := v.authProxy.BasicAuth(apr); err != nil {
+			ar.Response.RetryAfter = apr.Response.RetryAfter
 			return err
 		}
 
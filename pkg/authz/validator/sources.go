@@ -16,6 +16,8 @@ package validator
 
 import (
 	"context"
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	cookieutil "github.com/greenpau/go-authcrunch/pkg/authn/cookie"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
@@ -27,6 +29,7 @@ const (
 	tokenSourceHeader = "header"
 	tokenSourceCookie = "cookie"
 	tokenSourceQuery  = "query"
+	tokenSourceBearer = "bearer"
 )
 
 var (
@@ -49,6 +52,166 @@ func init() {
 	}
 }
 
+// TokenSourceConfig represents a source of the tokens in HTTP requests.
+// The sources are evaluated in the order of their configs, and the query
+// parameter source is used only when configured.
+type TokenSourceConfig struct {
+	// The type of the source, i.e. cookie, bearer, header, or query. The
+	// bearer source is the token in the Authorization header with the
+	// Bearer scheme.
+	Type string `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	// The names of the cookies, headers, e.g. X-API-Key, or query parameters
+	// holding the token. When empty, the names of the tokens of the keys
+	// apply, and the header source parses the Authorization header for
+	// name=token pairs.
+	Names []string `json:"names,omitempty" xml:"names,omitempty" yaml:"names,omitempty"`
+	// Disabled disables the source.
+	Disabled bool `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// Validate validates TokenSourceConfig.
+func (cfg *TokenSourceConfig) Validate() error {
+	switch cfg.Type {
+	case tokenSourceCookie, tokenSourceHeader, tokenSourceQuery:
+	case tokenSourceBearer:
+		if len(cfg.Names) > 0 {
+			return errors.ErrTokenSourceConfigNames.WithArgs(cfg.Type)
+		}
+	default:
+		return errors.ErrTokenSourceConfigType.WithArgs(cfg.Type)
+	}
+	for _, name := range cfg.Names {
+		if strings.TrimSpace(name) == "" {
+			return errors.ErrTokenSourceConfigEmptyName.WithArgs(cfg.Type)
+		}
+	}
+	return nil
+}
+
+// tokenSource is an enabled token source. When the names are nil, the
+// default names of the source type apply.
+type tokenSource struct {
+	kind  string
+	names map[string]interface{}
+}
+
+// SetSources sets the sources of the tokens and the order in which they
+// are evaluated. It overrides the source priority.
+func (v *TokenValidator) SetSources(cfgs []*TokenSourceConfig) error {
+	var sources []*tokenSource
+	var kinds []string
+	m := make(map[string]bool)
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if _, exists := m[cfg.Type]; exists {
+			return errors.ErrTokenSourceConfigDuplicate.WithArgs(cfg.Type)
+		}
+		m[cfg.Type] = true
+		if cfg.Disabled {
+			continue
+		}
+		src := &tokenSource{kind: cfg.Type}
+		if len(cfg.Names) > 0 {
+			src.names = make(map[string]interface{})
+			for _, name := range cfg.Names {
+				src.names[strings.TrimSpace(name)] = true
+			}
+		}
+		sources = append(sources, src)
+		kinds = append(kinds, cfg.Type)
+	}
+	if len(sources) == 0 {
+		return errors.ErrTokenSourceConfigNotEnabled
+	}
+	v.sources = sources
+	v.tokenSources = kinds
+	return nil
+}
+
+// parseSources searches for the token in the configured sources.
+func (v *TokenValidator) parseSources(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest) {
+	for _, src := range v.sources {
+		switch src.kind {
+		case tokenSourceCookie:
+			if src.names == nil {
+				v.parseCookies(ctx, r, ar)
+			} else {
+				v.parseCookieNames(r, ar, src.names)
+			}
+		case tokenSourceBearer:
+			v.parseBearerHeader(r, ar)
+		case tokenSourceHeader:
+			if src.names == nil {
+				v.parseAuthHeader(ctx, r, ar)
+			} else {
+				v.parseHeaders(r, ar, src.names)
+			}
+		case tokenSourceQuery:
+			if src.names == nil {
+				v.parseQueryParams(ctx, r, ar)
+			} else {
+				v.parseQueryParamNames(r, ar, src.names)
+			}
+		}
+		if ar.Token.Found {
+			return
+		}
+	}
+}
+
+// isSourceName returns true when the name is the custom name of a source
+// rather than the name of the tokens of the keys.
+func (v *TokenValidator) isSourceName(name string) bool {
+	for _, src := range v.sources {
+		if _, exists := src.names[name]; !exists {
+			continue
+		}
+		_, isCookie := v.authCookies[name]
+		_, isHeader := v.authHeaders[name]
+		_, isQueryParam := v.authQueryParams[name]
+		return !isCookie && !isHeader && !isQueryParam
+	}
+	return false
+}
+
+// parseBearerHeader searches for the token in HTTP Authorization header
+// with the Bearer scheme.
+func (v *TokenValidator) parseBearerHeader(r *http.Request, ar *requests.AuthorizationRequest) {
+	kv := strings.SplitN(strings.TrimSpace(r.Header.Get("Authorization")), " ", 2)
+	if len(kv) != 2 || !strings.EqualFold(kv[0], "Bearer") {
+		return
+	}
+	token := strings.TrimSpace(kv[1])
+	if token == "" {
+		return
+	}
+	ar.Token.Found = true
+	ar.Token.Name = "bearer"
+	ar.Token.Payload = token
+	ar.Token.Source = tokenSourceBearer
+}
+
+// parseHeaders searches for the token in the named HTTP headers, e.g.
+// X-API-Key. The Bearer scheme prefix, if any, is removed.
+func (v *TokenValidator) parseHeaders(r *http.Request, ar *requests.AuthorizationRequest, names map[string]interface{}) {
+	for k := range names {
+		value := strings.TrimSpace(r.Header.Get(k))
+		if len(value) > 7 && strings.EqualFold(value[:7], "Bearer ") {
+			value = strings.TrimSpace(value[7:])
+		}
+		if len(value) < 32 {
+			continue
+		}
+		ar.Token.Found = true
+		ar.Token.Name = k
+		ar.Token.Payload = value
+		ar.Token.Source = tokenSourceHeader
+		return
+	}
+}
+
 func (v *TokenValidator) clearAuthSources() {
 	v.clearAuthHeaders()
 	v.clearAuthCookies()
@@ -73,11 +236,15 @@ func (v *TokenValidator) clearAuthCookies() {
 // parseQueryParams authorizes HTTP requests based on the presence and the
 // content of the tokens in HTTP query parameters.
 func (v *TokenValidator) parseQueryParams(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest) {
+	v.parseQueryParamNames(r, ar, v.authQueryParams)
+}
+
+func (v *TokenValidator) parseQueryParamNames(r *http.Request, ar *requests.AuthorizationRequest, names map[string]interface{}) {
 	values := r.URL.Query()
 	if len(values) == 0 {
 		return
 	}
-	for k := range v.authQueryParams {
+	for k := range names {
 		value := values.Get(k)
 		if len(value) > 32 {
 			ar.Token.Found = true
@@ -129,16 +296,23 @@ func (v *TokenValidator) parseAuthHeader(ctx context.Context, r *http.Request, a
 }
 
 // AuthorizeCookies authorizes HTTP requests based on the presence and the
-// content of the tokens in HTTP cookies.
+// content of the tokens in HTTP cookies. The tokens split into chunks by
+// the portal are reassembled.
 func (v *TokenValidator) parseCookies(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest) {
-	for _, cookie := range r.Cookies() {
-		if _, exists := v.authCookies[cookie.Name]; !exists {
+	v.parseCookieNames(r, ar, v.authCookies)
+}
+
+func (v *TokenValidator) parseCookieNames(r *http.Request, ar *requests.AuthorizationRequest, names map[string]interface{}) {
+	cookies := r.Cookies()
+	for _, cookie := range cookies {
+		if _, exists := names[cookie.Name]; !exists {
 			continue
 		}
-		if len(cookie.Value) < 32 {
+		value, _ := cookieutil.JoinChunks(cookies, cookie.Name)
+		if len(value) < 32 {
 			continue
 		}
-		parts := strings.Split(strings.TrimSpace(cookie.Value), " ")
+		parts := strings.Split(strings.TrimSpace(value), " ")
 		ar.Token.Found = true
 		ar.Token.Name = cookie.Name
 		ar.Token.Payload = strings.TrimSpace(parts[0])
@@ -148,22 +322,50 @@ func (v *TokenValidator) parseCookies(ctx context.Context, r *http.Request, ar *
 	return
 }
 
+// parseToken verifies the token with the keys of the trusted issuer matching
+// the iss claim of the token, if any, or with the keys of the validator.
+func (v *TokenValidator) parseToken(ar *requests.AuthorizationRequest) (*user.User, error) {
+	if v.isSourceName(ar.Token.Name) {
+		// The tokens found by the custom names of the sources, e.g.
+		// X-API-Key, are verified with any of the keys, as the bearer
+		// tokens are.
+		name := ar.Token.Name
+		ar.Token.Name = "bearer"
+		defer func() { ar.Token.Name = name }()
+	}
+	if v.issuers != nil {
+		claims := jwtlib.MapClaims{}
+		if _, _, err := jwtlib.NewParser().ParseUnverified(ar.Token.Payload, claims); err == nil {
+			if iss, ok := claims["iss"].(string); ok {
+				if i, exists := v.issuers[iss]; exists {
+					return i.ParseToken(ar)
+				}
+			}
+		}
+	}
+	return v.keystore.ParseToken(ar)
+}
+
 // Authorize authorizes HTTP requests based on the presence and the content of
 // the tokens in the requests.
 func (v *TokenValidator) Authorize(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest) (usr *user.User, err error) {
 	// var token, tokenName, tokenSource string
 	// var found bool
-	for _, sourceName := range v.tokenSources {
-		switch sourceName {
-		case tokenSourceHeader:
-			v.parseAuthHeader(ctx, r, ar)
-		case tokenSourceCookie:
-			v.parseCookies(ctx, r, ar)
-		case tokenSourceQuery:
-			v.parseQueryParams(ctx, r, ar)
-		}
-		if ar.Token.Found {
-			break
+	if v.sources != nil {
+		v.parseSources(ctx, r, ar)
+	} else {
+		for _, sourceName := range v.tokenSources {
+			switch sourceName {
+			case tokenSourceHeader:
+				v.parseAuthHeader(ctx, r, ar)
+			case tokenSourceCookie:
+				v.parseCookies(ctx, r, ar)
+			case tokenSourceQuery:
+				v.parseQueryParams(ctx, r, ar)
+			}
+			if ar.Token.Found {
+				break
+			}
 		}
 	}
 
@@ -182,11 +384,14 @@ func (v *TokenValidator) Authorize(ctx context.Context, r *http.Request, ar *req
 	usr = v.cache.Get(ar.Token.Payload)
 	if usr == nil {
 		// The user is not in the cache.
-		usr, err = v.keystore.ParseToken(ar)
+		usr, err = v.parseToken(ar)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if usr.IsRelyingPartyToken() {
+		return nil, errors.ErrRelyingPartyToken
+	}
 
 	if err := v.guardian.authorize(ctx, r, usr); err != nil {
 		ar.Response.User = make(map[string]interface{})
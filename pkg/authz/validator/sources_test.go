@@ -24,6 +24,7 @@ import (
 
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/internal/testutils"
+	cookieutil "github.com/greenpau/go-authcrunch/pkg/authn/cookie"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
@@ -38,6 +39,8 @@ func TestAuthorizationSources(t *testing.T) {
 		enableCookieViolations       bool
 		enableHeaderViolations       bool
 		enableBearerHeaderViolations bool
+		// The size of the chunks of the cookies.
+		cookieChunkSize int
 		// The name of the token.
 		entries   []*testutils.InjectedTestToken
 		want      map[string]interface{}
@@ -66,6 +69,18 @@ func TestAuthorizationSources(t *testing.T) {
 			},
 			shouldErr: false,
 		},
+		{
+			name:            "default token sources and names with chunked cookie claim injection",
+			cookieChunkSize: 64,
+			entries: []*testutils.InjectedTestToken{
+				testutils.NewInjectedTestToken("access_token", tokenSourceCookie, `"name": "foo",`),
+			},
+			want: map[string]interface{}{
+				"token_name": "access_token",
+				"claim_name": "foo",
+			},
+			shouldErr: false,
+		},
 		{
 			name: "default token sources and names with query parameter claim injection",
 			entries: []*testutils.InjectedTestToken{
@@ -261,6 +276,18 @@ func TestAuthorizationSources(t *testing.T) {
 				}
 				switch entry.Location {
 				case tokenSourceCookie:
+					if tc.cookieChunkSize > 0 {
+						token := entry.User.Token
+						for i := 0; token != ""; i++ {
+							n := tc.cookieChunkSize
+							if n > len(token) {
+								n = len(token)
+							}
+							req.AddCookie(testutils.GetCookie(cookieutil.GetChunkName(tokenName, i), token[:n], 10))
+							token = token[n:]
+						}
+						continue
+					}
 					req.AddCookie(testutils.GetCookie(tokenName, entry.User.Token, 10))
 				case tokenSourceHeader:
 					req.Header.Set("Authorization", fmt.Sprintf("%s=%s", tokenName, entry.User.Token))
@@ -281,3 +308,177 @@ func TestAuthorizationSources(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenSources(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		sources []*TokenSourceConfig
+		inject  func(r *http.Request, token string)
+		want    map[string]interface{}
+		// Expected results.
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "bearer token source",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie"},
+				{Type: "bearer"},
+			},
+			inject: func(r *http.Request, token string) {
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			want: map[string]interface{}{
+				"token_name":   "bearer",
+				"token_source": "bearer",
+			},
+		},
+		{
+			name: "custom header token source",
+			sources: []*TokenSourceConfig{
+				{Type: "header", Names: []string{"X-API-Key"}},
+			},
+			inject: func(r *http.Request, token string) {
+				r.Header.Set("X-API-Key", token)
+			},
+			want: map[string]interface{}{
+				"token_name":   "X-API-Key",
+				"token_source": "header",
+			},
+		},
+		{
+			name: "custom cookie token source",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie", Names: []string{"session_token"}},
+			},
+			inject: func(r *http.Request, token string) {
+				r.AddCookie(testutils.GetCookie("session_token", token, 10))
+			},
+			want: map[string]interface{}{
+				"token_name":   "session_token",
+				"token_source": "cookie",
+			},
+		},
+		{
+			name: "query token source opt in",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie"},
+				{Type: "query"},
+			},
+			inject: func(r *http.Request, token string) {
+				r.URL.RawQuery = "access_token=" + token
+			},
+			want: map[string]interface{}{
+				"token_name":   "access_token",
+				"token_source": "query",
+			},
+		},
+		{
+			name: "query token source not configured",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie"},
+				{Type: "bearer"},
+			},
+			inject: func(r *http.Request, token string) {
+				r.URL.RawQuery = "access_token=" + token
+			},
+			shouldErr: true,
+			err:       errors.ErrNoTokenFound,
+		},
+		{
+			name: "disabled bearer token source",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie"},
+				{Type: "bearer", Disabled: true},
+			},
+			inject: func(r *http.Request, token string) {
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			shouldErr: true,
+			err:       errors.ErrNoTokenFound,
+		},
+		{
+			name: "unsupported token source type",
+			sources: []*TokenSourceConfig{
+				{Type: "body"},
+			},
+			shouldErr: true,
+			err:       errors.ErrTokenSourceConfigType.WithArgs("body"),
+		},
+		{
+			name: "duplicate token source type",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie"},
+				{Type: "cookie", Names: []string{"foo"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrTokenSourceConfigDuplicate.WithArgs("cookie"),
+		},
+		{
+			name: "bearer token source with names",
+			sources: []*TokenSourceConfig{
+				{Type: "bearer", Names: []string{"foo"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrTokenSourceConfigNames.WithArgs("bearer"),
+		},
+		{
+			name: "token source with empty name",
+			sources: []*TokenSourceConfig{
+				{Type: "header", Names: []string{" "}},
+			},
+			shouldErr: true,
+			err:       errors.ErrTokenSourceConfigEmptyName.WithArgs("header"),
+		},
+		{
+			name: "all token sources disabled",
+			sources: []*TokenSourceConfig{
+				{Type: "cookie", Disabled: true},
+			},
+			shouldErr: true,
+			err:       errors.ErrTokenSourceConfigNotEnabled,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			ks := testutils.NewTestCryptoKeyStore()
+			keys := ks.GetKeys()
+			validator := NewTokenValidator()
+			if err := validator.Configure(ctx, keys, testutils.NewTestGuestAccessList(), options.NewTokenValidatorOptions()); err != nil {
+				t.Fatal(err)
+			}
+			err := validator.SetSources(tc.sources)
+			if tc.inject == nil {
+				tests.EvalErrWithLog(t, err, "token sources", tc.shouldErr, tc.err, msgs)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entry := testutils.NewInjectedTestToken("access_token", "", `"name": "foo",`)
+			if err := keys[0].SignToken("HS512", entry.User); err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest("GET", "/protected/path", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.inject(req, entry.User.Token)
+
+			ar := requests.NewAuthorizationRequest()
+			usr, err := validator.Authorize(ctx, req, ar)
+			if tests.EvalErrWithLog(t, err, "token sources", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"token_name":   usr.TokenName,
+				"token_source": usr.TokenSource,
+			}
+			tests.EvalObjectsWithLog(t, "token sources", tc.want, got, msgs)
+		})
+	}
+}
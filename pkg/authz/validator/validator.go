@@ -16,15 +16,19 @@ package validator
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authproxy"
 	"github.com/greenpau/go-authcrunch/pkg/authz/cache"
+	"github.com/greenpau/go-authcrunch/pkg/authz/issuer"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 )
@@ -75,12 +79,15 @@ type TokenValidator struct {
 	accessList        *acl.AccessList
 	guardian          guardian
 	tokenSources      []string
+	sources           []*tokenSource
 	opts              *options.TokenValidatorOptions
 	basicAuthEnabled  bool
 	apiKeyAuthEnabled bool
 	customAuthEnabled bool
 	authProxyConfig   *authproxy.Config
 	authProxy         authproxy.Authenticator
+	// The trusted issuers, keyed by the value of the iss claim.
+	issuers map[string]*issuer.Issuer
 }
 
 // NewTokenValidator returns an instance of TokenValidator
@@ -103,8 +110,22 @@ func NewTokenValidator() *TokenValidator {
 	return v
 }
 
-// GetAuthCookies returns auth cookies registered with TokenValidator.
+// GetAuthCookies returns auth cookies registered with TokenValidator,
+// including the names of the cookies of the configured cookie source.
 func (v *TokenValidator) GetAuthCookies() map[string]interface{} {
+	for _, src := range v.sources {
+		if src.kind != tokenSourceCookie || src.names == nil {
+			continue
+		}
+		m := make(map[string]interface{})
+		for k, val := range v.authCookies {
+			m[k] = val
+		}
+		for k, val := range src.names {
+			m[k] = val
+		}
+		return m
+	}
 	return v.authCookies
 }
 
@@ -148,6 +169,7 @@ func (v *TokenValidator) SetSourcePriority(arr []string) error {
 		m[s] = true
 	}
 	v.tokenSources = arr
+	v.sources = nil
 	return nil
 }
 
@@ -156,6 +178,49 @@ func (v *TokenValidator) GetSourcePriority() []string {
 	return v.tokenSources
 }
 
+// withRequestLocation returns the access list input data with the country
+// and the city of the source address of the request, if known.
+func withRequestLocation(r *http.Request, data map[string]interface{}) map[string]interface{} {
+	loc := geo.LocateAddress(addrutil.GetSourceAddress(r))
+	if loc == nil {
+		return data
+	}
+	kv := make(map[string]interface{}, len(data)+2)
+	for k, v := range data {
+		kv[k] = v
+	}
+	kv["country"] = loc.Country
+	if loc.City != "" {
+		kv["city"] = loc.City
+	}
+	return kv
+}
+
+// guardianWithAudience requires the host of the request to be in the
+// audience of the token, in addition to the checks of the wrapped guardian.
+type guardianWithAudience struct {
+	guardian guardian
+}
+
+func (g *guardianWithAudience) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
+	if err := g.guardian.authorize(ctx, r, usr); err != nil {
+		return err
+	}
+	if len(usr.Claims.Audience) == 0 {
+		return errors.ErrAudienceNotFound
+	}
+	host := strings.ToLower(addrutil.GetSourceHost(r))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, aud := range usr.Claims.Audience {
+		if strings.ToLower(aud) == host {
+			return nil
+		}
+	}
+	return errors.ErrAudienceMismatch.WithArgs(usr.Claims.Audience, host)
+}
+
 func (g *guardianBase) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
 	// Note: the cache was removed because authorize uses the same
 	// authorization endpoint. Previously, the endpoint was
@@ -163,14 +228,14 @@ func (g *guardianBase) authorize(ctx context.Context, r *http.Request, usr *user
 	// if usr.Cached {
 	//	return nil
 	// }
-	if userAllowed := g.accessList.Allow(ctx, usr.GetData()); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, usr.GetData())); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	return nil
 }
 
 func (g *guardianWithSrcAddr) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
-	if userAllowed := g.accessList.Allow(ctx, usr.GetData()); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, usr.GetData())); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.Address == "" {
@@ -184,7 +249,7 @@ func (g *guardianWithSrcAddr) authorize(ctx context.Context, r *http.Request, us
 }
 
 func (g *guardianWithPathClaim) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
-	if userAllowed := g.accessList.Allow(ctx, usr.GetData()); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, usr.GetData())); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.AccessList == nil {
@@ -199,7 +264,7 @@ func (g *guardianWithPathClaim) authorize(ctx context.Context, r *http.Request,
 }
 
 func (g *guardianWithSrcAddrPathClaim) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
-	if userAllowed := g.accessList.Allow(ctx, usr.GetData()); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, usr.GetData())); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.Address == "" {
@@ -227,7 +292,7 @@ func (g *guardianWithMethodPath) authorize(ctx context.Context, r *http.Request,
 	}
 	kv["method"] = r.Method
 	kv["path"] = r.URL.Path
-	if userAllowed := g.accessList.Allow(ctx, kv); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, kv)); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	return nil
@@ -240,7 +305,7 @@ func (g *guardianWithMethodPathSrcAddr) authorize(ctx context.Context, r *http.R
 	}
 	kv["method"] = r.Method
 	kv["path"] = r.URL.Path
-	if userAllowed := g.accessList.Allow(ctx, kv); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, kv)); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.Address == "" {
@@ -260,7 +325,7 @@ func (g *guardianWithMethodPathPathClaim) authorize(ctx context.Context, r *http
 	}
 	kv["method"] = r.Method
 	kv["path"] = r.URL.Path
-	if userAllowed := g.accessList.Allow(ctx, kv); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, kv)); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.AccessList == nil {
@@ -281,7 +346,7 @@ func (g *guardianWithMethodPathSrcAddrPathClaim) authorize(ctx context.Context,
 	}
 	kv["method"] = r.Method
 	kv["path"] = r.URL.Path
-	if userAllowed := g.accessList.Allow(ctx, kv); !userAllowed {
+	if userAllowed := g.accessList.Allow(ctx, withRequestLocation(r, kv)); !userAllowed {
 		return errors.ErrAccessNotAllowed
 	}
 	if usr.Claims.Address == "" {
@@ -343,6 +408,98 @@ func (v *TokenValidator) Configure(ctx context.Context, keys []*kms.CryptoKey, a
 		g := &guardianBase{accessList: accessList}
 		v.guardian = g
 	}
+	if opts.ValidateAudience {
+		v.guardian = &guardianWithAudience{guardian: v.guardian}
+	}
+	return nil
+}
+
+// AuthorizeUser evaluates the access of an established identity to an HTTP
+// request, i.e. without the extraction and the verification of a token.
+func (v *TokenValidator) AuthorizeUser(ctx context.Context, r *http.Request, usr *user.User) error {
+	if v.guardian == nil {
+		return errors.ErrTokenValidatorOptionsNotFound
+	}
+	return v.guardian.authorize(ctx, r, usr)
+}
+
+// Explain returns the explanation of the decision made by the access list
+// for an HTTP request, e.g. the rule denying the request.
+func (v *TokenValidator) Explain(ctx context.Context, r *http.Request, usr *user.User) *acl.Decision {
+	if v.accessList == nil || usr == nil {
+		return nil
+	}
+	kv := usr.GetData()
+	if v.opts != nil && v.opts.ValidateMethodPath {
+		kv = make(map[string]interface{})
+		for k, v := range usr.GetData() {
+			kv[k] = v
+		}
+		kv["method"] = r.Method
+		kv["path"] = r.URL.Path
+	}
+	return v.accessList.Evaluate(ctx, withRequestLocation(r, kv))
+}
+
+// ShadowReportFunc receives the decisions of the active and the candidate
+// access lists when the decisions diverge.
+type ShadowReportFunc func(r *http.Request, usr *user.User, allowed, candidateAllowed bool)
+
+// guardianWithShadow evaluates a candidate access list alongside the access
+// list of the wrapped guardian. The decisions of the candidate access list
+// are reported, but never enforced.
+type guardianWithShadow struct {
+	guardian   guardian
+	accessList *acl.AccessList
+	methodPath bool
+	report     ShadowReportFunc
+}
+
+func (g *guardianWithShadow) authorize(ctx context.Context, r *http.Request, usr *user.User) error {
+	err := g.guardian.authorize(ctx, r, usr)
+	var allowed bool
+	switch err {
+	case nil:
+		allowed = true
+	case errors.ErrAccessNotAllowed:
+	default:
+		// The request was denied by other checks, e.g. source address.
+		return err
+	}
+	kv := usr.GetData()
+	if g.methodPath {
+		kv = make(map[string]interface{})
+		for k, v := range usr.GetData() {
+			kv[k] = v
+		}
+		kv["method"] = r.Method
+		kv["path"] = r.URL.Path
+	}
+	if candidateAllowed := g.accessList.Allow(ctx, withRequestLocation(r, kv)); candidateAllowed != allowed {
+		g.report(r, usr, allowed, candidateAllowed)
+	}
+	return err
+}
+
+// SetShadowAccessList adds the candidate access list evaluated alongside the
+// active one, i.e. dry run. The divergent decisions are passed to the report
+// function. Must be called after Configure.
+func (v *TokenValidator) SetShadowAccessList(ctx context.Context, accessList *acl.AccessList, report ShadowReportFunc) error {
+	if accessList == nil {
+		return errors.ErrNoAccessList
+	}
+	if len(accessList.GetRules()) == 0 {
+		return errors.ErrAccessListNoRules
+	}
+	if v.guardian == nil || v.opts == nil {
+		return errors.ErrTokenValidatorOptionsNotFound
+	}
+	v.guardian = &guardianWithShadow{
+		guardian:   v.guardian,
+		accessList: accessList,
+		methodPath: v.opts.ValidateMethodPath,
+		report:     report,
+	}
 	return nil
 }
 
@@ -392,11 +549,62 @@ func (v *TokenValidator) addKeys(ctx context.Context, keys []*kms.CryptoKey) err
 	return nil
 }
 
+// AddIssuers adds trusted issuers to TokenValidator. The tokens having the
+// iss claim of a trusted issuer are verified with the keys of the issuer.
+func (v *TokenValidator) AddIssuers(ctx context.Context, cfgs []*issuer.Config) error {
+	names := make(map[string]bool)
+	for _, i := range v.issuers {
+		names[i.GetName()] = true
+	}
+	for _, cfg := range cfgs {
+		i, err := issuer.NewIssuer(cfg)
+		if err != nil {
+			return err
+		}
+		if _, exists := names[i.GetName()]; exists {
+			return errors.ErrValidatorDuplicateIssuerName.WithArgs(i.GetName())
+		}
+		if v.issuers == nil {
+			v.issuers = make(map[string]*issuer.Issuer)
+		}
+		if _, exists := v.issuers[i.GetIssuer()]; exists {
+			return errors.ErrValidatorDuplicateIssuer.WithArgs(i.GetIssuer())
+		}
+		names[i.GetName()] = true
+		v.issuers[i.GetIssuer()] = i
+		for _, name := range i.GetTokenNames() {
+			v.authHeaders[name] = true
+			v.authCookies[name] = true
+			v.authQueryParams[name] = true
+		}
+	}
+	return nil
+}
+
 // CacheUser adds a user to token validator cache.
 func (v *TokenValidator) CacheUser(usr *user.User) error {
+	// The opaque tokens are looked up in the token store, and the other
+	// tokens in the revocation list, on every request, so that the revoked
+	// tokens are rejected immediately.
+	if kms.IsOpaqueToken(usr.Token) || v.keystore.HasRevocationList() {
+		return nil
+	}
 	return v.cache.Add(usr)
 }
 
+// Revalidate verifies the token of a previously authorized user again,
+// bypassing the cache, e.g. to detect the expired or revoked tokens of
+// long-lived requests.
+func (v *TokenValidator) Revalidate(usr *user.User) error {
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Found = true
+	ar.Token.Name = usr.TokenName
+	ar.Token.Payload = usr.Token
+	ar.Token.Source = usr.TokenSource
+	_, err := v.parseToken(ar)
+	return err
+}
+
 // RegisterAuthProxy registers authproxy.Authenticator  with TokenValidator.
 func (v *TokenValidator) RegisterAuthProxy(cfg *authproxy.Config, authenticators []authproxy.Authenticator) error {
 	if cfg == nil {
@@ -250,6 +250,17 @@ var (
         "addr": "10.10.10.10"
     }`
 
+	relyingParty = `{
+        "exp": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute).Unix()) + `,
+        "iat": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute*-1).Unix()) + `,
+        "nbf": ` + fmt.Sprintf("%d", time.Date(2015, 10, 10, 12, 0, 0, 0, time.UTC).Unix()) + `,
+        "aud": "grafana",
+        "sub": "smithj@outlook.com",
+        "roles": ["viewer"],
+        "scopes": ["openid", "groups"],
+        "token_use": "relying_party"
+    }`
+
 	editor2 = `{
         "exp": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute).Unix()) + `,
         "iat": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute*-1).Unix()) + `,
@@ -305,6 +316,18 @@ var (
         "roles": ["viewer"],
         "addr": "2001:DB8::21f:5bff:febf:ce22:8a2e"
     }`
+
+	viewer6 = `{
+        "exp": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute).Unix()) + `,
+        "iat": ` + fmt.Sprintf("%d", time.Now().Add(10*time.Minute*-1).Unix()) + `,
+        "nbf": ` + fmt.Sprintf("%d", time.Date(2015, 10, 10, 12, 0, 0, 0, time.UTC).Unix()) + `,
+        "name":   "Smith, John",
+        "email":  "smithj@outlook.com",
+        "origin": "localhost",
+        "sub":    "smithj@outlook.com",
+        "roles": ["viewer"],
+        "aud": ["app1.example.com", "app2.example.com"]
+    }`
 )
 
 func TestAuthorize(t *testing.T) {
@@ -321,6 +344,8 @@ func TestAuthorize(t *testing.T) {
 		validateAccessListPathClaim bool
 		validateSourceAddress       bool
 		validateMethodPath          bool
+		validateAudience            bool
+		host                        string
 		optionsDisabled             bool
 		want                        map[string]interface{}
 		shouldErr                   bool
@@ -951,6 +976,47 @@ func TestAuthorize(t *testing.T) {
 			validateSourceAddress: true,
 			sourceAddress:         "[2001:DB8::21f:5bff:febf:ce22:8a2e]:80",
 		},
+		// Audience restrictions of the tokens shared by the subdomains.
+		{
+			name:             "token audience includes request host",
+			claims:           viewer6,
+			config:           defaultRolesAllowACL,
+			method:           "GET",
+			path:             "/app/page3/allowed",
+			validateAudience: true,
+			host:             "App2.example.com:8443",
+		},
+		{
+			name:             "token audience does not include request host",
+			claims:           viewer6,
+			config:           defaultRolesAllowACL,
+			method:           "GET",
+			path:             "/app/page3/allowed",
+			validateAudience: true,
+			host:             "app3.example.com",
+			shouldErr:        true,
+			err:              errors.ErrAudienceMismatch.WithArgs([]string{"app1.example.com", "app2.example.com"}, "app3.example.com"),
+		},
+		{
+			name:             "token without audience",
+			claims:           viewer2,
+			config:           defaultRolesAllowACL,
+			method:           "GET",
+			path:             "/app/page3/allowed",
+			validateAudience: true,
+			host:             "app1.example.com",
+			shouldErr:        true,
+			err:              errors.ErrAudienceNotFound,
+		},
+		{
+			name:      "access token issued to relying party",
+			claims:    relyingParty,
+			config:    defaultRolesAllowACL,
+			method:    "GET",
+			path:      "/app/page3/allowed",
+			shouldErr: true,
+			err:       errors.ErrRelyingPartyToken,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -984,6 +1050,9 @@ func TestAuthorize(t *testing.T) {
 				if tc.validateMethodPath {
 					opts.ValidateMethodPath = true
 				}
+				if tc.validateAudience {
+					opts.ValidateAudience = true
+				}
 			}
 
 			if len(tc.config) > 0 {
@@ -1089,6 +1158,10 @@ func TestAuthorize(t *testing.T) {
 				req.Header.Set("X-Real-Ip", tc.sourceAddress)
 			}
 
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+
 			w := httptest.NewRecorder()
 			handler(w, req)
 			w.Result()
@@ -1303,3 +1376,86 @@ func TestSetSourcePriority(t *testing.T) {
 		})
 	}
 }
+
+func TestShadowAccessList(t *testing.T) {
+	ctx := context.Background()
+	logger := logutil.NewLogger()
+	newAccessList := func(rules []*acl.RuleConfiguration) *acl.AccessList {
+		accessList := acl.NewAccessList()
+		accessList.SetLogger(logger)
+		if err := accessList.AddRules(ctx, rules); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return accessList
+	}
+
+	var testcases = []struct {
+		name   string
+		scopes []string
+		method string
+		want   error
+		report map[string]interface{}
+	}{
+		{
+			name:   "active and candidate access lists allow",
+			scopes: []string{"read:books"},
+			method: "GET",
+		},
+		{
+			name:   "candidate access list denies",
+			scopes: []string{"read:books"},
+			method: "POST",
+			report: map[string]interface{}{
+				"allowed":           true,
+				"candidate_allowed": false,
+			},
+		},
+		{
+			name:   "candidate access list allows",
+			scopes: []string{"write:books"},
+			method: "GET",
+			want:   errors.ErrAccessNotAllowed,
+			report: map[string]interface{}{
+				"allowed":           false,
+				"candidate_allowed": true,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewTokenValidator()
+			opts := options.NewTokenValidatorOptions()
+			opts.ValidateMethodPath = true
+			keys := testutils.NewTestCryptoKeyStore().GetKeys()
+			if err := v.Configure(ctx, keys, newAccessList(defaultDenyACL), opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got map[string]interface{}
+			err := v.SetShadowAccessList(ctx, newAccessList([]*acl.RuleConfiguration{
+				{
+					Conditions: []string{"exact match method GET"},
+					Action:     `allow`,
+				},
+			}), func(r *http.Request, usr *user.User, allowed, candidateAllowed bool) {
+				got = map[string]interface{}{
+					"allowed":           allowed,
+					"candidate_allowed": candidateAllowed,
+				}
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			usr, err := user.NewUser(map[string]interface{}{
+				"sub":   "jsmith",
+				"scope": tc.scopes,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := httptest.NewRequest(tc.method, "/books", nil)
+			err = v.guardian.authorize(ctx, r, usr)
+			tests.EvalErrWithLog(t, err, "authorize", tc.want != nil, tc.want, nil)
+			tests.EvalObjectsWithLog(t, "report", tc.report, got, nil)
+		})
+	}
+}
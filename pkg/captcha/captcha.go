@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package captcha
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultDifficulty = 16
+	maxDifficulty     = 32
+	requestTimeout    = 10 * time.Second
+)
+
+var (
+	verifyURLs = map[string]string{
+		"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+		"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+		"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	}
+	responseFields = map[string]string{
+		"hcaptcha":  "h-captcha-response",
+		"recaptcha": "g-recaptcha-response",
+		"turnstile": "cf-turnstile-response",
+		"pow":       "pow-response",
+	}
+)
+
+// Config holds the configuration of CAPTCHA Verifier.
+type Config struct {
+	// Provider is either hcaptcha, recaptcha, turnstile, or pow. The pow
+	// provider is a proof-of-work challenge solved by the browser, and it
+	// does not depend on third parties.
+	Provider string `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	// SiteKey is the public key of the site rendering the widget.
+	SiteKey string `json:"site_key,omitempty" xml:"site_key,omitempty" yaml:"site_key,omitempty"`
+	// Secret is the key for the server-side verification. It may be a Vault
+	// reference.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	// VerifyURL overrides the server-side verification endpoint of the
+	// provider.
+	VerifyURL string `json:"verify_url,omitempty" xml:"verify_url,omitempty" yaml:"verify_url,omitempty"`
+	// MinScore is the lowest acceptable reCAPTCHA v3 score.
+	MinScore float64 `json:"min_score,omitempty" xml:"min_score,omitempty" yaml:"min_score,omitempty"`
+	// Difficulty is the number of leading zero bits required in the
+	// proof-of-work solution. Defaults to 16.
+	Difficulty int `json:"difficulty,omitempty" xml:"difficulty,omitempty" yaml:"difficulty,omitempty"`
+}
+
+// Verifier verifies the CAPTCHA responses submitted with forms.
+type Verifier struct {
+	config     *Config
+	verifyURL  string
+	difficulty int
+	// The key signing proof-of-work puzzles.
+	puzzleKey []byte
+	mu        sync.Mutex
+	// The proof-of-work puzzles solved and their expiry.
+	solved map[string]time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	switch cfg.Provider {
+	case "hcaptcha", "recaptcha", "turnstile":
+		if cfg.SiteKey == "" {
+			return errors.ErrCaptchaConfigSiteKeyEmpty.WithArgs(cfg.Provider)
+		}
+		if cfg.Secret == "" {
+			return errors.ErrCaptchaConfigSecretEmpty.WithArgs(cfg.Provider)
+		}
+		if cfg.MinScore < 0 || cfg.MinScore > 1 {
+			return errors.ErrCaptchaConfigMinScore.WithArgs(cfg.MinScore)
+		}
+	case "pow":
+		if cfg.Difficulty < 0 || cfg.Difficulty > maxDifficulty {
+			return errors.ErrCaptchaConfigDifficulty.WithArgs(maxDifficulty, cfg.Difficulty)
+		}
+	default:
+		return errors.ErrCaptchaConfigProviderInvalid.WithArgs(cfg.Provider)
+	}
+	return nil
+}
+
+// NewVerifier returns an instance of Verifier.
+func NewVerifier(cfg *Config) (*Verifier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	v := &Verifier{
+		config:     cfg,
+		verifyURL:  verifyURLs[cfg.Provider],
+		difficulty: defaultDifficulty,
+		solved:     make(map[string]time.Time),
+	}
+	if cfg.VerifyURL != "" {
+		v.verifyURL = cfg.VerifyURL
+	}
+	if cfg.Difficulty > 0 {
+		v.difficulty = cfg.Difficulty
+	}
+	if cfg.Provider == "pow" {
+		v.puzzleKey = make([]byte, 32)
+		if _, err := rand.Read(v.puzzleKey); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// GetProvider returns the CAPTCHA provider.
+func (v *Verifier) GetProvider() string {
+	return v.config.Provider
+}
+
+// GetSiteKey returns the site key rendering the widget.
+func (v *Verifier) GetSiteKey() string {
+	return v.config.SiteKey
+}
+
+// GetDifficulty returns the difficulty of proof-of-work puzzles.
+func (v *Verifier) GetDifficulty() int {
+	return v.difficulty
+}
+
+// GetResponseField returns the name of the form field holding the CAPTCHA
+// response.
+func (v *Verifier) GetResponseField() string {
+	return responseFields[v.config.Provider]
+}
+
+// Verify verifies the CAPTCHA response submitted from the remote address.
+func (v *Verifier) Verify(response, remoteAddr string) error {
+	if response == "" {
+		return errors.ErrCaptchaResponseEmpty
+	}
+	if v.config.Provider == "pow" {
+		return v.verifySolution(response)
+	}
+	return v.verifyResponse(response, remoteAddr)
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package captcha
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewVerifier(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid hcaptcha config",
+			config: &Config{Provider: "hcaptcha", SiteKey: "foo", Secret: "bar"},
+			want: map[string]interface{}{
+				"response_field": "h-captcha-response",
+				"site_key":       "foo",
+			},
+		},
+		{
+			name:   "valid turnstile config",
+			config: &Config{Provider: "turnstile", SiteKey: "foo", Secret: "bar"},
+			want: map[string]interface{}{
+				"response_field": "cf-turnstile-response",
+				"site_key":       "foo",
+			},
+		},
+		{
+			name:   "valid proof-of-work config",
+			config: &Config{Provider: "pow"},
+			want: map[string]interface{}{
+				"response_field": "pow-response",
+				"site_key":       "",
+			},
+		},
+		{
+			name:      "unsupported provider",
+			config:    &Config{Provider: "foo"},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigProviderInvalid.WithArgs("foo"),
+		},
+		{
+			name:      "recaptcha without site key",
+			config:    &Config{Provider: "recaptcha", Secret: "bar"},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigSiteKeyEmpty.WithArgs("recaptcha"),
+		},
+		{
+			name:      "recaptcha without secret",
+			config:    &Config{Provider: "recaptcha", SiteKey: "foo"},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigSecretEmpty.WithArgs("recaptcha"),
+		},
+		{
+			name:      "recaptcha with invalid min score",
+			config:    &Config{Provider: "recaptcha", SiteKey: "foo", Secret: "bar", MinScore: 2},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigMinScore.WithArgs(float64(2)),
+		},
+		{
+			name:      "proof-of-work with invalid difficulty",
+			config:    &Config{Provider: "pow", Difficulty: 64},
+			shouldErr: true,
+			err:       errors.ErrCaptchaConfigDifficulty.WithArgs(32, 64),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			v, err := NewVerifier(tc.config)
+			if tests.EvalErrWithLog(t, err, "captcha verifier", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"response_field": v.GetResponseField(),
+				"site_key":       v.GetSiteKey(),
+			}
+			tests.EvalObjectsWithLog(t, "captcha verifier", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestVerifyResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("secret") != "bar" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-secret"]}`))
+			return
+		}
+		switch r.Form.Get("response") {
+		case "human":
+			w.Write([]byte(`{"success": true, "score": 0.9}`))
+		case "bot":
+			w.Write([]byte(`{"success": true, "score": 0.1}`))
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	testcases := []struct {
+		name      string
+		response  string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "accepted response",
+			response: "human",
+		},
+		{
+			name:      "low score response",
+			response:  "bot",
+			shouldErr: true,
+			err:       errors.ErrCaptchaScoreTooLow.WithArgs("recaptcha", 0.1, 0.5),
+		},
+		{
+			name:      "rejected response",
+			response:  "foo",
+			shouldErr: true,
+			err:       errors.ErrCaptchaRejected.WithArgs("recaptcha", "invalid-input-response"),
+		},
+		{
+			name:      "empty response",
+			shouldErr: true,
+			err:       errors.ErrCaptchaResponseEmpty,
+		},
+	}
+
+	v, err := NewVerifier(&Config{Provider: "recaptcha", SiteKey: "foo", Secret: "bar", MinScore: 0.5, VerifyURL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			err := v.Verify(tc.response, "127.0.0.1")
+			tests.EvalErrWithLog(t, err, "captcha response", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestVerifySolution(t *testing.T) {
+	v, err := NewVerifier(&Config{Provider: "pow", Difficulty: 8})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	solve := func(puzzle string) string {
+		for nonce := 0; ; nonce++ {
+			solution := puzzle + ":" + strconv.Itoa(nonce)
+			if countLeadingZeroBits(sha256.Sum256([]byte(solution))) >= v.GetDifficulty() {
+				return solution
+			}
+		}
+	}
+
+	puzzle := v.NewPuzzle()
+	solution := solve(puzzle)
+	if err := v.Verify(solution, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = v.Verify(solution, "")
+	tests.EvalErrWithLog(t, err, "reused puzzle", true, errors.ErrCaptchaPuzzleReused, nil)
+
+	seed := strings.Split(puzzle, ".")[0]
+	tampered := solve(seed[:len(seed)-1] + "x." + strings.Split(puzzle, ".")[1])
+	err = v.Verify(tampered, "")
+	tests.EvalErrWithLog(t, err, "tampered puzzle", true, errors.ErrCaptchaPuzzleInvalid, nil)
+
+	puzzle = v.NewPuzzle()
+	for nonce := 0; ; nonce++ {
+		solution := puzzle + ":" + strconv.Itoa(nonce)
+		if countLeadingZeroBits(sha256.Sum256([]byte(solution))) < v.GetDifficulty() {
+			err = v.Verify(solution, "")
+			break
+		}
+	}
+	tests.EvalErrWithLog(t, err, "unsolved puzzle", true, errors.ErrCaptchaSolutionInvalid, nil)
+}
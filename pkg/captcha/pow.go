@@ -0,0 +1,99 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package captcha
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const puzzleLifetime = 10 * time.Minute
+
+// NewPuzzle returns a proof-of-work puzzle. The puzzle is stateless, it
+// holds the issue time and a random seed signed with the key of Verifier.
+// The browser searches for a nonce such that SHA-256 digest of
+// "<puzzle>:<nonce>" has the required number of leading zero bits, and
+// submits "<puzzle>:<nonce>" as the response.
+func (v *Verifier) NewPuzzle() string {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Unix()))
+	rand.Read(b[8:])
+	seed := hex.EncodeToString(b)
+	return seed + "." + v.signPuzzle(seed)
+}
+
+func (v *Verifier) signPuzzle(seed string) string {
+	h := hmac.New(sha256.New, v.puzzleKey)
+	h.Write([]byte(seed))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (v *Verifier) verifySolution(response string) error {
+	i := strings.LastIndex(response, ":")
+	if i < 0 {
+		return errors.ErrCaptchaSolutionInvalid
+	}
+	puzzle := response[:i]
+
+	parts := strings.Split(puzzle, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(v.signPuzzle(parts[0]))) {
+		return errors.ErrCaptchaPuzzleInvalid
+	}
+	b, err := hex.DecodeString(parts[0])
+	if err != nil || len(b) != 24 {
+		return errors.ErrCaptchaPuzzleInvalid
+	}
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(b)), 0).Add(puzzleLifetime)
+	if time.Now().After(expiresAt) {
+		return errors.ErrCaptchaPuzzleExpired
+	}
+
+	if countLeadingZeroBits(sha256.Sum256([]byte(response))) < v.difficulty {
+		return errors.ErrCaptchaSolutionInvalid
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	for k, exp := range v.solved {
+		if now.After(exp) {
+			delete(v.solved, k)
+		}
+	}
+	if _, exists := v.solved[puzzle]; exists {
+		return errors.ErrCaptchaPuzzleReused
+	}
+	v.solved[puzzle] = expiresAt
+	return nil
+}
+
+func countLeadingZeroBits(digest [32]byte) int {
+	var n int
+	for _, b := range digest {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package captcha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// verifyResponse submits the response to the siteverify endpoint of the
+// provider. hCaptcha, reCAPTCHA, and Turnstile share the protocol.
+func (v *Verifier) verifyResponse(response, remoteAddr string) error {
+	secret, err := credentials.ResolveSecret(v.config.Secret)
+	if err != nil {
+		return errors.ErrCaptchaVerification.WithArgs(v.config.Provider, err)
+	}
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("response", response)
+	if remoteAddr != "" {
+		params.Set("remoteip", remoteAddr)
+	}
+	if v.config.Provider == "hcaptcha" {
+		params.Set("sitekey", v.config.SiteKey)
+	}
+
+	cli := &http.Client{Timeout: requestTimeout}
+	resp, err := cli.PostForm(v.verifyURL, params)
+	if err != nil {
+		return errors.ErrCaptchaVerification.WithArgs(v.config.Provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.ErrCaptchaVerification.WithArgs(v.config.Provider, resp.Status)
+	}
+
+	var sv siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sv); err != nil {
+		return errors.ErrCaptchaVerification.WithArgs(v.config.Provider, err)
+	}
+	if !sv.Success {
+		return errors.ErrCaptchaRejected.WithArgs(v.config.Provider, strings.Join(sv.ErrorCodes, ", "))
+	}
+	if v.config.MinScore > 0 && sv.Score != nil && *sv.Score < v.config.MinScore {
+		return errors.ErrCaptchaScoreTooLow.WithArgs(v.config.Provider, *sv.Score, v.config.MinScore)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/sigv4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const awsSecretsManagerName = "aws_sm"
+
+var (
+	awsSTSEndpoint             = "https://sts.amazonaws.com/"
+	awsContainerCredentialsURL = "http://169.254.170.2"
+	awsInstanceMetadataURL     = "http://169.254.169.254"
+	awsHTTPClient              = &http.Client{Timeout: 10 * time.Second}
+	awsMetadataHTTPClient      = &http.Client{Timeout: 2 * time.Second}
+)
+
+// awsCredentials are AWS access keys. The temporary credentials obtained
+// with IAM roles have expiration time.
+type awsCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+var awsRoleCredentials struct {
+	mu    sync.Mutex
+	creds *awsCredentials
+}
+
+// getAWSSecret returns the secret from AWS Secrets Manager, e.g.
+// arn:aws:secretsmanager:us-east-1:111122223333:secret:prod/smtp#password.
+func getAWSSecret(ref, s string) (string, error) {
+	secretID, field := splitSecretField(s)
+	if secretID == "" {
+		return "", errors.ErrCredSecretReferenceInvalid.WithArgs(ref)
+	}
+
+	secret, err := secretCache.get(awsSecretsManagerName+":"+secretID, func() (string, error) {
+		return fetchAWSSecret(ref, secretID)
+	})
+	if err != nil {
+		return "", err
+	}
+	return extractSecretField(ref, secret, field)
+}
+
+func fetchAWSSecret(ref, secretID string) (string, error) {
+	var region string
+	// The region is a part of secret ARN.
+	if parts := strings.Split(secretID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", errors.ErrCredSecretRegionNotFound.WithArgs(ref)
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETSMANAGER")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com/"
+	}
+
+	creds, err := getAWSCredentials()
+	if err != nil {
+		return "", errors.ErrCredSecretProviderAuth.WithArgs(ref, awsSecretsManagerName, err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": secretID})
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, body, &sigv4.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, region, "secretsmanager", time.Now())
+
+	resp, err := awsHTTPClient.Do(req)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+
+	var data struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(respBody, &data); err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, err)
+	}
+	if data.SecretString == "" && data.SecretBinary != "" {
+		b, err := base64.StdEncoding.DecodeString(data.SecretBinary)
+		if err != nil {
+			return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, awsSecretsManagerName, err)
+		}
+		return string(b), nil
+	}
+	return data.SecretString, nil
+}
+
+// getAWSCredentials returns AWS credentials. The credentials are taken from
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables. Otherwise,
+// the temporary credentials of the IAM role are obtained with the web identity
+// token, e.g. EKS service account, from the container credentials endpoint,
+// e.g. ECS task role, or from the instance metadata service, e.g. EC2
+// instance profile.
+func getAWSCredentials() (*awsCredentials, error) {
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		return &awsCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	awsRoleCredentials.mu.Lock()
+	defer awsRoleCredentials.mu.Unlock()
+	// Refresh the credentials five minutes before they expire.
+	if creds := awsRoleCredentials.creds; creds != nil && time.Now().Add(5*time.Minute).Before(creds.Expiration) {
+		return creds, nil
+	}
+
+	var creds *awsCredentials
+	var err error
+	switch {
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && os.Getenv("AWS_ROLE_ARN") != "":
+		creds, err = getAWSWebIdentityCredentials()
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		creds, err = getAWSContainerCredentials()
+	case strings.ToLower(os.Getenv("AWS_EC2_METADATA_DISABLED")) != "true":
+		creds, err = getAWSInstanceCredentials()
+	default:
+		err = fmt.Errorf("no credential source configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+	awsRoleCredentials.creds = creds
+	return creds, nil
+}
+
+func getAWSWebIdentityCredentials() (*awsCredentials, error) {
+	token, err := ioutil.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = "authcrunch"
+	}
+	params := url.Values{}
+	params.Set("Action", "AssumeRoleWithWebIdentity")
+	params.Set("Version", "2011-06-15")
+	params.Set("RoleArn", os.Getenv("AWS_ROLE_ARN"))
+	params.Set("RoleSessionName", sessionName)
+	params.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	resp, err := awsHTTPClient.Get(awsSTSEndpoint + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sts: %s", resp.Status)
+	}
+	var data struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &awsCredentials{
+		AccessKeyID:     data.Credentials.AccessKeyID,
+		SecretAccessKey: data.Credentials.SecretAccessKey,
+		SessionToken:    data.Credentials.SessionToken,
+		Expiration:      data.Credentials.Expiration,
+	}, nil
+}
+
+func getAWSContainerCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if endpoint == "" {
+		endpoint = awsContainerCredentialsURL + os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return doAWSCredentialsRequest(awsMetadataHTTPClient, req)
+}
+
+func getAWSInstanceCredentials() (*awsCredentials, error) {
+	// Obtain IMDSv2 session token.
+	req, err := http.NewRequest(http.MethodPut, awsInstanceMetadataURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "300")
+	resp, err := awsMetadataHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("instance metadata: %s", resp.Status)
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = http.NewRequest(http.MethodGet, awsInstanceMetadataURL+"/latest/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+	resp, err = awsMetadataHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("instance metadata: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+	if role == "" {
+		return nil, fmt.Errorf("instance metadata: no iam role attached")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, awsInstanceMetadataURL+"/latest/meta-data/iam/security-credentials/"+role, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+	return doAWSCredentialsRequest(awsMetadataHTTPClient, req)
+}
+
+func doAWSCredentialsRequest(client *http.Client, req *http.Request) (*awsCredentials, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	creds := &awsCredentials{}
+	if err := json.NewDecoder(resp.Body).Decode(creds); err != nil {
+		return nil, err
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%s: malformed credentials", req.URL.Host)
+	}
+	return creds, nil
+}
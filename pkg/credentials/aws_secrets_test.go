@@ -0,0 +1,253 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func newTestAWSServer(t *testing.T, secrets map[string]map[string]string) *httptest.Server {
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("Action") != "AssumeRoleWithWebIdentity" || r.URL.Query().Get("WebIdentityToken") != "web-identity-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>`+
+			`<AccessKeyId>AKIDWEBIDENTITY</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>`+
+			`<SessionToken>session</SessionToken><Expiration>%s</Expiration>`+
+			`</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`, expiration)
+	})
+	mux.HandleFunc("/container/creds", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "container-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"AccessKeyId": "AKIDCONTAINER", "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`, expiration)
+	})
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("imds-token"))
+	})
+	mux.HandleFunc("/latest/meta-data/iam/security-credentials/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/myrole") {
+			fmt.Fprintf(w, `{"AccessKeyId": "AKIDINSTANCE", "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`, expiration)
+			return
+		}
+		w.Write([]byte("myrole\n"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			SecretID string `json:"SecretId"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		// The secrets are keyed by the access key identifier.
+		accessKeyID := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="), "/")[0]
+		secret, exists := secrets[accessKeyID][req.SecretID]
+		if !exists {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException"}`))
+			return
+		}
+		if strings.HasPrefix(secret, "binary:") {
+			json.NewEncoder(w).Encode(map[string]string{"SecretBinary": strings.TrimPrefix(secret, "binary:")})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"SecretString": secret})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func resetTestSecretCache() {
+	secretCache.entries = make(map[string]*secretValueEntry)
+	awsRoleCredentials.creds = nil
+	gcpAccessToken.token = ""
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	srv := newTestAWSServer(t, map[string]map[string]string{
+		"AKIDEXAMPLE": {
+			"prod/smtp":   `{"username": "foo", "password": "bar"}`,
+			"prod/plain":  "foobar",
+			"prod/binary": "binary:Zm9vYmFy",
+		},
+		"AKIDWEBIDENTITY": {"prod/plain": "web identity"},
+		"AKIDCONTAINER":   {"prod/plain": "container"},
+		"AKIDINSTANCE":    {"prod/plain": "instance"},
+	})
+	awsSTSEndpoint = srv.URL + "/sts/"
+	awsContainerCredentialsURL = srv.URL + "/container"
+	awsInstanceMetadataURL = srv.URL
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("web-identity-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name      string
+		input     string
+		env       map[string]string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test secret field with access keys",
+			input: "{aws_sm:prod/smtp#password}",
+			env:   map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret"},
+			want:  "bar",
+		},
+		{
+			name:  "test secret arn with region",
+			input: "{aws_sm:arn:aws:secretsmanager:us-west-2:111122223333:secret:prod/plain}",
+			env:   map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": ""},
+			// The test server does not know the secret by its arn.
+			shouldErr: true,
+			err: errors.ErrCredSecretProviderRequest.WithArgs(
+				"{aws_sm:arn:aws:secretsmanager:us-west-2:111122223333:secret:prod/plain}",
+				"aws_sm", `400 Bad Request: {"__type": "ResourceNotFoundException"}`,
+			),
+		},
+		{
+			name:  "test binary secret",
+			input: "{aws_sm:prod/binary}",
+			env:   map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret"},
+			want:  "foobar",
+		},
+		{
+			name:      "test secret without field",
+			input:     "{aws_sm:prod/smtp#domain}",
+			env:       map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret"},
+			shouldErr: true,
+			err:       errors.ErrCredSecretFieldNotFound.WithArgs("{aws_sm:prod/smtp#domain}", "domain"),
+		},
+		{
+			name:      "test secret without region",
+			input:     "{aws_sm:prod/plain}",
+			env:       map[string]string{"AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_REGION": ""},
+			shouldErr: true,
+			err:       errors.ErrCredSecretRegionNotFound.WithArgs("{aws_sm:prod/plain}"),
+		},
+		{
+			name:  "test web identity credentials",
+			input: "{aws_sm:prod/plain}",
+			env:   map[string]string{"AWS_WEB_IDENTITY_TOKEN_FILE": tokenPath, "AWS_ROLE_ARN": "arn:aws:iam::111122223333:role/authp"},
+			want:  "web identity",
+		},
+		{
+			name:  "test container credentials",
+			input: "{aws_sm:prod/plain}",
+			env:   map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/creds", "AWS_CONTAINER_AUTHORIZATION_TOKEN": "container-token"},
+			want:  "container",
+		},
+		{
+			name:  "test instance credentials",
+			input: "{aws_sm:prod/plain}",
+			want:  "instance",
+		},
+		{
+			name:      "test disabled instance credentials",
+			input:     "{aws_sm:prod/plain}",
+			env:       map[string]string{"AWS_EC2_METADATA_DISABLED": "true"},
+			shouldErr: true,
+			err:       errors.ErrCredSecretProviderAuth.WithArgs("{aws_sm:prod/plain}", "aws_sm", fmt.Errorf("no credential source configured")),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			resetTestSecretCache()
+			for _, k := range []string{
+				"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+				"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+				"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN",
+				"AWS_EC2_METADATA_DISABLED", "AWS_DEFAULT_REGION",
+			} {
+				t.Setenv(k, "")
+			}
+			t.Setenv("AWS_REGION", "us-east-1")
+			t.Setenv("AWS_ENDPOINT_URL_SECRETSMANAGER", srv.URL)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := ResolveSecret(tc.input)
+			if tests.EvalErrWithLog(t, err, "ResolveSecret", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "ResolveSecret", tc.want, got, nil)
+		})
+	}
+}
+
+func TestSecretCacheRotation(t *testing.T) {
+	resetTestSecretCache()
+	defer SetSecretCacheTTL(defaultSecretCacheTTL)
+
+	secrets := map[string]map[string]string{
+		"AKIDEXAMPLE": {"prod/plain": "v1"},
+	}
+	srv := newTestAWSServer(t, secrets)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ENDPOINT_URL_SECRETSMANAGER", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	var got []string
+	resolve := func() {
+		value, err := ResolveSecret("{aws_sm:prod/plain}")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, value)
+	}
+
+	resolve()
+	// The cached value is used until it expires.
+	secrets["AKIDEXAMPLE"]["prod/plain"] = "v2"
+	resolve()
+	// The rotated value is picked up once the cached value expires.
+	SetSecretCacheTTL(0)
+	resolve()
+	// The previous value is used when the refresh fails.
+	delete(secrets["AKIDEXAMPLE"], "prod/plain")
+	resolve()
+
+	tests.EvalObjectsWithLog(t, "ResolveSecret", []string{"v1", "v1", "v2", "v2"}, got, nil)
+}
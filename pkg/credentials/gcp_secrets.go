@@ -0,0 +1,150 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const gcpSecretManagerName = "gcp_sm"
+
+var (
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com/v1/"
+	gcpMetadataTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpHTTPClient            = &http.Client{Timeout: 10 * time.Second}
+)
+
+var gcpAccessToken struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// getGCPSecret returns the secret version from GCP Secret Manager, e.g.
+// projects/myproject/secrets/smtp/versions/latest#password. When the version
+// is not specified, the latest version is used.
+func getGCPSecret(ref, s string) (string, error) {
+	name, field := splitSecretField(s)
+	parts := strings.Split(name, "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		name += "/versions/latest"
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+	default:
+		return "", errors.ErrCredSecretReferenceInvalid.WithArgs(ref)
+	}
+
+	secret, err := secretCache.get(gcpSecretManagerName+":"+name, func() (string, error) {
+		return fetchGCPSecret(ref, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	return extractSecretField(ref, secret, field)
+}
+
+func fetchGCPSecret(ref, name string) (string, error) {
+	token, err := getGCPAccessToken()
+	if err != nil {
+		return "", errors.ErrCredSecretProviderAuth.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	req, err := http.NewRequest(http.MethodGet, gcpSecretManagerEndpoint+name+":access", nil)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := gcpHTTPClient.Do(req)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+
+	var data struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(respBody, &data); err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	b, err := base64.StdEncoding.DecodeString(data.Payload.Data)
+	if err != nil {
+		return "", errors.ErrCredSecretProviderRequest.WithArgs(ref, gcpSecretManagerName, err)
+	}
+	return string(b), nil
+}
+
+// getGCPAccessToken returns the access token from GOOGLE_OAUTH_ACCESS_TOKEN
+// environment variable or, when it is not set, the token of the service
+// account attached to the workload from the metadata server, e.g. GKE
+// workload identity.
+func getGCPAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	gcpAccessToken.mu.Lock()
+	defer gcpAccessToken.mu.Unlock()
+	if gcpAccessToken.token != "" && time.Now().Before(gcpAccessToken.expiresAt) {
+		return gcpAccessToken.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := gcpHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server: %s", resp.Status)
+	}
+	var data struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", err
+	}
+	if data.AccessToken == "" {
+		return "", fmt.Errorf("metadata server: empty access token")
+	}
+
+	gcpAccessToken.token = data.AccessToken
+	// Refresh the token a minute before it expires.
+	gcpAccessToken.expiresAt = time.Now().Add(time.Duration(data.ExpiresIn-60) * time.Second)
+	return data.AccessToken, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestGCPSecretManager(t *testing.T) {
+	secrets := map[string]string{
+		"projects/myproject/secrets/smtp/versions/latest": `{"username": "foo", "password": "bar"}`,
+		"projects/myproject/secrets/smtp/versions/1":      "foobar",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3600}`))
+			return
+		case r.Header.Get("Authorization") != "Bearer metadata-token" && r.Header.Get("Authorization") != "Bearer env-token":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")
+		secret, exists := secrets[name]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"status": "NOT_FOUND"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":    name,
+			"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(secret))},
+		})
+	}))
+	defer srv.Close()
+	gcpSecretManagerEndpoint = srv.URL + "/v1/"
+	gcpMetadataTokenURL = srv.URL + "/token"
+
+	testcases := []struct {
+		name      string
+		input     string
+		env       map[string]string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test latest secret version field with metadata token",
+			input: "{gcp_sm:projects/myproject/secrets/smtp#password}",
+			want:  "bar",
+		},
+		{
+			name:  "test secret version with access token",
+			input: "{gcp_sm:projects/myproject/secrets/smtp/versions/1}",
+			env:   map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "env-token"},
+			want:  "foobar",
+		},
+		{
+			name:      "test invalid secret name",
+			input:     "{gcp_sm:myproject/smtp}",
+			shouldErr: true,
+			err:       errors.ErrCredSecretReferenceInvalid.WithArgs("{gcp_sm:myproject/smtp}"),
+		},
+		{
+			name:      "test secret not found",
+			input:     "{gcp_sm:projects/myproject/secrets/ldap}",
+			shouldErr: true,
+			err: errors.ErrCredSecretProviderRequest.WithArgs(
+				"{gcp_sm:projects/myproject/secrets/ldap}",
+				"gcp_sm", `404 Not Found: {"error": {"status": "NOT_FOUND"}}`,
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			resetTestSecretCache()
+			t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := ResolveSecret(tc.input)
+			if tests.EvalErrWithLog(t, err, "ResolveSecret", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "ResolveSecret", tc.want, got, nil)
+		})
+	}
+}
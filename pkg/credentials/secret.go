@@ -0,0 +1,127 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
+)
+
+const secretExecTimeout = 10 * time.Second
+
+// IsSecretReference returns true if the value references a secret. The
+// supported references follow.
+//
+//   - {env.SMTP_PASS} is the value of the environment variable
+//   - {file:/run/secrets/smtp} is the content of the file
+//   - {exec:/usr/bin/pass show smtp} is the output of the command
+//   - {vault:kv/data/smtp#password} is the field of the Vault secret
+//   - {aws_sm:prod/smtp#password} is the field of AWS Secrets Manager secret
+//   - {gcp_sm:projects/myproject/secrets/smtp} is GCP Secret Manager secret
+//
+// The secrets held in AWS Secrets Manager and GCP Secret Manager are cached,
+// see SetSecretCacheTTL. The field is optional and selects the value from
+// the secret holding JSON object.
+//
+// The Vault references without the braces, e.g. vault:kv/data/smtp#password,
+// are supported for backward compatibility.
+func IsSecretReference(s string) bool {
+	if vault.IsReference(s) {
+		return true
+	}
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return false
+	}
+	ref := s[1 : len(s)-1]
+	for _, prefix := range []string{"env.", "file:", "exec:", "vault:", "aws_sm:", "gcp_sm:"} {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecret returns the value of the referenced secret. The values not
+// referencing secrets are returned as is.
+func ResolveSecret(s string) (string, error) {
+	if !IsSecretReference(s) {
+		return s, nil
+	}
+	if vault.IsReference(s) {
+		return vault.Resolve(s)
+	}
+
+	ref := s[1 : len(s)-1]
+	var value string
+	switch {
+	case strings.HasPrefix(ref, "env."):
+		name := strings.TrimPrefix(ref, "env.")
+		if name == "" {
+			return "", errors.ErrCredSecretReferenceInvalid.WithArgs(s)
+		}
+		v, exists := os.LookupEnv(name)
+		if !exists {
+			return "", errors.ErrCredSecretEnvNotFound.WithArgs(s, name)
+		}
+		value = v
+	case strings.HasPrefix(ref, "file:"):
+		fp := strings.TrimPrefix(ref, "file:")
+		if fp == "" {
+			return "", errors.ErrCredSecretReferenceInvalid.WithArgs(s)
+		}
+		b, err := os.ReadFile(fp)
+		if err != nil {
+			return "", errors.ErrCredSecretFileRead.WithArgs(s, err)
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	case strings.HasPrefix(ref, "exec:"):
+		args := strings.Fields(strings.TrimPrefix(ref, "exec:"))
+		if len(args) == 0 {
+			return "", errors.ErrCredSecretReferenceInvalid.WithArgs(s)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+		defer cancel()
+		b, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			return "", errors.ErrCredSecretExec.WithArgs(s, err)
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	case strings.HasPrefix(ref, "aws_sm:"):
+		v, err := getAWSSecret(s, strings.TrimPrefix(ref, "aws_sm:"))
+		if err != nil {
+			return "", err
+		}
+		value = v
+	case strings.HasPrefix(ref, "gcp_sm:"):
+		v, err := getGCPSecret(s, strings.TrimPrefix(ref, "gcp_sm:"))
+		if err != nil {
+			return "", err
+		}
+		value = v
+	default:
+		return vault.Resolve(ref)
+	}
+
+	if value == "" {
+		return "", errors.ErrCredSecretEmpty.WithArgs(s)
+	}
+	return value, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const defaultSecretCacheTTL = 5 * time.Minute
+
+// secretCache holds the secrets fetched from cloud secret managers. The
+// entries are refreshed once they expire, so that the rotated secrets are
+// picked up. When the refresh fails, the previous value is used.
+var secretCache = &secretValueCache{
+	entries: make(map[string]*secretValueEntry),
+	ttl:     defaultSecretCacheTTL,
+}
+
+type secretValueCache struct {
+	mu      sync.Mutex
+	entries map[string]*secretValueEntry
+	ttl     time.Duration
+}
+
+type secretValueEntry struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// SetSecretCacheTTL sets the duration for which the secrets fetched from
+// AWS Secrets Manager and GCP Secret Manager are cached. Defaults to five
+// minutes.
+func SetSecretCacheTTL(d time.Duration) {
+	secretCache.mu.Lock()
+	defer secretCache.mu.Unlock()
+	secretCache.ttl = d
+}
+
+func (c *secretValueCache) get(ref string, fetch func() (string, error)) (string, error) {
+	c.mu.Lock()
+	entry, exists := c.entries[ref]
+	ttl := c.ttl
+	c.mu.Unlock()
+	if exists && time.Since(entry.fetchedAt) < ttl {
+		return entry.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		if exists {
+			return entry.value, nil
+		}
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[ref] = &secretValueEntry{
+		value:     value,
+		fetchedAt: time.Now(),
+	}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// extractSecretField returns the field of a secret holding JSON object.
+// When the field is empty, the secret is returned as is.
+func extractSecretField(ref, secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(secret), &m); err != nil {
+		return "", errors.ErrCredSecretFieldNotFound.WithArgs(ref, field)
+	}
+	value, ok := m[field].(string)
+	if !ok {
+		return "", errors.ErrCredSecretFieldNotFound.WithArgs(ref, field)
+	}
+	return value, nil
+}
+
+// splitSecretField splits the secret name and the optional field, e.g.
+// prod/smtp#password.
+func splitSecretField(s string) (string, string) {
+	i := strings.LastIndex(s, "#")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestResolveSecret(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(fp, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTHCRUNCH_TEST_SECRET", "foobar")
+
+	testcases := []struct {
+		name      string
+		input     string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test plain value",
+			input: "foobar",
+			want: map[string]interface{}{
+				"reference": false,
+				"value":     "foobar",
+			},
+		},
+		{
+			name:  "test value in braces",
+			input: "{foobar}",
+			want: map[string]interface{}{
+				"reference": false,
+				"value":     "{foobar}",
+			},
+		},
+		{
+			name:  "test env reference",
+			input: "{env.AUTHCRUNCH_TEST_SECRET}",
+			want: map[string]interface{}{
+				"reference": true,
+				"value":     "foobar",
+			},
+		},
+		{
+			name:      "test env reference to unset variable",
+			input:     "{env.AUTHCRUNCH_TEST_UNSET}",
+			shouldErr: true,
+			err:       errors.ErrCredSecretEnvNotFound.WithArgs("{env.AUTHCRUNCH_TEST_UNSET}", "AUTHCRUNCH_TEST_UNSET"),
+		},
+		{
+			name:  "test file reference",
+			input: "{file:" + fp + "}",
+			want: map[string]interface{}{
+				"reference": true,
+				"value":     "s3cr3t",
+			},
+		},
+		{
+			name:      "test file reference to missing file",
+			input:     "{file:" + fp + ".missing}",
+			shouldErr: true,
+			err: errors.ErrCredSecretFileRead.WithArgs(
+				"{file:"+fp+".missing}",
+				fmt.Errorf("open %s.missing: no such file or directory", fp),
+			),
+		},
+		{
+			name:  "test exec reference",
+			input: "{exec:echo foobar}",
+			want: map[string]interface{}{
+				"reference": true,
+				"value":     "foobar",
+			},
+		},
+		{
+			name:      "test exec reference with empty output",
+			input:     "{exec:true}",
+			shouldErr: true,
+			err:       errors.ErrCredSecretEmpty.WithArgs("{exec:true}"),
+		},
+		{
+			name:      "test empty exec reference",
+			input:     "{exec:}",
+			shouldErr: true,
+			err:       errors.ErrCredSecretReferenceInvalid.WithArgs("{exec:}"),
+		},
+		{
+			name:      "test vault reference without client",
+			input:     "{vault:kv/data/idp#client_secret}",
+			shouldErr: true,
+			err:       errors.ErrVaultClientNotConfigured,
+		},
+		{
+			name:      "test legacy vault reference without client",
+			input:     "vault:kv/data/idp#client_secret",
+			shouldErr: true,
+			err:       errors.ErrVaultClientNotConfigured,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got := make(map[string]interface{})
+			got["reference"] = IsSecretReference(tc.input)
+			value, err := ResolveSecret(tc.input)
+			if tests.EvalErrWithLog(t, err, "ResolveSecret", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got["value"] = value
+			tests.EvalObjectsWithLog(t, "ResolveSecret", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type serviceAccount struct {
+	name           string
+	passwordHash   []byte
+	passwordDigest [sha256.Size]byte
+}
+
+// entry is a user entry of the directory.
+type entry struct {
+	username string
+	email    string
+	name     string
+	roles    []string
+}
+
+func newServiceAccount(cfg *ServiceAccountConfig) *serviceAccount {
+	account := &serviceAccount{name: cfg.Name}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(cfg.Password, prefix) {
+			account.passwordHash = []byte(cfg.Password)
+			return account
+		}
+	}
+	account.passwordDigest = sha256.Sum256([]byte(cfg.Password))
+	return account
+}
+
+func (account *serviceAccount) verify(password string) bool {
+	if account.passwordHash != nil {
+		return bcrypt.CompareHashAndPassword(account.passwordHash, []byte(password)) == nil
+	}
+	digest := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(account.passwordDigest[:], digest[:]) == 1
+}
+
+// handleBind handles the simple bind. The bind DN is either the DN of a
+// user, i.e. uid=<username>,ou=users,<base_dn> or mail=<email>,ou=users,<base_dn>,
+// the DN of a service account, i.e. cn=<name>,ou=services,<base_dn>, or
+// the username or the email address of a user.
+func (srv *Server) handleBind(sess *session, messageID int64, op *ber.Packet) bool {
+	sess.principal = nil
+	if len(op.Children) < 3 {
+		return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "", "malformed bind request")
+	}
+	if version, ok := op.Children[0].Value.(int64); !ok || version != 3 {
+		return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "", "unsupported protocol version")
+	}
+	name, _ := op.Children[1].Value.(string)
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported, "", "only simple bind is supported")
+	}
+	password := auth.Data.String()
+
+	if name == "" && password == "" {
+		// Anonymous bind. The anonymous sessions may not search.
+		return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "", "")
+	}
+
+	p, err := srv.authenticate(name, password)
+	if err != nil {
+		srv.logger.Warn(
+			"directory bind failed",
+			zap.String("name", srv.config.Name),
+			zap.String("bind_dn", name),
+			zap.String("src_ip", sess.srcAddr),
+			zap.Error(err),
+		)
+		return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "", "invalid credentials")
+	}
+
+	sess.principal = p
+	srv.logger.Info(
+		"Audit",
+		zap.String("event", "directory_bind"),
+		zap.String("name", srv.config.Name),
+		zap.String("username", p.name),
+		zap.Bool("service_account", p.service),
+		zap.String("src_ip", sess.srcAddr),
+	)
+	return srv.respond(sess, messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "", "")
+}
+
+func (srv *Server) authenticate(name, password string) (*principal, error) {
+	if password == "" {
+		// The unauthenticated bind, see RFC 4513, Section 5.1.2.
+		return nil, errors.ErrDirectoryBindUnauthenticated.WithArgs(name)
+	}
+
+	if !strings.Contains(name, "=") {
+		return srv.authenticateUser(name, password)
+	}
+
+	dn, err := ldap.ParseDN(name)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) != 1 {
+		return nil, errors.ErrDirectoryBindInvalidDN.WithArgs(name)
+	}
+	attr := dn.RDNs[0].Attributes[0]
+	parent := &ldap.DN{RDNs: dn.RDNs[1:]}
+
+	switch {
+	case parent.EqualFold(srv.servicesDN) && strings.EqualFold(attr.Type, "cn"):
+		account, exists := srv.accounts[strings.ToLower(attr.Value)]
+		if !exists || !account.verify(password) {
+			return nil, errors.ErrDirectoryServiceAccountAuthFailed.WithArgs(attr.Value)
+		}
+		return &principal{name: account.name, service: true}, nil
+	case parent.EqualFold(srv.usersDN) && isUserAttribute(attr.Type):
+		return srv.authenticateUser(attr.Value, password)
+	}
+	return nil, errors.ErrDirectoryBindInvalidDN.WithArgs(name)
+}
+
+// authenticateUser authenticates a user with the identity store, and checks
+// whether the account is locked and whether the user has the required roles.
+func (srv *Server) authenticateUser(identifier, password string) (*principal, error) {
+	rr := requests.NewRequest()
+	rr.User.Username = identifier
+	rr.User.Password = password
+	if err := srv.store.Request(operator.Authenticate, rr); err != nil {
+		return nil, err
+	}
+
+	e, err := srv.lookupUser(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(srv.config.RequiredRoles) > 0 && !hasAnyRole(e.roles, srv.config.RequiredRoles) {
+		return nil, errors.ErrDirectoryBindRoleNotAllowed.WithArgs(e.username)
+	}
+	return &principal{name: e.username}, nil
+}
+
+// lookupUser returns the entry of the user with the provided username or
+// email address. The locked accounts are not found.
+func (srv *Server) lookupUser(identifier string) (*entry, error) {
+	rr := requests.NewRequest()
+	rr.User.Username = identifier
+	if err := srv.store.Request(operator.IdentifyUser, rr); err != nil {
+		return nil, err
+	}
+	if rr.Response.Code != 200 {
+		return nil, errors.ErrDatabaseUserNotFound
+	}
+	e := &entry{
+		username: rr.User.Username,
+		email:    rr.User.Email,
+		name:     rr.User.FullName,
+		roles:    rr.User.Roles,
+	}
+
+	rr = requests.NewRequest()
+	rr.User.Username = e.username
+	rr.User.Email = e.email
+	if err := srv.store.Request(operator.GetUser, rr); err != nil {
+		return nil, err
+	}
+	if usr, ok := rr.Response.Payload.(*identity.User); ok && usr.Lockout.Active(time.Now()) {
+		return nil, errors.ErrDirectoryBindAccountLocked.WithArgs(e.username)
+	}
+	return e, nil
+}
+
+func isUserAttribute(s string) bool {
+	switch strings.ToLower(s) {
+	case "uid", "mail", "samaccountname", "userprincipalname":
+		return true
+	}
+	return false
+}
+
+func hasAnyRole(roles, allowedRoles []string) bool {
+	for _, allowedRole := range allowedRoles {
+		for _, role := range roles {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
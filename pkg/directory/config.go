@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config is the configuration of the LDAP directory backed by an identity
+// store. It allows the applications speaking only LDAP to authenticate the
+// users of the identity store with a simple bind.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Address is the address the directory listens on, e.g. 127.0.0.1:3389.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// BaseDN is the base of the directory tree, e.g. dc=authp,dc=local. The
+	// users are under "ou=users", and the service accounts are under
+	// "ou=services".
+	BaseDN string `json:"base_dn,omitempty" xml:"base_dn,omitempty" yaml:"base_dn,omitempty"`
+	// IdentityStore is the name of the local identity store with the users.
+	IdentityStore string `json:"identity_store,omitempty" xml:"identity_store,omitempty" yaml:"identity_store,omitempty"`
+	// RequiredRoles are the roles permitted to bind. When empty, any user
+	// may bind.
+	RequiredRoles []string `json:"required_roles,omitempty" xml:"required_roles,omitempty" yaml:"required_roles,omitempty"`
+	// ServiceAccounts are the accounts the applications bind with prior to
+	// searching for the users.
+	ServiceAccounts []*ServiceAccountConfig `json:"service_accounts,omitempty" xml:"service_accounts,omitempty" yaml:"service_accounts,omitempty"`
+	// TLSCertPath and TLSKeyPath enable LDAPS.
+	TLSCertPath string `json:"tls_cert_path,omitempty" xml:"tls_cert_path,omitempty" yaml:"tls_cert_path,omitempty"`
+	TLSKeyPath  string `json:"tls_key_path,omitempty" xml:"tls_key_path,omitempty" yaml:"tls_key_path,omitempty"`
+}
+
+// ServiceAccountConfig is the configuration of a service account.
+type ServiceAccountConfig struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Password is either a bcrypt hash, or a plain-text string.
+	Password string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+}
+
+// Validate validates directory config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrDirectoryConfigNameEmpty
+	}
+	if cfg.Address == "" {
+		return errors.ErrDirectoryConfigAddressEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.BaseDN == "" {
+		return errors.ErrDirectoryConfigBaseDNEmpty.WithArgs(cfg.Name)
+	}
+	if _, err := ldap.ParseDN(cfg.BaseDN); err != nil {
+		return errors.ErrDirectoryConfigBaseDNInvalid.WithArgs(cfg.Name, cfg.BaseDN, err)
+	}
+	if cfg.IdentityStore == "" {
+		return errors.ErrDirectoryConfigIdentityStoreEmpty.WithArgs(cfg.Name)
+	}
+	names := make(map[string]bool)
+	for _, account := range cfg.ServiceAccounts {
+		if account.Name == "" {
+			return errors.ErrDirectoryConfigServiceAccount.WithArgs(cfg.Name, "empty name")
+		}
+		if names[strings.ToLower(account.Name)] {
+			return errors.ErrDirectoryConfigServiceAccount.WithArgs(cfg.Name, "duplicate name "+account.Name)
+		}
+		names[strings.ToLower(account.Name)] = true
+		if account.Password == "" {
+			return errors.ErrDirectoryConfigServiceAccount.WithArgs(cfg.Name, "empty password for "+account.Name)
+		}
+	}
+	if (cfg.TLSCertPath == "") != (cfg.TLSKeyPath == "") {
+		return errors.ErrDirectoryConfigTLS.WithArgs(cfg.Name, "both certificate and key paths are required")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid directory config",
+			config: &Config{
+				Name:          "corp",
+				Address:       "127.0.0.1:3389",
+				BaseDN:        "dc=authp,dc=local",
+				IdentityStore: "localdb",
+				ServiceAccounts: []*ServiceAccountConfig{
+					{Name: "wiki", Password: "foobar"},
+				},
+			},
+		},
+		{
+			name:      "test directory config without name",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigNameEmpty,
+		},
+		{
+			name: "test directory config without address",
+			config: &Config{
+				Name: "corp",
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigAddressEmpty.WithArgs("corp"),
+		},
+		{
+			name: "test directory config without base dn",
+			config: &Config{
+				Name:    "corp",
+				Address: "127.0.0.1:3389",
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigBaseDNEmpty.WithArgs("corp"),
+		},
+		{
+			name: "test directory config with invalid base dn",
+			config: &Config{
+				Name:    "corp",
+				Address: "127.0.0.1:3389",
+				BaseDN:  "authp.local",
+			},
+			shouldErr: true,
+			err: errors.ErrDirectoryConfigBaseDNInvalid.WithArgs(
+				"corp", "authp.local", "DN ended with incomplete type, value pair",
+			),
+		},
+		{
+			name: "test directory config without identity store",
+			config: &Config{
+				Name:    "corp",
+				Address: "127.0.0.1:3389",
+				BaseDN:  "dc=authp,dc=local",
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigIdentityStoreEmpty.WithArgs("corp"),
+		},
+		{
+			name: "test directory config with duplicate service account",
+			config: &Config{
+				Name:          "corp",
+				Address:       "127.0.0.1:3389",
+				BaseDN:        "dc=authp,dc=local",
+				IdentityStore: "localdb",
+				ServiceAccounts: []*ServiceAccountConfig{
+					{Name: "wiki", Password: "foobar"},
+					{Name: "Wiki", Password: "foobar"},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigServiceAccount.WithArgs("corp", "duplicate name Wiki"),
+		},
+		{
+			name: "test directory config with service account without password",
+			config: &Config{
+				Name:          "corp",
+				Address:       "127.0.0.1:3389",
+				BaseDN:        "dc=authp,dc=local",
+				IdentityStore: "localdb",
+				ServiceAccounts: []*ServiceAccountConfig{
+					{Name: "wiki"},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigServiceAccount.WithArgs("corp", "empty password for wiki"),
+		},
+		{
+			name: "test directory config with tls certificate without key",
+			config: &Config{
+				Name:          "corp",
+				Address:       "127.0.0.1:3389",
+				BaseDN:        "dc=authp,dc=local",
+				IdentityStore: "localdb",
+				TLSCertPath:   "/etc/ssl/ldap.crt",
+			},
+			shouldErr: true,
+			err:       errors.ErrDirectoryConfigTLS.WithArgs("corp", "both certificate and key paths are required"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// handlePacket handles an LDAP message. It returns false when the connection
+// must be closed.
+func (srv *Server) handlePacket(sess *session, packet *ber.Packet) bool {
+	if len(packet.Children) < 2 {
+		return false
+	}
+	messageID, ok := packet.Children[0].Value.(int64)
+	if !ok {
+		return false
+	}
+	op := packet.Children[1]
+	if op.ClassType != ber.ClassApplication {
+		return false
+	}
+
+	switch op.Tag {
+	case ldap.ApplicationBindRequest:
+		return srv.handleBind(sess, messageID, op)
+	case ldap.ApplicationUnbindRequest:
+		return false
+	case ldap.ApplicationSearchRequest:
+		return srv.handleSearch(sess, messageID, op)
+	case ldap.ApplicationAbandonRequest:
+		return true
+	case ldap.ApplicationModifyRequest, ldap.ApplicationAddRequest, ldap.ApplicationDelRequest,
+		ldap.ApplicationModifyDNRequest, ldap.ApplicationCompareRequest:
+		// The directory is read-only. The response tag follows the request tag.
+		return srv.respond(sess, messageID, op.Tag+1, ldap.LDAPResultUnwillingToPerform, "", "directory is read-only")
+	case ldap.ApplicationExtendedRequest:
+		return srv.respond(sess, messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "", "extended operations are not supported")
+	}
+
+	srv.logger.Debug(
+		"unsupported directory operation",
+		zap.String("name", srv.config.Name),
+		zap.String("src_ip", sess.srcAddr),
+		zap.Any("op", op.Tag),
+	)
+	return false
+}
+
+// respond sends the LDAPResult response.
+func (srv *Server) respond(sess *session, messageID int64, tag ber.Tag, code uint16, matchedDN, msg string) bool {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, ldap.ApplicationMap[uint8(tag)])
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, matchedDN, "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, msg, "Diagnostic Message"))
+	return srv.send(sess, messageID, op)
+}
+
+func (srv *Server) send(sess *session, messageID int64, op *ber.Packet) bool {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	if _, err := sess.conn.Write(packet.Bytes()); err != nil {
+		return false
+	}
+	return true
+}
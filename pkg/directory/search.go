@@ -0,0 +1,412 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+var userObjectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+
+// record is a directory entry returned by a search.
+type record struct {
+	dn    string
+	attrs []*attribute
+}
+
+type attribute struct {
+	name   string
+	values []string
+}
+
+type searchRequest struct {
+	baseDN     string
+	scope      int64
+	sizeLimit  int64
+	typesOnly  bool
+	filter     *ber.Packet
+	attributes []string
+}
+
+// handleSearch handles the search request. The anonymous sessions may read
+// the root DSE only. The users may find their own entry, and the service
+// accounts may find any user entry.
+func (srv *Server) handleSearch(sess *session, messageID int64, op *ber.Packet) bool {
+	req, ok := parseSearchRequest(op)
+	if !ok {
+		return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "", "malformed search request")
+	}
+
+	if req.baseDN == "" && req.scope == ldap.ScopeBaseObject {
+		return srv.sendRecords(sess, messageID, req, []*record{srv.getRootDSE()})
+	}
+
+	if sess.principal == nil {
+		return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights, "", "bind required")
+	}
+
+	base, err := ldap.ParseDN(req.baseDN)
+	if err != nil {
+		return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInvalidDNSyntax, "", "invalid base dn")
+	}
+	if !srv.baseDN.EqualFold(base) && !srv.baseDN.AncestorOfFold(base) {
+		return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, srv.baseDN.String(), "")
+	}
+
+	var records []*record
+	switch {
+	case srv.baseDN.EqualFold(base):
+		if req.scope != ldap.ScopeSingleLevel {
+			records = append(records, srv.getContainer(srv.baseDN, "dc"))
+		}
+		records = append(records, srv.getContainer(srv.usersDN, "ou"))
+		if req.scope == ldap.ScopeWholeSubtree {
+			records = append(records, srv.findUsers(sess, req.filter, "")...)
+		}
+	case srv.usersDN.EqualFold(base):
+		if req.scope != ldap.ScopeSingleLevel {
+			records = append(records, srv.getContainer(srv.usersDN, "ou"))
+		}
+		if req.scope != ldap.ScopeBaseObject {
+			records = append(records, srv.findUsers(sess, req.filter, "")...)
+		}
+	case srv.usersDN.AncestorOfFold(base) && len(base.RDNs) == len(srv.usersDN.RDNs)+1:
+		attr := base.RDNs[0].Attributes[0]
+		if len(base.RDNs[0].Attributes) == 1 && isUserAttribute(attr.Type) {
+			records = append(records, srv.findUsers(sess, req.filter, attr.Value)...)
+		}
+	}
+
+	if len(records) == 0 && !srv.baseDN.EqualFold(base) && !srv.usersDN.EqualFold(base) {
+		return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, srv.baseDN.String(), "")
+	}
+
+	return srv.sendRecords(sess, messageID, req, records)
+}
+
+// findUsers returns the user entries matching the filter. When the
+// identifier is not empty, the search is limited to the user with the
+// identifier.
+func (srv *Server) findUsers(sess *session, filter *ber.Packet, identifier string) []*record {
+	var identifiers []string
+	switch {
+	case !sess.principal.service:
+		identifiers = []string{sess.principal.name}
+	case identifier != "":
+		identifiers = []string{identifier}
+	default:
+		identifiers = getFilterIdentifiers(filter)
+	}
+
+	var records []*record
+	seen := make(map[string]bool)
+	for _, s := range identifiers {
+		e, err := srv.lookupUser(s)
+		if err != nil {
+			srv.logger.Debug(
+				"directory user lookup failed",
+				zap.String("name", srv.config.Name),
+				zap.String("identifier", s),
+				zap.Error(err),
+			)
+			continue
+		}
+		if seen[e.username] {
+			continue
+		}
+		if identifier != "" && !strings.EqualFold(identifier, e.username) && !strings.EqualFold(identifier, e.email) {
+			continue
+		}
+		if !sess.principal.service && e.username != sess.principal.name {
+			continue
+		}
+		seen[e.username] = true
+		records = append(records, srv.getUserRecord(e))
+	}
+	return records
+}
+
+func (srv *Server) getRootDSE() *record {
+	return &record{
+		attrs: []*attribute{
+			{name: "objectClass", values: []string{"top"}},
+			{name: "namingContexts", values: []string{srv.baseDN.String()}},
+			{name: "supportedLDAPVersion", values: []string{"3"}},
+			{name: "vendorName", values: []string{"AuthCrunch"}},
+		},
+	}
+}
+
+func (srv *Server) getContainer(dn *ldap.DN, attrType string) *record {
+	rec := &record{dn: dn.String()}
+	value := dn.RDNs[0].Attributes[0].Value
+	switch attrType {
+	case "dc":
+		rec.attrs = []*attribute{
+			{name: "objectClass", values: []string{"top", "domain"}},
+			{name: "dc", values: []string{value}},
+		}
+	default:
+		rec.attrs = []*attribute{
+			{name: "objectClass", values: []string{"top", "organizationalUnit"}},
+			{name: "ou", values: []string{value}},
+		}
+	}
+	return rec
+}
+
+func (srv *Server) getUserRecord(e *entry) *record {
+	rec := &record{
+		dn: newChildDN("uid", e.username, srv.usersDN).String(),
+		attrs: []*attribute{
+			{name: "objectClass", values: userObjectClasses},
+			{name: "uid", values: []string{e.username}},
+		},
+	}
+	name := e.name
+	if name == "" {
+		name = e.username
+	}
+	rec.attrs = append(rec.attrs, &attribute{name: "cn", values: []string{name}})
+	rec.attrs = append(rec.attrs, &attribute{name: "displayName", values: []string{name}})
+	if e.email != "" {
+		rec.attrs = append(rec.attrs, &attribute{name: "mail", values: []string{e.email}})
+	}
+	if len(e.roles) > 0 {
+		rolesDN, _ := ldap.ParseDN("ou=roles," + srv.baseDN.String())
+		var groups []string
+		for _, role := range e.roles {
+			groups = append(groups, newChildDN("cn", role, rolesDN).String())
+		}
+		rec.attrs = append(rec.attrs, &attribute{name: "memberOf", values: groups})
+	}
+	return rec
+}
+
+func (srv *Server) sendRecords(sess *session, messageID int64, req *searchRequest, records []*record) bool {
+	var count int64
+	for _, rec := range records {
+		if !matchFilter(req.filter, rec) {
+			continue
+		}
+		if req.sizeLimit > 0 && count >= req.sizeLimit {
+			return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, "", "")
+		}
+		if !srv.send(sess, messageID, encodeRecord(rec, req)) {
+			return false
+		}
+		count++
+	}
+	return srv.respond(sess, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, "", "")
+}
+
+func encodeRecord(rec *record, req *searchRequest) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, rec.dn, "Object Name"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, attr := range rec.attrs {
+		if !isAttributeRequested(attr.name, req.attributes) {
+			continue
+		}
+		item := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr.name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		if !req.typesOnly {
+			for _, v := range attr.values {
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+		}
+		item.AppendChild(values)
+		attrs.AppendChild(item)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+func parseSearchRequest(op *ber.Packet) (*searchRequest, bool) {
+	if len(op.Children) < 8 {
+		return nil, false
+	}
+	req := &searchRequest{}
+	var ok bool
+	if req.baseDN, ok = op.Children[0].Value.(string); !ok {
+		return nil, false
+	}
+	if req.scope, ok = op.Children[1].Value.(int64); !ok {
+		return nil, false
+	}
+	if req.sizeLimit, ok = op.Children[3].Value.(int64); !ok {
+		return nil, false
+	}
+	if req.typesOnly, ok = op.Children[5].Value.(bool); !ok {
+		return nil, false
+	}
+	req.filter = op.Children[6]
+	for _, child := range op.Children[7].Children {
+		if s, ok := child.Value.(string); ok {
+			req.attributes = append(req.attributes, s)
+		}
+	}
+	return req, true
+}
+
+// getFilterIdentifiers returns the values of the equality assertions on
+// the user identifying attributes, e.g. (uid=jsmith) or (mail=jsmith@localhost).
+func getFilterIdentifiers(filter *ber.Packet) []string {
+	var identifiers []string
+	if filter.ClassType != ber.ClassContext {
+		return nil
+	}
+	switch filter.Tag {
+	case ldap.FilterAnd, ldap.FilterOr:
+		for _, child := range filter.Children {
+			identifiers = append(identifiers, getFilterIdentifiers(child)...)
+		}
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) == 2 && isUserAttribute(getString(filter.Children[0])) {
+			identifiers = append(identifiers, getString(filter.Children[1]))
+		}
+	}
+	return identifiers
+}
+
+// matchFilter evaluates the filter against the record. The attribute names
+// and values are compared case-insensitively.
+func matchFilter(filter *ber.Packet, rec *record) bool {
+	if filter == nil || filter.ClassType != ber.ClassContext {
+		return false
+	}
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(child, rec) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(child, rec) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		if len(filter.Children) != 1 {
+			return false
+		}
+		return !matchFilter(filter.Children[0], rec)
+	case ldap.FilterPresent:
+		return rec.getValues(filter.Data.String()) != nil
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := strings.ToLower(getString(filter.Children[1]))
+		for _, v := range rec.getValues(getString(filter.Children[0])) {
+			v = strings.ToLower(v)
+			switch filter.Tag {
+			case ldap.FilterGreaterOrEqual:
+				if v >= want {
+					return true
+				}
+			case ldap.FilterLessOrEqual:
+				if v <= want {
+					return true
+				}
+			default:
+				if v == want {
+					return true
+				}
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, v := range rec.getValues(getString(filter.Children[0])) {
+			if matchSubstrings(strings.ToLower(v), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchSubstrings(v string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		s := strings.ToLower(part.Data.String())
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+			v = ""
+		}
+	}
+	return true
+}
+
+func (rec *record) getValues(name string) []string {
+	for _, attr := range rec.attrs {
+		if strings.EqualFold(attr.name, name) {
+			return attr.values
+		}
+	}
+	return nil
+}
+
+func isAttributeRequested(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, s := range attributes {
+		if s == "*" || strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func getString(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	return p.Data.String()
+}
+
+func newChildDN(attrType, value string, parent *ldap.DN) *ldap.DN {
+	rdn := &ldap.RelativeDN{
+		Attributes: []*ldap.AttributeTypeAndValue{{Type: attrType, Value: value}},
+	}
+	return &ldap.DN{RDNs: append([]*ldap.RelativeDN{rdn}, parent.RDNs...)}
+}
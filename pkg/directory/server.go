@@ -0,0 +1,228 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"go.uber.org/zap"
+)
+
+const (
+	idleTimeout    = 5 * time.Minute
+	maxPacketBytes = 1 << 20
+)
+
+// Server is an LDAP directory backed by an identity store. It supports the
+// simple bind of the users and the service accounts, and the searches for
+// the users.
+type Server struct {
+	config     *Config
+	store      ids.IdentityStore
+	logger     *zap.Logger
+	baseDN     *ldap.DN
+	usersDN    *ldap.DN
+	servicesDN *ldap.DN
+	accounts   map[string]*serviceAccount
+	tlsConfig  *tls.Config
+	listener   net.Listener
+	mu         sync.Mutex
+	conns      map[net.Conn]bool
+	wg         sync.WaitGroup
+}
+
+// session is the state of a client connection.
+type session struct {
+	conn    net.Conn
+	srcAddr string
+	// The bound principal. It is nil for anonymous sessions.
+	principal *principal
+}
+
+type principal struct {
+	name    string
+	service bool
+}
+
+// NewServer returns an instance of Server.
+func NewServer(cfg *Config, store ids.IdentityStore, logger *zap.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, errors.ErrDirectoryIdentityStoreNotFound.WithArgs(cfg.Name, cfg.IdentityStore)
+	}
+	if store.GetKind() != "local" {
+		return nil, errors.ErrDirectoryIdentityStoreUnsupported.WithArgs(cfg.Name, cfg.IdentityStore)
+	}
+
+	srv := &Server{
+		config:   cfg,
+		store:    store,
+		logger:   logger,
+		accounts: make(map[string]*serviceAccount),
+		conns:    make(map[net.Conn]bool),
+	}
+	srv.baseDN, _ = ldap.ParseDN(cfg.BaseDN)
+	srv.usersDN, _ = ldap.ParseDN("ou=users," + cfg.BaseDN)
+	srv.servicesDN, _ = ldap.ParseDN("ou=services," + cfg.BaseDN)
+
+	for _, account := range cfg.ServiceAccounts {
+		srv.accounts[strings.ToLower(account.Name)] = newServiceAccount(account)
+	}
+
+	if cfg.TLSCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, errors.ErrDirectoryConfigTLS.WithArgs(cfg.Name, err)
+		}
+		srv.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	return srv, nil
+}
+
+// GetName returns the name of the directory.
+func (srv *Server) GetName() string {
+	return srv.config.Name
+}
+
+// GetAddress returns the address the directory listens on.
+func (srv *Server) GetAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listener != nil {
+		return srv.listener.Addr().String()
+	}
+	return srv.config.Address
+}
+
+// Start starts accepting the connections of the LDAP clients.
+func (srv *Server) Start() error {
+	var listener net.Listener
+	var err error
+	if srv.tlsConfig != nil {
+		listener, err = tls.Listen("tcp", srv.config.Address, srv.tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", srv.config.Address)
+	}
+	if err != nil {
+		return errors.ErrDirectoryStartFailed.WithArgs(srv.config.Name, err)
+	}
+
+	srv.mu.Lock()
+	srv.listener = listener
+	srv.mu.Unlock()
+
+	srv.logger.Info(
+		"started directory",
+		zap.String("name", srv.config.Name),
+		zap.String("address", listener.Addr().String()),
+		zap.String("base_dn", srv.config.BaseDN),
+		zap.String("identity_store", srv.config.IdentityStore),
+		zap.Bool("tls", srv.tlsConfig != nil),
+	)
+
+	srv.wg.Add(1)
+	go srv.serve(listener)
+	return nil
+}
+
+// Stop stops the directory and closes the client connections.
+func (srv *Server) Stop() error {
+	srv.mu.Lock()
+	listener := srv.listener
+	srv.listener = nil
+	for conn := range srv.conns {
+		conn.Close()
+	}
+	srv.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	err := listener.Close()
+	srv.wg.Wait()
+	return err
+}
+
+func (srv *Server) serve(listener net.Listener) {
+	defer srv.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		srv.mu.Lock()
+		srv.conns[conn] = true
+		srv.mu.Unlock()
+
+		srv.wg.Add(1)
+		go srv.handleConn(conn)
+	}
+}
+
+func (srv *Server) handleConn(conn net.Conn) {
+	defer srv.wg.Done()
+	defer func() {
+		conn.Close()
+		srv.mu.Lock()
+		delete(srv.conns, conn)
+		srv.mu.Unlock()
+	}()
+
+	sess := &session{
+		conn:    conn,
+		srcAddr: getSourceAddress(conn),
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		packet, err := readPacket(conn)
+		if err != nil {
+			return
+		}
+		if !srv.handlePacket(sess, packet) {
+			return
+		}
+	}
+}
+
+// readPacket reads an LDAP message. The size of the message is limited.
+func readPacket(conn net.Conn) (*ber.Packet, error) {
+	return ber.ReadPacket(io.LimitReader(conn, maxPacketBytes))
+}
+
+func getSourceAddress(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
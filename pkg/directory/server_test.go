@@ -0,0 +1,298 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+var errInvalidCredentials = fmt.Errorf(`LDAP Result Code 49 "Invalid Credentials": invalid credentials`)
+
+func newTestIdentityStore(t *testing.T) ids.IdentityStore {
+	db, err := testutils.CreateTestDatabase("TestDirectoryServer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	store, err := ids.NewIdentityStore(&ids.IdentityStoreConfig{
+		Name: "localdb",
+		Kind: "local",
+		Params: map[string]interface{}{
+			"path":  db.GetPath(),
+			"realm": "local",
+		},
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestDirectoryBind(t *testing.T) {
+	store := newTestIdentityStore(t)
+	testcases := []struct {
+		name          string
+		requiredRoles []string
+		lockout       bool
+		bindDN        string
+		password      string
+		shouldErr     bool
+	}{
+		{
+			name:     "test user bind with uid",
+			bindDN:   "uid=" + tests.TestUser1 + ",ou=users,dc=authp,dc=local",
+			password: tests.TestPwd1,
+		},
+		{
+			name:     "test user bind with mail",
+			bindDN:   "mail=" + tests.TestEmail1 + ",ou=users,dc=authp,dc=local",
+			password: tests.TestPwd1,
+		},
+		{
+			name:     "test user bind with username",
+			bindDN:   tests.TestUser2,
+			password: tests.TestPwd2,
+		},
+		{
+			name:     "test service account bind",
+			bindDN:   "cn=wiki,ou=services,dc=authp,dc=local",
+			password: "foobar",
+		},
+		{
+			name:      "test user bind with wrong password",
+			bindDN:    "uid=" + tests.TestUser1 + ",ou=users,dc=authp,dc=local",
+			password:  tests.TestPwd2,
+			shouldErr: true,
+		},
+		{
+			name:      "test bind of unknown user",
+			bindDN:    "uid=foobar,ou=users,dc=authp,dc=local",
+			password:  tests.TestPwd1,
+			shouldErr: true,
+		},
+		{
+			name:      "test bind outside of directory",
+			bindDN:    "uid=" + tests.TestUser1 + ",ou=people,dc=example,dc=com",
+			password:  tests.TestPwd1,
+			shouldErr: true,
+		},
+		{
+			name:      "test service account bind with wrong password",
+			bindDN:    "cn=wiki,ou=services,dc=authp,dc=local",
+			password:  "barfoo",
+			shouldErr: true,
+		},
+		{
+			name:          "test user bind without required roles",
+			requiredRoles: []string{"authp/admin"},
+			bindDN:        tests.TestUser2,
+			password:      tests.TestPwd2,
+			shouldErr:     true,
+		},
+		{
+			name:          "test user bind with required roles",
+			requiredRoles: []string{"authp/admin"},
+			bindDN:        tests.TestUser1,
+			password:      tests.TestPwd1,
+		},
+		{
+			name:      "test bind of locked user",
+			lockout:   true,
+			bindDN:    tests.TestUser2,
+			password:  tests.TestPwd2,
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			srv := newTestServer(t, store, tc.requiredRoles)
+			defer srv.Stop()
+			if tc.lockout {
+				lockUser(t, store, tests.TestUser2, tests.TestEmail2, true)
+				defer lockUser(t, store, tests.TestUser2, tests.TestEmail2, false)
+			}
+
+			conn, err := ldap.DialURL("ldap://" + srv.GetAddress())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			err = conn.Bind(tc.bindDN, tc.password)
+			if tests.EvalErrWithLog(t, err, "Bind", tc.shouldErr, errInvalidCredentials, msgs) {
+				return
+			}
+		})
+	}
+}
+
+func TestDirectorySearch(t *testing.T) {
+	store := newTestIdentityStore(t)
+	srv := newTestServer(t, store, nil)
+	defer srv.Stop()
+
+	testcases := []struct {
+		name      string
+		bindDN    string
+		password  string
+		baseDN    string
+		filter    string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test service account search by uid",
+			bindDN:   "cn=wiki,ou=services,dc=authp,dc=local",
+			password: "foobar",
+			baseDN:   "ou=users,dc=authp,dc=local",
+			filter:   "(&(objectClass=inetOrgPerson)(uid=" + tests.TestUser1 + "))",
+			want: map[string]interface{}{
+				"uid=jsmith,ou=users,dc=authp,dc=local": map[string][]string{
+					"objectClass": userObjectClasses,
+					"uid":         {tests.TestUser1},
+					"cn":          {tests.TestFullName1},
+					"displayName": {tests.TestFullName1},
+					"mail":        {tests.TestEmail1},
+					"memberOf": {
+						"cn=viewer,ou=roles,dc=authp,dc=local",
+						"cn=editor,ou=roles,dc=authp,dc=local",
+						"cn=admin,ou=roles,dc=authp,dc=local",
+						"cn=authp/admin,ou=roles,dc=authp,dc=local",
+					},
+				},
+			},
+		},
+		{
+			name:     "test service account search by mail substring",
+			bindDN:   "cn=wiki,ou=services,dc=authp,dc=local",
+			password: "foobar",
+			baseDN:   "dc=authp,dc=local",
+			filter:   "(|(mail=" + tests.TestEmail2 + ")(&(uid=" + tests.TestUser1 + ")(mail=nobody*)))",
+			want: map[string]interface{}{
+				"uid=bjones,ou=users,dc=authp,dc=local": map[string][]string{
+					"objectClass": userObjectClasses,
+					"uid":         {tests.TestUser2},
+					"cn":          {tests.TestUser2},
+					"displayName": {tests.TestUser2},
+					"mail":        {tests.TestEmail2},
+					"memberOf":    {"cn=viewer,ou=roles,dc=authp,dc=local"},
+				},
+			},
+		},
+		{
+			name:     "test user search of other user",
+			bindDN:   tests.TestUser2,
+			password: tests.TestPwd2,
+			baseDN:   "ou=users,dc=authp,dc=local",
+			filter:   "(uid=" + tests.TestUser1 + ")",
+			want:     map[string]interface{}{},
+		},
+		{
+			name:      "test anonymous search",
+			baseDN:    "ou=users,dc=authp,dc=local",
+			filter:    "(uid=" + tests.TestUser1 + ")",
+			shouldErr: true,
+			err:       fmt.Errorf(`LDAP Result Code 50 "Insufficient Access Rights": bind required`),
+		},
+		{
+			name:      "test search outside of directory",
+			bindDN:    "cn=wiki,ou=services,dc=authp,dc=local",
+			password:  "foobar",
+			baseDN:    "dc=example,dc=com",
+			filter:    "(objectClass=*)",
+			shouldErr: true,
+			err:       fmt.Errorf(`LDAP Result Code 32 "No Such Object": `),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			conn, err := ldap.DialURL("ldap://" + srv.GetAddress())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if tc.bindDN != "" {
+				if err := conn.Bind(tc.bindDN, tc.password); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resp, err := conn.Search(ldap.NewSearchRequest(
+				tc.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+				tc.filter, nil, nil,
+			))
+			if tests.EvalErrWithLog(t, err, "Search", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			got := make(map[string]interface{})
+			for _, entry := range resp.Entries {
+				attrs := make(map[string][]string)
+				for _, attr := range entry.Attributes {
+					attrs[attr.Name] = attr.Values
+				}
+				got[entry.DN] = attrs
+			}
+			tests.EvalObjectsWithLog(t, "Search", tc.want, got, msgs)
+		})
+	}
+}
+
+func newTestServer(t *testing.T, store ids.IdentityStore, requiredRoles []string) *Server {
+	srv, err := NewServer(&Config{
+		Name:          "corp",
+		Address:       "127.0.0.1:0",
+		BaseDN:        "dc=authp,dc=local",
+		IdentityStore: "localdb",
+		RequiredRoles: requiredRoles,
+		ServiceAccounts: []*ServiceAccountConfig{
+			{Name: "wiki", Password: "foobar"},
+		},
+	}, store, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func lockUser(t *testing.T, store ids.IdentityStore, username, email string, enabled bool) {
+	rr := requests.NewRequest()
+	rr.User.Username = username
+	rr.User.Email = email
+	if err := store.Request(operator.GetUser, rr); err != nil {
+		t.Fatal(err)
+	}
+	usr := rr.Response.Payload.(*identity.User)
+	usr.Lockout = &identity.LockoutState{Enabled: enabled}
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultTTL         = 300
+	defaultNegativeTTL = 30
+	defaultJitter      = 10
+	defaultMaxEntries  = 10000
+)
+
+// Config holds the configuration of the cache of the identity enrichment
+// lookups, e.g. the LDAP group searches or the group and organization
+// lookups of OAuth providers.
+type Config struct {
+	// The number of seconds the outcome of a successful lookup remains
+	// cached. Defaults to 300.
+	TTL int `json:"ttl,omitempty" xml:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// The number of seconds the failure of a lookup remains cached, i.e.
+	// the upstream is not queried again for the user. Defaults to 30.
+	NegativeTTL int `json:"negative_ttl,omitempty" xml:"negative_ttl,omitempty" yaml:"negative_ttl,omitempty"`
+	// The percentage by which the lifetime of an entry is randomly
+	// shortened, so that the entries cached together do not expire
+	// together. Defaults to 10.
+	Jitter int `json:"jitter,omitempty" xml:"jitter,omitempty" yaml:"jitter,omitempty"`
+	// The maximum number of cached users. Defaults to 10000.
+	MaxEntries int `json:"max_entries,omitempty" xml:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+}
+
+// Cache holds the outcomes of the identity enrichment lookups per user.
+type Cache struct {
+	config  *Config
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	keys      []string
+	values    []string
+	err       error
+	expiresAt time.Time
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.TTL < 0 {
+		return errors.ErrEnrichmentConfigTTL.WithArgs(cfg.TTL)
+	}
+	if cfg.NegativeTTL < 0 {
+		return errors.ErrEnrichmentConfigNegativeTTL.WithArgs(cfg.NegativeTTL)
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 100 {
+		return errors.ErrEnrichmentConfigJitter.WithArgs(cfg.Jitter)
+	}
+	if cfg.MaxEntries < 0 {
+		return errors.ErrEnrichmentConfigMaxEntries.WithArgs(cfg.MaxEntries)
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.NegativeTTL == 0 {
+		cfg.NegativeTTL = defaultNegativeTTL
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = defaultJitter
+	}
+	if cfg.MaxEntries == 0 {
+		cfg.MaxEntries = defaultMaxEntries
+	}
+	return nil
+}
+
+// NewCache returns an instance of Cache.
+func NewCache(cfg *Config) (*Cache, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		config:  cfg,
+		entries: make(map[string]*entry),
+	}
+	return c, nil
+}
+
+// Lookup returns the cached outcome of the lookup for the user. When
+// there is none, it calls the lookup function and caches its outcome,
+// including the error. The entry is stored under the first key, e.g. the
+// user DN, and is invalidated by any of the keys, e.g. the username or
+// the email address.
+func (c *Cache) Lookup(keys []string, fn func() ([]string, error)) ([]string, error) {
+	return c.lookup(time.Now(), keys, fn)
+}
+
+func (c *Cache) lookup(now time.Time, keys []string, fn func() ([]string, error)) ([]string, error) {
+	if len(keys) == 0 || keys[0] == "" {
+		return fn()
+	}
+	key := strings.ToLower(keys[0])
+
+	c.mu.Lock()
+	if e, exists := c.entries[key]; exists && now.Before(e.expiresAt) {
+		c.mu.Unlock()
+		return copyValues(e.values), e.err
+	}
+	c.mu.Unlock()
+
+	values, err := fn()
+	ttl := c.config.TTL
+	if err != nil {
+		ttl = c.config.NegativeTTL
+	}
+	lifetime := time.Duration(ttl) * time.Second
+	lifetime -= time.Duration(rand.Int63n(int64(lifetime)*int64(c.config.Jitter)/100 + 1))
+
+	e := &entry{
+		values:    copyValues(values),
+		err:       err,
+		expiresAt: now.Add(lifetime),
+	}
+	for _, k := range keys {
+		if k != "" {
+			e.keys = append(e.keys, strings.ToLower(k))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.config.MaxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = e
+	return values, err
+}
+
+// evict deletes the expired entries. When none expired, it deletes an
+// arbitrary entry.
+func (c *Cache) evict(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) < c.config.MaxEntries {
+		return
+	}
+	for k := range c.entries {
+		delete(c.entries, k)
+		return
+	}
+}
+
+// Invalidate deletes the entries of the user with any of the keys matching
+// the provided one. When the provided key is empty, it deletes all
+// entries. It returns the number of the deleted entries.
+func (c *Cache) Invalidate(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key == "" {
+		count := len(c.entries)
+		c.entries = make(map[string]*entry)
+		return count
+	}
+	key = strings.ToLower(key)
+	var count int
+	for k, e := range c.entries {
+		for _, s := range e.keys {
+			if s == key {
+				delete(c.entries, k)
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+func copyValues(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "default config",
+			config: &Config{},
+			want: &Config{
+				TTL:         defaultTTL,
+				NegativeTTL: defaultNegativeTTL,
+				Jitter:      defaultJitter,
+				MaxEntries:  defaultMaxEntries,
+			},
+		},
+		{
+			name:      "negative ttl",
+			config:    &Config{TTL: -1},
+			shouldErr: true,
+			err:       errors.ErrEnrichmentConfigTTL.WithArgs(-1),
+		},
+		{
+			name:      "negative negative ttl",
+			config:    &Config{NegativeTTL: -1},
+			shouldErr: true,
+			err:       errors.ErrEnrichmentConfigNegativeTTL.WithArgs(-1),
+		},
+		{
+			name:      "jitter out of range",
+			config:    &Config{Jitter: 101},
+			shouldErr: true,
+			err:       errors.ErrEnrichmentConfigJitter.WithArgs(101),
+		},
+		{
+			name:      "negative max entries",
+			config:    &Config{MaxEntries: -1},
+			shouldErr: true,
+			err:       errors.ErrEnrichmentConfigMaxEntries.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "enrichment config", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "config", tc.want, tc.config, msgs)
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	c, err := NewCache(&Config{TTL: 60, NegativeTTL: 10, Jitter: 50, MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var calls int
+	lookup := func() ([]string, error) {
+		calls++
+		return []string{"cn=admins,dc=contoso,dc=com"}, nil
+	}
+	keys := []string{"uid=jsmith,dc=contoso,dc=com", "jsmith", "jsmith@contoso.com"}
+	now := time.Now()
+
+	got, _ := c.lookup(now, keys, lookup)
+	tests.EvalObjects(t, "groups", []string{"cn=admins,dc=contoso,dc=com"}, got)
+	c.lookup(now.Add(29*time.Second), keys, lookup)
+	tests.EvalObjects(t, "cached lookup calls", 1, calls)
+	c.lookup(now.Add(61*time.Second), keys, lookup)
+	tests.EvalObjects(t, "expired lookup calls", 2, calls)
+
+	// The failures are cached for the negative ttl.
+	failure := fmt.Errorf("no groups found")
+	failed := func() ([]string, error) {
+		calls++
+		return nil, failure
+	}
+	_, err = c.lookup(now, []string{"uid=bjones,dc=contoso,dc=com"}, failed)
+	tests.EvalErrWithLog(t, err, "lookup", true, failure, nil)
+	_, err = c.lookup(now.Add(4*time.Second), []string{"uid=bjones,dc=contoso,dc=com"}, lookup)
+	tests.EvalErrWithLog(t, err, "cached failure", true, failure, nil)
+	tests.EvalObjects(t, "negative lookup calls", 3, calls)
+	c.lookup(now.Add(11*time.Second), []string{"uid=bjones,dc=contoso,dc=com"}, lookup)
+	tests.EvalObjects(t, "expired negative lookup calls", 4, calls)
+
+	// The entries are invalidated by any of their keys.
+	tests.EvalObjects(t, "invalidated by alias", 1, c.Invalidate("JSmith@contoso.com"))
+	tests.EvalObjects(t, "invalidated absent", 0, c.Invalidate("jsmith"))
+	tests.EvalObjects(t, "invalidated all", 1, c.Invalidate(""))
+
+	// The number of entries is bounded.
+	for _, k := range []string{"a", "b", "c"} {
+		c.lookup(now, []string{k}, lookup)
+	}
+	tests.EvalObjects(t, "entry count", 2, len(c.entries))
+}
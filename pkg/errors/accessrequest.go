@@ -0,0 +1,39 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Access request errors.
+const (
+	ErrAccessRequestConfigEmailProvider  StandardError = "access request: email provider is not set"
+	ErrAccessRequestConfigAppsNotFound   StandardError = "access request: no apps configured"
+	ErrAccessRequestConfigAppName        StandardError = "access request: app name must not be empty"
+	ErrAccessRequestConfigAppDuplicate   StandardError = "access request: app %q is duplicate"
+	ErrAccessRequestConfigAppRoles       StandardError = "access request: app %q has no roles"
+	ErrAccessRequestConfigAppAdmins      StandardError = "access request: app %q has no admin emails"
+	ErrAccessRequestConfigMaxLifetime    StandardError = "access request: app %q max lifetime must not be negative, got %d"
+	ErrAccessRequestConfigExpiryInterval StandardError = "access request: expiry interval must not be negative, got %d"
+
+	ErrAccessRequestAppNotFound      StandardError = "access request: app %q not found"
+	ErrAccessRequestRoleNotPermitted StandardError = "access request: role %q is not requestable for app %q"
+	ErrAccessRequestLifetime         StandardError = "access request: lifetime must not be negative, got %d"
+	ErrAccessRequestLifetimeExceeded StandardError = "access request: lifetime %d exceeds the maximum of %d seconds for app %q"
+	ErrAccessRequestDuplicate        StandardError = "access request: role %q is already requested or granted"
+	ErrAccessRequestNotFound         StandardError = "access request: request not found"
+	ErrAccessRequestStatus           StandardError = "access request: request is %s"
+	ErrAccessRequestVerdict          StandardError = "access request: verdict %q is unsupported"
+	ErrAccessRequestIDGenerate       StandardError = "access request: failed generating request id: %v"
+	ErrAccessRequestStoreLoad        StandardError = "access request: failed loading requests from %q: %v"
+	ErrAccessRequestStoreSave        StandardError = "access request: failed saving requests to %q: %v"
+)
@@ -19,6 +19,7 @@ const (
 	ErrAccessListRuleConfig                     StandardError = "acl rule configuration error: %v: %v"
 	ErrAccessListRuleConditionConfig            StandardError = "acl rule condition configuration error: %v: %v"
 	ErrAccessListNoRules                        StandardError = "acl has no rules"
+	ErrAccessListExtension                      StandardError = "acl rule %d condition references unavailable extension: %v"
 	ErrACLRuleConditionSyntaxMatchNotFound      StandardError = "invalid condition syntax, matcher not found: %v"
 	ErrACLRuleConditionSyntaxMatchFieldNotFound StandardError = "invalid condition syntax, matcher field not found: %v"
 	ErrACLRuleConditionSyntaxMatchValueNotFound StandardError = "invalid condition syntax, matcher values not found: %v"
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Admission control errors.
+const (
+	ErrAdmissionConfigMaxConcurrent StandardError = "admission: max concurrent requests must be greater than zero, got %d"
+	ErrAdmissionConfigMaxQueued     StandardError = "admission: max queued requests must not be negative, got %d"
+	ErrAdmissionConfigQueueTimeout  StandardError = "admission: queue timeout must not be negative, got %d"
+	ErrAdmissionConfigRetryAfter    StandardError = "admission: retry after must not be negative, got %d"
+	ErrAdmissionQueueFull           StandardError = "admission: queue is full"
+	ErrAdmissionQueueTimeout        StandardError = "admission: timed out waiting in queue"
+	ErrAdmissionCanceled            StandardError = "admission: request canceled while waiting in queue"
+)
@@ -20,8 +20,13 @@ const (
 	ErrAPIKeyUsageEmpty       StandardError = "api key usage type is empty"
 	ErrAPIKeyCommentEmpty     StandardError = "api key comment is empty"
 	ErrAPIKeyUsageUnsupported StandardError = "api key usage type %q is unsupported"
+	ErrAPIKeyExpiresAtPast    StandardError = "api key expiration time is in the past"
+	ErrAPIKeyRoleNotAssigned  StandardError = "api key role %q is not assigned to the user"
+	ErrAPIKeyRotated          StandardError = "api key has already been rotated"
+	ErrRotateAPIKey           StandardError = "failed rotating %q key: %v"
 
 	ErrLookupAPIKeyPayloadEmpty     StandardError = "api key payload is empty"
 	ErrLookupAPIKeyFailed           StandardError = "api key lookup failed"
 	ErrLookupAPIKeyMalformedPayload StandardError = "api key payload is malformed"
+	ErrLookupAPIKeyExpired          StandardError = "api key has expired"
 )
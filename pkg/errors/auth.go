@@ -20,6 +20,8 @@ const (
 	ErrUserInterfaceThemeNotFound            StandardError = "user interface validation for %s portal failed: %s theme not found"
 	ErrUserInterfaceBuiltinTemplateAddFailed StandardError = "user interface validation for %s portal failed for built-in template %s in %s theme: %v"
 	ErrUserInterfaceCustomTemplateAddFailed  StandardError = "user interface validation for %s portal failed for custom template %s in %s: %v"
+	ErrUserInterfaceBrandingAddFailed        StandardError = "user interface validation for %s portal failed for %q realm branding: %v"
+	ErrUserInterfaceTemplatesDirAddFailed    StandardError = "user interface validation for %s portal failed for templates directory %s: %v"
 
 	ErrCryptoKeyStoreConfig StandardError = "crypto key store configuration for %q instance failed: %v"
 	ErrGeneric              StandardError = "%s: %v"
@@ -0,0 +1,31 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Break-glass account errors.
+const (
+	ErrBreakGlassConfigUsernamesNotFound StandardError = "break glass: no accounts configured"
+	ErrBreakGlassConfigUsername          StandardError = "break glass: username must not be empty"
+	ErrBreakGlassConfigMaxAttempts       StandardError = "break glass: max attempts must not be negative, got %d"
+	ErrBreakGlassConfigInterval          StandardError = "break glass: interval must not be negative, got %d"
+	ErrBreakGlassConfigNotifyEmails      StandardError = "break glass: email provider %q has no notification recipients"
+	ErrBreakGlassConfigRealmNotFound     StandardError = "break glass: local identity store for realm %q not found"
+	ErrBreakGlassConfigAAGUIDsNotFound   StandardError = "break glass: no hardware security key aaguids configured"
+	ErrBreakGlassConfigAAGUID            StandardError = "break glass: %v"
+
+	ErrBreakGlassLoginMethod StandardError = "break glass account %q must sign in with a hardware security key"
+	ErrBreakGlassHardwareKey StandardError = "break glass account %q has no hardware security key"
+	ErrBreakGlassRateLimited StandardError = "break glass account %q exceeded login attempts, retry in %d seconds"
+)
@@ -0,0 +1,32 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// CAPTCHA errors.
+const (
+	ErrCaptchaConfigProviderInvalid StandardError = "captcha: provider %q is unsupported"
+	ErrCaptchaConfigSiteKeyEmpty    StandardError = "captcha: %s site key is empty"
+	ErrCaptchaConfigSecretEmpty     StandardError = "captcha: %s secret is empty"
+	ErrCaptchaConfigDifficulty      StandardError = "captcha: proof-of-work difficulty must be between 1 and %d, got %d"
+	ErrCaptchaConfigMinScore        StandardError = "captcha: min score must be between 0 and 1, got %v"
+	ErrCaptchaResponseEmpty         StandardError = "captcha: response is empty"
+	ErrCaptchaVerification          StandardError = "captcha: %s verification failed: %v"
+	ErrCaptchaRejected              StandardError = "captcha: %s rejected the response: %v"
+	ErrCaptchaScoreTooLow           StandardError = "captcha: %s score %v is below %v"
+	ErrCaptchaPuzzleInvalid         StandardError = "captcha: proof-of-work puzzle is invalid"
+	ErrCaptchaPuzzleExpired         StandardError = "captcha: proof-of-work puzzle has expired"
+	ErrCaptchaPuzzleReused          StandardError = "captcha: proof-of-work puzzle has already been used"
+	ErrCaptchaSolutionInvalid       StandardError = "captcha: proof-of-work solution is invalid"
+)
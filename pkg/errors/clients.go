@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Service client errors.
+const (
+	ErrClientConfigIDEmpty       StandardError = "clients: client id must not be empty"
+	ErrClientConfigIDDuplicate   StandardError = "clients: duplicate client id %q"
+	ErrClientConfigCredentials   StandardError = "clients: client %q has neither secret nor certificate subject"
+	ErrClientConfigTokenLifetime StandardError = "clients: client %q token lifetime must not be negative, got %d"
+	ErrClientAuthFailed          StandardError = "clients: client authentication failed"
+	ErrClientDisabled            StandardError = "clients: client %q is disabled"
+	ErrClientScopeNotAllowed     StandardError = "clients: scope %q is not allowed for client %q"
+)
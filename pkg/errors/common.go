@@ -85,6 +85,9 @@ const (
 	ErrAccessNotAllowedByPathACL          StandardError = "user role is valid, but not allowed by path access list"
 	ErrSourceAddressNotFound              StandardError = "source ip validation is enabled, but no ip address claim found"
 	ErrSourceAddressMismatch              StandardError = "source ip address mismatch between the claim %q and request %q"
+	ErrAudienceNotFound                   StandardError = "audience validation is enabled, but no audience claim found"
+	ErrAudienceMismatch                   StandardError = "audience claim %v does not include request host %q"
+	ErrRelyingPartyToken                  StandardError = "access token issued to relying party is not accepted"
 	ErrNoParsedClaims                     StandardError = "failed to extract claims"
 	ErrNoTokenFound                       StandardError = "no token found"
 	ErrInvalidParsedClaims                StandardError = "failed to extract claims: %s"
@@ -0,0 +1,30 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Login flow continuation errors.
+const (
+	ErrContinuationConfigKey      StandardError = "continuation config: encryption key must be at least %d characters long"
+	ErrContinuationConfigLifetime StandardError = "continuation config: lifetime must not be negative, got %d"
+	ErrContinuationConfigStore    StandardError = "continuation config: token store must not be empty"
+	ErrContinuationStore          StandardError = "continuation: token store %q failed: %v"
+	ErrContinuationRedeemed       StandardError = "continuation: token is redeemed or superseded"
+	ErrContinuationEncode         StandardError = "continuation: failed to encode token: %v"
+	ErrContinuationNotFound       StandardError = "continuation: token not found"
+	ErrContinuationMalformed      StandardError = "continuation: token is malformed"
+	ErrContinuationDecrypt        StandardError = "continuation: failed to decrypt token"
+	ErrContinuationExpired        StandardError = "continuation: token expired"
+	ErrContinuationFlowMismatch   StandardError = "continuation: token belongs to flow %q"
+)
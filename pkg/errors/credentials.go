@@ -18,4 +18,14 @@ package errors
 const (
 	ErrCredAddConfigType StandardError = "credential config %T is unsupported"
 	ErrCredKeyValueEmpty StandardError = "credential config %q key is empty"
+
+	ErrCredSecretReferenceInvalid StandardError = "secret reference %q is invalid, expected {env.<name>}, {file:<path>}, {exec:<command>}, {vault:<path>#<field>}, {aws_sm:<secret>#<field>}, or {gcp_sm:<secret>#<field>}"
+	ErrCredSecretEnvNotFound      StandardError = "secret reference %q: environment variable %q is not set"
+	ErrCredSecretFileRead         StandardError = "secret reference %q: failed reading file: %v"
+	ErrCredSecretExec             StandardError = "secret reference %q: command failed: %v"
+	ErrCredSecretEmpty            StandardError = "secret reference %q resolved to empty value"
+	ErrCredSecretFieldNotFound    StandardError = "secret reference %q: field %q not found"
+	ErrCredSecretRegionNotFound   StandardError = "secret reference %q: aws region not found"
+	ErrCredSecretProviderAuth     StandardError = "secret reference %q: %s credentials not found: %v"
+	ErrCredSecretProviderRequest  StandardError = "secret reference %q: %s request failed: %v"
 )
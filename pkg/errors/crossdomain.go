@@ -0,0 +1,24 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Cross-domain single sign-on errors.
+const (
+	ErrCrossDomainConfigDomainEmpty    StandardError = "cross-domain sso: parent domain is empty"
+	ErrCrossDomainConfigDomain         StandardError = "cross-domain sso: parent domain %q is invalid"
+	ErrCrossDomainConfigAudiencesEmpty StandardError = "cross-domain sso: no audiences found"
+	ErrCrossDomainConfigAudienceHost   StandardError = "cross-domain sso: audience %d host %q is not in the parent domain %q"
+	ErrCrossDomainTokenEncryption      StandardError = "cross-domain sso: the tokens must be encrypted, configure the token encryption key of the signing key"
+)
@@ -22,8 +22,14 @@ const (
 	ErrNewDatabaseDuplicateUserID StandardError = "failed initializing database: found duplicate user id %s %v"
 	ErrNewDatabaseDuplicateEmail  StandardError = "failed initializing database: found duplicate email address %s, %v"
 	ErrNewDatabaseDuplicateAPIKey StandardError = "failed initializing database: found duplicate api key %s, %v"
+	ErrNewDatabaseDuplicateGroup  StandardError = "failed initializing database: found duplicate group %s"
+
+	ErrNewDatabaseDuplicateLinkedIdentity StandardError = "failed initializing database: found duplicate linked identity %s of realm %s, %v"
 
 	ErrDatabaseCommit       StandardError = "failed database commit to %q: %v"
+	ErrDatabaseLock         StandardError = "failed locking database file %q: %v"
+	ErrDatabaseRollback     StandardError = "failed database rollback to revision %d: %v"
+	ErrDatabaseNoBackup     StandardError = "database backup of revision %d not found"
 	ErrDatabaseOperation    StandardError = "database operation failed: %v"
 	ErrDatabaseInvalidUser  StandardError = "username and email point to a different identity in the database"
 	ErrDatabaseUserNotFound StandardError = "user not found"
@@ -40,16 +46,36 @@ const (
 
 	ErrChangeUserPassword   StandardError = "failed change user password: %v"
 	ErrUpdateUserPassword   StandardError = "failed updating user password: %v"
+	ErrChangeUserEmail      StandardError = "failed changing user email address: %v"
 	ErrUserPasswordNotFound StandardError = "user password not set"
 	ErrUserPasswordInvalid  StandardError = "user password is invalid"
 
 	ErrUserPolicyCompliance     StandardError = "username policy compliance check failed"
 	ErrPasswordPolicyCompliance StandardError = "user password policy compliance check failed"
 
-	ErrAddUser    StandardError = "failed adding user %q: %v"
-	ErrDeleteUser StandardError = "failed deleting user %q: %v"
-	ErrGetUsers   StandardError = "failed retrieving users: %v"
-	ErrGetUser    StandardError = "failed retrieving user %q: %v"
+	ErrAddUser         StandardError = "failed adding user %q: %v"
+	ErrDeleteUser      StandardError = "failed deleting user %q: %v"
+	ErrGetUsers        StandardError = "failed retrieving users: %v"
+	ErrGetUser         StandardError = "failed retrieving user %q: %v"
+	ErrImportUser      StandardError = "failed importing user %q: %v"
+	ErrUpdateUserRoles StandardError = "failed updating roles of user %q: %v"
+	ErrUpdateUserOrgs  StandardError = "failed updating organizations of user %q: %v"
+	ErrResetMfaTokens  StandardError = "failed resetting MFA tokens of user %q: %v"
+	ErrLastAdminUser   StandardError = "the last administrator cannot be deleted or demoted"
+
+	ErrAddGroup            StandardError = "failed adding group %q: %v"
+	ErrUpdateGroup         StandardError = "failed updating group %q: %v"
+	ErrDeleteGroup         StandardError = "failed deleting group %q: %v"
+	ErrAddGroupMember      StandardError = "failed adding user %q to group %q: %v"
+	ErrDeleteGroupMember   StandardError = "failed removing user %q from group %q: %v"
+	ErrGroupNotFound       StandardError = "group %q not found"
+	ErrGroupExists         StandardError = "group %q already exists"
+	ErrGroupMemberNotFound StandardError = "user %q is not a member of group %q"
+	ErrGroupNameInvalid    StandardError = "invalid group name: %q"
+
+	ErrRegistrationNotFound StandardError = "registration %q not found"
+	ErrRegistrationReviewed StandardError = "registration %q has already been reviewed"
+	ErrRegistrationVerdict  StandardError = "failed recording verdict for registration %q: %v"
 
 	ErrPasswordEmpty                StandardError = "empty password"
 	ErrPasswordEmptyAlgorithm       StandardError = "empty password hash algorithm"
@@ -62,9 +88,46 @@ const (
 
 	ErrEmailAddressInvalid StandardError = "invalid email address"
 	ErrRoleEmpty           StandardError = "role name is empty"
+	ErrOrganizationInvalid StandardError = "invalid organization name: %q"
 
 	ErrParseNameFailed StandardError = "failed to parse name: %s"
 
+	ErrUpdateUserProfile StandardError = "failed updating user profile: %v"
+	ErrAddUserConsent    StandardError = "failed adding user consent for %q: %v"
+	ErrDeleteUserConsent StandardError = "failed withdrawing user consent %q: %v"
+	ErrConsentNotFound   StandardError = "consent %q not found"
+
+	ErrAddUserLinkedIdentity      StandardError = "failed linking identity %q of realm %q: %v"
+	ErrDeleteUserLinkedIdentity   StandardError = "failed unlinking identity %q: %v"
+	ErrLinkedIdentityInvalid      StandardError = "linked identity realm or subject is empty"
+	ErrLinkedIdentityExists       StandardError = "identity %q of realm %q is already linked"
+	ErrLinkedIdentityNotFound     StandardError = "linked identity %q not found"
+	ErrLookupLinkedIdentityFailed StandardError = "identity %q of realm %q is not linked"
+
+	ErrAddUserAcceptance StandardError = "failed adding user acceptance of %q: %v"
+	ErrAcceptanceInvalid StandardError = "document name or version is empty"
+
+	ErrExportUser         StandardError = "failed exporting user %q: %v"
+	ErrAddUserLoginRecord StandardError = "failed recording login of user %q: %v"
+	ErrLoginRecordInvalid StandardError = "login outcome or timestamp is empty"
+
+	ErrScheduleUserDeletion StandardError = "failed scheduling deletion of user %q: %v"
+	ErrCancelUserDeletion   StandardError = "failed cancelling deletion of user %q: %v"
+	ErrPurgeUserDeletions   StandardError = "failed purging deleted users: %v"
+	ErrDeletionTimeInvalid  StandardError = "deletion time is not set"
+
+	ErrGrantUserRole      StandardError = "failed granting role %q to user %q: %v"
+	ErrExpireUserRoles    StandardError = "failed expiring user roles: %v"
+	ErrRoleGrantExpiry    StandardError = "role grant expiry is not in the future"
+	ErrRoleGrantPermanent StandardError = "role %q is already assigned permanently"
+
+	ErrDisableDormantUsers StandardError = "failed disabling dormant users: %v"
+	ErrPruneLoginRecords   StandardError = "failed pruning login records: %v"
+	ErrEnableUser          StandardError = "failed enabling user %q: %v"
+	ErrPurgeRegistrations  StandardError = "failed purging registrations: %v"
+	ErrLifecycleCutoff     StandardError = "lifecycle cutoff is not set"
+	ErrUserDisabled        StandardError = "user account is disabled"
+
 	ErrCreditCardUnsupportedIssuer      StandardError = "unsupported credit card issuer: %v"
 	ErrCreditCardUnsupportedAssociation StandardError = "unsupported credit card association: %v"
 )
@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Delegated administration errors.
+const (
+	ErrDelegationConfigRole        StandardError = "delegation config: admin role must not be empty"
+	ErrDelegationConfigRoleFull    StandardError = "delegation config: admin role %q is reserved"
+	ErrDelegationConfigScope       StandardError = "delegation config: admin role %q has unsupported scope %q"
+	ErrDelegationConfigDuplicate   StandardError = "delegation config: admin role %q is duplicate"
+	ErrDelegationRealmNotPermitted StandardError = "delegation: realm %q is outside of the admin scope"
+	ErrDelegationRoleNotPermitted  StandardError = "delegation: role %q is outside of the admin scope"
+	ErrDelegationUserNotPermitted  StandardError = "delegation: user %q is outside of the admin scope"
+	ErrDelegationOrgNotPermitted   StandardError = "delegation: organization %q is outside of the admin scope"
+)
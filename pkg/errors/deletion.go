@@ -0,0 +1,29 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Account deletion errors.
+const (
+	ErrAccountDeletionConfigEmailProvider StandardError = "account deletion: email provider is not set"
+	ErrAccountDeletionConfigGracePeriod   StandardError = "account deletion: grace period must not be negative, got %d"
+	ErrAccountDeletionConfigCodeLifetime  StandardError = "account deletion: code lifetime must not be negative, got %d"
+	ErrAccountDeletionConfigMaxAttempts   StandardError = "account deletion: max attempts must not be negative, got %d"
+	ErrAccountDeletionConfigPurgeInterval StandardError = "account deletion: purge interval must not be negative, got %d"
+
+	ErrAccountDeletionNotFound     StandardError = "account deletion: no pending deletion request"
+	ErrAccountDeletionCodeExpired  StandardError = "account deletion: confirmation code has expired"
+	ErrAccountDeletionCodeMismatch StandardError = "account deletion: confirmation code is invalid"
+	ErrAccountDeletionCodeAttempts StandardError = "account deletion: too many failed confirmation attempts"
+)
@@ -0,0 +1,34 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Directory Errors
+const (
+	ErrDirectoryConfigNameEmpty          StandardError = "directory name is empty"
+	ErrDirectoryConfigAddressEmpty       StandardError = "directory %q address is empty"
+	ErrDirectoryConfigBaseDNEmpty        StandardError = "directory %q base dn is empty"
+	ErrDirectoryConfigBaseDNInvalid      StandardError = "directory %q base dn %q is invalid: %v"
+	ErrDirectoryConfigIdentityStoreEmpty StandardError = "directory %q identity store is empty"
+	ErrDirectoryConfigServiceAccount     StandardError = "directory %q service account is invalid: %v"
+	ErrDirectoryConfigTLS                StandardError = "directory %q tls configuration error: %v"
+	ErrDirectoryIdentityStoreNotFound    StandardError = "directory %q identity store %q not found"
+	ErrDirectoryIdentityStoreUnsupported StandardError = "directory %q identity store %q is not a local identity store"
+	ErrDirectoryStartFailed              StandardError = "directory %q failed to start: %v"
+	ErrDirectoryBindInvalidDN            StandardError = "bind dn %q is outside of the directory"
+	ErrDirectoryBindAccountLocked        StandardError = "user %q account is locked"
+	ErrDirectoryBindRoleNotAllowed       StandardError = "user %q has none of the required roles"
+	ErrDirectoryBindUnauthenticated      StandardError = "unauthenticated bind of %q is not allowed"
+	ErrDirectoryServiceAccountAuthFailed StandardError = "service account %q authentication failed"
+)
@@ -0,0 +1,32 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Email change errors.
+const (
+	ErrEmailChangeConfigEmailProvider StandardError = "email change: email provider is not set"
+	ErrEmailChangeConfigCodeLifetime  StandardError = "email change: code lifetime must not be negative, got %d"
+	ErrEmailChangeConfigMaxAttempts   StandardError = "email change: max attempts must not be negative, got %d"
+	ErrEmailChangeConfigMaxRequests   StandardError = "email change: max requests must not be negative, got %d"
+	ErrEmailChangeConfigWindow        StandardError = "email change: throttling window must not be negative, got %d"
+
+	ErrEmailChangeSameAddress  StandardError = "email change: new email address matches the current one"
+	ErrEmailChangeNotFound     StandardError = "email change: no pending email change"
+	ErrEmailChangeCodeExpired  StandardError = "email change: confirmation code has expired"
+	ErrEmailChangeCodeMismatch StandardError = "email change: confirmation code is invalid"
+	ErrEmailChangeCodeAttempts StandardError = "email change: too many failed confirmation attempts"
+	ErrEmailChangeAddressTaken StandardError = "email change: email address is already in use"
+	ErrEmailChangeThrottled    StandardError = "email change: too many requests, please try again later"
+)
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Audit Sink Errors
+const (
+	ErrAuditSinkConfigNameEmpty    StandardError = "audit sink name is empty"
+	ErrAuditSinkConfigKindInvalid  StandardError = "audit sink %q kind %q is not supported"
+	ErrAuditSinkConfigInvalid      StandardError = "audit sink %q configuration error: %v"
+	ErrAuditSinkTokenResolve       StandardError = "audit sink %q failed resolving token: %v"
+	ErrAuditSinkDelivery           StandardError = "audit sink %q failed delivering events: %v"
+	ErrAuditSinkDeliveryStatus     StandardError = "audit sink %q responded with status code %d"
+	ErrAuditSinkEventTypeUndefined StandardError = "audit sink %q event type %q is not defined"
+)
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Envoy External Authorization Errors
+const (
+	ErrExtAuthzConfigNameEmpty    StandardError = "ext_authz server name is empty"
+	ErrExtAuthzConfigAddressEmpty StandardError = "ext_authz server %q has neither grpc nor http address"
+	ErrExtAuthzConfigPolicyEmpty  StandardError = "ext_authz server %q authorization policy is empty"
+	ErrExtAuthzConfigPathPrefix   StandardError = "ext_authz server %q path prefix %q must start with a slash"
+	ErrExtAuthzPolicyNotFound     StandardError = "ext_authz server %q authorization policy %q not found"
+	ErrExtAuthzStartFailed        StandardError = "ext_authz server %q failed to start: %v"
+	ErrExtAuthzMalformedRequest   StandardError = "malformed check request: %v"
+)
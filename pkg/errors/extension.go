@@ -0,0 +1,35 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Extension errors.
+const (
+	ErrExtensionConfigNameEmpty       StandardError = "extension: name is empty"
+	ErrExtensionConfigPathEmpty       StandardError = "extension: %s: path is empty"
+	ErrExtensionConfigTimeoutInvalid  StandardError = "extension: %s: timeout %d is invalid"
+	ErrExtensionRead                  StandardError = "extension: %s: failed reading module: %v"
+	ErrExtensionCompile               StandardError = "extension: %s: failed compiling module: %v"
+	ErrExtensionInstantiate           StandardError = "extension: %s: failed instantiating module: %v"
+	ErrExtensionExportNotFound        StandardError = "extension: %s: module does not export %q"
+	ErrExtensionUnsupportedABIVersion StandardError = "extension: %s: unsupported abi version %d"
+	ErrExtensionNoCapabilities        StandardError = "extension: %s: module exports neither transform nor evaluate"
+	ErrExtensionCall                  StandardError = "extension: %s: %s call failed: %v"
+	ErrExtensionMemoryAccess          StandardError = "extension: %s: out of bounds memory access"
+	ErrExtensionInput                 StandardError = "extension: %s: failed encoding input: %v"
+	ErrExtensionOutput                StandardError = "extension: %s: failed decoding output: %v"
+	ErrExtensionTransformNotSupported StandardError = "extension: %s: transform not supported"
+	ErrExtensionEvaluateNotSupported  StandardError = "extension: %s: evaluate not supported"
+	ErrExtensionNotFound              StandardError = "extension: %s: not found"
+)
@@ -0,0 +1,22 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Access denied page errors.
+const (
+	ErrForbiddenPageConfigTemplate       StandardError = "forbidden page config: failed loading template %q: %v"
+	ErrForbiddenPageConfigRequestAccess  StandardError = "forbidden page config: request access url %q is invalid: %v"
+	ErrForbiddenPageConfigAPIPathInvalid StandardError = "forbidden page config: api path %q must start with a slash"
+)
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Forward Authentication Errors
+const (
+	ErrForwardAuthConfigNameEmpty    StandardError = "forward auth server name is empty"
+	ErrForwardAuthConfigAddressEmpty StandardError = "forward auth server %q address is empty"
+	ErrForwardAuthConfigPolicyEmpty  StandardError = "forward auth server %q authorization policy is empty"
+	ErrForwardAuthConfigPath         StandardError = "forward auth server %q path %q must start with a slash"
+	ErrForwardAuthConfigMode         StandardError = "forward auth server %q mode %q is unsupported"
+	ErrForwardAuthPolicyNotFound     StandardError = "forward auth server %q authorization policy %q not found"
+	ErrForwardAuthStartFailed        StandardError = "forward auth server %q failed to start: %v"
+)
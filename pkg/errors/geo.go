@@ -0,0 +1,24 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Geolocation errors.
+const (
+	ErrGeoConfigKindInvalid     StandardError = "geolocation config: unsupported database kind %q"
+	ErrGeoConfigPathEmpty       StandardError = "geolocation config: database path is empty"
+	ErrGeoConfigRefreshInterval StandardError = "geolocation config: refresh interval must not be negative, got %d"
+	ErrGeoDatabaseLoad          StandardError = "geolocation: failed loading database %q: %v"
+	ErrGeoDatabaseRecord        StandardError = "malformed record at line %d: %v"
+)
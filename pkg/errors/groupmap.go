@@ -0,0 +1,24 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Group mapping errors.
+const (
+	ErrGroupMapConfigPathEmpty       StandardError = "group mapping config: mapping file path is empty"
+	ErrGroupMapConfigRefreshInterval StandardError = "group mapping config: refresh interval must not be negative, got %d"
+	ErrGroupMapLoad                  StandardError = "group mapping: failed loading mapping file %q: %v"
+	ErrGroupMapEntryGroupEmpty       StandardError = "mapping %d has empty group"
+	ErrGroupMapEntryRolesEmpty       StandardError = "mapping %d of group %q has no roles"
+)
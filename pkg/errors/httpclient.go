@@ -0,0 +1,25 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// HTTP client pool errors.
+const (
+	ErrHTTPClientConfigMaxIdleConns        StandardError = "http client config: max idle connections must not be negative, got %d"
+	ErrHTTPClientConfigMaxIdleConnsPerHost StandardError = "http client config: max idle connections per host must not be negative, got %d"
+	ErrHTTPClientConfigMaxConnsPerHost     StandardError = "http client config: max connections per host must not be negative, got %d"
+	ErrHTTPClientConfigIdleConnTimeout     StandardError = "http client config: idle connection timeout must not be negative, got %d"
+	ErrHTTPClientConfigKeepAlive           StandardError = "http client config: keep-alive interval must not be negative, got %d"
+	ErrHTTPClientConfigDNSCacheTTL         StandardError = "http client config: dns cache ttl must not be negative, got %d"
+)
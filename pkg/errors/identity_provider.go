@@ -21,6 +21,10 @@ const (
 	// Generic Errors.
 	ErrIdentityProviderRequest StandardError = "%s failed: %v"
 
+	// Driver Errors.
+	ErrIdentityProviderDriverInvalid    StandardError = "identity provider driver %q is invalid"
+	ErrIdentityProviderDriverRegistered StandardError = "identity provider driver %q is already registered"
+
 	// Config Errors.
 	ErrIdentityProviderConfigureEmptyConfig    StandardError = "identity provider configuration is empty"
 	ErrIdentityProviderConfigureLoggerNotFound StandardError = "identity provider configuration has no logger"
@@ -62,4 +66,21 @@ const (
 	ErrIdentityProviderOauthMetadataFetchFailed                 StandardError = "failed to fetch metadata for OAuth 2.0 authorization server: %s"
 	ErrIdentityProviderOauthGetAccessTokenFailed                StandardError = "failed obtaining OAuth 2.0 access token, error: %v"
 	ErrIdentityProviderAuthorizationServerResponseFieldNotFound StandardError = "authorization server response has no %q field"
+
+	// Kerberos Errors
+	ErrIdentityProviderKerberosKeytabLoadFailed      StandardError = "failed loading Kerberos keytab %q: %v"
+	ErrIdentityProviderKerberosTrustedNetworkInvalid StandardError = "invalid Kerberos trusted network %q: %v"
+	ErrIdentityProviderKerberosTokenMalformed        StandardError = "malformed SPNEGO token: %v"
+	ErrIdentityProviderKerberosAuthFailed            StandardError = "Kerberos authentication failed: %v"
+
+	// Client Certificate Errors
+	ErrIdentityProviderCertificateTrustedCA             StandardError = "failed loading trusted CA certificates from %q: %v"
+	ErrIdentityProviderCertificateCRL                   StandardError = "failed loading CRL from %q: %v"
+	ErrIdentityProviderCertificateClaimMapping          StandardError = "invalid client certificate claim mapping: %v"
+	ErrIdentityProviderCertificateNotFound              StandardError = "client certificate not found"
+	ErrIdentityProviderCertificateInvalid               StandardError = "client certificate is invalid: %v"
+	ErrIdentityProviderCertificateRevoked               StandardError = "client certificate with serial %s is revoked"
+	ErrIdentityProviderCertificateRevocationCheckFailed StandardError = "client certificate revocation check failed: %v"
+	ErrIdentityProviderCertificateSubjectNotAllowed     StandardError = "client certificate subject %q is not allowed"
+	ErrIdentityProviderCertificateClaimNotFound         StandardError = "client certificate has no value for %q claim"
 )
@@ -21,6 +21,10 @@ const (
 	// Local identity store errors.
 	ErrIdentityStoreLocalConfigurePathEmpty    StandardError = "identity store configuration has empty database path"
 	ErrIdentityStoreLocalConfigurePathMismatch StandardError = "identity store configuration database path does not match to an existing path in the same realm: %v %v"
+	ErrIdentityStoreLocalConfigureBackupCount  StandardError = "identity store configuration backup count must not be negative, got %d"
+	ErrIdentityStoreLocalConfigureCompaction   StandardError = "identity store configuration compaction interval must not be negative, got %d"
+	ErrIdentityStoreLocalConfigureLoginRecords StandardError = "identity store configuration max login records must not be negative, got %d"
+	ErrIdentityStoreLocalReadOnly              StandardError = "identity store %q is read-only for maintenance, account changes are temporarily unavailable"
 
 	// LDAP identity store errors.
 	ErrIdentityStoreLdapAuthenticateInvalidUserEmail StandardError = "LDAP authentication request contains invalid user email"
@@ -28,6 +32,20 @@ const (
 	ErrIdentityStoreLdapAuthenticateInvalidPassword  StandardError = "LDAP authentication request contains invalid password"
 	ErrIdentityStoreLdapAuthFailed                   StandardError = "LDAP authentication failed: %v"
 
+	// External identity store errors.
+	ErrIdentityStoreExternalConfigURLEmpty           StandardError = "external identity store configuration has empty url"
+	ErrIdentityStoreExternalConfigURLInvalid         StandardError = "external identity store configuration url %q is invalid: %v"
+	ErrIdentityStoreExternalConfigURLNotSecure       StandardError = "external identity store configuration url %q must use https"
+	ErrIdentityStoreExternalConfigSecretEmpty        StandardError = "external identity store configuration has empty secret"
+	ErrIdentityStoreExternalConfigTimeout            StandardError = "external identity store configuration timeout must not be negative, got %d"
+	ErrIdentityStoreExternalAuthenticateInvalidInput StandardError = "external authentication request contains invalid username or password"
+	ErrIdentityStoreExternalAuthFailed               StandardError = "external authentication failed: %v"
+	ErrIdentityStoreExternalResponseInvalid          StandardError = "external identity store response is invalid: %v"
+
+	// Identity store driver errors.
+	ErrIdentityStoreDriverInvalid    StandardError = "identity store driver %q is invalid"
+	ErrIdentityStoreDriverRegistered StandardError = "identity store driver %q is already registered"
+
 	// Generic Errors.
 	ErrIdentityStoreRequest StandardError = "%s failed: %v"
 
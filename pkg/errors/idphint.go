@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Identity provider hint errors.
+const (
+	ErrIdentityProviderHintConfigSigningKey StandardError = "identity provider hint config: signing key must be at least %d characters long"
+	ErrIdentityProviderHintConfigLifetime   StandardError = "identity provider hint config: lifetime must not be negative, got %d"
+	ErrIdentityProviderHintConfigDelay      StandardError = "identity provider hint config: redirect delay must not be negative, got %d"
+	ErrIdentityProviderHintMalformed        StandardError = "identity provider hint: hint is malformed"
+	ErrIdentityProviderHintSignature        StandardError = "identity provider hint: hint signature is invalid"
+	ErrIdentityProviderHintExpired          StandardError = "identity provider hint: hint expired"
+	ErrIdentityProviderHintRealm            StandardError = "identity provider hint: realm %q is invalid"
+)
@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Impersonation errors.
+const (
+	ErrImpersonationConfigRole       StandardError = "impersonation config: role must not be empty"
+	ErrImpersonationConfigLifetime   StandardError = "impersonation config: lifetime must not be negative, got %d"
+	ErrImpersonationNotPermitted     StandardError = "impersonation: %s is not permitted to impersonate users"
+	ErrImpersonationNested           StandardError = "impersonation: %s is already impersonated by %s"
+	ErrImpersonationSelf             StandardError = "impersonation: %s cannot impersonate themselves"
+	ErrImpersonationProtectedUser    StandardError = "impersonation: user %s holds protected role %s"
+	ErrImpersonationLifetimeExceeded StandardError = "impersonation: requested duration %d exceeds %d seconds"
+	ErrImpersonationNotActive        StandardError = "impersonation: %s is not impersonated"
+)
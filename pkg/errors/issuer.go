@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Trusted token issuer errors.
+const (
+	ErrIssuerConfigNameNotFound     StandardError = "trusted issuer: name not found"
+	ErrIssuerConfigIssuerNotFound   StandardError = "trusted issuer %q: issuer not found"
+	ErrIssuerConfigKeysNotFound     StandardError = "trusted issuer %q: crypto key configs not found"
+	ErrIssuerConfigClaimMapping     StandardError = "trusted issuer %q: claim mapping %d: %s"
+	ErrIssuerConfigKeys             StandardError = "trusted issuer %q: %v"
+	ErrIssuerTokenAudienceMismatch  StandardError = "trusted issuer %q: token audience mismatch"
+	ErrValidatorDuplicateIssuer     StandardError = "token validator: duplicate trusted issuer %q"
+	ErrValidatorDuplicateIssuerName StandardError = "token validator: duplicate trusted issuer name %q"
+)
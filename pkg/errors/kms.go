@@ -46,6 +46,36 @@ const (
 	ErrCryptoKeyStoreAutoGenerateNotAvailable StandardError = "auto-generate not available when keystore is not empty"
 	ErrCryptoKeyStoreAutoGenerateFailed       StandardError = "failed to auto-generate keystore keypair: %v"
 	ErrCryptoKeyStoreAutoGenerateAlgo         StandardError = "auto-generate does not support %q algorithm"
+	// Remote JWKS
+	ErrCryptoKeyJwksFetch        StandardError = "kms: failed fetching jwks keys from %q: %v"
+	ErrCryptoKeyJwksKeysNotFound StandardError = "kms: no jwks keys found at %q"
+	ErrCryptoKeyJwksKeyNotFound  StandardError = "kms: jwks key %q not found at %q"
+	// External signers
+	ErrCryptoKeySignerURIInvalid     StandardError = "kms: %s signer key reference %q is invalid: %v"
+	ErrCryptoKeySignerUnavailable    StandardError = "kms: %s signer is not available: %v"
+	ErrCryptoKeySignerRequest        StandardError = "kms: %s signer request failed: %v"
+	ErrCryptoKeySignerPublicKey      StandardError = "kms: %s signer returned invalid public key: %v"
+	ErrCryptoKeySignerKeyUnsupported StandardError = "kms: %s signer key type %q is unsupported"
+	ErrCryptoKeySignerMethod         StandardError = "kms: %s signer does not support %s signing method"
+	ErrCryptoKeySignerSignature      StandardError = "kms: %s signer returned invalid signature: %v"
+	ErrCryptoKeySignerCredentials    StandardError = "kms: %s signer credentials not found"
+	ErrCryptoKeySignerRegionNotFound StandardError = "kms: %s signer region not found for key %q"
+	// Token encryption
+	ErrCryptoKeyTokenEncryption StandardError = "kms: failed encrypting token: %v"
+	ErrCryptoKeyTokenDecryption StandardError = "kms: failed decrypting token: %v"
+
+	// Opaque tokens
+	ErrCryptoKeyTokenStore          StandardError = "kms: token store %s: %v"
+	ErrCryptoKeyTokenStoreNotFound  StandardError = "kms: opaque token cannot be verified without token store"
+	ErrCryptoKeyTokenExpiryNotFound StandardError = "kms: opaque token requires expiry claim"
+	// Revocation list
+	ErrCryptoKeyRevocationStore StandardError = "kms: revocation store %s: %v"
+	ErrCryptoKeyTokenRevoked    StandardError = "kms: token is revoked"
+	// Per-realm key sets and algorithm allowlists
+	ErrCryptoKeyConfigAlgorithmMismatch StandardError = "kms: crypto key %q: algorithm %s is not supported by %s key"
+	ErrCryptoKeyConfigAlgorithmsUnmet   StandardError = "kms: crypto key %q: none of the allowed algorithms %s is supported by the key, which supports %s"
+	ErrCryptoKeyConfigRealmNotFound     StandardError = "kms: crypto key %q: realm %q has no identity store or provider"
+	ErrCryptoKeyTokenUnsigned           StandardError = "kms: unsigned token rejected"
 	// Signing
 	ErrUnsupportedSigningMethod StandardError = "kms: grantor does not support %s token signing method"
 	ErrUnexpectedSigningMethod  StandardError = "signing method mismatch: %v (expected) vs. %v (received)"
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Lifecycle errors.
+const (
+	ErrLifecycleConfigInterval     StandardError = "lifecycle config: interval %d is invalid"
+	ErrLifecycleConfigDays         StandardError = "lifecycle config: %s job days %d is invalid"
+	ErrLifecycleConfigDaysEmpty    StandardError = "lifecycle config: %s job requires days"
+	ErrLifecycleConfigLoginHistory StandardError = "lifecycle config: dormant accounts job requires login history"
+
+	ErrLifecycleJobNotFound StandardError = "lifecycle job %q not found"
+	ErrLifecycleJobDisabled StandardError = "lifecycle job %q is disabled"
+)
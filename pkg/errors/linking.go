@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Identity linking errors.
+const (
+	ErrIdentityLinkingConfigRealmEmpty StandardError = "identity linking: realm name is empty"
+	ErrIdentityLinkingConfigLifetime   StandardError = "identity linking: lifetime must not be negative, got %d"
+
+	ErrIdentityLinkingRealmNotAllowed StandardError = "identity linking: realm %q is not linkable"
+	ErrIdentityLinkingNotFound        StandardError = "identity linking: no pending identity link"
+	ErrIdentityLinkingExpired         StandardError = "identity linking: pending identity link has expired"
+	ErrIdentityLinkingRealmMismatch   StandardError = "identity linking: expected realm %q, got %q"
+	ErrIdentityLinkingStoreNotFound   StandardError = "identity linking: local identity store for realm %q not found"
+)
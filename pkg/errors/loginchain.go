@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Login chain errors.
+const (
+	ErrLoginChainConfigChainsEmpty  StandardError = "login chain config: no chains found"
+	ErrLoginChainConfigRealmEmpty   StandardError = "login chain config: chain %d has empty realm"
+	ErrLoginChainConfigRealmExists  StandardError = "login chain config: found duplicate chain for realm %q"
+	ErrLoginChainConfigMethodsEmpty StandardError = "login chain config: chain for realm %q has no methods"
+	ErrLoginChainConfigMethod       StandardError = "login chain config: chain for realm %q has unsupported method %q"
+	ErrLoginChainConfigMethodExists StandardError = "login chain config: chain for realm %q has duplicate method %q in realm %q"
+	ErrLoginChainRealmNotFound      StandardError = "login chain for realm %q refers to realm %q without identity store"
+)
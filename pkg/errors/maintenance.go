@@ -0,0 +1,28 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Maintenance errors.
+const (
+	ErrMaintenanceConfigMessage   StandardError = "maintenance config: failed parsing message template: %v"
+	ErrMaintenanceConfigNameEmpty StandardError = "maintenance config: %s name is empty"
+
+	ErrMaintenanceSwitchScope    StandardError = "maintenance switch scope %q is unsupported"
+	ErrMaintenanceSwitchName     StandardError = "maintenance switch of %s scope has no name"
+	ErrMaintenanceSwitchUntil    StandardError = "maintenance switch end time %s is in the past"
+	ErrMaintenanceSwitchMessage  StandardError = "maintenance switch message template is invalid: %v"
+	ErrMaintenanceSwitchNotFound StandardError = "maintenance switch %s not found"
+	ErrLoginDisabled             StandardError = "login to %s realm is disabled for maintenance"
+)
@@ -30,6 +30,7 @@ const (
 	ErrMfaTokenInvalidPeriod    StandardError = "invalid MFA token period: %d"
 	ErrMfaTokenInvalidDigits    StandardError = "invalid MFA token digits: %d"
 	ErrMfaTokenInvalidPasscode  StandardError = "invalid MFA token passcode: %v"
+	ErrMfaTokenAAGUID           StandardError = "invalid MFA token aaguid: %s"
 
 	ErrWebAuthnRegisterNotFound                          StandardError = "webauthn register not found"
 	ErrWebAuthnChallengeNotFound                         StandardError = "webauthn challenge not found"
@@ -0,0 +1,34 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Network filter errors.
+const (
+	ErrNetworkFilterConfigNetwork        StandardError = "network filter: invalid network or group %q"
+	ErrNetworkFilterConfigGroupName      StandardError = "network filter: group name is empty"
+	ErrNetworkFilterConfigGroupDuplicate StandardError = "network filter: duplicate group %q"
+	ErrNetworkFilterConfigGroupEmpty     StandardError = "network filter: group %q has no networks"
+	ErrNetworkFilterConfigAction         StandardError = "network filter: unsupported action %q"
+	ErrNetworkFilterConfigTarpitDelay    StandardError = "network filter: tarpit delay must not be negative, got %d"
+	ErrNetworkFilterConfigBanThreshold   StandardError = "network filter: ban threshold must not be negative, got %d"
+	ErrNetworkFilterConfigBanWindow      StandardError = "network filter: ban window must not be negative, got %d"
+	ErrNetworkFilterConfigBanDuration    StandardError = "network filter: ban duration must not be negative, got %d"
+	ErrNetworkFilterConfigBanAction      StandardError = "network filter: unsupported ban action %q"
+	ErrNetworkFilterConfigRealmName      StandardError = "network filter: realm name is empty"
+	ErrNetworkFilterConfigRealmDuplicate StandardError = "network filter: duplicate realm %q"
+	ErrNetworkFilterDenied               StandardError = "network filter: source address %q is denied"
+	ErrNetworkFilterNotAllowed           StandardError = "network filter: source address %q is not allowed"
+	ErrNetworkFilterBanned               StandardError = "network filter: source address %q is banned until %s"
+)
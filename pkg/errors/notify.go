@@ -0,0 +1,25 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Email notification errors.
+const (
+	ErrNotifyMessagingNil   StandardError = "notification: messaging is not configured"
+	ErrNotifyProvider       StandardError = "notification: email provider %q not found"
+	ErrNotifyProviderCreds  StandardError = "notification: email provider %q has no associated credentials"
+	ErrNotifyCredentialsNil StandardError = "notification: credentials are not configured"
+	ErrNotifyCredNotFound   StandardError = "notification: credential %q not found"
+	ErrNotifySend           StandardError = "notification %q via %q failed: %v"
+)
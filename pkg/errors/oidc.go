@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// OpenID Connect provider errors.
+const (
+	ErrOIDCConfigClientIDEmpty        StandardError = "oidc: client id must not be empty"
+	ErrOIDCConfigClientIDDuplicate    StandardError = "oidc: duplicate client id %q"
+	ErrOIDCConfigClientSecret         StandardError = "oidc: confidential client %q has no secret"
+	ErrOIDCConfigClientRedirectURIs   StandardError = "oidc: client %q has no redirect uris"
+	ErrOIDCConfigClientRedirectURI    StandardError = "oidc: client %q redirect uri %q is invalid"
+	ErrOIDCConfigClientScopeOpenID    StandardError = "oidc: client %q scopes must include openid"
+	ErrOIDCConfigClientClaimTransform StandardError = "oidc: client %q claim transform is invalid: %v"
+	ErrOIDCConfigCodeLifetime         StandardError = "oidc: code lifetime must not be negative, got %d"
+	ErrOIDCConfigIssuer               StandardError = "oidc: issuer %q is invalid"
+	ErrOIDCClientNotFound             StandardError = "oidc: client %q not found"
+	ErrOIDCClientAuthFailed           StandardError = "oidc: client authentication failed"
+	ErrOIDCRedirectURIMismatch        StandardError = "oidc: redirect uri %q is not registered for client %q"
+	ErrOIDCResponseTypeUnsupported    StandardError = "oidc: response type is unsupported"
+	ErrOIDCScopeInvalid               StandardError = "oidc: scope is invalid"
+	ErrOIDCCodeChallengeRequired      StandardError = "oidc: code challenge is required"
+	ErrOIDCCodeChallengeMethod        StandardError = "oidc: code challenge method is unsupported"
+	ErrOIDCCodeInvalid                StandardError = "oidc: authorization code is invalid or expired"
+	ErrOIDCCodeVerifierMismatch       StandardError = "oidc: code verifier mismatch"
+	ErrOIDCAuthorizationNotFound      StandardError = "oidc: authorization request not found or expired"
+	ErrOIDCTokenScopeInvalid          StandardError = "oidc: access token has no openid scope"
+
+	ErrOIDCConfigRegistrationTokens   StandardError = "oidc: client registration requires initial access tokens"
+	ErrOIDCConfigRegistrationStore    StandardError = "oidc: client registration requires token store"
+	ErrOIDCConfigRegistrationLifetime StandardError = "oidc: registered client lifetime must not be negative, got %d"
+	ErrOIDCRegistrationDisabled       StandardError = "oidc: client registration is disabled"
+	ErrOIDCRegistrationUnauthorized   StandardError = "oidc: initial access token is invalid"
+	ErrOIDCRegistrationStore          StandardError = "oidc: client registration store %q failed: %v"
+	ErrOIDCClientMetadataInvalid      StandardError = "oidc: client metadata is invalid: %s"
+	ErrOIDCClientMetadataRedirectURI  StandardError = "oidc: client metadata redirect uri %q is invalid"
+	ErrOIDCClientMetadataRedirectURIs StandardError = "oidc: client metadata has no redirect uris"
+)
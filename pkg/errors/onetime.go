@@ -0,0 +1,28 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// One-time token errors.
+const (
+	ErrOneTimeConfigTokenLifetime StandardError = "one-time token: token lifetime must not be negative, got %d"
+	ErrOneTimeConfigMaxRequests   StandardError = "one-time token: max requests must not be negative, got %d"
+	ErrOneTimeConfigWindow        StandardError = "one-time token: throttling window must not be negative, got %d"
+
+	ErrOneTimeTokenMalformed StandardError = "one-time token is malformed"
+	ErrOneTimeTokenSignature StandardError = "one-time token signature is invalid"
+	ErrOneTimeTokenExpired   StandardError = "one-time token has expired"
+	ErrOneTimeTokenUsed      StandardError = "one-time token has already been used"
+	ErrOneTimeThrottled      StandardError = "one-time token requests are throttled"
+)
@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Policy test errors.
+const (
+	ErrPolicyTestNameEmpty      StandardError = "policy test: name is empty"
+	ErrPolicyTestPolicyEmpty    StandardError = "policy test %q: authorization policy is empty"
+	ErrPolicyTestPathEmpty      StandardError = "policy test %q: path is empty"
+	ErrPolicyTestExpectInvalid  StandardError = "policy test %q: expected result %q is not allow or deny"
+	ErrPolicyTestIdentity       StandardError = "policy test %q: invalid identity: %v"
+	ErrPolicyTestPolicyNotFound StandardError = "policy test %q: authorization policy %q not found"
+	ErrPolicyTestLoad           StandardError = "failed loading policy tests from %q: %v"
+)
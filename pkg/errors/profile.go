@@ -0,0 +1,30 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Profile errors.
+const (
+	ErrProfileConfigFieldEmpty       StandardError = "profile: field rule has no field name"
+	ErrProfileConfigFieldUnsupported StandardError = "profile: field %q is not supported"
+	ErrProfileConfigFieldDuplicate   StandardError = "profile: field %q has more than one rule"
+	ErrProfileConfigMaxLength        StandardError = "profile: field %q max length must not be negative, got %d"
+	ErrProfileConfigPattern          StandardError = "profile: field %q pattern %q is invalid: %v"
+
+	ErrProfileFieldReadOnly  StandardError = "profile field %q is read-only"
+	ErrProfileFieldRequired  StandardError = "profile field %q is required"
+	ErrProfileFieldTooLong   StandardError = "profile field %q exceeds %d characters"
+	ErrProfileFieldPattern   StandardError = "profile field %q value has invalid format"
+	ErrProfileFieldNotListed StandardError = "profile field %q value %q is not allowed"
+)
@@ -0,0 +1,28 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Breached password check errors.
+const (
+	ErrPwnedConfigActionInvalid    StandardError = "breached password check: action %q is not supported"
+	ErrPwnedConfigURLInvalid       StandardError = "breached password check: url %q is invalid"
+	ErrPwnedConfigThresholdInvalid StandardError = "breached password check: threshold must not be negative, got %d"
+	ErrPwnedConfigTimeoutInvalid   StandardError = "breached password check: timeout must not be negative, got %d"
+	ErrPwnedFilterLoad             StandardError = "breached password check: failed loading filter %q: %v"
+	ErrPwnedFilterMalformed        StandardError = "breached password filter is malformed"
+	ErrPwnedRangeRequest           StandardError = "breached password check: range request failed: %v"
+	ErrPwnedRangeStatus            StandardError = "breached password check: range request returned status %d"
+	ErrPasswordCompromised         StandardError = "password appeared in a data breach, please choose a different password"
+)
@@ -0,0 +1,36 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Rate limit errors.
+const (
+	ErrRateLimitConfigMaxAttempts StandardError = "rate limit: max attempts must not be negative, got %d"
+	ErrRateLimitConfigInterval    StandardError = "rate limit: interval must not be negative, got %d"
+	ErrRateLimitExceeded          StandardError = "rate limit: too many attempts for %q"
+)
+
+// JSON login errors.
+const (
+	ErrLoginPasscodeRequired StandardError = "login: passcode required"
+	ErrLoginPasscodeInvalid  StandardError = "login: passcode is invalid"
+	ErrLoginCredentialsEmpty StandardError = "login: neither password nor api key provided"
+)
+
+// Login challenge errors.
+const (
+	ErrLoginChallengeConfigCaptchaNotFound StandardError = "login challenge: captcha config not found"
+	ErrLoginChallengeConfigInterval        StandardError = "login challenge: interval must not be negative, got %d"
+	ErrLoginChallengeRequired              StandardError = "login: challenge required"
+)
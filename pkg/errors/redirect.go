@@ -0,0 +1,30 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Redirect policy errors.
+const (
+	ErrRedirectConfigAllowedHost   StandardError = "redirect config: allowed host %q is invalid"
+	ErrRedirectConfigPattern       StandardError = "redirect config: allowed pattern %q is invalid: %v"
+	ErrRedirectConfigSigningKey    StandardError = "redirect config: signing key must be at least %d characters long"
+	ErrRedirectConfigTokenLifetime StandardError = "redirect config: token lifetime must not be negative, got %d"
+	ErrRedirectURLMalformed        StandardError = "redirect: url %q is malformed"
+	ErrRedirectURLScheme           StandardError = "redirect: url scheme %q is not allowed"
+	ErrRedirectURLNotAllowed       StandardError = "redirect: url %q is not allowed"
+	ErrRedirectTokenDisabled       StandardError = "redirect: signed tokens are disabled"
+	ErrRedirectTokenMalformed      StandardError = "redirect: token is malformed"
+	ErrRedirectTokenSignature      StandardError = "redirect: token signature is invalid"
+	ErrRedirectTokenExpired        StandardError = "redirect: token expired"
+)
@@ -26,4 +26,25 @@ const (
 	ErrUserRegistryConfigCredentialsNil                       StandardError = "user registration config %q credentials is nil"
 	ErrUserRegistryConfigCredentialsNotFound                  StandardError = "user registration config %q credential %q not found"
 	ErrUserRegistryConfigAdminEmailNotFound                   StandardError = "user registration config %q registration admin email not found"
+
+	// Invitation errors
+	ErrUserRegistryInviteNotFound     StandardError = "invitation code not found"
+	ErrUserRegistryInviteExpired      StandardError = "invitation code has expired"
+	ErrUserRegistryInviteExhausted    StandardError = "invitation code has been used up"
+	ErrUserRegistryInviteMaxUses      StandardError = "invitation max uses must be greater than zero, got %d"
+	ErrUserRegistryInviteStoreLoad    StandardError = "failed loading invitations from %q: %v"
+	ErrUserRegistryInviteStoreSave    StandardError = "failed saving invitations to %q: %v"
+	ErrUserRegistryInviteCodeGenerate StandardError = "failed generating invitation code: %v"
+
+	// Email domain errors
+	ErrUserRegistryEmailDomainDenied     StandardError = "email domain %q is not allowed to register"
+	ErrUserRegistryEmailAddressMalformed StandardError = "email address %q is malformed"
+
+	// Approval errors
+	ErrUserRegistryApprovalRole    StandardError = "failed assigning approval role %q: %v"
+	ErrUserRegistryApprovalImport  StandardError = "failed importing approved registration %q: %v"
+	ErrUserRegistryWebhookURL      StandardError = "webhook url %q is invalid"
+	ErrUserRegistryWebhookSecret   StandardError = "failed resolving webhook secret: %v"
+	ErrUserRegistryWebhookDelivery StandardError = "failed delivering webhook to %q: %v"
+	ErrUserRegistryWebhookStatus   StandardError = "webhook %q responded with status code %d"
 )
@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// gRPC API Errors
+const (
+	ErrRPCConfigNameEmpty    StandardError = "rpc server name is empty"
+	ErrRPCConfigAddressEmpty StandardError = "rpc server %q address is empty"
+	ErrRPCConfigPolicyEmpty  StandardError = "rpc server %q authorization policy is empty"
+	ErrRPCConfigTLS          StandardError = "rpc server %q tls configuration error: %v"
+	ErrRPCPolicyNotFound     StandardError = "rpc server %q authorization policy %q not found"
+	ErrRPCStartFailed        StandardError = "rpc server %q failed to start: %v"
+	ErrRPCRealmNotFound      StandardError = "realm %q not found"
+	ErrRPCUserNotFound       StandardError = "user %q not found"
+)
@@ -18,3 +18,16 @@ package errors
 const (
 	ErrNewServer StandardError = "server initialization failed: %s: %v"
 )
+
+// Server Reconfiguration Errors
+const (
+	ErrReconfigure               StandardError = "server reconfiguration failed: %s: %v"
+	ErrReconfigureUnsupported    StandardError = "server reconfiguration failed: changes to %s require restart"
+	ErrReconfigureDirectoryStore StandardError = "server reconfiguration failed: identity store %q is served by directory %q"
+	ErrReconfigurePolicyNotFound StandardError = "server reconfiguration failed: %s %q references authorization policy %q not found"
+)
+
+// Config Loading Errors
+const (
+	ErrLoadConfig StandardError = "failed loading config from %q: %v"
+)
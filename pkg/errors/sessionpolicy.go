@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Session policy errors.
+const (
+	ErrSessionPolicyConfigIdleTimeout      StandardError = "session policy: idle timeout must not be negative, got %d"
+	ErrSessionPolicyConfigAbsoluteLifetime StandardError = "session policy: absolute lifetime must not be negative, got %d"
+	ErrSessionPolicyConfigIdleExceeded     StandardError = "session policy: idle timeout %d exceeds absolute lifetime %d"
+	ErrSessionPolicyConfigRuleEmpty        StandardError = "session policy: rule %d has neither realm nor role"
+	ErrSessionPolicyNotFound               StandardError = "session policy: session not found"
+	ErrSessionPolicyIdleTimeout            StandardError = "session policy: session %s has been idle for too long"
+	ErrSessionPolicyAbsoluteLifetime       StandardError = "session policy: session %s exceeded its absolute lifetime"
+)
@@ -0,0 +1,25 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Sign-in alert errors.
+const (
+	ErrSignInAlertConfigEmailProvider StandardError = "sign-in alerts: email provider is not set"
+	ErrSignInAlertConfigTokenLifetime StandardError = "sign-in alerts: token lifetime must not be negative, got %d"
+	ErrSignInAlertConfigMaxSpeed      StandardError = "sign-in alerts: max speed must not be negative, got %d"
+	ErrSignInAlertConfigMaxDevices    StandardError = "sign-in alerts: max devices must not be negative, got %d"
+	ErrSignInAlertLoad                StandardError = "sign-in alerts: failed loading fingerprints from %q: %v"
+	ErrSignInAlertSave                StandardError = "sign-in alerts: failed saving fingerprints to %q: %v"
+)
@@ -19,5 +19,12 @@ const (
 	ErrSingleSignOnProviderConfigInvalid           StandardError = "invalid sso provider config: %v: %v"
 	ErrSingleSignOnProviderConfigureLoggerNotFound StandardError = "sso provider configuration has no logger"
 	ErrSingleSignOnProviderRequestMalformed        StandardError = "malformed sso provider request"
-	// ErrSingleSignOnProviderRequestInvalid          StandardError = "invalid sso provider request: %v"
+	ErrSingleSignOnProviderRequestInvalid          StandardError = "invalid sso provider request: %v"
+	ErrSingleSignOnProviderRequestUnsupported      StandardError = "sso provider %q does not support %s requests"
+	ErrSingleSignOnProviderServiceProviderNotFound StandardError = "service provider %q not found"
+	ErrSingleSignOnProviderServiceProviderDenied   StandardError = "user %q is not permitted to access service provider %q"
+	ErrSingleSignOnProviderServiceProviderMetadata StandardError = "service provider %q metadata error: %v"
+	ErrSingleSignOnProviderAssertionFailed         StandardError = "failed to issue assertion for service provider %q: %v"
+	ErrSingleSignOnProviderRoleCatalog             StandardError = "sso provider %q role catalog error: %v"
+	ErrSingleSignOnProviderOrganizationsRequest    StandardError = "aws organizations request failed: %v"
 )
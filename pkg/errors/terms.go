@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Terms errors.
+const (
+	ErrTermsConfigDocumentsEmpty    StandardError = "terms: no documents configured"
+	ErrTermsConfigDocumentName      StandardError = "terms: document has no name"
+	ErrTermsConfigDocumentDuplicate StandardError = "terms: document %q is defined more than once"
+	ErrTermsConfigDocumentTitle     StandardError = "terms: document %q has no title"
+	ErrTermsConfigDocumentURL       StandardError = "terms: document %q url %q is invalid"
+	ErrTermsConfigDocumentVersion   StandardError = "terms: document %q has no version"
+
+	ErrTermsNotAccepted StandardError = "Please accept the terms to continue"
+)
@@ -0,0 +1,21 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Token lifetime policy errors.
+const (
+	ErrTokenLifetimeConfigRuleEmpty StandardError = "token lifetime: rule %d has neither realm nor role"
+	ErrTokenLifetimeConfigLifetime  StandardError = "token lifetime: rule %d lifetime must be positive, got %d"
+)
@@ -0,0 +1,29 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Token store errors.
+const (
+	ErrTokenStoreConfigNameEmpty    StandardError = "token store: name is empty"
+	ErrTokenStoreConfigKindInvalid  StandardError = "token store: %s: kind %q is unsupported"
+	ErrTokenStoreConfigAddressEmpty StandardError = "token store: %s: address is empty"
+	ErrTokenStoreConfigDriverEmpty  StandardError = "token store: %s: sql driver is empty"
+	ErrTokenStoreConfigDSNEmpty     StandardError = "token store: %s: sql data source name is empty"
+	ErrTokenStoreConfigTableInvalid StandardError = "token store: %s: table name %q is invalid"
+	ErrTokenStoreNotFound           StandardError = "token store: %s: not found"
+	ErrTokenStoreTokenNotFound      StandardError = "token store: token not found"
+	ErrTokenStoreTokenEmpty         StandardError = "token store: token is empty"
+	ErrTokenStoreBackend            StandardError = "token store: %s: %v"
+)
@@ -29,4 +29,9 @@ const (
 	ErrValidatorAuthProxy                  StandardError = "token validator: auth proxy config is nil"
 	ErrValidatorAuthProxyPortalName        StandardError = "token validator: auth proxy config has empty portal name"
 	ErrValidatorAuthProxyNotFound          StandardError = "token validator: auth proxy %q not found"
+	ErrTokenSourceConfigType               StandardError = "token validator: unsupported token source type: %s"
+	ErrTokenSourceConfigDuplicate          StandardError = "token validator: duplicate token source type: %s"
+	ErrTokenSourceConfigNames              StandardError = "token validator: token source %s does not support names"
+	ErrTokenSourceConfigEmptyName          StandardError = "token validator: token source %s has an empty name"
+	ErrTokenSourceConfigNotEnabled         StandardError = "token validator: no token sources enabled"
 )
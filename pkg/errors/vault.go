@@ -0,0 +1,34 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Vault errors.
+const (
+	ErrVaultConfigAddressEmpty        StandardError = "vault: address is empty"
+	ErrVaultConfigAddressInvalid      StandardError = "vault: address %q is invalid"
+	ErrVaultConfigTokenEmpty          StandardError = "vault: token is empty"
+	ErrVaultConfigRefreshInterval     StandardError = "vault: refresh interval must not be negative, got %d"
+	ErrVaultClientNotConfigured       StandardError = "vault: client is not configured"
+	ErrVaultRequest                   StandardError = "vault: %s %s request failed: %v"
+	ErrVaultResponse                  StandardError = "vault: %s %s request failed: %s"
+	ErrVaultReferenceInvalid          StandardError = "vault: reference %q is invalid, expected vault:<path>#<field>"
+	ErrVaultSecretFieldNotFound       StandardError = "vault: field %q not found in secret %q"
+	ErrVaultSecretFieldNotString      StandardError = "vault: field %q in secret %q is not a string"
+	ErrVaultTransitKeyTypeUnsupported StandardError = "vault: transit key %q has unsupported type %q"
+	ErrVaultTransitKeyVersionNotFound StandardError = "vault: transit key %q version %d not found"
+	ErrVaultTransitKeyPublicKey       StandardError = "vault: transit key %q version %d has invalid public key: %v"
+	ErrVaultTransitSignMethod         StandardError = "vault: transit key %q does not support %s signing method"
+	ErrVaultTransitSignature          StandardError = "vault: transit key %q returned invalid signature: %v"
+)
@@ -0,0 +1,151 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5
+	defaultQueueSize     = 1000
+)
+
+var eventTypes = map[string]bool{
+	LoginSuccess:          true,
+	LoginFailure:          true,
+	MfaEnrolled:           true,
+	UserLocked:            true,
+	TokenRevoked:          true,
+	RegistrationApproved:  true,
+	TermsAccepted:         true,
+	UserDeletionScheduled: true,
+	UserReactivated:       true,
+	UserDeleted:           true,
+	AddressBanned:         true,
+	HoneypotLogin:         true,
+	UserImpersonated:      true,
+	ImpersonationEnded:    true,
+	StoreIntegrityChecked: true,
+	RoleGranted:           true,
+	RoleExpired:           true,
+	BreakGlassAttempt:     true,
+	BreakGlassLogin:       true,
+	UserDisabled:          true,
+}
+
+// SinkConfig is the configuration of an audit sink forwarding the security
+// events to a SIEM, i.e. as CEF or LEEF messages over syslog, or as Splunk
+// HTTP Event Collector posts.
+type SinkConfig struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Kind is either syslog or splunk_hec.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// Format is the format of the syslog messages, i.e. cef (default) or leef.
+	Format string `json:"format,omitempty" xml:"format,omitempty" yaml:"format,omitempty"`
+	// Network is the transport of the syslog messages, i.e. udp (default)
+	// or tcp.
+	Network string `json:"network,omitempty" xml:"network,omitempty" yaml:"network,omitempty"`
+	// Address is the address of the syslog server, e.g. siem.local:514.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// URL is the address of the HTTP Event Collector, e.g.
+	// https://splunk.local:8088/services/collector/event.
+	URL string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	// Token is the HTTP Event Collector token, or a vault reference to it.
+	Token      string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
+	Index      string `json:"index,omitempty" xml:"index,omitempty" yaml:"index,omitempty"`
+	SourceType string `json:"source_type,omitempty" xml:"source_type,omitempty" yaml:"source_type,omitempty"`
+	// EventTypes are the types of the forwarded events. When empty, all
+	// events are forwarded.
+	EventTypes []string `json:"event_types,omitempty" xml:"event_types,omitempty" yaml:"event_types,omitempty"`
+	// BatchSize is the maximum number of the events delivered at once.
+	BatchSize int `json:"batch_size,omitempty" xml:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	// FlushInterval is the maximum number of seconds the events wait for
+	// the delivery.
+	FlushInterval int `json:"flush_interval,omitempty" xml:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+	// QueueSize is the maximum number of the events awaiting the delivery.
+	// When the queue is full, e.g. the SIEM is unavailable, the new events
+	// are dropped.
+	QueueSize int `json:"queue_size,omitempty" xml:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+}
+
+// Validate validates audit sink config.
+func (cfg *SinkConfig) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrAuditSinkConfigNameEmpty
+	}
+	switch cfg.Kind {
+	case "syslog":
+		if cfg.Address == "" {
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "address is empty")
+		}
+		switch cfg.Format {
+		case "":
+			cfg.Format = "cef"
+		case "cef", "leef":
+		default:
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "unsupported format "+cfg.Format)
+		}
+		switch cfg.Network {
+		case "":
+			cfg.Network = "udp"
+		case "udp", "tcp":
+		default:
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "unsupported network "+cfg.Network)
+		}
+	case "splunk_hec":
+		if cfg.URL == "" {
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "url is empty")
+		}
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "url is invalid")
+		}
+		if cfg.Token == "" {
+			return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "token is empty")
+		}
+		if cfg.SourceType == "" {
+			cfg.SourceType = "authcrunch:event"
+		}
+	default:
+		return errors.ErrAuditSinkConfigKindInvalid.WithArgs(cfg.Name, cfg.Kind)
+	}
+	for _, eventType := range cfg.EventTypes {
+		if !eventTypes[eventType] {
+			return errors.ErrAuditSinkEventTypeUndefined.WithArgs(cfg.Name, eventType)
+		}
+	}
+	if cfg.BatchSize < 0 || cfg.FlushInterval < 0 || cfg.QueueSize < 0 {
+		return errors.ErrAuditSinkConfigInvalid.WithArgs(cfg.Name, "negative batch size, flush interval, or queue size")
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	return nil
+}
+
+func (cfg *SinkConfig) getFlushInterval() time.Duration {
+	return time.Duration(cfg.FlushInterval) * time.Second
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateSinkConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *SinkConfig
+		want      *SinkConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test syslog sink with defaults",
+			config: &SinkConfig{
+				Name:    "qradar",
+				Kind:    "syslog",
+				Address: "siem.local:514",
+			},
+			want: &SinkConfig{
+				Name:          "qradar",
+				Kind:          "syslog",
+				Format:        "cef",
+				Network:       "udp",
+				Address:       "siem.local:514",
+				BatchSize:     100,
+				FlushInterval: 5,
+				QueueSize:     1000,
+			},
+		},
+		{
+			name: "test splunk hec sink",
+			config: &SinkConfig{
+				Name:       "splunk",
+				Kind:       "splunk_hec",
+				URL:        "https://splunk.local:8088/services/collector/event",
+				Token:      "foobar",
+				EventTypes: []string{LoginFailure, UserLocked},
+				BatchSize:  10,
+			},
+			want: &SinkConfig{
+				Name:          "splunk",
+				Kind:          "splunk_hec",
+				URL:           "https://splunk.local:8088/services/collector/event",
+				Token:         "foobar",
+				SourceType:    "authcrunch:event",
+				EventTypes:    []string{LoginFailure, UserLocked},
+				BatchSize:     10,
+				FlushInterval: 5,
+				QueueSize:     1000,
+			},
+		},
+		{
+			name:      "test sink without name",
+			config:    &SinkConfig{},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkConfigNameEmpty,
+		},
+		{
+			name: "test sink with unsupported kind",
+			config: &SinkConfig{
+				Name: "foo",
+				Kind: "kafka",
+			},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkConfigKindInvalid.WithArgs("foo", "kafka"),
+		},
+		{
+			name: "test syslog sink with unsupported format",
+			config: &SinkConfig{
+				Name:    "qradar",
+				Kind:    "syslog",
+				Address: "siem.local:514",
+				Format:  "json",
+			},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkConfigInvalid.WithArgs("qradar", "unsupported format json"),
+		},
+		{
+			name: "test splunk hec sink without token",
+			config: &SinkConfig{
+				Name: "splunk",
+				Kind: "splunk_hec",
+				URL:  "https://splunk.local:8088/services/collector/event",
+			},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkConfigInvalid.WithArgs("splunk", "token is empty"),
+		},
+		{
+			name: "test sink with undefined event type",
+			config: &SinkConfig{
+				Name:       "qradar",
+				Kind:       "syslog",
+				Address:    "siem.local:514",
+				EventTypes: []string{"login.attempt"},
+			},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkEventTypeUndefined.WithArgs("qradar", "login.attempt"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "SinkConfig", tc.want, tc.config, msgs)
+		})
+	}
+}
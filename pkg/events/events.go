@@ -0,0 +1,175 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// The types of the security events.
+const (
+	LoginSuccess          = "login.success"
+	LoginFailure          = "login.failure"
+	MfaEnrolled           = "mfa.enrolled"
+	UserLocked            = "user.locked"
+	TokenRevoked          = "token.revoked"
+	RegistrationApproved  = "registration.approved"
+	TermsAccepted         = "terms.accepted"
+	UserDeletionScheduled = "user.deletion_scheduled"
+	UserReactivated       = "user.reactivated"
+	UserDeleted           = "user.deleted"
+	AddressBanned         = "address.banned"
+	HoneypotLogin         = "honeypot.login"
+	UserImpersonated      = "user.impersonated"
+	ImpersonationEnded    = "user.impersonation_ended"
+	StoreIntegrityChecked = "identity_store.integrity_checked"
+	RoleGranted           = "role.granted"
+	RoleExpired           = "role.expired"
+	BreakGlassAttempt     = "break_glass.attempt"
+	BreakGlassLogin       = "break_glass.login"
+	UserDisabled          = "user.disabled"
+)
+
+// Event is a security event.
+type Event struct {
+	Type          string                 `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	Timestamp     time.Time              `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Realm         string                 `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Method        string                 `json:"method,omitempty" xml:"method,omitempty" yaml:"method,omitempty"`
+	Username      string                 `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email         string                 `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	SourceAddress string                 `json:"source_address,omitempty" xml:"source_address,omitempty" yaml:"source_address,omitempty"`
+	Country       string                 `json:"country,omitempty" xml:"country,omitempty" yaml:"country,omitempty"`
+	City          string                 `json:"city,omitempty" xml:"city,omitempty" yaml:"city,omitempty"`
+	SessionID     string                 `json:"session_id,omitempty" xml:"session_id,omitempty" yaml:"session_id,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty" xml:"request_id,omitempty" yaml:"request_id,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty" xml:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// Sink consumes security events, e.g. writes them to an audit log.
+type Sink interface {
+	Handle(*Event)
+}
+
+// SinkFunc is an adapter allowing the use of a function as a Sink.
+type SinkFunc func(*Event)
+
+// Handle calls f(e).
+func (f SinkFunc) Handle(e *Event) {
+	f(e)
+}
+
+// Bus delivers the published events to the subscribed sinks.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[string]*subscription
+}
+
+type subscription struct {
+	sink  Sink
+	types map[string]bool
+}
+
+// Default is the bus the authentication portals, the identity stores, and
+// the user registries publish their events to.
+var Default = NewBus()
+
+// NewBus returns an instance of Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: make(map[string]*subscription),
+	}
+}
+
+// Subscribe subscribes the named sink to the events of the provided types.
+// When no types are provided, the sink receives all events. A subsequent
+// subscription with the same name replaces the previous one.
+func (b *Bus) Subscribe(name string, sink Sink, types ...string) {
+	s := &subscription{sink: sink}
+	if len(types) > 0 {
+		s.types = make(map[string]bool)
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[name] = s
+}
+
+// Unsubscribe removes the named sink.
+func (b *Bus) Unsubscribe(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscriptions, name)
+}
+
+// GetSinkNames returns the names of the subscribed sinks.
+func (b *Bus) GetSinkNames() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var names []string
+	for name := range b.subscriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Publish delivers the event to the sinks subscribed to its type. The
+// sinks are called synchronously, in the order of their names. A sink
+// failing with panic does not prevent the delivery to the other sinks.
+func (b *Bus) Publish(e *Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	b.mu.RLock()
+	var names []string
+	for name, s := range b.subscriptions {
+		if s.types != nil && !s.types[e.Type] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sinks []Sink
+	for _, name := range names {
+		sinks = append(sinks, b.subscriptions[name].sink)
+	}
+	b.mu.RUnlock()
+
+	for _, sink := range sinks {
+		deliver(sink, e)
+	}
+}
+
+func deliver(sink Sink, e *Event) {
+	defer func() {
+		recover()
+	}()
+	sink.Handle(e)
+}
+
+// Publish publishes the event to the default bus.
+func Publish(e *Event) {
+	Default.Publish(e)
+}
+
+// Subscribe subscribes the named sink to the default bus.
+func Subscribe(name string, sink Sink, types ...string) {
+	Default.Subscribe(name, sink, types...)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestBus(t *testing.T) {
+	testcases := []struct {
+		name   string
+		setup  func(b *Bus, got map[string][]string)
+		events []*Event
+		want   map[string][]string
+	}{
+		{
+			name: "test sink subscribed to all events",
+			setup: func(b *Bus, got map[string][]string) {
+				b.Subscribe("audit", newTestSink("audit", got))
+			},
+			events: []*Event{
+				{Type: LoginSuccess},
+				{Type: TokenRevoked},
+			},
+			want: map[string][]string{
+				"audit": {LoginSuccess, TokenRevoked},
+			},
+		},
+		{
+			name: "test sinks subscribed to selected events",
+			setup: func(b *Bus, got map[string][]string) {
+				b.Subscribe("audit", newTestSink("audit", got))
+				b.Subscribe("alerts", newTestSink("alerts", got), LoginFailure, UserLocked)
+			},
+			events: []*Event{
+				{Type: LoginSuccess},
+				{Type: LoginFailure},
+				{Type: UserLocked},
+			},
+			want: map[string][]string{
+				"audit":  {LoginSuccess, LoginFailure, UserLocked},
+				"alerts": {LoginFailure, UserLocked},
+			},
+		},
+		{
+			name: "test sink subscription replaced by name",
+			setup: func(b *Bus, got map[string][]string) {
+				b.Subscribe("audit", newTestSink("first", got))
+				b.Subscribe("audit", newTestSink("second", got))
+			},
+			events: []*Event{
+				{Type: MfaEnrolled},
+			},
+			want: map[string][]string{
+				"second": {MfaEnrolled},
+			},
+		},
+		{
+			name: "test unsubscribed sink",
+			setup: func(b *Bus, got map[string][]string) {
+				b.Subscribe("audit", newTestSink("audit", got))
+				b.Unsubscribe("audit")
+			},
+			events: []*Event{
+				{Type: LoginSuccess},
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "test failing sink does not prevent delivery",
+			setup: func(b *Bus, got map[string][]string) {
+				b.Subscribe("a", SinkFunc(func(e *Event) {
+					panic("sink failure")
+				}))
+				b.Subscribe("b", newTestSink("b", got))
+			},
+			events: []*Event{
+				{Type: RegistrationApproved},
+			},
+			want: map[string][]string{
+				"b": {RegistrationApproved},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got := make(map[string][]string)
+			b := NewBus()
+			tc.setup(b, got)
+			for _, e := range tc.events {
+				b.Publish(e)
+				if e.Timestamp.IsZero() || time.Since(e.Timestamp) > time.Minute {
+					t.Fatalf("unexpected event timestamp: %v", e.Timestamp)
+				}
+			}
+			tests.EvalObjectsWithLog(t, "events", tc.want, got, msgs)
+		})
+	}
+}
+
+func newTestSink(name string, got map[string][]string) Sink {
+	return SinkFunc(func(e *Event) {
+		got[name] = append(got[name], e.Type)
+	})
+}
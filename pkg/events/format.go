@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	siemVendor  = "AuthCrunch"
+	siemProduct = "authcrunch"
+	siemVersion = "1.0"
+)
+
+var eventNames = map[string]string{
+	LoginSuccess:          "User login succeeded",
+	LoginFailure:          "User login failed",
+	MfaEnrolled:           "MFA token enrolled",
+	UserLocked:            "User locked out",
+	TokenRevoked:          "Token revoked",
+	RegistrationApproved:  "User registration approved",
+	TermsAccepted:         "User accepted terms",
+	UserDeletionScheduled: "User account deletion scheduled",
+	UserReactivated:       "User account reactivated",
+	UserDeleted:           "User account deleted",
+	AddressBanned:         "Source address banned",
+	HoneypotLogin:         "Login attempt from banned address",
+	UserImpersonated:      "User impersonated by administrator",
+	ImpersonationEnded:    "User impersonation ended by administrator",
+	StoreIntegrityChecked: "Identity store integrity checked",
+	RoleGranted:           "Time-boxed role granted",
+	RoleExpired:           "Time-boxed role expired",
+	BreakGlassAttempt:     "Break-glass account login attempted",
+	BreakGlassLogin:       "Break-glass account logged in",
+	UserDisabled:          "Dormant user account disabled",
+}
+
+// getSeverity returns the severity of the event on the scale from 0 to 10.
+func getSeverity(e *Event) int {
+	switch e.Type {
+	case BreakGlassLogin:
+		return 10
+	case HoneypotLogin, BreakGlassAttempt:
+		return 9
+	case UserLocked, AddressBanned, UserImpersonated:
+		return 7
+	case LoginFailure:
+		return 5
+	}
+	return 3
+}
+
+func getName(e *Event) string {
+	if name, exists := eventNames[e.Type]; exists {
+		return name
+	}
+	return e.Type
+}
+
+// FormatCEF returns the event in ArcSight Common Event Format.
+func FormatCEF(e *Event) string {
+	var sb strings.Builder
+	sb.WriteString("CEF:0")
+	for _, s := range []string{siemVendor, siemProduct, siemVersion, e.Type, getName(e), strconv.Itoa(getSeverity(e))} {
+		sb.WriteString("|")
+		sb.WriteString(escapeCEFHeader(s))
+	}
+	sb.WriteString("|")
+
+	var ext []string
+	add := func(k, v string) {
+		if v != "" {
+			ext = append(ext, k+"="+escapeCEFExtension(v))
+		}
+	}
+	add("rt", strconv.FormatInt(e.Timestamp.UnixNano()/1e6, 10))
+	add("suser", e.Username)
+	add("src", e.SourceAddress)
+	if e.Realm != "" {
+		add("cs1Label", "realm")
+		add("cs1", e.Realm)
+	}
+	if e.Method != "" {
+		add("cs2Label", "method")
+		add("cs2", e.Method)
+	}
+	if e.Email != "" {
+		add("cs3Label", "email")
+		add("cs3", e.Email)
+	}
+	if e.SessionID != "" {
+		add("cs4Label", "sessionId")
+		add("cs4", e.SessionID)
+	}
+	if e.Country != "" {
+		add("cs5Label", "country")
+		add("cs5", e.Country)
+	}
+	if e.City != "" {
+		add("cs6Label", "city")
+		add("cs6", e.City)
+	}
+	add("externalId", e.RequestID)
+	add("msg", formatData(e))
+	sb.WriteString(strings.Join(ext, " "))
+	return sb.String()
+}
+
+// FormatLEEF returns the event in IBM QRadar Log Event Extended Format.
+func FormatLEEF(e *Event) string {
+	var sb strings.Builder
+	sb.WriteString("LEEF:1.0")
+	for _, s := range []string{siemVendor, siemProduct, siemVersion, e.Type} {
+		sb.WriteString("|")
+		sb.WriteString(escapeLEEFHeader(s))
+	}
+	sb.WriteString("|")
+
+	var attrs []string
+	add := func(k, v string) {
+		if v != "" {
+			attrs = append(attrs, k+"="+escapeLEEFAttribute(v))
+		}
+	}
+	add("devTime", e.Timestamp.UTC().Format("Jan 02 2006 15:04:05.000 MST"))
+	add("devTimeFormat", "MMM dd yyyy HH:mm:ss.SSS zzz")
+	add("cat", getName(e))
+	add("sev", strconv.Itoa(getSeverity(e)))
+	add("usrName", e.Username)
+	add("src", e.SourceAddress)
+	add("srcCountry", e.Country)
+	add("srcCity", e.City)
+	add("realm", e.Realm)
+	add("method", e.Method)
+	add("email", e.Email)
+	add("sessionId", e.SessionID)
+	add("requestId", e.RequestID)
+	add("data", formatData(e))
+	sb.WriteString(strings.Join(attrs, "\t"))
+	return sb.String()
+}
+
+// formatData returns the event data as JSON object.
+func formatData(e *Event) string {
+	if len(e.Data) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func escapeCEFHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+func escapeCEFExtension(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+func escapeLEEFHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+func escapeLEEFAttribute(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
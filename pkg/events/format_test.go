@@ -0,0 +1,87 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func newTestEvent(eventType string) *Event {
+	return &Event{
+		Type:          eventType,
+		Timestamp:     time.Date(2022, time.March, 4, 5, 6, 7, 890000000, time.UTC),
+		Realm:         "local",
+		Method:        "local",
+		Username:      "jsmith",
+		Email:         "jsmith@contoso.com",
+		SourceAddress: "10.0.0.1",
+		SessionID:     "abc",
+		RequestID:     "123",
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testcases := []struct {
+		name  string
+		event *Event
+		want  map[string]string
+	}{
+		{
+			name:  "test login success",
+			event: newTestEvent(LoginSuccess),
+			want: map[string]string{
+				"cef": "CEF:0|AuthCrunch|authcrunch|1.0|login.success|User login succeeded|3|" +
+					"rt=1646370367890 suser=jsmith src=10.0.0.1 cs1Label=realm cs1=local cs2Label=method cs2=local " +
+					"cs3Label=email cs3=jsmith@contoso.com cs4Label=sessionId cs4=abc externalId=123",
+				"leef": "LEEF:1.0|AuthCrunch|authcrunch|1.0|login.success|" +
+					"devTime=Mar 04 2022 05:06:07.890 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS zzz\t" +
+					"cat=User login succeeded\tsev=3\tusrName=jsmith\tsrc=10.0.0.1\trealm=local\tmethod=local\t" +
+					"email=jsmith@contoso.com\tsessionId=abc\trequestId=123",
+			},
+		},
+		{
+			name: "test login failure with escaped values",
+			event: &Event{
+				Type:      LoginFailure,
+				Timestamp: time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC),
+				Username:  "a=b\\c",
+				Data: map[string]interface{}{
+					"error": "bad\tpassword",
+				},
+			},
+			want: map[string]string{
+				"cef": `CEF:0|AuthCrunch|authcrunch|1.0|login.failure|User login failed|5|` +
+					`rt=1646370367000 suser=a\=b\\c msg={"error":"bad\\tpassword"}`,
+				"leef": "LEEF:1.0|AuthCrunch|authcrunch|1.0|login.failure|" +
+					"devTime=Mar 04 2022 05:06:07.000 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS zzz\t" +
+					"cat=User login failed\tsev=5\tusrName=a=b\\c\tdata={\"error\":\"bad\\tpassword\"}",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got := map[string]string{
+				"cef":  FormatCEF(tc.event),
+				"leef": FormatLEEF(tc.event),
+			}
+			tests.EvalObjectsWithLog(t, "output", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// sender delivers a batch of events to a SIEM.
+type sender interface {
+	send([]*Event) error
+	close() error
+}
+
+// Forwarder is an audit sink forwarding the security events to a SIEM. The
+// events are queued and delivered in batches in the background. When the
+// queue is full, the new events are dropped rather than blocking the
+// requests publishing them.
+type Forwarder struct {
+	config  *SinkConfig
+	sender  sender
+	logger  *zap.Logger
+	queue   chan *Event
+	flush   chan chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closed  bool
+	dropped uint64
+}
+
+// NewForwarder returns an instance of Forwarder and starts the delivery of
+// the events.
+func NewForwarder(cfg *SinkConfig, logger *zap.Logger) (*Forwarder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var s sender
+	var err error
+	switch cfg.Kind {
+	case "syslog":
+		s = newSyslogSender(cfg)
+	case "splunk_hec":
+		s, err = newHECSender(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newForwarder(cfg, s, logger), nil
+}
+
+func newForwarder(cfg *SinkConfig, s sender, logger *zap.Logger) *Forwarder {
+	f := &Forwarder{
+		config: cfg,
+		sender: s,
+		logger: logger,
+		queue:  make(chan *Event, cfg.QueueSize),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+	f.wg.Add(1)
+	go f.run()
+	return f
+}
+
+// GetName returns the name of the audit sink.
+func (f *Forwarder) GetName() string {
+	return f.config.Name
+}
+
+// GetEventTypes returns the types of the forwarded events.
+func (f *Forwarder) GetEventTypes() []string {
+	return f.config.EventTypes
+}
+
+// GetDropped returns the number of the events dropped due to the full queue.
+func (f *Forwarder) GetDropped() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dropped
+}
+
+// Handle implements Sink. It does not block.
+func (f *Forwarder) Handle(e *Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	select {
+	case f.queue <- e:
+	default:
+		f.dropped++
+		metrics.AuditEvents.Inc(f.config.Name, "dropped")
+	}
+}
+
+// Flush delivers the queued events.
+func (f *Forwarder) Flush() {
+	ch := make(chan struct{})
+	select {
+	case f.flush <- ch:
+		<-ch
+	case <-f.done:
+	}
+}
+
+// Close delivers the queued events and stops the forwarder.
+func (f *Forwarder) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	close(f.done)
+	f.mu.Unlock()
+	f.wg.Wait()
+	return f.sender.close()
+}
+
+func (f *Forwarder) run() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(f.config.getFlushInterval())
+	defer ticker.Stop()
+	var batch []*Event
+	for {
+		select {
+		case e := <-f.queue:
+			batch = append(batch, e)
+			if len(batch) >= f.config.BatchSize {
+				batch = f.deliver(batch)
+			}
+		case <-ticker.C:
+			batch = f.deliver(batch)
+		case ch := <-f.flush:
+			batch = f.deliver(f.drain(batch))
+			close(ch)
+		case <-f.done:
+			f.deliver(f.drain(batch))
+			return
+		}
+	}
+}
+
+func (f *Forwarder) drain(batch []*Event) []*Event {
+	for {
+		select {
+		case e := <-f.queue:
+			batch = append(batch, e)
+		default:
+			return batch
+		}
+	}
+}
+
+// deliver sends the events in batches and returns an empty batch. The
+// batches failing the delivery are discarded.
+func (f *Forwarder) deliver(batch []*Event) []*Event {
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > f.config.BatchSize {
+			n = f.config.BatchSize
+		}
+		if err := f.sender.send(batch[:n]); err != nil {
+			metrics.AuditEvents.Add(float64(n), f.config.Name, "failed")
+			f.logger.Warn(
+				"Failed forwarding security events",
+				zap.String("sink", f.config.Name),
+				zap.Int("count", n),
+				zap.Error(err),
+			)
+		} else {
+			metrics.AuditEvents.Add(float64(n), f.config.Name, "delivered")
+		}
+		batch = batch[n:]
+	}
+	return batch[:0]
+}
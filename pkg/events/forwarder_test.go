@@ -0,0 +1,166 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestForwarderSplunkHEC(t *testing.T) {
+	var mu sync.Mutex
+	var posts [][]string
+	var authHeaders []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		var types []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var m struct {
+				SourceType string `json:"sourcetype"`
+				Event      *Event `json:"event"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+				t.Errorf("unexpected event: %v", err)
+				continue
+			}
+			types = append(types, m.SourceType+" "+m.Event.Type)
+		}
+		posts = append(posts, types)
+	}))
+	defer srv.Close()
+
+	f, err := NewForwarder(&SinkConfig{
+		Name:      "splunk",
+		Kind:      "splunk_hec",
+		URL:       srv.URL + "/services/collector/event",
+		Token:     "foobar",
+		BatchSize: 2,
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, eventType := range []string{LoginSuccess, LoginFailure, UserLocked} {
+		f.Handle(newTestEvent(eventType))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests.EvalObjects(t, "posts", [][]string{
+		{"authcrunch:event login.success", "authcrunch:event login.failure"},
+		{"authcrunch:event user.locked"},
+	}, posts)
+	tests.EvalObjects(t, "authorization", []string{"Splunk foobar", "Splunk foobar"}, authHeaders)
+}
+
+func TestForwarderSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	f, err := NewForwarder(&SinkConfig{
+		Name:    "qradar",
+		Kind:    "syslog",
+		Format:  "leef",
+		Address: conn.LocalAddr().String(),
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	f.Handle(newTestEvent(LoginFailure))
+	f.Flush()
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<84>1 2022-03-04T05:06:07.890Z ") {
+		t.Fatalf("unexpected syslog header: %s", msg)
+	}
+	if !strings.Contains(msg, " authcrunch - login.failure - LEEF:1.0|AuthCrunch|authcrunch|1.0|login.failure|") {
+		t.Fatalf("unexpected syslog message: %s", msg)
+	}
+}
+
+type blockingSender struct {
+	release chan struct{}
+	mu      sync.Mutex
+	count   int
+}
+
+func (s *blockingSender) send(batch []*Event) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += len(batch)
+	return nil
+}
+
+func (s *blockingSender) close() error {
+	return nil
+}
+
+func TestForwarderBackpressure(t *testing.T) {
+	cfg := &SinkConfig{
+		Name:      "splunk",
+		Kind:      "splunk_hec",
+		URL:       "https://splunk.local:8088/services/collector/event",
+		Token:     "foobar",
+		BatchSize: 1,
+		QueueSize: 2,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := &blockingSender{release: make(chan struct{})}
+	f := newForwarder(cfg, s, logutil.NewLogger())
+
+	// The first event is taken by the blocked delivery, the next two fill
+	// the queue, and the remaining ones are dropped.
+	f.Handle(newTestEvent(LoginSuccess))
+	for {
+		f.mu.Lock()
+		n := len(f.queue)
+		f.mu.Unlock()
+		if n == 0 {
+			break
+		}
+	}
+	for i := 0; i < 5; i++ {
+		f.Handle(newTestEvent(LoginFailure))
+	}
+	tests.EvalObjects(t, "dropped", uint64(3), f.GetDropped())
+
+	close(s.release)
+	f.Close()
+	tests.EvalObjects(t, "delivered", 3, s.count)
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const hecTimeout = 10 * time.Second
+
+// hecSender posts the events to Splunk HTTP Event Collector.
+type hecSender struct {
+	config   *SinkConfig
+	token    string
+	hostname string
+	client   *http.Client
+}
+
+type hecEvent struct {
+	Time       float64 `json:"time"`
+	Host       string  `json:"host,omitempty"`
+	Source     string  `json:"source"`
+	SourceType string  `json:"sourcetype"`
+	Index      string  `json:"index,omitempty"`
+	Event      *Event  `json:"event"`
+}
+
+func newHECSender(cfg *SinkConfig) (*hecSender, error) {
+	token, err := credentials.ResolveSecret(cfg.Token)
+	if err != nil {
+		return nil, errors.ErrAuditSinkTokenResolve.WithArgs(cfg.Name, err)
+	}
+	s := &hecSender{
+		config: cfg,
+		token:  token,
+		client: &http.Client{Timeout: hecTimeout},
+	}
+	s.hostname, _ = os.Hostname()
+	return s, nil
+}
+
+func (s *hecSender) send(batch []*Event) error {
+	// The collector accepts multiple events in the body of a single post.
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range batch {
+		if err := enc.Encode(&hecEvent{
+			Time:       float64(e.Timestamp.UnixNano()/1e6) / 1e3,
+			Host:       s.hostname,
+			Source:     "authcrunch",
+			SourceType: s.config.SourceType,
+			Index:      s.config.Index,
+			Event:      e,
+		}); err != nil {
+			return errors.ErrAuditSinkDelivery.WithArgs(s.config.Name, err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, &body)
+	if err != nil {
+		return errors.ErrAuditSinkDelivery.WithArgs(s.config.Name, err)
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.ErrAuditSinkDelivery.WithArgs(s.config.Name, err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.ErrAuditSinkDeliveryStatus.WithArgs(s.config.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *hecSender) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"go.uber.org/zap"
+)
+
+type logSink struct {
+	logger *zap.Logger
+}
+
+// NewLogSink returns a sink writing the events to the provided logger as
+// audit entries.
+func NewLogSink(logger *zap.Logger) Sink {
+	return &logSink{logger: logger}
+}
+
+// Handle implements Sink.
+func (s *logSink) Handle(e *Event) {
+	fields := []zap.Field{
+		zap.String("event", e.Type),
+	}
+	if e.SessionID != "" {
+		fields = append(fields, zap.String("session_id", e.SessionID))
+	}
+	if e.RequestID != "" {
+		fields = append(fields, zap.String("request_id", e.RequestID))
+	}
+	if e.Realm != "" {
+		fields = append(fields, zap.String("realm", e.Realm))
+	}
+	if e.Method != "" {
+		fields = append(fields, zap.String("method", e.Method))
+	}
+	if e.Username != "" {
+		fields = append(fields, zap.String("username", e.Username))
+	}
+	if e.Email != "" {
+		fields = append(fields, zap.String("email", e.Email))
+	}
+	if e.SourceAddress != "" {
+		fields = append(fields, zap.String("src_ip", e.SourceAddress))
+	}
+	if e.Country != "" {
+		fields = append(fields, zap.String("country", e.Country))
+	}
+	if e.City != "" {
+		fields = append(fields, zap.String("city", e.City))
+	}
+	if len(e.Data) > 0 {
+		fields = append(fields, zap.Any("data", e.Data))
+	}
+	switch e.Type {
+	case LoginFailure, UserLocked, AddressBanned, HoneypotLogin:
+		s.logger.Warn("Audit", fields...)
+	default:
+		s.logger.Info("Audit", fields...)
+	}
+}
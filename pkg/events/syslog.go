@@ -0,0 +1,100 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	syslogFacilityAuthPriv = 10
+	syslogDialTimeout      = 5 * time.Second
+	syslogWriteTimeout     = 5 * time.Second
+)
+
+// syslogSender sends the events as RFC 5424 syslog messages.
+type syslogSender struct {
+	config   *SinkConfig
+	hostname string
+	format   func(*Event) string
+	conn     net.Conn
+}
+
+func newSyslogSender(cfg *SinkConfig) *syslogSender {
+	s := &syslogSender{
+		config: cfg,
+		format: FormatCEF,
+	}
+	if cfg.Format == "leef" {
+		s.format = FormatLEEF
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	return s
+}
+
+func (s *syslogSender) send(batch []*Event) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.config.Network, s.config.Address, syslogDialTimeout)
+		if err != nil {
+			return errors.ErrAuditSinkDelivery.WithArgs(s.config.Name, err)
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	for _, e := range batch {
+		msg := s.buildMessage(e)
+		if s.config.Network == "tcp" {
+			// Non-transparent framing, see RFC 6587.
+			msg += "\n"
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return errors.ErrAuditSinkDelivery.WithArgs(s.config.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *syslogSender) buildMessage(e *Event) string {
+	severity := 6
+	if getSeverity(e) >= 5 {
+		severity = 4
+	}
+	var sb strings.Builder
+	sb.WriteString("<" + strconv.Itoa(syslogFacilityAuthPriv*8+severity) + ">1 ")
+	sb.WriteString(e.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	sb.WriteString(" " + s.hostname + " authcrunch - " + e.Type + " - ")
+	sb.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(s.format(e)))
+	return sb.String()
+}
+
+func (s *syslogSender) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config is the configuration of the Envoy external authorization service.
+// The gRPC and HTTP variants of the service share the authorization policy.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// GRPCAddress is the address of the gRPC service, e.g. 127.0.0.1:9191.
+	GRPCAddress string `json:"grpc_address,omitempty" xml:"grpc_address,omitempty" yaml:"grpc_address,omitempty"`
+	// HTTPAddress is the address of the HTTP service, e.g. 127.0.0.1:9192.
+	HTTPAddress string `json:"http_address,omitempty" xml:"http_address,omitempty" yaml:"http_address,omitempty"`
+	// PathPrefix is the path_prefix of the Envoy HTTP service. It is removed
+	// from the path of the authorization requests.
+	PathPrefix string `json:"path_prefix,omitempty" xml:"path_prefix,omitempty" yaml:"path_prefix,omitempty"`
+	// AuthorizationPolicy is the name of the authorization policy making the
+	// access decisions.
+	AuthorizationPolicy string `json:"authorization_policy,omitempty" xml:"authorization_policy,omitempty" yaml:"authorization_policy,omitempty"`
+}
+
+// Validate validates ext_authz server config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrExtAuthzConfigNameEmpty
+	}
+	if cfg.GRPCAddress == "" && cfg.HTTPAddress == "" {
+		return errors.ErrExtAuthzConfigAddressEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.AuthorizationPolicy == "" {
+		return errors.ErrExtAuthzConfigPolicyEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.PathPrefix != "" && !strings.HasPrefix(cfg.PathPrefix, "/") {
+		return errors.ErrExtAuthzConfigPathPrefix.WithArgs(cfg.Name, cfg.PathPrefix)
+	}
+	cfg.PathPrefix = strings.TrimSuffix(cfg.PathPrefix, "/")
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid ext_authz config",
+			config: &Config{
+				Name:                "envoy",
+				GRPCAddress:         "127.0.0.1:9191",
+				HTTPAddress:         "127.0.0.1:9192",
+				PathPrefix:          "/authz/",
+				AuthorizationPolicy: "mypolicy",
+			},
+		},
+		{
+			name:      "test ext_authz config without name",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrExtAuthzConfigNameEmpty,
+		},
+		{
+			name: "test ext_authz config without addresses",
+			config: &Config{
+				Name: "envoy",
+			},
+			shouldErr: true,
+			err:       errors.ErrExtAuthzConfigAddressEmpty.WithArgs("envoy"),
+		},
+		{
+			name: "test ext_authz config without authorization policy",
+			config: &Config{
+				Name:        "envoy",
+				GRPCAddress: "127.0.0.1:9191",
+			},
+			shouldErr: true,
+			err:       errors.ErrExtAuthzConfigPolicyEmpty.WithArgs("envoy"),
+		},
+		{
+			name: "test ext_authz config with relative path prefix",
+			config: &Config{
+				Name:                "envoy",
+				HTTPAddress:         "127.0.0.1:9192",
+				PathPrefix:          "authz",
+				AuthorizationPolicy: "mypolicy",
+			},
+			shouldErr: true,
+			err:       errors.ErrExtAuthzConfigPathPrefix.WithArgs("envoy", "authz"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/extauthz/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CheckMethod is the full name of the method called by the Envoy ext_authz
+// filter with grpc_service.
+const CheckMethod = "/envoy.service.auth.v3.Authorization/Check"
+
+type authorizationServer interface {
+	Check(context.Context, *pb.CheckRequest) (*pb.CheckResponse, error)
+}
+
+// authorizationServiceDesc is envoy.service.auth.v3.Authorization. It is
+// declared by hand, because the messages are not in the Envoy package.
+var authorizationServiceDesc = grpc.ServiceDesc{
+	ServiceName: "envoy.service.auth.v3.Authorization",
+	HandlerType: (*authorizationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    checkHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "envoy/service/auth/v3/external_auth.proto",
+}
+
+func checkHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(authorizationServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CheckMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(authorizationServer).Check(ctx, req.(*pb.CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Check authorizes the HTTP request described by the attributes of the
+// check request.
+func (srv *Server) Check(ctx context.Context, req *pb.CheckRequest) (*pb.CheckResponse, error) {
+	r, err := newCheckHTTPRequest(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	d := authz.Decide(srv.authorizer, r)
+	srv.logDecision("grpc", r, d)
+
+	if d.Allowed {
+		resp := &pb.CheckResponse{
+			Status: &pb.Status{Code: int32(codes.OK)},
+		}
+		ok := &pb.OkHttpResponse{
+			Headers:         newHeaderValueOptions(d.UpstreamHeaders),
+			HeadersToRemove: d.RemovedHeaders,
+		}
+		resp.HttpResponse = &pb.CheckResponse_OkResponse{OkResponse: ok}
+		return resp, nil
+	}
+
+	code := codes.PermissionDenied
+	if d.StatusCode == http.StatusUnauthorized {
+		code = codes.Unauthenticated
+	}
+	resp := &pb.CheckResponse{
+		Status: &pb.Status{Code: int32(code), Message: d.Reason},
+	}
+	denied := &pb.DeniedHttpResponse{
+		Status:  &pb.HttpStatus{Code: int32(d.StatusCode)},
+		Headers: newHeaderValueOptions(d.ResponseHeaders),
+		Body:    string(d.Body),
+	}
+	resp.HttpResponse = &pb.CheckResponse_DeniedResponse{DeniedResponse: denied}
+	return resp, nil
+}
+
+// newCheckHTTPRequest maps the attributes of the check request to an HTTP
+// request.
+func newCheckHTTPRequest(ctx context.Context, req *pb.CheckRequest) (*http.Request, error) {
+	attrs := req.GetAttributes()
+	hr := attrs.GetRequest().GetHttp()
+	if hr == nil {
+		return nil, errors.ErrExtAuthzMalformedRequest.WithArgs("http request attributes not found")
+	}
+
+	headers := make(http.Header)
+	for k, v := range hr.Headers {
+		// Skip HTTP/2 pseudo-headers, e.g. :authority and :path.
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		headers.Set(k, v)
+	}
+
+	scheme := hr.Scheme
+	if scheme == "" {
+		scheme = headers.Get("X-Forwarded-Proto")
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	host := hr.Host
+	if host == "" {
+		host = hr.Headers[":authority"]
+	}
+	path := hr.Path
+	if path == "" {
+		path = hr.Headers[":path"]
+	}
+	method := hr.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, scheme+"://"+host+path, nil)
+	if err != nil || host == "" {
+		return nil, errors.ErrExtAuthzMalformedRequest.WithArgs("invalid url " + scheme + "://" + host + path)
+	}
+	r.Header = headers
+	r.Host = host
+	if sa := attrs.GetSource().GetAddress().GetSocketAddress(); sa != nil && sa.Address != "" {
+		r.RemoteAddr = net.JoinHostPort(sa.Address, strconv.Itoa(int(sa.PortValue)))
+	}
+	return r, nil
+}
+
+func newHeaderValueOptions(h http.Header) []*pb.HeaderValueOption {
+	var opts []*pb.HeaderValueOption
+	for k, values := range h {
+		for _, v := range values {
+			opts = append(opts, &pb.HeaderValueOption{
+				Header: &pb.HeaderValue{Key: k, Value: v},
+			})
+		}
+	}
+	return opts
+}
+
+func (srv *Server) logDecision(variant string, r *http.Request, d *authz.Decision) {
+	srv.logger.Debug(
+		"ext_authz decision",
+		zap.String("name", srv.config.Name),
+		zap.String("variant", variant),
+		zap.String("method", r.Method),
+		zap.String("url", r.URL.String()),
+		zap.Bool("allowed", d.Allowed),
+		zap.Int("status_code", d.StatusCode),
+		zap.String("reason", d.Reason),
+	)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+)
+
+// ServeHTTP implements the HTTP variant of the Envoy external authorization
+// protocol, i.e. the ext_authz filter with http_service. Envoy forwards the
+// method, the path, and the allowed headers of the original request. The
+// request is allowed when the response status is 200, and the headers of the
+// response are added to the upstream request. Otherwise, the response is
+// returned to the client.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.RequestURI(), srv.config.PathPrefix)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	scheme := r.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, scheme+"://"+r.Host+path, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`400 Bad Request`))
+		return
+	}
+	req.Header = r.Header.Clone()
+	req.Host = r.Host
+	req.RemoteAddr = r.RemoteAddr
+
+	d := authz.Decide(srv.authorizer, req)
+	srv.logDecision("http", req, d)
+
+	if d.Allowed {
+		for k, values := range d.UpstreamHeaders {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for k, values := range d.ResponseHeaders {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(d.StatusCode)
+	w.Write(d.Body)
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Server implements the Envoy external authorization protocol, i.e. the
+// ext_authz filter, with the access decisions of an authorization policy.
+type Server struct {
+	config       *Config
+	authorizer   authz.Authorizer
+	logger       *zap.Logger
+	grpcServer   *grpc.Server
+	httpServer   *http.Server
+	grpcListener net.Listener
+	httpListener net.Listener
+	mu           sync.Mutex
+	wg           sync.WaitGroup
+}
+
+// NewServer returns an instance of Server.
+func NewServer(cfg *Config, authorizer authz.Authorizer, logger *zap.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if authorizer == nil {
+		return nil, errors.ErrExtAuthzPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy)
+	}
+	srv := &Server{
+		config:     cfg,
+		authorizer: authorizer,
+		logger:     logger,
+	}
+	srv.grpcServer = grpc.NewServer()
+	srv.grpcServer.RegisterService(&authorizationServiceDesc, srv)
+	srv.httpServer = &http.Server{
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv, nil
+}
+
+// GetName returns the name of the server.
+func (srv *Server) GetName() string {
+	return srv.config.Name
+}
+
+// GetGRPCAddress returns the address the gRPC service listens on.
+func (srv *Server) GetGRPCAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.grpcListener != nil {
+		return srv.grpcListener.Addr().String()
+	}
+	return srv.config.GRPCAddress
+}
+
+// GetHTTPAddress returns the address the HTTP service listens on.
+func (srv *Server) GetHTTPAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.httpListener != nil {
+		return srv.httpListener.Addr().String()
+	}
+	return srv.config.HTTPAddress
+}
+
+// Start starts accepting the authorization requests of Envoy.
+func (srv *Server) Start() error {
+	var grpcListener, httpListener net.Listener
+	var err error
+	if srv.config.GRPCAddress != "" {
+		grpcListener, err = net.Listen("tcp", srv.config.GRPCAddress)
+		if err != nil {
+			return errors.ErrExtAuthzStartFailed.WithArgs(srv.config.Name, err)
+		}
+	}
+	if srv.config.HTTPAddress != "" {
+		httpListener, err = net.Listen("tcp", srv.config.HTTPAddress)
+		if err != nil {
+			if grpcListener != nil {
+				grpcListener.Close()
+			}
+			return errors.ErrExtAuthzStartFailed.WithArgs(srv.config.Name, err)
+		}
+	}
+
+	srv.mu.Lock()
+	srv.grpcListener = grpcListener
+	srv.httpListener = httpListener
+	srv.mu.Unlock()
+
+	if grpcListener != nil {
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.grpcServer.Serve(grpcListener)
+		}()
+	}
+	if httpListener != nil {
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			srv.httpServer.Serve(httpListener)
+		}()
+	}
+
+	srv.logger.Info(
+		"started ext_authz server",
+		zap.String("name", srv.config.Name),
+		zap.String("grpc_address", srv.GetGRPCAddress()),
+		zap.String("http_address", srv.GetHTTPAddress()),
+		zap.String("authorization_policy", srv.config.AuthorizationPolicy),
+	)
+	return nil
+}
+
+// Stop stops the server.
+func (srv *Server) Stop() error {
+	srv.mu.Lock()
+	grpcListener := srv.grpcListener
+	httpListener := srv.httpListener
+	srv.grpcListener = nil
+	srv.httpListener = nil
+	srv.mu.Unlock()
+
+	var err error
+	if grpcListener != nil {
+		srv.grpcServer.Stop()
+	}
+	if httpListener != nil {
+		err = srv.httpServer.Shutdown(context.Background())
+	}
+	srv.wg.Wait()
+	return err
+}
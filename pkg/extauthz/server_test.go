@@ -0,0 +1,219 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extauthz
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/extauthz/pb"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// testAuthorizer authorizes the requests with the "foobar" bearer token
+// from 10.0.0.1, and redirects the others to the login page.
+type testAuthorizer struct{}
+
+func (a *testAuthorizer) Authenticate(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	if r.Header.Get("Authorization") != "Bearer foobar" || addrutil.GetSourceAddress(r) != "10.0.0.1" {
+		w.Header().Set("Location", "https://auth.local/login?redirect_url="+r.URL.String())
+		w.WriteHeader(http.StatusFound)
+		w.Write([]byte(`302 Found`))
+		return errors.ErrNoTokenFound
+	}
+	ar.Response.Authorized = true
+	ar.Response.User = map[string]interface{}{"email": "jsmith@localhost"}
+	r.Header.Set("X-Token-User-Email", "jsmith@localhost")
+	r.Header.Del("Authorization")
+	return nil
+}
+
+func newTestServer(t *testing.T) *Server {
+	cfg := &Config{
+		Name:                "envoy",
+		GRPCAddress:         "127.0.0.1:0",
+		HTTPAddress:         "127.0.0.1:0",
+		PathPrefix:          "/authz",
+		AuthorizationPolicy: "mypolicy",
+	}
+	srv, err := NewServer(cfg, &testAuthorizer{}, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+	return srv
+}
+
+func TestGRPCCheck(t *testing.T) {
+	srv := newTestServer(t)
+	conn, err := grpc.Dial(srv.GetGRPCAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	newCheckRequest := func(auth string) *pb.CheckRequest {
+		return &pb.CheckRequest{
+			Attributes: &pb.AttributeContext{
+				Source: &pb.AttributeContext_Peer{
+					Address: &pb.Address{
+						Address: &pb.Address_SocketAddress{
+							SocketAddress: &pb.SocketAddress{Address: "10.0.0.1", PortValue: 443},
+						},
+					},
+				},
+				Request: &pb.AttributeContext_Request{
+					Http: &pb.AttributeContext_HttpRequest{
+						Method: "GET",
+						Scheme: "https",
+						Host:   "app.local",
+						Path:   "/foo?bar=baz",
+						Headers: map[string]string{
+							":authority":    "app.local",
+							"authorization": auth,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name string
+		req  *pb.CheckRequest
+		want map[string]interface{}
+	}{
+		{
+			name: "test allowed check request",
+			req:  newCheckRequest("Bearer foobar"),
+			want: map[string]interface{}{
+				"code":              int32(0),
+				"headers":           map[string]string{"X-Token-User-Email": "jsmith@localhost"},
+				"headers_to_remove": []string{"Authorization"},
+			},
+		},
+		{
+			name: "test denied check request",
+			req:  newCheckRequest("Bearer barfoo"),
+			want: map[string]interface{}{
+				"code":        int32(7),
+				"http_status": int32(302),
+				"headers":     map[string]string{"Location": "https://auth.local/login?redirect_url=https://app.local/foo?bar=baz"},
+				"body":        "302 Found",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			resp := &pb.CheckResponse{}
+			if err := conn.Invoke(context.Background(), CheckMethod, tc.req, resp); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"code": resp.GetStatus().GetCode(),
+			}
+			headers := make(map[string]string)
+			if ok := resp.GetOkResponse(); ok != nil {
+				for _, h := range ok.Headers {
+					headers[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+				}
+				got["headers_to_remove"] = ok.HeadersToRemove
+			}
+			if denied := resp.GetDeniedResponse(); denied != nil {
+				for _, h := range denied.Headers {
+					headers[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+				}
+				got["http_status"] = denied.GetStatus().GetCode()
+				got["body"] = denied.Body
+			}
+			got["headers"] = headers
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	srv := newTestServer(t)
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	testcases := []struct {
+		name string
+		auth string
+		want map[string]interface{}
+	}{
+		{
+			name: "test allowed check request",
+			auth: "Bearer foobar",
+			want: map[string]interface{}{
+				"status_code": 200,
+				"header":      "jsmith@localhost",
+			},
+		},
+		{
+			name: "test denied check request",
+			auth: "Bearer barfoo",
+			want: map[string]interface{}{
+				"status_code": 302,
+				"location":    "https://auth.local/login?redirect_url=https://app.local/foo?bar=baz",
+				"body":        "302 Found",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			req, _ := http.NewRequest("GET", "http://"+srv.GetHTTPAddress()+"/authz/foo?bar=baz", nil)
+			req.Host = "app.local"
+			req.Header.Set("Authorization", tc.auth)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("X-Forwarded-For", "10.0.0.1")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			got := map[string]interface{}{
+				"status_code": resp.StatusCode,
+			}
+			if resp.StatusCode == 200 {
+				got["header"] = resp.Header.Get("X-Token-User-Email")
+			} else {
+				got["location"] = resp.Header.Get("Location")
+				got["body"] = string(body)
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ABIVersion is the version of the interface between the host and
+// WebAssembly modules.
+//
+// A module must export the following:
+//
+//   - memory: the linear memory used to exchange data.
+//   - abi_version() -> i32: returns ABIVersion.
+//   - alloc(size i32) -> i32: returns a pointer to size bytes of memory.
+//
+// Additionally, a module exports at least one of the following:
+//
+//   - transform(ptr i32, len i32) -> i64: receives user claims as a JSON
+//     object and returns the transformed claims as a JSON object. The
+//     returned value packs the pointer in the upper 32 bits and the length
+//     in the lower 32 bits.
+//   - evaluate(ptr i32, len i32) -> i32: receives a JSON object with
+//     "field" and "value" keys and returns 1 when the condition matches.
+const ABIVersion = 1
+
+const defaultTimeout = 100
+
+// Config holds the configuration of Extension.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// The path to WebAssembly module file.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// The timeout in milliseconds of transform and evaluate calls. When
+	// a call exceeds the timeout, the module is closed and the subsequent
+	// calls fail.
+	Timeout int `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Extension is an instance of WebAssembly module implementing claim
+// transforms and/or ACL conditions.
+type Extension struct {
+	mu        sync.Mutex
+	name      string
+	runtime   wazero.Runtime
+	module    api.Module
+	alloc     api.Function
+	transform api.Function
+	evaluate  api.Function
+	timeout   time.Duration
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrExtensionConfigNameEmpty
+	}
+	if cfg.Path == "" {
+		return errors.ErrExtensionConfigPathEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.Timeout < 0 {
+		return errors.ErrExtensionConfigTimeoutInvalid.WithArgs(cfg.Name, cfg.Timeout)
+	}
+	return nil
+}
+
+// NewExtension returns an instance of Extension loaded from the module
+// referenced in Config.
+func NewExtension(cfg *Config) (*Extension, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, errors.ErrExtensionRead.WithArgs(cfg.Name, err)
+	}
+	ext, err := NewExtensionFromBytes(cfg.Name, b)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Timeout > 0 {
+		ext.timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+	return ext, nil
+}
+
+// NewExtensionFromBytes returns an instance of Extension loaded from the
+// WebAssembly binary.
+func NewExtensionFromBytes(name string, b []byte) (*Extension, error) {
+	ctx := context.Background()
+	// The executions are interrupted when the context deadline is exceeded.
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	compiled, err := r.CompileModule(ctx, b)
+	if err != nil {
+		r.Close(ctx)
+		return nil, errors.ErrExtensionCompile.WithArgs(name, err)
+	}
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(name))
+	if err != nil {
+		r.Close(ctx)
+		return nil, errors.ErrExtensionInstantiate.WithArgs(name, err)
+	}
+	ext := &Extension{
+		name:      name,
+		runtime:   r,
+		module:    mod,
+		alloc:     mod.ExportedFunction("alloc"),
+		transform: mod.ExportedFunction("transform"),
+		evaluate:  mod.ExportedFunction("evaluate"),
+		timeout:   defaultTimeout * time.Millisecond,
+	}
+	if err := ext.validate(ctx); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	return ext, nil
+}
+
+func (ext *Extension) validate(ctx context.Context) error {
+	if ext.module.Memory() == nil {
+		return errors.ErrExtensionExportNotFound.WithArgs(ext.name, "memory")
+	}
+	if ext.alloc == nil {
+		return errors.ErrExtensionExportNotFound.WithArgs(ext.name, "alloc")
+	}
+	fn := ext.module.ExportedFunction("abi_version")
+	if fn == nil {
+		return errors.ErrExtensionExportNotFound.WithArgs(ext.name, "abi_version")
+	}
+	results, err := fn.Call(ctx)
+	if err != nil {
+		return errors.ErrExtensionCall.WithArgs(ext.name, "abi_version", err)
+	}
+	if len(results) != 1 || uint32(results[0]) != ABIVersion {
+		var v uint64
+		if len(results) > 0 {
+			v = results[0]
+		}
+		return errors.ErrExtensionUnsupportedABIVersion.WithArgs(ext.name, v)
+	}
+	if ext.transform == nil && ext.evaluate == nil {
+		return errors.ErrExtensionNoCapabilities.WithArgs(ext.name)
+	}
+	return nil
+}
+
+// GetName returns the name of Extension.
+func (ext *Extension) GetName() string {
+	return ext.name
+}
+
+// CanTransform returns true if Extension implements claim transforms.
+func (ext *Extension) CanTransform() bool {
+	return ext.transform != nil
+}
+
+// CanEvaluate returns true if Extension implements ACL conditions.
+func (ext *Extension) CanEvaluate() bool {
+	return ext.evaluate != nil
+}
+
+// Transform passes user claims to the module and replaces them with the
+// claims returned by the module.
+func (ext *Extension) Transform(m map[string]interface{}) error {
+	if ext.transform == nil {
+		return errors.ErrExtensionTransformNotSupported.WithArgs(ext.name)
+	}
+	input, err := json.Marshal(m)
+	if err != nil {
+		return errors.ErrExtensionInput.WithArgs(ext.name, err)
+	}
+
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), ext.timeout)
+	defer cancel()
+	results, err := ext.call(ctx, "transform", ext.transform, input)
+	if err != nil {
+		return err
+	}
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	output, ok := ext.module.Memory().Read(ptr, size)
+	if !ok {
+		return errors.ErrExtensionMemoryAccess.WithArgs(ext.name)
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(output, &claims); err != nil {
+		return errors.ErrExtensionOutput.WithArgs(ext.name, err)
+	}
+	for k := range m {
+		delete(m, k)
+	}
+	for k, v := range claims {
+		m[k] = v
+	}
+	return nil
+}
+
+// Evaluate passes the name and the value of a field to the module and
+// returns true when the module reports a match.
+func (ext *Extension) Evaluate(field string, value interface{}) (bool, error) {
+	if ext.evaluate == nil {
+		return false, errors.ErrExtensionEvaluateNotSupported.WithArgs(ext.name)
+	}
+	input, err := json.Marshal(map[string]interface{}{
+		"field": field,
+		"value": value,
+	})
+	if err != nil {
+		return false, errors.ErrExtensionInput.WithArgs(ext.name, err)
+	}
+
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), ext.timeout)
+	defer cancel()
+	results, err := ext.call(ctx, "evaluate", ext.evaluate, input)
+	if err != nil {
+		return false, err
+	}
+	return uint32(results[0]) == 1, nil
+}
+
+func (ext *Extension) call(ctx context.Context, fnName string, fn api.Function, input []byte) ([]uint64, error) {
+	results, err := ext.alloc.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, errors.ErrExtensionCall.WithArgs(ext.name, "alloc", err)
+	}
+	if len(results) != 1 {
+		return nil, errors.ErrExtensionCall.WithArgs(ext.name, "alloc", "unexpected number of results")
+	}
+	ptr := uint32(results[0])
+	if !ext.module.Memory().Write(ptr, input) {
+		return nil, errors.ErrExtensionMemoryAccess.WithArgs(ext.name)
+	}
+	results, err = fn.Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, errors.ErrExtensionCall.WithArgs(ext.name, fnName, err)
+	}
+	if len(results) != 1 {
+		return nil, errors.ErrExtensionCall.WithArgs(ext.name, fnName, "unexpected number of results")
+	}
+	return results, nil
+}
+
+// Close releases the resources held by Extension.
+func (ext *Extension) Close() error {
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	return ext.runtime.Close(context.Background())
+}
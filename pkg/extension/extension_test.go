@@ -0,0 +1,184 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extension
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewExtension(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "load extension",
+			config: &Config{
+				Name: "claims",
+				Path: "../../testdata/extension/claims.wasm",
+			},
+			want: map[string]interface{}{
+				"name":          "claims",
+				"can_transform": true,
+				"can_evaluate":  true,
+			},
+		},
+		{
+			name:      "empty name",
+			config:    &Config{Path: "../../testdata/extension/claims.wasm"},
+			shouldErr: true,
+			err:       errors.ErrExtensionConfigNameEmpty,
+		},
+		{
+			name:      "empty path",
+			config:    &Config{Name: "claims"},
+			shouldErr: true,
+			err:       errors.ErrExtensionConfigPathEmpty.WithArgs("claims"),
+		},
+		{
+			name: "invalid timeout",
+			config: &Config{
+				Name:    "claims",
+				Path:    "../../testdata/extension/claims.wasm",
+				Timeout: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrExtensionConfigTimeoutInvalid.WithArgs("claims", -1),
+		},
+		{
+			name: "not a webassembly module",
+			config: &Config{
+				Name: "claims",
+				Path: "../../testdata/extension/claims.wat",
+			},
+			shouldErr: true,
+			err:       errors.ErrExtensionCompile.WithArgs("claims", "invalid magic number"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			ext, err := NewExtension(tc.config)
+			if tests.EvalErrWithLog(t, err, "extension", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			defer ext.Close()
+			got := map[string]interface{}{
+				"name":          ext.GetName(),
+				"can_transform": ext.CanTransform(),
+				"can_evaluate":  ext.CanEvaluate(),
+			}
+			tests.EvalObjectsWithLog(t, "extension", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestExtensionTransform(t *testing.T) {
+	ext, err := NewExtension(&Config{Name: "claims", Path: "../../testdata/extension/claims.wasm"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ext.Close()
+
+	m := map[string]interface{}{
+		"sub":   "jsmith",
+		"email": "jsmith@localhost.localdomain",
+	}
+	if err := ext.Transform(m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"sub":   "wasm",
+		"roles": []interface{}{"authp/user"},
+	}
+	tests.EvalObjects(t, "transform", want, m)
+}
+
+func TestExtensionEvaluate(t *testing.T) {
+	ext, err := NewExtension(&Config{Name: "claims", Path: "../../testdata/extension/claims.wasm"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ext.Close()
+
+	testcases := []struct {
+		name  string
+		field string
+		value interface{}
+		want  bool
+	}{
+		{name: "match role admin", field: "roles", value: "admin", want: true},
+		{name: "mismatch role user", field: "roles", value: "user", want: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ext.Evaluate(tc.field, tc.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("unexpected evaluation result: got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtensionTimeout(t *testing.T) {
+	ext, err := NewExtension(&Config{Name: "loop", Path: "../../testdata/extension/loop.wasm", Timeout: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ext.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ext.Evaluate("roles", "admin")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("evaluation did not time out")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ext, err := NewExtension(&Config{Name: "registered", Path: "../../testdata/extension/claims.wasm"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	Register(ext)
+	got, err := Get("registered")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != ext {
+		t.Fatalf("unexpected extension returned by registry")
+	}
+	if _, err := Get("unknown"); err == nil {
+		t.Fatalf("expected error for unknown extension")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extension
+
+import (
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Extension)
+)
+
+// Register adds Extension to the registry of loaded extensions. The
+// extension previously registered under the same name is closed.
+func Register(ext *Extension) {
+	registryMu.Lock()
+	prev, exists := registry[ext.name]
+	registry[ext.name] = ext
+	registryMu.Unlock()
+	if exists && prev != ext {
+		prev.Close()
+	}
+}
+
+// Get returns Extension registered under the provided name.
+func Get(name string) (*Extension, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ext, exists := registry[name]
+	if !exists {
+		return nil, errors.ErrExtensionNotFound.WithArgs(name)
+	}
+	return ext, nil
+}
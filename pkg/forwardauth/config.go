@@ -0,0 +1,95 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwardauth
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	// ModeNginx responds to the denied requests with 401 Unauthorized, as
+	// required by nginx auth_request. The sign-in URL is in the redirect
+	// header.
+	ModeNginx = "nginx"
+	// ModeTraefik responds to the denied requests with the response of the
+	// authorization policy, e.g. the redirect to the sign-in page, as
+	// returned to the client by Traefik forwardAuth.
+	ModeTraefik = "traefik"
+
+	defaultPath           = "/validate"
+	defaultTokenName      = "access_token"
+	defaultRedirectHeader = "X-Auth-Redirect"
+)
+
+// Config is the configuration of the forward authentication endpoint
+// compatible with nginx auth_request and Traefik forwardAuth.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Address is the address the endpoint listens on, e.g. 127.0.0.1:9193.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// Path is the path of the endpoint. The default is /validate.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Mode is either nginx (default) or traefik.
+	Mode string `json:"mode,omitempty" xml:"mode,omitempty" yaml:"mode,omitempty"`
+	// AuthorizationPolicy is the name of the authorization policy making the
+	// access decisions.
+	AuthorizationPolicy string `json:"authorization_policy,omitempty" xml:"authorization_policy,omitempty" yaml:"authorization_policy,omitempty"`
+	// TokenHeaders and TokenCookies are the additional headers and cookies
+	// with the tokens, e.g. X-Auth-Token. The tokens are passed to the
+	// authorization policy as TokenName.
+	TokenHeaders []string `json:"token_headers,omitempty" xml:"token_headers,omitempty" yaml:"token_headers,omitempty"`
+	TokenCookies []string `json:"token_cookies,omitempty" xml:"token_cookies,omitempty" yaml:"token_cookies,omitempty"`
+	// TokenName is the token name of the authorization policy. The default
+	// is access_token.
+	TokenName string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
+	// RedirectHeader is the response header with the sign-in URL. The
+	// default is X-Auth-Redirect.
+	RedirectHeader string `json:"redirect_header,omitempty" xml:"redirect_header,omitempty" yaml:"redirect_header,omitempty"`
+}
+
+// Validate validates forward auth config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrForwardAuthConfigNameEmpty
+	}
+	if cfg.Address == "" {
+		return errors.ErrForwardAuthConfigAddressEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.AuthorizationPolicy == "" {
+		return errors.ErrForwardAuthConfigPolicyEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return errors.ErrForwardAuthConfigPath.WithArgs(cfg.Name, cfg.Path)
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeNginx
+	case ModeNginx, ModeTraefik:
+	default:
+		return errors.ErrForwardAuthConfigMode.WithArgs(cfg.Name, cfg.Mode)
+	}
+	if cfg.TokenName == "" {
+		cfg.TokenName = defaultTokenName
+	}
+	if cfg.RedirectHeader == "" {
+		cfg.RedirectHeader = defaultRedirectHeader
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwardauth
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid forward auth config with defaults",
+			config: &Config{
+				Name:                "nginx",
+				Address:             "127.0.0.1:9193",
+				AuthorizationPolicy: "mypolicy",
+			},
+			want: &Config{
+				Name:                "nginx",
+				Address:             "127.0.0.1:9193",
+				Path:                "/validate",
+				Mode:                "nginx",
+				AuthorizationPolicy: "mypolicy",
+				TokenName:           "access_token",
+				RedirectHeader:      "X-Auth-Redirect",
+			},
+		},
+		{
+			name:      "test forward auth config without name",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrForwardAuthConfigNameEmpty,
+		},
+		{
+			name: "test forward auth config without address",
+			config: &Config{
+				Name: "nginx",
+			},
+			shouldErr: true,
+			err:       errors.ErrForwardAuthConfigAddressEmpty.WithArgs("nginx"),
+		},
+		{
+			name: "test forward auth config without authorization policy",
+			config: &Config{
+				Name:    "nginx",
+				Address: "127.0.0.1:9193",
+			},
+			shouldErr: true,
+			err:       errors.ErrForwardAuthConfigPolicyEmpty.WithArgs("nginx"),
+		},
+		{
+			name: "test forward auth config with relative path",
+			config: &Config{
+				Name:                "nginx",
+				Address:             "127.0.0.1:9193",
+				AuthorizationPolicy: "mypolicy",
+				Path:                "validate",
+			},
+			shouldErr: true,
+			err:       errors.ErrForwardAuthConfigPath.WithArgs("nginx", "validate"),
+		},
+		{
+			name: "test forward auth config with unsupported mode",
+			config: &Config{
+				Name:                "nginx",
+				Address:             "127.0.0.1:9193",
+				AuthorizationPolicy: "mypolicy",
+				Mode:                "apache",
+			},
+			shouldErr: true,
+			err:       errors.ErrForwardAuthConfigMode.WithArgs("nginx", "apache"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "config", tc.want, tc.config, msgs)
+		})
+	}
+}
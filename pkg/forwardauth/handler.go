@@ -0,0 +1,168 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwardauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cookieutil "github.com/greenpau/go-authcrunch/pkg/authn/cookie"
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"go.uber.org/zap"
+)
+
+// The identity response headers, as in oauth2-proxy.
+var identityHeaders = map[string]string{
+	"X-Auth-Request-User":  "id",
+	"X-Auth-Request-Email": "email",
+	"X-Auth-Request-Name":  "name",
+	"X-Auth-Request-Roles": "roles",
+}
+
+// ServeHTTP validates the authentication subrequest. The original request is
+// described by the X-Original-* headers set by nginx, or the X-Forwarded-*
+// headers set by Traefik. The allowed requests get 200 OK with the identity
+// response headers.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := srv.newOriginalRequest(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`400 Bad Request`))
+		return
+	}
+
+	d := authz.Decide(srv.authorizer, req)
+	srv.logger.Debug(
+		"forward auth decision",
+		zap.String("name", srv.config.Name),
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.Bool("allowed", d.Allowed),
+		zap.Int("status_code", d.StatusCode),
+		zap.String("reason", d.Reason),
+	)
+
+	if d.Allowed {
+		for k, values := range d.UpstreamHeaders {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		for k, field := range identityHeaders {
+			if v, exists := d.Identity[field]; exists {
+				w.Header().Set(k, fmt.Sprintf("%v", v))
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if srv.config.Mode == ModeTraefik {
+		for k, values := range d.ResponseHeaders {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(d.StatusCode)
+		w.Write(d.Body)
+		return
+	}
+
+	// The nginx auth_request accepts 401 and 403 only.
+	if d.StatusCode == http.StatusForbidden {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`403 Forbidden`))
+		return
+	}
+	if location := d.ResponseHeaders.Get("Location"); location != "" {
+		w.Header().Set(srv.config.RedirectHeader, location)
+	}
+	for _, v := range d.ResponseHeaders.Values("Set-Cookie") {
+		w.Header().Add("Set-Cookie", v)
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`401 Unauthorized`))
+}
+
+// newOriginalRequest returns the original request of the subrequest.
+func (srv *Server) newOriginalRequest(r *http.Request) (*http.Request, error) {
+	method := firstHeader(r, "X-Original-Method", "X-Forwarded-Method")
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	rawURL := r.Header.Get("X-Original-Url")
+	if rawURL == "" {
+		scheme := r.Header.Get("X-Forwarded-Proto")
+		if scheme == "" {
+			scheme = "http"
+		}
+		host := firstHeader(r, "X-Forwarded-Host")
+		if host == "" {
+			host = r.Host
+		}
+		uri := firstHeader(r, "X-Original-Uri", "X-Forwarded-Uri")
+		if uri == "" {
+			uri = "/"
+		}
+		rawURL = scheme + "://" + host + uri
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("malformed url %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+	req.Host = u.Host
+	req.RemoteAddr = r.RemoteAddr
+
+	if token := srv.findToken(r); token != "" {
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", srv.config.TokenName+"="+token)
+		}
+		req.AddCookie(&http.Cookie{Name: srv.config.TokenName, Value: token})
+	}
+	return req, nil
+}
+
+// findToken returns the token in the configured headers and cookies.
+func (srv *Server) findToken(r *http.Request) string {
+	for _, k := range srv.config.TokenHeaders {
+		if v := strings.TrimSpace(r.Header.Get(k)); v != "" {
+			return strings.TrimSpace(strings.TrimPrefix(v, "Bearer "))
+		}
+	}
+	for _, k := range srv.config.TokenCookies {
+		if v, found := cookieutil.JoinChunks(r.Cookies(), k); found && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstHeader(r *http.Request, keys ...string) string {
+	for _, k := range keys {
+		if v := r.Header.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwardauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+var testToken = strings.Repeat("a", 40)
+
+// testAuthorizer authorizes the requests with the test token in the
+// access_token cookie, forbids the requests to /admin, and redirects the
+// others to the login page.
+type testAuthorizer struct{}
+
+func (a *testAuthorizer) Authenticate(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	cookie, err := r.Cookie("access_token")
+	if err != nil || cookie.Value != testToken {
+		w.Header().Set("Location", "https://auth.local/login?redirect_url="+r.URL.String())
+		w.WriteHeader(http.StatusFound)
+		return errors.ErrNoTokenFound
+	}
+	if strings.HasPrefix(r.URL.Path, "/admin") {
+		w.WriteHeader(http.StatusForbidden)
+		return errors.ErrAccessNotAllowed
+	}
+	ar.Response.Authorized = true
+	ar.Response.User = map[string]interface{}{
+		"id":    "jsmith@localhost",
+		"email": "jsmith@localhost",
+		"roles": "authp/user",
+	}
+	r.Header.Set("X-Token-User-Email", "jsmith@localhost")
+	return nil
+}
+
+func TestServeHTTP(t *testing.T) {
+	testcases := []struct {
+		name    string
+		mode    string
+		headers map[string]string
+		want    map[string]interface{}
+	}{
+		{
+			name: "test nginx request with token in cookie",
+			headers: map[string]string{
+				"X-Original-Uri": "/foo",
+				"Cookie":         "access_token=" + testToken,
+			},
+			want: map[string]interface{}{
+				"status_code": 200,
+				"headers": map[string]string{
+					"X-Auth-Request-User":  "jsmith@localhost",
+					"X-Auth-Request-Email": "jsmith@localhost",
+					"X-Auth-Request-Roles": "authp/user",
+					"X-Token-User-Email":   "jsmith@localhost",
+				},
+			},
+		},
+		{
+			name: "test nginx request with token in custom header",
+			headers: map[string]string{
+				"X-Original-Url": "https://app.local/foo",
+				"X-Auth-Token":   "Bearer " + testToken,
+			},
+			want: map[string]interface{}{
+				"status_code": 200,
+				"headers": map[string]string{
+					"X-Auth-Request-User":  "jsmith@localhost",
+					"X-Auth-Request-Email": "jsmith@localhost",
+					"X-Auth-Request-Roles": "authp/user",
+					"X-Token-User-Email":   "jsmith@localhost",
+				},
+			},
+		},
+		{
+			name: "test nginx request with token in custom cookie",
+			headers: map[string]string{
+				"X-Original-Uri": "/foo",
+				"Cookie":         "_forward_auth=" + testToken,
+			},
+			want: map[string]interface{}{
+				"status_code": 200,
+				"headers": map[string]string{
+					"X-Auth-Request-User":  "jsmith@localhost",
+					"X-Auth-Request-Email": "jsmith@localhost",
+					"X-Auth-Request-Roles": "authp/user",
+					"X-Token-User-Email":   "jsmith@localhost",
+				},
+			},
+		},
+		{
+			name: "test nginx request without token",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Original-Uri":    "/foo?bar=baz",
+			},
+			want: map[string]interface{}{
+				"status_code": 401,
+				"headers": map[string]string{
+					"X-Auth-Redirect": "https://auth.local/login?redirect_url=https://app.local/foo?bar=baz",
+				},
+			},
+		},
+		{
+			name: "test nginx forbidden request",
+			headers: map[string]string{
+				"X-Original-Uri": "/admin",
+				"Cookie":         "access_token=" + testToken,
+			},
+			want: map[string]interface{}{
+				"status_code": 403,
+				"headers":     map[string]string{},
+			},
+		},
+		{
+			name: "test traefik request without token",
+			mode: "traefik",
+			headers: map[string]string{
+				"X-Forwarded-Method": "POST",
+				"X-Forwarded-Proto":  "https",
+				"X-Forwarded-Host":   "app.traefik",
+				"X-Forwarded-Uri":    "/foo",
+			},
+			want: map[string]interface{}{
+				"status_code": 302,
+				"headers": map[string]string{
+					"Location": "https://auth.local/login?redirect_url=https://app.traefik/foo",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cfg := &Config{
+				Name:                "default",
+				Address:             "127.0.0.1:0",
+				Mode:                tc.mode,
+				AuthorizationPolicy: "mypolicy",
+				TokenHeaders:        []string{"X-Auth-Token"},
+				TokenCookies:        []string{"_forward_auth"},
+			}
+			srv, err := NewServer(cfg, &testAuthorizer{}, logutil.NewLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "http://app.local/validate", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			got := map[string]interface{}{
+				"status_code": w.Code,
+			}
+			headers := make(map[string]string)
+			for k := range w.Header() {
+				if k == "Content-Type" {
+					continue
+				}
+				headers[k] = w.Header().Get(k)
+			}
+			got["headers"] = headers
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
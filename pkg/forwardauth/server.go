@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwardauth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Server is the forward authentication endpoint compatible with nginx
+// auth_request and Traefik forwardAuth.
+type Server struct {
+	config     *Config
+	authorizer authz.Authorizer
+	logger     *zap.Logger
+	httpServer *http.Server
+	listener   net.Listener
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// NewServer returns an instance of Server.
+func NewServer(cfg *Config, authorizer authz.Authorizer, logger *zap.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if authorizer == nil {
+		return nil, errors.ErrForwardAuthPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy)
+	}
+	srv := &Server{
+		config:     cfg,
+		authorizer: authorizer,
+		logger:     logger,
+	}
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, srv)
+	srv.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv, nil
+}
+
+// GetName returns the name of the server.
+func (srv *Server) GetName() string {
+	return srv.config.Name
+}
+
+// GetAddress returns the address the endpoint listens on.
+func (srv *Server) GetAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listener != nil {
+		return srv.listener.Addr().String()
+	}
+	return srv.config.Address
+}
+
+// Start starts accepting the authentication subrequests.
+func (srv *Server) Start() error {
+	listener, err := net.Listen("tcp", srv.config.Address)
+	if err != nil {
+		return errors.ErrForwardAuthStartFailed.WithArgs(srv.config.Name, err)
+	}
+
+	srv.mu.Lock()
+	srv.listener = listener
+	srv.mu.Unlock()
+
+	srv.logger.Info(
+		"started forward auth server",
+		zap.String("name", srv.config.Name),
+		zap.String("address", listener.Addr().String()),
+		zap.String("path", srv.config.Path),
+		zap.String("mode", srv.config.Mode),
+		zap.String("authorization_policy", srv.config.AuthorizationPolicy),
+	)
+
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		srv.httpServer.Serve(listener)
+	}()
+	return nil
+}
+
+// Stop stops the server.
+func (srv *Server) Stop() error {
+	srv.mu.Lock()
+	listener := srv.listener
+	srv.listener = nil
+	srv.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	err := srv.httpServer.Shutdown(context.Background())
+	srv.wg.Wait()
+	return err
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// KindMaxMind is the MaxMind GeoIP2 or GeoLite2 City database in MMDB
+	// format.
+	KindMaxMind = "maxmind"
+	// KindIP2Location is the IP2Location or IP2Location LITE database in
+	// CSV format, e.g. DB1, DB3, DB5 or DB11.
+	KindIP2Location = "ip2location"
+
+	defaultRefreshInterval = 3600
+)
+
+// Location is the geographic location of an IP address.
+type Location struct {
+	Country   string  `json:"country,omitempty" xml:"country,omitempty" yaml:"country,omitempty"`
+	City      string  `json:"city,omitempty" xml:"city,omitempty" yaml:"city,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty" xml:"latitude,omitempty" yaml:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty" xml:"longitude,omitempty" yaml:"longitude,omitempty"`
+}
+
+// String returns the city and the country of the location.
+func (l *Location) String() string {
+	var parts []string
+	for _, s := range []string{l.City, l.Country} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Locator resolves the location of IP addresses.
+type Locator interface {
+	Locate(net.IP) (*Location, error)
+}
+
+// database is a loaded geolocation database.
+type database interface {
+	Locator
+	Close() error
+}
+
+// Config holds the configuration of the geolocation database.
+type Config struct {
+	// The format of the database, i.e. maxmind or ip2location.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// The path to the database file.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// The number of seconds between the checks whether the database file
+	// changed. The changed file is reloaded. Defaults to 3600.
+	RefreshInterval int `json:"refresh_interval,omitempty" xml:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+}
+
+// Resolver resolves the location of IP addresses with the configured
+// database. The database is loaded on first use and reloaded when the
+// file changes. When the reload fails, the previously loaded database
+// remains in use.
+type Resolver struct {
+	config   *Config
+	open     func(string) (database, error)
+	interval time.Duration
+	logger   *zap.Logger
+	mu       sync.RWMutex
+	db       database
+	modTime  time.Time
+	checked  time.Time
+	err      error
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	switch cfg.Kind {
+	case KindMaxMind, KindIP2Location:
+	default:
+		return errors.ErrGeoConfigKindInvalid.WithArgs(cfg.Kind)
+	}
+	if cfg.Path == "" {
+		return errors.ErrGeoConfigPathEmpty
+	}
+	if cfg.RefreshInterval < 0 {
+		return errors.ErrGeoConfigRefreshInterval.WithArgs(cfg.RefreshInterval)
+	}
+	return nil
+}
+
+// NewResolver returns an instance of Resolver.
+func NewResolver(cfg *Config) (*Resolver, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Resolver{
+		config:   cfg,
+		interval: time.Duration(defaultRefreshInterval) * time.Second,
+	}
+	if cfg.RefreshInterval > 0 {
+		r.interval = time.Duration(cfg.RefreshInterval) * time.Second
+	}
+	switch cfg.Kind {
+	case KindMaxMind:
+		r.open = openMaxMind
+	case KindIP2Location:
+		r.open = openIP2Location
+	}
+	return r, nil
+}
+
+// SetLogger adds a logger to Resolver.
+func (r *Resolver) SetLogger(logger *zap.Logger) {
+	r.logger = logger
+}
+
+// Locate returns the location of the IP address. It returns nil when the
+// address is not in the database.
+func (r *Resolver) Locate(ip net.IP) (*Location, error) {
+	if err := r.refresh(time.Now()); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.db == nil {
+		return nil, nil
+	}
+	return r.db.Locate(ip)
+}
+
+// Close closes the database.
+func (r *Resolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.db == nil {
+		return nil
+	}
+	err := r.db.Close()
+	r.db = nil
+	r.modTime = time.Time{}
+	r.checked = time.Time{}
+	return err
+}
+
+// refresh loads the database, unless it was checked within the refresh
+// interval. The failure to load is remembered for the refresh interval,
+// i.e. a missing database is not looked up on every request.
+func (r *Resolver) refresh(now time.Time) error {
+	r.mu.RLock()
+	fresh := !r.checked.IsZero() && now.Sub(r.checked) < r.interval
+	db, err := r.db, r.err
+	r.mu.RUnlock()
+	if fresh {
+		if db == nil {
+			return err
+		}
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.checked.IsZero() && now.Sub(r.checked) < r.interval {
+		if r.db == nil {
+			return r.err
+		}
+		return nil
+	}
+	r.checked = now
+
+	fi, err := os.Stat(r.config.Path)
+	if err == nil && r.db != nil && fi.ModTime().Equal(r.modTime) {
+		return nil
+	}
+	if err == nil {
+		db, err = r.open(r.config.Path)
+	}
+	if err != nil {
+		r.err = errors.ErrGeoDatabaseLoad.WithArgs(r.config.Path, err)
+		if r.db == nil {
+			return r.err
+		}
+		if r.logger != nil {
+			r.logger.Warn(
+				"Failed reloading geolocation database",
+				zap.String("path", r.config.Path),
+				zap.Error(err),
+			)
+		}
+		return nil
+	}
+
+	if r.db != nil {
+		r.db.Close()
+	}
+	r.db = db
+	r.modTime = fi.ModTime()
+	r.err = nil
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const testIP2LocationDatabase = `"0","16777215","-","-","-","-","0.000000","0.000000"
+"16777216","16777471","US","United States of America","California","Los Angeles","34.052230","-118.243680"
+"16777472","16778239","CN","China","Fujian","Fuzhou","26.061390","119.306110"
+"281470681743360","281470698520575","-","-","-","-","0.000000","0.000000"
+"42540766411282592856903984951653826560","42540766490510755371168322545197776895","DE","Germany","Berlin","Berlin","52.524370","13.410530"
+`
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid maxmind config",
+			config: &Config{
+				Kind: "maxmind",
+				Path: "/var/lib/geoip/GeoLite2-City.mmdb",
+			},
+		},
+		{
+			name: "valid ip2location config",
+			config: &Config{
+				Kind:            "ip2location",
+				Path:            "/var/lib/geoip/IP2LOCATION-LITE-DB5.CSV",
+				RefreshInterval: 600,
+			},
+		},
+		{
+			name: "unsupported kind",
+			config: &Config{
+				Kind: "foobar",
+				Path: "/var/lib/geoip/GeoLite2-City.mmdb",
+			},
+			shouldErr: true,
+			err:       errors.ErrGeoConfigKindInvalid.WithArgs("foobar"),
+		},
+		{
+			name: "empty path",
+			config: &Config{
+				Kind: "maxmind",
+			},
+			shouldErr: true,
+			err:       errors.ErrGeoConfigPathEmpty,
+		},
+		{
+			name: "negative refresh interval",
+			config: &Config{
+				Kind:            "maxmind",
+				Path:            "/var/lib/geoip/GeoLite2-City.mmdb",
+				RefreshInterval: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrGeoConfigRefreshInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "geo config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestIP2LocationDatabase(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "IP2LOCATION-LITE-DB5.IPV6.CSV")
+	if err := ioutil.WriteFile(fp, []byte(testIP2LocationDatabase), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db, err := openIP2Location(fp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name string
+		addr string
+		want *Location
+	}{
+		{
+			name: "ipv4 address",
+			addr: "1.0.0.1",
+			want: &Location{Country: "US", City: "Los Angeles", Latitude: 34.05223, Longitude: -118.24368},
+		},
+		{
+			name: "last ipv4 address of range",
+			addr: "1.0.3.255",
+			want: &Location{Country: "CN", City: "Fuzhou", Latitude: 26.06139, Longitude: 119.30611},
+		},
+		{
+			name: "ipv6 address",
+			addr: "2001:db8::1",
+			want: &Location{Country: "DE", City: "Berlin", Latitude: 52.52437, Longitude: 13.41053},
+		},
+		{
+			name: "ipv4 address with unknown location",
+			addr: "0.0.0.1",
+		},
+		{
+			name: "address not in database",
+			addr: "192.168.1.1",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := db.Locate(net.ParseIP(tc.addr))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjects(t, "location", tc.want, got)
+		})
+	}
+}
+
+func TestIP2LocationDatabaseMalformed(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "IP2LOCATION-LITE-DB1.CSV")
+	if err := ioutil.WriteFile(fp, []byte("\"16777216\",\"foo\",\"US\",\"United States of America\"\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := openIP2Location(fp)
+	tests.EvalErr(t, err, "malformed database", true, errors.ErrGeoDatabaseRecord.WithArgs(1, fmt.Errorf("invalid ip number %q", "foo")))
+}
+
+func TestResolver(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "IP2LOCATION-LITE-DB5.CSV")
+	r, err := NewResolver(&Config{Kind: "ip2location", Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	// The database is loaded on first use.
+	_, err = r.Locate(net.ParseIP("1.0.0.1"))
+	if err == nil {
+		t.Fatalf("expected error for missing database")
+	}
+
+	if err := ioutil.WriteFile(fp, []byte(testIP2LocationDatabase), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The failure is remembered until the refresh interval elapses.
+	now := time.Now()
+	if err := r.refresh(now); err == nil {
+		t.Fatalf("expected error before refresh interval elapsed")
+	}
+	now = now.Add(r.interval)
+	if err := r.refresh(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loc, err := r.Locate(net.ParseIP("1.0.0.1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "country", "US", loc.Country)
+
+	// The changed database is reloaded.
+	if err := ioutil.WriteFile(fp, []byte(`"16777216","16777471","AU","Australia","Queensland","Brisbane","-27.467940","153.028090"`+"\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chtimes(fp, now, now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(r.interval)
+	if err := r.refresh(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loc, _ = r.Locate(net.ParseIP("1.0.0.1"))
+	tests.EvalObjects(t, "reloaded country", "AU", loc.Country)
+
+	// The previously loaded database remains in use when reload fails.
+	if err := os.Remove(fp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(r.interval)
+	if err := r.refresh(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loc, _ = r.Locate(net.ParseIP("1.0.0.1"))
+	tests.EvalObjects(t, "retained country", "AU", loc.Country)
+}
+
+func TestLocateAddress(t *testing.T) {
+	if loc := LocateAddress("1.0.0.1"); loc != nil {
+		t.Fatalf("expected no location without default resolver, got %v", loc)
+	}
+
+	fp := filepath.Join(t.TempDir(), "IP2LOCATION-LITE-DB5.CSV")
+	if err := ioutil.WriteFile(fp, []byte(testIP2LocationDatabase), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := NewResolver(&Config{Kind: "ip2location", Path: fp})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetDefaultResolver(r)
+	defer SetDefaultResolver(nil)
+
+	loc := LocateAddress("1.0.0.1")
+	if loc == nil {
+		t.Fatalf("expected location")
+	}
+	tests.EvalObjects(t, "location", "Los Angeles, US", loc.String())
+	if loc := LocateAddress("foobar"); loc != nil {
+		t.Fatalf("expected no location for invalid address, got %v", loc)
+	}
+}
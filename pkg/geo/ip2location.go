@@ -0,0 +1,151 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// The IP numbers of IPv4 addresses in the IPv6 database are IPv4-mapped
+// IPv6 addresses, i.e. ::ffff:0:0/96.
+var maxIPv4Number = big.NewInt(0xffffffff)
+var ipv4MappedPrefix = big.NewInt(0xffff << 32)
+
+// ip2LocationRange is a range of IP addresses and their location.
+type ip2LocationRange struct {
+	from     []byte
+	to       []byte
+	location *Location
+}
+
+// ip2LocationDatabase resolves the location of IP addresses with
+// IP2Location database in CSV format. The columns are the first and the
+// last IP numbers of the range, the country code, the country name,
+// the region, the city, the latitude and the longitude. The database
+// may have fewer columns, e.g. DB1 has the country only. Both IPv4 and
+// IPv6 databases are supported.
+type ip2LocationDatabase struct {
+	ranges []*ip2LocationRange
+}
+
+func openIP2Location(fp string) (database, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := &ip2LocationDatabase{}
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry, err := parseIP2LocationRecord(rec)
+		if err != nil {
+			return nil, errors.ErrGeoDatabaseRecord.WithArgs(line, err)
+		}
+		if entry != nil {
+			db.ranges = append(db.ranges, entry)
+		}
+	}
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].from, db.ranges[j].from) < 0
+	})
+	return db, nil
+}
+
+// parseIP2LocationRecord returns the range of the record. It returns nil
+// when the location of the range is unknown.
+func parseIP2LocationRecord(rec []string) (*ip2LocationRange, error) {
+	if len(rec) < 3 {
+		return nil, fmt.Errorf("expected at least 3 fields, got %d", len(rec))
+	}
+	from, err := parseIPNumber(rec[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseIPNumber(rec[1])
+	if err != nil {
+		return nil, err
+	}
+	if to.Cmp(maxIPv4Number) <= 0 {
+		from.Add(from, ipv4MappedPrefix)
+		to.Add(to, ipv4MappedPrefix)
+	}
+	if rec[2] == "" || rec[2] == "-" {
+		return nil, nil
+	}
+	entry := &ip2LocationRange{
+		from:     from.FillBytes(make([]byte, net.IPv6len)),
+		to:       to.FillBytes(make([]byte, net.IPv6len)),
+		location: &Location{Country: rec[2]},
+	}
+	if len(rec) > 5 && rec[5] != "-" {
+		entry.location.City = rec[5]
+	}
+	if len(rec) > 7 {
+		if entry.location.Latitude, err = strconv.ParseFloat(rec[6], 64); err != nil {
+			return nil, err
+		}
+		if entry.location.Longitude, err = strconv.ParseFloat(rec[7], 64); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func parseIPNumber(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 8*net.IPv6len {
+		return nil, fmt.Errorf("invalid ip number %q", s)
+	}
+	return n, nil
+}
+
+func (db *ip2LocationDatabase) Locate(ip net.IP) (*Location, error) {
+	key := ip.To16()
+	if key == nil {
+		return nil, nil
+	}
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].to, key) >= 0
+	})
+	if i == len(db.ranges) || bytes.Compare(db.ranges[i].from, key) > 0 {
+		return nil, nil
+	}
+	loc := *db.ranges[i].location
+	return &loc, nil
+}
+
+func (db *ip2LocationDatabase) Close() error {
+	return nil
+}
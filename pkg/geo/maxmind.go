@@ -0,0 +1,68 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// maxMindDatabase resolves the location of IP addresses with MaxMind
+// GeoIP2 or GeoLite2 City database.
+type maxMindDatabase struct {
+	reader *maxminddb.Reader
+}
+
+type maxMindRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+func openMaxMind(fp string) (database, error) {
+	reader, err := maxminddb.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	return &maxMindDatabase{reader: reader}, nil
+}
+
+func (db *maxMindDatabase) Locate(ip net.IP) (*Location, error) {
+	var rec maxMindRecord
+	if err := db.reader.Lookup(ip, &rec); err != nil {
+		return nil, err
+	}
+	if rec.Country.ISOCode == "" {
+		return nil, nil
+	}
+	return &Location{
+		Country:   rec.Country.ISOCode,
+		City:      rec.City.Names["en"],
+		Latitude:  rec.Location.Latitude,
+		Longitude: rec.Location.Longitude,
+	}, nil
+}
+
+func (db *maxMindDatabase) Close() error {
+	return db.reader.Close()
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"net"
+	"sync"
+)
+
+var (
+	defaultResolverMu sync.RWMutex
+	defaultResolver   *Resolver
+
+	// Default is the Locator backed by the default Resolver. It resolves
+	// no locations when the default Resolver is not set.
+	Default Locator = defaultLocator{}
+)
+
+type defaultLocator struct{}
+
+// SetDefaultResolver sets the Resolver used to annotate login events,
+// access list conditions and notifications with the location of the
+// source IP address. The previously set Resolver is closed.
+func SetDefaultResolver(r *Resolver) {
+	defaultResolverMu.Lock()
+	prev := defaultResolver
+	defaultResolver = r
+	defaultResolverMu.Unlock()
+	if prev != nil && prev != r {
+		prev.Close()
+	}
+}
+
+// GetDefaultResolver returns the default Resolver, if set.
+func GetDefaultResolver() *Resolver {
+	defaultResolverMu.RLock()
+	defer defaultResolverMu.RUnlock()
+	return defaultResolver
+}
+
+// LocateAddress returns the location of the source address with the
+// default Resolver. It returns nil when the location is unknown.
+func LocateAddress(addr string) *Location {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	loc, err := Default.Locate(ip)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+func (defaultLocator) Locate(ip net.IP) (*Location, error) {
+	r := GetDefaultResolver()
+	if r == nil {
+		return nil, nil
+	}
+	return r.Locate(ip)
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+)
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90
+	defaultKeepAlive           = 30
+)
+
+// Config holds the settings of the pooled connections to the upstream
+// identity providers.
+type Config struct {
+	// The maximum number of idle connections across all hosts. Defaults
+	// to 100.
+	MaxIdleConns int `json:"max_idle_conns,omitempty" xml:"max_idle_conns,omitempty" yaml:"max_idle_conns,omitempty"`
+	// The maximum number of idle connections per host. Defaults to 10.
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty" xml:"max_idle_conns_per_host,omitempty" yaml:"max_idle_conns_per_host,omitempty"`
+	// The maximum number of connections per host, including the active
+	// ones. Zero means no limit.
+	MaxConnsPerHost int `json:"max_conns_per_host,omitempty" xml:"max_conns_per_host,omitempty" yaml:"max_conns_per_host,omitempty"`
+	// The number of seconds an idle connection remains in the pool.
+	// Defaults to 90.
+	IdleConnTimeout int `json:"idle_conn_timeout,omitempty" xml:"idle_conn_timeout,omitempty" yaml:"idle_conn_timeout,omitempty"`
+	// The number of seconds between the TCP keep-alive probes. Defaults
+	// to 30.
+	KeepAlive int `json:"keep_alive,omitempty" xml:"keep_alive,omitempty" yaml:"keep_alive,omitempty"`
+	// Disables the reuse of the connections.
+	KeepAlivesDisabled bool `json:"keep_alives_disabled,omitempty" xml:"keep_alives_disabled,omitempty" yaml:"keep_alives_disabled,omitempty"`
+	// The number of seconds the resolved addresses of a host are cached.
+	// Zero disables the caching.
+	DNSCacheTTL int `json:"dns_cache_ttl,omitempty" xml:"dns_cache_ttl,omitempty" yaml:"dns_cache_ttl,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.MaxIdleConns < 0 {
+		return errors.ErrHTTPClientConfigMaxIdleConns.WithArgs(cfg.MaxIdleConns)
+	}
+	if cfg.MaxIdleConnsPerHost < 0 {
+		return errors.ErrHTTPClientConfigMaxIdleConnsPerHost.WithArgs(cfg.MaxIdleConnsPerHost)
+	}
+	if cfg.MaxConnsPerHost < 0 {
+		return errors.ErrHTTPClientConfigMaxConnsPerHost.WithArgs(cfg.MaxConnsPerHost)
+	}
+	if cfg.IdleConnTimeout < 0 {
+		return errors.ErrHTTPClientConfigIdleConnTimeout.WithArgs(cfg.IdleConnTimeout)
+	}
+	if cfg.KeepAlive < 0 {
+		return errors.ErrHTTPClientConfigKeepAlive.WithArgs(cfg.KeepAlive)
+	}
+	if cfg.DNSCacheTTL < 0 {
+		return errors.ErrHTTPClientConfigDNSCacheTTL.WithArgs(cfg.DNSCacheTTL)
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = defaultMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost == 0 {
+		cfg.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout == 0 {
+		cfg.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = defaultKeepAlive
+	}
+	return nil
+}
+
+var (
+	transports   = make(map[string]http.RoundTripper)
+	transportsMu sync.Mutex
+)
+
+// GetTransport returns the transport shared by the clients with the same
+// settings, so that the connections to the upstream hosts are reused across
+// the requests and the identity providers. When the config is nil, the
+// default settings apply.
+func GetTransport(cfg *Config, insecureSkipVerify bool) http.RoundTripper {
+	if cfg == nil {
+		cfg = &Config{}
+		cfg.Validate()
+	}
+	key := fmt.Sprintf("%+v/%t", *cfg, insecureSkipVerify)
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if tr, exists := transports[key]; exists {
+		return tr
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: time.Duration(cfg.KeepAlive) * time.Second,
+	}
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.IdleConnTimeout) * time.Second,
+		DisableKeepAlives:   cfg.KeepAlivesDisabled,
+		ForceAttemptHTTP2:   true,
+	}
+	if cfg.DNSCacheTTL > 0 {
+		r := newResolver(time.Duration(cfg.DNSCacheTTL)*time.Second, net.DefaultResolver.LookupHost)
+		tr.DialContext = r.dialContext(dialer.DialContext)
+	}
+	if insecureSkipVerify {
+		tr.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	transports[key] = &latencyTransport{base: tr}
+	return transports[key]
+}
+
+// NewClient returns the HTTP client with the shared transport and the
+// provided request timeout.
+func NewClient(cfg *Config, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: GetTransport(cfg, false),
+	}
+}
+
+// latencyTransport records the duration of the requests per upstream host.
+type latencyTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *latencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	startedAt := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	metrics.UpstreamRequestLatency.Observe(time.Since(startedAt).Seconds(), req.URL.Host, outcome)
+	return resp, err
+}
+
+// resolver caches the addresses of the upstream hosts.
+type resolver struct {
+	ttl     time.Duration
+	lookup  func(context.Context, string) ([]string, error)
+	mu      sync.RWMutex
+	entries map[string]*resolverEntry
+}
+
+type resolverEntry struct {
+	addrs     []string
+	expiresAt time.Time
+}
+
+func newResolver(ttl time.Duration, lookup func(context.Context, string) ([]string, error)) *resolver {
+	return &resolver{
+		ttl:     ttl,
+		lookup:  lookup,
+		entries: make(map[string]*resolverEntry),
+	}
+}
+
+// lookupHost returns the cached addresses of the host, if not expired, or
+// resolves them.
+func (r *resolver) lookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.RLock()
+	entry, exists := r.entries[host]
+	r.mu.RUnlock()
+	if exists && time.Now().Before(entry.expiresAt) {
+		return entry.addrs, nil
+	}
+	addrs, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.entries[host] = &resolverEntry{
+		addrs:     addrs,
+		expiresAt: time.Now().Add(r.ttl),
+	}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// dialContext returns the dial function connecting to the cached addresses
+// of the host.
+func (r *resolver) dialContext(dial func(context.Context, string, string) (net.Conn, error)) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := r.lookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host}
+		}
+		for _, ip := range addrs {
+			var conn net.Conn
+			conn, err = dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
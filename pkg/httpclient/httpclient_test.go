@@ -0,0 +1,168 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "validate config with defaults",
+			config: &Config{},
+			want: &Config{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90,
+				KeepAlive:           30,
+			},
+		},
+		{
+			name: "validate config with custom settings",
+			config: &Config{
+				MaxIdleConns:        20,
+				MaxIdleConnsPerHost: 5,
+				MaxConnsPerHost:     10,
+				IdleConnTimeout:     30,
+				KeepAlive:           15,
+				DNSCacheTTL:         60,
+			},
+			want: &Config{
+				MaxIdleConns:        20,
+				MaxIdleConnsPerHost: 5,
+				MaxConnsPerHost:     10,
+				IdleConnTimeout:     30,
+				KeepAlive:           15,
+				DNSCacheTTL:         60,
+			},
+		},
+		{
+			name:      "validate config with negative max connections per host",
+			config:    &Config{MaxConnsPerHost: -1},
+			shouldErr: true,
+			err:       errors.ErrHTTPClientConfigMaxConnsPerHost.WithArgs(-1),
+		},
+		{
+			name:      "validate config with negative dns cache ttl",
+			config:    &Config{DNSCacheTTL: -5},
+			shouldErr: true,
+			err:       errors.ErrHTTPClientConfigDNSCacheTTL.WithArgs(-5),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "config", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "config", tc.want, tc.config, msgs)
+		})
+	}
+}
+
+func TestGetTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{MaxConnsPerHost: 3}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tr := GetTransport(cfg, false)
+	tests.EvalObjects(t, "shared transport", true, tr == GetTransport(&Config{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		MaxConnsPerHost:     3,
+		IdleConnTimeout:     90,
+		KeepAlive:           30,
+	}, false))
+	tests.EvalObjects(t, "insecure transport", false, tr == GetTransport(cfg, true))
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := NewClient(cfg, time.Second).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	tests.EvalObjects(t, "status code", http.StatusOK, resp.StatusCode)
+
+	var b bytes.Buffer
+	if err := metrics.Default.Write(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := fmt.Sprintf(`authcrunch_upstream_request_duration_seconds_count{host=%q,outcome="success"} 1`, req.URL.Host)
+	tests.EvalObjects(t, "host latency recorded", true, strings.Contains(b.String(), want))
+}
+
+func TestResolver(t *testing.T) {
+	var lookups int
+	r := newResolver(time.Minute, func(_ context.Context, host string) ([]string, error) {
+		lookups++
+		switch host {
+		case "localhost.test":
+			return []string{"127.0.0.1"}, nil
+		case "empty.test":
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	dial := r.dialContext((&net.Dialer{Timeout: time.Second}).DialContext)
+	for i := 0; i < 3; i++ {
+		conn, err := dial(context.Background(), "tcp", net.JoinHostPort("localhost.test", port))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		conn.Close()
+	}
+	tests.EvalObjects(t, "cached lookups", 1, lookups)
+
+	if _, err := dial(context.Background(), "tcp", net.JoinHostPort("empty.test", port)); err == nil {
+		t.Fatal("expected error for host without addresses")
+	}
+	if _, err := dial(context.Background(), "tcp", net.JoinHostPort("unknown.test", port)); err == nil {
+		t.Fatal("expected error for unknown host")
+	}
+}
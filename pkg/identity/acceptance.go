@@ -0,0 +1,36 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"time"
+)
+
+// Acceptance is an instance of the acceptance of a version of a document,
+// e.g. terms of service or privacy policy, by a user.
+type Acceptance struct {
+	Document   string    `json:"document,omitempty" xml:"document,omitempty" yaml:"document,omitempty"`
+	Version    string    `json:"version,omitempty" xml:"version,omitempty" yaml:"version,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at,omitempty" xml:"accepted_at,omitempty" yaml:"accepted_at,omitempty"`
+}
+
+// NewAcceptance returns an instance of Acceptance.
+func NewAcceptance(doc, version string) *Acceptance {
+	return &Acceptance{
+		Document:   doc,
+		Version:    version,
+		AcceptedAt: time.Now().UTC(),
+	}
+}
@@ -39,6 +39,14 @@ type APIKey struct {
 	CreatedAt  time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
 	Disabled   bool      `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
 	DisabledAt time.Time `json:"disabled_at,omitempty" xml:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
+	Scopes     []string  `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Roles      []string  `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	RotatedAt  time.Time `json:"rotated_at,omitempty" xml:"rotated_at,omitempty" yaml:"rotated_at,omitempty"`
+	RotatedTo  string    `json:"rotated_to,omitempty" xml:"rotated_to,omitempty" yaml:"rotated_to,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty" xml:"last_used_at,omitempty" yaml:"last_used_at,omitempty"`
+	// The source address of the client that last used the key.
+	LastUsedAddress string `json:"last_used_address,omitempty" xml:"last_used_address,omitempty" yaml:"last_used_address,omitempty"`
 }
 
 // NewAPIKeyBundle returns an instance of APIKeyBundle.
@@ -78,6 +86,9 @@ func NewAPIKey(r *requests.Request) (*APIKey, error) {
 	if r.Key.Comment == "" {
 		return nil, errors.ErrAPIKeyCommentEmpty
 	}
+	if !r.Key.ExpiresAt.IsZero() && r.Key.ExpiresAt.Before(time.Now()) {
+		return nil, errors.ErrAPIKeyExpiresAtPast
+	}
 	p := &APIKey{
 		Comment:   r.Key.Comment,
 		ID:        GetRandomString(40),
@@ -85,6 +96,9 @@ func NewAPIKey(r *requests.Request) (*APIKey, error) {
 		Payload:   r.Key.Payload,
 		Usage:     r.Key.Usage,
 		CreatedAt: time.Now().UTC(),
+		Scopes:    r.Key.Scopes,
+		Roles:     r.Key.Roles,
+		ExpiresAt: r.Key.ExpiresAt.UTC(),
 	}
 	if r.Key.Disabled {
 		p.Disabled = true
@@ -101,6 +115,17 @@ func (p *APIKey) Disable() {
 	p.DisabledAt = time.Now().UTC()
 }
 
+// Active returns true when APIKey is neither disabled nor expired.
+func (p *APIKey) Active(t time.Time) bool {
+	if p.Disabled {
+		return false
+	}
+	if !p.ExpiresAt.IsZero() && !t.Before(p.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
 // Match returns true when the provided API matches.
 func (p *APIKey) Match(s string) bool {
 	if err := bcrypt.CompareHashAndPassword([]byte(p.Payload), []byte(s)); err == nil {
@@ -0,0 +1,58 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Consent is an instance of the consent a user granted to an application to
+// access the user identity with the specified scopes.
+type Consent struct {
+	ID        string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	App       string    `json:"app,omitempty" xml:"app,omitempty" yaml:"app,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	GrantedAt time.Time `json:"granted_at,omitempty" xml:"granted_at,omitempty" yaml:"granted_at,omitempty"`
+}
+
+// NewConsent returns an instance of Consent.
+func NewConsent(app string, scopes []string) *Consent {
+	c := &Consent{
+		ID:        NewID(),
+		App:       app,
+		GrantedAt: time.Now().UTC(),
+	}
+	c.AddScopes(scopes)
+	return c
+}
+
+// AddScopes merges the provided scopes into the Consent.
+func (c *Consent) AddScopes(scopes []string) {
+	m := make(map[string]bool)
+	for _, scope := range c.Scopes {
+		m[scope] = true
+	}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || m[scope] {
+			continue
+		}
+		m[scope] = true
+		c.Scopes = append(c.Scopes, scope)
+	}
+	sort.Strings(c.Scopes)
+}
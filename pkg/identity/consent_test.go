@@ -0,0 +1,28 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestNewConsent(t *testing.T) {
+	c := NewConsent("grafana", []string{"profile", "openid", " ", "profile"})
+	tests.EvalObjects(t, "scopes", []string{"openid", "profile"}, c.Scopes)
+	c.AddScopes([]string{"email"})
+	tests.EvalObjects(t, "merged scopes", []string{"email", "openid", "profile"}, c.Scopes)
+}
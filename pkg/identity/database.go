@@ -15,13 +15,15 @@
 package identity
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/internal/utils"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/versioned"
-	"io/ioutil"
+	"go.uber.org/zap"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,6 +31,10 @@ import (
 	"time"
 )
 
+// apiKeyLastUsedInterval is the minimum interval between the persisted
+// updates of the last use of an API key.
+const apiKeyLastUsedInterval = time.Minute
+
 var (
 	app           *versioned.PackageManager
 	appVersion    string
@@ -103,11 +109,26 @@ type Database struct {
 	Revision        uint64    `json:"revision,omitempty" xml:"revision,omitempty" yaml:"revision,omitempty"`
 	LastModified    time.Time `json:"last_modified,omitempty" xml:"last_modified,omitempty" yaml:"last_modified,omitempty"`
 	Users           []*User   `json:"users,omitempty" xml:"users,omitempty" yaml:"users,omitempty"`
+	Groups          []*Group  `json:"groups,omitempty" xml:"groups,omitempty" yaml:"groups,omitempty"`
 	refEmailAddress map[string]*User
 	refUsername     map[string]*User
 	refID           map[string]*User
 	refAPIKey       map[string]*User
-	path            string
+	refGroup        map[string]*Group
+	// The users by the realm and the subject of their linked identities.
+	refLinkedIdentity map[string]*User
+	// The digests of the API keys verified earlier. The lookup of a key with
+	// a known digest avoids the bcrypt comparison.
+	apiKeyDigests map[string][sha256.Size]byte
+	path          string
+	// The number of the previous revisions of the database file kept as
+	// backups.
+	backupCount int
+	// The outcome of the integrity check performed when the database was
+	// loaded.
+	integrityReport *IntegrityReport
+	// The user records set aside by the integrity check.
+	Quarantine []*QuarantinedUser `json:"quarantine,omitempty" xml:"quarantine,omitempty" yaml:"quarantine,omitempty"`
 }
 
 // NewDatabase return an instance of Database.
@@ -116,14 +137,7 @@ func NewDatabase(fp string) (*Database, error) {
 		return nil, errors.ErrNewDatabase.WithArgs(fp, "null path")
 	}
 
-	db := &Database{
-		mu:              &sync.RWMutex{},
-		path:            fp,
-		refUsername:     make(map[string]*User),
-		refID:           make(map[string]*User),
-		refEmailAddress: make(map[string]*User),
-		refAPIKey:       make(map[string]*User),
-	}
+	db := newDatabase(fp)
 	fileInfo, err := os.Stat(fp)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -148,7 +162,9 @@ func NewDatabase(fp string) (*Database, error) {
 		if err := json.Unmarshal(b, db); err != nil {
 			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
 		}
-		if changed := db.enforceDefaultPolicy(); changed {
+		changed := db.enforceDefaultPolicy()
+		db.integrityReport = db.checkIntegrity()
+		if changed || db.integrityReport.Changed() {
 			if err := db.commit(); err != nil {
 				return nil, errors.ErrNewDatabase.WithArgs(fp, err)
 			}
@@ -159,23 +175,46 @@ func NewDatabase(fp string) (*Database, error) {
 	// db.path = fp
 	db.Version = app.Version
 
+	if err := db.index(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func newDatabase(fp string) *Database {
+	return &Database{
+		mu:              &sync.RWMutex{},
+		path:            fp,
+		refUsername:     make(map[string]*User),
+		refID:           make(map[string]*User),
+		refEmailAddress: make(map[string]*User),
+		refAPIKey:       make(map[string]*User),
+		refGroup:        make(map[string]*Group),
+		apiKeyDigests:   make(map[string][sha256.Size]byte),
+
+		refLinkedIdentity: make(map[string]*User),
+	}
+}
+
+// index builds the lookup references of the users and the groups.
+func (db *Database) index() error {
 	for _, user := range db.Users {
 		if err := user.Valid(); err != nil {
-			return nil, errors.ErrNewDatabaseInvalidUser.WithArgs(user, err)
+			return errors.ErrNewDatabaseInvalidUser.WithArgs(user, err)
 		}
 		username := strings.ToLower(user.Username)
 		if _, exists := db.refUsername[username]; exists {
-			return nil, errors.ErrNewDatabaseDuplicateUser.WithArgs(user.Username, user)
+			return errors.ErrNewDatabaseDuplicateUser.WithArgs(user.Username, user)
 		}
 		if _, exists := db.refID[user.ID]; exists {
-			return nil, errors.ErrNewDatabaseDuplicateUserID.WithArgs(user.ID, user)
+			return errors.ErrNewDatabaseDuplicateUserID.WithArgs(user.ID, user)
 		}
 		db.refUsername[username] = user
 		db.refID[user.ID] = user
 		for _, email := range user.EmailAddresses {
 			emailAddress := strings.ToLower(email.Address)
 			if _, exists := db.refEmailAddress[emailAddress]; exists {
-				return nil, errors.ErrNewDatabaseDuplicateEmail.WithArgs(emailAddress, user)
+				return errors.ErrNewDatabaseDuplicateEmail.WithArgs(emailAddress, user)
 			}
 			db.refEmailAddress[emailAddress] = user
 		}
@@ -186,12 +225,26 @@ func NewDatabase(fp string) (*Database, error) {
 		}
 		for _, apiKey := range user.APIKeys {
 			if _, exists := db.refAPIKey[apiKey.Prefix]; exists {
-				return nil, errors.ErrNewDatabaseDuplicateAPIKey.WithArgs(apiKey.Prefix, user)
+				return errors.ErrNewDatabaseDuplicateAPIKey.WithArgs(apiKey.Prefix, user)
 			}
 			db.refAPIKey[apiKey.Prefix] = user
 		}
+		for _, li := range user.LinkedIdentities {
+			k := getLinkedIdentityKey(li.Realm, li.Subject)
+			if _, exists := db.refLinkedIdentity[k]; exists {
+				return errors.ErrNewDatabaseDuplicateLinkedIdentity.WithArgs(li.Subject, li.Realm, user)
+			}
+			db.refLinkedIdentity[k] = user
+		}
 	}
-	return db, nil
+	for _, group := range db.Groups {
+		groupName := strings.ToLower(group.Name)
+		if _, exists := db.refGroup[groupName]; exists {
+			return errors.ErrNewDatabaseDuplicateGroup.WithArgs(group.Name)
+		}
+		db.refGroup[groupName] = group
+	}
+	return nil
 }
 
 func (db *Database) enforceDefaultPolicy() bool {
@@ -268,6 +321,12 @@ func (db *Database) AddUser(r *requests.Request) error {
 	if err != nil {
 		return errors.ErrAddUser.WithArgs(r.User.Username, err)
 	}
+	if len(r.User.Organizations) > 0 {
+		if err := user.SetOrganizations(r.User.Organizations); err != nil {
+			return errors.ErrAddUser.WithArgs(r.User.Username, err)
+		}
+		user.Revision = 0
+	}
 	for i := 0; i < 10; i++ {
 		id := NewID()
 		if _, exists := db.refID[id]; !exists {
@@ -335,27 +394,329 @@ func (db *Database) GetUser(r *requests.Request) error {
 	return nil
 }
 
-// DeleteUser deletes a user by user id.
+// DeleteUser deletes a user identified by username and email address.
 func (db *Database) DeleteUser(r *requests.Request) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	// user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
-	_, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrDeleteUser.WithArgs(r.User.Username, err)
+	}
+	if user.HasAdminRights() && db.countAdminUsers() < 2 {
+		return errors.ErrDeleteUser.WithArgs(r.User.Username, errors.ErrLastAdminUser)
+	}
+	db.removeUser(user)
+	if err := db.commit(); err != nil {
+		return errors.ErrDeleteUser.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// removeUser removes a user and the references to the user from the
+// database. The changes are not committed.
+func (db *Database) removeUser(user *User) {
+	users := []*User{}
+	for _, entry := range db.Users {
+		if entry.ID == user.ID {
+			continue
+		}
+		users = append(users, entry)
+	}
+	db.Users = users
+	delete(db.refID, user.ID)
+	delete(db.refUsername, strings.ToLower(user.Username))
+	for _, email := range user.EmailAddresses {
+		delete(db.refEmailAddress, strings.ToLower(email.Address))
+	}
+	for _, k := range user.APIKeys {
+		delete(db.refAPIKey, k.Prefix)
+		delete(db.apiKeyDigests, k.Prefix)
+	}
+	for _, li := range user.LinkedIdentities {
+		delete(db.refLinkedIdentity, getLinkedIdentityKey(li.Realm, li.Subject))
+	}
+	for _, group := range db.Groups {
+		group.DeleteMember(user.Username)
+	}
+}
+
+// UpdateUserRoles replaces the roles of a user with the ones in
+// r.User.Roles. The retained time-boxed roles keep their expiry.
+func (db *Database) UpdateUserRoles(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+	}
+	prev := make(map[string]*Role)
+	for _, role := range user.Roles {
+		prev[role.String()] = role
+	}
+	roles := []*Role{}
+	var admin bool
+	for _, s := range r.User.Roles {
+		role, err := NewRole(s)
+		if err != nil {
+			return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+		}
+		if role.Name == "admin" && role.Organization == "authp" {
+			admin = true
+		}
+		if p, exists := prev[role.String()]; exists {
+			role = p
+		}
+		roles = append(roles, role)
+	}
+	if user.HasAdminRights() && !admin && db.countAdminUsers() < 2 {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, errors.ErrLastAdminUser)
+	}
+	user.Roles = roles
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// GrantUserRole assigns the role in r.RoleGrant to a user until the expiry
+// in r.RoleGrant.
+func (db *Database) GrantUserRole(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
-		return errors.ErrDeleteUser.WithArgs(r.Query.ID, err)
+		return errors.ErrGrantUserRole.WithArgs(r.RoleGrant.Role, r.User.Username, err)
 	}
-	return errors.ErrDeleteUser.WithArgs(r.Query.ID, "user delete operation is not supported")
-	// TODO: how do we delete a user ???
+	if err := user.GrantRole(r.RoleGrant.Role, r.RoleGrant.GrantedBy, r.RoleGrant.ExpiresAt); err != nil {
+		return errors.ErrGrantUserRole.WithArgs(r.RoleGrant.Role, r.User.Username, err)
+	}
+	if err := db.commit(); err != nil {
+		return errors.ErrGrantUserRole.WithArgs(r.RoleGrant.Role, r.User.Username, err)
+	}
+	return nil
+}
 
-	// if err := user.DeletePublicKey(r); err != nil {
-	//	return err
-	//}
-	/*
+// ExpireUserRoles removes the expired time-boxed roles from the users. The
+// response payload holds the expired role grants. In the dry run, the
+// expired roles are reported, but not removed.
+func (db *Database) ExpireUserRoles(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now().UTC()
+	grants := []*requests.RoleGrant{}
+	for _, user := range db.Users {
+		var expired []*Role
+		if r.Lifecycle.DryRun {
+			for _, role := range user.Roles {
+				if role.Expired(now) {
+					expired = append(expired, role)
+				}
+			}
+		} else {
+			expired = user.ExpireRoles(now)
+		}
+		for _, role := range expired {
+			grants = append(grants, &requests.RoleGrant{
+				Username:  user.Username,
+				Email:     user.GetMailClaim(),
+				Role:      role.String(),
+				GrantedBy: role.GrantedBy,
+				ExpiresAt: role.ExpiresAt,
+			})
+		}
+	}
+	if len(grants) > 0 && !r.Lifecycle.DryRun {
 		if err := db.commit(); err != nil {
-			return errors.ErrDeleteUser.WithArgs(r.Query.ID, err)
+			return errors.ErrExpireUserRoles.WithArgs(err)
 		}
-		return nil
-	*/
+	}
+	r.Response.Payload = grants
+	return nil
+}
+
+// UpdateUserOrganizations replaces the organizations of a user with the ones
+// in r.User.Organizations.
+func (db *Database) UpdateUserOrganizations(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrUpdateUserOrgs.WithArgs(r.User.Username, err)
+	}
+	if err := user.SetOrganizations(r.User.Organizations); err != nil {
+		return errors.ErrUpdateUserOrgs.WithArgs(r.User.Username, err)
+	}
+	if err := db.commit(); err != nil {
+		return errors.ErrUpdateUserOrgs.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// getEffectiveRoles returns the roles of a user along with the roles
+// inherited from the groups the user is a member of.
+func (db *Database) getEffectiveRoles(user *User) []string {
+	roles := user.GetRolesClaim()
+	roleMap := make(map[string]bool)
+	for _, role := range roles {
+		roleMap[role] = true
+	}
+	for _, group := range db.Groups {
+		if !group.HasMember(user.Username) {
+			continue
+		}
+		for _, role := range group.GetRolesClaim() {
+			if roleMap[role] {
+				continue
+			}
+			roleMap[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// GetGroups returns the groups in the database.
+func (db *Database) GetGroups(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	groups := []*Group{}
+	for _, group := range db.Groups {
+		entry := *group
+		groups = append(groups, &entry)
+	}
+	r.Response.Payload = groups
+	return nil
+}
+
+// GetGroup returns the group with the name in r.Group.Name.
+func (db *Database) GetGroup(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	group, exists := db.refGroup[strings.ToLower(r.Group.Name)]
+	if !exists {
+		return errors.ErrGroupNotFound.WithArgs(r.Group.Name)
+	}
+	entry := *group
+	r.Response.Payload = &entry
+	return nil
+}
+
+// AddGroup adds the group described by r.Group to the database.
+func (db *Database) AddGroup(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	group, err := NewGroup(r.Group.Name, r.Group.Description, r.Group.Roles)
+	if err != nil {
+		return errors.ErrAddGroup.WithArgs(r.Group.Name, err)
+	}
+	groupName := strings.ToLower(group.Name)
+	if _, exists := db.refGroup[groupName]; exists {
+		return errors.ErrAddGroup.WithArgs(r.Group.Name, errors.ErrGroupExists.WithArgs(group.Name))
+	}
+	db.Groups = append(db.Groups, group)
+	db.refGroup[groupName] = group
+	if err := db.commit(); err != nil {
+		return errors.ErrAddGroup.WithArgs(r.Group.Name, err)
+	}
+	r.Response.Payload = group
+	return nil
+}
+
+// UpdateGroup replaces the description and the roles of the group with the
+// ones in r.Group.
+func (db *Database) UpdateGroup(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	group, exists := db.refGroup[strings.ToLower(r.Group.Name)]
+	if !exists {
+		return errors.ErrUpdateGroup.WithArgs(r.Group.Name, errors.ErrGroupNotFound.WithArgs(r.Group.Name))
+	}
+	if err := group.Update(r.Group.Description, r.Group.Roles); err != nil {
+		return errors.ErrUpdateGroup.WithArgs(r.Group.Name, err)
+	}
+	if err := db.commit(); err != nil {
+		return errors.ErrUpdateGroup.WithArgs(r.Group.Name, err)
+	}
+	r.Response.Payload = group
+	return nil
+}
+
+// DeleteGroup deletes the group with the name in r.Group.Name.
+func (db *Database) DeleteGroup(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	groupName := strings.ToLower(r.Group.Name)
+	if _, exists := db.refGroup[groupName]; !exists {
+		return errors.ErrDeleteGroup.WithArgs(r.Group.Name, errors.ErrGroupNotFound.WithArgs(r.Group.Name))
+	}
+	groups := []*Group{}
+	for _, group := range db.Groups {
+		if strings.ToLower(group.Name) == groupName {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	db.Groups = groups
+	delete(db.refGroup, groupName)
+	if err := db.commit(); err != nil {
+		return errors.ErrDeleteGroup.WithArgs(r.Group.Name, err)
+	}
+	return nil
+}
+
+// AddGroupMember adds the user identified by r.User to the group with the
+// name in r.Group.Name.
+func (db *Database) AddGroupMember(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddGroupMember.WithArgs(r.User.Username, r.Group.Name, err)
+	}
+	group, exists := db.refGroup[strings.ToLower(r.Group.Name)]
+	if !exists {
+		return errors.ErrAddGroupMember.WithArgs(r.User.Username, r.Group.Name, errors.ErrGroupNotFound.WithArgs(r.Group.Name))
+	}
+	group.AddMember(user.Username)
+	if err := db.commit(); err != nil {
+		return errors.ErrAddGroupMember.WithArgs(r.User.Username, r.Group.Name, err)
+	}
+	return nil
+}
+
+// DeleteGroupMember removes the user identified by r.User from the group
+// with the name in r.Group.Name.
+func (db *Database) DeleteGroupMember(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	group, exists := db.refGroup[strings.ToLower(r.Group.Name)]
+	if !exists {
+		return errors.ErrDeleteGroupMember.WithArgs(r.User.Username, r.Group.Name, errors.ErrGroupNotFound.WithArgs(r.Group.Name))
+	}
+	if !group.DeleteMember(r.User.Username) {
+		return errors.ErrDeleteGroupMember.WithArgs(r.User.Username, r.Group.Name, errors.ErrGroupMemberNotFound.WithArgs(r.User.Username, r.Group.Name))
+	}
+	if err := db.commit(); err != nil {
+		return errors.ErrDeleteGroupMember.WithArgs(r.User.Username, r.Group.Name, err)
+	}
+	return nil
+}
+
+// ResetMfaTokens deletes all MFA tokens of a user.
+func (db *Database) ResetMfaTokens(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrResetMfaTokens.WithArgs(r.User.Username, err)
+	}
+	user.MfaTokens = nil
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return errors.ErrResetMfaTokens.WithArgs(r.User.Username, err)
+	}
+	return nil
 }
 
 // AuthenticateUser adds user identity to the database.
@@ -386,6 +747,11 @@ func (db *Database) AuthenticateUser(r *requests.Request) error {
 		return errors.ErrAuthFailed.WithArgs("malformed auth request")
 	}
 
+	if user.Lockout.Active(time.Now()) {
+		r.Response.Code = 400
+		return errors.ErrAuthFailed.WithArgs(errors.ErrUserDisabled)
+	}
+
 	r.Response.Code = 200
 	return nil
 }
@@ -446,6 +812,10 @@ func (db *Database) GetUserCount() int {
 func (db *Database) GetAdminUserCount() int {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
+	return db.countAdminUsers()
+}
+
+func (db *Database) countAdminUsers() int {
 	var counter int
 	for _, user := range db.Users {
 		if user.HasAdminRights() {
@@ -481,7 +851,7 @@ func (db *Database) commit() error {
 	if err != nil {
 		return errors.ErrDatabaseCommit.WithArgs(db.path, err)
 	}
-	if err := ioutil.WriteFile(db.path, []byte(data), 0600); err != nil {
+	if err := db.write(data); err != nil {
 		return errors.ErrDatabaseCommit.WithArgs(db.path, err)
 	}
 	return nil
@@ -566,13 +936,55 @@ func (db *Database) AddAPIKey(r *requests.Request) error {
 	if err != nil {
 		return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, err)
 	}
-	s := GetRandomStringFromRange(72, 96)
+	s, err := db.newAPIKeySecret(r)
+	if err != nil {
+		return err
+	}
+	if err := user.AddAPIKey(r); err != nil {
+		return err
+	}
+	db.refAPIKey[r.Key.Prefix] = user
+	r.Response.Payload = s
+	if err := db.commit(); err != nil {
+		return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, err)
+	}
+	return nil
+}
+
+// RotateAPIKey replaces an API key of a user with a new one. Both keys remain
+// valid until the grace period of the replaced key elapses.
+func (db *Database) RotateAPIKey(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrRotateAPIKey.WithArgs(r.Key.ID, err)
+	}
+	s, err := db.newAPIKeySecret(r)
+	if err != nil {
+		return err
+	}
+	if _, err := user.RotateAPIKey(r); err != nil {
+		return err
+	}
+	db.refAPIKey[r.Key.Prefix] = user
+	r.Response.Payload = s
+	if err := db.commit(); err != nil {
+		return errors.ErrRotateAPIKey.WithArgs(r.Key.ID, err)
+	}
+	return nil
+}
+
+// newAPIKeySecret generates a new API key with a unique prefix. The hash of
+// the key is in r.Key.Payload and the prefix is in r.Key.Prefix.
+func (db *Database) newAPIKeySecret(r *requests.Request) (string, error) {
 	failCount := 0
 	for {
+		s := GetRandomStringFromRange(72, 96)
 		hk, err := NewPassword(s)
 		if err != nil {
 			if failCount > 10 {
-				return err
+				return "", err
 			}
 			failCount++
 			continue
@@ -581,20 +993,10 @@ func (db *Database) AddAPIKey(r *requests.Request) error {
 		if _, exists := db.refAPIKey[keyPrefix]; exists {
 			continue
 		}
-		r.Response.Payload = s
 		r.Key.Payload = hk.Hash
 		r.Key.Prefix = keyPrefix
-		if err := user.AddAPIKey(r); err != nil {
-			return err
-		}
-		db.refAPIKey[keyPrefix] = user
-		break
-	}
-
-	if err := db.commit(); err != nil {
-		return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, err)
+		return s, nil
 	}
-	return nil
 }
 
 // DeleteAPIKey deletes an API key associated with a user by key id.
@@ -609,6 +1011,7 @@ func (db *Database) DeleteAPIKey(r *requests.Request) error {
 		return err
 	}
 	delete(db.refAPIKey, r.Key.Prefix)
+	delete(db.apiKeyDigests, r.Key.Prefix)
 	if err := db.commit(); err != nil {
 		return errors.ErrDeleteAPIKey.WithArgs(r.Key.Usage, err)
 	}
@@ -678,53 +1081,534 @@ func (db *Database) UpdateUserPassword(r *requests.Request) error {
 	return nil
 }
 
-// IdentifyUser returns user identity and a list of challenges that should be
-// satisfied prior to successfully authenticating a user.
-func (db *Database) IdentifyUser(r *requests.Request) error {
+// ChangeUserEmailAddress replaces the primary email address of a user with
+// the one in r.User.NewEmail.
+func (db *Database) ChangeUserEmailAddress(r *requests.Request) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	user, err := db.getUser(r.User.Username)
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
-		r.User.Username = "nobody"
-		r.User.Email = "nobody@localhost"
-		r.User.Challenges = []string{"password"}
-		return nil
+		return errors.ErrChangeUserEmail.WithArgs(err)
 	}
-	if r.Flags.Enabled {
-		user.GetFlags(r)
+	oldEmailAddress := strings.ToLower(user.GetMailClaim())
+	newEmailAddress := strings.ToLower(r.User.NewEmail)
+	if user2, exists := db.refEmailAddress[newEmailAddress]; exists && user2.ID != user.ID {
+		return errors.ErrChangeUserEmail.WithArgs("email address already in use")
+	}
+	if err := user.ChangeEmailAddress(r.User.NewEmail); err != nil {
+		return errors.ErrChangeUserEmail.WithArgs(err)
+	}
+	delete(db.refEmailAddress, oldEmailAddress)
+	db.refEmailAddress[newEmailAddress] = user
+	if err := db.commit(); err != nil {
+		return errors.ErrChangeUserEmail.WithArgs(err)
 	}
-	r.User.Username = user.Username
-	r.User.Email = user.GetMailClaim()
-	r.User.FullName = user.GetNameClaim()
-	r.User.Roles = user.GetRolesClaim()
-	r.User.Challenges = user.GetChallenges()
-	r.Response.Code = 200
 	return nil
 }
 
-// LookupAPIKey returns username and email associated with the provided API
-// key.
-func (db *Database) LookupAPIKey(r *requests.Request) error {
-	if r.Key.Payload == "" {
-		return errors.ErrLookupAPIKeyPayloadEmpty
-	}
-	if len(r.Key.Payload) < 72 {
-		return errors.ErrLookupAPIKeyMalformedPayload
-	}
-	r.Key.Prefix = string(r.Key.Payload[:24])
+// UpdateUserProfile updates the self-managed profile attributes of a user.
+func (db *Database) UpdateUserProfile(r *requests.Request) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	user, exists := db.refAPIKey[r.Key.Prefix]
-	if !exists {
-		return errors.ErrLookupAPIKeyFailed
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrUpdateUserProfile.WithArgs(err)
 	}
-	if err := user.LookupAPIKey(r); err != nil {
-		return err
+	if err := user.UpdateProfile(r.Profile.Name, r.Profile.Phone, r.Profile.Language); err != nil {
+		return errors.ErrUpdateUserProfile.WithArgs(err)
 	}
-	r.User.Username = user.Username
-	r.User.Email = user.GetMailClaim()
-	r.Response.Code = 200
-	return nil
+	if err := db.commit(); err != nil {
+		return errors.ErrUpdateUserProfile.WithArgs(err)
+	}
+	r.Response.Payload = user
+	return nil
+}
+
+// GetUserConsents returns a list of consents a user granted to applications.
+func (db *Database) GetUserConsents(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrGetUsers.WithArgs(err)
+	}
+	consents := []*Consent{}
+	consents = append(consents, user.Consents...)
+	r.Response.Payload = consents
+	return nil
+}
+
+// AddUserConsent records the consent a user granted to an application.
+func (db *Database) AddUserConsent(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddUserConsent.WithArgs(r.Consent.App, err)
+	}
+	consent := user.AddConsent(r.Consent.App, r.Consent.Scopes)
+	if err := db.commit(); err != nil {
+		return errors.ErrAddUserConsent.WithArgs(r.Consent.App, err)
+	}
+	r.Response.Payload = consent
+	return nil
+}
+
+// DeleteUserConsent withdraws the consent a user granted to an application.
+func (db *Database) DeleteUserConsent(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrDeleteUserConsent.WithArgs(r.Consent.ID, err)
+	}
+	if err := user.DeleteConsent(r.Consent.ID); err != nil {
+		return errors.ErrDeleteUserConsent.WithArgs(r.Consent.ID, err)
+	}
+	if err := db.commit(); err != nil {
+		return errors.ErrDeleteUserConsent.WithArgs(r.Consent.ID, err)
+	}
+	return nil
+}
+
+// GetUserLinkedIdentities returns a list of the identities at identity
+// providers linked to the account of a user.
+func (db *Database) GetUserLinkedIdentities(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrGetUsers.WithArgs(err)
+	}
+	linkedIdentities := []*LinkedIdentity{}
+	linkedIdentities = append(linkedIdentities, user.LinkedIdentities...)
+	r.Response.Payload = linkedIdentities
+	return nil
+}
+
+// AddUserLinkedIdentity links the identity at an identity provider to the
+// account of a user. The identity could be linked to a single account only.
+func (db *Database) AddUserLinkedIdentity(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddUserLinkedIdentity.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm, err)
+	}
+	k := getLinkedIdentityKey(r.LinkedIdentity.Realm, r.LinkedIdentity.Subject)
+	if _, exists := db.refLinkedIdentity[k]; exists {
+		return errors.ErrAddUserLinkedIdentity.WithArgs(
+			r.LinkedIdentity.Subject, r.LinkedIdentity.Realm,
+			errors.ErrLinkedIdentityExists.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm),
+		)
+	}
+	li, err := user.AddLinkedIdentity(r.LinkedIdentity.Realm, r.LinkedIdentity.Kind, r.LinkedIdentity.Subject, r.LinkedIdentity.Email)
+	if err != nil {
+		return errors.ErrAddUserLinkedIdentity.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm, err)
+	}
+	db.refLinkedIdentity[k] = user
+	if err := db.commit(); err != nil {
+		return errors.ErrAddUserLinkedIdentity.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm, err)
+	}
+	r.Response.Payload = li
+	return nil
+}
+
+// DeleteUserLinkedIdentity unlinks the identity at an identity provider
+// from the account of a user.
+func (db *Database) DeleteUserLinkedIdentity(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrDeleteUserLinkedIdentity.WithArgs(r.LinkedIdentity.ID, err)
+	}
+	li, err := user.DeleteLinkedIdentity(r.LinkedIdentity.ID)
+	if err != nil {
+		return errors.ErrDeleteUserLinkedIdentity.WithArgs(r.LinkedIdentity.ID, err)
+	}
+	delete(db.refLinkedIdentity, getLinkedIdentityKey(li.Realm, li.Subject))
+	if err := db.commit(); err != nil {
+		return errors.ErrDeleteUserLinkedIdentity.WithArgs(r.LinkedIdentity.ID, err)
+	}
+	r.Response.Payload = li
+	return nil
+}
+
+// LookupLinkedIdentity returns the identity of the user the identity at an
+// identity provider is linked to.
+func (db *Database) LookupLinkedIdentity(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, exists := db.refLinkedIdentity[getLinkedIdentityKey(r.LinkedIdentity.Realm, r.LinkedIdentity.Subject)]
+	if !exists {
+		return errors.ErrLookupLinkedIdentityFailed.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm)
+	}
+	if user.Lockout.Active(time.Now()) {
+		return errors.ErrLookupLinkedIdentityFailed.WithArgs(r.LinkedIdentity.Subject, r.LinkedIdentity.Realm)
+	}
+	r.User.Username = user.Username
+	r.User.Email = user.GetMailClaim()
+	r.User.FullName = user.GetNameClaim()
+	r.User.Roles = db.getEffectiveRoles(user)
+	r.User.Organizations = user.GetOrganizationsClaim()
+	r.Response.Code = 200
+	return nil
+}
+
+// GetUserAcceptances returns the versions of the documents a user accepted.
+func (db *Database) GetUserAcceptances(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrGetUsers.WithArgs(err)
+	}
+	acceptances := []*Acceptance{}
+	acceptances = append(acceptances, user.Acceptances...)
+	r.Response.Payload = acceptances
+	return nil
+}
+
+// AddUserAcceptance records the acceptance of a version of a document by
+// a user.
+func (db *Database) AddUserAcceptance(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.Acceptance.Document == "" || r.Acceptance.Version == "" {
+		return errors.ErrAddUserAcceptance.WithArgs(r.Acceptance.Document, errors.ErrAcceptanceInvalid)
+	}
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddUserAcceptance.WithArgs(r.Acceptance.Document, err)
+	}
+	acceptance := user.AddAcceptance(r.Acceptance.Document, r.Acceptance.Version)
+	if err := db.commit(); err != nil {
+		return errors.ErrAddUserAcceptance.WithArgs(r.Acceptance.Document, err)
+	}
+	r.Response.Payload = acceptance
+	return nil
+}
+
+// AddUserLoginRecord adds the authentication attempt in r.LoginRecord to
+// the login history of a user.
+func (db *Database) AddUserLoginRecord(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.LoginRecord.Outcome == "" || r.LoginRecord.Timestamp.IsZero() {
+		return errors.ErrAddUserLoginRecord.WithArgs(r.User.Username, errors.ErrLoginRecordInvalid)
+	}
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddUserLoginRecord.WithArgs(r.User.Username, err)
+	}
+	rec := &LoginRecord{
+		Timestamp: r.LoginRecord.Timestamp.UTC(),
+		Address:   r.LoginRecord.Address,
+		Country:   r.LoginRecord.Country,
+		City:      r.LoginRecord.City,
+		Device:    r.LoginRecord.Device,
+		Provider:  r.LoginRecord.Provider,
+		Outcome:   r.LoginRecord.Outcome,
+	}
+	user.AddLoginRecord(rec, r.LoginRecord.MaxEntries, r.LoginRecord.MaxAge)
+	if err := db.commit(); err != nil {
+		return errors.ErrAddUserLoginRecord.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// ExportUser returns the data held about a user, without secrets.
+func (db *Database) ExportUser(r *requests.Request) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrExportUser.WithArgs(r.User.Username, err)
+	}
+	r.Response.Payload = user.Export()
+	return nil
+}
+
+// ScheduleUserDeletion schedules the deletion of a user at the time in
+// r.Deletion.ScheduledAt.
+func (db *Database) ScheduleUserDeletion(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.Deletion.ScheduledAt.IsZero() {
+		return errors.ErrScheduleUserDeletion.WithArgs(r.User.Username, errors.ErrDeletionTimeInvalid)
+	}
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrScheduleUserDeletion.WithArgs(r.User.Username, err)
+	}
+	if user.HasAdminRights() && db.countAdminUsers() < 2 {
+		return errors.ErrScheduleUserDeletion.WithArgs(r.User.Username, errors.ErrLastAdminUser)
+	}
+	user.Deletion = NewDeletionState(r.Deletion.ScheduledAt)
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return errors.ErrScheduleUserDeletion.WithArgs(r.User.Username, err)
+	}
+	r.Response.Payload = user.Deletion
+	return nil
+}
+
+// CancelUserDeletion cancels the scheduled deletion of a user, if any. The
+// response payload indicates whether the deletion was cancelled.
+func (db *Database) CancelUserDeletion(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrCancelUserDeletion.WithArgs(r.User.Username, err)
+	}
+	if user.Deletion == nil {
+		r.Response.Payload = false
+		return nil
+	}
+	user.Deletion = nil
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return errors.ErrCancelUserDeletion.WithArgs(r.User.Username, err)
+	}
+	r.Response.Payload = true
+	return nil
+}
+
+// PurgeUserDeletions deletes the users whose scheduled deletion is due.
+// The response payload holds the deleted users.
+func (db *Database) PurgeUserDeletions(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now().UTC()
+	users := []*User{}
+	for _, user := range db.Users {
+		if !user.Deletion.Due(now) {
+			continue
+		}
+		if user.HasAdminRights() && db.countAdminUsers() < 2 {
+			continue
+		}
+		db.removeUser(user)
+		users = append(users, user)
+	}
+	if len(users) > 0 {
+		if err := db.commit(); err != nil {
+			return errors.ErrPurgeUserDeletions.WithArgs(err)
+		}
+	}
+	r.Response.Payload = users
+	return nil
+}
+
+// DisableDormantUsers disables the users whose last successful login, or
+// the creation of the account in the absence of logins, predates the cutoff
+// in r.Lifecycle. The last enabled administrator remains enabled. The
+// response payload holds the disabled users.
+func (db *Database) DisableDormantUsers(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.Lifecycle.Cutoff.IsZero() {
+		return errors.ErrDisableDormantUsers.WithArgs(errors.ErrLifecycleCutoff)
+	}
+	now := time.Now().UTC()
+	var admins int
+	for _, user := range db.Users {
+		if user.HasAdminRights() && !user.Lockout.Active(now) {
+			admins++
+		}
+	}
+	items := []*requests.LifecycleItem{}
+	for _, user := range db.Users {
+		if user.Lockout.Active(now) {
+			continue
+		}
+		lastSeen := user.GetLastLogin()
+		if lastSeen.IsZero() {
+			lastSeen = user.Created
+		}
+		if !lastSeen.Before(r.Lifecycle.Cutoff) {
+			continue
+		}
+		if user.HasAdminRights() {
+			if admins < 2 {
+				continue
+			}
+			admins--
+		}
+		items = append(items, &requests.LifecycleItem{
+			Username: user.Username,
+			Email:    user.GetMailClaim(),
+			Time:     lastSeen,
+		})
+		if r.Lifecycle.DryRun {
+			continue
+		}
+		user.Lockout = &LockoutState{Enabled: true, StartTime: now}
+		user.Revise()
+	}
+	if len(items) > 0 && !r.Lifecycle.DryRun {
+		if err := db.commit(); err != nil {
+			return errors.ErrDisableDormantUsers.WithArgs(err)
+		}
+	}
+	r.Response.Payload = items
+	return nil
+}
+
+// EnableUser removes the lockout of a user, e.g. the one of a dormant
+// account. The response payload indicates whether the user was disabled.
+func (db *Database) EnableUser(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrEnableUser.WithArgs(r.User.Username, err)
+	}
+	if user.Lockout == nil {
+		r.Response.Payload = false
+		return nil
+	}
+	user.Lockout = nil
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return errors.ErrEnableUser.WithArgs(r.User.Username, err)
+	}
+	r.Response.Payload = true
+	return nil
+}
+
+// PruneLoginRecords removes the records older than the cutoff in
+// r.Lifecycle from the login history of the users. The response payload
+// holds the users and the number of their removed records.
+func (db *Database) PruneLoginRecords(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.Lifecycle.Cutoff.IsZero() {
+		return errors.ErrPruneLoginRecords.WithArgs(errors.ErrLifecycleCutoff)
+	}
+	items := []*requests.LifecycleItem{}
+	for _, user := range db.Users {
+		var n int
+		if r.Lifecycle.DryRun {
+			for _, entry := range user.LoginHistory {
+				if entry.Timestamp.Before(r.Lifecycle.Cutoff) {
+					n++
+				}
+			}
+		} else {
+			n = user.PruneLoginHistory(r.Lifecycle.Cutoff)
+		}
+		if n == 0 {
+			continue
+		}
+		items = append(items, &requests.LifecycleItem{
+			Username: user.Username,
+			Email:    user.GetMailClaim(),
+			Count:    n,
+		})
+	}
+	if len(items) > 0 && !r.Lifecycle.DryRun {
+		if err := db.commit(); err != nil {
+			return errors.ErrPruneLoginRecords.WithArgs(err)
+		}
+	}
+	r.Response.Payload = items
+	return nil
+}
+
+// IdentifyUser returns user identity and a list of challenges that should be
+// satisfied prior to successfully authenticating a user.
+func (db *Database) IdentifyUser(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.getUser(r.User.Username)
+	if err != nil {
+		r.User.Username = "nobody"
+		r.User.Email = "nobody@localhost"
+		r.User.Challenges = []string{"password"}
+		return nil
+	}
+	if r.Flags.Enabled {
+		user.GetFlags(r)
+	}
+	r.User.Username = user.Username
+	r.User.Email = user.GetMailClaim()
+	r.User.FullName = user.GetNameClaim()
+	r.User.Roles = db.getEffectiveRoles(user)
+	r.User.Organizations = user.GetOrganizationsClaim()
+	r.User.Challenges = user.GetChallenges()
+	r.Response.Code = 200
+	return nil
+}
+
+// LookupAPIKey returns username and email associated with the provided API
+// key, along with the scopes, roles, and expiration time of the key. The key
+// is found via its prefix. The bcrypt comparison is performed once per key,
+// the subsequent lookups match the digest of the key.
+func (db *Database) LookupAPIKey(r *requests.Request) error {
+	if r.Key.Payload == "" {
+		return errors.ErrLookupAPIKeyPayloadEmpty
+	}
+	if len(r.Key.Payload) < 72 {
+		return errors.ErrLookupAPIKeyMalformedPayload
+	}
+	r.Key.Prefix = string(r.Key.Payload[:24])
+	digest := sha256.Sum256([]byte(r.Key.Payload))
+	now := time.Now().UTC()
+
+	db.mu.RLock()
+	user, exists := db.refAPIKey[r.Key.Prefix]
+	if !exists {
+		db.mu.RUnlock()
+		return errors.ErrLookupAPIKeyFailed
+	}
+	k := user.GetAPIKey(r.Key.Prefix)
+	if k == nil {
+		db.mu.RUnlock()
+		return errors.ErrLookupAPIKeyFailed
+	}
+	key := *k
+	knownDigest, verified := db.apiKeyDigests[r.Key.Prefix]
+	disabled := user.Lockout.Active(now)
+	db.mu.RUnlock()
+
+	if !key.Active(now) {
+		return errors.ErrLookupAPIKeyExpired
+	}
+	if disabled {
+		return errors.ErrLookupAPIKeyFailed
+	}
+	if verified {
+		verified = subtle.ConstantTimeCompare(knownDigest[:], digest[:]) == 1
+	}
+	if !verified && !key.Match(r.Key.Payload) {
+		return errors.ErrLookupAPIKeyFailed
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.apiKeyDigests[r.Key.Prefix] = digest
+	if k := user.GetAPIKey(r.Key.Prefix); k != nil {
+		lastUsedAt := k.LastUsedAt
+		k.LastUsedAt = now
+		k.LastUsedAddress = r.Key.Address
+		// The last use is persisted at most once per interval.
+		if now.Sub(lastUsedAt) >= apiKeyLastUsedInterval {
+			if err := db.commit(); err != nil && r.Logger != nil {
+				r.Logger.Warn("failed persisting api key last use", zap.String("key_id", k.ID), zap.Error(err))
+			}
+		}
+	}
+	r.User.Username = user.Username
+	r.User.Email = user.GetMailClaim()
+	r.Key.ID = key.ID
+	r.Key.Scopes = key.Scopes
+	r.Key.Roles = key.Roles
+	r.Key.ExpiresAt = key.ExpiresAt
+	r.Response.Code = 200
+	return nil
 }
 
 // AddMfaToken adds MFA token for a user.
@@ -878,3 +1762,175 @@ func (db *Database) UserExists(username, emailAddress string) (bool, error) {
 	}
 	return true, nil
 }
+
+// GetRegistrations returns copies of the user identities having registration
+// metadata, i.e. the users awaiting or having received a verdict.
+func (db *Database) GetRegistrations() ([]*User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	users := []*User{}
+	for _, user := range db.Users {
+		if user.Registration == nil {
+			continue
+		}
+		u, err := copyUser(user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// GetUserByRegistrationID returns a copy of the user identity associated
+// with the provided registration id.
+func (db *Database) GetUserByRegistrationID(s string) (*User, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	user, err := db.getUserByRegistrationID(s)
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(user)
+}
+
+// SetRegistrationVerdict approves or declines the registration with the
+// provided id and returns a copy of the associated user identity.
+func (db *Database) SetRegistrationVerdict(id string, approved bool, comment, reviewer string) (*User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, err := db.getUserByRegistrationID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Registration.Reviewed() {
+		return nil, errors.ErrRegistrationReviewed.WithArgs(id)
+	}
+	if approved {
+		user.Registration.Approve()
+	} else {
+		user.Registration.Decline()
+	}
+	user.Registration.Comment = comment
+	user.Registration.ReviewedBy = reviewer
+	user.Revise()
+	if err := db.commit(); err != nil {
+		return nil, errors.ErrRegistrationVerdict.WithArgs(id, err)
+	}
+	return copyUser(user)
+}
+
+// PurgeRegistrations removes the registrations created before the cutoff in
+// r.Lifecycle that were not approved, i.e. the ones awaiting a verdict or
+// declined. The response payload holds the copies of the removed user
+// identities.
+func (db *Database) PurgeRegistrations(r *requests.Request) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if r.Lifecycle.Cutoff.IsZero() {
+		return errors.ErrPurgeRegistrations.WithArgs(errors.ErrLifecycleCutoff)
+	}
+	users := []*User{}
+	for _, user := range db.Users {
+		if user.Registration == nil || user.Registration.Approved {
+			continue
+		}
+		if !user.Registration.CreatedAt.Before(r.Lifecycle.Cutoff) {
+			continue
+		}
+		u, err := copyUser(user)
+		if err != nil {
+			return errors.ErrPurgeRegistrations.WithArgs(err)
+		}
+		users = append(users, u)
+	}
+	if len(users) > 0 && !r.Lifecycle.DryRun {
+		for _, u := range users {
+			if user, exists := db.refID[u.ID]; exists {
+				db.removeUser(user)
+			}
+		}
+		if err := db.commit(); err != nil {
+			return errors.ErrPurgeRegistrations.WithArgs(err)
+		}
+	}
+	r.Response.Payload = users
+	return nil
+}
+
+// ImportUser adds an existing user identity, including its password hashes
+// and roles, to the database. The imported user receives a new id.
+func (db *Database) ImportUser(u *User) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if u == nil {
+		return errors.ErrImportUser.WithArgs("", "user is nil")
+	}
+	user, err := copyUser(u)
+	if err != nil {
+		return errors.ErrImportUser.WithArgs(u.Username, err)
+	}
+	if err := user.Valid(); err != nil {
+		return errors.ErrImportUser.WithArgs(user.Username, err)
+	}
+	for i := 0; i < 10; i++ {
+		id := NewID()
+		if _, exists := db.refID[id]; !exists {
+			user.ID = id
+			break
+		}
+	}
+	username := strings.ToLower(user.Username)
+	if _, exists := db.refUsername[username]; exists {
+		return errors.ErrImportUser.WithArgs(username, "username already in use")
+	}
+
+	emailAddresses := []string{}
+	for _, email := range user.EmailAddresses {
+		emailAddress := strings.ToLower(email.Address)
+		if _, exists := db.refEmailAddress[emailAddress]; exists {
+			return errors.ErrImportUser.WithArgs(emailAddress, "email address already in use")
+		}
+		emailAddresses = append(emailAddresses, emailAddress)
+	}
+
+	db.refUsername[username] = user
+	db.refID[user.ID] = user
+	for _, emailAddress := range emailAddresses {
+		db.refEmailAddress[emailAddress] = user
+	}
+	db.Users = append(db.Users, user)
+
+	if err := db.commit(); err != nil {
+		return errors.ErrImportUser.WithArgs(username, err)
+	}
+	return nil
+}
+
+func (db *Database) getUserByRegistrationID(s string) (*User, error) {
+	if s == "" {
+		return nil, errors.ErrRegistrationNotFound.WithArgs(s)
+	}
+	for _, user := range db.Users {
+		if user.Registration == nil {
+			continue
+		}
+		if user.Registration.ID == s {
+			return user, nil
+		}
+	}
+	return nil, errors.ErrRegistrationNotFound.WithArgs(s)
+}
+
+func copyUser(user *User) (*User, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	u := &User{}
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -434,7 +434,7 @@ func TestDatabaseAddUser(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrAddUser.WithArgs("foobar",
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 	}
@@ -458,6 +458,711 @@ func TestDatabaseAddUser(t *testing.T) {
 	}
 }
 
+func TestDatabaseImportUser(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseImportUser")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	user, err := NewUserWithRoles("foobar", testPwd2, "foobar@barfoo", "", []string{"authp/user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user.Registration = NewRegistration("foobar-registration")
+
+	err = db.ImportUser(user)
+	if tests.EvalErrWithLog(t, err, "import user", false, nil, nil) {
+		return
+	}
+	imported, err := db.getUserByUsername("foobar")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if imported.ID == user.ID {
+		t.Fatalf("expected imported user to receive new id")
+	}
+	if err := imported.VerifyPassword(testPwd2); err != nil {
+		t.Fatalf("expected imported user to keep password: %v", err)
+	}
+
+	err = db.ImportUser(user)
+	tests.EvalErrWithLog(t, err, "import duplicate user", true, errors.ErrImportUser.WithArgs("foobar", "username already in use"), nil)
+
+	found, err := db.SetRegistrationVerdict("foobar-registration", false, "duplicate", "admin@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "registration declined", true, found.Registration.Declined)
+	_, err = db.SetRegistrationVerdict("foobar-registration", true, "", "admin@localhost")
+	tests.EvalErrWithLog(t, err, "review reviewed registration", true, errors.ErrRegistrationReviewed.WithArgs("foobar-registration"), nil)
+}
+
+func TestDatabaseChangeUserEmailAddress(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseChangeUserEmailAddress")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+			NewEmail: testEmail2,
+		},
+	}
+	err = db.ChangeUserEmailAddress(req)
+	tests.EvalErrWithLog(t, err, "change to email in use", true, errors.ErrChangeUserEmail.WithArgs("email address already in use"), nil)
+
+	req.User.NewEmail = "jsmith.new@localhost"
+	err = db.ChangeUserEmailAddress(req)
+	if tests.EvalErrWithLog(t, err, "change email", false, nil, nil) {
+		return
+	}
+	user, err := db.getUserByEmailAddress("jsmith.new@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "username", testUser1, user.Username)
+	tests.EvalObjects(t, "mail claim", "jsmith.new@localhost", user.GetMailClaim())
+	if _, err := db.getUserByEmailAddress(testEmail1); err == nil {
+		t.Fatalf("expected previous email address %q to be released", testEmail1)
+	}
+}
+
+func TestDatabaseUserAdministration(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserAdministration")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser2,
+			Email:    testEmail2,
+			Roles:    []string{"authp/admin", "viewer"},
+		},
+	}
+	if err := db.UpdateUserRoles(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, err := db.getUserByUsername(testUser2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "roles", []string{"authp/admin", "viewer"}, user.GetRolesClaim())
+
+	req.User.Organizations = []string{"acme", "globex", "acme"}
+	if err := db.UpdateUserOrganizations(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "organizations", []string{"acme", "globex"}, user.GetOrganizationsClaim())
+	identifyReq := &requests.Request{User: requests.User{Username: testUser2}}
+	if err := db.IdentifyUser(identifyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "identified organizations", []string{"acme", "globex"}, identifyReq.User.Organizations)
+	req.User.Organizations = []string{"acme/editor"}
+	err = db.UpdateUserOrganizations(req)
+	tests.EvalErrWithLog(t, err, "invalid organization", true, errors.ErrUpdateUserOrgs.WithArgs(testUser2, errors.ErrOrganizationInvalid.WithArgs("acme/editor")), nil)
+
+	user.MfaTokens = append(user.MfaTokens, &MfaToken{ID: "foo"})
+	if err := db.ResetMfaTokens(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "mfa token count", 0, len(user.MfaTokens))
+
+	req.User.Roles = []string{"viewer"}
+	err = db.UpdateUserRoles(req)
+	tests.EvalErrWithLog(t, err, "demote last admin", true, errors.ErrUpdateUserRoles.WithArgs(testUser2, errors.ErrLastAdminUser), nil)
+
+	err = db.DeleteUser(req)
+	tests.EvalErrWithLog(t, err, "delete last admin", true, errors.ErrDeleteUser.WithArgs(testUser2, errors.ErrLastAdminUser), nil)
+
+	req = &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	if err := db.DeleteUser(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := db.getUserByUsername(testUser1); err == nil {
+		t.Fatalf("expected user %q to be deleted", testUser1)
+	}
+	if _, err := db.getUserByEmailAddress(testEmail1); err == nil {
+		t.Fatalf("expected email address %q to be released", testEmail1)
+	}
+}
+
+func TestDatabaseGroups(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseGroups")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	req := &requests.Request{}
+	req.Group.Name = "editors"
+	req.Group.Roles = []string{"publisher", "viewer"}
+	if err := db.AddGroup(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = db.AddGroup(req)
+	tests.EvalErrWithLog(t, err, "duplicate group", true, errors.ErrAddGroup.WithArgs("editors", errors.ErrGroupExists.WithArgs("editors")), nil)
+
+	req.User.Username = testUser1
+	req.User.Email = testEmail1
+	if err := db.AddGroupMember(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	identifyReq := &requests.Request{User: requests.User{Username: testUser1}}
+	if err := db.IdentifyUser(identifyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "inherited roles", []string{"viewer", "editor", "admin", "publisher"}, identifyReq.User.Roles)
+
+	req.Group.Roles = []string{"reviewer"}
+	if err := db.UpdateGroup(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.IdentifyUser(identifyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "updated roles", []string{"viewer", "editor", "admin", "reviewer"}, identifyReq.User.Roles)
+
+	if err := db.DeleteGroupMember(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = db.DeleteGroupMember(req)
+	tests.EvalErrWithLog(t, err, "absent member", true, errors.ErrDeleteGroupMember.WithArgs(testUser1, "editors", errors.ErrGroupMemberNotFound.WithArgs(testUser1, "editors")), nil)
+
+	if err := db.AddGroupMember(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.DeleteUser(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.GetGroup(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "members after user deletion", 0, len(req.Response.Payload.(*Group).Members))
+
+	if err := db.DeleteGroup(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = db.GetGroup(req)
+	tests.EvalErrWithLog(t, err, "deleted group", true, errors.ErrGroupNotFound.WithArgs("editors"), nil)
+}
+
+func TestDatabaseUserProfileAndConsents(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserProfileAndConsents")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	name, phone := "Smith, John", "+14155550100"
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+		Profile: requests.Profile{
+			Name:  &name,
+			Phone: &phone,
+		},
+	}
+	if err := db.UpdateUserProfile(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := req.Response.Payload.(*User).GetMetadata()
+	tests.EvalObjects(t, "name", "Smith, John", m.Name)
+	tests.EvalObjects(t, "phone", phone, m.Phone)
+
+	req.Consent = requests.Consent{App: "grafana", Scopes: []string{"openid"}}
+	if err := db.AddUserConsent(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Consent = requests.Consent{App: "grafana", Scopes: []string{"email"}}
+	if err := db.AddUserConsent(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	consent := req.Response.Payload.(*Consent)
+	tests.EvalObjects(t, "consent scopes", []string{"email", "openid"}, consent.Scopes)
+
+	if err := db.GetUserConsents(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "consent count", 1, len(req.Response.Payload.([]*Consent)))
+
+	req.Consent = requests.Consent{ID: "foo"}
+	err = db.DeleteUserConsent(req)
+	tests.EvalErrWithLog(t, err, "withdraw unknown consent", true, errors.ErrDeleteUserConsent.WithArgs("foo", errors.ErrConsentNotFound.WithArgs("foo")), nil)
+	req.Consent = requests.Consent{ID: consent.ID}
+	if err := db.DeleteUserConsent(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.GetUserConsents(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "consent count", 0, len(req.Response.Payload.([]*Consent)))
+}
+
+func TestDatabaseUserAcceptances(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserAcceptances")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	req.Acceptance = requests.Acceptance{Document: "terms"}
+	err = db.AddUserAcceptance(req)
+	tests.EvalErrWithLog(t, err, "acceptance without version", true, errors.ErrAddUserAcceptance.WithArgs("terms", errors.ErrAcceptanceInvalid), nil)
+
+	for _, version := range []string{"2023-01", "2023-06"} {
+		req.Acceptance = requests.Acceptance{Document: "terms", Version: version}
+		if err := db.AddUserAcceptance(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	req.Acceptance = requests.Acceptance{Document: "privacy", Version: "1"}
+	if err := db.AddUserAcceptance(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := db.GetUserAcceptances(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, a := range req.Response.Payload.([]*Acceptance) {
+		got[a.Document] = a.Version
+	}
+	tests.EvalObjects(t, "acceptances", map[string]string{"terms": "2023-06", "privacy": "1"}, got)
+}
+
+func TestDatabaseUserLinkedIdentities(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserLinkedIdentities")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	req.LinkedIdentity = requests.LinkedIdentity{Realm: "github"}
+	err = db.AddUserLinkedIdentity(req)
+	tests.EvalErrWithLog(t, err, "identity without subject", true, errors.ErrAddUserLinkedIdentity.WithArgs("", "github", errors.ErrLinkedIdentityInvalid), nil)
+
+	req.LinkedIdentity = requests.LinkedIdentity{Realm: "github", Kind: "oauth2", Subject: "1234", Email: "jsmith@github.com"}
+	if err := db.AddUserLinkedIdentity(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	li := req.Response.Payload.(*LinkedIdentity)
+	err = db.AddUserLinkedIdentity(req)
+	tests.EvalErrWithLog(t, err, "identity linked twice", true, errors.ErrAddUserLinkedIdentity.WithArgs("1234", "github", errors.ErrLinkedIdentityExists.WithArgs("1234", "github")), nil)
+
+	if err := db.GetUserLinkedIdentities(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "linked identity count", 1, len(req.Response.Payload.([]*LinkedIdentity)))
+
+	lookup := &requests.Request{LinkedIdentity: requests.LinkedIdentity{Realm: "github", Subject: "1234"}}
+	if err := db.LookupLinkedIdentity(lookup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "linked username", testUser1, lookup.User.Username)
+	lookup = &requests.Request{LinkedIdentity: requests.LinkedIdentity{Realm: "google", Subject: "1234"}}
+	err = db.LookupLinkedIdentity(lookup)
+	tests.EvalErrWithLog(t, err, "identity of other realm", true, errors.ErrLookupLinkedIdentityFailed.WithArgs("1234", "google"), nil)
+
+	req.LinkedIdentity = requests.LinkedIdentity{ID: "foo"}
+	err = db.DeleteUserLinkedIdentity(req)
+	tests.EvalErrWithLog(t, err, "unlink unknown identity", true, errors.ErrDeleteUserLinkedIdentity.WithArgs("foo", errors.ErrLinkedIdentityNotFound.WithArgs("foo")), nil)
+	req.LinkedIdentity = requests.LinkedIdentity{ID: li.ID}
+	if err := db.DeleteUserLinkedIdentity(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lookup = &requests.Request{LinkedIdentity: requests.LinkedIdentity{Realm: "github", Subject: "1234"}}
+	err = db.LookupLinkedIdentity(lookup)
+	tests.EvalErrWithLog(t, err, "unlinked identity", true, errors.ErrLookupLinkedIdentityFailed.WithArgs("1234", "github"), nil)
+}
+
+func TestDatabaseExportUser(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseExportUser")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	if err := db.ExportUser(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	export := req.Response.Payload.(*UserExport)
+	tests.EvalObjects(t, "username", testUser1, export.Metadata.Username)
+	tests.EvalObjects(t, "email", testEmail1, export.Metadata.Email)
+	if len(export.Passwords) == 0 {
+		t.Fatalf("expected password metadata")
+	}
+	for _, p := range export.Passwords {
+		tests.EvalObjects(t, "password hash", "", p.Hash)
+	}
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Passwords[0].Hash == "" {
+		t.Fatalf("expected export to leave the password hash of the user intact")
+	}
+
+	req.User.Username = "foobar"
+	err = db.ExportUser(req)
+	if err == nil {
+		t.Fatalf("expected error for unknown user")
+	}
+}
+
+func TestDatabaseUserLoginHistory(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserLoginHistory")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	err = db.AddUserLoginRecord(req)
+	tests.EvalErrWithLog(t, err, "record without outcome", true, errors.ErrAddUserLoginRecord.WithArgs(testUser1, errors.ErrLoginRecordInvalid), nil)
+
+	now := time.Now().UTC()
+	for i, offset := range []time.Duration{-48 * time.Hour, -3 * time.Hour, -2 * time.Hour, -time.Hour} {
+		req.LoginRecord = requests.LoginRecord{
+			Timestamp:  now.Add(offset),
+			Address:    fmt.Sprintf("192.0.2.%d", i+1),
+			Device:     "Mozilla/5.0",
+			Provider:   "localdb",
+			Outcome:    "success",
+			MaxEntries: 2,
+			MaxAge:     24 * time.Hour,
+		}
+		if i == 2 {
+			req.LoginRecord.Outcome = "failure"
+		}
+		if err := db.AddUserLoginRecord(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, rec := range user.LoginHistory {
+		got = append(got, rec.Address+" "+rec.Outcome)
+	}
+	tests.EvalObjects(t, "login history", []string{"192.0.2.4 success", "192.0.2.3 failure"}, got)
+}
+
+func TestDatabaseUserDeletion(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserDeletion")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser2,
+			Email:    testEmail2,
+		},
+	}
+	err = db.ScheduleUserDeletion(req)
+	tests.EvalErrWithLog(t, err, "deletion without time", true, errors.ErrScheduleUserDeletion.WithArgs(testUser2, errors.ErrDeletionTimeInvalid), nil)
+
+	// The login during the grace period cancels the deletion.
+	req.Deletion.ScheduledAt = time.Now().Add(time.Hour)
+	if err := db.ScheduleUserDeletion(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.CancelUserDeletion(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "cancelled", true, req.Response.Payload)
+	if err := db.CancelUserDeletion(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "cancelled twice", false, req.Response.Payload)
+
+	// The deletion is not due until the end of the grace period.
+	if err := db.ScheduleUserDeletion(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.PurgeUserDeletions(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "purged before due", 0, len(req.Response.Payload.([]*User)))
+
+	req.Deletion.ScheduledAt = time.Now().Add(-time.Second)
+	if err := db.ScheduleUserDeletion(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.PurgeUserDeletions(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	purged := req.Response.Payload.([]*User)
+	tests.EvalObjects(t, "purged", 1, len(purged))
+	tests.EvalObjects(t, "purged username", testUser2, purged[0].Username)
+	if _, err := db.getUser(testUser2); err == nil {
+		t.Fatalf("expected user %q to be deleted", testUser2)
+	}
+	if _, err := db.getUser(testUser1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDatabaseUserLifecycle(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseUserLifecycle")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	now := time.Now().UTC()
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser2,
+			Email:    testEmail2,
+		},
+		LoginRecord: requests.LoginRecord{
+			Timestamp: now.Add(-48 * time.Hour),
+			Outcome:   "success",
+		},
+	}
+	if err := db.AddUserLoginRecord(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The failed logins evicting the successful one from the login history
+	// do not make the user look dormant.
+	req.LoginRecord = requests.LoginRecord{
+		Timestamp:  now.Add(-30 * time.Minute),
+		Outcome:    "failure",
+		MaxEntries: 1,
+	}
+	if err := db.AddUserLoginRecord(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = db.DisableDormantUsers(&requests.Request{})
+	tests.EvalErrWithLog(t, err, "disable without cutoff", true, errors.ErrDisableDormantUsers.WithArgs(errors.ErrLifecycleCutoff), nil)
+
+	// The dry run reports the dormant users without disabling them. The
+	// user without logins is judged by the creation of the account.
+	dormantReq := &requests.Request{
+		Lifecycle: requests.Lifecycle{Cutoff: now.Add(-time.Hour), DryRun: true},
+	}
+	if err := db.DisableDormantUsers(dormantReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, item := range dormantReq.Response.Payload.([]*requests.LifecycleItem) {
+		got = append(got, item.Username)
+	}
+	tests.EvalObjects(t, "dormant users", []string{testUser2}, got)
+
+	// The user is not dormant before the last login.
+	dormantReq.Lifecycle.Cutoff = now.Add(-72 * time.Hour)
+	if err := db.DisableDormantUsers(dormantReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "dormant users before last login", 0, len(dormantReq.Response.Payload.([]*requests.LifecycleItem)))
+	dormantReq.Lifecycle.Cutoff = now.Add(-time.Hour)
+	authReq := &requests.Request{User: requests.User{Username: testUser2, Password: testPwd2}}
+	if err := db.AuthenticateUser(authReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dormantReq.Lifecycle.DryRun = false
+	if err := db.DisableDormantUsers(dormantReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = db.AuthenticateUser(authReq)
+	tests.EvalErrWithLog(t, err, "authenticate disabled user", true, errors.ErrAuthFailed.WithArgs(errors.ErrUserDisabled), nil)
+
+	if err := db.EnableUser(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "enabled", true, req.Response.Payload)
+	if err := db.AuthenticateUser(authReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pruneReq := &requests.Request{
+		Lifecycle: requests.Lifecycle{Cutoff: now},
+	}
+	if err := db.PruneLoginRecords(pruneReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := pruneReq.Response.Payload.([]*requests.LifecycleItem)
+	tests.EvalObjects(t, "pruned users", 1, len(items))
+	tests.EvalObjects(t, "pruned records", 1, items[0].Count)
+	user, err := db.getUser(testUser2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "login history", 0, len(user.LoginHistory))
+	tests.EvalObjects(t, "last login", now.Add(-48*time.Hour), user.LastLogin)
+}
+
+func TestDatabaseRoleGrants(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseRoleGrants")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser2,
+			Email:    testEmail2,
+		},
+		RoleGrant: requests.RoleGrant{
+			Role:      "ops/oncall",
+			GrantedBy: testEmail1,
+			ExpiresAt: time.Now().Add(-time.Second),
+		},
+	}
+	err = db.GrantUserRole(req)
+	tests.EvalErrWithLog(t, err, "grant expired", true, errors.ErrGrantUserRole.WithArgs("ops/oncall", testUser2, errors.ErrRoleGrantExpiry), nil)
+
+	req.RoleGrant.Role = "viewer"
+	req.RoleGrant.ExpiresAt = time.Now().Add(time.Hour)
+	err = db.GrantUserRole(req)
+	tests.EvalErrWithLog(t, err, "grant permanent role", true, errors.ErrGrantUserRole.WithArgs("viewer", testUser2, errors.ErrRoleGrantPermanent.WithArgs("viewer")), nil)
+
+	req.RoleGrant.Role = "ops/oncall"
+	if err := db.GrantUserRole(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	identifyReq := &requests.Request{User: requests.User{Username: testUser2}}
+	if err := db.IdentifyUser(identifyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "granted roles", []string{"viewer", "ops/oncall"}, identifyReq.User.Roles)
+
+	// The replacement of the roles retains the expiry of the grant.
+	req.User.Roles = []string{"viewer", "ops/oncall", "editor"}
+	if err := db.UpdateUserRoles(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.ExpireUserRoles(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "expired before due", 0, len(req.Response.Payload.([]*requests.RoleGrant)))
+
+	// The expired roles are omitted from the claims until removed.
+	user, err := db.getUserByUsername(testUser2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user.Roles[1].ExpiresAt = time.Now().Add(-time.Second)
+	if err := db.IdentifyUser(identifyReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "roles after expiry", []string{"viewer", "editor"}, identifyReq.User.Roles)
+	if user.HasRole("ops/oncall") {
+		t.Fatalf("expected role %q to be expired", "ops/oncall")
+	}
+
+	if err := db.ExpireUserRoles(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	grants := req.Response.Payload.([]*requests.RoleGrant)
+	tests.EvalObjects(t, "expired", 1, len(grants))
+	tests.EvalObjects(t, "expired role", "ops/oncall", grants[0].Role)
+	tests.EvalObjects(t, "expired granted by", testEmail1, grants[0].GrantedBy)
+	tests.EvalObjects(t, "remaining roles", 2, len(user.Roles))
+}
+
+func TestDatabaseAPIKeys(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseAPIKeys")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	newReq := func() *requests.Request {
+		return &requests.Request{
+			User: requests.User{
+				Username: testUser1,
+				Email:    testEmail1,
+			},
+			Key: requests.Key{
+				Usage:   "api",
+				Comment: "ci",
+			},
+		}
+	}
+
+	req := newReq()
+	req.Key.Roles = []string{"superuser"}
+	err = db.AddAPIKey(req)
+	tests.EvalErrWithLog(t, err, "add key with unassigned role", true, errors.ErrAddAPIKey.WithArgs("api", errors.ErrAPIKeyRoleNotAssigned.WithArgs("superuser")), nil)
+
+	req = newReq()
+	req.Key.Roles = []string{"viewer"}
+	req.Key.Scopes = []string{"read"}
+	req.Key.ExpiresAt = time.Now().Add(time.Hour)
+	if err := db.AddAPIKey(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	firstKeyID := req.Key.ID
+	firstKey := req.Response.Payload.(string)
+
+	for i := 0; i < 2; i++ {
+		req = newReq()
+		req.Key.Payload = firstKey
+		req.Key.Address = "10.0.0.1"
+		if err := db.LookupAPIKey(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tests.EvalObjects(t, "key roles", []string{"viewer"}, req.Key.Roles)
+		tests.EvalObjects(t, "key scopes", []string{"read"}, req.Key.Scopes)
+	}
+	req = newReq()
+	req.Key.Payload = firstKey[:30] + "!" + firstKey[31:]
+	err = db.LookupAPIKey(req)
+	tests.EvalErrWithLog(t, err, "lookup tampered key", true, errors.ErrLookupAPIKeyFailed, nil)
+
+	user, _ := db.getUserByUsername(testUser1)
+	tests.EvalObjects(t, "last used address", "10.0.0.1", user.GetAPIKey(firstKey[:24]).LastUsedAddress)
+
+	// The rotated key remains valid during the grace period.
+	req = newReq()
+	req.Key.ID = firstKeyID
+	req.Key.GracePeriod = 60
+	if err := db.RotateAPIKey(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secondKey := req.Response.Payload.(string)
+	for _, k := range []string{firstKey, secondKey} {
+		req = newReq()
+		req.Key.Payload = k
+		if err := db.LookupAPIKey(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tests.EvalObjects(t, "rotated key roles", []string{"viewer"}, req.Key.Roles)
+	}
+	req = newReq()
+	req.Key.ID = firstKeyID
+	err = db.RotateAPIKey(req)
+	tests.EvalErrWithLog(t, err, "rotate rotated key", true, errors.ErrRotateAPIKey.WithArgs(firstKeyID, errors.ErrAPIKeyRotated), nil)
+
+	user.GetAPIKey(firstKey[:24]).ExpiresAt = time.Now().Add(-time.Second)
+	req = newReq()
+	req.Key.Payload = firstKey
+	err = db.LookupAPIKey(req)
+	tests.EvalErrWithLog(t, err, "lookup expired key", true, errors.ErrLookupAPIKeyExpired, nil)
+}
+
 func TestDatabaseChangeUserPassword(t *testing.T) {
 	var databasePath string
 	db, err := createTestDatabase("TestDatabaseChangeUserPassword")
@@ -527,7 +1232,7 @@ func TestDatabaseChangeUserPassword(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrChangeUserPassword.WithArgs(
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 	}
@@ -738,7 +1443,7 @@ func TestDatabaseUserPublicKey(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrAddPublicKey.WithArgs("ssh",
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 		{
@@ -750,7 +1455,7 @@ func TestDatabaseUserPublicKey(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrDeletePublicKey.WithArgs("ssh",
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 	}
@@ -1055,7 +1760,7 @@ func TestDatabaseUserMfaToken(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrAddMfaToken.WithArgs(
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 		{
@@ -1073,7 +1778,7 @@ func TestDatabaseUserMfaToken(t *testing.T) {
 			overwritePath: path.Dir(databasePath),
 			shouldErr:     true,
 			err: errors.ErrDeleteMfaToken.WithArgs("zzzzzzzzzzzzzzzzzzzzzzzzzz5h3s765Tpx5Laa",
-				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "open "+path.Dir(databasePath)+": is a directory"),
+				errors.ErrDatabaseCommit.WithArgs(path.Dir(databasePath), "rename "+path.Dir(databasePath)+": is a directory"),
 			),
 		},
 	}
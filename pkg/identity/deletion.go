@@ -0,0 +1,43 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"time"
+)
+
+// DeletionState indicates that the user requested the deletion of the
+// account. The account is deleted at the scheduled time, unless the user
+// signs in beforehand.
+type DeletionState struct {
+	RequestedAt time.Time `json:"requested_at,omitempty" xml:"requested_at,omitempty" yaml:"requested_at,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at,omitempty" xml:"scheduled_at,omitempty" yaml:"scheduled_at,omitempty"`
+}
+
+// NewDeletionState returns an instance of DeletionState.
+func NewDeletionState(scheduledAt time.Time) *DeletionState {
+	return &DeletionState{
+		RequestedAt: time.Now().UTC(),
+		ScheduledAt: scheduledAt.UTC(),
+	}
+}
+
+// Due returns true when the account must be deleted at the provided time.
+func (s *DeletionState) Due(t time.Time) bool {
+	if s == nil || s.ScheduledAt.IsZero() {
+		return false
+	}
+	return !t.Before(s.ScheduledAt)
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"time"
+)
+
+// UserExport is the data held about a user, as disclosed to the user upon
+// a subject access request. The secrets, i.e. password hashes, MFA token
+// secrets, and API key digests, are omitted.
+type UserExport struct {
+	Metadata       *UserMetadata   `json:"metadata,omitempty" xml:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Roles          []string        `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	EmailAddresses []*EmailAddress `json:"email_addresses,omitempty" xml:"email_addresses,omitempty" yaml:"email_addresses,omitempty"`
+	Organizations  []*Organization `json:"organizations,omitempty" xml:"organizations,omitempty" yaml:"organizations,omitempty"`
+	StreetAddress  []*Location     `json:"street_address,omitempty" xml:"street_address,omitempty" yaml:"street_address,omitempty"`
+	Passwords      []*Password     `json:"passwords,omitempty" xml:"passwords,omitempty" yaml:"passwords,omitempty"`
+	MfaTokens      []*MfaToken     `json:"mfa_tokens,omitempty" xml:"mfa_tokens,omitempty" yaml:"mfa_tokens,omitempty"`
+	APIKeys        []*APIKey       `json:"api_keys,omitempty" xml:"api_keys,omitempty" yaml:"api_keys,omitempty"`
+	PublicKeys     []*PublicKey    `json:"public_keys,omitempty" xml:"public_keys,omitempty" yaml:"public_keys,omitempty"`
+	Consents       []*Consent      `json:"consents,omitempty" xml:"consents,omitempty" yaml:"consents,omitempty"`
+	Acceptances    []*Acceptance   `json:"acceptances,omitempty" xml:"acceptances,omitempty" yaml:"acceptances,omitempty"`
+	Registration   *Registration   `json:"registration,omitempty" xml:"registration,omitempty" yaml:"registration,omitempty"`
+	Lockout        *LockoutState   `json:"lockout,omitempty" xml:"lockout,omitempty" yaml:"lockout,omitempty"`
+	Deletion       *DeletionState  `json:"deletion,omitempty" xml:"deletion,omitempty" yaml:"deletion,omitempty"`
+	LoginHistory   []*LoginRecord  `json:"login_history,omitempty" xml:"login_history,omitempty" yaml:"login_history,omitempty"`
+	LastLogin      time.Time       `json:"last_login,omitempty" xml:"last_login,omitempty" yaml:"last_login,omitempty"`
+	// LinkedIdentities are the identities at identity providers linked to
+	// the account.
+	LinkedIdentities []*LinkedIdentity `json:"linked_identities,omitempty" xml:"linked_identities,omitempty" yaml:"linked_identities,omitempty"`
+}
+
+// Export returns the data held about the user, without secrets.
+func (user *User) Export() *UserExport {
+	e := &UserExport{
+		Metadata:       user.GetMetadata(),
+		Roles:          user.GetRolesClaim(),
+		EmailAddresses: user.EmailAddresses,
+		Organizations:  user.Organizations,
+		StreetAddress:  user.StreetAddress,
+		PublicKeys:     user.PublicKeys,
+		Consents:       user.Consents,
+		Acceptances:    user.Acceptances,
+		Registration:   user.Registration,
+		Lockout:        user.Lockout,
+		Deletion:       user.Deletion,
+		LoginHistory:   user.LoginHistory,
+		LastLogin:      user.LastLogin,
+
+		LinkedIdentities: user.LinkedIdentities,
+	}
+	for _, p := range user.Passwords {
+		entry := *p
+		entry.Hash = ""
+		e.Passwords = append(e.Passwords, &entry)
+	}
+	for _, token := range user.MfaTokens {
+		entry := *token
+		entry.Secret = ""
+		entry.Parameters = nil
+		e.MfaTokens = append(e.MfaTokens, &entry)
+	}
+	for _, k := range user.APIKeys {
+		entry := *k
+		entry.Payload = ""
+		e.APIKeys = append(e.APIKeys, &entry)
+	}
+	return e
+}
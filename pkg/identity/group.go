@@ -0,0 +1,114 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Group is a named bundle of roles. The members of a group inherit its
+// roles when their claims are resolved.
+type Group struct {
+	Name         string    `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Description  string    `json:"description,omitempty" xml:"description,omitempty" yaml:"description,omitempty"`
+	Roles        []*Role   `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Members      []string  `json:"members,omitempty" xml:"members,omitempty" yaml:"members,omitempty"`
+	Created      time.Time `json:"created,omitempty" xml:"created,omitempty" yaml:"created,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty" xml:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+}
+
+// NewGroup returns an instance of Group.
+func NewGroup(name, description string, roles []string) (*Group, error) {
+	g := &Group{
+		Name:    strings.TrimSpace(name),
+		Created: time.Now().UTC(),
+	}
+	if g.Name == "" || strings.ContainsAny(g.Name, "/ \t") {
+		return nil, errors.ErrGroupNameInvalid.WithArgs(name)
+	}
+	if err := g.Update(description, roles); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Update replaces the description and the roles of the group.
+func (g *Group) Update(description string, roles []string) error {
+	entries := []*Role{}
+	for _, s := range roles {
+		role, err := NewRole(s)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, role)
+	}
+	g.Description = description
+	g.Roles = entries
+	g.LastModified = time.Now().UTC()
+	return nil
+}
+
+// GetRolesClaim returns the roles of the group.
+func (g *Group) GetRolesClaim() []string {
+	var roles []string
+	for _, role := range g.Roles {
+		roles = append(roles, role.String())
+	}
+	return roles
+}
+
+// HasMember returns true when the user with the provided username is a
+// member of the group.
+func (g *Group) HasMember(username string) bool {
+	username = strings.ToLower(username)
+	for _, member := range g.Members {
+		if member == username {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMember adds the user with the provided username to the group.
+func (g *Group) AddMember(username string) {
+	if g.HasMember(username) {
+		return
+	}
+	g.Members = append(g.Members, strings.ToLower(username))
+	sort.Strings(g.Members)
+	g.LastModified = time.Now().UTC()
+}
+
+// DeleteMember removes the user with the provided username from the group.
+func (g *Group) DeleteMember(username string) bool {
+	username = strings.ToLower(username)
+	members := []string{}
+	for _, member := range g.Members {
+		if member == username {
+			continue
+		}
+		members = append(members, member)
+	}
+	if len(members) == len(g.Members) {
+		return false
+	}
+	g.Members = members
+	g.LastModified = time.Now().UTC()
+	return true
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestNewGroup(t *testing.T) {
+	testcases := []struct {
+		name      string
+		input     string
+		roles     []string
+		want      []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "valid group",
+			input: "editors",
+			roles: []string{"editor", "viewer"},
+			want:  []string{"editor", "viewer"},
+		},
+		{
+			name:      "empty group name",
+			shouldErr: true,
+			err:       errors.ErrGroupNameInvalid.WithArgs(""),
+		},
+		{
+			name:      "group name with space",
+			input:     "web editors",
+			shouldErr: true,
+			err:       errors.ErrGroupNameInvalid.WithArgs("web editors"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			group, err := NewGroup(tc.input, "", tc.roles)
+			if tests.EvalErrWithLog(t, err, "NewGroup", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "roles", tc.want, group.GetRolesClaim(), msgs)
+		})
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	group, err := NewGroup("editors", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group.AddMember("JSmith")
+	group.AddMember("bjones")
+	group.AddMember("jsmith")
+	tests.EvalObjects(t, "members", []string{"bjones", "jsmith"}, group.Members)
+	tests.EvalObjects(t, "has member", true, group.HasMember("JSMITH"))
+	tests.EvalObjects(t, "delete member", true, group.DeleteMember("jsmith"))
+	tests.EvalObjects(t, "delete absent member", false, group.DeleteMember("jsmith"))
+	tests.EvalObjects(t, "members", []string{"bjones"}, group.Members)
+}
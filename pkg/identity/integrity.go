@@ -0,0 +1,296 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// QuarantinedUser is a user record set aside by the integrity check of the
+// database. The record is kept in the database file for the review by an
+// administrator, but it is not available for authentication.
+type QuarantinedUser struct {
+	User          *User     `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Reason        string    `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	QuarantinedAt time.Time `json:"quarantined_at,omitempty" xml:"quarantined_at,omitempty" yaml:"quarantined_at,omitempty"`
+}
+
+// IntegrityIssue is a problem with a user record found by the integrity
+// check of the database.
+type IntegrityIssue struct {
+	UserID   string `json:"user_id,omitempty" xml:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Issue    string `json:"issue,omitempty" xml:"issue,omitempty" yaml:"issue,omitempty"`
+	// Action is either quarantined or repaired.
+	Action string `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// IntegrityReport is the outcome of the integrity check and the compaction
+// of the database.
+type IntegrityReport struct {
+	CheckedAt             time.Time         `json:"checked_at,omitempty" xml:"checked_at,omitempty" yaml:"checked_at,omitempty"`
+	Users                 int               `json:"users,omitempty" xml:"users,omitempty" yaml:"users,omitempty"`
+	Quarantined           int               `json:"quarantined,omitempty" xml:"quarantined,omitempty" yaml:"quarantined,omitempty"`
+	RemovedMfaTokens      int               `json:"removed_mfa_tokens,omitempty" xml:"removed_mfa_tokens,omitempty" yaml:"removed_mfa_tokens,omitempty"`
+	RemovedPasswords      int               `json:"removed_passwords,omitempty" xml:"removed_passwords,omitempty" yaml:"removed_passwords,omitempty"`
+	CompactedPasswords    int               `json:"compacted_passwords,omitempty" xml:"compacted_passwords,omitempty" yaml:"compacted_passwords,omitempty"`
+	CompactedLoginRecords int               `json:"compacted_login_records,omitempty" xml:"compacted_login_records,omitempty" yaml:"compacted_login_records,omitempty"`
+	Issues                []*IntegrityIssue `json:"issues,omitempty" xml:"issues,omitempty" yaml:"issues,omitempty"`
+}
+
+// Changed returns true when the check or the compaction modified the
+// database.
+func (r *IntegrityReport) Changed() bool {
+	if r.Quarantined > 0 || r.RemovedMfaTokens > 0 || r.RemovedPasswords > 0 {
+		return true
+	}
+	if r.CompactedPasswords > 0 || r.CompactedLoginRecords > 0 {
+		return true
+	}
+	return false
+}
+
+// GetData returns the summary of the report suitable for the event data.
+func (r *IntegrityReport) GetData() map[string]interface{} {
+	m := map[string]interface{}{
+		"users":                   r.Users,
+		"quarantined":             r.Quarantined,
+		"removed_mfa_tokens":      r.RemovedMfaTokens,
+		"removed_passwords":       r.RemovedPasswords,
+		"compacted_passwords":     r.CompactedPasswords,
+		"compacted_login_records": r.CompactedLoginRecords,
+	}
+	if len(r.Issues) > 0 {
+		var issues []string
+		for _, issue := range r.Issues {
+			issues = append(issues, issue.Action+" "+issue.Username+": "+issue.Issue)
+		}
+		m["issues"] = issues
+	}
+	return m
+}
+
+func (r *IntegrityReport) addIssue(user *User, issue, action string) {
+	r.Issues = append(r.Issues, &IntegrityIssue{
+		UserID:   user.ID,
+		Username: user.Username,
+		Issue:    issue,
+		Action:   action,
+	})
+}
+
+// GetIntegrityReport returns the report of the integrity check performed
+// when the database was loaded.
+func (db *Database) GetIntegrityReport() *IntegrityReport {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.integrityReport
+}
+
+// GetQuarantine returns the user records set aside by the integrity check.
+func (db *Database) GetQuarantine() []*QuarantinedUser {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.Quarantine
+}
+
+// Compact removes the password versions in excess of the password policy
+// and, when maxLoginRecords is positive, the oldest login records in excess
+// of it. The database is committed when any records were removed.
+func (db *Database) Compact(maxLoginRecords int) (*IntegrityReport, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	report := &IntegrityReport{
+		CheckedAt: time.Now().UTC(),
+		Users:     len(db.Users),
+	}
+	db.compact(maxLoginRecords, report)
+	if report.Changed() {
+		if err := db.commit(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// checkIntegrity validates the user records prior to the indexing of the
+// database. The invalid MFA tokens and the invalid historical passwords
+// are removed. The invalid users, the users with an invalid current
+// password, and the users sharing a username, an email address, an
+// identifier, or an API key with a preceding user are moved to the
+// quarantine.
+func (db *Database) checkIntegrity() *IntegrityReport {
+	report := &IntegrityReport{
+		CheckedAt: time.Now().UTC(),
+	}
+	ids := make(map[string]bool)
+	usernames := make(map[string]bool)
+	emailAddresses := make(map[string]bool)
+	apiKeys := make(map[string]bool)
+
+	var users []*User
+	for _, user := range db.Users {
+		if user == nil {
+			continue
+		}
+		repairUser(user, report)
+		if issue := checkUser(user, ids, usernames, emailAddresses, apiKeys); issue != "" {
+			db.Quarantine = append(db.Quarantine, &QuarantinedUser{
+				User:          user,
+				Reason:        issue,
+				QuarantinedAt: report.CheckedAt,
+			})
+			report.Quarantined++
+			report.addIssue(user, issue, "quarantined")
+			continue
+		}
+		ids[user.ID] = true
+		usernames[strings.ToLower(user.Username)] = true
+		for _, email := range user.EmailAddresses {
+			emailAddresses[strings.ToLower(email.Address)] = true
+		}
+		for _, apiKey := range user.APIKeys {
+			apiKeys[apiKey.Prefix] = true
+		}
+		users = append(users, user)
+	}
+	db.Users = users
+	report.Users = len(users)
+	db.compact(0, report)
+	return report
+}
+
+func (db *Database) compact(maxLoginRecords int, report *IntegrityReport) {
+	keepVersions := db.Policy.Password.KeepVersions
+	for _, user := range db.Users {
+		var compacted bool
+		if keepVersions > 0 && len(user.Passwords) > keepVersions {
+			report.CompactedPasswords += len(user.Passwords) - keepVersions
+			user.Passwords = user.Passwords[:keepVersions]
+			compacted = true
+		}
+		if maxLoginRecords > 0 && len(user.LoginHistory) > maxLoginRecords {
+			report.CompactedLoginRecords += len(user.LoginHistory) - maxLoginRecords
+			user.LoginHistory = user.LoginHistory[:maxLoginRecords]
+			compacted = true
+		}
+		if compacted {
+			user.Revise()
+		}
+	}
+}
+
+// repairUser removes the invalid MFA tokens and the invalid historical
+// passwords of the user.
+func repairUser(user *User, report *IntegrityReport) {
+	var repaired bool
+	var tokens []*MfaToken
+	for _, token := range user.MfaTokens {
+		if issue := checkMfaToken(token); issue != "" {
+			report.RemovedMfaTokens++
+			report.addIssue(user, issue, "repaired")
+			repaired = true
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	user.MfaTokens = tokens
+
+	if len(user.Passwords) > 1 {
+		passwords := []*Password{user.Passwords[0]}
+		for _, p := range user.Passwords[1:] {
+			if !validPasswordHash(p) {
+				report.RemovedPasswords++
+				report.addIssue(user, "invalid historical password hash", "repaired")
+				repaired = true
+				continue
+			}
+			passwords = append(passwords, p)
+		}
+		user.Passwords = passwords
+	}
+
+	if repaired {
+		user.Revise()
+	}
+}
+
+// checkUser returns the reason for the quarantine of the user, if any.
+func checkUser(user *User, ids, usernames, emailAddresses, apiKeys map[string]bool) string {
+	if err := user.Valid(); err != nil {
+		return err.Error()
+	}
+	if !validPasswordHash(user.Passwords[0]) {
+		return "invalid password hash"
+	}
+	if ids[user.ID] {
+		return "duplicate user id " + user.ID
+	}
+	if usernames[strings.ToLower(user.Username)] {
+		return "duplicate username " + user.Username
+	}
+	for _, email := range user.EmailAddresses {
+		if emailAddresses[strings.ToLower(email.Address)] {
+			return "duplicate email address " + email.Address
+		}
+	}
+	for _, apiKey := range user.APIKeys {
+		if apiKeys[apiKey.Prefix] {
+			return "duplicate api key " + apiKey.Prefix
+		}
+	}
+	return ""
+}
+
+// checkMfaToken returns the reason for the removal of the MFA token, if
+// any. The token is orphaned when it lacks the secret or the key material
+// required for the verification.
+func checkMfaToken(token *MfaToken) string {
+	if token == nil {
+		return "empty mfa token"
+	}
+	switch token.Type {
+	case "totp":
+		if token.Secret == "" {
+			return "mfa token " + token.ID + " has no secret"
+		}
+	case "u2f":
+		for _, k := range []string{"u2f_id", "key_type"} {
+			if _, exists := token.Parameters[k]; !exists {
+				return "mfa token " + token.ID + " has no " + k
+			}
+		}
+	default:
+		return "mfa token " + token.ID + " has unsupported type " + token.Type
+	}
+	return ""
+}
+
+func validPasswordHash(p *Password) bool {
+	if p == nil {
+		return false
+	}
+	switch p.Algorithm {
+	case "bcrypt", "":
+		if _, err := bcrypt.Cost([]byte(p.Hash)); err != nil {
+			return false
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestDatabaseIntegrityCheck(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseIntegrityCheck")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	user, err := db.getUserByUsername(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The copy of the user with a different identifier is a duplicate.
+	b, _ := json.Marshal(user)
+	duplicate := &User{}
+	if err := json.Unmarshal(b, duplicate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	duplicate.ID = NewID()
+	// The user without the identifier is invalid.
+	invalid := &User{Username: "invalid"}
+	user.MfaTokens = append(user.MfaTokens,
+		&MfaToken{ID: "orphan", Type: "totp"},
+		&MfaToken{ID: "valid", Type: "totp", Secret: "c71ca4c68bc14ec5b4ab8d3c3b63802c"},
+	)
+	user.Passwords = append(user.Passwords, &Password{Algorithm: "bcrypt", Hash: "foobar"})
+	db.Users = append(db.Users, duplicate, invalid)
+	if err := db.commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := reloaded.GetIntegrityReport()
+	tests.EvalObjects(t, "users", 2, report.Users)
+	tests.EvalObjects(t, "quarantined", 2, report.Quarantined)
+	tests.EvalObjects(t, "removed mfa tokens", 1, report.RemovedMfaTokens)
+	tests.EvalObjects(t, "removed passwords", 1, report.RemovedPasswords)
+	tests.EvalObjects(t, "changed", true, report.Changed())
+
+	var reasons []string
+	for _, entry := range reloaded.GetQuarantine() {
+		reasons = append(reasons, entry.Reason)
+	}
+	tests.EvalObjects(t, "quarantine reasons", []string{"duplicate username " + testUser1, "invalid user id length: 0"}, reasons)
+
+	user, err = reloaded.getUserByUsername(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "kept first user", true, user.ID != duplicate.ID)
+	tests.EvalObjects(t, "mfa tokens", 1, len(user.MfaTokens))
+	tests.EvalObjects(t, "passwords", 1, len(user.Passwords))
+
+	// The repaired database is persisted, the subsequent check is clean.
+	reloaded, err = NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "subsequent check changed", false, reloaded.GetIntegrityReport().Changed())
+	tests.EvalObjects(t, "persisted quarantine", 2, len(reloaded.GetQuarantine()))
+}
+
+func TestDatabaseCompact(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseCompact")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Password.KeepVersions = 2
+
+	user, err := db.getUserByUsername(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		user.Passwords = append(user.Passwords, user.Passwords[0])
+		user.LoginHistory = append(user.LoginHistory, &LoginRecord{Outcome: "success"})
+	}
+
+	report, err := db.Compact(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "compacted passwords", 2, report.CompactedPasswords)
+	tests.EvalObjects(t, "compacted login records", 2, report.CompactedLoginRecords)
+	tests.EvalObjects(t, "passwords", 2, len(user.Passwords))
+	tests.EvalObjects(t, "login history", 1, len(user.LoginHistory))
+
+	report, err = db.Compact(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "changed", false, report.Changed())
+}
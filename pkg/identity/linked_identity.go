@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"time"
+)
+
+// LinkedIdentity is an identity of a user at an identity provider, e.g.
+// GitHub, linked to the local account of the user. The user signing in
+// with the linked identity gets the local account.
+type LinkedIdentity struct {
+	ID       string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Realm    string    `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Kind     string    `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	Subject  string    `json:"subject,omitempty" xml:"subject,omitempty" yaml:"subject,omitempty"`
+	Email    string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at,omitempty" xml:"linked_at,omitempty" yaml:"linked_at,omitempty"`
+}
+
+// NewLinkedIdentity returns an instance of LinkedIdentity.
+func NewLinkedIdentity(realm, kind, subject, email string) *LinkedIdentity {
+	return &LinkedIdentity{
+		ID:       NewID(),
+		Realm:    realm,
+		Kind:     kind,
+		Subject:  subject,
+		Email:    email,
+		LinkedAt: time.Now().UTC(),
+	}
+}
+
+// getLinkedIdentityKey returns the key of the linked identity references
+// of Database.
+func getLinkedIdentityKey(realm, subject string) string {
+	return realm + "/" + subject
+}
@@ -31,3 +31,17 @@ type LockoutState struct {
 func NewLockoutState() *LockoutState {
 	return &LockoutState{}
 }
+
+// Active returns true when the lockout is in effect at the provided time.
+func (s *LockoutState) Active(t time.Time) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	if !s.StartTime.IsZero() && t.Before(s.StartTime) {
+		return false
+	}
+	if !s.EndTime.IsZero() && !t.Before(s.EndTime) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"sort"
+	"time"
+)
+
+// LoginRecord is an authentication attempt of a user.
+type LoginRecord struct {
+	Timestamp time.Time `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Address   string    `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	Country   string    `json:"country,omitempty" xml:"country,omitempty" yaml:"country,omitempty"`
+	City      string    `json:"city,omitempty" xml:"city,omitempty" yaml:"city,omitempty"`
+	// The user agent of the client.
+	Device string `json:"device,omitempty" xml:"device,omitempty" yaml:"device,omitempty"`
+	// The name of the identity store that authenticated the user.
+	Provider string `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	// Outcome is either success or failure.
+	Outcome string `json:"outcome,omitempty" xml:"outcome,omitempty" yaml:"outcome,omitempty"`
+}
+
+// Succeeded returns true when the authentication succeeded.
+func (rec *LoginRecord) Succeeded() bool {
+	return rec.Outcome == "success"
+}
+
+// AddLoginRecord adds the record to the login history of the user. The
+// records older than maxAge, if positive, and the oldest records in excess
+// of maxEntries are removed. The history is kept in reverse chronological
+// order.
+func (user *User) AddLoginRecord(rec *LoginRecord, maxEntries int, maxAge time.Duration) {
+	if rec.Succeeded() && rec.Timestamp.After(user.LastLogin) {
+		user.LastLogin = rec.Timestamp
+	}
+	history := []*LoginRecord{rec}
+	history = append(history, user.LoginHistory...)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.After(history[j].Timestamp)
+	})
+	if maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge)
+		for i, entry := range history {
+			if entry.Timestamp.Before(cutoff) {
+				history = history[:i]
+				break
+			}
+		}
+	}
+	if maxEntries > 0 && len(history) > maxEntries {
+		history = history[:maxEntries]
+	}
+	user.LoginHistory = history
+}
+
+// GetLastLogin returns the time of the most recent successful
+// authentication of the user, if any. The login history is consulted for
+// the users recorded before the tracking of the last login.
+func (user *User) GetLastLogin() time.Time {
+	t := user.LastLogin
+	for _, entry := range user.LoginHistory {
+		if entry.Succeeded() && entry.Timestamp.After(t) {
+			t = entry.Timestamp
+		}
+	}
+	return t
+}
+
+// PruneLoginHistory removes the records older than the cutoff from the
+// login history of the user and returns the number of the removed records.
+func (user *User) PruneLoginHistory(cutoff time.Time) int {
+	history := []*LoginRecord{}
+	for _, entry := range user.LoginHistory {
+		if entry.Timestamp.Before(cutoff) {
+			continue
+		}
+		history = append(history, entry)
+	}
+	n := len(user.LoginHistory) - len(history)
+	if n > 0 {
+		user.LoginHistory = history
+	}
+	return n
+}
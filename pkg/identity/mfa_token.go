@@ -26,6 +26,7 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"hash"
@@ -294,6 +295,9 @@ func NewMfaToken(req *requests.Request) (*MfaToken, error) {
 		p.Parameters["u2f_id"] = r.ID
 		p.Parameters["u2f_type"] = r.Type
 		p.Parameters["u2f_transports"] = strings.Join(r.Transports, ",")
+		if aaguid, err := NormalizeAAGUID(r.AttestationObject.AuthData.CredentialData.AAGUID); err == nil {
+			p.Parameters["u2f_aaguid"] = aaguid
+		}
 		p.Parameters["key_type"] = keyType
 		p.Parameters["key_algo"] = keyAlgo
 		//return nil, fmt.Errorf("XXX: %v", r.AttestationObject.AttestationStatement.Certificates)
@@ -461,6 +465,37 @@ func (p *MfaToken) WebAuthnRequest(payload string) (*WebAuthnAuthenticateRequest
 	return r, nil
 }
 
+// IsHardwareKey returns true when the MfaToken is a WebAuthn token of a
+// hardware security key model in the list of the normalized AAGUIDs. The
+// transports reported by the client are hints and do not identify the
+// authenticator.
+func (p *MfaToken) IsHardwareKey(aaguids []string) bool {
+	if p.Type != "u2f" || p.Parameters["u2f_aaguid"] == "" {
+		return false
+	}
+	for _, aaguid := range aaguids {
+		if aaguid == p.Parameters["u2f_aaguid"] {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAAGUID returns the AAGUID of an authenticator model as 32
+// lowercase hexadecimal digits. The all-zero AAGUID of the authenticators
+// not disclosing their model is rejected.
+func NormalizeAAGUID(s string) (string, error) {
+	aaguid := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	b, err := hex.DecodeString(aaguid)
+	if err != nil || len(b) != 16 {
+		return "", errors.ErrMfaTokenAAGUID.WithArgs(s)
+	}
+	if strings.Trim(aaguid, "0") == "" {
+		return "", errors.ErrMfaTokenAAGUID.WithArgs(s)
+	}
+	return aaguid, nil
+}
+
 // Disable disables MfaToken instance.
 func (p *MfaToken) Disable() {
 	p.Expired = true
@@ -319,3 +319,72 @@ func TestNewMfaToken(t *testing.T) {
 		})
 	}
 }
+
+func TestIsHardwareKey(t *testing.T) {
+	aaguids := []string{"cb69481e8ff7403993ec0a2729a154a8"}
+	testcases := []struct {
+		name  string
+		token *MfaToken
+		want  bool
+	}{
+		{
+			name:  "allowed hardware key model",
+			token: &MfaToken{Type: "u2f", Parameters: map[string]string{"u2f_aaguid": "cb69481e8ff7403993ec0a2729a154a8"}},
+			want:  true,
+		},
+		{
+			name:  "other authenticator model",
+			token: &MfaToken{Type: "u2f", Parameters: map[string]string{"u2f_aaguid": "adce000235bcc60a648b0b25f1f05503"}},
+		},
+		{
+			name:  "usb transport without aaguid",
+			token: &MfaToken{Type: "u2f", Parameters: map[string]string{"u2f_transports": "usb"}},
+		},
+		{
+			name:  "totp token",
+			token: &MfaToken{Type: "totp", Parameters: map[string]string{"u2f_aaguid": "cb69481e8ff7403993ec0a2729a154a8"}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tests.EvalObjects(t, "hardware key", tc.want, tc.token.IsHardwareKey(aaguids))
+		})
+	}
+}
+
+func TestNormalizeAAGUID(t *testing.T) {
+	testcases := []struct {
+		name      string
+		aaguid    string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "uuid",
+			aaguid: "CB69481E-8FF7-4039-93EC-0A2729A154A8",
+			want:   "cb69481e8ff7403993ec0a2729a154a8",
+		},
+		{
+			name:      "anonymous authenticator",
+			aaguid:    "00000000000000000000000000000000",
+			shouldErr: true,
+			err:       errors.ErrMfaTokenAAGUID.WithArgs("00000000000000000000000000000000"),
+		},
+		{
+			name:      "malformed",
+			aaguid:    "cb69481e",
+			shouldErr: true,
+			err:       errors.ErrMfaTokenAAGUID.WithArgs("cb69481e"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeAAGUID(tc.aaguid)
+			if tests.EvalErrWithLog(t, err, "normalize aaguid", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjects(t, "aaguid", tc.want, got)
+		})
+	}
+}
@@ -14,6 +14,12 @@
 
 package identity
 
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
 // Organization is an organized body of people with a particular purpose.
 type Organization struct {
 	ID      uint64   `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
@@ -25,3 +31,13 @@ type Organization struct {
 func NewOrganization() *Organization {
 	return &Organization{}
 }
+
+// NewOrganizationWithName returns an instance of Organization with the
+// provided name. The name prefixes the roles scoped to the organization,
+// e.g. acme in acme/editor, and cannot contain slashes or spaces.
+func NewOrganizationWithName(s string) (*Organization, error) {
+	if s == "" || strings.ContainsAny(s, "/ \t") {
+		return nil, errors.ErrOrganizationInvalid.WithArgs(s)
+	}
+	return &Organization{Name: s}, nil
+}
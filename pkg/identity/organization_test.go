@@ -15,9 +15,50 @@
 package identity
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
 )
 
 func TestNewOrganization(t *testing.T) {
 	NewOrganization()
 }
+
+func TestNewOrganizationWithName(t *testing.T) {
+	testcases := []struct {
+		name      string
+		input     string
+		want      *Organization
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "valid organization name",
+			input: "acme",
+			want:  &Organization{Name: "acme"},
+		},
+		{
+			name:      "empty organization name",
+			shouldErr: true,
+			err:       errors.ErrOrganizationInvalid.WithArgs(""),
+		},
+		{
+			name:      "organization name with slash",
+			input:     "acme/editor",
+			shouldErr: true,
+			err:       errors.ErrOrganizationInvalid.WithArgs("acme/editor"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got, err := NewOrganizationWithName(tc.input)
+			if tests.EvalErrWithLog(t, err, "NewOrganizationWithName", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "organization", tc.want, got, msgs)
+		})
+	}
+}
@@ -29,6 +29,8 @@ type Registration struct {
 	Approved   bool      `json:"approved,omitempty" xml:"approved,omitempty" yaml:"approved,omitempty"`
 	DeclinedAt time.Time `json:"declined_at,omitempty" xml:"declined_at,omitempty" yaml:"declined_at,omitempty"`
 	Declined   bool      `json:"declined,omitempty" xml:"declined,omitempty" yaml:"declined,omitempty"`
+	Comment    string    `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	ReviewedBy string    `json:"reviewed_by,omitempty" xml:"reviewed_by,omitempty" yaml:"reviewed_by,omitempty"`
 }
 
 // NewRegistration returns an instance of Registration.
@@ -51,3 +53,8 @@ func (r *Registration) Decline() {
 	r.Declined = true
 	r.DeclinedAt = time.Now().UTC()
 }
+
+// Reviewed returns true when the Registration was either approved or declined.
+func (r *Registration) Reviewed() bool {
+	return r.Approved || r.Declined
+}
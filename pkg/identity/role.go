@@ -17,12 +17,19 @@ package identity
 import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"strings"
+	"time"
 )
 
 // Role is the user role or entitlement in a system.
 type Role struct {
 	Name         string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
 	Organization string `json:"organization,omitempty" xml:"organization,omitempty" yaml:"organization,omitempty"`
+	// GrantedBy is the administrator, or the workflow, that granted the
+	// time-boxed role.
+	GrantedBy string `json:"granted_by,omitempty" xml:"granted_by,omitempty" yaml:"granted_by,omitempty"`
+	// ExpiresAt is the expiry of the time-boxed role. The role without the
+	// expiry is permanent.
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
 }
 
 // NewRole returns an instance of Role.
@@ -49,3 +56,14 @@ func (r *Role) String() string {
 	}
 	return r.Organization + "/" + r.Name
 }
+
+// IsTemporary returns true when the role is time-boxed.
+func (r *Role) IsTemporary() bool {
+	return !r.ExpiresAt.IsZero()
+}
+
+// Expired returns true when the time-boxed role expired at the provided
+// time.
+func (r *Role) Expired(t time.Time) bool {
+	return r.IsTemporary() && !t.Before(r.ExpiresAt)
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/greenpau/go-authcrunch/internal/utils"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const backupFileSuffix = ".bak"
+
+// SetBackupCount sets the number of the previous revisions of the database
+// file kept as backups. The backups are written alongside the database
+// file, e.g. user_db.json.42.bak holds revision 42.
+func (db *Database) SetBackupCount(n int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.backupCount = n
+}
+
+// GetBackups returns the revisions of the database kept as backups, the
+// most recent first.
+func (db *Database) GetBackups() ([]uint64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.getBackups()
+}
+
+// Rollback replaces the content of the database with the backup of the
+// provided revision. The restored content is committed as a new revision,
+// i.e. the revision number never decreases.
+func (db *Database) Rollback(revision uint64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	b, err := utils.ReadFileBytes(db.getBackupPath(revision))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.ErrDatabaseRollback.WithArgs(revision, errors.ErrDatabaseNoBackup.WithArgs(revision))
+		}
+		return errors.ErrDatabaseRollback.WithArgs(revision, err)
+	}
+	restored := newDatabase(db.path)
+	if err := json.Unmarshal(b, restored); err != nil {
+		return errors.ErrDatabaseRollback.WithArgs(revision, err)
+	}
+	if err := restored.index(); err != nil {
+		return errors.ErrDatabaseRollback.WithArgs(revision, err)
+	}
+	restored.mu = db.mu
+	restored.backupCount = db.backupCount
+	restored.Version = app.Version
+	restored.Revision = db.Revision
+	if err := restored.commit(); err != nil {
+		return errors.ErrDatabaseRollback.WithArgs(revision, err)
+	}
+	*db = *restored
+	return nil
+}
+
+// write replaces the database file with the data. The writers, including
+// the ones in other processes, are serialized with the advisory lock of
+// the database file. The data is written to a temporary file, synced, and
+// renamed over the database file, i.e. the database file is never partially
+// written.
+func (db *Database) write(data []byte) error {
+	unlock, err := lockFile(db.path + ".lock")
+	if err != nil {
+		return errors.ErrDatabaseLock.WithArgs(db.path, err)
+	}
+	defer unlock()
+	if db.backupCount > 0 {
+		if err := db.backup(); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(db.path, data)
+}
+
+// backup copies the database file to the backup of its revision and
+// deletes the backups exceeding the backup count.
+func (db *Database) backup() error {
+	b, err := utils.ReadFileBytes(db.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	current := &Database{}
+	if err := json.Unmarshal(b, current); err != nil {
+		// The unreadable file is not worth keeping.
+		return nil
+	}
+	if err := writeFileAtomic(db.getBackupPath(current.Revision), b); err != nil {
+		return err
+	}
+	revisions, err := db.getBackups()
+	if err != nil {
+		return err
+	}
+	for i := db.backupCount; i < len(revisions); i++ {
+		if err := os.Remove(db.getBackupPath(revisions[i])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Database) getBackupPath(revision uint64) string {
+	return fmt.Sprintf("%s.%d%s", db.path, revision, backupFileSuffix)
+}
+
+func (db *Database) getBackups() ([]uint64, error) {
+	entries, err := ioutil.ReadDir(filepath.Dir(db.path))
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(db.path) + "."
+	var revisions []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		revision, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), backupFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] > revisions[j] })
+	return revisions, nil
+}
+
+// writeFileAtomic writes the data to a temporary file in the directory of
+// the file, syncs it, and renames it to the file.
+func writeFileAtomic(fp string, data []byte) error {
+	if fi, err := os.Stat(fp); err == nil && fi.IsDir() {
+		return &os.PathError{Op: "rename", Path: fp, Err: syscall.EISDIR}
+	}
+	dir := filepath.Dir(fp)
+	f, err := ioutil.TempFile(dir, filepath.Base(fp)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Persist the rename. Some platforms do not support the syncing of
+	// directories.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
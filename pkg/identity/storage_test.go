@@ -0,0 +1,101 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestDatabaseBackupAndRollback(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseBackupAndRollback")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.SetBackupCount(2)
+
+	req := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}
+	revision := db.Revision
+	for _, roles := range [][]string{{"editor"}, {"viewer"}, {"reviewer"}} {
+		req.User.Roles = roles
+		if err := db.UpdateUserRoles(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	backups, err := db.GetBackups()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "backups", []uint64{revision + 2, revision + 1}, backups)
+
+	// The temporary files do not remain after the writes.
+	matches, _ := filepath.Glob(db.path + ".tmp*")
+	tests.EvalObjects(t, "temporary files", 0, len(matches))
+
+	if err := db.Rollback(revision + 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "revision after rollback", revision+4, db.Revision)
+	user, err := db.getUserByUsername(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "restored roles", []string{"editor"}, user.GetRolesClaim())
+
+	// The restored content is persisted.
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, err = reloaded.getUserByUsername(testUser1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "reloaded roles", []string{"editor"}, user.GetRolesClaim())
+
+	err = db.Rollback(revision)
+	tests.EvalErrWithLog(t, err, "pruned backup", true, errors.ErrDatabaseRollback.WithArgs(revision, errors.ErrDatabaseNoBackup.WithArgs(revision)), nil)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "user_db.json")
+	if err := ioutil.WriteFile(fp, []byte(`{"revision":1}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeFileAtomic(fp, []byte(`{"revision":2}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "content", `{"revision":2}`, string(b))
+	fi, err := os.Stat(fp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "mode", os.FileMode(0600), fi.Mode().Perm())
+}
@@ -33,6 +33,8 @@ type UserMetadata struct {
 	LastModified time.Time `json:"last_modified,omitempty" xml:"last_modified,omitempty" yaml:"last_modified,omitempty"`
 	Revision     int       `json:"revision,omitempty" xml:"revision,omitempty" yaml:"revision,omitempty"`
 	Avatar       string    `json:"avatar,omitempty" xml:"avatar,omitempty" yaml:"avatar,omitempty"`
+	Phone        string    `json:"phone,omitempty" xml:"phone,omitempty" yaml:"phone,omitempty"`
+	Language     string    `json:"language,omitempty" xml:"language,omitempty" yaml:"language,omitempty"`
 }
 
 // UserMetadataBundle is a collection of public users.
@@ -66,7 +68,19 @@ type User struct {
 	Revision       int             `json:"revision,omitempty" xml:"revision,omitempty" yaml:"revision,omitempty"`
 	Roles          []*Role         `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
 	Registration   *Registration   `json:"registration,omitempty" xml:"registration,omitempty" yaml:"registration,omitempty"`
-	rolesRef       map[string]interface{}
+	Phone          string          `json:"phone,omitempty" xml:"phone,omitempty" yaml:"phone,omitempty"`
+	Language       string          `json:"language,omitempty" xml:"language,omitempty" yaml:"language,omitempty"`
+	Consents       []*Consent      `json:"consents,omitempty" xml:"consents,omitempty" yaml:"consents,omitempty"`
+	Acceptances    []*Acceptance   `json:"acceptances,omitempty" xml:"acceptances,omitempty" yaml:"acceptances,omitempty"`
+	Deletion       *DeletionState  `json:"deletion,omitempty" xml:"deletion,omitempty" yaml:"deletion,omitempty"`
+	LoginHistory   []*LoginRecord  `json:"login_history,omitempty" xml:"login_history,omitempty" yaml:"login_history,omitempty"`
+	// LastLogin is the time of the most recent successful authentication.
+	// Unlike the login history, it is not subject to the retention limits.
+	LastLogin time.Time `json:"last_login,omitempty" xml:"last_login,omitempty" yaml:"last_login,omitempty"`
+	// LinkedIdentities are the identities of the user at identity providers
+	// linked to the account.
+	LinkedIdentities []*LinkedIdentity `json:"linked_identities,omitempty" xml:"linked_identities,omitempty" yaml:"linked_identities,omitempty"`
+	rolesRef         map[string]interface{}
 }
 
 // NewUserMetadataBundle returns an instance of UserMetadataBundle.
@@ -204,6 +218,142 @@ func (user *User) AddEmailAddress(s string) error {
 	return nil
 }
 
+// ChangeEmailAddress replaces the current primary email address of a user
+// identity with a confirmed one.
+func (user *User) ChangeEmailAddress(s string) error {
+	email, err := NewEmailAddress(s)
+	if err != nil {
+		return err
+	}
+	email.Confirmed = true
+	var current string
+	if len(user.EmailAddresses) > 0 {
+		current = user.GetMailClaim()
+	}
+	emailAddresses := []*EmailAddress{email}
+	for _, e := range user.EmailAddresses {
+		if e.Address == current || e.Address == email.Address {
+			continue
+		}
+		emailAddresses = append(emailAddresses, e)
+	}
+	user.EmailAddress = email
+	user.EmailAddresses = emailAddresses
+	user.Revise()
+	return nil
+}
+
+// UpdateProfile updates the self-managed profile attributes of a user
+// identity. The attributes set to nil are left unchanged.
+func (user *User) UpdateProfile(name, phone, language *string) error {
+	if name != nil {
+		if *name == "" {
+			user.Name = nil
+		} else {
+			n, err := ParseName(*name)
+			if err != nil {
+				return err
+			}
+			user.Name = n
+		}
+	}
+	if phone != nil {
+		user.Phone = *phone
+	}
+	if language != nil {
+		user.Language = *language
+	}
+	user.Revise()
+	return nil
+}
+
+// AddConsent records the consent to an application. The scopes of an
+// existing consent to the same application are merged.
+func (user *User) AddConsent(app string, scopes []string) *Consent {
+	for _, c := range user.Consents {
+		if c.App == app {
+			c.AddScopes(scopes)
+			c.GrantedAt = time.Now().UTC()
+			user.Revise()
+			return c
+		}
+	}
+	c := NewConsent(app, scopes)
+	user.Consents = append(user.Consents, c)
+	user.Revise()
+	return c
+}
+
+// DeleteConsent withdraws the consent with the provided id.
+func (user *User) DeleteConsent(id string) error {
+	var consents []*Consent
+	var found bool
+	for _, c := range user.Consents {
+		if c.ID == id {
+			found = true
+			continue
+		}
+		consents = append(consents, c)
+	}
+	if !found {
+		return errors.ErrConsentNotFound.WithArgs(id)
+	}
+	user.Consents = consents
+	user.Revise()
+	return nil
+}
+
+// AddLinkedIdentity links the identity at an identity provider to the user.
+func (user *User) AddLinkedIdentity(realm, kind, subject, email string) (*LinkedIdentity, error) {
+	if realm == "" || subject == "" {
+		return nil, errors.ErrLinkedIdentityInvalid
+	}
+	for _, li := range user.LinkedIdentities {
+		if li.Realm == realm && li.Subject == subject {
+			return nil, errors.ErrLinkedIdentityExists.WithArgs(subject, realm)
+		}
+	}
+	li := NewLinkedIdentity(realm, kind, subject, email)
+	user.LinkedIdentities = append(user.LinkedIdentities, li)
+	user.Revise()
+	return li, nil
+}
+
+// DeleteLinkedIdentity unlinks the linked identity with the provided id.
+func (user *User) DeleteLinkedIdentity(id string) (*LinkedIdentity, error) {
+	var linkedIdentities []*LinkedIdentity
+	var deleted *LinkedIdentity
+	for _, li := range user.LinkedIdentities {
+		if li.ID == id {
+			deleted = li
+			continue
+		}
+		linkedIdentities = append(linkedIdentities, li)
+	}
+	if deleted == nil {
+		return nil, errors.ErrLinkedIdentityNotFound.WithArgs(id)
+	}
+	user.LinkedIdentities = linkedIdentities
+	user.Revise()
+	return deleted, nil
+}
+
+// AddAcceptance records the acceptance of a version of a document. The
+// acceptance of a previous version of the same document is replaced.
+func (user *User) AddAcceptance(doc, version string) *Acceptance {
+	a := NewAcceptance(doc, version)
+	for i, entry := range user.Acceptances {
+		if entry.Document == doc {
+			user.Acceptances[i] = a
+			user.Revise()
+			return a
+		}
+	}
+	user.Acceptances = append(user.Acceptances, a)
+	user.Revise()
+	return a
+}
+
 // HasEmailAddresses checks whether a user has email address.
 func (user *User) HasEmailAddresses() bool {
 	if len(user.EmailAddresses) == 0 {
@@ -230,9 +380,10 @@ func (user *User) HasRole(s string) bool {
 		return false
 	}
 
+	now := time.Now()
 	for _, r := range user.Roles {
 		if (r.Name == role.Name) && (r.Organization == role.Organization) {
-			return true
+			return !r.Expired(now)
 		}
 	}
 	return false
@@ -269,6 +420,53 @@ func (user *User) AddRole(s string) error {
 	return nil
 }
 
+// GrantRole assigns a role to a user identity until the provided expiry.
+// The grant of a role already granted extends it, while the grant of a
+// permanently assigned role fails.
+func (user *User) GrantRole(s, grantedBy string, expiresAt time.Time) error {
+	role, err := NewRole(s)
+	if err != nil {
+		return err
+	}
+	if !time.Now().Before(expiresAt) {
+		return errors.ErrRoleGrantExpiry
+	}
+	role.GrantedBy = grantedBy
+	role.ExpiresAt = expiresAt.UTC()
+	for i, r := range user.Roles {
+		if (r.Name != role.Name) || (r.Organization != role.Organization) {
+			continue
+		}
+		if !r.IsTemporary() {
+			return errors.ErrRoleGrantPermanent.WithArgs(s)
+		}
+		user.Roles[i] = role
+		user.Revise()
+		return nil
+	}
+	user.Roles = append(user.Roles, role)
+	user.Revise()
+	return nil
+}
+
+// ExpireRoles removes the time-boxed roles expired at the provided time
+// from a user identity and returns them.
+func (user *User) ExpireRoles(t time.Time) []*Role {
+	var roles, expired []*Role
+	for _, r := range user.Roles {
+		if r.Expired(t) {
+			expired = append(expired, r)
+			continue
+		}
+		roles = append(roles, r)
+	}
+	if len(expired) > 0 {
+		user.Roles = roles
+		user.Revise()
+	}
+	return expired
+}
+
 // VerifyPassword verifies provided password matches to the one in the database.
 func (user *User) VerifyPassword(s string) error {
 	if len(user.Passwords) == 0 {
@@ -298,6 +496,9 @@ func (user *User) VerifyWebAuthnRequest(r *requests.Request) error {
 		if token.Type != "u2f" {
 			continue
 		}
+		if r.WebAuthn.HardwareKey && !token.IsHardwareKey(r.WebAuthn.HardwareKeyAAGUIDs) {
+			continue
+		}
 		if _, exists := token.Parameters["u2f_id"]; !exists {
 			continue
 		}
@@ -343,18 +544,52 @@ func (user *User) GetNameClaim() string {
 	return ""
 }
 
-// GetRolesClaim returns name field of a claim.
+// GetRolesClaim returns name field of a claim. The expired time-boxed roles
+// are omitted.
 func (user *User) GetRolesClaim() []string {
 	var roles []string
 	if len(user.Roles) == 0 {
 		return roles
 	}
+	now := time.Now()
 	for _, role := range user.Roles {
+		if role.Expired(now) {
+			continue
+		}
 		roles = append(roles, role.String())
 	}
 	return roles
 }
 
+// GetOrganizationsClaim returns the names of the organizations of a user.
+func (user *User) GetOrganizationsClaim() []string {
+	var orgs []string
+	for _, org := range user.Organizations {
+		orgs = append(orgs, org.Name)
+	}
+	return orgs
+}
+
+// SetOrganizations replaces the organizations of a user.
+func (user *User) SetOrganizations(names []string) error {
+	orgs := []*Organization{}
+	orgMap := make(map[string]bool)
+	for _, name := range names {
+		org, err := NewOrganizationWithName(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		if orgMap[org.Name] {
+			continue
+		}
+		orgMap[org.Name] = true
+		orgs = append(orgs, org)
+	}
+	user.Organizations = orgs
+	user.Revise()
+	return nil
+}
+
 // GetFullName returns the primary full name for a user.
 func (user *User) GetFullName() string {
 	if user.Name == nil {
@@ -421,12 +656,71 @@ func (user *User) DeletePublicKey(r *requests.Request) error {
 
 // AddAPIKey adds API key to a user identity.
 func (user *User) AddAPIKey(r *requests.Request) error {
+	for _, role := range r.Key.Roles {
+		if !user.HasRole(role) {
+			return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, errors.ErrAPIKeyRoleNotAssigned.WithArgs(role))
+		}
+	}
 	key, err := NewAPIKey(r)
 	if err != nil {
 		return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, err)
 	}
 	user.APIKeys = append(user.APIKeys, key)
+	r.Key.ID = key.ID
+	user.Revise()
+	return nil
+}
+
+// RotateAPIKey replaces the API key with the id in r.Key.ID with a new key
+// carrying the same attributes. The new key is in r.Key.Prefix and
+// r.Key.Payload. The replaced key remains valid for the grace period in
+// r.Key.GracePeriod.
+func (user *User) RotateAPIKey(r *requests.Request) (*APIKey, error) {
+	var prev *APIKey
+	for _, k := range user.APIKeys {
+		if k.ID == r.Key.ID {
+			prev = k
+			break
+		}
+	}
+	if prev == nil {
+		return nil, errors.ErrRotateAPIKey.WithArgs(r.Key.ID, "not found")
+	}
+	if prev.RotatedTo != "" {
+		return nil, errors.ErrRotateAPIKey.WithArgs(r.Key.ID, errors.ErrAPIKeyRotated)
+	}
+	now := time.Now().UTC()
+	if !prev.Active(now) {
+		return nil, errors.ErrRotateAPIKey.WithArgs(r.Key.ID, errors.ErrLookupAPIKeyExpired)
+	}
+	r.Key.Usage = prev.Usage
+	r.Key.Comment = prev.Comment
+	r.Key.Scopes = prev.Scopes
+	r.Key.Roles = prev.Roles
+	r.Key.ExpiresAt = prev.ExpiresAt
+	key, err := NewAPIKey(r)
+	if err != nil {
+		return nil, errors.ErrRotateAPIKey.WithArgs(prev.ID, err)
+	}
+	graceExpiresAt := now.Add(time.Duration(r.Key.GracePeriod) * time.Second)
+	if prev.ExpiresAt.IsZero() || graceExpiresAt.Before(prev.ExpiresAt) {
+		prev.ExpiresAt = graceExpiresAt
+	}
+	prev.RotatedAt = now
+	prev.RotatedTo = key.ID
+	user.APIKeys = append(user.APIKeys, key)
+	r.Key.ID = key.ID
 	user.Revise()
+	return prev, nil
+}
+
+// GetAPIKey returns the API key with the provided prefix.
+func (user *User) GetAPIKey(prefix string) *APIKey {
+	for _, k := range user.APIKeys {
+		if k.Prefix == prefix {
+			return k
+		}
+	}
 	return nil
 }
 
@@ -553,6 +847,8 @@ func (user *User) GetMetadata() *UserMetadata {
 		Created:      user.Created,
 		LastModified: user.LastModified,
 		Revision:     user.Revision,
+		Phone:        user.Phone,
+		Language:     user.Language,
 	}
 	if user.Avatar != nil {
 		m.Avatar = user.Avatar.Path
@@ -585,7 +881,11 @@ func (user *User) Revise() {
 
 // HasAdminRights returns true if the user has admin rights.
 func (user *User) HasAdminRights() bool {
+	now := time.Now()
 	for _, role := range user.Roles {
+		if role.Expired(now) {
+			continue
+		}
 		if role.Name == "admin" && role.Organization == "authp" {
 			return true
 		}
@@ -18,6 +18,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/idp/kerberos"
+	"github.com/greenpau/go-authcrunch/pkg/idp/mtls"
 	"github.com/greenpau/go-authcrunch/pkg/idp/oauth"
 	"github.com/greenpau/go-authcrunch/pkg/idp/saml"
 )
@@ -77,10 +79,11 @@ func (cfg *IdentityProviderConfig) Validate() error {
 			"accept_header_enabled",
 			"js_callback_enabled",
 			"logout_enabled",
-			// Retry and delay.
+			// Retry, delay, and timeout.
 			"delay_start",
 			"retry_attempts",
 			"retry_interval",
+			"request_timeout",
 			// AWS Cognito.
 			"user_pool_id",
 			"region",
@@ -116,10 +119,44 @@ func (cfg *IdentityProviderConfig) Validate() error {
 			"tls_insecure_skip_verify",
 			"login_icon",
 		}
+	case "kerberos":
+		requiredFields = []string{
+			"realm",
+			"driver",
+			"keytab_path",
+		}
+		optionalFields = []string{
+			"service_principal",
+			"domain_name",
+			"trusted_networks",
+			"silent_login_enabled",
+			"login_icon",
+		}
+	case "mtls":
+		requiredFields = []string{
+			"realm",
+			"driver",
+			"trusted_ca_paths",
+		}
+		optionalFields = []string{
+			"subject_filters",
+			"claim_mappings",
+			"revocation_mode",
+			"crl_paths",
+			"revocation_soft_fail",
+			"login_icon",
+		}
 	case "":
 		return errors.ErrIdentityProviderConfigInvalid.WithArgs("empty identity provider type")
 	default:
-		return errors.ErrIdentityProviderConfigInvalid.WithArgs("unsupported identity provider type " + cfg.Kind)
+		driver := getIdentityProviderDriver(cfg.Kind)
+		if driver == nil {
+			return errors.ErrIdentityProviderConfigInvalid.WithArgs("unsupported identity provider type " + cfg.Kind)
+		}
+		if err := driver.ValidateConfig(cfg); err != nil {
+			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
+		}
+		return nil
 	}
 
 	if err := validateFields(cfg.Params, requiredFields, optionalFields); err != nil {
@@ -142,6 +179,20 @@ func (cfg *IdentityProviderConfig) Validate() error {
 		if err := config.Validate(); err != nil {
 			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
 		}
+	case "kerberos":
+		config := &kerberos.Config{}
+		json.Unmarshal(b, config)
+		config.Name = cfg.Name
+		if err := config.Validate(); err != nil {
+			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
+		}
+	case "mtls":
+		config := &mtls.Config{}
+		json.Unmarshal(b, config)
+		config.Name = cfg.Name
+		if err := config.Validate(); err != nil {
+			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
+		}
 	}
 
 	return nil
@@ -84,6 +84,52 @@ func TestNewIdentityProviderConfig(t *testing.T) {
                 "driver": "generic",
                 "realm": "jumpcloud"
               }
+            }`,
+		},
+		{
+			name:   "test contoso kerberos identity provider",
+			driver: "contoso",
+			kind:   "kerberos",
+			params: map[string]interface{}{
+				"driver":           "windows",
+				"realm":            "contoso",
+				"keytab_path":      "/etc/authp/http.keytab",
+				"trusted_networks": []string{"10.0.0.0/8"},
+			},
+			want: `{
+              "kind": "kerberos",
+              "name": "contoso",
+              "params": {
+                "driver": "windows",
+                "keytab_path": "/etc/authp/http.keytab",
+                "realm": "contoso",
+                "trusted_networks": [
+                  "10.0.0.0/8"
+                ]
+              }
+            }`,
+		},
+		{
+			name:   "test piv client certificate identity provider",
+			driver: "piv",
+			kind:   "mtls",
+			params: map[string]interface{}{
+				"driver":           "piv",
+				"realm":            "piv",
+				"trusted_ca_paths": []string{"/etc/authp/piv_ca.pem"},
+				"revocation_mode":  "ocsp_crl",
+			},
+			want: `{
+              "kind": "mtls",
+              "name": "piv",
+              "params": {
+                "driver": "piv",
+                "realm": "piv",
+                "revocation_mode": "ocsp_crl",
+                "trusted_ca_paths": [
+                  "/etc/authp/piv_ca.pem"
+                ]
+              }
             }`,
 		},
 		{
@@ -0,0 +1,80 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idp
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// IdentityProviderDriver creates the identity providers of a kind not built
+// into this package. The drivers are compiled into the embedding binary and
+// registered with RegisterIdentityProviderDriver, typically from the init
+// function of the package providing the driver.
+type IdentityProviderDriver interface {
+	// ValidateConfig validates the parameters of the identity provider
+	// config.
+	ValidateConfig(*IdentityProviderConfig) error
+	// NewIdentityProvider returns an instance of the identity provider.
+	NewIdentityProvider(*IdentityProviderConfig, *zap.Logger) (IdentityProvider, error)
+}
+
+var (
+	builtinProviderKinds = map[string]bool{
+		"oauth":    true,
+		"saml":     true,
+		"kerberos": true,
+		"mtls":     true,
+	}
+	providerDrivers   = make(map[string]IdentityProviderDriver)
+	providerDriversMu sync.RWMutex
+)
+
+// RegisterIdentityProviderDriver registers the driver of the identity
+// providers of the provided kind. The built-in kinds cannot be overridden.
+func RegisterIdentityProviderDriver(kind string, driver IdentityProviderDriver) error {
+	if kind == "" || driver == nil {
+		return errors.ErrIdentityProviderDriverInvalid.WithArgs(kind)
+	}
+	providerDriversMu.Lock()
+	defer providerDriversMu.Unlock()
+	if _, exists := providerDrivers[kind]; exists || builtinProviderKinds[kind] {
+		return errors.ErrIdentityProviderDriverRegistered.WithArgs(kind)
+	}
+	providerDrivers[kind] = driver
+	return nil
+}
+
+// GetIdentityProviderDriverKinds returns the kinds of the registered
+// identity provider drivers.
+func GetIdentityProviderDriverKinds() []string {
+	providerDriversMu.RLock()
+	defer providerDriversMu.RUnlock()
+	var kinds []string
+	for kind := range providerDrivers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func getIdentityProviderDriver(kind string) IdentityProviderDriver {
+	providerDriversMu.RLock()
+	defer providerDriversMu.RUnlock()
+	return providerDrivers[kind]
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"go.uber.org/zap"
+)
+
+type testProviderDriver struct{}
+
+type testProvider struct {
+	name  string
+	realm string
+}
+
+func (d *testProviderDriver) ValidateConfig(cfg *IdentityProviderConfig) error {
+	if _, exists := cfg.Params["realm"]; !exists {
+		return fmt.Errorf("realm not found")
+	}
+	return nil
+}
+
+func (d *testProviderDriver) NewIdentityProvider(cfg *IdentityProviderConfig, logger *zap.Logger) (IdentityProvider, error) {
+	return &testProvider{name: cfg.Name, realm: cfg.Params["realm"].(string)}, nil
+}
+
+func (p *testProvider) GetRealm() string                               { return p.realm }
+func (p *testProvider) GetName() string                                { return p.name }
+func (p *testProvider) GetKind() string                                { return "test_driver" }
+func (p *testProvider) GetDriver() string                              { return "test_driver" }
+func (p *testProvider) GetConfig() map[string]interface{}              { return nil }
+func (p *testProvider) Configure() error                               { return nil }
+func (p *testProvider) Configured() bool                               { return true }
+func (p *testProvider) Request(operator.Type, *requests.Request) error { return nil }
+func (p *testProvider) GetLoginIcon() *icons.LoginIcon                 { return nil }
+func (p *testProvider) GetLogoutURL() string                           { return "" }
+func (p *testProvider) GetIdentityTokenCookieName() string             { return "" }
+
+func TestIdentityProviderDriver(t *testing.T) {
+	if err := RegisterIdentityProviderDriver("test_driver", &testProviderDriver{}); err != nil {
+		t.Fatalf("failed registering driver: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		kind      string
+		driver    IdentityProviderDriver
+		params    map[string]interface{}
+		register  bool
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "test register driver of built-in kind",
+			kind:      "oauth",
+			driver:    &testProviderDriver{},
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderDriverRegistered.WithArgs("oauth"),
+		},
+		{
+			name:      "test register driver twice",
+			kind:      "test_driver",
+			driver:    &testProviderDriver{},
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderDriverRegistered.WithArgs("test_driver"),
+		},
+		{
+			name:      "test register nil driver",
+			kind:      "foo",
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderDriverInvalid.WithArgs("foo"),
+		},
+		{
+			name: "test new identity provider with registered driver",
+			kind: "test_driver",
+			params: map[string]interface{}{
+				"realm": "contoso",
+			},
+			want: map[string]interface{}{
+				"name":  "default",
+				"kind":  "test_driver",
+				"realm": "contoso",
+				"kinds": []string{"test_driver"},
+			},
+		},
+		{
+			name:      "test new identity provider with invalid config",
+			kind:      "test_driver",
+			params:    map[string]interface{}{},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfigInvalid.WithArgs(fmt.Errorf("realm not found")),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			if tc.register {
+				err := RegisterIdentityProviderDriver(tc.kind, tc.driver)
+				tests.EvalErrWithLog(t, err, "register driver", tc.shouldErr, tc.err, msgs)
+				return
+			}
+			cfg, err := NewIdentityProviderConfig("default", tc.kind, tc.params)
+			if tests.EvalErrWithLog(t, err, "identity provider config", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			p, err := NewIdentityProvider(cfg, logutil.NewLogger())
+			if tests.EvalErrWithLog(t, err, "identity provider", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"name":  p.GetName(),
+				"kind":  p.GetKind(),
+				"realm": p.GetRealm(),
+				"kinds": GetIdentityProviderDriverKinds(),
+			}
+			tests.EvalObjectsWithLog(t, "identity provider", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kerberos
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"go.uber.org/zap"
+)
+
+// Authenticate performs authentication. When the request has no SPNEGO
+// token, the response code is 401 and the portal challenges the browser
+// with "WWW-Authenticate: Negotiate". When the request comes from outside
+// of the trusted networks, or the browser offers a token other than
+// Kerberos, e.g. NTLM, the response code is 302 and the user is redirected
+// to the login page.
+func (b *IdentityProvider) Authenticate(r *requests.Request) error {
+	r.Response.Code = http.StatusBadRequest
+	r.Response.RedirectURL = r.Upstream.BaseURL + path.Join(r.Upstream.BasePath, "login") + "?negotiate=false"
+
+	if !b.isTrustedSource(r.Upstream.Request) {
+		r.Response.Code = http.StatusFound
+		return nil
+	}
+
+	s := strings.SplitN(r.Upstream.Request.Header.Get("Authorization"), " ", 2)
+	if len(s) != 2 || s[0] != "Negotiate" {
+		r.Response.Code = http.StatusUnauthorized
+		return nil
+	}
+
+	token, err := parseToken(s[1])
+	if err != nil {
+		b.logger.Debug(
+			"SPNEGO negotiation fallback",
+			zap.String("request_id", r.ID),
+			zap.Error(err),
+		)
+		r.Response.Code = http.StatusFound
+		return nil
+	}
+
+	settings := []func(*service.Settings){service.DecodePAC(false)}
+	if b.config.ServicePrincipal != "" {
+		settings = append(settings, service.KeytabPrincipal(b.config.ServicePrincipal))
+	}
+	ok, creds, err := service.VerifyAPREQ(&token.APReq, service.NewSettings(b.keytab, settings...))
+	if err != nil {
+		return errors.ErrIdentityProviderKerberosAuthFailed.WithArgs(err)
+	}
+	if !ok {
+		return errors.ErrIdentityProviderKerberosAuthFailed.WithArgs("invalid AP-REQ")
+	}
+
+	r.Response.Code = http.StatusOK
+	r.Response.Payload = b.getClaims(creds)
+	return nil
+}
+
+// parseToken returns the Kerberos AP-REQ token from either the SPNEGO
+// token or the raw Kerberos token.
+func parseToken(s string) (*spnego.KRB5Token, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.ErrIdentityProviderKerberosTokenMalformed.WithArgs(err)
+	}
+
+	var st spnego.SPNEGOToken
+	if err := st.Unmarshal(data); err == nil {
+		if !st.Init || len(st.NegTokenInit.MechTypes) == 0 {
+			return nil, errors.ErrIdentityProviderKerberosTokenMalformed.WithArgs("not an initial token")
+		}
+		oid := st.NegTokenInit.MechTypes[0]
+		if !oid.Equal(gssapi.OIDKRB5.OID()) && !oid.Equal(gssapi.OIDMSLegacyKRB5.OID()) {
+			return nil, errors.ErrIdentityProviderKerberosTokenMalformed.WithArgs(fmt.Errorf("unsupported mechanism %s", oid))
+		}
+		data = st.NegTokenInit.MechTokenBytes
+	}
+
+	var token spnego.KRB5Token
+	if err := token.Unmarshal(data); err != nil {
+		return nil, errors.ErrIdentityProviderKerberosTokenMalformed.WithArgs(err)
+	}
+	if !token.IsAPReq() {
+		return nil, errors.ErrIdentityProviderKerberosTokenMalformed.WithArgs("not an AP-REQ token")
+	}
+	return &token, nil
+}
+
+func (b *IdentityProvider) getClaims(creds *credentials.Credentials) map[string]interface{} {
+	username := strings.ToLower(creds.UserName())
+	domainName := b.config.DomainName
+	if domainName == "" {
+		domainName = strings.ToLower(creds.Domain())
+	}
+
+	m := make(map[string]interface{})
+	m["sub"] = username
+	m["email"] = username + "@" + domainName
+	if name := creds.DisplayName(); name != "" && name != creds.UserName() {
+		m["name"] = name
+	}
+	m["metadata"] = map[string]interface{}{
+		"kerberos_principal": creds.CName().PrincipalNameString() + "@" + creds.Domain(),
+	}
+	return m
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kerberos
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config holds the configuration for the IdentityProvider.
+type Config struct {
+	// Name is the unique name associated with the IdentityProvider.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Realm the authentication realm associated with the IdentityProvider.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// Driver is the name of the driver associated with the IdentityProvider,
+	// e.g. windows for Active Directory.
+	Driver string `json:"driver,omitempty" xml:"driver,omitempty" yaml:"driver,omitempty"`
+	// KeytabPath is the path to the keytab holding the keys of the service
+	// principal, e.g. HTTP/auth.contoso.com@CONTOSO.COM.
+	KeytabPath string `json:"keytab_path,omitempty" xml:"keytab_path,omitempty" yaml:"keytab_path,omitempty"`
+	// ServicePrincipal is the name of the service principal in the keytab.
+	// When empty, the principal is the service name of the ticket.
+	ServicePrincipal string `json:"service_principal,omitempty" xml:"service_principal,omitempty" yaml:"service_principal,omitempty"`
+	// DomainName is the domain of the email addresses of the users. When
+	// empty, the domain is the lowercase Kerberos realm of the user.
+	DomainName string `json:"domain_name,omitempty" xml:"domain_name,omitempty" yaml:"domain_name,omitempty"`
+	// TrustedNetworks are the networks, e.g. 10.0.0.0/8, of the domain-joined
+	// workstations. The users outside of the networks get the login page.
+	// When empty, any network is trusted.
+	TrustedNetworks []string `json:"trusted_networks,omitempty" xml:"trusted_networks,omitempty" yaml:"trusted_networks,omitempty"`
+	// SilentLoginEnabled controls whether the login page starts the
+	// negotiation without the user clicking the login icon.
+	SilentLoginEnabled bool `json:"silent_login_enabled,omitempty" xml:"silent_login_enabled,omitempty" yaml:"silent_login_enabled,omitempty"`
+
+	// LoginIcon is the UI login icon attributes.
+	LoginIcon *icons.LoginIcon `json:"login_icon,omitempty" xml:"login_icon,omitempty" yaml:"login_icon,omitempty"`
+}
+
+// Validate validates identity provider configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrIdentityProviderConfigureNameEmpty
+	}
+	if cfg.Realm == "" {
+		return errors.ErrIdentityProviderConfigureRealmEmpty
+	}
+
+	switch cfg.Driver {
+	case "windows", "generic":
+	case "":
+		return errors.ErrIdentityProviderConfig.WithArgs("no Kerberos provider found")
+	default:
+		return errors.ErrIdentityProviderConfig.WithArgs(
+			fmt.Errorf("driver %q is unsupported", cfg.Driver),
+		)
+	}
+
+	if cfg.KeytabPath == "" {
+		return errors.ErrIdentityProviderConfig.WithArgs("keytab path not found")
+	}
+
+	for _, s := range cfg.TrustedNetworks {
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return errors.ErrIdentityProviderKerberosTrustedNetworkInvalid.WithArgs(s, err)
+		}
+	}
+
+	// Configure UI login icon.
+	if cfg.LoginIcon == nil {
+		cfg.LoginIcon = icons.NewLoginIcon(cfg.Driver)
+	} else {
+		cfg.LoginIcon.Configure(cfg.Driver)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kerberos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "validate active directory kerberos config",
+			config: &Config{
+				Name:             "contoso",
+				Realm:            "contoso",
+				Driver:           "windows",
+				KeytabPath:       "/etc/authp/http.keytab",
+				ServicePrincipal: "HTTP/auth.contoso.com",
+				TrustedNetworks:  []string{"10.0.0.0/8", "fd00::/8"},
+			},
+		},
+		{
+			name: "test empty config name",
+			config: &Config{
+				Realm: "contoso",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfigureNameEmpty,
+		},
+		{
+			name: "test empty config realm",
+			config: &Config{
+				Name: "contoso",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfigureRealmEmpty,
+		},
+		{
+			name: "test config without driver",
+			config: &Config{
+				Name:  "contoso",
+				Realm: "contoso",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs("no Kerberos provider found"),
+		},
+		{
+			name: "test config with unsupported driver",
+			config: &Config{
+				Name:   "contoso",
+				Realm:  "contoso",
+				Driver: "foobar",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs(fmt.Errorf("driver %q is unsupported", "foobar")),
+		},
+		{
+			name: "test config without keytab path",
+			config: &Config{
+				Name:   "contoso",
+				Realm:  "contoso",
+				Driver: "windows",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs("keytab path not found"),
+		},
+		{
+			name: "test config with invalid trusted network",
+			config: &Config{
+				Name:            "contoso",
+				Realm:           "contoso",
+				Driver:          "windows",
+				KeytabPath:      "/etc/authp/http.keytab",
+				TrustedNetworks: []string{"10.0.0.0"},
+			},
+			shouldErr: true,
+			err: errors.ErrIdentityProviderKerberosTrustedNetworkInvalid.WithArgs(
+				"10.0.0.0", "invalid CIDR address: 10.0.0.0",
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+		})
+	}
+}
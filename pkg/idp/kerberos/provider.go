@@ -0,0 +1,171 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kerberos
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"go.uber.org/zap"
+)
+
+const (
+	providerKind = "kerberos"
+)
+
+// IdentityProvider represents Kerberos-based identity provider. It performs
+// SPNEGO negotiation with the browsers of domain-joined workstations.
+type IdentityProvider struct {
+	config          *Config
+	keytab          *keytab.Keytab
+	trustedNetworks []*net.IPNet
+	logger          *zap.Logger
+	configured      bool
+}
+
+// NewIdentityProvider return an instance of IdentityProvider.
+func NewIdentityProvider(cfg *Config, logger *zap.Logger) (*IdentityProvider, error) {
+	if logger == nil {
+		return nil, errors.ErrIdentityProviderConfigureLoggerNotFound
+	}
+
+	b := &IdentityProvider{
+		config: cfg,
+		logger: logger,
+	}
+
+	if err := b.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetRealm return authentication realm.
+func (b *IdentityProvider) GetRealm() string {
+	return b.config.Realm
+}
+
+// GetName return the name associated with this identity provider.
+func (b *IdentityProvider) GetName() string {
+	return b.config.Name
+}
+
+// GetKind returns the authentication method associated with this identity provider.
+func (b *IdentityProvider) GetKind() string {
+	return providerKind
+}
+
+// Configured returns true if the identity provider was configured.
+func (b *IdentityProvider) Configured() bool {
+	return b.configured
+}
+
+// Request performs the requested identity provider operation.
+func (b *IdentityProvider) Request(op operator.Type, r *requests.Request) error {
+	switch op {
+	case operator.Authenticate:
+		return b.Authenticate(r)
+	}
+	return errors.ErrOperatorNotSupported.WithArgs(op)
+}
+
+// GetConfig returns IdentityProvider configuration.
+func (b *IdentityProvider) GetConfig() map[string]interface{} {
+	var m map[string]interface{}
+	j, _ := json.Marshal(b.config)
+	json.Unmarshal(j, &m)
+	return m
+}
+
+// Configure configures IdentityProvider.
+func (b *IdentityProvider) Configure() error {
+	kt, err := keytab.Load(b.config.KeytabPath)
+	if err != nil {
+		return errors.ErrIdentityProviderKerberosKeytabLoadFailed.WithArgs(b.config.KeytabPath, err)
+	}
+	b.keytab = kt
+
+	b.trustedNetworks = nil
+	for _, s := range b.config.TrustedNetworks {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return errors.ErrIdentityProviderKerberosTrustedNetworkInvalid.WithArgs(s, err)
+		}
+		b.trustedNetworks = append(b.trustedNetworks, network)
+	}
+
+	b.logger.Info(
+		"successfully configured Kerberos identity provider",
+		zap.String("keytab_path", b.config.KeytabPath),
+		zap.String("service_principal", b.config.ServicePrincipal),
+		zap.String("domain_name", b.config.DomainName),
+		zap.Strings("trusted_networks", b.config.TrustedNetworks),
+		zap.Bool("silent_login_enabled", b.config.SilentLoginEnabled),
+		zap.Any("login_icon", b.config.LoginIcon),
+	)
+
+	b.configured = true
+	return nil
+}
+
+// GetLoginIcon returns the instance of the icon associated with the provider.
+func (b *IdentityProvider) GetLoginIcon() *icons.LoginIcon {
+	return b.config.LoginIcon
+}
+
+// GetLogoutURL returns the logout URL associated with the provider.
+func (b *IdentityProvider) GetLogoutURL() string {
+	return ""
+}
+
+// GetDriver returns the name of the driver associated with the provider.
+func (b *IdentityProvider) GetDriver() string {
+	return b.config.Driver
+}
+
+// GetIdentityTokenCookieName returns the name of the identity token cookie associated with the provider.
+func (b *IdentityProvider) GetIdentityTokenCookieName() string {
+	return ""
+}
+
+// Negotiable returns true when the login page should start the negotiation
+// for the request without user interaction.
+func (b *IdentityProvider) Negotiable(r *http.Request) bool {
+	return b.config.SilentLoginEnabled && b.isTrustedSource(r)
+}
+
+func (b *IdentityProvider) isTrustedSource(r *http.Request) bool {
+	if len(b.trustedNetworks) == 0 {
+		return true
+	}
+	addr := net.ParseIP(addrutil.GetSourceAddress(r))
+	if addr == nil {
+		return false
+	}
+	for _, network := range b.trustedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kerberos
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const (
+	testKerberosRealm     = "CONTOSO.COM"
+	testServicePrincipal  = "HTTP/auth.contoso.com"
+	testServicePassword   = "http-service-secret"
+	testNegotiateFallback = "https://auth.contoso.com/auth/login?negotiate=false"
+)
+
+func TestAuthenticate(t *testing.T) {
+	kt := keytab.New()
+	if err := kt.AddEntry(testServicePrincipal, testKerberosRealm, testServicePassword, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+	tmpDir, err := tests.TempDir("TestKerberosAuthenticate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keytabPath := filepath.Join(tmpDir, "http.keytab")
+	b, err := kt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keytabPath, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	otherKt := keytab.New()
+	if err := otherKt.AddEntry(testServicePrincipal, testKerberosRealm, "foobar", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name       string
+		srcAddr    string
+		header     string
+		wantCode   int
+		wantClaims map[string]interface{}
+		shouldErr  bool
+		err        error
+	}{
+		{
+			name:     "test request without negotiate header",
+			srcAddr:  "10.1.1.1:43210",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "test request from untrusted network",
+			srcAddr:  "192.168.1.1:43210",
+			header:   "Negotiate " + newTestToken(t, kt, "jsmith"),
+			wantCode: http.StatusFound,
+		},
+		{
+			name:     "test request with ntlm token",
+			srcAddr:  "10.1.1.1:43210",
+			header:   "Negotiate TlRMTVNTUAABAAAAB4IIogAAAAAAAAAAAAAAAAAAAAAGAbEdAAAADw==",
+			wantCode: http.StatusFound,
+		},
+		{
+			name:     "test request with valid token",
+			srcAddr:  "10.1.1.1:43210",
+			header:   "Negotiate " + newTestToken(t, kt, "JSmith"),
+			wantCode: http.StatusOK,
+			wantClaims: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"metadata": map[string]interface{}{
+					"kerberos_principal": "JSmith@CONTOSO.COM",
+				},
+			},
+		},
+		{
+			name:      "test request with token for another key",
+			srcAddr:   "10.1.1.1:43210",
+			header:    "Negotiate " + newTestToken(t, otherKt, "jsmith"),
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err: errors.ErrIdentityProviderKerberosAuthFailed.WithArgs(
+				"[Root cause: Decrypting_Error] Decrypting_Error: error decrypting encpart of service ticket provided: " +
+					"error decrypting Ticket EncPart: error decrypting: integrity verification failed",
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			prv, err := NewIdentityProvider(&Config{
+				Name:            "contoso",
+				Realm:           "contoso",
+				Driver:          "windows",
+				KeytabPath:      keytabPath,
+				TrustedNetworks: []string{"10.0.0.0/8"},
+			}, logutil.NewLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := prv.Configure(); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "https://auth.contoso.com/auth/kerberos/contoso", nil)
+			req.RemoteAddr = tc.srcAddr
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := requests.NewRequest()
+			rr.Upstream.Request = req
+			rr.Upstream.BaseURL = "https://auth.contoso.com"
+			rr.Upstream.BasePath = "/auth"
+
+			err = prv.Authenticate(rr)
+			tests.EvalObjectsWithLog(t, "code", tc.wantCode, rr.Response.Code, msgs)
+			if tests.EvalErrWithLog(t, err, "Authenticate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "redirect url", testNegotiateFallback, rr.Response.RedirectURL, msgs)
+			if tc.wantClaims != nil {
+				tests.EvalObjectsWithLog(t, "claims", tc.wantClaims, rr.Response.Payload, msgs)
+			}
+		})
+	}
+}
+
+// newTestToken returns SPNEGO token with the service ticket issued by the
+// key distribution center sharing the keytab with the service.
+func newTestToken(t *testing.T, kt *keytab.Keytab, username string) string {
+	now := time.Now().UTC()
+	tkt, sessionKey, err := messages.NewTicket(
+		types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, username), testKerberosRealm,
+		types.NewPrincipalName(nametype.KRB_NT_SRV_INST, testServicePrincipal), testKerberosRealm,
+		types.NewKrbFlags(), kt, etypeID.AES256_CTS_HMAC_SHA1_96, 1,
+		now, now, now.Add(time.Hour), now.Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := client.NewWithPassword(username, testKerberosRealm, "user-secret", krbconfig.New())
+	negTokenInit, err := spnego.NewNegTokenInitKRB5(cl, tkt, sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := spnego.SPNEGOToken{Init: true, NegTokenInit: negTokenInit}
+	b, err := token.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+var (
+	oidSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidUserPrincipal    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+	oidSmartcardLogon   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}
+	oidUserID           = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+	oidSubjectSerialNum = asn1.ObjectIdentifier{2, 5, 4, 5}
+)
+
+// otherName is the otherName of the subject alternative name, see
+// RFC 5280, Section 4.2.1.6.
+type otherName struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+// Authenticate performs authentication. The client certificate is the
+// certificate the client presented in the TLS handshake with the portal.
+func (b *IdentityProvider) Authenticate(r *requests.Request) error {
+	r.Response.Code = http.StatusBadRequest
+
+	req := r.Upstream.Request
+	if req == nil || req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return errors.ErrIdentityProviderCertificateNotFound
+	}
+	certs := req.TLS.PeerCertificates
+	cert := certs[0]
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         b.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.ErrIdentityProviderCertificateInvalid.WithArgs(err)
+	}
+	if !isClientCertificate(cert) {
+		return errors.ErrIdentityProviderCertificateInvalid.WithArgs("certificate is not for client authentication")
+	}
+
+	if b.revocation != nil {
+		issuer := cert
+		if len(chains[0]) > 1 {
+			issuer = chains[0][1]
+		}
+		if err := b.revocation.check(cert, issuer); err != nil {
+			return err
+		}
+	}
+
+	if len(b.subjectFilters) > 0 {
+		var allowed bool
+		subject := cert.Subject.String()
+		for _, filter := range b.subjectFilters {
+			if filter.MatchString(subject) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.ErrIdentityProviderCertificateSubjectNotAllowed.WithArgs(subject)
+		}
+	}
+
+	m, err := b.getClaims(cert)
+	if err != nil {
+		return err
+	}
+
+	r.Response.Code = http.StatusOK
+	r.Response.Payload = m
+	return nil
+}
+
+func (b *IdentityProvider) getClaims(cert *x509.Certificate) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	var roles []string
+	for _, mapping := range b.claimMappings {
+		if _, exists := m[mapping.Claim]; exists {
+			continue
+		}
+		for _, v := range getValues(cert, mapping.Source) {
+			if mapping.pattern != nil {
+				match := mapping.pattern.FindStringSubmatchIndex(v)
+				if match == nil {
+					continue
+				}
+				if mapping.Template != "" {
+					v = string(mapping.pattern.ExpandString(nil, mapping.Template, v, match))
+				}
+			}
+			if v == "" {
+				continue
+			}
+			if mapping.Claim == "roles" {
+				roles = append(roles, v)
+				continue
+			}
+			m[mapping.Claim] = v
+			break
+		}
+	}
+
+	if _, exists := m["sub"]; !exists {
+		return nil, errors.ErrIdentityProviderCertificateClaimNotFound.WithArgs("sub")
+	}
+	if len(roles) > 0 {
+		m["roles"] = roles
+	}
+	m["metadata"] = map[string]interface{}{
+		"certificate_serial":      cert.SerialNumber.String(),
+		"certificate_issuer":      cert.Issuer.String(),
+		"certificate_fingerprint": getFingerprint(cert),
+	}
+	return m, nil
+}
+
+// getValues returns the values of the certificate attribute.
+func getValues(cert *x509.Certificate, source string) []string {
+	switch source {
+	case "subject.dn":
+		return []string{cert.Subject.String()}
+	case "subject.cn":
+		return nonEmpty(cert.Subject.CommonName)
+	case "subject.uid":
+		return getNameValues(cert.Subject.Names, oidUserID)
+	case "subject.ou":
+		return cert.Subject.OrganizationalUnit
+	case "subject.o":
+		return cert.Subject.Organization
+	case "subject.serial_number":
+		return getNameValues(cert.Subject.Names, oidSubjectSerialNum)
+	case "san.email":
+		return cert.EmailAddresses
+	case "san.upn":
+		return getUserPrincipalNames(cert)
+	case "san.dns":
+		return cert.DNSNames
+	case "san.uri":
+		var values []string
+		for _, u := range cert.URIs {
+			values = append(values, u.String())
+		}
+		return values
+	case "issuer.dn":
+		return []string{cert.Issuer.String()}
+	case "issuer.cn":
+		return nonEmpty(cert.Issuer.CommonName)
+	case "serial":
+		return []string{cert.SerialNumber.String()}
+	case "fingerprint":
+		return []string{getFingerprint(cert)}
+	}
+	return nil
+}
+
+func getNameValues(names []pkix.AttributeTypeAndValue, oid asn1.ObjectIdentifier) []string {
+	var values []string
+	for _, name := range names {
+		if !name.Type.Equal(oid) {
+			continue
+		}
+		if s, ok := name.Value.(string); ok && s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// getUserPrincipalNames returns the Microsoft User Principal Names, i.e.
+// the otherName entries of the subject alternative name of smartcard
+// certificates.
+func getUserPrincipalNames(cert *x509.Certificate) []string {
+	var values []string
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSubjectAltName) {
+			continue
+		}
+		var seq asn1.RawValue
+		if rest, err := asn1.Unmarshal(ext.Value, &seq); err != nil || len(rest) > 0 || !seq.IsCompound {
+			return nil
+		}
+		for data := seq.Bytes; len(data) > 0; {
+			var name asn1.RawValue
+			var err error
+			data, err = asn1.Unmarshal(data, &name)
+			if err != nil {
+				return values
+			}
+			if name.Class != asn1.ClassContextSpecific || name.Tag != 0 {
+				continue
+			}
+			var on otherName
+			if _, err := asn1.UnmarshalWithParams(name.FullBytes, &on, "tag:0"); err != nil {
+				continue
+			}
+			if !on.TypeID.Equal(oidUserPrincipal) {
+				continue
+			}
+			var upn string
+			if _, err := asn1.UnmarshalWithParams(on.Value.Bytes, &upn, "utf8"); err != nil {
+				continue
+			}
+			values = append(values, upn)
+		}
+	}
+	return values
+}
+
+func isClientCertificate(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	for _, usage := range cert.UnknownExtKeyUsage {
+		if usage.Equal(oidSmartcardLogon) {
+			return true
+		}
+	}
+	return false
+}
+
+func getFingerprint(cert *x509.Certificate) string {
+	return fmt.Sprintf("%x", sha256.Sum256(cert.Raw))
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
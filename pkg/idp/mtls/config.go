@@ -0,0 +1,186 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var (
+	supportedSources = map[string]bool{
+		"subject.dn":            true,
+		"subject.cn":            true,
+		"subject.uid":           true,
+		"subject.ou":            true,
+		"subject.o":             true,
+		"subject.serial_number": true,
+		"san.email":             true,
+		"san.upn":               true,
+		"san.dns":               true,
+		"san.uri":               true,
+		"issuer.dn":             true,
+		"issuer.cn":             true,
+		"serial":                true,
+		"fingerprint":           true,
+	}
+
+	// defaultClaimMappings derive the user identity from the User Principal
+	// Name of the smartcard certificates, and from the subject of the
+	// other certificates.
+	defaultClaimMappings = []*ClaimMapping{
+		{Claim: "sub", Source: "san.upn"},
+		{Claim: "sub", Source: "subject.uid"},
+		{Claim: "sub", Source: "subject.cn"},
+		{Claim: "email", Source: "san.email"},
+		{Claim: "email", Source: "san.upn"},
+		{Claim: "name", Source: "subject.cn"},
+	}
+)
+
+// Config holds the configuration for the IdentityProvider.
+type Config struct {
+	// Name is the unique name associated with the IdentityProvider.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Realm the authentication realm associated with the IdentityProvider.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// Driver is the name of the driver associated with the IdentityProvider,
+	// e.g. piv for smartcards.
+	Driver string `json:"driver,omitempty" xml:"driver,omitempty" yaml:"driver,omitempty"`
+	// TrustedCAPaths are the paths to PEM-encoded certificates of the
+	// certificate authorities issuing client certificates.
+	TrustedCAPaths []string `json:"trusted_ca_paths,omitempty" xml:"trusted_ca_paths,omitempty" yaml:"trusted_ca_paths,omitempty"`
+	// SubjectFilters are the regular expressions matching the allowed
+	// subject DNs, e.g. "OU=Engineering". When empty, any subject is allowed.
+	SubjectFilters []string `json:"subject_filters,omitempty" xml:"subject_filters,omitempty" yaml:"subject_filters,omitempty"`
+	// ClaimMappings are the rules deriving user claims from certificate
+	// attributes. When empty, the default rules apply.
+	ClaimMappings []*ClaimMapping `json:"claim_mappings,omitempty" xml:"claim_mappings,omitempty" yaml:"claim_mappings,omitempty"`
+	// RevocationMode is the revocation checking of the client certificates,
+	// i.e. crl, ocsp, or ocsp_crl. When empty, no revocation checking is done.
+	RevocationMode string `json:"revocation_mode,omitempty" xml:"revocation_mode,omitempty" yaml:"revocation_mode,omitempty"`
+	// CRLPaths are the paths to the certificate revocation lists. When
+	// empty, the lists are downloaded from the CRL distribution points of
+	// the certificates.
+	CRLPaths []string `json:"crl_paths,omitempty" xml:"crl_paths,omitempty" yaml:"crl_paths,omitempty"`
+	// RevocationSoftFail allows the certificates whose revocation status is
+	// unknown, e.g. when OCSP responder is unreachable.
+	RevocationSoftFail bool `json:"revocation_soft_fail,omitempty" xml:"revocation_soft_fail,omitempty" yaml:"revocation_soft_fail,omitempty"`
+
+	// LoginIcon is the UI login icon attributes.
+	LoginIcon *icons.LoginIcon `json:"login_icon,omitempty" xml:"login_icon,omitempty" yaml:"login_icon,omitempty"`
+}
+
+// ClaimMapping derives a user claim from a certificate attribute. For the
+// roles claim, each matching value adds a role. For the other claims, the
+// first matching rule wins.
+type ClaimMapping struct {
+	// Claim is the name of the claim, e.g. sub, email, name, or roles.
+	Claim string `json:"claim,omitempty" xml:"claim,omitempty" yaml:"claim,omitempty"`
+	// Source is the certificate attribute, e.g. subject.cn or san.upn.
+	Source string `json:"source,omitempty" xml:"source,omitempty" yaml:"source,omitempty"`
+	// Pattern is the regular expression the attribute value must match.
+	Pattern string `json:"pattern,omitempty" xml:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Template expands the submatches of the pattern, e.g. "$1". When
+	// empty, the claim is the attribute value.
+	Template string `json:"template,omitempty" xml:"template,omitempty" yaml:"template,omitempty"`
+}
+
+// Validate validates identity provider configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrIdentityProviderConfigureNameEmpty
+	}
+	if cfg.Realm == "" {
+		return errors.ErrIdentityProviderConfigureRealmEmpty
+	}
+
+	switch cfg.Driver {
+	case "piv", "generic":
+	case "":
+		return errors.ErrIdentityProviderConfig.WithArgs("no client certificate provider found")
+	default:
+		return errors.ErrIdentityProviderConfig.WithArgs(
+			fmt.Errorf("driver %q is unsupported", cfg.Driver),
+		)
+	}
+
+	if len(cfg.TrustedCAPaths) < 1 {
+		return errors.ErrIdentityProviderConfig.WithArgs("trusted CA paths not found")
+	}
+
+	for _, s := range cfg.SubjectFilters {
+		if _, err := regexp.Compile(s); err != nil {
+			return errors.ErrIdentityProviderConfig.WithArgs(
+				fmt.Errorf("invalid subject filter %q: %v", s, err),
+			)
+		}
+	}
+
+	for _, mapping := range cfg.ClaimMappings {
+		if err := mapping.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch cfg.RevocationMode {
+	case "", "crl", "ocsp", "ocsp_crl":
+	default:
+		return errors.ErrIdentityProviderConfig.WithArgs(
+			fmt.Errorf("revocation mode %q is unsupported", cfg.RevocationMode),
+		)
+	}
+
+	// Configure UI login icon.
+	if cfg.LoginIcon == nil {
+		cfg.LoginIcon = icons.NewLoginIcon(cfg.Driver)
+	} else {
+		cfg.LoginIcon.Configure(cfg.Driver)
+	}
+
+	return nil
+}
+
+// Validate validates claim mapping.
+func (m *ClaimMapping) Validate() error {
+	switch m.Claim {
+	case "sub", "email", "name", "roles":
+	case "":
+		return errors.ErrIdentityProviderCertificateClaimMapping.WithArgs("empty claim")
+	default:
+		return errors.ErrIdentityProviderCertificateClaimMapping.WithArgs(
+			fmt.Errorf("claim %q is unsupported", m.Claim),
+		)
+	}
+	if !supportedSources[m.Source] {
+		return errors.ErrIdentityProviderCertificateClaimMapping.WithArgs(
+			fmt.Errorf("source %q is unsupported", m.Source),
+		)
+	}
+	if m.Pattern != "" {
+		if _, err := regexp.Compile(m.Pattern); err != nil {
+			return errors.ErrIdentityProviderCertificateClaimMapping.WithArgs(
+				fmt.Errorf("invalid pattern %q: %v", m.Pattern, err),
+			)
+		}
+	}
+	if m.Template != "" && m.Pattern == "" {
+		return errors.ErrIdentityProviderCertificateClaimMapping.WithArgs("template requires pattern")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "validate piv client certificate config",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				SubjectFilters: []string{"O=Contoso"},
+				ClaimMappings: []*ClaimMapping{
+					{Claim: "sub", Source: "san.upn", Pattern: "^(.+)@contoso.com$", Template: "$1"},
+					{Claim: "roles", Source: "subject.ou"},
+				},
+				RevocationMode: "ocsp_crl",
+			},
+		},
+		{
+			name: "test empty config name",
+			config: &Config{
+				Realm: "piv",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfigureNameEmpty,
+		},
+		{
+			name: "test empty config realm",
+			config: &Config{
+				Name: "piv",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfigureRealmEmpty,
+		},
+		{
+			name: "test config without driver",
+			config: &Config{
+				Name:  "piv",
+				Realm: "piv",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs("no client certificate provider found"),
+		},
+		{
+			name: "test config with unsupported driver",
+			config: &Config{
+				Name:   "piv",
+				Realm:  "piv",
+				Driver: "foobar",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs(fmt.Errorf("driver %q is unsupported", "foobar")),
+		},
+		{
+			name: "test config without trusted ca paths",
+			config: &Config{
+				Name:   "piv",
+				Realm:  "piv",
+				Driver: "piv",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs("trusted CA paths not found"),
+		},
+		{
+			name: "test config with invalid subject filter",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				SubjectFilters: []string{"O=("},
+			},
+			shouldErr: true,
+			err: errors.ErrIdentityProviderConfig.WithArgs(
+				fmt.Errorf("invalid subject filter %q: %v", "O=(", "error parsing regexp: missing closing ): `O=(`"),
+			),
+		},
+		{
+			name: "test config with unsupported claim",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				ClaimMappings:  []*ClaimMapping{{Claim: "foo", Source: "subject.cn"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateClaimMapping.WithArgs(fmt.Errorf("claim %q is unsupported", "foo")),
+		},
+		{
+			name: "test config with unsupported claim source",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				ClaimMappings:  []*ClaimMapping{{Claim: "sub", Source: "subject.foo"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateClaimMapping.WithArgs(fmt.Errorf("source %q is unsupported", "subject.foo")),
+		},
+		{
+			name: "test config with claim template without pattern",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				ClaimMappings:  []*ClaimMapping{{Claim: "sub", Source: "subject.cn", Template: "$1"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateClaimMapping.WithArgs("template requires pattern"),
+		},
+		{
+			name: "test config with unsupported revocation mode",
+			config: &Config{
+				Name:           "piv",
+				Realm:          "piv",
+				Driver:         "piv",
+				TrustedCAPaths: []string{"/etc/authp/piv_ca.pem"},
+				RevocationMode: "foobar",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs(fmt.Errorf("revocation mode %q is unsupported", "foobar")),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Config", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+		})
+	}
+}
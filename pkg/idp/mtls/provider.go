@@ -0,0 +1,186 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"go.uber.org/zap"
+)
+
+const (
+	providerKind = "mtls"
+)
+
+// IdentityProvider represents client certificate-based identity provider.
+type IdentityProvider struct {
+	config         *Config
+	roots          *x509.CertPool
+	subjectFilters []*regexp.Regexp
+	claimMappings  []*claimMapping
+	revocation     *revocationChecker
+	logger         *zap.Logger
+	configured     bool
+}
+
+type claimMapping struct {
+	*ClaimMapping
+	pattern *regexp.Regexp
+}
+
+// NewIdentityProvider return an instance of IdentityProvider.
+func NewIdentityProvider(cfg *Config, logger *zap.Logger) (*IdentityProvider, error) {
+	if logger == nil {
+		return nil, errors.ErrIdentityProviderConfigureLoggerNotFound
+	}
+
+	b := &IdentityProvider{
+		config: cfg,
+		logger: logger,
+	}
+
+	if err := b.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetRealm return authentication realm.
+func (b *IdentityProvider) GetRealm() string {
+	return b.config.Realm
+}
+
+// GetName return the name associated with this identity provider.
+func (b *IdentityProvider) GetName() string {
+	return b.config.Name
+}
+
+// GetKind returns the authentication method associated with this identity provider.
+func (b *IdentityProvider) GetKind() string {
+	return providerKind
+}
+
+// Configured returns true if the identity provider was configured.
+func (b *IdentityProvider) Configured() bool {
+	return b.configured
+}
+
+// Request performs the requested identity provider operation.
+func (b *IdentityProvider) Request(op operator.Type, r *requests.Request) error {
+	switch op {
+	case operator.Authenticate:
+		return b.Authenticate(r)
+	}
+	return errors.ErrOperatorNotSupported.WithArgs(op)
+}
+
+// GetConfig returns IdentityProvider configuration.
+func (b *IdentityProvider) GetConfig() map[string]interface{} {
+	var m map[string]interface{}
+	j, _ := json.Marshal(b.config)
+	json.Unmarshal(j, &m)
+	return m
+}
+
+// Configure configures IdentityProvider.
+func (b *IdentityProvider) Configure() error {
+	b.roots = x509.NewCertPool()
+	for _, fp := range b.config.TrustedCAPaths {
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return errors.ErrIdentityProviderCertificateTrustedCA.WithArgs(fp, err)
+		}
+		if !b.roots.AppendCertsFromPEM(data) {
+			return errors.ErrIdentityProviderCertificateTrustedCA.WithArgs(fp, "no PEM certificates found")
+		}
+	}
+
+	b.subjectFilters = nil
+	for _, s := range b.config.SubjectFilters {
+		b.subjectFilters = append(b.subjectFilters, regexp.MustCompile(s))
+	}
+
+	mappings := b.config.ClaimMappings
+	if len(mappings) == 0 {
+		mappings = defaultClaimMappings
+	}
+	b.claimMappings = nil
+	for _, mapping := range mappings {
+		m := &claimMapping{ClaimMapping: mapping}
+		if mapping.Pattern != "" {
+			m.pattern = regexp.MustCompile(mapping.Pattern)
+		}
+		b.claimMappings = append(b.claimMappings, m)
+	}
+
+	b.revocation = nil
+	if b.config.RevocationMode != "" {
+		b.revocation = &revocationChecker{
+			mode:     b.config.RevocationMode,
+			softFail: b.config.RevocationSoftFail,
+			client:   httpclient.NewClient(nil, 10*time.Second),
+			crls:     make(map[string]*cachedCRL),
+			now:      time.Now,
+		}
+		for _, fp := range b.config.CRLPaths {
+			if err := b.revocation.loadCRL(fp); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.logger.Info(
+		"successfully configured client certificate identity provider",
+		zap.Strings("trusted_ca_paths", b.config.TrustedCAPaths),
+		zap.Strings("subject_filters", b.config.SubjectFilters),
+		zap.Any("claim_mappings", mappings),
+		zap.String("revocation_mode", b.config.RevocationMode),
+		zap.Strings("crl_paths", b.config.CRLPaths),
+		zap.Any("login_icon", b.config.LoginIcon),
+	)
+
+	b.configured = true
+	return nil
+}
+
+// GetLoginIcon returns the instance of the icon associated with the provider.
+func (b *IdentityProvider) GetLoginIcon() *icons.LoginIcon {
+	return b.config.LoginIcon
+}
+
+// GetLogoutURL returns the logout URL associated with the provider.
+func (b *IdentityProvider) GetLogoutURL() string {
+	return ""
+}
+
+// GetDriver returns the name of the driver associated with the provider.
+func (b *IdentityProvider) GetDriver() string {
+	return b.config.Driver
+}
+
+// GetIdentityTokenCookieName returns the name of the identity token cookie associated with the provider.
+func (b *IdentityProvider) GetIdentityTokenCookieName() string {
+	return ""
+}
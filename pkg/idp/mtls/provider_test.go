@@ -0,0 +1,374 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func TestAuthenticate(t *testing.T) {
+	tmpDir, err := tests.TempDir("TestCertificateAuthenticate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := newTestAuthority(t, "Contoso PIV CA")
+	otherCA := newTestAuthority(t, "Fabrikam CA")
+	caPath := filepath.Join(tmpDir, "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	crl, err := ca.cert.CreateCRL(rand.Reader, ca.key, []pkix.RevokedCertificate{
+		{SerialNumber: big.NewInt(1002), RevocationTime: time.Now().Add(-time.Hour)},
+	}, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crlPath := filepath.Join(tmpDir, "ca.crl")
+	if err := os.WriteFile(crlPath, crl, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status := ocsp.Good
+		if req.SerialNumber.Int64() == 1002 {
+			status = ocsp.Revoked
+		}
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Hour),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Hour),
+		}, ca.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	userCert := ca.issue(t, 1001, "Alice Smith", []string{"Engineering", "Operations"}, "asmith@contoso.com", responder.URL, x509.ExtKeyUsageClientAuth)
+	revokedCert := ca.issue(t, 1002, "Bob Jones", nil, "bjones@contoso.com", responder.URL, x509.ExtKeyUsageClientAuth)
+	serverCert := ca.issue(t, 1003, "Carol White", nil, "cwhite@contoso.com", responder.URL, x509.ExtKeyUsageServerAuth)
+	untrustedCert := otherCA.issue(t, 1004, "Alice Smith", nil, "asmith@contoso.com", responder.URL, x509.ExtKeyUsageClientAuth)
+
+	testcases := []struct {
+		name       string
+		config     *Config
+		cert       *x509.Certificate
+		wantCode   int
+		wantClaims map[string]interface{}
+		shouldErr  bool
+		err        error
+	}{
+		{
+			name:      "test request without client certificate",
+			config:    &Config{},
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateNotFound,
+		},
+		{
+			name:     "test request with valid certificate and default claim mappings",
+			config:   &Config{},
+			cert:     userCert,
+			wantCode: http.StatusOK,
+			wantClaims: map[string]interface{}{
+				"sub":   "asmith@contoso.com",
+				"email": "alice.smith@contoso.com",
+				"name":  "Alice Smith",
+				"metadata": map[string]interface{}{
+					"certificate_serial":      "1001",
+					"certificate_issuer":      "CN=Contoso PIV CA,O=Contoso",
+					"certificate_fingerprint": getFingerprint(userCert),
+				},
+			},
+		},
+		{
+			name: "test request with valid certificate and custom claim mappings",
+			config: &Config{
+				ClaimMappings: []*ClaimMapping{
+					{Claim: "sub", Source: "san.upn", Pattern: "^(.+)@contoso.com$", Template: "$1"},
+					{Claim: "roles", Source: "subject.ou", Pattern: "^Engineering$", Template: "authp/admin"},
+					{Claim: "roles", Source: "subject.o", Pattern: "^(.+)$", Template: "${1}/user"},
+				},
+			},
+			cert:     userCert,
+			wantCode: http.StatusOK,
+			wantClaims: map[string]interface{}{
+				"sub":   "asmith",
+				"roles": []string{"authp/admin", "Contoso/user"},
+				"metadata": map[string]interface{}{
+					"certificate_serial":      "1001",
+					"certificate_issuer":      "CN=Contoso PIV CA,O=Contoso",
+					"certificate_fingerprint": getFingerprint(userCert),
+				},
+			},
+		},
+		{
+			name:      "test request with certificate issued by untrusted authority",
+			config:    &Config{},
+			cert:      untrustedCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err: errors.ErrIdentityProviderCertificateInvalid.WithArgs(
+				"x509: certificate signed by unknown authority",
+			),
+		},
+		{
+			name:      "test request with server certificate",
+			config:    &Config{},
+			cert:      serverCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateInvalid.WithArgs("certificate is not for client authentication"),
+		},
+		{
+			name: "test request with certificate subject not allowed",
+			config: &Config{
+				SubjectFilters: []string{"OU=Finance"},
+			},
+			cert:      userCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err: errors.ErrIdentityProviderCertificateSubjectNotAllowed.WithArgs(
+				"CN=Alice Smith,OU=Operations+OU=Engineering,O=Contoso",
+			),
+		},
+		{
+			name: "test request with certificate revoked by crl",
+			config: &Config{
+				RevocationMode: "crl",
+				CRLPaths:       []string{crlPath},
+			},
+			cert:      revokedCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateRevoked.WithArgs("1002"),
+		},
+		{
+			name: "test request with certificate not revoked by crl",
+			config: &Config{
+				RevocationMode: "crl",
+				CRLPaths:       []string{crlPath},
+				ClaimMappings:  []*ClaimMapping{{Claim: "sub", Source: "serial"}},
+			},
+			cert:     userCert,
+			wantCode: http.StatusOK,
+			wantClaims: map[string]interface{}{
+				"sub": "1001",
+				"metadata": map[string]interface{}{
+					"certificate_serial":      "1001",
+					"certificate_issuer":      "CN=Contoso PIV CA,O=Contoso",
+					"certificate_fingerprint": getFingerprint(userCert),
+				},
+			},
+		},
+		{
+			name: "test request with certificate revoked by ocsp",
+			config: &Config{
+				RevocationMode: "ocsp",
+			},
+			cert:      revokedCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderCertificateRevoked.WithArgs("1002"),
+		},
+		{
+			name: "test request with certificate without crl distribution points",
+			config: &Config{
+				RevocationMode: "crl",
+			},
+			cert:      userCert,
+			wantCode:  http.StatusBadRequest,
+			shouldErr: true,
+			err: errors.ErrIdentityProviderCertificateRevocationCheckFailed.WithArgs(
+				"certificate has no CRL distribution points",
+			),
+		},
+		{
+			name: "test request with unknown revocation status and soft fail",
+			config: &Config{
+				RevocationMode:     "crl",
+				RevocationSoftFail: true,
+				ClaimMappings:      []*ClaimMapping{{Claim: "sub", Source: "subject.cn"}},
+			},
+			cert:     userCert,
+			wantCode: http.StatusOK,
+			wantClaims: map[string]interface{}{
+				"sub": "Alice Smith",
+				"metadata": map[string]interface{}{
+					"certificate_serial":      "1001",
+					"certificate_issuer":      "CN=Contoso PIV CA,O=Contoso",
+					"certificate_fingerprint": getFingerprint(userCert),
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cfg := tc.config
+			cfg.Name = "piv"
+			cfg.Realm = "piv"
+			cfg.Driver = "piv"
+			cfg.TrustedCAPaths = []string{caPath}
+			prv, err := NewIdentityProvider(cfg, logutil.NewLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := prv.Configure(); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "https://auth.contoso.com/auth/mtls/piv", nil)
+			req.TLS = &tls.ConnectionState{}
+			if tc.cert != nil {
+				req.TLS.PeerCertificates = []*x509.Certificate{tc.cert}
+			}
+			rr := requests.NewRequest()
+			rr.Upstream.Request = req
+
+			err = prv.Authenticate(rr)
+			tests.EvalObjectsWithLog(t, "code", tc.wantCode, rr.Response.Code, msgs)
+			if tests.EvalErrWithLog(t, err, "Authenticate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "claims", tc.wantClaims, rr.Response.Payload, msgs)
+		})
+	}
+}
+
+func newTestAuthority(t *testing.T, name string) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"Contoso"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthority{cert: cert, key: key}
+}
+
+// issue returns smartcard certificate with the user principal name and
+// the email address in the subject alternative name.
+func (ca *testAuthority) issue(t *testing.T, serial int64, name string, units []string, upn, ocspURL string, usage x509.ExtKeyUsage) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upnValue, err := asn1.MarshalWithParams(upn, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upnName, err := asn1.MarshalWithParams(otherName{
+		TypeID: oidUserPrincipal,
+		Value: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      upnValue,
+		},
+	}, "tag:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := fmt.Sprintf("%s@contoso.com", map[string]string{
+		"Alice Smith": "alice.smith",
+		"Bob Jones":   "bob.jones",
+		"Carol White": "carol.white",
+	}[name])
+	san, err := asn1.Marshal([]asn1.RawValue{
+		{FullBytes: upnName},
+		{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: []byte(email)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{
+			CommonName:         name,
+			Organization:       []string{"Contoso"},
+			OrganizationalUnit: units,
+		},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(24 * time.Hour),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{usage},
+		OCSPServer:      []string{ocspURL},
+		ExtraExtensions: []pkix.Extension{{Id: oidSubjectAltName, Value: san}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
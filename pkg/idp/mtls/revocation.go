@@ -0,0 +1,213 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+const maxRevocationResponseBytes = 10 << 20
+
+// revocationChecker checks whether the client certificates are revoked.
+type revocationChecker struct {
+	mode     string
+	softFail bool
+	client   *http.Client
+	mu       sync.Mutex
+	// crls are the revocation lists keyed by either the path or the URL
+	// of the list.
+	crls map[string]*cachedCRL
+	// static indicates whether the lists are configured, rather than
+	// downloaded from the distribution points.
+	static bool
+	now    func() time.Time
+}
+
+type cachedCRL struct {
+	list    *pkix.CertificateList
+	revoked map[string]bool
+}
+
+// errRevocationUnknown indicates the revocation status could not be
+// determined, e.g. the responder is unreachable.
+type errRevocationUnknown struct {
+	err error
+}
+
+func (e *errRevocationUnknown) Error() string {
+	return e.err.Error()
+}
+
+// check returns an error when the certificate is revoked, or when its
+// revocation status is unknown and soft fail is disabled.
+func (c *revocationChecker) check(cert, issuer *x509.Certificate) error {
+	var err error
+	switch c.mode {
+	case "crl":
+		err = c.checkCRL(cert, issuer)
+	case "ocsp":
+		err = c.checkOCSP(cert, issuer)
+	case "ocsp_crl":
+		err = c.checkOCSP(cert, issuer)
+		if _, unknown := err.(*errRevocationUnknown); unknown {
+			err = c.checkCRL(cert, issuer)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if _, unknown := err.(*errRevocationUnknown); unknown {
+		if c.softFail {
+			return nil
+		}
+		return errors.ErrIdentityProviderCertificateRevocationCheckFailed.WithArgs(err)
+	}
+	return err
+}
+
+func (c *revocationChecker) checkOCSP(cert, issuer *x509.Certificate) error {
+	if len(cert.OCSPServer) == 0 {
+		return &errRevocationUnknown{fmt.Errorf("certificate has no OCSP responder")}
+	}
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return &errRevocationUnknown{err}
+	}
+	resp, err := c.client.Post(cert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return &errRevocationUnknown{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &errRevocationUnknown{fmt.Errorf("OCSP responder returned status code %d", resp.StatusCode)}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseBytes))
+	if err != nil {
+		return &errRevocationUnknown{err}
+	}
+	status, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return &errRevocationUnknown{err}
+	}
+	switch status.Status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return errors.ErrIdentityProviderCertificateRevoked.WithArgs(cert.SerialNumber.String())
+	}
+	return &errRevocationUnknown{fmt.Errorf("OCSP responder returned unknown status")}
+}
+
+func (c *revocationChecker) checkCRL(cert, issuer *x509.Certificate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lists []*cachedCRL
+	if c.static {
+		for _, entry := range c.crls {
+			lists = append(lists, entry)
+		}
+	} else {
+		if len(cert.CRLDistributionPoints) == 0 {
+			return &errRevocationUnknown{fmt.Errorf("certificate has no CRL distribution points")}
+		}
+		for _, u := range cert.CRLDistributionPoints {
+			entry, exists := c.crls[u]
+			if !exists || entry.list.HasExpired(c.now()) {
+				var err error
+				entry, err = c.fetchCRL(u)
+				if err != nil {
+					return &errRevocationUnknown{err}
+				}
+				c.crls[u] = entry
+			}
+			lists = append(lists, entry)
+		}
+	}
+
+	var found bool
+	for _, entry := range lists {
+		if issuer.CheckCRLSignature(entry.list) != nil {
+			continue
+		}
+		if entry.list.HasExpired(c.now()) {
+			return &errRevocationUnknown{fmt.Errorf("CRL of %q has expired", issuer.Subject.String())}
+		}
+		found = true
+		if entry.revoked[cert.SerialNumber.String()] {
+			return errors.ErrIdentityProviderCertificateRevoked.WithArgs(cert.SerialNumber.String())
+		}
+	}
+	if !found {
+		return &errRevocationUnknown{fmt.Errorf("CRL of %q not found", issuer.Subject.String())}
+	}
+	return nil
+}
+
+func (c *revocationChecker) loadCRL(fp string) error {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return errors.ErrIdentityProviderCertificateCRL.WithArgs(fp, err)
+	}
+	entry, err := parseCRL(data)
+	if err != nil {
+		return errors.ErrIdentityProviderCertificateCRL.WithArgs(fp, err)
+	}
+	c.crls[fp] = entry
+	c.static = true
+	return nil
+}
+
+func (c *revocationChecker) fetchCRL(u string) (*cachedCRL, error) {
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CRL distribution point %q returned status code %d", u, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseCRL(data)
+}
+
+func parseCRL(data []byte) (*cachedCRL, error) {
+	list, err := x509.ParseCRL(data)
+	if err != nil {
+		return nil, err
+	}
+	entry := &cachedCRL{
+		list:    list,
+		revoked: make(map[string]bool),
+	}
+	for _, revoked := range list.TBSCertList.RevokedCertificates {
+		entry.revoked[revoked.SerialNumber.String()] = true
+	}
+	return entry, nil
+}
@@ -15,18 +15,20 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"github.com/greenpau/go-authcrunch/pkg/util"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -36,6 +38,7 @@ import (
 func (b *IdentityProvider) Authenticate(r *requests.Request) error {
 	reqPath := r.Upstream.BaseURL + path.Join(r.Upstream.BasePath, r.Upstream.Method, r.Upstream.Realm)
 	r.Response.Code = http.StatusBadRequest
+	ctx := r.GetContext()
 
 	var accessTokenExists, idTokenExists, codeExists, stateExists, errorExists, loginHintExists, additionalScopesExists bool
 	var reqParamsAccessToken, reqParamsIDToken, reqParamsState, reqParamsCode, reqParamsError, reqParamsLoginHint, additionalScopes string
@@ -95,18 +98,20 @@ func (b *IdentityProvider) Authenticate(r *requests.Request) error {
 				zap.String("session_id", r.Upstream.SessionID),
 				zap.String("request_id", r.ID),
 				zap.String("state", reqParamsState),
-				zap.String("code", reqParamsCode),
+				logutil.MaskedString("code", reqParamsCode),
 			)
 
 			reqRedirectURI := reqPath + "/authorization-code-callback"
 			var accessToken map[string]interface{}
 			var err error
+			spanCtx, span := tracing.Start(ctx, "oauth.FetchAccessToken", tracing.RealmKey.String(b.config.Realm))
 			switch b.config.Driver {
 			case "facebook":
-				accessToken, err = b.fetchFacebookAccessToken(reqRedirectURI, reqParamsState, reqParamsCode)
+				accessToken, err = b.fetchFacebookAccessToken(spanCtx, reqRedirectURI, reqParamsState, reqParamsCode)
 			default:
-				accessToken, err = b.fetchAccessToken(reqRedirectURI, reqParamsState, reqParamsCode)
+				accessToken, err = b.fetchAccessToken(spanCtx, reqRedirectURI, reqParamsState, reqParamsCode)
 			}
+			tracing.End(span, err)
 			if err != nil {
 				b.logger.Debug(
 					"failed fetching OAuth 2.0 access token from the authorization server",
@@ -119,41 +124,31 @@ func (b *IdentityProvider) Authenticate(r *requests.Request) error {
 			b.logger.Debug(
 				"received OAuth 2.0 authorization server access token",
 				zap.String("request_id", r.ID),
-				zap.Any("token", accessToken),
+				logutil.MaskedAny("token", accessToken),
 			)
 
 			var m map[string]interface{}
 
 			switch b.config.Driver {
 			case "github", "gitlab", "facebook", "discord", "patreon":
-				m, err = b.fetchClaims(accessToken)
+				spanCtx, span := tracing.Start(ctx, "oauth.FetchClaims", tracing.RealmKey.String(b.config.Realm))
+				m, err = b.fetchClaims(spanCtx, accessToken)
+				tracing.End(span, err)
 				if err != nil {
 					return errors.ErrIdentityProviderOauthFetchClaimsFailed.WithArgs(err)
 				}
 			default:
-				m, err = b.validateAccessToken(reqParamsState, accessToken)
+				m, err = b.validateAccessToken(ctx, reqParamsState, accessToken)
 				if err != nil {
 					return errors.ErrIdentityProviderOauthValidateAccessTokenFailed.WithArgs(err)
 				}
 			}
 
-			// Fetch user info.
-			if err := b.fetchUserInfo(accessToken, m); err != nil {
-				b.logger.Debug(
-					"failed fetching user info",
-					zap.String("request_id", r.ID),
-					zap.Error(err),
-				)
-			}
-
-			// Fetch subsequent user info, e.g. user groups.
-			if err := b.fetchUserGroups(accessToken, m); err != nil {
-				b.logger.Debug(
-					"failed fetching user groups",
-					zap.String("request_id", r.ID),
-					zap.Error(err),
-				)
-			}
+			// Fetch user info and subsequent user info, e.g. user groups.
+			b.enrichUserData(ctx, r.ID, accessToken, m, []*userEnrichment{
+				{name: "user info", span: "oauth.FetchUserInfo", fetch: b.fetchUserInfo},
+				{name: "user groups", span: "oauth.FetchUserGroups", fetch: b.fetchUserGroups},
+			})
 
 			if b.config.IdentityTokenCookieEnabled {
 				if v, exists := accessToken["id_token"]; exists {
@@ -176,7 +171,7 @@ func (b *IdentityProvider) Authenticate(r *requests.Request) error {
 				"access_token": reqParamsAccessToken,
 				"id_token":     reqParamsIDToken,
 			}
-			m, err := b.validateAccessToken(reqParamsState, accessToken)
+			m, err := b.validateAccessToken(ctx, reqParamsState, accessToken)
 			if err != nil {
 				return errors.ErrIdentityProviderOauthValidateAccessTokenFailed.WithArgs(err)
 			}
@@ -243,10 +238,14 @@ func (b *IdentityProvider) Authenticate(r *requests.Request) error {
 	return nil
 }
 
-func (b *IdentityProvider) fetchAccessToken(redirectURI, state, code string) (map[string]interface{}, error) {
+func (b *IdentityProvider) fetchAccessToken(ctx context.Context, redirectURI, state, code string) (map[string]interface{}, error) {
+	clientSecret, err := b.getClientSecret()
+	if err != nil {
+		return nil, err
+	}
 	params := url.Values{}
 	params.Set("client_id", b.config.ClientID)
-	params.Set("client_secret", b.config.ClientSecret)
+	params.Set("client_secret", clientSecret)
 	if !b.disablePassGrantType {
 		params.Set("grant_type", "authorization_code")
 	}
@@ -254,16 +253,12 @@ func (b *IdentityProvider) fetchAccessToken(redirectURI, state, code string) (ma
 	params.Set("code", code)
 	params.Set("redirect_uri", redirectURI)
 
-	cli := &http.Client{
-		Timeout: time.Second * 10,
-	}
-
 	cli, err := b.newBrowser()
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", b.tokenURL, strings.NewReader(params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", b.tokenURL, strings.NewReader(params.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -281,7 +276,7 @@ func (b *IdentityProvider) fetchAccessToken(redirectURI, state, code string) (ma
 		return nil, err
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return nil, err
@@ -289,18 +284,18 @@ func (b *IdentityProvider) fetchAccessToken(redirectURI, state, code string) (ma
 
 	b.logger.Debug(
 		"OAuth 2.0 access token response received",
-		zap.Any("body", respBody),
+		zap.Int("body_size", len(respBody)),
 		zap.String("redirect_uri", redirectURI),
 	)
 
-	data := make(map[string]interface{})
-	if err := json.Unmarshal(respBody, &data); err != nil {
+	data, err := parseTokenResponse(resp.Header.Get("Content-Type"), respBody)
+	if err != nil {
 		return nil, err
 	}
 
 	b.logger.Debug(
 		"OAuth 2.0 access token response decoded",
-		zap.Any("body", data),
+		logutil.MaskedAny("body", data),
 	)
 
 	if _, exists := data["error"]; exists {
@@ -323,23 +318,23 @@ func (b *IdentityProvider) fetchAccessToken(redirectURI, state, code string) (ma
 	return data, nil
 }
 
-func (b *IdentityProvider) fetchFacebookAccessToken(redirectURI, state, code string) (map[string]interface{}, error) {
+func (b *IdentityProvider) fetchFacebookAccessToken(ctx context.Context, redirectURI, state, code string) (map[string]interface{}, error) {
+	clientSecret, err := b.getClientSecret()
+	if err != nil {
+		return nil, err
+	}
 	params := url.Values{}
 	params.Set("client_id", b.config.ClientID)
-	params.Set("client_secret", b.config.ClientSecret)
+	params.Set("client_secret", clientSecret)
 	params.Set("code", code)
 	params.Set("redirect_uri", redirectURI)
 
-	cli := &http.Client{
-		Timeout: time.Second * 10,
-	}
-
 	cli, err := b.newBrowser()
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", b.tokenURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.tokenURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -356,18 +351,18 @@ func (b *IdentityProvider) fetchFacebookAccessToken(redirectURI, state, code str
 		return nil, err
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return nil, err
 	}
 	b.logger.Debug(
 		"OAuth 2.0 access token response received",
-		zap.Any("body", respBody),
+		zap.Int("body_size", len(respBody)),
 	)
 
-	data := make(map[string]interface{})
-	if err := json.Unmarshal(respBody, &data); err != nil {
+	data, err := parseTokenResponse(resp.Header.Get("Content-Type"), respBody)
+	if err != nil {
 		return nil, err
 	}
 	if _, exists := data["error"]; exists {
@@ -389,3 +384,24 @@ func (b *IdentityProvider) fetchFacebookAccessToken(redirectURI, state, code str
 	}
 	return data, nil
 }
+
+// parseTokenResponse decodes the response of the token endpoint. Some
+// authorization servers, e.g. GitHub without the JSON Accept header,
+// respond with form-encoded body.
+func parseTokenResponse(contentType string, body []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for k := range values {
+			data[k] = values.Get(k)
+		}
+		return data, nil
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
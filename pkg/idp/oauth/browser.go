@@ -15,46 +15,35 @@
 package oauth
 
 import (
-	"crypto/tls"
-	"net"
 	"net/http"
-	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 )
 
 type browserConfig struct {
 	TLSInsecureSkipVerify bool
+	// transport overrides the default transport, e.g. in tests.
+	transport http.RoundTripper
 }
 
+// newBrowser returns the HTTP client for the requests to the authorization
+// server and the upstream APIs. The clients share the pooled transport.
 func (b *IdentityProvider) newBrowser() (*http.Client, error) {
-	/*
-	   cj, err := cookiejar.New(nil)
-	   if err != nil {
-	       return nil, err
-	   }
-	*/
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout: 5 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: 5 * time.Second,
+	if b.browserConfig != nil && b.browserConfig.transport != nil {
+		return &http.Client{
+			Timeout:   b.requestTimeout,
+			Transport: tracing.NewTransport(b.browserConfig.transport),
+		}, nil
 	}
 
+	var insecureSkipVerify bool
 	if b.browserConfig != nil {
-		if b.browserConfig.TLSInsecureSkipVerify == true {
-			if tr.TLSClientConfig == nil {
-				tr.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-			} else {
-				tr.TLSClientConfig.InsecureSkipVerify = true
-			}
-		}
+		insecureSkipVerify = b.browserConfig.TLSInsecureSkipVerify
 	}
 
 	return &http.Client{
-		//Jar:       cj,
-		Timeout:   time.Second * 10,
-		Transport: tr,
+		Timeout:   b.requestTimeout,
+		Transport: tracing.NewTransport(httpclient.GetTransport(b.config.HTTPClientConfig, insecureSkipVerify)),
 	}, nil
 }
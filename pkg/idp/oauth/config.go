@@ -17,7 +17,9 @@ package oauth
 import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/enrichment"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
 	"net/url"
 	"regexp"
 	"strings"
@@ -25,6 +27,10 @@ import (
 
 const defaultIdentityTokenCookieName string = "AUTHP_ID_TOKEN"
 
+// defaultRequestTimeout is the number of seconds to wait for a response
+// from an OAuth 2.0 identity provider.
+const defaultRequestTimeout int = 10
+
 // Config holds the configuration for the IdentityProvider.
 type Config struct {
 	Name              string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
@@ -54,6 +60,9 @@ type Config struct {
 	RetryAttempts int `json:"retry_attempts,omitempty" xml:"retry_attempts,omitempty" yaml:"retry_attempts,omitempty"`
 	// The number of seconds to wait until the retrying.
 	RetryInterval int `json:"retry_interval,omitempty" xml:"retry_interval,omitempty" yaml:"retry_interval,omitempty"`
+	// The number of seconds to wait for a response from an OAuth 2.0
+	// identity provider. Defaults to 10 seconds.
+	RequestTimeout int `json:"request_timeout,omitempty" xml:"request_timeout,omitempty" yaml:"request_timeout,omitempty"`
 
 	UserRoleMapList []map[string]interface{} `json:"user_roles,omitempty" xml:"user_roles,omitempty" yaml:"user_roles,omitempty"`
 
@@ -108,6 +117,14 @@ type Config struct {
 	IdentityTokenCookieName string `json:"identity_token_cookie_name,omitempty" xml:"identity_token_cookie_name,omitempty" yaml:"identity_token_cookie_name,omitempty"`
 	// Enables the storing of id_token from OAuth provider in a HTTP cookie.
 	IdentityTokenCookieEnabled bool `json:"identity_token_cookie_enabled,omitempty" xml:"identity_token_cookie_enabled,omitempty" yaml:"identity_token_cookie_enabled,omitempty"`
+
+	// GroupCacheConfig holds the configuration of the cache of the user
+	// group and org lookups, e.g. Google groups or GitHub orgs.
+	GroupCacheConfig *enrichment.Config `json:"group_cache_config,omitempty" xml:"group_cache_config,omitempty" yaml:"group_cache_config,omitempty"`
+
+	// HTTPClientConfig is the configuration of the pooled connections to
+	// the authorization server and the upstream APIs.
+	HTTPClientConfig *httpclient.Config `json:"http_client_config,omitempty" xml:"http_client_config,omitempty" yaml:"http_client_config,omitempty"`
 }
 
 // Validate validates identity store configuration.
@@ -143,6 +160,12 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.RequestTimeout < 0 {
+		return errors.ErrIdentityProviderConfig.WithArgs(
+			fmt.Errorf("request timeout %d is invalid", cfg.RequestTimeout),
+		)
+	}
+
 	if len(cfg.Scopes) < 1 {
 		switch cfg.Driver {
 		case "facebook":
@@ -329,6 +352,18 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.GroupCacheConfig != nil {
+		if err := cfg.GroupCacheConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.HTTPClientConfig != nil {
+		if err := cfg.HTTPClientConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Configure UI login icon.
 	if cfg.LoginIcon == nil {
 		cfg.LoginIcon = icons.NewLoginIcon(cfg.Driver)
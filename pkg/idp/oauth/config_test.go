@@ -447,6 +447,21 @@ func TestValidateConfig(t *testing.T) {
 			shouldErr: true,
 			err:       errors.ErrIdentityProviderConfig.WithArgs("domain name not found"),
 		},
+		{
+			name: "test negative request timeout",
+			config: &Config{
+				Name:           "contoso",
+				Realm:          "contoso",
+				Driver:         "generic",
+				ClientID:       "foo",
+				ClientSecret:   "bar",
+				BaseAuthURL:    "https://localhost/oauth",
+				MetadataURL:    "https://localhost/oauth/.well-known/openid-configuration",
+				RequestTimeout: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityProviderConfig.WithArgs(fmt.Errorf("request timeout -1 is invalid")),
+		},
 		{
 			name: "test empty config name",
 			config: &Config{
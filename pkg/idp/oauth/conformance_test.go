@@ -0,0 +1,350 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+const (
+	testOAuthClientID     = "foo.apps.googleusercontent.com"
+	testOAuthClientSecret = "bar"
+)
+
+func TestIdentityProviderConformance(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":   "jsmith",
+		"email": "jsmith@contoso.com",
+		"name":  "John Smith",
+	}
+
+	testcases := []struct {
+		name           string
+		config         *Config
+		quirks         *tests.OAuthServerQuirks
+		profile        map[string]interface{}
+		want           map[string]interface{}
+		wantKeyFetches int
+		shouldErr      bool
+		err            error
+	}{
+		{
+			name: "generic driver",
+			config: &Config{
+				Driver:      "generic",
+				MetadataURL: "https://idp.contoso.com/.well-known/openid-configuration",
+			},
+			want: claims,
+		},
+		{
+			name: "generic driver with user info",
+			config: &Config{
+				Driver:         "generic",
+				MetadataURL:    "https://idp.contoso.com/.well-known/openid-configuration",
+				UserInfoFields: []string{"all"},
+			},
+			profile: map[string]interface{}{
+				"sub":   "jsmith",
+				"roles": []string{"admin"},
+			},
+			want: map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@contoso.com",
+				"name":  "John Smith",
+				"roles": []string{"admin"},
+				"userinfo": map[string]interface{}{
+					"sub": "jsmith",
+				},
+			},
+		},
+		{
+			name: "generic driver without json accept header",
+			config: &Config{
+				Driver:      "generic",
+				MetadataURL: "https://idp.contoso.com/.well-known/openid-configuration",
+			},
+			quirks: &tests.OAuthServerQuirks{AcceptHeaderRequired: true},
+			want:   claims,
+		},
+		{
+			name: "generic driver with form-encoded token response",
+			config: &Config{
+				Driver:              "generic",
+				MetadataURL:         "https://idp.contoso.com/.well-known/openid-configuration",
+				AcceptHeaderEnabled: true,
+			},
+			quirks: &tests.OAuthServerQuirks{FormEncodedTokenResponse: true},
+			want:   claims,
+		},
+		{
+			name: "generic driver with missing token type",
+			config: &Config{
+				Driver:      "generic",
+				MetadataURL: "https://idp.contoso.com/.well-known/openid-configuration",
+			},
+			quirks: &tests.OAuthServerQuirks{TokenTypeOmitted: true},
+			want:   claims,
+		},
+		{
+			name: "generic driver with rotating jwks keys",
+			config: &Config{
+				Driver:      "generic",
+				MetadataURL: "https://idp.contoso.com/.well-known/openid-configuration",
+			},
+			quirks:         &tests.OAuthServerQuirks{KeyRotationEnabled: true},
+			want:           claims,
+			wantKeyFetches: 2,
+		},
+		{
+			name: "generic driver with invalid client secret",
+			config: &Config{
+				Driver:       "generic",
+				MetadataURL:  "https://idp.contoso.com/.well-known/openid-configuration",
+				ClientSecret: "foobar",
+			},
+			shouldErr: true,
+			err: errors.ErrIdentityProviderOauthFetchAccessTokenFailed.WithArgs(
+				errors.ErrIdentityProviderOauthGetAccessTokenFailedDetailed.WithArgs("invalid_client", "client authentication failed"),
+			),
+		},
+		{
+			name: "okta driver",
+			config: &Config{
+				Driver:     "okta",
+				DomainName: "contoso.okta.com",
+				ServerID:   "default",
+			},
+			want: claims,
+		},
+		{
+			name:   "google driver",
+			config: &Config{Driver: "google"},
+			want:   claims,
+		},
+		{
+			name:   "azure driver",
+			config: &Config{Driver: "azure"},
+			want:   claims,
+		},
+		{
+			name: "cognito driver",
+			config: &Config{
+				Driver:     "cognito",
+				Region:     "us-east-1",
+				UserPoolID: "us-east-1_abc",
+			},
+			want: claims,
+		},
+		{
+			name:   "gitlab driver",
+			config: &Config{Driver: "gitlab"},
+			profile: map[string]interface{}{
+				"profile": "https://gitlab.com/jsmith",
+				"name":    "John Smith",
+				"email":   "jsmith@contoso.com",
+			},
+			want: map[string]interface{}{
+				"origin": "/userinfo",
+				"sub":    "https://gitlab.com/jsmith",
+				"name":   "John Smith",
+				"email":  "jsmith@contoso.com",
+			},
+		},
+		{
+			name:   "github driver",
+			config: &Config{Driver: "github"},
+			quirks: &tests.OAuthServerQuirks{AcceptHeaderRequired: true},
+			profile: map[string]interface{}{
+				"login":      "jsmith",
+				"id":         1234,
+				"name":       "John Smith",
+				"avatar_url": "https://avatars.githubusercontent.com/u/1234",
+			},
+			want: map[string]interface{}{
+				"origin":  "https://api.github.com/user",
+				"sub":     "github.com/jsmith",
+				"name":    "John Smith",
+				"picture": "https://avatars.githubusercontent.com/u/1234",
+				"metadata": map[string]interface{}{
+					"id": float64(1234),
+				},
+			},
+		},
+		{
+			name:   "facebook driver",
+			config: &Config{Driver: "facebook"},
+			profile: map[string]interface{}{
+				"id":    "1234",
+				"name":  "John Smith",
+				"email": "jsmith@contoso.com",
+			},
+			want: map[string]interface{}{
+				"origin": "https://graph.facebook.com/me",
+				"sub":    "1234",
+				"name":   "John Smith",
+				"email":  "jsmith@contoso.com",
+			},
+		},
+		{
+			name:   "discord driver",
+			config: &Config{Driver: "discord"},
+			profile: map[string]interface{}{
+				"id":       "1234",
+				"username": "jsmith",
+				"avatar":   "abcd",
+				"email":    "jsmith@contoso.com",
+			},
+			want: map[string]interface{}{
+				"origin":  "https://discord.com/api/v10/users/@me",
+				"sub":     "discord.com/1234",
+				"name":    "jsmith",
+				"picture": "https://cdn.discordapp.com/avatars/1234/abcd.png",
+				"email":   "jsmith@contoso.com",
+			},
+		},
+		{
+			name:   "patreon driver",
+			config: &Config{Driver: "patreon"},
+			profile: map[string]interface{}{
+				"data": map[string]interface{}{
+					"id":    "1234",
+					"email": "jsmith@contoso.com",
+				},
+				"included": []interface{}{
+					map[string]interface{}{
+						"id":         "5678",
+						"type":       "tier",
+						"attributes": map[string]interface{}{},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"origin": "https://www.patreon.com/api/oauth2/v2/identity",
+				"sub":    "patreon.com/user/1234",
+				"id":     "1234",
+				"email":  "jsmith@contoso.com",
+				"groups": []string{"patreon.com/tier/5678"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+
+			srv, err := tests.NewOAuthServer(testOAuthClientID, testOAuthClientSecret)
+			if err != nil {
+				t.Fatalf("failed starting oauth server: %v", err)
+			}
+			defer srv.Close()
+			srv.SetClaims(claims)
+			if tc.profile != nil {
+				srv.SetProfile(tc.profile)
+			}
+			if tc.quirks != nil {
+				srv.SetQuirks(tc.quirks)
+			}
+
+			tc.config.Name = tc.config.Driver
+			tc.config.Realm = tc.config.Driver
+			tc.config.ClientID = testOAuthClientID
+			if tc.config.ClientSecret == "" {
+				tc.config.ClientSecret = testOAuthClientSecret
+			}
+
+			prv, err := NewIdentityProvider(tc.config, logutil.NewLogger())
+			if tests.EvalErrWithLog(t, err, "NewIdentityProvider", false, nil, msgs) {
+				return
+			}
+			prv.browserConfig = &browserConfig{transport: srv.Transport()}
+			if tests.EvalErrWithLog(t, prv.Configure(), "IdentityProvider.Configure", false, nil, msgs) {
+				return
+			}
+
+			got, err := authenticateWithOAuthServer(prv, srv)
+			if tests.EvalErrWithLog(t, err, "IdentityProvider.Authenticate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			delete(got, "iat")
+			delete(got, "exp")
+			if v, ok := got["origin"].(string); ok {
+				got["origin"] = strings.TrimPrefix(v, srv.URL())
+			}
+			tests.EvalObjectsWithLog(t, "claims", tc.want, got, msgs)
+			if tc.wantKeyFetches > 0 {
+				tests.EvalObjectsWithLog(t, "jwks requests", tc.wantKeyFetches, srv.GetRequestCount("/jwks"), msgs)
+			}
+		})
+	}
+}
+
+// authenticateWithOAuthServer performs authorization code flow against
+// the mock authorization server and returns the claims of the user.
+func authenticateWithOAuthServer(prv *IdentityProvider, srv *tests.OAuthServer) (map[string]interface{}, error) {
+	reqURL := "https://localhost/auth/oauth2/" + prv.GetRealm()
+
+	r := newTestOAuthRequest(prv, reqURL)
+	if err := prv.Authenticate(r); err != nil {
+		return nil, err
+	}
+	if r.Response.Code != http.StatusFound {
+		return nil, fmt.Errorf("unexpected redirect response code: %d", r.Response.Code)
+	}
+
+	cli := &http.Client{
+		Transport: srv.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := cli.Get(r.Response.RedirectURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	callbackURL, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+	if callbackURL.Query().Get("code") == "" {
+		return nil, fmt.Errorf("authorization code not found in %q", callbackURL)
+	}
+
+	r = newTestOAuthRequest(prv, callbackURL.String())
+	if err := prv.Authenticate(r); err != nil {
+		return nil, err
+	}
+	return r.Response.Payload.(map[string]interface{}), nil
+}
+
+func newTestOAuthRequest(prv *IdentityProvider, reqURL string) *requests.Request {
+	r := requests.NewRequest()
+	r.ID = tests.NewID()
+	r.Upstream.BaseURL = "https://localhost"
+	r.Upstream.BasePath = "/auth"
+	r.Upstream.Method = "oauth2"
+	r.Upstream.Realm = prv.GetRealm()
+	r.Upstream.Request = httptest.NewRequest(http.MethodGet, reqURL, nil)
+	return r
+}
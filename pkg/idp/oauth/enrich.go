@@ -0,0 +1,78 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentEnrichments is the maximum number of the user enrichment
+// calls running concurrently.
+const maxConcurrentEnrichments = 4
+
+// userEnrichment is the call fetching additional user data, e.g. user info
+// or user groups, from the upstream APIs after the token exchange. The fetch
+// function must not modify the user data. Instead, it returns the function
+// merging the fetched data into the user data.
+type userEnrichment struct {
+	name  string
+	span  string
+	fetch func(context.Context, map[string]interface{}, map[string]interface{}) (func(map[string]interface{}), error)
+	merge func(map[string]interface{})
+}
+
+// enrichUserData runs the independent enrichment calls concurrently, each
+// bounded by the request timeout, and merges their results into the user
+// data in the order of the calls. The failed calls are logged and skipped.
+func (b *IdentityProvider) enrichUserData(ctx context.Context, requestID string, tokenData, userData map[string]interface{}, entries []*userEnrichment) {
+	g := new(errgroup.Group)
+	g.SetLimit(maxConcurrentEnrichments)
+	for _, entry := range entries {
+		entry := entry
+		g.Go(func() error {
+			callCtx := ctx
+			if b.requestTimeout > 0 {
+				var cancel context.CancelFunc
+				callCtx, cancel = context.WithTimeout(ctx, b.requestTimeout)
+				defer cancel()
+			}
+			spanCtx, span := tracing.Start(callCtx, entry.span, tracing.RealmKey.String(b.config.Realm))
+			merge, err := entry.fetch(spanCtx, tokenData, userData)
+			tracing.End(span, err)
+			if err != nil {
+				b.logger.Debug(
+					"failed fetching "+entry.name,
+					zap.String("request_id", requestID),
+					zap.Error(err),
+				)
+				return nil
+			}
+			entry.merge = merge
+			return nil
+		})
+	}
+	g.Wait()
+
+	for _, entry := range entries {
+		if entry.merge != nil {
+			entry.merge(userData)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestEnrichUserData(t *testing.T) {
+	b := &IdentityProvider{
+		config:         &Config{Realm: "contoso"},
+		logger:         logutil.NewLogger(),
+		requestTimeout: 50 * time.Millisecond,
+	}
+
+	newFetch := func(delay time.Duration, roles []string, err error) func(context.Context, map[string]interface{}, map[string]interface{}) (func(map[string]interface{}), error) {
+		return func(ctx context.Context, _, _ map[string]interface{}) (func(map[string]interface{}), error) {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return func(userData map[string]interface{}) {
+				if userRoles, exists := userData["roles"]; exists {
+					userData["roles"] = append(userRoles.([]string), roles...)
+				} else {
+					userData["roles"] = roles
+				}
+			}, nil
+		}
+	}
+
+	testcases := []struct {
+		name    string
+		entries []*userEnrichment
+		want    map[string]interface{}
+	}{
+		{
+			name: "merge results in order of calls",
+			entries: []*userEnrichment{
+				{name: "user info", fetch: newFetch(30*time.Millisecond, []string{"admin"}, nil)},
+				{name: "user groups", fetch: newFetch(10*time.Millisecond, []string{"editors"}, nil)},
+			},
+			want: map[string]interface{}{
+				"roles":   []string{"admin", "editors"},
+				"elapsed": true,
+			},
+		},
+		{
+			name: "skip failed and timed out calls",
+			entries: []*userEnrichment{
+				{name: "user info", fetch: newFetch(time.Second, []string{"admin"}, nil)},
+				{name: "user groups", fetch: newFetch(0, nil, fmt.Errorf("foobar"))},
+				{name: "user orgs", fetch: newFetch(0, []string{"viewer"}, nil)},
+			},
+			want: map[string]interface{}{
+				"roles":   []string{"viewer"},
+				"elapsed": true,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			userData := map[string]interface{}{"email": "jsmith@contoso.com"}
+			start := time.Now()
+			b.enrichUserData(context.Background(), "foo", map[string]interface{}{}, userData, tc.entries)
+			got := map[string]interface{}{
+				"roles": userData["roles"],
+				// The calls run concurrently and within the request timeout.
+				"elapsed": time.Since(start) < 45*time.Millisecond+b.requestTimeout,
+			}
+			tests.EvalObjectsWithLog(t, "user data", tc.want, got, msgs)
+		})
+	}
+}
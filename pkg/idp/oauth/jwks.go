@@ -26,8 +26,8 @@ import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/util"
-	"io/ioutil"
 	"math/big"
+	"os"
 	"strings"
 )
 
@@ -240,7 +240,7 @@ func NewJwksKeyFromRSAPrivateKey(privKey *rsa.PrivateKey) (*JwksKey, error) {
 
 // NewJwksKeyFromRSAPublicKeyPEM returns an instance of Jwks from RSA public key in PEM format.
 func NewJwksKeyFromRSAPublicKeyPEM(kid, fp string) (*JwksKey, error) {
-	kb, err := ioutil.ReadFile(fp)
+	kb, err := os.ReadFile(fp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,135 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPages is the maximum number of pages fetched from a paginated
+	// upstream API.
+	maxPages = 20
+	// maxPageItems is the maximum number of items, e.g. groups, fetched
+	// from a paginated upstream API.
+	maxPageItems = 2000
+)
+
+// pageHandler processes the body of a page. It returns the number of the
+// items in the page and, for the cursor-based APIs, the URL of the next page.
+type pageHandler func(body []byte) (int, string, error)
+
+// fetchPages fetches the pages of a paginated upstream API, starting with the
+// provided URL, and passes each page to the handler. The URL of the next page
+// is returned by the handler or, otherwise, is taken from the Link header or
+// the nextLink field of the page. The fetching stops when the page or the
+// item limit is reached.
+func (b *IdentityProvider) fetchPages(ctx context.Context, reqURL, authorization string, fn pageHandler) error {
+	cli, err := b.newBrowser()
+	if err != nil {
+		return err
+	}
+
+	var pages, items int
+	for reqURL != "" {
+		if pages >= maxPages || items >= maxPageItems {
+			b.logger.Warn(
+				"reached paginated response limit",
+				zap.String("identity_provider_name", b.config.Name),
+				zap.String("url", reqURL),
+				zap.Int("pages", pages),
+				zap.Int("items", items),
+			)
+			return nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Add("Authorization", authorization)
+
+		resp, err := cli.Do(req)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("failed fetching %s: status code %d", reqURL, resp.StatusCode)
+		}
+
+		b.logger.Debug(
+			"Received paginated response",
+			zap.String("url", reqURL),
+			zap.Any("body", body),
+		)
+
+		n, next, err := fn(body)
+		if err != nil {
+			return err
+		}
+		pages++
+		items += n
+		if next == "" {
+			next = getNextPageLink(resp.Header, body)
+		}
+		reqURL = next
+	}
+	return nil
+}
+
+// getNextPageLink returns the URL of the next page from the Link header,
+// e.g. GitHub and GitLab, or from the nextLink field of the page, e.g.
+// Microsoft Graph.
+func getNextPageLink(h http.Header, body []byte) string {
+	for _, link := range h.Values("Link") {
+		for _, entry := range strings.Split(link, ",") {
+			parts := strings.Split(entry, ";")
+			if len(parts) < 2 {
+				continue
+			}
+			for _, param := range parts[1:] {
+				if strings.TrimSpace(param) != `rel="next"` {
+					continue
+				}
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+
+	page := struct {
+		NextLink      string `json:"nextLink"`
+		ODataNextLink string `json:"@odata.nextLink"`
+	}{}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return ""
+	}
+	if page.ODataNextLink != "" {
+		return page.ODataNextLink
+	}
+	return page.NextLink
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestFetchPages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		switch r.URL.Path {
+		case "/link":
+			if page < 3 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/link?page=%d>; rel="next", <%s/link?page=3>; rel="last"`, srv.URL, page+1, srv.URL))
+			}
+			json.NewEncoder(w).Encode([]string{fmt.Sprintf("item%d", page)})
+		case "/nextlink":
+			resp := map[string]interface{}{"value": []string{fmt.Sprintf("item%d", page)}}
+			if page < 2 {
+				resp["@odata.nextLink"] = fmt.Sprintf("%s/nextlink?page=%d", srv.URL, page+1)
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/unbounded":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/unbounded?page=%d>; rel="next"`, srv.URL, page+1))
+			json.NewEncoder(w).Encode([]string{fmt.Sprintf("item%d", page)})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	b := &IdentityProvider{
+		config:         &Config{Name: "contoso"},
+		logger:         logutil.NewLogger(),
+		requestTimeout: 5 * time.Second,
+	}
+
+	testcases := []struct {
+		name      string
+		path      string
+		want      []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "fetch pages referenced in link header",
+			path: "/link",
+			want: []string{"item1", "item2", "item3"},
+		},
+		{
+			name: "fetch pages referenced in nextlink field",
+			path: "/nextlink",
+			want: []string{"item1", "item2"},
+		},
+		{
+			name: "stop fetching pages at page limit",
+			path: "/unbounded",
+			want: func() []string {
+				var items []string
+				for i := 1; i <= maxPages; i++ {
+					items = append(items, fmt.Sprintf("item%d", i))
+				}
+				return items
+			}(),
+		},
+		{
+			name:      "fail fetching unauthorized page",
+			path:      "/foo",
+			shouldErr: true,
+			err:       fmt.Errorf("failed fetching %s/foo: status code 401", srv.URL),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			var got []string
+			err := b.fetchPages(context.Background(), srv.URL+tc.path, "Bearer foo", func(body []byte) (int, string, error) {
+				var items []string
+				if err := json.Unmarshal(body, &items); err != nil {
+					page := struct {
+						Value []string `json:"value"`
+					}{}
+					if err := json.Unmarshal(body, &page); err != nil {
+						return 0, "", err
+					}
+					items = page.Value
+				}
+				got = append(got, items...)
+				return len(items), "", nil
+			})
+			if tests.EvalErrWithLog(t, err, "fetch pages", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "items", tc.want, got, msgs)
+		})
+	}
+}
@@ -15,13 +15,16 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/enrichment"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"regexp"
 	"strings"
@@ -56,6 +59,7 @@ type IdentityProvider struct {
 	// instance, the name could be gitlab.mydomain.com. It is derived from
 	// base url config entry.
 	serverName             string
+	requestTimeout         time.Duration
 	lastKeyFetch           time.Time
 	keyFetchAttempts       int
 	disableKeyVerification bool
@@ -75,6 +79,8 @@ type IdentityProvider struct {
 	configured    bool
 	// Disabled the check for the presence of email field in a token.
 	disableEmailClaimCheck bool
+	// The cache of the user group and org lookups.
+	groupCache *enrichment.Cache
 }
 
 // NewIdentityProvider returns an instance of IdentityProvider.
@@ -178,6 +184,11 @@ func (b *IdentityProvider) Configure() error {
 		b.tokenURL = b.config.TokenURL
 	}
 
+	b.requestTimeout = time.Duration(defaultRequestTimeout) * time.Second
+	if b.config.RequestTimeout > 0 {
+		b.requestTimeout = time.Duration(b.config.RequestTimeout) * time.Second
+	}
+
 	if b.config.TLSInsecureSkipVerify {
 		b.browserConfig = &browserConfig{
 			TLSInsecureSkipVerify: true,
@@ -241,6 +252,14 @@ func (b *IdentityProvider) Configure() error {
 		b.userOrgFilters = append(b.userOrgFilters, regexp.MustCompile(pattern))
 	}
 
+	if b.config.GroupCacheConfig != nil {
+		cache, err := enrichment.NewCache(b.config.GroupCacheConfig)
+		if err != nil {
+			return err
+		}
+		b.groupCache = cache
+	}
+
 	if b.config.DelayStart > 0 {
 		go b.fetchConfig()
 	} else {
@@ -261,6 +280,7 @@ func (b *IdentityProvider) Configure() error {
 		zap.Int("delayed_by", b.config.DelayStart),
 		zap.Int("retry_attempts", b.config.RetryAttempts),
 		zap.Int("retry_interval", b.config.RetryInterval),
+		zap.Duration("request_timeout", b.requestTimeout),
 		zap.Strings("scopes", b.config.Scopes),
 		zap.Any("login_icon", b.config.LoginIcon),
 	)
@@ -282,7 +302,7 @@ func (b *IdentityProvider) fetchConfig() error {
 	if b.authorizationURL == "" {
 		if b.config.RetryAttempts > 0 {
 			for i := 0; i < b.config.RetryAttempts; i++ {
-				err := b.fetchMetadataURL()
+				err := b.fetchMetadataURL(context.Background())
 				if err == nil {
 					break
 				}
@@ -298,7 +318,7 @@ func (b *IdentityProvider) fetchConfig() error {
 				time.Sleep(time.Duration(b.config.RetryInterval) * time.Second)
 			}
 		} else {
-			if err := b.fetchMetadataURL(); err != nil {
+			if err := b.fetchMetadataURL(context.Background()); err != nil {
 				b.logger.Debug(
 					"fetchMetadataURL failed",
 					zap.String("identity_provider_name", b.config.Name),
@@ -318,7 +338,7 @@ func (b *IdentityProvider) fetchConfig() error {
 	if !b.disableKeyVerification {
 		if b.config.RetryAttempts > 0 {
 			for i := 0; i < b.config.RetryAttempts; i++ {
-				err := b.fetchKeysURL()
+				err := b.fetchKeysURL(context.Background())
 				if err == nil {
 					break
 				}
@@ -334,7 +354,7 @@ func (b *IdentityProvider) fetchConfig() error {
 				time.Sleep(time.Duration(b.config.RetryInterval) * time.Second)
 			}
 		} else {
-			if err := b.fetchKeysURL(); err != nil {
+			if err := b.fetchKeysURL(context.Background()); err != nil {
 				return errors.ErrIdentityProviderOauthKeyFetchFailed.WithArgs(err)
 			}
 		}
@@ -342,17 +362,20 @@ func (b *IdentityProvider) fetchConfig() error {
 	return nil
 }
 
-func (b *IdentityProvider) fetchMetadataURL() error {
+func (b *IdentityProvider) fetchMetadataURL(ctx context.Context) error {
 	cli, err := b.newBrowser()
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("GET", b.config.MetadataURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.config.MetadataURL, nil)
+	if err != nil {
+		return err
+	}
 	resp, err := cli.Do(req)
 	if err != nil {
 		return err
 	}
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return err
@@ -388,7 +411,7 @@ func (b *IdentityProvider) countFetchKeysAttempt() {
 	return
 }
 
-func (b *IdentityProvider) fetchKeysURL() error {
+func (b *IdentityProvider) fetchKeysURL(ctx context.Context) error {
 	if b.keyFetchAttempts > 3 {
 		timeDiff := time.Now().UTC().Sub(b.lastKeyFetch).Minutes()
 		if timeDiff < 5 {
@@ -404,7 +427,7 @@ func (b *IdentityProvider) fetchKeysURL() error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("GET", b.keysURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.keysURL, nil)
 	if err != nil {
 		return err
 	}
@@ -415,7 +438,7 @@ func (b *IdentityProvider) fetchKeysURL() error {
 		return err
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return err
@@ -480,3 +503,11 @@ func (b *IdentityProvider) GetIdentityTokenCookieName() string {
 	}
 	return ""
 }
+
+// getClientSecret returns the client secret of the provider. When the secret
+// is a reference, e.g. {env.OAUTH_CLIENT_SECRET} or {vault:kv/data/idp#secret},
+// it is resolved on every call, so that the rotated secrets are picked up
+// without restart.
+func (b *IdentityProvider) getClientSecret() (string, error) {
+	return credentials.ResolveSecret(b.config.ClientSecret)
+}
@@ -15,81 +15,71 @@
 package oauth
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
+// discordGuildsPageSize is the maximum number of guilds returned by Discord
+// API per page.
+const discordGuildsPageSize = 200
+
 type userData struct {
 	Groups []string `json:"groups,omitempty"`
 }
 
-func (b *IdentityProvider) fetchGithubUserInfo(params map[string]interface{}) (*userData, error) {
-	var req *http.Request
-	var reqMethod, reqURL, authToken string
+func (b *IdentityProvider) fetchGithubUserInfo(ctx context.Context, params map[string]interface{}) (*userData, error) {
+	var reqURL, authToken string
 	data := &userData{}
 	reqURL = params["url"].(string)
-	if _, exists := params["method"]; exists {
-		reqMethod = params["method"].(string)
-	} else {
-		reqMethod = "GET"
-	}
 	authToken = params["token"].(string)
 
-	// Create new http client instance.
-	cli, err := b.newBrowser()
-	if err != nil {
-		return nil, err
-	}
-	req, err = http.NewRequest(reqMethod, reqURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Add("Authorization", "token "+authToken)
-
-	// Fetch data from the URL.
-	resp, err := cli.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	respBody, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	// Request the maximum page size, the subsequent pages are referenced
+	// in the Link header.
+	u, err := url.Parse(reqURL)
 	if err != nil {
 		return nil, err
 	}
-
-	b.logger.Debug("Additional user data received", zap.String("url", reqURL), zap.Any("body", respBody))
-
-	orgs := []map[string]interface{}{}
-	if err := json.Unmarshal(respBody, &orgs); err != nil {
-		return nil, err
-	}
-	for _, org := range orgs {
-		if _, exists := org["login"]; !exists {
-			continue
+	q := u.Query()
+	q.Set("per_page", "100")
+	u.RawQuery = q.Encode()
+
+	err = b.fetchPages(ctx, u.String(), "token "+authToken, func(respBody []byte) (int, string, error) {
+		orgs := []map[string]interface{}{}
+		if err := json.Unmarshal(respBody, &orgs); err != nil {
+			return 0, "", err
 		}
-		orgName := org["login"].(string)
-		// Exclude org from processing if it does not match org filters.
-		included := false
-		for _, rp := range b.userOrgFilters {
-			if rp.MatchString(orgName) {
-				included = true
-				break
+		for _, org := range orgs {
+			if _, exists := org["login"]; !exists {
+				continue
 			}
+			orgName := org["login"].(string)
+			// Exclude org from processing if it does not match org filters.
+			included := false
+			for _, rp := range b.userOrgFilters {
+				if rp.MatchString(orgName) {
+					included = true
+					break
+				}
+			}
+			if !included {
+				continue
+			}
+			data.Groups = append(data.Groups, fmt.Sprintf("github.com/%s/members", orgName))
 		}
-		if !included {
-			continue
-		}
-		data.Groups = append(data.Groups, fmt.Sprintf("github.com/%s/members", orgName))
+		return len(orgs), "", nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	b.logger.Debug(
@@ -101,7 +91,7 @@ func (b *IdentityProvider) fetchGithubUserInfo(params map[string]interface{}) (*
 	return data, nil
 }
 
-func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[string]interface{}, error) {
+func (b *IdentityProvider) fetchClaims(ctx context.Context, tokenData map[string]interface{}) (map[string]interface{}, error) {
 	var userURL string
 	var req *http.Request
 	var err error
@@ -136,7 +126,7 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 	// Setup http request for the URL.
 	switch b.config.Driver {
 	case "github", "gitlab", "discord":
-		req, err = http.NewRequest("GET", userURL, nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", userURL, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -147,13 +137,17 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 		params.Set("fields[member]", "full_name,is_follower,patron_status,currently_entitled_amount_cents,campaign_lifetime_support_cents")
 		params.Set("fields[tier]", "title")
 		params.Set("fields[benefit]", "title")
-		req, err = http.NewRequest("GET", userURL, nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", userURL, nil)
 		if err != nil {
 			return nil, err
 		}
 		req.URL.RawQuery = params.Encode()
 	case "facebook":
-		h := hmac.New(sha256.New, []byte(b.config.ClientSecret))
+		clientSecret, err := b.getClientSecret()
+		if err != nil {
+			return nil, err
+		}
+		h := hmac.New(sha256.New, []byte(clientSecret))
 		h.Write([]byte(tokenString))
 		appSecretProof := hex.EncodeToString(h.Sum(nil))
 		params := url.Values{}
@@ -161,7 +155,7 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 		params.Set("fields", "id,first_name,last_name,name,email")
 		params.Set("access_token", tokenString)
 		params.Set("appsecret_proof", appSecretProof)
-		req, err = http.NewRequest("GET", userURL, nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", userURL, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -185,7 +179,7 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 		return nil, err
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
 		return nil, err
@@ -287,7 +281,13 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 				"token":    tokenString,
 				"username": data["login"].(string),
 			}
-			userData, err := b.fetchGithubUserInfo(params)
+			orgGroups, err := b.lookupGroups([]string{"github.com/" + data["login"].(string)}, func() ([]string, error) {
+				userData, err := b.fetchGithubUserInfo(ctx, params)
+				if err != nil {
+					return nil, err
+				}
+				return userData.Groups, nil
+			})
 			if err != nil {
 				b.logger.Error(
 					"Failed extracting user org data",
@@ -295,11 +295,11 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 					zap.Error(err),
 				)
 			} else {
-				userGroups = append(userGroups, userData.Groups...)
+				userGroups = append(userGroups, orgGroups...)
 				b.logger.Debug(
 					"Successfully extracted user org data",
 					zap.String("identity_provider_name", b.config.Name),
-					zap.Any("extracted", userData),
+					zap.Any("extracted", orgGroups),
 				)
 			}
 		}
@@ -358,7 +358,17 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 			m["email"] = data["email"]
 		}
 		if b.ScopeExists("guilds") {
-			userData, err := b.fetchDiscordGuilds(tokenString)
+			guildKeys := []string{m["sub"].(string)}
+			if v, ok := m["email"].(string); ok {
+				guildKeys = append(guildKeys, v)
+			}
+			guildGroups, err := b.lookupGroups(guildKeys, func() ([]string, error) {
+				userData, err := b.fetchDiscordGuilds(ctx, tokenString)
+				if err != nil {
+					return nil, err
+				}
+				return userData.Groups, nil
+			})
 			if err != nil {
 				b.logger.Error(
 					"Failed extracting user guild data",
@@ -366,7 +376,7 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 					zap.Error(err),
 				)
 			} else {
-				userGroups = append(userGroups, userData.Groups...)
+				userGroups = append(userGroups, guildGroups...)
 			}
 		}
 		b.logger.Debug(
@@ -424,72 +434,58 @@ func (b *IdentityProvider) fetchClaims(tokenData map[string]interface{}) (map[st
 	return m, nil
 }
 
-func (b *IdentityProvider) fetchDiscordGuilds(authToken string) (*userData, error) {
-	var req *http.Request
+func (b *IdentityProvider) fetchDiscordGuilds(ctx context.Context, authToken string) (*userData, error) {
 	reqURL := "https://discord.com/api/v10/users/@me/guilds"
 	data := &userData{}
 
-	// Create new http client instance.
-	cli, err := b.newBrowser()
-	if err != nil {
-		return nil, err
-	}
-
-	req, err = http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Add("Authorization", "Bearer " + authToken)
-
-	// Fetch data from the URL.
-	resp, err := cli.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	respBody, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	b.logger.Debug(
-		"Received user guild infomation",
-		zap.String("url", reqURL),
-		zap.Any("body", respBody),
-	)
-
-	guilds := []map[string]interface{}{}
-	if err := json.Unmarshal(respBody, &guilds); err != nil {
-		return nil, err
-	}
-
-	for _, guild := range guilds {
-		guildID := guild["id"].(string)
-		// Exclude org from processing if it does not match org filters.
-		included := false
-		for _, rp := range b.userGroupFilters {
-			if rp.MatchString(guildID) {
-				included = true
-				break
-			}
-		}
-		if !included {
-			continue
+	// The guilds are paginated with the after cursor, i.e. the ID of the
+	// last guild of the previous page.
+	err := b.fetchPages(ctx, reqURL+"?limit="+strconv.Itoa(discordGuildsPageSize), "Bearer "+authToken, func(respBody []byte) (int, string, error) {
+		guilds := []map[string]interface{}{}
+		if err := json.Unmarshal(respBody, &guilds); err != nil {
+			return 0, "", err
 		}
 
-		// Check if the user has special permissions
-		if _, exists := guild["permissions"]; exists {
-			perm, err := strconv.Atoi(guild["permissions"].(string))
-			if err != nil {
+		var lastGuildID string
+		for _, guild := range guilds {
+			guildID, ok := guild["id"].(string)
+			if !ok {
 				continue
 			}
-			if (perm & 0x08) == 0x08 { // Check for admin privileges
-				data.Groups = append(data.Groups, fmt.Sprintf("discord.com/%s/admins", guildID))
+			lastGuildID = guildID
+			// Exclude org from processing if it does not match org filters.
+			included := false
+			for _, rp := range b.userGroupFilters {
+				if rp.MatchString(guildID) {
+					included = true
+					break
+				}
 			}
+			if !included {
+				continue
+			}
+
+			// Check if the user has special permissions
+			if _, exists := guild["permissions"]; exists {
+				perm, err := strconv.Atoi(guild["permissions"].(string))
+				if err != nil {
+					continue
+				}
+				if (perm & 0x08) == 0x08 { // Check for admin privileges
+					data.Groups = append(data.Groups, fmt.Sprintf("discord.com/%s/admins", guildID))
+				}
+			}
+
+			data.Groups = append(data.Groups, fmt.Sprintf("discord.com/%s/members", guildID))
 		}
 
-		data.Groups = append(data.Groups, fmt.Sprintf("discord.com/%s/members", guildID))
+		if len(guilds) < discordGuildsPageSize || lastGuildID == "" {
+			return len(guilds), "", nil
+		}
+		return len(guilds), reqURL + "?limit=" + strconv.Itoa(discordGuildsPageSize) + "&after=" + url.QueryEscape(lastGuildID), nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	b.logger.Debug(
@@ -15,13 +15,10 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
-
-	"go.uber.org/zap"
 )
 
 type googleResponse struct {
@@ -32,84 +29,84 @@ type googleResponse struct {
 	} `json:"response"`
 }
 
-func (b *IdentityProvider) fetchUserGroups(tokenData, userData map[string]interface{}) error {
-	var userURL string
-	var req *http.Request
-	var err error
-
+// fetchUserGroups fetches the groups of the user. It returns the function
+// adding the groups to the roles in the user data.
+func (b *IdentityProvider) fetchUserGroups(ctx context.Context, tokenData, userData map[string]interface{}) (func(map[string]interface{}), error) {
 	if b.config.Driver != "google" || !b.ScopeExists(
 		"https://www.googleapis.com/auth/cloud-identity.groups.readonly",
 		"https://www.googleapis.com/auth/cloud-identity.groups",
 	) {
-		return nil
+		return nil, nil
 	}
 	if tokenData == nil || userData == nil {
-		return nil
+		return nil, nil
 	}
 
 	if _, exists := tokenData["access_token"]; !exists {
-		return fmt.Errorf("access_token not found")
+		return nil, fmt.Errorf("access_token not found")
 	}
 
-	cli, err := b.newBrowser()
+	email := userData["email"].(string)
+	userGroups, err := b.lookupGroups([]string{email}, func() ([]string, error) {
+		return b.fetchGoogleGroups(ctx, tokenData["access_token"].(string), email)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return func(userData map[string]interface{}) {
+		if userRoles, exists := userData["roles"]; exists {
+			userData["roles"] = append(userRoles.([]string), userGroups...)
+		} else {
+			userData["roles"] = userGroups
+		}
+	}, nil
+}
+
+// fetchGoogleGroups returns the names of the Google groups the user with
+// the provided email address is a member of.
+func (b *IdentityProvider) fetchGoogleGroups(ctx context.Context, accessToken, email string) ([]string, error) {
+	var userURL string
+
 	switch b.config.Driver {
 	case "google":
 		userURL = "https://cloudidentity.googleapis.com/v1/groups/-/memberships:getMembershipGraph?query="
-		userURL += url.QueryEscape("'cloudidentity.googleapis.com/groups.discussion_forum' in labels && member_key_id=='" + userData["email"].(string) + "'")
-
-		req, err = http.NewRequest("GET", userURL, nil)
-		if err != nil {
-			return err
-		}
-		req.Header.Add("Authorization", "Bearer "+tokenData["access_token"].(string))
+		userURL += url.QueryEscape("'cloudidentity.googleapis.com/groups.discussion_forum' in labels && member_key_id=='" + email + "'")
 	default:
-		return fmt.Errorf("provider %s is unsupported for fetching user groups", b.config.Driver)
-	}
-
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := cli.Do(req)
-	if err != nil {
-		return err
+		return nil, fmt.Errorf("provider %s is unsupported for fetching user groups", b.config.Driver)
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		return err
-	}
-
-	b.logger.Debug(
-		"User groups received",
-		zap.Any("body", respBody),
-		zap.String("url", userURL),
-	)
-
-	switch b.config.Driver {
-	case "google":
+	userGroups := []string{}
+	err := b.fetchPages(ctx, userURL, "Bearer "+accessToken, func(respBody []byte) (int, string, error) {
 		var respParsed googleResponse
-		err = json.Unmarshal(respBody, &respParsed)
-		if err != nil {
-			return err
+		if err := json.Unmarshal(respBody, &respParsed); err != nil {
+			return 0, "", err
 		}
-		userGroups := []string{}
 		for _, group := range respParsed.Response.Groups {
 			userGroups = append(userGroups, group.DisplayName)
 		}
+		return len(respParsed.Response.Groups), "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userGroups, nil
+}
 
-		if userRoles, exists := userData["roles"]; exists {
-			userData["roles"] = append(userRoles.([]string), userGroups...)
-		} else {
-			userData["roles"] = userGroups
-		}
-
-	default:
-		return fmt.Errorf("provider %s is unsupported for fetching user groups", b.config.Driver)
+// lookupGroups returns the groups of the user from the group cache, when
+// configured. Otherwise, it calls the lookup function.
+func (b *IdentityProvider) lookupGroups(keys []string, fn func() ([]string, error)) ([]string, error) {
+	if b.groupCache == nil {
+		return fn()
 	}
+	return b.groupCache.Lookup(keys, fn)
+}
 
-	return nil
+// InvalidateGroupCache deletes the cached groups of the user. When the
+// user is empty, it deletes the cached groups of all users.
+func (b *IdentityProvider) InvalidateGroupCache(user string) int {
+	if b.groupCache == nil {
+		return 0
+	}
+	return b.groupCache.Invalidate(user)
 }
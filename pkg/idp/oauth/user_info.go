@@ -15,40 +15,43 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 	"go.uber.org/zap"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"strings"
 )
 
-func (b *IdentityProvider) fetchUserInfo(tokenData, userData map[string]interface{}) error {
+// fetchUserInfo fetches the user info of the user. It returns the function
+// merging the user info into the user data.
+func (b *IdentityProvider) fetchUserInfo(ctx context.Context, tokenData, userData map[string]interface{}) (func(map[string]interface{}), error) {
 	// The fetching of user info happens only if the below conditions are
 	// are met.
 	if b.config.Driver != "generic" || !b.ScopeExists("openid") || b.userInfoURL == "" {
-		return nil
+		return nil, nil
 	}
 	if len(b.userInfoFields) == 0 {
-		return nil
+		return nil, nil
 	}
 	if tokenData == nil || userData == nil {
-		return nil
+		return nil, nil
 	}
 	if _, exists := tokenData["access_token"]; !exists {
-		return fmt.Errorf("access_token not found")
+		return nil, fmt.Errorf("access_token not found")
 	}
 
 	// Initialize HTTP client.
 	cli, err := b.newBrowser()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", b.userInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", b.userInfoURL, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("Accept", "application/json")
@@ -57,13 +60,13 @@ func (b *IdentityProvider) fetchUserInfo(tokenData, userData map[string]interfac
 	// Fetch data from the URL.
 	resp, err := cli.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	b.logger.Debug(
@@ -74,7 +77,7 @@ func (b *IdentityProvider) fetchUserInfo(tokenData, userData map[string]interfac
 
 	userinfo := make(map[string]interface{})
 	if err := json.Unmarshal(respBody, &userinfo); err != nil {
-		return err
+		return nil, err
 	}
 
 	b.logger.Debug(
@@ -86,9 +89,6 @@ func (b *IdentityProvider) fetchUserInfo(tokenData, userData map[string]interfac
 	var roles []string
 	if _, exists := b.userInfoFields["all"]; exists {
 		roles = extractUserInfoRoles(userinfo)
-		if len(userinfo) > 0 {
-			userData["userinfo"] = userinfo
-		}
 	} else {
 		for k := range userinfo {
 			if _, exists := b.userInfoFields[k]; !exists {
@@ -97,16 +97,17 @@ func (b *IdentityProvider) fetchUserInfo(tokenData, userData map[string]interfac
 		}
 		if len(userinfo) > 0 {
 			roles = extractUserInfoRoles(userinfo)
-			if len(userinfo) > 0 {
-				userData["userinfo"] = userinfo
-			}
 		}
 	}
 
-	if len(roles) > 0 {
-		userData["roles"] = roles
-	}
-	return nil
+	return func(userData map[string]interface{}) {
+		if len(userinfo) > 0 {
+			userData["userinfo"] = userinfo
+		}
+		if len(roles) > 0 {
+			userData["roles"] = roles
+		}
+	}, nil
 }
 
 func extractUserInfoRoles(m map[string]interface{}) []string {
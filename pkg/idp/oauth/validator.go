@@ -15,6 +15,7 @@
 package oauth
 
 import (
+	"context"
 	"fmt"
 	jwtlib "github.com/golang-jwt/jwt/v4"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
@@ -31,7 +32,7 @@ var (
 	}
 )
 
-func (b *IdentityProvider) validateAccessToken(state string, data map[string]interface{}) (map[string]interface{}, error) {
+func (b *IdentityProvider) validateAccessToken(ctx context.Context, state string, data map[string]interface{}) (map[string]interface{}, error) {
 	var tokenString string
 	if v, exists := data[b.config.IdentityTokenName]; exists {
 		tokenString = v.(string)
@@ -64,7 +65,7 @@ func (b *IdentityProvider) validateAccessToken(state string, data map[string]int
 		key, exists := b.keys[keyID]
 		if !exists {
 			if !b.disableKeyVerification {
-				if err := b.fetchKeysURL(); err != nil {
+				if err := b.fetchKeysURL(ctx); err != nil {
 					return nil, errors.ErrIdentityProviderOauthKeyFetchFailed.WithArgs(err)
 				}
 			}
@@ -20,6 +20,8 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/idp/kerberos"
+	"github.com/greenpau/go-authcrunch/pkg/idp/mtls"
 	"github.com/greenpau/go-authcrunch/pkg/idp/oauth"
 	"github.com/greenpau/go-authcrunch/pkg/idp/saml"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
@@ -71,6 +73,22 @@ func NewIdentityProvider(cfg *IdentityProviderConfig, logger *zap.Logger) (Ident
 		}
 		config.Name = cfg.Name
 		p, err = saml.NewIdentityProvider(config, logger)
+	case "kerberos":
+		config := &kerberos.Config{}
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, errors.ErrIdentityProviderNewConfig.WithArgs(cfg.Params, err)
+		}
+		config.Name = cfg.Name
+		p, err = kerberos.NewIdentityProvider(config, logger)
+	case "mtls":
+		config := &mtls.Config{}
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, errors.ErrIdentityProviderNewConfig.WithArgs(cfg.Params, err)
+		}
+		config.Name = cfg.Name
+		p, err = mtls.NewIdentityProvider(config, logger)
+	default:
+		p, err = getIdentityProviderDriver(cfg.Kind).NewIdentityProvider(cfg, logger)
 	}
 
 	if err != nil {
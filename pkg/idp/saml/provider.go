@@ -23,13 +23,14 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
 	"go.uber.org/zap"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -124,7 +125,7 @@ func (b *IdentityProvider) Configure() error {
 		opts.URL = *idpMetadataURL
 		idpMetadata, err := samlsp.FetchMetadata(
 			context.Background(),
-			http.DefaultClient,
+			httpclient.NewClient(nil, 10*time.Second),
 			*idpMetadataURL,
 		)
 		if err != nil {
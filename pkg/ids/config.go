@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids/external"
 	"github.com/greenpau/go-authcrunch/pkg/ids/ldap"
 	"github.com/greenpau/go-authcrunch/pkg/ids/local"
 )
@@ -61,7 +62,9 @@ func (cfg *IdentityStoreConfig) Validate() error {
 			"registration_enabled",
 			"username_recovery_enabled",
 			"password_recovery_enabled",
+			"magic_link_enabled",
 			"contact_support_enabled",
+			"read_only",
 			"support_link",
 			"support_email",
 		}
@@ -88,10 +91,31 @@ func (cfg *IdentityStoreConfig) Validate() error {
 			"support_email",
 			"fallback_roles",
 		}
+	case "external":
+		requiredFields = []string{
+			"realm",
+			"url",
+			"secret",
+		}
+		optionalFields = []string{
+			"timeout",
+			"tls_insecure_skip_verify",
+			"login_icon",
+			"contact_support_enabled",
+			"support_link",
+			"support_email",
+		}
 	case "":
 		return errors.ErrIdentityStoreConfigInvalid.WithArgs("empty identity store type")
 	default:
-		return errors.ErrIdentityStoreConfigInvalid.WithArgs("unsupported identity store type " + cfg.Kind)
+		driver := getIdentityStoreDriver(cfg.Kind)
+		if driver == nil {
+			return errors.ErrIdentityStoreConfigInvalid.WithArgs("unsupported identity store type " + cfg.Kind)
+		}
+		if err := driver.ValidateConfig(cfg); err != nil {
+			return errors.ErrIdentityStoreConfigInvalid.WithArgs(err)
+		}
+		return nil
 	}
 
 	if err := validateFields(cfg.Params, requiredFields, optionalFields); err != nil {
@@ -114,6 +138,13 @@ func (cfg *IdentityStoreConfig) Validate() error {
 		if err := config.Validate(); err != nil {
 			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
 		}
+	case "external":
+		config := &external.Config{}
+		json.Unmarshal(b, config)
+		config.Name = cfg.Name
+		if err := config.Validate(); err != nil {
+			return errors.ErrIdentityProviderConfigInvalid.WithArgs(err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,78 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ids
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// IdentityStoreDriver creates the identity stores of a kind not built into
+// this package. The drivers are compiled into the embedding binary and
+// registered with RegisterIdentityStoreDriver, typically from the init
+// function of the package providing the driver.
+type IdentityStoreDriver interface {
+	// ValidateConfig validates the parameters of the identity store config.
+	ValidateConfig(*IdentityStoreConfig) error
+	// NewIdentityStore returns an instance of the identity store.
+	NewIdentityStore(*IdentityStoreConfig, *zap.Logger) (IdentityStore, error)
+}
+
+var (
+	builtinStoreKinds = map[string]bool{
+		"local":    true,
+		"ldap":     true,
+		"external": true,
+	}
+	storeDrivers   = make(map[string]IdentityStoreDriver)
+	storeDriversMu sync.RWMutex
+)
+
+// RegisterIdentityStoreDriver registers the driver of the identity stores of
+// the provided kind. The built-in kinds cannot be overridden.
+func RegisterIdentityStoreDriver(kind string, driver IdentityStoreDriver) error {
+	if kind == "" || driver == nil {
+		return errors.ErrIdentityStoreDriverInvalid.WithArgs(kind)
+	}
+	storeDriversMu.Lock()
+	defer storeDriversMu.Unlock()
+	if _, exists := storeDrivers[kind]; exists || builtinStoreKinds[kind] {
+		return errors.ErrIdentityStoreDriverRegistered.WithArgs(kind)
+	}
+	storeDrivers[kind] = driver
+	return nil
+}
+
+// GetIdentityStoreDriverKinds returns the kinds of the registered identity
+// store drivers.
+func GetIdentityStoreDriverKinds() []string {
+	storeDriversMu.RLock()
+	defer storeDriversMu.RUnlock()
+	var kinds []string
+	for kind := range storeDrivers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func getIdentityStoreDriver(kind string) IdentityStoreDriver {
+	storeDriversMu.RLock()
+	defer storeDriversMu.RUnlock()
+	return storeDrivers[kind]
+}
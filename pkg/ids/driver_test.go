@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ids
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"go.uber.org/zap"
+)
+
+type testStoreDriver struct{}
+
+type testStore struct {
+	name  string
+	realm string
+}
+
+func (d *testStoreDriver) ValidateConfig(cfg *IdentityStoreConfig) error {
+	if _, exists := cfg.Params["realm"]; !exists {
+		return fmt.Errorf("realm not found")
+	}
+	return nil
+}
+
+func (d *testStoreDriver) NewIdentityStore(cfg *IdentityStoreConfig, logger *zap.Logger) (IdentityStore, error) {
+	return &testStore{name: cfg.Name, realm: cfg.Params["realm"].(string)}, nil
+}
+
+func (s *testStore) GetRealm() string                               { return s.realm }
+func (s *testStore) GetName() string                                { return s.name }
+func (s *testStore) GetKind() string                                { return "test_driver" }
+func (s *testStore) GetConfig() map[string]interface{}              { return nil }
+func (s *testStore) Configure() error                               { return nil }
+func (s *testStore) Configured() bool                               { return true }
+func (s *testStore) Request(operator.Type, *requests.Request) error { return nil }
+func (s *testStore) GetLoginIcon() *icons.LoginIcon                 { return nil }
+
+func TestIdentityStoreDriver(t *testing.T) {
+	if err := RegisterIdentityStoreDriver("test_driver", &testStoreDriver{}); err != nil {
+		t.Fatalf("failed registering driver: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		kind      string
+		driver    IdentityStoreDriver
+		params    map[string]interface{}
+		register  bool
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "test register driver with empty kind",
+			driver:    &testStoreDriver{},
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreDriverInvalid.WithArgs(""),
+		},
+		{
+			name:      "test register nil driver",
+			kind:      "foo",
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreDriverInvalid.WithArgs("foo"),
+		},
+		{
+			name:      "test register driver of built-in kind",
+			kind:      "local",
+			driver:    &testStoreDriver{},
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreDriverRegistered.WithArgs("local"),
+		},
+		{
+			name:      "test register driver twice",
+			kind:      "test_driver",
+			driver:    &testStoreDriver{},
+			register:  true,
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreDriverRegistered.WithArgs("test_driver"),
+		},
+		{
+			name: "test new identity store with registered driver",
+			kind: "test_driver",
+			params: map[string]interface{}{
+				"realm": "contoso.com",
+			},
+			want: map[string]interface{}{
+				"name":  "default",
+				"kind":  "test_driver",
+				"realm": "contoso.com",
+				"kinds": []string{"test_driver"},
+			},
+		},
+		{
+			name:      "test new identity store with invalid config",
+			kind:      "test_driver",
+			params:    map[string]interface{}{},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreConfigInvalid.WithArgs(fmt.Errorf("realm not found")),
+		},
+		{
+			name: "test new identity store with unregistered driver",
+			kind: "foo",
+			params: map[string]interface{}{
+				"realm": "contoso.com",
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreConfigInvalid.WithArgs("unsupported identity store type foo"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			if tc.register {
+				err := RegisterIdentityStoreDriver(tc.kind, tc.driver)
+				tests.EvalErrWithLog(t, err, "register driver", tc.shouldErr, tc.err, msgs)
+				return
+			}
+			cfg, err := NewIdentityStoreConfig("default", tc.kind, tc.params)
+			if tests.EvalErrWithLog(t, err, "identity store config", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			st, err := NewIdentityStore(cfg, logutil.NewLogger())
+			if tests.EvalErrWithLog(t, err, "identity store", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"name":  st.GetName(),
+				"kind":  st.GetKind(),
+				"realm": st.GetRealm(),
+				"kinds": GetIdentityStoreDriverKinds(),
+			}
+			tests.EvalObjectsWithLog(t, "identity store", tc.want, got, msgs)
+		})
+	}
+}
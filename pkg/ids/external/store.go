@@ -0,0 +1,304 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	storeKind = "external"
+
+	// defaultTimeout is the number of seconds to wait for a response from
+	// the webhook.
+	defaultTimeout = 10
+)
+
+var (
+	emailRegexPattern    = regexp.MustCompile("^[a-zA-Z0-9.+\\._~-]{1,61}@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+	usernameRegexPattern = regexp.MustCompile("^[a-zA-Z0-9.+\\._~-]{1,61}$")
+)
+
+// Config holds the configuration for the IdentityStore.
+type Config struct {
+	Name  string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// URL is the HTTPS endpoint verifying the credentials of the users.
+	URL string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	// Secret is the shared secret signing the requests to and the
+	// responses from the endpoint. It may reference a secret, e.g.
+	// {env.WEBHOOK_SECRET}.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	// Timeout is the number of seconds to wait for a response from the
+	// endpoint. Defaults to 10.
+	Timeout int `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// TLSInsecureSkipVerify disables the verification of the certificate
+	// of the endpoint.
+	TLSInsecureSkipVerify bool `json:"tls_insecure_skip_verify,omitempty" xml:"tls_insecure_skip_verify,omitempty" yaml:"tls_insecure_skip_verify,omitempty"`
+
+	// LoginIcon is the UI login icon attributes.
+	LoginIcon *icons.LoginIcon `json:"login_icon,omitempty" xml:"login_icon,omitempty" yaml:"login_icon,omitempty"`
+
+	// ContactSupportEnabled controls whether contact support link is available.
+	ContactSupportEnabled bool `json:"contact_support_enabled,omitempty" xml:"contact_support_enabled,omitempty" yaml:"contact_support_enabled,omitempty"`
+	// SupportLink is the link to the support portal.
+	SupportLink string `json:"support_link,omitempty" xml:"support_link,omitempty" yaml:"support_link,omitempty"`
+	// SupportEmail is the email address to reach support.
+	SupportEmail string `json:"support_email,omitempty" xml:"support_email,omitempty" yaml:"support_email,omitempty"`
+}
+
+// IdentityStore represents authentication provider delegating the
+// verification of the credentials to an external endpoint.
+type IdentityStore struct {
+	config     *Config `json:"-"`
+	webhook    *webhook
+	logger     *zap.Logger
+	configured bool
+}
+
+// NewIdentityStore return an instance of external identity store.
+func NewIdentityStore(cfg *Config, logger *zap.Logger) (*IdentityStore, error) {
+	if logger == nil {
+		return nil, errors.ErrIdentityStoreConfigureLoggerNotFound
+	}
+
+	b := &IdentityStore{
+		config: cfg,
+		logger: logger,
+	}
+
+	if err := b.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetRealm return authentication realm.
+func (b *IdentityStore) GetRealm() string {
+	return b.config.Realm
+}
+
+// GetName return the name associated with this identity store.
+func (b *IdentityStore) GetName() string {
+	return b.config.Name
+}
+
+// GetKind returns the authentication method associated with this identity store.
+func (b *IdentityStore) GetKind() string {
+	return storeKind
+}
+
+// Configured returns true if the identity store was configured.
+func (b *IdentityStore) Configured() bool {
+	return b.configured
+}
+
+// Request performs the requested identity store operation.
+func (b *IdentityStore) Request(op operator.Type, r *requests.Request) error {
+	_, span := tracing.Start(r.GetContext(), "ids.Request",
+		tracing.RealmKey.String(b.GetRealm()),
+		tracing.MethodKey.String(b.GetKind()),
+		tracing.OperationKey.String(op.String()),
+	)
+	err := b.request(op, r)
+	tracing.End(span, err)
+	return err
+}
+
+func (b *IdentityStore) request(op operator.Type, r *requests.Request) error {
+	switch op {
+	case operator.Authenticate:
+		return b.Authenticate(r)
+	case operator.IdentifyUser:
+		return b.IdentifyUser(r)
+	case operator.ChangePassword:
+		return errors.ErrOperatorNotAvailable.WithArgs(op)
+	}
+	return errors.ErrOperatorNotSupported.WithArgs(op)
+}
+
+// Authenticate performs authentication.
+func (b *IdentityStore) Authenticate(r *requests.Request) error {
+	if !isValidUsername(r.User.Username) || r.User.Password == "" {
+		return errors.ErrIdentityStoreExternalAuthenticateInvalidInput
+	}
+	resp, err := b.webhook.call(r.GetContext(), &WebhookRequest{
+		Operation: "authenticate",
+		Realm:     b.config.Realm,
+		Username:  r.User.Username,
+		Password:  r.User.Password,
+		RequestID: r.ID,
+	})
+	if err != nil {
+		r.Response.Code = 500
+		return errors.ErrIdentityStoreExternalAuthFailed.WithArgs(err)
+	}
+	if !resp.Authenticated || resp.User == nil {
+		r.Response.Code = 401
+		reason := resp.Error
+		if reason == "" {
+			reason = "access denied"
+		}
+		return errors.ErrIdentityStoreExternalAuthFailed.WithArgs(reason)
+	}
+	b.setUser(r, resp.User)
+	b.logger.Debug(
+		"external authentication succeeded",
+		zap.String("realm", b.config.Realm),
+		zap.String("username", r.User.Username),
+		zap.Any("roles", r.User.Roles),
+	)
+	r.Response.Code = 200
+	return nil
+}
+
+// IdentifyUser performs user identification.
+func (b *IdentityStore) IdentifyUser(r *requests.Request) error {
+	if !isValidUsername(r.User.Username) {
+		return errors.ErrIdentityStoreExternalAuthenticateInvalidInput
+	}
+	resp, err := b.webhook.call(r.GetContext(), &WebhookRequest{
+		Operation: "identify",
+		Realm:     b.config.Realm,
+		Username:  r.User.Username,
+		RequestID: r.ID,
+	})
+	if err != nil {
+		r.Response.Code = 500
+		return errors.ErrIdentityStoreExternalAuthFailed.WithArgs(err)
+	}
+	if !resp.Found || resp.User == nil {
+		r.User.Username = "nobody"
+		r.User.Email = "nobody@localhost"
+		r.User.Challenges = []string{"password"}
+		return nil
+	}
+	b.setUser(r, resp.User)
+	r.Response.Code = 200
+	return nil
+}
+
+func (b *IdentityStore) setUser(r *requests.Request, usr *WebhookUser) {
+	if usr.Username != "" {
+		r.User.Username = usr.Username
+	}
+	r.User.Email = usr.Email
+	r.User.FullName = usr.Name
+	r.User.Roles = usr.Roles
+	r.User.Groups = usr.Groups
+	r.User.Challenges = []string{"password"}
+}
+
+// Configure configures IdentityStore.
+func (b *IdentityStore) Configure() error {
+	secret, err := credentials.ResolveSecret(b.config.Secret)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.ErrIdentityStoreExternalConfigSecretEmpty
+	}
+
+	b.webhook = newWebhook(b.config.URL, []byte(secret), time.Duration(b.config.Timeout)*time.Second, b.config.TLSInsecureSkipVerify)
+
+	// Configure UI login icon.
+	if b.config.LoginIcon == nil {
+		b.config.LoginIcon = icons.NewLoginIcon(storeKind)
+	} else {
+		b.config.LoginIcon.Configure(storeKind)
+	}
+
+	// Add support to the UI login icon.
+	b.config.LoginIcon.ContactSupportEnabled = b.config.ContactSupportEnabled
+	b.config.LoginIcon.SupportLink = b.config.SupportLink
+	b.config.LoginIcon.SupportEmail = b.config.SupportEmail
+
+	b.logger.Info(
+		"successfully configured identity store",
+		zap.String("name", b.config.Name),
+		zap.String("kind", storeKind),
+		zap.String("url", b.config.URL),
+		zap.Any("login_icon", b.config.LoginIcon),
+	)
+
+	b.configured = true
+	return nil
+}
+
+// GetConfig returns IdentityStore configuration.
+func (b *IdentityStore) GetConfig() map[string]interface{} {
+	var m map[string]interface{}
+	j, _ := json.Marshal(b.config)
+	json.Unmarshal(j, &m)
+	if _, exists := m["secret"]; exists {
+		m["secret"] = "**masked**"
+	}
+	return m
+}
+
+// Validate validates identity store configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrIdentityStoreConfigureNameEmpty
+	}
+	if cfg.Realm == "" {
+		return errors.ErrIdentityStoreConfigureRealmEmpty
+	}
+	if cfg.URL == "" {
+		return errors.ErrIdentityStoreExternalConfigURLEmpty
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return errors.ErrIdentityStoreExternalConfigURLInvalid.WithArgs(cfg.URL, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.ErrIdentityStoreExternalConfigURLNotSecure.WithArgs(cfg.URL)
+	}
+	if cfg.Secret == "" {
+		return errors.ErrIdentityStoreExternalConfigSecretEmpty
+	}
+	if cfg.Timeout < 0 {
+		return errors.ErrIdentityStoreExternalConfigTimeout.WithArgs(cfg.Timeout)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+// GetLoginIcon returns the instance of the icon associated with the provider.
+func (b *IdentityStore) GetLoginIcon() *icons.LoginIcon {
+	return b.config.LoginIcon
+}
+
+func isValidUsername(s string) bool {
+	if strings.Contains(s, "@") {
+		return emailRegexPattern.MatchString(s)
+	}
+	return usernameRegexPattern.MatchString(s)
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+const testSecret = "b0d2b6a2-2a8e-4b7a-9f3e-6f9c1b7b1d8e"
+
+func newTestWebhookServer(t *testing.T, tamper string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(TimestampHeader)
+		if r.Header.Get(SignatureHeader) != Sign([]byte(testSecret), timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := &WebhookRequest{}
+		json.Unmarshal(body, req)
+
+		resp := &WebhookResponse{Nonce: req.Nonce}
+		usr := &WebhookUser{
+			Username: "jsmith",
+			Email:    "jsmith@contoso.com",
+			Name:     "John Smith",
+			Roles:    []string{"authp/user"},
+		}
+		switch {
+		case req.Operation == "authenticate" && req.Username == "jsmith" && req.Password == "P@ssW0rd123":
+			resp.Authenticated = true
+			resp.User = usr
+		case req.Operation == "authenticate":
+			resp.Error = "invalid credentials"
+		case req.Operation == "identify" && req.Username == "jsmith":
+			resp.Found = true
+			resp.User = usr
+		}
+		if tamper == "nonce" {
+			resp.Nonce = "foobar"
+		}
+
+		respBody, _ := json.Marshal(resp)
+		respTimestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		if tamper == "timestamp" {
+			respTimestamp = strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		}
+		signature := Sign([]byte(testSecret), respTimestamp, respBody)
+		if tamper == "signature" {
+			signature = Sign([]byte("foobar"), respTimestamp, respBody)
+		}
+		w.Header().Set(TimestampHeader, respTimestamp)
+		w.Header().Set(SignatureHeader, signature)
+		w.Write(respBody)
+	}))
+}
+
+func TestIdentityStore(t *testing.T) {
+	testcases := []struct {
+		name     string
+		tamper   string
+		op       operator.Type
+		username string
+		password string
+		want     map[string]interface{}
+
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test authenticate user with valid credentials",
+			op:       operator.Authenticate,
+			username: "jsmith",
+			password: "P@ssW0rd123",
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"email":    "jsmith@contoso.com",
+				"name":     "John Smith",
+				"roles":    []string{"authp/user"},
+			},
+		},
+		{
+			name:      "test authenticate user with invalid credentials",
+			op:        operator.Authenticate,
+			username:  "jsmith",
+			password:  "foobar",
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalAuthFailed.WithArgs("invalid credentials"),
+		},
+		{
+			name:      "test authenticate user with invalid username",
+			op:        operator.Authenticate,
+			username:  "j smith",
+			password:  "foobar",
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalAuthenticateInvalidInput,
+		},
+		{
+			name:     "test identify existing user",
+			op:       operator.IdentifyUser,
+			username: "jsmith",
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"email":    "jsmith@contoso.com",
+				"name":     "John Smith",
+				"roles":    []string{"authp/user"},
+			},
+		},
+		{
+			name:     "test identify unknown user",
+			op:       operator.IdentifyUser,
+			username: "foobar",
+			want: map[string]interface{}{
+				"username": "nobody",
+				"email":    "nobody@localhost",
+			},
+		},
+		{
+			name:      "test response with invalid signature",
+			tamper:    "signature",
+			op:        operator.Authenticate,
+			username:  "jsmith",
+			password:  "P@ssW0rd123",
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalAuthFailed.WithArgs(errors.ErrIdentityStoreExternalResponseInvalid.WithArgs("signature mismatch")),
+		},
+		{
+			name:      "test response with mismatched nonce",
+			tamper:    "nonce",
+			op:        operator.Authenticate,
+			username:  "jsmith",
+			password:  "P@ssW0rd123",
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalAuthFailed.WithArgs(errors.ErrIdentityStoreExternalResponseInvalid.WithArgs("nonce mismatch")),
+		},
+		{
+			name:      "test response with stale timestamp",
+			tamper:    "timestamp",
+			op:        operator.Authenticate,
+			username:  "jsmith",
+			password:  "P@ssW0rd123",
+			shouldErr: true,
+		},
+		{
+			name:      "test unsupported operation",
+			op:        operator.ChangePassword,
+			username:  "jsmith",
+			shouldErr: true,
+			err:       errors.ErrOperatorNotAvailable.WithArgs(operator.ChangePassword),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			srv := newTestWebhookServer(t, tc.tamper)
+			defer srv.Close()
+
+			st, err := NewIdentityStore(&Config{
+				Name:                  "contoso-webhook",
+				Realm:                 "contoso",
+				URL:                   srv.URL,
+				Secret:                testSecret,
+				TLSInsecureSkipVerify: true,
+			}, logutil.NewLogger())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := st.Configure(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := requests.NewRequest()
+			r.User.Username = tc.username
+			r.User.Password = tc.password
+			err = st.Request(tc.op, r)
+			if tc.shouldErr && tc.err == nil {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if tests.EvalErrWithLog(t, err, "request", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"username": r.User.Username,
+				"email":    r.User.Email,
+			}
+			if r.User.FullName != "" {
+				got["name"] = r.User.FullName
+			}
+			if len(r.User.Roles) > 0 {
+				got["roles"] = r.User.Roles
+			}
+			tests.EvalObjectsWithLog(t, "user", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test valid config",
+			config: &Config{Name: "foo", Realm: "foo", URL: "https://localhost/verify", Secret: testSecret},
+		},
+		{
+			name:      "test config without url",
+			config:    &Config{Name: "foo", Realm: "foo", Secret: testSecret},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalConfigURLEmpty,
+		},
+		{
+			name:      "test config with plain http url",
+			config:    &Config{Name: "foo", Realm: "foo", URL: "http://localhost/verify", Secret: testSecret},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalConfigURLNotSecure.WithArgs("http://localhost/verify"),
+		},
+		{
+			name:      "test config without secret",
+			config:    &Config{Name: "foo", Realm: "foo", URL: "https://localhost/verify"},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalConfigSecretEmpty,
+		},
+		{
+			name:      "test config with negative timeout",
+			config:    &Config{Name: "foo", Realm: "foo", URL: "https://localhost/verify", Secret: testSecret, Timeout: -1},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreExternalConfigTimeout.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
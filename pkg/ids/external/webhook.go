@@ -0,0 +1,168 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/httpclient"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+const (
+	// TimestampHeader is the header holding the time, in seconds since
+	// epoch, the request or the response was signed at.
+	TimestampHeader = "X-Authcrunch-Timestamp"
+	// SignatureHeader is the header holding the signature of the request
+	// or the response.
+	SignatureHeader = "X-Authcrunch-Signature"
+
+	// maxClockSkew is the maximum difference between the time a response
+	// was signed at and the local time.
+	maxClockSkew = 5 * time.Minute
+	// maxResponseSize is the maximum size of a response of the endpoint.
+	maxResponseSize = 1 << 20
+)
+
+// WebhookRequest is the body of the requests to the endpoint. The operation
+// is either authenticate, i.e. the verification of the password of the user,
+// or identify, i.e. the lookup of the user.
+type WebhookRequest struct {
+	Operation string `json:"operation,omitempty" xml:"operation,omitempty" yaml:"operation,omitempty"`
+	Realm     string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Username  string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Password  string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty" yaml:"request_id,omitempty"`
+	// Nonce must be echoed in the response, binding the response to the
+	// request.
+	Nonce string `json:"nonce,omitempty" xml:"nonce,omitempty" yaml:"nonce,omitempty"`
+}
+
+// WebhookResponse is the body of the responses of the endpoint.
+type WebhookResponse struct {
+	Nonce         string       `json:"nonce,omitempty" xml:"nonce,omitempty" yaml:"nonce,omitempty"`
+	Authenticated bool         `json:"authenticated,omitempty" xml:"authenticated,omitempty" yaml:"authenticated,omitempty"`
+	Found         bool         `json:"found,omitempty" xml:"found,omitempty" yaml:"found,omitempty"`
+	User          *WebhookUser `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Error         string       `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// WebhookUser holds the claims of the user returned by the endpoint.
+type WebhookUser struct {
+	Username string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Name     string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Roles    []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Groups   []string `json:"groups,omitempty" xml:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// Sign returns the signature of the body signed at the provided time, i.e.
+// hex-encoded HMAC-SHA256 of the timestamp, a dot, and the body, prefixed
+// with sha256=.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+type webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+func newWebhook(u string, secret []byte, timeout time.Duration, insecureSkipVerify bool) *webhook {
+	return &webhook{
+		url:    u,
+		secret: secret,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: httpclient.GetTransport(nil, insecureSkipVerify),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// call sends the signed request to the endpoint and returns the response
+// after verifying its signature.
+func (w *webhook) call(ctx context.Context, req *WebhookRequest) (*WebhookResponse, error) {
+	req.Nonce = util.GetRandomString(32)
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set(TimestampHeader, timestamp)
+	httpReq.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+
+	httpResp, err := w.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+
+	respTimestamp := httpResp.Header.Get(TimestampHeader)
+	signedAt, err := strconv.ParseInt(respTimestamp, 10, 64)
+	if err != nil {
+		return nil, errors.ErrIdentityStoreExternalResponseInvalid.WithArgs(fmt.Sprintf("malformed timestamp %q", respTimestamp))
+	}
+	if d := w.now().Sub(time.Unix(signedAt, 0)); d > maxClockSkew || d < -maxClockSkew {
+		return nil, errors.ErrIdentityStoreExternalResponseInvalid.WithArgs(fmt.Sprintf("timestamp %q is outside of the allowed clock skew", respTimestamp))
+	}
+	signature := httpResp.Header.Get(SignatureHeader)
+	if !hmac.Equal([]byte(signature), []byte(Sign(w.secret, respTimestamp, respBody))) {
+		return nil, errors.ErrIdentityStoreExternalResponseInvalid.WithArgs("signature mismatch")
+	}
+
+	resp := &WebhookResponse{}
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return nil, errors.ErrIdentityStoreExternalResponseInvalid.WithArgs(err)
+	}
+	if resp.Nonce != req.Nonce {
+		return nil, errors.ErrIdentityStoreExternalResponseInvalid.WithArgs("nonce mismatch")
+	}
+	return resp, nil
+}
@@ -19,6 +19,8 @@ import (
 	"crypto/x509"
 	"fmt"
 	ldap "github.com/go-ldap/ldap/v3"
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/enrichment"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
@@ -45,6 +47,7 @@ type Authenticator struct {
 	fallbackRoles     []string
 	rootCAs           *x509.CertPool
 	groups            []*UserGroup
+	groupCache        *enrichment.Cache
 	logger            *zap.Logger
 }
 
@@ -164,6 +167,15 @@ func (sa *Authenticator) ConfigureBindCredentials(cfg *Config) error {
 		}
 	}
 
+	if credentials.IsSecretReference(password) {
+		// The password is resolved when binding.
+		sa.logger.Info(
+			"LDAP plugin configuration",
+			zap.String("phase", "bind_credentials"),
+			zap.String("password_reference", password),
+		)
+	}
+
 	sa.username = username
 	sa.password = password
 
@@ -279,6 +291,25 @@ func (sa *Authenticator) ConfigureUserGroups(cfg *Config) error {
 	return nil
 }
 
+// ConfigureGroupCache configures the cache of the POSIX group searches.
+func (sa *Authenticator) ConfigureGroupCache(cfg *Config) error {
+	if cfg.GroupCacheConfig == nil {
+		return nil
+	}
+	cache, err := enrichment.NewCache(cfg.GroupCacheConfig)
+	if err != nil {
+		return err
+	}
+	sa.logger.Info(
+		"LDAP plugin configuration",
+		zap.String("phase", "group_cache"),
+		zap.Int("ttl", cfg.GroupCacheConfig.TTL),
+		zap.Int("negative_ttl", cfg.GroupCacheConfig.NegativeTTL),
+	)
+	sa.groupCache = cache
+	return nil
+}
+
 // IdentifyUser returns user challenges.
 func (sa *Authenticator) IdentifyUser(r *requests.Request) error {
 	sa.mux.Lock()
@@ -418,41 +449,47 @@ func (sa *Authenticator) ConfigureTrustedAuthorities(cfg *Config) error {
 	return nil
 }
 
-func (sa *Authenticator) searchGroups(conn *ldap.Conn, reqData map[string]interface{}, roles map[string]bool) error {
-	if roles == nil {
-		roles = make(map[string]bool)
-	}
-
+// searchGroups returns the DNs of the POSIX groups of the user.
+func (sa *Authenticator) searchGroups(conn *ldap.Conn, reqData map[string]interface{}) ([]string, error) {
 	req := ldap.NewSearchRequest(reqData["base_dn"].(string), ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0,
 		reqData["timeout"].(int), false, reqData["search_group_filter"].(string), []string{"dn"}, nil,
 	)
 	if req == nil {
-		return fmt.Errorf("failed building group search LDAP request")
+		return nil, fmt.Errorf("failed building group search LDAP request")
 	}
 
 	resp, err := conn.Search(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(resp.Entries) < 1 {
-		return fmt.Errorf("no groups found for %s", reqData["user_dn"].(string))
+		return nil, fmt.Errorf("no groups found for %s", reqData["user_dn"].(string))
 	}
 
+	var groups []string
 	for _, entry := range resp.Entries {
-		for _, g := range sa.groups {
-			if g.GroupDN != entry.DN {
-				continue
-			}
-			for _, role := range g.Roles {
-				if role == "" {
-					continue
-				}
-				roles[role] = true
-			}
-		}
+		groups = append(groups, entry.DN)
 	}
-	return nil
+	return groups, nil
+}
+
+// lookupGroups returns the groups of the user from the group cache, when
+// configured. Otherwise, it calls the lookup function.
+func (sa *Authenticator) lookupGroups(keys []string, fn func() ([]string, error)) ([]string, error) {
+	if sa.groupCache == nil {
+		return fn()
+	}
+	return sa.groupCache.Lookup(keys, fn)
+}
+
+// InvalidateGroupCache deletes the cached groups of the user. When the
+// user is empty, it deletes the cached groups of all users.
+func (sa *Authenticator) InvalidateGroupCache(user string) int {
+	if sa.groupCache == nil {
+		return 0
+	}
+	return sa.groupCache.Invalidate(user)
 }
 
 func (sa *Authenticator) dial(server *AuthServer) (*ldap.Conn, error) {
@@ -539,7 +576,18 @@ func (sa *Authenticator) dial(server *AuthServer) (*ldap.Conn, error) {
 
 	ldapConnection.Start()
 
-	if err := ldapConnection.Bind(sa.username, sa.password); err != nil {
+	password, err := credentials.ResolveSecret(sa.password)
+	if err != nil {
+		sa.logger.Error(
+			"LDAP connection binding failed",
+			zap.String("server", server.Address),
+			zap.String("username", sa.username),
+			zap.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if err := ldapConnection.Bind(sa.username, password); err != nil {
 		sa.logger.Error(
 			"LDAP connection binding failed",
 			zap.String("server", server.Address),
@@ -619,6 +667,28 @@ func (sa *Authenticator) findUser(ldapConnection *ldap.Conn, server *AuthServer,
 	user := resp.Entries[0]
 	var userFullName, userLastName, userFirstName, userAccountName, userMail string
 	userRoles := make(map[string]bool)
+	var userGroups []string
+
+	for _, attr := range user.Attributes {
+		if len(attr.Values) < 1 {
+			continue
+		}
+		if attr.Name == sa.userAttributes.Name {
+			userFirstName = attr.Values[0]
+		}
+		if attr.Name == sa.userAttributes.Surname {
+			userLastName = attr.Values[0]
+		}
+		if attr.Name == sa.userAttributes.Username {
+			userAccountName = attr.Values[0]
+		}
+		if attr.Name == sa.userAttributes.MemberOf {
+			userGroups = append(userGroups, attr.Values...)
+		}
+		if attr.Name == sa.userAttributes.Email {
+			userMail = attr.Values[0]
+		}
+	}
 
 	if server.PosixGroups {
 		// Handle POSIX group memberships.
@@ -628,7 +698,10 @@ func (sa *Authenticator) findUser(ldapConnection *ldap.Conn, server *AuthServer,
 			"search_group_filter": strings.ReplaceAll(sa.searchGroupFilter, "%s", user.DN),
 			"timeout":             server.Timeout,
 		}
-		if err := sa.searchGroups(ldapConnection, searchGroupRequest, userRoles); err != nil {
+		groups, err := sa.lookupGroups([]string{user.DN, userAccountName, userMail}, func() ([]string, error) {
+			return sa.searchGroups(ldapConnection, searchGroupRequest)
+		})
+		if err != nil {
 			sa.logger.Error(
 				"LDAP group search failed, request",
 				zap.String("server", server.Address),
@@ -638,39 +711,21 @@ func (sa *Authenticator) findUser(ldapConnection *ldap.Conn, server *AuthServer,
 			)
 			return err
 		}
+		userGroups = append(userGroups, groups...)
 	}
 
-	for _, attr := range user.Attributes {
-		if len(attr.Values) < 1 {
-			continue
-		}
-		if attr.Name == sa.userAttributes.Name {
-			userFirstName = attr.Values[0]
-		}
-		if attr.Name == sa.userAttributes.Surname {
-			userLastName = attr.Values[0]
-		}
-		if attr.Name == sa.userAttributes.Username {
-			userAccountName = attr.Values[0]
-		}
-		if attr.Name == sa.userAttributes.MemberOf {
-			for _, v := range attr.Values {
-				for _, g := range sa.groups {
-					if g.GroupDN != v {
-						continue
-					}
-					for _, role := range g.Roles {
-						if role == "" {
-							continue
-						}
-						userRoles[role] = true
-					}
+	for _, v := range userGroups {
+		for _, g := range sa.groups {
+			if g.GroupDN != v {
+				continue
+			}
+			for _, role := range g.Roles {
+				if role == "" {
+					continue
 				}
+				userRoles[role] = true
 			}
 		}
-		if attr.Name == sa.userAttributes.Email {
-			userMail = attr.Values[0]
-		}
 	}
 
 	if userFirstName != "" {
@@ -700,6 +755,7 @@ func (sa *Authenticator) findUser(ldapConnection *ldap.Conn, server *AuthServer,
 	r.User.Username = userAccountName
 	r.User.Email = userMail
 	r.User.FullName = userFullName
+	r.User.Groups = userGroups
 
 	sa.logger.Debug(
 		"LDAP user match",
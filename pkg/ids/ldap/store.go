@@ -18,8 +18,10 @@ import (
 	"encoding/json"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
+	"github.com/greenpau/go-authcrunch/pkg/enrichment"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"go.uber.org/zap"
 	"net/url"
 	"regexp"
@@ -68,6 +70,10 @@ type Config struct {
 
 	// The roles assigned to a user when no matching LDAP groups found.
 	FallbackRoles []string `json:"fallback_roles,omitempty" xml:"fallback_roles,omitempty" yaml:"fallback_roles,omitempty"`
+
+	// GroupCacheConfig holds the configuration of the cache of the POSIX
+	// group searches.
+	GroupCacheConfig *enrichment.Config `json:"group_cache_config,omitempty" xml:"group_cache_config,omitempty" yaml:"group_cache_config,omitempty"`
 }
 
 // UserGroup represent the binding between BaseDN and a serarch filter.
@@ -148,6 +154,17 @@ func (b *IdentityStore) Configured() bool {
 
 // Request performs the requested identity store operation.
 func (b *IdentityStore) Request(op operator.Type, r *requests.Request) error {
+	_, span := tracing.Start(r.GetContext(), "ids.Request",
+		tracing.RealmKey.String(b.GetRealm()),
+		tracing.MethodKey.String(b.GetKind()),
+		tracing.OperationKey.String(op.String()),
+	)
+	err := b.request(op, r)
+	tracing.End(span, err)
+	return err
+}
+
+func (b *IdentityStore) request(op operator.Type, r *requests.Request) error {
 	switch op {
 	case operator.Authenticate:
 		return b.Authenticate(r)
@@ -229,6 +246,12 @@ func (b *IdentityStore) Configure() error {
 		return err
 	}
 
+	if err := b.authenticator.ConfigureGroupCache(b.config); err != nil {
+		b.logger.Error("failed configuring group cache",
+			zap.String("error", err.Error()))
+		return err
+	}
+
 	// Configure UI login icon.
 	if b.config.LoginIcon == nil {
 		b.config.LoginIcon = icons.NewLoginIcon(storeKind)
@@ -275,9 +298,20 @@ func (cfg *Config) Validate() error {
 	if cfg.Realm == "" {
 		return errors.ErrIdentityStoreConfigureRealmEmpty
 	}
+	if cfg.GroupCacheConfig != nil {
+		if err := cfg.GroupCacheConfig.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// InvalidateGroupCache deletes the cached groups of the user. When the
+// user is empty, it deletes the cached groups of all users.
+func (b *IdentityStore) InvalidateGroupCache(user string) int {
+	return b.authenticator.InvalidateGroupCache(user)
+}
+
 // GetLoginIcon returns the instance of the icon associated with the provider.
 func (b *IdentityStore) GetLoginIcon() *icons.LoginIcon {
 	return b.config.LoginIcon
@@ -152,6 +152,14 @@ func (sa *Authenticator) AddUser(r *requests.Request) error {
 	return sa.db.AddUser(r)
 }
 
+// ImportUser adds an existing user identity, e.g. an approved registration,
+// to database.
+func (sa *Authenticator) ImportUser(u *identity.User) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ImportUser(u)
+}
+
 // GetUsers retrieves users from database.
 func (sa *Authenticator) GetUsers(r *requests.Request) error {
 	sa.mux.Lock()
@@ -180,6 +188,138 @@ func (sa *Authenticator) ChangePassword(r *requests.Request) error {
 	return sa.db.ChangeUserPassword(r)
 }
 
+// ResetPassword sets a new password for a user without verifying the
+// current one.
+func (sa *Authenticator) ResetPassword(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateUserPassword(r)
+}
+
+// ChangeEmail replaces the primary email address of a user.
+func (sa *Authenticator) ChangeEmail(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ChangeUserEmailAddress(r)
+}
+
+// UpdateProfile updates the self-managed profile attributes of a user.
+func (sa *Authenticator) UpdateProfile(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateUserProfile(r)
+}
+
+// GetConsents returns a list of consents a user granted to applications.
+func (sa *Authenticator) GetConsents(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GetUserConsents(r)
+}
+
+// AddConsent records the consent a user granted to an application.
+func (sa *Authenticator) AddConsent(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddUserConsent(r)
+}
+
+// DeleteConsent withdraws the consent a user granted to an application.
+func (sa *Authenticator) DeleteConsent(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DeleteUserConsent(r)
+}
+
+// GetLinkedIdentities returns a list of the identities at identity providers
+// linked to the account of a user.
+func (sa *Authenticator) GetLinkedIdentities(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GetUserLinkedIdentities(r)
+}
+
+// AddLinkedIdentity links the identity at an identity provider to the
+// account of a user.
+func (sa *Authenticator) AddLinkedIdentity(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddUserLinkedIdentity(r)
+}
+
+// DeleteLinkedIdentity unlinks the identity at an identity provider from the
+// account of a user.
+func (sa *Authenticator) DeleteLinkedIdentity(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DeleteUserLinkedIdentity(r)
+}
+
+// LookupLinkedIdentity returns the identity of the user the identity at an
+// identity provider is linked to.
+func (sa *Authenticator) LookupLinkedIdentity(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.LookupLinkedIdentity(r)
+}
+
+// GetAcceptances returns the versions of the documents a user accepted.
+func (sa *Authenticator) GetAcceptances(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GetUserAcceptances(r)
+}
+
+// AddAcceptance records the acceptance of a version of a document by a user.
+func (sa *Authenticator) AddAcceptance(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddUserAcceptance(r)
+}
+
+// ScheduleDeletion schedules the deletion of a user.
+func (sa *Authenticator) ScheduleDeletion(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ScheduleUserDeletion(r)
+}
+
+// CancelDeletion cancels the scheduled deletion of a user.
+func (sa *Authenticator) CancelDeletion(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.CancelUserDeletion(r)
+}
+
+// PurgeDeletions deletes the users whose scheduled deletion is due.
+func (sa *Authenticator) PurgeDeletions(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.PurgeUserDeletions(r)
+}
+
+// ExportUser returns the data held about a user.
+func (sa *Authenticator) ExportUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ExportUser(r)
+}
+
+// Compact removes the historical records of the users in excess of the
+// retention limits.
+func (sa *Authenticator) Compact(maxLoginRecords int) (*identity.IntegrityReport, error) {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.Compact(maxLoginRecords)
+}
+
+// AddLoginRecord records an authentication attempt of a user.
+func (sa *Authenticator) AddLoginRecord(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddUserLoginRecord(r)
+}
+
 // AddPublicKey adds public key, e.g. GPG or SSH, for a user.
 func (sa *Authenticator) AddPublicKey(r *requests.Request) error {
 	sa.mux.Lock()
@@ -208,6 +348,13 @@ func (sa *Authenticator) AddAPIKey(r *requests.Request) error {
 	return sa.db.AddAPIKey(r)
 }
 
+// RotateAPIKey replaces API key associated with the user.
+func (sa *Authenticator) RotateAPIKey(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.RotateAPIKey(r)
+}
+
 // DeleteAPIKey removes API key associated with the user.
 func (sa *Authenticator) DeleteAPIKey(r *requests.Request) error {
 	sa.mux.Lock()
@@ -236,6 +383,112 @@ func (sa *Authenticator) DeleteMfaToken(r *requests.Request) error {
 	return sa.db.DeleteMfaToken(r)
 }
 
+// ResetMfaTokens removes all MFA tokens associated with the user.
+func (sa *Authenticator) ResetMfaTokens(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ResetMfaTokens(r)
+}
+
+// UpdateRoles replaces the roles of a user.
+func (sa *Authenticator) UpdateRoles(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateUserRoles(r)
+}
+
+// GrantRole assigns a time-boxed role to a user.
+func (sa *Authenticator) GrantRole(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GrantUserRole(r)
+}
+
+// ExpireRoles removes the expired time-boxed roles from the users.
+func (sa *Authenticator) ExpireRoles(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ExpireUserRoles(r)
+}
+
+// DisableDormantUsers disables the users without recent logins.
+func (sa *Authenticator) DisableDormantUsers(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DisableDormantUsers(r)
+}
+
+// EnableUser removes the lockout of a user.
+func (sa *Authenticator) EnableUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.EnableUser(r)
+}
+
+// PruneLoginRecords removes the old records from the login history of the
+// users.
+func (sa *Authenticator) PruneLoginRecords(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.PruneLoginRecords(r)
+}
+
+// UpdateOrganizations replaces the organizations of a user.
+func (sa *Authenticator) UpdateOrganizations(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateUserOrganizations(r)
+}
+
+// GetGroups returns the groups of users.
+func (sa *Authenticator) GetGroups(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GetGroups(r)
+}
+
+// GetGroup returns a group of users.
+func (sa *Authenticator) GetGroup(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.GetGroup(r)
+}
+
+// AddGroup adds a group of users.
+func (sa *Authenticator) AddGroup(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddGroup(r)
+}
+
+// UpdateGroup replaces the roles of a group of users.
+func (sa *Authenticator) UpdateGroup(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateGroup(r)
+}
+
+// DeleteGroup deletes a group of users.
+func (sa *Authenticator) DeleteGroup(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DeleteGroup(r)
+}
+
+// AddGroupMember adds a user to a group.
+func (sa *Authenticator) AddGroupMember(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddGroupMember(r)
+}
+
+// DeleteGroupMember removes a user from a group.
+func (sa *Authenticator) DeleteGroupMember(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DeleteGroupMember(r)
+}
+
 // GetMfaTokens returns a list of MFA token associated with a user.
 func (sa *Authenticator) GetMfaTokens(r *requests.Request) error {
 	sa.mux.Lock()
@@ -250,9 +503,8 @@ func (sa *Authenticator) IdentifyUser(r *requests.Request) error {
 	return sa.db.IdentifyUser(r)
 }
 
-// LookupAPIKey performs user lookup based on an API key.
+// LookupAPIKey performs user lookup based on an API key. The lookups are
+// not serialized, the database guards its own state.
 func (sa *Authenticator) LookupAPIKey(r *requests.Request) error {
-	sa.mux.Lock()
-	defer sa.mux.Unlock()
 	return sa.db.LookupAPIKey(r)
 }
@@ -19,8 +19,14 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/pwned"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 const (
@@ -43,6 +49,8 @@ type Config struct {
 	UsernameRecoveryEnabled bool `json:"username_recovery_enabled,omitempty" xml:"username_recovery_enabled,omitempty" yaml:"username_recovery_enabled,omitempty"`
 	// PasswordRecoveryEnabled controls whether a user could recover password by providing an email address.
 	PasswordRecoveryEnabled bool `json:"password_recovery_enabled,omitempty" xml:"password_recovery_enabled,omitempty" yaml:"password_recovery_enabled,omitempty"`
+	// MagicLinkEnabled controls whether a user could sign in with a link sent to an email address.
+	MagicLinkEnabled bool `json:"magic_link_enabled,omitempty" xml:"magic_link_enabled,omitempty" yaml:"magic_link_enabled,omitempty"`
 	// ContactSupportEnabled controls whether contact support link is available.
 	ContactSupportEnabled bool `json:"contact_support_enabled,omitempty" xml:"contact_support_enabled,omitempty" yaml:"contact_support_enabled,omitempty"`
 
@@ -50,14 +58,74 @@ type Config struct {
 	SupportLink string `json:"support_link,omitempty" xml:"support_link,omitempty" yaml:"support_link,omitempty"`
 	// SupportEmail is the email address to reach support.
 	SupportEmail string `json:"support_email,omitempty" xml:"support_email,omitempty" yaml:"support_email,omitempty"`
+
+	// PasswordBreachCheck holds the configuration of the check of new
+	// passwords against the known data breaches.
+	PasswordBreachCheck *pwned.Config `json:"password_breach_check,omitempty" xml:"password_breach_check,omitempty" yaml:"password_breach_check,omitempty"`
+
+	// BackupCount is the number of the previous revisions of the database
+	// file kept as backups for rollback.
+	BackupCount int `json:"backup_count,omitempty" xml:"backup_count,omitempty" yaml:"backup_count,omitempty"`
+
+	// CompactionInterval is the interval, in seconds, between the background
+	// compactions of the database. The compaction is disabled when zero.
+	CompactionInterval int `json:"compaction_interval,omitempty" xml:"compaction_interval,omitempty" yaml:"compaction_interval,omitempty"`
+	// MaxLoginRecords is the maximum number of the login records per user
+	// kept by the compaction. When zero, the login history is not compacted.
+	MaxLoginRecords int `json:"max_login_records,omitempty" xml:"max_login_records,omitempty" yaml:"max_login_records,omitempty"`
+
+	// ReadOnly blocks the registrations, the password changes, the MFA
+	// enrollments, and the other account changes, e.g. during a backup or
+	// a migration of the database. The users keep authenticating.
+	ReadOnly bool `json:"read_only,omitempty" xml:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// readOnlyOperators are the operators blocked in the read-only identity
+// store. The operators of the authentication, e.g. the lockout tracking, the
+// login records, and the terms acceptances, and the role expiry remain.
+var readOnlyOperators = map[operator.Type]bool{
+	operator.ChangePassword:       true,
+	operator.ResetPassword:        true,
+	operator.AddKeySSH:            true,
+	operator.AddKeyGPG:            true,
+	operator.DeletePublicKey:      true,
+	operator.AddMfaToken:          true,
+	operator.DeleteMfaToken:       true,
+	operator.ResetMfaTokens:       true,
+	operator.AddAPIKey:            true,
+	operator.DeleteAPIKey:         true,
+	operator.RotateAPIKey:         true,
+	operator.AddUser:              true,
+	operator.DeleteUser:           true,
+	operator.ChangeEmail:          true,
+	operator.UpdateProfile:        true,
+	operator.UpdateRoles:          true,
+	operator.UpdateOrganizations:  true,
+	operator.GrantRole:            true,
+	operator.AddLinkedIdentity:    true,
+	operator.DeleteLinkedIdentity: true,
+	operator.AddGroup:             true,
+	operator.UpdateGroup:          true,
+	operator.DeleteGroup:          true,
+	operator.AddGroupMember:       true,
+	operator.DeleteGroupMember:    true,
+	operator.ScheduleDeletion:     true,
+	operator.CancelDeletion:       true,
+	operator.PurgeDeletions:       true,
+	operator.DisableDormantUsers:  true,
+	operator.EnableUser:           true,
 }
 
 // IdentityStore represents authentication provider with local identity store.
 type IdentityStore struct {
 	config        *Config        `json:"-"`
 	authenticator *Authenticator `json:"-"`
+	pwned         *pwned.Checker
 	logger        *zap.Logger
 	configured    bool
+	compactMu     sync.Mutex
+	compacting    bool
+	compactedAt   time.Time
 }
 
 // NewIdentityStore return an instance of AuthDB-based identity store.
@@ -107,12 +175,30 @@ func (b *IdentityStore) Configured() bool {
 
 // Request performs the requested identity store operation.
 func (b *IdentityStore) Request(op operator.Type, r *requests.Request) error {
+	_, span := tracing.Start(r.GetContext(), "ids.Request",
+		tracing.RealmKey.String(b.GetRealm()),
+		tracing.MethodKey.String(b.GetKind()),
+		tracing.OperationKey.String(op.String()),
+	)
+	err := b.request(op, r)
+	tracing.End(span, err)
+	return err
+}
+
+func (b *IdentityStore) request(op operator.Type, r *requests.Request) error {
+	if b.config.ReadOnly && readOnlyOperators[op] {
+		return errors.ErrIdentityStoreLocalReadOnly.WithArgs(b.config.Name)
+	}
+	b.scheduleCompaction(time.Now())
 	switch op {
 	case operator.Authenticate:
 		return b.Authenticate(r)
 	case operator.IdentifyUser:
 		return b.authenticator.IdentifyUser(r)
 	case operator.ChangePassword:
+		if err := b.CheckPassword(r); err != nil {
+			return err
+		}
 		return b.authenticator.ChangePassword(r)
 	case operator.AddKeySSH:
 		return b.authenticator.AddPublicKey(r)
@@ -145,6 +231,75 @@ func (b *IdentityStore) Request(op operator.Type, r *requests.Request) error {
 		return b.authenticator.DeleteUser(r)
 	case operator.LookupAPIKey:
 		return b.authenticator.LookupAPIKey(r)
+	case operator.ResetPassword:
+		if err := b.CheckPassword(r); err != nil {
+			return err
+		}
+		return b.authenticator.ResetPassword(r)
+	case operator.ChangeEmail:
+		return b.authenticator.ChangeEmail(r)
+	case operator.UpdateProfile:
+		return b.authenticator.UpdateProfile(r)
+	case operator.GetConsents:
+		return b.authenticator.GetConsents(r)
+	case operator.AddConsent:
+		return b.authenticator.AddConsent(r)
+	case operator.DeleteConsent:
+		return b.authenticator.DeleteConsent(r)
+	case operator.GetLinkedIdentities:
+		return b.authenticator.GetLinkedIdentities(r)
+	case operator.AddLinkedIdentity:
+		return b.authenticator.AddLinkedIdentity(r)
+	case operator.DeleteLinkedIdentity:
+		return b.authenticator.DeleteLinkedIdentity(r)
+	case operator.LookupLinkedIdentity:
+		return b.authenticator.LookupLinkedIdentity(r)
+	case operator.RotateAPIKey:
+		return b.authenticator.RotateAPIKey(r)
+	case operator.UpdateRoles:
+		return b.authenticator.UpdateRoles(r)
+	case operator.UpdateOrganizations:
+		return b.authenticator.UpdateOrganizations(r)
+	case operator.GetGroups:
+		return b.authenticator.GetGroups(r)
+	case operator.GetGroup:
+		return b.authenticator.GetGroup(r)
+	case operator.AddGroup:
+		return b.authenticator.AddGroup(r)
+	case operator.UpdateGroup:
+		return b.authenticator.UpdateGroup(r)
+	case operator.DeleteGroup:
+		return b.authenticator.DeleteGroup(r)
+	case operator.AddGroupMember:
+		return b.authenticator.AddGroupMember(r)
+	case operator.DeleteGroupMember:
+		return b.authenticator.DeleteGroupMember(r)
+	case operator.ResetMfaTokens:
+		return b.authenticator.ResetMfaTokens(r)
+	case operator.GetAcceptances:
+		return b.authenticator.GetAcceptances(r)
+	case operator.AddAcceptance:
+		return b.authenticator.AddAcceptance(r)
+	case operator.ScheduleDeletion:
+		return b.authenticator.ScheduleDeletion(r)
+	case operator.CancelDeletion:
+		return b.authenticator.CancelDeletion(r)
+	case operator.PurgeDeletions:
+		return b.authenticator.PurgeDeletions(r)
+	case operator.GrantRole:
+		return b.authenticator.GrantRole(r)
+	case operator.ExpireRoles:
+		return b.authenticator.ExpireRoles(r)
+	case operator.DisableDormantUsers:
+		return b.authenticator.DisableDormantUsers(r)
+	case operator.EnableUser:
+		return b.authenticator.EnableUser(r)
+	case operator.PruneLoginRecords:
+		return b.authenticator.PruneLoginRecords(r)
+	case operator.ExportUser:
+		return b.authenticator.ExportUser(r)
+	case operator.AddLoginRecord:
+		return b.authenticator.AddLoginRecord(r)
 	}
 
 	b.logger.Error(
@@ -165,6 +320,23 @@ func (b *IdentityStore) Configure() error {
 	if err := b.authenticator.Configure(b.config.Path, b.config.Users); err != nil {
 		return err
 	}
+	if b.config.BackupCount > 0 {
+		b.authenticator.db.SetBackupCount(b.config.BackupCount)
+	}
+	if report := b.authenticator.db.GetIntegrityReport(); report != nil {
+		b.publishIntegrityReport(report)
+	}
+	b.compactMu.Lock()
+	b.compactedAt = time.Now()
+	b.compactMu.Unlock()
+
+	if b.config.PasswordBreachCheck != nil && b.pwned == nil {
+		checker, err := pwned.NewChecker(b.config.PasswordBreachCheck)
+		if err != nil {
+			return err
+		}
+		b.pwned = checker
+	}
 
 	b.logger.Info(
 		"successfully configured identity store",
@@ -178,6 +350,12 @@ func (b *IdentityStore) Configure() error {
 	return nil
 }
 
+// IsReadOnly returns true when the identity store blocks the account
+// changes.
+func (b *IdentityStore) IsReadOnly() bool {
+	return b.config.ReadOnly
+}
+
 // GetConfig returns IdentityStore configuration.
 func (b *IdentityStore) GetConfig() map[string]interface{} {
 	var m map[string]interface{}
@@ -194,6 +372,48 @@ func (b *IdentityStore) Authenticate(r *requests.Request) error {
 	return nil
 }
 
+// CheckPassword checks the new password in the request against the known
+// data breaches. When the password is compromised, it returns an error or,
+// when the check is configured to warn, sets the response message. The
+// password is accepted when the check is not available.
+func (b *IdentityStore) CheckPassword(r *requests.Request) error {
+	if b.pwned == nil || r.User.Password == "" {
+		return nil
+	}
+	compromised, err := b.pwned.Check(r.GetContext(), r.User.Password)
+	if err != nil {
+		b.logger.Warn(
+			"failed checking password against data breaches",
+			zap.String("name", b.config.Name),
+			zap.String("username", r.User.Username),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if !compromised {
+		return nil
+	}
+	b.logger.Warn(
+		"detected compromised password",
+		zap.String("name", b.config.Name),
+		zap.String("username", r.User.Username),
+		zap.String("action", b.pwned.GetAction()),
+	)
+	if b.pwned.GetAction() == pwned.ActionWarn {
+		r.Response.Message = "The password appeared in a data breach. Please consider choosing a different password."
+		return nil
+	}
+	return errors.ErrPasswordCompromised
+}
+
+// ImportUser adds an existing user identity to the identity store.
+func (b *IdentityStore) ImportUser(u *identity.User) error {
+	if b.config.ReadOnly {
+		return errors.ErrIdentityStoreLocalReadOnly.WithArgs(b.config.Name)
+	}
+	return b.authenticator.ImportUser(u)
+}
+
 // Validate validates identity store configuration.
 func (cfg *Config) Validate() error {
 	if cfg.Name == "" {
@@ -205,15 +425,87 @@ func (cfg *Config) Validate() error {
 	if cfg.Path == "" {
 		return errors.ErrIdentityStoreLocalConfigurePathEmpty
 	}
+	if cfg.BackupCount < 0 {
+		return errors.ErrIdentityStoreLocalConfigureBackupCount.WithArgs(cfg.BackupCount)
+	}
+	if cfg.CompactionInterval < 0 {
+		return errors.ErrIdentityStoreLocalConfigureCompaction.WithArgs(cfg.CompactionInterval)
+	}
+	if cfg.MaxLoginRecords < 0 {
+		return errors.ErrIdentityStoreLocalConfigureLoginRecords.WithArgs(cfg.MaxLoginRecords)
+	}
+	if cfg.PasswordBreachCheck != nil {
+		if err := cfg.PasswordBreachCheck.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// scheduleCompaction starts the compaction of the database in the
+// background when the compaction interval elapsed since the previous one.
+func (b *IdentityStore) scheduleCompaction(now time.Time) {
+	if b.config.CompactionInterval < 1 || b.config.ReadOnly || b.authenticator == nil {
+		return
+	}
+	b.compactMu.Lock()
+	defer b.compactMu.Unlock()
+	if b.compacting || now.Before(b.compactedAt.Add(time.Duration(b.config.CompactionInterval)*time.Second)) {
+		return
+	}
+	b.compacting = true
+	go b.compact()
+}
+
+func (b *IdentityStore) compact() {
+	report, err := b.authenticator.Compact(b.config.MaxLoginRecords)
+	b.compactMu.Lock()
+	b.compacting = false
+	b.compactedAt = time.Now()
+	b.compactMu.Unlock()
+	if err != nil {
+		b.logger.Error(
+			"failed compacting identity store database",
+			zap.String("name", b.config.Name),
+			zap.Error(err),
+		)
+		return
+	}
+	if report.Changed() {
+		b.publishIntegrityReport(report)
+	}
+}
+
+// publishIntegrityReport logs the outcome of the integrity check, or of the
+// compaction, of the database and publishes it to the event bus.
+func (b *IdentityStore) publishIntegrityReport(report *identity.IntegrityReport) {
+	for _, issue := range report.Issues {
+		b.logger.Warn(
+			"found identity store integrity issue",
+			zap.String("name", b.config.Name),
+			zap.String("user_id", issue.UserID),
+			zap.String("username", issue.Username),
+			zap.String("issue", issue.Issue),
+			zap.String("action", issue.Action),
+		)
+	}
+	data := report.GetData()
+	data["store"] = b.config.Name
+	events.Publish(&events.Event{
+		Type:  events.StoreIntegrityChecked,
+		Realm: b.config.Realm,
+		Data:  data,
+	})
+}
+
 // GetLoginIcon returns the instance of the icon associated with the provider.
 func (b *IdentityStore) GetLoginIcon() *icons.LoginIcon {
 	// Add support and credentials recovery to the UI login icon.
-	b.config.LoginIcon.RegistrationEnabled = b.config.RegistrationEnabled
+	// The read-only store accepts no registrations and password resets.
+	b.config.LoginIcon.RegistrationEnabled = b.config.RegistrationEnabled && !b.config.ReadOnly
 	b.config.LoginIcon.UsernameRecoveryEnabled = b.config.UsernameRecoveryEnabled
-	b.config.LoginIcon.PasswordRecoveryEnabled = b.config.PasswordRecoveryEnabled
+	b.config.LoginIcon.PasswordRecoveryEnabled = b.config.PasswordRecoveryEnabled && !b.config.ReadOnly
+	b.config.LoginIcon.MagicLinkEnabled = b.config.MagicLinkEnabled
 	b.config.LoginIcon.ContactSupportEnabled = b.config.ContactSupportEnabled
 	b.config.LoginIcon.SupportLink = b.config.SupportLink
 	b.config.LoginIcon.SupportEmail = b.config.SupportEmail
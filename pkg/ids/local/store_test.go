@@ -20,6 +20,7 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/testutils"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/pwned"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
 	"go.uber.org/zap"
@@ -126,6 +127,18 @@ func TestNewIdentityStore(t *testing.T) {
 			shouldErr: true,
 			err:       errors.ErrIdentityStoreConfigureNameEmpty,
 		},
+		{
+			name: "test invalid password breach check config",
+			config: &Config{
+				Name:                "local_store",
+				Realm:               "local",
+				Path:                filepath.Join(path.Dir(dbPath), "user_db1.json"),
+				PasswordBreachCheck: &pwned.Config{Action: "foo"},
+			},
+			logger:    logutil.NewLogger(),
+			shouldErr: true,
+			err:       errors.ErrPwnedConfigActionInvalid.WithArgs("foo"),
+		},
 		{
 			name: "test empty config realm",
 			config: &Config{
@@ -325,3 +338,73 @@ func TestConfigureIdentityStore(t *testing.T) {
 		})
 	}
 }
+
+func TestReadOnlyIdentityStore(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestReadOnlyIdentityStore")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	b, err := NewIdentityStore(&Config{
+		Name:     "local_store",
+		Realm:    "local",
+		Path:     db.GetPath(),
+		ReadOnly: true,
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("failed creating identity store: %v", err)
+	}
+	if err := b.Configure(); err != nil {
+		t.Fatalf("failed configuring identity store: %v", err)
+	}
+	tests.EvalObjects(t, "read only", true, b.IsReadOnly())
+
+	testcases := []struct {
+		name      string
+		op        operator.Type
+		req       *requests.Request
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "authenticate user",
+			op:   operator.Authenticate,
+			req: &requests.Request{
+				User: requests.User{Username: tests.TestUser1, Password: tests.TestPwd1},
+			},
+		},
+		{
+			name: "change password",
+			op:   operator.ChangePassword,
+			req: &requests.Request{
+				User: requests.User{Username: tests.TestUser1, Email: tests.TestEmail1, Password: "foobar", OldPassword: tests.TestPwd1},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreLocalReadOnly.WithArgs("local_store"),
+		},
+		{
+			name: "add mfa token",
+			op:   operator.AddMfaToken,
+			req: &requests.Request{
+				User: requests.User{Username: tests.TestUser1, Email: tests.TestEmail1},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreLocalReadOnly.WithArgs("local_store"),
+		},
+		{
+			name: "add user",
+			op:   operator.AddUser,
+			req: &requests.Request{
+				User: requests.User{Username: "jsmith", Email: "jsmith@localhost.localdomain", Password: tests.TestPwd1},
+			},
+			shouldErr: true,
+			err:       errors.ErrIdentityStoreLocalReadOnly.WithArgs("local_store"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := b.Request(tc.op, tc.req)
+			tests.EvalErrWithLog(t, err, "request", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/authn/icons"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids/external"
 	"github.com/greenpau/go-authcrunch/pkg/ids/ldap"
 	"github.com/greenpau/go-authcrunch/pkg/ids/local"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
@@ -67,6 +68,15 @@ func NewIdentityStore(cfg *IdentityStoreConfig, logger *zap.Logger) (IdentitySto
 		}
 		config.Name = cfg.Name
 		st, err = ldap.NewIdentityStore(config, logger)
+	case "external":
+		config := &external.Config{}
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, errors.ErrIdentityStoreNewConfig.WithArgs(cfg.Params, err)
+		}
+		config.Name = cfg.Name
+		st, err = external.NewIdentityStore(config, logger)
+	default:
+		st, err = getIdentityStoreDriver(cfg.Kind).NewIdentityStore(cfg, logger)
 	}
 
 	if err != nil {
@@ -95,6 +95,23 @@ func TestNewIdentityStore(t *testing.T) {
 			  "realm": "contoso.com"
 			}`,
 		},
+		{
+			name: "test external identity store",
+			config: &IdentityStoreConfig{
+				Name: "contoso-webhook",
+				Kind: "external",
+				Params: map[string]interface{}{
+					"realm":  "contoso",
+					"url":    "https://auth.contoso.com/verify",
+					"secret": "b0d2b6a2-2a8e-4b7a-9f3e-6f9c1b7b1d8e",
+				},
+			},
+			want: `{
+              "name": "contoso-webhook",
+              "kind": "external",
+              "realm": "contoso"
+            }`,
+		},
 		{
 			name: "test logger nil error",
 			config: &IdentityStoreConfig{
@@ -0,0 +1,204 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/sigv4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	awsKMSSignerName = "aws_kms"
+)
+
+var awsKMSSigningAlgorithms = map[string]string{
+	"RS256": "RSASSA_PKCS1_V1_5_SHA_256",
+	"RS384": "RSASSA_PKCS1_V1_5_SHA_384",
+	"RS512": "RSASSA_PKCS1_V1_5_SHA_512",
+	"ES256": "ECDSA_SHA_256",
+	"ES384": "ECDSA_SHA_384",
+	"ES512": "ECDSA_SHA_512",
+}
+
+// awsKMSSigner signs tokens with an asymmetric AWS KMS key. The credentials
+// are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN environment variables.
+type awsKMSSigner struct {
+	keyID     string
+	region    string
+	endpoint  string
+	client    *http.Client
+	mu        sync.RWMutex
+	publicKey crypto.PublicKey
+}
+
+func newAWSKMSSigner(keyID string) (*awsKMSSigner, error) {
+	if keyID == "" {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(awsKMSSignerName, keyID, "empty key id")
+	}
+	s := &awsKMSSigner{
+		keyID:  keyID,
+		client: &http.Client{Timeout: time.Second * 10},
+	}
+	// The region is a part of key ARN, e.g. arn:aws:kms:us-east-1:111122223333:key/<id>.
+	if parts := strings.Split(keyID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		s.region = parts[3]
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		return nil, errors.ErrCryptoKeySignerRegionNotFound.WithArgs(awsKMSSignerName, keyID)
+	}
+	s.endpoint = os.Getenv("AWS_ENDPOINT_URL_KMS")
+	if s.endpoint == "" {
+		s.endpoint = "https://kms." + s.region + ".amazonaws.com/"
+	}
+	return s, nil
+}
+
+// GetName returns the name of the signer.
+func (s *awsKMSSigner) GetName() string {
+	return awsKMSSignerName
+}
+
+// GetPublicKey fetches the public key of AWS KMS key.
+func (s *awsKMSSigner) GetPublicKey() (crypto.PublicKey, error) {
+	var resp struct {
+		PublicKey string `json:"PublicKey"`
+		KeyUsage  string `json:"KeyUsage"`
+	}
+	req := map[string]interface{}{"KeyId": s.keyID}
+	if err := s.do("GetPublicKey", req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.KeyUsage != "" && resp.KeyUsage != "SIGN_VERIFY" {
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(awsKMSSignerName, resp.KeyUsage)
+	}
+	b, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(awsKMSSignerName, err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(b)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(awsKMSSignerName, err)
+	}
+	if getSignerMethods(publicKey) == nil {
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(awsKMSSignerName, fmt.Sprintf("%T", publicKey))
+	}
+	s.mu.Lock()
+	s.publicKey = publicKey
+	s.mu.Unlock()
+	return publicKey, nil
+}
+
+// GetSigningMethods returns the JWT signing methods supported by AWS KMS key.
+func (s *awsKMSSigner) GetSigningMethods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return getSignerMethods(s.publicKey)
+}
+
+// Sign signs the digest with AWS KMS key.
+func (s *awsKMSSigner) Sign(method string, digest []byte) ([]byte, error) {
+	algo, exists := awsKMSSigningAlgorithms[method]
+	if !exists {
+		return nil, errors.ErrCryptoKeySignerMethod.WithArgs(awsKMSSignerName, method)
+	}
+	var resp struct {
+		Signature string `json:"Signature"`
+	}
+	req := map[string]interface{}{
+		"KeyId":            s.keyID,
+		"Message":          base64.StdEncoding.EncodeToString(digest),
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": algo,
+	}
+	if err := s.do("Sign", req, &resp); err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerSignature.WithArgs(awsKMSSignerName, err)
+	}
+	s.mu.RLock()
+	publicKey := s.publicKey
+	s.mu.RUnlock()
+	if _, ok := publicKey.(*ecdsa.PublicKey); ok {
+		// AWS KMS returns DER-encoded ECDSA signatures.
+		sig, err = encodeECDSASignature(sig, publicKey)
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerSignature.WithArgs(awsKMSSignerName, err)
+		}
+	}
+	return sig, nil
+}
+
+func (s *awsKMSSigner) do(action string, in, out interface{}) error {
+	accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKeyID == "" || secretAccessKey == "" {
+		return errors.ErrCryptoKeySignerCredentials.WithArgs(awsKMSSignerName)
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	sigv4.Sign(req, body, &sigv4.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}, s.region, "kms", time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(awsKMSSignerName, err)
+	}
+	return nil
+}
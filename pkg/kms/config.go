@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
 
 	"os"
 	"sort"
@@ -46,6 +47,14 @@ var (
 		"from":        true,
 		"env":         true,
 		"as":          true,
+		"jwks":        true,
+		"vault":       true,
+		"transit":     true,
+		"pkcs11":      true,
+		"aws_kms":     true,
+		"gcp_kms":     true,
+		"realm":       true,
+		"algorithms":  true,
 	}
 	reservedUsageKeywords = map[string]bool{
 		"sign":        true,
@@ -66,9 +75,9 @@ type CryptoKeyConfig struct {
 	Usage string `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
 	// TokenName is the token name associated with the key.
 	TokenName string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
-	// Source is either config or env.
+	// Source is either config, env, jwks, vault, pkcs11, aws_kms, or gcp_kms.
 	Source string `json:"source,omitempty" xml:"source,omitempty" yaml:"source,omitempty"`
-	// Algorithm is either hmac, rsa, or ecdsa.
+	// Algorithm is either hmac, rsa, ecdsa, jwks, transit, or signer.
 	Algorithm string `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
 	// EnvVarName is the name of environment variables holding either the value of
 	// a key or the path a directory or file containing a key.
@@ -83,6 +92,42 @@ type CryptoKeyConfig struct {
 	FilePath string `json:"file_path,omitempty" xml:"file_path,omitempty" yaml:"file_path,omitempty"`
 	// DirPath is the path to a directory containing crypto keys.
 	DirPath string `json:"dir_path,omitempty" xml:"dir_path,omitempty" yaml:"dir_path,omitempty"`
+	// JwksURL is the URL of a remote JWKS endpoint, e.g. the jwks_uri of
+	// an OpenID Connect provider, holding the keys for token verification.
+	JwksURL string `json:"jwks_url,omitempty" xml:"jwks_url,omitempty" yaml:"jwks_url,omitempty"`
+	// JwksRefreshInterval is the interval in seconds for refetching the keys
+	// from JwksURL. Defaults to 3600.
+	JwksRefreshInterval int `json:"jwks_refresh_interval,omitempty" xml:"jwks_refresh_interval,omitempty" yaml:"jwks_refresh_interval,omitempty"`
+	// VaultPath is the reference to a field in Vault KV secret holding either
+	// PEM-encoded key or shared secret, e.g. authp/jwt#signing_key.
+	VaultPath string `json:"vault_path,omitempty" xml:"vault_path,omitempty" yaml:"vault_path,omitempty"`
+	// VaultTransitKey is the name of Vault Transit key signing the tokens.
+	VaultTransitKey string `json:"vault_transit_key,omitempty" xml:"vault_transit_key,omitempty" yaml:"vault_transit_key,omitempty"`
+	// SignerURI is the reference to the key held by an external signer, i.e.
+	// PKCS #11 URI, AWS KMS key ARN, or Cloud KMS key version name.
+	SignerURI string `json:"signer_uri,omitempty" xml:"signer_uri,omitempty" yaml:"signer_uri,omitempty"`
+	// TokenEncryptionKey is the secret for the encryption of the issued
+	// tokens. When it is set, the tokens are JWE.
+	TokenEncryptionKey string `json:"token_encryption_key,omitempty" xml:"token_encryption_key,omitempty" yaml:"token_encryption_key,omitempty"`
+	// TokenEncryptionOnly indicates whether the claims are encrypted instead
+	// of signed and encrypted.
+	TokenEncryptionOnly bool `json:"token_encryption_only,omitempty" xml:"token_encryption_only,omitempty" yaml:"token_encryption_only,omitempty"`
+	// TokenStore is the name of the token store holding the claims of the
+	// issued tokens. When it is set, the tokens are opaque.
+	TokenStore string `json:"token_store,omitempty" xml:"token_store,omitempty" yaml:"token_store,omitempty"`
+	// RevocationStore is the name of the token store holding the revocation
+	// list of the JWT and JWE tokens verified by the key. The store is
+	// shared by the portals issuing and the gatekeepers verifying the tokens.
+	RevocationStore string `json:"revocation_store,omitempty" xml:"revocation_store,omitempty" yaml:"revocation_store,omitempty"`
+	// Realm binds the key to the realm of an identity store or an identity
+	// provider. The tokens of the users of the realm are signed with the
+	// keys bound to the realm, if any. The keys without realm sign the
+	// tokens of the other realms.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	// Algorithms is the allowlist of the token signing algorithms, e.g.
+	// RS256, accepted by the key. When it is empty, the algorithms of the
+	// key type are accepted.
+	Algorithms []string `json:"algorithms,omitempty" xml:"algorithms,omitempty" yaml:"algorithms,omitempty"`
 	// TokenLifetime is the expected token grant lifetime in seconds.
 	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
 	// Secret is the shared key used with HMAC algorithm.
@@ -125,6 +170,18 @@ func (k *CryptoKeyConfig) ToString() string {
 	if k.DirPath != "" {
 		sb.WriteString(", dir path: " + k.DirPath)
 	}
+	if k.JwksURL != "" {
+		sb.WriteString(", jwks url: " + k.JwksURL)
+	}
+	if k.VaultPath != "" {
+		sb.WriteString(", vault path: " + k.VaultPath)
+	}
+	if k.VaultTransitKey != "" {
+		sb.WriteString(", vault transit key: " + k.VaultTransitKey)
+	}
+	if k.SignerURI != "" {
+		sb.WriteString(", signer uri: " + k.SignerURI)
+	}
 	if k.validated || k.parsed {
 		sb.WriteString(", flags:")
 		if k.parsed {
@@ -140,6 +197,25 @@ func (k *CryptoKeyConfig) ToString() string {
 	if k.TokenLifetime != 0 {
 		sb.WriteString(fmt.Sprintf(" lifetime=%d", k.TokenLifetime))
 	}
+	if k.TokenEncryptionKey != "" {
+		if k.TokenEncryptionOnly {
+			sb.WriteString(" encryption=encrypt-only")
+		} else {
+			sb.WriteString(" encryption=sign-encrypt")
+		}
+	}
+	if k.TokenStore != "" {
+		sb.WriteString(" store=" + k.TokenStore)
+	}
+	if k.RevocationStore != "" {
+		sb.WriteString(" revocation=" + k.RevocationStore)
+	}
+	if k.Realm != "" {
+		sb.WriteString(" realm=" + k.Realm)
+	}
+	if len(k.Algorithms) > 0 {
+		sb.WriteString(" algorithms=" + strings.Join(k.Algorithms, ","))
+	}
 	return sb.String()
 }
 
@@ -174,15 +250,52 @@ func (k *CryptoKeyConfig) validate() error {
 		default:
 			return fmt.Errorf("key source type %q for env is invalid", k.EnvVarType)
 		}
+	case "jwks":
+		if k.JwksURL == "" {
+			return fmt.Errorf("key source jwks has no url")
+		}
+		if !strings.HasPrefix(k.JwksURL, "https://") && !strings.HasPrefix(k.JwksURL, "http://") {
+			return fmt.Errorf("key source jwks url %q is invalid", k.JwksURL)
+		}
+		if k.Usage != "verify" {
+			return fmt.Errorf("key source jwks supports verify usage only")
+		}
+	case "vault":
+		switch {
+		case k.VaultTransitKey != "":
+		case k.VaultPath != "":
+			if _, _, err := vault.ParseReference("vault:" + k.VaultPath); err != nil {
+				return fmt.Errorf("key source vault path %q is invalid", k.VaultPath)
+			}
+		default:
+			return fmt.Errorf("key source vault has no path or transit key")
+		}
+	case "pkcs11", "aws_kms", "gcp_kms":
+		if k.SignerURI == "" {
+			return fmt.Errorf("key source %s has no key reference", k.Source)
+		}
 	default:
 		return fmt.Errorf("key source %q is invalid", k.Source)
 	}
 
 	switch k.Algorithm {
-	case "hmac", "rsa", "ecdsa", "":
+	case "hmac", "rsa", "ecdsa", "jwks", "transit", "signer", "":
 	default:
 		return fmt.Errorf("key algorithm %q is invalid", k.Algorithm)
 	}
+
+	for _, alg := range k.Algorithms {
+		switch {
+		case strings.EqualFold(alg, "none"):
+			return fmt.Errorf("key %q algorithm allowlist must not include none", k.ID)
+		case signingMethods[alg] == "":
+			return fmt.Errorf("key %q algorithm allowlist entry %q is unsupported", k.ID, alg)
+		case k.Algorithm == "hmac" && signingMethods[alg] != "hmac":
+			return fmt.Errorf("key %q algorithm allowlist entry %q is not supported by shared secret", k.ID, alg)
+		case k.Algorithm != "hmac" && k.Algorithm != "" && signingMethods[alg] == "hmac":
+			return fmt.Errorf("key %q algorithm allowlist entry %q requires shared secret", k.ID, alg)
+		}
+	}
 	k.validated = true
 	return nil
 }
@@ -330,6 +443,12 @@ func ParseCryptoKeyConfigs(cfg string) ([]*CryptoKeyConfig, error) {
 				nk.ID = kid
 				nk.TokenName = curKey.TokenName
 				nk.TokenLifetime = curKey.TokenLifetime
+				nk.TokenEncryptionKey = curKey.TokenEncryptionKey
+				nk.TokenEncryptionOnly = curKey.TokenEncryptionOnly
+				nk.TokenStore = curKey.TokenStore
+				nk.RevocationStore = curKey.RevocationStore
+				nk.Realm = curKey.Realm
+				nk.Algorithms = curKey.Algorithms
 				key = nk
 				keys = append(keys, nk)
 				cursor = len(keys) - 1
@@ -369,10 +488,29 @@ func ParseCryptoKeyConfigs(cfg string) ([]*CryptoKeyConfig, error) {
 						return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
 					}
 					key.TokenLifetime = i
+				case "encrypt", "encrypt-only":
+					key.TokenEncryptionKey = args[i+2]
+					key.TokenEncryptionOnly = args[i+1] == "encrypt-only"
+				case "store":
+					key.TokenStore = args[i+2]
+				case "revocation":
+					key.RevocationStore = args[i+2]
 				default:
 					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "unknown key token setting")
 				}
 				i += 2
+			case "realm":
+				key.Realm = args[i+1]
+				i++
+			case "algorithms":
+				for _, arg := range args[i+1:] {
+					for _, alg := range strings.Split(arg, ",") {
+						if alg = strings.TrimSpace(alg); alg != "" {
+							key.Algorithms = append(key.Algorithms, alg)
+						}
+					}
+				}
+				i = max
 			case "verify", "sign", "sign-verify", "auto":
 				if key.Usage != "" {
 					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "duplicate key id")
@@ -397,6 +535,21 @@ func ParseCryptoKeyConfigs(cfg string) ([]*CryptoKeyConfig, error) {
 					case "directory":
 						key.Source = "config"
 						key.DirPath = args[i+3]
+					case "jwks":
+						key.Source = "jwks"
+						key.Algorithm = "jwks"
+						key.JwksURL = args[i+3]
+					case "vault":
+						key.Source = "vault"
+						key.VaultPath = args[i+3]
+					case "transit":
+						key.Source = "vault"
+						key.Algorithm = "transit"
+						key.VaultTransitKey = args[i+3]
+					case "pkcs11", "aws_kms", "gcp_kms":
+						key.Source = args[i+2]
+						key.Algorithm = "signer"
+						key.SignerURI = args[i+3]
 					case "env":
 						key.Source = "env"
 						key.EnvVarName = args[i+3]
@@ -414,6 +414,50 @@ func TestParseCryptoKeyConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "key bound to realm with algorithm allowlist",
+			config: `
+                crypto key k9738a405e99 sign-verify foobar
+                crypto key k9738a405e99 realm contoso
+                crypto key k9738a405e99 algorithms HS256, HS384
+            `,
+			want: map[string]interface{}{
+				"config_count": 1,
+				"configs": []*CryptoKeyConfig{
+					{
+						ID:            "k9738a405e99",
+						Usage:         "sign-verify",
+						TokenName:     "access_token",
+						Source:        "config",
+						Algorithm:     "hmac",
+						Secret:        "foobar",
+						Realm:         "contoso",
+						Algorithms:    []string{"HS256", "HS384"},
+						TokenLifetime: 900,
+						parsed:        true,
+						validated:     true,
+					},
+				},
+			},
+		},
+		{
+			name: "shared key with rsa algorithm in allowlist",
+			config: `
+                crypto key k9738a405e99 sign-verify foobar
+                crypto key k9738a405e99 algorithms RS256
+            `,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0, `key "k9738a405e99" algorithm allowlist entry "RS256" is not supported by shared secret`),
+		},
+		{
+			name: "key with none algorithm in allowlist",
+			config: `
+                crypto key k9738a405e99 verify from file ./../../testdata/rskeys/test_2_pub.pem
+                crypto key k9738a405e99 algorithms RS256 none
+            `,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0, `key "k9738a405e99" algorithm allowlist must not include none`),
+		},
 		{
 			name: "load keys from directory path",
 			config: `
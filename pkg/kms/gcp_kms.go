@@ -0,0 +1,247 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	gcpKMSSignerName = "gcp_kms"
+)
+
+var (
+	gcpKMSEndpoint         = "https://cloudkms.googleapis.com/v1/"
+	gcpMetadataTokenURL    = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpKMSSigningAlgorithm = map[string]string{
+		"RSA_SIGN_PKCS1_2048_SHA256": "RS256",
+		"RSA_SIGN_PKCS1_3072_SHA256": "RS256",
+		"RSA_SIGN_PKCS1_4096_SHA256": "RS256",
+		"RSA_SIGN_PKCS1_4096_SHA512": "RS512",
+		"EC_SIGN_P256_SHA256":        "ES256",
+		"EC_SIGN_P384_SHA384":        "ES384",
+	}
+	gcpKMSDigestNames = map[string]string{
+		"RS256": "sha256",
+		"RS512": "sha512",
+		"ES256": "sha256",
+		"ES384": "sha384",
+	}
+)
+
+// gcpKMSSigner signs tokens with an asymmetric Google Cloud KMS key version.
+// The access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN environment
+// variable or, when it is not set, from the metadata server.
+type gcpKMSSigner struct {
+	name        string
+	client      *http.Client
+	mu          sync.RWMutex
+	publicKey   crypto.PublicKey
+	method      string
+	accessToken string
+	expiresAt   time.Time
+}
+
+func newGCPKMSSigner(name string) (*gcpKMSSigner, error) {
+	// The name of the key version, e.g.
+	// projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>/cryptoKeyVersions/<version>.
+	parts := strings.Split(name, "/")
+	if len(parts) != 10 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" ||
+		parts[6] != "cryptoKeys" || parts[8] != "cryptoKeyVersions" {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(gcpKMSSignerName, name, "not a crypto key version name")
+	}
+	s := &gcpKMSSigner{
+		name:   name,
+		client: &http.Client{Timeout: time.Second * 10},
+	}
+	return s, nil
+}
+
+// GetName returns the name of the signer.
+func (s *gcpKMSSigner) GetName() string {
+	return gcpKMSSignerName
+}
+
+// GetPublicKey fetches the public key of Cloud KMS key version.
+func (s *gcpKMSSigner) GetPublicKey() (crypto.PublicKey, error) {
+	var resp struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := s.do(http.MethodGet, s.name+"/publicKey", nil, &resp); err != nil {
+		return nil, err
+	}
+	method, exists := gcpKMSSigningAlgorithm[resp.Algorithm]
+	if !exists {
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(gcpKMSSignerName, resp.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(resp.Pem))
+	if block == nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(gcpKMSSignerName, "not pem encoded")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(gcpKMSSignerName, err)
+	}
+	s.mu.Lock()
+	s.publicKey = publicKey
+	s.method = method
+	s.mu.Unlock()
+	return publicKey, nil
+}
+
+// GetSigningMethods returns the JWT signing method of Cloud KMS key version.
+// Unlike other backends, the key version is bound to a single algorithm.
+func (s *gcpKMSSigner) GetSigningMethods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.method == "" {
+		return nil
+	}
+	return []string{s.method}
+}
+
+// Sign signs the digest with Cloud KMS key version.
+func (s *gcpKMSSigner) Sign(method string, digest []byte) ([]byte, error) {
+	s.mu.RLock()
+	publicKey, keyMethod := s.publicKey, s.method
+	s.mu.RUnlock()
+	if method != keyMethod {
+		return nil, errors.ErrCryptoKeySignerMethod.WithArgs(gcpKMSSignerName, method)
+	}
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	req := map[string]interface{}{
+		"digest": map[string]string{
+			gcpKMSDigestNames[method]: base64.StdEncoding.EncodeToString(digest),
+		},
+	}
+	if err := s.do(http.MethodPost, s.name+":asymmetricSign", req, &resp); err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerSignature.WithArgs(gcpKMSSignerName, err)
+	}
+	if _, ok := publicKey.(*ecdsa.PublicKey); ok {
+		// Cloud KMS returns DER-encoded ECDSA signatures.
+		sig, err = encodeECDSASignature(sig, publicKey)
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerSignature.WithArgs(gcpKMSSignerName, err)
+		}
+	}
+	return sig, nil
+}
+
+func (s *gcpKMSSigner) getAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	s.mu.RLock()
+	token, expiresAt := s.accessToken, s.expiresAt
+	s.mu.RUnlock()
+	if token != "" && time.Now().Before(expiresAt) {
+		return token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", errors.ErrCryptoKeySignerCredentials.WithArgs(gcpKMSSignerName)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.ErrCryptoKeySignerCredentials.WithArgs(gcpKMSSignerName)
+	}
+	var data struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+	}
+	if data.AccessToken == "" {
+		return "", errors.ErrCryptoKeySignerCredentials.WithArgs(gcpKMSSignerName)
+	}
+
+	s.mu.Lock()
+	s.accessToken = data.AccessToken
+	// Refresh the token a minute before it expires.
+	s.expiresAt = time.Now().Add(time.Duration(data.ExpiresIn-60) * time.Second)
+	s.mu.Unlock()
+	return data.AccessToken, nil
+}
+
+func (s *gcpKMSSigner) do(method, path string, in, out interface{}) error {
+	token, err := s.getAccessToken()
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, gcpKMSEndpoint+path, body)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(respBody))))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return errors.ErrCryptoKeySignerRequest.WithArgs(gcpKMSSignerName, err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+)
+
+// maxTokenHeaders is the maximum number of the token headers kept in cache.
+// The tokens signed by the same key share the same header, so the number of
+// distinct headers of the verified tokens is small.
+const maxTokenHeaders = 1024
+
+// tokenHeader is the JOSE header of a signed token.
+type tokenHeader struct {
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	// The signing method and the header fields passed to the key function
+	// when the token is verified with the header.
+	method jwtlib.SigningMethod
+	fields map[string]interface{}
+}
+
+var (
+	tokenHeaders   = make(map[string]*tokenHeader)
+	tokenHeadersMu sync.RWMutex
+	tokenBufPool   = sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, 0, 256)
+			return &b
+		},
+	}
+)
+
+// parseTokenHeader returns the header of a signed token. The headers of the
+// verified tokens are served from cache, so that the header of the repeated
+// tokens is decoded once. It returns nil when the header is malformed.
+func parseTokenHeader(s string) *tokenHeader {
+	i := strings.IndexByte(s, '.')
+	if i < 1 {
+		return nil
+	}
+	segment := s[:i]
+
+	tokenHeadersMu.RLock()
+	h, exists := tokenHeaders[segment]
+	tokenHeadersMu.RUnlock()
+	if exists {
+		return h
+	}
+
+	bp := tokenBufPool.Get().(*[]byte)
+	defer tokenBufPool.Put(bp)
+	// The buffer holds both the encoded and the decoded header.
+	n := base64.RawURLEncoding.DecodedLen(len(segment))
+	if cap(*bp) < len(segment)+n {
+		*bp = make([]byte, len(segment)+n)
+	}
+	src := append((*bp)[:0], segment...)
+	dst := (*bp)[len(segment) : len(segment)+n]
+	n, err := base64.RawURLEncoding.Decode(dst, src)
+	if err != nil {
+		return nil
+	}
+	h = &tokenHeader{}
+	if err := json.Unmarshal(dst[:n], h); err != nil {
+		return nil
+	}
+	h.method = jwtlib.GetSigningMethod(h.Alg)
+	h.fields = map[string]interface{}{"alg": h.Alg}
+	if h.Kid != "" {
+		h.fields["kid"] = h.Kid
+	}
+	return h
+}
+
+// cacheTokenHeader caches the header of a verified token. The headers of the
+// tokens failing verification are never cached, i.e. the cache cannot be
+// filled with the headers of forged tokens. When the cache is full, a random
+// entry is evicted.
+func cacheTokenHeader(s string, h *tokenHeader) {
+	i := strings.IndexByte(s, '.')
+	if i < 1 || h == nil {
+		return
+	}
+	tokenHeadersMu.Lock()
+	defer tokenHeadersMu.Unlock()
+	if _, exists := tokenHeaders[s[:i]]; exists {
+		return
+	}
+	if len(tokenHeaders) >= maxTokenHeaders {
+		// The iteration order of a map is randomized.
+		for k := range tokenHeaders {
+			delete(tokenHeaders, k)
+			break
+		}
+	}
+	tokenHeaders[strings.Clone(s[:i])] = h
+}
+
+// acceptsMethod returns false when the key cannot verify the tokens signed
+// with the provided method. The check is performed prior to parsing the
+// token with the key.
+func (k *CryptoKey) acceptsMethod(alg string) bool {
+	switch k.Config.Algorithm {
+	case "hmac", "rsa", "ecdsa":
+		return signingMethods[alg] == k.Config.Algorithm
+	case "jwks", "signer":
+		return signingMethods[alg] == "rsa" || signingMethods[alg] == "ecdsa"
+	}
+	return true
+}
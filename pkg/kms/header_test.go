@@ -0,0 +1,167 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func newTestHeaderKeyStore(t testing.TB, n int) *CryptoKeyStore {
+	var lines []string
+	for i := 0; i < n; i++ {
+		lines = append(lines, fmt.Sprintf("crypto key k%d sign-verify 0e2fdcf8-6868-41a7-884b-%012d", i, i))
+	}
+	cfgs, err := ParseCryptoKeyConfigs(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	return ks
+}
+
+func TestParseTokenHeader(t *testing.T) {
+	ks := newTestHeaderKeyStore(t, 1)
+	usr := newTestUser()
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name  string
+		token string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "signed token",
+			token: usr.Token,
+			want:  map[string]interface{}{"alg": "HS512", "kid": "k0"},
+		},
+		{
+			name:  "token without header",
+			token: "foobar",
+		},
+		{
+			name:  "token with malformed header",
+			token: "!!!.foo.bar",
+		},
+		{
+			name:  "token with malformed header json",
+			token: "Zm9vYmFy.foo.bar",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			var got map[string]interface{}
+			if h := parseTokenHeader(tc.token); h != nil {
+				got = h.fields
+			}
+			tests.EvalObjectsWithLog(t, "header", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestCacheTokenHeader(t *testing.T) {
+	ks := newTestHeaderKeyStore(t, 1)
+	usr := newTestUser()
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	segment := usr.Token[:strings.IndexByte(usr.Token, '.')]
+	cached := func() bool {
+		tokenHeadersMu.RLock()
+		defer tokenHeadersMu.RUnlock()
+		_, exists := tokenHeaders[segment]
+		return exists
+	}
+
+	// The header of a forged token is not cached.
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = usr.Token[:strings.LastIndexByte(usr.Token, '.')] + ".Zm9vYmFy"
+	if _, err := ks.ParseTokenClaims(ar); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	tests.EvalObjects(t, "forged token header cached", false, cached())
+
+	ar.Token.Payload = usr.Token
+	if _, err := ks.ParseTokenClaims(ar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "verified token header cached", true, cached())
+
+	// The cache is bounded.
+	for i := 0; i < maxTokenHeaders+10; i++ {
+		cacheTokenHeader(fmt.Sprintf("header%d.foo.bar", i), &tokenHeader{})
+	}
+	tokenHeadersMu.RLock()
+	n := len(tokenHeaders)
+	tokenHeadersMu.RUnlock()
+	tests.EvalObjects(t, "cache size", maxTokenHeaders, n)
+}
+
+func TestParseTokenClaimsWithKeyID(t *testing.T) {
+	ks := newTestHeaderKeyStore(t, 3)
+	usr := newTestUser()
+	usr.Authenticator.Realm = "local"
+	k := ks.verifyKeys[2]
+	response, err := k.sign(nil, usr.AsMap())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := response.(string)
+
+	tests.EvalObjects(t, "first key", "k2", ks.getVerifyKeys(parseTokenHeader(token))[0].Config.ID)
+
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = token
+	claims, err := ks.ParseTokenClaims(ar)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "sub", "smithj@outlook.com", claims["sub"])
+}
+
+func BenchmarkParseToken(b *testing.B) {
+	for _, n := range []int{1, 10} {
+		b.Run(fmt.Sprintf("keys_%d", n), func(b *testing.B) {
+			ks := newTestHeaderKeyStore(b, n)
+			usr := newTestUser()
+			response, err := ks.verifyKeys[n-1].sign(nil, usr.AsMap())
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = response.(string)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ks.ParseToken(ar); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
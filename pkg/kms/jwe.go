@@ -0,0 +1,214 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var aesKeyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// tokenEncryption encrypts the tokens issued with a key, so that the claims
+// are not exposed to the browser. The tokens are JWE in compact
+// serialization, with the content encrypted using A256GCM and the content
+// encryption key wrapped with A256KW.
+type tokenEncryption struct {
+	// The key-encryption key is SHA-256 digest of the configured secret.
+	kek []byte
+	// When only is true, the claims are encrypted instead of being signed.
+	// Otherwise, the signed token is encrypted, i.e. nested JWT.
+	only bool
+}
+
+func newTokenEncryption(cfg *CryptoKeyConfig) (*tokenEncryption, error) {
+	secret, err := credentials.ResolveSecret(cfg.TokenEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.ErrCryptoKeyTokenEncryption.WithArgs("empty secret")
+	}
+	kek := sha256.Sum256([]byte(secret))
+	return &tokenEncryption{
+		kek:  kek[:],
+		only: cfg.TokenEncryptionOnly,
+	}, nil
+}
+
+// isEncryptedToken returns true when the token is JWE in compact
+// serialization.
+func isEncryptedToken(s string) bool {
+	return strings.Count(s, ".") == 4
+}
+
+func (te *tokenEncryption) encrypt(payload []byte) (string, error) {
+	header := map[string]interface{}{
+		"typ": "JWT",
+		"alg": "A256KW",
+		"enc": "A256GCM",
+	}
+	if !te.only {
+		header["cty"] = "JWT"
+	}
+	jh, err := json.Marshal(header)
+	if err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	cek := make([]byte, 32)
+	if _, err := rand.Read(cek); err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	wrappedKey, err := aesKeyWrap(te.kek, cek)
+	if err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", errors.ErrCryptoKeyTokenEncryption.WithArgs(err)
+	}
+	protected := base64.RawURLEncoding.EncodeToString(jh)
+	sealed := aead.Seal(nil, iv, payload, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(wrappedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+func (te *tokenEncryption) decrypt(s string) ([]byte, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 5 {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs("malformed token")
+	}
+	var decoded [5][]byte
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+		}
+		decoded[i] = b
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+	}
+	if header["alg"] != "A256KW" || header["enc"] != "A256GCM" {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(fmt.Sprintf("unsupported alg %v or enc %v", header["alg"], header["enc"]))
+	}
+	if _, nested := header["cty"]; nested == te.only {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs("unexpected content type")
+	}
+	cek, err := aesKeyUnwrap(te.kek, decoded[1])
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+	}
+	if len(decoded[2]) != aead.NonceSize() {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs("invalid iv")
+	}
+	payload, err := aead.Open(nil, decoded[2], append(decoded[3], decoded[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+	}
+	return payload, nil
+}
+
+// aesKeyWrap wraps the key with AES Key Wrap algorithm (RFC 3394).
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, fmt.Errorf("key wrap input must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(key) / 8
+	out := make([]byte, 8*(n+1))
+	copy(out[:8], aesKeyWrapIV)
+	copy(out[8:], key)
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b[:8], out[:8])
+			copy(b[8:], out[8*i:8*i+8])
+			block.Encrypt(b, b)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(out[8*i:8*i+8], b[8:])
+		}
+	}
+	return out, nil
+}
+
+// aesKeyUnwrap unwraps the key wrapped with AES Key Wrap algorithm.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, fmt.Errorf("wrapped key must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	r := make([]byte, 8*n)
+	copy(r, wrapped[8:])
+	b := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], r[8*(i-1):8*i])
+			block.Decrypt(b, b)
+			copy(a, b[:8])
+			copy(r[8*(i-1):8*i], b[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, aesKeyWrapIV) != 1 {
+		return nil, fmt.Errorf("key unwrap integrity check failed")
+	}
+	return r, nil
+}
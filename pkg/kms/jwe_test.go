@@ -0,0 +1,136 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestAESKeyWrap(t *testing.T) {
+	// The test vector of RFC 3394, section 4.6.
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	wrapped, err := aesKeyWrap(kek, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "wrapped key", "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21", hex.EncodeToString(wrapped))
+
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "unwrapped key", key, unwrapped)
+
+	wrapped[0] ^= 0x01
+	if _, err := aesKeyUnwrap(kek, wrapped); err == nil {
+		t.Fatalf("expected integrity check error")
+	}
+}
+
+func TestEncryptedTokens(t *testing.T) {
+	testcases := []struct {
+		name        string
+		config      string
+		verifier    string
+		contentType bool
+		shouldErr   bool
+		err         error
+	}{
+		{
+			name: "sign and encrypt token",
+			config: "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+			contentType: true,
+		},
+		{
+			name: "encrypt token claims without signing",
+			config: "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt-only 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+		},
+		{
+			name: "reject encrypted token with wrong encryption key",
+			config: "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+			verifier: "crypto key verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+				"crypto key token encrypt 0a5c8e3a-1b52-4f64-a44b-d0b0b3f1e2a9",
+			contentType: true,
+			shouldErr:   true,
+			err:         errors.ErrCryptoKeyStoreParseTokenFailed,
+		},
+		{
+			name:        "reject encrypted token without encryption key",
+			config:      "crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\ncrypto key token encrypt 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51",
+			verifier:    "crypto key verify 0e2fdcf8-6868-41a7-884b-7308795fc286",
+			contentType: true,
+			shouldErr:   true,
+			err:         errors.ErrCryptoKeyStoreParseTokenFailed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			newKeyStore := func(s string) *CryptoKeyStore {
+				cfgs, err := ParseCryptoKeyConfigs(s)
+				if err != nil {
+					t.Fatalf("failed parsing configs: %v", err)
+				}
+				ks := NewCryptoKeyStore()
+				if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+					t.Fatalf("failed adding keys: %v", err)
+				}
+				return ks
+			}
+
+			signer := newKeyStore(tc.config)
+			verifier := signer
+			if tc.verifier != "" {
+				verifier = newKeyStore(tc.verifier)
+			}
+
+			tests.EvalObjects(t, "token encryption", true, signer.HasTokenEncryption())
+			tests.EvalObjects(t, "token encryption without encryption key", false, newKeyStore("crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286").HasTokenEncryption())
+
+			usr := newTestUser()
+			if err := signer.SignToken(nil, nil, usr); err != nil {
+				t.Fatalf("failed signing token: %v", err)
+			}
+
+			// The claims are not readable from the token.
+			parts := strings.Split(usr.Token, ".")
+			tests.EvalObjects(t, "token parts", 5, len(parts))
+			header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+			tests.EvalObjects(t, "nested token", tc.contentType, strings.Contains(string(header), `"cty":"JWT"`))
+			if strings.Contains(usr.Token, base64.RawURLEncoding.EncodeToString([]byte("smithj"))) {
+				t.Fatalf("token exposes claims")
+			}
+
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = usr.Token
+			parsedUser, err := verifier.ParseToken(ar)
+			if tests.EvalErrWithLog(t, err, "parse token", tc.shouldErr, tc.err, []string{}) {
+				return
+			}
+			tests.EvalObjects(t, "subject", usr.Claims.Subject, parsedUser.Claims.Subject)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultJwksRefreshInterval = 3600
+	// minJwksRefreshInterval limits how often the keys are refetched when a
+	// token references an unknown key id.
+	minJwksRefreshInterval = 30 * time.Second
+)
+
+// jwksKey is a public key as it appears in a remote JWKS document.
+type jwksKey struct {
+	KeyID   string `json:"kid,omitempty"`
+	KeyType string `json:"kty,omitempty"`
+	Use     string `json:"use,omitempty"`
+	Alg     string `json:"alg,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+}
+
+// jwksKeySet holds the public keys fetched from a remote JWKS endpoint. The
+// keys are fetched on first use and refreshed periodically or when a token
+// references a key id not yet seen, e.g. after key rotation.
+type jwksKeySet struct {
+	mu              sync.RWMutex
+	url             string
+	refreshInterval time.Duration
+	fetchedAt       time.Time
+	keys            map[string]interface{}
+	client          *http.Client
+	// fetching is closed when the refresh in progress, if any, completes.
+	fetching chan struct{}
+}
+
+func newJwksKeySet(cfg *CryptoKeyConfig) *jwksKeySet {
+	ks := &jwksKeySet{
+		url:             cfg.JwksURL,
+		refreshInterval: time.Duration(defaultJwksRefreshInterval) * time.Second,
+		keys:            make(map[string]interface{}),
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg.JwksRefreshInterval > 0 {
+		ks.refreshInterval = time.Duration(cfg.JwksRefreshInterval) * time.Second
+	}
+	return ks
+}
+
+// getKey returns the public key with the provided key id. When the token has
+// no key id, the key is returned only if the key set has a single key.
+func (ks *jwksKeySet) getKey(kid string) (interface{}, error) {
+	ks.mu.RLock()
+	key, found := ks.lookup(kid)
+	age := time.Since(ks.fetchedAt)
+	ks.mu.RUnlock()
+
+	if found && age < ks.refreshInterval {
+		return key, nil
+	}
+	if !found && age < minJwksRefreshInterval {
+		return nil, errors.ErrCryptoKeyJwksKeyNotFound.WithArgs(kid, ks.url)
+	}
+
+	if err := ks.refresh(); err != nil {
+		if found {
+			// Keep using the stale key when the endpoint is unavailable.
+			return key, nil
+		}
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if key, found := ks.lookup(kid); found {
+		return key, nil
+	}
+	return nil, errors.ErrCryptoKeyJwksKeyNotFound.WithArgs(kid, ks.url)
+}
+
+func (ks *jwksKeySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" {
+		if len(ks.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, found := ks.keys[kid]
+	return key, found
+}
+
+// refresh fetches the keys and swaps the key set. The lock is not held
+// during the fetch, so that the verifications with the known keys proceed.
+// The concurrent requests wait for the refresh in progress.
+func (ks *jwksKeySet) refresh() error {
+	ks.mu.Lock()
+	if fetching := ks.fetching; fetching != nil {
+		ks.mu.Unlock()
+		<-fetching
+		return nil
+	}
+	if time.Since(ks.fetchedAt) < minJwksRefreshInterval {
+		// Another request has just refreshed the keys.
+		ks.mu.Unlock()
+		return nil
+	}
+	fetching := make(chan struct{})
+	ks.fetching = fetching
+	ks.fetchedAt = time.Now()
+	ks.mu.Unlock()
+
+	keys, err := ks.fetch()
+
+	ks.mu.Lock()
+	if err == nil {
+		ks.keys = keys
+	}
+	ks.fetching = nil
+	ks.mu.Unlock()
+	close(fetching)
+	return err
+}
+
+// fetch returns the signing keys published at the JWKS endpoint. The keys
+// of the unsupported types and the malformed keys are skipped.
+func (ks *jwksKeySet) fetch() (map[string]interface{}, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyJwksFetch.WithArgs(ks.url, err)
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.ErrCryptoKeyJwksFetch.WithArgs(ks.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.ErrCryptoKeyJwksFetch.WithArgs(ks.url, resp.Status)
+	}
+
+	var doc struct {
+		Keys []*jwksKey `json:"keys"`
+	}
+	if err := json.Unmarshal(respBody, &doc); err != nil {
+		return nil, errors.ErrCryptoKeyJwksFetch.WithArgs(ks.url, err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.getPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.ErrCryptoKeyJwksKeysNotFound.WithArgs(ks.url)
+	}
+	return keys, nil
+}
+
+func (k *jwksKey) getPublicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeJwksInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding modulus: %v", err)
+		}
+		e, err := decodeJwksInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding exponent: %v", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		pk := &ecdsa.PublicKey{}
+		switch k.Curve {
+		case "P-256":
+			pk.Curve = elliptic.P256()
+		case "P-384":
+			pk.Curve = elliptic.P384()
+		case "P-521":
+			pk.Curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeJwksInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding x coordinate: %v", err)
+		}
+		y, err := decodeJwksInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding y coordinate: %v", err)
+		}
+		pk.X = x
+		pk.Y = y
+		return pk, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+func decodeJwksInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// newJwksKey returns the public part of a signing key in JWKS format. The
+// keys based on shared secrets are never published.
+func newJwksKey(k *CryptoKey) *jwksKey {
+	if k.Sign == nil || k.Sign.Token == nil {
+		return nil
+	}
+	jk := &jwksKey{
+		KeyID: k.Sign.Token.ID,
+		Use:   "sig",
+		Alg:   k.Sign.Token.DefaultMethod,
+	}
+	var publicKey interface{}
+	switch pk := k.Sign.Secret.(type) {
+	case *rsa.PrivateKey:
+		publicKey = &pk.PublicKey
+	case *ecdsa.PrivateKey:
+		publicKey = &pk.PublicKey
+	}
+	if k.signer != nil {
+		pk, err := k.signer.getPublicKey()
+		if err != nil {
+			return nil
+		}
+		publicKey = pk
+	}
+	if k.transit != nil {
+		// The latest version of Vault Transit key signs new tokens.
+		_, version, err := k.transit.client.GetTransitKey(k.transit.name)
+		if err != nil {
+			return nil
+		}
+		pk, err := k.transit.client.GetTransitPublicKey(k.transit.name, version)
+		if err != nil {
+			return nil
+		}
+		jk.KeyID = k.transit.getKeyID(version)
+		publicKey = pk
+	}
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		jk.KeyType = "RSA"
+		jk.N = base64.RawURLEncoding.EncodeToString(pk.N.Bytes())
+		jk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pk.E)).Bytes())
+	case *ecdsa.PublicKey:
+		params := pk.Curve.Params()
+		size := (params.BitSize + 7) / 8
+		jk.KeyType = "EC"
+		jk.Curve = params.Name
+		jk.X = base64.RawURLEncoding.EncodeToString(pk.X.FillBytes(make([]byte, size)))
+		jk.Y = base64.RawURLEncoding.EncodeToString(pk.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil
+	}
+	return jk
+}
+
+// GetJWKS returns the JSON Web Key Set with the public keys of the
+// asymmetric signing keys in CryptoKeyStore.
+func (ks *CryptoKeyStore) GetJWKS() map[string]interface{} {
+	keys := []*jwksKey{}
+	for _, k := range ks.signKeys {
+		if jk := newJwksKey(k); jk != nil {
+			keys = append(keys, jk)
+		}
+	}
+	return map[string]interface{}{
+		"keys": keys,
+	}
+}
+
+// GetSigningMethods returns the signing methods of the asymmetric signing
+// keys in CryptoKeyStore.
+func (ks *CryptoKeyStore) GetSigningMethods() []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, k := range ks.signKeys {
+		jk := newJwksKey(k)
+		if jk == nil || jk.Alg == "" || seen[jk.Alg] {
+			continue
+		}
+		seen[jk.Alg] = true
+		methods = append(methods, jk.Alg)
+	}
+	return methods
+}
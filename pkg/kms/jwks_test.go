@@ -0,0 +1,200 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestRemoteJwksKeys(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed generating private key: %v", err)
+	}
+
+	var fetchCount int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchCount++
+		doc := map[string]interface{}{
+			"keys": []map[string]interface{}{
+				{
+					"kid": "a1b2c3",
+					"kty": "RSA",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(privKey.PublicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privKey.PublicKey.E)).Bytes()),
+				},
+				// The keys of unsupported types are skipped.
+				{
+					"kid": "g7h8i9",
+					"kty": "OKP",
+					"use": "sig",
+					"crv": "Ed25519",
+					"x":   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	defer srv.Close()
+
+	testcases := []struct {
+		name      string
+		config    string
+		kid       string
+		method    jwtlib.SigningMethod
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "token signed with key from remote jwks",
+			config: fmt.Sprintf("crypto key verify from jwks %s", srv.URL),
+			kid:    "a1b2c3",
+			method: jwtlib.SigningMethodRS256,
+		},
+		{
+			name:   "token without kid and single key in remote jwks",
+			config: fmt.Sprintf("crypto key verify from jwks %s", srv.URL),
+			method: jwtlib.SigningMethodRS256,
+		},
+		{
+			name:      "token signed with key not in remote jwks",
+			config:    fmt.Sprintf("crypto key verify from jwks %s", srv.URL),
+			kid:       "d4e5f6",
+			method:    jwtlib.SigningMethodRS256,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyStoreParseTokenFailed,
+		},
+		{
+			name:      "remote jwks key config with sign usage",
+			config:    fmt.Sprintf("crypto key sign from jwks %s", srv.URL),
+			shouldErr: true,
+			err: errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0,
+				fmt.Errorf("key source jwks supports verify usage only"),
+			),
+		},
+		{
+			name:      "remote jwks key config with invalid url",
+			config:    "crypto key verify from jwks foobar",
+			shouldErr: true,
+			err: errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0,
+				fmt.Errorf("key source jwks url %q is invalid", "foobar"),
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cfgs, err := ParseCryptoKeyConfigs(tc.config)
+			if err != nil {
+				if tests.EvalErrWithLog(t, err, "parse", tc.shouldErr, tc.err, msgs) {
+					return
+				}
+			}
+			ks := NewCryptoKeyStore()
+			if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+				t.Fatalf("failed adding keys: %v", err)
+			}
+
+			token := jwtlib.NewWithClaims(tc.method, jwtlib.MapClaims{
+				"sub":   "jsmith",
+				"roles": []string{"authp/user"},
+				"exp":   time.Now().Add(10 * time.Minute).Unix(),
+			})
+			if tc.kid != "" {
+				token.Header["kid"] = tc.kid
+			}
+			payload, err := token.SignedString(privKey)
+			if err != nil {
+				t.Fatalf("failed signing token: %v", err)
+			}
+
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = payload
+			usr, err := ks.ParseToken(ar)
+			if tests.EvalErrWithLog(t, err, "parse token", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "subject", "jsmith", usr.Claims.Subject, msgs)
+		})
+	}
+
+	// Each of the three key stores fetches the keys once.
+	if fetchCount != 3 {
+		t.Fatalf("unexpected jwks fetch count: %d", fetchCount)
+	}
+}
+
+func TestCryptoKeyStoreJWKS(t *testing.T) {
+	signer := NewCryptoKeyStore()
+	if err := signer.AutoGenerate("default", "ES512"); err != nil {
+		t.Fatalf("failed generating keys: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(signer.GetJWKS())
+	}))
+	defer srv.Close()
+
+	tests.EvalObjects(t, "signing methods", []string{"ES512"}, signer.GetSigningMethods())
+
+	// The keys published by the signer verify the tokens it issues.
+	cfgs, err := ParseCryptoKeyConfigs(fmt.Sprintf("crypto key verify from jwks %s", srv.URL))
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	verifier := NewCryptoKeyStore()
+	if err := verifier.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	usr := newTestUser()
+	if err := signer.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = usr.Token
+	parsedUser, err := verifier.ParseToken(ar)
+	if err != nil {
+		t.Fatalf("failed parsing token: %v", err)
+	}
+	tests.EvalObjects(t, "subject", usr.Claims.Subject, parsedUser.Claims.Subject)
+
+	// The keys based on shared secrets are not published.
+	cfgs, err = ParseCryptoKeyConfigs("crypto key sign-verify foobar")
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	shared := NewCryptoKeyStore()
+	if err := shared.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	b, _ := json.Marshal(shared.GetJWKS())
+	tests.EvalObjects(t, "shared jwks", `{"keys":[]}`, string(b))
+}
@@ -33,6 +33,7 @@ import (
 	"strings"
 
 	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/shared"
 	"github.com/greenpau/go-authcrunch/pkg/user"
@@ -40,9 +41,14 @@ import (
 
 // CryptoKey contains a crypto graphic key and associated metadata.
 type CryptoKey struct {
-	Config *CryptoKeyConfig   `json:"config,omitempty" xml:"config,omitempty" yaml:"config,omitempty"`
-	Sign   *CryptoKeyOperator `json:"sign,omitempty" xml:"sign,omitempty" yaml:"sign,omitempty"`
-	Verify *CryptoKeyOperator `json:"verify,omitempty" xml:"verify,omitempty" yaml:"verify,omitempty"`
+	Config  *CryptoKeyConfig   `json:"config,omitempty" xml:"config,omitempty" yaml:"config,omitempty"`
+	Sign    *CryptoKeyOperator `json:"sign,omitempty" xml:"sign,omitempty" yaml:"sign,omitempty"`
+	Verify  *CryptoKeyOperator `json:"verify,omitempty" xml:"verify,omitempty" yaml:"verify,omitempty"`
+	jwks    *jwksKeySet
+	transit *vaultTransitKey
+	signer  *signerKey
+	// encryption is set when the tokens are JWE.
+	encryption *tokenEncryption
 }
 
 // CryptoKeyTokenOperator represents CryptoKeyOperator token operator.
@@ -157,6 +163,24 @@ func GetKeysFromConfig(cfg *CryptoKeyConfig) ([]*CryptoKey, error) {
 		default:
 			return nil, fmt.Errorf("unsupported env config type %s", cfg.EnvVarType)
 		}
+	case "jwks":
+		// Discovered remote key set
+		k := newCryptoKey()
+		k.Config = cfg
+		k.Config.Algorithm = "jwks"
+		keys = append(keys, k)
+	case "vault":
+		vaultKeys, err := getKeysFromVault(cfg)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, vaultKeys...)
+	case "pkcs11", "aws_kms", "gcp_kms":
+		k, err := getKeyFromSigner(cfg)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
 	case "generate":
 		switch cfg.Algorithm {
 		case "ecdsa":
@@ -176,19 +200,75 @@ func GetKeysFromConfig(cfg *CryptoKeyConfig) ([]*CryptoKey, error) {
 	for _, k := range keys {
 		switch k.Config.Algorithm {
 		case "hmac":
+			secret, err := credentials.ResolveSecret(k.Config.Secret)
+			if err != nil {
+				return nil, err
+			}
 			k.Sign.Capable = true
 			k.Verify.Capable = true
-			k.Sign.Secret = []byte(k.Config.Secret)
-			k.Verify.Secret = []byte(k.Config.Secret)
+			k.Sign.Secret = []byte(secret)
+			k.Verify.Secret = []byte(secret)
 		case "rsa", "ecdsa":
+		case "jwks":
+			k.Verify.Capable = true
+			k.jwks = newJwksKeySet(k.Config)
+		case "transit", "signer":
 		default:
 			return nil, fmt.Errorf("unsupported config algorithm %s", k.Config.Algorithm)
 		}
+		if k.Config.TokenEncryptionKey != "" {
+			encryption, err := newTokenEncryption(k.Config)
+			if err != nil {
+				return nil, err
+			}
+			k.encryption = encryption
+		}
+		if err := k.restrictMethods(); err != nil {
+			return nil, err
+		}
 		k.enableUsage()
 	}
 	return keys, nil
 }
 
+// restrictMethods limits the signing and verification methods of the key to
+// the algorithms allowed by the key config. The algorithms of the other key
+// types, e.g. HS256 for an RSA key, are rejected.
+func (k *CryptoKey) restrictMethods() error {
+	if len(k.Config.Algorithms) == 0 {
+		return nil
+	}
+	for _, op := range []*CryptoKeyOperator{k.Sign, k.Verify} {
+		if !op.Capable {
+			continue
+		}
+		methods := op.Token.PreferredMethods
+		if len(methods) == 0 {
+			methods = getMethodsPerAlgo(k.Config.Algorithm)
+		}
+		families := make(map[string]bool)
+		for _, m := range methods {
+			families[signingMethods[m]] = true
+		}
+		var restricted []string
+		for _, alg := range k.Config.Algorithms {
+			if !families[signingMethods[alg]] {
+				return errors.ErrCryptoKeyConfigAlgorithmMismatch.WithArgs(k.Config.ID, alg, k.Config.Algorithm)
+			}
+			for _, m := range methods {
+				if m == alg {
+					restricted = append(restricted, m)
+				}
+			}
+		}
+		if len(restricted) == 0 {
+			return errors.ErrCryptoKeyConfigAlgorithmsUnmet.WithArgs(k.Config.ID, strings.Join(k.Config.Algorithms, ", "), strings.Join(methods, ", "))
+		}
+		op.Token.PreferredMethods = restricted
+	}
+	return nil
+}
+
 func (k *CryptoKey) enableUsage() {
 	methods := getMethodsPerAlgo(k.Config.Algorithm)
 	if k.Sign.Capable {
@@ -249,6 +329,31 @@ func (k *CryptoKey) sign(signMethod, data interface{}) (interface{}, error) {
 		}
 	}
 
+	if k.Config.TokenStore != "" {
+		return k.signOpaque(data)
+	}
+	if k.encryption == nil {
+		return k.signJWS(method, data)
+	}
+	if k.encryption.only {
+		jb, err := json.Marshal(data)
+		if err != nil {
+			return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+		}
+		return k.encryption.encrypt(jb)
+	}
+	signed, err := k.signJWS(method, data)
+	if err != nil {
+		return nil, err
+	}
+	return k.encryption.encrypt([]byte(signed.(string)))
+}
+
+func (k *CryptoKey) signJWS(method string, data interface{}) (interface{}, error) {
+	if k.transit != nil {
+		return k.signTransit(method, data)
+	}
+
 	header := map[string]interface{}{"typ": "JWT", "alg": method}
 	if k.Sign.Token.injectKeyID {
 		header["kid"] = k.Sign.Token.ID
@@ -263,6 +368,14 @@ func (k *CryptoKey) sign(signMethod, data interface{}) (interface{}, error) {
 	}
 	s := base64.RawURLEncoding.EncodeToString(jh) + "." + base64.RawURLEncoding.EncodeToString(jb)
 
+	if k.signer != nil {
+		sig, err := k.signer.sign(method, []byte(s))
+		if err != nil {
+			return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+		}
+		return s + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+	}
+
 	switch signingMethods[method] {
 	case "hmac":
 		return k.signHMAC(method, s)
@@ -277,6 +390,15 @@ func (k *CryptoKey) sign(signMethod, data interface{}) (interface{}, error) {
 
 // ProvideKey returns the appropriate encryption key.
 func (k *CryptoKey) ProvideKey(token *jwtlib.Token) (interface{}, error) {
+	alg, _ := token.Header["alg"].(string)
+	if alg == "" || strings.EqualFold(alg, "none") {
+		return nil, errors.ErrCryptoKeyTokenUnsigned
+	}
+	if len(k.Config.Algorithms) > 0 {
+		if _, allowed := k.Verify.Token.Methods[alg]; !allowed {
+			return nil, errors.ErrUnexpectedSigningMethod.WithArgs(strings.Join(k.Config.Algorithms, " or "), alg)
+		}
+	}
 	switch k.Config.Algorithm {
 	case "hmac":
 		if _, validMethod := token.Method.(*jwtlib.SigningMethodHMAC); !validMethod {
@@ -290,10 +412,104 @@ func (k *CryptoKey) ProvideKey(token *jwtlib.Token) (interface{}, error) {
 		if _, validMethod := token.Method.(*jwtlib.SigningMethodECDSA); !validMethod {
 			return nil, errors.ErrUnexpectedSigningMethod.WithArgs("ES", token.Header["alg"])
 		}
+	case "jwks":
+		switch token.Method.(type) {
+		case *jwtlib.SigningMethodRSA, *jwtlib.SigningMethodECDSA:
+		default:
+			return nil, errors.ErrUnexpectedSigningMethod.WithArgs("RS or ES", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return k.jwks.getKey(kid)
+	case "transit":
+		return k.provideTransitKey(token)
+	case "signer":
+		switch token.Method.(type) {
+		case *jwtlib.SigningMethodRSA, *jwtlib.SigningMethodECDSA:
+		default:
+			return nil, errors.ErrUnexpectedSigningMethod.WithArgs("RS or ES", token.Header["alg"])
+		}
+		return k.signer.getPublicKey()
 	}
 	return k.Verify.Secret, nil
 }
 
+// parseToken verifies the token and returns its claims. The encrypted tokens
+// are decrypted first. When the token has expired, the claims are returned
+// together with the error. The tokens in the revocation list are rejected.
+func (k *CryptoKey) parseToken(s string, h *tokenHeader) (jwtlib.MapClaims, error) {
+	if IsOpaqueToken(s) {
+		return k.parseOpaque(s)
+	}
+	claims, err := k.parseSigned(s, h)
+	if err != nil {
+		return claims, err
+	}
+	if err := k.checkRevoked(s); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parseSigned verifies a JWT or JWE token and returns its claims. When the
+// header of a JWT token has already been parsed, it is not decoded again.
+func (k *CryptoKey) parseSigned(s string, h *tokenHeader) (jwtlib.MapClaims, error) {
+	if isEncryptedToken(s) {
+		if k.encryption == nil {
+			return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs("no token encryption key")
+		}
+		b, err := k.encryption.decrypt(s)
+		if err != nil {
+			return nil, err
+		}
+		if k.encryption.only {
+			claims := jwtlib.MapClaims{}
+			if err := json.Unmarshal(b, &claims); err != nil {
+				return nil, errors.ErrCryptoKeyTokenDecryption.WithArgs(err)
+			}
+			return claims, claims.Valid()
+		}
+		s = string(b)
+	} else if h != nil && h.method != nil {
+		return k.parseWithHeader(s, h)
+	}
+	token, err := jwtlib.Parse(s, k.ProvideKey)
+	if token == nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwtlib.MapClaims)
+	if !ok {
+		return nil, err
+	}
+	return claims, err
+}
+
+// parseWithHeader verifies a JWT token with its parsed header. Similar to
+// jwtlib.Parse, the claims are returned with the validation error, e.g.
+// expired token, only when the signature is valid.
+func (k *CryptoKey) parseWithHeader(s string, h *tokenHeader) (jwtlib.MapClaims, error) {
+	i := strings.IndexByte(s, '.')
+	j := strings.LastIndexByte(s, '.')
+	if i < 0 || j <= i {
+		return nil, jwtlib.NewValidationError("token contains an invalid number of segments", jwtlib.ValidationErrorMalformed)
+	}
+	b, err := jwtlib.DecodeSegment(s[i+1 : j])
+	if err != nil {
+		return nil, &jwtlib.ValidationError{Inner: err, Errors: jwtlib.ValidationErrorMalformed}
+	}
+	claims := jwtlib.MapClaims{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, &jwtlib.ValidationError{Inner: err, Errors: jwtlib.ValidationErrorMalformed}
+	}
+	key, err := k.ProvideKey(&jwtlib.Token{Header: h.fields, Method: h.method, Claims: claims})
+	if err != nil {
+		return nil, &jwtlib.ValidationError{Inner: err, Errors: jwtlib.ValidationErrorUnverifiable}
+	}
+	if err := h.method.Verify(s[:j], s[j+1:], key); err != nil {
+		return nil, &jwtlib.ValidationError{Inner: err, Errors: jwtlib.ValidationErrorSignatureInvalid}
+	}
+	return claims, claims.Valid()
+}
+
 func extractBytesFromFile(fp string) ([]byte, error) {
 	ext := filepath.Ext(fp)
 	switch ext {
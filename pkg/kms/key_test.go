@@ -15,6 +15,7 @@
 package kms
 
 import (
+	"encoding/base64"
 	"fmt"
 	"github.com/google/go-cmp/cmp"
 	"github.com/greenpau/go-authcrunch/internal/tests"
@@ -564,3 +565,92 @@ func TestGetKeysFromCryptoKeyConfigs(t *testing.T) {
 		})
 	}
 }
+
+func TestAlgorithmAllowlist(t *testing.T) {
+	unsignedToken := func() string {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"smithj@outlook.com","exp":%d}`, time.Now().Add(10*time.Minute).Unix())))
+		return header + "." + payload + "."
+	}
+	testcases := []struct {
+		name      string
+		signer    string
+		verifier  string
+		token     string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "token signed with allowed algorithm",
+			signer:   "crypto key k1 sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\ncrypto key k1 algorithms HS256",
+			verifier: "crypto key k1 verify 0e2fdcf8-6868-41a7-884b-7308795fc286\ncrypto key k1 algorithms HS256",
+		},
+		{
+			name:      "token signed with algorithm outside of allowlist",
+			signer:    "crypto key k1 sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286",
+			verifier:  "crypto key k1 verify 0e2fdcf8-6868-41a7-884b-7308795fc286\ncrypto key k1 algorithms HS256",
+			shouldErr: true,
+			err:       errors.ErrUnexpectedSigningMethod.WithArgs("HS256", "HS512"),
+		},
+		{
+			name:      "unsigned token",
+			verifier:  "crypto key k1 verify 0e2fdcf8-6868-41a7-884b-7308795fc286",
+			token:     unsignedToken(),
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyTokenUnsigned,
+		},
+		{
+			name:      "rsa key with hmac algorithm in allowlist",
+			verifier:  "crypto key k9738a405e99 verify from file ./../../testdata/rskeys/test_2_pub.pem\ncrypto key k9738a405e99 algorithms RS256 HS256",
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigAlgorithmMismatch.WithArgs("k9738a405e99", "HS256", "rsa"),
+		},
+		{
+			name:      "ecdsa key without allowed algorithms",
+			verifier:  "crypto key k9738a405e99 sign-verify from file ./../../testdata/ecdsakeys/test_4_pri.pem\ncrypto key k9738a405e99 algorithms ES256",
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigAlgorithmsUnmet.WithArgs("k9738a405e99", "ES256", "ES512"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			getKey := func(s string) (*CryptoKey, error) {
+				cfgs, err := ParseCryptoKeyConfigs(s)
+				if err != nil {
+					t.Fatalf("failed parsing configs: %v", err)
+				}
+				keys, err := GetKeysFromConfigs(cfgs)
+				if err != nil {
+					return nil, err
+				}
+				return keys[0], nil
+			}
+
+			verifier, err := getKey(tc.verifier)
+			if tc.signer == "" && tc.token == "" {
+				tests.EvalErrWithLog(t, err, "load key", tc.shouldErr, tc.err, msgs)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			token := tc.token
+			if tc.signer != "" {
+				signer, err := getKey(tc.signer)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				usr := newTestUser()
+				if err := signer.SignToken(nil, usr); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				token = usr.Token
+			}
+
+			_, err = verifier.parseToken(token, nil)
+			tests.EvalErrWithLog(t, err, "parse token", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
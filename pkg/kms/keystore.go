@@ -17,7 +17,6 @@ package kms
 import (
 	"strings"
 
-	jwtlib "github.com/golang-jwt/jwt/v4"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
@@ -196,51 +195,104 @@ func (ks *CryptoKeyStore) AddKey(k *CryptoKey) error {
 
 // ParseToken parses JWT token and returns User instance.
 func (ks *CryptoKeyStore) ParseToken(ar *requests.AuthorizationRequest) (*user.User, error) {
-	for _, k := range ks.verifyKeys {
+	userData, err := ks.ParseTokenClaims(ar)
+	if err != nil {
+		return nil, err
+	}
+	usr, err := user.NewUser(userData)
+	if err != nil {
+		return usr, errors.ErrCryptoKeyStoreTokenData
+	}
+	return usr, nil
+}
+
+// ParseTokenClaims parses JWT token and returns the claims of a verified
+// token.
+func (ks *CryptoKeyStore) ParseTokenClaims(ar *requests.AuthorizationRequest) (map[string]interface{}, error) {
+	// The header of the signed tokens is used to skip the keys unable to
+	// verify the token, rather than parsing the token with each of them.
+	var header *tokenHeader
+	if !IsOpaqueToken(ar.Token.Payload) && !isEncryptedToken(ar.Token.Payload) {
+		header = parseTokenHeader(ar.Token.Payload)
+	}
+	for _, k := range ks.getVerifyKeys(header) {
 		if _, exists := reservedTokenNames[ar.Token.Name]; !exists {
 			if ar.Token.Name != k.Verify.Token.Name {
 				continue
 			}
 		}
-		parsedToken, err := jwtlib.Parse(ar.Token.Payload, k.ProvideKey)
+		if header != nil && !k.acceptsMethod(header.Alg) {
+			continue
+		}
+		claims, err := k.parseToken(ar.Token.Payload, header)
 		if err != nil && !strings.Contains(err.Error(), "is expired") {
 			continue
 		}
+		if header != nil && err == nil {
+			cacheTokenHeader(ar.Token.Payload, header)
+		}
 
-		userData := make(map[string]interface{})
-		errData := make(map[string]interface{})
-		for k, v := range parsedToken.Claims.(jwtlib.MapClaims) {
+		var errData map[string]interface{}
+		if err != nil {
+			errData = make(map[string]interface{})
+		}
+		for k, v := range claims {
 			switch k {
 			case "iss":
 				if strings.HasPrefix(v.(string), "http") {
 					ar.Redirect.AuthURL = strings.TrimSuffix(v.(string), "authorization-code-callback")
 				}
 			case "mail", "email":
-				errData["email"] = v.(string)
+				if errData != nil {
+					errData["email"] = v.(string)
+				}
 				ar.Redirect.LoginHint = v.(string)
 			case "sub", "name", "jti":
-				errData[k] = v.(string)
+				if errData != nil {
+					errData[k] = v.(string)
+				}
 			}
-			userData[k] = v
 		}
 
 		if err != nil {
 			ar.Response.User = errData
 			return nil, errors.ErrCryptoKeyStoreParseTokenExpired
 		}
+		return claims, nil
+	}
+	return nil, errors.ErrCryptoKeyStoreParseTokenFailed
+}
 
-		usr, err := user.NewUser(userData)
-		if err != nil {
-			return usr, errors.ErrCryptoKeyStoreTokenData
+// getVerifyKeys returns the verification keys with the key referenced by the
+// kid header of the token, if any, tried first.
+func (ks *CryptoKeyStore) getVerifyKeys(header *tokenHeader) []*CryptoKey {
+	if header == nil || header.Kid == "" || len(ks.verifyKeys) < 2 {
+		return ks.verifyKeys
+	}
+	for i, k := range ks.verifyKeys {
+		if k.Config.ID != header.Kid {
+			continue
+		}
+		if i == 0 {
+			break
 		}
-		return usr, nil
+		keys := make([]*CryptoKey, 0, len(ks.verifyKeys))
+		keys = append(keys, k)
+		keys = append(keys, ks.verifyKeys[:i]...)
+		keys = append(keys, ks.verifyKeys[i+1:]...)
+		return keys
 	}
-	return nil, errors.ErrCryptoKeyStoreParseTokenFailed
+	return ks.verifyKeys
 }
 
-// SignToken signs user claims and add signed token to user identity.
+// SignToken signs user claims and add signed token to user identity. The
+// token is signed with the keys bound to the realm of the user, if any.
 func (ks *CryptoKeyStore) SignToken(tokenName, signMethod interface{}, usr *user.User) error {
-	for _, k := range ks.signKeys {
+	realm := usr.Authenticator.Realm
+	if realm == "" && usr.Claims != nil {
+		realm = usr.Claims.Origin
+	}
+	for _, k := range ks.getRealmSignKeys(realm) {
 		if tokenName != nil {
 			if tokenName.(string) != k.Sign.Token.Name {
 				continue
@@ -257,9 +309,96 @@ func (ks *CryptoKeyStore) SignToken(tokenName, signMethod interface{}, usr *user
 	return errors.ErrCryptoKeyStoreSignTokenFailed
 }
 
+// SignClaims signs arbitrary claims, e.g. the claims of an OpenID Connect
+// ID token, and returns the signed token.
+func (ks *CryptoKeyStore) SignClaims(tokenName, signMethod interface{}, claims map[string]interface{}) (string, error) {
+	for _, k := range ks.getRealmSignKeys("") {
+		if tokenName != nil {
+			if tokenName.(string) != k.Sign.Token.Name {
+				continue
+			}
+		}
+		response, err := k.sign(signMethod, claims)
+		if err != nil {
+			return "", err
+		}
+		return response.(string), nil
+	}
+	return "", errors.ErrCryptoKeyStoreSignTokenFailed
+}
+
+// getRealmSignKeys returns the signing keys bound to the realm followed by
+// the signing keys without realm.
+func (ks *CryptoKeyStore) getRealmSignKeys(realm string) []*CryptoKey {
+	var keys, defaultKeys []*CryptoKey
+	for _, k := range ks.signKeys {
+		switch k.Config.Realm {
+		case "":
+			defaultKeys = append(defaultKeys, k)
+		case realm:
+			keys = append(keys, k)
+		}
+	}
+	return append(keys, defaultKeys...)
+}
+
+// RevokeToken removes an opaque token from the token stores of the
+// verification keys. The JWT and JWE tokens are added to the revocation
+// lists of the verification keys having revocation stores, and it is a no-op
+// for the keys without them. The revoked token is rejected immediately.
+func (ks *CryptoKeyStore) RevokeToken(token string) error {
+	if !IsOpaqueToken(token) {
+		for _, k := range ks.verifyKeys {
+			if k.Config.RevocationStore == "" {
+				continue
+			}
+			claims, err := k.parseSigned(token, nil)
+			if err != nil {
+				// The token is either not verified by the key or has
+				// already expired.
+				continue
+			}
+			if err := k.revokeSigned(token, claims); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, k := range ks.verifyKeys {
+		if k.Config.TokenStore == "" {
+			continue
+		}
+		if err := k.revokeOpaque(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasRevocationList returns true when any of the verification keys has a
+// revocation store.
+func (ks *CryptoKeyStore) HasRevocationList() bool {
+	for _, k := range ks.verifyKeys {
+		if k.Config.RevocationStore != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTokenEncryption returns true when the tokens signed by the default
+// signing key are encrypted.
+func (ks *CryptoKeyStore) HasTokenEncryption() bool {
+	keys := ks.getRealmSignKeys("")
+	if len(keys) < 1 {
+		return false
+	}
+	return keys[0].encryption != nil
+}
+
 // GetTokenLifetime returns lifetime for a signed token.
 func (ks *CryptoKeyStore) GetTokenLifetime(tokenName, signMethod interface{}) int {
-	for _, k := range ks.signKeys {
+	for _, k := range ks.getRealmSignKeys("") {
 		if tokenName != nil {
 			if tokenName.(string) != k.Sign.Token.Name {
 				continue
@@ -323,3 +323,50 @@ func TestCryptoKeyStoreAutoGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestRealmSignKeys(t *testing.T) {
+	cfgs, err := ParseCryptoKeyConfigs(
+		"crypto key k1 sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+			"crypto key k2 sign-verify 4a9e5e43-0c8a-4e23-9d3e-0a0f1e0c7f51\n" +
+			"crypto key k2 realm contoso",
+	)
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+
+	testcases := []struct {
+		name  string
+		realm string
+		want  string
+	}{
+		{name: "user of realm with key set", realm: "contoso", want: "k2"},
+		{name: "user of realm without key set", realm: "local", want: "k1"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			usr := newTestUser()
+			usr.Authenticator.Realm = tc.realm
+			if err := ks.SignToken(nil, nil, usr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			token, _, err := new(jwtlib.Parser).ParseUnverified(usr.Token, jwtlib.MapClaims{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "kid", tc.want, token.Header["kid"], msgs)
+
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = usr.Token
+			_, err = ks.ParseToken(ar)
+			tests.EvalErrWithLog(t, err, "parse token", false, nil, msgs)
+		})
+	}
+
+	tests.EvalObjects(t, "lifetime", 900, ks.GetTokenLifetime(nil, nil))
+}
@@ -36,6 +36,7 @@ var (
 		"hmac":  []string{"HS512", "HS384", "HS256"},
 		"rsa":   []string{"RS512", "RS384", "RS256"},
 		"ecdsa": []string{"ES512", "ES384", "ES256"},
+		"jwks":  []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
 	}
 )
 
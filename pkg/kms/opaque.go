@@ -0,0 +1,90 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+// IsOpaqueToken returns true when the token is a reference to the claims
+// held in a token store, rather than JWT or JWE.
+func IsOpaqueToken(s string) bool {
+	return s != "" && !strings.Contains(s, ".")
+}
+
+// signOpaque stores the claims in the token store of the key and returns
+// a random token referencing them. The claims are kept until they expire.
+func (k *CryptoKey) signOpaque(data interface{}) (interface{}, error) {
+	store, err := tokenstore.Get(k.Config.TokenStore)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	jb, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(jb, &claims); err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.ErrCryptoKeyTokenExpiryNotFound
+	}
+	token, err := tokenstore.NewToken()
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	if err := store.Add(token, claims, time.Unix(int64(exp), 0)); err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	return token, nil
+}
+
+// parseOpaque looks up the claims of an opaque token in the token store of
+// the key.
+func (k *CryptoKey) parseOpaque(s string) (jwtlib.MapClaims, error) {
+	if k.Config.TokenStore == "" {
+		return nil, errors.ErrCryptoKeyTokenStoreNotFound
+	}
+	store, err := tokenstore.Get(k.Config.TokenStore)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	m, err := store.Get(s)
+	if err != nil {
+		return nil, errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	claims := jwtlib.MapClaims(m)
+	return claims, claims.Valid()
+}
+
+// revokeOpaque removes an opaque token from the token store of the key.
+func (k *CryptoKey) revokeOpaque(s string) error {
+	store, err := tokenstore.Get(k.Config.TokenStore)
+	if err != nil {
+		return errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	if err := store.Delete(s); err != nil {
+		return errors.ErrCryptoKeyTokenStore.WithArgs(k.Config.TokenStore, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+func TestOpaqueTokens(t *testing.T) {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: "kms_test", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("failed creating token store: %v", err)
+	}
+	tokenstore.Register("kms_test", store)
+
+	cfgs, err := ParseCryptoKeyConfigs("crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+		"crypto key token store kms_test")
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+
+	usr := newTestUser()
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	tests.EvalObjects(t, "opaque token", true, IsOpaqueToken(usr.Token))
+
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = usr.Token
+	parsedUser, err := ks.ParseToken(ar)
+	if err != nil {
+		t.Fatalf("failed parsing token: %v", err)
+	}
+	tests.EvalObjects(t, "subject", usr.Claims.Subject, parsedUser.Claims.Subject)
+
+	// The key without token store does not accept opaque tokens.
+	plain := NewCryptoKeyStore()
+	plainCfgs, _ := ParseCryptoKeyConfigs("crypto key verify 0e2fdcf8-6868-41a7-884b-7308795fc286")
+	if err := plain.AddKeysWithConfigs(plainCfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	_, err = plain.ParseToken(ar)
+	tests.EvalErrWithLog(t, err, "parse token without store", true, errors.ErrCryptoKeyStoreParseTokenFailed, []string{})
+
+	// The revoked token is rejected.
+	if err := ks.RevokeToken(usr.Token); err != nil {
+		t.Fatalf("failed revoking token: %v", err)
+	}
+	_, err = ks.ParseToken(ar)
+	tests.EvalErrWithLog(t, err, "parse revoked token", true, errors.ErrCryptoKeyStoreParseTokenFailed, []string{})
+}
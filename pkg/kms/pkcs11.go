@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	pkcs11SignerName = "pkcs11"
+)
+
+// pkcs11Config is the key reference in PKCS #11 URI format (RFC 7512), e.g.
+// pkcs11:token=authp;object=jwt?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-source=/etc/authp/pin.
+type pkcs11Config struct {
+	modulePath string
+	tokenLabel string
+	slotID     int
+	hasSlotID  bool
+	keyLabel   string
+	keyID      []byte
+	pin        string
+}
+
+func parsePKCS11URI(s string) (*pkcs11Config, error) {
+	if !strings.HasPrefix(s, "pkcs11:") {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, "no pkcs11 scheme")
+	}
+	cfg := &pkcs11Config{}
+	uriPath := strings.TrimPrefix(s, "pkcs11:")
+	var uriQuery string
+	if i := strings.IndexRune(uriPath, '?'); i >= 0 {
+		uriPath, uriQuery = uriPath[:i], uriPath[i+1:]
+	}
+
+	for _, attr := range strings.Split(uriPath, ";") {
+		if attr == "" {
+			continue
+		}
+		k, v, err := parsePKCS11Attribute(attr)
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, err)
+		}
+		switch k {
+		case "token":
+			cfg.tokenLabel = v
+		case "object":
+			cfg.keyLabel = v
+		case "id":
+			cfg.keyID = []byte(v)
+		case "slot-id":
+			slotID, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, err)
+			}
+			cfg.slotID = slotID
+			cfg.hasSlotID = true
+		case "type":
+			if v != "private" {
+				return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, "object type must be private")
+			}
+		}
+	}
+
+	var pinSource string
+	for _, attr := range strings.Split(uriQuery, "&") {
+		if attr == "" {
+			continue
+		}
+		k, v, err := parsePKCS11Attribute(attr)
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, err)
+		}
+		switch k {
+		case "module-path":
+			cfg.modulePath = v
+		case "pin-value":
+			cfg.pin = v
+		case "pin-source":
+			pinSource = v
+		}
+	}
+
+	if cfg.modulePath == "" {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, "module-path not found")
+	}
+	if cfg.tokenLabel == "" && !cfg.hasSlotID {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, "token or slot-id not found")
+	}
+	if cfg.keyLabel == "" && len(cfg.keyID) == 0 {
+		return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, "object or id not found")
+	}
+	if pinSource != "" {
+		b, err := ioutil.ReadFile(strings.TrimPrefix(pinSource, "file:"))
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerURIInvalid.WithArgs(pkcs11SignerName, s, err)
+		}
+		cfg.pin = strings.TrimSpace(string(b))
+	}
+	return cfg, nil
+}
+
+func parsePKCS11Attribute(s string) (string, string, error) {
+	i := strings.IndexRune(s, '=')
+	if i < 1 {
+		return "", "", fmt.Errorf("malformed attribute %q", s)
+	}
+	v, err := url.PathUnescape(s[i+1:])
+	if err != nil {
+		return "", "", err
+	}
+	return s[:i], v, nil
+}
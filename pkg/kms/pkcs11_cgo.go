@@ -0,0 +1,254 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"math/big"
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/miekg/pkcs11"
+)
+
+var (
+	pkcs11DigestInfoPrefixes = map[crypto.Hash][]byte{
+		crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+		crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+		crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	}
+	pkcs11Curves = map[string]elliptic.Curve{
+		"1.2.840.10045.3.1.7": elliptic.P256(),
+		"1.3.132.0.34":        elliptic.P384(),
+		"1.3.132.0.35":        elliptic.P521(),
+	}
+)
+
+// pkcs11Signer signs tokens with a private key held by a PKCS #11 token,
+// e.g. a hardware security module.
+type pkcs11Signer struct {
+	cfg        *pkcs11Config
+	ctx        *pkcs11.Ctx
+	session    pkcs11.SessionHandle
+	privateKey pkcs11.ObjectHandle
+	mu         sync.Mutex
+	publicKey  crypto.PublicKey
+}
+
+func newPKCS11Signer(uri string) (Signer, error) {
+	cfg, err := parsePKCS11URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	ctx := pkcs11.New(cfg.modulePath)
+	if ctx == nil {
+		return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, "failed loading module "+cfg.modulePath)
+	}
+	if err := ctx.Initialize(); err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, err)
+	}
+	s := &pkcs11Signer{
+		cfg: cfg,
+		ctx: ctx,
+	}
+	slot, err := s.findSlot()
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, err)
+	}
+	s.session, err = ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, err)
+	}
+	if cfg.pin != "" {
+		if err := ctx.Login(s.session, pkcs11.CKU_USER, cfg.pin); err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+			return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, err)
+		}
+	}
+	s.privateKey, err = s.findObject(pkcs11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, err)
+	}
+	return s, nil
+}
+
+func (s *pkcs11Signer) findSlot() (uint, error) {
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, err
+	}
+	for _, slot := range slots {
+		if s.cfg.hasSlotID && uint(s.cfg.slotID) != slot {
+			continue
+		}
+		if s.cfg.tokenLabel != "" {
+			info, err := s.ctx.GetTokenInfo(slot)
+			if err != nil || info.Label != s.cfg.tokenLabel {
+				continue
+			}
+		}
+		return slot, nil
+	}
+	return 0, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, "token not found")
+}
+
+func (s *pkcs11Signer) findObject(class uint) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_CLASS, class)}
+	if s.cfg.keyLabel != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, s.cfg.keyLabel))
+	}
+	if len(s.cfg.keyID) > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, s.cfg.keyID))
+	}
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, err
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 1)
+	s.ctx.FindObjectsFinal(s.session)
+	if err != nil {
+		return 0, err
+	}
+	if len(objects) == 0 {
+		return 0, errors.ErrCryptoKeySignerUnavailable.WithArgs(pkcs11SignerName, "key not found")
+	}
+	return objects[0], nil
+}
+
+// GetName returns the name of the signer.
+func (s *pkcs11Signer) GetName() string {
+	return pkcs11SignerName
+}
+
+// GetPublicKey reads the public key matching the private key from the token.
+func (s *pkcs11Signer) GetPublicKey() (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, err := s.findObject(pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, err)
+	}
+	attrs, err := s.ctx.GetAttributeValue(s.session, obj, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil || len(attrs) == 0 {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, err)
+	}
+	var publicKey crypto.PublicKey
+	switch keyType := new(big.Int).SetBytes(reverseBytes(attrs[0].Value)).Uint64(); keyType {
+	case pkcs11.CKK_RSA:
+		attrs, err := s.ctx.GetAttributeValue(s.session, obj, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil || len(attrs) != 2 {
+			return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, err)
+		}
+		publicKey = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}
+	case pkcs11.CKK_EC:
+		attrs, err := s.ctx.GetAttributeValue(s.session, obj, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil || len(attrs) != 2 {
+			return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, err)
+		}
+		publicKey, err = parsePKCS11ECPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, err)
+		}
+	default:
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(pkcs11SignerName, keyType)
+	}
+	s.publicKey = publicKey
+	return publicKey, nil
+}
+
+// GetSigningMethods returns the JWT signing methods supported by the key.
+func (s *pkcs11Signer) GetSigningMethods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return getSignerMethods(s.publicKey)
+}
+
+// Sign signs the digest with the private key held by the token.
+func (s *pkcs11Signer) Sign(method string, digest []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var mechanism *pkcs11.Mechanism
+	switch s.publicKey.(type) {
+	case *rsa.PublicKey:
+		prefix, exists := pkcs11DigestInfoPrefixes[signerMethodHashes[method]]
+		if !exists || method[:2] != "RS" {
+			return nil, errors.ErrCryptoKeySignerMethod.WithArgs(pkcs11SignerName, method)
+		}
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		digest = append(append([]byte{}, prefix...), digest...)
+	case *ecdsa.PublicKey:
+		if method[:2] != "ES" {
+			return nil, errors.ErrCryptoKeySignerMethod.WithArgs(pkcs11SignerName, method)
+		}
+		// The signature is the concatenation of R and S.
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+	default:
+		return nil, errors.ErrCryptoKeySignerMethod.WithArgs(pkcs11SignerName, method)
+	}
+	if err := s.ctx.SignInit(s.session, []*pkcs11.Mechanism{mechanism}, s.privateKey); err != nil {
+		return nil, errors.ErrCryptoKeySignerRequest.WithArgs(pkcs11SignerName, err)
+	}
+	sig, err := s.ctx.Sign(s.session, digest)
+	if err != nil {
+		return nil, errors.ErrCryptoKeySignerRequest.WithArgs(pkcs11SignerName, err)
+	}
+	return sig, nil
+}
+
+func parsePKCS11ECPublicKey(params, point []byte) (*ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil, err
+	}
+	curve, exists := pkcs11Curves[oid.String()]
+	if !exists {
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(pkcs11SignerName, oid.String())
+	}
+	// The point is DER-encoded octet string holding uncompressed point.
+	var raw []byte
+	if _, err := asn1.Unmarshal(point, &raw); err != nil {
+		raw = point
+	}
+	x, y := elliptic.Unmarshal(curve, raw)
+	if x == nil {
+		return nil, errors.ErrCryptoKeySignerPublicKey.WithArgs(pkcs11SignerName, "malformed ec point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// reverseBytes converts the little-endian CK_ULONG attribute values.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
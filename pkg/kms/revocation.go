@@ -0,0 +1,63 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+// revokeSigned adds a JWT or JWE token to the revocation list in the
+// revocation store of the key. The entry is kept until the token expires.
+func (k *CryptoKey) revokeSigned(s string, claims jwtlib.MapClaims) error {
+	store, err := tokenstore.Get(k.Config.RevocationStore)
+	if err != nil {
+		return errors.ErrCryptoKeyRevocationStore.WithArgs(k.Config.RevocationStore, err)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.ErrCryptoKeyTokenExpiryNotFound
+	}
+	entry := map[string]interface{}{
+		"revoked_at": time.Now().UTC().Unix(),
+	}
+	if err := store.Add(s, entry, time.Unix(int64(exp), 0)); err != nil {
+		return errors.ErrCryptoKeyRevocationStore.WithArgs(k.Config.RevocationStore, err)
+	}
+	return nil
+}
+
+// checkRevoked returns an error when the token is in the revocation list of
+// the key. The tokens are rejected when the revocation store is unavailable.
+func (k *CryptoKey) checkRevoked(s string) error {
+	if k.Config.RevocationStore == "" {
+		return nil
+	}
+	store, err := tokenstore.Get(k.Config.RevocationStore)
+	if err != nil {
+		return errors.ErrCryptoKeyRevocationStore.WithArgs(k.Config.RevocationStore, err)
+	}
+	switch _, err := store.Get(s); err {
+	case nil:
+		return errors.ErrCryptoKeyTokenRevoked
+	case errors.ErrTokenStoreTokenNotFound:
+		return nil
+	default:
+		return errors.ErrCryptoKeyRevocationStore.WithArgs(k.Config.RevocationStore, err)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+)
+
+func TestRevocationList(t *testing.T) {
+	store, err := tokenstore.NewStore(&tokenstore.Config{Name: "kms_revocation_test", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("failed creating token store: %v", err)
+	}
+	tokenstore.Register("kms_revocation_test", store)
+
+	// The issuer signs JWT tokens, and the verifier shares its revocation
+	// list.
+	issuer := NewCryptoKeyStore()
+	issuerCfgs, _ := ParseCryptoKeyConfigs("crypto key sign-verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+		"crypto key token revocation kms_revocation_test")
+	if err := issuer.AddKeysWithConfigs(issuerCfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	verifier := NewCryptoKeyStore()
+	verifierCfgs, _ := ParseCryptoKeyConfigs("crypto key verify 0e2fdcf8-6868-41a7-884b-7308795fc286\n" +
+		"crypto key token revocation kms_revocation_test")
+	if err := verifier.AddKeysWithConfigs(verifierCfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	tests.EvalObjects(t, "revocation list", true, verifier.HasRevocationList())
+	tests.EvalObjects(t, "revocation list without store", false, NewCryptoKeyStore().HasRevocationList())
+
+	usr := newTestUser()
+	if err := issuer.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	tests.EvalObjects(t, "opaque token", false, IsOpaqueToken(usr.Token))
+
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = usr.Token
+	if _, err := verifier.ParseToken(ar); err != nil {
+		t.Fatalf("failed parsing token: %v", err)
+	}
+
+	// The token revoked by the issuer is rejected by the verifier.
+	if err := issuer.RevokeToken(usr.Token); err != nil {
+		t.Fatalf("failed revoking token: %v", err)
+	}
+	_, err = verifier.ParseToken(ar)
+	tests.EvalErrWithLog(t, err, "parse revoked token", true, errors.ErrCryptoKeyStoreParseTokenFailed, []string{})
+	_, err = issuer.ParseToken(ar)
+	tests.EvalErrWithLog(t, err, "parse revoked token by issuer", true, errors.ErrCryptoKeyStoreParseTokenFailed, []string{})
+
+	// The tokens are rejected when the revocation store is unavailable.
+	other := newTestUser()
+	if err := issuer.SignToken(nil, nil, other); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	k := verifier.verifyKeys[0]
+	k.Config.RevocationStore = "kms_revocation_test_unknown"
+	tests.EvalErrWithLog(t, k.checkRevoked(other.Token), "unavailable revocation store", true,
+		errors.ErrCryptoKeyRevocationStore.WithArgs("kms_revocation_test_unknown", errors.ErrTokenStoreNotFound.WithArgs("kms_revocation_test_unknown")), []string{})
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultSignerRefreshInterval = 3600
+)
+
+var signerMethodHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// Signer is a signing backend holding a private key outside of the portal,
+// e.g. in a hardware security module or a cloud key management service. The
+// private key never leaves the backend.
+type Signer interface {
+	// GetName returns the name of the backend, e.g. pkcs11 or aws_kms.
+	GetName() string
+	// GetPublicKey fetches the public key of the signing key.
+	GetPublicKey() (crypto.PublicKey, error)
+	// GetSigningMethods returns the JWT signing methods supported by the key.
+	GetSigningMethods() []string
+	// Sign signs the digest with the signing method and returns the signature
+	// in JWS format, i.e. PKCS #1 v1.5 signature for RSA keys and the
+	// concatenation of R and S for ECDSA keys.
+	Sign(method string, digest []byte) ([]byte, error)
+}
+
+// signerKey wraps Signer and caches its public key. The round trip to a
+// backend is expensive, therefore the public key is refreshed in the
+// background ahead of its expiry, while the verification continues with the
+// cached key.
+type signerKey struct {
+	signer          Signer
+	refreshInterval time.Duration
+	mu              sync.RWMutex
+	publicKey       crypto.PublicKey
+	fetchedAt       time.Time
+	latency         time.Duration
+	refreshing      bool
+}
+
+func newSignerKey(signer Signer) *signerKey {
+	return &signerKey{
+		signer:          signer,
+		refreshInterval: time.Duration(defaultSignerRefreshInterval) * time.Second,
+	}
+}
+
+func (sk *signerKey) getPublicKey() (crypto.PublicKey, error) {
+	sk.mu.Lock()
+	publicKey := sk.publicKey
+	// The refresh starts early by the time the backend took to respond the
+	// last time, so that the refreshed key arrives before the cached one
+	// expires.
+	expired := time.Since(sk.fetchedAt)+sk.latency >= sk.refreshInterval
+	refresh := publicKey != nil && expired && !sk.refreshing
+	if refresh {
+		sk.refreshing = true
+	}
+	sk.mu.Unlock()
+
+	if publicKey == nil {
+		return sk.refresh()
+	}
+	if refresh {
+		go sk.refresh()
+	}
+	return publicKey, nil
+}
+
+func (sk *signerKey) refresh() (crypto.PublicKey, error) {
+	start := time.Now()
+	publicKey, err := sk.signer.GetPublicKey()
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+	sk.refreshing = false
+	if err != nil {
+		if sk.publicKey != nil {
+			// Keep using the cached key while the backend is unavailable.
+			return sk.publicKey, nil
+		}
+		return nil, err
+	}
+	sk.publicKey = publicKey
+	sk.fetchedAt = time.Now()
+	sk.latency = sk.fetchedAt.Sub(start)
+	return publicKey, nil
+}
+
+func (sk *signerKey) sign(method string, input []byte) ([]byte, error) {
+	hash, exists := signerMethodHashes[method]
+	if !exists {
+		return nil, errors.ErrCryptoKeySignerMethod.WithArgs(sk.signer.GetName(), method)
+	}
+	h := hash.New()
+	h.Write(input)
+	return sk.signer.Sign(method, h.Sum(nil))
+}
+
+// newSigner returns Signer for the source of the key config.
+func newSigner(cfg *CryptoKeyConfig) (Signer, error) {
+	switch cfg.Source {
+	case "pkcs11":
+		return newPKCS11Signer(cfg.SignerURI)
+	case "aws_kms":
+		return newAWSKMSSigner(cfg.SignerURI)
+	case "gcp_kms":
+		return newGCPKMSSigner(cfg.SignerURI)
+	}
+	return nil, fmt.Errorf("unsupported signer %q", cfg.Source)
+}
+
+// getKeyFromSigner returns a key signing tokens with an external signer.
+func getKeyFromSigner(cfg *CryptoKeyConfig) (*CryptoKey, error) {
+	signer, err := newSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	k := newCryptoKey()
+	k.Config = cfg
+	k.Config.Algorithm = "signer"
+	k.signer = newSignerKey(signer)
+	// The public key is fetched upfront, because the signing methods depend
+	// on the type of the key.
+	if _, err := k.signer.getPublicKey(); err != nil {
+		return nil, err
+	}
+	methods := signer.GetSigningMethods()
+	if len(methods) == 0 {
+		return nil, errors.ErrCryptoKeySignerKeyUnsupported.WithArgs(signer.GetName(), cfg.SignerURI)
+	}
+	if cfg.Usage != "verify" {
+		k.Sign.Capable = true
+		k.Sign.Token.PreferredMethods = methods
+	}
+	if cfg.Usage != "sign" {
+		k.Verify.Capable = true
+		k.Verify.Token.PreferredMethods = methods
+	}
+	return k, nil
+}
+
+// getSignerMethods returns the JWT signing methods supported by a public key.
+func getSignerMethods(publicKey crypto.PublicKey) []string {
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512"}
+	case *ecdsa.PublicKey:
+		switch pk.Curve.Params().BitSize {
+		case 256:
+			return []string{"ES256"}
+		case 384:
+			return []string{"ES384"}
+		case 521:
+			return []string{"ES512"}
+		}
+	}
+	return nil
+}
+
+// encodeECDSASignature converts ASN.1 DER-encoded ECDSA signature to the
+// concatenation of R and S, as required by JWS.
+func encodeECDSASignature(der []byte, publicKey crypto.PublicKey) ([]byte, error) {
+	pk, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %T is not ecdsa", publicKey)
+	}
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	size := (pk.Curve.Params().BitSize + 7) / 8
+	if sig.R == nil || sig.S == nil || sig.R.BitLen() > 8*size || sig.S.BitLen() > 8*size {
+		return nil, fmt.Errorf("malformed ecdsa signature")
+	}
+	b := make([]byte, 2*size)
+	sig.R.FillBytes(b[:size])
+	sig.S.FillBytes(b[size:])
+	return b, nil
+}
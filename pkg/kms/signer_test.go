@@ -0,0 +1,300 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestParsePKCS11URI(t *testing.T) {
+	testcases := []struct {
+		name      string
+		uri       string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "parse pkcs11 uri with token and object labels",
+			uri:  "pkcs11:token=authp;object=jwt%20key;type=private?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234",
+			want: map[string]interface{}{
+				"module_path": "/usr/lib/softhsm/libsofthsm2.so",
+				"token_label": "authp",
+				"key_label":   "jwt key",
+				"pin":         "1234",
+			},
+		},
+		{
+			name: "parse pkcs11 uri with slot and key ids",
+			uri:  "pkcs11:slot-id=2;id=%01%02?module-path=/opt/hsm/lib.so",
+			want: map[string]interface{}{
+				"module_path": "/opt/hsm/lib.so",
+				"slot_id":     2,
+				"key_id":      []byte{1, 2},
+			},
+		},
+		{
+			name:      "parse pkcs11 uri without module path",
+			uri:       "pkcs11:token=authp;object=jwt",
+			shouldErr: true,
+			err: errors.ErrCryptoKeySignerURIInvalid.WithArgs(
+				"pkcs11", "pkcs11:token=authp;object=jwt", "module-path not found",
+			),
+		},
+		{
+			name:      "parse pkcs11 uri without key",
+			uri:       "pkcs11:token=authp?module-path=/opt/hsm/lib.so",
+			shouldErr: true,
+			err: errors.ErrCryptoKeySignerURIInvalid.WithArgs(
+				"pkcs11", "pkcs11:token=authp?module-path=/opt/hsm/lib.so", "object or id not found",
+			),
+		},
+		{
+			name:      "parse uri without pkcs11 scheme",
+			uri:       "token=authp",
+			shouldErr: true,
+			err:       errors.ErrCryptoKeySignerURIInvalid.WithArgs("pkcs11", "token=authp", "no pkcs11 scheme"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := parsePKCS11URI(tc.uri)
+			if tests.EvalErrWithLog(t, err, "pkcs11 uri", tc.shouldErr, tc.err, []string{}) {
+				return
+			}
+			got := map[string]interface{}{
+				"module_path": cfg.modulePath,
+			}
+			if cfg.tokenLabel != "" {
+				got["token_label"] = cfg.tokenLabel
+			}
+			if cfg.hasSlotID {
+				got["slot_id"] = cfg.slotID
+			}
+			if cfg.keyLabel != "" {
+				got["key_label"] = cfg.keyLabel
+			}
+			if len(cfg.keyID) > 0 {
+				got["key_id"] = cfg.keyID
+			}
+			if cfg.pin != "" {
+				got["pin"] = cfg.pin
+			}
+			tests.EvalObjectsWithLog(t, "pkcs11 uri", tc.want, got, []string{})
+		})
+	}
+}
+
+func TestExternalSigners(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+
+	// The fake AWS KMS holds ECDSA key.
+	var awsPublicKeyRequests int32
+	awsSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			atomic.AddInt32(&awsPublicKeyRequests, 1)
+			b, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+			json.NewEncoder(w).Encode(map[string]string{
+				"KeyId":     req["KeyId"],
+				"KeyUsage":  "SIGN_VERIFY",
+				"PublicKey": base64.StdEncoding.EncodeToString(b),
+			})
+		case "TrentService.Sign":
+			digest, _ := base64.StdEncoding.DecodeString(req["Message"])
+			sig, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"Signature": base64.StdEncoding.EncodeToString(sig),
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer awsSrv.Close()
+	t.Setenv("AWS_ENDPOINT_URL_KMS", awsSrv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+
+	// The fake Cloud KMS holds RSA key.
+	gcpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/publicKey"):
+			b, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+			json.NewEncoder(w).Encode(map[string]string{
+				"pem":       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})),
+				"algorithm": "RSA_SIGN_PKCS1_2048_SHA256",
+			})
+		case strings.HasSuffix(r.URL.Path, ":asymmetricSign"):
+			var req map[string]map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			digest, _ := base64.StdEncoding.DecodeString(req["digest"]["sha256"])
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"signature": base64.StdEncoding.EncodeToString(sig),
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer gcpSrv.Close()
+	defaultEndpoint := gcpKMSEndpoint
+	gcpKMSEndpoint = gcpSrv.URL + "/v1/"
+	defer func() { gcpKMSEndpoint = defaultEndpoint }()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.foobar")
+
+	testcases := []struct {
+		name    string
+		config  string
+		methods []string
+		keyType string
+	}{
+		{
+			name:    "sign and verify tokens with aws kms key",
+			config:  "crypto key sign-verify from aws_kms arn:aws:kms:us-east-1:111122223333:key/1234abcd",
+			methods: []string{"ES256"},
+			keyType: "EC",
+		},
+		{
+			name:    "sign and verify tokens with cloud kms key",
+			config:  "crypto key sign-verify from gcp_kms projects/authp/locations/global/keyRings/portal/cryptoKeys/jwt/cryptoKeyVersions/1",
+			methods: []string{"RS256"},
+			keyType: "RSA",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgs, err := ParseCryptoKeyConfigs(tc.config)
+			if err != nil {
+				t.Fatalf("failed parsing configs: %v", err)
+			}
+			ks := NewCryptoKeyStore()
+			if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+				t.Fatalf("failed adding keys: %v", err)
+			}
+			tests.EvalObjects(t, "signing methods", tc.methods, ks.GetSigningMethods())
+			tests.EvalObjects(t, "jwks key type", tc.keyType, ks.GetJWKS()["keys"].([]*jwksKey)[0].KeyType)
+
+			usr := newTestUser()
+			if err := ks.SignToken(nil, nil, usr); err != nil {
+				t.Fatalf("failed signing token: %v", err)
+			}
+			for i := 0; i < 3; i++ {
+				ar := requests.NewAuthorizationRequest()
+				ar.Token.Name = "access_token"
+				ar.Token.Payload = usr.Token
+				parsedUser, err := ks.ParseToken(ar)
+				if err != nil {
+					t.Fatalf("failed parsing token: %v", err)
+				}
+				tests.EvalObjects(t, "subject", usr.Claims.Subject, parsedUser.Claims.Subject)
+			}
+		})
+	}
+
+	// The public key is fetched once and served from cache afterwards.
+	tests.EvalObjects(t, "aws public key requests", int32(1), atomic.LoadInt32(&awsPublicKeyRequests))
+
+	// The unsupported signing methods are rejected.
+	cfgs, err := ParseCryptoKeyConfigs(testcases[0].config)
+	if err != nil {
+		t.Fatalf("failed parsing configs: %v", err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+		t.Fatalf("failed adding keys: %v", err)
+	}
+	err = ks.SignToken(nil, "RS256", newTestUser())
+	tests.EvalErrWithLog(t, err, "unsupported method", true, errors.ErrUnsupportedSigningMethod.WithArgs("RS256"), []string{})
+
+	// The missing credentials prevent the key from loading.
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = GetKeysFromConfigs(cfgs)
+	tests.EvalErrWithLog(t, err, "missing credentials", true, errors.ErrCryptoKeySignerCredentials.WithArgs("aws_kms"), []string{})
+}
+
+func TestSignerKeyRefresh(t *testing.T) {
+	signer := &testSigner{}
+	sk := newSignerKey(signer)
+	sk.refreshInterval = 50 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		if _, err := sk.getPublicKey(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	tests.EvalObjects(t, "fetches before expiry", int32(1), atomic.LoadInt32(&signer.fetches))
+
+	// After expiry, the cached key is served while the refresh runs in the
+	// background.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := sk.getPublicKey(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 50 && atomic.LoadInt32(&signer.fetches) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	tests.EvalObjects(t, "fetches after expiry", int32(2), atomic.LoadInt32(&signer.fetches))
+}
+
+type testSigner struct {
+	fetches int32
+}
+
+func (s *testSigner) GetName() string { return "test" }
+
+func (s *testSigner) GetPublicKey() (crypto.PublicKey, error) {
+	n := atomic.AddInt32(&s.fetches, 1)
+	return fmt.Sprintf("public key %d", n), nil
+}
+
+func (s *testSigner) GetSigningMethods() []string { return nil }
+
+func (s *testSigner) Sign(method string, digest []byte) ([]byte, error) { return nil, nil }
@@ -0,0 +1,136 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
+)
+
+// vaultTransitKey signs tokens with a Vault Transit key. The private key
+// never leaves Vault. The tokens carry the version of the key in kid header,
+// so that the tokens signed before key rotation remain verifiable.
+type vaultTransitKey struct {
+	name   string
+	client *vault.Client
+}
+
+func (tk *vaultTransitKey) getKeyID(version int) string {
+	return fmt.Sprintf("%s:v%d", tk.name, version)
+}
+
+func (tk *vaultTransitKey) getVersion(kid string) int {
+	if !strings.HasPrefix(kid, tk.name+":v") {
+		return 0
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(kid, tk.name+":v"))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// getKeysFromVault loads the key referenced by Vault-sourced key config.
+func getKeysFromVault(cfg *CryptoKeyConfig) ([]*CryptoKey, error) {
+	if cfg.VaultTransitKey != "" {
+		client, err := vault.GetDefaultClient()
+		if err != nil {
+			return nil, err
+		}
+		keyType, _, err := client.GetTransitKey(cfg.VaultTransitKey)
+		if err != nil {
+			return nil, err
+		}
+		k := newCryptoKey()
+		k.Config = cfg
+		k.Config.Algorithm = "transit"
+		k.transit = &vaultTransitKey{
+			name:   cfg.VaultTransitKey,
+			client: client,
+		}
+		methods := vault.GetTransitSigningMethods(keyType)
+		if cfg.Usage != "verify" {
+			k.Sign.Capable = true
+			k.Sign.Token.PreferredMethods = methods
+		}
+		if cfg.Usage != "sign" {
+			k.Verify.Capable = true
+			k.Verify.Token.PreferredMethods = methods
+		}
+		return []*CryptoKey{k}, nil
+	}
+
+	secret, err := vault.Resolve("vault:" + cfg.VaultPath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(secret, "---") {
+		// Discovered PEM-encoded key
+		k, err := extractKey([]byte(secret), cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []*CryptoKey{k}, nil
+	}
+	// Discovered shared key
+	k := newCryptoKey()
+	k.Config = cfg
+	k.Config.Algorithm = "hmac"
+	k.Config.Secret = secret
+	return []*CryptoKey{k}, nil
+}
+
+func (k *CryptoKey) signTransit(method string, data interface{}) (interface{}, error) {
+	_, version, err := k.transit.client.GetTransitKey(k.transit.name)
+	if err != nil {
+		return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+	}
+	header := map[string]interface{}{
+		"typ": "JWT",
+		"alg": method,
+		"kid": k.transit.getKeyID(version),
+	}
+	jh, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+	}
+	jb, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+	}
+	s := base64.RawURLEncoding.EncodeToString(jh) + "." + base64.RawURLEncoding.EncodeToString(jb)
+	sig, err := k.transit.client.TransitSign(k.transit.name, version, method, []byte(s))
+	if err != nil {
+		return nil, errors.ErrDataSigningFailed.WithArgs(method, err)
+	}
+	return s + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (k *CryptoKey) provideTransitKey(token *jwtlib.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwtlib.SigningMethodRSA, *jwtlib.SigningMethodECDSA:
+	default:
+		return nil, errors.ErrUnexpectedSigningMethod.WithArgs("RS or ES", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	return k.transit.client.GetTransitPublicKey(k.transit.name, k.transit.getVersion(kid))
+}
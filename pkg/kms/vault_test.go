@@ -0,0 +1,115 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
+)
+
+func TestVaultKeys(t *testing.T) {
+	srv := tests.NewVaultServer("s.foobar")
+	defer srv.Close()
+	srv.SetSecret("authp", map[string]interface{}{"token_secret": "b006d65b-c923-46a1-8da1-7d52558508fe"})
+	if err := srv.RotateTransitKey("authp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client, err := vault.NewClient(&vault.Config{Address: srv.URL(), Token: "s.foobar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vault.SetDefaultClient(client)
+	defer vault.SetDefaultClient(nil)
+
+	parseToken := func(ks *CryptoKeyStore, token string) (string, error) {
+		ar := requests.NewAuthorizationRequest()
+		ar.Token.Name = "access_token"
+		ar.Token.Payload = token
+		usr, err := ks.ParseToken(ar)
+		if err != nil {
+			return "", err
+		}
+		return usr.Claims.Subject, nil
+	}
+
+	newKeyStore := func(s string) *CryptoKeyStore {
+		cfgs, err := ParseCryptoKeyConfigs(s)
+		if err != nil {
+			t.Fatalf("failed parsing configs: %v", err)
+		}
+		ks := NewCryptoKeyStore()
+		if err := ks.AddKeysWithConfigs(cfgs); err != nil {
+			t.Fatalf("failed adding keys: %v", err)
+		}
+		return ks
+	}
+
+	// The shared secret is read from Vault KV.
+	ks := newKeyStore("crypto key sign-verify from vault authp#token_secret")
+	usr := newTestUser()
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	sub, err := parseToken(newKeyStore("crypto key verify b006d65b-c923-46a1-8da1-7d52558508fe"), usr.Token)
+	if err != nil {
+		t.Fatalf("failed parsing token: %v", err)
+	}
+	tests.EvalObjects(t, "kv subject", usr.Claims.Subject, sub)
+
+	// The tokens are signed by Vault Transit.
+	ks = newKeyStore("crypto key sign-verify from transit authp")
+	tests.EvalObjects(t, "signing methods", []string{"ES256"}, ks.GetSigningMethods())
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	oldToken := usr.Token
+	sub, err = parseToken(ks, oldToken)
+	if err != nil {
+		t.Fatalf("failed parsing token: %v", err)
+	}
+	tests.EvalObjects(t, "transit subject", usr.Claims.Subject, sub)
+	tests.EvalObjects(t, "transit jwks kid", "authp:v1", ks.GetJWKS()["keys"].([]*jwksKey)[0].KeyID)
+
+	// After the rotation, the new tokens are signed with the latest version
+	// of the key, and the tokens signed before the rotation remain valid.
+	if err := srv.RotateTransitKey("authp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client, err = vault.NewClient(&vault.Config{Address: srv.URL(), Token: "s.foobar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vault.SetDefaultClient(client)
+	ks = newKeyStore("crypto key sign-verify from transit authp")
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("failed signing token: %v", err)
+	}
+	tests.EvalObjects(t, "rotated token", false, usr.Token == oldToken)
+	for _, token := range []string{oldToken, usr.Token} {
+		if _, err := parseToken(ks, token); err != nil {
+			t.Fatalf("failed parsing token: %v", err)
+		}
+	}
+	tests.EvalObjects(t, "rotated jwks kid", "authp:v2", ks.GetJWKS()["keys"].([]*jwksKey)[0].KeyID)
+
+	// The tampered tokens are rejected.
+	if _, err := parseToken(ks, strings.TrimSuffix(usr.Token, usr.Token[len(usr.Token)-4:])+"AAAA"); err == nil {
+		t.Fatalf("expected error for tampered token")
+	}
+}
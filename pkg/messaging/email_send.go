@@ -20,6 +20,7 @@ import (
 	"github.com/emersion/go-smtp"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	"strings"
 	"time"
@@ -35,6 +36,15 @@ type EmailProviderSendInput struct {
 
 // Send sends an email message.
 func (e *EmailProvider) Send(req *EmailProviderSendInput) error {
+	if err := e.send(req); err != nil {
+		metrics.MessageDeliveries.Inc(e.Name, "failure")
+		return err
+	}
+	metrics.MessageDeliveries.Inc(e.Name, "success")
+	return nil
+}
+
+func (e *EmailProvider) send(req *EmailProviderSendInput) error {
 	dial := smtp.Dial
 	if e.Protocol == "smtps" {
 		dial = func(addr string) (*smtp.Client, error) {
@@ -58,7 +68,15 @@ func (e *EmailProvider) Send(req *EmailProviderSendInput) error {
 		if found, _ := c.Extension("AUTH"); !found {
 			return errors.ErrMessagingProviderAuthUnsupported
 		}
-		auth := sasl.NewPlainClient("", req.Credentials.Username, req.Credentials.Password)
+		username, err := credentials.ResolveSecret(req.Credentials.Username)
+		if err != nil {
+			return err
+		}
+		password, err := credentials.ResolveSecret(req.Credentials.Password)
+		if err != nil {
+			return err
+		}
+		auth := sasl.NewPlainClient("", username, password)
 		if err := c.Auth(auth); err != nil {
 			return err
 		}
@@ -4,6 +4,11 @@ package messaging
 var EmailTemplateBody = map[string]string{
 	"en/registration_confirmation": `<html>
   <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
     <p>
       Please confirm your registration by clicking this
       <a href="{{ .registration_url }}/ack/{{ .registration_id }}">link</a>
@@ -18,12 +23,23 @@ var EmailTemplateBody = map[string]string{
       <li>Username: <code>{{ .username }}</code></li>
       <li>Email: <code>{{ .email }}</code></li>
       <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
       <li>Timestamp: {{ .timestamp }}</li>
     </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
   </body>
 </html>`,
 	"en/registration_ready": `<html>
   <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
     <p>
       The following user successfully registered with the portal.
       Please use management interface to approve or decline the registration.
@@ -38,12 +54,23 @@ var EmailTemplateBody = map[string]string{
       <li>Username: <code>{{ .username }}</code></li>
       <li>Email: <code>{{ .email }}</code></li>
       <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
       <li>Timestamp: {{ .timestamp }}</li>
     </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
   </body>
 </html>`,
 	"en/registration_verdict": `<html>
   <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
     <p>
     {{- if eq .verdict "approved" -}}
       Your registration has been approved.
@@ -59,6 +86,326 @@ var EmailTemplateBody = map[string]string{
       <li>Email: <code>{{ .email }}</code></li>
       <li>Timestamp: {{ .timestamp }}</li>
     </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/password_recovery": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      We received a request to reset the password of the account associated
+      with this email address. Please use the link below to choose a new
+      password. The link is valid for {{ .lifetime }} and may be used once.
+    </p>
+    <p><a href="{{ .recovery_url }}">Reset Password</a></p>
+    <p>If you did not request a password reset, you may ignore this email.</p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/magic_link": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      We received a request to sign in to the account associated with this
+      email address. Please use the link below to sign in. The link is valid
+      for {{ .lifetime }} and may be used once.
+    </p>
+    <p><a href="{{ .login_url }}">Sign In</a></p>
+    <p>If you did not request to sign in, you may ignore this email.</p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/new_sign_in": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      Your account <code>{{ .username }}</code> was just used to sign in
+      {{- if eq .reason "impossible_travel" }} from a location too far from
+      the location of your previous sign-in to travel in between
+      {{- else if eq .reason "new_location" }} from a new location
+      {{- else }} from a new device{{ end }}.
+    </p>
+    <p>
+      If this was you, you may ignore this email. If you do not recognize
+      this activity, please use the link below to sign out all sessions
+      and change your password. The link is valid for {{ .lifetime }}.
+    </p>
+    <p><a href="{{ .revoke_url }}">Sign Out All Sessions</a></p>
+    <p>The sign-in metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>User Agent: <code>{{ .user_agent }}</code></li>
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/email_change_code": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      We received a request to change the email address of the account
+      <code>{{ .username }}</code> to this email address. Please use the
+      code below to confirm the change. The code is valid for {{ .lifetime }}.
+    </p>
+    <p><b>{{ .code }}</b></p>
+    <p>If you did not request the change, you may ignore this email.</p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/email_change_notice": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      We received a request to change the email address of the account
+      <code>{{ .username }}</code> from this email address to
+      <code>{{ .new_email }}</code>. This email address remains active
+      until the change is confirmed.
+    </p>
+    <p>
+      If you did not request the change, please change your password and
+      contact the administrator.
+    </p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/account_deletion_code": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      We received a request to delete the account <code>{{ .username }}</code>.
+      Please use the code below to confirm the deletion. The code is valid
+      for {{ .lifetime }}.
+    </p>
+    <p><b>{{ .code }}</b></p>
+    <p>If you did not request the deletion, you may ignore this email.</p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/account_deletion_notice": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      The account <code>{{ .username }}</code> is scheduled for deletion on
+      {{ .scheduled_at }}. After that, the account and the associated data
+      are permanently deleted.
+    </p>
+    <p>
+      If you changed your mind, sign in before the scheduled deletion to
+      reactivate the account.
+    </p>
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/access_request": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      The user <code>{{ .username }}</code> requested the <code>{{ .role }}</code>
+      role granting access to {{ .app }}. Please use management interface to
+      approve or decline the request.
+    </p>
+    {{- if .justification }}
+    <p>Justification: {{ .justification }}</p>
+    {{- end }}
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Access Request ID: {{ .access_request_id }}</li>
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>Email: <code>{{ .email }}</code></li>
+      <li>Lifetime: {{ if .lifetime }}{{ .lifetime }}{{ else }}permanent{{ end }}</li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/access_request_verdict": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+    {{- if eq .verdict "approved" -}}
+      Your request for the <code>{{ .role }}</code> role granting access to
+      {{ .app }} has been approved.
+      {{- if .expires_at }} The access expires on {{ .expires_at }}.{{ end }}
+      Please sign in again to use it.
+    {{- else if eq .verdict "declined" -}}
+      Your request for the <code>{{ .role }}</code> role granting access to
+      {{ .app }} has been declined.
+    {{- else -}}
+      Your access to {{ .app }} via the <code>{{ .role }}</code> role has
+      {{ if eq .verdict "expired" }}expired{{ else }}been revoked{{ end }}.
+    {{- end -}}
+    </p>
+    {{- if .comment }}
+    <p>Comment: {{ .comment }}</p>
+    {{- end }}
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Access Request ID: {{ .access_request_id }}</li>
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
+  </body>
+</html>`,
+	"en/break_glass_login": `<html>
+  <body>
+    {{- if .brand_logo_url }}
+    <p><img src="{{ .brand_logo_url }}" alt="{{ .brand_name }}" style="max-height: 48px" /></p>
+    {{- else if .brand_name }}
+    <h2{{ if .brand_primary_color }} style="color: {{ .brand_primary_color }}"{{ end }}>{{ .brand_name }}</h2>
+    {{- end }}
+    <p>
+      The break-glass account <code>{{ .username }}</code> signed in. The
+      account is reserved for emergencies, e.g. an outage of the identity
+      provider. If the sign-in is unexpected, please disable the account and
+      revoke its hardware security keys immediately.
+    </p>
+    <p>The sign-in metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Realm: {{ .realm }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>Email: <code>{{ .email }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      {{- if .location }}
+      <li>Location: {{ .location }}</li>
+      {{- end }}
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+    {{- if .brand_footer }}
+    <p style="font-size: small">{{ .brand_footer }}</p>
+    {{- end }}
   </body>
 </html>`,
 }
@@ -23,4 +23,20 @@ User Registration Approved
 {{- else -}}
 User Registration Declined
 {{- end -}}`,
+	"en/password_recovery":       `Password Reset Request`,
+	"en/email_change_code":       `Email Address Change Confirmation`,
+	"en/email_change_notice":     `Email Address Change Request`,
+	"en/magic_link":              `Sign In Link`,
+	"en/new_sign_in":             `New Sign-In to Your Account`,
+	"en/account_deletion_code":   `Account Deletion Confirmation`,
+	"en/account_deletion_notice": `Account Scheduled for Deletion`,
+	"en/access_request":          `Access Request for {{ .app }}`,
+	"en/access_request_verdict": `{{- if eq .verdict "approved" -}}
+Access Request Approved
+{{- else if eq .verdict "declined" -}}
+Access Request Declined
+{{- else -}}
+Access Expired
+{{- end -}}`,
+	"en/break_glass_login": `URGENT: Break-Glass Account {{ .username }} Signed In`,
 }
\ No newline at end of file
@@ -16,6 +16,7 @@ package messaging
 
 import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/util"
 	"io/ioutil"
 	"os"
@@ -33,6 +34,15 @@ type FileProviderSendInput struct {
 
 // Send writes a message to a file system.
 func (p *FileProvider) Send(req *FileProviderSendInput) error {
+	if err := p.send(req); err != nil {
+		metrics.MessageDeliveries.Inc(p.Name, "failure")
+		return err
+	}
+	metrics.MessageDeliveries.Inc(p.Name, "success")
+	return nil
+}
+
+func (p *FileProvider) send(req *FileProviderSendInput) error {
 	fileInfo, err := os.Stat(p.RootDir)
 	if err != nil {
 		if !os.IsNotExist(err) {
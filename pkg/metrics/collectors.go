@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// Default is the registry the authentication portals, the identity
+// providers, and the gatekeepers record their metrics to.
+var Default = NewRegistry()
+
+var (
+	// Logins counts the login attempts by realm, identity provider or
+	// store, and outcome, e.g. success, failure.
+	Logins = Default.NewCounter(
+		"authcrunch_logins_total",
+		"Number of login attempts by realm, provider, and outcome.",
+		"realm", "provider", "outcome",
+	)
+	// TokensIssued counts the access tokens issued by realm and grant,
+	// e.g. login, client_credentials, oidc.
+	TokensIssued = Default.NewCounter(
+		"authcrunch_tokens_issued_total",
+		"Number of access tokens issued by realm and grant.",
+		"realm", "grant",
+	)
+	// MfaChallenges counts the multi-factor authentication challenges by
+	// realm, challenge type, and outcome.
+	MfaChallenges = Default.NewCounter(
+		"authcrunch_mfa_challenges_total",
+		"Number of multi-factor authentication challenges by realm, type, and outcome.",
+		"realm", "type", "outcome",
+	)
+	// AuthorizationDecisions counts the decisions of the gatekeepers, i.e.
+	// allow, deny, bypass, anonymous.
+	AuthorizationDecisions = Default.NewCounter(
+		"authcrunch_authorization_decisions_total",
+		"Number of authorization decisions by gatekeeper, realm, and decision.",
+		"gatekeeper", "realm", "decision",
+	)
+	// ShadowDecisions counts the decisions of the candidate access lists of
+	// the gatekeepers diverging from the active ones, by the decision of the
+	// candidate access list, i.e. allow, deny.
+	ShadowDecisions = Default.NewCounter(
+		"authcrunch_shadow_decisions_total",
+		"Number of divergent decisions of candidate access lists by gatekeeper and decision.",
+		"gatekeeper", "decision",
+	)
+	// TokenSources counts the tokens found in the requests by gatekeeper and
+	// source, e.g. cookie, bearer, header, query.
+	TokenSources = Default.NewCounter(
+		"authcrunch_token_sources_total",
+		"Number of tokens found in the requests by gatekeeper and source.",
+		"gatekeeper", "source",
+	)
+	// StreamClosures counts the long-lived requests, i.e. WebSocket and
+	// Server-Sent Events, closed by the gatekeepers by kind and reason,
+	// i.e. expired, invalid.
+	StreamClosures = Default.NewCounter(
+		"authcrunch_stream_closures_total",
+		"Number of long-lived requests closed by gatekeeper, kind, and reason.",
+		"gatekeeper", "kind", "reason",
+	)
+	// IdentityProviderLatency observes the duration of the requests to the
+	// upstream identity providers.
+	IdentityProviderLatency = Default.NewHistogram(
+		"authcrunch_identity_provider_request_duration_seconds",
+		"Duration of the requests to the upstream identity providers.",
+		DefaultBuckets,
+		"realm", "provider",
+	)
+	// UpstreamRequestLatency observes the duration of the outbound requests
+	// of the identity providers by upstream host and outcome.
+	UpstreamRequestLatency = Default.NewHistogram(
+		"authcrunch_upstream_request_duration_seconds",
+		"Duration of the outbound requests to the upstream hosts by host and outcome.",
+		DefaultBuckets,
+		"host", "outcome",
+	)
+	// CacheRequests counts the cache lookups by cache and result, i.e. hit
+	// or miss.
+	CacheRequests = Default.NewCounter(
+		"authcrunch_cache_requests_total",
+		"Number of cache lookups by cache and result.",
+		"cache", "result",
+	)
+	// MessageDeliveries counts the message deliveries by messaging
+	// provider and outcome.
+	MessageDeliveries = Default.NewCounter(
+		"authcrunch_message_deliveries_total",
+		"Number of message deliveries by provider and outcome.",
+		"provider", "outcome",
+	)
+	// AuditEvents counts the security events forwarded by the audit sinks
+	// by sink and outcome, i.e. delivered, failed, dropped.
+	AuditEvents = Default.NewCounter(
+		"authcrunch_audit_events_total",
+		"Number of security events forwarded by audit sinks by sink and outcome.",
+		"sink", "outcome",
+	)
+)
+
+// RecordCacheLookup records a hit or a miss of the named cache.
+func RecordCacheLookup(name string, hit bool) {
+	if hit {
+		CacheRequests.Inc(name, "hit")
+		return
+	}
+	CacheRequests.Inc(name, "miss")
+}
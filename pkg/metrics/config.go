@@ -0,0 +1,45 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const defaultPath = "/metrics"
+
+// Config is the configuration of the metrics exposition endpoint.
+type Config struct {
+	// Address is the address the endpoint listens on, e.g. 127.0.0.1:9464.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// Path is the path of the endpoint. It defaults to /metrics.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// Validate validates metrics config.
+func (cfg *Config) Validate() error {
+	if cfg.Address == "" {
+		return errors.ErrMetricsConfigAddressEmpty
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return errors.ErrMetricsConfigPathInvalid.WithArgs(cfg.Path)
+	}
+	return nil
+}
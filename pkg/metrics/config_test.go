@@ -0,0 +1,80 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test metrics config with default path",
+			config: &Config{
+				Address: "127.0.0.1:9464",
+			},
+			want: &Config{
+				Address: "127.0.0.1:9464",
+				Path:    "/metrics",
+			},
+		},
+		{
+			name: "test metrics config with custom path",
+			config: &Config{
+				Address: "127.0.0.1:9464",
+				Path:    "/auth/metrics",
+			},
+			want: &Config{
+				Address: "127.0.0.1:9464",
+				Path:    "/auth/metrics",
+			},
+		},
+		{
+			name:      "test metrics config without address",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrMetricsConfigAddressEmpty,
+		},
+		{
+			name: "test metrics config with relative path",
+			config: &Config{
+				Address: "127.0.0.1:9464",
+				Path:    "metrics",
+			},
+			shouldErr: true,
+			err:       errors.ErrMetricsConfigPathInvalid.WithArgs("metrics"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "Config", tc.want, tc.config, msgs)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	testcases := []struct {
+		name  string
+		setup func(r *Registry)
+		want  []string
+	}{
+		{
+			name: "test counter without observations",
+			setup: func(r *Registry) {
+				r.NewCounter("test_logins_total", "Number of logins.", "realm", "outcome")
+			},
+			want: []string{
+				"# HELP test_logins_total Number of logins.",
+				"# TYPE test_logins_total counter",
+			},
+		},
+		{
+			name: "test counter with labels",
+			setup: func(r *Registry) {
+				c := r.NewCounter("test_logins_total", "Number of logins.", "realm", "outcome")
+				c.Inc("local", "success")
+				c.Inc("local", "success")
+				c.Add(3, "contoso", "failure")
+				c.Add(-1, "contoso", "failure")
+			},
+			want: []string{
+				"# HELP test_logins_total Number of logins.",
+				"# TYPE test_logins_total counter",
+				`test_logins_total{realm="contoso",outcome="failure"} 3`,
+				`test_logins_total{realm="local",outcome="success"} 2`,
+			},
+		},
+		{
+			name: "test counter with mismatched and escaped label values",
+			setup: func(r *Registry) {
+				c := r.NewCounter("test_logins_total", "Number of logins.", "realm", "outcome")
+				c.Inc(`a"b\c`)
+				c.Inc("local", "success", "extra")
+			},
+			want: []string{
+				"# HELP test_logins_total Number of logins.",
+				"# TYPE test_logins_total counter",
+				`test_logins_total{realm="a\"b\\c",outcome=""} 1`,
+				`test_logins_total{realm="local",outcome="success"} 1`,
+			},
+		},
+		{
+			name: "test counter without labels",
+			setup: func(r *Registry) {
+				c := r.NewCounter("test_requests_total", "Number of requests.")
+				c.Inc()
+			},
+			want: []string{
+				"# HELP test_requests_total Number of requests.",
+				"# TYPE test_requests_total counter",
+				"test_requests_total 1",
+			},
+		},
+		{
+			name: "test histogram",
+			setup: func(r *Registry) {
+				h := r.NewHistogram("test_duration_seconds", "Duration of requests.", []float64{0.5, 0.1}, "realm")
+				h.Observe(0.05, "google")
+				h.Observe(0.3, "google")
+				h.Observe(2, "google")
+			},
+			want: []string{
+				"# HELP test_duration_seconds Duration of requests.",
+				"# TYPE test_duration_seconds histogram",
+				`test_duration_seconds_bucket{realm="google",le="0.1"} 1`,
+				`test_duration_seconds_bucket{realm="google",le="0.5"} 2`,
+				`test_duration_seconds_bucket{realm="google",le="+Inf"} 3`,
+				`test_duration_seconds_sum{realm="google"} 2.35`,
+				`test_duration_seconds_count{realm="google"} 3`,
+			},
+		},
+		{
+			name: "test metrics are sorted by name",
+			setup: func(r *Registry) {
+				r.NewCounter("test_b_total", "B.").Inc()
+				r.NewCounter("test_a_total", "A.").Inc()
+			},
+			want: []string{
+				"# HELP test_a_total A.",
+				"# TYPE test_a_total counter",
+				"test_a_total 1",
+				"# HELP test_b_total B.",
+				"# TYPE test_b_total counter",
+				"test_b_total 1",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			r := NewRegistry()
+			tc.setup(r)
+			var buf bytes.Buffer
+			if err := r.Write(&buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			tests.EvalObjectsWithLog(t, "output", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Server exposes the metrics of a registry over HTTP.
+type Server struct {
+	config   *Config
+	registry *Registry
+	logger   *zap.Logger
+	mu       sync.Mutex
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer returns an instance of Server.
+func NewServer(cfg *Config, registry *Registry, logger *zap.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if registry == nil {
+		registry = Default
+	}
+	srv := &Server{
+		config:   cfg,
+		registry: registry,
+		logger:   logger,
+	}
+	return srv, nil
+}
+
+// GetAddress returns the address the endpoint listens on.
+func (srv *Server) GetAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listener != nil {
+		return srv.listener.Addr().String()
+	}
+	return srv.config.Address
+}
+
+// Start starts serving the metrics.
+func (srv *Server) Start() error {
+	listener, err := net.Listen("tcp", srv.config.Address)
+	if err != nil {
+		return errors.ErrMetricsStartFailed.WithArgs(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(srv.config.Path, srv.registry)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	srv.mu.Lock()
+	srv.listener = listener
+	srv.server = server
+	srv.mu.Unlock()
+
+	srv.logger.Info(
+		"started metrics endpoint",
+		zap.String("address", listener.Addr().String()),
+		zap.String("path", srv.config.Path),
+	)
+
+	go server.Serve(listener)
+	return nil
+}
+
+// Stop stops serving the metrics.
+func (srv *Server) Stop() error {
+	srv.mu.Lock()
+	server := srv.server
+	srv.server = nil
+	srv.listener = nil
+	srv.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Close()
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestServer(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("test_logins_total", "Number of logins.", "realm").Inc("local")
+
+	srv, err := NewServer(&Config{Address: "127.0.0.1:0"}, registry, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer srv.Stop()
+
+	resp, err := http.Get("http://" + srv.GetAddress() + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type: %s", got)
+	}
+	if !strings.Contains(string(body), `test_logins_total{realm="local"} 1`) {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	resp, err = http.Get("http://" + srv.GetAddress() + "/other")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+}
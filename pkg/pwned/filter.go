@@ -0,0 +1,150 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pwned
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"unicode/utf16"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/md4"
+)
+
+// filterMagic is the signature of bloom filter files.
+var filterMagic = [4]byte{'A', 'C', 'P', 'F'}
+
+// Filter is a bloom filter of NTLM hashes of compromised passwords.
+//
+// The file format is the 4-byte signature, the number of hash functions
+// as uint32, the number of bits as uint64, both big-endian, followed by
+// the bits of the filter.
+type Filter struct {
+	k    uint32
+	m    uint64
+	bits []byte
+}
+
+// NewFilter returns an empty Filter sized for n hashes at the provided
+// false positive rate.
+func NewFilter(n int, rate float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if rate <= 0 || rate >= 1 {
+		rate = 0.001
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{
+		k:    k,
+		m:    m,
+		bits: make([]byte, (m+7)/8),
+	}
+}
+
+// LoadFilter reads Filter from the file at the provided path.
+func LoadFilter(fp string) (*Filter, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFilter(bufio.NewReader(f))
+}
+
+// ReadFilter reads Filter from the reader.
+func ReadFilter(r io.Reader) (*Filter, error) {
+	var header struct {
+		Magic [4]byte
+		K     uint32
+		M     uint64
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, errors.ErrPwnedFilterMalformed
+	}
+	if header.Magic != filterMagic || header.K == 0 || header.M == 0 {
+		return nil, errors.ErrPwnedFilterMalformed
+	}
+	f := &Filter{
+		k:    header.K,
+		m:    header.M,
+		bits: make([]byte, (header.M+7)/8),
+	}
+	if _, err := io.ReadFull(r, f.bits); err != nil {
+		return nil, errors.ErrPwnedFilterMalformed
+	}
+	return f, nil
+}
+
+// WriteTo writes Filter to the writer.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	if _, err := w.Write(filterMagic[:]); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(w, binary.BigEndian, f.k); err != nil {
+		return 4, err
+	}
+	if err := binary.Write(w, binary.BigEndian, f.m); err != nil {
+		return 8, err
+	}
+	n, err := w.Write(f.bits)
+	return int64(16 + n), err
+}
+
+// Add adds the NTLM hash to Filter.
+func (f *Filter) Add(hash []byte) {
+	h1, h2 := f.split(hash)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+// Contains returns true when the NTLM hash is possibly in Filter.
+func (f *Filter) Contains(hash []byte) bool {
+	h1, h2 := f.split(hash)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// split derives two hash values from the NTLM hash. The MD4 digest is
+// uniformly distributed, therefore its halves are used directly.
+func (f *Filter) split(hash []byte) (uint64, uint64) {
+	var b [16]byte
+	copy(b[:], hash)
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:]) | 1
+}
+
+// NTLMHash returns the NTLM hash, i.e. MD4 digest of UTF-16LE encoding, of
+// the password.
+func NTLMHash(password string) []byte {
+	h := md4.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c), byte(c >> 8)})
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/tracing"
+)
+
+const (
+	// ActionReject rejects compromised passwords.
+	ActionReject = "reject"
+	// ActionWarn accepts compromised passwords and warns the user.
+	ActionWarn = "warn"
+
+	defaultURL       = "https://api.pwnedpasswords.com/range/"
+	defaultThreshold = 1
+	defaultTimeout   = 5
+)
+
+// Config holds the configuration of the compromised password check.
+type Config struct {
+	// The action taken when a password is compromised, i.e. reject or warn.
+	// Defaults to reject.
+	Action string `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+	// The URL of the k-anonymity range API. Defaults to the Have I Been
+	// Pwned Passwords API.
+	URL string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	// The path to the bloom filter file with NTLM hashes of compromised
+	// passwords. When set, the range API is not used.
+	FilterPath string `json:"filter_path,omitempty" xml:"filter_path,omitempty" yaml:"filter_path,omitempty"`
+	// The number of times a password must appear in the breaches to be
+	// considered compromised. Not applicable to the bloom filter.
+	Threshold int `json:"threshold,omitempty" xml:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// The timeout in seconds of the range API requests.
+	Timeout int `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Checker checks whether passwords appeared in data breaches.
+type Checker struct {
+	config    *Config
+	client    *http.Client
+	url       string
+	threshold int
+	filter    *Filter
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	switch cfg.Action {
+	case "", ActionReject, ActionWarn:
+	default:
+		return errors.ErrPwnedConfigActionInvalid.WithArgs(cfg.Action)
+	}
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.ErrPwnedConfigURLInvalid.WithArgs(cfg.URL)
+		}
+	}
+	if cfg.Threshold < 0 {
+		return errors.ErrPwnedConfigThresholdInvalid.WithArgs(cfg.Threshold)
+	}
+	if cfg.Timeout < 0 {
+		return errors.ErrPwnedConfigTimeoutInvalid.WithArgs(cfg.Timeout)
+	}
+	return nil
+}
+
+// NewChecker returns an instance of Checker.
+func NewChecker(cfg *Config) (*Checker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Checker{
+		config:    cfg,
+		url:       defaultURL,
+		threshold: defaultThreshold,
+	}
+	if cfg.URL != "" {
+		c.url = cfg.URL
+		if !strings.HasSuffix(c.url, "/") {
+			c.url += "/"
+		}
+	}
+	if cfg.Threshold > 0 {
+		c.threshold = cfg.Threshold
+	}
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	c.client = &http.Client{
+		Timeout:   time.Duration(timeout) * time.Second,
+		Transport: tracing.NewTransport(nil),
+	}
+	if cfg.FilterPath != "" {
+		filter, err := LoadFilter(cfg.FilterPath)
+		if err != nil {
+			return nil, errors.ErrPwnedFilterLoad.WithArgs(cfg.FilterPath, err)
+		}
+		c.filter = filter
+	}
+	return c, nil
+}
+
+// GetAction returns the action taken when a password is compromised.
+func (c *Checker) GetAction() string {
+	if c.config.Action == "" {
+		return ActionReject
+	}
+	return c.config.Action
+}
+
+// Check returns true when the password appeared in data breaches. Only
+// the first five characters of the SHA-1 hash of the password are sent to
+// the range API.
+func (c *Checker) Check(ctx context.Context, password string) (bool, error) {
+	if c.filter != nil {
+		return c.filter.Contains(NTLMHash(password)), nil
+	}
+	h := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(h[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+prefix, nil)
+	if err != nil {
+		return false, errors.ErrPwnedRangeRequest.WithArgs(err)
+	}
+	// The padding hides the number of the suffixes in the response.
+	req.Header.Set("Add-Padding", "true")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, errors.ErrPwnedRangeRequest.WithArgs(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.ErrPwnedRangeStatus.WithArgs(resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		arr := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(arr) != 2 || !strings.EqualFold(arr[0], suffix) {
+			continue
+		}
+		count, err := strconv.Atoi(arr[1])
+		if err != nil {
+			return false, errors.ErrPwnedRangeRequest.WithArgs(err)
+		}
+		return count >= c.threshold, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, errors.ErrPwnedRangeRequest.WithArgs(err)
+	}
+	return false, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid default config",
+			config: &Config{},
+		},
+		{
+			name:   "valid config with warn action",
+			config: &Config{Action: "warn", URL: "https://localhost/range", Threshold: 10},
+		},
+		{
+			name:      "config with invalid action",
+			config:    &Config{Action: "foo"},
+			shouldErr: true,
+			err:       errors.ErrPwnedConfigActionInvalid.WithArgs("foo"),
+		},
+		{
+			name:      "config with invalid url",
+			config:    &Config{URL: "ftp://localhost"},
+			shouldErr: true,
+			err:       errors.ErrPwnedConfigURLInvalid.WithArgs("ftp://localhost"),
+		},
+		{
+			name:      "config with negative threshold",
+			config:    &Config{Threshold: -1},
+			shouldErr: true,
+			err:       errors.ErrPwnedConfigThresholdInvalid.WithArgs(-1),
+		},
+		{
+			name:      "config with negative timeout",
+			config:    &Config{Timeout: -1},
+			shouldErr: true,
+			err:       errors.ErrPwnedConfigTimeoutInvalid.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, nil)
+		})
+	}
+}
+
+func TestCheckRange(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Add-Padding") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/range/5BAA6":
+			// The SHA-1 hash of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+			fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n")
+		case "/range/A94A8":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n")
+		}
+	}))
+	defer srv.Close()
+
+	testcases := []struct {
+		name      string
+		config    *Config
+		password  string
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "compromised password",
+			config:   &Config{URL: srv.URL + "/range"},
+			password: "password",
+			want:     true,
+		},
+		{
+			name:     "compromised password below threshold",
+			config:   &Config{URL: srv.URL + "/range/", Threshold: 10000000},
+			password: "password",
+		},
+		{
+			name:     "unknown password",
+			config:   &Config{URL: srv.URL + "/range"},
+			password: "correct horse battery staple",
+		},
+		{
+			name:      "throttled request",
+			config:    &Config{URL: srv.URL + "/range"},
+			password:  "test",
+			shouldErr: true,
+			err:       errors.ErrPwnedRangeStatus.WithArgs(http.StatusTooManyRequests),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewChecker(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := c.Check(context.Background(), tc.password)
+			if tests.EvalErrWithLog(t, err, "check", tc.shouldErr, tc.err, nil) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "compromised", tc.want, got, nil)
+		})
+	}
+
+	for _, p := range paths {
+		if len(p) != len("/range/")+5 {
+			t.Fatalf("unexpected range request path: %s", p)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests.EvalObjects(t, "ntlm hash", "8846f7eaee8fb117ad06bdd830b7586c", hex.EncodeToString(NTLMHash("password")))
+
+	f := NewFilter(1000, 0.001)
+	for i := 0; i < 1000; i++ {
+		f.Add(NTLMHash(fmt.Sprintf("password%d", i)))
+	}
+	f.Add(NTLMHash("password"))
+
+	var b bytes.Buffer
+	if _, err := f.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "pwned.bf")
+	if err := ioutil.WriteFile(fp, b.Bytes(), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := NewChecker(&Config{FilterPath: fp, Action: "warn"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "action", "warn", c.GetAction())
+	for _, tc := range []struct {
+		password string
+		want     bool
+	}{
+		{password: "password", want: true},
+		{password: "password500", want: true},
+		{password: "correct horse battery staple", want: false},
+	} {
+		got, err := c.Check(context.Background(), tc.password)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tests.EvalObjects(t, tc.password, tc.want, got)
+	}
+
+	if err := ioutil.WriteFile(fp, []byte("foobar"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = NewChecker(&Config{FilterPath: fp})
+	tests.EvalErrWithLog(t, err, "malformed filter", true, errors.ErrPwnedFilterLoad.WithArgs(fp, errors.ErrPwnedFilterMalformed), nil)
+}
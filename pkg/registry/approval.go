@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+// RegistrationRecord is the summary of a registration awaiting or having
+// received an administrator's verdict.
+type RegistrationRecord struct {
+	ID         string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Username   string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email      string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Roles      []string  `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Status     string    `json:"status,omitempty" xml:"status,omitempty" yaml:"status,omitempty"`
+	Comment    string    `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	ReviewedBy string    `json:"reviewed_by,omitempty" xml:"reviewed_by,omitempty" yaml:"reviewed_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty" xml:"reviewed_at,omitempty" yaml:"reviewed_at,omitempty"`
+}
+
+func newRegistrationRecord(user *identity.User) *RegistrationRecord {
+	rec := &RegistrationRecord{
+		ID:         user.Registration.ID,
+		Username:   user.Username,
+		Status:     "pending",
+		Comment:    user.Registration.Comment,
+		ReviewedBy: user.Registration.ReviewedBy,
+		CreatedAt:  user.Registration.CreatedAt,
+	}
+	if user.EmailAddress != nil {
+		rec.Email = user.EmailAddress.Address
+	}
+	for _, role := range user.Roles {
+		rec.Roles = append(rec.Roles, role.String())
+	}
+	switch {
+	case user.Registration.Approved:
+		rec.Status = "approved"
+		rec.ReviewedAt = user.Registration.ApprovedAt
+	case user.Registration.Declined:
+		rec.Status = "declined"
+		rec.ReviewedAt = user.Registration.DeclinedAt
+	}
+	return rec
+}
+
+// GetRegistrations returns the registrations held in the dropbox.
+func (r *LocaUserRegistry) GetRegistrations() ([]*RegistrationRecord, error) {
+	users, err := r.db.GetRegistrations()
+	if err != nil {
+		return nil, err
+	}
+	records := []*RegistrationRecord{}
+	for _, user := range users {
+		records = append(records, newRegistrationRecord(user))
+	}
+	return records, nil
+}
+
+// ApproveRegistration approves the registration with the provided id. The
+// user receives the approval roles and is passed to the importer, which adds
+// the user to the identity store associated with the registry.
+func (r *LocaUserRegistry) ApproveRegistration(id, comment, reviewer string, importer func(*identity.User) error) (*RegistrationRecord, error) {
+	user, err := r.db.GetUserByRegistrationID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Registration.Reviewed() {
+		return nil, errors.ErrRegistrationReviewed.WithArgs(id)
+	}
+	for _, role := range expandApprovalRoles(r.config.ApprovalRoles, user) {
+		if err := user.AddRole(role); err != nil {
+			return nil, errors.ErrUserRegistryApprovalRole.WithArgs(role, err)
+		}
+	}
+	if importer != nil {
+		if err := importer(user); err != nil {
+			return nil, errors.ErrUserRegistryApprovalImport.WithArgs(id, err)
+		}
+	}
+	reviewed, err := r.db.SetRegistrationVerdict(id, true, comment, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	reviewed.Roles = user.Roles
+	rec := newRegistrationRecord(reviewed)
+	r.publishEvent(events.RegistrationApproved, rec)
+	return rec, nil
+}
+
+// DeclineRegistration declines the registration with the provided id.
+func (r *LocaUserRegistry) DeclineRegistration(id, comment, reviewer string) (*RegistrationRecord, error) {
+	user, err := r.db.SetRegistrationVerdict(id, false, comment, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	rec := newRegistrationRecord(user)
+	r.publishEvent("registration.declined", rec)
+	return rec, nil
+}
+
+// PurgeRegistrations removes the registrations created before the cutoff
+// that were not approved. In the dry run, the registrations are reported,
+// but not removed.
+func (r *LocaUserRegistry) PurgeRegistrations(cutoff time.Time, dryRun bool) ([]*RegistrationRecord, error) {
+	req := &requests.Request{}
+	req.Lifecycle.Cutoff = cutoff
+	req.Lifecycle.DryRun = dryRun
+	if err := r.db.PurgeRegistrations(req); err != nil {
+		return nil, err
+	}
+	records := []*RegistrationRecord{}
+	for _, user := range req.Response.Payload.([]*identity.User) {
+		records = append(records, newRegistrationRecord(user))
+	}
+	return records, nil
+}
+
+func expandApprovalRoles(roles []string, user *identity.User) []string {
+	var email, domain string
+	if user.EmailAddress != nil {
+		email = user.EmailAddress.Address
+		if i := strings.LastIndex(email, "@"); i > 0 {
+			domain = email[i+1:]
+		}
+	}
+	repl := strings.NewReplacer(
+		"{username}", user.Username,
+		"{email}", email,
+		"{email_domain}", domain,
+	)
+	var expanded []string
+	for _, role := range roles {
+		expanded = append(expanded, repl.Replace(role))
+	}
+	return expanded
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestRegistrationApproval(t *testing.T) {
+	cfg := &UserRegistryConfig{
+		Name:          "default",
+		Dropbox:       filepath.Join(t.TempDir(), "registrations.json"),
+		EmailProvider: "default",
+		AdminEmails:   []string{"admin@localhost"},
+		IdentityStore: "localdb",
+		ApprovalRoles: []string{"authp/viewer", "org/{email_domain}"},
+	}
+	r, err := NewUserRegistry(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, entry := range []struct {
+		id       string
+		username string
+		email    string
+	}{
+		{id: "reg-1", username: "jsmith", email: "jsmith@contoso.com"},
+		{id: "reg-2", username: "bjones", email: "bjones@contoso.com"},
+	} {
+		req := &requests.Request{
+			User: requests.User{
+				Username: entry.username,
+				Password: "My@Secret123Password",
+				Email:    entry.email,
+				Roles:    []string{"authp/user"},
+			},
+			Query: requests.Query{ID: entry.id},
+		}
+		if err := r.AddUser(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := r.GetRegistrations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "registration count", 2, len(entries))
+	tests.EvalObjects(t, "registration status", "pending", entries[0].Status)
+
+	var imported *identity.User
+	entry, err := r.ApproveRegistration("reg-1", "welcome", "admin@localhost", func(u *identity.User) error {
+		imported = u
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "approved status", "approved", entry.Status)
+	tests.EvalObjects(t, "approved comment", "welcome", entry.Comment)
+	tests.EvalObjects(t, "approved reviewer", "admin@localhost", entry.ReviewedBy)
+	tests.EvalObjects(t, "approved roles", []string{"authp/user", "authp/viewer", "org/contoso.com"}, entry.Roles)
+	if imported == nil || !imported.HasRole("org/contoso.com") {
+		t.Fatalf("expected imported user with approval roles, got: %v", imported)
+	}
+	if err := imported.VerifyPassword("My@Secret123Password"); err != nil {
+		t.Fatalf("expected imported user to keep password: %v", err)
+	}
+
+	_, err = r.ApproveRegistration("reg-1", "", "admin@localhost", nil)
+	tests.EvalErrWithLog(t, err, "approve reviewed registration", true, errors.ErrRegistrationReviewed.WithArgs("reg-1"), nil)
+
+	entry, err = r.DeclineRegistration("reg-2", "unknown user", "admin@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "declined status", "declined", entry.Status)
+
+	_, err = r.DeclineRegistration("reg-3", "", "admin@localhost")
+	tests.EvalErrWithLog(t, err, "decline unknown registration", true, errors.ErrRegistrationNotFound.WithArgs("reg-3"), nil)
+}
+
+func TestRegistrationWebhook(t *testing.T) {
+	var payload webhookPayload
+	var signature string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		signature = req.Header.Get(webhookSignatureHeader)
+		body, _ = ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &payload)
+	}))
+	defer srv.Close()
+
+	r := &LocaUserRegistry{
+		config: &UserRegistryConfig{
+			Webhooks:      []string{srv.URL},
+			WebhookSecret: "foobar",
+		},
+	}
+	errs := r.sendWebhooks("registration.approved", &RegistrationRecord{ID: "reg-1", Status: "approved"})
+	tests.EvalObjects(t, "webhook errors", 0, len(errs))
+	tests.EvalObjects(t, "webhook event", "registration.approved", payload.Event)
+	tests.EvalObjects(t, "webhook registration id", "reg-1", payload.Registration.ID)
+	tests.EvalObjects(t, "webhook signature", signWebhookPayload("foobar", body), signature)
+
+	r.config.Webhooks = []string{srv.URL + "/missing", "http://127.0.0.1:1/hook"}
+	srv.Config.Handler = http.NotFoundHandler()
+	errs = r.sendWebhooks("registration.declined", &RegistrationRecord{ID: "reg-1"})
+	tests.EvalObjects(t, "webhook delivery errors", 2, len(errs))
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// checkEmailDomain checks the domain of an email address against the
+// allow and deny lists. A domain in the lists matches itself and its
+// subdomains. The deny list takes precedence.
+func checkEmailDomain(email string, allowed, denied []string) error {
+	i := strings.LastIndex(email, "@")
+	if i < 1 || i == len(email)-1 {
+		return errors.ErrUserRegistryEmailAddressMalformed.WithArgs(email)
+	}
+	domain := strings.ToLower(email[i+1:])
+	for _, d := range denied {
+		if matchEmailDomain(domain, d) {
+			return errors.ErrUserRegistryEmailDomainDenied.WithArgs(domain)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, d := range allowed {
+		if matchEmailDomain(domain, d) {
+			return nil
+		}
+	}
+	return errors.ErrUserRegistryEmailDomainDenied.WithArgs(domain)
+}
+
+func matchEmailDomain(domain, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(pattern, "*."))
+	return domain == pattern || strings.HasSuffix(domain, "."+pattern)
+}
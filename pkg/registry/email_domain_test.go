@@ -0,0 +1,85 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestCheckEmailDomain(t *testing.T) {
+	testcases := []struct {
+		name      string
+		email     string
+		allowed   []string
+		denied    []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "no lists",
+			email: "jsmith@contoso.com",
+		},
+		{
+			name:    "allowed domain",
+			email:   "jsmith@contoso.com",
+			allowed: []string{"contoso.com"},
+		},
+		{
+			name:    "allowed subdomain",
+			email:   "jsmith@eu.Contoso.com",
+			allowed: []string{"*.contoso.com"},
+		},
+		{
+			name:      "domain not in allow list",
+			email:     "jsmith@example.com",
+			allowed:   []string{"contoso.com"},
+			shouldErr: true,
+			err:       errors.ErrUserRegistryEmailDomainDenied.WithArgs("example.com"),
+		},
+		{
+			name:      "denied domain takes precedence",
+			email:     "jsmith@mailinator.contoso.com",
+			allowed:   []string{"contoso.com"},
+			denied:    []string{"mailinator.contoso.com"},
+			shouldErr: true,
+			err:       errors.ErrUserRegistryEmailDomainDenied.WithArgs("mailinator.contoso.com"),
+		},
+		{
+			name:      "lookalike domain is not a subdomain",
+			email:     "jsmith@notcontoso.com",
+			allowed:   []string{"contoso.com"},
+			shouldErr: true,
+			err:       errors.ErrUserRegistryEmailDomainDenied.WithArgs("notcontoso.com"),
+		},
+		{
+			name:      "malformed email address",
+			email:     "jsmith",
+			shouldErr: true,
+			err:       errors.ErrUserRegistryEmailAddressMalformed.WithArgs("jsmith"),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			err := checkEmailDomain(tc.email, tc.allowed, tc.denied)
+			tests.EvalErrWithLog(t, err, "email domain", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
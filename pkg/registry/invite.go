@@ -0,0 +1,199 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Invite is an invitation code allowing a limited number of registrations.
+type Invite struct {
+	Code      string    `json:"code,omitempty" xml:"code,omitempty" yaml:"code,omitempty"`
+	MaxUses   int       `json:"max_uses,omitempty" xml:"max_uses,omitempty" yaml:"max_uses,omitempty"`
+	Uses      int       `json:"uses,omitempty" xml:"uses,omitempty" yaml:"uses,omitempty"`
+	Roles     []string  `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty" xml:"created_by,omitempty" yaml:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// InviteStore holds the invitation codes. The codes are persisted in a
+// file, so that they survive restarts.
+type InviteStore struct {
+	mu      sync.Mutex
+	path    string
+	invites map[string]*Invite
+}
+
+// NewInviteStore returns an instance of InviteStore backed by the file.
+func NewInviteStore(fp string) (*InviteStore, error) {
+	s := &InviteStore{
+		path:    fp,
+		invites: make(map[string]*Invite),
+	}
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.ErrUserRegistryInviteStoreLoad.WithArgs(fp, err)
+	}
+	var invites []*Invite
+	if err := json.Unmarshal(b, &invites); err != nil {
+		return nil, errors.ErrUserRegistryInviteStoreLoad.WithArgs(fp, err)
+	}
+	for _, invite := range invites {
+		s.invites[invite.Code] = invite
+	}
+	return s, nil
+}
+
+// Create creates an invitation code usable up to the provided number of
+// times, assigning the roles to the registered users. Zero lifetime means
+// the code does not expire.
+func (s *InviteStore) Create(maxUses int, roles []string, lifetime time.Duration, createdBy string) (*Invite, error) {
+	if maxUses < 1 {
+		return nil, errors.ErrUserRegistryInviteMaxUses.WithArgs(maxUses)
+	}
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.ErrUserRegistryInviteCodeGenerate.WithArgs(err)
+	}
+	invite := &Invite{
+		Code:      base32.StdEncoding.EncodeToString(b),
+		MaxUses:   maxUses,
+		Roles:     roles,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if lifetime > 0 {
+		invite.ExpiresAt = invite.CreatedAt.Add(lifetime)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invites[invite.Code] = invite
+	if err := s.save(); err != nil {
+		delete(s.invites, invite.Code)
+		return nil, err
+	}
+	return invite, nil
+}
+
+// List returns the invitation codes sorted by creation time.
+func (s *InviteStore) List() []*Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var invites []*Invite
+	for _, invite := range s.invites {
+		entry := *invite
+		invites = append(invites, &entry)
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites
+}
+
+// Delete revokes the invitation code.
+func (s *InviteStore) Delete(code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invite, exists := s.invites[normalizeInviteCode(code)]
+	if !exists {
+		return errors.ErrUserRegistryInviteNotFound
+	}
+	delete(s.invites, invite.Code)
+	if err := s.save(); err != nil {
+		s.invites[invite.Code] = invite
+		return err
+	}
+	return nil
+}
+
+// Check returns the invitation code when it is usable.
+func (s *InviteStore) Check(code string) (*Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invite, err := s.check(code)
+	if err != nil {
+		return nil, err
+	}
+	entry := *invite
+	return &entry, nil
+}
+
+// Redeem uses the invitation code once.
+func (s *InviteStore) Redeem(code string) (*Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invite, err := s.check(code)
+	if err != nil {
+		return nil, err
+	}
+	invite.Uses++
+	if err := s.save(); err != nil {
+		invite.Uses--
+		return nil, err
+	}
+	entry := *invite
+	return &entry, nil
+}
+
+func (s *InviteStore) check(code string) (*Invite, error) {
+	invite, exists := s.invites[normalizeInviteCode(code)]
+	if !exists {
+		return nil, errors.ErrUserRegistryInviteNotFound
+	}
+	if !invite.ExpiresAt.IsZero() && time.Now().After(invite.ExpiresAt) {
+		return nil, errors.ErrUserRegistryInviteExpired
+	}
+	if invite.Uses >= invite.MaxUses {
+		return nil, errors.ErrUserRegistryInviteExhausted
+	}
+	return invite, nil
+}
+
+func (s *InviteStore) save() error {
+	var invites []*Invite
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	b, err := json.MarshalIndent(invites, "", "  ")
+	if err != nil {
+		return errors.ErrUserRegistryInviteStoreSave.WithArgs(s.path, err)
+	}
+	if err := ioutil.WriteFile(s.path, b, 0600); err != nil {
+		return errors.ErrUserRegistryInviteStoreSave.WithArgs(s.path, err)
+	}
+	return nil
+}
+
+func normalizeInviteCode(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
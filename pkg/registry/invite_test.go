@@ -0,0 +1,75 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestInviteStore(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "invites.json")
+	store, err := NewInviteStore(fp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = store.Create(0, nil, 0, "root@localhost")
+	tests.EvalErrWithLog(t, err, "zero max uses", true, errors.ErrUserRegistryInviteMaxUses.WithArgs(0), nil)
+
+	invite, err := store.Create(2, []string{"authp/editor"}, 0, "root@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expired, err := store.Create(1, nil, time.Nanosecond, "root@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// The codes survive restarts.
+	store, err = NewInviteStore(fp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "invite count", 2, len(store.List()))
+
+	got, err := store.Redeem(invite.Code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "invite roles", []string{"authp/editor"}, got.Roles)
+	if _, err := store.Check(invite.Code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Redeem(invite.Code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = store.Redeem(invite.Code)
+	tests.EvalErrWithLog(t, err, "exhausted invite", true, errors.ErrUserRegistryInviteExhausted, nil)
+
+	_, err = store.Check(expired.Code)
+	tests.EvalErrWithLog(t, err, "expired invite", true, errors.ErrUserRegistryInviteExpired, nil)
+
+	if err := store.Delete(expired.Code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = store.Check(expired.Code)
+	tests.EvalErrWithLog(t, err, "deleted invite", true, errors.ErrUserRegistryInviteNotFound, nil)
+}
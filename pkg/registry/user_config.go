@@ -15,9 +15,11 @@
 package registry
 
 import (
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
+	"strings"
 )
 
 // UserRegistryConfig represents a common set of configuration settings for user registration
@@ -47,6 +49,27 @@ type UserRegistryConfig struct {
 	AdminEmails []string `json:"admin_emails,omitempty" xml:"admin_emails,omitempty" yaml:"admin_emails,omitempty"`
 	// The name of the identity store associated with the Config.
 	IdentityStore string `json:"identity_store,omitempty" xml:"identity_store,omitempty" yaml:"identity_store,omitempty"`
+	// The switch determining whether a user must provide an invitation code.
+	RequireInvite bool `json:"require_invite,omitempty" xml:"require_invite,omitempty" yaml:"require_invite,omitempty"`
+	// The file path to invitation code database. Defaults to the dropbox
+	// path with _invites.json suffix.
+	InviteDropbox string `json:"invite_dropbox,omitempty" xml:"invite_dropbox,omitempty" yaml:"invite_dropbox,omitempty"`
+	// The email domains allowed to register. When empty, all domains are
+	// allowed. A domain matches its subdomains.
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty" xml:"allowed_email_domains,omitempty" yaml:"allowed_email_domains,omitempty"`
+	// The email domains denied from registering. The denied domains take
+	// precedence over the allowed ones.
+	DeniedEmailDomains []string `json:"denied_email_domains,omitempty" xml:"denied_email_domains,omitempty" yaml:"denied_email_domains,omitempty"`
+	// The roles assigned to a user when an administrator approves the
+	// registration. The roles may reference {username}, {email}, and
+	// {email_domain} placeholders.
+	ApprovalRoles []string `json:"approval_roles,omitempty" xml:"approval_roles,omitempty" yaml:"approval_roles,omitempty"`
+	// The URLs receiving registration state change notifications.
+	Webhooks []string `json:"webhooks,omitempty" xml:"webhooks,omitempty" yaml:"webhooks,omitempty"`
+	// The secret used to sign webhook payloads. It may reference a Vault secret.
+	WebhookSecret string `json:"webhook_secret,omitempty" xml:"webhook_secret,omitempty" yaml:"webhook_secret,omitempty"`
+	// The CAPTCHA or proof-of-work challenge protecting the registration form.
+	Captcha *captcha.Config `json:"captcha,omitempty" xml:"captcha,omitempty" yaml:"captcha,omitempty"`
 
 	credentials *credentials.Config `json:"credentials,omitempty" xml:"credentials,omitempty" yaml:"credentials,omitempty"`
 	messaging   *messaging.Config   `json:"messaging,omitempty" xml:"messaging,omitempty" yaml:"messaging,omitempty"`
@@ -72,6 +95,16 @@ func (cfg *UserRegistryConfig) Validate() error {
 	if cfg.IdentityStore == "" {
 		return errors.ErrUserRegistrationConfig.WithArgs(cfg.Name, "identity store name is not set")
 	}
+	for _, u := range cfg.Webhooks {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return errors.ErrUserRegistrationConfig.WithArgs(cfg.Name, errors.ErrUserRegistryWebhookURL.WithArgs(u))
+		}
+	}
+	if cfg.Captcha != nil {
+		if err := cfg.Captcha.Validate(); err != nil {
+			return errors.ErrUserRegistrationConfig.WithArgs(cfg.Name, err)
+		}
+	}
 	return nil
 }
 
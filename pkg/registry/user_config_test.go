@@ -87,6 +87,19 @@ func TestValidateUserRegistryConfig(t *testing.T) {
 				IdentityStore:       "foo",
 			},
 		},
+		{
+			name: "test user registration config with invalid webhook url",
+			config: &UserRegistryConfig{
+				Name:          "default",
+				Dropbox:       "foo",
+				EmailProvider: "bar",
+				AdminEmails:   []string{"root@localhost"},
+				IdentityStore: "foo",
+				Webhooks:      []string{"ftp://localhost/hook"},
+			},
+			shouldErr: true,
+			err:       errors.ErrUserRegistrationConfig.WithArgs("default", errors.ErrUserRegistryWebhookURL.WithArgs("ftp://localhost/hook")),
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
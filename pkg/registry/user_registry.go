@@ -16,18 +16,24 @@ package registry
 
 import (
 	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/captcha"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // LocaUserRegistry is a local registry.
 type LocaUserRegistry struct {
-	db     *identity.Database
-	config *UserRegistryConfig
-	cache  *RegistrationCache
-	logger *zap.Logger
+	db      *identity.Database
+	config  *UserRegistryConfig
+	cache   *RegistrationCache
+	captcha *captcha.Verifier
+	invites *InviteStore
+	logger  *zap.Logger
 }
 
 // UserRegistry represents user registry.
@@ -59,6 +65,20 @@ type UserRegistry interface {
 
 	Notify(map[string]string) error
 	GetIdentityStoreName() string
+	GetCaptcha() *captcha.Verifier
+
+	GetRequireInvite() bool
+	CheckInvite(string) (*Invite, error)
+	RedeemInvite(string) (*Invite, error)
+	CreateInvite(int, []string, time.Duration, string) (*Invite, error)
+	GetInvites() []*Invite
+	DeleteInvite(string) error
+	CheckEmailDomain(string) error
+
+	GetRegistrations() ([]*RegistrationRecord, error)
+	ApproveRegistration(string, string, string, func(*identity.User) error) (*RegistrationRecord, error)
+	DeclineRegistration(string, string, string) (*RegistrationRecord, error)
+	PurgeRegistrations(time.Time, bool) ([]*RegistrationRecord, error)
 }
 
 // NewUserRegistry returns UserRegistry instance.
@@ -86,6 +106,22 @@ func NewUserRegistry(cfg *UserRegistryConfig, logger *zap.Logger) (UserRegistry,
 		cache:  NewRegistrationCache(),
 	}
 
+	inviteDropbox := cfg.InviteDropbox
+	if inviteDropbox == "" {
+		inviteDropbox = strings.TrimSuffix(cfg.Dropbox, filepath.Ext(cfg.Dropbox)) + "_invites.json"
+	}
+	localRegistry.invites, err = NewInviteStore(inviteDropbox)
+	if err != nil {
+		return nil, errors.ErrUserRegistrationConfig.WithArgs(cfg.Name, err)
+	}
+
+	if cfg.Captcha != nil {
+		localRegistry.captcha, err = captcha.NewVerifier(cfg.Captcha)
+		if err != nil {
+			return nil, errors.ErrUserRegistrationConfig.WithArgs(cfg.Name, err)
+		}
+	}
+
 	localRegistry.cache.Run()
 
 	r = localRegistry
@@ -107,7 +143,15 @@ func (r *LocaUserRegistry) GetName() string {
 
 // AddUser adds user to the user registry.
 func (r *LocaUserRegistry) AddUser(rr *requests.Request) error {
-	return r.db.AddUser(rr)
+	if err := r.db.AddUser(rr); err != nil {
+		return err
+	}
+	if rr.Query.ID != "" {
+		if user, err := r.db.GetUserByRegistrationID(rr.Query.ID); err == nil {
+			r.publishEvent("registration.pending", newRegistrationRecord(user))
+		}
+	}
+	return nil
 }
 
 // GetRegistrationEntry returns a registration entry by id.
@@ -189,3 +233,45 @@ func (r *LocaUserRegistry) GetRequireDomainMailRecord() bool {
 func (r *LocaUserRegistry) GetIdentityStoreName() string {
 	return r.config.IdentityStore
 }
+
+// GetCaptcha returns the CAPTCHA verifier of the registration form. It is nil
+// when the CAPTCHA is not configured.
+func (r *LocaUserRegistry) GetCaptcha() *captcha.Verifier {
+	return r.captcha
+}
+
+// GetRequireInvite returns true if registration requires an invitation code.
+func (r *LocaUserRegistry) GetRequireInvite() bool {
+	return r.config.RequireInvite
+}
+
+// CheckInvite returns the invitation code when it is usable.
+func (r *LocaUserRegistry) CheckInvite(code string) (*Invite, error) {
+	return r.invites.Check(code)
+}
+
+// RedeemInvite uses the invitation code once.
+func (r *LocaUserRegistry) RedeemInvite(code string) (*Invite, error) {
+	return r.invites.Redeem(code)
+}
+
+// CreateInvite creates an invitation code.
+func (r *LocaUserRegistry) CreateInvite(maxUses int, roles []string, lifetime time.Duration, createdBy string) (*Invite, error) {
+	return r.invites.Create(maxUses, roles, lifetime, createdBy)
+}
+
+// GetInvites returns the invitation codes.
+func (r *LocaUserRegistry) GetInvites() []*Invite {
+	return r.invites.List()
+}
+
+// DeleteInvite revokes the invitation code.
+func (r *LocaUserRegistry) DeleteInvite(code string) error {
+	return r.invites.Delete(code)
+}
+
+// CheckEmailDomain checks whether the domain of the email address is allowed
+// to register.
+func (r *LocaUserRegistry) CheckEmailDomain(email string) error {
+	return checkEmailDomain(email, r.config.AllowedEmailDomains, r.config.DeniedEmailDomains)
+}
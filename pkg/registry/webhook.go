@@ -0,0 +1,121 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookSignatureHeader = "X-Authp-Signature"
+	webhookTimeout         = 10 * time.Second
+)
+
+type webhookPayload struct {
+	Event        string              `json:"event"`
+	Registration *RegistrationRecord `json:"registration"`
+	Timestamp    time.Time           `json:"timestamp"`
+}
+
+// publishEvent publishes a registration state change to the event bus and
+// notifies the configured webhooks. The webhook delivery happens in the
+// background.
+func (r *LocaUserRegistry) publishEvent(event string, rec *RegistrationRecord) {
+	events.Publish(&events.Event{
+		Type:     event,
+		Username: rec.Username,
+		Email:    rec.Email,
+		Data: map[string]interface{}{
+			"registration_id": rec.ID,
+			"status":          rec.Status,
+			"reviewed_by":     rec.ReviewedBy,
+			"user_registry":   r.config.Name,
+		},
+	})
+	if len(r.config.Webhooks) == 0 {
+		return
+	}
+	go func() {
+		for _, err := range r.sendWebhooks(event, rec) {
+			r.logger.Warn(
+				"Failed to deliver registration webhook",
+				zap.String("event", event),
+				zap.String("registration_id", rec.ID),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+func (r *LocaUserRegistry) sendWebhooks(event string, rec *RegistrationRecord) []error {
+	var errs []error
+	body, err := json.Marshal(&webhookPayload{
+		Event:        event,
+		Registration: rec,
+		Timestamp:    time.Now().UTC(),
+	})
+	if err != nil {
+		return append(errs, err)
+	}
+
+	var signature string
+	if r.config.WebhookSecret != "" {
+		secret, err := credentials.ResolveSecret(r.config.WebhookSecret)
+		if err != nil {
+			return append(errs, errors.ErrUserRegistryWebhookSecret.WithArgs(err))
+		}
+		signature = signWebhookPayload(secret, body)
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for _, u := range r.config.Webhooks {
+		req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+		if err != nil {
+			errs = append(errs, errors.ErrUserRegistryWebhookDelivery.WithArgs(u, err))
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set(webhookSignatureHeader, signature)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, errors.ErrUserRegistryWebhookDelivery.WithArgs(u, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			errs = append(errs, errors.ErrUserRegistryWebhookStatus.WithArgs(u, resp.StatusCode))
+		}
+	}
+	return errs
+}
+
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -29,6 +29,20 @@ type AuthorizationResponse struct {
 	Authorized bool                   `json:"authorized" xml:"authorized" yaml:"authorized"`
 	Bypassed   bool                   `json:"bypassed,omitempty" xml:"bypassed,omitempty" yaml:"bypassed,omitempty"`
 	Error      error                  `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying the
+	// request shed by the admission control.
+	RetryAfter int `json:"-"`
+	// Denial holds the reason the request was denied access.
+	Denial *AuthorizationDenial `json:"denial,omitempty" xml:"denial,omitempty" yaml:"denial,omitempty"`
+}
+
+// AuthorizationDenial holds the machine-readable reason of an authorization
+// failure.
+type AuthorizationDenial struct {
+	Reason       string   `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	Rule         int      `json:"rule,omitempty" xml:"rule,omitempty" yaml:"rule,omitempty"`
+	Comment      string   `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	MissingRoles []string `json:"missing_roles,omitempty" xml:"missing_roles,omitempty" yaml:"missing_roles,omitempty"`
 }
 
 // AuthorizationToken holds the token found in an authorization request.
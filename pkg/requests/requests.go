@@ -15,23 +15,46 @@
 package requests
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 // Request hold the data associated with identity database
 type Request struct {
-	ID       string      `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
-	Upstream Upstream    `json:"upstream,omitempty" xml:"upstream,omitempty" yaml:"upstream,omitempty"`
-	Sandbox  Sandbox     `json:"sandbox,omitempty" xml:"sandbox,omitempty" yaml:"sandbox,omitempty"`
-	User     User        `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
-	Query    Query       `json:"query,omitempty" xml:"query,omitempty" yaml:"query,omitempty"`
-	Key      Key         `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
-	MfaToken MfaToken    `json:"mfa_token,omitempty" xml:"mfa_token,omitempty" yaml:"mfa_token,omitempty"`
+	ID       string   `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Upstream Upstream `json:"upstream,omitempty" xml:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Sandbox  Sandbox  `json:"sandbox,omitempty" xml:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	User     User     `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Query    Query    `json:"query,omitempty" xml:"query,omitempty" yaml:"query,omitempty"`
+	Key      Key      `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
+	MfaToken MfaToken `json:"mfa_token,omitempty" xml:"mfa_token,omitempty" yaml:"mfa_token,omitempty"`
+	Profile  Profile  `json:"profile,omitempty" xml:"profile,omitempty" yaml:"profile,omitempty"`
+	Consent  Consent  `json:"consent,omitempty" xml:"consent,omitempty" yaml:"consent,omitempty"`
+	// LinkedIdentity holds the identity at an identity provider linked to
+	// the local account of a user.
+	LinkedIdentity LinkedIdentity `json:"linked_identity,omitempty" xml:"linked_identity,omitempty" yaml:"linked_identity,omitempty"`
+	// Acceptance holds the document version accepted by a user.
+	Acceptance Acceptance `json:"acceptance,omitempty" xml:"acceptance,omitempty" yaml:"acceptance,omitempty"`
+	// LoginRecord holds the authentication attempt recorded in the login
+	// history of a user.
+	LoginRecord LoginRecord `json:"login_record,omitempty" xml:"login_record,omitempty" yaml:"login_record,omitempty"`
+	// Deletion holds the schedule of the deletion of a user account.
+	Deletion Deletion `json:"deletion,omitempty" xml:"deletion,omitempty" yaml:"deletion,omitempty"`
+	// RoleGrant holds the time-boxed assignment of a role to a user.
+	RoleGrant RoleGrant `json:"role_grant,omitempty" xml:"role_grant,omitempty" yaml:"role_grant,omitempty"`
+	// Lifecycle holds the parameters of a scheduled user lifecycle job.
+	Lifecycle Lifecycle `json:"lifecycle,omitempty" xml:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	// Group holds the attributes of a group of users.
+	Group    Group       `json:"group,omitempty" xml:"group,omitempty" yaml:"group,omitempty"`
 	WebAuthn WebAuthn    `json:"web_authn,omitempty" xml:"web_authn,omitempty" yaml:"web_authn,omitempty"`
 	Flags    Flags       `json:"flags,omitempty" xml:"flags,omitempty" yaml:"flags,omitempty"`
 	Response Response    `json:"response,omitempty" xml:"response,omitempty" yaml:"response,omitempty"`
 	Logger   *zap.Logger `json:"-"`
+	// Context carries the trace of the request to the identity stores and
+	// providers.
+	Context context.Context `json:"-"`
 }
 
 // Response hold the response associated with identity database
@@ -86,22 +109,126 @@ type Query struct {
 type User struct {
 	Username    string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
 	Email       string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	NewEmail    string   `json:"new_email,omitempty" xml:"new_email,omitempty" yaml:"new_email,omitempty"`
 	Password    string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
 	OldPassword string   `json:"old_password,omitempty" xml:"old_password,omitempty" yaml:"old_password,omitempty"`
 	FullName    string   `json:"full_name,omitempty" xml:"full_name,omitempty" yaml:"full_name,omitempty"`
 	Roles       []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
 	Disabled    bool     `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
 	Challenges  []string `json:"challenges,omitempty" xml:"challenges,omitempty" yaml:"challenges,omitempty"`
+	// Organizations are the organizations, i.e. the tenants, the user
+	// belongs to.
+	Organizations []string `json:"organizations,omitempty" xml:"organizations,omitempty" yaml:"organizations,omitempty"`
+	// Groups are the upstream groups, e.g. LDAP group DNs, the user is a
+	// member of.
+	Groups []string `json:"groups,omitempty" xml:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// Profile holds the self-managed profile attributes of a user. The
+// attributes set to nil are left unchanged.
+type Profile struct {
+	Name     *string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Phone    *string `json:"phone,omitempty" xml:"phone,omitempty" yaml:"phone,omitempty"`
+	Language *string `json:"language,omitempty" xml:"language,omitempty" yaml:"language,omitempty"`
+}
+
+// Consent holds the attributes of the consent a user granted to an
+// application.
+type Consent struct {
+	ID     string   `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	App    string   `json:"app,omitempty" xml:"app,omitempty" yaml:"app,omitempty"`
+	Scopes []string `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+// LinkedIdentity holds the attributes of the identity of a user at an
+// identity provider.
+type LinkedIdentity struct {
+	ID      string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Realm   string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	Kind    string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	Subject string `json:"subject,omitempty" xml:"subject,omitempty" yaml:"subject,omitempty"`
+	Email   string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Acceptance holds the attributes of the acceptance of a version of a
+// document, e.g. terms of service, by a user.
+type Acceptance struct {
+	Document string `json:"document,omitempty" xml:"document,omitempty" yaml:"document,omitempty"`
+	Version  string `json:"version,omitempty" xml:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// LoginRecord holds the attributes of an authentication attempt and the
+// retention limits of the login history.
+type LoginRecord struct {
+	Timestamp time.Time `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Address   string    `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	Country   string    `json:"country,omitempty" xml:"country,omitempty" yaml:"country,omitempty"`
+	City      string    `json:"city,omitempty" xml:"city,omitempty" yaml:"city,omitempty"`
+	Device    string    `json:"device,omitempty" xml:"device,omitempty" yaml:"device,omitempty"`
+	Provider  string    `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	Outcome   string    `json:"outcome,omitempty" xml:"outcome,omitempty" yaml:"outcome,omitempty"`
+	// The maximum number of records and the maximum age of the records
+	// retained in the login history.
+	MaxEntries int           `json:"max_entries,omitempty" xml:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	MaxAge     time.Duration `json:"max_age,omitempty" xml:"max_age,omitempty" yaml:"max_age,omitempty"`
+}
+
+// Deletion holds the attributes of the scheduled deletion of a user
+// account.
+type Deletion struct {
+	ScheduledAt time.Time `json:"scheduled_at,omitempty" xml:"scheduled_at,omitempty" yaml:"scheduled_at,omitempty"`
+}
+
+// RoleGrant holds the attributes of the time-boxed assignment of a role to
+// a user.
+type RoleGrant struct {
+	Username  string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email     string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Role      string    `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty"`
+	GrantedBy string    `json:"granted_by,omitempty" xml:"granted_by,omitempty" yaml:"granted_by,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// Lifecycle holds the parameters of a scheduled user lifecycle job. The
+// records older than the cutoff are subject to the job. In the dry run, the
+// records are reported, but not changed.
+type Lifecycle struct {
+	Cutoff time.Time `json:"cutoff,omitempty" xml:"cutoff,omitempty" yaml:"cutoff,omitempty"`
+	DryRun bool      `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// LifecycleItem is a user account affected by a scheduled user lifecycle
+// job.
+type LifecycleItem struct {
+	Username string    `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email    string    `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Time     time.Time `json:"time,omitempty" xml:"time,omitempty" yaml:"time,omitempty"`
+	Count    int       `json:"count,omitempty" xml:"count,omitempty" yaml:"count,omitempty"`
+}
+
+// Group holds the attributes of a group of users. The members of a group
+// inherit its roles.
+type Group struct {
+	Name        string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Description string   `json:"description,omitempty" xml:"description,omitempty" yaml:"description,omitempty"`
+	Roles       []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
 }
 
 // Key holds crypto key attributes.
 type Key struct {
-	ID       string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
-	Prefix   string `json:"prefix,omitempty" xml:"prefix,omitempty" yaml:"prefix,omitempty"`
-	Comment  string `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
-	Usage    string `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
-	Payload  string `json:"payload,omitempty" xml:"payload,omitempty" yaml:"payload,omitempty"`
-	Disabled bool   `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	ID        string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Prefix    string    `json:"prefix,omitempty" xml:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Comment   string    `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	Usage     string    `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
+	Payload   string    `json:"payload,omitempty" xml:"payload,omitempty" yaml:"payload,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty" xml:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Roles     []string  `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	// The source address of the client presenting the key.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// The number of seconds the rotated key remains valid.
+	GracePeriod int `json:"grace_period,omitempty" xml:"grace_period,omitempty" yaml:"grace_period,omitempty"`
 }
 
 // MfaToken holds MFA token attributes.
@@ -122,6 +249,12 @@ type WebAuthn struct {
 	Register  string `json:"register,omitempty" xml:"register,omitempty" yaml:"register,omitempty"`
 	Challenge string `json:"challenge,omitempty" xml:"challenge,omitempty" yaml:"challenge,omitempty"`
 	Request   string `json:"request,omitempty" xml:"request,omitempty" yaml:"request,omitempty"`
+	// HardwareKey restricts the verification of the request to the tokens
+	// of the hardware security keys.
+	HardwareKey bool `json:"hardware_key,omitempty" xml:"hardware_key,omitempty" yaml:"hardware_key,omitempty"`
+	// HardwareKeyAAGUIDs are the normalized AAGUIDs of the hardware security
+	// key models accepted when HardwareKey is set.
+	HardwareKeyAAGUIDs []string `json:"hardware_key_aaguids,omitempty" xml:"hardware_key_aaguids,omitempty" yaml:"hardware_key_aaguids,omitempty"`
 }
 
 // Flags holds various flags.
@@ -137,3 +270,11 @@ type Flags struct {
 func NewRequest() *Request {
 	return &Request{}
 }
+
+// GetContext returns the context of the request.
+func (r *Request) GetContext() context.Context {
+	if r == nil || r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
+}
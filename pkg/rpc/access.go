@@ -0,0 +1,94 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/rpc/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type authorizationService struct {
+	pb.UnimplementedAuthorizationServer
+	srv *Server
+}
+
+// CheckAccess replays the described HTTP request through the authorization
+// policy and returns its decision.
+func (s *authorizationService) CheckAccess(ctx context.Context, req *pb.CheckAccessRequest) (*pb.CheckAccessResponse, error) {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	r, err := http.NewRequestWithContext(ctx, method, req.Url, nil)
+	if err != nil || r.URL.Host == "" {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("malformed url %q", req.Url))
+	}
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+	if v := r.Header.Get("Host"); v != "" {
+		r.Host = v
+	}
+	if req.SourceAddress != "" {
+		r.RemoteAddr = net.JoinHostPort(req.SourceAddress, "0")
+	} else {
+		r.RemoteAddr = getPeerAddress(ctx)
+	}
+
+	d := authz.Decide(s.srv.authorizer, r)
+	resp := &pb.CheckAccessResponse{
+		Allowed:         d.Allowed,
+		StatusCode:      int32(d.StatusCode),
+		Reason:          d.Reason,
+		ResponseHeaders: flattenHeaders(d.ResponseHeaders),
+		UpstreamHeaders: flattenHeaders(d.UpstreamHeaders),
+	}
+	if d.Identity != nil {
+		resp.Identity = make(map[string]string)
+		for k, v := range d.Identity {
+			resp.Identity[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	s.srv.logger.Debug(
+		"rpc access decision",
+		zap.String("name", s.srv.config.Name),
+		zap.String("method", method),
+		zap.String("url", req.Url),
+		zap.Bool("allowed", resp.Allowed),
+		zap.Int32("status_code", resp.StatusCode),
+	)
+	return resp, nil
+}
+
+func flattenHeaders(h http.Header) map[string]string {
+	if h == nil {
+		return nil
+	}
+	m := make(map[string]string)
+	for k, values := range h {
+		m[k] = strings.Join(values, ", ")
+	}
+	return m
+}
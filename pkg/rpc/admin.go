@@ -0,0 +1,230 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/rpc/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type adminService struct {
+	pb.UnimplementedAdminServer
+	srv *Server
+}
+
+// ListUsers returns the users of an identity store.
+func (s *adminService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	store, err := s.getStore(req.Realm)
+	if err != nil {
+		return nil, err
+	}
+	// The retrieval of users is performed on behalf of the administrator.
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	rr := requests.NewRequest()
+	rr.User.Username = c.subject
+	rr.User.Email = c.email
+	if err := store.Request(operator.GetUsers, rr); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	resp := &pb.ListUsersResponse{}
+	for _, entry := range rr.Response.Payload.(*identity.UserMetadataBundle).Get() {
+		resp.Users = append(resp.Users, &pb.User{
+			Id:       entry.ID,
+			Username: entry.Username,
+			Name:     entry.Name,
+			Email:    entry.Email,
+		})
+	}
+	return resp, nil
+}
+
+// GetUser returns a user of an identity store.
+func (s *adminService) GetUser(ctx context.Context, req *pb.UserRequest) (*pb.User, error) {
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	return s.getUser(store, rr)
+}
+
+// AddUser adds a user to an identity store.
+func (s *adminService) AddUser(ctx context.Context, req *pb.AddUserRequest) (*pb.User, error) {
+	store, err := s.getStore(req.Realm)
+	if err != nil {
+		return nil, err
+	}
+	rr := requests.NewRequest()
+	rr.User.Username = req.Username
+	rr.User.Password = req.Password
+	rr.User.Email = req.Email
+	rr.User.FullName = req.Name
+	rr.User.Roles = req.Roles
+	if err := store.Request(operator.AddUser, rr); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	rr.User.Password = ""
+	s.logOperation(ctx, "AddUser", store.GetRealm(), req.Username)
+	return s.getUser(store, rr)
+}
+
+// DeleteUser deletes a user from an identity store.
+func (s *adminService) DeleteUser(ctx context.Context, req *pb.UserRequest) (*pb.AdminResponse, error) {
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Request(operator.DeleteUser, rr); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	s.logOperation(ctx, "DeleteUser", store.GetRealm(), rr.User.Username)
+	return &pb.AdminResponse{}, nil
+}
+
+// UpdateRoles replaces the roles of a user.
+func (s *adminService) UpdateRoles(ctx context.Context, req *pb.UpdateRolesRequest) (*pb.User, error) {
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	rr.User.Roles = req.Roles
+	if err := store.Request(operator.UpdateRoles, rr); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	s.logOperation(ctx, "UpdateRoles", store.GetRealm(), rr.User.Username)
+	return s.getUser(store, rr)
+}
+
+// ResetPassword sets the password of a user.
+func (s *adminService) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.AdminResponse, error) {
+	if req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is empty")
+	}
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	rr.User.Password = req.Password
+	if err := store.Request(operator.ResetPassword, rr); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s.logOperation(ctx, "ResetPassword", store.GetRealm(), rr.User.Username)
+	return &pb.AdminResponse{Message: rr.Response.Message}, nil
+}
+
+// ResetMfaTokens removes the MFA tokens of a user.
+func (s *adminService) ResetMfaTokens(ctx context.Context, req *pb.UserRequest) (*pb.AdminResponse, error) {
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Request(operator.ResetMfaTokens, rr); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.logOperation(ctx, "ResetMfaTokens", store.GetRealm(), rr.User.Username)
+	return &pb.AdminResponse{}, nil
+}
+
+// AddAPIKey issues an API key to a user. The key is returned once.
+func (s *adminService) AddAPIKey(ctx context.Context, req *pb.AddAPIKeyRequest) (*pb.AddAPIKeyResponse, error) {
+	if req.ExpiresIn < 0 {
+		return nil, status.Error(codes.InvalidArgument, "expires_in is negative")
+	}
+	store, rr, err := s.identifyUser(req.Realm, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	rr.Key.Usage = req.Usage
+	if rr.Key.Usage == "" {
+		rr.Key.Usage = "api"
+	}
+	rr.Key.Comment = req.Comment
+	rr.Key.Roles = req.Roles
+	rr.Key.Scopes = req.Scopes
+	if req.ExpiresIn > 0 {
+		rr.Key.ExpiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).UTC()
+	}
+	if err := store.Request(operator.AddAPIKey, rr); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s.logOperation(ctx, "AddAPIKey", store.GetRealm(), rr.User.Username)
+	apiKey, _ := rr.Response.Payload.(string)
+	return &pb.AddAPIKeyResponse{ApiKey: apiKey}, nil
+}
+
+func (s *adminService) getStore(realm string) (ids.IdentityStore, error) {
+	if realm == "" {
+		realm = "local"
+	}
+	s.srv.mu.Lock()
+	store, exists := s.srv.stores[realm]
+	s.srv.mu.Unlock()
+	if !exists {
+		return nil, status.Error(codes.NotFound, errors.ErrRPCRealmNotFound.WithArgs(realm).Error())
+	}
+	return store, nil
+}
+
+// identifyUser returns the identity store and the request referencing the
+// username and email address of the user.
+func (s *adminService) identifyUser(realm, username string) (ids.IdentityStore, *requests.Request, error) {
+	store, err := s.getStore(realm)
+	if err != nil {
+		return nil, nil, err
+	}
+	rr := requests.NewRequest()
+	rr.User.Username = username
+	if err := store.Request(operator.IdentifyUser, rr); err != nil || rr.User.Username == "nobody" {
+		return nil, nil, status.Error(codes.NotFound, errors.ErrRPCUserNotFound.WithArgs(username).Error())
+	}
+	return store, rr, nil
+}
+
+func (s *adminService) getUser(store ids.IdentityStore, rr *requests.Request) (*pb.User, error) {
+	if err := store.Request(operator.GetUser, rr); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	usr := rr.Response.Payload.(*identity.User)
+	return &pb.User{
+		Id:            usr.ID,
+		Username:      usr.Username,
+		Name:          usr.GetFullName(),
+		Email:         usr.GetMailClaim(),
+		Roles:         usr.GetRolesClaim(),
+		MfaTokenCount: int32(len(usr.MfaTokens)),
+		ApiKeyCount:   int32(len(usr.APIKeys)),
+	}, nil
+}
+
+func (s *adminService) logOperation(ctx context.Context, op, realm, username string) {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	s.srv.logger.Info(
+		"Performed administrative operation",
+		zap.String("name", s.srv.config.Name),
+		zap.String("admin", c.email),
+		zap.String("realm", realm),
+		zap.String("operation", op),
+		zap.String("username", username),
+	)
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// Config is the configuration of the gRPC server exposing the authorization
+// decisions and the administrative operations.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Address is the address the server listens on, e.g. 127.0.0.1:9090.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// AuthorizationPolicy is the name of the authorization policy making the
+	// access decisions and authenticating the administrators.
+	AuthorizationPolicy string `json:"authorization_policy,omitempty" xml:"authorization_policy,omitempty" yaml:"authorization_policy,omitempty"`
+	// AdminRoles are the roles permitted to call the admin service. The
+	// default is authp/admin.
+	AdminRoles []string `json:"admin_roles,omitempty" xml:"admin_roles,omitempty" yaml:"admin_roles,omitempty"`
+	// AdminDisabled disables the admin service.
+	AdminDisabled bool `json:"admin_disabled,omitempty" xml:"admin_disabled,omitempty" yaml:"admin_disabled,omitempty"`
+	// TLSCertPath and TLSKeyPath enable TLS.
+	TLSCertPath string `json:"tls_cert_path,omitempty" xml:"tls_cert_path,omitempty" yaml:"tls_cert_path,omitempty"`
+	TLSKeyPath  string `json:"tls_key_path,omitempty" xml:"tls_key_path,omitempty" yaml:"tls_key_path,omitempty"`
+}
+
+// Validate validates rpc server config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrRPCConfigNameEmpty
+	}
+	if cfg.Address == "" {
+		return errors.ErrRPCConfigAddressEmpty.WithArgs(cfg.Name)
+	}
+	if cfg.AuthorizationPolicy == "" {
+		return errors.ErrRPCConfigPolicyEmpty.WithArgs(cfg.Name)
+	}
+	if (cfg.TLSCertPath == "") != (cfg.TLSKeyPath == "") {
+		return errors.ErrRPCConfigTLS.WithArgs(cfg.Name, "both certificate and key paths are required")
+	}
+	if len(cfg.AdminRoles) == 0 {
+		cfg.AdminRoles = []string{"authp/admin"}
+	}
+	return nil
+}
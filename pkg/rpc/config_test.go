@@ -0,0 +1,97 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		want      []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid rpc server config",
+			config: &Config{
+				Name:                "default",
+				Address:             "127.0.0.1:9090",
+				AuthorizationPolicy: "mypolicy",
+			},
+			want: []string{"authp/admin"},
+		},
+		{
+			name: "test rpc server config with admin roles",
+			config: &Config{
+				Name:                "default",
+				Address:             "127.0.0.1:9090",
+				AuthorizationPolicy: "mypolicy",
+				AdminRoles:          []string{"ops/admin"},
+			},
+			want: []string{"ops/admin"},
+		},
+		{
+			name:      "test rpc server config without name",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrRPCConfigNameEmpty,
+		},
+		{
+			name: "test rpc server config without address",
+			config: &Config{
+				Name: "default",
+			},
+			shouldErr: true,
+			err:       errors.ErrRPCConfigAddressEmpty.WithArgs("default"),
+		},
+		{
+			name: "test rpc server config without authorization policy",
+			config: &Config{
+				Name:    "default",
+				Address: "127.0.0.1:9090",
+			},
+			shouldErr: true,
+			err:       errors.ErrRPCConfigPolicyEmpty.WithArgs("default"),
+		},
+		{
+			name: "test rpc server config with tls certificate without key",
+			config: &Config{
+				Name:                "default",
+				Address:             "127.0.0.1:9090",
+				AuthorizationPolicy: "mypolicy",
+				TLSCertPath:         "/etc/ssl/rpc.crt",
+			},
+			shouldErr: true,
+			err:       errors.ErrRPCConfigTLS.WithArgs("default", "both certificate and key paths are required"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "admin roles", tc.want, tc.config.AdminRoles, msgs)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/rpc/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const adminServicePrefix = "/authcrunch.v1.Admin/"
+
+// Server is a gRPC server exposing the access decisions of an authorization
+// policy and the administrative operations of the identity stores.
+type Server struct {
+	config     *Config
+	authorizer authz.Authorizer
+	stores     map[string]ids.IdentityStore
+	adminRoles map[string]bool
+	logger     *zap.Logger
+	tlsConfig  *tls.Config
+	grpcServer *grpc.Server
+	listener   net.Listener
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+}
+
+type callerKey struct{}
+
+// caller is the administrator authenticated by the authorization policy.
+type caller struct {
+	subject string
+	email   string
+}
+
+// NewServer returns an instance of Server.
+func NewServer(cfg *Config, authorizer authz.Authorizer, stores []ids.IdentityStore, logger *zap.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if authorizer == nil {
+		return nil, errors.ErrRPCPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy)
+	}
+
+	srv := &Server{
+		config:     cfg,
+		authorizer: authorizer,
+		adminRoles: make(map[string]bool),
+		logger:     logger,
+	}
+	srv.SetIdentityStores(stores)
+	for _, role := range cfg.AdminRoles {
+		srv.adminRoles[role] = true
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(srv.intercept),
+	}
+	if cfg.TLSCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, errors.ErrRPCConfigTLS.WithArgs(cfg.Name, err)
+		}
+		srv.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(srv.tlsConfig)))
+	}
+
+	srv.grpcServer = grpc.NewServer(opts...)
+	pb.RegisterAuthorizationServer(srv.grpcServer, &authorizationService{srv: srv})
+	if !cfg.AdminDisabled {
+		pb.RegisterAdminServer(srv.grpcServer, &adminService{srv: srv})
+	}
+	return srv, nil
+}
+
+// GetName returns the name of the server.
+func (srv *Server) GetName() string {
+	return srv.config.Name
+}
+
+// SetIdentityStores replaces the identity stores available to the
+// administrative operations.
+func (srv *Server) SetIdentityStores(stores []ids.IdentityStore) {
+	m := make(map[string]ids.IdentityStore)
+	for _, store := range stores {
+		m[store.GetRealm()] = store
+	}
+	srv.mu.Lock()
+	srv.stores = m
+	srv.mu.Unlock()
+}
+
+// GetAddress returns the address the server listens on.
+func (srv *Server) GetAddress() string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listener != nil {
+		return srv.listener.Addr().String()
+	}
+	return srv.config.Address
+}
+
+// Start starts accepting the connections of the gRPC clients.
+func (srv *Server) Start() error {
+	listener, err := net.Listen("tcp", srv.config.Address)
+	if err != nil {
+		return errors.ErrRPCStartFailed.WithArgs(srv.config.Name, err)
+	}
+
+	srv.mu.Lock()
+	srv.listener = listener
+	srv.mu.Unlock()
+
+	srv.logger.Info(
+		"started rpc server",
+		zap.String("name", srv.config.Name),
+		zap.String("address", listener.Addr().String()),
+		zap.String("authorization_policy", srv.config.AuthorizationPolicy),
+		zap.Bool("admin", !srv.config.AdminDisabled),
+		zap.Bool("tls", srv.tlsConfig != nil),
+	)
+
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		srv.grpcServer.Serve(listener)
+	}()
+	return nil
+}
+
+// Stop stops the server and closes the client connections.
+func (srv *Server) Stop() error {
+	srv.mu.Lock()
+	listener := srv.listener
+	srv.listener = nil
+	srv.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	srv.grpcServer.Stop()
+	srv.wg.Wait()
+	return nil
+}
+
+// intercept authenticates the callers of the admin service.
+func (srv *Server) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
+		return handler(ctx, req)
+	}
+	c, err := srv.authenticateAdmin(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, callerKey{}, c), req)
+}
+
+func (srv *Server) authenticateAdmin(ctx context.Context, method string) (*caller, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+method, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, values := range md {
+			if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(k, v)
+			}
+		}
+		if v := md.Get(":authority"); len(v) > 0 {
+			r.Host = v[0]
+		}
+	}
+	r.RemoteAddr = getPeerAddress(ctx)
+
+	d := authz.Decide(srv.authorizer, r)
+	if !d.Allowed || d.Bypassed {
+		srv.logger.Debug(
+			"rpc admin authentication failed",
+			zap.String("name", srv.config.Name),
+			zap.String("method", method),
+			zap.String("src_ip", r.RemoteAddr),
+			zap.String("reason", d.Reason),
+		)
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	c := &caller{}
+	if v, ok := d.Identity["sub"].(string); ok {
+		c.subject = v
+	}
+	if v, ok := d.Identity["email"].(string); ok {
+		c.email = v
+	}
+	roles, _ := d.Identity["roles"].(string)
+	for _, role := range strings.Fields(roles) {
+		if srv.adminRoles[role] {
+			return c, nil
+		}
+	}
+	return nil, status.Error(codes.PermissionDenied, "permission denied")
+}
+
+func getPeerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/rpc/pb"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testAuthorizer authorizes the requests with the "admin" and "viewer"
+// bearer tokens, and redirects the others to the login page.
+type testAuthorizer struct{}
+
+func (a *testAuthorizer) Authenticate(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	switch r.Header.Get("Authorization") {
+	case "Bearer admin":
+		ar.Response.User = map[string]interface{}{
+			"sub":   tests.TestUser1,
+			"email": tests.TestEmail1,
+			"roles": "admin authp/admin",
+		}
+	case "Bearer viewer":
+		ar.Response.User = map[string]interface{}{
+			"sub":   tests.TestUser2,
+			"email": tests.TestEmail2,
+			"roles": "viewer",
+		}
+	default:
+		w.Header().Set("Location", "/login?redirect_url="+r.URL.Path)
+		w.WriteHeader(302)
+		return errors.ErrNoTokenFound
+	}
+	r.Header.Set("X-Token-User-Email", ar.Response.User["email"].(string))
+	ar.Response.Authorized = true
+	return nil
+}
+
+func newTestServer(t *testing.T) (*Server, *grpc.ClientConn) {
+	db, err := testutils.CreateTestDatabase("TestRPCServer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	store, err := ids.NewIdentityStore(&ids.IdentityStoreConfig{
+		Name: "localdb",
+		Kind: "local",
+		Params: map[string]interface{}{
+			"path":  db.GetPath(),
+			"realm": "local",
+		},
+	}, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Name:                "default",
+		Address:             "127.0.0.1:0",
+		AuthorizationPolicy: "mypolicy",
+	}
+	srv, err := NewServer(cfg, &testAuthorizer{}, []ids.IdentityStore{store}, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.Dial(srv.GetAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return srv, conn
+}
+
+func TestCheckAccess(t *testing.T) {
+	_, conn := newTestServer(t)
+	client := pb.NewAuthorizationClient(conn)
+
+	testcases := []struct {
+		name      string
+		req       *pb.CheckAccessRequest
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test allowed request",
+			req: &pb.CheckAccessRequest{
+				Method:  "GET",
+				Url:     "https://app.local/foo",
+				Headers: map[string]string{"Authorization": "Bearer admin"},
+			},
+			want: map[string]interface{}{
+				"allowed":          true,
+				"upstream_headers": map[string]string{"X-Token-User-Email": tests.TestEmail1},
+				"email":            tests.TestEmail1,
+			},
+		},
+		{
+			name: "test denied request",
+			req: &pb.CheckAccessRequest{
+				Url: "https://app.local/foo",
+			},
+			want: map[string]interface{}{
+				"allowed":     false,
+				"status_code": int32(302),
+				"reason":      errors.ErrNoTokenFound.Error(),
+				"location":    "/login?redirect_url=/foo",
+			},
+		},
+		{
+			name: "test request with malformed url",
+			req: &pb.CheckAccessRequest{
+				Url: "/foo",
+			},
+			shouldErr: true,
+			err:       status.Error(codes.InvalidArgument, `malformed url "/foo"`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			resp, err := client.CheckAccess(context.Background(), tc.req)
+			if tests.EvalErrWithLog(t, err, "CheckAccess", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"allowed": resp.Allowed,
+			}
+			if resp.Allowed {
+				got["upstream_headers"] = resp.UpstreamHeaders
+				got["email"] = resp.Identity["email"]
+			} else {
+				got["status_code"] = resp.StatusCode
+				got["reason"] = resp.Reason
+				got["location"] = resp.ResponseHeaders["Location"]
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestAdminService(t *testing.T) {
+	_, conn := newTestServer(t)
+	client := pb.NewAdminClient(conn)
+	adminCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer admin")
+	viewerCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer viewer")
+
+	_, err := client.ListUsers(context.Background(), &pb.ListUsersRequest{})
+	tests.EvalErrWithLog(t, err, "ListUsers without token", true, status.Error(codes.Unauthenticated, "unauthenticated"), nil)
+
+	_, err = client.ListUsers(viewerCtx, &pb.ListUsersRequest{})
+	tests.EvalErrWithLog(t, err, "ListUsers without admin role", true, status.Error(codes.PermissionDenied, "permission denied"), nil)
+
+	_, err = client.ListUsers(adminCtx, &pb.ListUsersRequest{Realm: "foo"})
+	tests.EvalErrWithLog(t, err, "ListUsers in unknown realm", true, status.Error(codes.NotFound, `realm "foo" not found`), nil)
+
+	users, err := client.ListUsers(adminCtx, &pb.ListUsersRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "user count", 2, len(users.Users))
+
+	usr, err := client.AddUser(adminCtx, &pb.AddUserRequest{
+		Username: "foobar",
+		Password: tests.NewRandomString(16),
+		Email:    "foobar@localhost",
+		Roles:    []string{"viewer"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "added user roles", []string{"viewer"}, usr.Roles)
+
+	usr, err = client.UpdateRoles(adminCtx, &pb.UpdateRolesRequest{Username: "foobar", Roles: []string{"editor", "viewer"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "updated user roles", []string{"editor", "viewer"}, usr.Roles)
+
+	if _, err := client.ResetPassword(adminCtx, &pb.ResetPasswordRequest{Username: "foobar", Password: tests.NewRandomString(16)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ResetMfaTokens(adminCtx, &pb.UserRequest{Username: "foobar"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key, err := client.AddAPIKey(adminCtx, &pb.AddAPIKeyRequest{Username: "foobar", Comment: "ci", ExpiresIn: 3600})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.ApiKey == "" {
+		t.Fatalf("expected api key")
+	}
+	usr, err = client.GetUser(adminCtx, &pb.UserRequest{Username: "foobar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "api key count", int32(1), usr.ApiKeyCount)
+
+	if _, err := client.DeleteUser(adminCtx, &pb.UserRequest{Username: "foobar"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = client.GetUser(adminCtx, &pb.UserRequest{Username: "foobar"})
+	tests.EvalErrWithLog(t, err, "GetUser after DeleteUser", true, status.Error(codes.NotFound, `user "foobar" not found`), nil)
+
+	_, err = client.DeleteUser(adminCtx, &pb.UserRequest{Username: tests.TestUser1})
+	if !strings.Contains(fmt.Sprint(err), errors.ErrLastAdminUser.Error()) {
+		t.Fatalf("expected last admin error, got: %v", err)
+	}
+}
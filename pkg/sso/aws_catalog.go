@@ -0,0 +1,262 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+	"go.uber.org/zap"
+)
+
+const defaultRoleCatalogRefreshInterval = 3600
+
+// RoleCatalogConfig represents the configuration of the catalog of the AWS
+// accounts and roles available for the role assumption.
+type RoleCatalogConfig struct {
+	// Source is the source of the catalog, i.e. file or organizations.
+	Source string `json:"source,omitempty" xml:"source,omitempty" yaml:"source,omitempty"`
+	// Path is the path to the catalog file.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// RoleNames are the names of the roles provisioned in every account. The
+	// accounts enumerated via AWS Organizations, and the accounts in the
+	// catalog file without roles, have these roles.
+	RoleNames []string `json:"role_names,omitempty" xml:"role_names,omitempty" yaml:"role_names,omitempty"`
+	// Endpoint is the endpoint of AWS Organizations API.
+	Endpoint        string `json:"endpoint,omitempty" xml:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Region          string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty" xml:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty" xml:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty" xml:"session_token,omitempty" yaml:"session_token,omitempty"`
+	// RefreshInterval is the interval, in seconds, between the reloads of
+	// the catalog.
+	RefreshInterval int `json:"refresh_interval,omitempty" xml:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+}
+
+// RoleCatalog is the catalog of the AWS accounts and roles.
+type RoleCatalog struct {
+	Accounts []*AccountEntry `json:"accounts,omitempty" xml:"accounts,omitempty" yaml:"accounts,omitempty"`
+}
+
+// AccountEntry is an AWS account in the catalog.
+type AccountEntry struct {
+	ID    string   `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Name  string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// AssumeRole is a role a user may assume in an AWS account.
+type AssumeRole struct {
+	Name        string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	AccountID   string `json:"account_id,omitempty" xml:"account_id,omitempty" yaml:"account_id,omitempty"`
+	AccountName string `json:"account_name,omitempty" xml:"account_name,omitempty" yaml:"account_name,omitempty"`
+}
+
+type roleCatalog struct {
+	mu           sync.Mutex
+	config       *RoleCatalogConfig
+	catalog      *RoleCatalog
+	loadedAt     time.Time
+	providerName string
+	logger       *zap.Logger
+	client       *organizationsClient
+	fetch        func() (*RoleCatalog, error)
+}
+
+// Validate validates role catalog config.
+func (cfg *RoleCatalogConfig) Validate() error {
+	switch cfg.Source {
+	case "file":
+		if cfg.Path == "" {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty role catalog path")
+		}
+	case "organizations":
+		if len(cfg.RoleNames) < 1 {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty role catalog role names")
+		}
+		if (cfg.AccessKeyID == "") != (cfg.SecretAccessKey == "") {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "incomplete role catalog credentials")
+		}
+	case "":
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty role catalog source")
+	default:
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("unsupported role catalog source %q", cfg.Source))
+	}
+	if cfg.RefreshInterval < 0 {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "negative role catalog refresh interval")
+	}
+	return nil
+}
+
+func newRoleCatalog(name string, cfg *RoleCatalogConfig, logger *zap.Logger) *roleCatalog {
+	c := &roleCatalog{
+		config:       cfg,
+		providerName: name,
+		logger:       logger,
+	}
+	switch cfg.Source {
+	case "file":
+		c.fetch = c.fetchFile
+	case "organizations":
+		c.client = newOrganizationsClient(cfg)
+		c.fetch = c.fetchOrganizations
+	}
+	return c
+}
+
+func (c *roleCatalog) fetchOrganizations() (*RoleCatalog, error) {
+	accounts, err := c.client.ListAccounts()
+	if err != nil {
+		return nil, err
+	}
+	catalog := &RoleCatalog{}
+	for _, account := range accounts {
+		catalog.Accounts = append(catalog.Accounts, &AccountEntry{
+			ID:    account.ID,
+			Name:  account.Name,
+			Roles: c.config.RoleNames,
+		})
+	}
+	return catalog, nil
+}
+
+func (c *roleCatalog) fetchFile() (*RoleCatalog, error) {
+	b, err := fileutil.ReadFileBytes(c.config.Path)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &RoleCatalog{}
+	if err := json.Unmarshal(b, catalog); err != nil {
+		return nil, err
+	}
+	for _, account := range catalog.Accounts {
+		if account.ID == "" {
+			return nil, fmt.Errorf("account with empty id")
+		}
+		if len(account.Roles) == 0 {
+			account.Roles = c.config.RoleNames
+		}
+	}
+	return catalog, nil
+}
+
+// get returns the catalog. The catalog is reloaded when the refresh interval
+// elapses. When the reload fails, the previously loaded catalog remains in
+// use.
+func (c *roleCatalog) get() (*RoleCatalog, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interval := c.config.RefreshInterval
+	if interval == 0 {
+		interval = defaultRoleCatalogRefreshInterval
+	}
+	if c.catalog != nil && time.Since(c.loadedAt) < time.Duration(interval)*time.Second {
+		return c.catalog, nil
+	}
+
+	catalog, err := c.fetch()
+	if err != nil {
+		if c.catalog == nil {
+			return nil, errors.ErrSingleSignOnProviderRoleCatalog.WithArgs(c.providerName, err)
+		}
+		c.logger.Warn(
+			"failed reloading role catalog",
+			zap.String("provider_name", c.providerName),
+			zap.Error(err),
+		)
+		c.loadedAt = time.Now()
+		return c.catalog, nil
+	}
+	c.catalog = catalog
+	c.loadedAt = time.Now()
+	return c.catalog, nil
+}
+
+// GetAssumeRoles returns the AWS roles the user with the provided roles may
+// assume. A role is available when the user has "aws/<account_id>/<role>"
+// role. The account ID and the role name may be a "*" wildcard, e.g.
+// "aws/*/ReadOnly" grants ReadOnly role in every account in the catalog.
+// Without the catalog, the roles are the ones in the user roles, and the
+// wildcards do not apply.
+func (p *Provider) GetAssumeRoles(roles []string) ([]*AssumeRole, error) {
+	grants := parseAssumeRoleClaims(roles)
+	if p.catalog == nil {
+		var entries []*AssumeRole
+		for _, grant := range grants {
+			if grant.AccountID == "*" || grant.Name == "*" {
+				continue
+			}
+			entries = append(entries, grant)
+		}
+		return entries, nil
+	}
+
+	catalog, err := p.catalog.get()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*AssumeRole
+	for _, account := range catalog.Accounts {
+		for _, roleName := range account.Roles {
+			for _, grant := range grants {
+				if grant.AccountID != "*" && grant.AccountID != account.ID {
+					continue
+				}
+				if grant.Name != "*" && grant.Name != roleName {
+					continue
+				}
+				entries = append(entries, &AssumeRole{
+					Name:        roleName,
+					AccountID:   account.ID,
+					AccountName: account.Name,
+				})
+				break
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].AccountName != entries[j].AccountName {
+			return entries[i].AccountName < entries[j].AccountName
+		}
+		return entries[i].AccountID < entries[j].AccountID
+	})
+	return entries, nil
+}
+
+func parseAssumeRoleClaims(roles []string) []*AssumeRole {
+	var entries []*AssumeRole
+	for _, entry := range roles {
+		arr := strings.Split(entry, "/")
+		if len(arr) != 3 {
+			continue
+		}
+		if arr[0] != "aws" || arr[1] == "" || arr[2] == "" {
+			continue
+		}
+		entries = append(entries, &AssumeRole{
+			Name:      arr[2],
+			AccountID: arr[1],
+		})
+	}
+	return entries
+}
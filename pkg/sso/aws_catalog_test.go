@@ -0,0 +1,156 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestGetAssumeRoles(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		input := make(map[string]interface{})
+		json.Unmarshal(body, &input)
+		requests = append(requests, map[string]interface{}{
+			"target":        r.Header.Get("X-Amz-Target"),
+			"authorization": strings.SplitN(r.Header.Get("Authorization"), ", Signature=", 2)[0],
+			"input":         input,
+		})
+		if input["NextToken"] == nil {
+			w.Write([]byte(`{"Accounts":[{"Id":"123456789012","Name":"production","Status":"ACTIVE"},` +
+				`{"Id":"333333333333","Name":"closed","Status":"SUSPENDED"}],"NextToken":"page2"}`))
+			return
+		}
+		w.Write([]byte(`{"Accounts":[{"Id":"210987654321","Name":"development","Status":"ACTIVE"}]}`))
+	}))
+	defer srv.Close()
+
+	testcases := []struct {
+		name         string
+		catalog      *RoleCatalogConfig
+		roles        []string
+		want         []*AssumeRole
+		wantRequests []map[string]interface{}
+		shouldErr    bool
+		err          error
+	}{
+		{
+			name:  "test roles without catalog",
+			roles: []string{"authp/user", "aws/123456789012/Administrator", "aws/*/ReadOnly"},
+			want: []*AssumeRole{
+				{Name: "Administrator", AccountID: "123456789012"},
+			},
+		},
+		{
+			name: "test roles from catalog file",
+			catalog: &RoleCatalogConfig{
+				Source:    "file",
+				Path:      "../../testdata/sso/aws_role_catalog.json",
+				RoleNames: []string{"Developer"},
+			},
+			roles: []string{"aws/123456789012/Administrator", "aws/*/ReadOnly", "aws/210987654321/*", "aws/999999999999/Administrator"},
+			want: []*AssumeRole{
+				{Name: "Developer", AccountID: "210987654321", AccountName: "development"},
+				{Name: "Administrator", AccountID: "123456789012", AccountName: "production"},
+				{Name: "ReadOnly", AccountID: "123456789012", AccountName: "production"},
+			},
+		},
+		{
+			name: "test roles from aws organizations",
+			catalog: &RoleCatalogConfig{
+				Source:          "organizations",
+				Endpoint:        srv.URL,
+				RoleNames:       []string{"Administrator", "ReadOnly"},
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			},
+			roles: []string{"aws/*/ReadOnly", "aws/333333333333/*"},
+			want: []*AssumeRole{
+				{Name: "ReadOnly", AccountID: "210987654321", AccountName: "development"},
+				{Name: "ReadOnly", AccountID: "123456789012", AccountName: "production"},
+			},
+			wantRequests: []map[string]interface{}{
+				{
+					"target":        "AWSOrganizationsV20161128.ListAccounts",
+					"authorization": "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20220101/us-east-1/organizations/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target",
+					"input":         map[string]interface{}{},
+				},
+				{
+					"target":        "AWSOrganizationsV20161128.ListAccounts",
+					"authorization": "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20220101/us-east-1/organizations/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target",
+					"input":         map[string]interface{}{"NextToken": "page2"},
+				},
+			},
+		},
+		{
+			name: "test catalog file not found",
+			catalog: &RoleCatalogConfig{
+				Source: "file",
+				Path:   "../../testdata/sso/foo.json",
+			},
+			roles:     []string{"aws/*/ReadOnly"},
+			shouldErr: true,
+			err: errors.ErrSingleSignOnProviderRoleCatalog.WithArgs(
+				"aws",
+				fmt.Errorf("open ../../testdata/sso/foo.json: no such file or directory"),
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = nil
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			cfg := &SingleSignOnProviderConfig{
+				Name:           "aws",
+				Driver:         "aws",
+				EntityID:       "caddy-authp-idp",
+				PrivateKeyPath: "../../testdata/sso/authp_saml.key",
+				CertPath:       "../../testdata/sso/authp_saml.crt",
+				Locations:      []string{"https://localhost/apps/sso/aws"},
+				RoleCatalog:    tc.catalog,
+			}
+			provider, err := NewSingleSignOnProvider(cfg, logutil.NewLogger())
+			if err != nil {
+				t.Fatalf("failed initializing sso provider: %v", err)
+			}
+			if tc.catalog != nil && tc.catalog.Source == "organizations" {
+				provider.(*Provider).catalog.client.now = func() time.Time {
+					return time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+				}
+			}
+
+			got, err := provider.GetAssumeRoles(tc.roles)
+			if tests.EvalErrWithLog(t, err, "assume roles", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "assume roles", tc.want, got, msgs)
+			if tc.wantRequests != nil {
+				tests.EvalObjectsWithLog(t, "requests", tc.wantRequests, requests, msgs)
+			}
+		})
+	}
+}
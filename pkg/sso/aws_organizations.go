@@ -0,0 +1,155 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/sigv4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultOrganizationsRegion = "us-east-1"
+	organizationsService       = "organizations"
+	organizationsTargetPrefix  = "AWSOrganizationsV20161128."
+	organizationsAccountActive = "ACTIVE"
+	organizationsContentType   = "application/x-amz-json-1.1"
+)
+
+type organizationsAccount struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+}
+
+type organizationsListAccountsResponse struct {
+	Accounts  []*organizationsAccount `json:"Accounts"`
+	NextToken string                  `json:"NextToken"`
+}
+
+// organizationsClient is a client of AWS Organizations API. It signs the
+// requests with AWS Signature Version 4.
+type organizationsClient struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	client          *http.Client
+	now             func() time.Time
+}
+
+func newOrganizationsClient(cfg *RoleCatalogConfig) *organizationsClient {
+	c := &organizationsClient{
+		endpoint:        cfg.Endpoint,
+		region:          cfg.Region,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		sessionToken:    cfg.SessionToken,
+		client:          &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+	if c.region == "" {
+		c.region = defaultOrganizationsRegion
+	}
+	if c.endpoint == "" {
+		c.endpoint = "https://organizations." + c.region + ".amazonaws.com"
+	}
+	if c.accessKeyID == "" {
+		// The credentials of the environment apply when the credentials are
+		// not configured.
+		c.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	return c
+}
+
+// ListAccounts returns the active accounts of the organization.
+func (c *organizationsClient) ListAccounts() ([]*organizationsAccount, error) {
+	var accounts []*organizationsAccount
+	var nextToken string
+	for {
+		input := make(map[string]interface{})
+		if nextToken != "" {
+			input["NextToken"] = nextToken
+		}
+		resp := &organizationsListAccountsResponse{}
+		if err := c.call("ListAccounts", input, resp); err != nil {
+			return nil, err
+		}
+		for _, account := range resp.Accounts {
+			if account.Status != "" && account.Status != organizationsAccountActive {
+				continue
+			}
+			accounts = append(accounts, account)
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+	return accounts, nil
+}
+
+func (c *organizationsClient) call(action string, input, output interface{}) error {
+	if c.accessKeyID == "" || c.secretAccessKey == "" {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs("credentials not found")
+	}
+	body, _ := json.Marshal(input)
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs(err)
+	}
+	req.Header.Set("Content-Type", organizationsContentType)
+	req.Header.Set("X-Amz-Target", organizationsTargetPrefix+action)
+	c.sign(req, body)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs(
+			fmt.Errorf("%s: status code %d: %s", action, resp.StatusCode, strings.TrimSpace(string(respBody))),
+		)
+	}
+	if err := json.Unmarshal(respBody, output); err != nil {
+		return errors.ErrSingleSignOnProviderOrganizationsRequest.WithArgs(err)
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 to the request.
+func (c *organizationsClient) sign(req *http.Request, body []byte) {
+	sigv4.Sign(req, body, &sigv4.Credentials{
+		AccessKeyID:     c.accessKeyID,
+		SecretAccessKey: c.secretAccessKey,
+		SessionToken:    c.sessionToken,
+	}, c.region, organizationsService, c.now())
+}
@@ -28,13 +28,19 @@ type SingleSignOnProviderConfig struct {
 	Locations      []string `json:"locations,omitempty" xml:"locations,omitempty" yaml:"locations,omitempty"`
 	PrivateKeyPath string   `json:"private_key_path,omitempty" xml:"private_key_path,omitempty" yaml:"private_key_path,omitempty"`
 	CertPath       string   `json:"cert_path,omitempty" xml:"cert_path,omitempty" yaml:"cert_path,omitempty"`
+	// ServiceProviders are the SAML service providers served by the provider
+	// with the "saml" driver.
+	ServiceProviders []*ServiceProviderConfig `json:"service_providers,omitempty" xml:"service_providers,omitempty" yaml:"service_providers,omitempty"`
+	// RoleCatalog is the catalog of the AWS accounts and roles of the
+	// provider with the "aws" driver.
+	RoleCatalog *RoleCatalogConfig `json:"role_catalog,omitempty" xml:"role_catalog,omitempty" yaml:"role_catalog,omitempty"`
 }
 
 // NewSingleSignOnProviderConfig returns SingleSignOnProviderConfig instance.
 func NewSingleSignOnProviderConfig(data map[string]interface{}) (*SingleSignOnProviderConfig, error) {
 
 	requiredFields := []string{"name", "entity_id", "locations", "private_key_path", "cert_path"}
-	optionalFields := []string{"driver"}
+	optionalFields := []string{"driver", "service_providers", "role_catalog"}
 
 	if err := validateFields(data, requiredFields, optionalFields); err != nil {
 		return nil, errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("input data error", err)
@@ -70,6 +76,38 @@ func (cfg *SingleSignOnProviderConfig) Validate() error {
 	}
 	switch cfg.Driver {
 	case "aws":
+		if len(cfg.ServiceProviders) > 0 {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "service providers are not supported by aws driver")
+		}
+		if cfg.RoleCatalog != nil {
+			if err := cfg.RoleCatalog.Validate(); err != nil {
+				return err
+			}
+		}
+	case "saml":
+		if cfg.RoleCatalog != nil {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "role catalog is not supported by saml driver")
+		}
+		if len(cfg.ServiceProviders) < 1 {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty service providers")
+		}
+		ids := make(map[string]bool)
+		entityIDs := make(map[string]bool)
+		for _, sp := range cfg.ServiceProviders {
+			if err := sp.Validate(); err != nil {
+				return err
+			}
+			if ids[sp.ID] {
+				return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("duplicate service provider id %q", sp.ID))
+			}
+			ids[sp.ID] = true
+			if sp.EntityID != "" {
+				if entityIDs[sp.EntityID] {
+					return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("duplicate service provider entity id %q", sp.EntityID))
+				}
+				entityIDs[sp.EntityID] = true
+			}
+		}
 	case "":
 		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty driver name")
 	default:
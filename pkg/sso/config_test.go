@@ -55,6 +55,173 @@ func TestNewSingleSignOnProviderConfig(t *testing.T) {
                 ]
             }`,
 		},
+		{
+			name: "test saml sso provider",
+			input: map[string]interface{}{
+				"name":             "generic",
+				"driver":           "saml",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml_idp.key",
+				"cert_path":        "../../testdata/sso/authp_saml_idp.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/generic/sso",
+				},
+				"service_providers": []map[string]interface{}{
+					{
+						"id":        "crm",
+						"entity_id": "urn:crm",
+						"acs_url":   "https://crm.example.com/saml/acs",
+						"attributes": map[string]string{
+							"Role": "roles",
+						},
+					},
+					{
+						"id":             "wiki",
+						"metadata_path":  "../../testdata/sso/sp_metadata.xml",
+						"name_id_format": "persistent",
+					},
+				},
+			},
+			want: `{
+				"name":             "generic",
+				"driver":           "saml",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml_idp.key",
+				"cert_path":        "../../testdata/sso/authp_saml_idp.crt",
+				"locations": [
+					"https://localhost/apps/sso/generic/sso"
+				],
+				"service_providers": [
+					{
+						"id":        "crm",
+						"entity_id": "urn:crm",
+						"acs_url":   "https://crm.example.com/saml/acs",
+						"attributes": {
+							"Role": "roles"
+						}
+					},
+					{
+						"id":             "wiki",
+						"metadata_path":  "../../testdata/sso/sp_metadata.xml",
+						"name_id_format": "persistent"
+					}
+				]
+			}`,
+		},
+		{
+			name: "test saml sso provider without service providers",
+			input: map[string]interface{}{
+				"name":             "generic",
+				"driver":           "saml",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml_idp.key",
+				"cert_path":        "../../testdata/sso/authp_saml_idp.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/generic/sso",
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty service providers"),
+		},
+		{
+			name: "test saml sso provider with duplicate service provider",
+			input: map[string]interface{}{
+				"name":             "generic",
+				"driver":           "saml",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml_idp.key",
+				"cert_path":        "../../testdata/sso/authp_saml_idp.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/generic/sso",
+				},
+				"service_providers": []map[string]interface{}{
+					{
+						"id":        "crm",
+						"entity_id": "urn:crm",
+						"acs_url":   "https://crm.example.com/saml/acs",
+					},
+					{
+						"id":        "crm",
+						"entity_id": "urn:crm2",
+						"acs_url":   "https://crm2.example.com/saml/acs",
+					},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", `duplicate service provider id "crm"`),
+		},
+		{
+			name: "test saml sso provider with service provider without acs url",
+			input: map[string]interface{}{
+				"name":             "generic",
+				"driver":           "saml",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml_idp.key",
+				"cert_path":        "../../testdata/sso/authp_saml_idp.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/generic/sso",
+				},
+				"service_providers": []map[string]interface{}{
+					{
+						"id":        "crm",
+						"entity_id": "urn:crm",
+					},
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", `empty service provider "crm" metadata path and acs url`),
+		},
+		{
+			name: "test aws sso provider with role catalog",
+			input: map[string]interface{}{
+				"name":             "aws",
+				"driver":           "aws",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml.key",
+				"cert_path":        "../../testdata/sso/authp_saml.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/aws",
+				},
+				"role_catalog": map[string]interface{}{
+					"source":           "organizations",
+					"role_names":       []string{"Administrator", "ReadOnly"},
+					"refresh_interval": 900,
+				},
+			},
+			want: `{
+				"name":             "aws",
+				"driver":           "aws",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml.key",
+				"cert_path":        "../../testdata/sso/authp_saml.crt",
+				"locations": [
+					"https://localhost/apps/sso/aws"
+				],
+				"role_catalog": {
+					"source":           "organizations",
+					"role_names":       ["Administrator", "ReadOnly"],
+					"refresh_interval": 900
+				}
+			}`,
+		},
+		{
+			name: "test aws sso provider with role catalog without path",
+			input: map[string]interface{}{
+				"name":             "aws",
+				"driver":           "aws",
+				"entity_id":        "caddy-authp-idp",
+				"private_key_path": "../../testdata/sso/authp_saml.key",
+				"cert_path":        "../../testdata/sso/authp_saml.crt",
+				"locations": []string{
+					"https://localhost/apps/sso/aws",
+				},
+				"role_catalog": map[string]interface{}{
+					"source": "file",
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty role catalog path"),
+		},
 		{
 			name:      "test empty sso provider parameters",
 			input:     nil,
@@ -0,0 +1,332 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/crewjam/saml"
+	samllog "github.com/crewjam/saml/logger"
+	"github.com/google/uuid"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+)
+
+const (
+	rsaSHA256SignatureMethod   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	ecdsaSHA256SignatureMethod = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	attrNameFormatBasic        = "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"
+)
+
+// AuthnRequest represents an authentication request of a service provider.
+// It is either received from the service provider, or initiated by the
+// portal.
+type AuthnRequest struct {
+	ServiceProviderID string `json:"service_provider_id,omitempty" xml:"service_provider_id,omitempty" yaml:"service_provider_id,omitempty"`
+	EntityID          string `json:"entity_id,omitempty" xml:"entity_id,omitempty" yaml:"entity_id,omitempty"`
+	RelayState        string `json:"relay_state,omitempty" xml:"relay_state,omitempty" yaml:"relay_state,omitempty"`
+	IdpInitiated      bool   `json:"idp_initiated,omitempty" xml:"idp_initiated,omitempty" yaml:"idp_initiated,omitempty"`
+	sp                *serviceProvider
+	request           *saml.IdpAuthnRequest
+}
+
+type serviceProvider struct {
+	config     *ServiceProviderConfig
+	descriptor *saml.EntityDescriptor
+}
+
+// Permits returns true when a user with the provided roles may access the
+// service provider.
+func (cfg *ServiceProviderConfig) Permits(roles []string) bool {
+	if len(cfg.AllowedRoles) == 0 {
+		return true
+	}
+	for _, allowedRole := range cfg.AllowedRoles {
+		for _, role := range roles {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configureIdentityProvider configures the SAML identity provider serving the
+// service providers.
+func (p *Provider) configureIdentityProvider() error {
+	signer, ok := p.privateKey.(crypto.Signer)
+	if !ok {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("private key error", "unsupported private key type")
+	}
+	signatureMethod := rsaSHA256SignatureMethod
+	if _, ok := signer.(*ecdsa.PrivateKey); ok {
+		signatureMethod = ecdsaSHA256SignatureMethod
+	}
+
+	ssoURL, err := url.Parse(p.config.Locations[0])
+	if err != nil {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("location error", err)
+	}
+	// The issuer of the assertions is the entity ID of the identity provider.
+	metadataURL, err := url.Parse(p.config.EntityID)
+	if err != nil || metadataURL.String() != p.config.EntityID {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "malformed entity id")
+	}
+
+	for _, cfg := range p.config.ServiceProviders {
+		descriptor, err := loadServiceProvider(cfg)
+		if err != nil {
+			return err
+		}
+		p.serviceProviders = append(p.serviceProviders, &serviceProvider{
+			config:     cfg,
+			descriptor: descriptor,
+		})
+	}
+
+	p.idp = &saml.IdentityProvider{
+		Key:                     signer,
+		Certificate:             p.cert,
+		Logger:                  samllog.DefaultLogger,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: p,
+		SignatureMethod:         signatureMethod,
+	}
+	return nil
+}
+
+// GetServiceProviders returns the configurations of the service providers.
+func (p *Provider) GetServiceProviders() []*ServiceProviderConfig {
+	return p.config.ServiceProviders
+}
+
+// GetServiceProvider returns the metadata of the service provider with the
+// provided entity ID. It implements saml.ServiceProviderProvider interface.
+func (p *Provider) GetServiceProvider(r *http.Request, entityID string) (*saml.EntityDescriptor, error) {
+	for _, sp := range p.serviceProviders {
+		if sp.descriptor.EntityID == entityID {
+			return sp.descriptor, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// ParseAuthnRequest parses the authentication request received from a
+// service provider with either HTTP-Redirect or HTTP-POST binding.
+func (p *Provider) ParseAuthnRequest(r *http.Request) (*AuthnRequest, error) {
+	if p.idp == nil {
+		return nil, errors.ErrSingleSignOnProviderRequestUnsupported.WithArgs(p.config.Name, "saml")
+	}
+	idp := *p.idp
+	req, err := saml.NewIdpAuthnRequest(&idp, r)
+	if err != nil {
+		return nil, errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+	}
+
+	// The destination of the request must match the location where the
+	// request was received.
+	msg := &saml.AuthnRequest{}
+	if err := xml.Unmarshal(req.RequestBuffer, msg); err != nil {
+		return nil, errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+	}
+	for _, location := range p.config.Locations {
+		if location != msg.Destination {
+			continue
+		}
+		ssoURL, err := url.Parse(location)
+		if err != nil {
+			return nil, errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+		}
+		idp.SSOURL = *ssoURL
+		break
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+	}
+
+	for _, sp := range p.serviceProviders {
+		if sp.descriptor.EntityID == req.ServiceProviderMetadata.EntityID {
+			return &AuthnRequest{
+				ServiceProviderID: sp.config.ID,
+				EntityID:          sp.descriptor.EntityID,
+				RelayState:        req.RelayState,
+				sp:                sp,
+				request:           req,
+			}, nil
+		}
+	}
+	return nil, errors.ErrSingleSignOnProviderServiceProviderNotFound.WithArgs(req.ServiceProviderMetadata.EntityID)
+}
+
+// NewAuthnRequest returns the authentication request of the IdP-initiated
+// login to the service provider with the provided ID.
+func (p *Provider) NewAuthnRequest(r *http.Request, serviceProviderID string) (*AuthnRequest, error) {
+	if p.idp == nil {
+		return nil, errors.ErrSingleSignOnProviderRequestUnsupported.WithArgs(p.config.Name, "saml")
+	}
+	for _, sp := range p.serviceProviders {
+		if sp.config.ID != serviceProviderID {
+			continue
+		}
+		idp := *p.idp
+		req := &saml.IdpAuthnRequest{
+			IDP:                     &idp,
+			HTTPRequest:             r,
+			RelayState:              sp.config.RelayState,
+			Now:                     saml.TimeNow(),
+			ServiceProviderMetadata: sp.descriptor,
+		}
+		for i := range sp.descriptor.SPSSODescriptors {
+			for j := range sp.descriptor.SPSSODescriptors[i].AssertionConsumerServices {
+				endpoint := sp.descriptor.SPSSODescriptors[i].AssertionConsumerServices[j]
+				if endpoint.Binding != saml.HTTPPostBinding {
+					continue
+				}
+				req.SPSSODescriptor = &sp.descriptor.SPSSODescriptors[i]
+				req.ACSEndpoint = &endpoint
+				break
+			}
+			if req.ACSEndpoint != nil {
+				break
+			}
+		}
+		if req.ACSEndpoint == nil {
+			return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(sp.config.ID, "no assertion consumer service with HTTP-POST binding")
+		}
+		return &AuthnRequest{
+			ServiceProviderID: sp.config.ID,
+			EntityID:          sp.descriptor.EntityID,
+			RelayState:        req.RelayState,
+			IdpInitiated:      true,
+			sp:                sp,
+			request:           req,
+		}, nil
+	}
+	return nil, errors.ErrSingleSignOnProviderServiceProviderNotFound.WithArgs(serviceProviderID)
+}
+
+// RespondAuthnRequest issues a signed assertion for the user and responds to
+// the authentication request by posting the assertion to the assertion
+// consumer service of the service provider.
+func (p *Provider) RespondAuthnRequest(w http.ResponseWriter, req *AuthnRequest, usr *user.User) error {
+	if !req.sp.config.Permits(usr.Claims.Roles) {
+		return errors.ErrSingleSignOnProviderServiceProviderDenied.WithArgs(usr.Claims.Subject, req.ServiceProviderID)
+	}
+	session := newSession(req.sp.config, usr)
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req.request, session); err != nil {
+		return errors.ErrSingleSignOnProviderAssertionFailed.WithArgs(req.ServiceProviderID, err)
+	}
+	if err := req.request.WriteResponse(w); err != nil {
+		return errors.ErrSingleSignOnProviderAssertionFailed.WithArgs(req.ServiceProviderID, err)
+	}
+	return nil
+}
+
+// newSession returns the session the assertion is made of. The attribute
+// statements of the assertion carry the claims of the user, transformed
+// for the service provider when it has the claim transform.
+func newSession(cfg *ServiceProviderConfig, usr *user.User) *saml.Session {
+	claims := usr.AsMap()
+	if cfg.ClaimTransform != nil {
+		claims = cfg.ClaimTransform.Apply(claims)
+	}
+	now := saml.TimeNow()
+	session := &saml.Session{
+		ID:             uuid.New().String(),
+		CreateTime:     now,
+		ExpireTime:     now.Add(time.Hour),
+		Index:          uuid.New().String(),
+		NameIDFormat:   string(nameIDFormats[cfg.NameIDFormat]),
+		Groups:         getClaimValues(claims["roles"]),
+		UserName:       usr.Claims.Subject,
+		UserEmail:      getClaimValue(claims["email"]),
+		UserCommonName: getClaimValue(claims["name"]),
+	}
+	if usr.Claims.ExpiresAt > 0 {
+		session.ExpireTime = time.Unix(usr.Claims.ExpiresAt, 0).UTC()
+	}
+
+	switch session.NameIDFormat {
+	case string(saml.EmailAddressNameIDFormat):
+		session.NameID = usr.Claims.Email
+	default:
+		session.NameID = usr.Claims.Subject
+	}
+	if session.NameID == "" {
+		session.NameID = usr.Claims.Subject
+	}
+
+	var attrNames []string
+	for attrName := range cfg.Attributes {
+		attrNames = append(attrNames, attrName)
+	}
+	sort.Strings(attrNames)
+
+	for _, attrName := range attrNames {
+		values := getClaimValues(claims[cfg.Attributes[attrName]])
+		if len(values) == 0 {
+			continue
+		}
+		attr := saml.Attribute{
+			Name:       attrName,
+			NameFormat: attrNameFormatBasic,
+		}
+		for _, v := range values {
+			attr.Values = append(attr.Values, saml.AttributeValue{
+				Type:  "xs:string",
+				Value: v,
+			})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, attr)
+	}
+	return session
+}
+
+func getClaimValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func getClaimValues(v interface{}) []string {
+	var values []string
+	switch val := v.(type) {
+	case string:
+		if val != "" {
+			values = append(values, val)
+		}
+	case []string:
+		values = append(values, val...)
+	case []interface{}:
+		for _, entry := range val {
+			values = append(values, fmt.Sprint(entry))
+		}
+	case nil:
+	default:
+		values = append(values, fmt.Sprint(val))
+	}
+	return values
+}
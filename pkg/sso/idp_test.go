@@ -0,0 +1,291 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+var samlResponsePattern = regexp.MustCompile(`name="SAMLResponse" value="([^"]+)"`)
+
+func newTestAuthnRequest(issuer, acsURL string) string {
+	return fmt.Sprintf(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" `+
+		`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="id-4f9a1c" Version="2.0" IssueInstant="%s" `+
+		`Destination="https://localhost/apps/sso/generic/sso" AssertionConsumerServiceURL="%s" `+
+		`ProtocolBinding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST">`+
+		`<saml:Issuer>%s</saml:Issuer></samlp:AuthnRequest>`,
+		time.Now().UTC().Format(time.RFC3339), acsURL, issuer)
+}
+
+func TestIdentityProvider(t *testing.T) {
+	cfg := &SingleSignOnProviderConfig{
+		Name:           "generic",
+		Driver:         "saml",
+		EntityID:       "caddy-authp-idp",
+		PrivateKeyPath: "../../testdata/sso/authp_saml_idp.key",
+		CertPath:       "../../testdata/sso/authp_saml_idp.crt",
+		Locations: []string{
+			"https://localhost/apps/sso/generic/sso",
+		},
+		ServiceProviders: []*ServiceProviderConfig{
+			{
+				ID:       "crm",
+				Name:     "CRM",
+				EntityID: "urn:crm",
+				ACSURL:   "https://crm.example.com/saml/acs",
+				Attributes: map[string]string{
+					"Role":  "roles",
+					"Email": "email",
+				},
+			},
+			{
+				ID:           "wiki",
+				MetadataPath: "../../testdata/sso/sp_metadata.xml",
+				NameIDFormat: "persistent",
+				AllowedRoles: []string{"authp/admin"},
+			},
+			{
+				ID:       "hr",
+				Name:     "HR",
+				EntityID: "urn:hr",
+				ACSURL:   "https://hr.example.com/saml/acs",
+				Attributes: map[string]string{
+					"Role":  "roles",
+					"Email": "email",
+				},
+				ClaimTransform: &transformer.AppConfig{
+					RoleMappings: map[string]string{
+						"authp/admin": "administrator",
+					},
+					DropUnmappedRoles: true,
+					Claims:            []string{"roles"},
+				},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name          string
+		method        string
+		issuer        string
+		acsURL        string
+		initiate      string
+		roles         []string
+		want          map[string]interface{}
+		shouldErr     bool
+		err           error
+		shouldRespErr bool
+		respErr       error
+	}{
+		{
+			name:   "test service provider request with redirect binding",
+			method: http.MethodGet,
+			issuer: "urn:crm",
+			acsURL: "https://crm.example.com/saml/acs",
+			roles:  []string{"authp/user", "authp/viewer"},
+			want: map[string]interface{}{
+				"service_provider_id": "crm",
+				"name_id":             "jsmith@localhost.localdomain",
+				"attributes": map[string][]string{
+					"Email": {"jsmith@localhost.localdomain"},
+					"Role":  {"authp/user", "authp/viewer"},
+				},
+			},
+		},
+		{
+			name:   "test service provider request with post binding",
+			method: http.MethodPost,
+			issuer: "urn:wiki",
+			acsURL: "https://wiki.example.com/saml/acs",
+			roles:  []string{"authp/admin"},
+			want: map[string]interface{}{
+				"service_provider_id": "wiki",
+				"name_id":             "jsmith",
+				"attributes":          map[string][]string{},
+			},
+		},
+		{
+			name:     "test identity provider initiated login",
+			initiate: "crm",
+			roles:    []string{"authp/user"},
+			want: map[string]interface{}{
+				"service_provider_id": "crm",
+				"name_id":             "jsmith@localhost.localdomain",
+				"attributes": map[string][]string{
+					"Email": {"jsmith@localhost.localdomain"},
+					"Role":  {"authp/user"},
+				},
+			},
+		},
+		{
+			name:     "test identity provider initiated login with claim transform",
+			initiate: "hr",
+			roles:    []string{"authp/admin", "authp/user"},
+			want: map[string]interface{}{
+				"service_provider_id": "hr",
+				"name_id":             "jsmith@localhost.localdomain",
+				"attributes": map[string][]string{
+					"Role": {"administrator"},
+				},
+			},
+		},
+		{
+			name:      "test identity provider initiated login to unknown service provider",
+			initiate:  "foo",
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderServiceProviderNotFound.WithArgs("foo"),
+		},
+		{
+			name:      "test service provider request from unknown service provider",
+			method:    http.MethodGet,
+			issuer:    "urn:foo",
+			acsURL:    "https://foo.example.com/saml/acs",
+			shouldErr: true,
+			err: errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(
+				fmt.Errorf("cannot handle request from unknown service provider urn:foo"),
+			),
+		},
+		{
+			name:          "test service provider request from user without allowed roles",
+			method:        http.MethodGet,
+			issuer:        "urn:wiki",
+			acsURL:        "https://wiki.example.com/saml/acs",
+			roles:         []string{"authp/user"},
+			shouldRespErr: true,
+			respErr:       errors.ErrSingleSignOnProviderServiceProviderDenied.WithArgs("jsmith", "wiki"),
+		},
+	}
+
+	provider, err := NewSingleSignOnProvider(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatalf("failed initializing sso provider: %v", err)
+	}
+	metadata, err := provider.GetMetadata()
+	if err != nil {
+		t.Fatalf("failed getting sso provider metadata: %v", err)
+	}
+	idpMetadata := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(metadata, idpMetadata); err != nil {
+		t.Fatalf("failed parsing sso provider metadata: %v", err)
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+
+			var req *AuthnRequest
+			var err error
+			if tc.initiate != "" {
+				r := httptest.NewRequest(http.MethodGet, "https://localhost/apps/sso/generic/login/"+tc.initiate, nil)
+				req, err = provider.NewAuthnRequest(r, tc.initiate)
+			} else {
+				msg := newTestAuthnRequest(tc.issuer, tc.acsURL)
+				var r *http.Request
+				if tc.method == http.MethodPost {
+					form := url.Values{}
+					form.Set("SAMLRequest", base64.StdEncoding.EncodeToString([]byte(msg)))
+					r = httptest.NewRequest(http.MethodPost, "https://localhost/apps/sso/generic/sso", strings.NewReader(form.Encode()))
+					r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				} else {
+					var b bytes.Buffer
+					fw, _ := flate.NewWriter(&b, flate.DefaultCompression)
+					fw.Write([]byte(msg))
+					fw.Close()
+					q := url.Values{}
+					q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(b.Bytes()))
+					r = httptest.NewRequest(http.MethodGet, "https://localhost/apps/sso/generic/sso?"+q.Encode(), nil)
+				}
+				req, err = provider.ParseAuthnRequest(r)
+			}
+			if tests.EvalErrWithLog(t, err, "parse request", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			usr, err := user.NewUser(map[string]interface{}{
+				"sub":   "jsmith",
+				"email": "jsmith@localhost.localdomain",
+				"name":  "John Smith",
+				"roles": tc.roles,
+				"exp":   time.Now().Add(time.Hour).Unix(),
+			})
+			if err != nil {
+				t.Fatalf("failed creating user: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			err = provider.RespondAuthnRequest(w, req, usr)
+			if tests.EvalErrWithLog(t, err, "respond", tc.shouldRespErr, tc.respErr, msgs) {
+				return
+			}
+
+			// Validate the signed assertion as the service provider would do.
+			match := samlResponsePattern.FindStringSubmatch(w.Body.String())
+			if len(match) != 2 {
+				t.Fatalf("SAMLResponse not found in response: %s", w.Body.String())
+			}
+			decoded, err := base64.StdEncoding.DecodeString(match[1])
+			if err != nil {
+				t.Fatalf("failed decoding SAMLResponse: %v", err)
+			}
+			acsURL, _ := url.Parse(req.request.ACSEndpoint.Location)
+			sp := &saml.ServiceProvider{
+				EntityID:          req.EntityID,
+				AcsURL:            *acsURL,
+				IDPMetadata:       idpMetadata,
+				AllowIDPInitiated: tc.initiate != "",
+			}
+			assertion, err := sp.ParseXMLResponse(decoded, []string{"id-4f9a1c"})
+			if err != nil {
+				t.Fatalf("failed validating assertion: %v", err)
+			}
+
+			attributes := make(map[string][]string)
+			for _, stmt := range assertion.AttributeStatements {
+				for _, attr := range stmt.Attributes {
+					if attr.NameFormat != attrNameFormatBasic {
+						continue
+					}
+					for _, v := range attr.Values {
+						attributes[attr.Name] = append(attributes[attr.Name], v.Value)
+					}
+				}
+			}
+			got := map[string]interface{}{
+				"service_provider_id": req.ServiceProviderID,
+				"name_id":             assertion.Subject.NameID.Value,
+				"attributes":          attributes,
+			}
+			tests.EvalObjectsWithLog(t, "assertion", tc.want, got, msgs)
+		})
+	}
+}
@@ -106,6 +106,15 @@ func (p *Provider) GetMetadata() ([]byte, error) {
 			},
 		}
 		entity.IDPSSODescriptor.SingleSignOnService = append(entity.IDPSSODescriptor.SingleSignOnService, svc)
+		if p.config.Driver == "saml" {
+			svc := SingleSignOnService{
+				Service: Service{
+					Binding:  "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect",
+					Location: location,
+				},
+			}
+			entity.IDPSSODescriptor.SingleSignOnService = append(entity.IDPSSODescriptor.SingleSignOnService, svc)
+		}
 	}
 
 	var b bytes.Buffer
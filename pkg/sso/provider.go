@@ -18,7 +18,11 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"net/http"
+
+	"github.com/crewjam/saml"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
 	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
 	"go.uber.org/zap"
 )
@@ -31,6 +35,11 @@ type SingleSignOnProvider interface {
 	Configure() error
 	Configured() bool
 	GetMetadata() ([]byte, error)
+	GetServiceProviders() []*ServiceProviderConfig
+	ParseAuthnRequest(*http.Request) (*AuthnRequest, error)
+	NewAuthnRequest(*http.Request, string) (*AuthnRequest, error)
+	RespondAuthnRequest(http.ResponseWriter, *AuthnRequest, *user.User) error
+	GetAssumeRoles([]string) ([]*AssumeRole, error)
 }
 
 // Provider represents sso provider.
@@ -41,6 +50,11 @@ type Provider struct {
 	cert       *x509.Certificate
 	privateKey any
 	metadata   []byte
+	// The SAML identity provider of the "saml" driver.
+	idp              *saml.IdentityProvider
+	serviceProviders []*serviceProvider
+	// The catalog of the AWS accounts and roles of the "aws" driver.
+	catalog *roleCatalog
 }
 
 // GetName return the name associated with sso provider.
@@ -118,6 +132,16 @@ func NewSingleSignOnProvider(cfg *SingleSignOnProviderConfig, logger *zap.Logger
 		privateKey: pk,
 	}
 
+	if cfg.RoleCatalog != nil {
+		prv.catalog = newRoleCatalog(cfg.Name, cfg.RoleCatalog, logger)
+	}
+
+	if cfg.Driver == "saml" {
+		if err := prv.configureIdentityProvider(); err != nil {
+			return nil, err
+		}
+	}
+
 	p = prv
 
 	return p, nil
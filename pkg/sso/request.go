@@ -15,7 +15,11 @@
 package sso
 
 import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/greenpau/go-authcrunch/pkg/errors"
@@ -37,6 +41,11 @@ const (
 	AssumeRoleRequest
 	// MenuRequest represents role selection request type.
 	MenuRequest
+	// SingleSignOnRequest represents the authentication request of a
+	// service provider.
+	SingleSignOnRequest
+	// LoginRequest represents IdP-initiated login request type.
+	LoginRequest
 )
 
 // Request represents sso provider request.
@@ -83,5 +92,47 @@ func ParseRequestURL(r *http.Request) (*Request, error) {
 		return req, nil
 	}
 
+	if parts[1] == "sso" {
+		req.Kind = SingleSignOnRequest
+		return req, nil
+	}
+
+	if strings.HasPrefix(parts[1], "login/") {
+		params := strings.TrimPrefix(parts[1], "login/")
+		if params == "" || strings.Contains(params, "/") {
+			return nil, errors.ErrSingleSignOnProviderRequestMalformed
+		}
+		req.Params = params
+		req.Kind = LoginRequest
+		return req, nil
+	}
+
 	return nil, errors.ErrSingleSignOnProviderRequestMalformed
 }
+
+// GetRedirectBindingURI returns the URI of the authentication request with
+// HTTP-Redirect binding. The request received with HTTP-POST binding is
+// converted, so that it survives the redirect to the login page.
+func GetRedirectBindingURI(r *http.Request) (string, error) {
+	if r.Method != http.MethodPost {
+		return r.URL.RequestURI(), nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+	}
+	msg, err := base64.StdEncoding.DecodeString(r.PostForm.Get("SAMLRequest"))
+	if err != nil {
+		return "", errors.ErrSingleSignOnProviderRequestInvalid.WithArgs(err)
+	}
+	var b bytes.Buffer
+	fw, _ := flate.NewWriter(&b, flate.DefaultCompression)
+	fw.Write(msg)
+	fw.Close()
+
+	q := url.Values{}
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(b.Bytes()))
+	if relayState := r.PostForm.Get("RelayState"); relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	return r.URL.Path + "?" + q.Encode(), nil
+}
@@ -82,6 +82,44 @@ func TestParseRequestURL(t *testing.T) {
 			shouldErr: true,
 			err:       errors.ErrSingleSignOnProviderRequestMalformed,
 		},
+		{
+			name: "test service provider authentication request",
+			input: &http.Request{
+				Method: "POST",
+				URL: &url.URL{
+					Path: "/apps/sso/generic/sso",
+				},
+			},
+			want: &Request{
+				ProviderName: "generic",
+				Kind:         SingleSignOnRequest,
+			},
+		},
+		{
+			name: "test identity provider initiated login request",
+			input: &http.Request{
+				Method: "GET",
+				URL: &url.URL{
+					Path: "/apps/sso/generic/login/crm",
+				},
+			},
+			want: &Request{
+				ProviderName: "generic",
+				Kind:         LoginRequest,
+				Params:       "crm",
+			},
+		},
+		{
+			name: "test malformed login request",
+			input: &http.Request{
+				Method: "GET",
+				URL: &url.URL{
+					Path: "/apps/sso/generic/login/crm/foo",
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrSingleSignOnProviderRequestMalformed,
+		},
 		{
 			name: "test malformed request",
 			input: &http.Request{
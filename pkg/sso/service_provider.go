@@ -0,0 +1,133 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sso
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+
+	"github.com/crewjam/saml"
+	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+)
+
+var nameIDFormats = map[string]saml.NameIDFormat{
+	"":            saml.EmailAddressNameIDFormat,
+	"email":       saml.EmailAddressNameIDFormat,
+	"persistent":  saml.PersistentNameIDFormat,
+	"transient":   saml.TransientNameIDFormat,
+	"unspecified": saml.UnspecifiedNameIDFormat,
+}
+
+// ServiceProviderConfig represents the configuration of a SAML service
+// provider.
+type ServiceProviderConfig struct {
+	// ID is the short name of the service provider used in the portal URLs.
+	ID   string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// EntityID is the entity ID of the service provider. It is optional when
+	// the metadata of the service provider is available.
+	EntityID string `json:"entity_id,omitempty" xml:"entity_id,omitempty" yaml:"entity_id,omitempty"`
+	// MetadataPath is the path to the metadata of the service provider.
+	MetadataPath string `json:"metadata_path,omitempty" xml:"metadata_path,omitempty" yaml:"metadata_path,omitempty"`
+	// ACSURL is the URL of the assertion consumer service of the service
+	// provider. It is used when the metadata is not available.
+	ACSURL string `json:"acs_url,omitempty" xml:"acs_url,omitempty" yaml:"acs_url,omitempty"`
+	// NameIDFormat is the format of the subject name identifier, i.e. email,
+	// persistent, transient, or unspecified. The email address of a user is
+	// the identifier of the email format, otherwise it is the subject of the
+	// user.
+	NameIDFormat string `json:"name_id_format,omitempty" xml:"name_id_format,omitempty" yaml:"name_id_format,omitempty"`
+	// Attributes maps the names of the assertion attributes to the names of
+	// the user claims, e.g. "Role" to "roles".
+	Attributes map[string]string `json:"attributes,omitempty" xml:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// AllowedRoles are the roles permitted to access the service provider.
+	// When empty, any user may access it.
+	AllowedRoles []string `json:"allowed_roles,omitempty" xml:"allowed_roles,omitempty" yaml:"allowed_roles,omitempty"`
+	// RelayState is the relay state of the IdP-initiated logins.
+	RelayState string `json:"relay_state,omitempty" xml:"relay_state,omitempty" yaml:"relay_state,omitempty"`
+	// ClaimTransform holds the transformation of the claims in the
+	// assertions issued for the service provider, e.g. the mapping of the
+	// roles.
+	ClaimTransform *transformer.AppConfig `json:"claim_transform,omitempty" xml:"claim_transform,omitempty" yaml:"claim_transform,omitempty"`
+}
+
+// Validate validates service provider config.
+func (cfg *ServiceProviderConfig) Validate() error {
+	if cfg.ID == "" {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", "empty service provider id")
+	}
+	if cfg.MetadataPath == "" {
+		if cfg.EntityID == "" {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("empty service provider %q entity id", cfg.ID))
+		}
+		if cfg.ACSURL == "" {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("empty service provider %q metadata path and acs url", cfg.ID))
+		}
+		if u, err := url.Parse(cfg.ACSURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("malformed service provider %q acs url", cfg.ID))
+		}
+	}
+	if _, exists := nameIDFormats[cfg.NameIDFormat]; !exists {
+		return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("unsupported service provider %q name id format %q", cfg.ID, cfg.NameIDFormat))
+	}
+	if cfg.ClaimTransform != nil {
+		if err := cfg.ClaimTransform.Validate(); err != nil {
+			return errors.ErrSingleSignOnProviderConfigInvalid.WithArgs("misconfiguration", fmt.Sprintf("service provider %q: %v", cfg.ID, err))
+		}
+	}
+	return nil
+}
+
+// loadServiceProvider returns the metadata of a service provider.
+func loadServiceProvider(cfg *ServiceProviderConfig) (*saml.EntityDescriptor, error) {
+	if cfg.MetadataPath == "" {
+		return &saml.EntityDescriptor{
+			EntityID: cfg.EntityID,
+			SPSSODescriptors: []saml.SPSSODescriptor{
+				{
+					AssertionConsumerServices: []saml.IndexedEndpoint{
+						{
+							Binding:  saml.HTTPPostBinding,
+							Location: cfg.ACSURL,
+							Index:    1,
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	b, err := fileutil.ReadFileBytes(cfg.MetadataPath)
+	if err != nil {
+		return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(cfg.ID, err)
+	}
+	entity := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(b, entity); err != nil {
+		return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(cfg.ID, err)
+	}
+	if entity.EntityID == "" {
+		return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(cfg.ID, "empty entity id")
+	}
+	if cfg.EntityID != "" && cfg.EntityID != entity.EntityID {
+		return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(cfg.ID, fmt.Sprintf("entity id %q does not match %q", entity.EntityID, cfg.EntityID))
+	}
+	if len(entity.SPSSODescriptors) < 1 {
+		return nil, errors.ErrSingleSignOnProviderServiceProviderMetadata.WithArgs(cfg.ID, "no service provider descriptors")
+	}
+	return entity, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+type memoryEntry struct {
+	claims    map[string]interface{}
+	expiresAt time.Time
+}
+
+// memoryStore holds the tokens in process memory. The tokens do not survive
+// restarts and are not shared between instances.
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*memoryEntry
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newMemoryStore(interval time.Duration) *memoryStore {
+	s := &memoryStore{
+		entries: make(map[string]*memoryEntry),
+		done:    make(chan struct{}),
+	}
+	go s.manage(interval)
+	return s
+}
+
+func (s *memoryStore) manage(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			s.mu.Lock()
+			for k, entry := range s.entries {
+				if now.After(entry.expiresAt) {
+					delete(s.entries, k)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Add stores the claims of the token until the expiry time.
+func (s *memoryStore) Add(token string, claims map[string]interface{}, expiresAt time.Time) error {
+	if token == "" {
+		return errors.ErrTokenStoreTokenEmpty
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[getTokenKey(token)] = &memoryEntry{claims: claims, expiresAt: expiresAt}
+	return nil
+}
+
+// Get returns the claims of the token.
+func (s *memoryStore) Get(token string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	entry, exists := s.entries[getTokenKey(token)]
+	s.mu.RUnlock()
+	if !exists || time.Now().After(entry.expiresAt) {
+		return nil, errors.ErrTokenStoreTokenNotFound
+	}
+	claims := make(map[string]interface{}, len(entry.claims))
+	for k, v := range entry.claims {
+		claims[k] = v
+	}
+	return claims, nil
+}
+
+// Delete removes the token.
+func (s *memoryStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, getTokenKey(token))
+	return nil
+}
+
+// Close stops the removal of expired tokens.
+func (s *memoryStore) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
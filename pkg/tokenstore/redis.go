@@ -0,0 +1,212 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	redisKeyPrefix   = "authp:token:"
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 5 * time.Second
+)
+
+// redisStore holds the tokens in Redis. It speaks the subset of RESP
+// protocol necessary to store, fetch and delete the tokens over a single
+// connection, re-established on failure.
+type redisStore struct {
+	mu   sync.Mutex
+	cfg  *Config
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func newRedisStore(cfg *Config) (*redisStore, error) {
+	s := &redisStore{cfg: cfg}
+	if err := s.connect(); err != nil {
+		return nil, errors.ErrTokenStoreBackend.WithArgs(cfg.Name, err)
+	}
+	return s, nil
+}
+
+func (s *redisStore) connect() error {
+	conn, err := net.DialTimeout("tcp", s.cfg.Address, redisDialTimeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.rd = bufio.NewReader(conn)
+	if s.cfg.Password != "" {
+		password, err := credentials.ResolveSecret(s.cfg.Password)
+		if err != nil {
+			s.disconnect()
+			return err
+		}
+		if _, err := s.do("AUTH", password); err != nil {
+			s.disconnect()
+			return err
+		}
+	}
+	if s.cfg.Database > 0 {
+		if _, err := s.do("SELECT", strconv.Itoa(s.cfg.Database)); err != nil {
+			s.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *redisStore) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn = nil
+	s.rd = nil
+}
+
+// exec runs a command, reconnecting once if the connection is broken.
+func (s *redisStore) exec(args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if err := s.connect(); err != nil {
+				return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+			}
+		}
+		resp, err := s.do(args...)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := err.(redisError); ok {
+			return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+		}
+		s.disconnect()
+		if i > 0 {
+			return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+		}
+	}
+	return nil, nil
+}
+
+func (s *redisStore) do(args ...string) (interface{}, error) {
+	s.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	buf := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+	if _, err := s.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRedisReply(s.rd)
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+func readRedisReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	}
+	return nil, fmt.Errorf("unsupported reply %q", line)
+}
+
+// Add stores the claims of the token until the expiry time.
+func (s *redisStore) Add(token string, claims map[string]interface{}, expiresAt time.Time) error {
+	if token == "" {
+		return errors.ErrTokenStoreTokenEmpty
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	ttl := int(time.Until(expiresAt).Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+	_, err = s.exec("SET", redisKeyPrefix+getTokenKey(token), string(b), "EX", strconv.Itoa(ttl))
+	return err
+}
+
+// Get returns the claims of the token.
+func (s *redisStore) Get(token string) (map[string]interface{}, error) {
+	resp, err := s.exec("GET", redisKeyPrefix+getTokenKey(token))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := resp.(string)
+	if !ok {
+		return nil, errors.ErrTokenStoreTokenNotFound
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &claims); err != nil {
+		return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	return claims, nil
+}
+
+// Delete removes the token.
+func (s *redisStore) Delete(token string) error {
+	_, err := s.exec("DEL", redisKeyPrefix+getTokenKey(token))
+	return err
+}
+
+// Close closes the connection to Redis.
+func (s *redisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnect()
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Store)
+)
+
+// Register adds Store to the registry of token stores. The store previously
+// registered under the same name is closed.
+func Register(name string, store Store) {
+	registryMu.Lock()
+	prev, exists := registry[name]
+	registry[name] = store
+	registryMu.Unlock()
+	if exists && prev != store {
+		prev.Close()
+	}
+}
+
+// Get returns Store registered under the provided name.
+func Get(name string) (Store, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	store, exists := registry[name]
+	if !exists {
+		return nil, errors.ErrTokenStoreNotFound.WithArgs(name)
+	}
+	return store, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/credentials"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// sqlStore holds the tokens in a SQL database. The driver is looked up in
+// database/sql registry, i.e. the binary must import it.
+type sqlStore struct {
+	cfg        *Config
+	db         *sql.DB
+	insertStmt string
+	selectStmt string
+	deleteStmt string
+	purgeStmt  string
+}
+
+func newSQLStore(cfg *Config) (*sqlStore, error) {
+	dsn, err := credentials.ResolveSecret(cfg.DSN)
+	if err != nil {
+		return nil, errors.ErrTokenStoreBackend.WithArgs(cfg.Name, err)
+	}
+	db, err := sql.Open(cfg.Driver, dsn)
+	if err != nil {
+		return nil, errors.ErrTokenStoreBackend.WithArgs(cfg.Name, err)
+	}
+
+	table := cfg.TableName
+	if table == "" {
+		table = defaultTableName
+	}
+	p := func(i int) string {
+		switch cfg.Driver {
+		case "postgres", "pgx":
+			return fmt.Sprintf("$%d", i)
+		}
+		return "?"
+	}
+
+	s := &sqlStore{
+		cfg:        cfg,
+		db:         db,
+		insertStmt: fmt.Sprintf("INSERT INTO %s (id, claims, expires_at) VALUES (%s, %s, %s)", table, p(1), p(2), p(3)),
+		selectStmt: fmt.Sprintf("SELECT claims, expires_at FROM %s WHERE id = %s", table, p(1)),
+		deleteStmt: fmt.Sprintf("DELETE FROM %s WHERE id = %s", table, p(1)),
+		purgeStmt:  fmt.Sprintf("DELETE FROM %s WHERE expires_at < %s", table, p(1)),
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id VARCHAR(64) PRIMARY KEY, claims TEXT NOT NULL, expires_at BIGINT NOT NULL)", table,
+	)); err != nil {
+		db.Close()
+		return nil, errors.ErrTokenStoreBackend.WithArgs(cfg.Name, err)
+	}
+	return s, nil
+}
+
+// Add stores the claims of the token until the expiry time. The expired
+// tokens are purged on insert.
+func (s *sqlStore) Add(token string, claims map[string]interface{}, expiresAt time.Time) error {
+	if token == "" {
+		return errors.ErrTokenStoreTokenEmpty
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	if _, err := s.db.Exec(s.purgeStmt, time.Now().Unix()); err != nil {
+		return errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	if _, err := s.db.Exec(s.insertStmt, getTokenKey(token), string(b), expiresAt.Unix()); err != nil {
+		return errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	return nil
+}
+
+// Get returns the claims of the token.
+func (s *sqlStore) Get(token string) (map[string]interface{}, error) {
+	var data string
+	var expiresAt int64
+	err := s.db.QueryRow(s.selectStmt, getTokenKey(token)).Scan(&data, &expiresAt)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.ErrTokenStoreTokenNotFound
+	case err != nil:
+		return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, errors.ErrTokenStoreTokenNotFound
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &claims); err != nil {
+		return nil, errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	return claims, nil
+}
+
+// Delete removes the token.
+func (s *sqlStore) Delete(token string) error {
+	if _, err := s.db.Exec(s.deleteStmt, getTokenKey(token)); err != nil {
+		return errors.ErrTokenStoreBackend.WithArgs(s.cfg.Name, err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
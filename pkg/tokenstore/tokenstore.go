@@ -0,0 +1,123 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const (
+	defaultTableName       = "authp_tokens"
+	defaultCleanupInterval = 60
+)
+
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config holds the configuration of token Store.
+type Config struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Kind is either memory, redis, or sql.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// Address is the host and port of Redis server.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// Password is the password of Redis server. It may be a Vault reference.
+	Password string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	// Database is the number of Redis database.
+	Database int `json:"database,omitempty" xml:"database,omitempty" yaml:"database,omitempty"`
+	// Driver is the name of the registered database/sql driver, e.g.
+	// postgres or mysql. The driver must be linked into the binary.
+	Driver string `json:"driver,omitempty" xml:"driver,omitempty" yaml:"driver,omitempty"`
+	// DSN is the data source name of SQL database.
+	DSN string `json:"dsn,omitempty" xml:"dsn,omitempty" yaml:"dsn,omitempty"`
+	// TableName is the name of SQL table holding the tokens. Defaults to
+	// authp_tokens.
+	TableName string `json:"table_name,omitempty" xml:"table_name,omitempty" yaml:"table_name,omitempty"`
+}
+
+// Store holds the claims of opaque tokens. The tokens are not stored as
+// is, the stores key the entries by SHA-256 digest of a token.
+type Store interface {
+	// Add stores the claims of the token until the expiry time.
+	Add(token string, claims map[string]interface{}, expiresAt time.Time) error
+	// Get returns the claims of the token.
+	Get(token string) (map[string]interface{}, error)
+	// Delete removes the token, i.e. revokes it.
+	Delete(token string) error
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return errors.ErrTokenStoreConfigNameEmpty
+	}
+	switch cfg.Kind {
+	case "memory":
+	case "redis":
+		if cfg.Address == "" {
+			return errors.ErrTokenStoreConfigAddressEmpty.WithArgs(cfg.Name)
+		}
+	case "sql":
+		if cfg.Driver == "" {
+			return errors.ErrTokenStoreConfigDriverEmpty.WithArgs(cfg.Name)
+		}
+		if cfg.DSN == "" {
+			return errors.ErrTokenStoreConfigDSNEmpty.WithArgs(cfg.Name)
+		}
+		if cfg.TableName != "" && !tableNameRegex.MatchString(cfg.TableName) {
+			return errors.ErrTokenStoreConfigTableInvalid.WithArgs(cfg.Name, cfg.TableName)
+		}
+	default:
+		return errors.ErrTokenStoreConfigKindInvalid.WithArgs(cfg.Name, cfg.Kind)
+	}
+	return nil
+}
+
+// NewStore returns an instance of Store.
+func NewStore(cfg *Config) (Store, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Kind {
+	case "redis":
+		return newRedisStore(cfg)
+	case "sql":
+		return newSQLStore(cfg)
+	}
+	return newMemoryStore(defaultCleanupInterval * time.Second), nil
+}
+
+// NewToken returns a random opaque token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getTokenKey returns the key of a token in a store.
+func getTokenKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
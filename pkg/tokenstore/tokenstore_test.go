@@ -0,0 +1,260 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// fakeRedisServer implements the commands used by redisStore.
+type fakeRedisServer struct {
+	mu       sync.Mutex
+	listener net.Listener
+	password string
+	data     map[string]string
+	expiry   map[string]time.Time
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting fake redis server: %v", err)
+	}
+	srv := &fakeRedisServer{
+		listener: l,
+		password: password,
+		data:     make(map[string]string),
+		expiry:   make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return srv
+}
+
+func (srv *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authenticated := srv.password == ""
+	for {
+		args, err := readFakeRedisCommand(rd)
+		if err != nil {
+			return
+		}
+		var reply string
+		srv.mu.Lock()
+		switch {
+		case args[0] == "AUTH":
+			if args[1] == srv.password {
+				authenticated = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required\r\n"
+		case args[0] == "SELECT":
+			reply = "+OK\r\n"
+		case args[0] == "SET":
+			srv.data[args[1]] = args[2]
+			ttl, _ := strconv.Atoi(args[4])
+			srv.expiry[args[1]] = time.Now().Add(time.Duration(ttl) * time.Second)
+			reply = "+OK\r\n"
+		case args[0] == "GET":
+			v, exists := srv.data[args[1]]
+			if !exists || time.Now().After(srv.expiry[args[1]]) {
+				reply = "$-1\r\n"
+			} else {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			}
+		case args[0] == "DEL":
+			delete(srv.data, args[1])
+			reply = ":1\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		srv.mu.Unlock()
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readFakeRedisCommand(rd *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(rd, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	var args []string
+	for i := 0; i < n; i++ {
+		var size int
+		if _, err := fmt.Fscanf(rd, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, b); err != nil {
+			return nil, err
+		}
+		args = append(args, string(b[:size]))
+	}
+	return args, nil
+}
+
+func TestNewStore(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid memory store",
+			config: &Config{Name: "default", Kind: "memory"},
+		},
+		{
+			name:      "empty name",
+			config:    &Config{Kind: "memory"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigNameEmpty,
+		},
+		{
+			name:      "unsupported kind",
+			config:    &Config{Name: "default", Kind: "foo"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigKindInvalid.WithArgs("default", "foo"),
+		},
+		{
+			name:      "redis store without address",
+			config:    &Config{Name: "default", Kind: "redis"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigAddressEmpty.WithArgs("default"),
+		},
+		{
+			name:      "sql store without driver",
+			config:    &Config{Name: "default", Kind: "sql", DSN: "foo"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigDriverEmpty.WithArgs("default"),
+		},
+		{
+			name:      "sql store without dsn",
+			config:    &Config{Name: "default", Kind: "sql", Driver: "postgres"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigDSNEmpty.WithArgs("default"),
+		},
+		{
+			name:      "sql store with invalid table name",
+			config:    &Config{Name: "default", Kind: "sql", Driver: "postgres", DSN: "foo", TableName: "tokens; DROP TABLE users"},
+			shouldErr: true,
+			err:       errors.ErrTokenStoreConfigTableInvalid.WithArgs("default", "tokens; DROP TABLE users"),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			store, err := NewStore(tc.config)
+			if tests.EvalErrWithLog(t, err, "token store", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			store.Close()
+		})
+	}
+}
+
+func TestStores(t *testing.T) {
+	srv := newFakeRedisServer(t, "foobar")
+
+	testcases := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "memory store",
+			config: &Config{Name: "memory", Kind: "memory"},
+		},
+		{
+			name:   "redis store",
+			config: &Config{Name: "redis", Kind: "redis", Address: srv.listener.Addr().String(), Password: "foobar", Database: 1},
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			store, err := NewStore(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer store.Close()
+
+			token, err := NewToken()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			claims := map[string]interface{}{"sub": "jsmith", "roles": []interface{}{"admin"}}
+			if err := store.Add(token, claims, time.Now().Add(time.Minute)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := store.Get(token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "claims", claims, got, msgs)
+
+			if err := store.Delete(token); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = store.Get(token)
+			tests.EvalErrWithLog(t, err, "revoked token", true, errors.ErrTokenStoreTokenNotFound, msgs)
+
+			_, err = store.Get("foo")
+			tests.EvalErrWithLog(t, err, "unknown token", true, errors.ErrTokenStoreTokenNotFound, msgs)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	store, err := NewStore(&Config{Name: "registry", Kind: "memory"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	Register("registry", store)
+	got, err := Get("registry")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != store {
+		t.Fatalf("unexpected store: %v", got)
+	}
+	_, err = Get("foo")
+	tests.EvalErrWithLog(t, err, "registry", true, errors.ErrTokenStoreNotFound.WithArgs("foo"), nil)
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceParent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+func TestTracePropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var upstreamTraceParent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceParent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	testcases := []struct {
+		name        string
+		traceParent string
+		want        map[string]interface{}
+	}{
+		{
+			name:        "test incoming request with trace context",
+			traceParent: testTraceParent,
+			want: map[string]interface{}{
+				"trace_id":          testTraceID,
+				"upstream_trace_id": testTraceID,
+			},
+		},
+		{
+			name: "test incoming request without trace context",
+			want: map[string]interface{}{
+				"trace_id":          trace.TraceID{}.String(),
+				"upstream_trace_id": "",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			upstreamTraceParent = ""
+
+			r := httptest.NewRequest("GET", "/auth/login", nil)
+			if tc.traceParent != "" {
+				r.Header.Set("traceparent", tc.traceParent)
+			}
+			ctx, span := StartServer(context.Background(), r, "test")
+			defer End(span, nil)
+
+			cli := &http.Client{Transport: NewTransport(nil)}
+			req, err := http.NewRequestWithContext(ctx, "GET", upstream.URL, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := cli.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			got := make(map[string]interface{})
+			got["trace_id"] = span.SpanContext().TraceID().String()
+			got["upstream_trace_id"] = ""
+			if len(upstreamTraceParent) > 35 {
+				got["upstream_trace_id"] = upstreamTraceParent[3:35]
+			}
+			if req.Header.Get("traceparent") != "" {
+				t.Fatalf("unexpected modification of the outgoing request headers")
+			}
+			tests.EvalObjectsWithLog(t, "trace", tc.want, got, msgs)
+		})
+	}
+}
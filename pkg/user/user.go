@@ -37,6 +37,11 @@ var reservedFields = map[string]interface{}{
 }
 */
 
+// TokenUseRelyingParty is the value of the token_use claim of the access
+// tokens issued to OpenID Connect relying parties. The tokens grant access
+// to the userinfo endpoint, but not to the portal or the gatekeepers.
+const TokenUseRelyingParty = "relying_party"
+
 // User is a user with claims and status.
 type User struct {
 	Claims          *Claims       `json:"claims,omitempty" xml:"claims,omitempty" yaml:"claims,omitempty"`
@@ -195,6 +200,12 @@ func (u *User) GetData() map[string]interface{} {
 	return u.tkv
 }
 
+// IsRelyingPartyToken returns true when the claims of the user come from an
+// access token issued to an OpenID Connect relying party.
+func (u *User) IsRelyingPartyToken() bool {
+	return u.GetClaimValueByField("token_use") == TokenUseRelyingParty
+}
+
 // SetRequestHeaders sets request headers associated with the user.
 func (u *User) SetRequestHeaders(m map[string]string) {
 	u.requestHeaders = m
@@ -261,6 +272,22 @@ func (u *User) SetExpiresAtClaim(i int64) {
 	u.mkv["exp"] = i
 }
 
+// SetAudienceClaim sets Audience claim.
+func (u *User) SetAudienceClaim(audiences []string) {
+	u.Claims.Audience = audiences
+	switch len(audiences) {
+	case 0:
+		delete(u.tkv, "aud")
+		delete(u.mkv, "aud")
+	case 1:
+		u.tkv["aud"] = audiences
+		u.mkv["aud"] = audiences[0]
+	default:
+		u.tkv["aud"] = audiences
+		u.mkv["aud"] = audiences
+	}
+}
+
 // SetIssuedAtClaim sets IssuedAt claim.
 func (u *User) SetIssuedAtClaim(i int64) {
 	u.Claims.IssuedAt = i
@@ -426,12 +453,18 @@ func NewCheckpoint(s string) (*Checkpoint, error) {
 	case "password":
 		c.Name = "Authenticate with password"
 		c.Type = "password"
-	//case "consent":
-	//	c.Name = "Acceptance and consent"
-	//	c.Type = "consent"
+	case "passkey":
+		c.Name = "Authenticate with passkey"
+		c.Type = "passkey"
+	case "consent":
+		c.Name = "Acceptance and consent"
+		c.Type = "consent"
 	default:
 		return nil, fmt.Errorf("unsupported keyword: %s", args[0])
 	}
+	if len(args) > 1 {
+		c.Parameters = cfgutil.EncodeArgs(args[1:])
+	}
 	return c, nil
 }
 
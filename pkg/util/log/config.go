@@ -0,0 +1,106 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The names of the fields redacted by default.
+var defaultRedactedFields = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"token",
+	"code",
+	"password",
+	"secret",
+	"client_secret",
+	"api_key",
+	"authorization",
+	"passcode",
+	"otp",
+	"sandbox_secret",
+}
+
+const redactedValue = "[REDACTED]"
+
+// SubsystemConfig overrides the log level for a subsystem, e.g. portal or
+// identity_provider.
+type SubsystemConfig struct {
+	Name  string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Level string `json:"level,omitempty" xml:"level,omitempty" yaml:"level,omitempty"`
+}
+
+// Config holds the configuration of the log levels and the redaction of
+// the sensitive fields.
+type Config struct {
+	// The minimum log level, i.e. debug, info, warn, or error. When empty,
+	// the level of the supplied logger applies.
+	Level string `json:"level,omitempty" xml:"level,omitempty" yaml:"level,omitempty"`
+	// The per-subsystem log levels. The subsystem is the name of the
+	// logger, including the names of its children.
+	Subsystems []*SubsystemConfig `json:"subsystems,omitempty" xml:"subsystems,omitempty" yaml:"subsystems,omitempty"`
+	// The names of the fields redacted in addition to the default ones.
+	RedactedFields []string `json:"redacted_fields,omitempty" xml:"redacted_fields,omitempty" yaml:"redacted_fields,omitempty"`
+	// Disables the redaction of the sensitive fields.
+	RedactionDisabled bool `json:"redaction_disabled,omitempty" xml:"redaction_disabled,omitempty" yaml:"redaction_disabled,omitempty"`
+}
+
+// RedactFunc is a redaction hook. It returns the replacement of the value
+// of the field and true when the field must be redacted.
+type RedactFunc func(key string, value interface{}) (interface{}, bool)
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Level != "" {
+		if _, err := parseLevel(cfg.Level); err != nil {
+			return errors.ErrLoggingConfigLevel.WithArgs(cfg.Level)
+		}
+	}
+	for _, sc := range cfg.Subsystems {
+		if sc.Name == "" {
+			return errors.ErrLoggingConfigSubsystemName
+		}
+		if _, err := parseLevel(sc.Level); err != nil || sc.Level == "" {
+			return errors.ErrLoggingConfigSubsystemLevel.WithArgs(sc.Level, sc.Name)
+		}
+	}
+	return nil
+}
+
+func parseLevel(s string) (zapcore.Level, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+		return lvl, err
+	}
+	return lvl, nil
+}
+
+// Wrap returns the logger applying the log levels and the redaction of the
+// config, and the redaction hooks. When the config is nil, the default
+// fields are redacted.
+func Wrap(logger *zap.Logger, cfg *Config, hooks ...RedactFunc) *zap.Logger {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newFilterCore(core, cfg, hooks)
+	}))
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// filterCore applies per-subsystem log levels and redacts the sensitive
+// fields prior to passing the entries to the wrapped core.
+type filterCore struct {
+	zapcore.Core
+	level      zapcore.Level
+	minLevel   zapcore.Level
+	subsystems map[string]zapcore.Level
+	redacted   map[string]bool
+	hooks      []RedactFunc
+}
+
+func newFilterCore(core zapcore.Core, cfg *Config, hooks []RedactFunc) *filterCore {
+	c := &filterCore{
+		Core:       core,
+		level:      zapcore.DebugLevel,
+		subsystems: make(map[string]zapcore.Level),
+		redacted:   make(map[string]bool),
+		hooks:      hooks,
+	}
+	if lvl, err := parseLevel(cfg.Level); err == nil && cfg.Level != "" {
+		c.level = lvl
+	}
+	c.minLevel = c.level
+	for _, sc := range cfg.Subsystems {
+		lvl, err := parseLevel(sc.Level)
+		if err != nil {
+			continue
+		}
+		c.subsystems[sc.Name] = lvl
+		if lvl < c.minLevel {
+			c.minLevel = lvl
+		}
+	}
+	if !cfg.RedactionDisabled {
+		for _, k := range defaultRedactedFields {
+			c.redacted[k] = true
+		}
+		for _, k := range cfg.RedactedFields {
+			c.redacted[strings.ToLower(k)] = true
+		}
+	}
+	return c
+}
+
+// Enabled returns true when the level is enabled for any subsystem.
+func (c *filterCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.minLevel && c.Core.Enabled(lvl)
+}
+
+// With adds structured context to the core.
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(c.redactFields(fields))
+	return &clone
+}
+
+// Check determines whether the entry is logged based on the level of the
+// subsystem of the logger.
+func (c *filterCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if e.Level < c.getLevel(e.LoggerName) || !c.Core.Enabled(e.Level) {
+		return ce
+	}
+	return ce.AddCore(e, c)
+}
+
+// Write redacts the fields and writes the entry to the wrapped core.
+func (c *filterCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(e, c.redactFields(fields))
+}
+
+// getLevel returns the level of the longest matching subsystem.
+func (c *filterCore) getLevel(name string) zapcore.Level {
+	lvl := c.level
+	var matched string
+	for subsystem, subsystemLevel := range c.subsystems {
+		if name != subsystem && !strings.HasPrefix(name, subsystem+".") {
+			continue
+		}
+		if len(subsystem) > len(matched) {
+			matched = subsystem
+			lvl = subsystemLevel
+		}
+	}
+	return lvl
+}
+
+func (c *filterCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	if len(c.redacted) == 0 && len(c.hooks) == 0 {
+		return fields
+	}
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = c.redactField(f)
+	}
+	return redacted
+}
+
+func (c *filterCore) redactField(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f
+	}
+	var value interface{}
+	if len(c.hooks) > 0 || c.redacted[strings.ToLower(f.Key)] {
+		value = getFieldValue(f)
+	}
+	if v, ok := c.redactValue(f.Key, value); ok {
+		return zap.Any(f.Key, v)
+	}
+	switch f.Type {
+	case zapcore.ReflectType:
+		switch m := f.Interface.(type) {
+		case map[string]interface{}:
+			return zap.Any(f.Key, c.redactMap(m))
+		case map[string]string:
+			return zap.Any(f.Key, c.redactStringMap(m))
+		}
+	}
+	return f
+}
+
+func (c *filterCore) redactValue(key string, value interface{}) (interface{}, bool) {
+	for _, hook := range c.hooks {
+		if v, ok := hook(key, value); ok {
+			return v, true
+		}
+	}
+	if c.redacted[strings.ToLower(key)] {
+		switch v := value.(type) {
+		case string:
+			return Mask(v), true
+		case []byte:
+			return Mask(string(v)), true
+		}
+		return redactedValue, true
+	}
+	return nil, false
+}
+
+func (c *filterCore) redactMap(m map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if rv, ok := c.redactValue(k, v); ok {
+			redacted[k] = rv
+			continue
+		}
+		switch nested := v.(type) {
+		case map[string]interface{}:
+			redacted[k] = c.redactMap(nested)
+		case map[string]string:
+			redacted[k] = c.redactStringMap(nested)
+		default:
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+func (c *filterCore) redactStringMap(m map[string]string) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if rv, ok := c.redactValue(k, v); ok {
+			redacted[k] = rv
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// getFieldValue returns the value of the field as it would be encoded.
+func getFieldValue(f zapcore.Field) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a structured log record.
+type Entry struct {
+	Time time.Time `json:"time,omitempty" xml:"time,omitempty" yaml:"time,omitempty"`
+	// The level of the record, i.e. debug, info, warn, error, dpanic,
+	// panic, or fatal.
+	Level string `json:"level,omitempty" xml:"level,omitempty" yaml:"level,omitempty"`
+	// The subsystem emitting the record, e.g. portal or identity_provider.
+	Subsystem string                 `json:"subsystem,omitempty" xml:"subsystem,omitempty" yaml:"subsystem,omitempty"`
+	Message   string                 `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty" xml:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Handler is a structured logger supplied by the embedders not using zap.
+type Handler interface {
+	Handle(entry *Entry) error
+}
+
+// NewHandlerLogger returns the logger writing the records to the handler.
+// The log levels and the redaction of the config apply.
+func NewHandlerLogger(h Handler, cfg *Config, hooks ...RedactFunc) *zap.Logger {
+	return Wrap(zap.New(&handlerCore{handler: h}), cfg, hooks...)
+}
+
+// handlerCore is zapcore.Core writing the entries to Handler.
+type handlerCore struct {
+	handler Handler
+	fields  []zapcore.Field
+}
+
+// Enabled returns true for all levels. The levels are enforced by the
+// filter core wrapping the handler core.
+func (c *handlerCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+// With adds structured context to the core.
+func (c *handlerCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &handlerCore{
+		handler: c.handler,
+		fields:  make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check adds the core to the checked entry.
+func (c *handlerCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(e, c)
+}
+
+// Write passes the entry to the handler.
+func (c *handlerCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return c.handler.Handle(&Entry{
+		Time:      e.Time,
+		Level:     e.Level.String(),
+		Subsystem: e.LoggerName,
+		Message:   e.Message,
+		Fields:    enc.Fields,
+	})
+}
+
+// Sync is a no-op.
+func (c *handlerCore) Sync() error {
+	return nil
+}
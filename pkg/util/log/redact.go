@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// The minimum length of the value masked with the prefix and hash.
+	// The shorter values, e.g. OTP codes, could be brute-forced by their
+	// hash and are replaced in full.
+	maskMinLength    = 16
+	maskPrefixLength = 4
+	maskHashLength   = 8
+)
+
+// Mask returns the prefix and the truncated SHA-256 hash of the value,
+// e.g. "eyJh...sha256:9f86d081". The hash allows correlating the same
+// secret across log entries without revealing it.
+func Mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) < maskMinLength {
+		return redactedValue
+	}
+	h := sha256.Sum256([]byte(s))
+	return s[:maskPrefixLength] + "...sha256:" + hex.EncodeToString(h[:])[:maskHashLength]
+}
+
+// MaskValue masks the sensitive values in the value. Strings are masked in
+// full, and the default sensitive keys are masked in maps.
+func MaskValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return Mask(value)
+	case []byte:
+		return Mask(string(value))
+	case map[string]interface{}:
+		return maskMap(value)
+	case map[string]string:
+		m := make(map[string]interface{}, len(value))
+		for k, s := range value {
+			m[k] = s
+		}
+		return maskMap(m)
+	case nil:
+		return nil
+	}
+	return redactedValue
+}
+
+func maskMap(m map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch v.(type) {
+		case map[string]interface{}, map[string]string:
+			masked[k] = MaskValue(v)
+			continue
+		}
+		if isDefaultRedactedField(k) {
+			masked[k] = MaskValue(v)
+			continue
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+func isDefaultRedactedField(k string) bool {
+	k = strings.ToLower(k)
+	for _, f := range defaultRedactedFields {
+		if k == f {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskedString returns the field with the masked string value. Unlike
+// the redaction of the wrapped logger, it applies regardless of whether
+// the logger passed by the embedder was wrapped.
+func MaskedString(key, value string) zap.Field {
+	return zap.String(key, Mask(value))
+}
+
+// MaskedAny returns the field with the masked value. See MaskValue.
+func MaskedAny(key string, value interface{}) zap.Field {
+	return zap.Any(key, MaskValue(value))
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// NewSlogHandler returns Handler writing the records to slog.Logger. The
+// subsystem is added as the subsystem attribute.
+func NewSlogHandler(logger *slog.Logger) Handler {
+	return &slogHandler{logger: logger}
+}
+
+type slogHandler struct {
+	logger *slog.Logger
+}
+
+// Handle writes the entry to slog.Logger.
+func (h *slogHandler) Handle(e *Entry) error {
+	var lvl slog.Level
+	switch e.Level {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "info":
+		lvl = slog.LevelInfo
+	case "warn":
+		lvl = slog.LevelWarn
+	default:
+		lvl = slog.LevelError
+	}
+	ctx := context.Background()
+	if !h.logger.Enabled(ctx, lvl) {
+		return nil
+	}
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys)+1)
+	if e.Subsystem != "" {
+		attrs = append(attrs, slog.String("subsystem", e.Subsystem))
+	}
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, e.Fields[k]))
+	}
+	h.logger.LogAttrs(ctx, lvl, e.Message, attrs...)
+	return nil
+}
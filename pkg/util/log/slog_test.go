@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	sl := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	logger := NewHandlerLogger(NewSlogHandler(sl), nil).Named("portal")
+	logger.Debug("skipped")
+	logger.Warn("token issued", zap.String("token", "foo"), zap.Int("ttl", 900))
+
+	got := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed decoding slog output %q: %v", buf.String(), err)
+	}
+	delete(got, "time")
+	want := map[string]interface{}{
+		"level":     "WARN",
+		"msg":       "token issued",
+		"subsystem": "portal",
+		"token":     "[REDACTED]",
+		"ttl":       float64(900),
+	}
+	tests.EvalObjects(t, "slog output", want, got)
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+const referencePrefix = "vault:"
+
+var (
+	defaultClientMu sync.RWMutex
+	defaultClient   *Client
+)
+
+// SetDefaultClient sets the Client used to resolve secret references. The
+// previously set Client is closed.
+func SetDefaultClient(c *Client) {
+	defaultClientMu.Lock()
+	prev := defaultClient
+	defaultClient = c
+	defaultClientMu.Unlock()
+	if prev != nil && prev != c {
+		prev.Close()
+	}
+}
+
+// GetDefaultClient returns the Client used to resolve secret references.
+func GetDefaultClient() (*Client, error) {
+	defaultClientMu.RLock()
+	defer defaultClientMu.RUnlock()
+	if defaultClient == nil {
+		return nil, errors.ErrVaultClientNotConfigured
+	}
+	return defaultClient, nil
+}
+
+// IsReference returns true if the value references a Vault secret, e.g.
+// vault:authp/ldap#password.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, referencePrefix)
+}
+
+// ParseReference returns the path and the field of a secret reference.
+func ParseReference(s string) (string, string, error) {
+	ref := strings.TrimPrefix(s, referencePrefix)
+	i := strings.LastIndex(ref, "#")
+	if i < 1 || i == len(ref)-1 {
+		return "", "", errors.ErrVaultReferenceInvalid.WithArgs(s)
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+// Resolve returns the value of the referenced Vault secret. The values not
+// referencing Vault secrets are returned as is.
+func Resolve(s string) (string, error) {
+	if !IsReference(s) {
+		return s, nil
+	}
+	path, field, err := ParseReference(s)
+	if err != nil {
+		return "", err
+	}
+	c, err := GetDefaultClient()
+	if err != nil {
+		return "", err
+	}
+	return c.ReadSecret(path, field)
+}
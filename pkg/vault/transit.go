@@ -0,0 +1,224 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+var (
+	transitKeyTypeMethods = map[string][]string{
+		"rsa-2048":   {"RS256", "RS384", "RS512"},
+		"rsa-3072":   {"RS256", "RS384", "RS512"},
+		"rsa-4096":   {"RS256", "RS384", "RS512"},
+		"ecdsa-p256": {"ES256"},
+		"ecdsa-p384": {"ES384"},
+		"ecdsa-p521": {"ES512"},
+	}
+
+	transitMethodHashes = map[string]string{
+		"RS256": "sha2-256",
+		"RS384": "sha2-384",
+		"RS512": "sha2-512",
+		"ES256": "sha2-256",
+		"ES384": "sha2-384",
+		"ES512": "sha2-512",
+	}
+)
+
+// transitKey holds the public keys of an asymmetric Transit signing key.
+type transitKey struct {
+	keyType       string
+	latestVersion int
+	publicKeys    map[int]interface{}
+	fetchedAt     time.Time
+}
+
+// GetTransitKey returns the type and the latest version of the Transit
+// signing key.
+func (c *Client) GetTransitKey(name string) (string, int, error) {
+	tk, err := c.getTransitKey(name, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	return tk.keyType, tk.latestVersion, nil
+}
+
+// GetTransitPublicKey returns the public key of the version of the Transit
+// signing key. When the version is zero, the latest version is returned.
+func (c *Client) GetTransitPublicKey(name string, version int) (interface{}, error) {
+	tk, err := c.getTransitKey(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = tk.latestVersion
+	}
+	pk, exists := tk.publicKeys[version]
+	if !exists {
+		return nil, errors.ErrVaultTransitKeyVersionNotFound.WithArgs(name, version)
+	}
+	return pk, nil
+}
+
+// GetTransitSigningMethods returns the JWT signing methods supported by the
+// Transit key type.
+func GetTransitSigningMethods(keyType string) []string {
+	return transitKeyTypeMethods[keyType]
+}
+
+func (c *Client) getTransitKey(name string, version int) (*transitKey, error) {
+	c.mu.RLock()
+	tk, found := c.transitKeys[name]
+	c.mu.RUnlock()
+
+	if found {
+		age := time.Since(tk.fetchedAt)
+		if _, exists := tk.publicKeys[version]; (exists || version == 0) && age < c.refreshInterval {
+			return tk, nil
+		}
+		if age < minRefreshInterval {
+			return tk, nil
+		}
+	}
+
+	fetched, err := c.fetchTransitKey(name)
+	if err != nil {
+		if found {
+			return tk, nil
+		}
+		return nil, err
+	}
+	c.mu.Lock()
+	c.transitKeys[name] = fetched
+	c.mu.Unlock()
+	return fetched, nil
+}
+
+func (c *Client) fetchTransitKey(name string) (*transitKey, error) {
+	var resp struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := c.do(http.MethodGet, c.transitMount+"/keys/"+name, nil, &resp); err != nil {
+		return nil, err
+	}
+	if _, supported := transitKeyTypeMethods[resp.Data.Type]; !supported {
+		return nil, errors.ErrVaultTransitKeyTypeUnsupported.WithArgs(name, resp.Data.Type)
+	}
+	tk := &transitKey{
+		keyType:       resp.Data.Type,
+		latestVersion: resp.Data.LatestVersion,
+		publicKeys:    make(map[int]interface{}),
+		fetchedAt:     time.Now(),
+	}
+	for k, v := range resp.Data.Keys {
+		version, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		block, _ := pem.Decode([]byte(v.PublicKey))
+		if block == nil {
+			return nil, errors.ErrVaultTransitKeyPublicKey.WithArgs(name, version, "not pem encoded")
+		}
+		pk, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.ErrVaultTransitKeyPublicKey.WithArgs(name, version, err)
+		}
+		tk.publicKeys[version] = pk
+	}
+	if _, exists := tk.publicKeys[tk.latestVersion]; !exists {
+		return nil, errors.ErrVaultTransitKeyVersionNotFound.WithArgs(name, tk.latestVersion)
+	}
+	return tk, nil
+}
+
+// TransitSign signs the input with the version of the Transit signing key
+// and returns the signature in JWS format, i.e. PKCS #1 v1.5 signature for
+// RSA keys and the concatenation of R and S for ECDSA keys.
+func (c *Client) TransitSign(name string, version int, method string, input []byte) ([]byte, error) {
+	keyType, _, err := c.GetTransitKey(name)
+	if err != nil {
+		return nil, err
+	}
+	var supported bool
+	for _, m := range transitKeyTypeMethods[keyType] {
+		if m == method {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, errors.ErrVaultTransitSignMethod.WithArgs(name, method)
+	}
+
+	body := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	}
+	if version > 0 {
+		body["key_version"] = version
+	}
+	if strings.HasPrefix(keyType, "rsa") {
+		body["signature_algorithm"] = "pkcs1v15"
+	} else {
+		body["marshaling_algorithm"] = "jws"
+	}
+
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	path := c.transitMount + "/sign/" + name + "/" + transitMethodHashes[method]
+	if err := c.do(http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+
+	// The signature has vault:v<version>:<signature> format.
+	parts := strings.SplitN(resp.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, errors.ErrVaultTransitSignature.WithArgs(name, "unexpected format")
+	}
+	sig, err := decodeSignature(parts[2])
+	if err != nil {
+		return nil, errors.ErrVaultTransitSignature.WithArgs(name, err)
+	}
+	return sig, nil
+}
+
+func decodeSignature(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "=")); err == nil {
+		return b, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding signature: %v", err)
+	}
+	return b, nil
+}
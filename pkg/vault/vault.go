@@ -0,0 +1,289 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultKVMount         = "secret"
+	defaultTransitMount    = "transit"
+	defaultRefreshInterval = 300
+	// minRefreshInterval limits how often the transit keys are refetched when
+	// a token references an unknown key version.
+	minRefreshInterval = 30 * time.Second
+	// renewRetryInterval is the delay before retrying a failed token renewal.
+	renewRetryInterval = 10 * time.Second
+)
+
+// Config holds the configuration of Vault Client.
+type Config struct {
+	// The address of Vault server, e.g. https://vault.example.com:8200.
+	// Defaults to VAULT_ADDR environment variable.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// The token used to authenticate to Vault. Defaults to VAULT_TOKEN
+	// environment variable.
+	Token string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
+	// The Vault Enterprise namespace.
+	Namespace string `json:"namespace,omitempty" xml:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// The mount path of KV version 2 secrets engine. Defaults to secret.
+	KVMount string `json:"kv_mount,omitempty" xml:"kv_mount,omitempty" yaml:"kv_mount,omitempty"`
+	// The mount path of Transit secrets engine. Defaults to transit.
+	TransitMount string `json:"transit_mount,omitempty" xml:"transit_mount,omitempty" yaml:"transit_mount,omitempty"`
+	// The number of seconds the secrets and the transit keys are cached
+	// before being re-read, e.g. to pick up rotated values. Defaults to 300.
+	RefreshInterval int `json:"refresh_interval,omitempty" xml:"refresh_interval,omitempty" yaml:"refresh_interval,omitempty"`
+}
+
+type cachedSecret struct {
+	data      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Client reads secrets from Vault KV version 2 secrets engine and signs
+// data with Vault Transit secrets engine.
+type Client struct {
+	mu              sync.RWMutex
+	address         string
+	token           string
+	namespace       string
+	kvMount         string
+	transitMount    string
+	refreshInterval time.Duration
+	secrets         map[string]*cachedSecret
+	transitKeys     map[string]*transitKey
+	client          *http.Client
+	logger          *zap.Logger
+	done            chan struct{}
+	closeOnce       sync.Once
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Address == "" {
+		return errors.ErrVaultConfigAddressEmpty
+	}
+	if !strings.HasPrefix(cfg.Address, "https://") && !strings.HasPrefix(cfg.Address, "http://") {
+		return errors.ErrVaultConfigAddressInvalid.WithArgs(cfg.Address)
+	}
+	if cfg.Token == "" {
+		return errors.ErrVaultConfigTokenEmpty
+	}
+	if cfg.RefreshInterval < 0 {
+		return errors.ErrVaultConfigRefreshInterval.WithArgs(cfg.RefreshInterval)
+	}
+	return nil
+}
+
+// NewClient returns an instance of Client.
+func NewClient(cfg *Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		address:         strings.TrimSuffix(cfg.Address, "/"),
+		token:           cfg.Token,
+		namespace:       cfg.Namespace,
+		kvMount:         strings.Trim(cfg.KVMount, "/"),
+		transitMount:    strings.Trim(cfg.TransitMount, "/"),
+		refreshInterval: time.Duration(defaultRefreshInterval) * time.Second,
+		secrets:         make(map[string]*cachedSecret),
+		transitKeys:     make(map[string]*transitKey),
+		client:          &http.Client{Timeout: 10 * time.Second},
+		logger:          zap.NewNop(),
+		done:            make(chan struct{}),
+	}
+	if c.kvMount == "" {
+		c.kvMount = defaultKVMount
+	}
+	if c.transitMount == "" {
+		c.transitMount = defaultTransitMount
+	}
+	if cfg.RefreshInterval > 0 {
+		c.refreshInterval = time.Duration(cfg.RefreshInterval) * time.Second
+	}
+	return c, nil
+}
+
+// SetLogger adds a logger to Client.
+func (c *Client) SetLogger(logger *zap.Logger) {
+	c.logger = logger
+}
+
+// ReadSecret returns the value of a field in the secret stored at the path
+// of KV version 2 secrets engine. The secrets are cached and re-read after
+// the refresh interval. When Vault is unavailable, the last known value is
+// returned.
+func (c *Client) ReadSecret(path, field string) (string, error) {
+	path = strings.Trim(path, "/")
+	c.mu.RLock()
+	secret, found := c.secrets[path]
+	c.mu.RUnlock()
+
+	if !found || time.Since(secret.fetchedAt) >= c.refreshInterval {
+		data, err := c.fetchSecret(path)
+		switch {
+		case err == nil:
+			secret = &cachedSecret{data: data, fetchedAt: time.Now()}
+			c.mu.Lock()
+			c.secrets[path] = secret
+			c.mu.Unlock()
+		case found:
+			c.logger.Warn(
+				"failed re-reading vault secret, using cached value",
+				zap.String("path", path),
+				zap.Error(err),
+			)
+		default:
+			return "", err
+		}
+	}
+
+	v, exists := secret.data[field]
+	if !exists {
+		return "", errors.ErrVaultSecretFieldNotFound.WithArgs(field, path)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.ErrVaultSecretFieldNotString.WithArgs(field, path)
+	}
+	return s, nil
+}
+
+func (c *Client) fetchSecret(path string) (map[string]interface{}, error) {
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := c.do(http.MethodGet, c.kvMount+"/data/"+path, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.Data == nil {
+		resp.Data.Data = make(map[string]interface{})
+	}
+	return resp.Data.Data, nil
+}
+
+// Start looks up the token used by Client and, when the token is
+// renewable, periodically renews its lease until Client is closed.
+func (c *Client) Start() error {
+	var resp struct {
+		Data struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if err := c.do(http.MethodGet, "auth/token/lookup-self", nil, &resp); err != nil {
+		return err
+	}
+	if !resp.Data.Renewable || resp.Data.TTL < 1 {
+		return nil
+	}
+	go c.renewToken(time.Duration(resp.Data.TTL) * time.Second)
+	return nil
+}
+
+func (c *Client) renewToken(ttl time.Duration) {
+	for {
+		timer := time.NewTimer(ttl / 2)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		var resp struct {
+			Auth struct {
+				LeaseDuration int `json:"lease_duration"`
+			} `json:"auth"`
+		}
+		if err := c.do(http.MethodPost, "auth/token/renew-self", map[string]interface{}{}, &resp); err != nil {
+			c.logger.Warn("failed renewing vault token", zap.Error(err))
+			ttl = 2 * renewRetryInterval
+			continue
+		}
+		c.logger.Debug("renewed vault token", zap.Int("lease_duration", resp.Auth.LeaseDuration))
+		if resp.Auth.LeaseDuration < 1 {
+			return
+		}
+		ttl = time.Duration(resp.Auth.LeaseDuration) * time.Second
+	}
+}
+
+// Close stops the renewal of the token lease.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reqBody *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.ErrVaultRequest.WithArgs(method, path, err)
+		}
+		reqBody = bytes.NewReader(b)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, c.address+"/v1/"+path, reqBody)
+	if err != nil {
+		return errors.ErrVaultRequest.WithArgs(method, path, err)
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+	req.Header.Set("X-Vault-Request", "true")
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.ErrVaultRequest.WithArgs(method, path, err)
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return errors.ErrVaultRequest.WithArgs(method, path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.ErrVaultResponse.WithArgs(method, path, resp.Status)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return errors.ErrVaultRequest.WithArgs(method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *Config
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid config",
+			config: &Config{
+				Address: "https://vault.example.com:8200",
+				Token:   "s.foobar",
+			},
+		},
+		{
+			name: "invalid address",
+			config: &Config{
+				Address: "vault.example.com",
+				Token:   "s.foobar",
+			},
+			shouldErr: true,
+			err:       errors.ErrVaultConfigAddressInvalid.WithArgs("vault.example.com"),
+		},
+		{
+			name: "negative refresh interval",
+			config: &Config{
+				Address:         "https://vault.example.com:8200",
+				Token:           "s.foobar",
+				RefreshInterval: -1,
+			},
+			shouldErr: true,
+			err:       errors.ErrVaultConfigRefreshInterval.WithArgs(-1),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.config.Validate()
+			tests.EvalErrWithLog(t, err, "vault config", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	srv := tests.NewVaultServer("s.foobar")
+	defer srv.Close()
+	srv.SetSecret("authp/oauth", map[string]interface{}{"client_secret": "foo"})
+
+	c, err := NewClient(&Config{Address: srv.URL(), Token: "s.foobar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetDefaultClient(c)
+
+	for i := 0; i < 2; i++ {
+		v, err := Resolve("vault:authp/oauth#client_secret")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tests.EvalObjects(t, "secret", "foo", v)
+	}
+	// The secret is read once and then served from the cache.
+	tests.EvalObjects(t, "request count", 1, srv.GetRequestCount("secret/data/authp/oauth"))
+
+	// The rotated secret is re-read after the refresh interval.
+	srv.SetSecret("authp/oauth", map[string]interface{}{"client_secret": "bar"})
+	c.secrets["authp/oauth"].fetchedAt = time.Now().Add(-time.Hour)
+	v, err := c.ReadSecret("authp/oauth", "client_secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "rotated secret", "bar", v)
+
+	v, err = Resolve("plaintext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "plain value", "plaintext", v)
+
+	if _, err := Resolve("vault:authp/oauth#client_id"); err == nil {
+		t.Fatalf("expected error for missing field")
+	}
+	if _, err := Resolve("vault:authp/oauth"); err == nil {
+		t.Fatalf("expected error for invalid reference")
+	}
+}
+
+func TestTransitSign(t *testing.T) {
+	srv := tests.NewVaultServer("s.foobar")
+	defer srv.Close()
+	if err := srv.RotateTransitKey("authp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := NewClient(&Config{Address: srv.URL(), Token: "s.foobar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	keyType, version, err := c.GetTransitKey("authp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "key type", "ecdsa-p256", keyType)
+	tests.EvalObjects(t, "methods", []string{"ES256"}, GetTransitSigningMethods(keyType))
+
+	input := []byte("header.payload")
+	sig, err := c.TransitSign("authp", version, "ES256", input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pk, err := c.GetTransitPublicKey("authp", version)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	digest := sha256.Sum256(input)
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(pk.(*ecdsa.PublicKey), digest[:], r, s) {
+		t.Fatalf("signature verification failed")
+	}
+
+	if _, err := c.TransitSign("authp", version, "RS256", input); err == nil {
+		t.Fatalf("expected error for unsupported signing method")
+	}
+}
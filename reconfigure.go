@@ -0,0 +1,262 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authcrunch
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ConfigDiff is the difference between the running and the new
+// configuration of Server.
+type ConfigDiff struct {
+	IdentityStores        SectionDiff `json:"identity_stores,omitempty" xml:"identity_stores,omitempty" yaml:"identity_stores,omitempty"`
+	IdentityProviders     SectionDiff `json:"identity_providers,omitempty" xml:"identity_providers,omitempty" yaml:"identity_providers,omitempty"`
+	SingleSignOnProviders SectionDiff `json:"single_sign_on_providers,omitempty" xml:"single_sign_on_providers,omitempty" yaml:"single_sign_on_providers,omitempty"`
+	UserRegistries        SectionDiff `json:"user_registries,omitempty" xml:"user_registries,omitempty" yaml:"user_registries,omitempty"`
+	AuthenticationPortals SectionDiff `json:"authentication_portals,omitempty" xml:"authentication_portals,omitempty" yaml:"authentication_portals,omitempty"`
+	AuthorizationPolicies SectionDiff `json:"authorization_policies,omitempty" xml:"authorization_policies,omitempty" yaml:"authorization_policies,omitempty"`
+	Credentials           bool        `json:"credentials,omitempty" xml:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Messaging             bool        `json:"messaging,omitempty" xml:"messaging,omitempty" yaml:"messaging,omitempty"`
+	// Unsupported is the list of the changed sections requiring the restart
+	// of the server, e.g. directories and network listeners.
+	Unsupported []string `json:"unsupported,omitempty" xml:"unsupported,omitempty" yaml:"unsupported,omitempty"`
+}
+
+// SectionDiff holds the names of the added, removed and changed entries of
+// a configuration section.
+type SectionDiff struct {
+	Added   []string `json:"added,omitempty" xml:"added,omitempty" yaml:"added,omitempty"`
+	Removed []string `json:"removed,omitempty" xml:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty" xml:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// IsEmpty returns true when the section has no changes.
+func (d SectionDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// IsChanged returns true when the entry with the name was changed.
+func (d SectionDiff) IsChanged(s string) bool {
+	for _, name := range d.Changed {
+		if name == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty returns true when the configurations are the same.
+func (d *ConfigDiff) IsEmpty() bool {
+	for _, section := range []SectionDiff{
+		d.IdentityStores,
+		d.IdentityProviders,
+		d.SingleSignOnProviders,
+		d.UserRegistries,
+		d.AuthenticationPortals,
+		d.AuthorizationPolicies,
+	} {
+		if !section.IsEmpty() {
+			return false
+		}
+	}
+	return !d.Credentials && !d.Messaging && len(d.Unsupported) == 0
+}
+
+// DiffConfig returns the difference between two configurations.
+func DiffConfig(prev, next *Config) *ConfigDiff {
+	prevEntries := getSectionEntries(prev)
+	nextEntries := getSectionEntries(next)
+	diff := &ConfigDiff{
+		IdentityStores:        diffSection(prevEntries["identity_stores"], nextEntries["identity_stores"]),
+		IdentityProviders:     diffSection(prevEntries["identity_providers"], nextEntries["identity_providers"]),
+		SingleSignOnProviders: diffSection(prevEntries["sso_providers"], nextEntries["sso_providers"]),
+		UserRegistries:        diffSection(prevEntries["user_registries"], nextEntries["user_registries"]),
+		AuthenticationPortals: diffSection(prevEntries["authentication_portals"], nextEntries["authentication_portals"]),
+		AuthorizationPolicies: diffSection(prevEntries["authorization_policies"], nextEntries["authorization_policies"]),
+		Credentials:           !isEqualJSON(prev.Credentials, next.Credentials),
+		Messaging:             !isEqualJSON(prev.Messaging, next.Messaging),
+	}
+
+	unsupported := []struct {
+		name       string
+		prev, next interface{}
+	}{
+		{"extensions", prev.Extensions, next.Extensions},
+		{"vault", prev.Vault, next.Vault},
+		{"token_stores", prev.TokenStores, next.TokenStores},
+		{"directories", prev.Directories, next.Directories},
+		{"metrics", prev.Metrics, next.Metrics},
+		{"audit_sinks", prev.AuditSinks, next.AuditSinks},
+		{"rpc_servers", prev.RPCServers, next.RPCServers},
+		{"ext_authz_servers", prev.ExtAuthzServers, next.ExtAuthzServers},
+		{"forward_auth_servers", prev.ForwardAuthServers, next.ForwardAuthServers},
+		{"geolocation", prev.Geolocation, next.Geolocation},
+		{"logging", prev.Logging, next.Logging},
+	}
+	for _, section := range unsupported {
+		if !isEqualJSON(section.prev, section.next) {
+			diff.Unsupported = append(diff.Unsupported, section.name)
+		}
+	}
+	return diff
+}
+
+// Reconfigure validates the configuration and applies it to the running
+// server. The identity providers and stores, sso providers, user registries,
+// portals and gatekeepers are rebuilt and swapped in at once. The entries
+// with unchanged configuration are reused, and the portals keep their
+// active sessions. When the configuration is invalid or any of the
+// components fails to initialize, the running server remains unchanged.
+func (srv *Server) Reconfigure(config *Config) error {
+	srv.reconfigureMu.Lock()
+	defer srv.reconfigureMu.Unlock()
+
+	if err := config.Validate(); err != nil {
+		return errors.ErrReconfigure.WithArgs("invalid config", err)
+	}
+
+	diff := DiffConfig(srv.config, config)
+	if len(diff.Unsupported) > 0 {
+		return errors.ErrReconfigureUnsupported.WithArgs(strings.Join(diff.Unsupported, ", "))
+	}
+	if diff.IsEmpty() {
+		return nil
+	}
+
+	for _, cfg := range config.Directories {
+		if diff.IdentityStores.IsChanged(cfg.IdentityStore) {
+			return errors.ErrReconfigureDirectoryStore.WithArgs(cfg.IdentityStore, cfg.Name)
+		}
+	}
+
+	staged := &Server{
+		config:    config,
+		logger:    srv.logger,
+		nameRefs:  newRefMap(),
+		realmRefs: newRefMap(),
+	}
+	if err := staged.configureComponents(srv, diff, errors.ErrReconfigure); err != nil {
+		return err
+	}
+
+	for _, cfg := range config.Directories {
+		if _, exists := staged.nameRefs.identityStores[cfg.IdentityStore]; !exists {
+			return errors.ErrReconfigureDirectoryStore.WithArgs(cfg.IdentityStore, cfg.Name)
+		}
+	}
+	for _, cfg := range config.RPCServers {
+		if _, exists := staged.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return errors.ErrReconfigurePolicyNotFound.WithArgs("rpc server", cfg.Name, cfg.AuthorizationPolicy)
+		}
+	}
+	for _, cfg := range config.ExtAuthzServers {
+		if _, exists := staged.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return errors.ErrReconfigurePolicyNotFound.WithArgs("ext_authz server", cfg.Name, cfg.AuthorizationPolicy)
+		}
+	}
+	for _, cfg := range config.ForwardAuthServers {
+		if _, exists := staged.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return errors.ErrReconfigurePolicyNotFound.WithArgs("forward auth server", cfg.Name, cfg.AuthorizationPolicy)
+		}
+	}
+
+	srv.mu.Lock()
+	srv.config = config
+	srv.portals = staged.portals
+	srv.gatekeepers = staged.gatekeepers
+	srv.identityStores = staged.identityStores
+	srv.identityProviders = staged.identityProviders
+	srv.ssoProviders = staged.ssoProviders
+	srv.userRegistries = staged.userRegistries
+	srv.nameRefs = staged.nameRefs
+	srv.realmRefs = staged.realmRefs
+	srv.mu.Unlock()
+
+	for _, rpcServer := range srv.rpcServers {
+		rpcServer.SetIdentityStores(staged.identityStores)
+	}
+
+	srv.logger.Info(
+		"Reconfigured server",
+		zap.Any("diff", diff),
+	)
+	return nil
+}
+
+func getSectionEntries(config *Config) map[string]map[string]string {
+	m := map[string]map[string]string{
+		"identity_stores":        make(map[string]string),
+		"identity_providers":     make(map[string]string),
+		"sso_providers":          make(map[string]string),
+		"user_registries":        make(map[string]string),
+		"authentication_portals": make(map[string]string),
+		"authorization_policies": make(map[string]string),
+	}
+	for _, cfg := range config.IdentityStores {
+		m["identity_stores"][cfg.Name] = toJSON(cfg)
+	}
+	for _, cfg := range config.IdentityProviders {
+		m["identity_providers"][cfg.Name] = toJSON(cfg)
+	}
+	for _, cfg := range config.SingleSignOnProviders {
+		m["sso_providers"][cfg.Name] = toJSON(cfg)
+	}
+	for _, cfg := range config.UserRegistries {
+		m["user_registries"][cfg.Name] = toJSON(cfg)
+	}
+	for _, cfg := range config.AuthenticationPortals {
+		m["authentication_portals"][cfg.Name] = toJSON(cfg)
+	}
+	for _, cfg := range config.AuthorizationPolicies {
+		m["authorization_policies"][cfg.Name] = toJSON(cfg)
+	}
+	return m
+}
+
+func diffSection(prev, next map[string]string) SectionDiff {
+	var diff SectionDiff
+	for name, s := range next {
+		prevEntry, exists := prev[name]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, name)
+		case prevEntry != s:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range prev {
+		if _, exists := next[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+func isEqualJSON(a, b interface{}) bool {
+	return toJSON(a) == toJSON(b)
+}
+
+func toJSON(i interface{}) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
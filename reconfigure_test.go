@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authcrunch
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authn"
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func newTestReconfigureConfig(t *testing.T, dbPath string, policies ...string) *Config {
+	cfg := NewConfig()
+	if err := cfg.AddIdentityStore("localdb", "local", map[string]interface{}{
+		"realm": "local",
+		"path":  dbPath,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.AddAuthenticationPortal(&authn.PortalConfig{
+		Name:           "myportal",
+		IdentityStores: []string{"localdb"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range policies {
+		if err := cfg.AddAuthorizationPolicy(&authz.PolicyConfig{
+			Name: name,
+			AccessListRules: []*acl.RuleConfiguration{
+				{
+					Conditions: []string{"match roles authp/admin authp/user"},
+					Action:     "allow stop",
+				},
+			},
+			AuthRedirectDisabled: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func TestReconfigure(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestReconfigure")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	dbPath := db.GetPath()
+
+	var testcases = []struct {
+		name      string
+		config    func(*testing.T) *Config
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test adding authorization policy",
+			config: func(t *testing.T) *Config {
+				return newTestReconfigureConfig(t, dbPath, "mygatekeeper", "othergatekeeper")
+			},
+			want: map[string]interface{}{
+				"policies":     []string{"mygatekeeper", "othergatekeeper"},
+				"store_reused": true,
+				"diff": &ConfigDiff{
+					AuthorizationPolicies: SectionDiff{
+						Added: []string{"othergatekeeper"},
+					},
+				},
+			},
+		},
+		{
+			name: "test removing authorization policy",
+			config: func(t *testing.T) *Config {
+				return newTestReconfigureConfig(t, dbPath)
+			},
+			want: map[string]interface{}{
+				"policies":     []string{},
+				"store_reused": true,
+				"diff": &ConfigDiff{
+					AuthorizationPolicies: SectionDiff{
+						Removed: []string{"mygatekeeper"},
+					},
+				},
+			},
+		},
+		{
+			name: "test changing identity store",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+				cfg.IdentityStores[0].Params["path"] = dbPath + ".new"
+				return cfg
+			},
+			want: map[string]interface{}{
+				"policies":     []string{"mygatekeeper"},
+				"store_reused": false,
+				"diff": &ConfigDiff{
+					IdentityStores: SectionDiff{
+						Changed: []string{"localdb"},
+					},
+				},
+			},
+		},
+		{
+			name: "test rollback on invalid config",
+			config: func(t *testing.T) *Config {
+				return NewConfig()
+			},
+			shouldErr: true,
+			err:       errors.ErrReconfigure.WithArgs("invalid config", fmt.Errorf("no portals and gatekeepers found")),
+		},
+		{
+			name: "test rollback on unsupported change",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+				if err := cfg.SetMetrics(&metrics.Config{Address: "127.0.0.1:0"}); err != nil {
+					t.Fatal(err)
+				}
+				return cfg
+			},
+			shouldErr: true,
+			err:       errors.ErrReconfigureUnsupported.WithArgs("metrics"),
+		},
+		{
+			name: "test rollback on identity store failure",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper", "othergatekeeper")
+				cfg.IdentityStores[0].Params["path"] = "/dev/null/users.json"
+				return cfg
+			},
+			shouldErr: true,
+			err: errors.ErrReconfigure.WithArgs(
+				"failed configuring identity store",
+				fmt.Errorf(`failed initializing database at "/dev/null/users.json": stat /dev/null/users.json: not a directory`),
+			),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, err := NewServer(newTestReconfigureConfig(t, dbPath, "mygatekeeper"), logutil.NewLogger())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			prevStore, _ := srv.GetIdentityStoreByRealm("local")
+			prevPortal, _ := srv.GetPortalByName("myportal")
+
+			prevConfig := srv.config
+			err = srv.Reconfigure(tc.config(t))
+			if err != nil {
+				// The running server must remain unchanged.
+				if portal, _ := srv.GetPortalByName("myportal"); portal != prevPortal {
+					t.Fatalf("portal was replaced despite failed reconfiguration")
+				}
+				if store, _ := srv.GetIdentityStoreByRealm("local"); store != prevStore {
+					t.Fatalf("identity store was replaced despite failed reconfiguration")
+				}
+				if _, err := srv.GetGatekeeperByName("mygatekeeper"); err != nil {
+					t.Fatalf("gatekeeper was removed despite failed reconfiguration")
+				}
+			}
+			if tests.EvalErrWithLog(t, err, "Reconfigure", tc.shouldErr, tc.err, nil) {
+				return
+			}
+
+			got := make(map[string]interface{})
+			policies := []string{}
+			for _, cfg := range srv.config.AuthorizationPolicies {
+				if _, err := srv.GetGatekeeperByName(cfg.Name); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				policies = append(policies, cfg.Name)
+			}
+			got["policies"] = policies
+			store, err := srv.GetIdentityStoreByRealm("local")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got["store_reused"] = store == prevStore
+			got["diff"] = DiffConfig(prevConfig, srv.config)
+
+			if portal, _ := srv.GetPortalByName("myportal"); portal == prevPortal {
+				t.Fatalf("portal was not rebuilt")
+			}
+
+			tests.EvalObjectsWithLog(t, "Reconfigure", tc.want, got, nil)
+		})
+	}
+}
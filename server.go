@@ -17,15 +17,29 @@ package authcrunch
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"sync"
 
 	"github.com/greenpau/go-authcrunch/pkg/authn"
 	"github.com/greenpau/go-authcrunch/pkg/authproxy"
 	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/directory"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/events"
+	"github.com/greenpau/go-authcrunch/pkg/extauthz"
+	"github.com/greenpau/go-authcrunch/pkg/extension"
+	"github.com/greenpau/go-authcrunch/pkg/forwardauth"
+	"github.com/greenpau/go-authcrunch/pkg/geo"
 	"github.com/greenpau/go-authcrunch/pkg/idp"
 	"github.com/greenpau/go-authcrunch/pkg/ids"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/registry"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/rpc"
 	"github.com/greenpau/go-authcrunch/pkg/sso"
+	"github.com/greenpau/go-authcrunch/pkg/tokenstore"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/greenpau/go-authcrunch/pkg/vault"
 	"go.uber.org/zap"
 )
 
@@ -40,16 +54,24 @@ type refMap struct {
 
 // Server represents AAA SF server.
 type Server struct {
-	config            *Config
-	portals           []*authn.Portal
-	gatekeepers       []*authz.Gatekeeper
-	identityStores    []ids.IdentityStore
-	identityProviders []idp.IdentityProvider
-	ssoProviders      []sso.SingleSignOnProvider
-	userRegistries    []registry.UserRegistry
-	nameRefs          refMap
-	realmRefs         refMap
-	logger            *zap.Logger
+	config             *Config
+	portals            []*authn.Portal
+	gatekeepers        []*authz.Gatekeeper
+	identityStores     []ids.IdentityStore
+	identityProviders  []idp.IdentityProvider
+	ssoProviders       []sso.SingleSignOnProvider
+	userRegistries     []registry.UserRegistry
+	directories        []*directory.Server
+	metrics            *metrics.Server
+	auditSinks         []*events.Forwarder
+	rpcServers         []*rpc.Server
+	extAuthzServers    []*extauthz.Server
+	forwardAuthServers []*forwardauth.Server
+	nameRefs           refMap
+	realmRefs          refMap
+	logger             *zap.Logger
+	mu                 sync.RWMutex
+	reconfigureMu      sync.Mutex
 }
 
 func newRefMap() refMap {
@@ -65,11 +87,14 @@ func newRefMap() refMap {
 
 // NewServer returns an instance of Server.
 func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
-	var authenticators []authproxy.Authenticator
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
+	if logger != nil {
+		logger = logutil.Wrap(logger, config.Logging)
+	}
+
 	srv := &Server{
 		config:    config,
 		logger:    logger,
@@ -77,19 +102,192 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 		realmRefs: newRefMap(),
 	}
 
-	for _, cfg := range config.IdentityProviders {
-		provider, err := idp.NewIdentityProvider(cfg, logger)
+	events.Subscribe("audit_log", events.NewLogSink(logger.Named("audit")))
+
+	for _, cfg := range config.AuditSinks {
+		sink, err := events.NewForwarder(cfg, logger.Named("audit"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing audit sink", err)
+		}
+		events.Subscribe("audit_sink/"+sink.GetName(), sink, sink.GetEventTypes()...)
+		srv.auditSinks = append(srv.auditSinks, sink)
+	}
+
+	if config.Vault != nil {
+		client, err := vault.NewClient(config.Vault)
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing vault client", err)
+		}
+		client.SetLogger(logger.Named("vault"))
+		if err := client.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing vault client", err)
+		}
+		vault.SetDefaultClient(client)
+	}
+
+	if config.Geolocation != nil {
+		resolver, err := geo.NewResolver(config.Geolocation)
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing geolocation resolver", err)
+		}
+		resolver.SetLogger(logger.Named("geolocation"))
+		geo.SetDefaultResolver(resolver)
+	}
+
+	extensionNames := make(map[string]bool)
+	for _, cfg := range config.Extensions {
+		if _, exists := extensionNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate extension name", cfg.Name)
+		}
+		ext, err := extension.NewExtension(cfg)
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing extension", err)
+		}
+		extensionNames[cfg.Name] = true
+		extension.Register(ext)
+	}
+
+	tokenStoreNames := make(map[string]bool)
+	for _, cfg := range config.TokenStores {
+		if _, exists := tokenStoreNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate token store name", cfg.Name)
+		}
+		store, err := tokenstore.NewStore(cfg)
 		if err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed initializing identity provider", err)
+			return nil, errors.ErrNewServer.WithArgs("failed initializing token store", err)
+		}
+		tokenStoreNames[cfg.Name] = true
+		tokenstore.Register(cfg.Name, store)
+	}
+
+	if err := srv.configureComponents(nil, nil, errors.ErrNewServer); err != nil {
+		return nil, err
+	}
+
+	directoryNames := make(map[string]bool)
+	for _, cfg := range config.Directories {
+		if _, exists := directoryNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate directory name", cfg.Name)
+		}
+		store, exists := srv.nameRefs.identityStores[cfg.IdentityStore]
+		if !exists {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing directory", errors.ErrDirectoryIdentityStoreNotFound.WithArgs(cfg.Name, cfg.IdentityStore))
+		}
+		dir, err := directory.NewServer(cfg, store, logger.Named("directory"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing directory", err)
+		}
+		if err := dir.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed starting directory", err)
+		}
+		directoryNames[cfg.Name] = true
+		srv.directories = append(srv.directories, dir)
+	}
+
+	if config.Metrics != nil {
+		endpoint, err := metrics.NewServer(config.Metrics, metrics.Default, logger.Named("metrics"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing metrics endpoint", err)
+		}
+		if err := endpoint.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed starting metrics endpoint", err)
+		}
+		srv.metrics = endpoint
+	}
+
+	rpcServerNames := make(map[string]bool)
+	for _, cfg := range config.RPCServers {
+		if _, exists := rpcServerNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate rpc server name", cfg.Name)
+		}
+		if _, exists := srv.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing rpc server", errors.ErrRPCPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy))
+		}
+		rpcServer, err := rpc.NewServer(cfg, &policyRef{srv: srv, name: cfg.AuthorizationPolicy}, srv.identityStores, logger.Named("rpc"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing rpc server", err)
+		}
+		if err := rpcServer.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed starting rpc server", err)
+		}
+		rpcServerNames[cfg.Name] = true
+		srv.rpcServers = append(srv.rpcServers, rpcServer)
+	}
+
+	extAuthzServerNames := make(map[string]bool)
+	for _, cfg := range config.ExtAuthzServers {
+		if _, exists := extAuthzServerNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate ext_authz server name", cfg.Name)
+		}
+		if _, exists := srv.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing ext_authz server", errors.ErrExtAuthzPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy))
+		}
+		extAuthzServer, err := extauthz.NewServer(cfg, &policyRef{srv: srv, name: cfg.AuthorizationPolicy}, logger.Named("ext_authz"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing ext_authz server", err)
+		}
+		if err := extAuthzServer.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed starting ext_authz server", err)
+		}
+		extAuthzServerNames[cfg.Name] = true
+		srv.extAuthzServers = append(srv.extAuthzServers, extAuthzServer)
+	}
+
+	forwardAuthServerNames := make(map[string]bool)
+	for _, cfg := range config.ForwardAuthServers {
+		if _, exists := forwardAuthServerNames[cfg.Name]; exists {
+			return nil, errors.ErrNewServer.WithArgs("duplicate forward auth server name", cfg.Name)
+		}
+		if _, exists := srv.nameRefs.gatekeepers[cfg.AuthorizationPolicy]; !exists {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing forward auth server", errors.ErrForwardAuthPolicyNotFound.WithArgs(cfg.Name, cfg.AuthorizationPolicy))
+		}
+		forwardAuthServer, err := forwardauth.NewServer(cfg, &policyRef{srv: srv, name: cfg.AuthorizationPolicy}, logger.Named("forward_auth"))
+		if err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed initializing forward auth server", err)
+		}
+		if err := forwardAuthServer.Start(); err != nil {
+			return nil, errors.ErrNewServer.WithArgs("failed starting forward auth server", err)
+		}
+		forwardAuthServerNames[cfg.Name] = true
+		srv.forwardAuthServers = append(srv.forwardAuthServers, forwardAuthServer)
+	}
+
+	return srv, nil
+}
+
+// configureComponents initializes identity providers and stores, sso
+// providers, user registries, portals and gatekeepers of the server. When
+// the server replaces a previous one, the components not affected by the
+// configuration changes are reused. The errors are reported with the fail
+// error template.
+func (srv *Server) configureComponents(prev *Server, diff *ConfigDiff, fail errors.StandardError) error {
+	var authenticators []authproxy.Authenticator
+	config := srv.config
+	logger := srv.logger
+
+	for _, cfg := range config.IdentityProviders {
+		var provider idp.IdentityProvider
+		var reused bool
+		if prev != nil && !diff.IdentityProviders.IsChanged(cfg.Name) {
+			provider, reused = prev.nameRefs.identityProviders[cfg.Name]
+		}
+		if !reused {
+			var err error
+			provider, err = idp.NewIdentityProvider(cfg, logger.Named("identity_provider"))
+			if err != nil {
+				return fail.WithArgs("failed initializing identity provider", err)
+			}
 		}
 		if _, exists := srv.nameRefs.identityProviders[provider.GetName()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate identity provider name", provider.GetName())
+			return fail.WithArgs("duplicate identity provider name", provider.GetName())
 		}
 		if _, exists := srv.realmRefs.identityProviders[provider.GetRealm()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate identity provider realm", provider.GetRealm())
+			return fail.WithArgs("duplicate identity provider realm", provider.GetRealm())
 		}
-		if err := provider.Configure(); err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed configuring identity provider", err)
+		if !reused {
+			if err := provider.Configure(); err != nil {
+				return fail.WithArgs("failed configuring identity provider", err)
+			}
 		}
 		srv.nameRefs.identityProviders[provider.GetName()] = provider
 		srv.realmRefs.identityProviders[provider.GetRealm()] = provider
@@ -97,18 +295,28 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 	}
 
 	for _, cfg := range config.IdentityStores {
-		store, err := ids.NewIdentityStore(cfg, logger)
-		if err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed initializing identity store", err)
+		var store ids.IdentityStore
+		var reused bool
+		if prev != nil && !diff.IdentityStores.IsChanged(cfg.Name) {
+			store, reused = prev.nameRefs.identityStores[cfg.Name]
+		}
+		if !reused {
+			var err error
+			store, err = ids.NewIdentityStore(cfg, logger.Named("identity_store"))
+			if err != nil {
+				return fail.WithArgs("failed initializing identity store", err)
+			}
 		}
 		if _, exists := srv.nameRefs.identityStores[store.GetName()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate identity store name", store.GetName())
+			return fail.WithArgs("duplicate identity store name", store.GetName())
 		}
 		if _, exists := srv.realmRefs.identityStores[store.GetRealm()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate identity store realm", store.GetRealm())
+			return fail.WithArgs("duplicate identity store realm", store.GetRealm())
 		}
-		if err := store.Configure(); err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed configuring identity store", err)
+		if !reused {
+			if err := store.Configure(); err != nil {
+				return fail.WithArgs("failed configuring identity store", err)
+			}
 		}
 		srv.nameRefs.identityStores[store.GetName()] = store
 		srv.realmRefs.identityStores[store.GetRealm()] = store
@@ -116,27 +324,47 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 	}
 
 	for _, cfg := range config.SingleSignOnProviders {
-		provider, err := sso.NewSingleSignOnProvider(cfg, logger)
-		if err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed initializing sso provider", err)
+		var provider sso.SingleSignOnProvider
+		var reused bool
+		if prev != nil && !diff.SingleSignOnProviders.IsChanged(cfg.Name) {
+			provider, reused = prev.nameRefs.ssoProviders[cfg.Name]
+		}
+		if !reused {
+			var err error
+			provider, err = sso.NewSingleSignOnProvider(cfg, logger.Named("sso_provider"))
+			if err != nil {
+				return fail.WithArgs("failed initializing sso provider", err)
+			}
 		}
 		if _, exists := srv.nameRefs.ssoProviders[provider.GetName()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate sso provider name", provider.GetName())
+			return fail.WithArgs("duplicate sso provider name", provider.GetName())
 		}
-		if err := provider.Configure(); err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed configuring sso provider", err)
+		if !reused {
+			if err := provider.Configure(); err != nil {
+				return fail.WithArgs("failed configuring sso provider", err)
+			}
 		}
 		srv.nameRefs.ssoProviders[provider.GetName()] = provider
 		srv.ssoProviders = append(srv.ssoProviders, provider)
 	}
 
 	for _, cfg := range config.UserRegistries {
-		userRegistry, err := registry.NewUserRegistry(cfg, logger)
-		if err != nil {
-			return nil, errors.ErrNewServer.WithArgs("failed initializing user registry", err)
+		var userRegistry registry.UserRegistry
+		var reused bool
+		// The user registries send emails, and have to pick up the changes
+		// to messaging providers and their credentials.
+		if prev != nil && !diff.UserRegistries.IsChanged(cfg.Name) && !diff.Messaging && !diff.Credentials {
+			userRegistry, reused = prev.nameRefs.userRegistries[cfg.Name]
+		}
+		if !reused {
+			var err error
+			userRegistry, err = registry.NewUserRegistry(cfg, logger.Named("user_registry"))
+			if err != nil {
+				return fail.WithArgs("failed initializing user registry", err)
+			}
 		}
 		if _, exists := srv.nameRefs.userRegistries[userRegistry.GetName()]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate user registry name", userRegistry.GetName())
+			return fail.WithArgs("duplicate user registry name", userRegistry.GetName())
 		}
 		srv.nameRefs.userRegistries[userRegistry.GetName()] = userRegistry
 		srv.userRegistries = append(srv.userRegistries, userRegistry)
@@ -145,7 +373,7 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 	for _, cfg := range config.AuthenticationPortals {
 		params := authn.PortalParameters{
 			Config:                cfg,
-			Logger:                logger,
+			Logger:                logger.Named("portal"),
 			IdentityStores:        srv.identityStores,
 			IdentityProviders:     srv.identityProviders,
 			SingleSignOnProviders: srv.ssoProviders,
@@ -153,11 +381,17 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 
 		portal, err := authn.NewPortal(params)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		if _, exists := srv.nameRefs.portals[cfg.Name]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate authentication portal name", cfg.Name)
+			return fail.WithArgs("duplicate authentication portal name", cfg.Name)
+		}
+
+		if prev != nil {
+			if prevPortal, exists := prev.nameRefs.portals[cfg.Name]; exists {
+				portal.TransferSessions(prevPortal)
+			}
 		}
 
 		srv.nameRefs.portals[cfg.Name] = portal
@@ -166,13 +400,13 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 	}
 
 	for _, cfg := range config.AuthorizationPolicies {
-		gatekeeper, err := authz.NewGatekeeper(cfg, logger)
+		gatekeeper, err := authz.NewGatekeeper(cfg, logger.Named("gatekeeper"))
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		if _, exists := srv.nameRefs.gatekeepers[cfg.Name]; exists {
-			return nil, errors.ErrNewServer.WithArgs("duplicate authorization policy name", cfg.Name)
+			return fail.WithArgs("duplicate authorization policy name", cfg.Name)
 		}
 		srv.nameRefs.gatekeepers[cfg.Name] = gatekeeper
 		srv.gatekeepers = append(srv.gatekeepers, gatekeeper)
@@ -180,7 +414,7 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 
 	for _, gatekeeper := range srv.gatekeepers {
 		if err := gatekeeper.AddAuthenticators(authenticators); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -191,16 +425,18 @@ func NewServer(config *Config, logger *zap.Logger) (*Server, error) {
 				continue
 			}
 			if err := portal.AddUserRegistry(userRegistry); err != nil {
-				return nil, errors.ErrNewServer.WithArgs("failed adding registry to portal", err)
+				return fail.WithArgs("failed adding registry to portal", err)
 			}
 		}
 	}
 
-	return srv, nil
+	return nil
 }
 
 // GetConfig returns Server configuration.
 func (srv *Server) GetConfig() map[string]interface{} {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
 	var m map[string]interface{}
 	b, _ := json.Marshal(srv.config)
 	json.Unmarshal(b, &m)
@@ -209,6 +445,8 @@ func (srv *Server) GetConfig() map[string]interface{} {
 
 // GetPortalByName returns an instance of authn.Portal based on its name.
 func (srv *Server) GetPortalByName(s string) (*authn.Portal, error) {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
 	if portal, exists := srv.nameRefs.portals[s]; exists {
 		return portal, nil
 	}
@@ -217,8 +455,38 @@ func (srv *Server) GetPortalByName(s string) (*authn.Portal, error) {
 
 // GetGatekeeperByName returns an instance of authz.Gatekeeper based on its name.
 func (srv *Server) GetGatekeeperByName(s string) (*authz.Gatekeeper, error) {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
 	if gatekeeper, exists := srv.nameRefs.gatekeepers[s]; exists {
 		return gatekeeper, nil
 	}
 	return nil, fmt.Errorf("gatekeeper not found")
 }
+
+// GetIdentityStoreByRealm returns an instance of ids.IdentityStore based on
+// its realm.
+func (srv *Server) GetIdentityStoreByRealm(s string) (ids.IdentityStore, error) {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	if store, exists := srv.realmRefs.identityStores[s]; exists {
+		return store, nil
+	}
+	return nil, fmt.Errorf("identity store not found")
+}
+
+// policyRef is a reference to an authorization policy by its name. It
+// resolves the gatekeeper on every request, so that the listeners keep
+// serving the current policy after the server reconfiguration.
+type policyRef struct {
+	srv  *Server
+	name string
+}
+
+// Authenticate authorizes the request with the referenced policy.
+func (r *policyRef) Authenticate(w http.ResponseWriter, req *http.Request, ar *requests.AuthorizationRequest) error {
+	gatekeeper, err := r.srv.GetGatekeeperByName(r.name)
+	if err != nil {
+		return err
+	}
+	return gatekeeper.Authenticate(w, req, ar)
+}
@@ -0,0 +1,21 @@
+;; The source of claims.wasm test module.
+(module
+  (memory (export "memory") 1)
+  (data (i32.const 16) "{\"sub\":\"wasm\",\"roles\":[\"authp/user\"]}")
+  (func (export "abi_version") (result i32)
+    i32.const 1)
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  ;; Replaces the claims with the JSON object stored at offset 16.
+  (func (export "transform") (param i32 i32) (result i64)
+    i64.const 68719476773)
+  ;; Matches when the value of the field ends with "n".
+  (func (export "evaluate") (param i32 i32) (result i32)
+    local.get 0
+    local.get 1
+    i32.add
+    i32.const 3
+    i32.sub
+    i32.load8_u
+    i32.const 110
+    i32.eq))
@@ -0,0 +1,12 @@
+;; The source of loop.wasm test module.
+(module
+  (memory (export "memory") 1)
+  (func (export "abi_version") (result i32)
+    i32.const 1)
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  ;; Never returns.
+  (func (export "evaluate") (param i32 i32) (result i32)
+    (loop
+      br 0)
+    i32.const 0))
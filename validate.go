@@ -0,0 +1,271 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authcrunch
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authz/policytest"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationIssue is a configuration problem found by ValidateConfig.
+type ValidationIssue struct {
+	Section string `json:"section,omitempty" xml:"section,omitempty" yaml:"section,omitempty"`
+	Name    string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	Hint    string `json:"hint,omitempty" xml:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+// EffectiveACL is the access list in effect for an authentication portal
+// or an authorization policy once the defaults are applied.
+type EffectiveACL struct {
+	Section string                   `json:"section,omitempty" xml:"section,omitempty" yaml:"section,omitempty"`
+	Name    string                   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Rules   []*acl.RuleConfiguration `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// ValidationReport is the outcome of ValidateConfig.
+type ValidationReport struct {
+	Valid       bool               `json:"valid,omitempty" xml:"valid,omitempty" yaml:"valid,omitempty"`
+	Issues      []*ValidationIssue `json:"issues,omitempty" xml:"issues,omitempty" yaml:"issues,omitempty"`
+	AccessLists []*EffectiveACL    `json:"access_lists,omitempty" xml:"access_lists,omitempty" yaml:"access_lists,omitempty"`
+	// The outcomes of the policy tests.
+	PolicyTests []*policytest.Result `json:"policy_tests,omitempty" xml:"policy_tests,omitempty" yaml:"policy_tests,omitempty"`
+}
+
+func (r *ValidationReport) addIssue(section, name, hint string, msg string, args ...interface{}) {
+	r.Issues = append(r.Issues, &ValidationIssue{
+		Section: section,
+		Name:    name,
+		Message: fmt.Sprintf(msg, args...),
+		Hint:    hint,
+	})
+}
+
+// LoadConfig reads Config from a JSON or YAML file.
+func LoadConfig(fp string) (*Config, error) {
+	b, err := fileutil.ReadFileBytes(fp)
+	if err != nil {
+		return nil, errors.ErrLoadConfig.WithArgs(fp, err)
+	}
+	config := NewConfig()
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, config)
+	default:
+		err = json.Unmarshal(b, config)
+	}
+	if err != nil {
+		return nil, errors.ErrLoadConfig.WithArgs(fp, err)
+	}
+	return config, nil
+}
+
+// ValidateConfig performs a dry run of the server initialization. It
+// validates the cross-references between realms, identity stores and
+// providers, user registries, portals and policies, and then initializes
+// the identity stores and providers, portals and gatekeepers, which loads
+// the crypto keys and the user interface templates. Unlike NewServer, it
+// starts no listeners. All the problems found are reported at once.
+//
+// Note that the initialization creates local identity store databases
+// when they do not exist.
+func ValidateConfig(config *Config, logger *zap.Logger) *ValidationReport {
+	report := &ValidationReport{}
+
+	checkConfigReferences(config, report)
+	if len(report.Issues) > 0 {
+		return report
+	}
+
+	if err := config.Validate(); err != nil {
+		report.addIssue("config", "", "", "%v", err)
+		return report
+	}
+
+	staged := &Server{
+		config:    config,
+		logger:    logger,
+		nameRefs:  newRefMap(),
+		realmRefs: newRefMap(),
+	}
+	if err := staged.configureComponents(nil, nil, errors.ErrNewServer); err != nil {
+		report.addIssue("components", "", "", "%v", err)
+		return report
+	}
+
+	for _, cfg := range config.AuthenticationPortals {
+		report.AccessLists = append(report.AccessLists, &EffectiveACL{
+			Section: "authentication_portals",
+			Name:    cfg.Name,
+			Rules:   cfg.AccessListConfigs,
+		})
+	}
+	for _, cfg := range config.AuthorizationPolicies {
+		report.AccessLists = append(report.AccessLists, &EffectiveACL{
+			Section: "authorization_policies",
+			Name:    cfg.Name,
+			Rules:   cfg.AccessListRules,
+		})
+	}
+
+	runPolicyTests(config, staged, report)
+	if len(report.Issues) > 0 {
+		return report
+	}
+
+	report.Valid = true
+	return report
+}
+
+// runPolicyTests evaluates the policy tests with the gatekeepers and reports
+// the tests with unexpected decisions.
+func runPolicyTests(config *Config, srv *Server, report *ValidationReport) {
+	if len(config.PolicyTests) == 0 {
+		return
+	}
+	suite := &policytest.Suite{Tests: config.PolicyTests}
+	report.PolicyTests = suite.Run(func(name string) (policytest.Evaluator, error) {
+		return srv.GetGatekeeperByName(name)
+	})
+	for _, res := range report.PolicyTests {
+		switch {
+		case res.Error != "":
+			report.addIssue("policy_tests", res.Name, "fix the policy test", "%s", res.Error)
+		case !res.Passed:
+			report.addIssue("policy_tests", res.Name, "update the access list rules or the expected decision", "authorization policy %q: expected %s, got %s", res.Policy, res.Expected, res.Actual)
+		}
+	}
+}
+
+// checkConfigReferences reports the entries referencing the identity stores,
+// providers and policies which are not configured, and the realms shared by
+// multiple identity stores or providers.
+func checkConfigReferences(config *Config, report *ValidationReport) {
+	if len(config.AuthenticationPortals) < 1 && len(config.AuthorizationPolicies) < 1 {
+		report.addIssue("config", "", "add an authentication portal or an authorization policy", "no portals and gatekeepers found")
+	}
+
+	realms := make(map[string]string)
+	identityStores := make(map[string]bool)
+	for _, cfg := range config.IdentityStores {
+		if identityStores[cfg.Name] {
+			report.addIssue("identity_stores", cfg.Name, "rename one of the identity stores", "duplicate identity store name")
+		}
+		identityStores[cfg.Name] = true
+		realm, _ := cfg.Params["realm"].(string)
+		if realm == "" {
+			continue
+		}
+		if prev, exists := realms[realm]; exists {
+			report.addIssue("identity_stores", cfg.Name, "assign a unique realm to each identity store and provider", "realm %q is already used by %s", realm, prev)
+			continue
+		}
+		realms[realm] = fmt.Sprintf("identity store %q", cfg.Name)
+	}
+
+	identityProviders := make(map[string]bool)
+	for _, cfg := range config.IdentityProviders {
+		if identityProviders[cfg.Name] {
+			report.addIssue("identity_providers", cfg.Name, "rename one of the identity providers", "duplicate identity provider name")
+		}
+		identityProviders[cfg.Name] = true
+		realm, _ := cfg.Params["realm"].(string)
+		if realm == "" {
+			continue
+		}
+		if prev, exists := realms[realm]; exists {
+			report.addIssue("identity_providers", cfg.Name, "assign a unique realm to each identity store and provider", "realm %q is already used by %s", realm, prev)
+			continue
+		}
+		realms[realm] = fmt.Sprintf("identity provider %q", cfg.Name)
+	}
+
+	ssoProviders := make(map[string]bool)
+	for _, cfg := range config.SingleSignOnProviders {
+		ssoProviders[cfg.Name] = true
+	}
+
+	policies := make(map[string]bool)
+	for _, cfg := range config.AuthorizationPolicies {
+		if policies[cfg.Name] {
+			report.addIssue("authorization_policies", cfg.Name, "rename one of the policies", "duplicate authorization policy name")
+		}
+		policies[cfg.Name] = true
+	}
+
+	for _, cfg := range config.AuthenticationPortals {
+		for _, name := range cfg.IdentityStores {
+			if _, disabled := config.disabledIdentityStores[name]; !disabled && !identityStores[name] {
+				report.addIssue("authentication_portals", cfg.Name, "add the identity store or remove it from the portal", "identity store %q not found", name)
+			}
+		}
+		for _, name := range cfg.IdentityProviders {
+			if _, disabled := config.disabledIdentityProviders[name]; !disabled && !identityProviders[name] {
+				report.addIssue("authentication_portals", cfg.Name, "add the identity provider or remove it from the portal", "identity provider %q not found", name)
+			}
+		}
+		for _, name := range cfg.SingleSignOnProviders {
+			if !ssoProviders[name] {
+				report.addIssue("authentication_portals", cfg.Name, "add the sso provider or remove it from the portal", "sso provider %q not found", name)
+			}
+		}
+	}
+
+	for _, cfg := range config.UserRegistries {
+		if !identityStores[cfg.IdentityStore] {
+			report.addIssue("user_registries", cfg.Name, "point the user registry to a local identity store", "identity store %q not found", cfg.IdentityStore)
+		}
+	}
+
+	for _, cfg := range config.Directories {
+		if !identityStores[cfg.IdentityStore] {
+			report.addIssue("directories", cfg.Name, "point the directory to a local identity store", "identity store %q not found", cfg.IdentityStore)
+		}
+	}
+
+	for _, cfg := range config.RPCServers {
+		if !policies[cfg.AuthorizationPolicy] {
+			report.addIssue("rpc_servers", cfg.Name, "add the authorization policy or reference an existing one", "authorization policy %q not found", cfg.AuthorizationPolicy)
+		}
+	}
+	for _, cfg := range config.ExtAuthzServers {
+		if !policies[cfg.AuthorizationPolicy] {
+			report.addIssue("ext_authz_servers", cfg.Name, "add the authorization policy or reference an existing one", "authorization policy %q not found", cfg.AuthorizationPolicy)
+		}
+	}
+	for _, cfg := range config.ForwardAuthServers {
+		if !policies[cfg.AuthorizationPolicy] {
+			report.addIssue("forward_auth_servers", cfg.Name, "add the authorization policy or reference an existing one", "authorization policy %q not found", cfg.AuthorizationPolicy)
+		}
+	}
+	for _, cfg := range config.PolicyTests {
+		if err := cfg.Validate(); err != nil {
+			report.addIssue("policy_tests", cfg.Name, "fix the policy test", "%v", err)
+			continue
+		}
+		if !policies[cfg.Policy] {
+			report.addIssue("policy_tests", cfg.Name, "add the authorization policy or reference an existing one", "authorization policy %q not found", cfg.Policy)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authcrunch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authn"
+	"github.com/greenpau/go-authcrunch/pkg/authz/policytest"
+	"github.com/greenpau/go-authcrunch/pkg/forwardauth"
+	"github.com/greenpau/go-authcrunch/pkg/registry"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+)
+
+func TestValidateConfig(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestValidateConfig")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	dbPath := db.GetPath()
+
+	var testcases = []struct {
+		name   string
+		config func(*testing.T) *Config
+		want   *ValidationReport
+	}{
+		{
+			name: "test valid config",
+			config: func(t *testing.T) *Config {
+				return newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+			},
+			want: &ValidationReport{
+				Valid: true,
+				AccessLists: []*EffectiveACL{
+					{
+						Section: "authentication_portals",
+						Name:    "myportal",
+						Rules: []*acl.RuleConfiguration{
+							{
+								Conditions: []string{"match roles authp/admin authp/user authp/guest superuser superadmin"},
+								Action:     "allow stop",
+							},
+						},
+					},
+					{
+						Section: "authorization_policies",
+						Name:    "mygatekeeper",
+						Rules: []*acl.RuleConfiguration{
+							{
+								Conditions: []string{"match roles authp/admin authp/user"},
+								Action:     "allow stop",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test config without portals and policies",
+			config: func(t *testing.T) *Config {
+				return NewConfig()
+			},
+			want: &ValidationReport{
+				Issues: []*ValidationIssue{
+					{
+						Section: "config",
+						Message: "no portals and gatekeepers found",
+						Hint:    "add an authentication portal or an authorization policy",
+					},
+				},
+			},
+		},
+		{
+			name: "test config with broken references",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+				if err := cfg.AddIdentityStore("otherdb", "local", map[string]interface{}{
+					"realm": "local",
+					"path":  dbPath,
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if err := cfg.AddAuthenticationPortal(&authn.PortalConfig{
+					Name:              "otherportal",
+					IdentityProviders: []string{"contoso"},
+				}); err != nil {
+					t.Fatal(err)
+				}
+				cfg.UserRegistries = append(cfg.UserRegistries, &registry.UserRegistryConfig{
+					Name:          "myregistry",
+					IdentityStore: "userdb",
+				})
+				cfg.ForwardAuthServers = append(cfg.ForwardAuthServers, &forwardauth.Config{
+					Name:                "nginx",
+					AuthorizationPolicy: "otherpolicy",
+				})
+				return cfg
+			},
+			want: &ValidationReport{
+				Issues: []*ValidationIssue{
+					{
+						Section: "identity_stores",
+						Name:    "otherdb",
+						Message: `realm "local" is already used by identity store "localdb"`,
+						Hint:    "assign a unique realm to each identity store and provider",
+					},
+					{
+						Section: "authentication_portals",
+						Name:    "otherportal",
+						Message: `identity provider "contoso" not found`,
+						Hint:    "add the identity provider or remove it from the portal",
+					},
+					{
+						Section: "user_registries",
+						Name:    "myregistry",
+						Message: `identity store "userdb" not found`,
+						Hint:    "point the user registry to a local identity store",
+					},
+					{
+						Section: "forward_auth_servers",
+						Name:    "nginx",
+						Message: `authorization policy "otherpolicy" not found`,
+						Hint:    "add the authorization policy or reference an existing one",
+					},
+				},
+			},
+		},
+		{
+			name: "test config with failing policy test",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+				cfg.PolicyTests = []*policytest.Case{
+					{
+						Name:     "user accesses app",
+						Policy:   "mygatekeeper",
+						Identity: map[string]interface{}{"sub": "jsmith", "roles": []interface{}{"authp/user"}},
+						Path:     "/app",
+						Expect:   "allow",
+					},
+					{
+						Name:     "guest accesses app",
+						Policy:   "mygatekeeper",
+						Identity: map[string]interface{}{"sub": "guest", "roles": []interface{}{"authp/guest"}},
+						Path:     "/app",
+						Expect:   "allow",
+					},
+				}
+				return cfg
+			},
+			want: &ValidationReport{
+				Issues: []*ValidationIssue{
+					{
+						Section: "policy_tests",
+						Name:    "guest accesses app",
+						Message: `authorization policy "mygatekeeper": expected allow, got deny`,
+						Hint:    "update the access list rules or the expected decision",
+					},
+				},
+				AccessLists: []*EffectiveACL{
+					{
+						Section: "authentication_portals",
+						Name:    "myportal",
+						Rules: []*acl.RuleConfiguration{
+							{
+								Conditions: []string{"match roles authp/admin authp/user authp/guest superuser superadmin"},
+								Action:     "allow stop",
+							},
+						},
+					},
+					{
+						Section: "authorization_policies",
+						Name:    "mygatekeeper",
+						Rules: []*acl.RuleConfiguration{
+							{
+								Conditions: []string{"match roles authp/admin authp/user"},
+								Action:     "allow stop",
+							},
+						},
+					},
+				},
+				PolicyTests: []*policytest.Result{
+					{
+						Name:     "user accesses app",
+						Policy:   "mygatekeeper",
+						Expected: "allow",
+						Actual:   "allow",
+						Passed:   true,
+					},
+					{
+						Name:     "guest accesses app",
+						Policy:   "mygatekeeper",
+						Expected: "allow",
+						Actual:   "deny",
+					},
+				},
+			},
+		},
+		{
+			name: "test config with failing identity store",
+			config: func(t *testing.T) *Config {
+				cfg := newTestReconfigureConfig(t, dbPath, "mygatekeeper")
+				cfg.IdentityStores[0].Params["path"] = "/dev/null/users.json"
+				return cfg
+			},
+			want: &ValidationReport{
+				Issues: []*ValidationIssue{
+					{
+						Section: "components",
+						Message: `server initialization failed: failed configuring identity store: failed initializing database at "/dev/null/users.json": stat /dev/null/users.json: not a directory`,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ValidateConfig(tc.config(t), logutil.NewLogger())
+			tests.EvalObjectsWithLog(t, "ValidateConfig", tc.want, got, nil)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	var testcases = []struct {
+		name      string
+		fileName  string
+		data      string
+		want      map[string]interface{}
+		shouldErr bool
+	}{
+		{
+			name:     "test loading json config",
+			fileName: "config.json",
+			data:     `{"authorization_policies": [{"name": "mypolicy", "auth_url_path": "/auth"}]}`,
+			want: map[string]interface{}{
+				"policy_name": "mypolicy",
+				"auth_url":    "/auth",
+			},
+		},
+		{
+			name:     "test loading yaml config",
+			fileName: "config.yaml",
+			data:     "authorization_policies:\n  - name: mypolicy\n    auth_url_path: /auth\n",
+			want: map[string]interface{}{
+				"policy_name": "mypolicy",
+				"auth_url":    "/auth",
+			},
+		},
+		{
+			name:      "test loading malformed json config",
+			fileName:  "malformed.json",
+			data:      `{"authorization_policies": {}}`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fp := filepath.Join(dir, tc.fileName)
+			if err := os.WriteFile(fp, []byte(tc.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(fp)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error, got success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"policy_name": cfg.AuthorizationPolicies[0].Name,
+				"auth_url":    cfg.AuthorizationPolicies[0].AuthURLPath,
+			}
+			tests.EvalObjectsWithLog(t, "LoadConfig", tc.want, got, nil)
+		})
+	}
+}